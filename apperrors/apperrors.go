@@ -0,0 +1,171 @@
+// Package apperrors типизированные ошибки приложения: вид ошибки определяет HTTP статус,
+// код - машиночитаемый идентификатор ошибки в теле ответа
+package apperrors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+// Kind вид ошибки
+type Kind string
+
+// Виды ошибок
+const (
+	KindNotFound     Kind = "not_found"    // Объект не найден
+	KindValidation   Kind = "validation"   // Некорректные входные данные
+	KindConflict     Kind = "conflict"     // Конфликт с текущим состоянием
+	KindUnavailable  Kind = "unavailable"  // Зависимость временно недоступна
+	KindTimeout      Kind = "timeout"      // Истекло время выполнения
+	KindUnauthorized Kind = "unauthorized" // Требуется вход или неверные учетные данные
+	KindInternal     Kind = "internal"     // Внутренняя ошибка
+)
+
+// Error типизированная ошибка: вид, машиночитаемый код, сообщение и исходная ошибка
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+// Error возвращает сообщение с исходной ошибкой
+func (e *Error) Error() string {
+	switch {
+	case e.Message == "" && e.Err != nil:
+		return e.Err.Error()
+	case e.Err != nil:
+		return e.Message + ": " + e.Err.Error()
+	default:
+		return e.Message
+	}
+}
+
+// Unwrap возвращает исходную ошибку
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New создает ошибку заданного вида; пустой code заменяется видом
+func New(kind Kind, code, message string) *Error {
+	if code == "" {
+		code = string(kind)
+	}
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Wrap оборачивает err ошибкой заданного вида; nil остается nil
+func Wrap(kind Kind, code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if code == "" {
+		code = string(kind)
+	}
+	return &Error{Kind: kind, Code: code, Err: err}
+}
+
+// NotFound объект не найден
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+// Validation некорректные входные данные
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+// Conflict конфликт с текущим состоянием
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// Unavailable зависимость временно недоступна
+func Unavailable(code, message string) *Error {
+	return New(KindUnavailable, code, message)
+}
+
+// Unauthorized требуется вход или неверные учетные данные
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+// KindOf определяет вид ошибки. Кроме типизированных ошибок распознаются sql.ErrNoRows
+// и ошибки отмены контекста; остальные ошибки считаются внутренними
+func KindOf(err error) Kind {
+	var appErr *Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &appErr):
+		return appErr.Kind
+	case errors.Is(err, sql.ErrNoRows):
+		return KindNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return KindTimeout
+	case errors.Is(err, context.Canceled):
+		return KindUnavailable
+	default:
+		return KindInternal
+	}
+}
+
+// CodeOf возвращает машиночитаемый код ошибки
+func CodeOf(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Code != "" {
+		return appErr.Code
+	}
+	return string(KindOf(err))
+}
+
+// HTTPStatus возвращает HTTP статус для ошибки
+func HTTPStatus(err error) int {
+	switch KindOf(err) {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CodeForStatus возвращает код ошибки по HTTP статусу для ответов без типизированной ошибки
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return string(KindNotFound)
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return string(KindValidation)
+	case http.StatusConflict:
+		return string(KindConflict)
+	case http.StatusServiceUnavailable:
+		return string(KindUnavailable)
+	case http.StatusGatewayTimeout:
+		return string(KindTimeout)
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	default:
+		if status >= http.StatusInternalServerError {
+			return string(KindInternal)
+		}
+		return "error"
+	}
+}
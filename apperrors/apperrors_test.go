@@ -0,0 +1,81 @@
+package apperrors
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestClassification(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		kind   Kind
+		code   string
+		status int
+	}{
+		{"not found", NotFound("upload_not_found", "upload x not found"), KindNotFound, "upload_not_found", http.StatusNotFound},
+		{"wrapped validation", fmt.Errorf("handler: %w", Wrap(KindValidation, "invalid_xml", errors.New("EOF"))), KindValidation, "invalid_xml", http.StatusBadRequest},
+		{"conflict without code", New(KindConflict, "", "already exists"), KindConflict, "conflict", http.StatusConflict},
+		{"unavailable", Unavailable("database_unavailable", "serviceDB is nil"), KindUnavailable, "database_unavailable", http.StatusServiceUnavailable},
+		{"no rows", fmt.Errorf("failed to get client: %w", sql.ErrNoRows), KindNotFound, "not_found", http.StatusNotFound},
+		{"unauthorized", Unauthorized("invalid_credentials", "invalid username or password"), KindUnauthorized, "invalid_credentials", http.StatusUnauthorized},
+		{"deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), KindTimeout, "timeout", http.StatusGatewayTimeout},
+		{"plain", errors.New("disk I/O error"), KindInternal, "internal", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.kind {
+				t.Errorf("KindOf() = %s, want %s", got, tt.kind)
+			}
+			if got := CodeOf(tt.err); got != tt.code {
+				t.Errorf("CodeOf() = %s, want %s", got, tt.code)
+			}
+			if got := HTTPStatus(tt.err); got != tt.status {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	inner := errors.New("EOF")
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{Wrap(KindValidation, "invalid_xml", inner), "EOF"},
+		{&Error{Kind: KindNotFound, Message: "upload x not found", Err: sql.ErrNoRows}, "upload x not found: sql: no rows in result set"},
+		{NotFound("", "missing"), "missing"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+	if Wrap(KindInternal, "", nil) != nil {
+		t.Error("Wrap(nil) must return nil")
+	}
+	if !errors.Is(Wrap(KindValidation, "", inner), inner) {
+		t.Error("Wrap() must keep original error for errors.Is")
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]string{
+		http.StatusNotFound:            "not_found",
+		http.StatusUnprocessableEntity: "validation",
+		http.StatusConflict:            "conflict",
+		http.StatusMethodNotAllowed:    "method_not_allowed",
+		http.StatusBadGateway:          "internal",
+		http.StatusTeapot:              "error",
+	}
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"httpserver/database"
 	"httpserver/nomenclature"
 )
 
@@ -32,6 +33,16 @@ type AIClassificationResponse struct {
 	Alternatives [][]string `json:"alternatives,omitempty"`
 }
 
+// classificationSystemPrompt системный промпт AI классификации
+const classificationSystemPrompt = `Ты - эксперт по классификации товаров и услуг. 
+
+ВАЖНО: 
+- Физические товары (материалы, оборудование, изделия) НЕ могут быть услугами
+- Если видишь марку, модель, технические характеристики - это товар
+- Услуги описывают действия, работы, консультации, а не физические объекты
+
+Отвечай только в формате JSON.`
+
 // NewAIClassifier создает новый AI классификатор
 func NewAIClassifier(apiKey string, model string) *AIClassifier {
 	if model == "" {
@@ -41,8 +52,10 @@ func NewAIClassifier(apiKey string, model string) *AIClassifier {
 			model = "GLM-4.5-Air" // Последний fallback
 		}
 	}
+	aiClient := nomenclature.NewAIClient(apiKey, model)
+	aiClient.SetJob("classification")
 	return &AIClassifier{
-		aiClient: nomenclature.NewAIClient(apiKey, model),
+		aiClient: aiClient,
 	}
 }
 
@@ -66,6 +79,14 @@ func (ai *AIClassifier) ClassifyWithAI(request AIClassificationRequest) (*AIClas
 	return ai.parseAIResponse(response)
 }
 
+// ContentHash возвращает хеш входных данных запроса: модель, системный промпт и промпт объекта
+// (наименование, описание и дерево классификатора). extra - параметры, влияющие на сохраняемый результат
+// (например, стратегия свертки категорий)
+func (ai *AIClassifier) ContentHash(request AIClassificationRequest, extra ...string) string {
+	parts := append([]string{ai.aiClient.Model(), classificationSystemPrompt, ai.buildClassificationPrompt(request)}, extra...)
+	return database.ClassificationContentHash(parts...)
+}
+
 // buildClassificationPrompt строит промпт для классификации
 func (ai *AIClassifier) buildClassificationPrompt(request AIClassificationRequest) string {
 	classifierSummary := ai.summarizeClassifierTree()
@@ -193,16 +214,6 @@ func (ai *AIClassifier) callAI(prompt string) (string, error) {
 	// Но сначала нужно проверить, есть ли такой метод
 	// Если нет, используем ProcessProduct с кастомным промптом
 
-	// Создаем системный промпт
-	systemPrompt := `Ты - эксперт по классификации товаров и услуг. 
-
-ВАЖНО: 
-- Физические товары (материалы, оборудование, изделия) НЕ могут быть услугами
-- Если видишь марку, модель, технические характеристики - это товар
-- Услуги описывают действия, работы, консультации, а не физические объекты
-
-Отвечай только в формате JSON.`
-
 	// Используем ProcessProduct, но нам нужен другой формат ответа
 	// Создадим временный метод или используем существующий
 
@@ -210,7 +221,7 @@ func (ai *AIClassifier) callAI(prompt string) (string, error) {
 	// Иначе используем ProcessProduct и парсим ответ
 
 	// Пока используем упрощенный подход - вызываем через стандартный метод
-	result, err := ai.aiClient.GetCompletion(systemPrompt, prompt)
+	result, err := ai.aiClient.GetCompletion(classificationSystemPrompt, prompt)
 	if err != nil {
 		return "", fmt.Errorf("AI API call failed: %w", err)
 	}
@@ -0,0 +1,157 @@
+package classification
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"httpserver/database"
+)
+
+// FlattenTree разворачивает дерево в список узлов для хранения строками.
+// Родитель узла определяется по его положению в дереве, порядок соседей сохраняется в Position.
+func FlattenTree(root *CategoryNode) []database.ClassifierNode {
+	var nodes []database.ClassifierNode
+
+	var walk func(node *CategoryNode, parentCode string, position int)
+	walk = func(node *CategoryNode, parentCode string, position int) {
+		item := database.ClassifierNode{
+			Code:       node.ID,
+			ParentCode: parentCode,
+			Name:       node.Name,
+			Level:      node.Level,
+			Path:       node.Path,
+			Position:   position,
+		}
+		if len(node.Metadata) > 0 {
+			if data, err := json.Marshal(node.Metadata); err == nil {
+				item.Metadata = string(data)
+			}
+		}
+		nodes = append(nodes, item)
+
+		for i := range node.Children {
+			walk(&node.Children[i], node.ID, i)
+		}
+	}
+	walk(root, "", 0)
+
+	return nodes
+}
+
+// BuildTreeFromNodes собирает дерево из узлов, сохраненных строками.
+// Если узлов без родителя несколько, они объединяются под синтетическим корнем "root".
+func BuildTreeFromNodes(nodes []database.ClassifierNode) (*CategoryNode, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("classifier has no nodes")
+	}
+
+	known := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		known[node.Code] = true
+	}
+
+	// Индексы дочерних узлов по коду родителя (узлы уже упорядочены по position)
+	childrenOf := make(map[string][]int, len(nodes))
+	var roots []int
+	for i, node := range nodes {
+		if node.ParentCode == "" || !known[node.ParentCode] {
+			roots = append(roots, i)
+			continue
+		}
+		childrenOf[node.ParentCode] = append(childrenOf[node.ParentCode], i)
+	}
+
+	visited := make(map[string]bool, len(nodes))
+	var build func(i int) CategoryNode
+	build = func(i int) CategoryNode {
+		node := nodes[i]
+		result := CategoryNode{
+			ID:       node.Code,
+			Name:     node.Name,
+			Path:     node.Path,
+			Level:    node.Level,
+			ParentID: node.ParentCode,
+		}
+		if node.Metadata != "" {
+			json.Unmarshal([]byte(node.Metadata), &result.Metadata)
+		}
+
+		// Защита от циклов при поврежденных данных
+		if visited[node.Code] {
+			return result
+		}
+		visited[node.Code] = true
+
+		if children := childrenOf[node.Code]; len(children) > 0 {
+			result.Children = make([]CategoryNode, 0, len(children))
+			for _, child := range children {
+				result.Children = append(result.Children, build(child))
+			}
+		}
+		return result
+	}
+
+	if len(roots) == 1 {
+		root := build(roots[0])
+		return &root, nil
+	}
+
+	root := &CategoryNode{ID: "root", Children: make([]CategoryNode, 0, len(roots))}
+	for _, i := range roots {
+		root.Children = append(root.Children, build(i))
+	}
+	return root, nil
+}
+
+// LoadClassifierTree загружает дерево классификатора из строк category_classifier_nodes.
+// Для классификаторов, сохраненных до появления таблицы узлов, дерево разбирается из
+// tree_structure и узлы сохраняются строками, чтобы следующие загрузки не разбирали JSON.
+func LoadClassifierTree(db *database.DB, classifier *database.CategoryClassifier) (*CategoryNode, error) {
+	nodes, err := db.GetClassifierNodes(classifier.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) > 0 {
+		return BuildTreeFromNodes(nodes)
+	}
+
+	if classifier.TreeStructure == "" {
+		return nil, fmt.Errorf("classifier %d has no tree", classifier.ID)
+	}
+
+	var tree CategoryNode
+	if err := json.Unmarshal([]byte(classifier.TreeStructure), &tree); err != nil {
+		return nil, fmt.Errorf("failed to parse classifier tree: %w", err)
+	}
+
+	if err := db.SaveClassifierNodes(classifier.ID, FlattenTree(&tree)); err != nil {
+		log.Printf("[Classification] Не удалось сохранить узлы классификатора %d: %v", classifier.ID, err)
+	}
+
+	return &tree, nil
+}
+
+// SyncClassifierTreeStructure пересобирает tree_structure классификатора из узлов после их редактирования
+func SyncClassifierTreeStructure(db *database.DB, classifierID int) (*CategoryNode, error) {
+	nodes, err := db.GetClassifierNodes(classifierID)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, db.SaveClassifierTreeStructure(classifierID, "")
+	}
+
+	tree, err := BuildTreeFromNodes(nodes)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal classifier tree: %w", err)
+	}
+	if err := db.SaveClassifierTreeStructure(classifierID, string(data)); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
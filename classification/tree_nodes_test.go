@@ -0,0 +1,241 @@
+package classification
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+// generateTestTree строит дерево заданной глубины с фиксированным ветвлением (как 6 уровней КПВЭД)
+func generateTestTree(depth, branching int) *CategoryNode {
+	root := &CategoryNode{ID: "root", Name: "КПВЭД", Path: "КПВЭД"}
+
+	var fill func(node *CategoryNode, level int)
+	fill = func(node *CategoryNode, level int) {
+		if level > depth {
+			return
+		}
+		for i := 0; i < branching; i++ {
+			code := fmt.Sprintf("%d", i+1)
+			if node.ID != "root" {
+				code = node.ID + "." + code
+			}
+			child := CategoryNode{
+				ID:       code,
+				Name:     "Категория " + code,
+				Path:     node.Path + " / Категория " + code,
+				Level:    level,
+				ParentID: node.ID,
+			}
+			fill(&child, level+1)
+			node.Children = append(node.Children, child)
+		}
+	}
+	fill(root, 1)
+
+	return root
+}
+
+// newTestClassifierDB создает in-memory БД с классификатором из JSON дерева
+func newTestClassifierDB(t testing.TB, tree *CategoryNode) (*database.DB, *database.CategoryClassifier) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	treeJSON, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Failed to marshal tree: %v", err)
+	}
+
+	classifier, err := db.CreateCategoryClassifier(&database.CategoryClassifier{
+		Name:          "КПВЭД",
+		MaxDepth:      6,
+		TreeStructure: string(treeJSON),
+		IsActive:      true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	return db, classifier
+}
+
+// countTreeNodes считает узлы дерева вместе с корнем
+func countTreeNodes(node *CategoryNode) int {
+	count := 1
+	for i := range node.Children {
+		count += countTreeNodes(&node.Children[i])
+	}
+	return count
+}
+
+func TestLoadClassifierTree(t *testing.T) {
+	tree := generateTestTree(3, 3)
+	tree.Children[1].Metadata = map[string]interface{}{"source": "kpved"}
+
+	db, classifier := newTestClassifierDB(t, tree)
+	defer db.Close()
+
+	// Первая загрузка разбирает JSON и сохраняет узлы строками
+	first, err := LoadClassifierTree(db, classifier)
+	if err != nil {
+		t.Fatalf("LoadClassifierTree() error = %v", err)
+	}
+
+	count, err := db.CountClassifierNodes(classifier.ID)
+	if err != nil {
+		t.Fatalf("CountClassifierNodes() error = %v", err)
+	}
+	if count != countTreeNodes(tree) {
+		t.Fatalf("Expected %d stored nodes, got %d", countTreeNodes(tree), count)
+	}
+
+	// Вторая загрузка собирает дерево из строк
+	classifier.TreeStructure = ""
+	second, err := LoadClassifierTree(db, classifier)
+	if err != nil {
+		t.Fatalf("LoadClassifierTree() from rows error = %v", err)
+	}
+
+	if countTreeNodes(first) != countTreeNodes(second) {
+		t.Fatalf("Expected %d nodes after rebuild, got %d", countTreeNodes(first), countTreeNodes(second))
+	}
+	if second.ID != "root" || len(second.Children) != 3 {
+		t.Fatalf("Unexpected root: %s with %d children", second.ID, len(second.Children))
+	}
+	if got := second.Children[2].Children[0].ID; got != "3.1" {
+		t.Errorf("Expected sibling order to be preserved, got %s", got)
+	}
+	if got := second.Children[1].Metadata["source"]; got != "kpved" {
+		t.Errorf("Expected metadata to be preserved, got %v", got)
+	}
+
+	children, err := db.GetClassifierNodeChildren(classifier.ID, "2")
+	if err != nil {
+		t.Fatalf("GetClassifierNodeChildren() error = %v", err)
+	}
+	if len(children) != 3 || children[0].Code != "2.1" || children[0].Level != 2 {
+		t.Errorf("Unexpected children of 2: %+v", children)
+	}
+
+	// Обновление дерева сбрасывает узлы, они пересобираются из нового JSON
+	classifier.TreeStructure = `{"id":"root","name":"КПВЭД","children":[{"id":"A","name":"Раздел A","level":1}]}`
+	if err := db.UpdateCategoryClassifier(classifier); err != nil {
+		t.Fatalf("UpdateCategoryClassifier() error = %v", err)
+	}
+	updated, err := LoadClassifierTree(db, classifier)
+	if err != nil {
+		t.Fatalf("LoadClassifierTree() after update error = %v", err)
+	}
+	if len(updated.Children) != 1 || updated.Children[0].ID != "A" {
+		t.Errorf("Expected updated tree, got %+v", updated.Children)
+	}
+}
+
+func TestBuildTreeFromNodesMultipleRoots(t *testing.T) {
+	nodes := []database.ClassifierNode{
+		{Code: "A", Name: "Раздел A", Level: 1},
+		{Code: "B", Name: "Раздел B", Level: 1, Position: 1},
+		{Code: "A.1", ParentCode: "A", Name: "Класс A.1", Level: 2},
+		{Code: "X.1", ParentCode: "X", Name: "Сирота", Level: 2},
+	}
+
+	tree, err := BuildTreeFromNodes(nodes)
+	if err != nil {
+		t.Fatalf("BuildTreeFromNodes() error = %v", err)
+	}
+	if tree.ID != "root" || len(tree.Children) != 3 {
+		t.Fatalf("Expected synthetic root with 3 children, got %s with %d", tree.ID, len(tree.Children))
+	}
+	if len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].ID != "A.1" {
+		t.Errorf("Expected A.1 under A, got %+v", tree.Children[0].Children)
+	}
+
+	if _, err := BuildTreeFromNodes(nil); err == nil {
+		t.Error("Expected error for empty nodes")
+	}
+}
+
+// Бенчмарки сравнивают разбор JSON поля tree_structure с загрузкой узлов строками
+// для дерева из 6 уровней (4^6 листьев, ~5.5 тыс. узлов).
+
+func BenchmarkClassifierTreeJSON(b *testing.B) {
+	treeJSON, _ := json.Marshal(generateTestTree(6, 4))
+	b.SetBytes(int64(len(treeJSON)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var tree CategoryNode
+		if err := json.Unmarshal(treeJSON, &tree); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClassifierTreeRows(b *testing.B) {
+	db, classifier := newTestClassifierDB(b, generateTestTree(6, 4))
+	defer db.Close()
+	if _, err := LoadClassifierTree(db, classifier); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadClassifierTree(db, classifier); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClassifierNodeChildren(b *testing.B) {
+	db, classifier := newTestClassifierDB(b, generateTestTree(6, 4))
+	defer db.Close()
+	if _, err := LoadClassifierTree(db, classifier); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	// Обход одного уровня - основной сценарий UI и поиска, не требующий всего дерева
+	for i := 0; i < b.N; i++ {
+		if _, err := db.GetClassifierNodeChildren(classifier.ID, "1.2.3"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkClassifierTreeFolding сворачивает пути всех листьев дерева КПВЭД каждой стратегией
+func BenchmarkClassifierTreeFolding(b *testing.B) {
+	var paths [][]string
+	var collect func(node *CategoryNode)
+	collect = func(node *CategoryNode) {
+		if len(node.Children) == 0 {
+			paths = append(paths, strings.Split(node.Path, " / "))
+			return
+		}
+		for i := range node.Children {
+			collect(&node.Children[i])
+		}
+	}
+	collect(generateTestTree(6, 4))
+
+	sm := NewStrategyManager()
+	for _, strategyID := range []string{"top_priority", "bottom_priority", "mixed_priority"} {
+		b.Run(strategyID, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, path := range paths {
+					if _, err := sm.FoldCategory(path, strategyID); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
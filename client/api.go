@@ -0,0 +1,101 @@
+package client
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// UploadListItem краткая информация о выгрузке для списка
+type UploadListItem struct {
+	UploadUUID     string     `json:"upload_uuid"`
+	StartedAt      time.Time  `json:"started_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	Status         string     `json:"status"`
+	Version1C      string     `json:"version_1c"`
+	ConfigName     string     `json:"config_name"`
+	TotalConstants int        `json:"total_constants"`
+	TotalCatalogs  int        `json:"total_catalogs"`
+	TotalItems     int        `json:"total_items"`
+}
+
+// CatalogInfo информация о справочнике с количеством элементов
+type CatalogInfo struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Synonym   string    `json:"synonym"`
+	ItemCount int       `json:"item_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DocumentTypeInfo вид документа выгрузки
+type DocumentTypeInfo struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Synonym       string    `json:"synonym"`
+	DocumentCount int       `json:"document_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UploadDetails детальная информация о выгрузке
+type UploadDetails struct {
+	UploadUUID     string        `json:"upload_uuid"`
+	StartedAt      time.Time     `json:"started_at"`
+	CompletedAt    *time.Time    `json:"completed_at,omitempty"`
+	Status         string        `json:"status"`
+	Version1C      string        `json:"version_1c"`
+	ConfigName     string        `json:"config_name"`
+	TotalConstants int           `json:"total_constants"`
+	TotalCatalogs  int           `json:"total_catalogs"`
+	TotalItems     int           `json:"total_items"`
+	Catalogs       []CatalogInfo `json:"catalogs"`
+	Constants      []interface{} `json:"constants"`
+	// Документы выгрузки по видам; счетчик в uploads не хранится и подсчитывается по данным
+	TotalDocuments int                `json:"total_documents"`
+	DocumentTypes  []DocumentTypeInfo `json:"document_types,omitempty"`
+	// Счетчики, подсчитанные по фактическим данным, и признак расхождения с сохраненными
+	ComputedCounts *UploadCounters `json:"computed_counts,omitempty"`
+	CountersDrift  bool            `json:"counters_drift"`
+}
+
+// UploadCounters счетчики выгрузки, подсчитанные по фактическим данным
+type UploadCounters struct {
+	Constants int `json:"constants"`
+	Catalogs  int `json:"catalogs"`
+	Items     int `json:"items"`
+	Documents int `json:"documents"`
+}
+
+// DataItem элемент данных для API ответа
+type DataItem struct {
+	XMLName   xml.Name  `xml:"item"`
+	Type      string    `xml:"type,attr"` // "constant" или "catalog_item"
+	ID        int       `xml:"id,attr"`
+	Data      string    `xml:",innerxml"` // XML данные как строка
+	CreatedAt time.Time `xml:"created_at,attr"`
+}
+
+// DataResponse ответ с данными выгрузки
+type DataResponse struct {
+	XMLName    xml.Name   `xml:"data_response"`
+	UploadUUID string     `xml:"upload_uuid"`
+	Type       string     `xml:"type"` // "all", "constants", "catalogs"
+	Page       int        `xml:"page"`
+	Limit      int        `xml:"limit"`
+	Total      int        `xml:"total"`
+	Items      []DataItem `xml:"items>item"`
+}
+
+// VerifyRequest запрос на проверку передачи
+type VerifyRequest struct {
+	ReceivedIDs []int `json:"received_ids"`
+}
+
+// VerifyResponse ответ на проверку передачи
+type VerifyResponse struct {
+	UploadUUID    string `json:"upload_uuid"`
+	ExpectedTotal int    `json:"expected_total"`
+	ReceivedCount int    `json:"received_count"`
+	MissingIDs    []int  `json:"missing_ids,omitempty"`
+	IsComplete    bool   `json:"is_complete"`
+	Message       string `json:"message"`
+}
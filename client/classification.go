@@ -0,0 +1,80 @@
+package client
+
+import "context"
+
+// ClassifyItemRequest запрос AI классификации товара по категориям
+type ClassifyItemRequest struct {
+	ItemName   string                 `json:"item_name"`
+	ItemCode   string                 `json:"item_code"`
+	StrategyID string                 `json:"strategy_id"` // Пустая - top_priority
+	Context    map[string]interface{} `json:"context,omitempty"`
+	Category   string                 `json:"category,omitempty"`
+}
+
+// ItemClassification путь категории и альтернативы, предложенные AI
+type ItemClassification struct {
+	CategoryPath []string   `json:"category_path"`
+	Confidence   float64    `json:"confidence"`
+	Reasoning    string     `json:"reasoning"`
+	Alternatives [][]string `json:"alternatives,omitempty"`
+}
+
+// ClassifyItemResponse результат классификации товара
+type ClassifyItemResponse struct {
+	ItemName       string             `json:"item_name"`
+	ItemCode       string             `json:"item_code"`
+	Category       []string           `json:"category"`
+	Confidence     float64            `json:"confidence"`
+	Reasoning      string             `json:"reasoning"`
+	Strategy       string             `json:"strategy"`
+	Classification ItemClassification `json:"classification"`
+}
+
+// ClassifyItem классифицирует товар по дереву категорий
+// POST /api/classification/classify-item
+func (c *Client) ClassifyItem(ctx context.Context, req *ClassifyItemRequest) (*ClassifyItemResponse, error) {
+	var resp ClassifyItemResponse
+	if err := c.postJSON(ctx, "/api/classification/classify-item", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// KpvedClassifyRequest запрос иерархической классификации по КПВЭД
+type KpvedClassifyRequest struct {
+	NormalizedName string `json:"normalized_name"`
+	Category       string `json:"category"` // Пустая - "общее"
+}
+
+// KpvedStep шаг иерархической классификации (уровень классификатора)
+type KpvedStep struct {
+	Level      string  `json:"level"`
+	LevelName  string  `json:"level_name"`
+	Code       string  `json:"code"`
+	Name       string  `json:"name"`
+	Confidence float64 `json:"confidence"`
+	Reasoning  string  `json:"reasoning"`
+	Duration   int64   `json:"duration_ms"`
+}
+
+// KpvedClassifyResponse результат иерархической классификации по КПВЭД
+type KpvedClassifyResponse struct {
+	FinalCode       string      `json:"final_code"`
+	FinalName       string      `json:"final_name"`
+	FinalConfidence float64     `json:"final_confidence"`
+	Steps           []KpvedStep `json:"steps"`
+	TotalDuration   int64       `json:"total_duration_ms"`
+	CacheHits       int         `json:"cache_hits"`
+	AICallsCount    int         `json:"ai_calls_count"`
+	Model           string      `json:"model,omitempty"`
+}
+
+// ClassifyKpved определяет код КПВЭД нормализованного наименования
+// POST /api/kpved/classify-hierarchical
+func (c *Client) ClassifyKpved(ctx context.Context, req *KpvedClassifyRequest) (*KpvedClassifyResponse, error) {
+	var resp KpvedClassifyResponse
+	if err := c.postJSON(ctx, "/api/kpved/classify-hierarchical", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,271 @@
+// Package client клиент HTTP API сервера выгрузок для внутренних сервисов: протокол выгрузки из 1С
+// (рукопожатие, метаданные, константы, справочники, номенклатура, завершение), получение данных выгрузок
+// и классификация. Структуры запросов и ответов протокола общие с сервером.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Значения по умолчанию
+const (
+	DefaultTimeout    = 5 * time.Minute
+	DefaultMaxRetries = 3
+
+	// Задержка повтора, если сервер не указал Retry-After, и ее верхняя граница
+	defaultRetryDelay = time.Second
+	maxRetryDelay     = time.Minute
+	// Ограничение размера тела ответа
+	maxResponseSize = 256 << 20
+)
+
+// Config настройки клиента
+type Config struct {
+	BaseURL    string        // Адрес сервера, например http://localhost:9999
+	Token      string        // Bearer токен (API ключ или токен сессии), пустой - без авторизации
+	HTTPClient *http.Client  // nil - http.Client с таймаутом Timeout
+	Timeout    time.Duration // Таймаут запроса (0 - DefaultTimeout)
+	// Повторы при 429 и 503 с учетом Retry-After (0 - DefaultMaxRetries, отрицательное - без повторов)
+	MaxRetries int
+	UserAgent  string
+}
+
+// Client клиент API сервера. Безопасен для одновременного использования
+type Client struct {
+	baseURL    *url.URL
+	token      string
+	userAgent  string
+	httpClient *http.Client
+	maxRetries int
+	retryDelay time.Duration // Базовая задержка повтора без Retry-After
+}
+
+// New создает клиент
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("base URL is required")
+	}
+	baseURL, err := url.Parse(strings.TrimRight(cfg.BaseURL, "/"))
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid base URL: %s", cfg.BaseURL)
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		httpClient = &http.Client{Timeout: timeout}
+	}
+	maxRetries := cfg.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = DefaultMaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = "httpserver-client"
+	}
+
+	return &Client{
+		baseURL:    baseURL,
+		token:      cfg.Token,
+		userAgent:  userAgent,
+		httpClient: httpClient,
+		maxRetries: maxRetries,
+		retryDelay: defaultRetryDelay,
+	}, nil
+}
+
+// APIError ошибка, возвращенная сервером
+type APIError struct {
+	StatusCode int
+	Code       string // Машиночитаемый код ошибки
+	Message    string
+	RetryAfter time.Duration // Для 429: через сколько повторить запрос
+	// Для 426 Upgrade Required: минимальная поддерживаемая и текущая версии протокола сервера
+	MinProtocolVersion int
+	ProtocolVersion    int
+	// Для 409 на /complete: расхождения сверки полноты выгрузки
+	Discrepancies []CompletenessDiscrepancy
+}
+
+// Error реализует интерфейс error
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("server returned %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsStatus проверяет, что err - ошибка сервера с заданным HTTP статусом
+func IsStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// postXML отправляет пакет протокола выгрузки и разбирает XML ответ
+func (c *Client) postXML(ctx context.Context, path string, request, response interface{}) error {
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", path, err)
+	}
+	data, err := c.do(ctx, http.MethodPost, path, nil, append([]byte(xml.Header), body...), "application/xml; charset=utf-8")
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
+	}
+	return nil
+}
+
+// getXML выполняет GET запрос и разбирает XML ответ
+func (c *Client) getXML(ctx context.Context, path string, query url.Values, response interface{}) error {
+	data, err := c.do(ctx, http.MethodGet, path, query, nil, "")
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
+	}
+	return nil
+}
+
+// getJSON выполняет GET запрос и разбирает JSON ответ
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, response interface{}) error {
+	data, err := c.do(ctx, http.MethodGet, path, query, nil, "")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
+	}
+	return nil
+}
+
+// postJSON отправляет JSON и разбирает JSON ответ
+func (c *Client) postJSON(ctx context.Context, path string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", path, err)
+	}
+	data, err := c.do(ctx, http.MethodPost, path, nil, body, "application/json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
+	}
+	return nil
+}
+
+// do выполняет запрос и возвращает тело успешного ответа (2xx). При 429 и 503 запрос повторяется
+// не более maxRetries раз с задержкой из Retry-After; остальные ошибки возвращаются как *APIError
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, contentType string) ([]byte, error) {
+	target := *c.baseURL
+	target.Path = c.baseURL.Path + path
+	target.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s response: %w", path, err)
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return data, nil
+		}
+
+		apiErr := parseAPIError(resp, data)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+		if !retryable || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+		delay := apiErr.RetryAfter
+		if delay <= 0 {
+			delay = c.retryDelay * time.Duration(attempt+1)
+		}
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// parseAPIError разбирает ответ об ошибке: XML протокола выгрузки (error_response), JSON API или текст
+func parseAPIError(resp *http.Response, data []byte) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		var xmlErr ErrorResponse
+		if err := xml.Unmarshal(trimmed, &xmlErr); err == nil {
+			apiErr.Code = xmlErr.Code
+			apiErr.Message = xmlErr.Error
+			if xmlErr.Message != "" {
+				apiErr.Message = xmlErr.Message
+			}
+			apiErr.RetryAfter = time.Duration(xmlErr.RetryAfter) * time.Second
+			apiErr.MinProtocolVersion = xmlErr.MinProtocolVersion
+			apiErr.ProtocolVersion = xmlErr.ProtocolVersion
+			apiErr.Discrepancies = xmlErr.Discrepancies
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var jsonErr struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if err := json.Unmarshal(trimmed, &jsonErr); err == nil {
+			apiErr.Code = jsonErr.Code
+			apiErr.Message = jsonErr.Error
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = string(trimmed)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	c, err := New(Config{BaseURL: ts.URL + "/", Token: "secret", MaxRetries: 2})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	c.retryDelay = time.Millisecond
+	return c
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		wantErr bool
+	}{
+		{"valid", "http://localhost:9999", false},
+		{"with path", "https://example.com/httpserver/", false},
+		{"empty", "", true},
+		{"without scheme", "localhost:9999", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{BaseURL: tt.baseURL})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New(%q) error = %v, wantErr %v", tt.baseURL, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        APIError
+	}{
+		{
+			name: "protocol xml", status: http.StatusUpgradeRequired, contentType: "application/xml",
+			body: `<error_response><success>false</success><error>upgrade</error><code>protocol_upgrade_required</code>` +
+				`<message>protocol version 1 is no longer supported</message><min_protocol_version>2</min_protocol_version><protocol_version>3</protocol_version></error_response>`,
+			want: APIError{StatusCode: http.StatusUpgradeRequired, Code: "protocol_upgrade_required",
+				Message: "protocol version 1 is no longer supported", MinProtocolVersion: 2, ProtocolVersion: 3},
+		},
+		{
+			name: "json api", status: http.StatusNotFound, contentType: "application/json",
+			body: `{"error":"Upload not found","code":"not_found","timestamp":"2024-05-01T10:00:00Z"}`,
+			want: APIError{StatusCode: http.StatusNotFound, Code: "not_found", Message: "Upload not found"},
+		},
+		{
+			name: "plain text", status: http.StatusMethodNotAllowed, contentType: "text/plain",
+			body: "Method not allowed\n",
+			want: APIError{StatusCode: http.StatusMethodNotAllowed, Message: "Method not allowed"},
+		},
+		{
+			name: "empty body", status: http.StatusForbidden,
+			want: APIError{StatusCode: http.StatusForbidden, Message: "Forbidden"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			_, err := c.GetUpload(context.Background(), "u-1")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want *APIError", err)
+			}
+			if !reflect.DeepEqual(*apiErr, tt.want) {
+				t.Errorf("error = %+v, want %+v", *apiErr, tt.want)
+			}
+			if !IsStatus(err, tt.status) {
+				t.Errorf("IsStatus(%d) = false", tt.status)
+			}
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+		wantStatus   int
+	}{
+		{"rate limited then ok", []int{http.StatusTooManyRequests, http.StatusOK}, 2, 0},
+		{"unavailable then ok", []int{http.StatusServiceUnavailable, http.StatusOK}, 2, 0},
+		{"retries exhausted", []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests}, 3, http.StatusTooManyRequests},
+		{"bad request is not retried", []int{http.StatusBadRequest, http.StatusOK}, 1, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&attempts, 1)
+				status := tt.statuses[n-1]
+				if status != http.StatusOK {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(status)
+					io.WriteString(w, `<error_response><error>slow down</error><code>rate_limited</code></error_response>`)
+					return
+				}
+				io.WriteString(w, `<complete_response><success>true</success></complete_response>`)
+			})
+			upload := &Upload{client: c, Response: &HandshakeResponse{UploadUUID: "u-1"}}
+			_, err := upload.Complete(context.Background())
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantStatus == 0 && err != nil {
+				t.Errorf("Complete() error = %v", err)
+			}
+			if tt.wantStatus != 0 && !IsStatus(err, tt.wantStatus) {
+				t.Errorf("Complete() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestClientRetryHonorsContext(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.ListUploads(ctx, UploadListOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ListUploads() error = %v, want context deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("retry wait ignored context: %v", elapsed)
+	}
+}
+
+func TestClientRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(c *Client) error
+		wantPath  string
+		wantQuery string
+		wantBody  string
+		response  string
+	}{
+		{
+			name: "list uploads",
+			call: func(c *Client) error {
+				_, err := c.ListUploads(context.Background(), UploadListOptions{
+					Status: "completed", DatabaseID: 7, From: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Limit: 20, SortBy: "status", Order: "asc",
+				})
+				return err
+			},
+			wantPath:  "/api/uploads",
+			wantQuery: "database_id=7&from=2024-05-01T00%3A00%3A00Z&limit=20&order=asc&sort_by=status&status=completed",
+			response:  `{"uploads":[],"total":0,"limit":20,"offset":0}`,
+		},
+		{
+			name: "upload data",
+			call: func(c *Client) error {
+				_, err := c.GetUploadData(context.Background(), "u-1", DataOptions{Type: "catalogs", CatalogNames: []string{"Номенклатура", "Склады"}, Page: 2})
+				return err
+			},
+			wantPath:  "/api/uploads/u-1/data",
+			wantQuery: "catalog_names=%D0%9D%D0%BE%D0%BC%D0%B5%D0%BD%D0%BA%D0%BB%D0%B0%D1%82%D1%83%D1%80%D0%B0%2C%D0%A1%D0%BA%D0%BB%D0%B0%D0%B4%D1%8B&page=2&type=catalogs",
+			response:  `<data_response><upload_uuid>u-1</upload_uuid></data_response>`,
+		},
+		{
+			name: "verify",
+			call: func(c *Client) error {
+				_, err := c.VerifyUpload(context.Background(), "u-1", nil)
+				return err
+			},
+			wantPath: "/api/uploads/u-1/verify",
+			wantBody: `{"received_ids":[]}`,
+			response: `{"upload_uuid":"u-1","is_complete":true}`,
+		},
+		{
+			name: "kpved",
+			call: func(c *Client) error {
+				_, err := c.ClassifyKpved(context.Background(), &KpvedClassifyRequest{NormalizedName: "молоток"})
+				return err
+			},
+			wantPath: "/api/kpved/classify-hierarchical",
+			wantBody: `{"normalized_name":"молоток","category":""}`,
+			response: `{"final_code":"25.73.30","steps":[{"level":"section","code":"C"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+					t.Errorf("Authorization = %q", got)
+				}
+				if r.URL.Path != tt.wantPath || r.URL.RawQuery != tt.wantQuery {
+					t.Errorf("request = %s?%s, want %s?%s", r.URL.Path, r.URL.RawQuery, tt.wantPath, tt.wantQuery)
+				}
+				body, _ := io.ReadAll(r.Body)
+				if string(body) != tt.wantBody {
+					t.Errorf("body = %s, want %s", body, tt.wantBody)
+				}
+				io.WriteString(w, tt.response)
+			})
+			if err := tt.call(c); err != nil {
+				t.Errorf("call error = %v", err)
+			}
+		})
+	}
+}
+
+func TestUploadFillsProtocolFields(t *testing.T) {
+	var metadata MetadataRequest
+	var items CatalogItemsRequest
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/handshake":
+			var req HandshakeRequest
+			if err := xml.Unmarshal(body, &req); err != nil || req.ProtocolVersion != "3" || req.Timestamp == "" {
+				t.Errorf("handshake = %+v (%v)", req, err)
+			}
+			io.WriteString(w, `<handshake_response><success>true</success><upload_uuid>u-1</upload_uuid><protocol_version>3</protocol_version></handshake_response>`)
+		case "/metadata":
+			xml.Unmarshal(body, &metadata)
+			io.WriteString(w, `<metadata_response><success>true</success></metadata_response>`)
+		case "/catalog/items":
+			xml.Unmarshal(body, &items)
+			io.WriteString(w, `<catalog_items_response><success>true</success><processed_count>1</processed_count></catalog_items_response>`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	ctx := context.Background()
+	upload, err := c.StartUpload(ctx, HandshakeRequest{DatabaseID: "7", Version1C: "8.3.24", ConfigName: "УТ"})
+	if err != nil {
+		t.Fatalf("StartUpload() error = %v", err)
+	}
+	if _, err := upload.SendMetadata(ctx, &MetadataRequest{}); err != nil {
+		t.Fatalf("SendMetadata() error = %v", err)
+	}
+	if metadata.UploadUUID != "u-1" || metadata.DatabaseID != "7" || metadata.Version1C != "8.3.24" || metadata.ConfigName != "УТ" {
+		t.Errorf("metadata = %+v", metadata)
+	}
+
+	attributes := "<Артикул>A-1</Артикул><Вес>1,5</Вес>"
+	if _, err := upload.SendCatalogItems(ctx, "Номенклатура", []CatalogItem{{Reference: "ref-1", Name: "Молоток", Attributes: XMLContent{Content: attributes}}}); err != nil {
+		t.Fatalf("SendCatalogItems() error = %v", err)
+	}
+	if items.UploadUUID != "u-1" || len(items.Items) != 1 || items.Items[0].Timestamp == "" {
+		t.Fatalf("catalog items = %+v", items)
+	}
+	if got := strings.TrimSpace(items.Items[0].Attributes.String()); got != attributes {
+		t.Errorf("attributes round trip = %q, want %q", got, attributes)
+	}
+}
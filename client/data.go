@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UploadListOptions фильтры, сортировка и пагинация списка выгрузок. Пустые значения не передаются
+type UploadListOptions struct {
+	Status     string
+	ConfigName string
+	ClientID   int
+	ProjectID  int
+	DatabaseID int
+	From       time.Time // Начало периода по времени запуска выгрузки
+	To         time.Time
+	Limit      int
+	Offset     int
+	SortBy     string // started_at, status, config_name
+	Order      string // asc, desc
+}
+
+// query параметры запроса списка выгрузок
+func (o UploadListOptions) query() url.Values {
+	query := url.Values{}
+	setString := func(key, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	setInt := func(key string, value int) {
+		if value > 0 {
+			query.Set(key, strconv.Itoa(value))
+		}
+	}
+	setString("status", o.Status)
+	setString("config_name", o.ConfigName)
+	setInt("client_id", o.ClientID)
+	setInt("project_id", o.ProjectID)
+	setInt("database_id", o.DatabaseID)
+	if !o.From.IsZero() {
+		query.Set("from", o.From.Format(time.RFC3339))
+	}
+	if !o.To.IsZero() {
+		query.Set("to", o.To.Format(time.RFC3339))
+	}
+	setInt("limit", o.Limit)
+	setInt("offset", o.Offset)
+	setString("sort_by", o.SortBy)
+	setString("order", o.Order)
+	return query
+}
+
+// UploadList страница списка выгрузок
+type UploadList struct {
+	Uploads []UploadListItem `json:"uploads"`
+	Total   int              `json:"total"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
+
+// ListUploads возвращает список выгрузок
+// GET /api/uploads
+func (c *Client) ListUploads(ctx context.Context, opts UploadListOptions) (*UploadList, error) {
+	var list UploadList
+	if err := c.getJSON(ctx, "/api/uploads", opts.query(), &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetUpload возвращает детали выгрузки со справочниками и константами
+// GET /api/uploads/{uuid}
+func (c *Client) GetUpload(ctx context.Context, uploadUUID string) (*UploadDetails, error) {
+	var details UploadDetails
+	if err := c.getJSON(ctx, "/api/uploads/"+url.PathEscape(uploadUUID), nil, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// DataOptions параметры получения данных выгрузки
+type DataOptions struct {
+	Type         string   // all (по умолчанию), constants, catalogs
+	CatalogNames []string // Только элементы указанных справочников
+	Page         int      // Номер страницы с 1
+	Limit        int      // Размер страницы (сервер ограничивает 1000)
+}
+
+// GetUploadData возвращает страницу констант и элементов справочников выгрузки
+// GET /api/uploads/{uuid}/data
+func (c *Client) GetUploadData(ctx context.Context, uploadUUID string, opts DataOptions) (*DataResponse, error) {
+	query := url.Values{}
+	if opts.Type != "" {
+		query.Set("type", opts.Type)
+	}
+	if len(opts.CatalogNames) > 0 {
+		query.Set("catalog_names", strings.Join(opts.CatalogNames, ","))
+	}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var data DataResponse
+	if err := c.getXML(ctx, "/api/uploads/"+url.PathEscape(uploadUUID)+"/data", query, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// VerifyUpload сверяет полученные ID констант и элементов с данными выгрузки на сервере
+// POST /api/uploads/{uuid}/verify
+func (c *Client) VerifyUpload(ctx context.Context, uploadUUID string, receivedIDs []int) (*VerifyResponse, error) {
+	if receivedIDs == nil {
+		receivedIDs = []int{}
+	}
+	var resp VerifyResponse
+	if err := c.postJSON(ctx, "/api/uploads/"+url.PathEscape(uploadUUID)+"/verify", &VerifyRequest{ReceivedIDs: receivedIDs}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
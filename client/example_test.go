@@ -0,0 +1,118 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"httpserver/client"
+)
+
+func ExampleNew() {
+	api, err := client.New(client.Config{
+		BaseURL: "http://localhost:9999",
+		Token:   "api-key",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_ = api
+}
+
+// Полный цикл выгрузки справочника: рукопожатие, описание справочника, пакет элементов и завершение
+func ExampleClient_StartUpload() {
+	api, err := client.New(client.Config{BaseURL: "http://localhost:9999", Token: "api-key"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+
+	upload, err := api.StartUpload(ctx, client.HandshakeRequest{
+		DatabaseID: "7",
+		Version1C:  "8.3.24",
+		ConfigName: "УправлениеТорговлей",
+		UploadType: "Номенклатура",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := upload.SendCatalogMeta(ctx, &client.CatalogMetaRequest{Name: "Номенклатура", Synonym: "Номенклатура"}); err != nil {
+		log.Fatal(err)
+	}
+	resp, err := upload.SendCatalogItems(ctx, "Номенклатура", []client.CatalogItem{{
+		Reference:  "8f3c1a52-0000-0000-0000-000000000001",
+		Code:       "00001",
+		Name:       "Молоток слесарный",
+		Attributes: client.XMLContent{Content: "<Артикул>A-1</Артикул>"},
+	}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("processed %d, failed %d\n", resp.ProcessedCount, resp.FailedCount)
+
+	if _, err := upload.Complete(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Постраничное чтение элементов справочника и сверка полученных ID
+func ExampleClient_GetUploadData() {
+	api, err := client.New(client.Config{BaseURL: "http://localhost:9999", Token: "api-key"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+	uploadUUID := "7342e4de-4155-49d9-8039-68fcbf371208"
+
+	var received []int
+	for page := 1; ; page++ {
+		data, err := api.GetUploadData(ctx, uploadUUID, client.DataOptions{Type: "catalogs", CatalogNames: []string{"Номенклатура"}, Page: page, Limit: 500})
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, item := range data.Items {
+			received = append(received, item.ID)
+		}
+		if page*data.Limit >= data.Total {
+			break
+		}
+	}
+
+	result, err := api.VerifyUpload(ctx, uploadUUID, received)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.IsComplete, result.MissingIDs)
+}
+
+// Обработка ошибок сервера: устаревшая версия протокола и отсутствующая выгрузка
+func ExampleAPIError() {
+	api, err := client.New(client.Config{BaseURL: "http://localhost:9999"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = api.Handshake(context.Background(), &client.HandshakeRequest{ConfigName: "УТ", ProtocolVersion: "1"})
+
+	var apiErr *client.APIError
+	switch {
+	case client.IsStatus(err, http.StatusUpgradeRequired) && errors.As(err, &apiErr):
+		fmt.Printf("update the processing to protocol %d or newer\n", apiErr.MinProtocolVersion)
+	case err != nil:
+		log.Fatal(err)
+	}
+}
+
+// Классификация по КПВЭД нормализованного наименования
+func ExampleClient_ClassifyKpved() {
+	api, err := client.New(client.Config{BaseURL: "http://localhost:9999", Token: "api-key"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	result, err := api.ClassifyKpved(context.Background(), &client.KpvedClassifyRequest{NormalizedName: "молоток слесарный"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(result.FinalCode, result.FinalName, result.FinalConfidence)
+}
@@ -0,0 +1,513 @@
+package client
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Версии протокола выгрузки из 1С. Обработка и сервер обновляются независимо, поэтому
+// сервер принимает все версии от минимальной поддерживаемой и разбирает пакеты по правилам версии клиента
+const (
+	// ProtocolVersionLegacy первые обработки: реквизиты элемента справочника в <attributes>,
+	// табличные части в <tabular_sections>
+	ProtocolVersionLegacy = 1
+	// ProtocolVersionUnversioned обработки, не передающие protocol_version: реквизиты в <attributes_xml>
+	ProtocolVersionUnversioned = 2
+	// ProtocolVersionCurrent текущая версия: upload_uuid клиента в рукопожатии, описания
+	// справочников в /metadata и /catalog/meta, продвигаемые реквизиты
+	ProtocolVersionCurrent = 3
+)
+
+// HandshakeRequest запрос рукопожатия
+type HandshakeRequest struct {
+	XMLName       xml.Name `xml:"handshake"`
+	DatabaseID    string   `xml:"database_id,omitempty"`
+	Version1C     string   `xml:"version_1c"`
+	ConfigName    string   `xml:"config_name"`
+	ConfigVersion string   `xml:"config_version,omitempty"`
+	ComputerName  string   `xml:"computer_name,omitempty"`
+	UserName      string   `xml:"user_name,omitempty"`
+	Timestamp     string   `xml:"timestamp"`
+	UploadType    string   `xml:"upload_type,omitempty"` // Тип выгружаемых данных (Номенклатура, Контрагенты, ПолнаяВыгрузка и т.д.)
+	// Поля для итераций
+	IterationNumber int    `xml:"iteration_number,omitempty"`
+	IterationLabel  string `xml:"iteration_label,omitempty"`
+	ProgrammerName  string `xml:"programmer_name,omitempty"`
+	UploadPurpose   string `xml:"upload_purpose,omitempty"`
+	ParentUploadID  string `xml:"parent_upload_id,omitempty"` // UUID родительской выгрузки
+	// UUID выгрузки, заданный клиентом. Нужен при приеме через очередь, где последующие
+	// пакеты публикуются, не дожидаясь ответа на рукопожатие
+	UploadUUID string `xml:"upload_uuid,omitempty"`
+	// Версия протокола выгрузки клиента; пусто - обработка без поддержки версий (ProtocolVersionUnversioned)
+	ProtocolVersion string `xml:"protocol_version,omitempty"`
+	// Тестовая выгрузка: данные принимаются во временную БД, удаляемую по истечении срока,
+	// и не попадают в рабочие базы
+	Sandbox bool `xml:"sandbox,omitempty"`
+}
+
+// HandshakeResponse ответ на рукопожатие
+type HandshakeResponse struct {
+	XMLName      xml.Name `xml:"handshake_response"`
+	Success      bool     `xml:"success"`
+	UploadUUID   string   `xml:"upload_uuid"`
+	ClientName   string   `xml:"client_name,omitempty"`
+	ProjectName  string   `xml:"project_name,omitempty"`
+	DatabaseName string   `xml:"database_name,omitempty"`
+	DatabasePath string   `xml:"database_path,omitempty"` // Путь к созданной БД
+	DatabaseID   int      `xml:"database_id,omitempty"`   // ID в service.db (если зарегистрирована)
+	Message      string   `xml:"message"`
+	Timestamp    string   `xml:"timestamp"`
+	// Согласованная версия протокола: версия клиента, но не новее версии сервера
+	ProtocolVersion    int  `xml:"protocol_version"`
+	MinProtocolVersion int  `xml:"min_protocol_version"`
+	ProtocolDeprecated bool `xml:"protocol_deprecated,omitempty"` // Версия клиента устарела, обработку нужно обновить
+	// Тестовая выгрузка и время удаления ее временной БД (RFC3339)
+	Sandbox          bool   `xml:"sandbox,omitempty"`
+	SandboxExpiresAt string `xml:"sandbox_expires_at,omitempty"`
+}
+
+// MetadataRequest запрос метаинформации
+type MetadataRequest struct {
+	XMLName       xml.Name `xml:"metadata"`
+	UploadUUID    string   `xml:"upload_uuid"`
+	DatabaseID    string   `xml:"database_id,omitempty"`
+	Version1C     string   `xml:"version_1c"`
+	ConfigName    string   `xml:"config_name"`
+	ConfigVersion string   `xml:"config_version,omitempty"`
+	ComputerName  string   `xml:"computer_name,omitempty"`
+	UserName      string   `xml:"user_name,omitempty"`
+	Timestamp     string   `xml:"timestamp"`
+	// Описания справочников конфигурации (реквизиты, типы, табличные части), необязательно
+	Catalogs []MetadataCatalog `xml:"catalogs>catalog,omitempty"`
+	// Объявленное число констант выгрузки; сверяется с принятыми при /complete (nil - не объявлено)
+	ExpectedConstants *int `xml:"expected_constants,omitempty"`
+}
+
+// MetadataAttribute описание реквизита справочника из метаданных 1С
+type MetadataAttribute struct {
+	Name      string `xml:"name"`
+	Synonym   string `xml:"synonym,omitempty"`
+	Type      string `xml:"type"` // Тип 1С: Строка, Число, Булево, Дата, СправочникСсылка.Имя
+	Length    int    `xml:"length,omitempty"`
+	Precision int    `xml:"precision,omitempty"`
+	Scale     int    `xml:"scale,omitempty"`
+	Required  bool   `xml:"required,omitempty"`
+}
+
+// MetadataTablePart описание табличной части справочника
+type MetadataTablePart struct {
+	Name       string              `xml:"name"`
+	Synonym    string              `xml:"synonym,omitempty"`
+	Attributes []MetadataAttribute `xml:"attributes>attribute"`
+}
+
+// MetadataCatalog описание справочника из метаданных 1С
+type MetadataCatalog struct {
+	Name       string              `xml:"name"`
+	Synonym    string              `xml:"synonym,omitempty"`
+	Attributes []MetadataAttribute `xml:"attributes>attribute"`
+	TableParts []MetadataTablePart `xml:"table_parts>table_part"`
+	// Объявленное число элементов справочника; сверяется с сохраненными при /complete (nil - не объявлено)
+	ExpectedItems *int `xml:"expected_items,omitempty"`
+}
+
+// MetadataResponse ответ на метаинформацию
+type MetadataResponse struct {
+	XMLName   xml.Name `xml:"metadata_response"`
+	Success   bool     `xml:"success"`
+	Message   string   `xml:"message"`
+	Timestamp string   `xml:"timestamp"`
+}
+
+// ConstantValue обертка для значения константы с поддержкой вложенного XML
+type ConstantValue struct {
+	Content string
+}
+
+// UnmarshalXML кастомный парсер для получения всего содержимого тега value
+func (cv *ConstantValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var content strings.Builder
+	depth := 0
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			// Записываем открывающий тег
+			content.WriteString("<" + t.Name.Local)
+			for _, attr := range t.Attr {
+				escaped := strings.ReplaceAll(attr.Value, "&", "&amp;")
+				escaped = strings.ReplaceAll(escaped, "<", "&lt;")
+				escaped = strings.ReplaceAll(escaped, ">", "&gt;")
+				escaped = strings.ReplaceAll(escaped, "\"", "&quot;")
+				content.WriteString(" " + attr.Name.Local + "=\"" + escaped + "\"")
+			}
+			content.WriteString(">")
+		case xml.EndElement:
+			if t.Name == start.Name && depth == 0 {
+				// Это закрывающий тег value - завершаем
+				cv.Content = content.String()
+				return nil
+			}
+			depth--
+			content.WriteString("</" + t.Name.Local + ">")
+		case xml.CharData:
+			// Записываем текстовое содержимое (уже экранировано парсером)
+			content.Write(t)
+		}
+	}
+
+	cv.Content = content.String()
+	return nil
+}
+
+// MarshalXML записывает Content внутрь тега value как есть: текстовое значение должно быть экранировано,
+// составное значение передается вложенным XML
+func (cv ConstantValue) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(rawXML{Inner: cv.Content}, start)
+}
+
+// XMLContent обертка для XML содержимого (для attributes_xml и table_parts)
+type XMLContent struct {
+	Content string
+}
+
+// UnmarshalXML кастомный парсер для получения всего содержимого XML тега
+func (xc *XMLContent) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var content strings.Builder
+	depth := 0
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			// Записываем открывающий тег
+			content.WriteString("<" + t.Name.Local)
+			for _, attr := range t.Attr {
+				// Атрибуты уже экранированы в XML, просто записываем их
+				content.WriteString(" " + attr.Name.Local + "=\"" + attr.Value + "\"")
+			}
+			content.WriteString(">")
+		case xml.EndElement:
+			if t.Name == start.Name && depth == 0 {
+				// Это закрывающий тег - завершаем
+				xc.Content = content.String()
+				return nil
+			}
+			depth--
+			content.WriteString("</" + t.Name.Local + ">")
+		case xml.CharData:
+			// Записываем текстовое содержимое
+			content.Write(t)
+		}
+	}
+
+	xc.Content = content.String()
+	return nil
+}
+
+// String возвращает содержимое как строку
+func (xc *XMLContent) String() string {
+	return xc.Content
+}
+
+// MarshalXML записывает содержимое внутрь тега без экранирования (Content должен быть корректным XML)
+func (xc XMLContent) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(rawXML{Inner: xc.Content}, start)
+}
+
+// rawXML содержимое тега, записываемое как есть
+type rawXML struct {
+	Inner string `xml:",innerxml"`
+}
+
+// ConstantRequest запрос константы
+type ConstantRequest struct {
+	XMLName    xml.Name      `xml:"constant"`
+	UploadUUID string        `xml:"upload_uuid"`
+	Name       string        `xml:"name"`
+	Synonym    string        `xml:"synonym"`
+	Type       string        `xml:"type"`
+	Value      ConstantValue `xml:"value"` // Используем структуру с кастомным UnmarshalXML для получения всего содержимого тега value
+	Timestamp  string        `xml:"timestamp"`
+}
+
+// ConstantResponse ответ на константу
+type ConstantResponse struct {
+	XMLName   xml.Name `xml:"constant_response"`
+	Success   bool     `xml:"success"`
+	Message   string   `xml:"message"`
+	Timestamp string   `xml:"timestamp"`
+}
+
+// CatalogMetaRequest запрос метаданных справочника
+type CatalogMetaRequest struct {
+	XMLName    xml.Name `xml:"catalog_meta"`
+	UploadUUID string   `xml:"upload_uuid"`
+	Name       string   `xml:"name"`
+	Synonym    string   `xml:"synonym"`
+	Timestamp  string   `xml:"timestamp"`
+	// Реквизиты, которые нужно продвинуть в индексируемые колонки (необязательно)
+	IndexedAttributes []string `xml:"indexed_attributes>attribute,omitempty"`
+	// Описание реквизитов и табличных частей (необязательно, как в /metadata)
+	Attributes []MetadataAttribute `xml:"attributes>attribute,omitempty"`
+	TableParts []MetadataTablePart `xml:"table_parts>table_part,omitempty"`
+}
+
+// CatalogMetaResponse ответ на метаданные справочника
+type CatalogMetaResponse struct {
+	XMLName   xml.Name `xml:"catalog_meta_response"`
+	Success   bool     `xml:"success"`
+	CatalogID int      `xml:"catalog_id"`
+	Message   string   `xml:"message"`
+	Timestamp string   `xml:"timestamp"`
+}
+
+// CatalogItemRequest запрос элемента справочника
+type CatalogItemRequest struct {
+	XMLName     xml.Name   `xml:"catalog_item"`
+	UploadUUID  string     `xml:"upload_uuid"`
+	CatalogName string     `xml:"catalog_name"`
+	Reference   string     `xml:"reference"`
+	Code        string     `xml:"code"`
+	Name        string     `xml:"name"`
+	Attributes  XMLContent `xml:"attributes_xml"` // XML строка - используем кастомный парсер
+	TableParts  XMLContent `xml:"table_parts"`    // XML строка - используем кастомный парсер
+	Timestamp   string     `xml:"timestamp"`
+	// Иерархия групп 1С: ссылка на родителя (Родитель) и признак группы (ЭтоГруппа).
+	// Если не переданы, берутся из одноименных реквизитов
+	ParentReference string `xml:"parent_reference,omitempty"`
+	IsGroup         bool   `xml:"is_group,omitempty"`
+}
+
+// CatalogItemResponse ответ на элемент справочника
+type CatalogItemResponse struct {
+	XMLName   xml.Name `xml:"catalog_item_response"`
+	Success   bool     `xml:"success"`
+	Message   string   `xml:"message"`
+	Timestamp string   `xml:"timestamp"`
+}
+
+// CatalogItem элемент справочника для пакетной выгрузки
+type CatalogItem struct {
+	XMLName    xml.Name   `xml:"item"`
+	Reference  string     `xml:"reference"`
+	Code       string     `xml:"code"`
+	Name       string     `xml:"name"`
+	Attributes XMLContent `xml:"attributes_xml"` // XML строка - используем кастомный парсер
+	TableParts XMLContent `xml:"table_parts"`    // XML строка - используем кастомный парсер
+	Timestamp  string     `xml:"timestamp"`
+	// Иерархия групп 1С, как в CatalogItemRequest
+	ParentReference string `xml:"parent_reference,omitempty"`
+	IsGroup         bool   `xml:"is_group,omitempty"`
+}
+
+// CatalogItemsRequest запрос пакетной загрузки элементов справочника
+type CatalogItemsRequest struct {
+	XMLName     xml.Name      `xml:"catalog_items"`
+	UploadUUID  string        `xml:"upload_uuid"`
+	CatalogName string        `xml:"catalog_name"`
+	Items       []CatalogItem `xml:"items>item"`
+	// Номер пакета справочника начиная с 1; по нему при /complete проверяется непрерывность пакетов (0 - без номера)
+	BatchNumber int `xml:"batch_number,omitempty"`
+}
+
+// CatalogItemsResponse ответ на пакетную загрузку элементов справочника
+type CatalogItemsResponse struct {
+	XMLName        xml.Name `xml:"catalog_items_response"`
+	Success        bool     `xml:"success"`
+	ProcessedCount int      `xml:"processed_count"`
+	FailedCount    int      `xml:"failed_count"`
+	Message        string   `xml:"message"`
+	Timestamp      string   `xml:"timestamp"`
+}
+
+// CatalogAttachmentRequest вложение элемента справочника (изображение, PDF спецификации) с содержимым в base64.
+// Вложение можно передать и multipart/form-data запросом с теми же полями и файлом в поле file
+type CatalogAttachmentRequest struct {
+	XMLName     xml.Name `xml:"catalog_attachment"`
+	UploadUUID  string   `xml:"upload_uuid"`
+	CatalogName string   `xml:"catalog_name"`
+	Reference   string   `xml:"reference"` // Ссылка элемента справочника
+	FileName    string   `xml:"file_name"`
+	ContentType string   `xml:"content_type,omitempty"`
+	Description string   `xml:"description,omitempty"`
+	Content     string   `xml:"content"` // Содержимое файла в base64
+	Timestamp   string   `xml:"timestamp"`
+}
+
+// CatalogAttachmentResponse ответ на вложение элемента справочника
+type CatalogAttachmentResponse struct {
+	XMLName      xml.Name `xml:"catalog_attachment_response"`
+	Success      bool     `xml:"success"`
+	AttachmentID int      `xml:"attachment_id"`
+	Size         int64    `xml:"size"`
+	SHA256       string   `xml:"sha256"`
+	Message      string   `xml:"message"`
+	Timestamp    string   `xml:"timestamp"`
+}
+
+// DocumentItemRequest запрос документа (шапка, реквизиты и табличные части). Вид документа
+// регистрируется в выгрузке при получении первого документа
+type DocumentItemRequest struct {
+	XMLName      xml.Name   `xml:"document_item"`
+	UploadUUID   string     `xml:"upload_uuid"`
+	DocumentType string     `xml:"document_type"`     // Вид документа (ПриходнаяНакладная, ПоступлениеТоваровУслуг и т.д.)
+	Synonym      string     `xml:"synonym,omitempty"` // Представление вида документа
+	Reference    string     `xml:"reference"`
+	Number       string     `xml:"number"`
+	Date         string     `xml:"date"`
+	Posted       bool       `xml:"posted,omitempty"`
+	Attributes   XMLContent `xml:"attributes_xml"`
+	TableParts   XMLContent `xml:"table_parts"`
+	Timestamp    string     `xml:"timestamp"`
+}
+
+// DocumentItemResponse ответ на документ
+type DocumentItemResponse struct {
+	XMLName   xml.Name `xml:"document_item_response"`
+	Success   bool     `xml:"success"`
+	Message   string   `xml:"message"`
+	Timestamp string   `xml:"timestamp"`
+}
+
+// DocumentItem документ для пакетной выгрузки
+type DocumentItem struct {
+	XMLName    xml.Name   `xml:"item"`
+	Reference  string     `xml:"reference"`
+	Number     string     `xml:"number"`
+	Date       string     `xml:"date"`
+	Posted     bool       `xml:"posted,omitempty"`
+	Attributes XMLContent `xml:"attributes_xml"`
+	TableParts XMLContent `xml:"table_parts"`
+	Timestamp  string     `xml:"timestamp"`
+}
+
+// DocumentItemsRequest запрос пакетной загрузки документов одного вида
+type DocumentItemsRequest struct {
+	XMLName      xml.Name       `xml:"document_items"`
+	UploadUUID   string         `xml:"upload_uuid"`
+	DocumentType string         `xml:"document_type"`
+	Synonym      string         `xml:"synonym,omitempty"`
+	Items        []DocumentItem `xml:"items>item"`
+}
+
+// DocumentItemsResponse ответ на пакетную загрузку документов
+type DocumentItemsResponse struct {
+	XMLName        xml.Name `xml:"document_items_response"`
+	Success        bool     `xml:"success"`
+	ProcessedCount int      `xml:"processed_count"`
+	FailedCount    int      `xml:"failed_count"`
+	Message        string   `xml:"message"`
+	Timestamp      string   `xml:"timestamp"`
+}
+
+// NomenclatureItem элемент номенклатуры с характеристикой для пакетной загрузки
+type NomenclatureItem struct {
+	XMLName                 xml.Name `xml:"item"`
+	NomenclatureReference   string   `xml:"nomenclature_reference"`
+	NomenclatureCode        string   `xml:"nomenclature_code"`
+	NomenclatureName        string   `xml:"nomenclature_name"`
+	CharacteristicReference string   `xml:"characteristic_reference,omitempty"`
+	CharacteristicName      string   `xml:"characteristic_name,omitempty"`
+	Attributes              string   `xml:"attributes"`
+	TableParts              string   `xml:"table_parts"`
+	Timestamp               string   `xml:"timestamp,omitempty"`
+}
+
+// NomenclatureBatchRequest запрос пакетной загрузки номенклатуры с характеристиками
+type NomenclatureBatchRequest struct {
+	XMLName    xml.Name           `xml:"nomenclature_batch"`
+	UploadUUID string             `xml:"upload_uuid"`
+	DatabaseID string             `xml:"database_id,omitempty"`
+	Items      []NomenclatureItem `xml:"items>item"`
+	Timestamp  string             `xml:"timestamp,omitempty"`
+}
+
+// NomenclatureBatchResponse ответ на пакетную загрузку номенклатуры
+type NomenclatureBatchResponse struct {
+	XMLName        xml.Name `xml:"nomenclature_batch_response"`
+	Success        bool     `xml:"success"`
+	ProcessedCount int      `xml:"processed_count"`
+	FailedCount    int      `xml:"failed_count"`
+	Message        string   `xml:"message"`
+	Timestamp      string   `xml:"timestamp"`
+}
+
+// CompleteRequest запрос завершения выгрузки
+type CompleteRequest struct {
+	XMLName    xml.Name `xml:"complete"`
+	UploadUUID string   `xml:"upload_uuid"`
+	Timestamp  string   `xml:"timestamp"`
+}
+
+// CompleteResponse ответ на завершение выгрузки
+type CompleteResponse struct {
+	XMLName   xml.Name `xml:"complete_response"`
+	Success   bool     `xml:"success"`
+	Message   string   `xml:"message"`
+	Timestamp string   `xml:"timestamp"`
+	// Статус выгрузки: completed или completed_with_warnings при расхождениях сверки полноты
+	Status        string                    `xml:"status,omitempty"`
+	Discrepancies []CompletenessDiscrepancy `xml:"discrepancies>discrepancy,omitempty"`
+	// Количества, которые были бы записаны рабочей выгрузкой (только для тестовой выгрузки)
+	SandboxCounts *SandboxCounts `xml:"sandbox_counts,omitempty"`
+}
+
+// SandboxCounts принятые тестовой выгрузкой константы, справочники, элементы и документы
+type SandboxCounts struct {
+	Constants int `xml:"constants"`
+	Catalogs  int `xml:"catalogs"`
+	Items     int `xml:"items"`
+	Documents int `xml:"documents"`
+}
+
+// Виды расхождений сверки полноты выгрузки
+const (
+	DiscrepancyConstantsCount = "constants_count" // Число констант не совпадает с объявленным
+	DiscrepancyItemsCount     = "items_count"     // Число элементов справочника не совпадает с объявленным
+	DiscrepancyMissingBatches = "missing_batches" // Пропущены номера пакетов справочника
+)
+
+// CompletenessDiscrepancy расхождение, найденное сервером при завершении выгрузки
+type CompletenessDiscrepancy struct {
+	Kind           string `xml:"kind"`
+	Catalog        string `xml:"catalog,omitempty"`
+	Expected       int    `xml:"expected"`
+	Actual         int    `xml:"actual"`
+	MissingBatches []int  `xml:"missing_batches>batch,omitempty"`
+	Message        string `xml:"message"`
+}
+
+// ErrorResponse общий ответ об ошибке
+type ErrorResponse struct {
+	XMLName    xml.Name `xml:"error_response"`
+	Success    bool     `xml:"success"`
+	Error      string   `xml:"error"`
+	Code       string   `xml:"code"` // Машиночитаемый код ошибки
+	Message    string   `xml:"message"`
+	Timestamp  string   `xml:"timestamp"`
+	RetryAfter int      `xml:"retry_after,omitempty"` // Через сколько секунд повторить пакет при превышении ограничения скорости
+	// Версии протокола в ответе 426 Upgrade Required: минимальная поддерживаемая и текущая версия сервера
+	MinProtocolVersion int `xml:"min_protocol_version,omitempty"`
+	ProtocolVersion    int `xml:"protocol_version,omitempty"`
+	// Расхождения сверки полноты, из-за которых отклонено завершение выгрузки (409)
+	Discrepancies []CompletenessDiscrepancy `xml:"discrepancies>discrepancy,omitempty"`
+}
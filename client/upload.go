@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// timestamp текущее время в формате протокола выгрузки
+func timestamp() string {
+	return time.Now().Format(time.RFC3339)
+}
+
+// Handshake выполняет рукопожатие и регистрирует новую выгрузку. Пустые ProtocolVersion и Timestamp
+// заполняются текущей версией протокола и текущим временем
+func (c *Client) Handshake(ctx context.Context, req *HandshakeRequest) (*HandshakeResponse, error) {
+	if req.ProtocolVersion == "" {
+		req.ProtocolVersion = strconv.Itoa(ProtocolVersionCurrent)
+	}
+	if req.Timestamp == "" {
+		req.Timestamp = timestamp()
+	}
+	var resp HandshakeResponse
+	if err := c.postXML(ctx, "/handshake", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Upload сеанс выгрузки, начатый рукопожатием. Методы заполняют UUID выгрузки, метки времени
+// и номера пакетов справочников
+type Upload struct {
+	client    *Client
+	handshake HandshakeRequest
+	// Response ответ сервера на рукопожатие
+	Response *HandshakeResponse
+
+	mu      sync.Mutex
+	batches map[string]int // Номер последнего пакета по справочникам
+}
+
+// StartUpload выполняет рукопожатие и возвращает сеанс выгрузки
+func (c *Client) StartUpload(ctx context.Context, req HandshakeRequest) (*Upload, error) {
+	resp, err := c.Handshake(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	return &Upload{client: c, handshake: req, Response: resp}, nil
+}
+
+// UUID идентификатор выгрузки, выданный сервером
+func (u *Upload) UUID() string {
+	return u.Response.UploadUUID
+}
+
+// SendMetadata отправляет описание конфигурации. Параметры конфигурации по умолчанию берутся из рукопожатия
+func (u *Upload) SendMetadata(ctx context.Context, req *MetadataRequest) (*MetadataResponse, error) {
+	req.UploadUUID = u.UUID()
+	if req.DatabaseID == "" {
+		req.DatabaseID = u.handshake.DatabaseID
+	}
+	if req.Version1C == "" {
+		req.Version1C = u.handshake.Version1C
+	}
+	if req.ConfigName == "" {
+		req.ConfigName = u.handshake.ConfigName
+	}
+	if req.ConfigVersion == "" {
+		req.ConfigVersion = u.handshake.ConfigVersion
+	}
+	if req.Timestamp == "" {
+		req.Timestamp = timestamp()
+	}
+	var resp MetadataResponse
+	if err := u.client.postXML(ctx, "/metadata", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendConstant отправляет значение константы
+func (u *Upload) SendConstant(ctx context.Context, req *ConstantRequest) (*ConstantResponse, error) {
+	req.UploadUUID = u.UUID()
+	if req.Timestamp == "" {
+		req.Timestamp = timestamp()
+	}
+	var resp ConstantResponse
+	if err := u.client.postXML(ctx, "/constant", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendCatalogMeta регистрирует справочник выгрузки
+func (u *Upload) SendCatalogMeta(ctx context.Context, req *CatalogMetaRequest) (*CatalogMetaResponse, error) {
+	req.UploadUUID = u.UUID()
+	if req.Timestamp == "" {
+		req.Timestamp = timestamp()
+	}
+	var resp CatalogMetaResponse
+	if err := u.client.postXML(ctx, "/catalog/meta", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendCatalogItem отправляет один элемент справочника
+func (u *Upload) SendCatalogItem(ctx context.Context, req *CatalogItemRequest) (*CatalogItemResponse, error) {
+	req.UploadUUID = u.UUID()
+	if req.Timestamp == "" {
+		req.Timestamp = timestamp()
+	}
+	var resp CatalogItemResponse
+	if err := u.client.postXML(ctx, "/catalog/item", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendCatalogItems отправляет очередной пакет элементов справочника. Пакеты нумеруются по порядку вызовов
+// для справочника, чтобы сервер при завершении выгрузки обнаружил потерянные пакеты; повторить пакет
+// с тем же номером можно через SendCatalogItemsBatch
+func (u *Upload) SendCatalogItems(ctx context.Context, catalogName string, items []CatalogItem) (*CatalogItemsResponse, error) {
+	u.mu.Lock()
+	if u.batches == nil {
+		u.batches = make(map[string]int)
+	}
+	u.batches[catalogName]++
+	batchNumber := u.batches[catalogName]
+	u.mu.Unlock()
+	return u.SendCatalogItemsBatch(ctx, catalogName, batchNumber, items)
+}
+
+// SendCatalogItemsBatch отправляет пакет элементов справочника с заданным номером (0 - без номера)
+func (u *Upload) SendCatalogItemsBatch(ctx context.Context, catalogName string, batchNumber int, items []CatalogItem) (*CatalogItemsResponse, error) {
+	now := timestamp()
+	for i := range items {
+		if items[i].Timestamp == "" {
+			items[i].Timestamp = now
+		}
+	}
+	req := &CatalogItemsRequest{UploadUUID: u.UUID(), CatalogName: catalogName, Items: items, BatchNumber: batchNumber}
+	var resp CatalogItemsResponse
+	if err := u.client.postXML(ctx, "/catalog/items", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendDocumentItem отправляет один документ
+func (u *Upload) SendDocumentItem(ctx context.Context, req *DocumentItemRequest) (*DocumentItemResponse, error) {
+	req.UploadUUID = u.UUID()
+	if req.Timestamp == "" {
+		req.Timestamp = timestamp()
+	}
+	var resp DocumentItemResponse
+	if err := u.client.postXML(ctx, "/document/item", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendDocumentItems отправляет пакет документов одного вида
+func (u *Upload) SendDocumentItems(ctx context.Context, documentType string, items []DocumentItem) (*DocumentItemsResponse, error) {
+	now := timestamp()
+	for i := range items {
+		if items[i].Timestamp == "" {
+			items[i].Timestamp = now
+		}
+	}
+	req := &DocumentItemsRequest{UploadUUID: u.UUID(), DocumentType: documentType, Items: items}
+	var resp DocumentItemsResponse
+	if err := u.client.postXML(ctx, "/document/items", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendAttachment отправляет вложение элемента справочника; содержимое кодируется в base64
+func (u *Upload) SendAttachment(ctx context.Context, catalogName, reference, fileName, contentType string, content []byte) (*CatalogAttachmentResponse, error) {
+	req := &CatalogAttachmentRequest{
+		UploadUUID:  u.UUID(),
+		CatalogName: catalogName,
+		Reference:   reference,
+		FileName:    fileName,
+		ContentType: contentType,
+		Content:     base64.StdEncoding.EncodeToString(content),
+		Timestamp:   timestamp(),
+	}
+	var resp CatalogAttachmentResponse
+	if err := u.client.postXML(ctx, "/catalog/attachment", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendNomenclatureBatch отправляет пакет номенклатуры с характеристиками
+func (u *Upload) SendNomenclatureBatch(ctx context.Context, items []NomenclatureItem) (*NomenclatureBatchResponse, error) {
+	req := &NomenclatureBatchRequest{
+		UploadUUID: u.UUID(),
+		DatabaseID: u.handshake.DatabaseID,
+		Items:      items,
+		Timestamp:  timestamp(),
+	}
+	var resp NomenclatureBatchResponse
+	if err := u.client.postXML(ctx, "/api/v1/upload/nomenclature/batch", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Complete завершает выгрузку. Если сервер нашел расхождения сверки полноты, в режиме warn выгрузка
+// завершается со статусом completed_with_warnings и расхождениями в ответе, в режиме strict возвращается
+// *APIError со статусом 409 и расхождениями в Discrepancies
+func (u *Upload) Complete(ctx context.Context) (*CompleteResponse, error) {
+	req := &CompleteRequest{UploadUUID: u.UUID(), Timestamp: timestamp()}
+	var resp CompleteResponse
+	if err := u.client.postXML(ctx, "/complete", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+const usage = `Использование:
+  backfill stages
+  backfill list <путь_к_normalized_data.db>
+  backfill run -stage имя [-category К] [-id-from N] [-id-to N] [-only-empty] [-batch N] [-throttle 100ms] <путь_к_normalized_data.db>
+  backfill resume <путь_к_normalized_data.db> <id_задания>
+
+Прерывание (Ctrl+C) приостанавливает задание после текущего пакета, resume продолжает его с сохраненной позиции.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	stageName := flags.String("stage", "", "Этап заполнения (см. backfill stages)")
+	category := flags.String("category", "", "Только записи категории")
+	idFrom := flags.Int("id-from", 0, "Начальный id записи")
+	idTo := flags.Int("id-to", 0, "Конечный id записи")
+	onlyEmpty := flags.Bool("only-empty", false, "Только записи с незаполненной колонкой")
+	batchSize := flags.Int("batch", normalization.DefaultBackfillBatchSize, "Записей в пакете")
+	throttle := flags.Duration("throttle", 0, "Пауза между пакетами")
+	flags.Parse(os.Args[2:])
+
+	if command == "stages" {
+		for _, stage := range normalization.BackfillStages() {
+			fmt.Printf("%-24s -> %-24s %s\n", stage.Name(), stage.Column(), stage.Description())
+		}
+		return
+	}
+	if flags.NArg() < 1 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	db, err := database.NewDB(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer db.Close()
+
+	var job *database.BackfillJob
+	switch command {
+	case "list":
+		jobs, err := db.ListBackfillJobs(100)
+		if err != nil {
+			log.Fatalf("Ошибка получения заданий: %v", err)
+		}
+		for _, job := range jobs {
+			fmt.Printf("%5d  %-24s %-10s %d/%d, изменено %d, ошибок %d\n",
+				job.ID, job.Stage, job.Status, job.Processed, job.Total, job.Updated, job.Failed)
+		}
+		return
+	case "run":
+		scope := database.BackfillScope{Category: *category, IDFrom: *idFrom, IDTo: *idTo, OnlyEmpty: *onlyEmpty}
+		if job, err = normalization.NewBackfillJob(db, *stageName, scope, *batchSize, *throttle); err != nil {
+			log.Fatalf("Ошибка создания задания: %v", err)
+		}
+		log.Printf("Создано задание %d: этап %s, колонка %s, записей %d", job.ID, job.Stage, job.Column, job.Total)
+	case "resume":
+		id, err := strconv.Atoi(flags.Arg(1))
+		if err != nil {
+			log.Fatalf("Некорректный id задания: %q", flags.Arg(1))
+		}
+		if job, err = db.GetBackfillJob(id); err != nil {
+			log.Fatalf("Ошибка получения задания: %v", err)
+		}
+		if job.Status == database.BackfillCompleted {
+			log.Printf("Задание %d уже завершено", job.ID)
+			return
+		}
+		log.Printf("Продолжение задания %d с записи %d: обработано %d из %d", job.ID, job.LastID, job.Processed, job.Total)
+	default:
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lastReport := time.Now()
+	err = normalization.RunBackfill(ctx, db, job, func(job *database.BackfillJob) {
+		if time.Since(lastReport) >= 5*time.Second || job.Status == database.BackfillCompleted {
+			lastReport = time.Now()
+			log.Printf("Обработано %d из %d, изменено %d, ошибок %d", job.Processed, job.Total, job.Updated, job.Failed)
+		}
+	})
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Printf("Задание %d приостановлено на записи %d, продолжение: backfill resume %s %d", job.ID, job.LastID, flags.Arg(0), job.ID)
+	case err != nil:
+		log.Fatalf("Задание %d остановлено ошибкой: %v", job.ID, err)
+	default:
+		log.Printf("Задание %d завершено: обработано %d, изменено %d, ошибок %d", job.ID, job.Processed, job.Updated, job.Failed)
+		if job.Failed > 0 {
+			log.Printf("Последняя ошибка: %s", job.LastError)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"httpserver/classification"
+	"httpserver/database"
+	"httpserver/normalization"
+	"httpserver/quality"
+)
+
+// benchResult результат замера одного сценария
+type benchResult struct {
+	Name     string        `json:"name"`
+	Ops      int           `json:"ops"`
+	Duration time.Duration `json:"duration_ns"`
+	NsPerOp  float64       `json:"ns_per_op"`
+}
+
+// benchReport набор результатов прогона, сохраняемый как базовая линия
+type benchReport struct {
+	Items     int           `json:"items"`
+	CreatedAt time.Time     `json:"created_at"`
+	Results   []benchResult `json:"results"`
+}
+
+// Синтетические части наименований номенклатуры
+var (
+	itemKinds  = []string{"Молоток слесарный", "Болт М8х40 оцинк.", "Кабель ВВГнг 3х2,5", "Краска ПФ-115 белая", "Перчатки х/б", "Труба ПНД 32мм", "Саморез 4,2х76", "Лампа LED E27 10Вт"}
+	itemBrands = []string{"Bosch", "Makita", "Зубр", "Kraftool", "Stayer", ""}
+	categories = [][]string{
+		{"Инструмент", "Ручной инструмент", "Молотки", "Слесарные молотки"},
+		{"Крепеж", "Болты", "Оцинкованные болты"},
+		{"Электротовары", "Кабель и провод", "Силовой кабель", "ВВГ", "ВВГнг"},
+		{"Лакокрасочные материалы", "Эмали", "Алкидные эмали"},
+	}
+)
+
+// syntheticItemName возвращает наименование i-го синтетического элемента; каждый 20-й - опечатка предыдущего
+func syntheticItemName(i int) string {
+	n := i
+	suffix := ""
+	if i%20 == 19 {
+		n = i - 1
+		suffix = "."
+	}
+	return fmt.Sprintf("%s %s ER-%08d %dкг%s", itemKinds[n%len(itemKinds)], itemBrands[n%len(itemBrands)], n, n%50+1, suffix)
+}
+
+// measure выполняет сценарий и фиксирует время на одну операцию
+func measure(name string, ops int, fn func() error) benchResult {
+	start := time.Now()
+	if err := fn(); err != nil {
+		log.Fatalf("Ошибка сценария %s: %v", name, err)
+	}
+	elapsed := time.Since(start)
+	result := benchResult{Name: name, Ops: ops, Duration: elapsed}
+	if ops > 0 {
+		result.NsPerOp = float64(elapsed.Nanoseconds()) / float64(ops)
+	}
+	fmt.Fprintf(os.Stderr, "%-28s %8d ops %12s %14.0f ns/op\n", name, ops, elapsed.Round(time.Millisecond), result.NsPerOp)
+	return result
+}
+
+func main() {
+	items := flag.Int("items", 100000, "Количество синтетических элементов")
+	batchSize := flag.Int("batch", 500, "Размер пакета при загрузке (как в /catalog/items)")
+	pageSize := flag.Int("page", 500, "Размер страницы при чтении элементов")
+	dbPath := flag.String("db", "", "Путь к файлу БД (по умолчанию временный файл)")
+	baselinePath := flag.String("baseline", "", "Файл базовой линии для сравнения результатов")
+	savePath := flag.String("save", "", "Сохранить результаты в файл (JSON)")
+	tolerance := flag.Float64("tolerance", 0.2, "Допустимое замедление относительно базовой линии (0.2 = 20%)")
+	verbose := flag.Bool("v", false, "Выводить лог сервера во время замеров")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	path := *dbPath
+	if path == "" {
+		dir, err := os.MkdirTemp("", "bench")
+		if err != nil {
+			log.Fatalf("Ошибка создания временного каталога: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		path = filepath.Join(dir, "bench.db")
+	}
+
+	report := run(path, *items, *batchSize, *pageSize)
+
+	if *savePath != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*savePath, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка сохранения результатов: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Результаты сохранены: %s\n", *savePath)
+	}
+
+	if *baselinePath != "" {
+		data, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка чтения базовой линии: %v\n", err)
+			os.Exit(1)
+		}
+		var baseline benchReport
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка разбора базовой линии: %v\n", err)
+			os.Exit(1)
+		}
+		if regressions := compare(baseline, report, *tolerance); len(regressions) > 0 {
+			fmt.Fprintln(os.Stderr, "\nОбнаружены регрессии производительности:")
+			for _, r := range regressions {
+				fmt.Fprintln(os.Stderr, "  "+r)
+			}
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "\nРегрессий относительно базовой линии нет")
+	}
+}
+
+// run загружает синтетический набор и замеряет горячие пути
+func run(path string, items, batchSize, pageSize int) benchReport {
+	db, err := database.NewDB(path)
+	if err != nil {
+		log.Fatalf("Ошибка создания БД: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("bench-upload", "8.3", "Бенчмарк")
+	if err != nil {
+		log.Fatalf("Ошибка создания выгрузки: %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "nomenclature")
+	if err != nil {
+		log.Fatalf("Ошибка создания справочника: %v", err)
+	}
+
+	report := benchReport{Items: items, CreatedAt: time.Now()}
+	attributes := "<Реквизит Имя=\"Артикул\">A-1</Реквизит><Реквизит Имя=\"Вес\">1,5</Реквизит>"
+
+	// Поэлементная загрузка пакетов /catalog/items
+	report.Results = append(report.Results, measure("ingest_catalog_items", items, func() error {
+		for i := 0; i < items; i++ {
+			if err := db.AddCatalogItem(catalog.ID, fmt.Sprintf("ref-%d", i), fmt.Sprintf("%08d", i), syntheticItemName(i), attributes, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	// Пакетная загрузка номенклатуры /nomenclature/batch
+	report.Results = append(report.Results, measure("ingest_nomenclature_batch", items, func() error {
+		batch := make([]database.NomenclatureItem, 0, batchSize)
+		for i := 0; i < items; i++ {
+			batch = append(batch, database.NomenclatureItem{
+				NomenclatureReference: fmt.Sprintf("ref-%d", i),
+				NomenclatureCode:      fmt.Sprintf("%08d", i),
+				NomenclatureName:      syntheticItemName(i),
+				AttributesXML:         attributes,
+			})
+			if len(batch) == batchSize || i == items-1 {
+				if err := db.AddNomenclatureItemsBatch(upload.ID, batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		return nil
+	}))
+
+	// Постраничный обход всех элементов выгрузки
+	pages := (items + pageSize - 1) / pageSize
+	report.Results = append(report.Results, measure("paginate_catalog_items", pages, func() error {
+		for offset := 0; offset < items; offset += pageSize {
+			if _, _, err := db.GetCatalogItemsByUpload(upload.ID, nil, offset, pageSize); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	// Нечеткий поиск дубликатов номенклатуры
+	report.Results = append(report.Results, measure("fuzzy_duplicates", items, func() error {
+		_, err := quality.NewFuzzyMatcher(db, 0.85).FindDuplicateNames(upload.ID, 0)
+		return err
+	}))
+
+	// Обнаружение паттернов в наименованиях
+	detector := normalization.NewPatternDetector()
+	report.Results = append(report.Results, measure("pattern_detection", items, func() error {
+		for i := 0; i < items; i++ {
+			detector.DetectPatterns(syntheticItemName(i))
+		}
+		return nil
+	}))
+
+	// Свертка путей классификатора
+	sm := classification.NewStrategyManager()
+	report.Results = append(report.Results, measure("classifier_folding", items, func() error {
+		for i := 0; i < items; i++ {
+			if _, err := sm.FoldCategory(categories[i%len(categories)], "top_priority"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	return report
+}
+
+// compare сравнивает результаты с базовой линией и возвращает описания регрессий
+func compare(baseline, current benchReport, tolerance float64) []string {
+	base := make(map[string]benchResult, len(baseline.Results))
+	for _, r := range baseline.Results {
+		base[r.Name] = r
+	}
+
+	var regressions []string
+	for _, r := range current.Results {
+		prev, ok := base[r.Name]
+		if !ok || prev.NsPerOp == 0 {
+			continue
+		}
+		change := r.NsPerOp/prev.NsPerOp - 1
+		if change > tolerance {
+			regressions = append(regressions, fmt.Sprintf("%s: %.0f ns/op -> %.0f ns/op (+%.0f%%)", r.Name, prev.NsPerOp, r.NsPerOp, change*100))
+		}
+	}
+	sort.Strings(regressions)
+	return regressions
+}
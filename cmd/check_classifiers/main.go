@@ -105,6 +105,9 @@ func main() {
 		// Подсчитываем размер дерева
 		treeSize := len(classifier.TreeStructure)
 		fmt.Printf("Размер дерева: %d байт (%.2f KB)\n", treeSize, float64(treeSize)/1024)
+		if nodeCount, err := db.CountClassifierNodes(classifier.ID); err == nil {
+			fmt.Printf("Узлов в таблице: %d\n", nodeCount)
+		}
 		fmt.Println()
 	}
 
@@ -62,10 +62,10 @@ func main() {
 	fmt.Printf("Максимальная глубина: %d\n", classifier.MaxDepth)
 	fmt.Println()
 
-	// Парсим дерево классификатора
-	var classifierTree classification.CategoryNode
-	if err := json.Unmarshal([]byte(classifier.TreeStructure), &classifierTree); err != nil {
-		log.Fatalf("Ошибка парсинга дерева классификатора: %v", err)
+	// Загружаем дерево классификатора
+	classifierTree, err := classification.LoadClassifierTree(db, classifier)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки дерева классификатора: %v", err)
 	}
 
 	// Создаем менеджер конфигурации для получения модели
@@ -86,7 +86,7 @@ func main() {
 	}
 
 	aiClassifier := classification.NewAIClassifier(apiKey, model)
-	aiClassifier.SetClassifierTree(&classifierTree)
+	aiClassifier.SetClassifierTree(classifierTree)
 
 	// Создаем менеджер стратегий
 	strategyManager := classification.NewStrategyManager()
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -79,10 +78,10 @@ func main() {
 	fmt.Printf("Максимальная глубина: %d\n", classifier.MaxDepth)
 	fmt.Println()
 
-	// Парсим дерево классификатора
-	var classifierTree classification.CategoryNode
-	if err := json.Unmarshal([]byte(classifier.TreeStructure), &classifierTree); err != nil {
-		log.Fatalf("Ошибка парсинга дерева классификатора: %v", err)
+	// Загружаем дерево классификатора
+	classifierTree, err := classification.LoadClassifierTree(db, classifier)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки дерева классификатора: %v", err)
 	}
 
 	// Создаем AI классификатор
@@ -105,7 +104,7 @@ func main() {
 	}
 
 	aiClassifier := classification.NewAIClassifier(apiKey, model)
-	aiClassifier.SetClassifierTree(&classifierTree)
+	aiClassifier.SetClassifierTree(classifierTree)
 
 	// Создаем менеджер стратегий
 	strategyManager := classification.NewStrategyManager()
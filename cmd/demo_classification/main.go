@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -72,8 +71,7 @@ func main() {
 	fmt.Printf("Примеры элементов для классификации (%d шт.):\n\n", len(items))
 
 	// Парсим дерево классификатора для демонстрации
-	var classifierTree classification.CategoryNode
-	if err := json.Unmarshal([]byte(classifier.TreeStructure), &classifierTree); err == nil {
+	if classifierTree, err := classification.LoadClassifierTree(db, classifier); err == nil {
 		fmt.Println("Структура классификатора КПВЭД:")
 		fmt.Printf("  Корневых категорий: %d\n", len(classifierTree.Children))
 		if len(classifierTree.Children) > 0 {
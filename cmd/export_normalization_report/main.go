@@ -1,23 +1,38 @@
 package main
 
 import (
+	"database/sql"
+	"flag"
 	"fmt"
-	"html/template"
 	"log"
 	"os"
-	"time"
+	"path/filepath"
+	"strings"
 
 	"httpserver/database"
+	"httpserver/i18n"
+	"httpserver/reports"
 )
 
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Println("Использование: export_normalization_report <путь_к_базе.db> <путь_к_файлу.html>")
+	byKpved := flag.Bool("by-kpved", false, "Отчет по разделам и классам КПВЭД (HTML или XLSX по расширению файла)")
+	classifierPath := flag.String("classifier", "", "Путь к сервисной базе с классификатором КПВЭД (наименования разделов и классов)")
+	examplesPerClass := flag.Int("examples", reports.DefaultKpvedExamplesPerClass, "Число примеров на класс КПВЭД")
+	templateArg := flag.String("template", "normalization", "Имя шаблона отчета или путь к файлу шаблона .html")
+	templatesDir := flag.String("templates-dir", "", "Каталог пользовательских шаблонов отчетов")
+	clientName := flag.String("client", "", "Наименование клиента в шапке отчета")
+	logoURL := flag.String("logo", "", "URL логотипа клиента")
+	primaryColor := flag.String("color", "", "Основной цвет оформления отчета (например, #3498db)")
+	lang := flag.String("lang", i18n.FromEnv(), "Язык отчета: ru, en, kk")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Println("Использование: export_normalization_report [-by-kpved] [-template имя|файл.html] [-templates-dir каталог] [-client имя] [-logo url] [-color #rrggbb] [-lang ru|en|kk] [-classifier service.db] [-examples N] <путь_к_базе.db> <путь_к_файлу.html|.xlsx>")
 		os.Exit(1)
 	}
 
-	dbPath := os.Args[1]
-	outputFile := os.Args[2]
+	dbPath := flag.Arg(0)
+	outputFile := flag.Arg(1)
 
 	db, err := database.NewDB(dbPath)
 	if err != nil {
@@ -25,206 +40,39 @@ func main() {
 	}
 	defer db.Close()
 
-	// Собираем данные
-	var totalItems, normalizedCount, kpvedCount, uniqueCategories int
-	var changedCount, mergedCount int
-
-	db.QueryRow("SELECT COUNT(*) FROM catalog_items").Scan(&totalItems)
-	db.QueryRow("SELECT COUNT(*) FROM normalized_data").Scan(&normalizedCount)
-	db.QueryRow("SELECT COUNT(*) FROM normalized_data WHERE kpved_code IS NOT NULL AND kpved_code != ''").Scan(&kpvedCount)
-	db.QueryRow("SELECT COUNT(DISTINCT category) FROM normalized_data WHERE category IS NOT NULL AND category != ''").Scan(&uniqueCategories)
-	db.QueryRow("SELECT COUNT(*) FROM normalized_data WHERE source_name != normalized_name AND normalized_name IS NOT NULL AND normalized_name != ''").Scan(&changedCount)
-	db.QueryRow("SELECT COUNT(*) FROM normalized_data WHERE merged_count > 1").Scan(&mergedCount)
-
-	// Топ категории
-	type CategoryStat struct {
-		Name      string
-		Count     int
-		Percent   float64
-	}
-	var topCategories []CategoryStat
-	categoryQuery := `
-		SELECT category, COUNT(*) as count
-		FROM normalized_data
-		WHERE category IS NOT NULL AND category != ''
-		GROUP BY category
-		ORDER BY count DESC
-		LIMIT 20
-	`
-	rows, _ := db.Query(categoryQuery)
-	if rows != nil {
-		defer rows.Close()
-		for rows.Next() {
-			var cat CategoryStat
-			if err := rows.Scan(&cat.Name, &cat.Count); err == nil {
-				cat.Percent = float64(cat.Count) / float64(normalizedCount) * 100
-				topCategories = append(topCategories, cat)
-			}
-		}
+	if *byKpved {
+		exportKpvedReport(db, dbPath, outputFile, *classifierPath, *examplesPerClass, *lang)
+		return
 	}
 
-	// Примеры
-	type Example struct {
-		Source      string
-		Normalized  string
-		Category    string
-		Code        string
-	}
-	var examples []Example
-	exampleQuery := `
-		SELECT source_name, normalized_name, category, code
-		FROM normalized_data
-		WHERE category IS NOT NULL AND category != ''
-		ORDER BY category, id
-		LIMIT 30
-	`
-	exampleRows, _ := db.Query(exampleQuery)
-	if exampleRows != nil {
-		defer exampleRows.Close()
-		for exampleRows.Next() {
-			var ex Example
-			if err := exampleRows.Scan(&ex.Source, &ex.Normalized, &ex.Category, &ex.Code); err == nil {
-				examples = append(examples, ex)
-			}
+	var classifier *sql.DB
+	if *classifierPath != "" {
+		serviceDB, err := database.NewServiceDB(*classifierPath)
+		if err != nil {
+			log.Fatalf("Ошибка подключения к базе классификатора: %v", err)
 		}
+		defer serviceDB.Close()
+		classifier = serviceDB.GetDB()
 	}
 
-	// Генерируем HTML
-	htmlTemplate := `<!DOCTYPE html>
-<html lang="ru">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>Отчет о нормализации и классификации</title>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 20px; background: #f5f5f5; }
-        .container { max-width: 1200px; margin: 0 auto; background: white; padding: 30px; border-radius: 10px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
-        h1 { color: #2c3e50; border-bottom: 3px solid #3498db; padding-bottom: 10px; }
-        h2 { color: #34495e; margin-top: 30px; }
-        .stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(200px, 1fr)); gap: 20px; margin: 20px 0; }
-        .stat-card { background: #ecf0f1; padding: 20px; border-radius: 8px; text-align: center; }
-        .stat-value { font-size: 2em; font-weight: bold; color: #3498db; }
-        .stat-label { color: #7f8c8d; margin-top: 5px; }
-        table { width: 100%; border-collapse: collapse; margin: 20px 0; }
-        th, td { padding: 12px; text-align: left; border-bottom: 1px solid #ddd; }
-        th { background: #3498db; color: white; }
-        tr:hover { background: #f5f5f5; }
-        .example { background: #fff; padding: 15px; margin: 10px 0; border-left: 4px solid #3498db; border-radius: 4px; }
-        .category { color: #27ae60; font-weight: bold; }
-        .progress { background: #ecf0f1; border-radius: 10px; height: 30px; margin: 10px 0; overflow: hidden; }
-        .progress-bar { background: #3498db; height: 100%; display: flex; align-items: center; justify-content: center; color: white; font-weight: bold; }
-        .timestamp { color: #95a5a6; font-size: 0.9em; margin-top: 20px; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>📊 Отчет о нормализации и классификации</h1>
-        <div class="timestamp">Сгенерировано: {{.Timestamp}}</div>
-
-        <h2>📈 Общая статистика</h2>
-        <div class="stats">
-            <div class="stat-card">
-                <div class="stat-value">{{.TotalItems}}</div>
-                <div class="stat-label">Всего элементов</div>
-            </div>
-            <div class="stat-card">
-                <div class="stat-value">{{.NormalizedCount}}</div>
-                <div class="stat-label">Нормализовано</div>
-            </div>
-            <div class="stat-card">
-                <div class="stat-value">{{.UniqueCategories}}</div>
-                <div class="stat-label">Уникальных категорий</div>
-            </div>
-            <div class="stat-card">
-                <div class="stat-value">{{.KpvedCount}}</div>
-                <div class="stat-label">С КПВЭД</div>
-            </div>
-        </div>
-
-        <h2>📋 Прогресс нормализации</h2>
-        <div class="progress">
-            <div class="progress-bar" style="width: {{.NormalizedPercent}}%">{{.NormalizedPercent}}%</div>
-        </div>
-
-        <h2>🏷️ Прогресс классификации КПВЭД</h2>
-        <div class="progress">
-            <div class="progress-bar" style="width: {{.KpvedPercent}}%">{{.KpvedPercent}}%</div>
-        </div>
-
-        <h2>📊 Топ-20 категорий</h2>
-        <table>
-            <thead>
-                <tr>
-                    <th>№</th>
-                    <th>Категория</th>
-                    <th>Количество</th>
-                    <th>Процент</th>
-                </tr>
-            </thead>
-            <tbody>
-                {{range $i, $cat := .TopCategories}}
-                <tr>
-                    <td>{{add $i 1}}</td>
-                    <td>{{$cat.Name}}</td>
-                    <td>{{$cat.Count}}</td>
-                    <td>{{printf "%.1f" $cat.Percent}}%</td>
-                </tr>
-                {{end}}
-            </tbody>
-        </table>
-
-        <h2>📦 Примеры нормализованных записей</h2>
-        {{range .Examples}}
-        <div class="example">
-            <strong>Исходное:</strong> {{.Source}}<br>
-            <strong>Нормализованное:</strong> {{.Normalized}}<br>
-            <span class="category">Категория:</span> {{.Category}} | <strong>Код:</strong> {{.Code}}
-        </div>
-        {{end}}
-
-        <h2>✨ Дополнительная статистика</h2>
-        <ul>
-            <li>Изменено названий: {{.ChangedCount}} ({{printf "%.1f" .ChangedPercent}}%)</li>
-            <li>Записей с объединением: {{.MergedCount}}</li>
-        </ul>
-    </div>
-</body>
-</html>`
-
-	tmpl, err := template.New("report").Funcs(template.FuncMap{
-		"add": func(a, b int) int { return a + b },
-		"printf": fmt.Sprintf,
-	}).Parse(htmlTemplate)
+	// Шаблон задается именем встроенного шаблона или путем к файлу
+	engine := reports.NewEngine(*templatesDir, nil)
+	templateName := *templateArg
+	if strings.HasSuffix(templateName, ".html") {
+		engine = reports.NewEngine(filepath.Dir(templateName), nil)
+		templateName = strings.TrimSuffix(filepath.Base(templateName), ".html")
+	}
+	tpl, err := engine.Lookup(templateName)
 	if err != nil {
-		log.Fatalf("Ошибка парсинга шаблона: %v", err)
-	}
-
-	data := struct {
-		Timestamp         string
-		TotalItems        int
-		NormalizedCount   int
-		NormalizedPercent float64
-		KpvedCount        int
-		KpvedPercent      float64
-		UniqueCategories  int
-		ChangedCount      int
-		ChangedPercent    float64
-		MergedCount       int
-		TopCategories     []CategoryStat
-		Examples          []Example
-	}{
-		Timestamp:         time.Now().Format("2006-01-02 15:04:05"),
-		TotalItems:        totalItems,
-		NormalizedCount:   normalizedCount,
-		NormalizedPercent: float64(normalizedCount) / float64(totalItems) * 100,
-		KpvedCount:        kpvedCount,
-		KpvedPercent:      float64(kpvedCount) / float64(normalizedCount) * 100,
-		UniqueCategories:  uniqueCategories,
-		ChangedCount:      changedCount,
-		ChangedPercent:    float64(changedCount) / float64(normalizedCount) * 100,
-		MergedCount:       mergedCount,
-		TopCategories:     topCategories,
-		Examples:          examples,
+		log.Fatalf("Ошибка загрузки шаблона %s: %v", *templateArg, err)
+	}
+
+	stats := reports.NewProvider(db.GetDB(), classifier)
+	ctx := &reports.ReportContext{
+		Database: filepath.Base(dbPath),
+		Lang:     *lang,
+		Branding: reports.Branding{ClientName: *clientName, LogoURL: *logoURL, PrimaryColor: *primaryColor},
+		Stats:    stats,
 	}
 
 	file, err := os.Create(outputFile)
@@ -233,13 +81,59 @@ func main() {
 	}
 	defer file.Close()
 
-	if err := tmpl.Execute(file, data); err != nil {
+	if err := engine.Render(file, tpl, ctx); err != nil {
 		log.Fatalf("Ошибка генерации HTML: %v", err)
 	}
 
-	fmt.Printf("✅ Отчет успешно создан: %s\n", outputFile)
-	fmt.Printf("   Всего элементов: %d\n", totalItems)
-	fmt.Printf("   Нормализовано: %d (%.1f%%)\n", normalizedCount, data.NormalizedPercent)
-	fmt.Printf("   Классифицировано по КПВЭД: %d (%.1f%%)\n", kpvedCount, data.KpvedPercent)
+	fmt.Printf("✅ Отчет успешно создан: %s (шаблон %s)\n", outputFile, tpl.Name)
+	if summary, err := stats.Summary(); err == nil {
+		fmt.Printf("   Всего элементов: %d\n", summary.TotalItems)
+		fmt.Printf("   Нормализовано: %d (%.1f%%)\n", summary.NormalizedCount, summary.NormalizedPercent)
+		fmt.Printf("   Классифицировано по КПВЭД: %d (%.1f%%)\n", summary.KpvedCount, summary.KpvedPercent)
+	}
 }
 
+// exportKpvedReport сохраняет отчет о классификации, сгруппированный по разделам КПВЭД
+func exportKpvedReport(db *database.DB, dbPath, outputFile, classifierPath string, examples int, lang string) {
+	var serviceDB *database.ServiceDB
+	if classifierPath != "" {
+		var err error
+		serviceDB, err = database.NewServiceDB(classifierPath)
+		if err != nil {
+			log.Fatalf("Ошибка подключения к базе классификатора: %v", err)
+		}
+		defer serviceDB.Close()
+	}
+
+	options := reports.KpvedReportOptions{Database: filepath.Base(dbPath), ExamplesPerClass: examples}
+	if examples == 0 {
+		options.ExamplesPerClass = -1
+	}
+	var classifier *sql.DB
+	if serviceDB != nil {
+		classifier = serviceDB.GetDB()
+	}
+	report, err := reports.BuildKpvedReport(db.GetDB(), classifier, options)
+	if err != nil {
+		log.Fatalf("Ошибка построения отчета: %v", err)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Ошибка создания файла: %v", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(outputFile), ".xlsx") {
+		err = reports.RenderKpvedReportXLSX(file, report, lang)
+	} else {
+		err = reports.RenderKpvedReportHTML(file, report, lang)
+	}
+	if err != nil {
+		log.Fatalf("Ошибка генерации отчета: %v", err)
+	}
+
+	fmt.Printf("✅ Отчет по разделам КПВЭД создан: %s\n", outputFile)
+	fmt.Printf("   Классифицировано: %d из %d (%.1f%%)\n", report.ClassifiedItems, report.TotalItems, report.Coverage)
+	fmt.Printf("   Разделов: %d\n", len(report.Sections))
+}
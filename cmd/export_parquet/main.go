@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"httpserver/database"
+	"httpserver/parquet"
+)
+
+const usage = `Использование:
+  export_parquet catalog-items -upload uuid [-catalogs Справочник1,Справочник2] [-row-group-mb N] [-codec gzip|none] <путь_к_базе.db> <файл.parquet>
+  export_parquet normalized [-row-group-mb N] [-codec gzip|none] <путь_к_normalized_data.db> <файл.parquet>`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	uploadUUID := flags.String("upload", "", "UUID выгрузки (для catalog-items)")
+	catalogs := flags.String("catalogs", "", "Справочники через запятую (по умолчанию все)")
+	rowGroupMB := flags.Int("row-group-mb", parquet.DefaultRowGroupSize>>20, "Размер группы строк, МБ")
+	codec := flags.String("codec", "gzip", "Сжатие страниц: gzip или none")
+	batchSize := flags.Int("batch", 1000, "Строк в одном запросе к базе")
+	flags.Parse(os.Args[2:])
+
+	if flags.NArg() < 2 || (command != "catalog-items" && command != "normalized") {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	options := parquet.Options{RowGroupSize: *rowGroupMB << 20}
+	switch *codec {
+	case "gzip":
+		options.Codec = parquet.CodecGzip
+	case "none":
+		options.Codec = parquet.CodecNone
+	default:
+		log.Fatalf("Неизвестный кодек сжатия: %s", *codec)
+	}
+
+	db, err := database.NewDB(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer db.Close()
+
+	file, err := os.Create(flags.Arg(1))
+	if err != nil {
+		log.Fatalf("Ошибка создания файла: %v", err)
+	}
+
+	exported := 0
+	export := database.ParquetExport{
+		Options:   options,
+		BatchSize: *batchSize,
+		Progress: func(rows int) {
+			exported += rows
+			if exported%100000 < rows {
+				log.Printf("Выгружено строк: %d", exported)
+			}
+		},
+	}
+
+	var rows int64
+	ctx := context.Background()
+	switch command {
+	case "catalog-items":
+		if *uploadUUID == "" {
+			log.Fatalf("Не указан UUID выгрузки (-upload)")
+		}
+		upload, err := db.GetUploadByUUID(*uploadUUID)
+		if err != nil {
+			log.Fatalf("Выгрузка %s не найдена: %v", *uploadUUID, err)
+		}
+		var catalogNames []string
+		for _, name := range strings.Split(*catalogs, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				catalogNames = append(catalogNames, name)
+			}
+		}
+		rows, err = db.ExportCatalogItemsParquet(ctx, upload.ID, catalogNames, file, export)
+	case "normalized":
+		rows, err = db.ExportNormalizedDataParquet(ctx, file, export)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(flags.Arg(1))
+		log.Fatalf("Ошибка выгрузки: %v", err)
+	}
+
+	fmt.Printf("Выгружено %d строк в %s\n", rows, flags.Arg(1))
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// GeneratorConfig параметры синтетической выгрузки 1С
+type GeneratorConfig struct {
+	Catalogs       int     // Количество справочников
+	Items          int     // Количество элементов в каждом справочнике
+	Attributes     int     // Количество реквизитов у элемента (насыщенность)
+	DuplicateRatio float64 // Доля элементов - вариаций уже сгенерированных наименований
+	NoiseRatio     float64 // Доля элементов с "мусором" в наименовании
+	KazakhRatio    float64 // Доля наименований на казахском языке
+	Seed           int64
+}
+
+// SyntheticAttribute описание реквизита синтетического справочника
+type SyntheticAttribute struct {
+	Name      string
+	Type      string
+	Length    int
+	Precision int
+	Scale     int
+	Required  bool
+}
+
+// SyntheticItem элемент синтетического справочника
+type SyntheticItem struct {
+	Reference  string
+	Code       string
+	Name       string
+	Attributes []AttributeValue
+}
+
+// AttributeValue значение реквизита элемента
+type AttributeValue struct {
+	Name  string
+	Value string
+}
+
+// SyntheticCatalog синтетический справочник с элементами
+type SyntheticCatalog struct {
+	Name       string
+	Synonym    string
+	Attributes []SyntheticAttribute
+	Items      []SyntheticItem
+}
+
+// Справочники типовой конфигурации в порядке генерации
+var catalogNames = []struct{ name, synonym string }{
+	{"Номенклатура", "Номенклатура"},
+	{"Контрагенты", "Контрагенты"},
+	{"Склады", "Склады (места хранения)"},
+	{"ЕдиницыИзмерения", "Единицы измерения"},
+	{"ФизическиеЛица", "Физические лица"},
+}
+
+// Реквизиты в порядке убывания частоты использования
+var attributePool = []SyntheticAttribute{
+	{Name: "Артикул", Type: "Строка", Length: 25, Required: true},
+	{Name: "Вес", Type: "Число", Precision: 15, Scale: 3},
+	{Name: "ЕдиницаИзмерения", Type: "Строка", Length: 10},
+	{Name: "Производитель", Type: "СправочникСсылка.Контрагенты"},
+	{Name: "СтранаПроисхождения", Type: "Строка", Length: 50},
+	{Name: "ДатаСоздания", Type: "Дата"},
+	{Name: "Услуга", Type: "Булево"},
+	{Name: "Количество", Type: "Число", Precision: 10},
+	{Name: "Описание", Type: "Строка"},
+	{Name: "Штрихкод", Type: "Строка", Length: 13},
+}
+
+// Части наименований на русском и казахском языках
+var (
+	ruNouns      = []string{"Молоток", "Болт", "Кабель", "Краска", "Перчатки", "Труба", "Саморез", "Лампа", "Дрель", "Ключ гаечный", "Цемент", "Доска обрезная"}
+	ruAdjectives = []string{"слесарный", "оцинкованный", "медный", "белая", "рабочие", "стальная", "светодиодная", "ударная", "комбинированный", "строительный"}
+	kzNouns      = []string{"Балға", "Бұранда", "Кабель", "Бояу", "Қолғап", "Құбыр", "Шам", "Бұрғы", "Кілт", "Цемент", "Тақтай"}
+	kzAdjectives = []string{"слесарлық", "мырышталған", "мыс", "ақ", "жұмыс", "болат", "жарықдиодты", "соққылы", "құрылыс"}
+	specs        = []string{"М8х40", "3х2,5", "ПФ-115", "32мм", "4,2х76", "E27 10Вт", "500г", "М500", "25х150х6000", "1,5кВт"}
+	brands       = []string{"Bosch", "Makita", "Зубр", "Kraftool", "Stayer", "Tikkurila", "IEK"}
+	units        = []string{"шт", "кг", "м", "упак", "л", "м2"}
+	countries    = []string{"Казахстан", "Россия", "Китай", "Германия", "Турция"}
+	orgForms     = []string{"ТОО", "ООО", "АО", "ИП"}
+	noise        = []string{"!!!", " (НЕ ИСПОЛЬЗОВАТЬ)", "  ", " ***", "__", " - копия", "?"}
+)
+
+// Generator генератор синтетических выгрузок
+type Generator struct {
+	config GeneratorConfig
+	rnd    *rand.Rand
+}
+
+// NewGenerator создает генератор с нормализованными параметрами
+func NewGenerator(config GeneratorConfig) *Generator {
+	if config.Catalogs <= 0 {
+		config.Catalogs = 1
+	}
+	if config.Attributes < 0 {
+		config.Attributes = 0
+	}
+	if config.Attributes > len(attributePool) {
+		config.Attributes = len(attributePool)
+	}
+	return &Generator{config: config, rnd: rand.New(rand.NewSource(config.Seed))}
+}
+
+// Generate строит справочники с элементами
+func (g *Generator) Generate() []SyntheticCatalog {
+	catalogs := make([]SyntheticCatalog, 0, g.config.Catalogs)
+	for c := 0; c < g.config.Catalogs; c++ {
+		catalog := SyntheticCatalog{Attributes: attributePool[:g.config.Attributes]}
+		if c < len(catalogNames) {
+			catalog.Name, catalog.Synonym = catalogNames[c].name, catalogNames[c].synonym
+		} else {
+			catalog.Name = fmt.Sprintf("Справочник%d", c+1)
+			catalog.Synonym = fmt.Sprintf("Справочник %d", c+1)
+		}
+
+		catalog.Items = make([]SyntheticItem, 0, g.config.Items)
+		for i := 0; i < g.config.Items; i++ {
+			var name string
+			if i > 0 && g.rnd.Float64() < g.config.DuplicateRatio {
+				name = g.duplicateName(catalog.Items[g.rnd.Intn(len(catalog.Items))].Name)
+			} else {
+				name = g.itemName(catalog.Name)
+			}
+			if g.rnd.Float64() < g.config.NoiseRatio {
+				name = g.addNoise(name)
+			}
+			catalog.Items = append(catalog.Items, SyntheticItem{
+				Reference:  g.reference(),
+				Code:       fmt.Sprintf("%s-%06d", strings.ToUpper(string([]rune(catalog.Name)[:2])), i+1),
+				Name:       name,
+				Attributes: g.attributeValues(catalog.Attributes, i),
+			})
+		}
+		catalogs = append(catalogs, catalog)
+	}
+	return catalogs
+}
+
+// itemName формирует наименование элемента в стиле справочника
+func (g *Generator) itemName(catalogName string) string {
+	kazakh := g.rnd.Float64() < g.config.KazakhRatio
+	switch catalogName {
+	case "Контрагенты":
+		nouns := ruNouns
+		if kazakh {
+			nouns = kzNouns
+		}
+		return fmt.Sprintf("%s \"%s%s\"", g.pick(orgForms), g.pick(nouns), g.pick([]string{"Строй", "Снаб", "Торг", "Сервис", ""}))
+	case "Склады":
+		if kazakh {
+			return fmt.Sprintf("%s қоймасы №%d", g.pick(countries), g.rnd.Intn(20)+1)
+		}
+		return fmt.Sprintf("Склад %s №%d", g.pick([]string{"основной", "центральный", "розничный", "транзитный"}), g.rnd.Intn(20)+1)
+	case "ЕдиницыИзмерения":
+		return g.pick(units)
+	}
+
+	if kazakh {
+		return fmt.Sprintf("%s %s %s", g.pick(kzNouns), g.pick(kzAdjectives), g.pick(specs))
+	}
+	name := fmt.Sprintf("%s %s %s", g.pick(ruNouns), g.pick(ruAdjectives), g.pick(specs))
+	if g.rnd.Intn(3) == 0 {
+		name += " " + g.pick(brands)
+	}
+	return name
+}
+
+// duplicateName возвращает вариацию наименования, как при ручном вводе дублей
+func (g *Generator) duplicateName(name string) string {
+	switch g.rnd.Intn(5) {
+	case 0:
+		return strings.ToUpper(name)
+	case 1:
+		return strings.ReplaceAll(name, " ", "  ")
+	case 2:
+		return name + "."
+	case 3:
+		// Опечатка: перестановка двух соседних букв
+		runes := []rune(name)
+		if len(runes) > 3 {
+			i := 1 + g.rnd.Intn(len(runes)-2)
+			runes[i], runes[i+1] = runes[i+1], runes[i]
+		}
+		return string(runes)
+	default:
+		return strings.ToLower(name)
+	}
+}
+
+// addNoise добавляет в наименование типичный "мусор"
+func (g *Generator) addNoise(name string) string {
+	if g.rnd.Intn(2) == 0 {
+		return " " + name + g.pick(noise)
+	}
+	return name + g.pick(noise)
+}
+
+// attributeValues формирует значения реквизитов элемента
+func (g *Generator) attributeValues(attributes []SyntheticAttribute, index int) []AttributeValue {
+	values := make([]AttributeValue, 0, len(attributes))
+	for _, attr := range attributes {
+		var value string
+		switch attr.Name {
+		case "Артикул":
+			value = fmt.Sprintf("ART-%06d", index+1)
+		case "Вес":
+			value = strings.Replace(fmt.Sprintf("%.3f", g.rnd.Float64()*50), ".", ",", 1)
+		case "ЕдиницаИзмерения":
+			value = g.pick(units)
+		case "Производитель":
+			value = g.pick(brands)
+		case "СтранаПроисхождения":
+			value = g.pick(countries)
+		case "ДатаСоздания":
+			value = fmt.Sprintf("%04d-%02d-%02dT00:00:00", 2015+g.rnd.Intn(10), g.rnd.Intn(12)+1, g.rnd.Intn(28)+1)
+		case "Услуга":
+			value = g.pick([]string{"Истина", "Ложь"})
+		case "Количество":
+			value = fmt.Sprintf("%d", g.rnd.Intn(1000))
+		case "Описание":
+			value = g.itemName("Номенклатура")
+		case "Штрихкод":
+			value = fmt.Sprintf("46%011d", g.rnd.Int63n(100000000000))
+		}
+		values = append(values, AttributeValue{Name: attr.Name, Value: value})
+	}
+	return values
+}
+
+// reference возвращает случайный GUID в формате ссылок 1С
+func (g *Generator) reference() string {
+	b := make([]byte, 16)
+	g.rnd.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (g *Generator) pick(values []string) string {
+	return values[g.rnd.Intn(len(values))]
+}
+
+// AttributesXML формирует реквизиты элемента в формате <Имя>значение</Имя>
+func (item SyntheticItem) AttributesXML() string {
+	var sb strings.Builder
+	for _, attr := range item.Attributes {
+		sb.WriteString("<" + attr.Name + ">")
+		xmlEscape(&sb, attr.Value)
+		sb.WriteString("</" + attr.Name + ">")
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"httpserver/database"
+)
+
+func main() {
+	catalogs := flag.Int("catalogs", 1, "Количество справочников")
+	items := flag.Int("items", 1000, "Количество элементов в каждом справочнике")
+	attributes := flag.Int("attributes", 5, fmt.Sprintf("Количество реквизитов у элемента (0-%d)", len(attributePool)))
+	duplicates := flag.Float64("duplicates", 0.1, "Доля дублей (вариаций существующих наименований)")
+	noiseRatio := flag.Float64("noise", 0.05, "Доля наименований с мусором")
+	kazakh := flag.Float64("kz", 0.2, "Доля наименований на казахском языке")
+	seed := flag.Int64("seed", 1, "Начальное значение генератора (одинаковый seed - одинаковые данные)")
+	format := flag.String("format", "xml", "Формат вывода: xml (запросы протокола выгрузки) или db (SQLite)")
+	out := flag.String("out", "testdata_upload", "Каталог для XML-запросов или путь к файлу БД")
+	batchSize := flag.Int("batch", 500, "Элементов в одном запросе /catalog/items")
+	configName := flag.String("config", "УправлениеТорговлей", "Имя конфигурации в рукопожатии")
+	serverURL := flag.String("server", "", "Отправить запросы на сервер (например, http://localhost:9999)")
+	replayDir := flag.String("replay", "", "Отправить ранее сгенерированные запросы из каталога (вместе с -server)")
+	flag.Parse()
+
+	if *replayDir != "" {
+		if *serverURL == "" {
+			log.Fatal("Для -replay необходимо указать -server")
+		}
+		payloads, err := ReadPayloads(*replayDir)
+		if err != nil {
+			log.Fatalf("Ошибка чтения запросов: %v", err)
+		}
+		replay(*serverURL, payloads)
+		return
+	}
+
+	start := time.Now()
+	generated := NewGenerator(GeneratorConfig{
+		Catalogs:       *catalogs,
+		Items:          *items,
+		Attributes:     *attributes,
+		DuplicateRatio: *duplicates,
+		NoiseRatio:     *noiseRatio,
+		KazakhRatio:    *kazakh,
+		Seed:           *seed,
+	}).Generate()
+
+	switch *format {
+	case "xml":
+		payloads := BuildPayloads(generated, *configName, *batchSize)
+		if err := WritePayloads(*out, payloads); err != nil {
+			log.Fatalf("Ошибка записи запросов: %v", err)
+		}
+		fmt.Printf("Сгенерировано %d запросов в %s за %v\n", len(payloads), *out, time.Since(start).Round(time.Millisecond))
+		if *serverURL != "" {
+			replay(*serverURL, payloads)
+		}
+	case "db":
+		uploadUUID, err := writeDatabase(*out, generated, *configName)
+		if err != nil {
+			log.Fatalf("Ошибка записи в БД: %v", err)
+		}
+		fmt.Printf("Выгрузка %s записана в %s за %v\n", uploadUUID, *out, time.Since(start).Round(time.Millisecond))
+	default:
+		log.Fatalf("Неизвестный формат: %s (ожидается xml или db)", *format)
+	}
+}
+
+// replay отправляет запросы на сервер и печатает итог
+func replay(serverURL string, payloads []Payload) {
+	start := time.Now()
+	uploadUUID, err := Replay(serverURL, payloads)
+	if err != nil {
+		log.Fatalf("Ошибка отправки: %v", err)
+	}
+	fmt.Printf("Отправлено %d запросов на %s за %v, upload_uuid: %s\n", len(payloads), serverURL, time.Since(start).Round(time.Millisecond), uploadUUID)
+}
+
+// writeDatabase записывает выгрузку напрямую в SQLite БД (схема БД выгрузки)
+func writeDatabase(path string, catalogs []SyntheticCatalog, configName string) (string, error) {
+	db, err := database.NewDB(path)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	// Отладочный лог БД на каждый элемент не нужен при генерации
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	uploadUUID := uuid.New().String()
+	upload, err := db.CreateUpload(uploadUUID, "8.3.24", configName)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	for _, catalog := range catalogs {
+		created, err := db.AddCatalog(upload.ID, catalog.Name, catalog.Synonym)
+		if err != nil {
+			return "", fmt.Errorf("failed to add catalog %s: %w", catalog.Name, err)
+		}
+
+		metadata := &database.CatalogMetadata{UploadID: upload.ID, CatalogName: catalog.Name, Synonym: catalog.Synonym}
+		for _, attr := range catalog.Attributes {
+			metadata.Attributes = append(metadata.Attributes, database.NewCatalogAttributeDefinition(attr.Name, "", attr.Type,
+				attr.Length, attr.Precision, attr.Scale, attr.Required))
+		}
+		if err := database.SaveCatalogMetadata(db.GetDB(), metadata); err != nil {
+			return "", fmt.Errorf("failed to save metadata for %s: %w", catalog.Name, err)
+		}
+
+		for _, item := range catalog.Items {
+			if err := db.AddCatalogItem(created.ID, item.Reference, item.Code, item.Name, item.AttributesXML(), ""); err != nil {
+				return "", fmt.Errorf("failed to add item %s: %w", item.Reference, err)
+			}
+		}
+	}
+
+	if err := db.CompleteUpload(upload.ID); err != nil {
+		return "", fmt.Errorf("failed to complete upload: %w", err)
+	}
+	return uploadUUID, nil
+}
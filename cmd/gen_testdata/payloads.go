@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UploadUUIDPlaceholder подставляется вместо upload_uuid, который сервер выдает при рукопожатии
+const UploadUUIDPlaceholder = "{{upload_uuid}}"
+
+// Payload запрос протокола выгрузки
+type Payload struct {
+	Endpoint string `json:"endpoint"`
+	File     string `json:"file"`
+	body     []byte
+}
+
+func xmlEscape(sb *strings.Builder, value string) {
+	xml.EscapeText(sb, []byte(value))
+}
+
+// element формирует <tag>значение</tag> с экранированием
+func element(tag, value string) string {
+	var sb strings.Builder
+	sb.WriteString("<" + tag + ">")
+	xmlEscape(&sb, value)
+	sb.WriteString("</" + tag + ">")
+	return sb.String()
+}
+
+// BuildPayloads формирует последовательность запросов выгрузки, как их отправляет обработка 1С
+func BuildPayloads(catalogs []SyntheticCatalog, configName string, batchSize int) []Payload {
+	timestamp := element("timestamp", time.Now().Format(time.RFC3339))
+	uuid := element("upload_uuid", UploadUUIDPlaceholder)
+	var payloads []Payload
+	add := func(endpoint, name, body string) {
+		payloads = append(payloads, Payload{
+			Endpoint: endpoint,
+			File:     fmt.Sprintf("%04d_%s.xml", len(payloads), name),
+			body:     []byte(xml.Header + body),
+		})
+	}
+
+	add("/handshake", "handshake", "<handshake>"+element("version_1c", "8.3.24")+element("config_name", configName)+
+		element("computer_name", "gen_testdata")+element("user_name", "Генератор")+timestamp+"</handshake>")
+
+	var meta strings.Builder
+	meta.WriteString("<metadata>" + uuid + element("version_1c", "8.3.24") + element("config_name", configName) + timestamp + "<catalogs>")
+	for _, catalog := range catalogs {
+		meta.WriteString("<catalog>" + element("name", catalog.Name) + element("synonym", catalog.Synonym) + "<attributes>")
+		for _, attr := range catalog.Attributes {
+			meta.WriteString("<attribute>" + element("name", attr.Name) + element("type", attr.Type))
+			if attr.Length > 0 {
+				meta.WriteString(element("length", fmt.Sprint(attr.Length)))
+			}
+			if attr.Precision > 0 {
+				meta.WriteString(element("precision", fmt.Sprint(attr.Precision)) + element("scale", fmt.Sprint(attr.Scale)))
+			}
+			if attr.Required {
+				meta.WriteString(element("required", "true"))
+			}
+			meta.WriteString("</attribute>")
+		}
+		meta.WriteString("</attributes></catalog>")
+	}
+	meta.WriteString("</catalogs></metadata>")
+	add("/metadata", "metadata", meta.String())
+
+	for c, catalog := range catalogs {
+		add("/catalog/meta", fmt.Sprintf("catalog_meta_%d", c+1), "<catalog_meta>"+uuid+element("name", catalog.Name)+
+			element("synonym", catalog.Synonym)+timestamp+"</catalog_meta>")
+
+		for start := 0; start < len(catalog.Items); start += batchSize {
+			end := start + batchSize
+			if end > len(catalog.Items) {
+				end = len(catalog.Items)
+			}
+			var batch strings.Builder
+			batch.WriteString("<catalog_items>" + uuid + element("catalog_name", catalog.Name) + "<items>")
+			for _, item := range catalog.Items[start:end] {
+				batch.WriteString("<item>" + element("reference", item.Reference) + element("code", item.Code) + element("name", item.Name) +
+					"<attributes_xml>" + item.AttributesXML() + "</attributes_xml><table_parts></table_parts>" + timestamp + "</item>")
+			}
+			batch.WriteString("</items></catalog_items>")
+			add("/catalog/items", fmt.Sprintf("catalog_items_%d_%06d", c+1, start), batch.String())
+		}
+	}
+
+	add("/complete", "complete", "<complete>"+uuid+timestamp+"</complete>")
+	return payloads
+}
+
+// WritePayloads сохраняет запросы в каталог вместе с manifest.json (порядок отправки)
+func WritePayloads(dir string, payloads []Payload) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for _, p := range payloads {
+		if err := os.WriteFile(filepath.Join(dir, p.File), p.body, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", p.File, err)
+		}
+	}
+	manifest, _ := json.MarshalIndent(payloads, "", "  ")
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), manifest, 0644)
+}
+
+// ReadPayloads загружает ранее сохраненные запросы по manifest.json
+func ReadPayloads(dir string) ([]Payload, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var payloads []Payload
+	if err := json.Unmarshal(data, &payloads); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	for i := range payloads {
+		if payloads[i].body, err = os.ReadFile(filepath.Join(dir, payloads[i].File)); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", payloads[i].File, err)
+		}
+	}
+	return payloads, nil
+}
+
+// Replay отправляет запросы на сервер, подставляя upload_uuid из ответа на рукопожатие
+func Replay(serverURL string, payloads []Payload) (string, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	serverURL = strings.TrimRight(serverURL, "/")
+	uploadUUID := ""
+
+	for _, p := range payloads {
+		body := bytes.ReplaceAll(p.body, []byte(UploadUUIDPlaceholder), []byte(uploadUUID))
+		resp, err := client.Post(serverURL+p.Endpoint, "application/xml; charset=utf-8", bytes.NewReader(body))
+		if err != nil {
+			return uploadUUID, fmt.Errorf("%s: %w", p.File, err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return uploadUUID, fmt.Errorf("%s: %s returned %d: %s", p.File, p.Endpoint, resp.StatusCode, strings.TrimSpace(string(respBody)))
+		}
+
+		if p.Endpoint == "/handshake" {
+			var handshake struct {
+				UploadUUID string `xml:"upload_uuid"`
+			}
+			if err := xml.Unmarshal(respBody, &handshake); err != nil || handshake.UploadUUID == "" {
+				return "", fmt.Errorf("handshake response has no upload_uuid: %s", strings.TrimSpace(string(respBody)))
+			}
+			uploadUUID = handshake.UploadUUID
+		}
+	}
+	return uploadUUID, nil
+}
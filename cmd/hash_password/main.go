@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"httpserver/database"
+)
+
+const usage = `Использование:
+  hash_password < пароль
+
+Печатает bcrypt хеш пароля для BREAK_GLASS_PASSWORD_HASH. Пароль читается из первой строки
+стандартного ввода, чтобы не оставлять его в истории команд`
+
+func main() {
+	if len(os.Args) > 1 {
+		fmt.Println(usage)
+		os.Exit(1)
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatalf("Ошибка чтения пароля: %v", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if len(password) < database.MinPasswordLength {
+		log.Fatalf("Пароль должен быть не короче %d символов", database.MinPasswordLength)
+	}
+	if len(password) > database.MaxPasswordLength {
+		log.Fatalf("Пароль должен быть не длиннее %d байт", database.MaxPasswordLength)
+	}
+
+	hash, err := database.HashPassword(password)
+	if err != nil {
+		log.Fatalf("Ошибка хеширования пароля: %v", err)
+	}
+	fmt.Println(hash)
+}
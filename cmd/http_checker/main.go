@@ -87,17 +87,27 @@ func main() {
 	timeout := flag.Duration("timeout", 7*time.Second, "Таймаут для запросов")
 	maxRetries := flag.Int("retries", 3, "Максимальное количество повторов")
 	concurrent := flag.Int("concurrent", 5, "Количество одновременных проверок")
+	selfCheck := flag.String("self-check", "", "Адрес запущенного httpserver (например http://localhost:9999) для автоматической генерации проверок по его маршрутам")
+	saveConfig := flag.String("save-config", "", "Файл для сохранения сгенерированной конфигурации проверок (JSON)")
 	flag.Parse()
 
 	// Настройка логирования
 	setupLogging(*logPath)
 
 	// Загрузка конфигурации
-	config, err := loadConfig(*configFile, *urlsFile, *timeout, *maxRetries, *concurrent)
+	config, err := loadConfig(*configFile, *urlsFile, *selfCheck, *timeout, *maxRetries, *concurrent)
 	if err != nil {
 		logger.Fatalf("Ошибка загрузки конфигурации: %v", err)
 	}
 
+	if *saveConfig != "" {
+		if err := saveGeneratedConfig(config, *saveConfig); err != nil {
+			logger.Printf("⚠️  Ошибка сохранения конфигурации: %v", err)
+		} else {
+			logger.Printf("✅ Конфигурация проверок сохранена: %s", *saveConfig)
+		}
+	}
+
 	logger.Printf("🚀 Начало проверки HTTP статусов")
 	logger.Printf("📊 Всего URL для проверки: %d", len(config.URLs))
 	logger.Printf("⚙️  Параметры: timeout=%v, retries=%d, concurrent=%d", 
@@ -174,7 +184,7 @@ func setupLogging(logPath string) {
 	logger.Printf("📝 Логирование в файл: %s", logPath)
 }
 
-func loadConfig(configFile, urlsFile string, timeout time.Duration, maxRetries, concurrent int) (*CheckConfig, error) {
+func loadConfig(configFile, urlsFile, selfCheckURL string, timeout time.Duration, maxRetries, concurrent int) (*CheckConfig, error) {
 	config := &CheckConfig{
 		Timeout:          timeout,
 		MaxRetries:       maxRetries,
@@ -200,6 +210,15 @@ func loadConfig(configFile, urlsFile string, timeout time.Duration, maxRetries,
 		logger.Printf("✅ Конфигурация загружена из: %s", configFile)
 	}
 
+	// В режиме самопроверки набор URL формируется по маршрутам сервера,
+	// из файла конфигурации используются только общие параметры
+	if selfCheckURL != "" {
+		config.URLs = nil
+		if err := buildSelfCheckConfig(selfCheckURL, config); err != nil {
+			return nil, err
+		}
+	}
+
 	// Загрузка URL из файла
 	if urlsFile != "" {
 		urls, err := loadURLsFromFile(urlsFile)
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// serverRoute описание маршрута из /api/routes сервера
+type serverRoute struct {
+	Path  string `json:"path"`
+	Group string `json:"group"`
+	Smoke *struct {
+		Method         string `json:"method"`
+		ExpectedStatus int    `json:"expected_status"`
+		Category       string `json:"category"`
+	} `json:"smoke,omitempty"`
+}
+
+// serverRoutesResponse ответ /api/routes
+type serverRoutesResponse struct {
+	Routes []serverRoute `json:"routes"`
+	Total  int           `json:"total"`
+}
+
+// defaultSelfCheckURLs набор проверок для серверов, у которых еще нет /api/routes
+var defaultSelfCheckURLs = []URLCheck{
+	{URL: "/health", Method: "GET", ExpectedStatus: 200, Category: "critical"},
+	{URL: "/stats", Method: "GET", ExpectedStatus: 200, Category: "critical"},
+	{URL: "/api/uploads", Method: "GET", ExpectedStatus: 200, Category: "api"},
+	{URL: "/api/normalization/status", Method: "GET", ExpectedStatus: 200, Category: "api"},
+	{URL: "/api/quality/stats", Method: "GET", ExpectedStatus: 200, Category: "api"},
+	{URL: "/api/monitoring/metrics", Method: "GET", ExpectedStatus: 200, Category: "monitoring"},
+}
+
+// buildSelfCheckConfig опрашивает запущенный httpserver и формирует набор проверок
+// на основе маршрутов с подсказками smoke. Если сервер не поддерживает /api/routes,
+// используется встроенный набор базовых эндпоинтов.
+func buildSelfCheckConfig(baseURL string, config *CheckConfig) error {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		return fmt.Errorf("не указан адрес сервера для самопроверки")
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return fmt.Errorf("адрес сервера должен начинаться с http:// или https://: %s", baseURL)
+	}
+
+	checks, err := fetchSmokeChecks(baseURL, config.Timeout)
+	if err != nil {
+		logger.Printf("⚠️  Не удалось получить маршруты сервера (%v), используется базовый набор проверок", err)
+		checks = make([]URLCheck, len(defaultSelfCheckURLs))
+		copy(checks, defaultSelfCheckURLs)
+	}
+
+	for i := range checks {
+		checks[i].URL = baseURL + checks[i].URL
+	}
+
+	config.URLs = append(config.URLs, checks...)
+	logger.Printf("✅ Сформировано %d проверок для сервера %s", len(checks), baseURL)
+	return nil
+}
+
+// fetchSmokeChecks получает список маршрутов и оставляет только пригодные для smoke-проверки
+func fetchSmokeChecks(baseURL string, timeout time.Duration) ([]URLCheck, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(baseURL + "/api/routes?smoke=true")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("/api/routes вернул статус %d", resp.StatusCode)
+	}
+
+	var routes serverRoutesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
+		return nil, fmt.Errorf("ошибка разбора ответа /api/routes: %w", err)
+	}
+
+	checks := make([]URLCheck, 0, len(routes.Routes))
+	for _, route := range routes.Routes {
+		if route.Smoke == nil {
+			continue
+		}
+		method := route.Smoke.Method
+		if method == "" {
+			method = "GET"
+		}
+		category := route.Smoke.Category
+		if category == "" {
+			category = route.Group
+		}
+		checks = append(checks, URLCheck{
+			URL:            route.Path,
+			Method:         method,
+			ExpectedStatus: route.Smoke.ExpectedStatus,
+			Category:       category,
+		})
+	}
+
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("сервер не вернул маршрутов для smoke-проверки")
+	}
+
+	return checks, nil
+}
+
+// saveGeneratedConfig сохраняет сформированную конфигурацию для повторного использования
+func saveGeneratedConfig(config *CheckConfig, filename string) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
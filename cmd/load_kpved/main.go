@@ -342,6 +342,11 @@ func main() {
 		log.Fatalf("Ошибка сохранения классификатора: %v", err)
 	}
 
+	// Сохраняем узлы дерева строками, чтобы не разбирать JSON при каждом использовании
+	if err := db.SaveClassifierNodes(created.ID, classification.FlattenTree(tree)); err != nil {
+		log.Fatalf("Ошибка сохранения узлов классификатора: %v", err)
+	}
+
 	fmt.Printf("Классификатор успешно загружен!\n")
 	fmt.Printf("ID: %d\n", created.ID)
 	fmt.Printf("Название: %s\n", created.Name)
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	_ "github.com/mattn/go-sqlite3"
+	"httpserver/database"
+	"httpserver/provisioning"
+)
+
+const usage = `Использование: provision [-apply] [-service-db путь] [-db путь] [-actor имя] <файл.yaml>
+Сравнивает файл провижининга (клиенты, проекты, базы данных, правила словарей, классификаторы, API ключи)
+с сервисной БД и показывает план. С -apply приводит состояние к файлу; повторный запуск ничего не меняет.
+Объекты, отсутствующие в файле, не удаляются. Токены созданных API ключей выводятся один раз.
+`
+
+func main() {
+	apply := flag.Bool("apply", false, "Применить изменения (без флага - только план)")
+	serviceDBPath := flag.String("service-db", envOr("SERVICE_DATABASE_PATH", "service.db"), "Путь к сервисной БД")
+	dbPath := flag.String("db", envOr("DATABASE_PATH", "data.db"), "Путь к основной БД (классификаторы категорий)")
+	actor := flag.String("actor", envOr("USER", "provisioning"), "Автор изменений")
+	flag.Usage = func() { fmt.Print(usage) }
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Ошибка чтения файла: %v", err)
+	}
+	spec, err := provisioning.ParseSpec(data)
+	if err != nil {
+		log.Fatalf("Ошибка файла провижининга: %v", err)
+	}
+
+	serviceDB, err := database.NewServiceDB(*serviceDBPath)
+	if err != nil {
+		log.Fatalf("Ошибка подключения к сервисной БД: %v", err)
+	}
+	defer serviceDB.Close()
+
+	// Основная БД нужна только для назначения классификаторов
+	var db *database.DB
+	if needsMainDB(spec) {
+		if db, err = database.NewDB(*dbPath); err != nil {
+			log.Fatalf("Ошибка подключения к основной БД: %v", err)
+		}
+		defer db.Close()
+	}
+
+	provisioner := provisioning.NewProvisioner(serviceDB, db, *actor)
+	var plan *provisioning.Plan
+	if *apply {
+		plan, err = provisioner.Apply(spec)
+	} else {
+		plan, err = provisioner.Plan(spec)
+	}
+	if plan != nil {
+		printPlan(plan)
+	}
+	if err != nil {
+		log.Fatalf("Ошибка провижининга: %v", err)
+	}
+
+	switch {
+	case *apply:
+		fmt.Printf("\nПрименено: создано %d, изменено %d, без изменений %d\n", plan.Create, plan.Update, plan.Unchanged)
+	case plan.HasChanges():
+		fmt.Printf("\nПлан: создать %d, изменить %d, без изменений %d. Для применения запустите с -apply\n",
+			plan.Create, plan.Update, plan.Unchanged)
+	default:
+		fmt.Println("\nСостояние соответствует файлу, изменений нет")
+	}
+
+	if len(plan.Tokens) > 0 {
+		names := make([]string, 0, len(plan.Tokens))
+		for name := range plan.Tokens {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println("\nТокены API ключей (сохраните, повторно они не показываются):")
+		for _, name := range names {
+			fmt.Printf("  %s: %s\n", name, plan.Tokens[name])
+		}
+	}
+}
+
+// printPlan выводит изменения: + создание, ~ изменение, * перевыпуск токена, = без изменений
+func printPlan(plan *provisioning.Plan) {
+	marks := map[string]string{
+		provisioning.ActionCreate:    "+",
+		provisioning.ActionUpdate:    "~",
+		provisioning.ActionUnchanged: "=",
+		provisioning.ActionRotate:    "*",
+	}
+	for _, change := range plan.Changes {
+		fmt.Printf("%s %-10s %s\n", marks[change.Action], change.Kind, change.Key)
+		for _, field := range change.Fields {
+			fmt.Printf("      %s\n", field)
+		}
+	}
+}
+
+// needsMainDB проверяет, назначаются ли в файле классификаторы
+func needsMainDB(spec *provisioning.Spec) bool {
+	for _, client := range spec.Clients {
+		for _, project := range client.Projects {
+			if len(project.Classifiers) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -13,27 +13,31 @@ import (
 )
 
 func main() {
-	if len(os.Args) < 3 {
-		fmt.Println("Использование: reclassify_with_kpved <путь_к_базе.db> <classifier_id> [strategy_id] [limit]")
+	force := flag.Bool("force", false, "Классифицировать и записи, входные данные которых не изменились")
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 2 {
+		fmt.Println("Использование: reclassify_with_kpved [--force] <путь_к_базе.db> <classifier_id> [strategy_id] [limit]")
 		fmt.Println("Пример: reclassify_with_kpved 1c_data.db 1 top_priority")
 		fmt.Println("Пример: reclassify_with_kpved 1c_data.db 1 top_priority 100")
+		fmt.Println("Записи, классифицированные с теми же наименованием, промптом и моделью, пропускаются без --force")
 		os.Exit(1)
 	}
 
-	dbPath := os.Args[1]
-	classifierIDStr := os.Args[2]
+	dbPath := args[0]
+	classifierIDStr := args[1]
 
 	var classifierID int
 	fmt.Sscanf(classifierIDStr, "%d", &classifierID)
 
 	strategyID := "top_priority"
-	if len(os.Args) >= 4 {
-		strategyID = os.Args[3]
+	if len(args) >= 3 {
+		strategyID = args[2]
 	}
 
 	limit := 0
-	if len(os.Args) >= 5 {
-		fmt.Sscanf(os.Args[4], "%d", &limit)
+	if len(args) >= 4 {
+		fmt.Sscanf(args[3], "%d", &limit)
 	}
 
 	fmt.Printf("Переклассификация с использованием КПВЭД\n")
@@ -62,10 +66,10 @@ func main() {
 	fmt.Printf("Максимальная глубина: %d\n", classifier.MaxDepth)
 	fmt.Println()
 
-	// Парсим дерево классификатора
-	var classifierTree classification.CategoryNode
-	if err := json.Unmarshal([]byte(classifier.TreeStructure), &classifierTree); err != nil {
-		log.Fatalf("Ошибка парсинга дерева классификатора: %v", err)
+	// Загружаем дерево классификатора
+	classifierTree, err := classification.LoadClassifierTree(db, classifier)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки дерева классификатора: %v", err)
 	}
 
 	// Создаем менеджер конфигурации для получения модели
@@ -86,7 +90,7 @@ func main() {
 	}
 
 	aiClassifier := classification.NewAIClassifier(apiKey, model)
-	aiClassifier.SetClassifierTree(&classifierTree)
+	aiClassifier.SetClassifierTree(classifierTree)
 
 	// Создаем менеджер стратегий
 	strategyManager := classification.NewStrategyManager()
@@ -94,7 +98,7 @@ func main() {
 	// Получаем нормализованные записи для переклассификации
 	fmt.Println("Загрузка нормализованных записей...")
 	query := `
-		SELECT id, source_name, normalized_name, code, category
+		SELECT id, source_name, normalized_name, code, category, COALESCE(classification_hash, '')
 		FROM normalized_data
 		WHERE source_name IS NOT NULL AND source_name != ''
 		ORDER BY id
@@ -115,12 +119,13 @@ func main() {
 		NormalizedName string
 		Code          string
 		OldCategory   string
+		Hash          string
 	}
 
 	var items []Item
 	for rows.Next() {
 		var item Item
-		if err := rows.Scan(&item.ID, &item.SourceName, &item.NormalizedName, &item.Code, &item.OldCategory); err != nil {
+		if err := rows.Scan(&item.ID, &item.SourceName, &item.NormalizedName, &item.Code, &item.OldCategory, &item.Hash); err != nil {
 			log.Printf("Ошибка сканирования: %v", err)
 			continue
 		}
@@ -152,6 +157,13 @@ func main() {
 			MaxLevels:   classifier.MaxDepth,
 		}
 
+		// Входные данные не изменились с прошлой классификации
+		contentHash := aiClassifier.ContentHash(aiRequest, strategyID)
+		if !*force && item.Hash == contentHash {
+			skippedCount++
+			continue
+		}
+
 		aiResponse, err := aiClassifier.ClassifyWithAI(aiRequest)
 		if err != nil {
 			log.Printf("Ошибка классификации для %s (ID: %d): %v", item.SourceName, item.ID, err)
@@ -185,7 +197,8 @@ func main() {
 			SET category = ?,
 			    kpved_code = ?,
 			    kpved_name = ?,
-			    kpved_confidence = ?
+			    kpved_confidence = ?,
+			    classification_hash = ?
 			WHERE id = ?
 		`
 
@@ -196,7 +209,7 @@ func main() {
 			kpvedName = aiResponse.CategoryPath[len(aiResponse.CategoryPath)-1]
 		}
 
-		_, err = db.Exec(updateQuery, newCategory, kpvedCode, kpvedName, aiResponse.Confidence, item.ID)
+		_, err = db.Exec(updateQuery, newCategory, kpvedCode, kpvedName, aiResponse.Confidence, contentHash, item.ID)
 		if err != nil {
 			log.Printf("Ошибка обновления для %s (ID: %d): %v", item.SourceName, item.ID, err)
 			errorCount++
@@ -226,7 +239,7 @@ func main() {
 	fmt.Printf("Всего записей: %d\n", totalItems)
 	fmt.Printf("Успешно переклассифицировано: %d\n", successCount)
 	fmt.Printf("Ошибок: %d\n", errorCount)
-	fmt.Printf("Пропущено: %d\n", skippedCount)
+	fmt.Printf("Пропущено (данные не изменились): %d\n", skippedCount)
 	fmt.Printf("Время выполнения: %v\n", elapsed)
 	if successCount > 0 {
 		fmt.Printf("Средняя скорость: %.2f элементов/сек\n", float64(successCount)/elapsed.Seconds())
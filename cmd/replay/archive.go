@@ -0,0 +1,278 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// uploadUUIDPlaceholder подставляется cmd/gen_testdata вместо upload_uuid, который сервер выдает при рукопожатии
+const uploadUUIDPlaceholder = "{{upload_uuid}}"
+
+// rootEndpoints эндпоинты протокола выгрузки по корневому элементу XML запроса
+var rootEndpoints = map[string]string{
+	"handshake":          "/handshake",
+	"metadata":           "/metadata",
+	"constant":           "/constant",
+	"catalog_meta":       "/catalog/meta",
+	"catalog_item":       "/catalog/item",
+	"catalog_items":      "/catalog/items",
+	"catalog_attachment": "/catalog/attachment",
+	"document_item":      "/document/item",
+	"document_items":     "/document/items",
+	"nomenclature_batch": "/api/v1/upload/nomenclature/batch",
+	"complete":           "/complete",
+}
+
+var (
+	uploadUUIDPattern = regexp.MustCompile(`<upload_uuid>\s*([^<\s]+)\s*</upload_uuid>`)
+	timestampPattern  = regexp.MustCompile(`<timestamp>\s*([^<]+?)\s*</timestamp>`)
+)
+
+// Request запрос выгрузки в том виде, в котором его отправила обработка 1С
+type Request struct {
+	Endpoint string
+	File     string
+	SentAt   time.Time // Нулевое - время отправки неизвестно
+	Body     []byte
+}
+
+// Archive запросы одной выгрузки в порядке отправки
+type Archive struct {
+	Source     string
+	UploadUUID string // upload_uuid исходной выгрузки (или заглушка gen_testdata)
+	Requests   []Request
+}
+
+// manifestEntry запись manifest.json (формат cmd/gen_testdata)
+type manifestEntry struct {
+	Endpoint string    `json:"endpoint"`
+	File     string    `json:"file"`
+	SentAt   time.Time `json:"sent_at,omitempty"`
+}
+
+// envelope сообщение очереди приема выгрузок (server.IngestEnvelope) с необязательным временем отправки
+type envelope struct {
+	UploadUUID string    `json:"upload_uuid"`
+	Sequence   int64     `json:"sequence"`
+	Endpoint   string    `json:"endpoint"`
+	Payload    string    `json:"payload"`
+	SentAt     time.Time `json:"sent_at,omitempty"`
+}
+
+// LoadArchive читает запросы выгрузки из архива:
+//   - каталога или zip с manifest.json (порядок и эндпоинты запросов);
+//   - каталога или zip с XML запросами без манифеста: порядок по имени файла, эндпоинт по корневому элементу;
+//   - файла .jsonl с сообщениями очереди приема; uploadUUID выбирает выгрузку, если их в файле несколько
+func LoadArchive(source, uploadUUID string) (*Archive, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var archive *Archive
+	switch {
+	case info.IsDir():
+		archive, err = loadFS(os.DirFS(source))
+	case strings.HasSuffix(strings.ToLower(source), ".zip"):
+		var zr *zip.ReadCloser
+		if zr, err = zip.OpenReader(source); err != nil {
+			return nil, fmt.Errorf("failed to open zip: %w", err)
+		}
+		defer zr.Close()
+		archive, err = loadFS(zr)
+	case strings.HasSuffix(source, ".jsonl") || strings.HasSuffix(source, ".ndjson"):
+		archive, err = loadEnvelopes(source, uploadUUID)
+	default:
+		return nil, fmt.Errorf("unsupported archive %s: expected directory, .zip or .jsonl", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(archive.Requests) == 0 {
+		return nil, fmt.Errorf("archive %s contains no upload requests", source)
+	}
+	if archive.Requests[0].Endpoint != "/handshake" {
+		return nil, fmt.Errorf("archive %s does not start with a handshake (first request: %s)", source, archive.Requests[0].Endpoint)
+	}
+
+	archive.Source = source
+	for i := range archive.Requests {
+		r := &archive.Requests[i]
+		if r.SentAt.IsZero() {
+			r.SentAt = bodyTimestamp(r.Body)
+		}
+		if archive.UploadUUID == "" && r.Endpoint != "/handshake" {
+			if m := uploadUUIDPattern.FindSubmatch(r.Body); m != nil {
+				archive.UploadUUID = string(m[1])
+			}
+		}
+	}
+	return archive, nil
+}
+
+// loadFS читает запросы из каталога или zip; манифест ищется ближе всего к корню архива
+func loadFS(fsys fs.FS) (*Archive, error) {
+	var manifests, files []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch {
+		case path.Base(p) == "manifest.json":
+			manifests = append(manifests, p)
+		case strings.HasSuffix(strings.ToLower(p), ".xml"):
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifests) > 0 {
+		sort.Slice(manifests, func(i, j int) bool {
+			return strings.Count(manifests[i], "/") < strings.Count(manifests[j], "/")
+		})
+		return loadManifest(fsys, manifests[0])
+	}
+
+	sort.Strings(files)
+	archive := &Archive{}
+	for _, file := range files {
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		root := rootElement(body)
+		endpoint, ok := rootEndpoints[root]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown request <%s>", file, root)
+		}
+		archive.Requests = append(archive.Requests, Request{Endpoint: endpoint, File: file, Body: body})
+	}
+	return archive, nil
+}
+
+// loadManifest читает запросы в порядке manifest.json; пути файлов отсчитываются от каталога манифеста
+func loadManifest(fsys fs.FS, manifestPath string) (*Archive, error) {
+	data, err := fs.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var entries []manifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	dir := path.Dir(manifestPath)
+	archive := &Archive{}
+	for _, e := range entries {
+		body, err := fs.ReadFile(fsys, path.Join(dir, e.File))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", e.File, err)
+		}
+		archive.Requests = append(archive.Requests, Request{Endpoint: e.Endpoint, File: e.File, SentAt: e.SentAt, Body: body})
+	}
+	return archive, nil
+}
+
+// loadEnvelopes читает сообщения очереди приема одной выгрузки в порядке sequence
+func loadEnvelopes(source, uploadUUID string) (*Archive, error) {
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	byUpload := map[string][]envelope{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e envelope
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		byUpload[e.UploadUUID] = append(byUpload[e.UploadUUID], e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if uploadUUID == "" {
+		if len(byUpload) > 1 {
+			uploads := make([]string, 0, len(byUpload))
+			for u := range byUpload {
+				uploads = append(uploads, u)
+			}
+			sort.Strings(uploads)
+			return nil, fmt.Errorf("archive contains %d uploads, select one with -upload: %s", len(uploads), strings.Join(uploads, ", "))
+		}
+		for u := range byUpload {
+			uploadUUID = u
+		}
+	}
+	envelopes, ok := byUpload[uploadUUID]
+	if !ok {
+		return nil, fmt.Errorf("upload %s not found in archive", uploadUUID)
+	}
+	sort.SliceStable(envelopes, func(i, j int) bool { return envelopes[i].Sequence < envelopes[j].Sequence })
+
+	archive := &Archive{UploadUUID: uploadUUID}
+	for _, e := range envelopes {
+		endpoint := "/" + strings.TrimPrefix(e.Endpoint, "/")
+		if endpoint == "/nomenclature/batch" {
+			endpoint = "/api/v1/upload/nomenclature/batch"
+		}
+		archive.Requests = append(archive.Requests, Request{
+			Endpoint: endpoint,
+			File:     fmt.Sprintf("%s#%d", path.Base(source), e.Sequence),
+			SentAt:   e.SentAt,
+			Body:     []byte(e.Payload),
+		})
+	}
+	return archive, nil
+}
+
+// rootElement возвращает имя корневого элемента XML
+func rootElement(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// bodyTimestamp время отправки из элемента <timestamp> запроса (нулевое, если его нет)
+func bodyTimestamp(body []byte) time.Time {
+	m := timestampPattern.FindSubmatch(body)
+	if m == nil {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, string(m[1])); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
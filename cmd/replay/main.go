@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	serverURL := flag.String("server", "http://localhost:9999", "Адрес целевого сервера")
+	uploadUUID := flag.String("upload", "", "upload_uuid выгрузки в .jsonl архиве с несколькими выгрузками")
+	speed := flag.Float64("speed", 0, "Темп относительно исходной выгрузки (1 - как в оригинале, 2 - вдвое быстрее; 0 - без пауз)")
+	maxPause := flag.Duration("max-pause", 30*time.Second, "Ограничение одной паузы между запросами (0 - без ограничения)")
+	sessions := flag.Int("sessions", 1, "Сколько раз воспроизвести выгрузку")
+	concurrency := flag.Int("concurrency", 1, "Одновременно воспроизводимых выгрузок")
+	sandbox := flag.Bool("sandbox", false, "Воспроизводить как тестовые выгрузки, не попадающие в рабочие БД")
+	keepGoing := flag.Bool("keep-going", false, "Продолжать выгрузку после ошибочного ответа сервера")
+	timeout := flag.Duration("timeout", 5*time.Minute, "Таймаут одного запроса")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Использование: %s [флаги] <архив>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Архив - каталог или zip с manifest.json (формат cmd/gen_testdata) или XML запросами выгрузки,")
+		fmt.Fprintln(os.Stderr, "либо .jsonl с сообщениями очереди приема выгрузок.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *speed < 0 || *sessions <= 0 || *concurrency <= 0 {
+		log.Fatal("-speed не может быть отрицательным, -sessions и -concurrency должны быть больше 0")
+	}
+
+	archive, err := LoadArchive(flag.Arg(0), *uploadUUID)
+	if err != nil {
+		log.Fatalf("Ошибка чтения архива: %v", err)
+	}
+	var payloadBytes int
+	for _, req := range archive.Requests {
+		payloadBytes += len(req.Body)
+	}
+	fmt.Fprintf(os.Stderr, "Архив %s: %d запросов (%d байт), исходная выгрузка %s\n",
+		archive.Source, len(archive.Requests), payloadBytes, archive.UploadUUID)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	replayer := NewReplayer(archive, ReplayConfig{
+		ServerURL:   *serverURL,
+		Speed:       *speed,
+		MaxPause:    *maxPause,
+		Sessions:    *sessions,
+		Concurrency: *concurrency,
+		Sandbox:     *sandbox,
+		KeepGoing:   *keepGoing,
+		Timeout:     *timeout,
+	})
+	start := time.Now()
+	results := replayer.Run(ctx)
+	elapsed := time.Since(start)
+
+	failed := 0
+	for _, r := range results {
+		if r.Session == 0 {
+			continue // Не запущена из-за прерывания
+		}
+		status := "ok"
+		if r.Err != nil {
+			failed++
+			status = r.Err.Error()
+		}
+		fmt.Printf("Выгрузка %d: %s, запросов %d (ошибок %d) за %v: %s\n",
+			r.Session, r.UploadUUID, r.Sent, r.Failed, r.Duration.Round(time.Millisecond), status)
+	}
+
+	fmt.Printf("\n%-36s %8s %7s %12s %10s %10s %10s %10s\n", "endpoint", "requests", "failed", "bytes", "avg", "p50", "p95", "max")
+	var total int
+	for _, s := range replayer.Stats() {
+		total += s.Requests
+		fmt.Printf("%-36s %8d %7d %12d %10v %10v %10v %10v\n", s.Endpoint, s.Requests, s.Failed, s.Bytes,
+			s.Avg().Round(time.Microsecond), s.Percentile(0.5).Round(time.Microsecond),
+			s.Percentile(0.95).Round(time.Microsecond), s.Percentile(1).Round(time.Microsecond))
+	}
+	if elapsed > 0 {
+		fmt.Printf("\nОтправлено %d запросов на %s за %v (%.1f запросов/с)\n", total, *serverURL, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
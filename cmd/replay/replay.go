@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReplayConfig параметры воспроизведения
+type ReplayConfig struct {
+	ServerURL   string
+	Speed       float64       // Множитель исходного темпа (2 - вдвое быстрее); 0 - без пауз
+	MaxPause    time.Duration // Ограничение одной паузы между запросами (0 - без ограничения)
+	Sessions    int           // Сколько раз воспроизвести выгрузку
+	Concurrency int           // Одновременно воспроизводимых выгрузок
+	Sandbox     bool          // Воспроизводить как тестовые выгрузки (sandbox в рукопожатии)
+	KeepGoing   bool          // Продолжать выгрузку после ошибочного ответа
+	Timeout     time.Duration // Таймаут одного запроса
+}
+
+// SessionResult итог воспроизведения одной копии выгрузки
+type SessionResult struct {
+	Session    int
+	UploadUUID string
+	Sent       int
+	Failed     int
+	Duration   time.Duration
+	Err        error
+}
+
+// EndpointStats время ответа сервера по эндпоинту
+type EndpointStats struct {
+	Endpoint  string
+	Requests  int
+	Failed    int
+	Bytes     int64
+	latencies []time.Duration
+}
+
+// Avg среднее время ответа
+func (e *EndpointStats) Avg() time.Duration {
+	if len(e.latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, l := range e.latencies {
+		total += l
+	}
+	return total / time.Duration(len(e.latencies))
+}
+
+// Percentile время ответа, которое не превышают p (0-1) запросов
+func (e *EndpointStats) Percentile(p float64) time.Duration {
+	if len(e.latencies) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), e.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// Replayer воспроизводит запросы архива на целевом сервере
+type Replayer struct {
+	config  ReplayConfig
+	archive *Archive
+	client  *http.Client
+
+	mu    sync.Mutex
+	stats map[string]*EndpointStats
+}
+
+// NewReplayer создает воспроизведение архива
+func NewReplayer(archive *Archive, config ReplayConfig) *Replayer {
+	if config.Sessions <= 0 {
+		config.Sessions = 1
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	config.ServerURL = strings.TrimRight(config.ServerURL, "/")
+	return &Replayer{
+		config:  config,
+		archive: archive,
+		client:  &http.Client{Timeout: config.Timeout},
+		stats:   make(map[string]*EndpointStats),
+	}
+}
+
+// Run воспроизводит выгрузку config.Sessions раз, не более config.Concurrency одновременно
+func (r *Replayer) Run(ctx context.Context) []SessionResult {
+	results := make([]SessionResult, r.config.Sessions)
+	sessions := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < r.config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for session := range sessions {
+				results[session] = r.replaySession(ctx, session)
+			}
+		}()
+	}
+	for session := 0; session < r.config.Sessions; session++ {
+		select {
+		case sessions <- session:
+		case <-ctx.Done():
+		}
+	}
+	close(sessions)
+	wg.Wait()
+	return results
+}
+
+// Stats время ответа по эндпоинтам в порядке их первого появления в архиве
+func (r *Replayer) Stats() []*EndpointStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*EndpointStats
+	seen := map[string]bool{}
+	for _, req := range r.archive.Requests {
+		if stats, ok := r.stats[req.Endpoint]; ok && !seen[req.Endpoint] {
+			seen[req.Endpoint] = true
+			result = append(result, stats)
+		}
+	}
+	return result
+}
+
+// replaySession отправляет запросы архива по порядку, каждый пакет - тем же телом, что и в исходной выгрузке.
+// upload_uuid исходной выгрузки заменяется выданным сервером при рукопожатии
+func (r *Replayer) replaySession(ctx context.Context, session int) (result SessionResult) {
+	result.Session = session + 1
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	for i, req := range r.archive.Requests {
+		if i > 0 {
+			if err := r.pause(ctx, r.archive.Requests[i-1].SentAt, req.SentAt); err != nil {
+				result.Err = err
+				return result
+			}
+		}
+
+		body := req.Body
+		if req.Endpoint == "/handshake" {
+			body = r.prepareHandshake(body)
+		}
+		if result.UploadUUID != "" {
+			body = bytes.ReplaceAll(body, []byte(uploadUUIDPlaceholder), []byte(result.UploadUUID))
+			if r.archive.UploadUUID != "" {
+				body = bytes.ReplaceAll(body, []byte(r.archive.UploadUUID), []byte(result.UploadUUID))
+			}
+		}
+
+		status, respBody, err := r.send(ctx, req.Endpoint, body)
+		result.Sent++
+		if err == nil && status != http.StatusOK {
+			err = fmt.Errorf("%s returned %d: %s", req.Endpoint, status, strings.TrimSpace(string(respBody)))
+		}
+		if err != nil {
+			result.Failed++
+			if result.Err == nil {
+				result.Err = fmt.Errorf("%s: %w", req.File, err)
+			}
+			if !r.config.KeepGoing || req.Endpoint == "/handshake" {
+				return result
+			}
+			continue
+		}
+
+		if req.Endpoint == "/handshake" {
+			var handshake struct {
+				UploadUUID string `xml:"upload_uuid"`
+			}
+			if err := xml.Unmarshal(respBody, &handshake); err != nil || handshake.UploadUUID == "" {
+				result.Err = fmt.Errorf("handshake response has no upload_uuid: %s", strings.TrimSpace(string(respBody)))
+				return result
+			}
+			result.UploadUUID = handshake.UploadUUID
+		}
+	}
+	return result
+}
+
+// prepareHandshake заменяет заданный клиентом upload_uuid новым, чтобы копии не конфликтовали с исходной
+// выгрузкой, и при необходимости помечает выгрузку как тестовую
+func (r *Replayer) prepareHandshake(body []byte) []byte {
+	body = uploadUUIDPattern.ReplaceAllFunc(body, func([]byte) []byte {
+		return []byte("<upload_uuid>" + uuid.New().String() + "</upload_uuid>")
+	})
+	if r.config.Sandbox && !bytes.Contains(body, []byte("<sandbox>")) {
+		if i := bytes.LastIndex(body, []byte("</handshake>")); i >= 0 {
+			body = append(append(append([]byte(nil), body[:i]...), "<sandbox>true</sandbox>"...), body[i:]...)
+		}
+	}
+	return body
+}
+
+// pause выдерживает исходный интервал между запросами с учетом скорости воспроизведения
+func (r *Replayer) pause(ctx context.Context, prev, next time.Time) error {
+	if r.config.Speed <= 0 || prev.IsZero() || next.IsZero() || !next.After(prev) {
+		return ctx.Err()
+	}
+	delay := time.Duration(float64(next.Sub(prev)) / r.config.Speed)
+	if r.config.MaxPause > 0 && delay > r.config.MaxPause {
+		delay = r.config.MaxPause
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send отправляет запрос и учитывает время ответа
+func (r *Replayer) send(ctx context.Context, endpoint string, body []byte) (int, []byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.config.ServerURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	start := time.Now()
+	resp, err := r.client.Do(httpReq)
+	var status int
+	var respBody []byte
+	if err == nil {
+		status = resp.StatusCode
+		respBody, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	latency := time.Since(start)
+
+	r.mu.Lock()
+	stats, ok := r.stats[endpoint]
+	if !ok {
+		stats = &EndpointStats{Endpoint: endpoint}
+		r.stats[endpoint] = stats
+	}
+	stats.Requests++
+	stats.Bytes += int64(len(body))
+	if err != nil || status != http.StatusOK {
+		stats.Failed++
+	} else {
+		stats.latencies = append(stats.latencies, latency)
+	}
+	r.mu.Unlock()
+	return status, respBody, err
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+const usage = `Использование: review_patterns [-session имя] [-reviewer имя] [-limit N] [-retry-skipped] <путь_к_базе.db>
+       review_patterns -session имя -export исправления.csv <путь_к_базе.db>
+       review_patterns -session имя -apply <путь_к_базе.db>
+Поочередно показывает в терминале предложения исправления наименований (алгоритм и AI при ARLIAI_API_KEY).
+Клавиши: a - принять, e - исправить вручную, s - пропустить, q - выйти.
+Решения сохраняются в pattern_review_decisions; повторный запуск с той же сессией продолжает проверку.
+Принятые исправления сессии выгружаются в CSV (-export) или применяются к catalog_items (-apply).
+`
+
+// suggestion предложение исправления наименования
+type suggestion struct {
+	name       string
+	fix        string
+	source     string
+	confidence float64
+	reasoning  string
+	warning    string // Ошибка AI, из-за которой показано алгоритмическое исправление
+	matches    []normalization.PatternMatch
+}
+
+func main() {
+	session := flag.String("session", "default", "Имя сессии проверки")
+	reviewer := flag.String("reviewer", os.Getenv("USER"), "Имя проверяющего")
+	limit := flag.Int("limit", 0, "Максимум наименований для проверки (0 - все)")
+	retrySkipped := flag.Bool("retry-skipped", false, "Показать повторно пропущенные наименования")
+	exportPath := flag.String("export", "", "Выгрузить принятые исправления сессии в CSV и выйти")
+	apply := flag.Bool("apply", false, "Применить принятые исправления сессии к catalog_items и выйти")
+	flag.Usage = func() { fmt.Print(usage) }
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	db, err := database.NewDB(flag.Arg(0))
+	if err != nil {
+		log.Fatalf("Ошибка подключения: %v", err)
+	}
+	defer db.Close()
+
+	if *exportPath != "" || *apply {
+		if *exportPath != "" {
+			count, err := exportCorrections(db, *session, *exportPath)
+			if err != nil {
+				log.Fatalf("Ошибка выгрузки исправлений: %v", err)
+			}
+			fmt.Printf("Сессия %q: выгружено исправлений %d в %s\n", *session, count, *exportPath)
+		}
+		if *apply {
+			updated, err := db.ApplyPatternReviewCorrections(*session)
+			if err != nil {
+				log.Fatalf("Ошибка применения исправлений: %v", err)
+			}
+			fmt.Printf("Сессия %q: исправлено записей catalog_items %d\n", *session, updated)
+		}
+		return
+	}
+
+	detector := normalization.NewPatternDetector()
+	var aiIntegrator *normalization.PatternAIIntegrator
+	if apiKey := os.Getenv("ARLIAI_API_KEY"); apiKey != "" {
+		aiIntegrator = normalization.NewPatternAIIntegrator(detector, normalization.NewAINormalizer(apiKey))
+		fmt.Println("✓ AI интегратор инициализирован")
+	} else {
+		fmt.Println("⚠ ARLIAI_API_KEY не установлен, показываются только алгоритмические исправления")
+	}
+
+	decided, err := db.GetPatternReviewDecisions(*session)
+	if err != nil {
+		log.Fatalf("Ошибка чтения решений: %v", err)
+	}
+	names, err := loadNames(db)
+	if err != nil {
+		log.Fatalf("Ошибка запроса: %v", err)
+	}
+
+	// Наименования без решения в сессии; пропущенные - только при -retry-skipped
+	var pending []string
+	for _, name := range names {
+		decision, ok := decided[name]
+		if ok && !(*retrySkipped && decision == database.PatternDecisionSkip) {
+			continue
+		}
+		pending = append(pending, name)
+	}
+	if len(pending) == 0 {
+		fmt.Printf("Сессия %q: решений %d, наименований к проверке нет\n", *session, len(decided))
+		return
+	}
+
+	restore, err := enableRawMode(int(os.Stdin.Fd()))
+	if err != nil {
+		log.Fatalf("Проверка требует интерактивного терминала: %v", err)
+	}
+	screen := &reviewScreen{
+		in:      bufio.NewReader(os.Stdin),
+		out:     os.Stdout,
+		session: *session,
+		counts:  make(map[string]int),
+	}
+	err = review(db, screen, detector, aiIntegrator, pending, *reviewer, *limit)
+	restore()
+	fmt.Print("\033[H\033[2J")
+	if err != nil {
+		log.Printf("Проверка прервана: %v", err)
+	}
+	printTotals(*session, screen.counts)
+}
+
+// review проводит проверку наименований до конца списка, лимита или выхода проверяющего.
+// Наименования без предложенных изменений фиксируются решением unchanged без показа
+func review(db *database.DB, screen *reviewScreen, detector *normalization.PatternDetector,
+	aiIntegrator *normalization.PatternAIIntegrator, pending []string, reviewer string, limit int) error {
+	shown := 0
+	for i, name := range pending {
+		if limit > 0 && shown >= limit {
+			return nil
+		}
+		screen.showProgress(i+1, len(pending), name)
+		s := suggest(detector, aiIntegrator, name)
+
+		decision := &database.PatternReviewDecision{
+			Session:      screen.session,
+			OriginalName: name,
+			Suggestion:   s.fix,
+			Source:       s.source,
+			Confidence:   s.confidence,
+			Reviewer:     reviewer,
+		}
+		if s.fix == "" || s.fix == name {
+			decision.Suggestion = name
+			decision.Decision = database.PatternDecisionUnchanged
+		} else {
+			shown++
+			action, err := decide(screen, i+1, len(pending), s, decision)
+			if err != nil {
+				return err
+			}
+			if action == actionQuit {
+				return nil
+			}
+		}
+		if err := db.SavePatternReviewDecision(decision); err != nil {
+			return fmt.Errorf("ошибка сохранения решения: %w", err)
+		}
+		screen.counts[decision.Decision]++
+	}
+	return nil
+}
+
+// decide получает решение проверяющего; отмена исправления возвращает к выбору действия
+func decide(screen *reviewScreen, position, total int, s suggestion, decision *database.PatternReviewDecision) (string, error) {
+	for {
+		action, err := screen.choose(position, total, s)
+		if err != nil {
+			return "", err
+		}
+		switch action {
+		case actionAccept:
+			decision.Decision = database.PatternDecisionAccept
+		case actionSkip:
+			decision.Decision = database.PatternDecisionSkip
+		case actionEdit:
+			edited, ok, err := screen.editLine(position, total, s)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				continue
+			}
+			decision.Decision = database.PatternDecisionEdit
+			decision.FinalName = edited
+		}
+		return action, nil
+	}
+}
+
+// exportCorrections выгружает принятые и исправленные наименования сессии в CSV
+func exportCorrections(db *database.DB, session, path string) (int, error) {
+	corrections, err := db.ListPatternReviewCorrections(session)
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"original_name", "final_name", "decision", "source", "confidence", "reviewer", "decided_at"})
+	for _, c := range corrections {
+		writer.Write([]string{c.OriginalName, c.FinalName, c.Decision, c.Source,
+			strconv.FormatFloat(c.Confidence, 'f', 2, 64), c.Reviewer, c.DecidedAt.Format(time.RFC3339)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return 0, err
+	}
+	return len(corrections), file.Close()
+}
+
+// loadNames возвращает уникальные наименования справочников в постоянном порядке
+func loadNames(db *database.DB) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT name
+		FROM catalog_items
+		WHERE name IS NOT NULL AND name != ''
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// suggest возвращает предложение AI, а при его отсутствии или ошибке - алгоритмическое исправление
+func suggest(detector *normalization.PatternDetector, aiIntegrator *normalization.PatternAIIntegrator, name string) suggestion {
+	var warning string
+	if aiIntegrator != nil {
+		result, err := aiIntegrator.SuggestCorrectionWithAI(name)
+		if err == nil {
+			source := "algorithmic"
+			if result.AISuggestedFix != "" && result.FinalSuggestion == result.AISuggestedFix {
+				source = "ai"
+			}
+			return suggestion{
+				name:       name,
+				fix:        result.FinalSuggestion,
+				source:     source,
+				confidence: result.Confidence,
+				reasoning:  result.Reasoning,
+				matches:    result.DetectedPatterns,
+			}
+		}
+		warning = fmt.Sprintf("Ошибка AI: %v", err)
+	}
+	matches := detector.DetectPatterns(name)
+	if len(matches) == 0 {
+		return suggestion{name: name, warning: warning}
+	}
+	return suggestion{name: name, fix: detector.ApplyFixes(name, matches), source: "algorithmic", warning: warning, matches: matches}
+}
+
+// printTotals выводит итоги запуска
+func printTotals(session string, counts map[string]int) {
+	fmt.Printf("\nСессия %q: принято %d, исправлено %d, пропущено %d, без изменений %d\n", session,
+		counts[database.PatternDecisionAccept], counts[database.PatternDecisionEdit],
+		counts[database.PatternDecisionSkip], counts[database.PatternDecisionUnchanged])
+	fmt.Printf("Продолжить проверку: review_patterns -session %s <путь_к_базе.db>\n", session)
+	fmt.Printf("Применить исправления: review_patterns -session %s -apply <путь_к_базе.db>\n", session)
+}
@@ -0,0 +1,22 @@
+//go:build linux
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// enableRawMode переводит терминал в посимвольный ввод без эха и возвращает функцию восстановления режима
+func enableRawMode(fd int) (func(), error) {
+	saved, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+	raw := *saved
+	raw.Lflag &^= unix.ECHO | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Iflag &^= unix.IXON | unix.ICRNL
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+	return func() { unix.IoctlSetTermios(fd, unix.TCSETS, saved) }, nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// enableRawMode посимвольный ввод реализован только для терминалов Linux
+func enableRawMode(fd int) (func(), error) {
+	return nil, errors.New("интерактивная проверка поддерживается только в терминале Linux")
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"httpserver/database"
+)
+
+// Управляющие клавиши терминала в посимвольном режиме
+const (
+	keyCtrlC     = 3
+	keyCtrlU     = 21
+	keyBackspace = 127
+	keyCtrlH     = 8
+	keyEscape    = 27
+	keyEnter     = '\r'
+)
+
+// Действия проверяющего над предложением
+const (
+	actionAccept = "accept"
+	actionEdit   = "edit"
+	actionSkip   = "skip"
+	actionQuit   = "quit"
+)
+
+// reviewScreen полноэкранный интерфейс проверки: перерисовывает карточку наименования
+// и читает клавиши без Enter, пустой или неизвестный ввод не принимается как решение
+type reviewScreen struct {
+	in      *bufio.Reader
+	out     io.Writer
+	session string
+	counts  map[string]int
+	status  string // Подсказка после неверного ввода, показывается до следующей перерисовки
+}
+
+// showProgress выводит экран ожидания, пока алгоритм и AI готовят предложение
+func (sc *reviewScreen) showProgress(position, total int, name string) {
+	sc.header(position, total)
+	fmt.Fprintf(sc.out, "  %s\n\n  Подготовка предложения...\n", name)
+}
+
+// header очищает экран и выводит сессию, прогресс и итоги решений
+func (sc *reviewScreen) header(position, total int) {
+	fmt.Fprint(sc.out, "\033[H\033[2J")
+	fmt.Fprintf(sc.out, "\033[1mПроверка наименований\033[0m  сессия %q  [%d/%d]\n", sc.session, position, total)
+	fmt.Fprintf(sc.out, "принято %d  исправлено %d  пропущено %d  без изменений %d\n",
+		sc.counts[database.PatternDecisionAccept], sc.counts[database.PatternDecisionEdit],
+		sc.counts[database.PatternDecisionSkip], sc.counts[database.PatternDecisionUnchanged])
+	fmt.Fprintln(sc.out, strings.Repeat("─", 72))
+}
+
+// render выводит карточку предложения
+func (sc *reviewScreen) render(position, total int, s suggestion) {
+	sc.header(position, total)
+	fmt.Fprintf(sc.out, "  Исходное:    %s\n", s.name)
+	fmt.Fprintf(sc.out, "  Предложение: \033[32m%s\033[0m\n", s.fix)
+	fmt.Fprintf(sc.out, "  Источник:    %s", s.source)
+	if s.confidence > 0 {
+		fmt.Fprintf(sc.out, ", уверенность %.2f", s.confidence)
+	}
+	fmt.Fprintln(sc.out)
+	if s.reasoning != "" {
+		fmt.Fprintf(sc.out, "  Обоснование: %s\n", s.reasoning)
+	}
+	if s.warning != "" {
+		fmt.Fprintf(sc.out, "  \033[33m⚠ %s\033[0m\n", s.warning)
+	}
+	if len(s.matches) > 0 {
+		fmt.Fprintln(sc.out, "\n  Найденные паттерны:")
+		for _, match := range s.matches {
+			fmt.Fprintf(sc.out, "    - [%s] %s: '%s'\n", match.Severity, match.Description, match.MatchedText)
+		}
+	}
+	fmt.Fprintln(sc.out, strings.Repeat("─", 72))
+	fmt.Fprintln(sc.out, "  a - принять   e - исправить   s - пропустить   q - выйти")
+	sc.flushStatus()
+}
+
+// flushStatus выводит и сбрасывает подсказку
+func (sc *reviewScreen) flushStatus() {
+	if sc.status != "" {
+		fmt.Fprintf(sc.out, "  \033[31m%s\033[0m\n", sc.status)
+		sc.status = ""
+	}
+}
+
+// choose показывает предложение и ждет клавишу действия; Enter и прочие клавиши не принимаются
+func (sc *reviewScreen) choose(position, total int, s suggestion) (string, error) {
+	for {
+		sc.render(position, total, s)
+		key, err := sc.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch unicode.ToLower(key) {
+		case 'a', 'ф':
+			return actionAccept, nil
+		case 'e', 'у':
+			return actionEdit, nil
+		case 's', 'ы':
+			return actionSkip, nil
+		case 'q', 'й', keyCtrlC:
+			return actionQuit, nil
+		default:
+			sc.status = "Выберите действие: a, e, s или q"
+		}
+	}
+}
+
+// editLine редактирует наименование, начиная с предложения. Enter с пустой строкой не принимается,
+// Esc отменяет исправление и возвращает к выбору действия
+func (sc *reviewScreen) editLine(position, total int, s suggestion) (string, bool, error) {
+	buffer := []rune(s.fix)
+	for {
+		sc.render(position, total, s)
+		fmt.Fprintln(sc.out, "  Enter - сохранить   Esc - отмена   Ctrl+U - очистить")
+		fmt.Fprintf(sc.out, "\n  Новое наименование: %s", string(buffer))
+		key, err := sc.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch key {
+		case keyEnter, '\n':
+			edited := strings.TrimSpace(string(buffer))
+			if edited == "" {
+				sc.status = "Наименование не может быть пустым"
+				continue
+			}
+			return edited, true, nil
+		case keyEscape, keyCtrlC:
+			return "", false, nil
+		case keyBackspace, keyCtrlH:
+			if len(buffer) > 0 {
+				buffer = buffer[:len(buffer)-1]
+			}
+		case keyCtrlU:
+			buffer = buffer[:0]
+		default:
+			if unicode.IsPrint(key) {
+				buffer = append(buffer, key)
+			}
+		}
+	}
+}
+
+// readKey читает нажатую клавишу; последовательности стрелок и функциональных клавиш пропускаются
+func (sc *reviewScreen) readKey() (rune, error) {
+	for {
+		key, _, err := sc.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if key != keyEscape || sc.in.Buffered() == 0 {
+			return key, nil
+		}
+		// ESC [ ... буква или ~
+		if next, _ := sc.in.Peek(1); len(next) == 0 || (next[0] != '[' && next[0] != 'O') {
+			return key, nil
+		}
+		sc.in.ReadByte()
+		for sc.in.Buffered() > 0 {
+			b, err := sc.in.ReadByte()
+			if err != nil || (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || b == '~' {
+				break
+			}
+		}
+	}
+}
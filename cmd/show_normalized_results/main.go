@@ -1,23 +1,42 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"httpserver/database"
+	"httpserver/reports"
 )
 
+const usage = `Использование: show_normalized_results [-format text|csv|tsv|json] [-dataset набор] <путь_к_базе.db> [limit]
+Наборы данных для -format csv|tsv|json: %s (по умолчанию normalized)
+`
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Использование: show_normalized_results <путь_к_базе.db> [limit]")
+	format := flag.String("format", reports.FormatText, "Формат вывода: text, csv, tsv, json")
+	dataset := flag.String("dataset", reports.DatasetNormalized, "Набор данных для форматов csv, tsv, json")
+	flag.Usage = func() { fmt.Printf(usage, strings.Join(reports.InspectionDatasets, ", ")) }
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
 		os.Exit(1)
 	}
+	outputFormat, err := reports.ParseOutputFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	dbPath := os.Args[1]
+	dbPath := flag.Arg(0)
 	limit := 20
-	if len(os.Args) >= 3 {
-		fmt.Sscanf(os.Args[2], "%d", &limit)
+	if flag.NArg() >= 2 {
+		if limit, err = strconv.Atoi(flag.Arg(1)); err != nil || limit <= 0 {
+			log.Fatalf("Некорректный limit: %s", flag.Arg(1))
+		}
 	}
 
 	db, err := database.NewDB(dbPath)
@@ -26,147 +45,102 @@ func main() {
 	}
 	defer db.Close()
 
+	provider := reports.NewProvider(db.GetDB(), nil)
+	if outputFormat != reports.FormatText {
+		table, err := provider.InspectionTable(*dataset, limit)
+		if err != nil {
+			log.Fatalf("Ошибка получения данных: %v", err)
+		}
+		if err := reports.WriteTable(os.Stdout, outputFormat, table); err != nil {
+			log.Fatalf("Ошибка вывода: %v", err)
+		}
+		return
+	}
+
 	fmt.Println("╔══════════════════════════════════════════════════════════════╗")
 	fmt.Println("║          РЕЗУЛЬТАТЫ НОРМАЛИЗАЦИИ И КЛАССИФИКАЦИИ             ║")
 	fmt.Println("╚══════════════════════════════════════════════════════════════╝")
 	fmt.Println()
 
-	// Проверяем normalized_data
-	var normalizedCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM normalized_data").Scan(&normalizedCount)
+	summary, err := provider.Summary()
 	if err != nil {
-		normalizedCount = 0
+		log.Fatalf("Ошибка получения статистики: %v", err)
 	}
+	catalog, err := provider.CatalogClassification()
+	if err != nil {
+		log.Fatalf("Ошибка получения статистики справочников: %v", err)
+	}
+
+	if summary.NormalizedCount > 0 {
+		fmt.Printf("✅ Найдено нормализованных записей: %d\n\n", summary.NormalizedCount)
 
-	if normalizedCount > 0 {
-		fmt.Printf("✅ Найдено нормализованных записей: %d\n\n", normalizedCount)
-
-		// Получаем примеры через прямой запрос
-		query := `
-			SELECT id, source_reference, source_name, code, normalized_name,
-			       normalized_reference, category, merged_count, 
-			       COALESCE(ai_confidence, 0) as ai_confidence,
-			       COALESCE(ai_reasoning, '') as ai_reasoning,
-			       COALESCE(processing_level, '') as processing_level,
-			       COALESCE(kpved_code, '') as kpved_code,
-			       COALESCE(kpved_name, '') as kpved_name,
-			       COALESCE(kpved_confidence, 0) as kpved_confidence,
-			       created_at
-			FROM normalized_data
-			ORDER BY id
-			LIMIT ?
-		`
-		rows, err := db.Query(query, limit)
+		records, err := provider.NormalizedRecords(limit)
 		if err != nil {
 			log.Printf("Ошибка получения нормализованных записей: %v", err)
-		} else {
-			defer rows.Close()
-			count := 0
-			for rows.Next() {
-				var id, mergedCount int
-				var sourceRef, sourceName, code, normalizedName, normalizedRef, category string
-				var aiConfidence, kpvedConfidence float64
-				var aiReasoning, processingLevel, kpvedCode, kpvedName string
-				var createdAt string
-				
-				if err := rows.Scan(&id, &sourceRef, &sourceName, &code, &normalizedName,
-					&normalizedRef, &category, &mergedCount, &aiConfidence, &aiReasoning,
-					&processingLevel, &kpvedCode, &kpvedName, &kpvedConfidence, &createdAt); err != nil {
-					log.Printf("Ошибка сканирования: %v", err)
-					continue
-				}
-				
-				count++
-				if count == 1 {
-					fmt.Printf("📋 Примеры нормализованных записей:\n\n")
-				}
-				
-				fmt.Printf("═══ Пример #%d ═══\n", count)
-				fmt.Printf("Исходное название: %s\n", sourceName)
-				if normalizedName != "" && normalizedName != sourceName {
-					fmt.Printf("Нормализованное:   %s\n", normalizedName)
-				}
-				if code != "" {
-					fmt.Printf("Код:               %s\n", code)
-				}
-				if category != "" {
-					fmt.Printf("Категория:         %s\n", category)
-				}
-				if kpvedCode != "" {
-					fmt.Printf("КПВЭД код:         %s\n", kpvedCode)
-				}
-				if kpvedName != "" {
-					fmt.Printf("КПВЭД название:    %s\n", kpvedName)
-				}
-				if kpvedConfidence > 0 {
-					fmt.Printf("Уверенность КПВЭД: %.1f%%\n", kpvedConfidence*100)
-				}
-				if aiConfidence > 0 {
-					fmt.Printf("Уверенность AI:    %.1f%%\n", aiConfidence*100)
-				}
-				if mergedCount > 0 {
-					fmt.Printf("Объединено записей: %d\n", mergedCount)
-				}
-				fmt.Println()
+		}
+		if len(records) > 0 {
+			fmt.Printf("📋 Примеры нормализованных записей:\n\n")
+		}
+		for i, record := range records {
+			fmt.Printf("═══ Пример #%d ═══\n", i+1)
+			fmt.Printf("Исходное название: %s\n", record.SourceName)
+			if record.NormalizedName != "" && record.NormalizedName != record.SourceName {
+				fmt.Printf("Нормализованное:   %s\n", record.NormalizedName)
 			}
-			
-			if count > 0 {
-				// Статистика
-				fmt.Println("📊 Статистика:")
-				var avgConfidence float64
-				db.QueryRow("SELECT AVG(ai_confidence) FROM normalized_data WHERE ai_confidence > 0").Scan(&avgConfidence)
-				if avgConfidence > 0 {
-					fmt.Printf("   Средняя уверенность AI: %.1f%%\n", avgConfidence*100)
-				}
+			if record.Code != "" {
+				fmt.Printf("Код:               %s\n", record.Code)
+			}
+			if record.Category != "" {
+				fmt.Printf("Категория:         %s\n", record.Category)
+			}
+			if record.KpvedCode != "" {
+				fmt.Printf("КПВЭД код:         %s\n", record.KpvedCode)
+			}
+			if record.KpvedName != "" {
+				fmt.Printf("КПВЭД название:    %s\n", record.KpvedName)
+			}
+			if record.KpvedConfidence > 0 {
+				fmt.Printf("Уверенность КПВЭД: %.1f%%\n", record.KpvedConfidence*100)
+			}
+			if record.AIConfidence > 0 {
+				fmt.Printf("Уверенность AI:    %.1f%%\n", record.AIConfidence*100)
+			}
+			if record.MergedCount > 0 {
+				fmt.Printf("Объединено записей: %d\n", record.MergedCount)
+			}
+			fmt.Println()
+		}
 
-				var kpvedCount int
-				db.QueryRow("SELECT COUNT(*) FROM normalized_data WHERE kpved_code IS NOT NULL AND kpved_code != ''").Scan(&kpvedCount)
-				fmt.Printf("   С классификацией КПВЭД: %d (%.1f%%)\n", kpvedCount, float64(kpvedCount)/float64(normalizedCount)*100)
+		if len(records) > 0 {
+			fmt.Println("📊 Статистика:")
+			if summary.AvgAIConfidence > 0 {
+				fmt.Printf("   Средняя уверенность AI: %.1f%%\n", summary.AvgAIConfidence*100)
 			}
+			fmt.Printf("   С классификацией КПВЭД: %d (%.1f%%)\n", summary.KpvedCount, summary.KpvedPercent)
 		}
 	} else {
 		fmt.Println("⚠ Нормализованные записи не найдены в таблице normalized_data")
 		fmt.Println()
 	}
 
-	// Проверяем catalog_items с нормализованными именами
-	var catalogNormalizedCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM catalog_items WHERE normalized_name IS NOT NULL AND normalized_name != ''").Scan(&catalogNormalizedCount)
-	if err != nil {
-		catalogNormalizedCount = 0
-	}
+	if catalog.NormalizedNames > 0 {
+		fmt.Printf("✅ Нормализованных названий в catalog_items: %d\n\n", catalog.NormalizedNames)
 
-	if catalogNormalizedCount > 0 {
-		fmt.Printf("✅ Нормализованных названий в catalog_items: %d\n\n", catalogNormalizedCount)
-		
-		// Примеры
-		query := `
-			SELECT id, code, name, normalized_name, category_level1, category_level2
-			FROM catalog_items
-			WHERE normalized_name IS NOT NULL AND normalized_name != '' AND normalized_name != name
-			LIMIT ?
-		`
-		rows, err := db.Query(query, limit)
-		if err == nil {
-			defer rows.Close()
-			fmt.Println("📝 Примеры нормализации названий:")
-			count := 0
-			for rows.Next() && count < limit {
-				var id int
-				var code, name, normalized, level1, level2 string
-				if err := rows.Scan(&id, &code, &name, &normalized, &level1, &level2); err == nil {
-					count++
-					fmt.Printf("\n   %d. [%s]\n", count, code)
-					fmt.Printf("      Исходное:     %s\n", name)
-					fmt.Printf("      Нормализованное: %s\n", normalized)
-					if level1 != "" {
-						fmt.Printf("      Категория:    %s", level1)
-						if level2 != "" {
-							fmt.Printf(" / %s", level2)
-						}
-						fmt.Println()
-					}
+		names, err := provider.NormalizedNames(limit)
+		if err != nil {
+			log.Printf("Ошибка получения нормализованных названий: %v", err)
+		}
+		fmt.Println("📝 Примеры нормализации названий:")
+		for i, name := range names {
+			fmt.Printf("\n   %d. [%s]\n", i+1, name.Code)
+			fmt.Printf("      Исходное:     %s\n", name.Name)
+			fmt.Printf("      Нормализованное: %s\n", name.NormalizedName)
+			if name.CategoryLevel1 != "" {
+				fmt.Printf("      Категория:    %s", name.CategoryLevel1)
+				if name.CategoryLevel2 != "" {
+					fmt.Printf(" / %s", name.CategoryLevel2)
 				}
+				fmt.Println()
 			}
 		}
 		fmt.Println()
@@ -176,17 +150,9 @@ func main() {
 	fmt.Println("╔══════════════════════════════════════════════════════════════╗")
 	fmt.Println("║                    ИТОГОВАЯ СВОДКА                           ║")
 	fmt.Println("╚══════════════════════════════════════════════════════════════╝")
-	
-	var totalCatalogItems int
-	db.QueryRow("SELECT COUNT(*) FROM catalog_items").Scan(&totalCatalogItems)
-	
-	var classifiedCount int
-	db.QueryRow("SELECT COUNT(*) FROM catalog_items WHERE category_level1 IS NOT NULL AND category_level1 != ''").Scan(&classifiedCount)
-	
-	fmt.Printf("📦 Всего элементов в catalog_items: %d\n", totalCatalogItems)
-	fmt.Printf("✅ Классифицировано: %d (%.1f%%)\n", classifiedCount, float64(classifiedCount)/float64(totalCatalogItems)*100)
-	fmt.Printf("📋 Нормализованных записей: %d\n", normalizedCount)
-	fmt.Printf("✨ Нормализованных названий: %d\n", catalogNormalizedCount)
+	fmt.Printf("📦 Всего элементов в catalog_items: %d\n", catalog.TotalItems)
+	fmt.Printf("✅ Классифицировано: %d (%.1f%%)\n", catalog.ClassifiedCount, catalog.ClassifiedPercent)
+	fmt.Printf("📋 Нормализованных записей: %d\n", summary.NormalizedCount)
+	fmt.Printf("✨ Нормализованных названий: %d\n", catalog.NormalizedNames)
 	fmt.Println()
 }
-
@@ -16,6 +16,7 @@ func main() {
 	if len(os.Args) < 2 {
 		fmt.Println("Использование: test_patterns <путь_к_базе> [limit]")
 		fmt.Println("Пример: test_patterns 1c_data.db 50")
+		fmt.Println("Для поочередной проверки предложений с сохранением решений: review_patterns 1c_data.db")
 		os.Exit(1)
 	}
 
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Действия, записываемые в журнал аудита
+const (
+	AuditActionDatabaseSwitch    = "database_switch"    // Переключение активной БД сервера
+	AuditActionConfigReload      = "config_reload"      // Перезагрузка конфигурации без перезапуска
+	AuditActionProjectArchive    = "project_archive"    // Архивация проекта
+	AuditActionProjectRestore    = "project_restore"    // Восстановление проекта из архива
+	AuditActionProjectClone      = "project_clone"      // Клонирование конфигурации проекта
+	AuditActionGatesOverride     = "gates_override"     // Запуск выгрузки в 1С без прохождения порогов качества
+	AuditActionUserLogin         = "user_login"         // Вход пользователя веб-интерфейса
+	AuditActionUserLogout        = "user_logout"        // Выход пользователя веб-интерфейса
+	AuditActionUserCreate        = "user_create"        // Создание пользователя веб-интерфейса
+	AuditActionUserUpdate        = "user_update"        // Блокировка, разблокировка или смена роли пользователя
+	AuditActionProvision         = "provision"          // Применение файла провижининга клиентов и проектов
+	AuditActionProjectEncryption = "project_encryption" // Включение, отключение шифрования проекта, смена мастер-ключа
+	AuditActionRedactionProfile  = "redaction_profile"  // Создание, изменение, удаление или назначение профиля скрытия колонок
+	AuditActionRedactionApplied  = "redaction_applied"  // Выдача или экспорт данных с профилем скрытия колонок
+)
+
+// AuditEvent запись журнала аудита административных действий
+type AuditEvent struct {
+	ID        int                    `json:"id"`
+	Action    string                 `json:"action"`
+	Actor     string                 `json:"actor"`
+	Target    string                 `json:"target"`
+	Status    string                 `json:"status"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// CreateAuditLogTable создает таблицу журнала аудита
+func CreateAuditLogTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL,
+			actor TEXT NOT NULL DEFAULT '',
+			target TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT '',
+			details TEXT NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_log table: %w", err)
+	}
+	return nil
+}
+
+// RecordAuditEvent добавляет запись в журнал аудита
+func (db *ServiceDB) RecordAuditEvent(event *AuditEvent) error {
+	if event.Action == "" {
+		return fmt.Errorf("audit action is required")
+	}
+	details := "{}"
+	if len(event.Details) > 0 {
+		data, err := json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit details: %w", err)
+		}
+		details = string(data)
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	result, err := db.conn.Exec(`
+		INSERT INTO audit_log (action, actor, target, status, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, event.Action, event.Actor, event.Target, event.Status, details, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get audit event id: %w", err)
+	}
+	event.ID = int(id)
+	return nil
+}
+
+// GetAuditEvents возвращает последние записи журнала аудита, action пустой - все действия
+func (db *ServiceDB) GetAuditEvents(action string, limit int) ([]*AuditEvent, error) {
+	query := `SELECT id, action, actor, target, status, details, created_at FROM audit_log`
+	var args []interface{}
+	if action != "" {
+		query += " WHERE action = ?"
+		args = append(args, action)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*AuditEvent{}
+	for rows.Next() {
+		event := &AuditEvent{}
+		var details string
+		if err := rows.Scan(&event.ID, &event.Action, &event.Actor, &event.Target, &event.Status, &details, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if details != "" && details != "{}" {
+			if err := json.Unmarshal([]byte(details), &event.Details); err != nil {
+				return nil, fmt.Errorf("failed to parse audit details: %w", err)
+			}
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,297 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// Статусы заданий заполнения производных колонок
+const (
+	BackfillPending   = "pending"   // Создано, еще не запускалось
+	BackfillRunning   = "running"   // Выполняется
+	BackfillPaused    = "paused"    // Остановлено, продолжается с сохраненной позиции
+	BackfillCompleted = "completed" // Все записи области обработаны
+	BackfillFailed    = "failed"    // Остановлено ошибкой БД
+)
+
+// ErrBackfillJobNotFound задание заполнения не найдено
+var ErrBackfillJobNotFound = apperrors.NotFound("backfill_job_not_found", "backfill job not found")
+
+// backfillColumnRegex допустимые имена колонок: имя подставляется в запросы
+var backfillColumnRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// BackfillScope область записей normalized_data, обрабатываемых заданием
+type BackfillScope struct {
+	Category  string `json:"category,omitempty"`
+	IDFrom    int    `json:"id_from,omitempty"`
+	IDTo      int    `json:"id_to,omitempty"`
+	OnlyEmpty bool   `json:"only_empty,omitempty"` // Только записи с незаполненной колонкой
+}
+
+// BackfillJob задание заполнения производной колонки normalized_data этапом преобразования.
+// Записи обрабатываются пакетами по возрастанию id; LastID - позиция, с которой задание продолжается
+type BackfillJob struct {
+	ID         int           `json:"id"`
+	Stage      string        `json:"stage"`
+	Column     string        `json:"column"`
+	Scope      BackfillScope `json:"scope"`
+	BatchSize  int           `json:"batch_size"`
+	ThrottleMs int           `json:"throttle_ms"` // Пауза между пакетами, снижает нагрузку на БД
+	Status     string        `json:"status"`
+	LastID     int           `json:"last_id"`
+	Total      int           `json:"total"`
+	Processed  int           `json:"processed"`
+	Updated    int           `json:"updated"`
+	Failed     int           `json:"failed"`
+	LastError  string        `json:"last_error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// BackfillRow запись normalized_data для этапа преобразования; Value - текущее значение колонки
+type BackfillRow struct {
+	ID              int
+	SourceReference string
+	SourceName      string
+	Code            string
+	NormalizedName  string
+	Category        string
+	Value           string
+}
+
+// CreateBackfillJobsTable создает таблицу заданий заполнения производных колонок
+func CreateBackfillJobsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS backfill_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			stage TEXT NOT NULL,
+			column_name TEXT NOT NULL,
+			scope TEXT NOT NULL DEFAULT '{}',
+			batch_size INTEGER NOT NULL,
+			throttle_ms INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			last_id INTEGER NOT NULL DEFAULT 0,
+			total INTEGER NOT NULL DEFAULT 0,
+			processed INTEGER NOT NULL DEFAULT 0,
+			updated INTEGER NOT NULL DEFAULT 0,
+			failed INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			finished_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create backfill_jobs table: %w", err)
+	}
+	return nil
+}
+
+// backfillWhere условие области задания для записей после afterID
+func backfillWhere(column string, scope BackfillScope, afterID int) (string, []interface{}, error) {
+	if !backfillColumnRegex.MatchString(column) {
+		return "", nil, fmt.Errorf("invalid backfill column: %q", column)
+	}
+	conditions := []string{"id > ?"}
+	args := []interface{}{afterID}
+	if scope.Category != "" {
+		conditions = append(conditions, "category = ?")
+		args = append(args, scope.Category)
+	}
+	if scope.IDFrom > 0 {
+		conditions = append(conditions, "id >= ?")
+		args = append(args, scope.IDFrom)
+	}
+	if scope.IDTo > 0 {
+		conditions = append(conditions, "id <= ?")
+		args = append(args, scope.IDTo)
+	}
+	if scope.OnlyEmpty {
+		conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s = '')", column, column))
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// CreateBackfillJob создает задание и подсчитывает записи в его области
+func (db *DB) CreateBackfillJob(job *BackfillJob) error {
+	where, args, err := backfillWhere(job.Column, job.Scope, 0)
+	if err != nil {
+		return apperrors.Validation("invalid_backfill", err.Error())
+	}
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM normalized_data WHERE `+where, args...).Scan(&job.Total); err != nil {
+		return fmt.Errorf("failed to count backfill rows: %w", err)
+	}
+
+	scope, err := json.Marshal(job.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backfill scope: %w", err)
+	}
+	now := time.Now().UTC()
+	job.Status = BackfillPending
+	job.CreatedAt, job.UpdatedAt = now, now
+	result, err := db.conn.Exec(`
+		INSERT INTO backfill_jobs (stage, column_name, scope, batch_size, throttle_ms, status, total, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.Stage, job.Column, string(scope), job.BatchSize, job.ThrottleMs, job.Status, job.Total, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create backfill job: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get backfill job id: %w", err)
+	}
+	job.ID = int(id)
+	return nil
+}
+
+// SaveBackfillProgress сохраняет позицию, счетчики и статус задания
+func (db *DB) SaveBackfillProgress(job *BackfillJob) error {
+	job.UpdatedAt = time.Now().UTC()
+	if job.Status == BackfillCompleted || job.Status == BackfillFailed {
+		finishedAt := job.UpdatedAt
+		job.FinishedAt = &finishedAt
+	} else {
+		job.FinishedAt = nil
+	}
+	_, err := db.conn.Exec(`
+		UPDATE backfill_jobs
+		SET status = ?, last_id = ?, processed = ?, updated = ?, failed = ?, last_error = ?, updated_at = ?, finished_at = ?
+		WHERE id = ?
+	`, job.Status, job.LastID, job.Processed, job.Updated, job.Failed, job.LastError, job.UpdatedAt, job.FinishedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save backfill progress: %w", err)
+	}
+	return nil
+}
+
+// PauseInterruptedBackfillJobs переводит задания, прерванные остановкой процесса, в приостановленные
+func (db *DB) PauseInterruptedBackfillJobs() (int, error) {
+	result, err := db.conn.Exec(`UPDATE backfill_jobs SET status = ?, updated_at = ? WHERE status = ?`,
+		BackfillPaused, time.Now().UTC(), BackfillRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pause interrupted backfill jobs: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
+
+// GetBackfillJob возвращает задание заполнения
+func (db *DB) GetBackfillJob(id int) (*BackfillJob, error) {
+	jobs, err := db.queryBackfillJobs(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrBackfillJobNotFound
+	}
+	return jobs[0], nil
+}
+
+// ListBackfillJobs возвращает задания заполнения, последние первыми
+func (db *DB) ListBackfillJobs(limit int) ([]*BackfillJob, error) {
+	return db.queryBackfillJobs(`ORDER BY id DESC LIMIT ?`, limit)
+}
+
+func (db *DB) queryBackfillJobs(where string, args ...interface{}) ([]*BackfillJob, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, stage, column_name, scope, batch_size, throttle_ms, status, last_id, total,
+		       processed, updated, failed, last_error, created_at, updated_at, finished_at
+		FROM backfill_jobs `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backfill jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*BackfillJob
+	for rows.Next() {
+		job := &BackfillJob{}
+		var scope string
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&job.ID, &job.Stage, &job.Column, &scope, &job.BatchSize, &job.ThrottleMs, &job.Status,
+			&job.LastID, &job.Total, &job.Processed, &job.Updated, &job.Failed, &job.LastError,
+			&job.CreatedAt, &job.UpdatedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan backfill job: %w", err)
+		}
+		if err := json.Unmarshal([]byte(scope), &job.Scope); err != nil {
+			return nil, fmt.Errorf("failed to parse backfill scope of job %d: %w", job.ID, err)
+		}
+		if finishedAt.Valid {
+			job.FinishedAt = &finishedAt.Time
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate backfill jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetBackfillBatch возвращает следующий пакет записей области задания после позиции LastID
+func (db *DB) GetBackfillBatch(job *BackfillJob) ([]BackfillRow, error) {
+	where, args, err := backfillWhere(job.Column, job.Scope, job.LastID)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, job.BatchSize)
+	rows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT id, COALESCE(source_reference, ''), COALESCE(source_name, ''), COALESCE(code, ''),
+		       COALESCE(normalized_name, ''), COALESCE(category, ''), COALESCE(CAST(%s AS TEXT), '')
+		FROM normalized_data
+		WHERE %s
+		ORDER BY id
+		LIMIT ?
+	`, job.Column, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backfill batch: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []BackfillRow
+	for rows.Next() {
+		var row BackfillRow
+		if err := rows.Scan(&row.ID, &row.SourceReference, &row.SourceName, &row.Code,
+			&row.NormalizedName, &row.Category, &row.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan backfill row: %w", err)
+		}
+		batch = append(batch, row)
+	}
+	return batch, rows.Err()
+}
+
+// ApplyBackfillBatch записывает значения колонки одной транзакцией
+func (db *DB) ApplyBackfillBatch(column string, values map[int]string) error {
+	if !backfillColumnRegex.MatchString(column) {
+		return fmt.Errorf("invalid backfill column: %q", column)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`UPDATE normalized_data SET %s = ? WHERE id = ?`, column))
+	if err != nil {
+		return fmt.Errorf("failed to prepare backfill statement: %w", err)
+	}
+	defer stmt.Close()
+	for id, value := range values {
+		if _, err := stmt.Exec(value, id); err != nil {
+			return fmt.Errorf("failed to backfill item %d: %w", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit backfill batch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// Статусы фоновых задач
+const (
+	BackgroundJobRunning     = "running"     // Выполняется
+	BackgroundJobCompleted   = "completed"   // Завершена успешно
+	BackgroundJobFailed      = "failed"      // Завершена с ошибкой
+	BackgroundJobInterrupted = "interrupted" // Остановлена при завершении работы сервера
+)
+
+// ErrBackgroundJobLeased задача того же вида и с тем же именем уже выполняется (на этом или другом экземпляре сервера)
+var ErrBackgroundJobLeased = apperrors.Conflict("job_already_running", "job is already running")
+
+// BackgroundJob запись о фоновой задаче (нормализация, классификация, анализ качества, экспорт).
+// Выполняющаяся задача арендована экземпляром сервера InstanceID до LeaseExpiresAt; аренда продлевается
+// heartbeat экземпляра, задача с истекшей арендой считается прерванной
+type BackgroundJob struct {
+	ID             int                    `json:"id"`
+	Kind           string                 `json:"kind"`
+	Name           string                 `json:"name"`
+	Status         string                 `json:"status"`
+	Error          string                 `json:"error,omitempty"`
+	Checkpoint     map[string]interface{} `json:"checkpoint,omitempty"`
+	InstanceID     string                 `json:"instance_id,omitempty"`
+	LeaseExpiresAt *time.Time             `json:"lease_expires_at,omitempty"`
+	StartedAt      time.Time              `json:"started_at"`
+	FinishedAt     *time.Time             `json:"finished_at,omitempty"`
+}
+
+// backgroundJobColumns колонки, читаемые scanBackgroundJobs
+const backgroundJobColumns = `id, kind, name, status, error, checkpoint, instance_id, lease_expires_at, started_at, finished_at`
+
+// CreateBackgroundJobsTable создает таблицу фоновых задач
+func CreateBackgroundJobsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS background_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			checkpoint TEXT NOT NULL DEFAULT '{}',
+			started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			finished_at TIMESTAMP,
+			reported INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_background_jobs_status ON background_jobs(status, reported);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create background_jobs table: %w", err)
+	}
+	return nil
+}
+
+// MigrateBackgroundJobLeases добавляет аренду задач экземплярами сервера. Задачи, оставшиеся в статусе running
+// до миграции, не имеют владельца и переводятся в interrupted, после чего одновременно может выполняться
+// только одна задача каждого вида и имени
+func MigrateBackgroundJobLeases(db *sql.DB) error {
+	migrations := []string{
+		`ALTER TABLE background_jobs ADD COLUMN instance_id TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE background_jobs ADD COLUMN lease_expires_at TIMESTAMP`,
+	}
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(strings.ToLower(err.Error()), "duplicate column") {
+			return fmt.Errorf("migration failed: %s, error: %w", migration, err)
+		}
+	}
+
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_background_jobs_running'`).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check background jobs lease index: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+	if _, err := db.Exec(`
+		UPDATE background_jobs SET status = ?, error = 'server stopped while job was running'
+		WHERE status = ? AND instance_id = ''
+	`, BackgroundJobInterrupted, BackgroundJobRunning); err != nil {
+		return fmt.Errorf("failed to mark stale background jobs: %w", err)
+	}
+	if _, err := db.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_background_jobs_running ON background_jobs(kind, name) WHERE status = 'running'
+	`); err != nil {
+		return fmt.Errorf("failed to create background jobs lease index: %w", err)
+	}
+	return nil
+}
+
+// StartBackgroundJob сохраняет запущенную фоновую задачу и заполняет ее ID. Задача с заданным InstanceID
+// арендуется на leaseTTL; задача того же вида и имени с истекшей арендой переводится в interrupted.
+// Если такая задача уже выполняется, возвращает ErrBackgroundJobLeased
+func (db *ServiceDB) StartBackgroundJob(job *BackgroundJob, leaseTTL time.Duration) error {
+	if job.Kind == "" {
+		return fmt.Errorf("background job kind is required")
+	}
+	if job.StartedAt.IsZero() {
+		job.StartedAt = time.Now()
+	}
+	job.Status = BackgroundJobRunning
+	job.LeaseExpiresAt = nil
+	if job.InstanceID != "" && leaseTTL > 0 {
+		expires := time.Now().UTC().Add(leaseTTL)
+		job.LeaseExpiresAt = &expires
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE background_jobs SET status = ?, error = 'job lease expired', finished_at = ?
+		WHERE kind = ? AND name = ? AND status = ? AND lease_expires_at IS NOT NULL AND lease_expires_at < ?
+	`, BackgroundJobInterrupted, time.Now(), job.Kind, job.Name, BackgroundJobRunning, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to expire background job lease: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO background_jobs (kind, name, status, instance_id, lease_expires_at, started_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, job.Kind, job.Name, job.Status, job.InstanceID, job.LeaseExpiresAt, job.StartedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrBackgroundJobLeased
+		}
+		return fmt.Errorf("failed to start background job: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get background job id: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit background job: %w", err)
+	}
+	job.ID = int(id)
+	return nil
+}
+
+// RenewBackgroundJobLeases продлевает аренду выполняющихся задач экземпляра до now+leaseTTL
+func (db *ServiceDB) RenewBackgroundJobLeases(instanceID string, leaseTTL time.Duration) error {
+	_, err := db.conn.Exec(`
+		UPDATE background_jobs SET lease_expires_at = ?
+		WHERE instance_id = ? AND status = ?
+	`, time.Now().UTC().Add(leaseTTL), instanceID, BackgroundJobRunning)
+	if err != nil {
+		return fmt.Errorf("failed to renew background job leases: %w", err)
+	}
+	return nil
+}
+
+// FinishBackgroundJob сохраняет итоговый статус задачи и последний checkpoint
+func (db *ServiceDB) FinishBackgroundJob(id int, status, errMessage string, checkpoint map[string]interface{}) error {
+	data := "{}"
+	if len(checkpoint) > 0 {
+		encoded, err := json.Marshal(checkpoint)
+		if err != nil {
+			return fmt.Errorf("failed to marshal background job checkpoint: %w", err)
+		}
+		data = string(encoded)
+	}
+	_, err := db.conn.Exec(`
+		UPDATE background_jobs SET status = ?, error = ?, checkpoint = ?, finished_at = ?
+		WHERE id = ?
+	`, status, errMessage, data, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to finish background job: %w", err)
+	}
+	return nil
+}
+
+// TakeUnfinishedBackgroundJobs возвращает задачи, не завершенные в предыдущем запуске экземпляра instanceID,
+// и отмечает их как сообщенные. Задачи экземпляра, оставшиеся в статусе running (сервер остановлен аварийно),
+// и задачи с истекшей арендой переводятся в interrupted. Задачи других экземпляров с действующей арендой не затрагиваются
+func (db *ServiceDB) TakeUnfinishedBackgroundJobs(instanceID string) ([]*BackgroundJob, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE background_jobs SET status = ?, error = 'server stopped while job was running'
+		WHERE status = ? AND (instance_id = ? OR (lease_expires_at IS NOT NULL AND lease_expires_at < ?))
+	`, BackgroundJobInterrupted, BackgroundJobRunning, instanceID, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to mark stale background jobs: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT `+backgroundJobColumns+`
+		FROM background_jobs WHERE status = ? AND reported = 0
+		ORDER BY id
+	`, BackgroundJobInterrupted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unfinished background jobs: %w", err)
+	}
+	jobs, err := scanBackgroundJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE background_jobs SET reported = 1 WHERE status = ? AND reported = 0`, BackgroundJobInterrupted); err != nil {
+		return nil, fmt.Errorf("failed to mark background jobs reported: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit unfinished background jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetBackgroundJobs возвращает последние фоновые задачи, status пустой - все статусы
+func (db *ServiceDB) GetBackgroundJobs(status string, limit int) ([]*BackgroundJob, error) {
+	query := `SELECT ` + backgroundJobColumns + ` FROM background_jobs`
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get background jobs: %w", err)
+	}
+	return scanBackgroundJobs(rows)
+}
+
+// scanBackgroundJobs читает записи фоновых задач и закрывает rows
+func scanBackgroundJobs(rows *sql.Rows) ([]*BackgroundJob, error) {
+	defer rows.Close()
+
+	jobs := []*BackgroundJob{}
+	for rows.Next() {
+		job := &BackgroundJob{}
+		var checkpoint string
+		var leaseExpiresAt, finishedAt sql.NullTime
+		if err := rows.Scan(&job.ID, &job.Kind, &job.Name, &job.Status, &job.Error, &checkpoint, &job.InstanceID, &leaseExpiresAt, &job.StartedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan background job: %w", err)
+		}
+		if checkpoint != "" && checkpoint != "{}" {
+			if err := json.Unmarshal([]byte(checkpoint), &job.Checkpoint); err != nil {
+				return nil, fmt.Errorf("failed to parse background job checkpoint: %w", err)
+			}
+		}
+		if leaseExpiresAt.Valid {
+			job.LeaseExpiresAt = &leaseExpiresAt.Time
+		}
+		if finishedAt.Valid {
+			job.FinishedAt = &finishedAt.Time
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
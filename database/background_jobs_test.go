@@ -0,0 +1,163 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+func TestBackgroundJobs(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	jobs := map[string]*BackgroundJob{
+		"completed":   {Kind: "normalization", Name: "catalog_items"},
+		"interrupted": {Kind: "reclassification", Name: "top_priority"},
+		"crashed":     {Kind: "export", Name: "job-1"},
+	}
+	// Задачи создаются в фиксированном порядке: незавершенные возвращаются по возрастанию ID
+	for _, name := range []string{"completed", "interrupted", "crashed"} {
+		if err := db.StartBackgroundJob(jobs[name], 0); err != nil {
+			t.Fatalf("StartBackgroundJob(%s) error = %v", name, err)
+		}
+	}
+	if err := db.StartBackgroundJob(&BackgroundJob{}, 0); err == nil {
+		t.Error("StartBackgroundJob() must reject empty kind")
+	}
+
+	if err := db.FinishBackgroundJob(jobs["completed"].ID, BackgroundJobCompleted, "", nil); err != nil {
+		t.Fatalf("FinishBackgroundJob() error = %v", err)
+	}
+	checkpoint := map[string]interface{}{"processed": 40.0}
+	if err := db.FinishBackgroundJob(jobs["interrupted"].ID, BackgroundJobInterrupted, "stopped by server shutdown", checkpoint); err != nil {
+		t.Fatalf("FinishBackgroundJob() error = %v", err)
+	}
+
+	unfinished, err := db.TakeUnfinishedBackgroundJobs("")
+	if err != nil {
+		t.Fatalf("TakeUnfinishedBackgroundJobs() error = %v", err)
+	}
+	if len(unfinished) != 2 {
+		t.Fatalf("TakeUnfinishedBackgroundJobs() = %d jobs, want 2", len(unfinished))
+	}
+
+	tests := []struct {
+		name       string
+		job        *BackgroundJob
+		checkpoint interface{}
+	}{
+		{"interrupted", unfinished[0], 40.0},
+		{"crashed", unfinished[1], nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.job.ID != jobs[tt.name].ID {
+				t.Errorf("ID = %d, want %d", tt.job.ID, jobs[tt.name].ID)
+			}
+			if tt.job.Status != BackgroundJobInterrupted {
+				t.Errorf("Status = %q, want %q", tt.job.Status, BackgroundJobInterrupted)
+			}
+			if tt.job.Checkpoint["processed"] != tt.checkpoint {
+				t.Errorf("Checkpoint = %v, want processed %v", tt.job.Checkpoint, tt.checkpoint)
+			}
+		})
+	}
+
+	again, err := db.TakeUnfinishedBackgroundJobs("")
+	if err != nil {
+		t.Fatalf("TakeUnfinishedBackgroundJobs() error = %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("unfinished jobs must be reported once, got %d", len(again))
+	}
+
+	history, err := db.GetBackgroundJobs(BackgroundJobCompleted, 10)
+	if err != nil {
+		t.Fatalf("GetBackgroundJobs() error = %v", err)
+	}
+	if len(history) != 1 || history[0].FinishedAt == nil {
+		t.Errorf("GetBackgroundJobs(completed) = %+v, want one finished job", history)
+	}
+}
+
+func TestBackgroundJobLeases(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	owned := &BackgroundJob{Kind: "export", Name: "job-1", InstanceID: "node-a"}
+	if err := db.StartBackgroundJob(owned, time.Minute); err != nil {
+		t.Fatalf("StartBackgroundJob() error = %v", err)
+	}
+	expired := &BackgroundJob{Kind: "export", Name: "job-2", InstanceID: "node-a"}
+	if err := db.StartBackgroundJob(expired, time.Minute); err != nil {
+		t.Fatalf("StartBackgroundJob() error = %v", err)
+	}
+	// Экземпляр node-a перестал продлевать аренду job-2
+	if _, err := db.conn.Exec(`UPDATE background_jobs SET lease_expires_at = ? WHERE id = ?`, time.Now().UTC().Add(-time.Second), expired.ID); err != nil {
+		t.Fatalf("failed to expire lease: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		job      *BackgroundJob
+		wantKind apperrors.Kind
+	}{
+		{"leased by another instance", &BackgroundJob{Kind: "export", Name: "job-1", InstanceID: "node-b"}, apperrors.KindConflict},
+		{"leased by same instance", &BackgroundJob{Kind: "export", Name: "job-1", InstanceID: "node-a"}, apperrors.KindConflict},
+		{"other name", &BackgroundJob{Kind: "export", Name: "job-3", InstanceID: "node-b"}, ""},
+		{"expired lease is taken over", &BackgroundJob{Kind: "export", Name: "job-2", InstanceID: "node-b"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.StartBackgroundJob(tt.job, time.Minute)
+			if tt.wantKind == "" {
+				if err != nil {
+					t.Errorf("StartBackgroundJob() error = %v", err)
+				}
+				return
+			}
+			if apperrors.KindOf(err) != tt.wantKind {
+				t.Errorf("StartBackgroundJob() error = %v, want %s", err, tt.wantKind)
+			}
+		})
+	}
+
+	// Запуск node-c не затрагивает задачи других экземпляров с действующей арендой
+	unfinished, err := db.TakeUnfinishedBackgroundJobs("node-c")
+	if err != nil {
+		t.Fatalf("TakeUnfinishedBackgroundJobs() error = %v", err)
+	}
+	if len(unfinished) != 1 || unfinished[0].ID != expired.ID || unfinished[0].Error != "job lease expired" {
+		t.Errorf("unfinished = %+v, want expired job-2", unfinished)
+	}
+	running, err := db.GetBackgroundJobs(BackgroundJobRunning, 10)
+	if err != nil {
+		t.Fatalf("GetBackgroundJobs() error = %v", err)
+	}
+	if len(running) != 3 {
+		t.Errorf("running jobs = %d, want 3", len(running))
+	}
+
+	if err := db.RenewBackgroundJobLeases("node-a", time.Hour); err != nil {
+		t.Fatalf("RenewBackgroundJobLeases() error = %v", err)
+	}
+	renewed, _ := db.GetBackgroundJobs(BackgroundJobRunning, 10)
+	for _, job := range renewed {
+		if job.ID == owned.ID && (job.LeaseExpiresAt == nil || time.Until(*job.LeaseExpiresAt) < 30*time.Minute) {
+			t.Errorf("owned job lease = %v, want renewed for an hour", job.LeaseExpiresAt)
+		}
+	}
+	if err := db.FinishBackgroundJob(owned.ID, BackgroundJobCompleted, "", nil); err != nil {
+		t.Fatalf("FinishBackgroundJob() error = %v", err)
+	}
+	if err := db.StartBackgroundJob(&BackgroundJob{Kind: "export", Name: "job-1", InstanceID: "node-b"}, time.Minute); err != nil {
+		t.Errorf("StartBackgroundJob() after finish error = %v", err)
+	}
+}
@@ -1,6 +1,10 @@
 package database
 
 import (
+	"fmt"
+	"io"
+	"log"
+	"os"
 	"testing"
 )
 
@@ -127,3 +131,154 @@ func BenchmarkCreateNormalizationSession(b *testing.B) {
 	}
 }
 
+
+// benchmarkItemName возвращает синтетическое наименование номенклатуры для бенчмарков
+func benchmarkItemName(i int) string {
+	names := []string{"Молоток слесарный", "Болт М8х40 оцинк.", "Кабель ВВГнг 3х2,5", "Краска ПФ-115 белая", "Перчатки х/б"}
+	return fmt.Sprintf("%s ER-%08d %dкг", names[i%len(names)], i, i%50+1)
+}
+
+// BenchmarkCatalogItemsBatchIngest тестирует производительность сохранения пакета /catalog/items (500 элементов)
+func BenchmarkCatalogItemsBatchIngest(b *testing.B) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		b.Fatalf("Failed to create test DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("test-uuid", "8.3", "test-config")
+	if err != nil {
+		b.Fatalf("Failed to create upload: %v", err)
+	}
+
+	catalog, err := db.AddCatalog(upload.ID, "TestCatalog", "test_catalog")
+	if err != nil {
+		b.Fatalf("Failed to create catalog: %v", err)
+	}
+
+	const batchSize = 500
+	attributes := "<Реквизит Имя=\"Артикул\">A-1</Реквизит><Реквизит Имя=\"Вес\">1,5</Реквизит>"
+
+	// Отладочный лог вставки форматируется, но не выводится
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < batchSize; j++ {
+			n := i*batchSize + j
+			err := db.AddCatalogItem(catalog.ID, fmt.Sprintf("ref-%d", n), fmt.Sprintf("%06d", n), benchmarkItemName(n), attributes, "")
+			if err != nil {
+				b.Fatalf("Failed to insert item: %v", err)
+			}
+		}
+	}
+}
+
+// BenchmarkAddNomenclatureItemsBatch тестирует производительность пакетной вставки номенклатуры (500 элементов)
+func BenchmarkAddNomenclatureItemsBatch(b *testing.B) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		b.Fatalf("Failed to create test DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("test-uuid", "8.3", "test-config")
+	if err != nil {
+		b.Fatalf("Failed to create upload: %v", err)
+	}
+
+	items := make([]NomenclatureItem, 500)
+	for i := range items {
+		items[i] = NomenclatureItem{
+			NomenclatureReference: fmt.Sprintf("ref-%d", i),
+			NomenclatureCode:      fmt.Sprintf("%06d", i),
+			NomenclatureName:      benchmarkItemName(i),
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.AddNomenclatureItemsBatch(upload.ID, items); err != nil {
+			b.Fatalf("Failed to insert batch: %v", err)
+		}
+	}
+}
+
+// BenchmarkAddCatalogItemToTable тестирует производительность вставки в динамическую таблицу справочника
+func BenchmarkAddCatalogItemToTable(b *testing.B) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		b.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("test-uuid", "8.3", "test-config")
+	if err != nil {
+		b.Fatalf("Failed to create upload: %v", err)
+	}
+
+	tableName, err := GetOrCreateCatalogTable(db.conn, "Номенклатура")
+	if err != nil {
+		b.Fatalf("Failed to create catalog table: %v", err)
+	}
+	attributes := "<Реквизит Имя=\"Артикул\">A-1</Реквизит><Реквизит Имя=\"Вес\">1,5</Реквизит>"
+
+	// Отладочный лог вставки форматируется, но не выводится
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := db.AddCatalogItemToTable(tableName, upload.ID, fmt.Sprintf("ref-%d", i), fmt.Sprintf("%06d", i), benchmarkItemName(i), attributes, "")
+		if err != nil {
+			b.Fatalf("Failed to insert item: %v", err)
+		}
+	}
+}
+
+// BenchmarkGetCatalogItemsByUploadPagination тестирует постраничное получение элементов на 10k записей
+func BenchmarkGetCatalogItemsByUploadPagination(b *testing.B) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		b.Fatalf("Failed to create test DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("test-uuid", "8.3", "test-config")
+	if err != nil {
+		b.Fatalf("Failed to create upload: %v", err)
+	}
+
+	catalog, err := db.AddCatalog(upload.ID, "TestCatalog", "test_catalog")
+	if err != nil {
+		b.Fatalf("Failed to create catalog: %v", err)
+	}
+
+	const total = 10000
+	tx, err := db.conn.Begin()
+	if err != nil {
+		b.Fatalf("Failed to begin transaction: %v", err)
+	}
+	for i := 0; i < total; i++ {
+		_, err := tx.Exec("INSERT INTO catalog_items (catalog_id, reference, code, name) VALUES (?, ?, ?, ?)",
+			catalog.ID, fmt.Sprintf("ref-%d", i), fmt.Sprintf("%06d", i), benchmarkItemName(i))
+		if err != nil {
+			b.Fatalf("Failed to insert item: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatalf("Failed to commit: %v", err)
+	}
+
+	for _, offset := range []int{0, total / 2, total - 100} {
+		b.Run(fmt.Sprintf("offset=%d", offset), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _, err := db.GetCatalogItemsByUpload(upload.ID, nil, offset, 100)
+				if err != nil {
+					b.Fatalf("Failed to get items: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Решения политики порогов уверенности
+const (
+	ConfidenceDecisionAccept = "accept" // Автоматически принять
+	ConfidenceDecisionReview = "review" // Поставить в очередь на проверку
+	ConfidenceDecisionReject = "reject" // Отклонить результат
+)
+
+// Пороги политики по умолчанию (если для проекта и глобально ничего не задано)
+const (
+	DefaultAcceptThreshold = 0.85
+	DefaultReviewThreshold = 0.5
+)
+
+// ClassificationOutcome проверенный исход классификации (из mark-correct/mark-incorrect)
+type ClassificationOutcome struct {
+	ID            int       `json:"id"`
+	Model         string    `json:"model"`
+	Source        string    `json:"source"`
+	RawConfidence float64   `json:"raw_confidence"`
+	Correct       bool      `json:"correct"`
+	ProjectID     int       `json:"project_id,omitempty"`
+	Reference     string    `json:"reference,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ConfidencePolicy политика порогов уверенности проекта (ProjectID = 0 - глобальная)
+type ConfidencePolicy struct {
+	ProjectID       int       `json:"project_id"`
+	AcceptThreshold float64   `json:"accept_threshold"` // Не ниже - принять автоматически
+	ReviewThreshold float64   `json:"review_threshold"` // Не ниже - на проверку, ниже - отклонить
+	UpdatedAt       time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultConfidencePolicy возвращает политику по умолчанию
+func DefaultConfidencePolicy() ConfidencePolicy {
+	return ConfidencePolicy{
+		AcceptThreshold: DefaultAcceptThreshold,
+		ReviewThreshold: DefaultReviewThreshold,
+	}
+}
+
+// Validate проверяет корректность порогов
+func (p ConfidencePolicy) Validate() error {
+	if p.ReviewThreshold < 0 || p.AcceptThreshold > 1 {
+		return fmt.Errorf("thresholds must be within [0, 1]")
+	}
+	if p.ReviewThreshold > p.AcceptThreshold {
+		return fmt.Errorf("review_threshold must not exceed accept_threshold")
+	}
+	return nil
+}
+
+// Decide возвращает решение для (откалиброванной) уверенности
+func (p ConfidencePolicy) Decide(confidence float64) string {
+	switch {
+	case confidence >= p.AcceptThreshold:
+		return ConfidenceDecisionAccept
+	case confidence >= p.ReviewThreshold:
+		return ConfidenceDecisionReview
+	}
+	return ConfidenceDecisionReject
+}
+
+// CreateCalibrationTables создает таблицы исходов и политик порогов,
+// а также добавляет в normalized_data исходную уверенность, модель и решение политики
+func CreateCalibrationTables(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS classification_outcomes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			model TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT 'kpved',
+			raw_confidence REAL NOT NULL,
+			correct INTEGER NOT NULL,
+			project_id INTEGER NOT NULL DEFAULT 0,
+			reference TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_classification_outcomes_model ON classification_outcomes(model);
+
+		CREATE TABLE IF NOT EXISTS confidence_policies (
+			project_id INTEGER PRIMARY KEY,
+			accept_threshold REAL NOT NULL,
+			review_threshold REAL NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create calibration tables: %w", err)
+	}
+
+	migrations := []string{
+		`ALTER TABLE normalized_data ADD COLUMN kpved_model TEXT`,
+		`ALTER TABLE normalized_data ADD COLUMN kpved_raw_confidence REAL`,
+		`ALTER TABLE normalized_data ADD COLUMN confidence_decision TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_normalized_confidence_decision ON normalized_data(confidence_decision)`,
+	}
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil {
+			errStr := strings.ToLower(err.Error())
+			if !strings.Contains(errStr, "duplicate column") &&
+				!strings.Contains(errStr, "already exists") {
+				return fmt.Errorf("migration failed: %s, error: %w", migration, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RecordGroupOutcome записывает проверенный исход КПВЭД классификации группы.
+// Вызывается до изменения записей, пока в них сохранены модель и исходная уверенность.
+// Возвращает false, если у группы нет классификации или она уже подтверждена.
+func (db *DB) RecordGroupOutcome(normalizedName, category string, correct bool) (bool, error) {
+	result, err := db.conn.Exec(`
+		INSERT INTO classification_outcomes (model, source, raw_confidence, correct, reference)
+		SELECT COALESCE(kpved_model, ''), 'kpved', COALESCE(kpved_raw_confidence, kpved_confidence, 0), ?,
+		       normalized_name || ' / ' || category
+		FROM normalized_data
+		WHERE normalized_name = ? AND category = ?
+		  AND kpved_code IS NOT NULL AND TRIM(kpved_code) != ''
+		  AND COALESCE(validation_status, '') != 'correct'
+		LIMIT 1
+	`, correct, normalizedName, category)
+	if err != nil {
+		return false, fmt.Errorf("failed to record classification outcome: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// GetClassificationOutcomes возвращает последние проверенные исходы модели
+func (db *DB) GetClassificationOutcomes(model string, limit int) ([]ClassificationOutcome, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, model, source, raw_confidence, correct, project_id, COALESCE(reference, ''), created_at
+		FROM classification_outcomes
+		WHERE model = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var outcomes []ClassificationOutcome
+	for rows.Next() {
+		var outcome ClassificationOutcome
+		if err := rows.Scan(&outcome.ID, &outcome.Model, &outcome.Source, &outcome.RawConfidence,
+			&outcome.Correct, &outcome.ProjectID, &outcome.Reference, &outcome.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan classification outcome: %w", err)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes, rows.Err()
+}
+
+// GetOutcomeModels возвращает модели, для которых есть проверенные исходы
+func (db *DB) GetOutcomeModels() ([]string, error) {
+	rows, err := db.conn.Query(`SELECT DISTINCT model FROM classification_outcomes ORDER BY model`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outcome models: %w", err)
+	}
+	defer rows.Close()
+
+	models := []string{}
+	for rows.Next() {
+		var model string
+		if err := rows.Scan(&model); err != nil {
+			return nil, fmt.Errorf("failed to scan outcome model: %w", err)
+		}
+		models = append(models, model)
+	}
+
+	return models, rows.Err()
+}
+
+// GetConfidencePolicy возвращает политику проекта, иначе глобальную, иначе политику по умолчанию
+func (db *DB) GetConfidencePolicy(projectID int) (ConfidencePolicy, error) {
+	policy := DefaultConfidencePolicy()
+	err := db.conn.QueryRow(`
+		SELECT project_id, accept_threshold, review_threshold, updated_at
+		FROM confidence_policies
+		WHERE project_id IN (?, 0)
+		ORDER BY project_id DESC
+		LIMIT 1
+	`, projectID).Scan(&policy.ProjectID, &policy.AcceptThreshold, &policy.ReviewThreshold, &policy.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return DefaultConfidencePolicy(), nil
+	}
+	if err != nil {
+		return policy, fmt.Errorf("failed to get confidence policy: %w", err)
+	}
+	return policy, nil
+}
+
+// GetConfidencePolicies возвращает все заданные политики
+func (db *DB) GetConfidencePolicies() ([]ConfidencePolicy, error) {
+	rows, err := db.conn.Query(`
+		SELECT project_id, accept_threshold, review_threshold, updated_at
+		FROM confidence_policies
+		ORDER BY project_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get confidence policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []ConfidencePolicy{}
+	for rows.Next() {
+		var policy ConfidencePolicy
+		if err := rows.Scan(&policy.ProjectID, &policy.AcceptThreshold, &policy.ReviewThreshold, &policy.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan confidence policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// SaveConfidencePolicy создает или обновляет политику проекта
+func (db *DB) SaveConfidencePolicy(policy ConfidencePolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	_, err := db.conn.Exec(`
+		INSERT INTO confidence_policies (project_id, accept_threshold, review_threshold, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(project_id) DO UPDATE SET
+			accept_threshold = excluded.accept_threshold,
+			review_threshold = excluded.review_threshold,
+			updated_at = CURRENT_TIMESTAMP
+	`, policy.ProjectID, policy.AcceptThreshold, policy.ReviewThreshold)
+	if err != nil {
+		return fmt.Errorf("failed to save confidence policy: %w", err)
+	}
+	return nil
+}
+
+// GetReviewQueue возвращает группы, поставленные политикой на проверку и еще не проверенные
+func (db *DB) GetReviewQueue(limit, offset int) ([]map[string]interface{}, int, error) {
+	const where = `confidence_decision = 'review' AND COALESCE(validation_status, '') NOT IN ('correct', 'incorrect')`
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(DISTINCT normalized_name || '|' || category) FROM normalized_data WHERE ` + where).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count review queue: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT normalized_name, category, COALESCE(kpved_code, ''), COALESCE(kpved_name, ''),
+		       COALESCE(kpved_confidence, 0), COALESCE(kpved_raw_confidence, kpved_confidence, 0),
+		       COALESCE(kpved_model, ''), COUNT(*)
+		FROM normalized_data
+		WHERE `+where+`
+		GROUP BY normalized_name, category
+		ORDER BY MAX(merged_count) DESC, normalized_name
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get review queue: %w", err)
+	}
+	defer rows.Close()
+
+	items := []map[string]interface{}{}
+	for rows.Next() {
+		var name, category, code, kpvedName, model string
+		var confidence, rawConfidence float64
+		var count int
+		if err := rows.Scan(&name, &category, &code, &kpvedName, &confidence, &rawConfidence, &model, &count); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review queue item: %w", err)
+		}
+		items = append(items, map[string]interface{}{
+			"normalized_name":      name,
+			"category":             category,
+			"kpved_code":           code,
+			"kpved_name":           kpvedName,
+			"kpved_confidence":     confidence,
+			"kpved_raw_confidence": rawConfidence,
+			"kpved_model":          model,
+			"items_count":          count,
+		})
+	}
+
+	return items, total, rows.Err()
+}
+
+// calibrationValues возвращает значения колонок калибровки для вставки
+// (NULL, если политика порогов к записи не применялась)
+func (item *NormalizedItem) calibrationValues() (interface{}, interface{}, interface{}) {
+	if item.ConfidenceDecision == "" {
+		return nil, nil, nil
+	}
+	return item.KpvedRawConfidence, item.KpvedModel, item.ConfidenceDecision
+}
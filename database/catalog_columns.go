@@ -0,0 +1,515 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Допустимые типы продвигаемых колонок (ключ - тип API, значение - тип SQLite)
+var catalogColumnTypes = map[string]string{
+	"text":    "TEXT",
+	"integer": "INTEGER",
+	"real":    "REAL",
+}
+
+// CatalogColumnMapping маппинг реквизита справочника на типизированную индексируемую колонку
+type CatalogColumnMapping struct {
+	ID            int       `json:"id"`
+	CatalogName   string    `json:"catalog_name"`
+	AttributeName string    `json:"attribute_name"`
+	ColumnName    string    `json:"column_name"`
+	ColumnType    string    `json:"column_type"` // text, integer, real
+	Source        string    `json:"source"`      // api, metadata
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CreateCatalogColumnMappingsTable создает таблицу маппингов реквизитов на колонки
+func CreateCatalogColumnMappingsTable(db *sql.DB) error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS catalog_column_mappings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		catalog_name TEXT NOT NULL,
+		attribute_name TEXT NOT NULL,
+		column_name TEXT NOT NULL,
+		column_type TEXT NOT NULL DEFAULT 'text',
+		source TEXT DEFAULT 'api',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(catalog_name, attribute_name)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_catalog_column_mappings_catalog ON catalog_column_mappings(catalog_name);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create catalog_column_mappings table: %w", err)
+	}
+
+	return nil
+}
+
+// CatalogAttributeColumnName формирует имя колонки для реквизита
+// Например: "Производитель" -> "attr_proizvoditel"
+func CatalogAttributeColumnName(attributeName string) string {
+	columnName := "attr_" + transliterateIdentifier(attributeName)
+	if len(columnName) > 64 {
+		columnName = columnName[:64]
+	}
+	return columnName
+}
+
+// uniqueCatalogColumnName возвращает имя колонки, не занятое другими реквизитами справочника:
+// при совпадении транслитераций (или обрезке длинных имен) добавляется суффикс _2, _3, ...
+func uniqueCatalogColumnName(columnName string, taken map[string]bool) string {
+	if !taken[columnName] {
+		return columnName
+	}
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		base := columnName
+		if len(base)+len(suffix) > 64 {
+			base = base[:64-len(suffix)]
+		}
+		if candidate := base + suffix; !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// catalogColumnExists проверяет, добавлена ли колонка в таблицу справочника
+func catalogColumnExists(db *sql.DB, tableName, columnName string) (bool, error) {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, tableName, columnName).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check column %s of %s: %w", columnName, tableName, err)
+	}
+	return count > 0, nil
+}
+
+// SaveCatalogColumnMapping сохраняет маппинг реквизита и добавляет колонку в таблицу справочника, если она уже создана
+func SaveCatalogColumnMapping(db *sql.DB, catalogName, attributeName, columnType, source string) (*CatalogColumnMapping, error) {
+	catalogName = strings.TrimSpace(catalogName)
+	attributeName = strings.TrimSpace(attributeName)
+	if catalogName == "" || attributeName == "" {
+		return nil, fmt.Errorf("catalog name and attribute name are required")
+	}
+
+	if columnType == "" {
+		columnType = "text"
+	}
+	if _, ok := catalogColumnTypes[columnType]; !ok {
+		return nil, fmt.Errorf("unsupported column type: %s", columnType)
+	}
+	if source == "" {
+		source = "api"
+	}
+
+	columnName := CatalogAttributeColumnName(attributeName)
+	if columnName == "attr_" || !isValidTableName(columnName) {
+		return nil, fmt.Errorf("invalid attribute name: %s", attributeName)
+	}
+
+	if err := CreateCatalogColumnMappingsTable(db); err != nil {
+		return nil, err
+	}
+
+	// Имя колонки уникально в пределах справочника; у существующего маппинга оно не меняется
+	existing, err := GetCatalogColumnMappings(db, catalogName)
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, m := range existing {
+		if m.AttributeName != attributeName {
+			taken[m.ColumnName] = true
+			continue
+		}
+		columnName = m.ColumnName
+		// Тип уже добавленной колонки в SQLite не меняется, поэтому смена типа в маппинге запрещена
+		if m.ColumnType != columnType && source != "metadata" {
+			if tableName, err := GetCatalogTableName(db, catalogName); err == nil {
+				exists, err := catalogColumnExists(db, tableName, m.ColumnName)
+				if err != nil {
+					return nil, err
+				}
+				if exists {
+					return nil, fmt.Errorf("column %s already exists with type %s, type cannot be changed to %s", m.ColumnName, m.ColumnType, columnType)
+				}
+			}
+		}
+	}
+	columnName = uniqueCatalogColumnName(columnName, taken)
+
+	// Маппинги из метаданных выгрузки не перезаписывают маппинги, объявленные через API
+	conflictClause := `DO UPDATE SET column_type = excluded.column_type, source = excluded.source`
+	if source == "metadata" {
+		conflictClause = `DO NOTHING`
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO catalog_column_mappings (catalog_name, attribute_name, column_name, column_type, source)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(catalog_name, attribute_name) `+conflictClause,
+		catalogName, attributeName, columnName, columnType, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save column mapping: %w", err)
+	}
+
+	// Если таблица справочника уже существует - сразу добавляем колонку
+	if tableName, err := GetCatalogTableName(db, catalogName); err == nil {
+		if err := ApplyCatalogColumnMappings(db, catalogName, tableName); err != nil {
+			return nil, err
+		}
+	}
+
+	mappings, err := GetCatalogColumnMappings(db, catalogName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range mappings {
+		if mappings[i].AttributeName == attributeName {
+			return &mappings[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("column mapping not found after save: %s.%s", catalogName, attributeName)
+}
+
+// GetCatalogColumnMappings возвращает маппинги реквизитов справочника (все справочники, если имя пустое)
+func GetCatalogColumnMappings(db *sql.DB, catalogName string) ([]CatalogColumnMapping, error) {
+	query := `
+		SELECT id, catalog_name, attribute_name, column_name, column_type, COALESCE(source, ''), created_at
+		FROM catalog_column_mappings
+	`
+	var args []interface{}
+	if catalogName != "" {
+		query += " WHERE catalog_name = ?"
+		args = append(args, catalogName)
+	}
+	query += " ORDER BY catalog_name, attribute_name"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		// В БД без таблицы маппингов (не единая БД справочников) маппингов нет
+		if strings.Contains(err.Error(), "no such table") {
+			return []CatalogColumnMapping{}, nil
+		}
+		return nil, fmt.Errorf("failed to get column mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := make([]CatalogColumnMapping, 0)
+	for rows.Next() {
+		var m CatalogColumnMapping
+		if err := rows.Scan(&m.ID, &m.CatalogName, &m.AttributeName, &m.ColumnName, &m.ColumnType, &m.Source, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan column mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column mappings: %w", err)
+	}
+
+	return mappings, nil
+}
+
+// GetCatalogColumnMappingsByTable возвращает маппинги реквизитов по имени таблицы справочника
+func GetCatalogColumnMappingsByTable(db *sql.DB, tableName string) ([]CatalogColumnMapping, error) {
+	catalogName, err := GetCatalogNameFromTable(db, tableName)
+	if err != nil {
+		return []CatalogColumnMapping{}, nil
+	}
+	return GetCatalogColumnMappings(db, catalogName)
+}
+
+// DeleteCatalogColumnMapping удаляет маппинг реквизита
+// Колонка в таблице справочника остается (SQLite не поддерживает удаление колонок в старых версиях),
+// но перестает заполняться при загрузке
+func DeleteCatalogColumnMapping(db *sql.DB, catalogName, attributeName string) error {
+	result, err := db.Exec(`DELETE FROM catalog_column_mappings WHERE catalog_name = ? AND attribute_name = ?`, catalogName, attributeName)
+	if err != nil {
+		return fmt.Errorf("failed to delete column mapping: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("column mapping not found: %s.%s", catalogName, attributeName)
+	}
+	return nil
+}
+
+// ApplyCatalogColumnMappings добавляет в таблицу справочника колонки и индексы для всех маппингов
+func ApplyCatalogColumnMappings(db *sql.DB, catalogName, tableName string) error {
+	if !isValidTableName(tableName) {
+		return fmt.Errorf("invalid table name: %s", tableName)
+	}
+
+	mappings, err := GetCatalogColumnMappings(db, catalogName)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range mappings {
+		sqlType := catalogColumnTypes[m.ColumnType]
+		if sqlType == "" {
+			sqlType = "TEXT"
+		}
+
+		_, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, tableName, m.ColumnName, sqlType))
+		if err != nil {
+			errStr := strings.ToLower(err.Error())
+			if !strings.Contains(errStr, "duplicate column") && !strings.Contains(errStr, "already exists") {
+				return fmt.Errorf("failed to add column %s to %s: %w", m.ColumnName, tableName, err)
+			}
+		}
+
+		indexSQL := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s(%s)`, tableName, m.ColumnName, tableName, m.ColumnName)
+		if _, err := db.Exec(indexSQL); err != nil {
+			return fmt.Errorf("failed to create index for column %s: %w", m.ColumnName, err)
+		}
+	}
+
+	return nil
+}
+
+// BackfillCatalogColumn заполняет продвинутую колонку для уже загруженных элементов справочника.
+// sealed определяет зашифрованные реквизиты: такие элементы пропускаются, чтобы открытые значения
+// не попали в колонку (nil - шифрование не проверяется). Возвращает количество обновленных строк
+func BackfillCatalogColumn(db *sql.DB, tableName string, mapping CatalogColumnMapping, sealed func(string) bool) (int, error) {
+	if !isValidTableName(tableName) || !isValidTableName(mapping.ColumnName) {
+		return 0, fmt.Errorf("invalid table or column name")
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT id, attributes_xml FROM %s WHERE %s IS NULL`, tableName, mapping.ColumnName))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read catalog items: %w", err)
+	}
+
+	type pendingUpdate struct {
+		id    int
+		value interface{}
+	}
+	var updates []pendingUpdate
+	for rows.Next() {
+		var id int
+		var attrs sql.NullString
+		if err := rows.Scan(&id, &attrs); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan catalog item: %w", err)
+		}
+		if sealed != nil && sealed(attrs.String) {
+			continue
+		}
+		values := ExtractAttributeValues(attrs.String)
+		if value := convertCatalogColumnValue(values[mapping.AttributeName], mapping.ColumnType); value != nil {
+			updates = append(updates, pendingUpdate{id: id, value: value})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("error iterating catalog items: %w", err)
+	}
+	rows.Close()
+
+	if len(updates) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, tableName, mapping.ColumnName))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare backfill statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, u := range updates {
+		if _, err := stmt.Exec(u.value, u.id); err != nil {
+			return 0, fmt.Errorf("failed to backfill item %d: %w", u.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit backfill: %w", err)
+	}
+
+	return len(updates), nil
+}
+
+// ExtractAttributeValues разбирает реквизиты элемента (JSON-объект или последовательность
+// XML-элементов вида <Имя>значение</Имя>) в карту имя -> значение.
+// Ошибки разбора игнорируются: возвращаются реквизиты, прочитанные до ошибки
+func ExtractAttributeValues(attributes string) map[string]string {
+	values, _ := ParseAttributeValues(attributes)
+	return values
+}
+
+// ParseAttributeValues разбирает реквизиты элемента как ExtractAttributeValues, но сообщает об ошибке формата
+func ParseAttributeValues(attributes string) (map[string]string, error) {
+	values := make(map[string]string)
+	attributes = strings.TrimSpace(attributes)
+	if attributes == "" {
+		return values, nil
+	}
+
+	// JSON формат
+	if strings.HasPrefix(attributes, "{") {
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(attributes), &raw); err != nil {
+			return values, fmt.Errorf("invalid attributes JSON: %w", err)
+		}
+		for key, value := range raw {
+			if value != nil {
+				values[key] = fmt.Sprint(value)
+			}
+		}
+		return values, nil
+	}
+
+	// XML может прийти экранированным
+	if strings.Contains(attributes, "&lt;") && !strings.Contains(attributes, "<") {
+		attributes = html.UnescapeString(attributes)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader("<root>" + attributes + "</root>"))
+	depth := 0
+	var current string
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return values, fmt.Errorf("invalid attributes XML: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				current = t.Name.Local
+				text.Reset()
+			}
+		case xml.CharData:
+			if depth == 2 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 2 && current != "" {
+				values[current] = strings.TrimSpace(text.String())
+				current = ""
+			}
+			depth--
+		}
+	}
+
+	return values, nil
+}
+
+// convertCatalogColumnValue приводит значение реквизита к типу колонки (nil - значение отсутствует или не приводится)
+func convertCatalogColumnValue(value, columnType string) interface{} {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	switch columnType {
+	case "integer":
+		cleaned := strings.NewReplacer(" ", "", "\u00a0", "").Replace(value)
+		if n, err := strconv.ParseInt(cleaned, 10, 64); err == nil {
+			return n
+		}
+		return nil
+	case "real":
+		cleaned := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(value)
+		if f, err := strconv.ParseFloat(cleaned, 64); err == nil {
+			return f
+		}
+		return nil
+	default:
+		return value
+	}
+}
+
+// SearchCatalogItemsByAttribute ищет элементы справочника по значению реквизита.
+// Если реквизит продвинут в колонку - используется индексированная колонка,
+// иначе выполняется поиск по XML реквизитов (LIKE). Зашифрованные элементы не находятся ни одним способом:
+// их реквизиты не продвигаются в колонки
+func SearchCatalogItemsByAttribute(db *sql.DB, catalogName, attributeName, value string, limit, offset int) ([]map[string]interface{}, bool, error) {
+	tableName, err := GetCatalogTableName(db, catalogName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	mappings, err := GetCatalogColumnMappings(db, catalogName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var where string
+	var args []interface{}
+	indexed := false
+	for _, m := range mappings {
+		if m.AttributeName == attributeName {
+			where = m.ColumnName + " = ?"
+			args = append(args, convertCatalogColumnValue(value, m.ColumnType))
+			indexed = true
+			break
+		}
+	}
+	if !indexed {
+		where = "(attributes_xml LIKE ? OR attributes_xml LIKE ?)"
+		args = append(args,
+			"%<"+attributeName+">"+value+"</"+attributeName+">%",
+			"%\""+attributeName+"\": \""+value+"\"%")
+	}
+
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, upload_id, reference, code, name, attributes_xml
+		FROM %s
+		WHERE %s
+		ORDER BY id
+		LIMIT ? OFFSET ?
+	`, tableName, where), args...)
+	if err != nil {
+		return nil, indexed, fmt.Errorf("failed to search catalog items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var id, uploadID int
+		var reference string
+		var code, name, attrs sql.NullString
+		if err := rows.Scan(&id, &uploadID, &reference, &code, &name, &attrs); err != nil {
+			return nil, indexed, fmt.Errorf("failed to scan catalog item: %w", err)
+		}
+		items = append(items, map[string]interface{}{
+			"id":         id,
+			"upload_id":  uploadID,
+			"reference":  reference,
+			"code":       code.String,
+			"name":       name.String,
+			"attributes": attrs.String,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, indexed, fmt.Errorf("error iterating catalog items: %w", err)
+	}
+
+	return items, indexed, nil
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestExtractAttributeValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		key   string
+		want  string
+	}{
+		{"xml", "<Артикул>ART-001</Артикул><Производитель>ООО Ромашка</Производитель>", "Производитель", "ООО Ромашка"},
+		{"escaped xml", "&lt;Вес&gt;1,5&lt;/Вес&gt;", "Вес", "1,5"},
+		{"json", `{"Артикул": "ART-001", "Производитель": "Бош"}`, "Производитель", "Бош"},
+		{"empty", "", "Производитель", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExtractAttributeValues(tc.input)[tc.key]; got != tc.want {
+				t.Errorf("ExtractAttributeValues(%q)[%q] = %q, want %q", tc.input, tc.key, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCatalogColumnMappingPromotion(t *testing.T) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("uuid-columns", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	// Маппинг объявлен до создания таблицы справочника
+	if _, err := SaveCatalogColumnMapping(db.conn, "Номенклатура", "Производитель", "text", "api"); err != nil {
+		t.Fatalf("SaveCatalogColumnMapping failed: %v", err)
+	}
+	tableName, err := GetOrCreateCatalogTable(db.conn, "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetOrCreateCatalogTable failed: %v", err)
+	}
+
+	items := []struct{ ref, attrs string }{
+		{"ref-1", "<Производитель>Бош</Производитель>"},
+		{"ref-2", "<Производитель>Макита</Производитель><Вес>2,5</Вес>"},
+	}
+	for _, item := range items {
+		if err := db.AddCatalogItemToTable(tableName, upload.ID, item.ref, item.ref, "Дрель", item.attrs, ""); err != nil {
+			t.Fatalf("AddCatalogItemToTable failed: %v", err)
+		}
+	}
+
+	found, indexed, err := SearchCatalogItemsByAttribute(db.conn, "Номенклатура", "Производитель", "Макита", 10, 0)
+	if err != nil {
+		t.Fatalf("SearchCatalogItemsByAttribute failed: %v", err)
+	}
+	if !indexed || len(found) != 1 || found[0]["reference"] != "ref-2" {
+		t.Fatalf("expected indexed match ref-2, got indexed=%v items=%v", indexed, found)
+	}
+
+	// Маппинг на существующую таблицу с дозаполнением
+	mapping, err := SaveCatalogColumnMapping(db.conn, "Номенклатура", "Вес", "real", "api")
+	if err != nil {
+		t.Fatalf("SaveCatalogColumnMapping failed: %v", err)
+	}
+	backfilled, err := BackfillCatalogColumn(db.conn, tableName, *mapping, nil)
+	if err != nil {
+		t.Fatalf("BackfillCatalogColumn failed: %v", err)
+	}
+	if backfilled != 1 {
+		t.Errorf("expected 1 backfilled row, got %d", backfilled)
+	}
+
+	var weight float64
+	if err := db.conn.QueryRow("SELECT "+mapping.ColumnName+" FROM "+tableName+" WHERE reference = 'ref-2'").Scan(&weight); err != nil {
+		t.Fatalf("Failed to read promoted column: %v", err)
+	}
+	if weight != 2.5 {
+		t.Errorf("expected weight 2.5, got %f", weight)
+	}
+
+	// Маппинг из метаданных не перезаписывает тип, заданный через API
+	if _, err := SaveCatalogColumnMapping(db.conn, "Номенклатура", "Вес", "text", "metadata"); err != nil {
+		t.Fatalf("SaveCatalogColumnMapping failed: %v", err)
+	}
+	mappings, err := GetCatalogColumnMappings(db.conn, "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetCatalogColumnMappings failed: %v", err)
+	}
+	for _, m := range mappings {
+		if m.AttributeName == "Вес" && m.ColumnType != "real" {
+			t.Errorf("metadata mapping overrode API type: %s", m.ColumnType)
+		}
+	}
+
+	// Тип уже добавленной колонки не меняется
+	if _, err := SaveCatalogColumnMapping(db.conn, "Номенклатура", "Вес", "text", "api"); err == nil {
+		t.Error("expected error when changing type of existing column")
+	}
+
+	// Реквизиты с совпадающей транслитерацией получают разные колонки
+	upper, err := SaveCatalogColumnMapping(db.conn, "Номенклатура", "ВЕС", "text", "api")
+	if err != nil {
+		t.Fatalf("SaveCatalogColumnMapping failed: %v", err)
+	}
+	if upper.ColumnName != mapping.ColumnName+"_2" {
+		t.Errorf("colliding attribute column = %s, want %s_2", upper.ColumnName, mapping.ColumnName)
+	}
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// CatalogGroupPathAttribute имя атрибута с путем групп 1С элемента ("Материалы / Крепеж"). Если атрибут
+// выбран в атрибутах промпта проекта, путь групп передается AI и используется классификацией как подсказка
+const CatalogGroupPathAttribute = "Группа1С"
+
+// catalogGroupPathSeparator разделитель групп в пути
+const catalogGroupPathSeparator = " / "
+
+// emptyReference пустая ссылка 1С (элемент в корне справочника)
+const emptyReference = "00000000-0000-0000-0000-000000000000"
+
+// CatalogHierarchyNode положение элемента справочника в иерархии групп 1С (ЭтоГруппа/Родитель)
+type CatalogHierarchyNode struct {
+	Reference       string `json:"reference"`
+	ParentReference string `json:"parent_reference,omitempty"`
+	IsGroup         bool   `json:"is_group"`
+	Code            string `json:"code,omitempty"`
+	Name            string `json:"name"`
+}
+
+// CatalogTreeNode узел дерева справочника
+type CatalogTreeNode struct {
+	Reference string             `json:"reference"`
+	Code      string             `json:"code,omitempty"`
+	Name      string             `json:"name"`
+	IsGroup   bool               `json:"is_group"`
+	Children  []*CatalogTreeNode `json:"children,omitempty"`
+}
+
+// NormalizeParentReference возвращает ссылку на родителя; пустая ссылка 1С означает корень
+func NormalizeParentReference(reference string) string {
+	reference = strings.TrimSpace(reference)
+	if reference == emptyReference {
+		return ""
+	}
+	return reference
+}
+
+// ParseIsGroup разбирает значение ЭтоГруппа из реквизитов 1С
+func ParseIsGroup(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "да", "истина":
+		return true
+	}
+	return false
+}
+
+// CreateCatalogHierarchyTable создает таблицу иерархии групп справочников выгрузок
+func CreateCatalogHierarchyTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS catalog_hierarchy (
+			upload_id INTEGER NOT NULL,
+			catalog_name TEXT NOT NULL,
+			reference TEXT NOT NULL,
+			parent_reference TEXT NOT NULL DEFAULT '',
+			is_group INTEGER NOT NULL DEFAULT 0,
+			code TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (upload_id, catalog_name, reference),
+			FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_catalog_hierarchy_parent ON catalog_hierarchy(upload_id, catalog_name, parent_reference);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create catalog hierarchy table: %w", err)
+	}
+	return nil
+}
+
+// SaveCatalogHierarchy сохраняет положение элементов справочника в иерархии. Повторная отправка
+// элемента заменяет прежнее положение
+func (db *DB) SaveCatalogHierarchy(uploadID int, catalogName string, nodes []CatalogHierarchyNode) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO catalog_hierarchy (upload_id, catalog_name, reference, parent_reference, is_group, code, name)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(upload_id, catalog_name, reference) DO UPDATE SET
+			parent_reference = excluded.parent_reference, is_group = excluded.is_group,
+			code = excluded.code, name = excluded.name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare catalog hierarchy statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, node := range nodes {
+		if node.Reference == "" {
+			continue
+		}
+		if _, err := stmt.Exec(uploadID, catalogName, node.Reference, NormalizeParentReference(node.ParentReference),
+			node.IsGroup, node.Code, node.Name); err != nil {
+			return fmt.Errorf("failed to save catalog hierarchy node %s: %w", node.Reference, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// getCatalogHierarchyNodes возвращает сохраненные узлы иерархии справочника выгрузки по порядку кодов
+func (db *DB) getCatalogHierarchyNodes(uploadID int, catalogName string, groupsOnly bool) ([]CatalogHierarchyNode, error) {
+	query := `
+		SELECT reference, parent_reference, is_group, code, name
+		FROM catalog_hierarchy
+		WHERE upload_id = ? AND catalog_name = ?`
+	if groupsOnly {
+		query += ` AND is_group = 1`
+	}
+	rows, err := db.conn.Query(query+` ORDER BY is_group DESC, code, name`, uploadID, catalogName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog hierarchy: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []CatalogHierarchyNode
+	for rows.Next() {
+		var node CatalogHierarchyNode
+		if err := rows.Scan(&node.Reference, &node.ParentReference, &node.IsGroup, &node.Code, &node.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog hierarchy node: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// GetCatalogTree возвращает дерево справочника выгрузки: группы и элементы (groupsOnly - только группы).
+// Элементы, родитель которых не выгружен, и элементы с циклической ссылкой на родителя попадают в корень
+func (db *DB) GetCatalogTree(uploadID int, catalogName string, groupsOnly bool) ([]*CatalogTreeNode, error) {
+	nodes, err := db.getCatalogHierarchyNodes(uploadID, catalogName, groupsOnly)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCatalogTree(nodes), nil
+}
+
+// BuildCatalogTree собирает дерево из узлов иерархии с сохранением их порядка
+func BuildCatalogTree(nodes []CatalogHierarchyNode) []*CatalogTreeNode {
+	parents := make(map[string]string, len(nodes))
+	treeNodes := make(map[string]*CatalogTreeNode, len(nodes))
+	for _, node := range nodes {
+		parents[node.Reference] = NormalizeParentReference(node.ParentReference)
+		treeNodes[node.Reference] = &CatalogTreeNode{Reference: node.Reference, Code: node.Code, Name: node.Name, IsGroup: node.IsGroup}
+	}
+
+	roots := []*CatalogTreeNode{}
+	for _, node := range nodes {
+		treeNode := treeNodes[node.Reference]
+		parent, ok := treeNodes[parents[node.Reference]]
+		if !ok || inCatalogCycle(parents, node.Reference) {
+			roots = append(roots, treeNode)
+			continue
+		}
+		parent.Children = append(parent.Children, treeNode)
+	}
+	return roots
+}
+
+// inCatalogCycle проверяет, что цепочка родителей элемента замыкается
+func inCatalogCycle(parents map[string]string, reference string) bool {
+	visited := map[string]bool{reference: true}
+	for current := parents[reference]; current != ""; current = parents[current] {
+		if visited[current] {
+			return true
+		}
+		visited[current] = true
+	}
+	return false
+}
+
+// catalogGroupPath путь групп от корня до родителя элемента; names - наименования групп по ссылкам
+func catalogGroupPath(parents, names map[string]string, reference string) string {
+	var path []string
+	visited := map[string]bool{reference: true}
+	for current := parents[reference]; current != "" && !visited[current]; current = parents[current] {
+		visited[current] = true
+		name, ok := names[current]
+		if !ok {
+			break
+		}
+		path = append([]string{name}, path...)
+	}
+	return strings.Join(path, catalogGroupPathSeparator)
+}
+
+// GetCatalogGroupPaths возвращает пути групп 1С элементов всех справочников БД по ссылке элемента.
+// Элементы более поздних выгрузок заменяют прежние; в БД без таблицы иерархии результат пустой
+func (db *DB) GetCatalogGroupPaths() (map[string]string, error) {
+	paths := make(map[string]string)
+	var exists int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'catalog_hierarchy'`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check catalog hierarchy table: %w", err)
+	}
+	if exists == 0 {
+		return paths, nil
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT upload_id, catalog_name, reference, parent_reference, is_group, name
+		FROM catalog_hierarchy
+		ORDER BY upload_id, catalog_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog hierarchy: %w", err)
+	}
+	defer rows.Close()
+
+	type catalogKey struct {
+		uploadID    int
+		catalogName string
+	}
+	type catalogLinks struct {
+		parents, groupNames map[string]string
+		references          []string
+	}
+	var order []catalogKey
+	catalogs := make(map[catalogKey]*catalogLinks)
+	for rows.Next() {
+		var key catalogKey
+		var reference, parent, name string
+		var isGroup bool
+		if err := rows.Scan(&key.uploadID, &key.catalogName, &reference, &parent, &isGroup, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog hierarchy node: %w", err)
+		}
+		links, ok := catalogs[key]
+		if !ok {
+			links = &catalogLinks{parents: make(map[string]string), groupNames: make(map[string]string)}
+			catalogs[key] = links
+			order = append(order, key)
+		}
+		links.parents[reference] = parent
+		links.references = append(links.references, reference)
+		if isGroup {
+			links.groupNames[reference] = name
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate catalog hierarchy: %w", err)
+	}
+
+	for _, key := range order {
+		links := catalogs[key]
+		for _, reference := range links.references {
+			if path := catalogGroupPath(links.parents, links.groupNames, reference); path != "" {
+				paths[reference] = path
+			}
+		}
+	}
+	return paths, nil
+}
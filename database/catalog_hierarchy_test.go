@@ -0,0 +1,109 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+// catalogTreeShape компактное представление дерева для сравнения: "Имя(Дети...)"
+func catalogTreeShape(nodes []*CatalogTreeNode) string {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		part := node.Name
+		if len(node.Children) > 0 {
+			part += "(" + catalogTreeShape(node.Children) + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestCatalogHierarchy(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUploadWithDatabase("upload-hierarchy", "8.3", "УТ", nil, "", "", "", 1, "", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateUploadWithDatabase() error = %v", err)
+	}
+
+	nodes := []CatalogHierarchyNode{
+		{Reference: "g1", ParentReference: emptyReference, IsGroup: true, Code: "01", Name: "Материалы"},
+		{Reference: "g2", ParentReference: "g1", IsGroup: true, Code: "02", Name: "Крепеж"},
+		{Reference: "i1", ParentReference: "g2", Code: "10", Name: "Болт"},
+		{Reference: "i2", ParentReference: "missing", Code: "11", Name: "Сирота"},
+		{Reference: "c1", ParentReference: "c2", IsGroup: true, Code: "20", Name: "Цикл1"},
+		{Reference: "c2", ParentReference: "c1", IsGroup: true, Code: "21", Name: "Цикл2"},
+	}
+	if err := db.SaveCatalogHierarchy(upload.ID, "Номенклатура", nodes); err != nil {
+		t.Fatalf("SaveCatalogHierarchy() error = %v", err)
+	}
+	// Повторная отправка элемента заменяет его положение
+	if err := db.SaveCatalogHierarchy(upload.ID, "Номенклатура", []CatalogHierarchyNode{
+		{Reference: "i1", ParentReference: "g2", Code: "10", Name: "Болт М8"},
+	}); err != nil {
+		t.Fatalf("SaveCatalogHierarchy() repeat error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		catalog    string
+		groupsOnly bool
+		want       string
+	}{
+		{"full tree with orphan and cycle", "Номенклатура", false, "Материалы(Крепеж(Болт М8)),Цикл1,Цикл2,Сирота"},
+		{"groups only", "Номенклатура", true, "Материалы(Крепеж),Цикл1,Цикл2"},
+		{"unknown catalog", "Контрагенты", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := db.GetCatalogTree(upload.ID, tt.catalog, tt.groupsOnly)
+			if err != nil {
+				t.Fatalf("GetCatalogTree() error = %v", err)
+			}
+			if got := catalogTreeShape(tree); got != tt.want {
+				t.Errorf("GetCatalogTree() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("group paths", func(t *testing.T) {
+		paths, err := db.GetCatalogGroupPaths()
+		if err != nil {
+			t.Fatalf("GetCatalogGroupPaths() error = %v", err)
+		}
+		want := map[string]string{"g2": "Материалы", "i1": "Материалы / Крепеж", "c1": "Цикл2", "c2": "Цикл1"}
+		if len(paths) != len(want) {
+			t.Errorf("GetCatalogGroupPaths() = %v, want %v", paths, want)
+		}
+		for reference, path := range want {
+			if paths[reference] != path {
+				t.Errorf("path[%s] = %q, want %q", reference, paths[reference], path)
+			}
+		}
+	})
+}
+
+func TestParseIsGroup(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"true", true},
+		{"Истина", true},
+		{" да ", true},
+		{"1", true},
+		{"false", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := ParseIsGroup(tt.value); got != tt.want {
+				t.Errorf("ParseIsGroup(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
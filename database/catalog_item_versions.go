@@ -0,0 +1,238 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Поля элемента справочника в истории изменений; реквизиты - с префиксом CatalogItemAttributeField
+const (
+	CatalogItemFieldCode       = "code"
+	CatalogItemFieldName       = "name"
+	CatalogItemFieldTableParts = "table_parts"
+	CatalogItemAttributeField  = "attributes."
+)
+
+// CatalogItemFieldChange значение поля элемента до и после выгрузки
+type CatalogItemFieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// CatalogItemVersion версия элемента справочника в базе 1С: состояние элемента в выгрузке и поля,
+// изменившиеся относительно предыдущей версии. Версии хранятся отдельно от выгрузок и не удаляются вместе с ними
+type CatalogItemVersion struct {
+	ID            int                               `json:"id"`
+	DatabaseID    int                               `json:"database_id"`
+	CatalogName   string                            `json:"catalog_name"`
+	Reference     string                            `json:"reference"`
+	Version       int                               `json:"version"`
+	UploadID      int                               `json:"upload_id"`
+	UploadUUID    string                            `json:"upload_uuid,omitempty"` // Пусто, если выгрузка удалена
+	Code          string                            `json:"code"`
+	Name          string                            `json:"name"`
+	Attributes    map[string]string                 `json:"attributes"`
+	ChangedFields map[string]CatalogItemFieldChange `json:"changed_fields"` // Пусто у первой версии
+	CreatedAt     time.Time                         `json:"created_at"`
+}
+
+// CreateCatalogItemVersionsTable создает таблицу истории версий элементов справочников единой БД
+func CreateCatalogItemVersionsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS catalog_item_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			database_id INTEGER NOT NULL DEFAULT 0,
+			table_name TEXT NOT NULL,
+			reference TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			upload_id INTEGER NOT NULL,
+			code TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			attributes TEXT NOT NULL DEFAULT '{}',
+			table_parts_hash TEXT NOT NULL DEFAULT '',
+			changed_fields TEXT NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(database_id, table_name, reference, version)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_catalog_item_versions_reference ON catalog_item_versions(reference);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create catalog_item_versions table: %w", err)
+	}
+	return nil
+}
+
+// keyedContentHash HMAC-SHA256 значения на ключе проекта
+func keyedContentHash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// uploadDatabaseID возвращает базу 1С выгрузки; 0, если выгрузка не привязана к базе
+func uploadDatabaseID(tx *sql.Tx, uploadID int) int {
+	var databaseID int
+	if err := tx.QueryRow(`SELECT COALESCE(database_id, 0) FROM uploads WHERE id = ?`, uploadID).Scan(&databaseID); err != nil {
+		return 0
+	}
+	return databaseID
+}
+
+// recordCatalogItemVersion добавляет версию элемента, если он новый для базы или изменился с последней версии.
+// Версии сравниваются по открытым значениям; у зашифрованных элементов в истории хранятся HMAC реквизитов
+// на ключе проекта, чтобы значения нельзя было подобрать перебором
+func recordCatalogItemVersion(tx *sql.Tx, tableName string, databaseID, uploadID int, item CatalogTableItem) error {
+	if item.Sealed && len(item.DigestKey) == 0 {
+		return fmt.Errorf("sealed catalog item %s has no digest key", item.Reference)
+	}
+	attributesXML, tablePartsXML := item.plainContent()
+	attributes := ExtractAttributeValues(attributesXML)
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+	if item.Sealed {
+		for name, value := range attributes {
+			attributes[name] = keyedContentHash(item.DigestKey, value)
+		}
+	}
+	tablePartsHash := ""
+	if tablePartsXML != "" {
+		if item.Sealed {
+			tablePartsHash = keyedContentHash(item.DigestKey, tablePartsXML)
+		} else {
+			tablePartsHash = ClassificationContentHash(tablePartsXML)
+		}
+	}
+
+	var (
+		version        int
+		prevCode       string
+		prevName       string
+		prevAttributes string
+		prevTableParts string
+	)
+	err := tx.QueryRow(`
+		SELECT version, code, name, attributes, table_parts_hash
+		FROM catalog_item_versions
+		WHERE database_id = ? AND table_name = ? AND reference = ?
+		ORDER BY version DESC
+		LIMIT 1
+	`, databaseID, tableName, item.Reference).Scan(&version, &prevCode, &prevName, &prevAttributes, &prevTableParts)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get catalog item version: %w", err)
+	}
+
+	changes := map[string]CatalogItemFieldChange{}
+	if err == nil {
+		previous := map[string]string{}
+		if err := json.Unmarshal([]byte(prevAttributes), &previous); err != nil {
+			return fmt.Errorf("failed to parse catalog item version attributes: %w", err)
+		}
+		changes = diffCatalogItem(prevCode, prevName, previous, prevTableParts, item.Code, item.Name, attributes, tablePartsHash)
+		if len(changes) == 0 {
+			return nil
+		}
+	}
+
+	attributesJSON, err := json.Marshal(attributes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal catalog item attributes: %w", err)
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal catalog item changes: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO catalog_item_versions
+			(database_id, table_name, reference, version, upload_id, code, name, attributes, table_parts_hash, changed_fields, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, databaseID, tableName, item.Reference, version+1, uploadID, item.Code, item.Name,
+		string(attributesJSON), tablePartsHash, string(changesJSON), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record catalog item version: %w", err)
+	}
+	return nil
+}
+
+// diffCatalogItem возвращает поля, значения которых различаются между версиями.
+// Табличные части сравниваются по хешу, их значения в истории не хранятся
+func diffCatalogItem(oldCode, oldName string, oldAttributes map[string]string, oldTableParts string,
+	newCode, newName string, newAttributes map[string]string, newTableParts string) map[string]CatalogItemFieldChange {
+	changes := map[string]CatalogItemFieldChange{}
+	if oldCode != newCode {
+		changes[CatalogItemFieldCode] = CatalogItemFieldChange{Old: oldCode, New: newCode}
+	}
+	if oldName != newName {
+		changes[CatalogItemFieldName] = CatalogItemFieldChange{Old: oldName, New: newName}
+	}
+	for name, value := range newAttributes {
+		if old, ok := oldAttributes[name]; !ok || old != value {
+			changes[CatalogItemAttributeField+name] = CatalogItemFieldChange{Old: old, New: value}
+		}
+	}
+	for name, old := range oldAttributes {
+		if _, ok := newAttributes[name]; !ok {
+			changes[CatalogItemAttributeField+name] = CatalogItemFieldChange{Old: old}
+		}
+	}
+	if oldTableParts != newTableParts {
+		changes[CatalogItemFieldTableParts] = CatalogItemFieldChange{}
+	}
+	return changes
+}
+
+// GetCatalogItemHistory возвращает версии элемента по ссылке во всех базах и справочниках
+// (или в указанных catalogName и databaseID, 0 - любая база) в порядке версий
+func (db *DB) GetCatalogItemHistory(reference, catalogName string, databaseID int) ([]*CatalogItemVersion, error) {
+	conditions := []string{"v.reference = ?"}
+	args := []interface{}{reference}
+	if catalogName != "" {
+		conditions = append(conditions, "m.catalog_name = ?")
+		args = append(args, catalogName)
+	}
+	if databaseID > 0 {
+		conditions = append(conditions, "v.database_id = ?")
+		args = append(args, databaseID)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT v.id, v.database_id, COALESCE(m.catalog_name, v.table_name), v.reference, v.version, v.upload_id,
+		       COALESCE(u.upload_uuid, ''), v.code, v.name, v.attributes, v.changed_fields, v.created_at
+		FROM catalog_item_versions v
+		LEFT JOIN catalog_mappings m ON m.table_name = v.table_name
+		LEFT JOIN uploads u ON u.id = v.upload_id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY v.database_id, v.table_name, v.version
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog item history: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []*CatalogItemVersion
+	for rows.Next() {
+		version := &CatalogItemVersion{}
+		var attributes, changes string
+		if err := rows.Scan(&version.ID, &version.DatabaseID, &version.CatalogName, &version.Reference, &version.Version,
+			&version.UploadID, &version.UploadUUID, &version.Code, &version.Name, &attributes, &changes, &version.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog item version: %w", err)
+		}
+		if err := json.Unmarshal([]byte(attributes), &version.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to parse catalog item version attributes: %w", err)
+		}
+		if err := json.Unmarshal([]byte(changes), &version.ChangedFields); err != nil {
+			return nil, fmt.Errorf("failed to parse catalog item version changes: %w", err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate catalog item history: %w", err)
+	}
+	return versions, nil
+}
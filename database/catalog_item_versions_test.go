@@ -0,0 +1,151 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCatalogItemHistory(t *testing.T) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+
+	tableName, err := GetOrCreateCatalogTable(db.conn, "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetOrCreateCatalogTable failed: %v", err)
+	}
+
+	// Три выгрузки базы 1 и одна выгрузка базы 2
+	uploads := []struct {
+		uuid       string
+		databaseID int
+		name       string
+		attributes string
+		tableParts string
+	}{
+		{"u1", 1, "Дрель", "<Производитель>Бош</Производитель><Вес>2</Вес>", ""},
+		{"u2", 1, "Дрель", "<Производитель>Бош</Производитель><Вес>2</Вес>", ""},
+		{"u3", 1, "Дрель ударная", "<Производитель>Макита</Производитель>", "<Строка>1</Строка>"},
+		{"u4", 2, "Дрель", "<Производитель>Бош</Производитель>", ""},
+	}
+	for _, u := range uploads {
+		upload, err := db.CreateUpload(u.uuid, "8.3", "УправлениеТорговлей")
+		if err != nil {
+			t.Fatalf("Failed to create upload: %v", err)
+		}
+		if _, err := db.Exec(`UPDATE uploads SET database_id = ? WHERE id = ?`, u.databaseID, upload.ID); err != nil {
+			t.Fatalf("Failed to set database_id: %v", err)
+		}
+		if err := db.AddCatalogItemToTable(tableName, upload.ID, "ref-1", "0001", u.name, u.attributes, u.tableParts); err != nil {
+			t.Fatalf("AddCatalogItemToTable failed: %v", err)
+		}
+	}
+
+	// Удаление выгрузки не удаляет историю
+	if _, err := db.Exec(`DELETE FROM uploads WHERE upload_uuid = 'u1'`); err != nil {
+		t.Fatalf("Failed to delete upload: %v", err)
+	}
+
+	versions, err := db.GetCatalogItemHistory("ref-1", "", 1)
+	if err != nil {
+		t.Fatalf("GetCatalogItemHistory() error = %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("versions = %d, want 2 (unchanged upload u2 adds no version)", len(versions))
+	}
+	first, second := versions[0], versions[1]
+	if first.Version != 1 || first.UploadUUID != "" || len(first.ChangedFields) != 0 || first.Attributes["Вес"] != "2" {
+		t.Errorf("first version = %+v, want initial version of deleted upload", first)
+	}
+	if second.Version != 2 || second.UploadUUID != "u3" || second.CatalogName != "Номенклатура" {
+		t.Errorf("second version = %+v, want version 2 from u3", second)
+	}
+
+	tests := []struct {
+		field string
+		want  CatalogItemFieldChange
+	}{
+		{CatalogItemFieldName, CatalogItemFieldChange{Old: "Дрель", New: "Дрель ударная"}},
+		{CatalogItemAttributeField + "Производитель", CatalogItemFieldChange{Old: "Бош", New: "Макита"}},
+		{CatalogItemAttributeField + "Вес", CatalogItemFieldChange{Old: "2"}},
+		{CatalogItemFieldTableParts, CatalogItemFieldChange{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, ok := second.ChangedFields[tt.field]
+			if !ok || got != tt.want {
+				t.Errorf("ChangedFields[%s] = %+v, %v, want %+v", tt.field, got, ok, tt.want)
+			}
+		})
+	}
+	if _, ok := second.ChangedFields[CatalogItemFieldCode]; ok {
+		t.Errorf("ChangedFields contains unchanged code: %+v", second.ChangedFields)
+	}
+
+	// Версии ведутся отдельно по базам 1С
+	all, err := db.GetCatalogItemHistory("ref-1", "Номенклатура", 0)
+	if err != nil || len(all) != 3 || all[2].DatabaseID != 2 || all[2].Version != 1 {
+		t.Errorf("history across databases = %d versions, %v, want 3 with database 2 starting at version 1", len(all), err)
+	}
+}
+
+func TestSealedCatalogItems(t *testing.T) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+
+	mapping, err := SaveCatalogColumnMapping(db.conn, "Номенклатура", "Производитель", "text", "api")
+	if err != nil {
+		t.Fatalf("SaveCatalogColumnMapping failed: %v", err)
+	}
+	tableName, err := GetOrCreateCatalogTable(db.conn, "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetOrCreateCatalogTable failed: %v", err)
+	}
+
+	// Шифртекст при каждой отправке разный, открытые значения совпадают
+	for i, ciphertext := range []string{"enc:v1:p1:AAAA", "enc:v1:p1:BBBB"} {
+		upload, err := db.CreateUpload(fmt.Sprintf("sealed-%d", i), "8.3", "УправлениеТорговлей")
+		if err != nil {
+			t.Fatalf("Failed to create upload: %v", err)
+		}
+		inserted, _, err := db.AddCatalogItemsToTable(tableName, upload.ID, []CatalogTableItem{{
+			Reference:          "ref-1",
+			Code:               "0001",
+			Name:               "Дрель",
+			AttributesXML:      ciphertext,
+			TablePartsXML:      ciphertext,
+			Sealed:             true,
+			PlainAttributesXML: "<Производитель>Бош</Производитель>",
+			PlainTablePartsXML: "<Строка>1</Строка>",
+			DigestKey:          []byte("project-digest-key"),
+		}})
+		if err != nil || inserted != 1 {
+			t.Fatalf("AddCatalogItemsToTable() = %d, %v", inserted, err)
+		}
+	}
+
+	// Открытые значения зашифрованных элементов в типизированные колонки не попадают
+	found, indexed, err := SearchCatalogItemsByAttribute(db.conn, "Номенклатура", "Производитель", "Бош", 10, 0)
+	if err != nil || !indexed || len(found) != 0 {
+		t.Errorf("search by promoted column = %d items, indexed %v, %v; want none", len(found), indexed, err)
+	}
+	sealed := func(value string) bool { return strings.HasPrefix(value, "enc:") }
+	if backfilled, err := BackfillCatalogColumn(db.conn, tableName, *mapping, sealed); err != nil || backfilled != 0 {
+		t.Errorf("BackfillCatalogColumn() = %d, %v; want sealed items skipped", backfilled, err)
+	}
+
+	versions, err := db.GetCatalogItemHistory("ref-1", "", 0)
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("versions = %d, %v; want 1 (same plaintext adds no version)", len(versions), err)
+	}
+	// Хеш зависит от ключа проекта: без ключа значение по хешу не подобрать
+	if got := versions[0].Attributes["Производитель"]; got != keyedContentHash([]byte("project-digest-key"), "Бош") {
+		t.Errorf("version attribute = %q, want keyed hash instead of plaintext", got)
+	}
+}
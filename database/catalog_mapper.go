@@ -33,12 +33,38 @@ func NormalizeCatalogName(catalogName string) string {
 		return "unknown_items"
 	}
 
+	tableName := transliterateIdentifier(catalogName)
+
+	// 6. Если имя пустое, используем дефолтное
+	if tableName == "" {
+		tableName = "unknown"
+	}
+
+	// 7. Добавляем суффикс _items
+	tableName += "_items"
+
+	// 8. Если имя начинается с цифры, добавляем префикс
+	if len(tableName) > 0 && tableName[0] >= '0' && tableName[0] <= '9' {
+		tableName = "cat_" + tableName
+	}
+
+	// 9. Ограничиваем длину (SQLite позволяет до 1024 символов, но разумно ограничить)
+	if len(tableName) > 64 {
+		tableName = tableName[:64]
+	}
+
+	return tableName
+}
+
+// transliterateIdentifier преобразует произвольное имя в идентификатор SQL
+// (транслитерация, нижний регистр, подчеркивания вместо разделителей)
+func transliterateIdentifier(name string) string {
 	// 1. Убираем пробелы в начале и конце
-	catalogName = strings.TrimSpace(catalogName)
+	name = strings.TrimSpace(name)
 
 	// 2. Транслитерация
 	result := strings.Builder{}
-	for _, ch := range catalogName {
+	for _, ch := range name {
 		if translitStr, ok := translitMap[ch]; ok {
 			result.WriteString(translitStr)
 		} else if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9') {
@@ -51,38 +77,18 @@ func NormalizeCatalogName(catalogName string) string {
 		// Остальные символы пропускаем
 	}
 
-	tableName := result.String()
+	identifier := result.String()
 
 	// 3. Приводим к нижнему регистру
-	tableName = strings.ToLower(tableName)
+	identifier = strings.ToLower(identifier)
 
 	// 4. Удаляем множественные подчеркивания
-	for strings.Contains(tableName, "__") {
-		tableName = strings.ReplaceAll(tableName, "__", "_")
+	for strings.Contains(identifier, "__") {
+		identifier = strings.ReplaceAll(identifier, "__", "_")
 	}
 
 	// 5. Удаляем подчеркивания в начале и конце
-	tableName = strings.Trim(tableName, "_")
-
-	// 6. Если имя пустое, используем дефолтное
-	if tableName == "" {
-		tableName = "unknown"
-	}
-
-	// 7. Добавляем суффикс _items
-	tableName += "_items"
-
-	// 8. Если имя начинается с цифры, добавляем префикс
-	if len(tableName) > 0 && tableName[0] >= '0' && tableName[0] <= '9' {
-		tableName = "cat_" + tableName
-	}
-
-	// 9. Ограничиваем длину (SQLite позволяет до 1024 символов, но разумно ограничить)
-	if len(tableName) > 64 {
-		tableName = tableName[:64]
-	}
-
-	return tableName
+	return strings.Trim(identifier, "_")
 }
 
 // GetOrCreateCatalogTable получает имя таблицы для справочника или создает новую
@@ -102,6 +108,11 @@ func GetOrCreateCatalogTable(db *sql.DB, catalogName string) (string, error) {
 		return "", fmt.Errorf("failed to create catalog table: %w", err)
 	}
 
+	// Добавляем колонки для заранее объявленных маппингов реквизитов
+	if err := ApplyCatalogColumnMappings(db, catalogName, tableName); err != nil {
+		return "", fmt.Errorf("failed to apply column mappings: %w", err)
+	}
+
 	// Сохраняем маппинг
 	if err := SaveCatalogMapping(db, catalogName, tableName); err != nil {
 		return "", fmt.Errorf("failed to save catalog mapping: %w", err)
@@ -112,6 +123,11 @@ func GetOrCreateCatalogTable(db *sql.DB, catalogName string) (string, error) {
 
 // GetCatalogTableName получает имя таблицы для справочника из маппинга
 func GetCatalogTableName(db *sql.DB, catalogName string) (string, error) {
+	cacheKey := catalogTableCacheKey{conn: db, catalogName: catalogName}
+	if cached, ok := catalogTableLookupCache.get(cacheKey); ok {
+		return cached.(string), nil
+	}
+
 	query := `SELECT table_name FROM catalog_mappings WHERE catalog_name = ?`
 	
 	var tableName string
@@ -123,6 +139,7 @@ func GetCatalogTableName(db *sql.DB, catalogName string) (string, error) {
 		return "", fmt.Errorf("failed to get catalog table name: %w", err)
 	}
 
+	catalogTableLookupCache.put(cacheKey, tableName)
 	return tableName, nil
 }
 
@@ -135,6 +152,7 @@ func SaveCatalogMapping(db *sql.DB, catalogName, tableName string) error {
 		return fmt.Errorf("failed to save catalog mapping: %w", err)
 	}
 
+	catalogTableLookupCache.remove(catalogTableCacheKey{conn: db, catalogName: catalogName})
 	return nil
 }
 
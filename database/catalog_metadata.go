@@ -0,0 +1,472 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// CatalogAttributeDefinition описание реквизита справочника из метаданных 1С
+type CatalogAttributeDefinition struct {
+	Name      string `json:"name"`
+	Synonym   string `json:"synonym,omitempty"`
+	TypeName  string `json:"type_name"` // Тип в терминах 1С: Строка, Число, СправочникСсылка.Контрагенты
+	Kind      string `json:"kind"`      // Вид значения (ConstantKind*): string, number, boolean, date, reference
+	Length    int    `json:"length,omitempty"`
+	Precision int    `json:"precision,omitempty"`
+	Scale     int    `json:"scale,omitempty"`
+	Required  bool   `json:"required"`
+}
+
+// CatalogTablePartDefinition описание табличной части справочника
+type CatalogTablePartDefinition struct {
+	Name       string                       `json:"name"`
+	Synonym    string                       `json:"synonym,omitempty"`
+	Attributes []CatalogAttributeDefinition `json:"attributes"`
+}
+
+// CatalogMetadata метаданные справочника в рамках выгрузки
+type CatalogMetadata struct {
+	UploadID    int                          `json:"upload_id"`
+	CatalogName string                       `json:"catalog_name"`
+	Synonym     string                       `json:"synonym,omitempty"`
+	Attributes  []CatalogAttributeDefinition `json:"attributes"`
+	TableParts  []CatalogTablePartDefinition `json:"table_parts"`
+	UpdatedAt   time.Time                    `json:"updated_at"`
+}
+
+// Attribute возвращает описание реквизита шапки по имени
+func (m *CatalogMetadata) Attribute(name string) (CatalogAttributeDefinition, bool) {
+	for _, attr := range m.Attributes {
+		if attr.Name == name {
+			return attr, true
+		}
+	}
+	return CatalogAttributeDefinition{}, false
+}
+
+// ColumnType возвращает тип продвигаемой колонки для реквизита (text, integer, real)
+func (a CatalogAttributeDefinition) ColumnType() string {
+	if a.Kind != ConstantKindNumber {
+		return "text"
+	}
+	if a.Scale == 0 && a.Precision > 0 {
+		return "integer"
+	}
+	return "real"
+}
+
+// CastValue приводит значение реквизита к объявленному типу.
+// Пустое значение (и пустая дата 1С) возвращается как nil; ошибка означает несоответствие типу.
+func (a CatalogAttributeDefinition) CastValue(value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	switch a.Kind {
+	case ConstantKindBoolean:
+		if parsed, ok := parseConstantBool(value); ok {
+			return parsed, nil
+		}
+		return nil, fmt.Errorf("value %q is not a boolean", value)
+	case ConstantKindNumber:
+		parsed, ok := parseConstantNumber(value)
+		if !ok {
+			return nil, fmt.Errorf("value %q is not a number", value)
+		}
+		if a.ColumnType() == "integer" {
+			if parsed != float64(int64(parsed)) {
+				return nil, fmt.Errorf("value %q is not an integer", value)
+			}
+			return int64(parsed), nil
+		}
+		return parsed, nil
+	case ConstantKindDate:
+		parsed, ok := parseConstantDate(value)
+		if !ok {
+			return nil, fmt.Errorf("value %q is not a date", value)
+		}
+		if parsed.Year() <= 1 {
+			return nil, nil
+		}
+		return parsed.Format(constantDateLayout), nil
+	case ConstantKindString:
+		if a.Length > 0 && utf8.RuneCountInString(value) > a.Length {
+			return nil, fmt.Errorf("value length %d exceeds declared length %d", utf8.RuneCountInString(value), a.Length)
+		}
+	}
+	return value, nil
+}
+
+// CastAttributeValues приводит извлеченные реквизиты элемента к объявленным типам.
+// Необъявленные реквизиты и значения, не приводимые к типу, возвращаются строками.
+func (m *CatalogMetadata) CastAttributeValues(values map[string]string) map[string]interface{} {
+	typed := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		typed[name] = value
+		if attr, ok := m.Attribute(name); ok {
+			if cast, err := attr.CastValue(value); err == nil {
+				typed[name] = cast
+			}
+		}
+	}
+	return typed
+}
+
+// Виды нарушений при проверке элемента справочника по метаданным
+const (
+	AttributeViolationUnknown         = "unknown_attribute"
+	AttributeViolationTypeMismatch    = "type_mismatch"
+	AttributeViolationMissingRequired = "missing_required"
+	AttributeViolationMalformed       = "malformed_attributes"
+)
+
+// AttributeViolation нарушение, найденное при проверке реквизитов элемента по метаданным
+type AttributeViolation struct {
+	Type      string `json:"type"`
+	Attribute string `json:"attribute,omitempty"`
+	Expected  string `json:"expected,omitempty"` // Объявленный тип 1С
+	Actual    string `json:"actual,omitempty"`   // Полученное значение
+	Message   string `json:"message"`
+}
+
+// Severity возвращает важность нарушения в шкале data_quality_issues
+func (v AttributeViolation) Severity() string {
+	switch v.Type {
+	case AttributeViolationMalformed:
+		return "CRITICAL"
+	case AttributeViolationMissingRequired:
+		return "HIGH"
+	case AttributeViolationTypeMismatch:
+		return "MEDIUM"
+	}
+	return "LOW"
+}
+
+// ValidateAttributeValues проверяет реквизиты элемента: обязательные заполнены, значения соответствуют типам,
+// а необъявленные в метаданных реквизиты отмечаются как неизвестные
+func (m *CatalogMetadata) ValidateAttributeValues(values map[string]string) []AttributeViolation {
+	var violations []AttributeViolation
+	for _, attr := range m.Attributes {
+		value, ok := values[attr.Name]
+		if !ok || strings.TrimSpace(value) == "" {
+			if attr.Required {
+				violations = append(violations, AttributeViolation{
+					Type:      AttributeViolationMissingRequired,
+					Attribute: attr.Name,
+					Expected:  attr.TypeName,
+					Message:   "required attribute is missing",
+				})
+			}
+			continue
+		}
+		if _, err := attr.CastValue(value); err != nil {
+			violations = append(violations, AttributeViolation{
+				Type:      AttributeViolationTypeMismatch,
+				Attribute: attr.Name,
+				Expected:  attr.TypeName,
+				Actual:    value,
+				Message:   err.Error(),
+			})
+		}
+	}
+
+	unknown := make([]string, 0)
+	for name := range values {
+		if _, ok := m.Attribute(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		violations = append(violations, AttributeViolation{
+			Type:      AttributeViolationUnknown,
+			Attribute: name,
+			Actual:    values[name],
+			Message:   "attribute is not declared in catalog metadata",
+		})
+	}
+	return violations
+}
+
+// ValidateAttributes разбирает реквизиты элемента и проверяет их по метаданным;
+// неразбираемый XML реквизитов возвращается единственным нарушением
+func (m *CatalogMetadata) ValidateAttributes(attributes string) []AttributeViolation {
+	values, err := ParseAttributeValues(attributes)
+	if err != nil {
+		return []AttributeViolation{{Type: AttributeViolationMalformed, Message: err.Error()}}
+	}
+	return m.ValidateAttributeValues(values)
+}
+
+// NewCatalogAttributeDefinition создает описание реквизита, определяя вид значения по типу 1С
+func NewCatalogAttributeDefinition(name, synonym, typeName string, length, precision, scale int, required bool) CatalogAttributeDefinition {
+	return CatalogAttributeDefinition{
+		Name:      strings.TrimSpace(name),
+		Synonym:   strings.TrimSpace(synonym),
+		TypeName:  strings.TrimSpace(typeName),
+		Kind:      constantKindByDeclaredType(typeName),
+		Length:    length,
+		Precision: precision,
+		Scale:     scale,
+		Required:  required,
+	}
+}
+
+// CreateCatalogMetadataTables создает таблицы метаданных справочников выгрузки
+func CreateCatalogMetadataTables(db *sql.DB) error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS catalog_metadata (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		upload_id INTEGER NOT NULL,
+		catalog_name TEXT NOT NULL,
+		synonym TEXT,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(upload_id, catalog_name)
+	);
+
+	-- Табличные части справочников
+	CREATE TABLE IF NOT EXISTS catalog_table_parts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		upload_id INTEGER NOT NULL,
+		catalog_name TEXT NOT NULL,
+		name TEXT NOT NULL,
+		synonym TEXT,
+		position INTEGER DEFAULT 0,
+		UNIQUE(upload_id, catalog_name, name)
+	);
+
+	-- Реквизиты справочников и табличных частей (table_part = '' для реквизитов шапки)
+	CREATE TABLE IF NOT EXISTS catalog_attribute_definitions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		upload_id INTEGER NOT NULL,
+		catalog_name TEXT NOT NULL,
+		table_part TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL,
+		synonym TEXT,
+		type_name TEXT,
+		kind TEXT NOT NULL DEFAULT 'string',
+		length INTEGER DEFAULT 0,
+		precision INTEGER DEFAULT 0,
+		scale INTEGER DEFAULT 0,
+		required INTEGER DEFAULT 0,
+		position INTEGER DEFAULT 0,
+		UNIQUE(upload_id, catalog_name, table_part, name)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_catalog_attribute_definitions_catalog ON catalog_attribute_definitions(upload_id, catalog_name);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create catalog metadata tables: %w", err)
+	}
+	return nil
+}
+
+// SaveCatalogMetadata сохраняет метаданные справочника, заменяя ранее присланные для этой выгрузки
+func SaveCatalogMetadata(db *sql.DB, metadata *CatalogMetadata) error {
+	metadata.CatalogName = strings.TrimSpace(metadata.CatalogName)
+	if metadata.CatalogName == "" {
+		return fmt.Errorf("catalog name is required")
+	}
+
+	if err := CreateCatalogMetadataTables(db); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO catalog_metadata (upload_id, catalog_name, synonym, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(upload_id, catalog_name) DO UPDATE SET synonym = excluded.synonym, updated_at = CURRENT_TIMESTAMP
+	`, metadata.UploadID, metadata.CatalogName, metadata.Synonym)
+	if err != nil {
+		return fmt.Errorf("failed to save catalog metadata: %w", err)
+	}
+
+	for _, table := range []string{"catalog_table_parts", "catalog_attribute_definitions"} {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE upload_id = ? AND catalog_name = ?`, table),
+			metadata.UploadID, metadata.CatalogName); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	attrStmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO catalog_attribute_definitions
+			(upload_id, catalog_name, table_part, name, synonym, type_name, kind, length, precision, scale, required, position)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare attribute statement: %w", err)
+	}
+	defer attrStmt.Close()
+
+	saveAttributes := func(tablePart string, attributes []CatalogAttributeDefinition) error {
+		for i, attr := range attributes {
+			if attr.Name == "" {
+				return fmt.Errorf("attribute name is required (catalog %s, position %d)", metadata.CatalogName, i)
+			}
+			if attr.Kind == "" {
+				attr.Kind = constantKindByDeclaredType(attr.TypeName)
+			}
+			if _, err := attrStmt.Exec(metadata.UploadID, metadata.CatalogName, tablePart, attr.Name, attr.Synonym,
+				attr.TypeName, attr.Kind, attr.Length, attr.Precision, attr.Scale, attr.Required, i); err != nil {
+				return fmt.Errorf("failed to save attribute %s: %w", attr.Name, err)
+			}
+		}
+		return nil
+	}
+
+	if err := saveAttributes("", metadata.Attributes); err != nil {
+		return err
+	}
+	for i, part := range metadata.TableParts {
+		if part.Name == "" {
+			return fmt.Errorf("table part name is required (catalog %s, position %d)", metadata.CatalogName, i)
+		}
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO catalog_table_parts (upload_id, catalog_name, name, synonym, position)
+			VALUES (?, ?, ?, ?, ?)
+		`, metadata.UploadID, metadata.CatalogName, part.Name, part.Synonym, i); err != nil {
+			return fmt.Errorf("failed to save table part %s: %w", part.Name, err)
+		}
+		if err := saveAttributes(part.Name, part.Attributes); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit catalog metadata: %w", err)
+	}
+	return nil
+}
+
+// GetCatalogMetadata возвращает метаданные справочника выгрузки или nil, если они не присылались
+func GetCatalogMetadata(db *sql.DB, uploadID int, catalogName string) (*CatalogMetadata, error) {
+	list, err := getCatalogMetadata(db, uploadID, catalogName)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// GetUploadCatalogMetadata возвращает метаданные всех справочников выгрузки
+func GetUploadCatalogMetadata(db *sql.DB, uploadID int) ([]CatalogMetadata, error) {
+	return getCatalogMetadata(db, uploadID, "")
+}
+
+// getCatalogMetadata загружает метаданные справочников выгрузки (всех, если имя пустое)
+func getCatalogMetadata(db *sql.DB, uploadID int, catalogName string) ([]CatalogMetadata, error) {
+	filter := ""
+	args := []interface{}{uploadID}
+	if catalogName != "" {
+		filter = " AND catalog_name = ?"
+		args = append(args, catalogName)
+	}
+
+	rows, err := db.Query(`
+		SELECT catalog_name, COALESCE(synonym, ''), updated_at
+		FROM catalog_metadata WHERE upload_id = ?`+filter+`
+		ORDER BY catalog_name
+	`, args...)
+	if err != nil {
+		// Метаданные еще не присылались в эту БД
+		if strings.Contains(err.Error(), "no such table") {
+			return []CatalogMetadata{}, nil
+		}
+		return nil, fmt.Errorf("failed to get catalog metadata: %w", err)
+	}
+
+	list := make([]CatalogMetadata, 0)
+	index := make(map[string]int)
+	for rows.Next() {
+		m := CatalogMetadata{UploadID: uploadID, Attributes: []CatalogAttributeDefinition{}, TableParts: []CatalogTablePartDefinition{}}
+		if err := rows.Scan(&m.CatalogName, &m.Synonym, &m.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan catalog metadata: %w", err)
+		}
+		index[m.CatalogName] = len(list)
+		list = append(list, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating catalog metadata: %w", err)
+	}
+	if len(list) == 0 {
+		return list, nil
+	}
+
+	partRows, err := db.Query(`
+		SELECT catalog_name, name, COALESCE(synonym, '')
+		FROM catalog_table_parts WHERE upload_id = ?`+filter+`
+		ORDER BY catalog_name, position
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog table parts: %w", err)
+	}
+	parts := make(map[string]map[string]int)
+	for partRows.Next() {
+		var catalog string
+		var part CatalogTablePartDefinition
+		if err := partRows.Scan(&catalog, &part.Name, &part.Synonym); err != nil {
+			partRows.Close()
+			return nil, fmt.Errorf("failed to scan catalog table part: %w", err)
+		}
+		i, ok := index[catalog]
+		if !ok {
+			continue
+		}
+		if parts[catalog] == nil {
+			parts[catalog] = make(map[string]int)
+		}
+		part.Attributes = []CatalogAttributeDefinition{}
+		parts[catalog][part.Name] = len(list[i].TableParts)
+		list[i].TableParts = append(list[i].TableParts, part)
+	}
+	partRows.Close()
+	if err := partRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating catalog table parts: %w", err)
+	}
+
+	attrRows, err := db.Query(`
+		SELECT catalog_name, table_part, name, COALESCE(synonym, ''), COALESCE(type_name, ''), kind,
+		       length, precision, scale, required
+		FROM catalog_attribute_definitions WHERE upload_id = ?`+filter+`
+		ORDER BY catalog_name, table_part, position
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog attributes: %w", err)
+	}
+	defer attrRows.Close()
+	for attrRows.Next() {
+		var catalog, tablePart string
+		var attr CatalogAttributeDefinition
+		if err := attrRows.Scan(&catalog, &tablePart, &attr.Name, &attr.Synonym, &attr.TypeName, &attr.Kind,
+			&attr.Length, &attr.Precision, &attr.Scale, &attr.Required); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog attribute: %w", err)
+		}
+		i, ok := index[catalog]
+		if !ok {
+			continue
+		}
+		if tablePart == "" {
+			list[i].Attributes = append(list[i].Attributes, attr)
+		} else if p, ok := parts[catalog][tablePart]; ok {
+			list[i].TableParts[p].Attributes = append(list[i].TableParts[p].Attributes, attr)
+		}
+	}
+	if err := attrRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating catalog attributes: %w", err)
+	}
+
+	return list, nil
+}
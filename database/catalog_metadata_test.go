@@ -0,0 +1,91 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestCatalogAttributeCastValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		attr    CatalogAttributeDefinition
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{"string", NewCatalogAttributeDefinition("Артикул", "", "Строка", 10, 0, 0, false), "ART-001", "ART-001", false},
+		{"string too long", NewCatalogAttributeDefinition("Артикул", "", "Строка", 3, 0, 0, false), "ART-001", nil, true},
+		{"integer", NewCatalogAttributeDefinition("Количество", "", "Число", 0, 10, 0, false), "1 200", int64(1200), false},
+		{"integer with fraction", NewCatalogAttributeDefinition("Количество", "", "Число", 0, 10, 0, false), "1,5", nil, true},
+		{"real", NewCatalogAttributeDefinition("Вес", "", "Число", 0, 15, 3, false), "2,5", 2.5, false},
+		{"not a number", NewCatalogAttributeDefinition("Вес", "", "Число", 0, 15, 3, false), "тяжелый", nil, true},
+		{"boolean", NewCatalogAttributeDefinition("Услуга", "", "Булево", 0, 0, 0, false), "Истина", true, false},
+		{"date", NewCatalogAttributeDefinition("Дата", "", "Дата", 0, 0, 0, false), "01.02.2024", "2024-02-01T00:00:00", false},
+		{"empty date", NewCatalogAttributeDefinition("Дата", "", "Дата", 0, 0, 0, false), "0001-01-01T00:00:00", nil, false},
+		{"reference", NewCatalogAttributeDefinition("Производитель", "", "СправочникСсылка.Контрагенты", 0, 0, 0, false), "Бош", "Бош", false},
+		{"empty", NewCatalogAttributeDefinition("Вес", "", "Число", 0, 15, 3, false), " ", nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.attr.CastValue(tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CastValue(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("CastValue(%q) = %#v, want %#v", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCatalogMetadataStorage(t *testing.T) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+
+	metadata := &CatalogMetadata{
+		UploadID:    1,
+		CatalogName: "Номенклатура",
+		Synonym:     "Номенклатура",
+		Attributes: []CatalogAttributeDefinition{
+			NewCatalogAttributeDefinition("Артикул", "Артикул", "Строка", 25, 0, 0, true),
+			NewCatalogAttributeDefinition("Вес", "Вес, кг", "Число", 0, 15, 3, false),
+		},
+		TableParts: []CatalogTablePartDefinition{{
+			Name:       "ЕдиницыИзмерения",
+			Attributes: []CatalogAttributeDefinition{NewCatalogAttributeDefinition("Коэффициент", "", "Число", 0, 10, 3, false)},
+		}},
+	}
+	if err := SaveCatalogMetadata(db.conn, metadata); err != nil {
+		t.Fatalf("SaveCatalogMetadata failed: %v", err)
+	}
+	// Повторная отправка заменяет описание
+	metadata.Attributes = metadata.Attributes[:1]
+	if err := SaveCatalogMetadata(db.conn, metadata); err != nil {
+		t.Fatalf("SaveCatalogMetadata (repeat) failed: %v", err)
+	}
+
+	got, err := GetCatalogMetadata(db.conn, 1, "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetCatalogMetadata failed: %v", err)
+	}
+	if got == nil || len(got.Attributes) != 1 || len(got.TableParts) != 1 || len(got.TableParts[0].Attributes) != 1 {
+		t.Fatalf("unexpected metadata: %+v", got)
+	}
+	if attr := got.Attributes[0]; attr.Kind != ConstantKindString || attr.Length != 25 || !attr.Required {
+		t.Errorf("unexpected attribute: %+v", attr)
+	}
+
+	// Вес удален из описания при повторной отправке и считается неизвестным реквизитом
+	problems := got.ValidateAttributeValues(map[string]string{"Вес": "1"})
+	if len(problems) != 2 || problems[0].Type != AttributeViolationMissingRequired || problems[0].Attribute != "Артикул" ||
+		problems[1].Type != AttributeViolationUnknown || problems[1].Attribute != "Вес" {
+		t.Errorf("ValidateAttributeValues = %+v, want missing Артикул and unknown Вес", problems)
+	}
+
+	if missing, err := GetCatalogMetadata(db.conn, 2, "Номенклатура"); err != nil || missing != nil {
+		t.Errorf("GetCatalogMetadata(other upload) = %+v, %v; want nil, nil", missing, err)
+	}
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KpvedTranslationClassifier ключ классификатора КПВЭД в таблице переводов;
+// классификаторы категорий указываются своим ID
+const KpvedTranslationClassifier = "kpved"
+
+// translationColumnAliases названия колонок заголовка файла переводов
+var translationColumnAliases = map[string][]string{
+	"code":     {"code", "код"},
+	"language": {"language", "lang", "язык"},
+	"name":     {"name", "translation", "наименование", "перевод"},
+}
+
+// CategoryNameTranslation перевод наименования категории классификатора
+type CategoryNameTranslation struct {
+	Classifier string    `json:"classifier"`
+	Code       string    `json:"code"`
+	Language   string    `json:"language"`
+	Name       string    `json:"name"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CreateCategoryNameTranslationsTable создает таблицу переводов наименований категорий классификаторов
+func CreateCategoryNameTranslationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS category_name_translations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			classifier TEXT NOT NULL,
+			code TEXT NOT NULL,
+			language TEXT NOT NULL,
+			name TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(classifier, code, language)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create category_name_translations table: %w", err)
+	}
+	return nil
+}
+
+// ParseCategoryTranslationRows разбирает строки файла переводов. Колонки определяются по заголовку;
+// без заголовка: код, язык, наименование. Если колонки языка нет или она пуста, используется defaultLanguage.
+// Возвращает переводы и описания отклоненных строк
+func ParseCategoryTranslationRows(rows [][]string, defaultLanguage string) ([]*CategoryNameTranslation, []string) {
+	columns := map[string]int{"code": 0, "language": 1, "name": 2}
+	start := 0
+	if len(rows) > 0 {
+		if header, ok := translationHeaderColumns(rows[0]); ok {
+			columns = header
+			start = 1
+		} else if defaultLanguage != "" && len(rows[0]) == 2 {
+			// Файл из двух колонок без заголовка: код и перевод на язык по умолчанию
+			columns = map[string]int{"code": 0, "name": 1}
+		}
+	}
+
+	cell := func(row []string, column string) string {
+		index, ok := columns[column]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	var translations []*CategoryNameTranslation
+	var issues []string
+	for i := start; i < len(rows); i++ {
+		translation := &CategoryNameTranslation{
+			Code:     cell(rows[i], "code"),
+			Language: strings.ToLower(cell(rows[i], "language")),
+			Name:     cell(rows[i], "name"),
+		}
+		if translation.Language == "" {
+			translation.Language = defaultLanguage
+		}
+		switch {
+		case translation.Code == "" && translation.Name == "":
+			continue
+		case translation.Code == "" || translation.Name == "":
+			issues = append(issues, fmt.Sprintf("row %d: code and name are required", i+1))
+		case translation.Language == "":
+			issues = append(issues, fmt.Sprintf("row %d: language is required", i+1))
+		default:
+			translations = append(translations, translation)
+		}
+	}
+	return translations, issues
+}
+
+// translationHeaderColumns определяет колонки по заголовку; false, если первая строка не заголовок
+func translationHeaderColumns(row []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, cell := range row {
+		value := strings.ToLower(strings.TrimSpace(cell))
+		for column, aliases := range translationColumnAliases {
+			for _, alias := range aliases {
+				if value == alias {
+					columns[column] = i
+				}
+			}
+		}
+	}
+	_, hasCode := columns["code"]
+	_, hasName := columns["name"]
+	return columns, hasCode && hasName
+}
+
+// ImportCategoryNameTranslations сохраняет переводы классификатора; существующие переводы кода
+// на тот же язык заменяются. Возвращает число сохраненных переводов
+func (db *ServiceDB) ImportCategoryNameTranslations(classifier string, translations []*CategoryNameTranslation) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO category_name_translations (classifier, code, language, name, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(classifier, code, language) DO UPDATE SET
+			name = excluded.name, updated_at = excluded.updated_at
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare translation insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, translation := range translations {
+		translation.Classifier = classifier
+		if _, err := stmt.Exec(classifier, translation.Code, translation.Language, translation.Name); err != nil {
+			return 0, fmt.Errorf("failed to save translation for %s: %w", translation.Code, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit translations: %w", err)
+	}
+	return len(translations), nil
+}
+
+// ListCategoryNameTranslations возвращает переводы классификатора (language "" - на все языки)
+func (db *ServiceDB) ListCategoryNameTranslations(classifier, language string) ([]*CategoryNameTranslation, error) {
+	rows, err := db.conn.Query(`
+		SELECT classifier, code, language, name, updated_at
+		FROM category_name_translations
+		WHERE classifier = ? AND (? = '' OR language = ?)
+		ORDER BY code, language
+	`, classifier, language, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query translations: %w", err)
+	}
+	defer rows.Close()
+
+	translations := []*CategoryNameTranslation{}
+	for rows.Next() {
+		var t CategoryNameTranslation
+		if err := rows.Scan(&t.Classifier, &t.Code, &t.Language, &t.Name, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan translation: %w", err)
+		}
+		translations = append(translations, &t)
+	}
+	return translations, rows.Err()
+}
+
+// DeleteCategoryNameTranslations удаляет переводы классификатора (language "" - на все языки)
+func (db *ServiceDB) DeleteCategoryNameTranslations(classifier, language string) (int64, error) {
+	result, err := db.conn.Exec(`
+		DELETE FROM category_name_translations WHERE classifier = ? AND (? = '' OR language = ?)
+	`, classifier, language, language)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete translations: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// LoadCategoryNameTranslations возвращает переводы классификатора на язык: код -> наименование.
+// Для nil db или базы без таблицы переводов возвращается пустой справочник
+func LoadCategoryNameTranslations(db *sql.DB, classifier, language string) (map[string]string, error) {
+	translations := make(map[string]string)
+	if db == nil || language == "" {
+		return translations, nil
+	}
+	rows, err := db.Query(`
+		SELECT code, name FROM category_name_translations WHERE classifier = ? AND language = ?
+	`, classifier, language)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return translations, nil
+		}
+		return nil, fmt.Errorf("failed to load translations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code, name string
+		if err := rows.Scan(&code, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan translation: %w", err)
+		}
+		translations[code] = name
+	}
+	return translations, rows.Err()
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCategoryTranslationRows(t *testing.T) {
+	tests := []struct {
+		name            string
+		rows            [][]string
+		defaultLanguage string
+		want            []CategoryNameTranslation
+		issues          int
+	}{
+		{
+			name: "header in any order",
+			rows: [][]string{{"Язык", "Код", "Наименование"}, {"KK", "01", "Өсімдік шаруашылығы"}, {"", "", ""}},
+			want: []CategoryNameTranslation{{Code: "01", Language: "kk", Name: "Өсімдік шаруашылығы"}},
+		},
+		{
+			name:   "no header",
+			rows:   [][]string{{"A", "kk", "Ауыл шаруашылығы"}, {"B", "kk", ""}},
+			want:   []CategoryNameTranslation{{Code: "A", Language: "kk", Name: "Ауыл шаруашылығы"}},
+			issues: 1,
+		},
+		{
+			name:            "two columns with default language",
+			rows:            [][]string{{"A", "Ауыл шаруашылығы"}},
+			defaultLanguage: "kk",
+			want:            []CategoryNameTranslation{{Code: "A", Language: "kk", Name: "Ауыл шаруашылығы"}},
+		},
+		{
+			name:   "language required",
+			rows:   [][]string{{"code", "name"}, {"A", "Ауыл шаруашылығы"}},
+			issues: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translations, issues := ParseCategoryTranslationRows(tt.rows, tt.defaultLanguage)
+			var got []CategoryNameTranslation
+			for _, translation := range translations {
+				got = append(got, *translation)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("translations = %+v, want %+v", got, tt.want)
+			}
+			if len(issues) != tt.issues {
+				t.Errorf("issues = %v, want %d", issues, tt.issues)
+			}
+		})
+	}
+}
+
+func TestCategoryNameTranslations(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	imported, err := db.ImportCategoryNameTranslations(KpvedTranslationClassifier, []*CategoryNameTranslation{
+		{Code: "A", Language: "kk", Name: "Ауыл"},
+		{Code: "01", Language: "kk", Name: "Өсімдік"},
+		{Code: "01", Language: "en", Name: "Crop production"},
+	})
+	if err != nil || imported != 3 {
+		t.Fatalf("ImportCategoryNameTranslations() = %d, %v", imported, err)
+	}
+	// Повторный импорт заменяет перевод
+	if _, err := db.ImportCategoryNameTranslations(KpvedTranslationClassifier, []*CategoryNameTranslation{
+		{Code: "A", Language: "kk", Name: "Ауыл шаруашылығы"},
+	}); err != nil {
+		t.Fatalf("ImportCategoryNameTranslations() error: %v", err)
+	}
+
+	got, err := LoadCategoryNameTranslations(db.GetDB(), KpvedTranslationClassifier, "kk")
+	want := map[string]string{"A": "Ауыл шаруашылығы", "01": "Өсімдік"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadCategoryNameTranslations() = %v, %v, want %v", got, err, want)
+	}
+	if got, err := LoadCategoryNameTranslations(db.GetDB(), "7", "kk"); err != nil || len(got) != 0 {
+		t.Errorf("LoadCategoryNameTranslations(other classifier) = %v, %v, want empty", got, err)
+	}
+
+	list, err := db.ListCategoryNameTranslations(KpvedTranslationClassifier, "")
+	if err != nil || len(list) != 3 {
+		t.Fatalf("ListCategoryNameTranslations() = %d, %v, want 3", len(list), err)
+	}
+	deleted, err := db.DeleteCategoryNameTranslations(KpvedTranslationClassifier, "en")
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteCategoryNameTranslations() = %d, %v, want 1", deleted, err)
+	}
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Статусы строк файла исправлений классификации
+const (
+	CorrectionStatusApplied   = "applied"   // Код КПВЭД записей исправлен
+	CorrectionStatusConfirmed = "confirmed" // Записи уже имели этот код, классификация подтверждена
+	CorrectionStatusNotFound  = "not_found" // Записи по ссылке или коду не найдены
+	CorrectionStatusInvalid   = "invalid"   // Строка отклонена при проверке
+)
+
+// ClassificationCorrection строка файла исправлений: ссылка или код записи -> исправленный код КПВЭД
+type ClassificationCorrection struct {
+	RowNumber int    `json:"row"`
+	Reference string `json:"reference,omitempty"`
+	Code      string `json:"code,omitempty"`
+	KpvedCode string `json:"kpved_code"`
+	KpvedName string `json:"kpved_name,omitempty"`
+	Status    string `json:"status"`
+	Issue     string `json:"issue,omitempty"`
+	Updated   int    `json:"updated"` // Записей, у которых изменен код КПВЭД
+}
+
+// ClassificationCorrectionsResult итог применения файла исправлений
+type ClassificationCorrectionsResult struct {
+	Rows             int  `json:"rows"`
+	Applied          int  `json:"applied"`
+	Confirmed        int  `json:"confirmed"`
+	NotFound         int  `json:"not_found"`
+	Invalid          int  `json:"invalid"`
+	ItemsUpdated     int  `json:"items_updated"`
+	OutcomesRecorded int  `json:"outcomes_recorded"` // Исходов классификации для калибровки
+	DryRun           bool `json:"dry_run"`
+}
+
+// ApplyClassificationCorrections применяет исправления КПВЭД в одной транзакции. Строки с заполненным
+// статусом (отклоненные при проверке) пропускаются. Записи ищутся по source_reference или code;
+// исправленный код сохраняется как назначенный вручную и подтвержденный, поэтому попадает
+// в подтвержденные классификации (GetVerifiedClassifications). Для записей, классифицированных моделью,
+// исход (совпал ли код модели с исправлением) записывается в classification_outcomes для калибровки.
+// При dryRun изменения откатываются, результат показывает, что было бы применено
+func (db *DB) ApplyClassificationCorrections(corrections []*ClassificationCorrection, editor string, dryRun bool) (*ClassificationCorrectionsResult, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	type matchedItem struct {
+		id, version                          int
+		kpvedCode, kpvedName                 string
+		model, validationStatus, name, group string
+		rawConfidence                        float64
+	}
+
+	result := &ClassificationCorrectionsResult{Rows: len(corrections), DryRun: dryRun}
+	for _, correction := range corrections {
+		if correction.Status != "" {
+			result.Invalid++
+			continue
+		}
+
+		rows, err := tx.Query(`
+			SELECT id, COALESCE(version, 1), COALESCE(kpved_code, ''), COALESCE(kpved_name, ''),
+			       COALESCE(kpved_model, ''), COALESCE(validation_status, ''), COALESCE(normalized_name, ''), COALESCE(category, ''),
+			       COALESCE(kpved_raw_confidence, kpved_confidence, 0)
+			FROM normalized_data
+			WHERE (? != '' AND source_reference = ?) OR (? != '' AND code = ?)
+		`, correction.Reference, correction.Reference, correction.Code, correction.Code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find normalized items for row %d: %w", correction.RowNumber, err)
+		}
+		var items []matchedItem
+		for rows.Next() {
+			var item matchedItem
+			if err := rows.Scan(&item.id, &item.version, &item.kpvedCode, &item.kpvedName,
+				&item.model, &item.validationStatus, &item.name, &item.group, &item.rawConfidence); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan normalized item: %w", err)
+			}
+			items = append(items, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read normalized items: %w", err)
+		}
+		if len(items) == 0 {
+			correction.Status = CorrectionStatusNotFound
+			correction.Issue = "no normalized items with this reference or code"
+			result.NotFound++
+			continue
+		}
+
+		for _, item := range items {
+			if item.kpvedCode == correction.KpvedCode && item.validationStatus == "correct" {
+				continue
+			}
+
+			// Исход модели учитывается в калибровке один раз, пока классификация не подтверждена
+			if item.kpvedCode != "" && item.model != ManualEditModel && item.validationStatus != "correct" {
+				_, err := tx.Exec(`
+					INSERT INTO classification_outcomes (model, source, raw_confidence, correct, reference)
+					VALUES (?, 'kpved', ?, ?, ?)
+				`, item.model, item.rawConfidence, item.kpvedCode == correction.KpvedCode, item.name+" / "+item.group)
+				if err != nil {
+					return nil, fmt.Errorf("failed to record classification outcome: %w", err)
+				}
+				result.OutcomesRecorded++
+			}
+
+			_, err := tx.Exec(`
+				UPDATE normalized_data
+				SET kpved_code = ?, kpved_name = ?, kpved_confidence = 1.0, kpved_raw_confidence = NULL,
+				    kpved_model = ?, confidence_decision = ?, validation_status = 'correct', validation_reason = ?,
+				    version = COALESCE(version, 1) + 1, updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?
+			`, correction.KpvedCode, correction.KpvedName, ManualEditModel, ConfidenceDecisionAccept, "client correction", item.id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to update normalized item %d: %w", item.id, err)
+			}
+
+			details := map[string]interface{}{
+				"row":          correction.RowNumber,
+				"version_from": item.version,
+				"version_to":   item.version + 1,
+			}
+			if item.kpvedCode != correction.KpvedCode {
+				details["changes"] = []NormalizedFieldChange{
+					{Field: "kpved_code", Old: item.kpvedCode, New: correction.KpvedCode},
+					{Field: "kpved_name", Old: item.kpvedName, New: correction.KpvedName},
+				}
+			}
+			record := NewLineageRecord(LineageEventClientCorrection, editor, 0, details)
+			if err := insertLineageRecords(tx, item.id, []*LineageRecord{record}); err != nil {
+				return nil, err
+			}
+			if item.kpvedCode != correction.KpvedCode {
+				correction.Updated++
+			}
+		}
+
+		if correction.Updated > 0 {
+			correction.Status = CorrectionStatusApplied
+			result.Applied++
+		} else {
+			correction.Status = CorrectionStatusConfirmed
+			result.Confirmed++
+		}
+		result.ItemsUpdated += correction.Updated
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// ValidateClassificationCorrections проверяет строки файла исправлений: заполненность, коды КПВЭД
+// по классификатору (код -> наименование) и противоречия внутри файла. Отклоненным строкам
+// выставляется статус invalid, проверенным подставляется наименование кода
+func ValidateClassificationCorrections(corrections []*ClassificationCorrection, classifier map[string]string) {
+	firstRow := make(map[string]*ClassificationCorrection)
+	for _, correction := range corrections {
+		key := correction.Reference + "\x00" + correction.Code
+		first, duplicate := firstRow[key]
+		name, known := classifier[correction.KpvedCode]
+		switch {
+		case correction.Reference == "" && correction.Code == "":
+			correction.Issue = "reference or code is required"
+		case correction.KpvedCode == "":
+			correction.Issue = "kpved code is required"
+		case !kpvedCodeRegex.MatchString(correction.KpvedCode):
+			correction.Issue = "invalid KPVED code format"
+		case !known:
+			correction.Issue = "unknown KPVED code"
+		case duplicate && first.KpvedCode != correction.KpvedCode:
+			correction.Issue = fmt.Sprintf("conflicts with row %d correcting to %s", first.RowNumber, first.KpvedCode)
+		case duplicate:
+			correction.Issue = fmt.Sprintf("duplicate of row %d", first.RowNumber)
+		default:
+			firstRow[key] = correction
+			if strings.TrimSpace(correction.KpvedName) == "" {
+				correction.KpvedName = name
+			}
+			continue
+		}
+		correction.Status = CorrectionStatusInvalid
+	}
+}
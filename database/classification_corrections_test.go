@@ -0,0 +1,99 @@
+package database
+
+import "testing"
+
+func TestClassificationCorrections(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code, kpved_name, kpved_model, kpved_raw_confidence, validation_status)
+		VALUES ('r1', 'Болт', 'c1', 'болт м8', 'крепеж', '25.94.12', 'Гайки', 'glm', 0.7, ''),
+		       ('r2', 'Гайка', 'c2', 'гайка м8', 'крепеж', '25.94.12', 'Гайки', 'glm', 0.9, ''),
+		       ('r3', 'Шайба', 'c3', 'шайба м8', 'крепеж', NULL, NULL, NULL, NULL, '')`)
+	if err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+
+	classifier := map[string]string{"25.94.11": "Болты", "25.94.12": "Гайки", "25.94.13": "Шайбы"}
+	corrections := []*ClassificationCorrection{
+		{RowNumber: 2, Reference: "r1", KpvedCode: "25.94.11"},
+		{RowNumber: 3, Code: "c2", KpvedCode: "25.94.12"},
+		{RowNumber: 4, Code: "c3", KpvedCode: "25.94.13"},
+		{RowNumber: 5, Reference: "r9", KpvedCode: "25.94.13"},
+		{RowNumber: 6, Reference: "r1", KpvedCode: "25.94.13"},
+		{RowNumber: 7, Code: "c3", KpvedCode: "99.99.99"},
+		{RowNumber: 8, Code: "c3", KpvedCode: "болт"},
+		{RowNumber: 9, KpvedCode: "25.94.13"},
+	}
+	ValidateClassificationCorrections(corrections, classifier)
+
+	// Пробный запуск ничего не меняет
+	if _, err := db.ApplyClassificationCorrections(corrections, "client", true); err != nil {
+		t.Fatalf("ApplyClassificationCorrections(dry run) error = %v", err)
+	}
+	if verified, _ := db.GetVerifiedClassifications(); len(verified) != 0 {
+		t.Fatalf("dry run changed data: %+v", verified)
+	}
+	for _, correction := range corrections {
+		if correction.Status != CorrectionStatusInvalid {
+			correction.Status, correction.Updated = "", 0
+		}
+	}
+
+	result, err := db.ApplyClassificationCorrections(corrections, "client", false)
+	if err != nil {
+		t.Fatalf("ApplyClassificationCorrections() error = %v", err)
+	}
+	want := ClassificationCorrectionsResult{Rows: 8, Applied: 2, Confirmed: 1, NotFound: 1, Invalid: 4, ItemsUpdated: 2, OutcomesRecorded: 2}
+	if *result != want {
+		t.Errorf("result = %+v, want %+v", *result, want)
+	}
+
+	tests := []struct {
+		row    int
+		status string
+	}{
+		{2, CorrectionStatusApplied},
+		{3, CorrectionStatusConfirmed},
+		{4, CorrectionStatusApplied},
+		{5, CorrectionStatusNotFound},
+		{6, CorrectionStatusInvalid},
+		{7, CorrectionStatusInvalid},
+		{8, CorrectionStatusInvalid},
+		{9, CorrectionStatusInvalid},
+	}
+	for i, tt := range tests {
+		if corrections[i].RowNumber != tt.row || corrections[i].Status != tt.status {
+			t.Errorf("row %d: status = %s (%s), want %s", tt.row, corrections[i].Status, corrections[i].Issue, tt.status)
+		}
+	}
+
+	verified, err := db.GetVerifiedClassifications()
+	if err != nil {
+		t.Fatalf("GetVerifiedClassifications() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, v := range verified {
+		got[v.NormalizedName] = v.KpvedCode + " " + v.KpvedName
+	}
+	if len(got) != 3 || got["болт м8"] != "25.94.11 Болты" || got["шайба м8"] != "25.94.13 Шайбы" {
+		t.Errorf("verified = %v", got)
+	}
+
+	outcomes, err := db.GetClassificationOutcomes("glm", 10)
+	if err != nil {
+		t.Fatalf("GetClassificationOutcomes() error = %v", err)
+	}
+	correct := 0
+	for _, outcome := range outcomes {
+		if outcome.Correct {
+			correct++
+		}
+	}
+	if len(outcomes) != 2 || correct != 1 {
+		t.Errorf("outcomes = %+v, want one correct and one incorrect", outcomes)
+	}
+}
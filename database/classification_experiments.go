@@ -0,0 +1,392 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// Статусы экспериментов классификации
+const (
+	ExperimentActive  = "active"  // Доля вызовов классификации направляется в варианты
+	ExperimentStopped = "stopped" // Маршрутизация остановлена, результаты доступны для отчета
+)
+
+// ErrExperimentNotFound эксперимент не найден
+var ErrExperimentNotFound = apperrors.NotFound("experiment_not_found", "experiment not found")
+
+// ErrExperimentActive уже есть активный эксперимент: вызовы маршрутизируются только в один
+var ErrExperimentActive = apperrors.Conflict("experiment_active", "another experiment is already active")
+
+// ClassificationExperiment A/B эксперимент моделей и промптов классификации КПВЭД
+type ClassificationExperiment struct {
+	ID             int                 `json:"id"`
+	Name           string              `json:"name"`
+	Status         string              `json:"status"`
+	TrafficPercent int                 `json:"traffic_percent"` // Доля групп, классифицируемых вариантами эксперимента
+	Variants       []ExperimentVariant `json:"variants"`
+	CreatedAt      time.Time           `json:"created_at"`
+	StoppedAt      *time.Time          `json:"stopped_at,omitempty"`
+}
+
+// ExperimentVariant вариант эксперимента: модель и дополнительные указания промпта.
+// Вариант без модели и указаний - контрольный, классифицирует рабочей цепочкой моделей
+type ExperimentVariant struct {
+	ID                 int     `json:"id"`
+	Name               string  `json:"name"`
+	Model              string  `json:"model,omitempty"`
+	PromptInstructions string  `json:"prompt_instructions,omitempty"`
+	Weight             int     `json:"weight"`
+	CostPerCall        float64 `json:"cost_per_call,omitempty"` // Стоимость одного вызова AI для оценки затрат варианта
+}
+
+// ExperimentCall результат классификации группы вариантом эксперимента
+type ExperimentCall struct {
+	ExperimentID   int
+	VariantID      int
+	NormalizedName string
+	Category       string
+	KpvedCode      string
+	Confidence     float64
+	Model          string
+	AICalls        int
+	DurationMs     int64
+	Error          string
+}
+
+// ExperimentVariantReport показатели варианта: объем и стоимость вызовов, точность на эталонных
+// группах (подтвержденные и исправленные вручную классификации) и по оценкам проверки результатов
+type ExperimentVariantReport struct {
+	ExperimentVariant
+	Calls             int      `json:"calls"`
+	Errors            int      `json:"errors"`
+	AICalls           int      `json:"ai_calls"`
+	AvgConfidence     float64  `json:"avg_confidence"`
+	AvgDurationMs     float64  `json:"avg_duration_ms"`
+	EstimatedCost     float64  `json:"estimated_cost"`
+	GoldenEvaluated   int      `json:"golden_evaluated"`
+	GoldenCorrect     int      `json:"golden_correct"`
+	GoldenAccuracy    *float64 `json:"golden_accuracy,omitempty"`
+	FeedbackEvaluated int      `json:"feedback_evaluated"`
+	FeedbackCorrect   int      `json:"feedback_correct"`
+	FeedbackAccuracy  *float64 `json:"feedback_accuracy,omitempty"`
+}
+
+// CreateClassificationExperimentTables создает таблицы экспериментов, вариантов и результатов вызовов
+func CreateClassificationExperimentTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS classification_experiments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			status TEXT NOT NULL,
+			traffic_percent INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			stopped_at TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS classification_experiment_variants (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			experiment_id INTEGER NOT NULL REFERENCES classification_experiments(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			prompt_instructions TEXT NOT NULL DEFAULT '',
+			weight INTEGER NOT NULL DEFAULT 1,
+			cost_per_call REAL NOT NULL DEFAULT 0
+		);
+
+		CREATE TABLE IF NOT EXISTS classification_experiment_calls (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			experiment_id INTEGER NOT NULL,
+			variant_id INTEGER NOT NULL,
+			normalized_name TEXT NOT NULL,
+			category TEXT NOT NULL DEFAULT '',
+			kpved_code TEXT NOT NULL DEFAULT '',
+			confidence REAL NOT NULL DEFAULT 0,
+			model TEXT NOT NULL DEFAULT '',
+			ai_calls INTEGER NOT NULL DEFAULT 0,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			feedback INTEGER,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_experiment_calls_variant ON classification_experiment_calls(experiment_id, variant_id);
+		CREATE INDEX IF NOT EXISTS idx_experiment_calls_group ON classification_experiment_calls(normalized_name, category);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create classification experiment tables: %w", err)
+	}
+	return nil
+}
+
+// CreateClassificationExperiment создает активный эксперимент с вариантами
+func (db *DB) CreateClassificationExperiment(experiment *ClassificationExperiment) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var active int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM classification_experiments WHERE status = ?`, ExperimentActive).Scan(&active); err != nil {
+		return fmt.Errorf("failed to check active experiments: %w", err)
+	}
+	if active > 0 {
+		return ErrExperimentActive
+	}
+
+	experiment.Status = ExperimentActive
+	experiment.CreatedAt = time.Now().UTC()
+	result, err := tx.Exec(`INSERT INTO classification_experiments (name, status, traffic_percent, created_at) VALUES (?, ?, ?, ?)`,
+		experiment.Name, experiment.Status, experiment.TrafficPercent, experiment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create experiment: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get experiment id: %w", err)
+	}
+	experiment.ID = int(id)
+
+	for i := range experiment.Variants {
+		variant := &experiment.Variants[i]
+		result, err := tx.Exec(`
+			INSERT INTO classification_experiment_variants (experiment_id, name, model, prompt_instructions, weight, cost_per_call)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, experiment.ID, variant.Name, variant.Model, variant.PromptInstructions, variant.Weight, variant.CostPerCall)
+		if err != nil {
+			return fmt.Errorf("failed to create experiment variant: %w", err)
+		}
+		variantID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get variant id: %w", err)
+		}
+		variant.ID = int(variantID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit experiment: %w", err)
+	}
+	return nil
+}
+
+// StopClassificationExperiment останавливает маршрутизацию вызовов в варианты эксперимента
+func (db *DB) StopClassificationExperiment(id int) (*ClassificationExperiment, error) {
+	if _, err := db.conn.Exec(`
+		UPDATE classification_experiments SET status = ?, stopped_at = ? WHERE id = ? AND status = ?
+	`, ExperimentStopped, time.Now().UTC(), id, ExperimentActive); err != nil {
+		return nil, fmt.Errorf("failed to stop experiment: %w", err)
+	}
+	return db.GetClassificationExperiment(id)
+}
+
+// GetClassificationExperiment возвращает эксперимент с вариантами
+func (db *DB) GetClassificationExperiment(id int) (*ClassificationExperiment, error) {
+	experiments, err := db.queryClassificationExperiments(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(experiments) == 0 {
+		return nil, ErrExperimentNotFound
+	}
+	return experiments[0], nil
+}
+
+// GetActiveClassificationExperiment возвращает активный эксперимент; nil, если экспериментов не запущено
+func (db *DB) GetActiveClassificationExperiment() (*ClassificationExperiment, error) {
+	experiments, err := db.queryClassificationExperiments(`WHERE status = ? ORDER BY id DESC LIMIT 1`, ExperimentActive)
+	if err != nil || len(experiments) == 0 {
+		return nil, err
+	}
+	return experiments[0], nil
+}
+
+// ListClassificationExperiments возвращает эксперименты, последние первыми
+func (db *DB) ListClassificationExperiments() ([]*ClassificationExperiment, error) {
+	return db.queryClassificationExperiments(`ORDER BY id DESC`)
+}
+
+func (db *DB) queryClassificationExperiments(where string, args ...interface{}) ([]*ClassificationExperiment, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, name, status, traffic_percent, created_at, stopped_at FROM classification_experiments `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiments: %w", err)
+	}
+	defer rows.Close()
+
+	var experiments []*ClassificationExperiment
+	for rows.Next() {
+		experiment := &ClassificationExperiment{}
+		var stoppedAt sql.NullTime
+		if err := rows.Scan(&experiment.ID, &experiment.Name, &experiment.Status, &experiment.TrafficPercent,
+			&experiment.CreatedAt, &stoppedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment: %w", err)
+		}
+		if stoppedAt.Valid {
+			experiment.StoppedAt = &stoppedAt.Time
+		}
+		experiments = append(experiments, experiment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiments: %w", err)
+	}
+	rows.Close()
+
+	for _, experiment := range experiments {
+		if experiment.Variants, err = db.getExperimentVariants(experiment.ID); err != nil {
+			return nil, err
+		}
+	}
+	return experiments, nil
+}
+
+func (db *DB) getExperimentVariants(experimentID int) ([]ExperimentVariant, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, name, model, prompt_instructions, weight, cost_per_call
+		FROM classification_experiment_variants WHERE experiment_id = ? ORDER BY id
+	`, experimentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment variants: %w", err)
+	}
+	defer rows.Close()
+
+	variants := []ExperimentVariant{}
+	for rows.Next() {
+		var variant ExperimentVariant
+		if err := rows.Scan(&variant.ID, &variant.Name, &variant.Model, &variant.PromptInstructions,
+			&variant.Weight, &variant.CostPerCall); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment variant: %w", err)
+		}
+		variants = append(variants, variant)
+	}
+	return variants, rows.Err()
+}
+
+// RecordExperimentCall сохраняет результат классификации группы вариантом эксперимента
+func (db *DB) RecordExperimentCall(call ExperimentCall) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO classification_experiment_calls
+			(experiment_id, variant_id, normalized_name, category, kpved_code, confidence, model, ai_calls, duration_ms, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, call.ExperimentID, call.VariantID, call.NormalizedName, call.Category, call.KpvedCode, call.Confidence,
+		call.Model, call.AICalls, call.DurationMs, call.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record experiment call: %w", err)
+	}
+	return nil
+}
+
+// RecordExperimentFeedback записывает оценку проверки группы в последний вызов эксперимента, результат
+// которого сохранен в normalized_data. Вызывается до изменения записей группы, пока в них сохранен код
+func (db *DB) RecordExperimentFeedback(normalizedName, category string, correct bool) error {
+	_, err := db.conn.Exec(`
+		UPDATE classification_experiment_calls SET feedback = ?
+		WHERE id = (
+			SELECT c.id FROM classification_experiment_calls c
+			WHERE c.normalized_name = ? AND c.category = ? AND c.error = ''
+			  AND EXISTS (
+				SELECT 1 FROM normalized_data n
+				WHERE n.normalized_name = c.normalized_name AND n.category = c.category AND n.kpved_code = c.kpved_code
+			  )
+			ORDER BY c.id DESC
+			LIMIT 1
+		)
+	`, correct, normalizedName, category)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to record experiment feedback: %w", err)
+	}
+	return nil
+}
+
+// GetExperimentReport возвращает показатели вариантов эксперимента. Эталон - группы с подтвержденной
+// или исправленной вручную классификацией; оценки проверки - пометки групп верными и неверными
+func (db *DB) GetExperimentReport(experiment *ClassificationExperiment) ([]ExperimentVariantReport, error) {
+	reports := make([]ExperimentVariantReport, len(experiment.Variants))
+	index := make(map[int]*ExperimentVariantReport, len(experiment.Variants))
+	for i, variant := range experiment.Variants {
+		reports[i].ExperimentVariant = variant
+		index[variant.ID] = &reports[i]
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT variant_id, COUNT(*),
+		       COALESCE(SUM(CASE WHEN error != '' THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(ai_calls), 0),
+		       COALESCE(AVG(CASE WHEN error = '' THEN confidence END), 0),
+		       COALESCE(AVG(duration_ms), 0),
+		       COALESCE(SUM(CASE WHEN feedback IS NOT NULL THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN feedback = 1 THEN 1 ELSE 0 END), 0)
+		FROM classification_experiment_calls
+		WHERE experiment_id = ?
+		GROUP BY variant_id
+	`, experiment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment stats: %w", err)
+	}
+	for rows.Next() {
+		var variantID int
+		var stats ExperimentVariantReport
+		if err := rows.Scan(&variantID, &stats.Calls, &stats.Errors, &stats.AICalls, &stats.AvgConfidence,
+			&stats.AvgDurationMs, &stats.FeedbackEvaluated, &stats.FeedbackCorrect); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan experiment stats: %w", err)
+		}
+		if report, ok := index[variantID]; ok {
+			stats.ExperimentVariant = report.ExperimentVariant
+			*report = stats
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment stats: %w", err)
+	}
+
+	// Точность на эталоне: совпадение кода варианта с подтвержденным кодом группы
+	rows, err = db.conn.Query(`
+		SELECT c.variant_id, COUNT(*), COALESCE(SUM(CASE WHEN c.kpved_code = g.kpved_code THEN 1 ELSE 0 END), 0)
+		FROM classification_experiment_calls c
+		JOIN (
+			SELECT normalized_name, category, MAX(kpved_code) AS kpved_code
+			FROM normalized_data
+			WHERE kpved_code IS NOT NULL AND TRIM(kpved_code) != ''
+			  AND (validation_status = 'correct' OR kpved_model = ?)
+			GROUP BY normalized_name, category
+		) g ON g.normalized_name = c.normalized_name AND g.category = c.category
+		WHERE c.experiment_id = ? AND c.error = ''
+		GROUP BY c.variant_id
+	`, ManualEditModel, experiment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get experiment golden stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var variantID, evaluated, correct int
+		if err := rows.Scan(&variantID, &evaluated, &correct); err != nil {
+			return nil, fmt.Errorf("failed to scan experiment golden stats: %w", err)
+		}
+		if report, ok := index[variantID]; ok {
+			report.GoldenEvaluated = evaluated
+			report.GoldenCorrect = correct
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate experiment golden stats: %w", err)
+	}
+
+	for i := range reports {
+		report := &reports[i]
+		report.EstimatedCost = float64(report.AICalls) * report.CostPerCall
+		if report.GoldenEvaluated > 0 {
+			accuracy := coverageRatio(int64(report.GoldenCorrect), int64(report.GoldenEvaluated))
+			report.GoldenAccuracy = &accuracy
+		}
+		if report.FeedbackEvaluated > 0 {
+			accuracy := coverageRatio(int64(report.FeedbackCorrect), int64(report.FeedbackEvaluated))
+			report.FeedbackAccuracy = &accuracy
+		}
+	}
+	return reports, nil
+}
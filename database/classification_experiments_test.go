@@ -0,0 +1,101 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"httpserver/apperrors"
+)
+
+func TestClassificationExperimentReport(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	experiment := &ClassificationExperiment{
+		Name:           "flash vs pro",
+		TrafficPercent: 50,
+		Variants: []ExperimentVariant{
+			{Name: "control", Weight: 1, CostPerCall: 0.1},
+			{Name: "pro", Model: "pro-model", Weight: 1, CostPerCall: 0.5},
+		},
+	}
+	if err := db.CreateClassificationExperiment(experiment); err != nil {
+		t.Fatalf("CreateClassificationExperiment() error = %v", err)
+	}
+	second := &ClassificationExperiment{Name: "other", TrafficPercent: 10, Variants: experiment.Variants}
+	if err := db.CreateClassificationExperiment(second); !errors.Is(err, ErrExperimentActive) {
+		t.Fatalf("second active experiment error = %v, want ErrExperimentActive", err)
+	}
+
+	// Эталон: подтвержденная группа "болт" и исправленная вручную группа "гайка"
+	if _, err := db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code, kpved_model, validation_status)
+		VALUES ('r1', 'Болт', '1', 'болт', 'крепеж', '25.94.11', 'flash', 'correct'),
+		       ('r2', 'Гайка', '2', 'гайка', 'крепеж', '25.94.12', ?, ''),
+		       ('r3', 'Шайба', '3', 'шайба', 'крепеж', '25.94.13', 'pro-model', '')`, ManualEditModel); err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+
+	control, pro := experiment.Variants[0].ID, experiment.Variants[1].ID
+	for _, call := range []ExperimentCall{
+		{VariantID: control, NormalizedName: "болт", Category: "крепеж", KpvedCode: "25.94.11", Confidence: 0.9, AICalls: 2},
+		{VariantID: control, NormalizedName: "гайка", Category: "крепеж", KpvedCode: "25.94.99", Confidence: 0.7, AICalls: 2},
+		{VariantID: pro, NormalizedName: "гайка", Category: "крепеж", KpvedCode: "25.94.12", Confidence: 0.95, AICalls: 3},
+		{VariantID: pro, NormalizedName: "шайба", Category: "крепеж", KpvedCode: "25.94.13", Confidence: 0.8, AICalls: 3},
+		{VariantID: pro, NormalizedName: "винт", Category: "крепеж", Error: "timeout"},
+	} {
+		call.ExperimentID = experiment.ID
+		if err := db.RecordExperimentCall(call); err != nil {
+			t.Fatalf("RecordExperimentCall() error = %v", err)
+		}
+	}
+	if err := db.RecordExperimentFeedback("шайба", "крепеж", false); err != nil {
+		t.Fatalf("RecordExperimentFeedback() error = %v", err)
+	}
+
+	reports, err := db.GetExperimentReport(experiment)
+	if err != nil {
+		t.Fatalf("GetExperimentReport() error = %v", err)
+	}
+	tests := []struct {
+		name                      string
+		report                    ExperimentVariantReport
+		calls, errors, aiCalls    int
+		cost                      float64
+		goldenEvaluated, golden   int
+		feedbackEvaluated, wrongs int
+	}{
+		{"control", reports[0], 2, 0, 4, 0.4, 2, 1, 0, 0},
+		{"pro", reports[1], 3, 1, 6, 3, 1, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.report
+			if r.Name != tt.name || r.Calls != tt.calls || r.Errors != tt.errors || r.AICalls != tt.aiCalls {
+				t.Errorf("report = %+v, want %d calls, %d errors, %d ai calls", r, tt.calls, tt.errors, tt.aiCalls)
+			}
+			if r.EstimatedCost < tt.cost-1e-9 || r.EstimatedCost > tt.cost+1e-9 {
+				t.Errorf("EstimatedCost = %v, want %v", r.EstimatedCost, tt.cost)
+			}
+			if r.GoldenEvaluated != tt.goldenEvaluated || r.GoldenCorrect != tt.golden {
+				t.Errorf("golden = %d/%d, want %d/%d", r.GoldenCorrect, r.GoldenEvaluated, tt.golden, tt.goldenEvaluated)
+			}
+			if r.FeedbackEvaluated != tt.feedbackEvaluated || r.FeedbackEvaluated-r.FeedbackCorrect != tt.wrongs {
+				t.Errorf("feedback = %d/%d, want %d evaluated, %d incorrect", r.FeedbackCorrect, r.FeedbackEvaluated, tt.feedbackEvaluated, tt.wrongs)
+			}
+		})
+	}
+
+	stopped, err := db.StopClassificationExperiment(experiment.ID)
+	if err != nil || stopped.Status != ExperimentStopped || stopped.StoppedAt == nil {
+		t.Fatalf("StopClassificationExperiment() = %+v, %v, want stopped", stopped, err)
+	}
+	if active, err := db.GetActiveClassificationExperiment(); err != nil || active != nil {
+		t.Errorf("GetActiveClassificationExperiment() after stop = %+v, %v, want none", active, err)
+	}
+	if _, err := db.GetClassificationExperiment(999); apperrors.HTTPStatus(err) != 404 {
+		t.Errorf("GetClassificationExperiment(999) error = %v, want not found", err)
+	}
+}
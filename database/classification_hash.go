@@ -0,0 +1,33 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// MigrateNormalizedDataClassificationHash добавляет в normalized_data хеш входных данных последней классификации
+func MigrateNormalizedDataClassificationHash(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE normalized_data ADD COLUMN classification_hash TEXT DEFAULT ''`)
+	if err != nil {
+		errStr := strings.ToLower(err.Error())
+		if !strings.Contains(errStr, "duplicate column") &&
+			!strings.Contains(errStr, "already exists") {
+			return fmt.Errorf("failed to add normalized_data.classification_hash column: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClassificationContentHash хеш входных данных классификации (наименование, атрибуты, промпт, модель).
+// Записи с совпадающим хешем повторно классифицировать не нужно: AI получит те же данные
+func ClassificationContentHash(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
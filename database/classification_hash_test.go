@@ -0,0 +1,24 @@
+package database
+
+import "testing"
+
+func TestClassificationContentHash(t *testing.T) {
+	base := ClassificationContentHash("model", "prompt", "болт м8")
+	tests := []struct {
+		name  string
+		parts []string
+		same  bool
+	}{
+		{"same input", []string{"model", "prompt", "болт м8"}, true},
+		{"changed name", []string{"model", "prompt", "болт м10"}, false},
+		{"changed model", []string{"model-2", "prompt", "болт м8"}, false},
+		{"parts are not concatenated", []string{"modelprompt", "", "болт м8"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassificationContentHash(tt.parts...); (got == base) != tt.same {
+				t.Errorf("ClassificationContentHash(%q) equal = %v, want %v", tt.parts, got == base, tt.same)
+			}
+		})
+	}
+}
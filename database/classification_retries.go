@@ -0,0 +1,234 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Классы ошибок классификации записей
+const (
+	ClassificationErrorTransient = "transient" // Временная ошибка (таймаут, ограничение частоты, недоступность): запись повторяется
+	ClassificationErrorRejected  = "rejected"  // Результат отклонен политикой уверенности
+	ClassificationErrorPermanent = "permanent" // Ошибка, которую повтор не исправит
+)
+
+// Статусы записей очереди повторной классификации
+const (
+	ClassificationRetryPending  = "pending"  // Ожидает повтора
+	ClassificationRetryResolved = "resolved" // Запись классифицирована
+	ClassificationRetryFailed   = "failed"   // Окончательная ошибка: постоянная или исчерпаны попытки
+)
+
+// ClassificationRetry запись normalized_data, классификация которой завершилась ошибкой
+type ClassificationRetry struct {
+	ID             int        `json:"id"`
+	ItemID         int        `json:"item_id"`
+	NormalizedName string     `json:"normalized_name"`
+	Category       string     `json:"category"`
+	ErrorClass     string     `json:"error_class"`
+	LastError      string     `json:"last_error"`
+	Attempts       int        `json:"attempts"`
+	Status         string     `json:"status"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ClassificationFailure ошибка классификации записи для очереди повторов
+type ClassificationFailure struct {
+	ItemID         int
+	NormalizedName string
+	Category       string
+	ErrorClass     string
+	Error          string
+}
+
+// ClassificationRetryPolicy политика повторов: задержка удваивается с каждой попыткой от BaseDelay до MaxDelay
+type ClassificationRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// delay задержка перед повтором после attempts неудачных попыток
+func (p ClassificationRetryPolicy) delay(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// CreateClassificationRetriesTable создает очередь повторной классификации записей normalized_data
+func CreateClassificationRetriesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS classification_retries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_id INTEGER NOT NULL UNIQUE,
+			normalized_name TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT '',
+			error_class TEXT NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			next_attempt_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create classification_retries table: %w", err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_classification_retries_due ON classification_retries(status, next_attempt_at)`); err != nil {
+		return fmt.Errorf("failed to create classification_retries index: %w", err)
+	}
+	return nil
+}
+
+// RecordClassificationFailure записывает ошибку классификации записи и увеличивает счетчик попыток.
+// Временные ошибки планируются к повтору по политике, остальные и исчерпавшие попытки отмечаются окончательными
+func (db *DB) RecordClassificationFailure(failure ClassificationFailure, policy ClassificationRetryPolicy) (*ClassificationRetry, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var attempts int
+	err = tx.QueryRow(`SELECT attempts FROM classification_retries WHERE item_id = ? AND status != ?`,
+		failure.ItemID, ClassificationRetryResolved).Scan(&attempts)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get classification retry: %w", err)
+	}
+	attempts++
+
+	status := ClassificationRetryFailed
+	var nextAttemptAt *time.Time
+	if failure.ErrorClass == ClassificationErrorTransient && attempts < policy.MaxAttempts {
+		status = ClassificationRetryPending
+		next := time.Now().Add(policy.delay(attempts)).UTC()
+		nextAttemptAt = &next
+	}
+
+	now := time.Now().UTC()
+	if _, err := tx.Exec(`
+		INSERT INTO classification_retries
+			(item_id, normalized_name, category, error_class, last_error, attempts, status, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(item_id) DO UPDATE SET
+			normalized_name = excluded.normalized_name, category = excluded.category,
+			error_class = excluded.error_class, last_error = excluded.last_error, attempts = excluded.attempts,
+			status = excluded.status, next_attempt_at = excluded.next_attempt_at, updated_at = excluded.updated_at
+	`, failure.ItemID, failure.NormalizedName, failure.Category, failure.ErrorClass, failure.Error,
+		attempts, status, nextAttemptAt, now, now); err != nil {
+		return nil, fmt.Errorf("failed to record classification failure: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit classification failure: %w", err)
+	}
+
+	return db.getClassificationRetry(failure.ItemID)
+}
+
+// ResolveClassificationRetry отмечает запись очереди классифицированной; записи без ошибок не затрагиваются
+func (db *DB) ResolveClassificationRetry(itemID int) error {
+	_, err := db.conn.Exec(`
+		UPDATE classification_retries SET status = ?, next_attempt_at = NULL, updated_at = ?
+		WHERE item_id = ? AND status != ?
+	`, ClassificationRetryResolved, time.Now().UTC(), itemID, ClassificationRetryResolved)
+	if err != nil {
+		return fmt.Errorf("failed to resolve classification retry: %w", err)
+	}
+	return nil
+}
+
+// GetDueClassificationRetries возвращает записи, время повтора которых наступило, в порядке очереди
+func (db *DB) GetDueClassificationRetries(now time.Time, limit int) ([]*ClassificationRetry, error) {
+	return db.queryClassificationRetries(`
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?
+	`, ClassificationRetryPending, now.UTC(), limit)
+}
+
+// ListClassificationRetries возвращает записи очереди с указанным статусом (пустой - все), последние изменения первыми
+func (db *DB) ListClassificationRetries(status string, limit, offset int) ([]*ClassificationRetry, error) {
+	if status == "" {
+		return db.queryClassificationRetries(`ORDER BY updated_at DESC, id DESC LIMIT ? OFFSET ?`, limit, offset)
+	}
+	return db.queryClassificationRetries(`WHERE status = ? ORDER BY updated_at DESC, id DESC LIMIT ? OFFSET ?`, status, limit, offset)
+}
+
+// CountClassificationRetries возвращает количество записей очереди по статусам и по классам окончательных ошибок
+func (db *DB) CountClassificationRetries() (byStatus map[string]int, failedByClass map[string]int, err error) {
+	rows, err := db.conn.Query(`SELECT status, error_class, COUNT(*) FROM classification_retries GROUP BY status, error_class`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count classification retries: %w", err)
+	}
+	defer rows.Close()
+
+	byStatus = map[string]int{
+		ClassificationRetryPending:  0,
+		ClassificationRetryResolved: 0,
+		ClassificationRetryFailed:   0,
+	}
+	failedByClass = make(map[string]int)
+	for rows.Next() {
+		var status, errorClass string
+		var count int
+		if err := rows.Scan(&status, &errorClass, &count); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan classification retry count: %w", err)
+		}
+		byStatus[status] += count
+		if status == ClassificationRetryFailed {
+			failedByClass[errorClass] += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate classification retry counts: %w", err)
+	}
+	return byStatus, failedByClass, nil
+}
+
+func (db *DB) getClassificationRetry(itemID int) (*ClassificationRetry, error) {
+	retries, err := db.queryClassificationRetries(`WHERE item_id = ?`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if len(retries) == 0 {
+		return nil, fmt.Errorf("classification retry for item %d not found", itemID)
+	}
+	return retries[0], nil
+}
+
+func (db *DB) queryClassificationRetries(where string, args ...interface{}) ([]*ClassificationRetry, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, item_id, normalized_name, category, error_class, last_error, attempts, status,
+		       next_attempt_at, created_at, updated_at
+		FROM classification_retries `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification retries: %w", err)
+	}
+	defer rows.Close()
+
+	var retries []*ClassificationRetry
+	for rows.Next() {
+		retry := &ClassificationRetry{}
+		var nextAttemptAt sql.NullTime
+		if err := rows.Scan(&retry.ID, &retry.ItemID, &retry.NormalizedName, &retry.Category, &retry.ErrorClass,
+			&retry.LastError, &retry.Attempts, &retry.Status, &nextAttemptAt, &retry.CreatedAt, &retry.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan classification retry: %w", err)
+		}
+		if nextAttemptAt.Valid {
+			retry.NextAttemptAt = &nextAttemptAt.Time
+		}
+		retries = append(retries, retry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate classification retries: %w", err)
+	}
+	return retries, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassificationRetryPolicyDelay(t *testing.T) {
+	policy := ClassificationRetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute, MaxDelay: 5 * time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.delay(tt.attempts); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestClassificationRetries(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	policy := ClassificationRetryPolicy{MaxAttempts: 2, BaseDelay: time.Minute}
+	transient := ClassificationFailure{ItemID: 1, NormalizedName: "болт", Category: "крепеж", ErrorClass: ClassificationErrorTransient, Error: "timeout"}
+
+	retry, err := db.RecordClassificationFailure(transient, policy)
+	if err != nil {
+		t.Fatalf("RecordClassificationFailure() error = %v", err)
+	}
+	if retry.Status != ClassificationRetryPending || retry.Attempts != 1 || retry.NextAttemptAt == nil {
+		t.Fatalf("first transient failure = %+v, want pending retry", retry)
+	}
+	if due, err := db.GetDueClassificationRetries(time.Now(), 10); err != nil || len(due) != 0 {
+		t.Fatalf("GetDueClassificationRetries() before delay = %d, %v, want none", len(due), err)
+	}
+	if due, err := db.GetDueClassificationRetries(time.Now().Add(2*time.Minute), 10); err != nil || len(due) != 1 {
+		t.Fatalf("GetDueClassificationRetries() after delay = %d, %v, want one", len(due), err)
+	}
+
+	// Исчерпанные попытки переводят запись в окончательные ошибки
+	if retry, err = db.RecordClassificationFailure(transient, policy); err != nil {
+		t.Fatalf("RecordClassificationFailure() error = %v", err)
+	}
+	if retry.Status != ClassificationRetryFailed || retry.Attempts != 2 || retry.NextAttemptAt != nil {
+		t.Fatalf("second transient failure = %+v, want failed", retry)
+	}
+
+	// Постоянная ошибка не повторяется
+	permanent := ClassificationFailure{ItemID: 2, NormalizedName: "гайка", ErrorClass: ClassificationErrorRejected, Error: "rejected"}
+	if retry, err = db.RecordClassificationFailure(permanent, policy); err != nil || retry.Status != ClassificationRetryFailed {
+		t.Fatalf("RecordClassificationFailure(rejected) = %+v, %v, want failed", retry, err)
+	}
+
+	// После успешной классификации новая ошибка начинает отсчет попыток заново
+	if err := db.ResolveClassificationRetry(1); err != nil {
+		t.Fatalf("ResolveClassificationRetry() error = %v", err)
+	}
+	if retry, err = db.RecordClassificationFailure(transient, policy); err != nil || retry.Attempts != 1 || retry.Status != ClassificationRetryPending {
+		t.Fatalf("failure after resolve = %+v, %v, want first attempt", retry, err)
+	}
+
+	counts, failedByClass, err := db.CountClassificationRetries()
+	if err != nil {
+		t.Fatalf("CountClassificationRetries() error = %v", err)
+	}
+	if counts[ClassificationRetryPending] != 1 || counts[ClassificationRetryFailed] != 1 || failedByClass[ClassificationErrorRejected] != 1 {
+		t.Errorf("counts = %v, failed by class = %v", counts, failedByClass)
+	}
+	failed, err := db.ListClassificationRetries(ClassificationRetryFailed, 10, 0)
+	if err != nil || len(failed) != 1 || failed[0].ItemID != 2 {
+		t.Errorf("ListClassificationRetries(failed) = %+v, %v, want item 2", failed, err)
+	}
+}
@@ -0,0 +1,517 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ClassifierPathSeparator разделитель имен в пути узла классификатора
+const ClassifierPathSeparator = " / "
+
+// Ошибки редактирования дерева классификатора
+var (
+	ErrClassifierNodeNotFound = errors.New("classifier node not found")
+	ErrClassifierNodeExists   = errors.New("classifier node already exists")
+	ErrClassifierNodeInvalid  = errors.New("invalid classifier node change")
+)
+
+// ClassifierMergeResult результат слияния категорий
+type ClassifierMergeResult struct {
+	Target        ClassifierNode `json:"target"`
+	MovedChildren int            `json:"moved_children"`
+	MovedItems    int            `json:"moved_items"`
+}
+
+// classifierTreeEdit узлы классификатора, загруженные для изменения в транзакции
+type classifierTreeEdit struct {
+	tx           *sql.Tx
+	classifierID int
+	maxDepth     int
+	nodes        map[string]*ClassifierNode
+	children     map[string][]*ClassifierNode
+	changed      map[string]bool
+}
+
+// editClassifierNodes загружает узлы классификатора и выполняет изменение в одной транзакции.
+// Измененные узлы (включая пересчитанные пути потомков) записываются после успешного edit
+func (db *DB) editClassifierNodes(classifierID int, edit func(tree *classifierTreeEdit) error) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tree := &classifierTreeEdit{
+		tx:           tx,
+		classifierID: classifierID,
+		nodes:        make(map[string]*ClassifierNode),
+		children:     make(map[string][]*ClassifierNode),
+		changed:      make(map[string]bool),
+	}
+	err = tx.QueryRow(`SELECT COALESCE(max_depth, 0) FROM category_classifiers WHERE id = ?`, classifierID).Scan(&tree.maxDepth)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("classifier %d not found", classifierID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get classifier: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT `+classifierNodeColumns+`
+		FROM category_classifier_nodes
+		WHERE classifier_id = ?
+		ORDER BY level, position, id
+	`, classifierID)
+	if err != nil {
+		return fmt.Errorf("failed to get classifier nodes: %w", err)
+	}
+	for rows.Next() {
+		node, err := scanClassifierNode(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan classifier node: %w", err)
+		}
+		tree.nodes[node.Code] = node
+		tree.children[node.ParentCode] = append(tree.children[node.ParentCode], node)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate classifier nodes: %w", err)
+	}
+
+	if err := edit(tree); err != nil {
+		return err
+	}
+
+	for code := range tree.changed {
+		node, ok := tree.nodes[code]
+		if !ok {
+			continue
+		}
+		var parentCode interface{}
+		if node.ParentCode != "" {
+			parentCode = node.ParentCode
+		}
+		_, err := tx.Exec(`
+			UPDATE category_classifier_nodes
+			SET parent_code = ?, name = ?, level = ?, path = ?, position = ?
+			WHERE id = ?
+		`, parentCode, node.Name, node.Level, node.Path, node.Position, node.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update classifier node %s: %w", code, err)
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE category_classifiers SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, classifierID); err != nil {
+		return fmt.Errorf("failed to update classifier: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// node возвращает узел по коду
+func (t *classifierTreeEdit) node(code string) (*ClassifierNode, error) {
+	node, ok := t.nodes[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrClassifierNodeNotFound, code)
+	}
+	return node, nil
+}
+
+// isDescendant проверяет, находится ли узел code в поддереве ancestor (включая сам ancestor)
+func (t *classifierTreeEdit) isDescendant(code, ancestor string) bool {
+	seen := make(map[string]bool)
+	for code != "" && !seen[code] {
+		if code == ancestor {
+			return true
+		}
+		seen[code] = true
+		node, ok := t.nodes[code]
+		if !ok {
+			return false
+		}
+		code = node.ParentCode
+	}
+	return false
+}
+
+// subtreeHeight возвращает высоту поддерева узла (0 для листа)
+func (t *classifierTreeEdit) subtreeHeight(code string) int {
+	height := 0
+	for _, child := range t.children[code] {
+		if h := t.subtreeHeight(child.Code) + 1; h > height {
+			height = h
+		}
+	}
+	return height
+}
+
+// childLevel возвращает уровень дочернего узла parent (узлы верхнего уровня имеют уровень 1)
+func (t *classifierTreeEdit) childLevel(parent *ClassifierNode) int {
+	if parent == nil {
+		return 1
+	}
+	return parent.Level + 1
+}
+
+// checkDepth проверяет, что поддерево высотой height на уровне level не превышает max_depth
+func (t *classifierTreeEdit) checkDepth(level, height int) error {
+	if t.maxDepth > 0 && level+height > t.maxDepth {
+		return fmt.Errorf("%w: depth %d exceeds classifier max depth %d", ErrClassifierNodeInvalid, level+height, t.maxDepth)
+	}
+	return nil
+}
+
+// nextPosition возвращает позицию в конце списка дочерних узлов parentCode
+func (t *classifierTreeEdit) nextPosition(parentCode string) int {
+	position := 0
+	for _, sibling := range t.children[parentCode] {
+		if sibling.Position >= position {
+			position = sibling.Position + 1
+		}
+	}
+	return position
+}
+
+// detach убирает узел из списка дочерних узлов его родителя
+func (t *classifierTreeEdit) detach(node *ClassifierNode) {
+	siblings := t.children[node.ParentCode]
+	for i, sibling := range siblings {
+		if sibling.Code == node.Code {
+			t.children[node.ParentCode] = append(siblings[:i:i], siblings[i+1:]...)
+			return
+		}
+	}
+}
+
+// attach переносит узел под нового родителя в конец списка дочерних узлов
+func (t *classifierTreeEdit) attach(node *ClassifierNode, parent *ClassifierNode) {
+	t.detach(node)
+	node.ParentCode = ""
+	if parent != nil {
+		node.ParentCode = parent.Code
+	}
+	node.Position = t.nextPosition(node.ParentCode)
+	t.children[node.ParentCode] = append(t.children[node.ParentCode], node)
+	t.changed[node.Code] = true
+}
+
+// repath пересчитывает уровень и путь узла и всех его потомков
+func (t *classifierTreeEdit) repath(node *ClassifierNode) {
+	var parent *ClassifierNode
+	if node.ParentCode != "" {
+		parent = t.nodes[node.ParentCode]
+	}
+	level, path := t.childLevel(parent), node.Name
+	if parent != nil && parent.Path != "" {
+		path = parent.Path + ClassifierPathSeparator + node.Name
+	}
+	if node.Level != level || node.Path != path {
+		node.Level, node.Path = level, path
+		t.changed[node.Code] = true
+	}
+	for _, child := range t.children[node.Code] {
+		t.repath(child)
+	}
+}
+
+// categoryChain возвращает имена категорий узла от верхнего уровня, как они хранятся в
+// category_level1..5 классифицированных элементов (корневой узел уровня 0 не входит)
+func (t *classifierTreeEdit) categoryChain(node *ClassifierNode) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	for node != nil && !seen[node.Code] {
+		seen[node.Code] = true
+		if node.Level >= 1 {
+			chain = append([]string{node.Name}, chain...)
+		}
+		node = t.nodes[node.ParentCode]
+	}
+	return chain
+}
+
+// validateClassifierNodeName проверяет имя категории
+func validateClassifierNodeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrClassifierNodeInvalid)
+	}
+	if strings.Contains(name, strings.TrimSpace(ClassifierPathSeparator)) {
+		return "", fmt.Errorf("%w: name must not contain %q", ErrClassifierNodeInvalid, strings.TrimSpace(ClassifierPathSeparator))
+	}
+	return name, nil
+}
+
+// AddClassifierNode добавляет категорию в дерево классификатора (пустой parentCode - верхний уровень)
+func (db *DB) AddClassifierNode(classifierID int, parentCode, code, name string) (*ClassifierNode, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, fmt.Errorf("%w: code is required", ErrClassifierNodeInvalid)
+	}
+	name, err := validateClassifierNodeName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var added *ClassifierNode
+	err = db.editClassifierNodes(classifierID, func(tree *classifierTreeEdit) error {
+		if _, exists := tree.nodes[code]; exists {
+			return fmt.Errorf("%w: %s", ErrClassifierNodeExists, code)
+		}
+		var parent *ClassifierNode
+		if parentCode != "" {
+			if parent, err = tree.node(parentCode); err != nil {
+				return err
+			}
+		}
+		if err := tree.checkDepth(tree.childLevel(parent), 0); err != nil {
+			return err
+		}
+
+		added = &ClassifierNode{ClassifierID: classifierID, Code: code, ParentCode: parentCode, Name: name}
+		added.Position = tree.nextPosition(parentCode)
+		tree.nodes[code] = added
+		tree.repath(added)
+		delete(tree.changed, code)
+
+		var parentValue interface{}
+		if parentCode != "" {
+			parentValue = parentCode
+		}
+		result, err := tree.tx.Exec(`
+			INSERT INTO category_classifier_nodes (classifier_id, code, parent_code, name, level, path, position)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, classifierID, code, parentValue, added.Name, added.Level, added.Path, added.Position)
+		if err != nil {
+			return fmt.Errorf("failed to insert classifier node %s: %w", code, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get classifier node ID: %w", err)
+		}
+		added.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RenameClassifierNode переименовывает категорию и пересчитывает пути ее потомков
+func (db *DB) RenameClassifierNode(classifierID int, code, name string) (*ClassifierNode, error) {
+	name, err := validateClassifierNodeName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var renamed ClassifierNode
+	err = db.editClassifierNodes(classifierID, func(tree *classifierTreeEdit) error {
+		node, err := tree.node(code)
+		if err != nil {
+			return err
+		}
+		if node.Name != name {
+			node.Name = name
+			tree.changed[code] = true
+			tree.repath(node)
+		}
+		renamed = *node
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &renamed, nil
+}
+
+// MoveClassifierNode переносит категорию вместе с поддеревом под другого родителя
+// (пустой parentCode - на верхний уровень). Перенос в собственное поддерево запрещен
+func (db *DB) MoveClassifierNode(classifierID int, code, parentCode string) (*ClassifierNode, error) {
+	var moved ClassifierNode
+	err := db.editClassifierNodes(classifierID, func(tree *classifierTreeEdit) error {
+		node, err := tree.node(code)
+		if err != nil {
+			return err
+		}
+		var parent *ClassifierNode
+		if parentCode != "" {
+			if parent, err = tree.node(parentCode); err != nil {
+				return err
+			}
+			if tree.isDescendant(parentCode, code) {
+				return fmt.Errorf("%w: cannot move %s under its own subtree", ErrClassifierNodeInvalid, code)
+			}
+		}
+		if err := tree.checkDepth(tree.childLevel(parent), tree.subtreeHeight(code)); err != nil {
+			return err
+		}
+
+		if node.ParentCode != parentCode {
+			tree.attach(node, parent)
+			tree.repath(node)
+		}
+		moved = *node
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &moved, nil
+}
+
+// DeleteClassifierNode удаляет категорию без дочерних узлов
+func (db *DB) DeleteClassifierNode(classifierID int, code string) error {
+	return db.editClassifierNodes(classifierID, func(tree *classifierTreeEdit) error {
+		node, err := tree.node(code)
+		if err != nil {
+			return err
+		}
+		if len(tree.children[code]) > 0 {
+			return fmt.Errorf("%w: %s has child categories, merge or move them first", ErrClassifierNodeInvalid, code)
+		}
+		if _, err := tree.tx.Exec(`DELETE FROM category_classifier_nodes WHERE id = ?`, node.ID); err != nil {
+			return fmt.Errorf("failed to delete classifier node %s: %w", code, err)
+		}
+		tree.detach(node)
+		delete(tree.nodes, code)
+		return nil
+	})
+}
+
+// MergeClassifierNodes сливает категорию sourceCode в targetCode: дочерние категории переносятся
+// в target, source удаляется. При moveItems элементы, классифицированные в source (и его потомков),
+// переносятся в target в catalog_items и nomenclature_items по совпадению category_level1..5
+func (db *DB) MergeClassifierNodes(classifierID int, sourceCode, targetCode string, moveItems bool) (*ClassifierMergeResult, error) {
+	if sourceCode == targetCode {
+		return nil, fmt.Errorf("%w: cannot merge %s into itself", ErrClassifierNodeInvalid, sourceCode)
+	}
+
+	result := &ClassifierMergeResult{}
+	err := db.editClassifierNodes(classifierID, func(tree *classifierTreeEdit) error {
+		source, err := tree.node(sourceCode)
+		if err != nil {
+			return err
+		}
+		target, err := tree.node(targetCode)
+		if err != nil {
+			return err
+		}
+		if tree.isDescendant(targetCode, sourceCode) {
+			return fmt.Errorf("%w: cannot merge %s into its own subtree", ErrClassifierNodeInvalid, sourceCode)
+		}
+		if height := tree.subtreeHeight(sourceCode); height > 0 {
+			if err := tree.checkDepth(tree.childLevel(target), height-1); err != nil {
+				return err
+			}
+		}
+
+		sourceChain, targetChain := tree.categoryChain(source), tree.categoryChain(target)
+		children := append([]*ClassifierNode(nil), tree.children[sourceCode]...)
+		for _, child := range children {
+			tree.attach(child, target)
+			tree.repath(child)
+		}
+		result.MovedChildren = len(children)
+
+		if _, err := tree.tx.Exec(`DELETE FROM category_classifier_nodes WHERE id = ?`, source.ID); err != nil {
+			return fmt.Errorf("failed to delete classifier node %s: %w", sourceCode, err)
+		}
+		tree.detach(source)
+		delete(tree.nodes, sourceCode)
+		delete(tree.changed, sourceCode)
+
+		if moveItems && len(sourceChain) > 0 && len(targetChain) > 0 {
+			for _, table := range []string{"catalog_items", "nomenclature_items"} {
+				moved, err := moveClassifiedItemsTx(tree.tx, table, sourceChain, targetChain)
+				if err != nil {
+					return err
+				}
+				result.MovedItems += moved
+			}
+		}
+		result.Target = *target
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// maxCategoryLevels число колонок category_levelN у классифицированных элементов
+const maxCategoryLevels = 5
+
+// moveClassifiedItemsTx заменяет префикс пути категории from на to у элементов таблицы.
+// Таблицы без колонок классификации пропускаются
+func moveClassifiedItemsTx(tx *sql.Tx, table string, from, to []string) (int, error) {
+	if len(from) > maxCategoryLevels {
+		return 0, nil
+	}
+	var columns int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name LIKE 'category_level_'`, table).Scan(&columns); err != nil {
+		return 0, fmt.Errorf("failed to check %s columns: %w", table, err)
+	}
+	if columns < maxCategoryLevels {
+		return 0, nil
+	}
+
+	conditions := make([]string, len(from))
+	args := make([]interface{}, len(from))
+	for i, name := range from {
+		conditions[i] = fmt.Sprintf("category_level%d = ?", i+1)
+		args[i] = name
+	}
+	rows, err := tx.Query(fmt.Sprintf(`
+		SELECT id, COALESCE(category_level1, ''), COALESCE(category_level2, ''), COALESCE(category_level3, ''),
+		       COALESCE(category_level4, ''), COALESCE(category_level5, '')
+		FROM %s WHERE %s
+	`, table, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find classified items in %s: %w", table, err)
+	}
+	type itemLevels struct {
+		id     int
+		levels [maxCategoryLevels]string
+	}
+	var items []itemLevels
+	for rows.Next() {
+		var item itemLevels
+		if err := rows.Scan(&item.id, &item.levels[0], &item.levels[1], &item.levels[2], &item.levels[3], &item.levels[4]); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan classified item: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to find classified items in %s: %w", table, err)
+	}
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		UPDATE %s SET category_level1 = ?, category_level2 = ?, category_level3 = ?, category_level4 = ?, category_level5 = ?
+		WHERE id = ?
+	`, table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare classified item update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, item := range items {
+		path := append(append([]string{}, to...), item.levels[len(from):]...)
+		var levels [maxCategoryLevels]interface{}
+		for i := range levels {
+			if i < len(path) && path[i] != "" {
+				levels[i] = path[i]
+			}
+		}
+		if _, err := stmt.Exec(levels[0], levels[1], levels[2], levels[3], levels[4], item.id); err != nil {
+			return 0, fmt.Errorf("failed to move classified item %d: %w", item.id, err)
+		}
+	}
+	return len(items), nil
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifierNodeEditing(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	classifier, err := db.CreateCategoryClassifier(&CategoryClassifier{Name: "Группы клиента", MaxDepth: 3, IsActive: true})
+	if err != nil {
+		t.Fatalf("CreateCategoryClassifier() error = %v", err)
+	}
+	id := classifier.ID
+
+	for _, n := range []struct{ parent, code, name string }{
+		{"", "tools", "Инструмент"},
+		{"tools", "hand", "Ручной"},
+		{"hand", "hammers", "Молотки"},
+		{"", "fasteners", "Крепеж"},
+		{"fasteners", "bolts", "Болты"},
+	} {
+		if _, err := db.AddClassifierNode(id, n.parent, n.code, n.name); err != nil {
+			t.Fatalf("AddClassifierNode(%s) error = %v", n.code, err)
+		}
+	}
+
+	invalid := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"duplicate code", func() error { _, err := db.AddClassifierNode(id, "", "tools", "Еще"); return err }, ErrClassifierNodeExists},
+		{"unknown parent", func() error { _, err := db.AddClassifierNode(id, "missing", "x", "X"); return err }, ErrClassifierNodeNotFound},
+		{"too deep", func() error { _, err := db.AddClassifierNode(id, "hammers", "x", "X"); return err }, ErrClassifierNodeInvalid},
+		{"empty name", func() error { _, err := db.RenameClassifierNode(id, "tools", " "); return err }, ErrClassifierNodeInvalid},
+		{"move into own subtree", func() error { _, err := db.MoveClassifierNode(id, "tools", "hand"); return err }, ErrClassifierNodeInvalid},
+		{"move exceeds depth", func() error { _, err := db.MoveClassifierNode(id, "hand", "bolts"); return err }, ErrClassifierNodeInvalid},
+		{"merge into descendant", func() error { _, err := db.MergeClassifierNodes(id, "tools", "hammers", false); return err }, ErrClassifierNodeInvalid},
+		{"delete with children", func() error { return db.DeleteClassifierNode(id, "tools") }, ErrClassifierNodeInvalid},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.RenameClassifierNode(id, "tools", "Инструменты"); err != nil {
+		t.Fatalf("RenameClassifierNode() error = %v", err)
+	}
+	if _, err := db.MoveClassifierNode(id, "hand", "fasteners"); err != nil {
+		t.Fatalf("MoveClassifierNode() error = %v", err)
+	}
+
+	// Элемент, классифицированный в Крепеж / Ручной / Молотки, при слиянии Крепеж -> Инструменты
+	if _, err := db.conn.Exec(`INSERT INTO uploads (upload_uuid) VALUES ('u-1')`); err != nil {
+		t.Fatalf("insert upload: %v", err)
+	}
+	if _, err := db.conn.Exec(`INSERT INTO catalogs (upload_id, name) VALUES (1, 'Номенклатура')`); err != nil {
+		t.Fatalf("insert catalog: %v", err)
+	}
+	_, err = db.conn.Exec(`INSERT INTO catalog_items (catalog_id, reference, name, category_level1, category_level2, category_level3)
+		VALUES (1, 'ref-1', 'Молоток 500г', 'Крепеж', 'Ручной', 'Молотки'), (1, 'ref-2', 'Дрель', 'Электро', '', '')`)
+	if err != nil {
+		t.Fatalf("insert catalog items: %v", err)
+	}
+
+	result, err := db.MergeClassifierNodes(id, "fasteners", "tools", true)
+	if err != nil {
+		t.Fatalf("MergeClassifierNodes() error = %v", err)
+	}
+	if result.MovedChildren != 2 || result.MovedItems != 1 {
+		t.Errorf("MergeClassifierNodes() = %+v, want 2 children and 1 item moved", result)
+	}
+
+	paths := map[string]string{}
+	nodes, _ := db.GetClassifierNodes(id)
+	for _, node := range nodes {
+		paths[node.Code] = node.Path
+	}
+	want := map[string]string{
+		"tools":   "Инструменты",
+		"hand":    "Инструменты / Ручной",
+		"hammers": "Инструменты / Ручной / Молотки",
+		"bolts":   "Инструменты / Болты",
+	}
+	if len(paths) != len(want) {
+		t.Errorf("nodes after merge = %v, want %v", paths, want)
+	}
+	for code, path := range want {
+		if paths[code] != path {
+			t.Errorf("path of %s = %q, want %q", code, paths[code], path)
+		}
+	}
+
+	var level1, level2, level3 string
+	db.conn.QueryRow(`SELECT category_level1, category_level2, category_level3 FROM catalog_items WHERE reference = 'ref-1'`).Scan(&level1, &level2, &level3)
+	if level1 != "Инструменты" || level2 != "Ручной" || level3 != "Молотки" {
+		t.Errorf("moved item levels = %s / %s / %s", level1, level2, level3)
+	}
+
+	if err := db.DeleteClassifierNode(id, "bolts"); err != nil {
+		t.Errorf("DeleteClassifierNode() error = %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ClassifierNode узел дерева классификатора, хранимый отдельной строкой
+type ClassifierNode struct {
+	ID           int    `json:"id"`
+	ClassifierID int    `json:"classifier_id"`
+	Code         string `json:"code"`
+	ParentCode   string `json:"parent_code,omitempty"`
+	Name         string `json:"name"`
+	Level        int    `json:"level"`
+	Path         string `json:"path"`
+	Position     int    `json:"position"`           // Порядок среди соседних узлов
+	Metadata     string `json:"metadata,omitempty"` // JSON с метаданными узла
+}
+
+// classifierNodeColumns колонки узлов в порядке, ожидаемом scanClassifierNode
+const classifierNodeColumns = `id, classifier_id, code, COALESCE(parent_code, ''), name, level, COALESCE(path, ''), position, COALESCE(metadata, '')`
+
+// CreateClassifierNodesTable создает таблицу узлов классификаторов.
+// Заменяет разбор JSON поля category_classifiers.tree_structure при каждом обращении.
+func CreateClassifierNodesTable(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS category_classifier_nodes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			classifier_id INTEGER NOT NULL,
+			code TEXT NOT NULL,
+			parent_code TEXT,
+			name TEXT NOT NULL,
+			level INTEGER NOT NULL DEFAULT 0,
+			path TEXT,
+			position INTEGER NOT NULL DEFAULT 0,
+			metadata TEXT,
+			FOREIGN KEY (classifier_id) REFERENCES category_classifiers(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_classifier_nodes_code ON category_classifier_nodes(classifier_id, code);
+		CREATE INDEX IF NOT EXISTS idx_classifier_nodes_parent ON category_classifier_nodes(classifier_id, parent_code);
+		CREATE INDEX IF NOT EXISTS idx_classifier_nodes_level ON category_classifier_nodes(classifier_id, level);
+		CREATE INDEX IF NOT EXISTS idx_classifier_nodes_path ON category_classifier_nodes(path);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create category_classifier_nodes table: %w", err)
+	}
+
+	return nil
+}
+
+// scanClassifierNode сканирует строку с колонками classifierNodeColumns
+func scanClassifierNode(scanner rowScanner) (*ClassifierNode, error) {
+	node := &ClassifierNode{}
+	err := scanner.Scan(&node.ID, &node.ClassifierID, &node.Code, &node.ParentCode,
+		&node.Name, &node.Level, &node.Path, &node.Position, &node.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// SaveClassifierNodes заменяет узлы классификатора переданными (в одной транзакции)
+func (db *DB) SaveClassifierNodes(classifierID int, nodes []ClassifierNode) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM category_classifier_nodes WHERE classifier_id = ?`, classifierID); err != nil {
+		return fmt.Errorf("failed to delete classifier nodes: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO category_classifier_nodes
+		(classifier_id, code, parent_code, name, level, path, position, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare classifier node statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, node := range nodes {
+		var parentCode, metadata interface{}
+		if node.ParentCode != "" {
+			parentCode = node.ParentCode
+		}
+		if node.Metadata != "" {
+			metadata = node.Metadata
+		}
+		if _, err := stmt.Exec(classifierID, node.Code, parentCode, node.Name, node.Level, node.Path, node.Position, metadata); err != nil {
+			return fmt.Errorf("failed to insert classifier node %s: %w", node.Code, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteClassifierNodes удаляет узлы классификатора (они будут пересобраны из tree_structure)
+func (db *DB) DeleteClassifierNodes(classifierID int) error {
+	if _, err := db.conn.Exec(`DELETE FROM category_classifier_nodes WHERE classifier_id = ?`, classifierID); err != nil {
+		return fmt.Errorf("failed to delete classifier nodes: %w", err)
+	}
+	return nil
+}
+
+// queryClassifierNodes выполняет запрос узлов и сканирует результат
+func (db *DB) queryClassifierNodes(query string, args ...interface{}) ([]ClassifierNode, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classifier nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []ClassifierNode
+	for rows.Next() {
+		node, err := scanClassifierNode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan classifier node: %w", err)
+		}
+		nodes = append(nodes, *node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate classifier nodes: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// GetClassifierNodes возвращает все узлы классификатора в порядке обхода по уровням
+func (db *DB) GetClassifierNodes(classifierID int) ([]ClassifierNode, error) {
+	return db.queryClassifierNodes(`
+		SELECT `+classifierNodeColumns+`
+		FROM category_classifier_nodes
+		WHERE classifier_id = ?
+		ORDER BY level, position, id
+	`, classifierID)
+}
+
+// GetClassifierNodeChildren возвращает дочерние узлы (для пустого parentCode - узлы без родителя)
+func (db *DB) GetClassifierNodeChildren(classifierID int, parentCode string) ([]ClassifierNode, error) {
+	if parentCode == "" {
+		return db.queryClassifierNodes(`
+			SELECT `+classifierNodeColumns+`
+			FROM category_classifier_nodes
+			WHERE classifier_id = ? AND parent_code IS NULL
+			ORDER BY position, id
+		`, classifierID)
+	}
+	return db.queryClassifierNodes(`
+		SELECT `+classifierNodeColumns+`
+		FROM category_classifier_nodes
+		WHERE classifier_id = ? AND parent_code = ?
+		ORDER BY position, id
+	`, classifierID, parentCode)
+}
+
+// CountClassifierNodes возвращает количество узлов классификатора
+func (db *DB) CountClassifierNodes(classifierID int) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM category_classifier_nodes WHERE classifier_id = ?`, classifierID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count classifier nodes: %w", err)
+	}
+	return count, nil
+}
+
+// SaveClassifierTreeStructure обновляет JSON дерева классификатора, не трогая узлы.
+// Используется после редактирования узлов, чтобы tree_structure оставалось согласованным с ними
+func (db *DB) SaveClassifierTreeStructure(classifierID int, treeJSON string) error {
+	_, err := db.conn.Exec(`UPDATE category_classifiers SET tree_structure = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, treeJSON, classifierID)
+	if err != nil {
+		return fmt.Errorf("failed to save classifier tree structure: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Статусы строк файла соответствий клиента
+const (
+	CodeMappingStatusValid   = "valid"   // Соответствие принято
+	CodeMappingStatusWarning = "warning" // Соответствие принято, но требует внимания
+	CodeMappingStatusInvalid = "invalid" // Соответствие отклонено и не применяется
+)
+
+// ClientMappingFile загруженный файл соответствий кодов клиента
+type ClientMappingFile struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"project_id"`
+	FileName    string    `json:"file_name"`
+	Format      string    `json:"format"`
+	TotalRows   int       `json:"total_rows"`
+	ValidRows   int       `json:"valid_rows"`
+	WarningRows int       `json:"warning_rows"`
+	InvalidRows int       `json:"invalid_rows"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ClientCodeMapping строка файла соответствий: старый код клиента -> новый код
+type ClientCodeMapping struct {
+	ID         int    `json:"id"`
+	FileID     int    `json:"file_id"`
+	ProjectID  int    `json:"project_id"`
+	RowNumber  int    `json:"row_number"`
+	SourceCode string `json:"source_code"`
+	TargetCode string `json:"target_code"`
+	TargetName string `json:"target_name,omitempty"`
+	Status     string `json:"status"`
+	Issue      string `json:"issue,omitempty"`
+}
+
+// IsApplicable проверяет, применяется ли соответствие при нормализации
+func (m *ClientCodeMapping) IsApplicable() bool {
+	return m.Status == CodeMappingStatusValid || m.Status == CodeMappingStatusWarning
+}
+
+// CreateClientCodeMappingTables создает таблицы файлов соответствий клиентов
+func CreateClientCodeMappingTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS client_mapping_files (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			project_id INTEGER NOT NULL,
+			file_name TEXT NOT NULL,
+			format TEXT NOT NULL,
+			total_rows INTEGER NOT NULL DEFAULT 0,
+			valid_rows INTEGER NOT NULL DEFAULT 0,
+			warning_rows INTEGER NOT NULL DEFAULT 0,
+			invalid_rows INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE IF NOT EXISTS client_code_mappings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			file_id INTEGER NOT NULL,
+			project_id INTEGER NOT NULL,
+			row_number INTEGER NOT NULL,
+			source_code TEXT NOT NULL,
+			target_code TEXT NOT NULL,
+			target_name TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			issue TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY(file_id) REFERENCES client_mapping_files(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_client_mapping_files_project ON client_mapping_files(project_id);
+		CREATE INDEX IF NOT EXISTS idx_client_code_mappings_file ON client_code_mappings(file_id, status);
+		CREATE INDEX IF NOT EXISTS idx_client_code_mappings_project ON client_code_mappings(project_id, source_code);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create client mapping tables: %w", err)
+	}
+	return nil
+}
+
+// CreateClientMappingFile сохраняет файл соответствий проекта вместе с его строками
+func (db *ServiceDB) CreateClientMappingFile(file *ClientMappingFile, mappings []*ClientCodeMapping) error {
+	file.FileName = strings.TrimSpace(file.FileName)
+	if file.FileName == "" {
+		return fmt.Errorf("mapping file name is required")
+	}
+	file.TotalRows = len(mappings)
+	file.ValidRows, file.WarningRows, file.InvalidRows = 0, 0, 0
+	for _, mapping := range mappings {
+		switch mapping.Status {
+		case CodeMappingStatusValid:
+			file.ValidRows++
+		case CodeMappingStatusWarning:
+			file.WarningRows++
+		case CodeMappingStatusInvalid:
+			file.InvalidRows++
+		default:
+			return fmt.Errorf("invalid mapping status %q in row %d", mapping.Status, mapping.RowNumber)
+		}
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO client_mapping_files (project_id, file_name, format, total_rows, valid_rows, warning_rows, invalid_rows, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, file.ProjectID, file.FileName, file.Format, file.TotalRows, file.ValidRows, file.WarningRows, file.InvalidRows, now)
+	if err != nil {
+		return fmt.Errorf("failed to create mapping file: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get mapping file id: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO client_code_mappings (file_id, project_id, row_number, source_code, target_code, target_name, status, issue)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare mapping insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, mapping := range mappings {
+		result, err := stmt.Exec(id, file.ProjectID, mapping.RowNumber, mapping.SourceCode, mapping.TargetCode,
+			mapping.TargetName, mapping.Status, mapping.Issue)
+		if err != nil {
+			return fmt.Errorf("failed to insert mapping row %d: %w", mapping.RowNumber, err)
+		}
+		mappingID, _ := result.LastInsertId()
+		mapping.ID = int(mappingID)
+		mapping.FileID = int(id)
+		mapping.ProjectID = file.ProjectID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit mapping file: %w", err)
+	}
+	file.ID = int(id)
+	file.CreatedAt = now
+	return nil
+}
+
+// GetClientMappingFiles возвращает файлы соответствий проекта, новые первыми
+func (db *ServiceDB) GetClientMappingFiles(projectID int) ([]*ClientMappingFile, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, project_id, file_name, format, total_rows, valid_rows, warning_rows, invalid_rows, created_at
+		FROM client_mapping_files
+		WHERE project_id = ?
+		ORDER BY id DESC
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mapping files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []*ClientMappingFile{}
+	for rows.Next() {
+		file := &ClientMappingFile{}
+		if err := rows.Scan(&file.ID, &file.ProjectID, &file.FileName, &file.Format, &file.TotalRows,
+			&file.ValidRows, &file.WarningRows, &file.InvalidRows, &file.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan mapping file: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// GetClientMappingFile возвращает файл соответствий проекта
+func (db *ServiceDB) GetClientMappingFile(projectID, id int) (*ClientMappingFile, error) {
+	file := &ClientMappingFile{}
+	err := db.conn.QueryRow(`
+		SELECT id, project_id, file_name, format, total_rows, valid_rows, warning_rows, invalid_rows, created_at
+		FROM client_mapping_files
+		WHERE id = ? AND project_id = ?
+	`, id, projectID).Scan(&file.ID, &file.ProjectID, &file.FileName, &file.Format, &file.TotalRows,
+		&file.ValidRows, &file.WarningRows, &file.InvalidRows, &file.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("mapping file not found")
+		}
+		return nil, fmt.Errorf("failed to get mapping file: %w", err)
+	}
+	return file, nil
+}
+
+// GetClientCodeMappings возвращает строки файла соответствий, status пустой - все статусы
+func (db *ServiceDB) GetClientCodeMappings(projectID, fileID int, status string) ([]*ClientCodeMapping, error) {
+	query := `
+		SELECT id, file_id, project_id, row_number, source_code, target_code, target_name, status, issue
+		FROM client_code_mappings
+		WHERE project_id = ? AND file_id = ?`
+	args := []interface{}{projectID, fileID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY row_number"
+	return db.queryClientCodeMappings(query, args...)
+}
+
+// GetActiveClientCodeMappings возвращает применяемые соответствия проекта.
+// Если код встречается в нескольких файлах, действует соответствие из последнего загруженного файла
+func (db *ServiceDB) GetActiveClientCodeMappings(projectID int) ([]*ClientCodeMapping, error) {
+	mappings, err := db.queryClientCodeMappings(`
+		SELECT id, file_id, project_id, row_number, source_code, target_code, target_name, status, issue
+		FROM client_code_mappings
+		WHERE project_id = ? AND status IN (?, ?)
+		ORDER BY file_id DESC, row_number
+	`, projectID, CodeMappingStatusValid, CodeMappingStatusWarning)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(mappings))
+	active := make([]*ClientCodeMapping, 0, len(mappings))
+	for _, mapping := range mappings {
+		if seen[mapping.SourceCode] {
+			continue
+		}
+		seen[mapping.SourceCode] = true
+		active = append(active, mapping)
+	}
+	return active, nil
+}
+
+// queryClientCodeMappings выполняет запрос строк файлов соответствий
+func (db *ServiceDB) queryClientCodeMappings(query string, args ...interface{}) ([]*ClientCodeMapping, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get code mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []*ClientCodeMapping{}
+	for rows.Next() {
+		mapping := &ClientCodeMapping{}
+		if err := rows.Scan(&mapping.ID, &mapping.FileID, &mapping.ProjectID, &mapping.RowNumber, &mapping.SourceCode,
+			&mapping.TargetCode, &mapping.TargetName, &mapping.Status, &mapping.Issue); err != nil {
+			return nil, fmt.Errorf("failed to scan code mapping: %w", err)
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, rows.Err()
+}
+
+// DeleteClientMappingFile удаляет файл соответствий проекта вместе с его строками
+func (db *ServiceDB) DeleteClientMappingFile(projectID, id int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM client_mapping_files WHERE id = ? AND project_id = ?`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete mapping file: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("mapping file not found")
+	}
+	if _, err := tx.Exec(`DELETE FROM client_code_mappings WHERE file_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete code mappings: %w", err)
+	}
+	return tx.Commit()
+}
+
+// codeLookupBatchSize ограничивает число параметров в запросах поиска кодов
+const codeLookupBatchSize = 500
+
+// FindExistingCodes возвращает коды, присутствующие в исходных или нормализованных данных базы
+func (db *DB) FindExistingCodes(codes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	err := forEachCodeBatch(codes, func(placeholders string, args []interface{}) error {
+		rows, err := db.conn.Query(`
+			SELECT code FROM catalog_items WHERE code IN (`+placeholders+`)
+			UNION
+			SELECT code FROM normalized_data WHERE code IN (`+placeholders+`)
+		`, append(args, args...)...)
+		if err != nil {
+			return fmt.Errorf("failed to find existing codes: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var code sql.NullString
+			if err := rows.Scan(&code); err != nil {
+				return fmt.Errorf("failed to scan code: %w", err)
+			}
+			existing[code.String] = true
+		}
+		return rows.Err()
+	})
+	return existing, err
+}
+
+// GetNormalizedReferencesByCodes возвращает группу нормализации (normalized_reference) для каждого найденного кода
+func (db *DB) GetNormalizedReferencesByCodes(codes []string) (map[string]string, error) {
+	references := make(map[string]string)
+	err := forEachCodeBatch(codes, func(placeholders string, args []interface{}) error {
+		rows, err := db.conn.Query(`
+			SELECT code, normalized_reference FROM normalized_data
+			WHERE code IN (`+placeholders+`)
+		`, args...)
+		if err != nil {
+			return fmt.Errorf("failed to get normalized references: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var code, reference sql.NullString
+			if err := rows.Scan(&code, &reference); err != nil {
+				return fmt.Errorf("failed to scan normalized reference: %w", err)
+			}
+			references[code.String] = reference.String
+		}
+		return rows.Err()
+	})
+	return references, err
+}
+
+// forEachCodeBatch вызывает fn для пакетов кодов с готовым списком параметров запроса
+func forEachCodeBatch(codes []string, fn func(placeholders string, args []interface{}) error) error {
+	for start := 0; start < len(codes); start += codeLookupBatchSize {
+		end := start + codeLookupBatchSize
+		if end > len(codes) {
+			end = len(codes)
+		}
+		args := make([]interface{}, 0, end-start)
+		for _, code := range codes[start:end] {
+			args = append(args, code)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+		if err := fn(placeholders, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
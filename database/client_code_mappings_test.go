@@ -0,0 +1,117 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestClientMappingFiles(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Клиент", "ООО Клиент", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Проект", "nomenclature", "", "", 0.8)
+	if err != nil {
+		t.Fatalf("CreateClientProject() error = %v", err)
+	}
+
+	first := &ClientMappingFile{ProjectID: project.ID, FileName: "old.csv", Format: "csv"}
+	err = db.CreateClientMappingFile(first, []*ClientCodeMapping{
+		{RowNumber: 1, SourceCode: "A-1", TargetCode: "N-1", Status: CodeMappingStatusValid},
+		{RowNumber: 2, SourceCode: "A-2", TargetCode: "N-2", Status: CodeMappingStatusWarning, Issue: "source code not found in project data"},
+		{RowNumber: 3, SourceCode: "A-3", TargetCode: "A-3", Status: CodeMappingStatusInvalid, Issue: "source code maps to itself"},
+	})
+	if err != nil {
+		t.Fatalf("CreateClientMappingFile() error = %v", err)
+	}
+	if first.ValidRows != 1 || first.WarningRows != 1 || first.InvalidRows != 1 || first.TotalRows != 3 {
+		t.Errorf("file counters = %+v", first)
+	}
+
+	second := &ClientMappingFile{ProjectID: project.ID, FileName: "new.xlsx", Format: "xlsx"}
+	err = db.CreateClientMappingFile(second, []*ClientCodeMapping{
+		{RowNumber: 2, SourceCode: "A-1", TargetCode: "N-9", Status: CodeMappingStatusValid},
+	})
+	if err != nil {
+		t.Fatalf("CreateClientMappingFile() error = %v", err)
+	}
+
+	if err := db.CreateClientMappingFile(&ClientMappingFile{ProjectID: project.ID, FileName: "bad.csv"},
+		[]*ClientCodeMapping{{RowNumber: 1, SourceCode: "A", TargetCode: "B", Status: "unknown"}}); err == nil {
+		t.Error("CreateClientMappingFile() must reject unknown status")
+	}
+
+	files, err := db.GetClientMappingFiles(project.ID)
+	if err != nil || len(files) != 2 || files[0].ID != second.ID {
+		t.Fatalf("GetClientMappingFiles() = %+v, error = %v", files, err)
+	}
+
+	tests := []struct {
+		name   string
+		status string
+		want   int
+	}{
+		{"all rows", "", 3},
+		{"invalid rows", CodeMappingStatusInvalid, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings, err := db.GetClientCodeMappings(project.ID, first.ID, tt.status)
+			if err != nil || len(mappings) != tt.want {
+				t.Errorf("GetClientCodeMappings() = %d rows, error = %v, want %d", len(mappings), err, tt.want)
+			}
+		})
+	}
+
+	active, err := db.GetActiveClientCodeMappings(project.ID)
+	if err != nil {
+		t.Fatalf("GetActiveClientCodeMappings() error = %v", err)
+	}
+	targets := map[string]string{}
+	for _, mapping := range active {
+		targets[mapping.SourceCode] = mapping.TargetCode
+	}
+	if len(targets) != 2 || targets["A-1"] != "N-9" || targets["A-2"] != "N-2" {
+		t.Errorf("active mappings = %v, want latest file to override A-1 and invalid rows skipped", targets)
+	}
+
+	if err := db.DeleteClientMappingFile(project.ID, second.ID); err != nil {
+		t.Fatalf("DeleteClientMappingFile() error = %v", err)
+	}
+	if err := db.DeleteClientMappingFile(project.ID, second.ID); err == nil {
+		t.Error("DeleteClientMappingFile() must fail for deleted file")
+	}
+	if mappings, _ := db.GetClientCodeMappings(project.ID, second.ID, ""); len(mappings) != 0 {
+		t.Errorf("rows of deleted file remain: %+v", mappings)
+	}
+}
+
+func TestFindExistingCodes(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.InsertNormalizedItemsWithAttributesBatch([]*NormalizedItem{
+		{SourceReference: "r1", SourceName: "Болт", Code: "c1", NormalizedName: "болт", NormalizedReference: "болт", Category: "крепеж"},
+		{SourceReference: "r2", SourceName: "Болт", Code: "c2", NormalizedName: "болт", NormalizedReference: "болт", Category: "крепеж"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("InsertNormalizedItemsWithAttributesBatch() error = %v", err)
+	}
+
+	existing, err := db.FindExistingCodes([]string{"c1", "c2", "missing"})
+	if err != nil || len(existing) != 2 || !existing["c1"] || existing["missing"] {
+		t.Errorf("FindExistingCodes() = %v, error = %v", existing, err)
+	}
+	references, err := db.GetNormalizedReferencesByCodes([]string{"c1", "missing"})
+	if err != nil || len(references) != 1 || references["c1"] != "болт" {
+		t.Errorf("GetNormalizedReferencesByCodes() = %v, error = %v", references, err)
+	}
+}
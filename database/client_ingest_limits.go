@@ -0,0 +1,72 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ClientIngestLimits ограничения скорости приема выгрузок клиента; 0 - без ограничения
+type ClientIngestLimits struct {
+	ClientID       int       `json:"client_id"`
+	ItemsPerSecond float64   `json:"items_per_second"`
+	MBPerSecond    float64   `json:"mb_per_second"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CreateClientIngestLimitsTable создает таблицу ограничений скорости приема выгрузок по клиентам
+func CreateClientIngestLimitsTable(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS client_ingest_limits (
+			client_id INTEGER PRIMARY KEY,
+			items_per_second REAL NOT NULL DEFAULT 0,
+			mb_per_second REAL NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create client ingest limits table: %w", err)
+	}
+
+	return nil
+}
+
+// GetClientIngestLimits возвращает ограничения клиента или nil, если они не заданы
+func (db *ServiceDB) GetClientIngestLimits(clientID int) (*ClientIngestLimits, error) {
+	limits := &ClientIngestLimits{}
+	err := db.conn.QueryRow(`
+		SELECT client_id, items_per_second, mb_per_second, updated_at
+		FROM client_ingest_limits WHERE client_id = ?
+	`, clientID).Scan(&limits.ClientID, &limits.ItemsPerSecond, &limits.MBPerSecond, &limits.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client ingest limits: %w", err)
+	}
+	return limits, nil
+}
+
+// SetClientIngestLimits сохраняет ограничения клиента
+func (db *ServiceDB) SetClientIngestLimits(limits *ClientIngestLimits) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO client_ingest_limits (client_id, items_per_second, mb_per_second)
+		VALUES (?, ?, ?)
+		ON CONFLICT(client_id) DO UPDATE SET
+			items_per_second = excluded.items_per_second, mb_per_second = excluded.mb_per_second,
+			updated_at = CURRENT_TIMESTAMP
+	`, limits.ClientID, limits.ItemsPerSecond, limits.MBPerSecond)
+	if err != nil {
+		return fmt.Errorf("failed to set client ingest limits: %w", err)
+	}
+	return nil
+}
+
+// DeleteClientIngestLimits удаляет ограничения клиента: действуют ограничения по умолчанию
+func (db *ServiceDB) DeleteClientIngestLimits(clientID int) error {
+	if _, err := db.conn.Exec(`DELETE FROM client_ingest_limits WHERE client_id = ?`, clientID); err != nil {
+		return fmt.Errorf("failed to delete client ingest limits: %w", err)
+	}
+	return nil
+}
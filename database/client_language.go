@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// MigrateClientLanguage добавляет колонку языка клиента в таблицу clients
+func MigrateClientLanguage(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE clients ADD COLUMN language TEXT DEFAULT ''`)
+	if err != nil {
+		errStr := strings.ToLower(err.Error())
+		if !strings.Contains(errStr, "duplicate column") &&
+			!strings.Contains(errStr, "already exists") {
+			return fmt.Errorf("failed to add clients.language column: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetClientLanguage возвращает язык клиента; пустая строка, если язык не задан или клиент не найден
+func (db *ServiceDB) GetClientLanguage(clientID int) (string, error) {
+	var language sql.NullString
+	err := db.conn.QueryRow(`SELECT language FROM clients WHERE id = ?`, clientID).Scan(&language)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get client language: %w", err)
+	}
+	return language.String, nil
+}
+
+// SetClientLanguage сохраняет язык клиента (пустая строка сбрасывает настройку)
+func (db *ServiceDB) SetClientLanguage(clientID int, language string) error {
+	result, err := db.conn.Exec(`
+		UPDATE clients SET language = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, language, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to set client language: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("client not found")
+	}
+	return nil
+}
+
+// MigrateClientReportLanguage добавляет колонку второго языка отчетов клиента: наименования категорий
+// в отчетах выводятся на основном языке и в переводе на этот язык
+func MigrateClientReportLanguage(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE clients ADD COLUMN report_second_language TEXT DEFAULT ''`)
+	if err != nil {
+		errStr := strings.ToLower(err.Error())
+		if !strings.Contains(errStr, "duplicate column") &&
+			!strings.Contains(errStr, "already exists") {
+			return fmt.Errorf("failed to add clients.report_second_language column: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetClientReportSecondLanguage возвращает второй язык отчетов клиента; пустая строка, если не задан
+func (db *ServiceDB) GetClientReportSecondLanguage(clientID int) (string, error) {
+	var language sql.NullString
+	err := db.conn.QueryRow(`SELECT report_second_language FROM clients WHERE id = ?`, clientID).Scan(&language)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get client report language: %w", err)
+	}
+	return language.String, nil
+}
+
+// SetClientReportSecondLanguage сохраняет второй язык отчетов клиента (пустая строка - отчеты на одном языке)
+func (db *ServiceDB) SetClientReportSecondLanguage(clientID int, language string) error {
+	result, err := db.conn.Exec(`
+		UPDATE clients SET report_second_language = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, language, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to set client report language: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("client not found")
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Каналы событий, ретранслируемых между экземплярами сервера
+const (
+	ClusterChannelMonitoring = "monitoring" // События мониторинга (JSON)
+	ClusterChannelNormalizer = "normalizer" // Сообщения нормализации для SSE
+)
+
+// ClusterInstance экземпляр сервера, работающий с общей service.db
+type ClusterInstance struct {
+	ID          string    `json:"id"`
+	Hostname    string    `json:"hostname"`
+	PID         int       `json:"pid"`
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	Alive       bool      `json:"alive"`
+}
+
+// ClusterEvent событие, опубликованное экземпляром сервера для остальных экземпляров
+type ClusterEvent struct {
+	ID         int64     `json:"id"`
+	InstanceID string    `json:"instance_id"`
+	Channel    string    `json:"channel"`
+	Payload    string    `json:"payload"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateClusterTables создает таблицы экземпляров сервера и ретрансляции событий
+func CreateClusterTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS cluster_instances (
+			instance_id TEXT PRIMARY KEY,
+			hostname TEXT NOT NULL DEFAULT '',
+			pid INTEGER NOT NULL DEFAULT 0,
+			started_at TIMESTAMP NOT NULL,
+			heartbeat_at TIMESTAMP NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS cluster_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			instance_id TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_cluster_events_created ON cluster_events(created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create cluster tables: %w", err)
+	}
+	return nil
+}
+
+// RegisterClusterInstance регистрирует экземпляр сервера или обновляет запись при его перезапуске
+func (db *ServiceDB) RegisterClusterInstance(instance *ClusterInstance) error {
+	if instance.ID == "" {
+		return fmt.Errorf("instance id is required")
+	}
+	now := time.Now().UTC()
+	if instance.StartedAt.IsZero() {
+		instance.StartedAt = now
+	}
+	instance.HeartbeatAt = now
+	_, err := db.conn.Exec(`
+		INSERT INTO cluster_instances (instance_id, hostname, pid, started_at, heartbeat_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(instance_id) DO UPDATE SET
+			hostname = excluded.hostname, pid = excluded.pid, started_at = excluded.started_at, heartbeat_at = excluded.heartbeat_at
+	`, instance.ID, instance.Hostname, instance.PID, instance.StartedAt.UTC(), instance.HeartbeatAt)
+	if err != nil {
+		return fmt.Errorf("failed to register cluster instance: %w", err)
+	}
+	return nil
+}
+
+// HeartbeatClusterInstance отмечает экземпляр живым и продлевает аренду его фоновых задач на leaseTTL.
+// Возвращает false, если экземпляр не зарегистрирован (запись удалена)
+func (db *ServiceDB) HeartbeatClusterInstance(instanceID string, leaseTTL time.Duration) (bool, error) {
+	result, err := db.conn.Exec(`UPDATE cluster_instances SET heartbeat_at = ? WHERE instance_id = ?`, time.Now().UTC(), instanceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update cluster instance heartbeat: %w", err)
+	}
+	if err := db.RenewBackgroundJobLeases(instanceID, leaseTTL); err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// UnregisterClusterInstance удаляет экземпляр при штатной остановке
+func (db *ServiceDB) UnregisterClusterInstance(instanceID string) error {
+	if _, err := db.conn.Exec(`DELETE FROM cluster_instances WHERE instance_id = ?`, instanceID); err != nil {
+		return fmt.Errorf("failed to unregister cluster instance: %w", err)
+	}
+	return nil
+}
+
+// GetClusterInstances возвращает зарегистрированные экземпляры. Экземпляр жив, если его heartbeat не старше staleAfter
+func (db *ServiceDB) GetClusterInstances(staleAfter time.Duration) ([]*ClusterInstance, error) {
+	rows, err := db.conn.Query(`
+		SELECT instance_id, hostname, pid, started_at, heartbeat_at
+		FROM cluster_instances ORDER BY started_at, instance_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster instances: %w", err)
+	}
+	defer rows.Close()
+
+	threshold := time.Now().Add(-staleAfter)
+	instances := []*ClusterInstance{}
+	for rows.Next() {
+		instance := &ClusterInstance{}
+		if err := rows.Scan(&instance.ID, &instance.Hostname, &instance.PID, &instance.StartedAt, &instance.HeartbeatAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cluster instance: %w", err)
+		}
+		instance.Alive = instance.HeartbeatAt.After(threshold)
+		instances = append(instances, instance)
+	}
+	return instances, rows.Err()
+}
+
+// PublishClusterEvent сохраняет событие экземпляра для ретрансляции остальным экземплярам
+func (db *ServiceDB) PublishClusterEvent(instanceID, channel, payload string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO cluster_events (instance_id, channel, payload, created_at) VALUES (?, ?, ?, ?)
+	`, instanceID, channel, payload, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to publish cluster event: %w", err)
+	}
+	return nil
+}
+
+// GetClusterEventsSince возвращает события других экземпляров (кроме excludeInstance) с ID больше afterID
+func (db *ServiceDB) GetClusterEventsSince(afterID int64, excludeInstance string, limit int) ([]*ClusterEvent, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, instance_id, channel, payload, created_at FROM cluster_events
+		WHERE id > ? AND instance_id != ?
+		ORDER BY id LIMIT ?
+	`, afterID, excludeInstance, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*ClusterEvent{}
+	for rows.Next() {
+		event := &ClusterEvent{}
+		if err := rows.Scan(&event.ID, &event.InstanceID, &event.Channel, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cluster event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// LastClusterEventID возвращает ID последнего события; новый экземпляр ретранслирует только последующие события
+func (db *ServiceDB) LastClusterEventID() (int64, error) {
+	var id int64
+	if err := db.conn.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM cluster_events`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get last cluster event id: %w", err)
+	}
+	return id, nil
+}
+
+// PruneClusterEvents удаляет события старше before. Возвращает число удаленных событий
+func (db *ServiceDB) PruneClusterEvents(before time.Time) (int64, error) {
+	result, err := db.conn.Exec(`DELETE FROM cluster_events WHERE created_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune cluster events: %w", err)
+	}
+	return result.RowsAffected()
+}
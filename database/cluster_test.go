@@ -0,0 +1,82 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClusterInstancesAndEvents(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	for _, id := range []string{"node-a", "node-b"} {
+		if err := db.RegisterClusterInstance(&ClusterInstance{ID: id, Hostname: "host", PID: 1}); err != nil {
+			t.Fatalf("RegisterClusterInstance(%s) error = %v", id, err)
+		}
+	}
+	if err := db.RegisterClusterInstance(&ClusterInstance{}); err == nil {
+		t.Error("RegisterClusterInstance() must reject empty id")
+	}
+	if ok, err := db.HeartbeatClusterInstance("node-a", time.Minute); err != nil || !ok {
+		t.Errorf("HeartbeatClusterInstance() = %v, %v", ok, err)
+	}
+	if err := db.UnregisterClusterInstance("node-b"); err != nil {
+		t.Fatalf("UnregisterClusterInstance() error = %v", err)
+	}
+	if ok, _ := db.HeartbeatClusterInstance("node-b", time.Minute); ok {
+		t.Error("HeartbeatClusterInstance() of unregistered instance must return false")
+	}
+	instances, err := db.GetClusterInstances(time.Minute)
+	if err != nil || len(instances) != 1 || !instances[0].Alive {
+		t.Errorf("GetClusterInstances() = %+v, %v, want alive node-a", instances, err)
+	}
+	if stale, _ := db.GetClusterInstances(-time.Minute); len(stale) != 1 || stale[0].Alive {
+		t.Errorf("GetClusterInstances() = %+v, want stale node-a", stale)
+	}
+
+	events := []struct {
+		instance string
+		channel  string
+	}{
+		{"node-a", ClusterChannelMonitoring},
+		{"node-b", ClusterChannelNormalizer},
+		{"node-b", ClusterChannelMonitoring},
+	}
+	for _, e := range events {
+		if err := db.PublishClusterEvent(e.instance, e.channel, "{}"); err != nil {
+			t.Fatalf("PublishClusterEvent() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		afterID int64
+		exclude string
+		want    int
+	}{
+		{"events of other instances", 0, "node-a", 2},
+		{"after id", 2, "node-a", 1},
+		{"own events excluded", 0, "node-b", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.GetClusterEventsSince(tt.afterID, tt.exclude, 100)
+			if err != nil {
+				t.Fatalf("GetClusterEventsSince() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("GetClusterEventsSince() = %d events, want %d", len(got), tt.want)
+			}
+		})
+	}
+
+	if last, err := db.LastClusterEventID(); err != nil || last != 3 {
+		t.Errorf("LastClusterEventID() = %d, %v, want 3", last, err)
+	}
+	if pruned, err := db.PruneClusterEvents(time.Now().Add(time.Minute)); err != nil || pruned != 3 {
+		t.Errorf("PruneClusterEvents() = %d, %v, want 3", pruned, err)
+	}
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Виды типизированных значений констант
+const (
+	ConstantKindBoolean   = "boolean"
+	ConstantKindNumber    = "number"
+	ConstantKindDate      = "date"
+	ConstantKindString    = "string"
+	ConstantKindReference = "reference"
+	ConstantKindUndefined = "undefined"
+)
+
+// constantDateLayout формат хранения дат констант (1С передает даты без часового пояса)
+const constantDateLayout = "2006-01-02T15:04:05"
+
+// constantValueTagRegex разбирает XML значения константы вида <number>1</number> или <undefined/>
+var constantValueTagRegex = regexp.MustCompile(`(?s)^\s*<([A-Za-z_]+)\s*(?:/>|>(.*)</([A-Za-z_]+)>)\s*$`)
+
+// constantColumns колонки констант в порядке, ожидаемом scanConstant
+const constantColumns = `id, upload_id, name, synonym, type, value, value_kind, typed_value, created_at`
+
+// ParseConstantValue разбирает сырое XML значение константы в типизированное значение.
+// Вид определяется по тегу значения, а если тега нет - по объявленному типу 1С.
+// Если значение не разбирается как объявленный тип, оно сохраняется как строка.
+func ParseConstantValue(declaredType, rawValue string) (string, interface{}) {
+	tag, text := "", strings.TrimSpace(rawValue)
+	if match := constantValueTagRegex.FindStringSubmatch(rawValue); match != nil && (match[3] == "" || match[3] == match[1]) {
+		tag, text = strings.ToLower(match[1]), strings.TrimSpace(match[2])
+	}
+
+	kind := constantKindByTag(tag)
+	if kind == "" {
+		kind = constantKindByDeclaredType(declaredType)
+	}
+
+	switch kind {
+	case ConstantKindUndefined:
+		return kind, nil
+	case ConstantKindBoolean:
+		if value, ok := parseConstantBool(text); ok {
+			return kind, value
+		}
+	case ConstantKindNumber:
+		if value, ok := parseConstantNumber(text); ok {
+			return kind, value
+		}
+	case ConstantKindDate:
+		if value, ok := parseConstantDate(text); ok {
+			return kind, value.Format(constantDateLayout)
+		}
+	case ConstantKindReference:
+		return kind, text
+	}
+
+	return ConstantKindString, text
+}
+
+// constantKindByTag определяет вид значения по XML тегу, который формирует обработка 1С
+func constantKindByTag(tag string) string {
+	switch tag {
+	case "boolean":
+		return ConstantKindBoolean
+	case "number":
+		return ConstantKindNumber
+	case "date":
+		return ConstantKindDate
+	case "string", "uuid":
+		return ConstantKindString
+	case "reference":
+		return ConstantKindReference
+	case "undefined":
+		return ConstantKindUndefined
+	}
+	return ""
+}
+
+// constantKindByDeclaredType определяет вид значения по объявленному типу 1С
+func constantKindByDeclaredType(declaredType string) string {
+	normalized := strings.ToLower(strings.TrimSpace(declaredType))
+	switch {
+	case normalized == "булево" || normalized == "boolean":
+		return ConstantKindBoolean
+	case normalized == "число" || normalized == "number":
+		return ConstantKindNumber
+	case normalized == "дата" || normalized == "date":
+		return ConstantKindDate
+	case strings.Contains(normalized, "ссылка") || strings.HasSuffix(normalized, "ref"):
+		return ConstantKindReference
+	}
+	return ConstantKindString
+}
+
+// parseConstantBool разбирает булево значение (true/false, Да/Нет, Истина/Ложь, 1/0)
+func parseConstantBool(text string) (bool, bool) {
+	switch strings.ToLower(text) {
+	case "true", "да", "истина", "1":
+		return true, true
+	case "false", "нет", "ложь", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// parseConstantNumber разбирает число в представлении 1С ("1 234,56" с неразрывными пробелами)
+func parseConstantNumber(text string) (float64, bool) {
+	cleaned := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(text)
+	if cleaned == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(cleaned, 64)
+	return value, err == nil
+}
+
+// parseConstantDate разбирает дату в форматах XML и представления 1С
+func parseConstantDate(text string) (time.Time, bool) {
+	layouts := []string{constantDateLayout, time.RFC3339, "2006-01-02", "02.01.2006 15:04:05", "02.01.2006"}
+	for _, layout := range layouts {
+		if value, err := time.Parse(layout, text); err == nil {
+			return value, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// decodeConstantTypedValue приводит значение колонки typed_value к типу Go по виду значения
+func decodeConstantTypedValue(kind string, raw interface{}) interface{} {
+	if raw == nil {
+		return nil
+	}
+	if bytes, ok := raw.([]byte); ok {
+		raw = string(bytes)
+	}
+
+	switch kind {
+	case ConstantKindBoolean:
+		switch value := raw.(type) {
+		case int64:
+			return value != 0
+		case bool:
+			return value
+		}
+	case ConstantKindNumber:
+		switch value := raw.(type) {
+		case float64:
+			return value
+		case int64:
+			return float64(value)
+		}
+	}
+	return raw
+}
+
+// FormatConstantTypedValue возвращает строковое представление типизированного значения (для XML)
+func FormatConstantTypedValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// scanConstant сканирует строку с колонками constantColumns
+func scanConstant(scanner rowScanner) (*Constant, error) {
+	constant := &Constant{}
+	var valueKind sql.NullString
+	var typedValue interface{}
+
+	err := scanner.Scan(
+		&constant.ID, &constant.UploadID, &constant.Name, &constant.Synonym,
+		&constant.Type, &constant.Value, &valueKind, &typedValue, &constant.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	constant.ValueKind = valueKind.String
+	constant.TypedValue = decodeConstantTypedValue(constant.ValueKind, typedValue)
+	return constant, nil
+}
+
+// MigrateConstantsTypedValues добавляет в таблицу constants колонки типизированного значения
+// и заполняет их для уже загруженных констант
+func MigrateConstantsTypedValues(db *sql.DB) error {
+	migrations := []string{
+		`ALTER TABLE constants ADD COLUMN value_kind TEXT`,
+		`ALTER TABLE constants ADD COLUMN typed_value`,
+	}
+
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil {
+			errStr := strings.ToLower(err.Error())
+			if !strings.Contains(errStr, "duplicate column") &&
+				!strings.Contains(errStr, "already exists") {
+				return fmt.Errorf("migration failed: %s, error: %w", migration, err)
+			}
+		}
+	}
+
+	// Заполняем типизированные значения для констант, загруженных до миграции
+	rows, err := db.Query(`SELECT id, COALESCE(type, ''), COALESCE(value, '') FROM constants WHERE value_kind IS NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to query constants for backfill: %w", err)
+	}
+
+	type pendingConstant struct {
+		id                  int
+		declaredType, value string
+	}
+	var pending []pendingConstant
+	for rows.Next() {
+		var item pendingConstant
+		if err := rows.Scan(&item.id, &item.declaredType, &item.value); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan constant for backfill: %w", err)
+		}
+		pending = append(pending, item)
+	}
+	rows.Close()
+
+	for _, item := range pending {
+		kind, typed := ParseConstantValue(item.declaredType, item.value)
+		if _, err := db.Exec(`UPDATE constants SET value_kind = ?, typed_value = ? WHERE id = ?`, kind, typed, item.id); err != nil {
+			return fmt.Errorf("failed to backfill constant %d: %w", item.id, err)
+		}
+	}
+
+	return nil
+}
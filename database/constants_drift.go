@@ -0,0 +1,250 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Признаки чувствительных констант: пути, адреса серверов и учетные данные
+const (
+	ConstantSensitivePath   = "path"   // Путь к файлу, каталогу или базе
+	ConstantSensitiveServer = "server" // Адрес сервера, URL или строка соединения
+	ConstantSensitiveSecret = "secret" // Пароль, токен, ключ - значение в отчете маскируется
+)
+
+// constantSensitiveKeywords слова в имени и синониме константы по признакам,
+// как в поиске констант утилиты analyze_1c_db
+var constantSensitiveKeywords = map[string][]string{
+	ConstantSensitivePath:   {"путь", "каталог", "папк", "расположение", "директори", "path", "folder", "dir"},
+	ConstantSensitiveServer: {"сервер", "адрес", "хост", "порт", "url", "host", "server", "port", "endpoint"},
+	ConstantSensitiveSecret: {"пароль", "токен", "секрет", "ключapi", "password", "passwd", "token", "secret", "apikey"},
+}
+
+var (
+	constantPathValueRegex   = regexp.MustCompile(`^(?:[A-Za-z]:[\\/]|\\\\[^\\]+\\|/[^/\s]+/)`)
+	constantServerValueRegex = regexp.MustCompile(`(?i)^[a-z][a-z0-9+.-]*://|\bsrvr\s*=|^\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?$`)
+)
+
+// ConstantSensitivity возвращает признаки чувствительной константы по имени, синониму и значению
+func ConstantSensitivity(name, synonym, value string) []string {
+	text := strings.ToLower(name + " " + synonym)
+	text = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(text)
+	value = strings.TrimSpace(value)
+
+	var reasons []string
+	for _, reason := range []string{ConstantSensitivePath, ConstantSensitiveServer, ConstantSensitiveSecret} {
+		matched := false
+		for _, keyword := range constantSensitiveKeywords[reason] {
+			if strings.Contains(text, keyword) {
+				matched = true
+				break
+			}
+		}
+		switch {
+		case matched:
+		case reason == ConstantSensitivePath:
+			matched = constantPathValueRegex.MatchString(value) || strings.HasPrefix(strings.ToLower(value), "file=")
+		case reason == ConstantSensitiveServer:
+			matched = constantServerValueRegex.MatchString(value)
+		}
+		if matched {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// ConstantDriftDatabase база проекта в матрице расхождений и ее последняя завершенная выгрузка
+type ConstantDriftDatabase struct {
+	DatabaseID int                `json:"database_id"`
+	Database   string             `json:"database"`
+	Upload     *ConstantUploadRef `json:"upload,omitempty"` // Нет, если завершенных выгрузок не было
+	Error      string             `json:"error,omitempty"`
+}
+
+// ConstantDriftCell значение константы в одной базе. Variant - номер варианта значения
+// (0 - самое частое среди баз), -1 - константы в базе нет
+type ConstantDriftCell struct {
+	Present   bool        `json:"present"`
+	Value     interface{} `json:"value,omitempty"`
+	ValueKind string      `json:"value_kind,omitempty"`
+	Variant   int         `json:"variant"`
+}
+
+// ConstantDriftRow строка матрицы: значения константы по базам в порядке ConstantDriftReport.Databases
+type ConstantDriftRow struct {
+	Name      string              `json:"name"`
+	Synonym   string              `json:"synonym"`
+	Values    []ConstantDriftCell `json:"values"`
+	Variants  int                 `json:"variants"`
+	Drift     bool                `json:"drift"`   // Значения различаются между базами
+	Missing   bool                `json:"missing"` // Константы нет в части баз
+	Sensitive []string            `json:"sensitive,omitempty"`
+}
+
+// ConstantDriftReport матрица расхождений констант между базами проекта
+type ConstantDriftReport struct {
+	Databases        []ConstantDriftDatabase `json:"databases"`
+	Constants        []ConstantDriftRow      `json:"constants"`
+	Total            int                     `json:"total"`
+	Drifted          int                     `json:"drifted"`
+	Missing          int                     `json:"missing"`
+	Sensitive        int                     `json:"sensitive"`
+	SensitiveDrifted int                     `json:"sensitive_drifted"`
+}
+
+// latestCompletedUpload возвращает последнюю завершенную выгрузку базы данных, в том числе
+// с расхождениями сверки полноты, или nil
+func (db *DB) latestCompletedUpload(databaseID int) (*ConstantUploadRef, error) {
+	ref := &ConstantUploadRef{}
+	err := db.conn.QueryRow(`
+		SELECT id, upload_uuid, started_at FROM uploads
+		WHERE database_id = ? AND status IN (?, ?)
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1
+	`, databaseID, UploadStatusCompleted, UploadStatusCompletedWithWarnings).Scan(&ref.ID, &ref.UploadUUID, &ref.StartedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest upload of database %d: %w", databaseID, err)
+	}
+	return ref, nil
+}
+
+// GetConstantDrift сравнивает константы последних завершенных выгрузок баз: одноименные константы
+// с разными значениями отмечаются расхождением. Ошибка чтения базы записывается в ее колонку
+// и не прерывает отчет. Значения константы с признаком ConstantSensitiveSecret маскируются
+func (db *DB) GetConstantDrift(databases []ConstantDriftDatabase) *ConstantDriftReport {
+	report := &ConstantDriftReport{Databases: databases, Constants: []ConstantDriftRow{}}
+	if report.Databases == nil {
+		report.Databases = []ConstantDriftDatabase{}
+	}
+
+	byDatabase := make([]map[string]*Constant, len(databases))
+	names := map[string]bool{}
+	for i := range report.Databases {
+		column := &report.Databases[i]
+		upload, err := db.latestCompletedUpload(column.DatabaseID)
+		if err != nil {
+			column.Error = err.Error()
+			continue
+		}
+		if upload == nil {
+			continue
+		}
+		column.Upload = upload
+		constants, err := db.constantsByName(upload.ID)
+		if err != nil {
+			column.Error = err.Error()
+			continue
+		}
+		byDatabase[i] = constants
+		for name := range constants {
+			names[name] = true
+		}
+	}
+
+	for name := range names {
+		report.Constants = append(report.Constants, buildConstantDriftRow(name, byDatabase))
+	}
+	sort.Slice(report.Constants, func(i, j int) bool {
+		return report.Constants[i].Name < report.Constants[j].Name
+	})
+
+	report.Total = len(report.Constants)
+	for _, row := range report.Constants {
+		if row.Drift {
+			report.Drifted++
+		}
+		if row.Missing {
+			report.Missing++
+		}
+		if len(row.Sensitive) > 0 {
+			report.Sensitive++
+			if row.Drift {
+				report.SensitiveDrifted++
+			}
+		}
+	}
+	return report
+}
+
+// buildConstantDriftRow строит строку матрицы; базы без выгрузки (nil) не считаются отсутствием константы
+func buildConstantDriftRow(name string, byDatabase []map[string]*Constant) ConstantDriftRow {
+	row := ConstantDriftRow{Name: name, Values: make([]ConstantDriftCell, len(byDatabase))}
+	counts := map[string]int{}
+	firstSeen := map[string]int{}
+	comparable := make([]string, len(byDatabase))
+	reasons := map[string]bool{}
+
+	for i, constants := range byDatabase {
+		row.Values[i].Variant = -1
+		if constants == nil {
+			continue
+		}
+		constant, ok := constants[name]
+		if !ok {
+			row.Missing = true
+			continue
+		}
+		if row.Synonym == "" {
+			row.Synonym = constant.Synonym
+		}
+		row.Values[i] = ConstantDriftCell{Present: true, Value: constant.TypedValue, ValueKind: constant.ValueKind}
+		key := constantComparable(constant.ValueKind, constant.TypedValue, constant.Value)
+		comparable[i] = key
+		if _, ok := firstSeen[key]; !ok {
+			firstSeen[key] = i
+		}
+		counts[key]++
+
+		value := constant.Value
+		if constant.ValueKind != "" {
+			value = FormatConstantTypedValue(constant.TypedValue)
+		}
+		for _, reason := range ConstantSensitivity(name, constant.Synonym, value) {
+			reasons[reason] = true
+		}
+	}
+
+	// Варианты по убыванию частоты, при равенстве - по первой базе со значением
+	variants := make([]string, 0, len(counts))
+	for key := range counts {
+		variants = append(variants, key)
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		if counts[variants[i]] != counts[variants[j]] {
+			return counts[variants[i]] > counts[variants[j]]
+		}
+		return firstSeen[variants[i]] < firstSeen[variants[j]]
+	})
+	index := make(map[string]int, len(variants))
+	for i, key := range variants {
+		index[key] = i
+	}
+	for i := range row.Values {
+		if row.Values[i].Present {
+			row.Values[i].Variant = index[comparable[i]]
+		}
+	}
+	row.Variants = len(variants)
+	row.Drift = row.Variants > 1
+
+	for _, reason := range []string{ConstantSensitivePath, ConstantSensitiveServer, ConstantSensitiveSecret} {
+		if reasons[reason] {
+			row.Sensitive = append(row.Sensitive, reason)
+		}
+	}
+	if reasons[ConstantSensitiveSecret] {
+		for i := range row.Values {
+			if row.Values[i].Present {
+				row.Values[i].Value = RedactionMaskValue
+			}
+		}
+	}
+	return row
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestConstantSensitivity(t *testing.T) {
+	tests := []struct {
+		name     string
+		constant string
+		synonym  string
+		value    string
+		want     []string
+	}{
+		{"path by name", "ПутьКФайлуБазы", "", "", []string{ConstantSensitivePath}},
+		{"windows path value", "Обмен", "Обмен", `D:\Обмен\in`, []string{ConstantSensitivePath}},
+		{"unc path value", "Обмен", "", `\\srv01\share\in`, []string{ConstantSensitivePath}},
+		{"server by synonym", "Адрес1", "Адрес сервера", "", []string{ConstantSensitiveServer}},
+		{"url value", "Сайт", "", "https://example.org/api", []string{ConstantSensitiveServer}},
+		{"connection string", "Подключение", "", `Srvr="app01";Ref="ut"`, []string{ConstantSensitiveServer}},
+		{"ip value", "Узел", "", "10.0.0.15:1541", []string{ConstantSensitiveServer}},
+		{"password", "ПарольSMTP", "", "secret", []string{ConstantSensitiveSecret}},
+		{"regular constant", "ОсновнаяВалюта", "Основная валюта", "RUB", nil},
+		{"number is not ip", "СтавкаНДС", "", "20", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConstantSensitivity(tt.constant, tt.synonym, tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConstantSensitivity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetConstantDrift(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	first, second, third, empty := 1, 2, 3, 4
+	uploads := []struct {
+		databaseID *int
+		constants  map[string]string
+		status     string
+	}{
+		// Более ранняя выгрузка первой базы не участвует в сравнении
+		{&first, map[string]string{"ОсновнаяВалюта": "EUR"}, UploadStatusCompleted},
+		{&first, map[string]string{"ОсновнаяВалюта": "RUB", "ПутьКОбмену": `D:\exchange`, "ПарольSMTP": "a"}, UploadStatusCompleted},
+		{&second, map[string]string{"ОсновнаяВалюта": "RUB", "ПутьКОбмену": `E:\exchange`, "ПарольSMTP": "b"}, UploadStatusCompleted},
+		// Выгрузка с расхождениями сверки полноты тоже считается завершенной
+		{&third, map[string]string{"ОсновнаяВалюта": "<string>RUB</string>", "ПутьКОбмену": `D:\exchange`}, UploadStatusCompletedWithWarnings},
+		// Незавершенная выгрузка не учитывается
+		{&empty, map[string]string{"ОсновнаяВалюта": "USD"}, ""},
+	}
+	for i, u := range uploads {
+		upload, err := db.CreateUploadWithDatabase(fmt.Sprintf("upload-%d", i), "8.3", "УТ", u.databaseID, "", "", "", 1, "", "", "", nil)
+		if err != nil {
+			t.Fatalf("CreateUploadWithDatabase() error = %v", err)
+		}
+		for name, value := range u.constants {
+			if err := db.AddConstant(upload.ID, name, name, "Строка", value); err != nil {
+				t.Fatalf("AddConstant() error = %v", err)
+			}
+		}
+		switch u.status {
+		case UploadStatusCompleted:
+			db.CompleteUpload(upload.ID)
+		case UploadStatusCompletedWithWarnings:
+			db.CompleteUploadWithWarnings(upload.ID)
+		}
+	}
+
+	report := db.GetConstantDrift([]ConstantDriftDatabase{
+		{DatabaseID: first, Database: "Москва"},
+		{DatabaseID: second, Database: "Казань"},
+		{DatabaseID: third, Database: "Самара"},
+		{DatabaseID: empty, Database: "Новая"},
+	})
+	if report.Databases[0].Upload == nil || report.Databases[0].Upload.UploadUUID != "upload-1" || report.Databases[3].Upload != nil {
+		t.Fatalf("Databases = %+v, want latest completed uploads", report.Databases)
+	}
+	if report.Total != 3 || report.Drifted != 2 || report.Missing != 1 || report.Sensitive != 2 || report.SensitiveDrifted != 2 {
+		t.Errorf("summary = total %d drifted %d missing %d sensitive %d/%d, want 3 2 1 2/2",
+			report.Total, report.Drifted, report.Missing, report.Sensitive, report.SensitiveDrifted)
+	}
+
+	rows := map[string]ConstantDriftRow{}
+	for _, row := range report.Constants {
+		rows[row.Name] = row
+	}
+	tests := []struct {
+		name         string
+		constant     string
+		wantDrift    bool
+		wantMissing  bool
+		wantVariants []int
+		wantValues   []interface{}
+	}{
+		{"same value in different format", "ОсновнаяВалюта", false, false, []int{0, 0, 0, -1}, []interface{}{"RUB", "RUB", "RUB", nil}},
+		{"majority variant first", "ПутьКОбмену", true, false, []int{0, 1, 0, -1}, []interface{}{`D:\exchange`, `E:\exchange`, `D:\exchange`, nil}},
+		{"secret masked", "ПарольSMTP", true, true, []int{0, 1, -1, -1}, []interface{}{RedactionMaskValue, RedactionMaskValue, nil, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, ok := rows[tt.constant]
+			if !ok {
+				t.Fatalf("constant %s not in report", tt.constant)
+			}
+			if row.Drift != tt.wantDrift || row.Missing != tt.wantMissing {
+				t.Errorf("drift = %v, missing = %v, want %v, %v", row.Drift, row.Missing, tt.wantDrift, tt.wantMissing)
+			}
+			for i, cell := range row.Values {
+				if cell.Variant != tt.wantVariants[i] || cell.Value != tt.wantValues[i] {
+					t.Errorf("cell %d = variant %d value %v, want %d %v", i, cell.Variant, cell.Value, tt.wantVariants[i], tt.wantValues[i])
+				}
+			}
+		})
+	}
+}
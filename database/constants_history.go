@@ -0,0 +1,210 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Виды изменения константы между выгрузками
+const (
+	ConstantChangeAdded   = "added"
+	ConstantChangeRemoved = "removed"
+	ConstantChangeChanged = "changed"
+)
+
+// ConstantHistoryEntry значение константы в одной выгрузке базы данных
+type ConstantHistoryEntry struct {
+	UploadID     int         `json:"upload_id"`
+	UploadUUID   string      `json:"upload_uuid"`
+	UploadStatus string      `json:"upload_status"`
+	StartedAt    time.Time   `json:"started_at"`
+	Synonym      string      `json:"synonym"`
+	Type         string      `json:"type"`
+	Value        string      `json:"value"`
+	ValueKind    string      `json:"value_kind,omitempty"`
+	TypedValue   interface{} `json:"typed_value"`
+	// Changed значение отличается от предыдущей выгрузки, в которой была константа
+	Changed           bool        `json:"changed"`
+	PreviousValue     interface{} `json:"previous_value,omitempty"`
+	PreviousValueKind string      `json:"previous_value_kind,omitempty"`
+}
+
+// ConstantChange изменение константы между двумя выгрузками
+type ConstantChange struct {
+	Name              string      `json:"name"`
+	Synonym           string      `json:"synonym"`
+	Change            string      `json:"change"`
+	PreviousValue     interface{} `json:"previous_value,omitempty"`
+	PreviousValueKind string      `json:"previous_value_kind,omitempty"`
+	CurrentValue      interface{} `json:"current_value,omitempty"`
+	CurrentValueKind  string      `json:"current_value_kind,omitempty"`
+}
+
+// ConstantUploadRef выгрузка, участвующая в сравнении констант
+type ConstantUploadRef struct {
+	ID         int       `json:"id"`
+	UploadUUID string    `json:"upload_uuid"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// ConstantChangesReport изменения констант между двумя последними завершенными выгрузками базы
+type ConstantChangesReport struct {
+	DatabaseID int                `json:"database_id"`
+	Current    *ConstantUploadRef `json:"current,omitempty"`
+	Previous   *ConstantUploadRef `json:"previous,omitempty"`
+	Changes    []ConstantChange   `json:"changes"`
+	Unchanged  int                `json:"unchanged"`
+}
+
+// constantComparable значение константы для сравнения: типизированное значение, если оно есть,
+// иначе сырое XML, чтобы различия форматирования XML не считались изменением
+func constantComparable(kind string, typedValue interface{}, rawValue string) string {
+	if kind == "" {
+		return rawValue
+	}
+	return kind + ":" + FormatConstantTypedValue(typedValue)
+}
+
+// GetConstantHistory возвращает значения константы во всех выгрузках базы данных в порядке времени
+// с отметкой изменений относительно предыдущей выгрузки
+func (db *DB) GetConstantHistory(databaseID int, name string) ([]*ConstantHistoryEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT u.id, u.upload_uuid, u.status, u.started_at,
+		       COALESCE(c.synonym, ''), COALESCE(c.type, ''), COALESCE(c.value, ''), c.value_kind, c.typed_value
+		FROM constants c
+		JOIN uploads u ON u.id = c.upload_id
+		WHERE u.database_id = ? AND c.name = ?
+		ORDER BY u.started_at, u.id, c.id
+	`, databaseID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get constant history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []*ConstantHistoryEntry{}
+	var previous *ConstantHistoryEntry
+	for rows.Next() {
+		entry := &ConstantHistoryEntry{}
+		var valueKind sql.NullString
+		var typedValue interface{}
+		if err := rows.Scan(&entry.UploadID, &entry.UploadUUID, &entry.UploadStatus, &entry.StartedAt,
+			&entry.Synonym, &entry.Type, &entry.Value, &valueKind, &typedValue); err != nil {
+			return nil, fmt.Errorf("failed to scan constant history: %w", err)
+		}
+		entry.ValueKind = valueKind.String
+		entry.TypedValue = decodeConstantTypedValue(entry.ValueKind, typedValue)
+
+		// Константа повторно в той же выгрузке (повтор пакета) - берем последнее значение
+		if previous != nil && previous.UploadID == entry.UploadID {
+			history = history[:len(history)-1]
+			if len(history) > 0 {
+				previous = history[len(history)-1]
+			} else {
+				previous = nil
+			}
+		}
+		if previous != nil && constantComparable(previous.ValueKind, previous.TypedValue, previous.Value) !=
+			constantComparable(entry.ValueKind, entry.TypedValue, entry.Value) {
+			entry.Changed = true
+			entry.PreviousValue = previous.TypedValue
+			entry.PreviousValueKind = previous.ValueKind
+		}
+		history = append(history, entry)
+		previous = entry
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate constant history: %w", err)
+	}
+	return history, nil
+}
+
+// GetChangedConstants сравнивает константы двух последних завершенных выгрузок базы данных, в том числе
+// завершенных с расхождениями сверки полноты. Если завершенных выгрузок меньше двух, возвращает отчет без изменений
+func (db *DB) GetChangedConstants(databaseID int) (*ConstantChangesReport, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, upload_uuid, started_at FROM uploads
+		WHERE database_id = ? AND status IN (?, ?)
+		ORDER BY started_at DESC, id DESC
+		LIMIT 2
+	`, databaseID, UploadStatusCompleted, UploadStatusCompletedWithWarnings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest uploads: %w", err)
+	}
+	var uploads []*ConstantUploadRef
+	for rows.Next() {
+		ref := &ConstantUploadRef{}
+		if err := rows.Scan(&ref.ID, &ref.UploadUUID, &ref.StartedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan upload: %w", err)
+		}
+		uploads = append(uploads, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate uploads: %w", err)
+	}
+
+	report := &ConstantChangesReport{DatabaseID: databaseID, Changes: []ConstantChange{}}
+	if len(uploads) > 0 {
+		report.Current = uploads[0]
+	}
+	if len(uploads) < 2 {
+		return report, nil
+	}
+	report.Previous = uploads[1]
+
+	current, err := db.constantsByName(report.Current.ID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := db.constantsByName(report.Previous.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, constant := range current {
+		old, ok := previous[name]
+		switch {
+		case !ok:
+			report.Changes = append(report.Changes, ConstantChange{
+				Name: name, Synonym: constant.Synonym, Change: ConstantChangeAdded,
+				CurrentValue: constant.TypedValue, CurrentValueKind: constant.ValueKind,
+			})
+		case constantComparable(old.ValueKind, old.TypedValue, old.Value) != constantComparable(constant.ValueKind, constant.TypedValue, constant.Value):
+			report.Changes = append(report.Changes, ConstantChange{
+				Name: name, Synonym: constant.Synonym, Change: ConstantChangeChanged,
+				PreviousValue: old.TypedValue, PreviousValueKind: old.ValueKind,
+				CurrentValue: constant.TypedValue, CurrentValueKind: constant.ValueKind,
+			})
+		default:
+			report.Unchanged++
+		}
+	}
+	for name, old := range previous {
+		if _, ok := current[name]; !ok {
+			report.Changes = append(report.Changes, ConstantChange{
+				Name: name, Synonym: old.Synonym, Change: ConstantChangeRemoved,
+				PreviousValue: old.TypedValue, PreviousValueKind: old.ValueKind,
+			})
+		}
+	}
+	sort.Slice(report.Changes, func(i, j int) bool {
+		return report.Changes[i].Name < report.Changes[j].Name
+	})
+	return report, nil
+}
+
+// constantsByName возвращает константы выгрузки по имени; при повторе берется последнее значение
+func (db *DB) constantsByName(uploadID int) (map[string]*Constant, error) {
+	constants, err := db.GetConstantsByUpload(uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get constants of upload %d: %w", uploadID, err)
+	}
+	byName := make(map[string]*Constant, len(constants))
+	for _, constant := range constants {
+		byName[constant.Name] = constant
+	}
+	return byName, nil
+}
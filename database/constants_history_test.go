@@ -0,0 +1,101 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestConstantHistory(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	databaseID, otherDatabaseID := 7, 8
+	uploads := []struct {
+		databaseID *int
+		constants  map[string]string
+	}{
+		{&databaseID, map[string]string{"ОсновнаяВалюта": "<string>RUB</string>", "СтавкаНДС": "<number>20</number>"}},
+		{&otherDatabaseID, map[string]string{"ОсновнаяВалюта": "EUR"}},
+		// Другое представление того же значения не считается изменением
+		{&databaseID, map[string]string{"ОсновнаяВалюта": "RUB", "СтавкаНДС": "<number>20,0</number>"}},
+		{&databaseID, map[string]string{"ОсновнаяВалюта": "USD", "НоваяКонстанта": "<boolean>true</boolean>"}},
+	}
+	for i, u := range uploads {
+		upload, err := db.CreateUploadWithDatabase(fmt.Sprintf("upload-%d", i), "8.3", "УТ", u.databaseID, "", "", "", 1, "", "", "", nil)
+		if err != nil {
+			t.Fatalf("CreateUploadWithDatabase() error = %v", err)
+		}
+		for name, value := range u.constants {
+			constType := "Строка"
+			if name == "СтавкаНДС" {
+				constType = "Число"
+			}
+			if err := db.AddConstant(upload.ID, name, name, constType, value); err != nil {
+				t.Fatalf("AddConstant() error = %v", err)
+			}
+		}
+		// Выгрузка с расхождениями сверки полноты тоже считается завершенной
+		if i == len(uploads)-1 {
+			db.CompleteUploadWithWarnings(upload.ID)
+		} else {
+			db.CompleteUpload(upload.ID)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		constant    string
+		wantUploads []string
+		wantChanged []bool
+	}{
+		{"changed value", "ОсновнаяВалюта", []string{"upload-0", "upload-2", "upload-3"}, []bool{false, false, true}},
+		{"same number in different format", "СтавкаНДС", []string{"upload-0", "upload-2"}, []bool{false, false}},
+		{"unknown constant", "Нет", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := db.GetConstantHistory(databaseID, tt.constant)
+			if err != nil {
+				t.Fatalf("GetConstantHistory() error = %v", err)
+			}
+			if len(history) != len(tt.wantUploads) {
+				t.Fatalf("GetConstantHistory() = %d entries, want %d", len(history), len(tt.wantUploads))
+			}
+			for i, entry := range history {
+				if entry.UploadUUID != tt.wantUploads[i] || entry.Changed != tt.wantChanged[i] {
+					t.Errorf("entry %d = %s changed %v, want %s changed %v", i, entry.UploadUUID, entry.Changed, tt.wantUploads[i], tt.wantChanged[i])
+				}
+			}
+		})
+	}
+
+	history, _ := db.GetConstantHistory(databaseID, "ОсновнаяВалюта")
+	if last := history[len(history)-1]; last.PreviousValue != "RUB" || last.TypedValue != "USD" {
+		t.Errorf("last entry previous = %v, value = %v, want RUB -> USD", last.PreviousValue, last.TypedValue)
+	}
+
+	report, err := db.GetChangedConstants(databaseID)
+	if err != nil {
+		t.Fatalf("GetChangedConstants() error = %v", err)
+	}
+	if report.Current.UploadUUID != "upload-3" || report.Previous.UploadUUID != "upload-2" || report.Unchanged != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	var changes []string
+	for _, change := range report.Changes {
+		changes = append(changes, change.Name+":"+change.Change)
+	}
+	data, _ := json.Marshal(changes)
+	if want := `["НоваяКонстанта:added","ОсновнаяВалюта:changed","СтавкаНДС:removed"]`; string(data) != want {
+		t.Errorf("changes = %s, want %s", data, want)
+	}
+
+	single, err := db.GetChangedConstants(otherDatabaseID)
+	if err != nil || single.Previous != nil || len(single.Changes) != 0 {
+		t.Errorf("GetChangedConstants(single upload) = %+v, %v", single, err)
+	}
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CrashReport отчет о панике в фоновом воркере
+type CrashReport struct {
+	ID        int                    `json:"id"`
+	Component string                 `json:"component"`
+	JobID     *int                   `json:"job_id,omitempty"`
+	JobKind   string                 `json:"job_kind,omitempty"`
+	JobName   string                 `json:"job_name,omitempty"`
+	Panic     string                 `json:"panic"`
+	Stack     string                 `json:"stack"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Restarted bool                   `json:"restarted"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// CreateCrashReportsTable создает таблицу отчетов о сбоях воркеров
+func CreateCrashReportsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS crash_reports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			component TEXT NOT NULL,
+			job_id INTEGER,
+			job_kind TEXT NOT NULL DEFAULT '',
+			job_name TEXT NOT NULL DEFAULT '',
+			panic TEXT NOT NULL,
+			stack TEXT NOT NULL DEFAULT '',
+			details TEXT NOT NULL DEFAULT '{}',
+			restarted INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_crash_reports_component ON crash_reports(component, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create crash_reports table: %w", err)
+	}
+	return nil
+}
+
+// RecordCrashReport сохраняет отчет о сбое и заполняет его ID
+func (db *ServiceDB) RecordCrashReport(report *CrashReport) error {
+	if report.Component == "" {
+		return fmt.Errorf("crash component is required")
+	}
+	details := "{}"
+	if len(report.Details) > 0 {
+		data, err := json.Marshal(report.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal crash details: %w", err)
+		}
+		details = string(data)
+	}
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now()
+	}
+
+	result, err := db.conn.Exec(`
+		INSERT INTO crash_reports (component, job_id, job_kind, job_name, panic, stack, details, restarted, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, report.Component, report.JobID, report.JobKind, report.JobName, report.Panic, report.Stack, details, report.Restarted, report.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record crash report: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get crash report id: %w", err)
+	}
+	report.ID = int(id)
+	return nil
+}
+
+// GetCrashReports возвращает последние отчеты о сбоях, component пустой - все компоненты
+func (db *ServiceDB) GetCrashReports(component string, limit int) ([]*CrashReport, error) {
+	query := `SELECT id, component, job_id, job_kind, job_name, panic, stack, details, restarted, created_at FROM crash_reports`
+	var args []interface{}
+	if component != "" {
+		query += " WHERE component = ?"
+		args = append(args, component)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crash reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []*CrashReport{}
+	for rows.Next() {
+		report := &CrashReport{}
+		var jobID sql.NullInt64
+		var details string
+		if err := rows.Scan(&report.ID, &report.Component, &jobID, &report.JobKind, &report.JobName,
+			&report.Panic, &report.Stack, &details, &report.Restarted, &report.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan crash report: %w", err)
+		}
+		if jobID.Valid {
+			id := int(jobID.Int64)
+			report.JobID = &id
+		}
+		if details != "" && details != "{}" {
+			if err := json.Unmarshal([]byte(details), &report.Details); err != nil {
+				return nil, fmt.Errorf("failed to parse crash details: %w", err)
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
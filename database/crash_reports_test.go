@@ -0,0 +1,56 @@
+package database
+
+import "testing"
+
+func TestCrashReports(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	jobID := 5
+	reports := []*CrashReport{
+		{Component: "kpved_worker", Panic: "index out of range", Stack: "goroutine 1", Details: map[string]interface{}{"worker_id": 2.0}, Restarted: true},
+		{Component: "normalization", JobID: &jobID, JobKind: "normalization", Panic: "nil map"},
+	}
+	for _, report := range reports {
+		if err := db.RecordCrashReport(report); err != nil {
+			t.Fatalf("RecordCrashReport() error = %v", err)
+		}
+	}
+	if err := db.RecordCrashReport(&CrashReport{Panic: "boom"}); err == nil {
+		t.Error("RecordCrashReport() must reject empty component")
+	}
+
+	tests := []struct {
+		component string
+		want      int
+	}{
+		{"", 2},
+		{"kpved_worker", 1},
+		{"export", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.component, func(t *testing.T) {
+			got, err := db.GetCrashReports(tt.component, 10)
+			if err != nil {
+				t.Fatalf("GetCrashReports() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("GetCrashReports(%q) = %d reports, want %d", tt.component, len(got), tt.want)
+			}
+		})
+	}
+
+	got, err := db.GetCrashReports("", 10)
+	if err != nil {
+		t.Fatalf("GetCrashReports() error = %v", err)
+	}
+	if got[0].JobID == nil || *got[0].JobID != jobID {
+		t.Errorf("JobID = %v, want %d", got[0].JobID, jobID)
+	}
+	if !got[1].Restarted || got[1].Details["worker_id"] != 2.0 {
+		t.Errorf("report = %+v, want restarted with worker details", got[1])
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// DatabaseFileStats сведения о файле БД для списка баз: активность, объем данных, покрытие
+// нормализацией и классификацией и признаки состояния. Показатели таблиц, которых нет в файле, не заполняются
+type DatabaseFileStats struct {
+	SchemaVersion          int        `json:"schema_version"` // PRAGMA schema_version: растет с каждым изменением схемы
+	UserVersion            int        `json:"user_version"`   // PRAGMA user_version
+	Locked                 bool       `json:"locked"`         // Файл заблокирован другим процессом на запись
+	Uploads                *int64     `json:"uploads,omitempty"`
+	LastUploadAt           *time.Time `json:"last_upload_at,omitempty"`
+	CatalogItems           *int64     `json:"catalog_items,omitempty"`
+	NomenclatureItems      *int64     `json:"nomenclature_items,omitempty"`
+	NormalizedItems        *int64     `json:"normalized_items,omitempty"`
+	ClassifiedItems        *int64     `json:"classified_items,omitempty"` // Нормализованные записи с кодом КПВЭД
+	NormalizationCoverage  *float64   `json:"normalization_coverage,omitempty"`
+	ClassificationCoverage *float64   `json:"classification_coverage,omitempty"`
+}
+
+// InspectDatabaseFile собирает сведения о файле БД через соединение только для чтения.
+// Блокировка файла другим процессом не считается ошибкой: возвращается Locked без показателей
+func InspectDatabaseFile(dbPath string) (*DatabaseFileStats, error) {
+	conn, err := sql.Open("sqlite3", readOnlyDSN(dbPath)+"&_busy_timeout=100")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	stats := &DatabaseFileStats{}
+	if err := conn.QueryRow(`PRAGMA schema_version`).Scan(&stats.SchemaVersion); err != nil {
+		if isDatabaseLockedError(err) {
+			stats.Locked = true
+			return stats, nil
+		}
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if err := conn.QueryRow(`PRAGMA user_version`).Scan(&stats.UserVersion); err != nil {
+		return nil, fmt.Errorf("failed to read user version: %w", err)
+	}
+
+	tables := make(map[string]bool)
+	rows, err := conn.Query(`SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tables: %w", err)
+	}
+
+	count := func(table, query string) (*int64, error) {
+		if !tables[table] {
+			return nil, nil
+		}
+		var n int64
+		if err := conn.QueryRow(query).Scan(&n); err != nil {
+			if isDatabaseLockedError(err) {
+				stats.Locked = true
+			}
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		return &n, nil
+	}
+
+	if stats.Uploads, err = count("uploads", `SELECT COUNT(*) FROM uploads`); err != nil {
+		return stats, err
+	}
+	if stats.Uploads != nil && *stats.Uploads > 0 {
+		var lastUpload sql.NullTime
+		if err := conn.QueryRow(`SELECT started_at FROM uploads ORDER BY started_at DESC LIMIT 1`).Scan(&lastUpload); err == nil && lastUpload.Valid {
+			stats.LastUploadAt = &lastUpload.Time
+		}
+	}
+	if stats.CatalogItems, err = count("catalog_items", `SELECT COUNT(*) FROM catalog_items`); err != nil {
+		return stats, err
+	}
+	if stats.NomenclatureItems, err = count("nomenclature_items", `SELECT COUNT(*) FROM nomenclature_items`); err != nil {
+		return stats, err
+	}
+	if stats.NormalizedItems, err = count("normalized_data", `SELECT COUNT(*) FROM normalized_data`); err != nil {
+		return stats, err
+	}
+	if stats.NormalizedItems == nil {
+		return stats, nil
+	}
+
+	// Покрытие нормализацией: доля исходных записей, попавших в normalized_data
+	var sourceItems int64
+	for _, n := range []*int64{stats.CatalogItems, stats.NomenclatureItems} {
+		if n != nil {
+			sourceItems += *n
+		}
+	}
+	if sourceItems > 0 {
+		var normalizedSources int64
+		if err := conn.QueryRow(`SELECT COUNT(DISTINCT source_reference) FROM normalized_data`).Scan(&normalizedSources); err != nil {
+			return stats, fmt.Errorf("failed to count normalized sources: %w", err)
+		}
+		coverage := coverageRatio(normalizedSources, sourceItems)
+		stats.NormalizationCoverage = &coverage
+	}
+
+	// Покрытие классификацией; в БД до миграции полей КПВЭД не рассчитывается
+	var classified int64
+	if err := conn.QueryRow(`
+		SELECT COUNT(*) FROM normalized_data WHERE kpved_code IS NOT NULL AND TRIM(kpved_code) != ''
+	`).Scan(&classified); err == nil {
+		stats.ClassifiedItems = &classified
+		if *stats.NormalizedItems > 0 {
+			coverage := coverageRatio(classified, *stats.NormalizedItems)
+			stats.ClassificationCoverage = &coverage
+		}
+	}
+	return stats, nil
+}
+
+// coverageRatio доля part от total, не больше единицы, округленная до 0.001
+func coverageRatio(part, total int64) float64 {
+	ratio := float64(part) / float64(total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	return math.Round(ratio*1000) / 1000
+}
+
+// isDatabaseLockedError проверяет, что запрос не выполнен из-за блокировки файла другим соединением
+func isDatabaseLockedError(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "database is locked") || strings.Contains(message, "database is busy")
+}
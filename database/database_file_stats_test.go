@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspectDatabaseFile(t *testing.T) {
+	dir := t.TempDir()
+
+	uploadsPath := filepath.Join(dir, "uploads.db")
+	db, err := NewDB(uploadsPath)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	upload, err := db.CreateUpload("uuid-1", "8.3", "Бухгалтерия")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "Номенклатура")
+	if err != nil {
+		t.Fatalf("Failed to add catalog: %v", err)
+	}
+	for _, ref := range []string{"ref-1", "ref-2", "ref-3", "ref-4"} {
+		if err := db.AddCatalogItem(catalog.ID, ref, ref, "Товар "+ref, nil, nil); err != nil {
+			t.Fatalf("Failed to add catalog item: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code)
+		VALUES ('ref-1', 'Товар ref-1', 'ref-1', 'товар', 'прочее', '01.11'), ('ref-2', 'Товар ref-2', 'ref-2', 'товар', 'прочее', '')`); err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+	db.Close()
+
+	// Файл без таблиц выгрузок, заблокированный другим соединением
+	lockedPath := filepath.Join(dir, "locked.db")
+	locker, err := sql.Open("sqlite3", lockedPath+"?_journal_mode=DELETE&_txlock=exclusive")
+	if err != nil {
+		t.Fatalf("Failed to open locked DB: %v", err)
+	}
+	defer locker.Close()
+	locker.SetMaxOpenConns(1)
+	if _, err := locker.Exec(`CREATE TABLE items (id INTEGER)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	t.Run("uploads database", func(t *testing.T) {
+		stats, err := InspectDatabaseFile(uploadsPath)
+		if err != nil {
+			t.Fatalf("InspectDatabaseFile() error = %v", err)
+		}
+		if stats.Locked || stats.SchemaVersion == 0 {
+			t.Errorf("health = locked %v, schema version %d", stats.Locked, stats.SchemaVersion)
+		}
+		if stats.Uploads == nil || *stats.Uploads != 1 || stats.LastUploadAt == nil {
+			t.Errorf("uploads = %v, last upload %v, want 1 upload", stats.Uploads, stats.LastUploadAt)
+		}
+		if stats.CatalogItems == nil || *stats.CatalogItems != 4 || stats.NormalizedItems == nil || *stats.NormalizedItems != 2 {
+			t.Errorf("items = %v catalog, %v normalized, want 4 and 2", stats.CatalogItems, stats.NormalizedItems)
+		}
+		if stats.NormalizationCoverage == nil || *stats.NormalizationCoverage != 0.5 {
+			t.Errorf("normalization coverage = %v, want 0.5", stats.NormalizationCoverage)
+		}
+		if stats.ClassificationCoverage == nil || *stats.ClassificationCoverage != 0.5 {
+			t.Errorf("classification coverage = %v, want 0.5", stats.ClassificationCoverage)
+		}
+	})
+
+	t.Run("unlocked database without upload tables", func(t *testing.T) {
+		stats, err := InspectDatabaseFile(lockedPath)
+		if err != nil {
+			t.Fatalf("InspectDatabaseFile() error = %v", err)
+		}
+		if stats.Locked || stats.Uploads != nil || stats.NormalizedItems != nil {
+			t.Errorf("InspectDatabaseFile() = %+v, want no upload stats", stats)
+		}
+	})
+
+	t.Run("locked database", func(t *testing.T) {
+		// BEGIN EXCLUSIVE в режиме журнала отката блокирует чтение файла другими соединениями
+		tx, err := locker.Begin()
+		if err != nil {
+			t.Fatalf("Failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec(`INSERT INTO items (id) VALUES (1)`); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		stats, err := InspectDatabaseFile(lockedPath)
+		if err != nil {
+			t.Fatalf("InspectDatabaseFile() error = %v", err)
+		}
+		if !stats.Locked {
+			t.Errorf("InspectDatabaseFile() = %+v, want locked", stats)
+		}
+	})
+}
@@ -1,19 +1,26 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
+	"httpserver/apperrors"
+	"httpserver/tracing"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // DB обертка для работы с базой данных
 type DB struct {
-	conn *sql.DB
+	conn     *sql.DB
+	readOnly *DB     // Представление через пул только для чтения (nil - не настроен)
+	primary  *sql.DB // Основное соединение для представления только для чтения (ключ кешей)
 }
 
 // Upload представляет выгрузку из 1С
@@ -44,12 +51,16 @@ type Upload struct {
 
 // Constant представляет константу из 1С
 type Constant struct {
-	ID        int       `json:"id"`
-	UploadID  int       `json:"upload_id"`
-	Name      string    `json:"name"`
-	Synonym   string    `json:"synonym"`
-	Type      string    `json:"type"`
-	Value     string    `json:"value"`
+	ID       int    `json:"id"`
+	UploadID int    `json:"upload_id"`
+	Name     string `json:"name"`
+	Synonym  string `json:"synonym"`
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	// Типизированное значение, разобранное при загрузке по объявленному типу 1С
+	ValueKind  string      `json:"value_kind,omitempty"`
+	TypedValue interface{} `json:"typed_value"`
+
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -120,6 +131,12 @@ func NewDBWithConfig(dbPath string, config DBConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	// Пул только для чтения открываем после инициализации схемы
+	if err := db.attachReadOnlyPool(dbPath, config); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -130,8 +147,23 @@ func CreateNewDatabaseWithSchema(dbPath string, config DBConfig) (*DB, error) {
 	return NewDBWithConfig(dbPath, config)
 }
 
+// attachReadOnlyPool подключает пул только для чтения, если он включен в конфигурации
+func (db *DB) attachReadOnlyPool(dbPath string, config DBConfig) error {
+	readConn, err := openReadOnlyPool(dbPath, config)
+	if err != nil {
+		return err
+	}
+	if readConn != nil {
+		db.readOnly = &DB{conn: readConn, primary: db.conn}
+	}
+	return nil
+}
+
 // Close закрывает подключение к базе данных
 func (db *DB) Close() error {
+	if db.readOnly != nil {
+		db.readOnly.conn.Close()
+	}
 	return db.conn.Close()
 }
 
@@ -231,6 +263,10 @@ func (db *DB) GetUploadByID(id int) (*Upload, error) {
 
 // GetUploadByUUID получает выгрузку по UUID
 func (db *DB) GetUploadByUUID(uuid string) (*Upload, error) {
+	if upload, ok := db.getCachedUpload(uuid); ok {
+		return upload, nil
+	}
+
 	query := `
 		SELECT id, upload_uuid, started_at, completed_at, status, 
 		       version_1c, config_name, total_constants, total_catalogs, total_items,
@@ -254,6 +290,9 @@ func (db *DB) GetUploadByUUID(uuid string) (*Upload, error) {
 		&upload.IterationNumber, &upload.IterationLabel, &upload.ProgrammerName, &upload.UploadPurpose, &parentUploadID,
 	)
 	
+	if err == sql.ErrNoRows {
+		return nil, &apperrors.Error{Kind: apperrors.KindNotFound, Code: "upload_not_found", Message: fmt.Sprintf("upload %s not found", uuid), Err: err}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get upload by UUID: %w", err)
 	}
@@ -278,6 +317,8 @@ func (db *DB) GetUploadByUUID(uuid string) (*Upload, error) {
 		upload.CompletedAt = &completedAt.Time
 	}
 	
+	db.cacheUpload(upload)
+
 	return upload, nil
 }
 
@@ -285,15 +326,17 @@ func (db *DB) GetUploadByUUID(uuid string) (*Upload, error) {
 func (db *DB) CompleteUpload(uploadID int) error {
 	query := `
 		UPDATE uploads 
-		SET completed_at = CURRENT_TIMESTAMP, status = 'completed'
+		SET completed_at = CURRENT_TIMESTAMP, status = ?
 		WHERE id = ?
 	`
 	
-	_, err := db.conn.Exec(query, uploadID)
+	_, err := db.conn.Exec(query, UploadStatusCompleted, uploadID)
 	if err != nil {
 		return fmt.Errorf("failed to complete upload: %w", err)
 	}
 	
+	db.InvalidateUpload(uploadID)
+
 	return nil
 }
 
@@ -307,11 +350,12 @@ func (db *DB) AddConstant(uploadID int, name, synonym, constType, value string)
 	defer tx.Rollback()
 	
 	query := `
-		INSERT INTO constants (upload_id, name, synonym, type, value)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO constants (upload_id, name, synonym, type, value, value_kind, typed_value)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 	
-	_, err = tx.Exec(query, uploadID, name, synonym, constType, value)
+	valueKind, typedValue := ParseConstantValue(constType, value)
+	_, err = tx.Exec(query, uploadID, name, synonym, constType, value, valueKind, typedValue)
 	if err != nil {
 		return fmt.Errorf("failed to add constant: %w", err)
 	}
@@ -326,6 +370,7 @@ func (db *DB) AddConstant(uploadID int, name, synonym, constType, value string)
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	db.adjustCachedUploadCounters(uploadID, 1, 0, 0)
 	
 	return nil
 }
@@ -364,6 +409,7 @@ func (db *DB) AddCatalog(uploadID int, name, synonym string) (*Catalog, error) {
 	if err = tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	db.adjustCachedUploadCounters(uploadID, 0, 1, 0)
 	
 	return &Catalog{
 		ID:       int(id),
@@ -488,6 +534,7 @@ func (db *DB) AddCatalogItem(catalogID int, reference, code, name string, attrib
 		log.Printf("[DEBUG DB] ✗ Ошибка коммита транзакции: %v", err)
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	db.adjustCachedUploadCounters(uploadID, 0, 0, 1)
 	
 	log.Printf("[DEBUG DB] ✓ Транзакция успешно закоммичена")
 	log.Printf("[DEBUG DB] ✓ Элемент сохранен в БД: catalog_id=%d, code=%s, name=%s, attributes_xml длина=%d", catalogID, code, name, len(attrsXML))
@@ -566,6 +613,7 @@ func (db *DB) AddNomenclatureItem(uploadID int, nomenclatureRef, nomenclatureCod
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	db.adjustCachedUploadCounters(uploadID, 0, 0, 1)
 	
 	return nil
 }
@@ -611,6 +659,7 @@ func (db *DB) AddNomenclatureItemsBatch(uploadID int, items []NomenclatureItem)
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	db.adjustCachedUploadCounters(uploadID, 0, 0, len(items))
 	
 	return nil
 }
@@ -630,6 +679,65 @@ func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
 	return db.conn.Exec(query, args...)
 }
 
+// QueryRowContext выполняет запрос и возвращает одну строку; запрос прерывается при отмене ctx
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	row := db.conn.QueryRowContext(ctx, query, args...)
+	span.RecordError(row.Err())
+	return row
+}
+
+// QueryContext выполняет запрос и возвращает несколько строк; запрос прерывается при отмене ctx
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
+
+// ExecContext выполняет запрос без возврата строк; запрос прерывается при отмене ctx
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	result, err := db.conn.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+	return result, err
+}
+
+// startQuerySpan начинает спан запроса к БД, если запрос выполняется в рамках трассировки
+func startQuerySpan(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	statement := strings.Join(strings.Fields(query), " ")
+	name := statement
+	if i := strings.IndexByte(name, ' '); i > 0 {
+		name = name[:i]
+	}
+	return tracing.StartChild(ctx, "db "+strings.ToUpper(name), tracing.SpanKindClient,
+		tracing.Attr("db.system", "sqlite"),
+		tracing.Attr("db.statement", statement),
+	)
+}
+
+// GetInProgressUploadUUIDs возвращает UUID незавершенных выгрузок
+func (db *DB) GetInProgressUploadUUIDs() ([]string, error) {
+	rows, err := db.conn.Query(`SELECT upload_uuid FROM uploads WHERE status = 'in_progress' ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get in-progress uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var uuids []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, fmt.Errorf("failed to scan upload uuid: %w", err)
+		}
+		uuids = append(uuids, uuid)
+	}
+	return uuids, rows.Err()
+}
+
 // GetStats получает статистику по выгрузкам
 func (db *DB) GetStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -972,14 +1080,19 @@ func (db *DB) GetUploadDetails(uuid string) (*Upload, []*Catalog, []*Constant, e
 
 // GetConstantsByUpload получает все константы выгрузки
 func (db *DB) GetConstantsByUpload(uploadID int) ([]*Constant, error) {
+	return db.GetConstantsByUploadContext(context.Background(), uploadID)
+}
+
+// GetConstantsByUploadContext получает константы выгрузки; запрос прерывается при отмене ctx
+func (db *DB) GetConstantsByUploadContext(ctx context.Context, uploadID int) ([]*Constant, error) {
 	query := `
-		SELECT id, upload_id, name, synonym, type, value, created_at
+		SELECT `+constantColumns+`
 		FROM constants
 		WHERE upload_id = ?
 		ORDER BY id
 	`
 	
-	rows, err := db.conn.Query(query, uploadID)
+	rows, err := db.QueryContext(ctx, query, uploadID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get constants: %w", err)
 	}
@@ -987,11 +1100,7 @@ func (db *DB) GetConstantsByUpload(uploadID int) ([]*Constant, error) {
 	
 	var constants []*Constant
 	for rows.Next() {
-		constant := &Constant{}
-		err := rows.Scan(
-			&constant.ID, &constant.UploadID, &constant.Name, &constant.Synonym,
-			&constant.Type, &constant.Value, &constant.CreatedAt,
-		)
+		constant, err := scanConstant(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan constant: %w", err)
 		}
@@ -1041,6 +1150,16 @@ func (db *DB) GetCatalogsByUpload(uploadID int) ([]*Catalog, error) {
 
 // GetCatalogItemsByUpload получает элементы справочников выгрузки с фильтрацией и пагинацией
 func (db *DB) GetCatalogItemsByUpload(uploadID int, catalogNames []string, offset, limit int) ([]*CatalogItem, int, error) {
+	return db.GetCatalogItemsByUploadContext(context.Background(), uploadID, catalogNames, offset, limit)
+}
+
+// GetCatalogItemsByUploadContext получает элементы справочников выгрузки; запросы прерываются при отмене ctx
+func (db *DB) GetCatalogItemsByUploadContext(ctx context.Context, uploadID int, catalogNames []string, offset, limit int) ([]*CatalogItem, int, error) {
+	// В единой БД справочников элементы хранятся в динамических таблицах
+	if exists, err := TableExists(db.conn, "catalog_items"); err == nil && !exists {
+		return db.getCatalogItemsFromDynamicTables(ctx, uploadID, catalogNames, offset, limit)
+	}
+
 	// Строим запрос с фильтрацией
 	// Используем правильные имена колонок: attributes_xml и table_parts_xml
 	// Включаем все поля из БД, включая catalog_name
@@ -1077,7 +1196,7 @@ func (db *DB) GetCatalogItemsByUpload(uploadID int, catalogNames []string, offse
 	// Получаем общее количество для пагинации
 	var totalCount int
 	countQuery := "SELECT COUNT(*) FROM (" + query + ")"
-	err := db.conn.QueryRow(countQuery, args...).Scan(&totalCount)
+	err := db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
 	}
@@ -1088,7 +1207,7 @@ func (db *DB) GetCatalogItemsByUpload(uploadID int, catalogNames []string, offse
 		args = append(args, limit, offset)
 	}
 	
-	rows, err := db.conn.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get catalog items: %w", err)
 	}
@@ -1150,7 +1269,7 @@ func (db *DB) GetCatalogItemCountByCatalog(uploadID int) (map[int]int, error) {
 // GetConstantsByUploadWithPagination получает константы с пагинацией
 func (db *DB) GetConstantsByUploadWithPagination(uploadID int, limit, offset int) ([]*Constant, error) {
 	query := `
-		SELECT id, upload_id, name, synonym, type, value, created_at
+		SELECT `+constantColumns+`
 		FROM constants
 		WHERE upload_id = ?
 		ORDER BY id
@@ -1165,11 +1284,7 @@ func (db *DB) GetConstantsByUploadWithPagination(uploadID int, limit, offset int
 	
 	var constants []*Constant
 	for rows.Next() {
-		constant := &Constant{}
-		err := rows.Scan(
-			&constant.ID, &constant.UploadID, &constant.Name, &constant.Synonym,
-			&constant.Type, &constant.Value, &constant.CreatedAt,
-		)
+		constant, err := scanConstant(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan constant: %w", err)
 		}
@@ -1335,22 +1450,31 @@ func (db *DB) CleanOldCatalogItems() error {
 
 // NormalizedItem представляет нормализованную запись
 type NormalizedItem struct {
-	ID                  int       `json:"id"`
-	SourceReference     string    `json:"source_reference"`
-	SourceName          string    `json:"source_name"`
-	Code                string    `json:"code"`
-	NormalizedName      string    `json:"normalized_name"`
-	NormalizedReference string    `json:"normalized_reference"`
-	Category            string    `json:"category"`
-	MergedCount         int       `json:"merged_count"`
-	AIConfidence        float64   `json:"ai_confidence"`
-	AIReasoning         string    `json:"ai_reasoning"`
-	ProcessingLevel     string    `json:"processing_level"`
-	KpvedCode           string    `json:"kpved_code"`
-	KpvedName           string    `json:"kpved_name"`
-	KpvedConfidence     float64   `json:"kpved_confidence"`
-	QualityScore        float64   `json:"quality_score"`
-	CreatedAt           time.Time `json:"created_at"`
+	ID                  int        `json:"id"`
+	SourceReference     string     `json:"source_reference"`
+	SourceName          string     `json:"source_name"`
+	Code                string     `json:"code"`
+	NormalizedName      string     `json:"normalized_name"`
+	NormalizedReference string     `json:"normalized_reference"`
+	Category            string     `json:"category"`
+	MergedCount         int        `json:"merged_count"`
+	AIConfidence        float64    `json:"ai_confidence"`
+	AIReasoning         string     `json:"ai_reasoning"`
+	ProcessingLevel     string     `json:"processing_level"`
+	KpvedCode           string     `json:"kpved_code"`
+	KpvedName           string     `json:"kpved_name"`
+	KpvedConfidence     float64    `json:"kpved_confidence"`
+	KpvedRawConfidence  float64    `json:"kpved_raw_confidence,omitempty"` // Уверенность модели до калибровки
+	KpvedModel          string     `json:"kpved_model,omitempty"`
+	ConfidenceDecision  string     `json:"confidence_decision,omitempty"` // accept/review/reject по политике порогов
+	QualityScore        float64    `json:"quality_score"`
+	StandardReferences  string     `json:"standard_references,omitempty"` // Обозначения стандартов через "; "
+	Version             int        `json:"version"` // Увеличивается при каждом ручном изменении
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty"`
+
+	// События происхождения, сохраняемые вместе с записью при пакетной вставке
+	Lineage []*LineageRecord `json:"-"`
 }
 
 // ItemAttribute представляет извлеченный атрибут товара
@@ -1439,6 +1563,32 @@ func (db *DB) GetCatalogItemsFromTable(tableName, referenceCol, codeCol, nameCol
 	return items, nil
 }
 
+// GetCatalogItemAttributesFromTable возвращает реквизиты записей источника: reference -> реквизиты (XML или JSON).
+// Таблица без колонки attributes возвращает ошибку
+func (db *DB) GetCatalogItemAttributesFromTable(tableName, referenceCol string) (map[string]string, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, attributes
+		FROM %s
+		WHERE attributes IS NOT NULL AND attributes != ''
+	`, referenceCol, tableName)
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog item attributes from %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	attributes := make(map[string]string)
+	for rows.Next() {
+		var reference, value string
+		if err := rows.Scan(&reference, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog item attributes: %w", err)
+		}
+		attributes[reference] = value
+	}
+	return attributes, rows.Err()
+}
+
 // InsertNormalizedItem вставляет нормализованную запись в normalized_data
 func (db *DB) InsertNormalizedItem(sourceReference, sourceName, code, normalizedName, normalizedReference, category string, mergedCount int) error {
 	query := `
@@ -1471,8 +1621,8 @@ func (db *DB) InsertNormalizedItemsBatch(items []*NormalizedItem) (map[string]in
 
 	stmt, err := tx.Prepare(`
 		INSERT OR REPLACE INTO normalized_data
-		(source_reference, source_name, code, normalized_name, normalized_reference, category, merged_count, ai_confidence, ai_reasoning, processing_level, kpved_code, kpved_name, kpved_confidence)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(source_reference, source_name, code, normalized_name, normalized_reference, category, merged_count, ai_confidence, ai_reasoning, processing_level, kpved_code, kpved_name, kpved_confidence, kpved_raw_confidence, kpved_model, confidence_decision, standard_references)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare statement: %w", err)
@@ -1482,6 +1632,7 @@ func (db *DB) InsertNormalizedItemsBatch(items []*NormalizedItem) (map[string]in
 	codeToID := make(map[string]int)
 
 	for _, item := range items {
+		rawConfidence, model, decision := item.calibrationValues()
 		result, err := stmt.Exec(
 			item.SourceReference,
 			item.SourceName,
@@ -1496,6 +1647,10 @@ func (db *DB) InsertNormalizedItemsBatch(items []*NormalizedItem) (map[string]in
 			item.KpvedCode,
 			item.KpvedName,
 			item.KpvedConfidence,
+			rawConfidence,
+			model,
+			decision,
+			item.StandardReferences,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert normalized item: %w", err)
@@ -1507,6 +1662,10 @@ func (db *DB) InsertNormalizedItemsBatch(items []*NormalizedItem) (map[string]in
 			return nil, fmt.Errorf("failed to get last insert id: %w", err)
 		}
 		
+		if err := insertLineageRecords(tx, int(id), item.Lineage); err != nil {
+			return nil, err
+		}
+
 		// ВАЖНО: Если несколько элементов имеют одинаковый код, 
 		// в map будет сохранен только ID последнего элемента с таким кодом.
 		// Это нормально, если код должен быть уникальным в рамках батча.
@@ -1578,6 +1737,48 @@ func (db *DB) GetNormalizedItems(offset, limit int) ([]*NormalizedItem, error) {
 	return items, nil
 }
 
+// GetNormalizedItemByID получает нормализованную запись по ID
+func (db *DB) GetNormalizedItemByID(id int) (*NormalizedItem, error) {
+	query := `
+		SELECT id, COALESCE(source_reference, ''), COALESCE(source_name, ''), COALESCE(code, ''), COALESCE(normalized_name, ''),
+		       COALESCE(normalized_reference, ''), COALESCE(category, ''), merged_count, COALESCE(ai_confidence, 0),
+		       COALESCE(ai_reasoning, ''), COALESCE(processing_level, ''), COALESCE(kpved_code, ''), COALESCE(kpved_name, ''),
+		       COALESCE(kpved_confidence, 0), COALESCE(version, 1), created_at, updated_at
+		FROM normalized_data
+		WHERE id = ?
+	`
+
+	item := &NormalizedItem{}
+	var updatedAt sql.NullTime
+	err := db.conn.QueryRow(query, id).Scan(
+		&item.ID,
+		&item.SourceReference,
+		&item.SourceName,
+		&item.Code,
+		&item.NormalizedName,
+		&item.NormalizedReference,
+		&item.Category,
+		&item.MergedCount,
+		&item.AIConfidence,
+		&item.AIReasoning,
+		&item.ProcessingLevel,
+		&item.KpvedCode,
+		&item.KpvedName,
+		&item.KpvedConfidence,
+		&item.Version,
+		&item.CreatedAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get normalized item %d: %w", id, err)
+	}
+	if updatedAt.Valid {
+		item.UpdatedAt = &updatedAt.Time
+	}
+
+	return item, nil
+}
+
 // InsertItemAttributesBatch вставляет пакет атрибутов для нормализованного товара
 func (db *DB) InsertItemAttributesBatch(normalizedItemID int, attributes []*ItemAttribute) error {
 	if len(attributes) == 0 {
@@ -1642,8 +1843,8 @@ func (db *DB) InsertNormalizedItemsWithAttributesBatch(items []*NormalizedItem,
 	// Подготавливаем statement для вставки items
 	itemStmt, err := tx.Prepare(`
 		INSERT OR REPLACE INTO normalized_data
-		(source_reference, source_name, code, normalized_name, normalized_reference, category, merged_count, ai_confidence, ai_reasoning, processing_level, kpved_code, kpved_name, kpved_confidence)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		(source_reference, source_name, code, normalized_name, normalized_reference, category, merged_count, ai_confidence, ai_reasoning, processing_level, kpved_code, kpved_name, kpved_confidence, kpved_raw_confidence, kpved_model, confidence_decision, standard_references)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare item statement: %w", err)
@@ -1665,6 +1866,7 @@ func (db *DB) InsertNormalizedItemsWithAttributesBatch(items []*NormalizedItem,
 
 	// Вставляем items
 	for _, item := range items {
+		rawConfidence, model, decision := item.calibrationValues()
 		result, err := itemStmt.Exec(
 			item.SourceReference,
 			item.SourceName,
@@ -1679,6 +1881,10 @@ func (db *DB) InsertNormalizedItemsWithAttributesBatch(items []*NormalizedItem,
 			item.KpvedCode,
 			item.KpvedName,
 			item.KpvedConfidence,
+			rawConfidence,
+			model,
+			decision,
+			item.StandardReferences,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert normalized item: %w", err)
@@ -1691,6 +1897,10 @@ func (db *DB) InsertNormalizedItemsWithAttributesBatch(items []*NormalizedItem,
 		}
 
 		itemID := int(id)
+		if err := insertLineageRecords(tx, itemID, item.Lineage); err != nil {
+			return nil, err
+		}
+
 		if item.Code != "" {
 			codeToID[item.Code] = itemID
 
@@ -2779,6 +2989,8 @@ func (db *DB) UpdateUploadIteration(uploadID int, iterationNumber int, iteration
 		return fmt.Errorf("failed to update upload iteration: %w", err)
 	}
 
+	db.InvalidateUpload(uploadID)
+
 	return nil
 }
 
@@ -3310,37 +3522,194 @@ func (db *DB) AddCatalogItemToTable(tableName string, uploadID int, reference, c
 		}
 	}
 	
+	inserted, itemErrors, err := db.AddCatalogItemsToTable(tableName, uploadID, []CatalogTableItem{{
+		Reference:     reference,
+		Code:          code,
+		Name:          name,
+		AttributesXML: attrsXML,
+		TablePartsXML: partsXML,
+	}})
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return itemErrors[0]
+	}
+	
+	log.Printf("✓ Добавлен элемент в таблицу %s (upload_id=%d, reference=%s)", tableName, uploadID, reference)
+	return nil
+}
+
+// CatalogTableItem элемент справочника для пакетной записи в динамическую таблицу
+type CatalogTableItem struct {
+	Reference     string
+	Code          string
+	Name          string
+	AttributesXML string
+	TablePartsXML string
+	// Sealed - AttributesXML/TablePartsXML зашифрованы ключом проекта, исходные значения переданы в Plain*:
+	// по ним сравниваются версии элемента. Типизированные колонки зашифрованных элементов не заполняются.
+	// DigestKey - ключ HMAC проекта для хешей реквизитов в истории версий
+	Sealed             bool
+	PlainAttributesXML string
+	PlainTablePartsXML string
+	DigestKey          []byte
+}
+
+// plainContent возвращает реквизиты и табличные части элемента до шифрования
+func (item CatalogTableItem) plainContent() (attributes, tableParts string) {
+	if item.Sealed {
+		return item.PlainAttributesXML, item.PlainTablePartsXML
+	}
+	return item.AttributesXML, item.TablePartsXML
+}
+
+// AddCatalogItemsToTable добавляет пакет элементов справочника в динамическую таблицу одной транзакцией.
+// Счетчик total_items увеличивается в той же транзакции на число фактически вставленных строк.
+// Ошибки отдельных элементов возвращаются в itemErrors (по индексу элемента), такие элементы пропускаются
+func (db *DB) AddCatalogItemsToTable(tableName string, uploadID int, items []CatalogTableItem) (inserted int, itemErrors []error, err error) {
+	itemErrors = make([]error, len(items))
+	if len(items) == 0 {
+		return 0, itemErrors, nil
+	}
+	
+	// Продвигаем реквизиты с маппингом в типизированные колонки
+	columns := "upload_id, reference, code, name, attributes_xml, table_parts_xml"
+	placeholders := "?, ?, ?, ?, ?, ?"
+	mappings, mappingsErr := GetCatalogColumnMappingsByTable(db.conn, tableName)
+	if mappingsErr != nil {
+		mappings = nil // Без маппингов пишем только базовые колонки, как и раньше
+	}
+	for _, m := range mappings {
+		columns += ", " + m.ColumnName
+		placeholders += ", ?"
+	}
+	
 	// Используем транзакцию для атомарности
 	tx, err := db.conn.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 	
 	// Формируем динамический SQL запрос (безопасно, так как tableName валидируется)
-	query := fmt.Sprintf(`
-		INSERT INTO %s (upload_id, reference, code, name, attributes_xml, table_parts_xml)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, tableName)
-	
-	_, err = tx.Exec(query, uploadID, reference, code, name, attrsXML, partsXML)
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		VALUES (%s)
+	`, tableName, columns, placeholders))
 	if err != nil {
-		return fmt.Errorf("failed to add catalog item to table %s: %w", tableName, err)
+		return 0, nil, fmt.Errorf("failed to add catalog item to table %s: %w", tableName, err)
 	}
+	defer stmt.Close()
 	
-	// Обновляем счетчик total_items
-	_, err = tx.Exec("UPDATE uploads SET total_items = total_items + 1 WHERE id = ?", uploadID)
-	if err != nil {
-		return fmt.Errorf("failed to update items counter: %w", err)
+	// Версии элементов ведутся в пределах базы 1С выгрузки
+	databaseID := uploadDatabaseID(tx, uploadID)
+	
+	for i, item := range items {
+		args := []interface{}{uploadID, item.Reference, item.Code, item.Name, item.AttributesXML, item.TablePartsXML}
+		if len(mappings) > 0 {
+			// Продвинутые колонки хранят открытые значения, поэтому у зашифрованных элементов остаются пустыми
+			var values map[string]string
+			if !item.Sealed {
+				values = ExtractAttributeValues(item.AttributesXML)
+			}
+			for _, m := range mappings {
+				args = append(args, convertCatalogColumnValue(values[m.AttributeName], m.ColumnType))
+			}
+		}
+		if _, execErr := stmt.Exec(args...); execErr != nil {
+			itemErrors[i] = fmt.Errorf("failed to add catalog item to table %s: %w", tableName, execErr)
+			continue
+		}
+		inserted++
+		if versionErr := recordCatalogItemVersion(tx, tableName, databaseID, uploadID, item); versionErr != nil {
+			log.Printf("Не удалось записать версию элемента %s таблицы %s: %v", item.Reference, tableName, versionErr)
+		}
+	}
+	
+	// Обновляем счетчик total_items на число вставленных элементов
+	if inserted > 0 {
+		_, err = tx.Exec("UPDATE uploads SET total_items = total_items + ? WHERE id = ?", inserted, uploadID)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to update items counter: %w", err)
+		}
 	}
 	
 	// Подтверждаем транзакцию
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return 0, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	db.adjustCachedUploadCounters(uploadID, 0, 0, inserted)
 	
-	log.Printf("✓ Добавлен элемент в таблицу %s (upload_id=%d, reference=%s)", tableName, uploadID, reference)
-	return nil
+	return inserted, itemErrors, nil
+}
+
+// getCatalogItemsFromDynamicTables получает элементы справочников выгрузки из всех динамических таблиц
+// единой БД. Порядок: по имени справочника, затем по ID элемента
+func (db *DB) getCatalogItemsFromDynamicTables(ctx context.Context, uploadID int, catalogNames []string, offset, limit int) ([]*CatalogItem, int, error) {
+	tables, err := GetAllCatalogTables(db.conn)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	wanted := make(map[string]bool, len(catalogNames))
+	for _, name := range catalogNames {
+		wanted[name] = true
+	}
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		if len(wanted) == 0 || wanted[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return []*CatalogItem{}, 0, nil
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	args := make([]interface{}, 0, len(names)*3)
+	for i, name := range names {
+		// Имена таблиц берутся из catalog_mappings и проверены при создании
+		parts = append(parts, fmt.Sprintf(`
+			SELECT id, ? AS catalog_name, ? AS catalog_order, reference, code, name,
+			       COALESCE(attributes_xml, '') AS attributes, COALESCE(table_parts_xml, '') AS table_parts, created_at
+			FROM %s WHERE upload_id = ?`, tables[name]))
+		args = append(args, name, i, uploadID)
+	}
+	query := "SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") ORDER BY catalog_order, id"
+
+	var totalCount int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+")", args...).Scan(&totalCount); err != nil {
+		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	if limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get catalog items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*CatalogItem
+	for rows.Next() {
+		item := &CatalogItem{}
+		var order int
+		if err := rows.Scan(&item.ID, &item.CatalogName, &order, &item.Reference, &item.Code, &item.Name,
+			&item.Attributes, &item.TableParts, &item.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan catalog item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating catalog items: %w", err)
+	}
+
+	return items, totalCount, nil
 }
 
 // GetCatalogItemsFromDynamicTable получает элементы справочника из динамической таблицы (для единой БД)
@@ -3434,6 +3803,11 @@ func NewUnifiedDBWithConfig(dbPath string, config DBConfig) (*DB, error) {
 		return nil, fmt.Errorf("failed to initialize unified schema: %w", err)
 	}
 
+	if err := db.attachReadOnlyPool(dbPath, config); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
 	log.Println("✓ Единая БД справочников инициализирована")
 	return db, nil
 }
\ No newline at end of file
@@ -170,6 +170,20 @@ func (db *DB) GetCategoryClassifiersByFilter(clientID *int, projectID *int, acti
 	return classifiers, nil
 }
 
+// AssignCategoryClassifier закрепляет классификатор за клиентом и проектом, не затрагивая дерево категорий
+func (db *DB) AssignCategoryClassifier(id int, clientID, projectID *int) error {
+	result, err := db.conn.Exec(`
+		UPDATE category_classifiers SET client_id = ?, project_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, clientID, projectID, id)
+	if err != nil {
+		return fmt.Errorf("failed to assign category classifier: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("category classifier not found")
+	}
+	return nil
+}
+
 // UpdateCategoryClassifier обновляет классификатор категорий
 func (db *DB) UpdateCategoryClassifier(classifier *CategoryClassifier) error {
 	query := `
@@ -193,6 +207,11 @@ func (db *DB) UpdateCategoryClassifier(classifier *CategoryClassifier) error {
 		return fmt.Errorf("failed to update category classifier: %w", err)
 	}
 
+	// Дерево могло измениться - узлы будут пересобраны из tree_structure при следующей загрузке
+	if classifier.TreeStructure != "" {
+		return db.DeleteClassifierNodes(classifier.ID)
+	}
+
 	return nil
 }
 
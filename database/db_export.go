@@ -1,11 +1,19 @@
 package database
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 const defaultExportBatchSize = 500
 
 // StreamConstantsByUpload читает константы выгрузки порциями и передает батчи в handler.
 func (db *DB) StreamConstantsByUpload(uploadID int, batchSize int, handler func([]*Constant) error) error {
+	return db.StreamConstantsByUploadContext(context.Background(), uploadID, batchSize, handler)
+}
+
+// StreamConstantsByUploadContext читает константы порциями; чтение прекращается при отмене ctx.
+func (db *DB) StreamConstantsByUploadContext(ctx context.Context, uploadID int, batchSize int, handler func([]*Constant) error) error {
 	if handler == nil {
 		return fmt.Errorf("handler is required")
 	}
@@ -15,7 +23,7 @@ func (db *DB) StreamConstantsByUpload(uploadID int, batchSize int, handler func(
 
 	offset := 0
 	for {
-		batch, err := db.getConstantsBatch(uploadID, offset, batchSize)
+		batch, err := db.getConstantsBatch(ctx, uploadID, offset, batchSize)
 		if err != nil {
 			return err
 		}
@@ -34,16 +42,24 @@ func (db *DB) StreamConstantsByUpload(uploadID int, batchSize int, handler func(
 
 // StreamCatalogsByUpload читает метаданные справочников (с фильтрацией по именам) и передает их обработчику.
 func (db *DB) StreamCatalogsByUpload(uploadID int, catalogNames []string, handler func(*Catalog) error) error {
+	return db.StreamCatalogsByUploadContext(context.Background(), uploadID, catalogNames, handler)
+}
+
+// StreamCatalogsByUploadContext передает метаданные справочников обработчику до отмены ctx.
+func (db *DB) StreamCatalogsByUploadContext(ctx context.Context, uploadID int, catalogNames []string, handler func(*Catalog) error) error {
 	if handler == nil {
 		return fmt.Errorf("handler is required")
 	}
 
-	catalogs, err := db.getCatalogsFiltered(uploadID, catalogNames)
+	catalogs, err := db.getCatalogsFiltered(ctx, uploadID, catalogNames)
 	if err != nil {
 		return err
 	}
 
 	for _, catalog := range catalogs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := handler(catalog); err != nil {
 			return err
 		}
@@ -53,6 +69,11 @@ func (db *DB) StreamCatalogsByUpload(uploadID int, catalogNames []string, handle
 
 // StreamCatalogItems читает элементы справочников выгрузки батчами и передает в handler.
 func (db *DB) StreamCatalogItems(uploadID int, catalogNames []string, batchSize int, handler func([]*CatalogItem) error) error {
+	return db.StreamCatalogItemsContext(context.Background(), uploadID, catalogNames, batchSize, handler)
+}
+
+// StreamCatalogItemsContext читает элементы справочников батчами; чтение прекращается при отмене ctx.
+func (db *DB) StreamCatalogItemsContext(ctx context.Context, uploadID int, catalogNames []string, batchSize int, handler func([]*CatalogItem) error) error {
 	if handler == nil {
 		return fmt.Errorf("handler is required")
 	}
@@ -62,7 +83,7 @@ func (db *DB) StreamCatalogItems(uploadID int, catalogNames []string, batchSize
 
 	offset := 0
 	for {
-		items, err := db.getCatalogItemsBatch(uploadID, catalogNames, offset, batchSize)
+		items, err := db.getCatalogItemsBatch(ctx, uploadID, catalogNames, offset, batchSize)
 		if err != nil {
 			return err
 		}
@@ -81,6 +102,11 @@ func (db *DB) StreamCatalogItems(uploadID int, catalogNames []string, batchSize
 
 // StreamNomenclatureItems читает номенклатуру с характеристиками батчами.
 func (db *DB) StreamNomenclatureItems(uploadID int, batchSize int, handler func([]*NomenclatureItem) error) error {
+	return db.StreamNomenclatureItemsContext(context.Background(), uploadID, batchSize, handler)
+}
+
+// StreamNomenclatureItemsContext читает номенклатуру батчами; чтение прекращается при отмене ctx.
+func (db *DB) StreamNomenclatureItemsContext(ctx context.Context, uploadID int, batchSize int, handler func([]*NomenclatureItem) error) error {
 	if handler == nil {
 		return fmt.Errorf("handler is required")
 	}
@@ -90,7 +116,7 @@ func (db *DB) StreamNomenclatureItems(uploadID int, batchSize int, handler func(
 
 	offset := 0
 	for {
-		items, err := db.getNomenclatureBatch(uploadID, offset, batchSize)
+		items, err := db.getNomenclatureBatch(ctx, uploadID, offset, batchSize)
 		if err != nil {
 			return err
 		}
@@ -107,16 +133,16 @@ func (db *DB) StreamNomenclatureItems(uploadID int, batchSize int, handler func(
 	}
 }
 
-func (db *DB) getConstantsBatch(uploadID int, offset, limit int) ([]*Constant, error) {
+func (db *DB) getConstantsBatch(ctx context.Context, uploadID int, offset, limit int) ([]*Constant, error) {
 	query := `
-		SELECT id, upload_id, name, synonym, type, value, created_at
+		SELECT `+constantColumns+`
 		FROM constants
 		WHERE upload_id = ?
 		ORDER BY id
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := db.conn.Query(query, uploadID, limit, offset)
+	rows, err := db.QueryContext(ctx, query, uploadID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get constants batch: %w", err)
 	}
@@ -124,8 +150,8 @@ func (db *DB) getConstantsBatch(uploadID int, offset, limit int) ([]*Constant, e
 
 	var result []*Constant
 	for rows.Next() {
-		item := &Constant{}
-		if err := rows.Scan(&item.ID, &item.UploadID, &item.Name, &item.Synonym, &item.Type, &item.Value, &item.CreatedAt); err != nil {
+		item, err := scanConstant(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan constant: %w", err)
 		}
 		result = append(result, item)
@@ -138,7 +164,7 @@ func (db *DB) getConstantsBatch(uploadID int, offset, limit int) ([]*Constant, e
 	return result, nil
 }
 
-func (db *DB) getCatalogsFiltered(uploadID int, catalogNames []string) ([]*Catalog, error) {
+func (db *DB) getCatalogsFiltered(ctx context.Context, uploadID int, catalogNames []string) ([]*Catalog, error) {
 	baseQuery := `
 		SELECT id, upload_id, name, synonym, created_at
 		FROM catalogs
@@ -162,7 +188,7 @@ func (db *DB) getCatalogsFiltered(uploadID int, catalogNames []string) ([]*Catal
 
 	baseQuery += " ORDER BY name"
 
-	rows, err := db.conn.Query(baseQuery, args...)
+	rows, err := db.QueryContext(ctx, baseQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get catalogs: %w", err)
 	}
@@ -184,12 +210,12 @@ func (db *DB) getCatalogsFiltered(uploadID int, catalogNames []string) ([]*Catal
 	return catalogs, nil
 }
 
-func (db *DB) getCatalogItemsBatch(uploadID int, catalogNames []string, offset, limit int) ([]*CatalogItem, error) {
-	items, _, err := db.GetCatalogItemsByUpload(uploadID, catalogNames, offset, limit)
+func (db *DB) getCatalogItemsBatch(ctx context.Context, uploadID int, catalogNames []string, offset, limit int) ([]*CatalogItem, error) {
+	items, _, err := db.GetCatalogItemsByUploadContext(ctx, uploadID, catalogNames, offset, limit)
 	return items, err
 }
 
-func (db *DB) getNomenclatureBatch(uploadID int, offset, limit int) ([]*NomenclatureItem, error) {
+func (db *DB) getNomenclatureBatch(ctx context.Context, uploadID int, offset, limit int) ([]*NomenclatureItem, error) {
 	query := `
 		SELECT id, upload_id, nomenclature_reference, nomenclature_code, nomenclature_name,
 		       characteristic_reference, characteristic_name, 
@@ -202,7 +228,7 @@ func (db *DB) getNomenclatureBatch(uploadID int, offset, limit int) ([]*Nomencla
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := db.conn.Query(query, uploadID, limit, offset)
+	rows, err := db.QueryContext(ctx, query, uploadID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get nomenclature batch: %w", err)
 	}
@@ -0,0 +1,437 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/parquet"
+)
+
+// ParquetExport параметры выгрузки таблицы в Parquet
+type ParquetExport struct {
+	Options   parquet.Options
+	BatchSize int            // Строк в одном запросе к БД
+	Progress  func(rows int) // Вызывается после записи каждого батча (nil - не вызывается)
+	// Redact скрывает поля элемента справочника перед записью (nil - элементы пишутся как есть)
+	Redact func(item *CatalogItem)
+}
+
+func (e ParquetExport) batchSize() int {
+	if e.BatchSize <= 0 {
+		return defaultExportBatchSize
+	}
+	return e.BatchSize
+}
+
+// redact скрывает поля элементов перед записью
+func (e ParquetExport) redact(items []*CatalogItem) {
+	if e.Redact == nil {
+		return
+	}
+	for _, item := range items {
+		e.Redact(item)
+	}
+}
+
+func (e ParquetExport) progress(rows int) {
+	if e.Progress != nil {
+		e.Progress(rows)
+	}
+}
+
+// parquetAttribute колонка реквизита: реквизиты, извлеченные из EAV хранения, разворачиваются в колонки
+type parquetAttribute struct {
+	name       string
+	column     parquet.Column
+	definition *CatalogAttributeDefinition // Описание из метаданных 1С для приведения значений (nil - строка)
+}
+
+// parquetAttributeType тип колонки реквизита по метаданным 1С
+func parquetAttributeType(definition CatalogAttributeDefinition) parquet.Type {
+	switch definition.Kind {
+	case ConstantKindNumber:
+		if definition.ColumnType() == "integer" {
+			return parquet.Int64
+		}
+		return parquet.Double
+	case ConstantKindBoolean:
+		return parquet.Boolean
+	case ConstantKindDate:
+		return parquet.Timestamp
+	default:
+		return parquet.String
+	}
+}
+
+// value приводит значение реквизита к типу колонки; не приводимые и пустые значения - NULL
+func (a *parquetAttribute) value(raw string) interface{} {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	switch a.column.Type {
+	case parquet.String:
+		return raw
+	case parquet.Timestamp:
+		if parsed, ok := parseConstantDate(raw); ok && parsed.Year() > 1 {
+			return parsed
+		}
+		return nil
+	case parquet.Double:
+		if a.definition == nil {
+			if parsed, ok := parseConstantNumber(raw); ok {
+				return parsed
+			}
+			return nil
+		}
+	}
+	cast, err := a.definition.CastValue(raw)
+	if err != nil {
+		return nil
+	}
+	return cast
+}
+
+// attributeColumns назначает реквизитам уникальные имена колонок attr_<транслитерация> в порядке имен
+func attributeColumns(attributes map[string]*parquetAttribute, reserved map[string]bool) ([]*parquetAttribute, string) {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]*parquetAttribute, 0, len(names))
+	columnNames := make(map[string]string, len(names))
+	for _, name := range names {
+		attribute := attributes[name]
+		base := CatalogAttributeColumnName(name)
+		column := base
+		for n := 2; reserved[column]; n++ {
+			column = base + "_" + strconv.Itoa(n)
+		}
+		reserved[column] = true
+		attribute.column.Name = column
+		columnNames[column] = name
+		list = append(list, attribute)
+	}
+	mapping, _ := json.Marshal(columnNames)
+	return list, string(mapping)
+}
+
+// ExportCatalogItemsParquet записывает элементы справочников выгрузки в Parquet. Реквизиты элементов
+// разворачиваются в колонки attr_*; типы колонок берутся из метаданных справочников 1С, реквизиты
+// без метаданных (или с разными типами в разных справочниках) выгружаются строками.
+// Соответствие колонок исходным именам реквизитов записывается в метаданные файла (attribute_columns).
+// Возвращает число строк
+func (db *DB) ExportCatalogItemsParquet(ctx context.Context, uploadID int, catalogNames []string, w io.Writer, export ParquetExport) (int64, error) {
+	metadata, err := GetUploadCatalogMetadata(db.conn, uploadID)
+	if err != nil {
+		return 0, err
+	}
+	definitions := make(map[string]*CatalogMetadata, len(metadata))
+	for i := range metadata {
+		definitions[metadata[i].CatalogName] = &metadata[i]
+	}
+
+	// Первый проход: состав реквизитов и их типы
+	attributes := make(map[string]*parquetAttribute)
+	err = db.StreamCatalogItemsContext(ctx, uploadID, catalogNames, export.batchSize(), func(items []*CatalogItem) error {
+		export.redact(items)
+		for _, item := range items {
+			for name := range ExtractAttributeValues(item.Attributes) {
+				var definition *CatalogAttributeDefinition
+				columnType := parquet.String
+				if catalog := definitions[item.CatalogName]; catalog != nil {
+					if attr, ok := catalog.Attribute(name); ok {
+						definition = &attr
+						columnType = parquetAttributeType(attr)
+					}
+				}
+				existing := attributes[name]
+				if existing == nil {
+					attributes[name] = &parquetAttribute{name: name, column: parquet.Column{Type: columnType}, definition: definition}
+					continue
+				}
+				if existing.column.Type != columnType {
+					existing.column.Type = parquet.String
+					existing.definition = nil
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	columns := []parquet.Column{
+		{Name: "id", Type: parquet.Int64},
+		{Name: "catalog_name", Type: parquet.String},
+		{Name: "reference", Type: parquet.String},
+		{Name: "code", Type: parquet.String},
+		{Name: "name", Type: parquet.String},
+		{Name: "created_at", Type: parquet.Timestamp},
+	}
+	reserved := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		reserved[column.Name] = true
+	}
+	attributeList, mapping := attributeColumns(attributes, reserved)
+	for _, attribute := range attributeList {
+		columns = append(columns, attribute.column)
+	}
+
+	options := export.Options
+	options.Metadata = withMetadata(options.Metadata, map[string]string{
+		"source":            "catalog_items",
+		"upload_id":         strconv.Itoa(uploadID),
+		"attribute_columns": mapping,
+	})
+	writer, err := parquet.NewWriter(w, columns, options)
+	if err != nil {
+		return 0, err
+	}
+
+	// Второй проход: строки
+	row := make([]interface{}, len(columns))
+	err = db.StreamCatalogItemsContext(ctx, uploadID, catalogNames, export.batchSize(), func(items []*CatalogItem) error {
+		export.redact(items)
+		for _, item := range items {
+			values := ExtractAttributeValues(item.Attributes)
+			row[0], row[1], row[2], row[3], row[4], row[5] = item.ID, item.CatalogName, item.Reference, item.Code, item.Name, item.CreatedAt
+			for i, attribute := range attributeList {
+				row[6+i] = nil
+				if raw, ok := values[attribute.name]; ok {
+					row[6+i] = attribute.value(raw)
+				}
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		export.progress(len(items))
+		return nil
+	})
+	if err != nil {
+		return writer.Rows(), err
+	}
+	return writer.Rows(), writer.Close()
+}
+
+// ExportNormalizedDataParquet записывает нормализованные записи в Parquet. Атрибуты, извлеченные
+// при нормализации (normalized_item_attributes), разворачиваются в колонки attr_*: числовые
+// атрибуты - DOUBLE, остальные - строки; единицы измерения - в колонки attr_*_unit.
+// Для повторяющегося атрибута записи берется первое извлеченное значение. Возвращает число строк
+func (db *DB) ExportNormalizedDataParquet(ctx context.Context, w io.Writer, export ParquetExport) (int64, error) {
+	// Первый проход по атрибутам: состав, числовые ли значения, есть ли единицы измерения
+	type attributeProfile struct {
+		numeric bool
+		hasUnit bool
+	}
+	profiles := make(map[string]*attributeProfile)
+	rows, err := db.QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(attribute_name, ''), attribute_type), attribute_value, COALESCE(unit, '')
+		FROM normalized_item_attributes
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to profile normalized attributes: %w", err)
+	}
+	for rows.Next() {
+		var name, value, unit string
+		if err := rows.Scan(&name, &value, &unit); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan normalized attribute: %w", err)
+		}
+		profile := profiles[name]
+		if profile == nil {
+			profile = &attributeProfile{numeric: true}
+			profiles[name] = profile
+		}
+		if _, ok := parseConstantNumber(value); !ok {
+			profile.numeric = false
+		}
+		profile.hasUnit = profile.hasUnit || unit != ""
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating normalized attributes: %w", err)
+	}
+
+	columns := []parquet.Column{
+		{Name: "id", Type: parquet.Int64},
+		{Name: "source_reference", Type: parquet.String},
+		{Name: "source_name", Type: parquet.String},
+		{Name: "code", Type: parquet.String},
+		{Name: "normalized_name", Type: parquet.String},
+		{Name: "normalized_reference", Type: parquet.String},
+		{Name: "category", Type: parquet.String},
+		{Name: "merged_count", Type: parquet.Int64},
+		{Name: "ai_confidence", Type: parquet.Double},
+		{Name: "processing_level", Type: parquet.String},
+		{Name: "kpved_code", Type: parquet.String},
+		{Name: "kpved_name", Type: parquet.String},
+		{Name: "kpved_confidence", Type: parquet.Double},
+		{Name: "created_at", Type: parquet.Timestamp},
+	}
+	baseColumns := len(columns)
+	reserved := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		reserved[column.Name] = true
+	}
+	attributes := make(map[string]*parquetAttribute, len(profiles))
+	for name, profile := range profiles {
+		columnType := parquet.String
+		if profile.numeric {
+			columnType = parquet.Double
+		}
+		attributes[name] = &parquetAttribute{name: name, column: parquet.Column{Type: columnType}}
+	}
+	attributeList, mapping := attributeColumns(attributes, reserved)
+	// Позиции колонок значения и единицы измерения для каждого атрибута
+	valueIndex := make(map[string]int, len(attributeList))
+	unitIndex := make(map[string]int)
+	for _, attribute := range attributeList {
+		valueIndex[attribute.name] = len(columns)
+		columns = append(columns, attribute.column)
+		if profiles[attribute.name].hasUnit {
+			unitColumn := attribute.column.Name + "_unit"
+			for n := 2; reserved[unitColumn]; n++ {
+				unitColumn = attribute.column.Name + "_unit_" + strconv.Itoa(n)
+			}
+			reserved[unitColumn] = true
+			unitIndex[attribute.name] = len(columns)
+			columns = append(columns, parquet.Column{Name: unitColumn, Type: parquet.String})
+		}
+	}
+
+	options := export.Options
+	options.Metadata = withMetadata(options.Metadata, map[string]string{
+		"source":            "normalized_data",
+		"attribute_columns": mapping,
+	})
+	writer, err := parquet.NewWriter(w, columns, options)
+	if err != nil {
+		return 0, err
+	}
+
+	// Второй проход: записи батчами по возрастанию id с атрибутами батча
+	row := make([]interface{}, len(columns))
+	lastID := 0
+	for {
+		items, err := db.getNormalizedExportBatch(ctx, lastID, export.batchSize())
+		if err != nil {
+			return writer.Rows(), err
+		}
+		if len(items) == 0 {
+			break
+		}
+		lastID = items[len(items)-1].ID
+		attributeValues, err := db.getNormalizedExportAttributes(ctx, items[0].ID, lastID)
+		if err != nil {
+			return writer.Rows(), err
+		}
+
+		for _, item := range items {
+			row[0], row[1], row[2], row[3], row[4] = item.ID, item.SourceReference, item.SourceName, item.Code, item.NormalizedName
+			row[5], row[6], row[7], row[8], row[9] = item.NormalizedReference, item.Category, item.MergedCount, item.AIConfidence, item.ProcessingLevel
+			row[10], row[11], row[12], row[13] = item.KpvedCode, item.KpvedName, item.KpvedConfidence, item.CreatedAt
+			for i := baseColumns; i < len(row); i++ {
+				row[i] = nil
+			}
+			for _, attr := range attributeValues[item.ID] {
+				index := valueIndex[attr.AttributeName]
+				if row[index] != nil {
+					continue
+				}
+				row[index] = attributes[attr.AttributeName].value(attr.AttributeValue)
+				if unit, ok := unitIndex[attr.AttributeName]; ok && attr.Unit != "" {
+					row[unit] = attr.Unit
+				}
+			}
+			if err := writer.Write(row); err != nil {
+				return writer.Rows(), err
+			}
+		}
+		export.progress(len(items))
+		if len(items) < export.batchSize() {
+			break
+		}
+	}
+	return writer.Rows(), writer.Close()
+}
+
+// getNormalizedExportBatch читает нормализованные записи с id больше afterID
+func (db *DB) getNormalizedExportBatch(ctx context.Context, afterID, limit int) ([]*NormalizedItem, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, COALESCE(source_reference, ''), COALESCE(source_name, ''), COALESCE(code, ''), COALESCE(normalized_name, ''),
+		       COALESCE(normalized_reference, ''), COALESCE(category, ''), COALESCE(merged_count, 1), COALESCE(ai_confidence, 0),
+		       COALESCE(processing_level, ''), COALESCE(kpved_code, ''), COALESCE(kpved_name, ''), COALESCE(kpved_confidence, 0),
+		       created_at
+		FROM normalized_data
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get normalized export batch: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*NormalizedItem
+	for rows.Next() {
+		item := &NormalizedItem{}
+		var createdAt *time.Time
+		if err := rows.Scan(&item.ID, &item.SourceReference, &item.SourceName, &item.Code, &item.NormalizedName,
+			&item.NormalizedReference, &item.Category, &item.MergedCount, &item.AIConfidence,
+			&item.ProcessingLevel, &item.KpvedCode, &item.KpvedName, &item.KpvedConfidence, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan normalized item: %w", err)
+		}
+		if createdAt != nil {
+			item.CreatedAt = *createdAt
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// getNormalizedExportAttributes читает атрибуты записей с id в диапазоне [fromID, toID]
+func (db *DB) getNormalizedExportAttributes(ctx context.Context, fromID, toID int) (map[int][]*ItemAttribute, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT normalized_item_id, COALESCE(NULLIF(attribute_name, ''), attribute_type), attribute_value, COALESCE(unit, '')
+		FROM normalized_item_attributes
+		WHERE normalized_item_id BETWEEN ? AND ?
+		ORDER BY id
+	`, fromID, toID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get normalized export attributes: %w", err)
+	}
+	defer rows.Close()
+
+	attributes := make(map[int][]*ItemAttribute)
+	for rows.Next() {
+		attr := &ItemAttribute{}
+		if err := rows.Scan(&attr.NormalizedItemID, &attr.AttributeName, &attr.AttributeValue, &attr.Unit); err != nil {
+			return nil, fmt.Errorf("failed to scan normalized attribute: %w", err)
+		}
+		attributes[attr.NormalizedItemID] = append(attributes[attr.NormalizedItemID], attr)
+	}
+	return attributes, rows.Err()
+}
+
+// withMetadata дополняет пользовательские метаданные файла служебными, не изменяя исходную карту
+func withMetadata(user, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(user)+len(extra))
+	for key, value := range user {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"httpserver/parquet"
+)
+
+func TestExportCatalogItemsParquet(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("uuid-parquet", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "Номенклатура")
+	if err != nil {
+		t.Fatalf("Failed to add catalog: %v", err)
+	}
+	err = SaveCatalogMetadata(db.conn, &CatalogMetadata{
+		UploadID:    upload.ID,
+		CatalogName: "Номенклатура",
+		Attributes: []CatalogAttributeDefinition{
+			{Name: "Вес", Kind: ConstantKindNumber, Precision: 10, Scale: 3},
+			{Name: "Количество", Kind: ConstantKindNumber, Precision: 10},
+			{Name: "Активен", Kind: ConstantKindBoolean},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SaveCatalogMetadata failed: %v", err)
+	}
+
+	items := []struct{ ref, attrs string }{
+		{"ref-1", "<Вес>1,5</Вес><Количество>10</Количество><Активен>true</Активен><Производитель>Бош</Производитель>"},
+		{"ref-2", "<Вес>не число</Вес><Производитель>Макита</Производитель>"},
+		{"ref-3", ""},
+	}
+	for _, item := range items {
+		if err := db.AddCatalogItem(catalog.ID, item.ref, item.ref, "Дрель", item.attrs, ""); err != nil {
+			t.Fatalf("AddCatalogItem failed: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	progress := 0
+	rows, err := db.ExportCatalogItemsParquet(context.Background(), upload.ID, nil, &buf, ParquetExport{
+		BatchSize: 2,
+		Progress:  func(n int) { progress += n },
+	})
+	if err != nil {
+		t.Fatalf("ExportCatalogItemsParquet failed: %v", err)
+	}
+	if rows != 3 || progress != 3 {
+		t.Fatalf("rows = %d, progress = %d, want 3", rows, progress)
+	}
+
+	file, err := parquet.Read(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parquet.Read failed: %v", err)
+	}
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(file.Metadata["attribute_columns"]), &mapping); err != nil {
+		t.Fatalf("attribute_columns metadata: %v", err)
+	}
+	columns := make(map[string]int, len(file.Columns))
+	for i, column := range file.Columns {
+		if name, ok := mapping[column.Name]; ok {
+			columns[name] = i
+		} else {
+			columns[column.Name] = i
+		}
+	}
+
+	tests := []struct {
+		column   string
+		wantType parquet.Type
+		want     []interface{}
+	}{
+		{"reference", parquet.String, []interface{}{"ref-1", "ref-2", "ref-3"}},
+		{"Вес", parquet.Double, []interface{}{1.5, nil, nil}},
+		{"Количество", parquet.Int64, []interface{}{int64(10), nil, nil}},
+		{"Активен", parquet.Boolean, []interface{}{true, nil, nil}},
+		{"Производитель", parquet.String, []interface{}{"Бош", "Макита", nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			index, ok := columns[tt.column]
+			if !ok {
+				t.Fatalf("column %q not found in %v", tt.column, file.Columns)
+			}
+			if file.Columns[index].Type != tt.wantType {
+				t.Errorf("type = %v, want %v", file.Columns[index].Type, tt.wantType)
+			}
+			for r, want := range tt.want {
+				if got := file.Rows[r][index]; got != want {
+					t.Errorf("row %d = %v, want %v", r, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExportNormalizedDataParquet(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	items := []*NormalizedItem{
+		{SourceReference: "ref-1", SourceName: "Болт М10х50", Code: "001", NormalizedName: "болт", Category: "крепеж", MergedCount: 2, AIConfidence: 0.9},
+		{SourceReference: "ref-2", SourceName: "Кабель ВВГ", Code: "002", NormalizedName: "кабель", Category: "электрика", MergedCount: 1},
+	}
+	attributes := map[string][]*ItemAttribute{
+		"001": {
+			{AttributeType: "dimension", AttributeName: "length", AttributeValue: "50", Unit: "mm"},
+			{AttributeType: "dimension", AttributeName: "length", AttributeValue: "60", Unit: "mm"},
+			{AttributeType: "article_code", AttributeValue: "М10"},
+		},
+		"002": {{AttributeType: "dimension", AttributeName: "length", AttributeValue: "2,5"}},
+	}
+	if _, err := db.InsertNormalizedItemsWithAttributesBatch(items, attributes); err != nil {
+		t.Fatalf("InsertNormalizedItemsWithAttributesBatch failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	rows, err := db.ExportNormalizedDataParquet(context.Background(), &buf, ParquetExport{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("ExportNormalizedDataParquet failed: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("rows = %d, want 2", rows)
+	}
+
+	file, err := parquet.Read(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parquet.Read failed: %v", err)
+	}
+	columns := make(map[string]int, len(file.Columns))
+	for i, column := range file.Columns {
+		columns[column.Name] = i
+	}
+
+	tests := []struct {
+		column   string
+		wantType parquet.Type
+		want     []interface{}
+	}{
+		{"normalized_name", parquet.String, []interface{}{"болт", "кабель"}},
+		{"merged_count", parquet.Int64, []interface{}{int64(2), int64(1)}},
+		{"attr_length", parquet.Double, []interface{}{50.0, 2.5}},
+		{"attr_length_unit", parquet.String, []interface{}{"mm", nil}},
+		{"attr_article_code", parquet.String, []interface{}{"М10", nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column, func(t *testing.T) {
+			index, ok := columns[tt.column]
+			if !ok {
+				t.Fatalf("column %q not found in %v", tt.column, file.Columns)
+			}
+			if file.Columns[index].Type != tt.wantType {
+				t.Errorf("type = %v, want %v", file.Columns[index].Type, tt.wantType)
+			}
+			for r, want := range tt.want {
+				if got := file.Rows[r][index]; got != want {
+					t.Errorf("row %d = %v, want %v", r, got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
 	"testing"
 )
 
@@ -252,3 +255,171 @@ func TestConcurrentAccess(t *testing.T) {
 	t.Skip("Skipping concurrent access test for in-memory SQLite")
 }
 
+func TestReadOnlyPool(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "read_pool.db")
+	db, err := NewDBWithConfig(dbPath, DBConfig{ReadPoolMaxOpenConns: 2})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	if db.ReadOnly() == db {
+		t.Fatal("expected separate read-only view when read pool is configured")
+	}
+
+	if _, err := db.CreateUpload("uuid-read-pool", "8.3", "БухгалтерияПредприятия"); err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	upload, err := db.ReadOnly().GetUploadByUUID("uuid-read-pool")
+	if err != nil {
+		t.Fatalf("Read through read-only pool failed: %v", err)
+	}
+	if upload.ConfigName != "БухгалтерияПредприятия" {
+		t.Errorf("unexpected upload from read pool: %+v", upload)
+	}
+
+	if _, err := db.ReadOnly().Exec("DELETE FROM uploads"); err == nil {
+		t.Error("expected write through read-only pool to fail")
+	}
+
+	writeStats, readStats := db.PoolStats()
+	if readStats == nil || readStats.MaxOpenConnections != 2 {
+		t.Errorf("unexpected read pool stats: %+v", readStats)
+	}
+	if writeStats.MaxOpenConnections != 25 {
+		t.Errorf("unexpected write pool stats: %+v", writeStats)
+	}
+
+	// Для in-memory БД пул чтения не создается
+	memDB, err := NewDBWithConfig(":memory:", DBConfig{ReadPoolMaxOpenConns: 2})
+	if err != nil {
+		t.Fatalf("Failed to create in-memory DB: %v", err)
+	}
+	defer memDB.Close()
+	if memDB.ReadOnly() != memDB {
+		t.Error("expected in-memory DB to use its own pool for reads")
+	}
+}
+
+func TestParseConstantValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		declaredType string
+		raw          string
+		wantKind     string
+		wantValue    interface{}
+	}{
+		{"boolean tag", "Булево", "<boolean>true</boolean>", ConstantKindBoolean, true},
+		{"number with separators", "Число", "<number>1 234,5</number>", ConstantKindNumber, 1234.5},
+		{"date tag", "Дата", "<date>2024-03-01T10:20:30</date>", ConstantKindDate, "2024-03-01T10:20:30"},
+		{"reference", "Ссылка", "<reference>Основная организация</reference>", ConstantKindReference, "Основная организация"},
+		{"undefined", "Объект", "<undefined/>", ConstantKindUndefined, nil},
+		{"untagged by declared type", "Число", "42", ConstantKindNumber, 42.0},
+		{"invalid number falls back to string", "Число", "<number>abc</number>", ConstantKindString, "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, value := ParseConstantValue(tt.declaredType, tt.raw)
+			if kind != tt.wantKind || value != tt.wantValue {
+				t.Errorf("ParseConstantValue(%q, %q) = (%q, %v), want (%q, %v)", tt.declaredType, tt.raw, kind, value, tt.wantKind, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestConstantTypedValueRoundTrip(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("uuid-constants", "8.3", "БухгалтерияПредприятия")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	if err := db.AddConstant(upload.ID, "ИспользоватьВалюту", "", "Булево", "<boolean>false</boolean>"); err != nil {
+		t.Fatalf("AddConstant failed: %v", err)
+	}
+	if err := db.AddConstant(upload.ID, "СтавкаНДС", "", "Число", "<number>12</number>"); err != nil {
+		t.Fatalf("AddConstant failed: %v", err)
+	}
+
+	constants, err := db.GetConstantsByUpload(upload.ID)
+	if err != nil {
+		t.Fatalf("GetConstantsByUpload failed: %v", err)
+	}
+	if len(constants) != 2 {
+		t.Fatalf("expected 2 constants, got %d", len(constants))
+	}
+	if constants[0].ValueKind != ConstantKindBoolean || constants[0].TypedValue != false {
+		t.Errorf("unexpected boolean constant: %q %v", constants[0].ValueKind, constants[0].TypedValue)
+	}
+	if constants[1].ValueKind != ConstantKindNumber || constants[1].TypedValue != 12.0 {
+		t.Errorf("unexpected number constant: %q %v", constants[1].ValueKind, constants[1].TypedValue)
+	}
+	if constants[1].Value != "<number>12</number>" {
+		t.Errorf("raw value must be preserved, got %q", constants[1].Value)
+	}
+}
+
+func TestCatalogItemsContextCancellation(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("ctx-uuid", "8.3", "test-config")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "TestCatalog", "test_catalog")
+	if err != nil {
+		t.Fatalf("Failed to create catalog: %v", err)
+	}
+	for _, code := range []string{"1", "2", "3", "4", "5"} {
+		if err := db.AddCatalogItem(catalog.ID, "ref"+code, code, "Item "+code, "", ""); err != nil {
+			t.Fatalf("Failed to insert catalog item: %v", err)
+		}
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("query", func(t *testing.T) {
+		if _, _, err := db.GetCatalogItemsByUploadContext(cancelled, upload.ID, nil, 0, 10); !errors.Is(err, context.Canceled) {
+			t.Errorf("GetCatalogItemsByUploadContext() error = %v, want context.Canceled", err)
+		}
+		if _, err := db.GetConstantsByUploadContext(cancelled, upload.ID); !errors.Is(err, context.Canceled) {
+			t.Errorf("GetConstantsByUploadContext() error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("stream stops after cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		batches := 0
+		err := db.StreamCatalogItemsContext(ctx, upload.ID, nil, 2, func(items []*CatalogItem) error {
+			batches++
+			cancel()
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) || batches != 1 {
+			t.Errorf("StreamCatalogItemsContext() error = %v, batches = %d", err, batches)
+		}
+	})
+
+	t.Run("stream without cancel", func(t *testing.T) {
+		read := 0
+		err := db.StreamCatalogItems(upload.ID, nil, 2, func(items []*CatalogItem) error {
+			read += len(items)
+			return nil
+		})
+		if err != nil || read != 5 {
+			t.Errorf("StreamCatalogItems() error = %v, read = %d", err, read)
+		}
+	})
+}
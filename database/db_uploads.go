@@ -0,0 +1,194 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Допустимые поля сортировки списка выгрузок (ключ - параметр API, значение - колонка)
+var uploadSortColumns = map[string]string{
+	"started_at":  "started_at",
+	"status":      "status",
+	"config_name": "config_name",
+}
+
+// sqliteTimestampLayout формат CURRENT_TIMESTAMP в SQLite (UTC), используется для сравнения дат
+const sqliteTimestampLayout = "2006-01-02 15:04:05"
+
+// UploadListFilter параметры фильтрации, сортировки и пагинации списка выгрузок
+type UploadListFilter struct {
+	Status      string
+	ClientID    *int
+	ProjectID   *int
+	DatabaseID  *int
+	ConfigName  string // Поиск по подстроке
+	StartedFrom *time.Time
+	StartedTo   *time.Time
+	SortBy      string // started_at, status, config_name
+	SortDesc    bool
+	Limit       int
+	Offset      int
+}
+
+// rowScanner общий интерфейс для *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// uploadColumns колонки выгрузки в порядке, ожидаемом scanUpload
+const uploadColumns = `id, upload_uuid, started_at, completed_at, status,
+		       version_1c, config_name, total_constants, total_catalogs, total_items,
+		       database_id, client_id, project_id, computer_name, user_name, config_version,
+		       iteration_number, iteration_label, programmer_name, upload_purpose, parent_upload_id`
+
+// scanUpload считывает выгрузку из строки результата запроса с колонками uploadColumns
+func scanUpload(scanner rowScanner) (*Upload, error) {
+	upload := &Upload{}
+	var databaseID, clientID, projectID, parentUploadID sql.NullInt64
+	var completedAt sql.NullTime
+	var version1C, configName, computerName, userName, configVersion sql.NullString
+	var iterationLabel, programmerName, uploadPurpose sql.NullString
+	var iterationNumber sql.NullInt64
+
+	err := scanner.Scan(
+		&upload.ID, &upload.UploadUUID, &upload.StartedAt, &completedAt,
+		&upload.Status, &version1C, &configName,
+		&upload.TotalConstants, &upload.TotalCatalogs, &upload.TotalItems,
+		&databaseID, &clientID, &projectID,
+		&computerName, &userName, &configVersion,
+		&iterationNumber, &iterationLabel, &programmerName, &uploadPurpose, &parentUploadID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	upload.Version1C = version1C.String
+	upload.ConfigName = configName.String
+	upload.ComputerName = computerName.String
+	upload.UserName = userName.String
+	upload.ConfigVersion = configVersion.String
+	upload.IterationNumber = int(iterationNumber.Int64)
+	upload.IterationLabel = iterationLabel.String
+	upload.ProgrammerName = programmerName.String
+	upload.UploadPurpose = uploadPurpose.String
+
+	if databaseID.Valid {
+		id := int(databaseID.Int64)
+		upload.DatabaseID = &id
+	}
+	if clientID.Valid {
+		id := int(clientID.Int64)
+		upload.ClientID = &id
+	}
+	if projectID.Valid {
+		id := int(projectID.Int64)
+		upload.ProjectID = &id
+	}
+	if parentUploadID.Valid {
+		id := int(parentUploadID.Int64)
+		upload.ParentUploadID = &id
+	}
+	if completedAt.Valid {
+		upload.CompletedAt = &completedAt.Time
+	}
+
+	return upload, nil
+}
+
+// buildUploadFilterClause формирует WHERE-условие для фильтра выгрузок
+func buildUploadFilterClause(filter UploadListFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.ClientID != nil {
+		conditions = append(conditions, "client_id = ?")
+		args = append(args, *filter.ClientID)
+	}
+	if filter.ProjectID != nil {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, *filter.ProjectID)
+	}
+	if filter.DatabaseID != nil {
+		conditions = append(conditions, "database_id = ?")
+		args = append(args, *filter.DatabaseID)
+	}
+	if filter.ConfigName != "" {
+		conditions = append(conditions, "config_name LIKE ?")
+		args = append(args, "%"+filter.ConfigName+"%")
+	}
+	if filter.StartedFrom != nil {
+		conditions = append(conditions, "started_at >= ?")
+		args = append(args, filter.StartedFrom.UTC().Format(sqliteTimestampLayout))
+	}
+	if filter.StartedTo != nil {
+		conditions = append(conditions, "started_at <= ?")
+		args = append(args, filter.StartedTo.UTC().Format(sqliteTimestampLayout))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// GetUploadsFiltered получает страницу выгрузок с фильтрацией и сортировкой на стороне SQL
+// Возвращает выгрузки страницы и общее количество выгрузок, подходящих под фильтр
+func (db *DB) GetUploadsFiltered(filter UploadListFilter) ([]*Upload, int, error) {
+	whereClause, args := buildUploadFilterClause(filter)
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM uploads " + whereClause
+	if err := db.conn.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count uploads: %w", err)
+	}
+
+	sortColumn, ok := uploadSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = "started_at"
+	}
+	direction := "ASC"
+	if filter.SortDesc {
+		direction = "DESC"
+	}
+
+	// id добавлен для стабильного порядка при одинаковых значениях сортировки
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM uploads
+		%s
+		ORDER BY %s %s, id %s
+	`, uploadColumns, whereClause, sortColumn, direction, direction)
+
+	queryArgs := append([]interface{}{}, args...)
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		queryArgs = append(queryArgs, filter.Limit, filter.Offset)
+	}
+
+	rows, err := db.conn.Query(query, queryArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get uploads: %w", err)
+	}
+	defer rows.Close()
+
+	uploads := make([]*Upload, 0)
+	for rows.Next() {
+		upload, err := scanUpload(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan upload: %w", err)
+		}
+		uploads = append(uploads, upload)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating uploads: %w", err)
+	}
+
+	return uploads, total, nil
+}
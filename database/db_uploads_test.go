@@ -0,0 +1,180 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetUploadsFiltered(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	configs := []string{"БухгалтерияПредприятия", "УправлениеТорговлей", "БухгалтерияКазахстана"}
+	for i, configName := range configs {
+		upload, err := db.CreateUpload("uuid-"+configName, "8.3", configName)
+		if err != nil {
+			t.Fatalf("Failed to create upload: %v", err)
+		}
+		if i == 0 {
+			if err := db.CompleteUpload(upload.ID); err != nil {
+				t.Fatalf("Failed to complete upload: %v", err)
+			}
+		}
+	}
+
+	uploads, total, err := db.GetUploadsFiltered(UploadListFilter{ConfigName: "Бухгалтерия", SortBy: "config_name"})
+	if err != nil {
+		t.Fatalf("GetUploadsFiltered failed: %v", err)
+	}
+	if total != 2 || len(uploads) != 2 {
+		t.Fatalf("expected 2 uploads, got total=%d len=%d", total, len(uploads))
+	}
+	if uploads[0].ConfigName != "БухгалтерияКазахстана" {
+		t.Errorf("expected ascending sort by config_name, got %s first", uploads[0].ConfigName)
+	}
+
+	uploads, total, err = db.GetUploadsFiltered(UploadListFilter{Status: "completed"})
+	if err != nil {
+		t.Fatalf("GetUploadsFiltered failed: %v", err)
+	}
+	if total != 1 || uploads[0].ConfigName != "БухгалтерияПредприятия" {
+		t.Errorf("expected only completed upload, got total=%d", total)
+	}
+
+	uploads, total, err = db.GetUploadsFiltered(UploadListFilter{Limit: 1, Offset: 1, SortBy: "config_name", SortDesc: true})
+	if err != nil {
+		t.Fatalf("GetUploadsFiltered failed: %v", err)
+	}
+	if total != 3 || len(uploads) != 1 {
+		t.Fatalf("expected page of 1 from 3, got total=%d len=%d", total, len(uploads))
+	}
+	if uploads[0].ConfigName != "БухгалтерияПредприятия" {
+		t.Errorf("unexpected upload on second page: %s", uploads[0].ConfigName)
+	}
+}
+
+func TestUploadDailyRollups(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	serviceDB, err := NewServiceDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer serviceDB.Close()
+
+	upload, err := db.CreateUpload("uuid-rollup", "8.3", "БухгалтерияПредприятия")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	if _, err := db.Exec("UPDATE uploads SET client_id = 1, project_id = 2 WHERE id = ?", upload.ID); err != nil {
+		t.Fatalf("Failed to set client: %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "Номенклатура")
+	if err != nil {
+		t.Fatalf("Failed to add catalog: %v", err)
+	}
+	for _, ref := range []string{"ref-1", "ref-2"} {
+		if err := db.AddCatalogItem(catalog.ID, ref, ref, "Товар "+ref, nil, nil); err != nil {
+			t.Fatalf("Failed to add catalog item: %v", err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code)
+		VALUES ('ref-1', 'Товар ref-1', 'ref-1', 'товар', 'прочее', '01.11')`); err != nil {
+		t.Fatalf("Failed to insert normalized item: %v", err)
+	}
+
+	rollups, err := db.ComputeUploadDailyRollups(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("ComputeUploadDailyRollups failed: %v", err)
+	}
+	if len(rollups) != 1 {
+		t.Fatalf("expected 1 rollup, got %d", len(rollups))
+	}
+	rollup := rollups[0]
+	if rollup.ClientID != 1 || rollup.ProjectID != 2 || rollup.UploadsCount != 1 {
+		t.Errorf("unexpected rollup key/counts: %+v", rollup)
+	}
+	if rollup.ItemsIngested != 2 || rollup.NormalizedItems != 1 || rollup.ClassifiedItems != 1 {
+		t.Errorf("unexpected coverage counts: %+v", rollup)
+	}
+
+	// Повторное сохранение не должно дублировать строки
+	for i := 0; i < 2; i++ {
+		if err := serviceDB.SaveUploadDailyRollups(rollups); err != nil {
+			t.Fatalf("SaveUploadDailyRollups failed: %v", err)
+		}
+	}
+
+	clientID := 1
+	points, err := serviceDB.GetUploadRollupSeries(UploadRollupFilter{ClientID: &clientID})
+	if err != nil {
+		t.Fatalf("GetUploadRollupSeries failed: %v", err)
+	}
+	if len(points) != 1 || points[0].UploadsCount != 1 {
+		t.Fatalf("unexpected series: %+v", points)
+	}
+	if points[0].NormalizationCoverage != 0.5 {
+		t.Errorf("expected normalization coverage 0.5, got %f", points[0].NormalizationCoverage)
+	}
+
+	otherClient := 99
+	points, err = serviceDB.GetUploadRollupSeries(UploadRollupFilter{ClientID: &otherClient})
+	if err != nil {
+		t.Fatalf("GetUploadRollupSeries failed: %v", err)
+	}
+	if len(points) != 0 {
+		t.Errorf("expected empty series for other client, got %d points", len(points))
+	}
+}
+
+func TestUploadLookupCache(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("uuid-cache", "8.3", "БухгалтерияПредприятия")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	if _, err := db.GetUploadByUUID("uuid-cache"); err != nil {
+		t.Fatalf("GetUploadByUUID failed: %v", err)
+	}
+	before := uploadLookupCache.stats()
+
+	// Счетчики в кеше обновляются при записи, повторное чтение обслуживается из кеша
+	if err := db.AddConstant(upload.ID, "Константа", "", "Строка", "1"); err != nil {
+		t.Fatalf("AddConstant failed: %v", err)
+	}
+	cached, err := db.GetUploadByUUID("uuid-cache")
+	if err != nil {
+		t.Fatalf("GetUploadByUUID failed: %v", err)
+	}
+	if cached.TotalConstants != 1 {
+		t.Errorf("expected 1 constant in cached upload, got %d", cached.TotalConstants)
+	}
+	if after := uploadLookupCache.stats(); after.Hits != before.Hits+1 {
+		t.Errorf("expected cache hit, hits %d -> %d", before.Hits, after.Hits)
+	}
+
+	// Изменение статуса инвалидирует запись
+	if err := db.CompleteUpload(upload.ID); err != nil {
+		t.Fatalf("CompleteUpload failed: %v", err)
+	}
+	completed, err := db.GetUploadByUUID("uuid-cache")
+	if err != nil {
+		t.Fatalf("GetUploadByUUID failed: %v", err)
+	}
+	if completed.Status != "completed" || completed.CompletedAt == nil {
+		t.Errorf("expected completed upload after invalidation, got status %q", completed.Status)
+	}
+}
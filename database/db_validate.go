@@ -0,0 +1,72 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// mainDatabaseTables таблицы, обязательные для непустой основной БД выгрузок
+var mainDatabaseTables = []string{"uploads", "catalogs", "catalog_items"}
+
+// ValidateDatabaseFile проверяет файл перед подключением в качестве основной БД:
+// файл должен быть целой базой SQLite, а непустая база - содержать таблицы выгрузок.
+// Пустая база допустима, ее схема будет создана при открытии. Файл открывается только для чтения
+func ValidateDatabaseFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("database file not found")
+		}
+		return fmt.Errorf("failed to stat database file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("database path is a directory")
+	}
+
+	conn, err := sql.Open("sqlite3", readOnlyDSN(path))
+	if err != nil {
+		return fmt.Errorf("failed to open database file: %w", err)
+	}
+	defer conn.Close()
+
+	var check string
+	if err := conn.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return fmt.Errorf("file is not a valid SQLite database: %w", err)
+	}
+	if check != "ok" {
+		return fmt.Errorf("database integrity check failed: %s", check)
+	}
+
+	rows, err := conn.Query("SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return fmt.Errorf("failed to read database schema: %w", err)
+	}
+	defer rows.Close()
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to read database schema: %w", err)
+		}
+		tables[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read database schema: %w", err)
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	var missing []string
+	for _, table := range mainDatabaseTables {
+		if !tables[table] {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("database schema is not compatible, missing tables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateDatabaseFile(t *testing.T) {
+	dir := t.TempDir()
+
+	mainPath := filepath.Join(dir, "main.db")
+	db, err := NewDB(mainPath)
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	db.Close()
+
+	servicePath := filepath.Join(dir, "service.db")
+	serviceDB, err := NewServiceDB(servicePath)
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	serviceDB.Close()
+
+	emptyPath := filepath.Join(dir, "empty.db")
+	garbagePath := filepath.Join(dir, "garbage.db")
+	os.WriteFile(emptyPath, nil, 0644)
+	os.WriteFile(garbagePath, []byte("SQLite format 2\x00 definitely not a database page"), 0644)
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"main database", mainPath, false},
+		{"empty file", emptyPath, false},
+		{"service database", servicePath, true},
+		{"not a database", garbagePath, true},
+		{"missing file", filepath.Join(dir, "missing.db"), true},
+		{"directory", dir, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDatabaseFile(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDatabaseFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -285,3 +285,61 @@ func TestRevertToStage(t *testing.T) {
 	}
 }
 
+
+func TestNormalizedItemLineage(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("lineage-uuid", "8.3", "test-config")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "")
+	if err != nil {
+		t.Fatalf("Failed to create catalog: %v", err)
+	}
+	if err := db.AddCatalogItem(catalog.ID, "ref1", "code1", "Болт М10", "", ""); err != nil {
+		t.Fatalf("Failed to create catalog item: %v", err)
+	}
+
+	var sourceID int
+	if err := db.QueryRow("SELECT id FROM catalog_items WHERE reference = ?", "ref1").Scan(&sourceID); err != nil {
+		t.Fatalf("Failed to get catalog item ID: %v", err)
+	}
+	if _, err := db.CreateNormalizationSession(sourceID, "Болт М10"); err != nil {
+		t.Fatalf("Failed to create normalization session: %v", err)
+	}
+
+	codeToID, err := db.InsertNormalizedItemsBatch([]*NormalizedItem{{
+		SourceReference: "ref1",
+		SourceName:      "Болт М10",
+		Code:            "code1",
+		NormalizedName:  "болт",
+		Category:        "крепеж",
+		MergedCount:     1,
+		Lineage: []*LineageRecord{
+			NewLineageRecord(LineageEventSource, "catalog_items", sourceID, nil),
+			NewLineageRecord(LineageEventRules, "name_normalizer", sourceID, map[string]interface{}{"normalized_name": "болт"}),
+		},
+	}})
+	if err != nil {
+		t.Fatalf("InsertNormalizedItemsBatch failed: %v", err)
+	}
+
+	lineage, err := db.GetNormalizedItemLineage(codeToID["code1"])
+	if err != nil {
+		t.Fatalf("GetNormalizedItemLineage failed: %v", err)
+	}
+	if len(lineage.Events) != 2 {
+		t.Errorf("expected 2 lineage events, got %d", len(lineage.Events))
+	}
+	if len(lineage.Sources) != 1 || lineage.Sources[0].UploadUUID != "lineage-uuid" {
+		t.Errorf("expected source from upload lineage-uuid, got %+v", lineage.Sources)
+	}
+	if len(lineage.Sessions) != 1 {
+		t.Errorf("expected 1 normalization session, got %d", len(lineage.Sessions))
+	}
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DuplicateEdge пара похожих элементов, найденная нечетким сопоставителем.
+// Элементы одного кластера связаны с его опорным элементом (ClusterKey)
+type DuplicateEdge struct {
+	ClusterKey      string  `json:"cluster_key"`
+	SourceReference string  `json:"source_reference"`
+	SourceName      string  `json:"source_name"`
+	SourceCategory  string  `json:"source_category,omitempty"`
+	TargetReference string  `json:"target_reference"`
+	TargetName      string  `json:"target_name"`
+	TargetCategory  string  `json:"target_category,omitempty"`
+	Similarity      float64 `json:"similarity"`
+}
+
+// DuplicateEdgeFilter фильтр связей дублей выгрузки; пустые значения не ограничивают выборку
+type DuplicateEdgeFilter struct {
+	UploadID      int
+	Category      string
+	MinSimilarity float64
+}
+
+// CreateDuplicateEdgesTable создает таблицу связей нечетких дублей для построения графа
+func CreateDuplicateEdgesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS duplicate_edges (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			upload_id INTEGER NOT NULL,
+			database_id INTEGER NOT NULL DEFAULT 0,
+			cluster_key TEXT NOT NULL,
+			source_reference TEXT NOT NULL,
+			source_name TEXT,
+			source_category TEXT,
+			target_reference TEXT NOT NULL,
+			target_name TEXT,
+			target_category TEXT,
+			similarity REAL NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(upload_id, source_reference, target_reference)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_duplicate_edges_upload ON duplicate_edges(upload_id, cluster_key);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create duplicate_edges table: %w", err)
+	}
+	return nil
+}
+
+// ReplaceDuplicateEdges заменяет связи дублей выгрузки результатами последнего поиска
+func (db *DB) ReplaceDuplicateEdges(uploadID, databaseID int, edges []DuplicateEdge) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_edges WHERE upload_id = ?`, uploadID); err != nil {
+		return fmt.Errorf("failed to delete duplicate edges: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO duplicate_edges
+		(upload_id, database_id, cluster_key, source_reference, source_name, source_category,
+		 target_reference, target_name, target_category, similarity)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare duplicate edge insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, edge := range edges {
+		if _, err := stmt.Exec(uploadID, databaseID, edge.ClusterKey, edge.SourceReference, edge.SourceName, edge.SourceCategory,
+			edge.TargetReference, edge.TargetName, edge.TargetCategory, edge.Similarity); err != nil {
+			return fmt.Errorf("failed to insert duplicate edge: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetDuplicateEdges возвращает связи дублей выгрузки, сгруппированные по кластерам.
+// Фильтр по категории оставляет кластеры, в которых хотя бы один элемент относится к категории
+func (db *DB) GetDuplicateEdges(filter DuplicateEdgeFilter) ([]DuplicateEdge, error) {
+	query := `
+		SELECT cluster_key, source_reference, COALESCE(source_name, ''), COALESCE(source_category, ''),
+		       target_reference, COALESCE(target_name, ''), COALESCE(target_category, ''), similarity
+		FROM duplicate_edges
+		WHERE upload_id = ? AND similarity >= ?`
+	args := []interface{}{filter.UploadID, filter.MinSimilarity}
+	if filter.Category != "" {
+		query += ` AND cluster_key IN (
+			SELECT cluster_key FROM duplicate_edges
+			WHERE upload_id = ? AND (source_category = ? OR target_category = ?)
+		)`
+		args = append(args, filter.UploadID, filter.Category, filter.Category)
+	}
+	query += ` ORDER BY cluster_key, similarity DESC, target_reference`
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duplicate edges: %w", err)
+	}
+	defer rows.Close()
+
+	edges := []DuplicateEdge{}
+	for rows.Next() {
+		var edge DuplicateEdge
+		if err := rows.Scan(&edge.ClusterKey, &edge.SourceReference, &edge.SourceName, &edge.SourceCategory,
+			&edge.TargetReference, &edge.TargetName, &edge.TargetCategory, &edge.Similarity); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate edge: %w", err)
+		}
+		edges = append(edges, edge)
+	}
+	return edges, rows.Err()
+}
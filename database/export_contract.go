@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Правила контракта данных, проверяемые перед выгрузкой в 1С/ERP
+const (
+	ContractRuleMissingRequired = "missing_required"
+	ContractRuleDuplicateCode   = "duplicate_code"
+	ContractRuleUnknownKpved    = "unknown_kpved"
+	ContractRuleNameTooLong     = "name_too_long"
+	ContractRuleCodeTooLong     = "code_too_long"
+)
+
+// Поля записей, на которые ссылается контракт
+const (
+	ContractFieldReference = "reference"
+	ContractFieldCode      = "code"
+	ContractFieldName      = "name"
+	ContractFieldCategory  = "category"   // Только нормализованные данные
+	ContractFieldKpvedCode = "kpved_code" // Только нормализованные данные
+)
+
+// Источники проверяемых записей
+const (
+	ContractSourceCatalogItems   = "catalog_items"
+	ContractSourceNormalizedData = "normalized_data"
+)
+
+// MaxContractViolations сколько нарушений хранится в отчете; счетчики ведутся по всем
+const MaxContractViolations = 10000
+
+// DefaultContractMaxNameLength длина наименования справочника 1С по умолчанию
+const DefaultContractMaxNameLength = 100
+
+// ExportContract требования принимающей учетной системы к выгружаемым записям
+type ExportContract struct {
+	RequiredFields []string // Поля, которые должны быть заполнены (поля, которых нет у источника, не проверяются)
+	MaxNameLength  int      // 0 - не ограничено
+	MaxCodeLength  int      // 0 - не ограничено
+	// Коды действующей версии классификатора КПВЭД; nil - коды не проверяются
+	KpvedCodes map[string]bool
+}
+
+// DefaultExportContract контракт по умолчанию: заполнены ссылка, код и наименование, наименование не длиннее 100 символов
+func DefaultExportContract() ExportContract {
+	return ExportContract{
+		RequiredFields: []string{ContractFieldReference, ContractFieldCode, ContractFieldName},
+		MaxNameLength:  DefaultContractMaxNameLength,
+	}
+}
+
+// ValidateContractFields проверяет имена обязательных полей
+func ValidateContractFields(fields []string) error {
+	for _, field := range fields {
+		switch field {
+		case ContractFieldReference, ContractFieldCode, ContractFieldName, ContractFieldCategory, ContractFieldKpvedCode:
+		default:
+			return fmt.Errorf("unknown contract field: %s", field)
+		}
+	}
+	return nil
+}
+
+// ContractViolation нарушение контракта записью
+type ContractViolation struct {
+	Source    string `json:"source"`
+	Scope     string `json:"scope,omitempty"` // Справочник, в пределах которого код должен быть уникален
+	ItemID    int    `json:"item_id"`
+	Reference string `json:"reference,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Field     string `json:"field"`
+	Rule      string `json:"rule"`
+	Value     string `json:"value,omitempty"`
+	Message   string `json:"message"`
+}
+
+// ContractReport результат проверки контракта
+type ContractReport struct {
+	Checked    int                 `json:"checked"`
+	Violations int                 `json:"violations"`
+	ByRule     map[string]int      `json:"by_rule"`
+	Items      []ContractViolation `json:"items"`
+	Truncated  bool                `json:"truncated"` // Нарушений больше, чем MaxContractViolations
+}
+
+// NewContractReport создает пустой отчет
+func NewContractReport() *ContractReport {
+	return &ContractReport{ByRule: make(map[string]int), Items: make([]ContractViolation, 0)}
+}
+
+func (r *ContractReport) add(violation ContractViolation) {
+	r.Violations++
+	r.ByRule[violation.Rule]++
+	if len(r.Items) >= MaxContractViolations {
+		r.Truncated = true
+		return
+	}
+	r.Items = append(r.Items, violation)
+}
+
+// contractRecord запись в виде, общем для всех источников
+type contractRecord struct {
+	source string
+	scope  string
+	id     int
+	fields map[string]string
+}
+
+// contractChecker проверяет записи одного источника по контракту
+type contractChecker struct {
+	contract ExportContract
+	report   *ContractReport
+	codes    map[string]string // scope + код -> ссылка первой записи с этим кодом
+}
+
+func newContractChecker(contract ExportContract, report *ContractReport) *contractChecker {
+	return &contractChecker{contract: contract, report: report, codes: make(map[string]string)}
+}
+
+func (c *contractChecker) check(record contractRecord) {
+	c.report.Checked++
+	reference, code := record.fields[ContractFieldReference], record.fields[ContractFieldCode]
+	violation := func(field, rule, value, message string) {
+		c.report.add(ContractViolation{
+			Source: record.source, Scope: record.scope, ItemID: record.id, Reference: reference, Code: code,
+			Field: field, Rule: rule, Value: value, Message: message,
+		})
+	}
+
+	for _, field := range c.contract.RequiredFields {
+		if value, ok := record.fields[field]; ok && strings.TrimSpace(value) == "" {
+			violation(field, ContractRuleMissingRequired, "", "required field is empty")
+		}
+	}
+
+	if code = strings.TrimSpace(code); code != "" {
+		key := record.scope + "\x00" + code
+		if first, exists := c.codes[key]; exists {
+			violation(ContractFieldCode, ContractRuleDuplicateCode, code, fmt.Sprintf("code is already used by %s", first))
+		} else {
+			c.codes[key] = reference
+		}
+		if c.contract.MaxCodeLength > 0 && utf8.RuneCountInString(code) > c.contract.MaxCodeLength {
+			violation(ContractFieldCode, ContractRuleCodeTooLong, code,
+				fmt.Sprintf("code length %d exceeds %d", utf8.RuneCountInString(code), c.contract.MaxCodeLength))
+		}
+	}
+
+	if name := record.fields[ContractFieldName]; c.contract.MaxNameLength > 0 && utf8.RuneCountInString(name) > c.contract.MaxNameLength {
+		violation(ContractFieldName, ContractRuleNameTooLong, name,
+			fmt.Sprintf("name length %d exceeds %d", utf8.RuneCountInString(name), c.contract.MaxNameLength))
+	}
+
+	if kpved := strings.TrimSpace(record.fields[ContractFieldKpvedCode]); kpved != "" && c.contract.KpvedCodes != nil && !c.contract.KpvedCodes[kpved] {
+		violation(ContractFieldKpvedCode, ContractRuleUnknownKpved, kpved, "KPVED code is not present in the active classifier")
+	}
+}
+
+// ValidateCatalogItemsContract проверяет элементы справочников выгрузки по контракту.
+// Уникальность кода проверяется в пределах справочника
+func (db *DB) ValidateCatalogItemsContract(ctx context.Context, uploadID int, catalogNames []string, contract ExportContract, report *ContractReport) error {
+	checker := newContractChecker(contract, report)
+	return db.StreamCatalogItemsContext(ctx, uploadID, catalogNames, defaultExportBatchSize, func(items []*CatalogItem) error {
+		for _, item := range items {
+			checker.check(contractRecord{
+				source: ContractSourceCatalogItems,
+				scope:  item.CatalogName,
+				id:     item.ID,
+				fields: map[string]string{
+					ContractFieldReference: item.Reference,
+					ContractFieldCode:      item.Code,
+					ContractFieldName:      item.Name,
+				},
+			})
+		}
+		return nil
+	})
+}
+
+// ValidateNormalizedContract проверяет нормализованные записи по контракту: ссылка и наименование
+// берутся нормализованные, код КПВЭД сверяется с действующим классификатором
+func (db *DB) ValidateNormalizedContract(ctx context.Context, contract ExportContract, report *ContractReport) error {
+	checker := newContractChecker(contract, report)
+	lastID := 0
+	for {
+		items, err := db.getNormalizedExportBatch(ctx, lastID, defaultExportBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			checker.check(contractRecord{
+				source: ContractSourceNormalizedData,
+				id:     item.ID,
+				fields: map[string]string{
+					ContractFieldReference: item.NormalizedReference,
+					ContractFieldCode:      item.Code,
+					ContractFieldName:      item.NormalizedName,
+					ContractFieldCategory:  item.Category,
+					ContractFieldKpvedCode: item.KpvedCode,
+				},
+			})
+		}
+		if len(items) < defaultExportBatchSize {
+			return nil
+		}
+		lastID = items[len(items)-1].ID
+	}
+}
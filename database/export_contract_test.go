@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateCatalogItemsContract(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, _ := db.CreateUpload("uuid-contract", "8.3", "УправлениеТорговлей")
+	goods, _ := db.AddCatalog(upload.ID, "Номенклатура", "")
+	partners, _ := db.AddCatalog(upload.ID, "Контрагенты", "")
+	db.AddCatalogItem(goods.ID, "ref-1", "001", "Болт", "", "")
+	db.AddCatalogItem(goods.ID, "ref-2", "001", "Гайка", "", "")
+	db.AddCatalogItem(goods.ID, "ref-3", "003", "", "", "")
+	db.AddCatalogItem(goods.ID, "ref-4", "004", strings.Repeat("Ш", 101), "", "")
+	// Тот же код в другом справочнике допустим
+	db.AddCatalogItem(partners.ID, "ref-5", "001", "ТОО Ромашка", "", "")
+
+	report := NewContractReport()
+	if err := db.ValidateCatalogItemsContract(context.Background(), upload.ID, nil, DefaultExportContract(), report); err != nil {
+		t.Fatalf("ValidateCatalogItemsContract failed: %v", err)
+	}
+	if report.Checked != 5 || report.Violations != 3 {
+		t.Fatalf("report = %+v, want 5 checked and 3 violations", report)
+	}
+	for _, rule := range []string{ContractRuleDuplicateCode, ContractRuleMissingRequired, ContractRuleNameTooLong} {
+		if report.ByRule[rule] != 1 {
+			t.Errorf("by_rule[%s] = %d, want 1", rule, report.ByRule[rule])
+		}
+	}
+	if violation := report.Items[0]; violation.Reference != "ref-2" || !strings.Contains(violation.Message, "ref-1") {
+		t.Errorf("duplicate violation = %+v", violation)
+	}
+}
+
+func TestValidateNormalizedContract(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	items := []*NormalizedItem{
+		{SourceReference: "ref-1", Code: "001", NormalizedName: "болт", NormalizedReference: "болт м10", Category: "крепеж", KpvedCode: "25.94.11"},
+		{SourceReference: "ref-2", Code: "002", NormalizedName: "гайка", NormalizedReference: "гайка м10", KpvedCode: "99.99"},
+		{SourceReference: "ref-3", Code: "ABCDEFGHIJKL", NormalizedName: "шайба", NormalizedReference: "шайба", Category: "крепеж"},
+	}
+	if _, err := db.InsertNormalizedItemsBatch(items); err != nil {
+		t.Fatalf("InsertNormalizedItemsBatch failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		contract ExportContract
+		want     map[string]int
+	}{
+		{"default contract", DefaultExportContract(), map[string]int{}},
+		{"kpved classifier", ExportContract{KpvedCodes: map[string]bool{"25.94.11": true}}, map[string]int{ContractRuleUnknownKpved: 1}},
+		{"category and code length", ExportContract{RequiredFields: []string{ContractFieldCategory}, MaxCodeLength: 11},
+			map[string]int{ContractRuleMissingRequired: 1, ContractRuleCodeTooLong: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewContractReport()
+			if err := db.ValidateNormalizedContract(context.Background(), tt.contract, report); err != nil {
+				t.Fatalf("ValidateNormalizedContract failed: %v", err)
+			}
+			if report.Checked != 3 || len(report.ByRule) != len(tt.want) {
+				t.Fatalf("report = %+v, want %v", report, tt.want)
+			}
+			for rule, count := range tt.want {
+				if report.ByRule[rule] != count {
+					t.Errorf("by_rule[%s] = %d, want %d", rule, report.ByRule[rule], count)
+				}
+			}
+		})
+	}
+
+	if err := ValidateContractFields([]string{"code", "price"}); err == nil {
+		t.Error("ValidateContractFields must reject unknown field")
+	}
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExportCursor отметка последней успешной обратной выгрузки в приемник: позиция журнала
+// изменений normalized_data и время выгрузки. Используется отбором "только измененные"
+type ExportCursor struct {
+	UploadUUID string    `json:"upload_uuid"`
+	Target     string    `json:"target"`
+	ChangeSeq  int64     `json:"change_seq"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// CreateExportCursorsTable создает таблицу отметок обратных выгрузок
+func CreateExportCursorsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS export_cursors (
+			upload_uuid TEXT NOT NULL,
+			target TEXT NOT NULL,
+			change_seq INTEGER NOT NULL DEFAULT 0,
+			exported_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (upload_uuid, target)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create export_cursors table: %w", err)
+	}
+	return nil
+}
+
+// GetExportCursor возвращает отметку предыдущей выгрузки в приемник; nil, если выгрузок еще не было
+func (db *DB) GetExportCursor(uploadUUID, target string) (*ExportCursor, error) {
+	cursor := &ExportCursor{UploadUUID: uploadUUID, Target: target}
+	err := db.conn.QueryRow(`
+		SELECT change_seq, exported_at FROM export_cursors WHERE upload_uuid = ? AND target = ?
+	`, uploadUUID, target).Scan(&cursor.ChangeSeq, &cursor.ExportedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// SaveExportCursor сохраняет отметку успешной выгрузки в приемник
+func (db *DB) SaveExportCursor(cursor ExportCursor) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO export_cursors (upload_uuid, target, change_seq, exported_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(upload_uuid, target) DO UPDATE SET change_seq = excluded.change_seq, exported_at = excluded.exported_at
+	`, cursor.UploadUUID, cursor.Target, cursor.ChangeSeq, cursor.ExportedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save export cursor: %w", err)
+	}
+	return nil
+}
+
+// GetNormalizedSourceReferences возвращает ссылки исходных записей, попавших в normalized_data;
+// onlyClassified - только записи с кодом КПВЭД
+func (db *DB) GetNormalizedSourceReferences(onlyClassified bool) (map[string]bool, error) {
+	query := `SELECT DISTINCT source_reference FROM normalized_data WHERE source_reference IS NOT NULL AND source_reference != ''`
+	if onlyClassified {
+		query += ` AND kpved_code IS NOT NULL AND TRIM(kpved_code) != ''`
+	}
+	return db.querySourceReferences(query)
+}
+
+// GetChangedSourceReferences возвращает ссылки исходных записей, нормализованные данные которых
+// изменились после позиции журнала afterSeq. Удаленные записи не возвращаются
+func (db *DB) GetChangedSourceReferences(afterSeq int64) (map[string]bool, error) {
+	return db.querySourceReferences(`
+		SELECT DISTINCT n.source_reference
+		FROM normalized_changes c
+		JOIN normalized_data n ON n.id = c.item_id
+		WHERE c.seq > ? AND n.source_reference IS NOT NULL AND n.source_reference != ''
+	`, afterSeq)
+}
+
+func (db *DB) querySourceReferences(query string, args ...interface{}) (map[string]bool, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source references: %w", err)
+	}
+	defer rows.Close()
+
+	references := make(map[string]bool)
+	for rows.Next() {
+		var reference string
+		if err := rows.Scan(&reference); err != nil {
+			return nil, fmt.Errorf("failed to scan source reference: %w", err)
+		}
+		references[reference] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate source references: %w", err)
+	}
+	return references, nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExportCursorsAndSourceReferences(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code)
+		VALUES ('ref-1', 'Болт', '1', 'болт', 'крепеж', '25.94.11'), ('ref-2', 'Гайка', '2', 'гайка', 'крепеж', '')`); err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+
+	cursor, err := db.GetExportCursor("uuid-1", "pull")
+	if err != nil || cursor != nil {
+		t.Fatalf("GetExportCursor() before export = %+v, %v, want nil", cursor, err)
+	}
+	head, err := db.GetNormalizedChangesHead()
+	if err != nil {
+		t.Fatalf("GetNormalizedChangesHead() error = %v", err)
+	}
+	exportedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.SaveExportCursor(ExportCursor{UploadUUID: "uuid-1", Target: "pull", ChangeSeq: head, ExportedAt: exportedAt}); err != nil {
+		t.Fatalf("SaveExportCursor() error = %v", err)
+	}
+	cursor, err = db.GetExportCursor("uuid-1", "pull")
+	if err != nil || cursor == nil || cursor.ChangeSeq != head || !cursor.ExportedAt.Equal(exportedAt) {
+		t.Fatalf("GetExportCursor() = %+v, %v, want seq %d", cursor, err, head)
+	}
+
+	if _, err := db.Exec(`UPDATE normalized_data SET normalized_name = 'гайка м10' WHERE source_reference = 'ref-2'`); err != nil {
+		t.Fatalf("Failed to update normalized item: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query func() (map[string]bool, error)
+		want  map[string]bool
+	}{
+		{"normalized", func() (map[string]bool, error) { return db.GetNormalizedSourceReferences(false) }, map[string]bool{"ref-1": true, "ref-2": true}},
+		{"classified", func() (map[string]bool, error) { return db.GetNormalizedSourceReferences(true) }, map[string]bool{"ref-1": true}},
+		{"changed after cursor", func() (map[string]bool, error) { return db.GetChangedSourceReferences(cursor.ChangeSeq) }, map[string]bool{"ref-2": true}},
+		{"changed from start", func() (map[string]bool, error) { return db.GetChangedSourceReferences(0) }, map[string]bool{"ref-1": true, "ref-2": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query()
+			if err != nil {
+				t.Fatalf("query error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("references = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
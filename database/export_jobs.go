@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ExportJobRecord задача выгрузки, сохраненная в service.db, чтобы ее статус и задачи pull были доступны
+// после перезапуска и на любом экземпляре сервера. View - представление задачи для API, State - параметры,
+// по которым экземпляр восстанавливает задачу pull; оба хранятся в JSON
+type ExportJobRecord struct {
+	ID         string    `json:"id"`
+	UploadUUID string    `json:"upload_uuid"`
+	Type       string    `json:"type"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	InstanceID string    `json:"instance_id,omitempty"` // Экземпляр, создавший задачу
+	View       string    `json:"view"`
+	State      string    `json:"state"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// exportJobColumns колонки, читаемые scanExportJob
+const exportJobColumns = `id, upload_uuid, type, status, error, instance_id, view, state, created_at, updated_at`
+
+// CreateExportJobsTable создает таблицу задач выгрузки
+func CreateExportJobsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS export_jobs (
+			id TEXT PRIMARY KEY,
+			upload_uuid TEXT NOT NULL,
+			type TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			instance_id TEXT NOT NULL DEFAULT '',
+			view TEXT NOT NULL DEFAULT '{}',
+			state TEXT NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_export_jobs_upload ON export_jobs(upload_uuid, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create export_jobs table: %w", err)
+	}
+	return nil
+}
+
+// SaveExportJob сохраняет задачу выгрузки; повторное сохранение заменяет статус, представление и параметры
+func (db *ServiceDB) SaveExportJob(record *ExportJobRecord) error {
+	record.UpdatedAt = time.Now()
+	_, err := db.conn.Exec(`
+		INSERT INTO export_jobs (id, upload_uuid, type, status, error, instance_id, view, state, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status, error = excluded.error, view = excluded.view, state = excluded.state,
+			updated_at = excluded.updated_at
+	`, record.ID, record.UploadUUID, record.Type, record.Status, record.Error, record.InstanceID,
+		record.View, record.State, record.CreatedAt, record.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save export job: %w", err)
+	}
+	return nil
+}
+
+// UpdateExportJobStatus изменяет статус задачи выгрузки без изменения представления (задача прервана
+// остановкой экземпляра, который ее выполнял)
+func (db *ServiceDB) UpdateExportJobStatus(id, status, errMessage string) error {
+	_, err := db.conn.Exec(`UPDATE export_jobs SET status = ?, error = ?, updated_at = ? WHERE id = ?`,
+		status, errMessage, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update export job status: %w", err)
+	}
+	return nil
+}
+
+// GetExportJob возвращает задачу выгрузки или nil, если задача не найдена
+func (db *ServiceDB) GetExportJob(id string) (*ExportJobRecord, error) {
+	row := db.conn.QueryRow(`SELECT `+exportJobColumns+` FROM export_jobs WHERE id = ?`, id)
+	record, err := scanExportJob(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export job: %w", err)
+	}
+	return record, nil
+}
+
+// GetExportJobs возвращает последние задачи выгрузки, uploadUUID пустой - задачи всех выгрузок
+func (db *ServiceDB) GetExportJobs(uploadUUID string, limit int) ([]*ExportJobRecord, error) {
+	query := `SELECT ` + exportJobColumns + ` FROM export_jobs`
+	args := []interface{}{}
+	if uploadUUID != "" {
+		query += ` WHERE upload_uuid = ?`
+		args = append(args, uploadUUID)
+	}
+	query += ` ORDER BY created_at DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*ExportJobRecord
+	for rows.Next() {
+		record, err := scanExportJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export job: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// scanExportJob читает задачу выгрузки из строки результата
+func scanExportJob(row rowScanner) (*ExportJobRecord, error) {
+	var record ExportJobRecord
+	if err := row.Scan(&record.ID, &record.UploadUUID, &record.Type, &record.Status, &record.Error,
+		&record.InstanceID, &record.View, &record.State, &record.CreatedAt, &record.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
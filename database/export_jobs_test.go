@@ -0,0 +1,77 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportJobs(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	created := time.Now().Add(-time.Hour)
+	records := []*ExportJobRecord{
+		{ID: "job-1", UploadUUID: "upload-1", Type: "pull", Status: "pending", InstanceID: "a", View: `{"id":"job-1"}`, State: `{}`, CreatedAt: created},
+		{ID: "job-2", UploadUUID: "upload-1", Type: "protocol", Status: "running", InstanceID: "a", View: `{"id":"job-2"}`, State: `{}`, CreatedAt: created.Add(time.Minute)},
+		{ID: "job-3", UploadUUID: "upload-2", Type: "parquet", Status: "completed", InstanceID: "b", View: `{"id":"job-3"}`, State: `{}`, CreatedAt: created.Add(2 * time.Minute)},
+	}
+	for _, record := range records {
+		if err := db.SaveExportJob(record); err != nil {
+			t.Fatalf("SaveExportJob(%s) error = %v", record.ID, err)
+		}
+	}
+
+	// Повторное сохранение обновляет статус, но не владельца и время создания
+	update := *records[0]
+	update.Status, update.InstanceID, update.View = "running", "b", `{"id":"job-1","status":"running"}`
+	if err := db.SaveExportJob(&update); err != nil {
+		t.Fatalf("SaveExportJob(update) error = %v", err)
+	}
+	got, err := db.GetExportJob("job-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetExportJob() = %+v, %v", got, err)
+	}
+	if got.Status != "running" || got.InstanceID != "a" || got.View != update.View || !got.CreatedAt.Equal(created) {
+		t.Errorf("GetExportJob() = %+v, want updated status with original owner", got)
+	}
+	if missing, err := db.GetExportJob("missing"); err != nil || missing != nil {
+		t.Errorf("GetExportJob(missing) = %+v, %v; want nil", missing, err)
+	}
+
+	if err := db.UpdateExportJobStatus("job-2", "failed", "server stopped"); err != nil {
+		t.Fatalf("UpdateExportJobStatus() error = %v", err)
+	}
+	if got, _ := db.GetExportJob("job-2"); got.Status != "failed" || got.Error != "server stopped" {
+		t.Errorf("job-2 after UpdateExportJobStatus() = %+v", got)
+	}
+
+	tests := []struct {
+		name       string
+		uploadUUID string
+		limit      int
+		want       []string
+	}{
+		{"upload", "upload-1", 0, []string{"job-2", "job-1"}},
+		{"all", "", 0, []string{"job-3", "job-2", "job-1"}},
+		{"limit", "", 1, []string{"job-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := db.GetExportJobs(tt.uploadUUID, tt.limit)
+			if err != nil {
+				t.Fatalf("GetExportJobs() error = %v", err)
+			}
+			var ids []string
+			for _, job := range jobs {
+				ids = append(ids, job.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("GetExportJobs() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// VerifiedClassification подтвержденная КПВЭД классификация наименования
+type VerifiedClassification struct {
+	NormalizedName string `json:"normalized_name"`
+	KpvedCode      string `json:"kpved_code"`
+	KpvedName      string `json:"kpved_name"`
+}
+
+// HistoricalClassificationRun учет прогона классификации с применением подтвержденных решений
+type HistoricalClassificationRun struct {
+	ID           int       `json:"id"`
+	RunType      string    `json:"run_type"` // normalization, reclassify
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Items        int       `json:"items"`      // Элементы (группы) прогона
+	IndexSize    int       `json:"index_size"` // Подтвержденных наименований в индексе
+	Hits         int       `json:"hits"`
+	Misses       int       `json:"misses"`
+	AIClassified int       `json:"ai_classified"`
+	AICalls      int       `json:"ai_calls"`
+	SavedAICalls int       `json:"saved_ai_calls"`
+}
+
+// CreateHistoricalClassificationRunsTable создает таблицу учета прогонов классификации
+func CreateHistoricalClassificationRunsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS historical_classification_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_type TEXT NOT NULL,
+			started_at TIMESTAMP NOT NULL,
+			finished_at TIMESTAMP NOT NULL,
+			items INTEGER NOT NULL DEFAULT 0,
+			index_size INTEGER NOT NULL DEFAULT 0,
+			hits INTEGER NOT NULL DEFAULT 0,
+			misses INTEGER NOT NULL DEFAULT 0,
+			ai_classified INTEGER NOT NULL DEFAULT 0,
+			ai_calls INTEGER NOT NULL DEFAULT 0,
+			saved_ai_calls INTEGER NOT NULL DEFAULT 0
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_historical_classification_runs_started ON historical_classification_runs(started_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create historical_classification_runs table: %w", err)
+	}
+	return nil
+}
+
+// GetVerifiedClassifications возвращает подтвержденные классификации: отмеченные как верные
+// или назначенные вручную
+func (db *DB) GetVerifiedClassifications() ([]VerifiedClassification, error) {
+	rows, err := db.conn.Query(`
+		SELECT normalized_name, kpved_code, COALESCE(kpved_name, '')
+		FROM normalized_data
+		WHERE kpved_code IS NOT NULL AND TRIM(kpved_code) != ''
+		  AND (validation_status = 'correct' OR kpved_model = ?)
+	`, ManualEditModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verified classifications: %w", err)
+	}
+	defer rows.Close()
+
+	var result []VerifiedClassification
+	for rows.Next() {
+		var item VerifiedClassification
+		if err := rows.Scan(&item.NormalizedName, &item.KpvedCode, &item.KpvedName); err != nil {
+			return nil, fmt.Errorf("failed to scan verified classification: %w", err)
+		}
+		result = append(result, item)
+	}
+	return result, rows.Err()
+}
+
+// SaveHistoricalClassificationRun сохраняет учет прогона классификации
+func (db *DB) SaveHistoricalClassificationRun(run *HistoricalClassificationRun) error {
+	result, err := db.conn.Exec(`
+		INSERT INTO historical_classification_runs
+		(run_type, started_at, finished_at, items, index_size, hits, misses, ai_classified, ai_calls, saved_ai_calls)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.RunType, run.StartedAt, run.FinishedAt, run.Items, run.IndexSize, run.Hits, run.Misses,
+		run.AIClassified, run.AICalls, run.SavedAICalls)
+	if err != nil {
+		return fmt.Errorf("failed to save historical classification run: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get historical classification run ID: %w", err)
+	}
+	run.ID = int(id)
+	return nil
+}
+
+// GetHistoricalClassificationRuns возвращает последние прогоны классификации (новые первыми)
+func (db *DB) GetHistoricalClassificationRuns(limit int) ([]HistoricalClassificationRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := db.conn.Query(`
+		SELECT id, run_type, started_at, finished_at, items, index_size, hits, misses, ai_classified, ai_calls, saved_ai_calls
+		FROM historical_classification_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get historical classification runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []HistoricalClassificationRun{}
+	for rows.Next() {
+		var run HistoricalClassificationRun
+		if err := rows.Scan(&run.ID, &run.RunType, &run.StartedAt, &run.FinishedAt, &run.Items, &run.IndexSize,
+			&run.Hits, &run.Misses, &run.AIClassified, &run.AICalls, &run.SavedAICalls); err != nil {
+			return nil, fmt.Errorf("failed to scan historical classification run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifiedClassificationsAndRuns(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code, kpved_name, kpved_model, validation_status)
+		VALUES ('r1', 'Болт', 'r1', 'болт м8', 'крепеж', '25.94.11', 'Болты', 'glm', 'correct'),
+		       ('r2', 'Гайка', 'r2', 'гайка м8', 'крепеж', '25.94.12', 'Гайки', ?, ''),
+		       ('r3', 'Шайба', 'r3', 'шайба м8', 'крепеж', '25.94.13', 'Шайбы', 'glm', ''),
+		       ('r4', 'Винт', 'r4', 'винт м8', 'крепеж', '', '', ?, 'correct')`, ManualEditModel, ManualEditModel)
+	if err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+
+	verified, err := db.GetVerifiedClassifications()
+	if err != nil {
+		t.Fatalf("GetVerifiedClassifications() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, v := range verified {
+		got[v.NormalizedName] = v.KpvedCode
+	}
+	if len(got) != 2 || got["болт м8"] != "25.94.11" || got["гайка м8"] != "25.94.12" {
+		t.Errorf("GetVerifiedClassifications() = %v, want correct and manual items only", got)
+	}
+
+	started := time.Now().Add(-time.Minute)
+	for _, run := range []*HistoricalClassificationRun{
+		{RunType: "normalization", StartedAt: started, FinishedAt: started, Items: 10, Hits: 4, SavedAICalls: 8},
+		{RunType: "reclassify", StartedAt: started.Add(time.Second), FinishedAt: time.Now(), Items: 5, Hits: 1, SavedAICalls: 3},
+	} {
+		if err := db.SaveHistoricalClassificationRun(run); err != nil || run.ID == 0 {
+			t.Fatalf("SaveHistoricalClassificationRun() id = %d, error = %v", run.ID, err)
+		}
+	}
+	runs, err := db.GetHistoricalClassificationRuns(0)
+	if err != nil {
+		t.Fatalf("GetHistoricalClassificationRuns() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].RunType != "reclassify" || runs[1].SavedAICalls != 8 {
+		t.Errorf("GetHistoricalClassificationRuns() = %+v, want newest first", runs)
+	}
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Статусы сообщений очереди приема выгрузок
+const (
+	IngestMessageStatusProcessing = "processing" // Сообщение захвачено обработчиком
+	IngestMessageStatusProcessed  = "processed"
+	IngestMessageStatusFailed     = "failed"
+)
+
+// IngestQueueMessage сообщение очереди приема выгрузки; ключ (upload_uuid, sequence)
+// исключает повторную обработку при повторной доставке
+type IngestQueueMessage struct {
+	UploadUUID  string    `json:"upload_uuid"`
+	Sequence    int64     `json:"sequence"`
+	Endpoint    string    `json:"endpoint"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Attempts    int       `json:"attempts"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// CreateIngestQueueTable создает журнал обработанных сообщений очереди приема выгрузок
+func CreateIngestQueueTable(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS ingest_queue_messages (
+			upload_uuid TEXT NOT NULL,
+			sequence INTEGER NOT NULL,
+			endpoint TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT,
+			attempts INTEGER NOT NULL DEFAULT 1,
+			processed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (upload_uuid, sequence)
+		);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create ingest queue table: %w", err)
+	}
+
+	return nil
+}
+
+// GetIngestQueueMessage возвращает запись о сообщении или nil, если оно еще не обрабатывалось
+func (db *ServiceDB) GetIngestQueueMessage(uploadUUID string, sequence int64) (*IngestQueueMessage, error) {
+	row := db.conn.QueryRow(`
+		SELECT upload_uuid, sequence, endpoint, status, error, attempts, processed_at
+		FROM ingest_queue_messages WHERE upload_uuid = ? AND sequence = ?
+	`, uploadUUID, sequence)
+	message, err := scanIngestQueueMessage(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest queue message: %w", err)
+	}
+	return message, nil
+}
+
+// ClaimIngestQueueMessage захватывает сообщение для обработки, атомарно записывая его в журнал со статусом processing.
+// Возвращает nil, если сообщение захвачено, иначе существующую запись: обработанное сообщение или обработку,
+// выполняющуюся при параллельной доставке. Захват старше staleAfter считается брошенным (сбой обработчика)
+// и перехватывается с увеличением числа попыток
+func (db *ServiceDB) ClaimIngestQueueMessage(uploadUUID string, sequence int64, endpoint string, staleAfter time.Duration) (*IngestQueueMessage, error) {
+	now := time.Now().UTC()
+	result, err := db.conn.Exec(`
+		INSERT INTO ingest_queue_messages (upload_uuid, sequence, endpoint, status, processed_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(upload_uuid, sequence) DO UPDATE SET
+			endpoint = excluded.endpoint, attempts = attempts + 1, processed_at = excluded.processed_at
+		WHERE status = ? AND processed_at < ?
+	`, uploadUUID, sequence, endpoint, IngestMessageStatusProcessing, now, IngestMessageStatusProcessing, now.Add(-staleAfter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim ingest queue message: %w", err)
+	}
+	if claimed, err := result.RowsAffected(); err == nil && claimed > 0 {
+		return nil, nil
+	}
+
+	existing, err := db.GetIngestQueueMessage(uploadUUID, sequence)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("ingest queue message %s #%d is neither claimed nor recorded", uploadUUID, sequence)
+	}
+	return existing, nil
+}
+
+// ReleaseIngestQueueMessage снимает захват сообщения, обработку которого нужно повторить при следующей доставке
+func (db *ServiceDB) ReleaseIngestQueueMessage(uploadUUID string, sequence int64) error {
+	_, err := db.conn.Exec(`DELETE FROM ingest_queue_messages WHERE upload_uuid = ? AND sequence = ? AND status = ?`,
+		uploadUUID, sequence, IngestMessageStatusProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to release ingest queue message: %w", err)
+	}
+	return nil
+}
+
+// SaveIngestQueueMessage фиксирует итог обработки сообщения; попытки учитываются при захвате
+func (db *ServiceDB) SaveIngestQueueMessage(message *IngestQueueMessage) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO ingest_queue_messages (upload_uuid, sequence, endpoint, status, error)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(upload_uuid, sequence) DO UPDATE SET
+			endpoint = excluded.endpoint, status = excluded.status, error = excluded.error,
+			processed_at = CURRENT_TIMESTAMP
+	`, message.UploadUUID, message.Sequence, message.Endpoint, message.Status, message.Error)
+	if err != nil {
+		return fmt.Errorf("failed to save ingest queue message: %w", err)
+	}
+	return nil
+}
+
+// GetIngestQueueMessages возвращает сообщения выгрузки в порядке номеров
+func (db *ServiceDB) GetIngestQueueMessages(uploadUUID string) ([]*IngestQueueMessage, error) {
+	rows, err := db.conn.Query(`
+		SELECT upload_uuid, sequence, endpoint, status, error, attempts, processed_at
+		FROM ingest_queue_messages WHERE upload_uuid = ? ORDER BY sequence
+	`, uploadUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest queue messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*IngestQueueMessage{}
+	for rows.Next() {
+		message, err := scanIngestQueueMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ingest queue message: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// scanIngestQueueMessage читает сообщение очереди из строки результата
+func scanIngestQueueMessage(scanner rowScanner) (*IngestQueueMessage, error) {
+	message := &IngestQueueMessage{}
+	var errorText sql.NullString
+	if err := scanner.Scan(&message.UploadUUID, &message.Sequence, &message.Endpoint, &message.Status,
+		&errorText, &message.Attempts, &message.ProcessedAt); err != nil {
+		return nil, err
+	}
+	message.Error = errorText.String
+	return message, nil
+}
@@ -0,0 +1,400 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Типы событий хронологии выгрузки
+const (
+	UploadEventIngestStats   = "ingest_stats"   // Статистика приема по справочникам
+	UploadEventIngestAnomaly = "ingest_anomaly" // Аномалия по сравнению с прошлой выгрузкой базы
+)
+
+// Виды аномалий приема
+const (
+	IngestAnomalyItemsDrop      = "items_drop"      // Элементов стало заметно меньше
+	IngestAnomalyItemsSpike     = "items_spike"     // Элементов стало заметно больше
+	IngestAnomalyCatalogMissing = "catalog_missing" // Справочник пропал из выгрузки
+	IngestAnomalyNullNames      = "null_names"      // Выросла доля элементов без наименования
+	IngestAnomalyAttributesDrop = "attributes_drop" // Уменьшилось среднее число реквизитов
+)
+
+// nomenclatureStatsCatalog справочник, под которым в статистике учитывается таблица номенклатуры
+const nomenclatureStatsCatalog = "Номенклатура"
+
+// CatalogIngestStats статистика приема одного справочника выгрузки
+type CatalogIngestStats struct {
+	CatalogName   string  `json:"catalog_name"`
+	Items         int     `json:"items"`
+	AvgAttributes float64 `json:"avg_attributes"` // Среднее число заполненных реквизитов элемента
+	NullNameRate  float64 `json:"null_name_rate"` // Доля элементов без наименования, 0..1
+}
+
+// UploadIngestStats статистика приема выгрузки по справочникам
+type UploadIngestStats struct {
+	UploadUUID  string               `json:"upload_uuid"`
+	DatabaseID  int                  `json:"database_id"`
+	Catalogs    []CatalogIngestStats `json:"catalogs"`
+	CollectedAt time.Time            `json:"collected_at"`
+}
+
+// IngestAnomaly отклонение статистики справочника от прошлой выгрузки той же базы
+type IngestAnomaly struct {
+	CatalogName string  `json:"catalog_name"`
+	Kind        string  `json:"kind"`
+	Severity    string  `json:"severity"` // WARNING или ERROR
+	Message     string  `json:"message"`
+	Previous    float64 `json:"previous"`
+	Current     float64 `json:"current"`
+}
+
+// IngestAnomalyThresholds пороги обнаружения аномалий приема
+type IngestAnomalyThresholds struct {
+	ItemsChangeRatio   float64 // Относительное изменение числа элементов (0.5 - на 50%)
+	MinItems           int     // Справочники меньше порога (в обеих выгрузках) не сравниваются по числу элементов
+	NullNameRateGrowth float64 // Рост доли элементов без наименования (абсолютный, 0.1 - на 10 п.п.)
+	AttributesDrop     float64 // Относительное уменьшение среднего числа реквизитов
+}
+
+// DefaultIngestAnomalyThresholds пороги по умолчанию
+var DefaultIngestAnomalyThresholds = IngestAnomalyThresholds{
+	ItemsChangeRatio:   0.5,
+	MinItems:           100,
+	NullNameRateGrowth: 0.1,
+	AttributesDrop:     0.3,
+}
+
+// UploadEvent событие хронологии выгрузки
+type UploadEvent struct {
+	ID         int                    `json:"id"`
+	UploadUUID string                 `json:"upload_uuid"`
+	DatabaseID int                    `json:"database_id,omitempty"`
+	EventType  string                 `json:"event_type"`
+	Severity   string                 `json:"severity"` // INFO, WARNING, ERROR
+	Message    string                 `json:"message"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// CreateIngestStatsTables создает таблицы статистики приема по справочникам и хронологии выгрузок
+func CreateIngestStatsTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_catalog_stats (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			upload_uuid TEXT NOT NULL,
+			database_id INTEGER NOT NULL DEFAULT 0,
+			catalog_name TEXT NOT NULL,
+			items INTEGER NOT NULL DEFAULT 0,
+			avg_attributes REAL NOT NULL DEFAULT 0,
+			null_name_rate REAL NOT NULL DEFAULT 0,
+			collected_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(upload_uuid, catalog_name)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_upload_catalog_stats_database ON upload_catalog_stats(database_id, collected_at);
+
+		CREATE TABLE IF NOT EXISTS upload_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			upload_uuid TEXT NOT NULL,
+			database_id INTEGER NOT NULL DEFAULT 0,
+			event_type TEXT NOT NULL,
+			severity TEXT NOT NULL DEFAULT 'INFO',
+			message TEXT NOT NULL DEFAULT '',
+			details TEXT NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_upload_events_upload ON upload_events(upload_uuid, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create ingest stats tables: %w", err)
+	}
+	return nil
+}
+
+// CollectIngestStats считает статистику приема по справочникам выгрузки. Пустые справочники
+// попадают в статистику с нулем элементов, номенклатура учитывается как справочник Номенклатура
+func (db *DB) CollectIngestStats(ctx context.Context, uploadID int) ([]CatalogIngestStats, error) {
+	type accumulator struct {
+		items, attributes, nullNames int
+	}
+	catalogs := make(map[string]*accumulator)
+	entry := func(name string) *accumulator {
+		acc := catalogs[name]
+		if acc == nil {
+			acc = &accumulator{}
+			catalogs[name] = acc
+		}
+		return acc
+	}
+
+	list, err := db.GetCatalogsByUpload(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, catalog := range list {
+		entry(catalog.Name)
+	}
+
+	err = db.StreamCatalogItemsContext(ctx, uploadID, nil, 0, func(items []*CatalogItem) error {
+		for _, item := range items {
+			acc := entry(item.CatalogName)
+			acc.items++
+			acc.attributes += len(ExtractAttributeValues(item.Attributes))
+			if strings.TrimSpace(item.Name) == "" {
+				acc.nullNames++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect catalog stats: %w", err)
+	}
+	err = db.StreamNomenclatureItemsContext(ctx, uploadID, 0, func(items []*NomenclatureItem) error {
+		acc := entry(nomenclatureStatsCatalog)
+		for _, item := range items {
+			acc.items++
+			acc.attributes += len(ExtractAttributeValues(item.AttributesXML))
+			if strings.TrimSpace(item.NomenclatureName) == "" {
+				acc.nullNames++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect nomenclature stats: %w", err)
+	}
+
+	stats := make([]CatalogIngestStats, 0, len(catalogs))
+	for name, acc := range catalogs {
+		catalog := CatalogIngestStats{CatalogName: name, Items: acc.items}
+		if acc.items > 0 {
+			catalog.AvgAttributes = math.Round(float64(acc.attributes)/float64(acc.items)*100) / 100
+			catalog.NullNameRate = math.Round(float64(acc.nullNames)/float64(acc.items)*10000) / 10000
+		}
+		stats = append(stats, catalog)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].CatalogName < stats[j].CatalogName })
+	return stats, nil
+}
+
+// CompareIngestStats сравнивает статистику выгрузки с прошлой выгрузкой той же базы и возвращает аномалии
+func CompareIngestStats(previous, current []CatalogIngestStats, thresholds IngestAnomalyThresholds) []IngestAnomaly {
+	byName := make(map[string]CatalogIngestStats, len(current))
+	for _, catalog := range current {
+		byName[catalog.CatalogName] = catalog
+	}
+
+	anomalies := []IngestAnomaly{}
+	for _, prev := range previous {
+		cur, ok := byName[prev.CatalogName]
+		if !ok {
+			if prev.Items >= thresholds.MinItems {
+				anomalies = append(anomalies, IngestAnomaly{
+					CatalogName: prev.CatalogName,
+					Kind:        IngestAnomalyCatalogMissing,
+					Severity:    "ERROR",
+					Message:     fmt.Sprintf("%s: справочник отсутствует в выгрузке (в прошлой выгрузке %d элементов)", prev.CatalogName, prev.Items),
+					Previous:    float64(prev.Items),
+				})
+			}
+			continue
+		}
+
+		if thresholds.ItemsChangeRatio > 0 && (prev.Items >= thresholds.MinItems || cur.Items >= thresholds.MinItems) {
+			anomaly := IngestAnomaly{CatalogName: prev.CatalogName, Previous: float64(prev.Items), Current: float64(cur.Items)}
+			switch {
+			case float64(cur.Items) <= float64(prev.Items)*(1-thresholds.ItemsChangeRatio):
+				anomaly.Kind = IngestAnomalyItemsDrop
+				anomaly.Severity = "ERROR"
+				anomaly.Message = fmt.Sprintf("%s: элементов стало %d вместо %d", prev.CatalogName, cur.Items, prev.Items)
+				anomalies = append(anomalies, anomaly)
+			case float64(cur.Items) >= float64(prev.Items)*(1+thresholds.ItemsChangeRatio):
+				anomaly.Kind = IngestAnomalyItemsSpike
+				anomaly.Severity = "WARNING"
+				anomaly.Message = fmt.Sprintf("%s: элементов стало %d вместо %d", prev.CatalogName, cur.Items, prev.Items)
+				anomalies = append(anomalies, anomaly)
+			}
+		}
+
+		if cur.Items == 0 || prev.Items == 0 {
+			continue
+		}
+		if thresholds.NullNameRateGrowth > 0 && cur.NullNameRate-prev.NullNameRate >= thresholds.NullNameRateGrowth {
+			anomalies = append(anomalies, IngestAnomaly{
+				CatalogName: prev.CatalogName,
+				Kind:        IngestAnomalyNullNames,
+				Severity:    "WARNING",
+				Message:     fmt.Sprintf("%s: доля элементов без наименования выросла с %.1f%% до %.1f%%", prev.CatalogName, prev.NullNameRate*100, cur.NullNameRate*100),
+				Previous:    prev.NullNameRate,
+				Current:     cur.NullNameRate,
+			})
+		}
+		if thresholds.AttributesDrop > 0 && prev.AvgAttributes > 0 && cur.AvgAttributes <= prev.AvgAttributes*(1-thresholds.AttributesDrop) {
+			anomalies = append(anomalies, IngestAnomaly{
+				CatalogName: prev.CatalogName,
+				Kind:        IngestAnomalyAttributesDrop,
+				Severity:    "WARNING",
+				Message:     fmt.Sprintf("%s: в среднем %.1f реквизитов на элемент вместо %.1f", prev.CatalogName, cur.AvgAttributes, prev.AvgAttributes),
+				Previous:    prev.AvgAttributes,
+				Current:     cur.AvgAttributes,
+			})
+		}
+	}
+	return anomalies
+}
+
+// SaveUploadIngestStats сохраняет статистику приема выгрузки, заменяя ранее посчитанную
+func (db *ServiceDB) SaveUploadIngestStats(stats *UploadIngestStats) error {
+	if stats.UploadUUID == "" {
+		return fmt.Errorf("upload uuid is required")
+	}
+	if stats.CollectedAt.IsZero() {
+		stats.CollectedAt = time.Now()
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM upload_catalog_stats WHERE upload_uuid = ?`, stats.UploadUUID); err != nil {
+		return fmt.Errorf("failed to delete upload catalog stats: %w", err)
+	}
+	for _, catalog := range stats.Catalogs {
+		_, err := tx.Exec(`
+			INSERT INTO upload_catalog_stats (upload_uuid, database_id, catalog_name, items, avg_attributes, null_name_rate, collected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, stats.UploadUUID, stats.DatabaseID, catalog.CatalogName, catalog.Items, catalog.AvgAttributes, catalog.NullNameRate, stats.CollectedAt)
+		if err != nil {
+			return fmt.Errorf("failed to save upload catalog stats: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetUploadIngestStats возвращает статистику приема выгрузки, nil - статистика не считалась
+func (db *ServiceDB) GetUploadIngestStats(uploadUUID string) (*UploadIngestStats, error) {
+	return db.queryIngestStats(`
+		SELECT upload_uuid, database_id, catalog_name, items, avg_attributes, null_name_rate, collected_at
+		FROM upload_catalog_stats
+		WHERE upload_uuid = ?
+		ORDER BY catalog_name
+	`, uploadUUID)
+}
+
+// GetPreviousIngestStats возвращает статистику последней выгрузки базы, посчитанной раньше указанной
+func (db *ServiceDB) GetPreviousIngestStats(databaseID int, uploadUUID string) (*UploadIngestStats, error) {
+	return db.queryIngestStats(`
+		SELECT upload_uuid, database_id, catalog_name, items, avg_attributes, null_name_rate, collected_at
+		FROM upload_catalog_stats
+		WHERE upload_uuid = (
+			SELECT upload_uuid FROM upload_catalog_stats
+			WHERE database_id = ? AND upload_uuid != ?
+				AND collected_at <= COALESCE((SELECT MIN(collected_at) FROM upload_catalog_stats WHERE upload_uuid = ?), CURRENT_TIMESTAMP)
+			ORDER BY collected_at DESC, id DESC
+			LIMIT 1
+		)
+		ORDER BY catalog_name
+	`, databaseID, uploadUUID, uploadUUID)
+}
+
+// queryIngestStats читает статистику одной выгрузки
+func (db *ServiceDB) queryIngestStats(query string, args ...interface{}) (*UploadIngestStats, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload catalog stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats *UploadIngestStats
+	for rows.Next() {
+		var uploadUUID string
+		var databaseID int
+		var catalog CatalogIngestStats
+		var collectedAt time.Time
+		if err := rows.Scan(&uploadUUID, &databaseID, &catalog.CatalogName, &catalog.Items,
+			&catalog.AvgAttributes, &catalog.NullNameRate, &collectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan upload catalog stats: %w", err)
+		}
+		if stats == nil {
+			stats = &UploadIngestStats{UploadUUID: uploadUUID, DatabaseID: databaseID, CollectedAt: collectedAt}
+		}
+		stats.Catalogs = append(stats.Catalogs, catalog)
+	}
+	return stats, rows.Err()
+}
+
+// RecordUploadEvent добавляет событие в хронологию выгрузки
+func (db *ServiceDB) RecordUploadEvent(event *UploadEvent) error {
+	if event.UploadUUID == "" || event.EventType == "" {
+		return fmt.Errorf("upload uuid and event type are required")
+	}
+	if event.Severity == "" {
+		event.Severity = "INFO"
+	}
+	details := "{}"
+	if len(event.Details) > 0 {
+		data, err := json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal upload event details: %w", err)
+		}
+		details = string(data)
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	result, err := db.conn.Exec(`
+		INSERT INTO upload_events (upload_uuid, database_id, event_type, severity, message, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, event.UploadUUID, event.DatabaseID, event.EventType, event.Severity, event.Message, details, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record upload event: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get upload event id: %w", err)
+	}
+	event.ID = int(id)
+	return nil
+}
+
+// GetUploadEvents возвращает хронологию выгрузки в порядке возникновения событий
+func (db *ServiceDB) GetUploadEvents(uploadUUID string) ([]*UploadEvent, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, upload_uuid, database_id, event_type, severity, message, details, created_at
+		FROM upload_events
+		WHERE upload_uuid = ?
+		ORDER BY created_at, id
+	`, uploadUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*UploadEvent{}
+	for rows.Next() {
+		event := &UploadEvent{}
+		var details string
+		if err := rows.Scan(&event.ID, &event.UploadUUID, &event.DatabaseID, &event.EventType, &event.Severity,
+			&event.Message, &details, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan upload event: %w", err)
+		}
+		if details != "" && details != "{}" {
+			if err := json.Unmarshal([]byte(details), &event.Details); err != nil {
+				return nil, fmt.Errorf("failed to parse upload event details: %w", err)
+			}
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCompareIngestStats(t *testing.T) {
+	previous := []CatalogIngestStats{
+		{CatalogName: "Номенклатура", Items: 80000, AvgAttributes: 12, NullNameRate: 0.01},
+		{CatalogName: "Контрагенты", Items: 500, AvgAttributes: 6, NullNameRate: 0},
+		{CatalogName: "Склады", Items: 20, AvgAttributes: 2},
+		{CatalogName: "Валюты", Items: 3},
+	}
+	tests := []struct {
+		name    string
+		current []CatalogIngestStats
+		want    []string
+	}{
+		{
+			name: "no changes",
+			current: []CatalogIngestStats{
+				{CatalogName: "Номенклатура", Items: 81000, AvgAttributes: 12, NullNameRate: 0.01},
+				{CatalogName: "Контрагенты", Items: 510, AvgAttributes: 6},
+				{CatalogName: "Склады", Items: 2, AvgAttributes: 2},
+			},
+		},
+		{
+			name: "drop and missing catalog",
+			current: []CatalogIngestStats{
+				{CatalogName: "Номенклатура", Items: 12000, AvgAttributes: 12, NullNameRate: 0.01},
+			},
+			want: []string{IngestAnomalyItemsDrop, IngestAnomalyCatalogMissing},
+		},
+		{
+			name: "spike, null names and attributes",
+			current: []CatalogIngestStats{
+				{CatalogName: "Номенклатура", Items: 80000, AvgAttributes: 4, NullNameRate: 0.3},
+				{CatalogName: "Контрагенты", Items: 900, AvgAttributes: 6},
+				{CatalogName: "Склады", Items: 20, AvgAttributes: 2},
+			},
+			want: []string{IngestAnomalyNullNames, IngestAnomalyAttributesDrop, IngestAnomalyItemsSpike},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareIngestStats(previous, tt.current, DefaultIngestAnomalyThresholds)
+			if len(got) != len(tt.want) {
+				t.Fatalf("CompareIngestStats() = %+v, want kinds %v", got, tt.want)
+			}
+			for i, anomaly := range got {
+				if anomaly.Kind != tt.want[i] || anomaly.Message == "" {
+					t.Errorf("anomaly %d = %+v, want kind %s", i, anomaly, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestIngestStats(t *testing.T) {
+	db, err := NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+	upload, _ := db.CreateUpload("uuid-stats", "8.3", "УправлениеТорговлей")
+	units, _ := db.AddCatalog(upload.ID, "ЕдиницыИзмерения", "")
+	db.AddCatalog(upload.ID, "Склады", "")
+	db.AddCatalogItem(units.ID, "ref-1", "796", "шт", "<Код>796</Код><Полное>Штука</Полное>", "")
+	db.AddCatalogItem(units.ID, "ref-2", "166", "", "", "")
+	db.AddNomenclatureItem(upload.ID, "ref-3", "001", "Болт", "", "", "<Артикул>A-1</Артикул>", nil)
+
+	stats, err := db.CollectIngestStats(context.Background(), upload.ID)
+	if err != nil {
+		t.Fatalf("CollectIngestStats() error = %v", err)
+	}
+	want := []CatalogIngestStats{
+		{CatalogName: "ЕдиницыИзмерения", Items: 2, AvgAttributes: 1, NullNameRate: 0.5},
+		{CatalogName: "Номенклатура", Items: 1, AvgAttributes: 1},
+		{CatalogName: "Склады"},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("CollectIngestStats() = %+v, want %+v", stats, want)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+
+	service, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer service.Close()
+	started := time.Now().Add(-time.Hour)
+	for i, uuid := range []string{"uuid-1", "uuid-2", "uuid-3"} {
+		err := service.SaveUploadIngestStats(&UploadIngestStats{UploadUUID: uuid, DatabaseID: 7, Catalogs: stats[:i+1], CollectedAt: started.Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatalf("SaveUploadIngestStats() error = %v", err)
+		}
+	}
+	// Повторное сохранение заменяет статистику выгрузки
+	if err := service.SaveUploadIngestStats(&UploadIngestStats{UploadUUID: "uuid-3", DatabaseID: 7, Catalogs: stats, CollectedAt: started.Add(2 * time.Minute)}); err != nil {
+		t.Fatalf("SaveUploadIngestStats() error = %v", err)
+	}
+	saved, err := service.GetUploadIngestStats("uuid-3")
+	if err != nil || saved == nil || len(saved.Catalogs) != 3 {
+		t.Fatalf("GetUploadIngestStats() = %+v, %v", saved, err)
+	}
+
+	tests := []struct {
+		upload string
+		want   string
+	}{
+		{"uuid-3", "uuid-2"},
+		{"uuid-2", "uuid-1"},
+		{"uuid-1", ""},
+		{"uuid-new", "uuid-3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.upload, func(t *testing.T) {
+			previous, err := service.GetPreviousIngestStats(7, tt.upload)
+			if err != nil {
+				t.Fatalf("GetPreviousIngestStats() error = %v", err)
+			}
+			got := ""
+			if previous != nil {
+				got = previous.UploadUUID
+			}
+			if got != tt.want {
+				t.Errorf("previous of %s = %q, want %q", tt.upload, got, tt.want)
+			}
+		})
+	}
+
+	events := []*UploadEvent{
+		{UploadUUID: "uuid-3", EventType: UploadEventIngestStats, Message: "Принято"},
+		{UploadUUID: "uuid-3", EventType: UploadEventIngestAnomaly, Severity: "ERROR", Details: map[string]interface{}{"kind": IngestAnomalyItemsDrop}},
+		{UploadUUID: "uuid-2", EventType: UploadEventIngestStats},
+	}
+	for _, event := range events {
+		if err := service.RecordUploadEvent(event); err != nil {
+			t.Fatalf("RecordUploadEvent() error = %v", err)
+		}
+	}
+	if err := service.RecordUploadEvent(&UploadEvent{UploadUUID: "uuid-3"}); err == nil {
+		t.Error("RecordUploadEvent() must reject empty event type")
+	}
+	timeline, err := service.GetUploadEvents("uuid-3")
+	if err != nil {
+		t.Fatalf("GetUploadEvents() error = %v", err)
+	}
+	if len(timeline) != 2 || timeline[0].Severity != "INFO" || timeline[1].Details["kind"] != IngestAnomalyItemsDrop {
+		t.Errorf("timeline = %+v", timeline)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"regexp"
@@ -26,8 +27,14 @@ func ParseKpvedFile(filePath string) ([]KpvedEntry, error) {
 	}
 	defer file.Close()
 
+	return ParseKpved(file)
+}
+
+// ParseKpved разбирает классификатор КПВЭД в формате файла КПВЭД.txt: четыре строки заголовка,
+// далее "код<TAB>наименование", продолжение наименования - на следующих строках
+func ParseKpved(r io.Reader) ([]KpvedEntry, error) {
 	var entries []KpvedEntry
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 
 	// Регулярное выражение для кода КПВЭД
 	// Matches: A, 01, 01.1, 01.11, 01.11.1, 01.11.11
@@ -115,7 +122,7 @@ func ParseKpvedFile(filePath string) ([]KpvedEntry, error) {
 		return nil, fmt.Errorf("failed to read KPVED file: %w", err)
 	}
 
-	log.Printf("Parsed %d KPVED entries from file", len(entries))
+	log.Printf("Parsed %d KPVED entries", len(entries))
 	return entries, nil
 }
 
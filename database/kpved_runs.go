@@ -0,0 +1,403 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// Статусы запусков классификации КПВЭД
+const (
+	KpvedRunRunning     = "running"     // Выполняется
+	KpvedRunCompleted   = "completed"   // Все группы обработаны
+	KpvedRunStopped     = "stopped"     // Остановлен пользователем, необработанные группы ожидают продолжения
+	KpvedRunInterrupted = "interrupted" // Прерван остановкой сервера, необработанные группы ожидают продолжения
+)
+
+// Статусы групп запуска классификации КПВЭД
+const (
+	KpvedRunItemPending = "pending" // Ожидает классификации
+	KpvedRunItemDone    = "done"    // Код записан в normalized_data
+	KpvedRunItemFailed  = "failed"  // Классификация завершилась ошибкой
+)
+
+// ErrKpvedRunNotFound запуск классификации не найден
+var ErrKpvedRunNotFound = apperrors.NotFound("kpved_run_not_found", "kpved run not found")
+
+// ErrKpvedRunActive запуск классификации уже выполняется
+var ErrKpvedRunActive = apperrors.Conflict("kpved_run_active", "kpved run is already running")
+
+// KpvedRun запуск иерархической переклассификации КПВЭД с количеством групп по статусам
+type KpvedRun struct {
+	ID         int        `json:"id"`
+	Status     string     `json:"status"`
+	Model      string     `json:"model"`
+	Limit      int        `json:"limit"`
+	Total      int        `json:"total"`
+	Pending    int        `json:"pending"`
+	Done       int        `json:"done"`
+	Failed     int        `json:"failed"`
+	StartedAt  time.Time  `json:"started_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// KpvedRunItem группа нормализованных записей, классифицируемая в рамках запуска
+type KpvedRunItem struct {
+	NormalizedName string `json:"normalized_name"`
+	Category       string `json:"category"`
+	MergedCount    int    `json:"merged_count"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	KpvedCode      string `json:"kpved_code,omitempty"`
+}
+
+// KpvedRunResult результат классификации группы, записываемый в normalized_data
+type KpvedRunResult struct {
+	NormalizedName     string
+	Category           string
+	Code               string
+	Name               string
+	Confidence         float64
+	RawConfidence      float64
+	Model              string
+	ConfidenceDecision string
+}
+
+// CreateKpvedRunTables создает таблицы запусков классификации КПВЭД и отметок обработки групп.
+// Таблицы хранятся рядом с normalized_data, чтобы код группы и отметка записывались одной транзакцией
+func CreateKpvedRunTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS kpved_classification_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			status TEXT NOT NULL,
+			model TEXT NOT NULL DEFAULT '',
+			group_limit INTEGER NOT NULL DEFAULT 0,
+			total INTEGER NOT NULL DEFAULT 0,
+			started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			finished_at TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS kpved_classification_run_items (
+			run_id INTEGER NOT NULL REFERENCES kpved_classification_runs(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			normalized_name TEXT NOT NULL,
+			category TEXT NOT NULL,
+			merged_count INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL DEFAULT 'pending',
+			error TEXT NOT NULL DEFAULT '',
+			kpved_code TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (run_id, normalized_name, category)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_kpved_run_items_status ON kpved_classification_run_items(run_id, status, position);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create kpved run tables: %w", err)
+	}
+	return nil
+}
+
+// CreateKpvedRun сохраняет запуск классификации и его группы в статусе pending одной транзакцией
+func (db *DB) CreateKpvedRun(model string, limit int, items []KpvedRunItem) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO kpved_classification_runs (status, model, group_limit, total)
+		VALUES (?, ?, ?, ?)
+	`, KpvedRunRunning, model, limit, len(items))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create kpved run: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get kpved run id: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO kpved_classification_run_items (run_id, position, normalized_name, category, merged_count)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare kpved run items: %w", err)
+	}
+	defer stmt.Close()
+	for i, item := range items {
+		if _, err := stmt.Exec(id, i, item.NormalizedName, item.Category, item.MergedCount); err != nil {
+			return 0, fmt.Errorf("failed to save kpved run item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit kpved run: %w", err)
+	}
+	return int(id), nil
+}
+
+// ResumeKpvedRun переводит остановленный или прерванный запуск в статус running и возвращает
+// его необработанные группы в исходном порядке. Группы, которые уже получили код КПВЭД
+// (например, записанный до сбоя без отметки), отмечаются выполненными без повторной классификации.
+// При retryFailed группы, завершившиеся ошибкой, снова ставятся в очередь
+func (db *DB) ResumeKpvedRun(id int, retryFailed bool) ([]KpvedRunItem, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM kpved_classification_runs WHERE id = ?`, id).Scan(&status); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrKpvedRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get kpved run: %w", err)
+	}
+	if status == KpvedRunRunning {
+		return nil, ErrKpvedRunActive
+	}
+
+	if retryFailed {
+		if _, err := tx.Exec(`
+			UPDATE kpved_classification_run_items SET status = ?, error = '', updated_at = CURRENT_TIMESTAMP
+			WHERE run_id = ? AND status = ?
+		`, KpvedRunItemPending, id, KpvedRunItemFailed); err != nil {
+			return nil, fmt.Errorf("failed to requeue failed kpved run items: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`
+		UPDATE kpved_classification_run_items
+		SET status = ?, error = '', updated_at = CURRENT_TIMESTAMP,
+			kpved_code = COALESCE((
+				SELECT MAX(nd.kpved_code) FROM normalized_data nd
+				WHERE nd.normalized_name = kpved_classification_run_items.normalized_name
+					AND nd.category = kpved_classification_run_items.category
+			), '')
+		WHERE run_id = ? AND status = ? AND NOT EXISTS (
+			SELECT 1 FROM normalized_data nd
+			WHERE nd.normalized_name = kpved_classification_run_items.normalized_name
+				AND nd.category = kpved_classification_run_items.category
+				AND (nd.kpved_code IS NULL OR TRIM(nd.kpved_code) = '')
+		)
+	`, KpvedRunItemDone, id, KpvedRunItemPending); err != nil {
+		return nil, fmt.Errorf("failed to mark classified kpved run items: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT normalized_name, category, merged_count, status, error, kpved_code
+		FROM kpved_classification_run_items
+		WHERE run_id = ? AND status = ?
+		ORDER BY position
+	`, id, KpvedRunItemPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending kpved run items: %w", err)
+	}
+	var items []KpvedRunItem
+	for rows.Next() {
+		var item KpvedRunItem
+		if err := rows.Scan(&item.NormalizedName, &item.Category, &item.MergedCount, &item.Status, &item.Error, &item.KpvedCode); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan kpved run item: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kpved run items: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE kpved_classification_runs SET status = ?, finished_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, KpvedRunRunning, id); err != nil {
+		return nil, fmt.Errorf("failed to resume kpved run: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit kpved run resume: %w", err)
+	}
+	return items, nil
+}
+
+// ApplyKpvedRunResult записывает код КПВЭД во все записи группы и отмечает группу выполненной
+// одной транзакцией: после сбоя группа либо классифицирована и отмечена, либо остается в очереди.
+// Повторное применение того же результата не меняет данные. Возвращает результат обновления normalized_data
+func (db *DB) ApplyKpvedRunResult(ctx context.Context, runID int, result KpvedRunResult) (sql.Result, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updated, err := tx.ExecContext(ctx, `
+		UPDATE normalized_data
+		SET kpved_code = ?, kpved_name = ?, kpved_confidence = ?,
+		    kpved_raw_confidence = ?, kpved_model = ?, confidence_decision = ?
+		WHERE normalized_name = ? AND category = ?
+	`, result.Code, result.Name, result.Confidence, result.RawConfidence, result.Model, result.ConfidenceDecision,
+		result.NormalizedName, result.Category)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE kpved_classification_run_items SET status = ?, error = '', kpved_code = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE run_id = ? AND normalized_name = ? AND category = ?
+	`, KpvedRunItemDone, result.Code, runID, result.NormalizedName, result.Category); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE kpved_classification_runs SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, runID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// MarkKpvedRunItemFailed отмечает группу запуска, классификация которой завершилась ошибкой
+func (db *DB) MarkKpvedRunItemFailed(runID int, normalizedName, category, message string) error {
+	_, err := db.conn.Exec(`
+		UPDATE kpved_classification_run_items SET status = ?, error = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE run_id = ? AND normalized_name = ? AND category = ? AND status = ?
+	`, KpvedRunItemFailed, message, runID, normalizedName, category, KpvedRunItemPending)
+	if err != nil {
+		return fmt.Errorf("failed to mark kpved run item failed: %w", err)
+	}
+	return nil
+}
+
+// FinishKpvedRun завершает выполняющийся запуск со статусом completed или stopped
+func (db *DB) FinishKpvedRun(id int, status string) error {
+	_, err := db.conn.Exec(`
+		UPDATE kpved_classification_runs SET status = ?, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = ?
+	`, status, id, KpvedRunRunning)
+	if err != nil {
+		return fmt.Errorf("failed to finish kpved run: %w", err)
+	}
+	return nil
+}
+
+// InterruptKpvedRuns переводит запуски, оставшиеся в статусе running после остановки сервера,
+// в interrupted и возвращает их идентификаторы. Вызывается при запуске сервера до начала новых запусков
+func (db *DB) InterruptKpvedRuns() ([]int, error) {
+	rows, err := db.conn.Query(`SELECT id FROM kpved_classification_runs WHERE status = ? ORDER BY id`, KpvedRunRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running kpved runs: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan kpved run id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kpved runs: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := db.conn.Exec(`
+			UPDATE kpved_classification_runs SET status = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ? AND status = ?
+		`, KpvedRunInterrupted, id, KpvedRunRunning); err != nil {
+			return nil, fmt.Errorf("failed to interrupt kpved run %d: %w", id, err)
+		}
+	}
+	return ids, nil
+}
+
+// kpvedRunSelect выборка запусков с количеством групп по статусам
+const kpvedRunSelect = `
+	SELECT r.id, r.status, r.model, r.group_limit, r.total,
+		COALESCE(SUM(CASE WHEN i.status = 'pending' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN i.status = 'done' THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN i.status = 'failed' THEN 1 ELSE 0 END), 0),
+		r.started_at, r.updated_at, r.finished_at
+	FROM kpved_classification_runs r
+	LEFT JOIN kpved_classification_run_items i ON i.run_id = r.id
+`
+
+// scanKpvedRuns читает запуски выборки kpvedRunSelect
+func scanKpvedRuns(rows *sql.Rows) ([]KpvedRun, error) {
+	defer rows.Close()
+	runs := []KpvedRun{}
+	for rows.Next() {
+		var run KpvedRun
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &run.Status, &run.Model, &run.Limit, &run.Total,
+			&run.Pending, &run.Done, &run.Failed, &run.StartedAt, &run.UpdatedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan kpved run: %w", err)
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kpved runs: %w", err)
+	}
+	return runs, nil
+}
+
+// GetKpvedRun возвращает запуск классификации с количеством групп по статусам
+func (db *DB) GetKpvedRun(id int) (*KpvedRun, error) {
+	rows, err := db.conn.Query(kpvedRunSelect+` WHERE r.id = ? GROUP BY r.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kpved run: %w", err)
+	}
+	runs, err := scanKpvedRuns(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, ErrKpvedRunNotFound
+	}
+	return &runs[0], nil
+}
+
+// ListKpvedRuns возвращает последние запуски классификации, не более limit
+func (db *DB) ListKpvedRuns(limit int) ([]KpvedRun, error) {
+	rows, err := db.conn.Query(kpvedRunSelect+` GROUP BY r.id ORDER BY r.id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kpved runs: %w", err)
+	}
+	return scanKpvedRuns(rows)
+}
+
+// GetKpvedRunItems возвращает группы запуска с заданным статусом (пустой - все) в исходном порядке, не более limit
+func (db *DB) GetKpvedRunItems(runID int, status string, limit int) ([]KpvedRunItem, error) {
+	rows, err := db.conn.Query(`
+		SELECT normalized_name, category, merged_count, status, error, kpved_code
+		FROM kpved_classification_run_items
+		WHERE run_id = ? AND (? = '' OR status = ?)
+		ORDER BY position
+		LIMIT ?
+	`, runID, status, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kpved run items: %w", err)
+	}
+	defer rows.Close()
+	items := []KpvedRunItem{}
+	for rows.Next() {
+		var item KpvedRunItem
+		if err := rows.Scan(&item.NormalizedName, &item.Category, &item.MergedCount, &item.Status, &item.Error, &item.KpvedCode); err != nil {
+			return nil, fmt.Errorf("failed to scan kpved run item: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate kpved run items: %w", err)
+	}
+	return items, nil
+}
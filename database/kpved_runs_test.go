@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestKpvedRunResume(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	groups := []struct{ name, category string }{
+		{"болт м10", "крепеж"},
+		{"гайка м10", "крепеж"},
+		{"кабель ввг", "кабели"},
+		{"краска белая", "лкм"},
+	}
+	items := make([]KpvedRunItem, len(groups))
+	for i, g := range groups {
+		if _, err := db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category)
+			VALUES (?, ?, ?, ?, ?)`, g.name, g.name, g.name, g.name, g.category); err != nil {
+			t.Fatalf("Failed to insert normalized item: %v", err)
+		}
+		items[i] = KpvedRunItem{NormalizedName: g.name, Category: g.category, MergedCount: 1}
+	}
+
+	runID, err := db.CreateKpvedRun("model-a", 10, items)
+	if err != nil {
+		t.Fatalf("CreateKpvedRun() error = %v", err)
+	}
+	if _, err := db.ResumeKpvedRun(runID, false); !errors.Is(err, ErrKpvedRunActive) {
+		t.Errorf("ResumeKpvedRun() of running run error = %v, want ErrKpvedRunActive", err)
+	}
+
+	// Первая группа классифицирована и отмечена, вторая завершилась ошибкой
+	apply := KpvedRunResult{NormalizedName: "болт м10", Category: "крепеж", Code: "25.94.11", Name: "Болты", Confidence: 0.9}
+	for i := 0; i < 2; i++ {
+		if _, err := db.ApplyKpvedRunResult(context.Background(), runID, apply); err != nil {
+			t.Fatalf("ApplyKpvedRunResult() error = %v", err)
+		}
+	}
+	if err := db.MarkKpvedRunItemFailed(runID, "гайка м10", "крепеж", "ai call failed"); err != nil {
+		t.Fatalf("MarkKpvedRunItemFailed() error = %v", err)
+	}
+	// Третья группа получила код, но сервер остановился до отметки
+	if _, err := db.Exec(`UPDATE normalized_data SET kpved_code = '27.32.13' WHERE normalized_name = 'кабель ввг'`); err != nil {
+		t.Fatalf("Failed to update normalized item: %v", err)
+	}
+
+	interrupted, err := db.InterruptKpvedRuns()
+	if err != nil || len(interrupted) != 1 || interrupted[0] != runID {
+		t.Fatalf("InterruptKpvedRuns() = %v, %v, want [%d]", interrupted, err, runID)
+	}
+	run, err := db.GetKpvedRun(runID)
+	if err != nil {
+		t.Fatalf("GetKpvedRun() error = %v", err)
+	}
+	if run.Status != KpvedRunInterrupted || run.Total != 4 || run.Done != 1 || run.Failed != 1 || run.Pending != 2 {
+		t.Errorf("GetKpvedRun() = %+v, want interrupted with 1 done, 1 failed, 2 pending", run)
+	}
+
+	tests := []struct {
+		name        string
+		retryFailed bool
+		want        []string
+	}{
+		{"pending only", false, []string{"краска белая"}},
+		{"retry failed", true, []string{"гайка м10", "краска белая"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending, err := db.ResumeKpvedRun(runID, tt.retryFailed)
+			if err != nil {
+				t.Fatalf("ResumeKpvedRun() error = %v", err)
+			}
+			if len(pending) != len(tt.want) {
+				t.Fatalf("ResumeKpvedRun() = %+v, want %v", pending, tt.want)
+			}
+			for i, item := range pending {
+				if item.NormalizedName != tt.want[i] {
+					t.Errorf("pending[%d] = %q, want %q", i, item.NormalizedName, tt.want[i])
+				}
+			}
+			if err := db.FinishKpvedRun(runID, KpvedRunStopped); err != nil {
+				t.Fatalf("FinishKpvedRun() error = %v", err)
+			}
+		})
+	}
+
+	done, err := db.GetKpvedRunItems(runID, KpvedRunItemDone, 10)
+	if err != nil || len(done) != 2 || done[1].KpvedCode != "27.32.13" {
+		t.Errorf("GetKpvedRunItems(done) = %+v, %v, want classified groups with codes", done, err)
+	}
+	runs, err := db.ListKpvedRuns(10)
+	if err != nil || len(runs) != 1 || runs[0].Status != KpvedRunStopped || runs[0].FinishedAt == nil {
+		t.Errorf("ListKpvedRuns() = %+v, %v, want one stopped run", runs, err)
+	}
+	if _, err := db.ResumeKpvedRun(runID+1, false); !errors.Is(err, ErrKpvedRunNotFound) {
+		t.Errorf("ResumeKpvedRun() of missing run error = %v, want ErrKpvedRunNotFound", err)
+	}
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Типы событий происхождения нормализованной записи
+const (
+	LineageEventSource           = "source"            // Исходный элемент справочника
+	LineageEventRules            = "rules"             // Алгоритмическая нормализация (правила и паттерны)
+	LineageEventAICall           = "ai_call"           // Вызов AI нормализации
+	LineageEventClassification   = "classification"    // Классификация КПВЭД
+	LineageEventMerge            = "merge"             // Объединение дубликатов
+	LineageEventManualEdit       = "manual_edit"       // Ручное исправление аналитиком
+	LineageEventClientMapping    = "client_mapping"    // Соответствие кодов из файла клиента
+	LineageEventClientCorrection = "client_correction" // Исправление классификации из файла клиента
+)
+
+// LineageRecord событие происхождения нормализованной записи
+type LineageRecord struct {
+	ID               int       `json:"id"`
+	NormalizedItemID int       `json:"normalized_item_id"`
+	EventType        string    `json:"event_type"`
+	SourceItemID     *int      `json:"source_item_id,omitempty"`
+	SessionID        *int      `json:"session_id,omitempty"`
+	Reference        string    `json:"reference,omitempty"` // Таблица источника, правило, модель и т.п.
+	Details          string    `json:"details,omitempty"`   // JSON с подробностями события
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// LineageSource исходный элемент справочника с выгрузкой, из которой он пришел
+type LineageSource struct {
+	CatalogItemID int    `json:"catalog_item_id"`
+	Reference     string `json:"reference"`
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	CatalogName   string `json:"catalog_name,omitempty"`
+	UploadID      int    `json:"upload_id,omitempty"`
+	UploadUUID    string `json:"upload_uuid,omitempty"`
+}
+
+// LineageSession сессия версионированной нормализации исходного элемента со стадиями
+type LineageSession struct {
+	Session *NormalizationSession `json:"session"`
+	Stages  []*NormalizationStage `json:"stages"`
+}
+
+// NormalizedItemLineage полная цепочка происхождения нормализованной записи
+type NormalizedItemLineage struct {
+	Item     *NormalizedItem  `json:"item"`
+	Sources  []LineageSource  `json:"sources"`
+	Sessions []LineageSession `json:"sessions"`
+	Events   []*LineageRecord `json:"events"`
+}
+
+// NewLineageRecord создает событие происхождения с деталями в JSON
+func NewLineageRecord(eventType, reference string, sourceItemID int, details map[string]interface{}) *LineageRecord {
+	record := &LineageRecord{
+		EventType: eventType,
+		Reference: reference,
+	}
+	if sourceItemID > 0 {
+		id := sourceItemID
+		record.SourceItemID = &id
+	}
+	if len(details) > 0 {
+		if data, err := json.Marshal(details); err == nil {
+			record.Details = string(data)
+		}
+	}
+	return record
+}
+
+// CreateNormalizedItemLineageTable создает таблицу событий происхождения нормализованных записей
+func CreateNormalizedItemLineageTable(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS normalized_item_lineage (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			normalized_item_id INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			source_item_id INTEGER,
+			session_id INTEGER,
+			reference TEXT,
+			details TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_lineage_item_id ON normalized_item_lineage(normalized_item_id);
+		CREATE INDEX IF NOT EXISTS idx_lineage_source_item_id ON normalized_item_lineage(source_item_id);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create normalized_item_lineage table: %w", err)
+	}
+
+	return nil
+}
+
+// insertLineageRecords вставляет события происхождения в рамках транзакции
+func insertLineageRecords(tx *sql.Tx, normalizedItemID int, records []*LineageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO normalized_item_lineage
+		(normalized_item_id, event_type, source_item_id, session_id, reference, details)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare lineage statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		if _, err := stmt.Exec(normalizedItemID, record.EventType, record.SourceItemID, record.SessionID, record.Reference, record.Details); err != nil {
+			return fmt.Errorf("failed to insert lineage record: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AddNormalizedItemLineage добавляет события происхождения к существующей нормализованной записи
+func (db *DB) AddNormalizedItemLineage(normalizedItemID int, records []*LineageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertLineageRecords(tx, normalizedItemID, records); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetNormalizedItemLineage собирает цепочку происхождения нормализованной записи:
+// исходные элементы и выгрузки, сессии версионированной нормализации и сохраненные события
+func (db *DB) GetNormalizedItemLineage(normalizedItemID int) (*NormalizedItemLineage, error) {
+	item, err := db.GetNormalizedItemByID(normalizedItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage := &NormalizedItemLineage{
+		Item:     item,
+		Sources:  []LineageSource{},
+		Sessions: []LineageSession{},
+		Events:   []*LineageRecord{},
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, normalized_item_id, event_type, source_item_id, session_id,
+		       COALESCE(reference, ''), COALESCE(details, ''), created_at
+		FROM normalized_item_lineage
+		WHERE normalized_item_id = ?
+		ORDER BY id
+	`, normalizedItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lineage records: %w", err)
+	}
+
+	var sourceItemIDs []interface{}
+	seenSources := make(map[int]bool)
+	for rows.Next() {
+		record := &LineageRecord{}
+		var sourceItemID, sessionID sql.NullInt64
+		if err := rows.Scan(&record.ID, &record.NormalizedItemID, &record.EventType, &sourceItemID, &sessionID,
+			&record.Reference, &record.Details, &record.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan lineage record: %w", err)
+		}
+		if sourceItemID.Valid {
+			id := int(sourceItemID.Int64)
+			record.SourceItemID = &id
+			if !seenSources[id] {
+				seenSources[id] = true
+				sourceItemIDs = append(sourceItemIDs, id)
+			}
+		}
+		if sessionID.Valid {
+			id := int(sessionID.Int64)
+			record.SessionID = &id
+		}
+		lineage.Events = append(lineage.Events, record)
+	}
+	rows.Close()
+
+	if len(sourceItemIDs) == 0 {
+		return lineage, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(sourceItemIDs)), ",")
+
+	// Исходные элементы и выгрузки, из которых они пришли
+	sourceRows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT ci.id, COALESCE(ci.reference, ''), COALESCE(ci.code, ''), COALESCE(ci.name, ''),
+		       COALESCE(c.name, ''), COALESCE(u.id, 0), COALESCE(u.upload_uuid, '')
+		FROM catalog_items ci
+		LEFT JOIN catalogs c ON c.id = ci.catalog_id
+		LEFT JOIN uploads u ON u.id = c.upload_id
+		WHERE ci.id IN (%s)
+		ORDER BY ci.id
+	`, placeholders), sourceItemIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lineage sources: %w", err)
+	}
+	for sourceRows.Next() {
+		var source LineageSource
+		if err := sourceRows.Scan(&source.CatalogItemID, &source.Reference, &source.Code, &source.Name,
+			&source.CatalogName, &source.UploadID, &source.UploadUUID); err != nil {
+			sourceRows.Close()
+			return nil, fmt.Errorf("failed to scan lineage source: %w", err)
+		}
+		lineage.Sources = append(lineage.Sources, source)
+	}
+	sourceRows.Close()
+
+	// Сессии версионированной нормализации исходных элементов
+	sessionRows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT id FROM normalization_sessions WHERE catalog_item_id IN (%s) ORDER BY id
+	`, placeholders), sourceItemIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lineage sessions: %w", err)
+	}
+	var sessionIDs []int
+	for sessionRows.Next() {
+		var id int
+		if err := sessionRows.Scan(&id); err != nil {
+			sessionRows.Close()
+			return nil, fmt.Errorf("failed to scan lineage session: %w", err)
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	sessionRows.Close()
+
+	for _, sessionID := range sessionIDs {
+		session, err := db.GetNormalizationSession(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		stages, err := db.GetSessionHistory(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		lineage.Sessions = append(lineage.Sessions, LineageSession{Session: session, Stages: stages})
+	}
+
+	return lineage, nil
+}
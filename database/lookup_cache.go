@@ -0,0 +1,232 @@
+package database
+
+import (
+	"container/list"
+	"database/sql"
+	"sync"
+)
+
+// Размеры кешей горячих справочных запросов
+const (
+	uploadLookupCacheSize       = 1024
+	catalogTableLookupCacheSize = 1024
+)
+
+// LookupCacheStats статистика кеша справочных запросов
+type LookupCacheStats struct {
+	Name     string  `json:"name"`
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRate  float64 `json:"hit_rate"`
+	Size     int     `json:"size"`
+	Capacity int     `json:"capacity"`
+}
+
+// lruEntry элемент LRU кеша
+type lruEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+// lruCache потокобезопасный LRU кеш фиксированного размера
+type lruCache struct {
+	name     string
+	capacity int
+	mu       sync.Mutex
+	ll       *list.List
+	items    map[interface{}]*list.Element
+	hits     int64
+	misses   int64
+	onEvict  func(key, value interface{})
+}
+
+// newLRUCache создает LRU кеш с заданной емкостью
+func newLRUCache(name string, capacity int) *lruCache {
+	return &lruCache{
+		name:     name,
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[interface{}]*list.Element),
+	}
+}
+
+// get возвращает значение и отмечает его как недавно использованное
+func (c *lruCache) get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		c.hits++
+		return elem.Value.(*lruEntry).value, true
+	}
+	c.misses++
+	return nil, false
+}
+
+// put добавляет или заменяет значение, вытесняя самое старое при переполнении
+func (c *lruCache) put(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.ll.MoveToFront(elem)
+		elem.Value.(*lruEntry).value = value
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		entry := oldest.Value.(*lruEntry)
+		c.ll.Remove(oldest)
+		delete(c.items, entry.key)
+		if c.onEvict != nil {
+			c.onEvict(entry.key, entry.value)
+		}
+	}
+}
+
+// update изменяет значение на месте, если оно есть в кеше (не влияет на статистику)
+func (c *lruCache) update(key interface{}, fn func(value interface{})) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		fn(elem.Value.(*lruEntry).value)
+	}
+}
+
+// remove удаляет значение из кеша
+func (c *lruCache) remove(key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// stats возвращает статистику кеша
+func (c *lruCache) stats() LookupCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := LookupCacheStats{
+		Name:     c.name,
+		Hits:     c.hits,
+		Misses:   c.misses,
+		Size:     c.ll.Len(),
+		Capacity: c.capacity,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// uploadCacheKey ключ кеша выгрузок (соединение БД + UUID)
+type uploadCacheKey struct {
+	conn *sql.DB
+	uuid string
+}
+
+// uploadIDKey ключ индекса ID -> UUID
+type uploadIDKey struct {
+	conn *sql.DB
+	id   int
+}
+
+// catalogTableCacheKey ключ кеша маппинга справочник -> таблица
+type catalogTableCacheKey struct {
+	conn        *sql.DB
+	catalogName string
+}
+
+var (
+	uploadLookupCache       = newLRUCache("uploads", uploadLookupCacheSize)
+	catalogTableLookupCache = newLRUCache("catalog_tables", catalogTableLookupCacheSize)
+
+	// Индекс ID -> UUID для инвалидации и обновления счетчиков по ID выгрузки
+	uploadIDIndex      = make(map[uploadIDKey]string)
+	uploadIDIndexMutex sync.Mutex
+)
+
+func init() {
+	uploadLookupCache.onEvict = func(key, value interface{}) {
+		upload := value.(*Upload)
+		uploadIDIndexMutex.Lock()
+		delete(uploadIDIndex, uploadIDKey{conn: key.(uploadCacheKey).conn, id: upload.ID})
+		uploadIDIndexMutex.Unlock()
+	}
+}
+
+// GetLookupCacheStats возвращает статистику кешей справочных запросов
+func GetLookupCacheStats() []LookupCacheStats {
+	return []LookupCacheStats{
+		uploadLookupCache.stats(),
+		catalogTableLookupCache.stats(),
+	}
+}
+
+// cacheConn возвращает соединение, по которому ключуются кеши
+// (представление только для чтения использует кеш основной БД)
+func (db *DB) cacheConn() *sql.DB {
+	if db.primary != nil {
+		return db.primary
+	}
+	return db.conn
+}
+
+// getCachedUpload возвращает копию выгрузки из кеша
+func (db *DB) getCachedUpload(uuid string) (*Upload, bool) {
+	value, ok := uploadLookupCache.get(uploadCacheKey{conn: db.cacheConn(), uuid: uuid})
+	if !ok {
+		return nil, false
+	}
+	upload := *value.(*Upload)
+	return &upload, true
+}
+
+// cacheUpload сохраняет копию выгрузки в кеш
+func (db *DB) cacheUpload(upload *Upload) {
+	conn := db.cacheConn()
+	cached := *upload
+	uploadLookupCache.put(uploadCacheKey{conn: conn, uuid: upload.UploadUUID}, &cached)
+
+	uploadIDIndexMutex.Lock()
+	uploadIDIndex[uploadIDKey{conn: conn, id: upload.ID}] = upload.UploadUUID
+	uploadIDIndexMutex.Unlock()
+}
+
+// uploadCacheKeyByID находит ключ кеша по ID выгрузки
+func (db *DB) uploadCacheKeyByID(uploadID int) (uploadCacheKey, bool) {
+	conn := db.cacheConn()
+	uploadIDIndexMutex.Lock()
+	uuid, ok := uploadIDIndex[uploadIDKey{conn: conn, id: uploadID}]
+	uploadIDIndexMutex.Unlock()
+	return uploadCacheKey{conn: conn, uuid: uuid}, ok
+}
+
+// InvalidateUpload удаляет выгрузку из кеша. Нужно вызывать после прямых UPDATE таблицы uploads
+func (db *DB) InvalidateUpload(uploadID int) {
+	if key, ok := db.uploadCacheKeyByID(uploadID); ok {
+		uploadLookupCache.remove(key)
+	}
+}
+
+// adjustCachedUploadCounters обновляет счетчики выгрузки в кеше после успешной записи,
+// чтобы загрузка элементов не сбрасывала кеш на каждом запросе
+func (db *DB) adjustCachedUploadCounters(uploadID, constants, catalogs, items int) {
+	key, ok := db.uploadCacheKeyByID(uploadID)
+	if !ok {
+		return
+	}
+	uploadLookupCache.update(key, func(value interface{}) {
+		upload := value.(*Upload)
+		upload.TotalConstants += constants
+		upload.TotalCatalogs += catalogs
+		upload.TotalItems += items
+	})
+}
@@ -0,0 +1,500 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// nomenclatureCatalogName справочник catalog_items, элементы которого сверяются с nomenclature_items
+const nomenclatureCatalogName = "Номенклатура"
+
+// Режимы сверки номенклатуры
+const (
+	NomenclatureReconcileReport   = "report"   // Только отметить расхождения
+	NomenclatureReconcileBackfill = "backfill" // Дополнить недостающие строки противоположной таблицы
+)
+
+// Статусы сверки номенклатуры
+const (
+	NomenclatureReconcileRunning   = "running"
+	NomenclatureReconcileCompleted = "completed"
+	NomenclatureReconcileFailed    = "failed"
+)
+
+// Виды расхождений nomenclature_items и catalog_items
+const (
+	NomenclatureIssueCatalogMissing      = "catalog_missing"      // Ссылка есть только в nomenclature_items
+	NomenclatureIssueNomenclatureMissing = "nomenclature_missing" // Ссылка есть только в catalog_items
+	NomenclatureIssueMismatch            = "mismatch"             // Ссылка есть в обеих таблицах, код или наименование различаются
+)
+
+// Решения по расхождениям
+const (
+	NomenclatureIssueFlagged    = "flagged"
+	NomenclatureIssueBackfilled = "backfilled"
+)
+
+// ErrNomenclatureReconciliationNotFound сверка номенклатуры не найдена
+var ErrNomenclatureReconciliationNotFound = apperrors.NotFound("nomenclature_reconciliation_not_found", "nomenclature reconciliation not found")
+
+// NomenclatureReconciliation сверка nomenclature_items с элементами справочника "Номенклатура" в catalog_items.
+// Строки сопоставляются по ссылке внутри выгрузки; UploadID = 0 - все выгрузки, где заполнены обе таблицы
+type NomenclatureReconciliation struct {
+	ID                  int        `json:"id"`
+	UploadID            int        `json:"upload_id,omitempty"`
+	Mode                string     `json:"mode"`
+	Status              string     `json:"status"`
+	Error               string     `json:"error,omitempty"`
+	Uploads             int        `json:"uploads"`
+	NomenclatureRefs    int        `json:"nomenclature_refs"` // Различных ссылок в nomenclature_items
+	CatalogRefs         int        `json:"catalog_refs"`
+	Matched             int        `json:"matched"`
+	CatalogMissing      int        `json:"catalog_missing"`
+	NomenclatureMissing int        `json:"nomenclature_missing"`
+	Mismatched          int        `json:"mismatched"`
+	Backfilled          int        `json:"backfilled"`
+	StartedAt           time.Time  `json:"started_at"`
+	FinishedAt          *time.Time `json:"finished_at,omitempty"`
+}
+
+// NomenclatureReconciliationIssue расхождение, найденное сверкой
+type NomenclatureReconciliationIssue struct {
+	ID               int    `json:"id"`
+	RunID            int    `json:"run_id"`
+	UploadID         int    `json:"upload_id"`
+	Reference        string `json:"reference"`
+	Kind             string `json:"kind"`
+	NomenclatureCode string `json:"nomenclature_code,omitempty"`
+	NomenclatureName string `json:"nomenclature_name,omitempty"`
+	CatalogCode      string `json:"catalog_code,omitempty"`
+	CatalogName      string `json:"catalog_name,omitempty"`
+	Resolution       string `json:"resolution"`
+}
+
+// reconcileRow строка одной из сверяемых таблиц
+type reconcileRow struct {
+	code, name, attributes, tableParts string
+}
+
+// CreateNomenclatureReconciliationTables создает таблицы сверок номенклатуры и найденных расхождений
+func CreateNomenclatureReconciliationTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS nomenclature_reconciliations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			upload_id INTEGER NOT NULL DEFAULT 0,
+			mode TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			uploads INTEGER NOT NULL DEFAULT 0,
+			nomenclature_refs INTEGER NOT NULL DEFAULT 0,
+			catalog_refs INTEGER NOT NULL DEFAULT 0,
+			matched INTEGER NOT NULL DEFAULT 0,
+			catalog_missing INTEGER NOT NULL DEFAULT 0,
+			nomenclature_missing INTEGER NOT NULL DEFAULT 0,
+			mismatched INTEGER NOT NULL DEFAULT 0,
+			backfilled INTEGER NOT NULL DEFAULT 0,
+			started_at TIMESTAMP NOT NULL,
+			finished_at TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS nomenclature_reconciliation_issues (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id INTEGER NOT NULL,
+			upload_id INTEGER NOT NULL,
+			reference TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			nomenclature_code TEXT NOT NULL DEFAULT '',
+			nomenclature_name TEXT NOT NULL DEFAULT '',
+			catalog_code TEXT NOT NULL DEFAULT '',
+			catalog_name TEXT NOT NULL DEFAULT '',
+			resolution TEXT NOT NULL,
+			FOREIGN KEY(run_id) REFERENCES nomenclature_reconciliations(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_nomenclature_reconciliation_issues_run ON nomenclature_reconciliation_issues(run_id, kind);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create nomenclature reconciliation tables: %w", err)
+	}
+	return nil
+}
+
+// CreateNomenclatureReconciliation проверяет параметры и сохраняет сверку со статусом running;
+// сверка выполняется RunNomenclatureReconciliation
+func (db *DB) CreateNomenclatureReconciliation(uploadID int, mode string) (*NomenclatureReconciliation, error) {
+	if mode == "" {
+		mode = NomenclatureReconcileReport
+	}
+	if mode != NomenclatureReconcileReport && mode != NomenclatureReconcileBackfill {
+		return nil, apperrors.Validation("invalid_reconcile_mode", fmt.Sprintf("mode must be %s or %s", NomenclatureReconcileReport, NomenclatureReconcileBackfill))
+	}
+	if uploadID < 0 {
+		return nil, apperrors.Validation("invalid_upload_id", "upload_id must not be negative")
+	}
+	if uploadID > 0 {
+		var exists int
+		if err := db.conn.QueryRow("SELECT COUNT(*) FROM uploads WHERE id = ?", uploadID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check upload: %w", err)
+		}
+		if exists == 0 {
+			return nil, apperrors.NotFound("upload_not_found", fmt.Sprintf("upload %d not found", uploadID))
+		}
+	}
+
+	run := &NomenclatureReconciliation{UploadID: uploadID, Mode: mode, Status: NomenclatureReconcileRunning, StartedAt: time.Now()}
+	result, err := db.conn.Exec(`
+		INSERT INTO nomenclature_reconciliations (upload_id, mode, status, started_at) VALUES (?, ?, ?, ?)
+	`, run.UploadID, run.Mode, run.Status, run.StartedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nomenclature reconciliation: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nomenclature reconciliation ID: %w", err)
+	}
+	run.ID = int(id)
+	return run, nil
+}
+
+// RunNomenclatureReconciliation выполняет сверку и сохраняет итог. Каждая выгрузка сверяется в отдельной
+// транзакции: при отмене ctx уже сверенные выгрузки остаются в итогах, сверка завершается ошибкой.
+// Расхождения кода и наименования только отмечаются: какая из таблиц верна, сверка не решает
+func (db *DB) RunNomenclatureReconciliation(ctx context.Context, run *NomenclatureReconciliation) error {
+	runErr := db.reconcileNomenclatureUploads(ctx, run)
+	if err := db.FinishNomenclatureReconciliation(run, runErr); err != nil {
+		return err
+	}
+	return runErr
+}
+
+// FinishNomenclatureReconciliation сохраняет итог сверки; runErr != nil - сверка завершилась ошибкой
+func (db *DB) FinishNomenclatureReconciliation(run *NomenclatureReconciliation, runErr error) error {
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Status = NomenclatureReconcileCompleted
+	if runErr != nil {
+		run.Status = NomenclatureReconcileFailed
+		run.Error = runErr.Error()
+	}
+	_, err := db.conn.Exec(`
+		UPDATE nomenclature_reconciliations
+		SET status = ?, error = ?, uploads = ?, nomenclature_refs = ?, catalog_refs = ?, matched = ?,
+		    catalog_missing = ?, nomenclature_missing = ?, mismatched = ?, backfilled = ?, finished_at = ?
+		WHERE id = ?
+	`, run.Status, run.Error, run.Uploads, run.NomenclatureRefs, run.CatalogRefs, run.Matched,
+		run.CatalogMissing, run.NomenclatureMissing, run.Mismatched, run.Backfilled, finished, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save nomenclature reconciliation: %w", err)
+	}
+	return nil
+}
+
+// reconcileNomenclatureUploads сверяет выбранную выгрузку или все выгрузки с данными в обеих таблицах.
+// Выгрузки, где заполнена только одна таблица, при сверке всех выгрузок пропускаются: в них номенклатура
+// передана одним способом, и это не расхождение
+func (db *DB) reconcileNomenclatureUploads(ctx context.Context, run *NomenclatureReconciliation) error {
+	uploadIDs := []int{run.UploadID}
+	if run.UploadID == 0 {
+		rows, err := db.conn.Query(`
+			SELECT u.id FROM uploads u
+			WHERE EXISTS (SELECT 1 FROM nomenclature_items ni WHERE ni.upload_id = u.id)
+			  AND EXISTS (
+			      SELECT 1 FROM catalog_items ci JOIN catalogs c ON c.id = ci.catalog_id
+			      WHERE c.upload_id = u.id AND c.name = ?
+			  )
+			ORDER BY u.id
+		`, nomenclatureCatalogName)
+		if err != nil {
+			return fmt.Errorf("failed to select uploads for reconciliation: %w", err)
+		}
+		uploadIDs = nil
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan upload ID: %w", err)
+			}
+			uploadIDs = append(uploadIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to select uploads for reconciliation: %w", err)
+		}
+	}
+
+	for _, uploadID := range uploadIDs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := db.reconcileNomenclatureUpload(run, uploadID); err != nil {
+			return err
+		}
+		run.Uploads++
+	}
+	return nil
+}
+
+// loadReconcileRows загружает строки таблицы по ссылке; для повторяющихся ссылок (характеристики
+// номенклатуры) берется первая строка
+func loadReconcileRows(tx *sql.Tx, query string, args ...interface{}) (map[string]reconcileRow, []string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	result := map[string]reconcileRow{}
+	var order []string
+	for rows.Next() {
+		var reference string
+		var row reconcileRow
+		if err := rows.Scan(&reference, &row.code, &row.name, &row.attributes, &row.tableParts); err != nil {
+			return nil, nil, err
+		}
+		if _, ok := result[reference]; ok {
+			continue
+		}
+		result[reference] = row
+		order = append(order, reference)
+	}
+	return result, order, rows.Err()
+}
+
+// reconcileNomenclatureUpload сверяет одну выгрузку и записывает расхождения
+func (db *DB) reconcileNomenclatureUpload(run *NomenclatureReconciliation, uploadID int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	nomenclature, nomenclatureOrder, err := loadReconcileRows(tx, `
+		SELECT nomenclature_reference, COALESCE(nomenclature_code, ''), COALESCE(nomenclature_name, ''),
+		       COALESCE(attributes_xml, ''), COALESCE(table_parts_xml, '')
+		FROM nomenclature_items WHERE upload_id = ? ORDER BY id
+	`, uploadID)
+	if err != nil {
+		return fmt.Errorf("failed to load nomenclature items of upload %d: %w", uploadID, err)
+	}
+	catalog, catalogOrder, err := loadReconcileRows(tx, `
+		SELECT ci.reference, COALESCE(ci.code, ''), COALESCE(ci.name, ''),
+		       COALESCE(ci.attributes_xml, ''), COALESCE(ci.table_parts_xml, '')
+		FROM catalog_items ci JOIN catalogs c ON c.id = ci.catalog_id
+		WHERE c.upload_id = ? AND c.name = ? ORDER BY ci.id
+	`, uploadID, nomenclatureCatalogName)
+	if err != nil {
+		return fmt.Errorf("failed to load catalog items of upload %d: %w", uploadID, err)
+	}
+	run.NomenclatureRefs += len(nomenclature)
+	run.CatalogRefs += len(catalog)
+
+	issueStmt, err := tx.Prepare(`
+		INSERT INTO nomenclature_reconciliation_issues
+		(run_id, upload_id, reference, kind, nomenclature_code, nomenclature_name, catalog_code, catalog_name, resolution)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare reconciliation issue statement: %w", err)
+	}
+	defer issueStmt.Close()
+
+	backfill := run.Mode == NomenclatureReconcileBackfill
+	resolution := NomenclatureIssueFlagged
+	if backfill {
+		resolution = NomenclatureIssueBackfilled
+	}
+	catalogID, catalogsAdded := 0, 0
+	itemsAdded := 0
+
+	for _, reference := range nomenclatureOrder {
+		item := nomenclature[reference]
+		other, ok := catalog[reference]
+		if ok {
+			if item.code == other.code && item.name == other.name {
+				run.Matched++
+				continue
+			}
+			run.Mismatched++
+			if _, err := issueStmt.Exec(run.ID, uploadID, reference, NomenclatureIssueMismatch, item.code, item.name,
+				other.code, other.name, NomenclatureIssueFlagged); err != nil {
+				return fmt.Errorf("failed to save reconciliation issue: %w", err)
+			}
+			continue
+		}
+
+		run.CatalogMissing++
+		if backfill {
+			if catalogID == 0 {
+				if catalogID, catalogsAdded, err = nomenclatureCatalogForBackfill(tx, uploadID); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO catalog_items (catalog_id, reference, code, name, attributes_xml, table_parts_xml)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, catalogID, reference, item.code, item.name, item.attributes, item.tableParts); err != nil {
+				return fmt.Errorf("failed to backfill catalog item %s: %w", reference, err)
+			}
+			itemsAdded++
+			run.Backfilled++
+		}
+		if _, err := issueStmt.Exec(run.ID, uploadID, reference, NomenclatureIssueCatalogMissing, item.code, item.name,
+			"", "", resolution); err != nil {
+			return fmt.Errorf("failed to save reconciliation issue: %w", err)
+		}
+	}
+
+	for _, reference := range catalogOrder {
+		if _, ok := nomenclature[reference]; ok {
+			continue
+		}
+		item := catalog[reference]
+		run.NomenclatureMissing++
+		if backfill {
+			if _, err := tx.Exec(`
+				INSERT INTO nomenclature_items (upload_id, nomenclature_reference, nomenclature_code, nomenclature_name, attributes_xml, table_parts_xml)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, uploadID, reference, item.code, item.name, item.attributes, item.tableParts); err != nil {
+				return fmt.Errorf("failed to backfill nomenclature item %s: %w", reference, err)
+			}
+			itemsAdded++
+			run.Backfilled++
+		}
+		if _, err := issueStmt.Exec(run.ID, uploadID, reference, NomenclatureIssueNomenclatureMissing, "", "",
+			item.code, item.name, resolution); err != nil {
+			return fmt.Errorf("failed to save reconciliation issue: %w", err)
+		}
+	}
+
+	if itemsAdded > 0 {
+		if _, err := tx.Exec("UPDATE uploads SET total_items = total_items + ? WHERE id = ?", itemsAdded, uploadID); err != nil {
+			return fmt.Errorf("failed to update items counter: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reconciliation of upload %d: %w", uploadID, err)
+	}
+	if itemsAdded > 0 || catalogsAdded > 0 {
+		db.adjustCachedUploadCounters(uploadID, 0, catalogsAdded, itemsAdded)
+	}
+	return nil
+}
+
+// nomenclatureCatalogForBackfill возвращает справочник "Номенклатура" выгрузки, создавая его при отсутствии
+func nomenclatureCatalogForBackfill(tx *sql.Tx, uploadID int) (id int, added int, err error) {
+	err = tx.QueryRow("SELECT id FROM catalogs WHERE upload_id = ? AND name = ? ORDER BY id LIMIT 1", uploadID, nomenclatureCatalogName).Scan(&id)
+	if err == nil {
+		return id, 0, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("failed to find nomenclature catalog: %w", err)
+	}
+
+	result, err := tx.Exec("INSERT INTO catalogs (upload_id, name, synonym) VALUES (?, ?, ?)", uploadID, nomenclatureCatalogName, nomenclatureCatalogName)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create nomenclature catalog: %w", err)
+	}
+	catalogID, err := result.LastInsertId()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get catalog ID: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE uploads SET total_catalogs = total_catalogs + 1 WHERE id = ?", uploadID); err != nil {
+		return 0, 0, fmt.Errorf("failed to update catalogs counter: %w", err)
+	}
+	return int(catalogID), 1, nil
+}
+
+// scanNomenclatureReconciliation читает строку сверки
+func scanNomenclatureReconciliation(scanner interface{ Scan(...interface{}) error }) (*NomenclatureReconciliation, error) {
+	run := &NomenclatureReconciliation{}
+	var finishedAt sql.NullTime
+	if err := scanner.Scan(&run.ID, &run.UploadID, &run.Mode, &run.Status, &run.Error, &run.Uploads, &run.NomenclatureRefs,
+		&run.CatalogRefs, &run.Matched, &run.CatalogMissing, &run.NomenclatureMissing, &run.Mismatched, &run.Backfilled,
+		&run.StartedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return run, nil
+}
+
+// nomenclatureReconciliationColumns колонки сверки в порядке scanNomenclatureReconciliation
+const nomenclatureReconciliationColumns = `id, upload_id, mode, status, error, uploads, nomenclature_refs, catalog_refs,
+	matched, catalog_missing, nomenclature_missing, mismatched, backfilled, started_at, finished_at`
+
+// GetNomenclatureReconciliation возвращает сверку по ID
+func (db *DB) GetNomenclatureReconciliation(id int) (*NomenclatureReconciliation, error) {
+	run, err := scanNomenclatureReconciliation(db.conn.QueryRow(
+		`SELECT `+nomenclatureReconciliationColumns+` FROM nomenclature_reconciliations WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNomenclatureReconciliationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nomenclature reconciliation: %w", err)
+	}
+	return run, nil
+}
+
+// GetNomenclatureReconciliations возвращает последние сверки, новые первыми
+func (db *DB) GetNomenclatureReconciliations(limit int) ([]*NomenclatureReconciliation, error) {
+	rows, err := db.conn.Query(`SELECT `+nomenclatureReconciliationColumns+`
+		FROM nomenclature_reconciliations ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nomenclature reconciliations: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*NomenclatureReconciliation{}
+	for rows.Next() {
+		run, err := scanNomenclatureReconciliation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan nomenclature reconciliation: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// GetNomenclatureReconciliationIssues возвращает расхождения сверки (kind "" - все виды) и их общее число
+func (db *DB) GetNomenclatureReconciliationIssues(runID int, kind string, limit, offset int) ([]*NomenclatureReconciliationIssue, int, error) {
+	where := "WHERE run_id = ?"
+	args := []interface{}{runID}
+	if kind != "" {
+		where += " AND kind = ?"
+		args = append(args, kind)
+	}
+
+	var total int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM nomenclature_reconciliation_issues "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reconciliation issues: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, run_id, upload_id, reference, kind, nomenclature_code, nomenclature_name, catalog_code, catalog_name, resolution
+		FROM nomenclature_reconciliation_issues `+where+`
+		ORDER BY id LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get reconciliation issues: %w", err)
+	}
+	defer rows.Close()
+
+	issues := []*NomenclatureReconciliationIssue{}
+	for rows.Next() {
+		issue := &NomenclatureReconciliationIssue{}
+		if err := rows.Scan(&issue.ID, &issue.RunID, &issue.UploadID, &issue.Reference, &issue.Kind, &issue.NomenclatureCode,
+			&issue.NomenclatureName, &issue.CatalogCode, &issue.CatalogName, &issue.Resolution); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan reconciliation issue: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate reconciliation issues: %w", err)
+	}
+	return issues, total, nil
+}
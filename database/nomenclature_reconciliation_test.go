@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+// seedNomenclatureReconciliation создает выгрузку с расхождениями nomenclature_items и catalog_items
+func seedNomenclatureReconciliation(t *testing.T, db *DB, uuid string) int {
+	upload, err := db.CreateUpload(uuid, "8.3", "УТ")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "Номенклатура")
+	if err != nil {
+		t.Fatalf("AddCatalog() error = %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO nomenclature_items (upload_id, nomenclature_reference, nomenclature_code, nomenclature_name, characteristic_reference) VALUES
+			(?, 'ref-1', '001', 'Болт М8', 'char-1'),
+			(?, 'ref-1', '001', 'Болт М8', 'char-2'),
+			(?, 'ref-2', '002', 'Гайка М8', NULL),
+			(?, 'ref-3', '003', 'Шайба', NULL)
+	`, upload.ID, upload.ID, upload.ID, upload.ID); err != nil {
+		t.Fatalf("Failed to seed nomenclature_items: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO catalog_items (catalog_id, reference, code, name) VALUES
+			(?, 'ref-1', '001', 'Болт М8'),
+			(?, 'ref-2', '002', 'Гайка М10'),
+			(?, 'ref-4', '004', 'Винт')
+	`, catalog.ID, catalog.ID, catalog.ID); err != nil {
+		t.Fatalf("Failed to seed catalog_items: %v", err)
+	}
+	return upload.ID
+}
+
+func TestNomenclatureReconciliation(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           string
+		wantResolution string
+		wantBackfilled int
+		wantSecondRun  int // Расхождений при повторной сверке
+	}{
+		{"report", NomenclatureReconcileReport, NomenclatureIssueFlagged, 0, 3},
+		{"backfill", NomenclatureReconcileBackfill, NomenclatureIssueBackfilled, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+			if err != nil {
+				t.Fatalf("Failed to create database: %v", err)
+			}
+			defer db.Close()
+			uploadID := seedNomenclatureReconciliation(t, db, "reconcile-"+tt.name)
+			// Выгрузка только с nomenclature_items при сверке всех выгрузок пропускается
+			single, err := db.CreateUpload("single-"+tt.name, "8.3", "УТ")
+			if err != nil {
+				t.Fatalf("CreateUpload() error = %v", err)
+			}
+			if _, err := db.Exec(`INSERT INTO nomenclature_items (upload_id, nomenclature_reference) VALUES (?, 'ref-9')`, single.ID); err != nil {
+				t.Fatalf("Failed to seed nomenclature_items: %v", err)
+			}
+
+			run, err := db.CreateNomenclatureReconciliation(0, tt.mode)
+			if err != nil {
+				t.Fatalf("CreateNomenclatureReconciliation() error = %v", err)
+			}
+			if err := db.RunNomenclatureReconciliation(context.Background(), run); err != nil {
+				t.Fatalf("RunNomenclatureReconciliation() error = %v", err)
+			}
+			saved, err := db.GetNomenclatureReconciliation(run.ID)
+			if err != nil {
+				t.Fatalf("GetNomenclatureReconciliation() error = %v", err)
+			}
+			if saved.Status != NomenclatureReconcileCompleted || saved.Uploads != 1 || saved.NomenclatureRefs != 3 || saved.CatalogRefs != 3 ||
+				saved.Matched != 1 || saved.CatalogMissing != 1 || saved.NomenclatureMissing != 1 || saved.Mismatched != 1 ||
+				saved.Backfilled != tt.wantBackfilled || saved.FinishedAt == nil {
+				t.Errorf("reconciliation = %+v", saved)
+			}
+
+			issues, total, err := db.GetNomenclatureReconciliationIssues(run.ID, NomenclatureIssueCatalogMissing, 10, 0)
+			if err != nil {
+				t.Fatalf("GetNomenclatureReconciliationIssues() error = %v", err)
+			}
+			if total != 1 || issues[0].Reference != "ref-3" || issues[0].UploadID != uploadID || issues[0].Resolution != tt.wantResolution {
+				t.Errorf("catalog_missing issues = %d %+v", total, issues)
+			}
+			if _, total, _ := db.GetNomenclatureReconciliationIssues(run.ID, "", 10, 0); total != 3 {
+				t.Errorf("all issues = %d, want 3", total)
+			}
+
+			// После дополнения остается только расхождение наименования
+			second, err := db.CreateNomenclatureReconciliation(uploadID, NomenclatureReconcileReport)
+			if err != nil {
+				t.Fatalf("CreateNomenclatureReconciliation() error = %v", err)
+			}
+			if err := db.RunNomenclatureReconciliation(context.Background(), second); err != nil {
+				t.Fatalf("RunNomenclatureReconciliation() error = %v", err)
+			}
+			if issues := second.CatalogMissing + second.NomenclatureMissing + second.Mismatched; issues != tt.wantSecondRun {
+				t.Errorf("second run issues = %d, want %d: %+v", issues, tt.wantSecondRun, second)
+			}
+		})
+	}
+}
+
+func TestCreateNomenclatureReconciliationValidation(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name     string
+		uploadID int
+		mode     string
+	}{
+		{"unknown mode", 0, "delete"},
+		{"negative upload", -1, ""},
+		{"missing upload", 42, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.CreateNomenclatureReconciliation(tt.uploadID, tt.mode); err == nil {
+				t.Error("CreateNomenclatureReconciliation() error = nil")
+			}
+		})
+	}
+	if _, err := db.GetNomenclatureReconciliation(1); err != ErrNomenclatureReconciliationNotFound {
+		t.Errorf("GetNomenclatureReconciliation() error = %v, want ErrNomenclatureReconciliationNotFound", err)
+	}
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Виды записей словарей нормализации
+const (
+	DictionaryKindAbbreviation = "abbreviation" // Сокращение и его полная форма
+	DictionaryKindStopWord     = "stop_word"    // Слово, не учитываемое при сравнении наименований
+	DictionaryKindProtected    = "protected"    // Токен, который нельзя изменять при нормализации
+)
+
+// NormalizationDictionaryEntry запись словаря нормализации проекта
+type NormalizationDictionaryEntry struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"project_id"`
+	Kind        string    `json:"kind"`
+	Term        string    `json:"term"`
+	Replacement string    `json:"replacement,omitempty"` // Полная форма для сокращений
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// IsValidDictionaryKind проверяет вид записи словаря
+func IsValidDictionaryKind(kind string) bool {
+	switch kind {
+	case DictionaryKindAbbreviation, DictionaryKindStopWord, DictionaryKindProtected:
+		return true
+	}
+	return false
+}
+
+// CreateNormalizationDictionariesTable создает таблицу словарей нормализации проектов
+func CreateNormalizationDictionariesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS normalization_dictionary_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			project_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			term TEXT NOT NULL,
+			replacement TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(project_id, kind, term),
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_normalization_dictionary_project ON normalization_dictionary_entries(project_id, kind);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create normalization_dictionary_entries table: %w", err)
+	}
+	return nil
+}
+
+// validateDictionaryEntry проверяет и приводит запись словаря к хранимому виду
+func validateDictionaryEntry(entry *NormalizationDictionaryEntry) error {
+	entry.Kind = strings.TrimSpace(entry.Kind)
+	entry.Term = strings.TrimSpace(entry.Term)
+	entry.Replacement = strings.TrimSpace(entry.Replacement)
+	if !IsValidDictionaryKind(entry.Kind) {
+		return fmt.Errorf("invalid dictionary kind %q", entry.Kind)
+	}
+	if entry.Term == "" {
+		return fmt.Errorf("dictionary term is required")
+	}
+	if entry.Kind == DictionaryKindAbbreviation {
+		if entry.Replacement == "" {
+			return fmt.Errorf("abbreviation %q requires replacement", entry.Term)
+		}
+	} else {
+		entry.Replacement = ""
+	}
+	return nil
+}
+
+// CreateNormalizationDictionaryEntry добавляет запись в словарь проекта
+func (db *ServiceDB) CreateNormalizationDictionaryEntry(entry *NormalizationDictionaryEntry) error {
+	if err := validateDictionaryEntry(entry); err != nil {
+		return err
+	}
+	now := time.Now()
+	result, err := db.conn.Exec(`
+		INSERT INTO normalization_dictionary_entries (project_id, kind, term, replacement, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, entry.ProjectID, entry.Kind, entry.Term, entry.Replacement, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create dictionary entry: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get dictionary entry id: %w", err)
+	}
+	entry.ID = int(id)
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	return nil
+}
+
+// GetNormalizationDictionaryEntry возвращает запись словаря проекта
+func (db *ServiceDB) GetNormalizationDictionaryEntry(projectID, id int) (*NormalizationDictionaryEntry, error) {
+	entry := &NormalizationDictionaryEntry{}
+	err := db.conn.QueryRow(`
+		SELECT id, project_id, kind, term, replacement, created_at, updated_at
+		FROM normalization_dictionary_entries
+		WHERE id = ? AND project_id = ?
+	`, id, projectID).Scan(&entry.ID, &entry.ProjectID, &entry.Kind, &entry.Term, &entry.Replacement,
+		&entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("dictionary entry not found")
+		}
+		return nil, fmt.Errorf("failed to get dictionary entry: %w", err)
+	}
+	return entry, nil
+}
+
+// GetNormalizationDictionaryEntries возвращает записи словаря проекта, kind пустой - все виды
+func (db *ServiceDB) GetNormalizationDictionaryEntries(projectID int, kind string) ([]*NormalizationDictionaryEntry, error) {
+	query := `
+		SELECT id, project_id, kind, term, replacement, created_at, updated_at
+		FROM normalization_dictionary_entries
+		WHERE project_id = ?`
+	args := []interface{}{projectID}
+	if kind != "" {
+		query += " AND kind = ?"
+		args = append(args, kind)
+	}
+	query += " ORDER BY kind, term"
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dictionary entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*NormalizationDictionaryEntry{}
+	for rows.Next() {
+		entry := &NormalizationDictionaryEntry{}
+		if err := rows.Scan(&entry.ID, &entry.ProjectID, &entry.Kind, &entry.Term, &entry.Replacement,
+			&entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dictionary entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// UpdateNormalizationDictionaryEntry изменяет запись словаря проекта
+func (db *ServiceDB) UpdateNormalizationDictionaryEntry(entry *NormalizationDictionaryEntry) error {
+	if err := validateDictionaryEntry(entry); err != nil {
+		return err
+	}
+	now := time.Now()
+	result, err := db.conn.Exec(`
+		UPDATE normalization_dictionary_entries
+		SET kind = ?, term = ?, replacement = ?, updated_at = ?
+		WHERE id = ? AND project_id = ?
+	`, entry.Kind, entry.Term, entry.Replacement, now, entry.ID, entry.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to update dictionary entry: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("dictionary entry not found")
+	}
+	entry.UpdatedAt = now
+	return nil
+}
+
+// DeleteNormalizationDictionaryEntry удаляет запись словаря проекта
+func (db *ServiceDB) DeleteNormalizationDictionaryEntry(projectID, id int) error {
+	result, err := db.conn.Exec(`DELETE FROM normalization_dictionary_entries WHERE id = ? AND project_id = ?`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dictionary entry: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("dictionary entry not found")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestNormalizationDictionaryEntries(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	invalid := []struct {
+		name  string
+		entry NormalizationDictionaryEntry
+	}{
+		{"unknown kind", NormalizationDictionaryEntry{Kind: "synonym", Term: "нерж"}},
+		{"empty term", NormalizationDictionaryEntry{Kind: DictionaryKindStopWord, Term: "  "}},
+		{"abbreviation without replacement", NormalizationDictionaryEntry{Kind: DictionaryKindAbbreviation, Term: "нерж"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := tt.entry
+			entry.ProjectID = project.ID
+			if err := db.CreateNormalizationDictionaryEntry(&entry); err == nil {
+				t.Errorf("CreateNormalizationDictionaryEntry(%+v) expected error", tt.entry)
+			}
+		})
+	}
+
+	abbreviation := &NormalizationDictionaryEntry{ProjectID: project.ID, Kind: DictionaryKindAbbreviation, Term: " нерж. ", Replacement: "нержавеющий"}
+	if err := db.CreateNormalizationDictionaryEntry(abbreviation); err != nil {
+		t.Fatalf("CreateNormalizationDictionaryEntry() error = %v", err)
+	}
+	protected := &NormalizationDictionaryEntry{ProjectID: project.ID, Kind: DictionaryKindProtected, Term: "ВВГнг", Replacement: "ignored"}
+	if err := db.CreateNormalizationDictionaryEntry(protected); err != nil {
+		t.Fatalf("CreateNormalizationDictionaryEntry() error = %v", err)
+	}
+	if protected.Replacement != "" {
+		t.Errorf("protected token replacement = %q, want empty", protected.Replacement)
+	}
+	duplicate := &NormalizationDictionaryEntry{ProjectID: project.ID, Kind: DictionaryKindProtected, Term: "ВВГнг"}
+	if err := db.CreateNormalizationDictionaryEntry(duplicate); err == nil {
+		t.Error("duplicate entry expected error")
+	}
+
+	entries, err := db.GetNormalizationDictionaryEntries(project.ID, DictionaryKindAbbreviation)
+	if err != nil || len(entries) != 1 || entries[0].Term != "нерж." {
+		t.Fatalf("GetNormalizationDictionaryEntries() = %+v, error = %v", entries, err)
+	}
+
+	abbreviation.Replacement = "нержавеющая"
+	if err := db.UpdateNormalizationDictionaryEntry(abbreviation); err != nil {
+		t.Fatalf("UpdateNormalizationDictionaryEntry() error = %v", err)
+	}
+	got, err := db.GetNormalizationDictionaryEntry(project.ID, abbreviation.ID)
+	if err != nil || got.Replacement != "нержавеющая" {
+		t.Errorf("GetNormalizationDictionaryEntry() = %+v, error = %v", got, err)
+	}
+	if _, err := db.GetNormalizationDictionaryEntry(project.ID+1, abbreviation.ID); err == nil {
+		t.Error("entry of another project expected not found")
+	}
+
+	if err := db.DeleteNormalizationDictionaryEntry(project.ID, protected.ID); err != nil {
+		t.Fatalf("DeleteNormalizationDictionaryEntry() error = %v", err)
+	}
+	if err := db.DeleteNormalizationDictionaryEntry(project.ID, protected.ID); err == nil {
+		t.Error("second delete expected error")
+	}
+	if entries, _ := db.GetNormalizationDictionaryEntries(project.ID, ""); len(entries) != 1 {
+		t.Errorf("entries after delete = %d, want 1", len(entries))
+	}
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Уровни событий нормализации в истории
+const (
+	NormalizationEventInfo  = "INFO"
+	NormalizationEventWarn  = "WARN"
+	NormalizationEventError = "ERROR"
+)
+
+// NormalizationEvent событие нормализации или классификации из истории.
+// SessionID - фоновая задача запуска нормализации, к которому относится событие (0 - вне запуска)
+type NormalizationEvent struct {
+	ID         int64     `json:"id"`
+	SessionID  int       `json:"session_id"`
+	InstanceID string    `json:"instance_id,omitempty"`
+	Level      string    `json:"level"`
+	Message    string    `json:"message"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// NormalizationEventFilter условия выборки истории событий; пустые поля не ограничивают выборку
+type NormalizationEventFilter struct {
+	SessionID int
+	Level     string
+	Query     string // Подстрока сообщения
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// CreateNormalizationEventsTable создает таблицу истории событий нормализации
+func CreateNormalizationEventsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS normalization_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL DEFAULT 0,
+			instance_id TEXT NOT NULL DEFAULT '',
+			level TEXT NOT NULL,
+			message TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_normalization_events_session ON normalization_events(session_id, id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create normalization_events table: %w", err)
+	}
+	return nil
+}
+
+// AddNormalizationEvents записывает события одной транзакцией и оставляет в истории
+// не более keep последних событий (keep <= 0 - без ограничения)
+func (db *ServiceDB) AddNormalizationEvents(events []NormalizationEvent, keep int) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO normalization_events (session_id, instance_id, level, message, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare normalization event statement: %w", err)
+	}
+	defer stmt.Close()
+	for _, event := range events {
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now()
+		}
+		if _, err := stmt.Exec(event.SessionID, event.InstanceID, event.Level, event.Message, event.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("failed to insert normalization event: %w", err)
+		}
+	}
+
+	if keep > 0 {
+		if _, err := tx.Exec(`
+			DELETE FROM normalization_events
+			WHERE id <= (SELECT id FROM normalization_events ORDER BY id DESC LIMIT 1 OFFSET ?)
+		`, keep); err != nil {
+			return fmt.Errorf("failed to prune normalization events: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit normalization events: %w", err)
+	}
+	return nil
+}
+
+// GetNormalizationEvents возвращает события истории по фильтру, последние первыми, и общее число подходящих
+func (db *ServiceDB) GetNormalizationEvents(filter NormalizationEventFilter) ([]*NormalizationEvent, int, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.SessionID > 0 {
+		conditions = append(conditions, "session_id = ?")
+		args = append(args, filter.SessionID)
+	}
+	if filter.Level != "" {
+		conditions = append(conditions, "level = ?")
+		args = append(args, strings.ToUpper(filter.Level))
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, "message LIKE ?")
+		args = append(args, "%"+filter.Query+"%")
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.Until.UTC())
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM normalization_events `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count normalization events: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, session_id, instance_id, level, message, created_at
+		FROM normalization_events `+where+`
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get normalization events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*NormalizationEvent{}
+	for rows.Next() {
+		event := &NormalizationEvent{}
+		if err := rows.Scan(&event.ID, &event.SessionID, &event.InstanceID, &event.Level, &event.Message, &event.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan normalization event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate normalization events: %w", err)
+	}
+	return events, total, nil
+}
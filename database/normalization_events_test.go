@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNormalizationEventsHistory(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	var events []NormalizationEvent
+	for i := 0; i < 6; i++ {
+		level := NormalizationEventInfo
+		if i%3 == 2 {
+			level = NormalizationEventError
+		}
+		events = append(events, NormalizationEvent{
+			SessionID: 1 + i/3,
+			Level:     level,
+			Message:   fmt.Sprintf("КПВЭД: событие %d", i),
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	// В истории остаются 5 последних событий
+	if err := db.AddNormalizationEvents(events[:4], 5); err != nil {
+		t.Fatalf("AddNormalizationEvents() error = %v", err)
+	}
+	if err := db.AddNormalizationEvents(events[4:], 5); err != nil {
+		t.Fatalf("AddNormalizationEvents() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		filter       NormalizationEventFilter
+		wantTotal    int
+		wantMessages []string
+	}{
+		{"bounded history, newest first", NormalizationEventFilter{Limit: 2}, 5, []string{"КПВЭД: событие 5", "КПВЭД: событие 4"}},
+		{"session", NormalizationEventFilter{SessionID: 1, Limit: 10}, 2, []string{"КПВЭД: событие 2", "КПВЭД: событие 1"}},
+		{"level is case insensitive", NormalizationEventFilter{Level: "error", Limit: 10}, 2, []string{"КПВЭД: событие 5", "КПВЭД: событие 2"}},
+		{"message substring", NormalizationEventFilter{Query: "событие 3", Limit: 10}, 1, []string{"КПВЭД: событие 3"}},
+		{"time range", NormalizationEventFilter{Since: start.Add(2 * time.Minute), Until: start.Add(3 * time.Minute), Limit: 10}, 2, []string{"КПВЭД: событие 3", "КПВЭД: событие 2"}},
+		{"offset", NormalizationEventFilter{Limit: 10, Offset: 4}, 5, []string{"КПВЭД: событие 1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := db.GetNormalizationEvents(tt.filter)
+			if err != nil {
+				t.Fatalf("GetNormalizationEvents() error = %v", err)
+			}
+			if total != tt.wantTotal || len(got) != len(tt.wantMessages) {
+				t.Fatalf("GetNormalizationEvents() = %d events of %d, want %d of %d", len(got), total, len(tt.wantMessages), tt.wantTotal)
+			}
+			for i, event := range got {
+				if event.Message != tt.wantMessages[i] {
+					t.Errorf("event %d = %q, want %q", i, event.Message, tt.wantMessages[i])
+				}
+			}
+		})
+	}
+}
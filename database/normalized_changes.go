@@ -0,0 +1,217 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Типы изменений журнала нормализованных записей
+const (
+	NormalizedChangeInsert = "insert"
+	NormalizedChangeUpdate = "update"
+	NormalizedChangeDelete = "delete"
+)
+
+// ErrChangesCursorExpired курсор указывает на изменения, удаленные очисткой журнала: потребитель
+// должен заново получить полные данные и продолжить с head
+var ErrChangesCursorExpired = errors.New("changes cursor expired")
+
+// normalizedChangeFields поля normalized_data, изменения которых попадают в журнал изменений.
+// Служебные поля (version, updated_at, classification_hash) не отслеживаются
+var normalizedChangeFields = []string{
+	"source_reference", "source_name", "code", "normalized_name", "normalized_reference", "category",
+	"merged_count", "ai_confidence", "ai_reasoning", "processing_level",
+	"kpved_code", "kpved_name", "kpved_confidence", "kpved_raw_confidence", "kpved_model", "confidence_decision",
+	"quality_score", "validation_status", "validation_reason", "standard_references",
+}
+
+// NormalizedChange запись журнала изменений нормализованных данных. Seq возрастает в порядке фиксации:
+// SQLite выполняет записи последовательно, поэтому изменение с меньшим Seq зафиксировано раньше
+type NormalizedChange struct {
+	Seq           int64           `json:"seq"`
+	ItemID        int             `json:"item_id"`
+	ChangeType    string          `json:"change_type"`
+	ChangedFields []string        `json:"changed_fields"`
+	ChangedAt     time.Time       `json:"changed_at"`
+	Item          *NormalizedItem `json:"item,omitempty"` // Текущее состояние записи; нет для удаленных
+}
+
+// CreateNormalizedChangesJournal создает журнал изменений normalized_data и триггеры, которые его ведут.
+// Журнал ведется триггерами, чтобы в него попадали изменения всех путей записи: нормализации,
+// переклассификации, исправлений и ручного редактирования. Вызывается после миграций normalized_data:
+// отслеживаются только существующие поля
+func CreateNormalizedChangesJournal(db *sql.DB) error {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS normalized_changes (
+			seq INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_id INTEGER NOT NULL,
+			change_type TEXT NOT NULL,
+			changed_fields TEXT NOT NULL DEFAULT '[]',
+			changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create normalized changes table: %w", err)
+	}
+
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('normalized_data')`)
+	if err != nil {
+		return fmt.Errorf("failed to read normalized_data columns: %w", err)
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan normalized_data column: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read normalized_data columns: %w", err)
+	}
+
+	var fields []string
+	for _, field := range normalizedChangeFields {
+		if existing[field] {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("normalized_data has no tracked columns")
+	}
+	changed := make([]string, len(fields))
+	conditions := make([]string, len(fields))
+	for i, field := range fields {
+		changed[i] = fmt.Sprintf(`SELECT '%s' AS field WHERE OLD.%s IS NOT NEW.%s`, field, field, field)
+		conditions[i] = fmt.Sprintf(`OLD.%s IS NOT NEW.%s`, field, field)
+	}
+
+	// Триггеры пересоздаются, чтобы учесть поля, добавленные миграциями после их создания
+	statements := []string{
+		`DROP TRIGGER IF EXISTS trg_normalized_changes_insert`,
+		`DROP TRIGGER IF EXISTS trg_normalized_changes_update`,
+		`DROP TRIGGER IF EXISTS trg_normalized_changes_delete`,
+		`CREATE TRIGGER trg_normalized_changes_insert AFTER INSERT ON normalized_data BEGIN
+			INSERT INTO normalized_changes (item_id, change_type) VALUES (NEW.id, 'insert');
+		END`,
+		`CREATE TRIGGER trg_normalized_changes_update AFTER UPDATE ON normalized_data
+		WHEN ` + strings.Join(conditions, " OR ") + ` BEGIN
+			INSERT INTO normalized_changes (item_id, change_type, changed_fields)
+			SELECT NEW.id, 'update', json_group_array(field) FROM (` + strings.Join(changed, " UNION ALL ") + `);
+		END`,
+		`CREATE TRIGGER trg_normalized_changes_delete AFTER DELETE ON normalized_data BEGIN
+			INSERT INTO normalized_changes (item_id, change_type) VALUES (OLD.id, 'delete');
+		END`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create normalized changes trigger: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetNormalizedChanges возвращает изменения с seq больше afterSeq по порядку фиксации, не более limit,
+// с текущим состоянием измененных записей. hasMore - есть изменения после последнего возвращенного.
+// Курсор старше очищенной части журнала - ErrChangesCursorExpired
+func (db *DB) GetNormalizedChanges(afterSeq int64, limit int) (changes []*NormalizedChange, hasMore bool, err error) {
+	floor, err := db.GetNormalizedChangesFloor()
+	if err != nil {
+		return nil, false, err
+	}
+	if afterSeq < floor {
+		return nil, false, fmt.Errorf("%w: oldest available cursor is %d", ErrChangesCursorExpired, floor)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT c.seq, c.item_id, c.change_type, c.changed_fields, c.changed_at,
+		       n.id, COALESCE(n.source_reference, ''), COALESCE(n.source_name, ''), COALESCE(n.code, ''),
+		       COALESCE(n.normalized_name, ''), COALESCE(n.normalized_reference, ''), COALESCE(n.category, ''),
+		       COALESCE(n.merged_count, 1), COALESCE(n.ai_confidence, 0), COALESCE(n.processing_level, ''),
+		       COALESCE(n.kpved_code, ''), COALESCE(n.kpved_name, ''), COALESCE(n.kpved_confidence, 0),
+		       COALESCE(n.quality_score, 0), COALESCE(n.version, 1), n.created_at, n.updated_at
+		FROM normalized_changes c
+		LEFT JOIN normalized_data n ON n.id = c.item_id AND c.change_type != 'delete'
+		WHERE c.seq > ?
+		ORDER BY c.seq
+		LIMIT ?
+	`, afterSeq, limit+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get normalized changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes = []*NormalizedChange{}
+	for rows.Next() {
+		change := &NormalizedChange{}
+		var fields string
+		var itemID sql.NullInt64
+		var createdAt, updatedAt sql.NullTime
+		item := &NormalizedItem{}
+		if err := rows.Scan(&change.Seq, &change.ItemID, &change.ChangeType, &fields, &change.ChangedAt,
+			&itemID, &item.SourceReference, &item.SourceName, &item.Code,
+			&item.NormalizedName, &item.NormalizedReference, &item.Category,
+			&item.MergedCount, &item.AIConfidence, &item.ProcessingLevel,
+			&item.KpvedCode, &item.KpvedName, &item.KpvedConfidence,
+			&item.QualityScore, &item.Version, &createdAt, &updatedAt); err != nil {
+			return nil, false, fmt.Errorf("failed to scan normalized change: %w", err)
+		}
+		change.ChangedFields = []string{}
+		if err := json.Unmarshal([]byte(fields), &change.ChangedFields); err != nil {
+			return nil, false, fmt.Errorf("failed to parse changed fields of normalized change %d: %w", change.Seq, err)
+		}
+		if itemID.Valid {
+			item.ID = int(itemID.Int64)
+			item.CreatedAt = createdAt.Time
+			if updatedAt.Valid {
+				item.UpdatedAt = &updatedAt.Time
+			}
+			change.Item = item
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to iterate normalized changes: %w", err)
+	}
+	if len(changes) > limit {
+		return changes[:limit], true, nil
+	}
+	return changes, false, nil
+}
+
+// GetNormalizedChangesHead возвращает seq последнего изменения журнала (0 - журнал пуст)
+func (db *DB) GetNormalizedChangesHead() (int64, error) {
+	var seq int64
+	if err := db.conn.QueryRow(`SELECT COALESCE(MAX(seq), 0) FROM normalized_changes`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to get normalized changes head: %w", err)
+	}
+	return seq, nil
+}
+
+// GetNormalizedChangesFloor возвращает наименьший курсор, после которого журнал хранится полностью
+// (0 - журнал не очищался)
+func (db *DB) GetNormalizedChangesFloor() (int64, error) {
+	var floor int64
+	if err := db.conn.QueryRow(`SELECT COALESCE(MIN(seq), 1) - 1 FROM normalized_changes`).Scan(&floor); err != nil {
+		return 0, fmt.Errorf("failed to get normalized changes floor: %w", err)
+	}
+	return floor, nil
+}
+
+// PruneNormalizedChanges удаляет изменения журнала, зафиксированные раньше before. Последнее изменение
+// сохраняется всегда: по нему определяются head и начало журнала. Возвращает количество удаленных записей
+func (db *DB) PruneNormalizedChanges(before time.Time) (int64, error) {
+	result, err := db.conn.Exec(`
+		DELETE FROM normalized_changes
+		WHERE changed_at < ? AND seq < (SELECT MAX(seq) FROM normalized_changes)
+	`, before.UTC().Format(sqliteTimestampLayout))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune normalized changes: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizedChangesJournal(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	statements := []string{
+		`INSERT INTO normalized_data (id, source_reference, code, normalized_name, category) VALUES
+			(1, 'r1', 'c1', 'болт м10', 'крепеж'), (2, 'r2', 'c2', 'гайка м8', 'крепеж')`,
+		`UPDATE normalized_data SET kpved_code = '25.94.11', kpved_name = 'Болты' WHERE id = 1`,
+		// Изменение только служебных полей и запись тех же значений в журнал не попадают
+		`UPDATE normalized_data SET version = 2, updated_at = CURRENT_TIMESTAMP WHERE id = 1`,
+		`UPDATE normalized_data SET category = 'крепеж' WHERE id = 2`,
+		`DELETE FROM normalized_data WHERE id = 2`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Exec(%q) error = %v", statement, err)
+		}
+	}
+
+	all, hasMore, err := db.GetNormalizedChanges(0, 100)
+	if err != nil {
+		t.Fatalf("GetNormalizedChanges() error = %v", err)
+	}
+	if hasMore {
+		t.Errorf("hasMore = true, want false")
+	}
+	want := []struct {
+		itemID     int
+		changeType string
+		fields     []string
+		hasItem    bool
+	}{
+		{1, NormalizedChangeInsert, []string{}, true},
+		{2, NormalizedChangeInsert, []string{}, false},
+		{1, NormalizedChangeUpdate, []string{"kpved_code", "kpved_name"}, true},
+		{2, NormalizedChangeDelete, []string{}, false},
+	}
+	if len(all) != len(want) {
+		t.Fatalf("changes = %d, want %d", len(all), len(want))
+	}
+	for i, w := range want {
+		t.Run(w.changeType, func(t *testing.T) {
+			got := all[i]
+			if got.ItemID != w.itemID || got.ChangeType != w.changeType || !reflect.DeepEqual(got.ChangedFields, w.fields) {
+				t.Errorf("change %d = %d %s %v, want %d %s %v", i, got.ItemID, got.ChangeType, got.ChangedFields, w.itemID, w.changeType, w.fields)
+			}
+			if (got.Item != nil) != w.hasItem {
+				t.Errorf("change %d item = %+v, want present %v", i, got.Item, w.hasItem)
+			}
+			if i > 0 && got.Seq <= all[i-1].Seq {
+				t.Errorf("seq %d is not increasing after %d", got.Seq, all[i-1].Seq)
+			}
+		})
+	}
+	if all[2].Item.KpvedCode != "25.94.11" {
+		t.Errorf("updated item kpved_code = %q", all[2].Item.KpvedCode)
+	}
+
+	t.Run("cursor pages", func(t *testing.T) {
+		page, hasMore, err := db.GetNormalizedChanges(all[0].Seq, 2)
+		if err != nil {
+			t.Fatalf("GetNormalizedChanges() error = %v", err)
+		}
+		if !hasMore || len(page) != 2 || page[0].Seq != all[1].Seq {
+			t.Errorf("page = %d changes from %d, hasMore %v", len(page), page[0].Seq, hasMore)
+		}
+		head, err := db.GetNormalizedChangesHead()
+		if err != nil || head != all[len(all)-1].Seq {
+			t.Errorf("GetNormalizedChangesHead() = %d, %v", head, err)
+		}
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		pruned, err := db.PruneNormalizedChanges(time.Now().Add(time.Hour))
+		if err != nil || pruned != int64(len(all)-1) {
+			t.Fatalf("PruneNormalizedChanges() = %d, %v; want all but the last change", pruned, err)
+		}
+		// Курсор до очищенной части журнала устарел, head остается действительным
+		if _, _, err := db.GetNormalizedChanges(all[0].Seq, 10); !errors.Is(err, ErrChangesCursorExpired) {
+			t.Errorf("GetNormalizedChanges(pruned cursor) error = %v, want ErrChangesCursorExpired", err)
+		}
+		head := all[len(all)-1].Seq
+		if changes, _, err := db.GetNormalizedChanges(head, 10); err != nil || len(changes) != 0 {
+			t.Errorf("GetNormalizedChanges(head) = %d changes, %v", len(changes), err)
+		}
+		if got, _ := db.GetNormalizedChangesHead(); got != head {
+			t.Errorf("head after prune = %d, want %d", got, head)
+		}
+	})
+}
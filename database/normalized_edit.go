@@ -0,0 +1,293 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxNormalizedItemsBulkEdit максимальное число записей в одном пакетном редактировании
+const MaxNormalizedItemsBulkEdit = 500
+
+// maxNormalizedFieldLength максимальная длина текстового поля при ручном редактировании
+const maxNormalizedFieldLength = 500
+
+// ManualEditModel значение kpved_model для кодов, назначенных вручную
+const ManualEditModel = "manual"
+
+var kpvedCodeRegex = regexp.MustCompile(`^([A-Z]|\d{2}(\.\d{1,2}){0,3})$`)
+
+// ErrNormalizedItemVersionConflict запись изменена другим пользователем после чтения
+var ErrNormalizedItemVersionConflict = errors.New("normalized item version conflict")
+
+// NormalizedItemEdit ручное изменение нормализованной записи.
+// Поля со значением nil не изменяются, пустой kpved_code снимает классификацию.
+type NormalizedItemEdit struct {
+	ID             int     `json:"id"`
+	Version        int     `json:"version"` // Версия, прочитанная клиентом
+	NormalizedName *string `json:"normalized_name,omitempty"`
+	Category       *string `json:"category,omitempty"`
+	KpvedCode      *string `json:"kpved_code,omitempty"`
+	KpvedName      *string `json:"kpved_name,omitempty"`
+	Comment        string  `json:"comment,omitempty"`
+}
+
+// NormalizedItemFieldError ошибка проверки поля изменения
+type NormalizedItemFieldError struct {
+	ItemID  int    `json:"id"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *NormalizedItemFieldError) Error() string {
+	return fmt.Sprintf("item %d: %s: %s", e.ItemID, e.Field, e.Message)
+}
+
+// NormalizedItemConflict расхождение версии записи
+type NormalizedItemConflict struct {
+	ItemID          int `json:"id"`
+	ExpectedVersion int `json:"expected_version"`
+	CurrentVersion  int `json:"current_version"`
+}
+
+// NormalizedItemConflictError записи, измененные после чтения клиентом
+type NormalizedItemConflictError struct {
+	Conflicts []NormalizedItemConflict
+}
+
+func (e *NormalizedItemConflictError) Error() string {
+	ids := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		ids = append(ids, fmt.Sprintf("%d", conflict.ItemID))
+	}
+	return fmt.Sprintf("normalized items modified concurrently: %s", strings.Join(ids, ", "))
+}
+
+func (e *NormalizedItemConflictError) Unwrap() error {
+	return ErrNormalizedItemVersionConflict
+}
+
+// NormalizedFieldChange изменение одного поля, сохраняемое в происхождении записи
+type NormalizedFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Validate убирает лишние пробелы и проверяет поля изменения
+func (e *NormalizedItemEdit) Validate() error {
+	if e.ID <= 0 {
+		return &NormalizedItemFieldError{ItemID: e.ID, Field: "id", Message: "must be positive"}
+	}
+	if e.Version <= 0 {
+		return &NormalizedItemFieldError{ItemID: e.ID, Field: "version", Message: "is required for optimistic locking"}
+	}
+	if e.NormalizedName == nil && e.Category == nil && e.KpvedCode == nil && e.KpvedName == nil {
+		return &NormalizedItemFieldError{ItemID: e.ID, Field: "fields", Message: "nothing to update"}
+	}
+
+	required := []struct {
+		name  string
+		value *string
+	}{
+		{"normalized_name", e.NormalizedName},
+		{"category", e.Category},
+	}
+	for _, field := range required {
+		if field.value == nil {
+			continue
+		}
+		*field.value = strings.TrimSpace(*field.value)
+		if *field.value == "" {
+			return &NormalizedItemFieldError{ItemID: e.ID, Field: field.name, Message: "must not be empty"}
+		}
+		if utf8.RuneCountInString(*field.value) > maxNormalizedFieldLength {
+			return &NormalizedItemFieldError{ItemID: e.ID, Field: field.name, Message: fmt.Sprintf("must not exceed %d characters", maxNormalizedFieldLength)}
+		}
+	}
+
+	if e.KpvedName != nil {
+		*e.KpvedName = strings.TrimSpace(*e.KpvedName)
+		if e.KpvedCode == nil {
+			return &NormalizedItemFieldError{ItemID: e.ID, Field: "kpved_name", Message: "requires kpved_code"}
+		}
+	}
+	if e.KpvedCode != nil {
+		*e.KpvedCode = strings.TrimSpace(*e.KpvedCode)
+		if *e.KpvedCode != "" && !kpvedCodeRegex.MatchString(*e.KpvedCode) {
+			return &NormalizedItemFieldError{ItemID: e.ID, Field: "kpved_code", Message: "invalid KPVED code format"}
+		}
+		if *e.KpvedCode == "" && e.KpvedName != nil && *e.KpvedName != "" {
+			return &NormalizedItemFieldError{ItemID: e.ID, Field: "kpved_name", Message: "must be empty when kpved_code is cleared"}
+		}
+	}
+
+	return nil
+}
+
+// MigrateNormalizedDataVersionFields добавляет в normalized_data версию и время последнего изменения
+func MigrateNormalizedDataVersionFields(db *sql.DB) error {
+	migrations := []string{
+		`ALTER TABLE normalized_data ADD COLUMN version INTEGER DEFAULT 1`,
+		`ALTER TABLE normalized_data ADD COLUMN updated_at TIMESTAMP`,
+	}
+
+	for _, migration := range migrations {
+		// Игнорируем ошибки, если поле уже существует
+		if _, err := db.Exec(migration); err != nil {
+			errStr := strings.ToLower(err.Error())
+			if !strings.Contains(errStr, "duplicate column") &&
+				!strings.Contains(errStr, "already exists") {
+				return fmt.Errorf("migration failed: %s, error: %w", migration, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateNormalizedItems применяет ручные изменения в одной транзакции.
+// Каждое изменение проверяется по версии записи: если хотя бы одна запись изменена после
+// чтения клиентом, ничего не сохраняется и возвращается *NormalizedItemConflictError.
+// Изменения полей записываются в происхождение записи событием manual_edit.
+func (db *DB) UpdateNormalizedItems(edits []NormalizedItemEdit, editor string) ([]*NormalizedItem, error) {
+	if len(edits) > MaxNormalizedItemsBulkEdit {
+		return nil, fmt.Errorf("too many items: %d (max %d)", len(edits), MaxNormalizedItemsBulkEdit)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	type currentValues struct {
+		name, category, kpvedCode, kpvedName string
+		version                              int
+	}
+
+	current := make([]currentValues, len(edits))
+	seen := make(map[int]bool, len(edits))
+	var conflicts []NormalizedItemConflict
+	for i, edit := range edits {
+		if seen[edit.ID] {
+			return nil, &NormalizedItemFieldError{ItemID: edit.ID, Field: "id", Message: "duplicate item in request"}
+		}
+		seen[edit.ID] = true
+
+		values := &current[i]
+		err := tx.QueryRow(`
+			SELECT COALESCE(normalized_name, ''), COALESCE(category, ''), COALESCE(kpved_code, ''),
+			       COALESCE(kpved_name, ''), COALESCE(version, 1)
+			FROM normalized_data WHERE id = ?
+		`, edit.ID).Scan(&values.name, &values.category, &values.kpvedCode, &values.kpvedName, &values.version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get normalized item %d: %w", edit.ID, err)
+		}
+		if values.version != edit.Version {
+			conflicts = append(conflicts, NormalizedItemConflict{
+				ItemID:          edit.ID,
+				ExpectedVersion: edit.Version,
+				CurrentVersion:  values.version,
+			})
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, &NormalizedItemConflictError{Conflicts: conflicts}
+	}
+
+	for i, edit := range edits {
+		values := current[i]
+		var changes []NormalizedFieldChange
+		var sets []string
+		var args []interface{}
+
+		if edit.NormalizedName != nil && *edit.NormalizedName != values.name {
+			changes = append(changes, NormalizedFieldChange{Field: "normalized_name", Old: values.name, New: *edit.NormalizedName})
+			sets = append(sets, "normalized_name = ?")
+			args = append(args, *edit.NormalizedName)
+		}
+		if edit.Category != nil && *edit.Category != values.category {
+			changes = append(changes, NormalizedFieldChange{Field: "category", Old: values.category, New: *edit.Category})
+			sets = append(sets, "category = ?")
+			args = append(args, *edit.Category)
+		}
+		if edit.KpvedCode != nil {
+			kpvedName := values.kpvedName
+			if edit.KpvedName != nil {
+				kpvedName = *edit.KpvedName
+			} else if *edit.KpvedCode != values.kpvedCode {
+				kpvedName = ""
+			}
+
+			if *edit.KpvedCode != values.kpvedCode || kpvedName != values.kpvedName {
+				changes = append(changes, NormalizedFieldChange{Field: "kpved_code", Old: values.kpvedCode, New: *edit.KpvedCode})
+				if kpvedName != values.kpvedName {
+					changes = append(changes, NormalizedFieldChange{Field: "kpved_name", Old: values.kpvedName, New: kpvedName})
+				}
+
+				if *edit.KpvedCode == "" {
+					// Снятие классификации возвращает запись в число неклассифицированных
+					sets = append(sets, "kpved_code = NULL", "kpved_name = NULL", "kpved_confidence = 0",
+						"kpved_raw_confidence = NULL", "kpved_model = NULL", "confidence_decision = NULL")
+				} else {
+					// Код, назначенный аналитиком, считается достоверным
+					sets = append(sets, "kpved_code = ?", "kpved_name = ?", "kpved_confidence = 1.0",
+						"kpved_raw_confidence = NULL", "kpved_model = ?", "confidence_decision = ?")
+					args = append(args, *edit.KpvedCode, kpvedName, ManualEditModel, ConfidenceDecisionAccept)
+				}
+			}
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		sets = append(sets, "version = COALESCE(version, 1) + 1", "updated_at = CURRENT_TIMESTAMP")
+		args = append(args, edit.ID, values.version)
+		result, err := tx.Exec(
+			fmt.Sprintf("UPDATE normalized_data SET %s WHERE id = ? AND COALESCE(version, 1) = ?", strings.Join(sets, ", ")),
+			args...,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update normalized item %d: %w", edit.ID, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return nil, &NormalizedItemConflictError{Conflicts: []NormalizedItemConflict{{
+				ItemID:          edit.ID,
+				ExpectedVersion: edit.Version,
+			}}}
+		}
+
+		details := map[string]interface{}{
+			"changes":      changes,
+			"version_from": values.version,
+			"version_to":   values.version + 1,
+		}
+		if edit.Comment != "" {
+			details["comment"] = edit.Comment
+		}
+		record := NewLineageRecord(LineageEventManualEdit, editor, 0, details)
+		if err := insertLineageRecords(tx, edit.ID, []*LineageRecord{record}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	items := make([]*NormalizedItem, 0, len(edits))
+	for _, edit := range edits {
+		item, err := db.GetNormalizedItemByID(edit.ID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
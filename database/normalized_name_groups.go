@@ -0,0 +1,45 @@
+package database
+
+import "fmt"
+
+// NormalizedNameGroup группа записей normalized_data с одинаковым наименованием и категорией
+type NormalizedNameGroup struct {
+	Name     string `json:"normalized_name"`
+	Category string `json:"category"`
+	Items    int    `json:"items"`
+}
+
+// GetNormalizedNameGroups возвращает все группы наименований (в указанной категории, если она задана)
+func (db *DB) GetNormalizedNameGroups(category string) ([]NormalizedNameGroup, error) {
+	where := "1 = 1"
+	var args []interface{}
+	if category != "" {
+		where = "category = ?"
+		args = append(args, category)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT COALESCE(normalized_name, ''), COALESCE(category, ''), COUNT(*)
+		FROM normalized_data
+		WHERE `+where+`
+		GROUP BY normalized_name, category
+		ORDER BY category, normalized_name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get normalized name groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []NormalizedNameGroup
+	for rows.Next() {
+		var group NormalizedNameGroup
+		if err := rows.Scan(&group.Name, &group.Category, &group.Items); err != nil {
+			return nil, fmt.Errorf("failed to scan normalized name group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate normalized name groups: %w", err)
+	}
+	return groups, nil
+}
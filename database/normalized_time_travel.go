@@ -0,0 +1,245 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrNormalizedItemNotCreatedAsOf нормализованная запись создана позже запрошенного момента
+var ErrNormalizedItemNotCreatedAsOf = errors.New("normalized item did not exist at the requested time")
+
+// NormalizedItemState состояние нормализованной записи на момент времени, восстановленное по происхождению:
+// из текущих значений откатываются изменения, записанные в происхождение после этого момента
+type NormalizedItemState struct {
+	ID              int       `json:"id"`
+	SourceReference string    `json:"source_reference"`
+	SourceName      string    `json:"source_name"`
+	Code            string    `json:"code"`
+	NormalizedName  string    `json:"normalized_name"`
+	Category        string    `json:"category"`
+	KpvedCode       string    `json:"kpved_code"`
+	KpvedName       string    `json:"kpved_name"`
+	Version         int       `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	AsOf            time.Time `json:"as_of"`
+	EventsReverted  int       `json:"events_reverted"`       // Событий происхождения после as_of
+	Approximate     bool      `json:"approximate,omitempty"` // Часть событий не содержит прежних значений полей
+}
+
+// field возвращает указатель на восстанавливаемое поле состояния
+func (s *NormalizedItemState) field(name string) *string {
+	switch name {
+	case "normalized_name":
+		return &s.NormalizedName
+	case "category":
+		return &s.Category
+	case "kpved_code":
+		return &s.KpvedCode
+	case "kpved_name":
+		return &s.KpvedName
+	}
+	return nil
+}
+
+// lineageDetails разобранные подробности события, влияющие на значения полей
+type lineageDetails struct {
+	Changes     []NormalizedFieldChange `json:"changes"`
+	VersionFrom *int                    `json:"version_from"`
+	KpvedCode   *string                 `json:"kpved_code"`
+	KpvedName   *string                 `json:"kpved_name"`
+}
+
+// parseLineageDetails разбирает подробности события; нераспознанные подробности не влияют на состояние
+func parseLineageDetails(record *LineageRecord) lineageDetails {
+	var details lineageDetails
+	if record.Details != "" {
+		json.Unmarshal([]byte(record.Details), &details)
+	}
+	return details
+}
+
+// assignedFields возвращает значения полей, установленные событием. Ручные изменения и исправления
+// клиента перечисляют изменения полей, классификация - назначенный код КПВЭД
+func (d lineageDetails) assignedFields(eventType string) map[string]string {
+	assigned := make(map[string]string)
+	for _, change := range d.Changes {
+		assigned[change.Field] = change.New
+	}
+	if len(d.Changes) == 0 && eventType == LineageEventClassification {
+		if d.KpvedCode != nil {
+			assigned["kpved_code"] = *d.KpvedCode
+		}
+		if d.KpvedName != nil {
+			assigned["kpved_name"] = *d.KpvedName
+		}
+	}
+	return assigned
+}
+
+// revertLineage откатывает состояние к моменту asOf по событиям происхождения записи в порядке их записи.
+// События с прежними значениями полей откатываются точно; для событий без них (классификация ранних
+// версий) прежнее значение берется из предыдущего события, установившего поле, а если такого нет,
+// состояние помечается приблизительным
+func revertLineage(state *NormalizedItemState, events []*LineageRecord) {
+	details := make([]lineageDetails, len(events))
+	for i, event := range events {
+		details[i] = parseLineageDetails(event)
+	}
+
+	for i := len(events) - 1; i >= 0; i-- {
+		if !events[i].CreatedAt.After(state.AsOf) {
+			continue
+		}
+		state.EventsReverted++
+		if details[i].VersionFrom != nil {
+			state.Version = *details[i].VersionFrom
+		}
+		if len(details[i].Changes) > 0 {
+			for _, change := range details[i].Changes {
+				if field := state.field(change.Field); field != nil {
+					*field = change.Old
+				}
+			}
+			continue
+		}
+
+		for name := range details[i].assignedFields(events[i].EventType) {
+			field := state.field(name)
+			if field == nil {
+				continue
+			}
+			found := false
+			for j := i - 1; j >= 0 && !found; j-- {
+				if value, ok := details[j].assignedFields(events[j].EventType)[name]; ok {
+					*field = value
+					found = true
+				}
+			}
+			if !found {
+				state.Approximate = true
+			}
+		}
+	}
+}
+
+// GetNormalizedItemAsOf восстанавливает состояние нормализованной записи на момент asOf.
+// Возвращает ErrNormalizedItemNotCreatedAsOf, если запись создана позже
+func (db *DB) GetNormalizedItemAsOf(id int, asOf time.Time) (*NormalizedItemState, error) {
+	states, err := db.normalizedItemStatesAsOf(`WHERE id = ?`, []interface{}{id}, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		item, err := db.GetNormalizedItemByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("normalized item %d created at %s: %w", id, item.CreatedAt.Format(time.RFC3339), ErrNormalizedItemNotCreatedAsOf)
+	}
+	return states[0], nil
+}
+
+// GetUploadNormalizedItemsAsOf возвращает состояние нормализованных записей исходных элементов выгрузки
+// на момент asOf; записи, созданные позже, не входят в результат. Связь с выгрузкой - по source_reference
+func (db *DB) GetUploadNormalizedItemsAsOf(uploadID int, asOf time.Time, offset, limit int) ([]*NormalizedItemState, int, error) {
+	// Индекс нужен для EXISTS-подзапросов по source_reference
+	if _, err := db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_normalized_source_reference ON normalized_data(source_reference)`); err != nil {
+		return nil, 0, fmt.Errorf("failed to create source_reference index: %w", err)
+	}
+
+	where := `
+		WHERE created_at <= ? AND EXISTS (
+			SELECT 1 FROM catalog_items ci
+			JOIN catalogs c ON c.id = ci.catalog_id
+			WHERE c.upload_id = ? AND ci.reference = normalized_data.source_reference
+		)`
+	args := []interface{}{asOf.UTC(), uploadID}
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM normalized_data `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count normalized items: %w", err)
+	}
+
+	page := where + ` ORDER BY id`
+	if limit > 0 {
+		page += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+	states, err := db.normalizedItemStatesAsOf(page, args, asOf)
+	if err != nil {
+		return nil, 0, err
+	}
+	return states, total, nil
+}
+
+// normalizedItemStatesAsOf выбирает записи по условию и откатывает каждую к моменту asOf.
+// Записи, созданные позже asOf, пропускаются
+func (db *DB) normalizedItemStatesAsOf(where string, args []interface{}, asOf time.Time) ([]*NormalizedItemState, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, COALESCE(source_reference, ''), COALESCE(source_name, ''), COALESCE(code, ''),
+		       COALESCE(normalized_name, ''), COALESCE(category, ''), COALESCE(kpved_code, ''),
+		       COALESCE(kpved_name, ''), COALESCE(version, 1), created_at
+		FROM normalized_data `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get normalized items: %w", err)
+	}
+
+	states := []*NormalizedItemState{}
+	byID := make(map[int]*NormalizedItemState)
+	for rows.Next() {
+		state := &NormalizedItemState{AsOf: asOf}
+		if err := rows.Scan(&state.ID, &state.SourceReference, &state.SourceName, &state.Code,
+			&state.NormalizedName, &state.Category, &state.KpvedCode, &state.KpvedName,
+			&state.Version, &state.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan normalized item: %w", err)
+		}
+		if state.CreatedAt.After(asOf) {
+			continue
+		}
+		states = append(states, state)
+		byID[state.ID] = state
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate normalized items: %w", err)
+	}
+	if len(states) == 0 {
+		return states, nil
+	}
+
+	ids := make([]interface{}, 0, len(states))
+	for _, state := range states {
+		ids = append(ids, state.ID)
+	}
+	lineageRows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT id, normalized_item_id, event_type, COALESCE(details, ''), created_at
+		FROM normalized_item_lineage
+		WHERE normalized_item_id IN (%s)
+		ORDER BY id
+	`, strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")), ids...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lineage records: %w", err)
+	}
+	events := make(map[int][]*LineageRecord, len(states))
+	for lineageRows.Next() {
+		record := &LineageRecord{}
+		if err := lineageRows.Scan(&record.ID, &record.NormalizedItemID, &record.EventType, &record.Details, &record.CreatedAt); err != nil {
+			lineageRows.Close()
+			return nil, fmt.Errorf("failed to scan lineage record: %w", err)
+		}
+		events[record.NormalizedItemID] = append(events[record.NormalizedItemID], record)
+	}
+	lineageRows.Close()
+	if err := lineageRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate lineage records: %w", err)
+	}
+
+	for id, state := range byID {
+		revertLineage(state, events[id])
+	}
+	return states, nil
+}
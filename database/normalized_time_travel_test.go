@@ -0,0 +1,92 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNormalizedItemsAsOf(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO normalized_data (id, source_reference, source_name, code, normalized_name, category, kpved_code, kpved_name, version, created_at)
+		VALUES (1, 'r1', 'Болт', 'c1', 'болт м10', 'крепеж', '25.94.11', 'Болты', 2, '2026-01-01 10:00:00'),
+		       (2, 'r2', 'Гайка', 'c2', 'гайка м8', 'крепеж', '25.94.12', 'Гайки', 1, '2026-01-01 10:00:00')`)
+	if err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO normalized_item_lineage (normalized_item_id, event_type, reference, details, created_at) VALUES
+		(1, 'classification', 'kpved_hierarchical', '{"kpved_code":"25.94.12","kpved_name":"Гайки"}', '2026-01-01 10:00:00'),
+		(1, 'manual_edit', 'analyst', '{"changes":[{"field":"normalized_name","old":"болт м8","new":"болт м10"}],"version_from":1,"version_to":2}', '2026-02-01 09:00:00'),
+		(1, 'classification', 'scoped_reclassify', '{"kpved_code":"25.94.11","kpved_name":"Болты"}', '2026-03-01 09:00:00'),
+		(2, 'classification', 'scoped_reclassify', '{"kpved_code":"25.94.12","kpved_name":"Гайки"}', '2026-03-01 09:00:00')`)
+	if err != nil {
+		t.Fatalf("Failed to insert lineage: %v", err)
+	}
+
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse(time.RFC3339, value)
+		return parsed
+	}
+	tests := []struct {
+		name            string
+		id              int
+		asOf            string
+		wantErr         error
+		wantName        string
+		wantKpved       string
+		wantVersion     int
+		wantReverted    int
+		wantApproximate bool
+	}{
+		{"before creation", 1, "2025-12-31T00:00:00Z", ErrNormalizedItemNotCreatedAsOf, "", "", 0, 0, false},
+		{"after creation", 1, "2026-01-15T00:00:00Z", nil, "болт м8", "25.94.12", 1, 2, false},
+		{"after manual edit", 1, "2026-02-15T00:00:00Z", nil, "болт м10", "25.94.12", 2, 1, false},
+		{"current", 1, "2026-04-01T00:00:00Z", nil, "болт м10", "25.94.11", 2, 0, false},
+		{"classification without previous value", 2, "2026-02-01T00:00:00Z", nil, "гайка м8", "25.94.12", 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := db.GetNormalizedItemAsOf(tt.id, date(tt.asOf))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetNormalizedItemAsOf() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if state.NormalizedName != tt.wantName || state.KpvedCode != tt.wantKpved || state.Version != tt.wantVersion ||
+				state.EventsReverted != tt.wantReverted || state.Approximate != tt.wantApproximate {
+				t.Errorf("state = %+v", state)
+			}
+		})
+	}
+
+	upload, err := db.CreateUpload("uuid-as-of", "8.3", "БухгалтерияПредприятия")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "Номенклатура")
+	if err != nil {
+		t.Fatalf("Failed to add catalog: %v", err)
+	}
+	if err := db.AddCatalogItem(catalog.ID, "r1", "c1", "Болт", nil, nil); err != nil {
+		t.Fatalf("Failed to add catalog item: %v", err)
+	}
+
+	for _, tt := range []struct {
+		asOf      string
+		wantTotal int
+	}{{"2025-12-31T00:00:00Z", 0}, {"2026-01-15T00:00:00Z", 1}} {
+		states, total, err := db.GetUploadNormalizedItemsAsOf(upload.ID, date(tt.asOf), 0, 10)
+		if err != nil || total != tt.wantTotal || len(states) != tt.wantTotal {
+			t.Fatalf("GetUploadNormalizedItemsAsOf(%s) = %d items, total %d, %v; want %d", tt.asOf, len(states), total, err, tt.wantTotal)
+		}
+		if total > 0 && states[0].NormalizedName != "болт м8" {
+			t.Errorf("upload state = %+v, want name before manual edit", states[0])
+		}
+	}
+}
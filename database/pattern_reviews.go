@@ -0,0 +1,174 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Решения по предложениям исправления наименований
+const (
+	PatternDecisionAccept = "accept" // Предложение принято без изменений
+	PatternDecisionEdit   = "edit"   // Наименование исправлено проверяющим
+	PatternDecisionSkip   = "skip"   // Наименование оставлено как есть
+	// PatternDecisionUnchanged алгоритм и AI не предложили изменений; фиксируется, чтобы продолженная
+	// сессия не запрашивала предложение повторно
+	PatternDecisionUnchanged = "unchanged"
+)
+
+// PatternReviewDecision решение проверяющего по предложению исправления наименования
+type PatternReviewDecision struct {
+	ID           int       `json:"id"`
+	Session      string    `json:"session"` // Сессия проверки, по ней продолжается прерванная проверка
+	OriginalName string    `json:"original_name"`
+	Suggestion   string    `json:"suggestion"`           // Предложение алгоритма или AI
+	FinalName    string    `json:"final_name,omitempty"` // Принятое наименование; пустое для skip
+	Decision     string    `json:"decision"`
+	Source       string    `json:"source,omitempty"` // algorithmic или ai
+	Confidence   float64   `json:"confidence,omitempty"`
+	Reviewer     string    `json:"reviewer,omitempty"`
+	DecidedAt    time.Time `json:"decided_at"`
+}
+
+// CreatePatternReviewDecisionsTable создает таблицу решений по предложениям исправления наименований
+func CreatePatternReviewDecisionsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pattern_review_decisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session TEXT NOT NULL,
+			original_name TEXT NOT NULL,
+			suggestion TEXT NOT NULL DEFAULT '',
+			final_name TEXT NOT NULL DEFAULT '',
+			decision TEXT NOT NULL,
+			source TEXT NOT NULL DEFAULT '',
+			confidence REAL NOT NULL DEFAULT 0,
+			reviewer TEXT NOT NULL DEFAULT '',
+			decided_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(session, original_name)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create pattern_review_decisions table: %w", err)
+	}
+	return nil
+}
+
+// SavePatternReviewDecision сохраняет решение; повторное решение по наименованию в сессии заменяет прежнее
+func (db *DB) SavePatternReviewDecision(decision *PatternReviewDecision) error {
+	decision.Session = strings.TrimSpace(decision.Session)
+	decision.FinalName = strings.TrimSpace(decision.FinalName)
+	if decision.Session == "" {
+		return fmt.Errorf("review session is required")
+	}
+	switch decision.Decision {
+	case PatternDecisionAccept:
+		decision.FinalName = decision.Suggestion
+	case PatternDecisionEdit:
+		if decision.FinalName == "" {
+			return fmt.Errorf("edited name is required")
+		}
+	case PatternDecisionSkip, PatternDecisionUnchanged:
+		decision.FinalName = ""
+	default:
+		return fmt.Errorf("invalid review decision %q", decision.Decision)
+	}
+
+	decision.DecidedAt = time.Now()
+	err := db.conn.QueryRow(`
+		INSERT INTO pattern_review_decisions
+		(session, original_name, suggestion, final_name, decision, source, confidence, reviewer, decided_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(session, original_name) DO UPDATE SET
+			suggestion = excluded.suggestion, final_name = excluded.final_name, decision = excluded.decision,
+			source = excluded.source, confidence = excluded.confidence, reviewer = excluded.reviewer,
+			decided_at = excluded.decided_at
+		RETURNING id
+	`, decision.Session, decision.OriginalName, decision.Suggestion, decision.FinalName, decision.Decision,
+		decision.Source, decision.Confidence, decision.Reviewer, decision.DecidedAt).Scan(&decision.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save review decision: %w", err)
+	}
+	return nil
+}
+
+// GetPatternReviewDecisions возвращает решения сессии: наименование -> решение
+func (db *DB) GetPatternReviewDecisions(session string) (map[string]string, error) {
+	rows, err := db.conn.Query(`
+		SELECT original_name, decision FROM pattern_review_decisions WHERE session = ?
+	`, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review decisions: %w", err)
+	}
+	defer rows.Close()
+
+	decisions := make(map[string]string)
+	for rows.Next() {
+		var name, decision string
+		if err := rows.Scan(&name, &decision); err != nil {
+			return nil, fmt.Errorf("failed to scan review decision: %w", err)
+		}
+		decisions[name] = decision
+	}
+	return decisions, rows.Err()
+}
+
+// ListPatternReviewCorrections возвращает принятые и исправленные наименования сессии
+func (db *DB) ListPatternReviewCorrections(session string) ([]*PatternReviewDecision, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, session, original_name, suggestion, final_name, decision, source, confidence, reviewer, decided_at
+		FROM pattern_review_decisions
+		WHERE session = ? AND decision IN (?, ?)
+		ORDER BY id
+	`, session, PatternDecisionAccept, PatternDecisionEdit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review corrections: %w", err)
+	}
+	defer rows.Close()
+
+	var corrections []*PatternReviewDecision
+	for rows.Next() {
+		var d PatternReviewDecision
+		if err := rows.Scan(&d.ID, &d.Session, &d.OriginalName, &d.Suggestion, &d.FinalName, &d.Decision,
+			&d.Source, &d.Confidence, &d.Reviewer, &d.DecidedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan review correction: %w", err)
+		}
+		corrections = append(corrections, &d)
+	}
+	return corrections, rows.Err()
+}
+
+// ApplyPatternReviewCorrections заменяет в catalog_items исходные наименования принятыми исправлениями сессии.
+// Возвращает число измененных записей; повторное применение ничего не меняет
+func (db *DB) ApplyPatternReviewCorrections(session string) (int64, error) {
+	corrections, err := db.ListPatternReviewCorrections(session)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var updated int64
+	for _, c := range corrections {
+		if c.FinalName == "" || c.FinalName == c.OriginalName {
+			continue
+		}
+		result, err := tx.Exec(`UPDATE catalog_items SET name = ? WHERE name = ?`, c.FinalName, c.OriginalName)
+		if err != nil {
+			return 0, fmt.Errorf("failed to apply correction for %q: %w", c.OriginalName, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to apply correction for %q: %w", c.OriginalName, err)
+		}
+		updated += affected
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit corrections: %w", err)
+	}
+	return updated, nil
+}
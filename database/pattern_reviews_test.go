@@ -0,0 +1,92 @@
+package database
+
+import "testing"
+
+func TestPatternReviewDecisions(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name     string
+		decision PatternReviewDecision
+		wantErr  bool
+	}{
+		{"accept", PatternReviewDecision{Session: "s1", OriginalName: "Болт  М8", Suggestion: "Болт М8", Decision: PatternDecisionAccept}, false},
+		{"edit", PatternReviewDecision{Session: "s1", OriginalName: "гайка м8", Suggestion: "Гайка м8", FinalName: " Гайка М8 ", Decision: PatternDecisionEdit}, false},
+		{"skip", PatternReviewDecision{Session: "s1", OriginalName: "Шайба", Suggestion: "шайба", FinalName: "x", Decision: PatternDecisionSkip}, false},
+		{"edit without name", PatternReviewDecision{Session: "s1", OriginalName: "Винт", Decision: PatternDecisionEdit}, true},
+		{"unknown decision", PatternReviewDecision{Session: "s1", OriginalName: "Винт", Decision: "maybe"}, true},
+		{"no session", PatternReviewDecision{OriginalName: "Винт", Decision: PatternDecisionSkip}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := tt.decision
+			err := db.SavePatternReviewDecision(&decision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SavePatternReviewDecision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && decision.ID == 0 {
+				t.Error("decision ID is not set")
+			}
+		})
+	}
+
+	// Повторное решение заменяет прежнее
+	redo := PatternReviewDecision{Session: "s1", OriginalName: "Шайба", Suggestion: "шайба", Decision: PatternDecisionAccept}
+	if err := db.SavePatternReviewDecision(&redo); err != nil {
+		t.Fatalf("SavePatternReviewDecision(redo) error = %v", err)
+	}
+	other := PatternReviewDecision{Session: "s2", OriginalName: "Болт  М8", Decision: PatternDecisionSkip}
+	if err := db.SavePatternReviewDecision(&other); err != nil {
+		t.Fatalf("SavePatternReviewDecision(other session) error = %v", err)
+	}
+
+	decisions, err := db.GetPatternReviewDecisions("s1")
+	if err != nil {
+		t.Fatalf("GetPatternReviewDecisions() error = %v", err)
+	}
+	if len(decisions) != 3 || decisions["Шайба"] != PatternDecisionAccept || decisions["гайка м8"] != PatternDecisionEdit {
+		t.Errorf("GetPatternReviewDecisions() = %v", decisions)
+	}
+
+	corrections, err := db.ListPatternReviewCorrections("s1")
+	if err != nil {
+		t.Fatalf("ListPatternReviewCorrections() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, c := range corrections {
+		got[c.OriginalName] = c.FinalName
+	}
+	if len(got) != 3 || got["Болт  М8"] != "Болт М8" || got["гайка м8"] != "Гайка М8" || got["Шайба"] != "шайба" {
+		t.Errorf("ListPatternReviewCorrections() = %v", got)
+	}
+
+	// Наименование без предложений фиксируется, но не попадает в исправления
+	unchanged := PatternReviewDecision{Session: "s1", OriginalName: "Гвоздь", Suggestion: "Гвоздь", Decision: PatternDecisionUnchanged}
+	if err := db.SavePatternReviewDecision(&unchanged); err != nil {
+		t.Fatalf("SavePatternReviewDecision(unchanged) error = %v", err)
+	}
+	if decisions, _ := db.GetPatternReviewDecisions("s1"); decisions["Гвоздь"] != PatternDecisionUnchanged {
+		t.Errorf("unchanged decision = %q", decisions["Гвоздь"])
+	}
+
+	if _, err := db.conn.Exec(`INSERT INTO catalog_items (catalog_id, reference, name) VALUES
+		(1, 'r1', 'Болт  М8'), (1, 'r2', 'Болт  М8'), (1, 'r3', 'гайка м8'), (1, 'r4', 'Гвоздь')`); err != nil {
+		t.Fatalf("Failed to insert catalog items: %v", err)
+	}
+	updated, err := db.ApplyPatternReviewCorrections("s1")
+	if err != nil || updated != 3 {
+		t.Fatalf("ApplyPatternReviewCorrections() = %d, %v; want 3", updated, err)
+	}
+	if updated, err := db.ApplyPatternReviewCorrections("s1"); err != nil || updated != 0 {
+		t.Errorf("repeated ApplyPatternReviewCorrections() = %d, %v; want 0", updated, err)
+	}
+	var fixed int
+	db.conn.QueryRow(`SELECT COUNT(*) FROM catalog_items WHERE name IN ('Болт М8', 'Гайка М8', 'Гвоздь')`).Scan(&fixed)
+	if fixed != 4 {
+		t.Errorf("corrected catalog items = %d, want 4", fixed)
+	}
+}
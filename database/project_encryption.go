@@ -0,0 +1,99 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ProjectEncryption настройка шифрования данных выгрузок проекта. Ключ данных проекта хранится только
+// обернутым мастер-ключом развертывания; при отключении шифрования ключ сохраняется, чтобы ранее
+// зашифрованные данные оставались читаемыми
+type ProjectEncryption struct {
+	ProjectID   int       `json:"project_id"`
+	Enabled     bool      `json:"enabled"`
+	MasterKeyID string    `json:"master_key_id"`
+	WrappedKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+}
+
+// CreateProjectEncryptionTable создает таблицу ключей шифрования данных проектов
+func CreateProjectEncryptionTable(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS project_encryption_keys (
+			project_id INTEGER PRIMARY KEY,
+			enabled INTEGER NOT NULL DEFAULT 1,
+			master_key_id TEXT NOT NULL,
+			wrapped_key TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_by TEXT NOT NULL DEFAULT '',
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create project encryption keys table: %w", err)
+	}
+
+	return nil
+}
+
+// GetProjectEncryption возвращает настройку шифрования проекта или nil, если ключ проекту не создавался
+func (db *ServiceDB) GetProjectEncryption(projectID int) (*ProjectEncryption, error) {
+	enc := &ProjectEncryption{}
+	err := db.conn.QueryRow(`
+		SELECT project_id, enabled, master_key_id, wrapped_key, created_at, updated_at, updated_by
+		FROM project_encryption_keys WHERE project_id = ?
+	`, projectID).Scan(&enc.ProjectID, &enc.Enabled, &enc.MasterKeyID, &enc.WrappedKey, &enc.CreatedAt, &enc.UpdatedAt, &enc.UpdatedBy)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project encryption: %w", err)
+	}
+	return enc, nil
+}
+
+// EnableProjectEncryption включает шифрование проекта. Ключ данных сохраняется только при первом включении:
+// повторное включение использует прежний ключ
+func (db *ServiceDB) EnableProjectEncryption(projectID int, masterKeyID, wrappedKey, actor string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO project_encryption_keys (project_id, enabled, master_key_id, wrapped_key, updated_by)
+		VALUES (?, 1, ?, ?, ?)
+		ON CONFLICT(project_id) DO UPDATE SET enabled = 1, updated_at = CURRENT_TIMESTAMP, updated_by = excluded.updated_by
+	`, projectID, masterKeyID, wrappedKey, actor)
+	if err != nil {
+		return fmt.Errorf("failed to enable project encryption: %w", err)
+	}
+	return nil
+}
+
+// DisableProjectEncryption прекращает шифрование новых данных проекта; ключ остается для чтения
+func (db *ServiceDB) DisableProjectEncryption(projectID int, actor string) error {
+	_, err := db.conn.Exec(`
+		UPDATE project_encryption_keys SET enabled = 0, updated_at = CURRENT_TIMESTAMP, updated_by = ?
+		WHERE project_id = ?
+	`, actor, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to disable project encryption: %w", err)
+	}
+	return nil
+}
+
+// RewrapProjectEncryptionKey сохраняет ключ данных проекта, обернутый другим мастер-ключом (смена мастер-ключа)
+func (db *ServiceDB) RewrapProjectEncryptionKey(projectID int, masterKeyID, wrappedKey, actor string) error {
+	result, err := db.conn.Exec(`
+		UPDATE project_encryption_keys SET master_key_id = ?, wrapped_key = ?, updated_at = CURRENT_TIMESTAMP, updated_by = ?
+		WHERE project_id = ?
+	`, masterKeyID, wrappedKey, actor, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to rewrap project encryption key: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("project encryption key not found")
+	}
+	return nil
+}
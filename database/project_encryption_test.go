@@ -0,0 +1,54 @@
+package database
+
+import "testing"
+
+func TestProjectEncryption(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	if enc, err := db.GetProjectEncryption(project.ID); err != nil || enc != nil {
+		t.Fatalf("GetProjectEncryption() = %+v, %v, want nil", enc, err)
+	}
+	if err := db.RewrapProjectEncryptionKey(project.ID, "k2", "other", "admin"); err == nil {
+		t.Fatal("RewrapProjectEncryptionKey() without key: want error")
+	}
+
+	steps := []struct {
+		name        string
+		apply       func() error
+		wantEnabled bool
+		wantMaster  string
+		wantWrapped string
+	}{
+		{"enable", func() error { return db.EnableProjectEncryption(project.ID, "k1", "wrapped-1", "admin") }, true, "k1", "wrapped-1"},
+		{"disable keeps key", func() error { return db.DisableProjectEncryption(project.ID, "admin") }, false, "k1", "wrapped-1"},
+		{"re-enable keeps key", func() error { return db.EnableProjectEncryption(project.ID, "k1", "wrapped-new", "operator") }, true, "k1", "wrapped-1"},
+		{"rewrap", func() error { return db.RewrapProjectEncryptionKey(project.ID, "k2", "wrapped-2", "admin") }, true, "k2", "wrapped-2"},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.apply(); err != nil {
+				t.Fatalf("apply error = %v", err)
+			}
+			enc, err := db.GetProjectEncryption(project.ID)
+			if err != nil || enc == nil {
+				t.Fatalf("GetProjectEncryption() = %+v, %v", enc, err)
+			}
+			if enc.Enabled != tt.wantEnabled || enc.MasterKeyID != tt.wantMaster || enc.WrappedKey != tt.wantWrapped {
+				t.Errorf("encryption = %+v, want enabled=%v master=%s wrapped=%s", enc, tt.wantEnabled, tt.wantMaster, tt.wantWrapped)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"httpserver/apperrors"
+)
+
+// Статусы жизненного цикла проекта
+const (
+	ProjectStatusActive   = "active"
+	ProjectStatusArchived = "archived" // Только чтение, не показывается в списках
+)
+
+// ErrProjectArchived изменение архивного проекта
+var ErrProjectArchived = apperrors.Conflict("project_archived", "project is archived")
+
+// ErrProjectNotArchived восстановление проекта, который не в архиве
+var ErrProjectNotArchived = apperrors.Conflict("project_not_archived", "project is not archived")
+
+// ProjectCloneSummary результат клонирования конфигурации проекта
+type ProjectCloneSummary struct {
+	Project           *ClientProject `json:"project"`
+	SourceProjectID   int            `json:"source_project_id"`
+	DictionaryEntries int            `json:"dictionary_entries"`
+	Classifiers       int            `json:"classifiers"`
+	ClassifierNodes   int            `json:"classifier_nodes"`
+	Strategies        int            `json:"strategies"`
+	ConfidencePolicy  bool           `json:"confidence_policy"`
+	PromptAttributes  bool           `json:"prompt_attributes"`
+	SimilarityConfig  bool           `json:"similarity_config"`
+}
+
+// ListClientProjects возвращает проекты клиента; архивные - только при includeArchived
+func (db *ServiceDB) ListClientProjects(clientID int, includeArchived bool) ([]*ClientProject, error) {
+	projects, err := db.GetClientProjects(clientID)
+	if err != nil || includeArchived {
+		return projects, err
+	}
+	active := make([]*ClientProject, 0, len(projects))
+	for _, project := range projects {
+		if project.Status != ProjectStatusArchived {
+			active = append(active, project)
+		}
+	}
+	return active, nil
+}
+
+// IsProjectArchived проверяет, находится ли проект в архиве
+func (db *ServiceDB) IsProjectArchived(id int) (bool, error) {
+	var status sql.NullString
+	err := db.conn.QueryRow(`SELECT status FROM client_projects WHERE id = ?`, id).Scan(&status)
+	if err != nil {
+		return false, fmt.Errorf("failed to get project status: %w", err)
+	}
+	return status.String == ProjectStatusArchived, nil
+}
+
+// ArchiveClientProject переводит проект в архив
+func (db *ServiceDB) ArchiveClientProject(id int) error {
+	return db.setProjectStatus(id, ProjectStatusArchived, ErrProjectArchived)
+}
+
+// RestoreClientProject возвращает проект из архива
+func (db *ServiceDB) RestoreClientProject(id int) error {
+	return db.setProjectStatus(id, ProjectStatusActive, ErrProjectNotArchived)
+}
+
+// setProjectStatus меняет статус архивации проекта; sameState возвращается, если проект уже в нужном состоянии
+func (db *ServiceDB) setProjectStatus(id int, status string, sameState error) error {
+	archived, err := db.IsProjectArchived(id)
+	if err != nil {
+		return err
+	}
+	if archived == (status == ProjectStatusArchived) {
+		return sameState
+	}
+	_, err = db.conn.Exec(`UPDATE client_projects SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to update project status: %w", err)
+	}
+	return nil
+}
+
+// CloneClientProject создает проект клиента targetClientID с конфигурацией проекта sourceID:
+// параметрами проекта, атрибутами промптов AI, словарями нормализации и настройкой сходства.
+// Базы данных, эталоны, соответствия кодов, подтвержденные пары дубликатов и сессии не копируются. cloneRelated вызывается до фиксации транзакции для копирования настроек,
+// хранящихся вне сервисной БД; его ошибка отменяет создание проекта
+func (db *ServiceDB) CloneClientProject(sourceID, targetClientID int, name string, cloneRelated func(summary *ProjectCloneSummary) error) (*ProjectCloneSummary, error) {
+	source, err := db.GetClientProject(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, apperrors.Validation("project_name_required", "project name is required")
+	}
+	if _, err := db.GetClient(targetClientID); err != nil {
+		return nil, apperrors.Wrap(apperrors.KindNotFound, "client_not_found", err)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO client_projects (client_id, name, project_type, description, source_system, status, target_quality_score)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, targetClientID, name, source.ProjectType, source.Description, source.SourceSystem, ProjectStatusActive, source.TargetQualityScore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project ID: %w", err)
+	}
+
+	result, err = tx.Exec(`
+		INSERT INTO normalization_dictionary_entries (project_id, kind, term, replacement)
+		SELECT ?, kind, term, replacement FROM normalization_dictionary_entries WHERE project_id = ?
+	`, id, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone dictionaries: %w", err)
+	}
+	entries, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`
+		UPDATE client_projects SET prompt_attributes = (SELECT prompt_attributes FROM client_projects WHERE id = ?)
+		WHERE id = ? AND EXISTS (SELECT 1 FROM client_projects WHERE id = ? AND COALESCE(prompt_attributes, '') != '')
+	`, sourceID, id, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone prompt attributes: %w", err)
+	}
+	promptAttributes, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`
+		INSERT INTO project_similarity_configs (project_id, metrics, threshold)
+		SELECT ?, metrics, threshold FROM project_similarity_configs WHERE project_id = ?
+	`, id, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone similarity config: %w", err)
+	}
+	similarityConfigs, _ := result.RowsAffected()
+
+	summary := &ProjectCloneSummary{
+		Project: &ClientProject{
+			ID:                 int(id),
+			ClientID:           targetClientID,
+			Name:               name,
+			ProjectType:        source.ProjectType,
+			Description:        source.Description,
+			SourceSystem:       source.SourceSystem,
+			Status:             ProjectStatusActive,
+			TargetQualityScore: source.TargetQualityScore,
+		},
+		SourceProjectID:   sourceID,
+		DictionaryEntries: int(entries),
+		PromptAttributes:  promptAttributes > 0,
+		SimilarityConfig:  similarityConfigs > 0,
+	}
+	if cloneRelated != nil {
+		if err := cloneRelated(summary); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if summary.Project, err = db.GetClientProject(int(id)); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// CloneProjectClassification копирует в одной транзакции классификаторы проекта (с узлами) и политику
+// порогов уверенности в проект summary.Project. Стратегии свертки задаются на уровне клиента
+// и копируются, только если проект клонируется для другого клиента; стратегия по умолчанию
+// остается ею, если у клиента нет своей
+func (db *DB) CloneProjectClassification(summary *ProjectCloneSummary, sourceClientID int) error {
+	target := summary.Project
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id FROM category_classifiers WHERE project_id = ? ORDER BY id`, summary.SourceProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to get project classifiers: %w", err)
+	}
+	var classifierIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan classifier: %w", err)
+		}
+		classifierIDs = append(classifierIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read project classifiers: %w", err)
+	}
+
+	for _, sourceID := range classifierIDs {
+		result, err := tx.Exec(`
+			INSERT INTO category_classifiers (name, description, max_depth, tree_structure, client_id, project_id, is_active)
+			SELECT name, description, max_depth, tree_structure, ?, ?, is_active FROM category_classifiers WHERE id = ?
+		`, target.ClientID, target.ID, sourceID)
+		if err != nil {
+			return fmt.Errorf("failed to clone classifier %d: %w", sourceID, err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get classifier ID: %w", err)
+		}
+		result, err = tx.Exec(`
+			INSERT INTO category_classifier_nodes (classifier_id, code, parent_code, name, level, path, position, metadata)
+			SELECT ?, code, parent_code, name, level, path, position, metadata FROM category_classifier_nodes WHERE classifier_id = ?
+		`, id, sourceID)
+		if err != nil {
+			return fmt.Errorf("failed to clone classifier %d nodes: %w", sourceID, err)
+		}
+		nodes, _ := result.RowsAffected()
+		summary.Classifiers++
+		summary.ClassifierNodes += int(nodes)
+	}
+
+	if target.ClientID != sourceClientID {
+		var hasDefault bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM folding_strategies WHERE client_id = ? AND is_default = 1)`, target.ClientID).Scan(&hasDefault)
+		if err != nil {
+			return fmt.Errorf("failed to check default strategy: %w", err)
+		}
+		result, err := tx.Exec(`
+			INSERT INTO folding_strategies (name, description, strategy_config, client_id, is_default)
+			SELECT name, description, strategy_config, ?, CASE WHEN ? THEN 0 ELSE is_default END
+			FROM folding_strategies WHERE client_id = ?
+		`, target.ClientID, hasDefault, sourceClientID)
+		if err != nil {
+			return fmt.Errorf("failed to clone folding strategies: %w", err)
+		}
+		strategies, _ := result.RowsAffected()
+		summary.Strategies = int(strategies)
+	}
+
+	result, err := tx.Exec(`
+		INSERT OR REPLACE INTO confidence_policies (project_id, accept_threshold, review_threshold, updated_at)
+		SELECT ?, accept_threshold, review_threshold, CURRENT_TIMESTAMP FROM confidence_policies WHERE project_id = ?
+	`, target.ID, summary.SourceProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to clone confidence policy: %w", err)
+	}
+	policies, _ := result.RowsAffected()
+	summary.ConfidencePolicy = policies > 0
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteProjectClassification удаляет классификаторы и политику порогов проекта.
+// Используется для отмены клонирования, если проект не удалось создать
+func (db *DB) DeleteProjectClassification(projectID int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`DELETE FROM category_classifier_nodes WHERE classifier_id IN (SELECT id FROM category_classifiers WHERE project_id = ?)`,
+		`DELETE FROM category_classifiers WHERE project_id = ?`,
+		`DELETE FROM confidence_policies WHERE project_id = ?`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, projectID); err != nil {
+			return fmt.Errorf("failed to delete project classification: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProjectArchiveRestore(t *testing.T) {
+	serviceDB, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	client, _ := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	active, _ := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	archived, _ := serviceDB.CreateClientProject(client.ID, "Старый проект", "nomenclature", "", "1C", 0.9)
+
+	if err := serviceDB.ArchiveClientProject(archived.ID); err != nil {
+		t.Fatalf("ArchiveClientProject() error = %v", err)
+	}
+	if err := serviceDB.ArchiveClientProject(archived.ID); !errors.Is(err, ErrProjectArchived) {
+		t.Errorf("second ArchiveClientProject() error = %v, want ErrProjectArchived", err)
+	}
+	if err := serviceDB.RestoreClientProject(active.ID); !errors.Is(err, ErrProjectNotArchived) {
+		t.Errorf("RestoreClientProject(active) error = %v, want ErrProjectNotArchived", err)
+	}
+
+	tests := []struct {
+		name            string
+		includeArchived bool
+		want            int
+	}{
+		{"active only", false, 1},
+		{"with archived", true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, err := serviceDB.ListClientProjects(client.ID, tt.includeArchived)
+			if err != nil {
+				t.Fatalf("ListClientProjects() error = %v", err)
+			}
+			if len(projects) != tt.want {
+				t.Errorf("ListClientProjects() = %d projects, want %d", len(projects), tt.want)
+			}
+		})
+	}
+
+	if err := serviceDB.RestoreClientProject(archived.ID); err != nil {
+		t.Fatalf("RestoreClientProject() error = %v", err)
+	}
+	if isArchived, _ := serviceDB.IsProjectArchived(archived.ID); isArchived {
+		t.Error("project is still archived after restore")
+	}
+}
+
+func TestCloneClientProject(t *testing.T) {
+	serviceDB, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	source, _ := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	target, _ := serviceDB.CreateClient("ООО Лютик", "", "", "", "", "", "test")
+	project, _ := serviceDB.CreateClientProject(source.ID, "Номенклатура", "nomenclature", "Шаблон", "1C", 0.95)
+	for _, entry := range []*NormalizationDictionaryEntry{
+		{ProjectID: project.ID, Kind: DictionaryKindAbbreviation, Term: "эл", Replacement: "электрический"},
+		{ProjectID: project.ID, Kind: DictionaryKindStopWord, Term: "прочее"},
+	} {
+		if err := serviceDB.CreateNormalizationDictionaryEntry(entry); err != nil {
+			t.Fatalf("CreateNormalizationDictionaryEntry() error = %v", err)
+		}
+	}
+
+	classifier, err := db.CreateCategoryClassifier(&CategoryClassifier{Name: "Группы", MaxDepth: 3, TreeStructure: "{}", ClientID: &source.ID, ProjectID: &project.ID, IsActive: true})
+	if err != nil {
+		t.Fatalf("CreateCategoryClassifier() error = %v", err)
+	}
+	db.AddClassifierNode(classifier.ID, "", "tools", "Инструмент")
+	db.AddClassifierNode(classifier.ID, "tools", "hand", "Ручной")
+	if _, err := db.CreateFoldingStrategy(&FoldingStrategy{Name: "По группам", StrategyConfig: "{}", ClientID: &source.ID, IsDefault: true}); err != nil {
+		t.Fatalf("CreateFoldingStrategy() error = %v", err)
+	}
+	if err := db.SaveConfidencePolicy(ConfidencePolicy{ProjectID: project.ID, AcceptThreshold: 0.9, ReviewThreshold: 0.6}); err != nil {
+		t.Fatalf("SaveConfidencePolicy() error = %v", err)
+	}
+	if _, err := serviceDB.SetProjectPromptAttributes(project.ID, []string{"Артикул", "Производитель"}); err != nil {
+		t.Fatalf("SetProjectPromptAttributes() error = %v", err)
+	}
+	similarity := SimilarityConfig{ProjectID: project.ID, Metrics: []SimilarityMetricWeight{{Metric: "levenshtein", Weight: 1}}, Threshold: 0.8}
+	if err := serviceDB.SaveProjectSimilarityConfig(similarity); err != nil {
+		t.Fatalf("SaveProjectSimilarityConfig() error = %v", err)
+	}
+
+	t.Run("related clone failure rolls back project", func(t *testing.T) {
+		_, err := serviceDB.CloneClientProject(project.ID, target.ID, "Неудачный", func(*ProjectCloneSummary) error {
+			return errors.New("main database unavailable")
+		})
+		if err == nil {
+			t.Fatal("CloneClientProject() error = nil, want callback error")
+		}
+		projects, _ := serviceDB.GetClientProjects(target.ID)
+		if len(projects) != 0 {
+			t.Errorf("target client has %d projects after failed clone, want 0", len(projects))
+		}
+	})
+
+	t.Run("unknown target client", func(t *testing.T) {
+		if _, err := serviceDB.CloneClientProject(project.ID, 999, "Копия", nil); err == nil {
+			t.Error("CloneClientProject() error = nil, want not found")
+		}
+	})
+
+	summary, err := serviceDB.CloneClientProject(project.ID, target.ID, "Номенклатура Лютик", func(summary *ProjectCloneSummary) error {
+		return db.CloneProjectClassification(summary, source.ID)
+	})
+	if err != nil {
+		t.Fatalf("CloneClientProject() error = %v", err)
+	}
+	clone := summary.Project
+	if clone.ClientID != target.ID || clone.Description != "Шаблон" || clone.TargetQualityScore != 0.95 || clone.Status != ProjectStatusActive {
+		t.Errorf("cloned project = %+v", clone)
+	}
+	if summary.DictionaryEntries != 2 || summary.Classifiers != 1 || summary.ClassifierNodes != 2 || summary.Strategies != 1 ||
+		!summary.ConfidencePolicy || !summary.PromptAttributes || !summary.SimilarityConfig {
+		t.Errorf("summary = %+v", summary)
+	}
+	if attributes, _ := serviceDB.GetProjectPromptAttributes(clone.ID); len(attributes) != 2 || attributes[0] != "Артикул" {
+		t.Errorf("cloned prompt attributes = %v", attributes)
+	}
+	if config, _ := serviceDB.GetProjectSimilarityConfig(clone.ID); config == nil || config.Threshold != 0.8 || len(config.Metrics) != 1 {
+		t.Errorf("cloned similarity config = %+v", config)
+	}
+
+	entries, _ := serviceDB.GetNormalizationDictionaryEntries(clone.ID, "")
+	if len(entries) != 2 {
+		t.Errorf("cloned dictionary entries = %d, want 2", len(entries))
+	}
+	if got := countProjectClassifiers(t, db, target.ID, clone.ID); got != 1 {
+		t.Errorf("cloned classifiers = %d, want 1", got)
+	}
+	strategies, _ := db.GetFoldingStrategiesByClient(target.ID)
+	if len(strategies) != 1 || !strategies[0].IsDefault {
+		t.Errorf("cloned strategies = %+v", strategies)
+	}
+	policy, _ := db.GetConfidencePolicy(clone.ID)
+	if policy.AcceptThreshold != 0.9 || policy.ReviewThreshold != 0.6 {
+		t.Errorf("cloned policy = %+v", policy)
+	}
+
+	if err := db.DeleteProjectClassification(clone.ID); err != nil {
+		t.Fatalf("DeleteProjectClassification() error = %v", err)
+	}
+	if got := countProjectClassifiers(t, db, target.ID, clone.ID); got != 0 {
+		t.Errorf("classifiers after delete = %d, want 0", got)
+	}
+	if got := countProjectClassifiers(t, db, source.ID, project.ID); got != 1 {
+		t.Errorf("source classifiers = %d, want 1", got)
+	}
+}
+
+// countProjectClassifiers считает классификаторы проекта клиента
+func countProjectClassifiers(t *testing.T, db *DB, clientID, projectID int) int {
+	t.Helper()
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM category_classifiers WHERE client_id = ? AND project_id = ?`, clientID, projectID).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to count classifiers: %v", err)
+	}
+	return count
+}
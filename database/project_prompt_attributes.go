@@ -0,0 +1,79 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MigrateProjectPromptAttributes добавляет в проекты список атрибутов, передаваемых AI вместе с наименованием
+func MigrateProjectPromptAttributes(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE client_projects ADD COLUMN prompt_attributes TEXT DEFAULT ''`)
+	if err != nil {
+		errStr := strings.ToLower(err.Error())
+		if !strings.Contains(errStr, "duplicate column") &&
+			!strings.Contains(errStr, "already exists") {
+			return fmt.Errorf("failed to add client_projects.prompt_attributes column: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetProjectPromptAttributes возвращает атрибуты проекта для промптов AI; пустой список, если не заданы
+func (db *ServiceDB) GetProjectPromptAttributes(projectID int) ([]string, error) {
+	var value sql.NullString
+	err := db.conn.QueryRow(`SELECT prompt_attributes FROM client_projects WHERE id = ?`, projectID).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("project not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project prompt attributes: %w", err)
+	}
+	attributes := []string{}
+	if value.String == "" {
+		return attributes, nil
+	}
+	if err := json.Unmarshal([]byte(value.String), &attributes); err != nil {
+		return nil, fmt.Errorf("failed to decode project prompt attributes: %w", err)
+	}
+	return attributes, nil
+}
+
+// SetProjectPromptAttributes сохраняет атрибуты проекта для промптов AI; пустые и повторяющиеся имена отбрасываются
+func (db *ServiceDB) SetProjectPromptAttributes(projectID int, attributes []string) ([]string, error) {
+	cleaned := []string{}
+	seen := make(map[string]bool)
+	for _, attribute := range attributes {
+		attribute = strings.TrimSpace(attribute)
+		key := strings.ToLower(attribute)
+		if attribute == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, attribute)
+	}
+	value := ""
+	if len(cleaned) > 0 {
+		data, err := json.Marshal(cleaned)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode project prompt attributes: %w", err)
+		}
+		value = string(data)
+	}
+
+	result, err := db.conn.Exec(`
+		UPDATE client_projects SET prompt_attributes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, value, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set project prompt attributes: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("project not found")
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProjectPromptAttributes(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	if got, err := db.GetProjectPromptAttributes(project.ID); err != nil || len(got) != 0 {
+		t.Fatalf("GetProjectPromptAttributes() = %v, %v, want empty", got, err)
+	}
+
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{"trims and deduplicates", []string{" Производитель ", "article", "производитель", ""}, []string{"Производитель", "article"}},
+		{"clears", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved, err := db.SetProjectPromptAttributes(project.ID, tt.input)
+			if err != nil || !reflect.DeepEqual(saved, tt.want) {
+				t.Fatalf("SetProjectPromptAttributes() = %v, %v, want %v", saved, err, tt.want)
+			}
+			if got, err := db.GetProjectPromptAttributes(project.ID); err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetProjectPromptAttributes() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.SetProjectPromptAttributes(project.ID+100, []string{"article"}); err == nil {
+		t.Error("SetProjectPromptAttributes(unknown project) expected error")
+	}
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Статусы нарушений качества
+const (
+	QualityViolationOpen         = "open"
+	QualityViolationAcknowledged = "acknowledged" // Принято в работу, нарушение остается нерешенным
+	QualityViolationDismissed    = "dismissed"    // Отклонено как несущественное
+	QualityViolationResolved     = "resolved"
+)
+
+// MigrateQualityViolationStatus добавляет нарушениям качества статус; нарушения, разрешенные
+// до миграции, получают статус resolved
+func MigrateQualityViolationStatus(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE quality_violations ADD COLUMN status TEXT DEFAULT 'open'`)
+	if err != nil {
+		errStr := strings.ToLower(err.Error())
+		if !strings.Contains(errStr, "duplicate column") &&
+			!strings.Contains(errStr, "already exists") {
+			return fmt.Errorf("failed to add quality_violations.status column: %w", err)
+		}
+	}
+	statements := []string{
+		`UPDATE quality_violations SET status = 'resolved' WHERE resolved_at IS NOT NULL AND COALESCE(status, 'open') = 'open'`,
+		`CREATE INDEX IF NOT EXISTS idx_qv_status_rule ON quality_violations(status, rule_name)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to migrate quality_violations status: %w", err)
+		}
+	}
+	return nil
+}
+
+// QualityViolationFilter фильтр нарушений для массовых действий. Пустые поля не ограничивают выборку,
+// пустой Status выбирает открытые нарушения
+type QualityViolationFilter struct {
+	IDs      []int  `json:"ids,omitempty"`
+	RuleName string `json:"rule_name,omitempty"`
+	Category string `json:"category,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Status   string `json:"status,omitempty"`
+}
+
+// where формирует условие WHERE фильтра
+func (f QualityViolationFilter) where() (string, []interface{}) {
+	status := f.Status
+	if status == "" {
+		status = QualityViolationOpen
+	}
+	conditions := []string{"COALESCE(status, 'open') = ?"}
+	args := []interface{}{status}
+
+	columns := []struct {
+		column string
+		value  string
+	}{
+		{"rule_name", f.RuleName},
+		{"category", f.Category},
+		{"severity", f.Severity},
+		{"field", f.Field},
+	}
+	for _, c := range columns {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if len(f.IDs) > 0 {
+		placeholders := make([]string, len(f.IDs))
+		for i, id := range f.IDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		conditions = append(conditions, "id IN ("+strings.Join(placeholders, ",")+")")
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// FindQualityViolations возвращает нарушения по фильтру в порядке id, не более limit
+func (db *DB) FindQualityViolations(filter QualityViolationFilter, limit int) ([]QualityViolation, error) {
+	where, args := filter.where()
+	args = append(args, limit)
+	rows, err := db.conn.Query(`
+		SELECT id, normalized_item_id, rule_name, category, severity,
+			description, COALESCE(field, ''), COALESCE(current_value, ''), COALESCE(recommendation, ''),
+			detected_at, resolved_at, COALESCE(resolved_by, ''), COALESCE(status, 'open')
+		FROM quality_violations`+where+` ORDER BY id LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find violations: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []QualityViolation
+	for rows.Next() {
+		var v QualityViolation
+		if err := rows.Scan(&v.ID, &v.NormalizedItemID, &v.RuleName, &v.Category, &v.Severity,
+			&v.Description, &v.Field, &v.CurrentValue, &v.Recommendation,
+			&v.DetectedAt, &v.ResolvedAt, &v.ResolvedBy, &v.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan violation: %w", err)
+		}
+		violations = append(violations, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate violations: %w", err)
+	}
+	return violations, nil
+}
+
+// SetQualityViolationsStatus меняет статус нарушений одной транзакцией. Для dismissed и resolved
+// заполняются resolved_at и resolved_by, для open и acknowledged они сбрасываются.
+// Возвращает количество измененных нарушений
+func (db *DB) SetQualityViolationsStatus(ids []int, status, resolvedBy string) (int, error) {
+	var query string
+	var resolvedArgs []interface{}
+	switch status {
+	case QualityViolationDismissed, QualityViolationResolved:
+		query = `UPDATE quality_violations SET status = ?, resolved_at = ?, resolved_by = ? WHERE id = ?`
+		resolvedArgs = []interface{}{time.Now(), resolvedBy}
+	case QualityViolationOpen, QualityViolationAcknowledged:
+		query = `UPDATE quality_violations SET status = ?, resolved_at = NULL, resolved_by = NULL WHERE id = ?`
+	default:
+		return 0, fmt.Errorf("unknown violation status: %q", status)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare violation status update: %w", err)
+	}
+	defer stmt.Close()
+
+	updated := 0
+	for _, id := range ids {
+		args := append([]interface{}{status}, resolvedArgs...)
+		result, err := stmt.Exec(append(args, id)...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update violation %d: %w", id, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil {
+			updated += int(affected)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit violation status update: %w", err)
+	}
+	return updated, nil
+}
+
+// FindFixSuggestion возвращает непримененное предложение с конкретным значением поля записи
+// (set_value, correct_format) с наибольшей уверенностью; nil - исправлять нечем.
+// Предложения повторной обработки и проверки содержат инструкцию, а не значение, и не подходят
+func (db *DB) FindFixSuggestion(normalizedItemID int, field string) (*QualitySuggestion, error) {
+	s := &QualitySuggestion{}
+	var currentValue, reasoning sql.NullString
+	err := db.conn.QueryRow(`
+		SELECT id, normalized_item_id, suggestion_type, priority, field,
+			current_value, suggested_value, confidence, reasoning,
+			auto_applyable, applied, created_at
+		FROM quality_suggestions
+		WHERE normalized_item_id = ? AND field = ? AND COALESCE(applied, 0) = 0
+		  AND suggestion_type IN ('set_value', 'correct_format')
+		  AND COALESCE(suggested_value, '') != ''
+		ORDER BY confidence DESC, id
+		LIMIT 1
+	`, normalizedItemID, field).Scan(&s.ID, &s.NormalizedItemID, &s.SuggestionType, &s.Priority, &s.Field,
+		&currentValue, &s.SuggestedValue, &s.Confidence, &reasoning,
+		&s.AutoApplyable, &s.Applied, &s.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find fix suggestion: %w", err)
+	}
+	s.CurrentValue = currentValue.String
+	s.Reasoning = reasoning.String
+	return s, nil
+}
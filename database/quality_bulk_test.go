@@ -0,0 +1,114 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQualityViolationBulkStatus(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO normalized_data (id, code, normalized_name, category) VALUES (1, 'c1', 'болт', 'крепеж'), (2, 'c2', 'гайка', 'крепеж')`); err != nil {
+		t.Fatalf("insert error = %v", err)
+	}
+
+	violations := []QualityViolation{
+		{NormalizedItemID: 1, RuleName: "name_length", Category: "format", Severity: "warning", Field: "normalized_name"},
+		{NormalizedItemID: 2, RuleName: "name_length", Category: "format", Severity: "warning", Field: "normalized_name"},
+		{NormalizedItemID: 2, RuleName: "require_kpved_code", Category: "completeness", Severity: "error", Field: "kpved_code"},
+	}
+	for i := range violations {
+		violations[i].DetectedAt = time.Now()
+		if err := db.SaveQualityViolation(&violations[i]); err != nil {
+			t.Fatalf("SaveQualityViolation() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter QualityViolationFilter
+		want   int
+	}{
+		{"all open", QualityViolationFilter{}, 3},
+		{"by rule", QualityViolationFilter{RuleName: "name_length"}, 2},
+		{"by ids and severity", QualityViolationFilter{IDs: []int{violations[0].ID, violations[2].ID}, Severity: "error"}, 1},
+		{"dismissed", QualityViolationFilter{Status: QualityViolationDismissed}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := db.FindQualityViolations(tt.filter, 100)
+			if err != nil {
+				t.Fatalf("FindQualityViolations() error = %v", err)
+			}
+			if len(found) != tt.want {
+				t.Errorf("FindQualityViolations() = %d violations, want %d", len(found), tt.want)
+			}
+		})
+	}
+
+	updated, err := db.SetQualityViolationsStatus([]int{violations[0].ID, violations[1].ID}, QualityViolationDismissed, "аналитик")
+	if err != nil || updated != 2 {
+		t.Fatalf("SetQualityViolationsStatus() = %d, %v", updated, err)
+	}
+	dismissed, err := db.FindQualityViolations(QualityViolationFilter{Status: QualityViolationDismissed}, 100)
+	if err != nil || len(dismissed) != 2 {
+		t.Fatalf("dismissed violations = %d, %v", len(dismissed), err)
+	}
+	if dismissed[0].ResolvedAt == nil || dismissed[0].ResolvedBy != "аналитик" {
+		t.Errorf("dismissed violation = %+v, want resolved_at and resolved_by", dismissed[0])
+	}
+	if _, err := db.SetQualityViolationsStatus([]int{violations[0].ID}, "closed", "аналитик"); err == nil {
+		t.Errorf("SetQualityViolationsStatus() with unknown status: want error")
+	}
+}
+
+func TestFindFixSuggestion(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO normalized_data (id, code, normalized_name, category) VALUES (1, 'c1', 'болт', 'крепеж')`); err != nil {
+		t.Fatalf("insert error = %v", err)
+	}
+
+	suggestions := []QualitySuggestion{
+		{NormalizedItemID: 1, SuggestionType: "reprocess", Priority: "high", Field: "kpved_code", SuggestedValue: "Запустить классификацию КПВЭД", Confidence: 0.9},
+		{NormalizedItemID: 1, SuggestionType: "correct_format", Priority: "low", Field: "normalized_name", SuggestedValue: "болт м10", Confidence: 0.5},
+		{NormalizedItemID: 1, SuggestionType: "correct_format", Priority: "low", Field: "normalized_name", SuggestedValue: "болт м10х50", Confidence: 0.7},
+	}
+	for i := range suggestions {
+		suggestions[i].CreatedAt = time.Now()
+		if err := db.SaveQualitySuggestion(&suggestions[i]); err != nil {
+			t.Fatalf("SaveQualitySuggestion() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		want  string
+	}{
+		{"most confident value", "normalized_name", "болт м10х50"},
+		{"instruction is not a fix", "kpved_code", ""},
+		{"no suggestion", "category", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion, err := db.FindFixSuggestion(1, tt.field)
+			if err != nil {
+				t.Fatalf("FindFixSuggestion() error = %v", err)
+			}
+			got := ""
+			if suggestion != nil {
+				got = suggestion.SuggestedValue
+			}
+			if got != tt.want {
+				t.Errorf("FindFixSuggestion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
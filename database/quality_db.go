@@ -39,6 +39,7 @@ type QualityViolation struct {
 	DetectedAt       time.Time `json:"detected_at"`
 	ResolvedAt       *time.Time `json:"resolved_at,omitempty"`
 	ResolvedBy       string    `json:"resolved_by,omitempty"`
+	Status           string    `json:"status"` // open, acknowledged, dismissed, resolved
 }
 
 // QualitySuggestion предложение по улучшению
@@ -276,7 +277,7 @@ func (db *DB) GetViolations(filters map[string]interface{}, limit, offset int) (
 	query := `
 		SELECT id, normalized_item_id, rule_name, category, severity,
 			description, field, current_value, recommendation,
-			detected_at, resolved_at, resolved_by
+			detected_at, resolved_at, COALESCE(resolved_by, ''), COALESCE(status, 'open')
 		FROM quality_violations
 	` + whereClause + ` ORDER BY detected_at DESC LIMIT ? OFFSET ?`
 
@@ -303,6 +304,7 @@ func (db *DB) GetViolations(filters map[string]interface{}, limit, offset int) (
 			&v.DetectedAt,
 			&v.ResolvedAt,
 			&v.ResolvedBy,
+			&v.Status,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan violation: %w", err)
@@ -317,11 +319,11 @@ func (db *DB) GetViolations(filters map[string]interface{}, limit, offset int) (
 func (db *DB) ResolveViolation(id int, resolvedBy string) error {
 	query := `
 		UPDATE quality_violations
-		SET resolved_at = ?, resolved_by = ?
+		SET resolved_at = ?, resolved_by = ?, status = ?
 		WHERE id = ?
 	`
 
-	_, err := db.conn.Exec(query, time.Now(), resolvedBy, id)
+	_, err := db.conn.Exec(query, time.Now(), resolvedBy, QualityViolationResolved, id)
 	if err != nil {
 		return fmt.Errorf("failed to resolve violation: %w", err)
 	}
@@ -558,6 +560,25 @@ func (db *DB) MarkDuplicateGroupMerged(id int) error {
 		return fmt.Errorf("failed to mark duplicate group as merged: %w", err)
 	}
 
+	// Фиксируем объединение в истории происхождения основной записи группы
+	var itemIDsJSON string
+	var masterID int
+	var similarity float64
+	err = db.conn.QueryRow(`SELECT item_ids_json, COALESCE(suggested_master_id, 0), similarity_score FROM duplicate_groups WHERE id = ?`, id).
+		Scan(&itemIDsJSON, &masterID, &similarity)
+	if err == nil && masterID > 0 {
+		var itemIDs []int
+		json.Unmarshal([]byte(itemIDsJSON), &itemIDs)
+		record := NewLineageRecord(LineageEventMerge, "duplicate_group", 0, map[string]interface{}{
+			"duplicate_group_id": id,
+			"merged_item_ids":    itemIDs,
+			"similarity_score":   similarity,
+		})
+		if err := db.AddNormalizedItemLineage(masterID, []*LineageRecord{record}); err != nil {
+			return fmt.Errorf("failed to record merge lineage: %w", err)
+		}
+	}
+
 	return nil
 }
 
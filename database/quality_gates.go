@@ -0,0 +1,111 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// QualityGates пороги качества нормализованных данных базы, которые должны выполняться перед выгрузкой в 1С.
+// nil порог не проверяется
+type QualityGates struct {
+	DatabaseID                int       `json:"database_id"`
+	MinClassificationCoverage *float64  `json:"min_classification_coverage"` // Минимальная доля классифицированных записей, 0..1
+	MaxDuplicateRate          *float64  `json:"max_duplicate_rate"`          // Максимальная доля дублей по наименованию, 0..1
+	RequireEmptyReviewQueue   bool      `json:"require_empty_review_queue"`  // Очередь проверки групп должна быть пустой
+	UpdatedAt                 time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultQualityGates пороги, предлагаемые для базы без настроенных порогов
+func DefaultQualityGates(databaseID int) QualityGates {
+	coverage, duplicates := 0.95, 0.02
+	return QualityGates{
+		DatabaseID:                databaseID,
+		MinClassificationCoverage: &coverage,
+		MaxDuplicateRate:          &duplicates,
+		RequireEmptyReviewQueue:   true,
+	}
+}
+
+// Validate проверяет, что пороги заданы долями от 0 до 1
+func (g QualityGates) Validate() error {
+	for name, value := range map[string]*float64{
+		"min_classification_coverage": g.MinClassificationCoverage,
+		"max_duplicate_rate":          g.MaxDuplicateRate,
+	} {
+		if value != nil && (*value < 0 || *value > 1) {
+			return apperrors.Validation("invalid_quality_gate", fmt.Sprintf("%s must be between 0 and 1", name))
+		}
+	}
+	return nil
+}
+
+// CreateQualityGatesTable создает таблицу порогов качества баз данных
+func CreateQualityGatesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS quality_gates (
+			database_id INTEGER PRIMARY KEY,
+			min_classification_coverage REAL,
+			max_duplicate_rate REAL,
+			require_empty_review_queue INTEGER NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create quality_gates table: %w", err)
+	}
+	return nil
+}
+
+// GetQualityGates возвращает пороги качества базы или nil, если они не настроены
+func (db *ServiceDB) GetQualityGates(databaseID int) (*QualityGates, error) {
+	gates := &QualityGates{}
+	var coverage, duplicates sql.NullFloat64
+	err := db.conn.QueryRow(`
+		SELECT database_id, min_classification_coverage, max_duplicate_rate, require_empty_review_queue, updated_at
+		FROM quality_gates WHERE database_id = ?
+	`, databaseID).Scan(&gates.DatabaseID, &coverage, &duplicates, &gates.RequireEmptyReviewQueue, &gates.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quality gates: %w", err)
+	}
+	if coverage.Valid {
+		gates.MinClassificationCoverage = &coverage.Float64
+	}
+	if duplicates.Valid {
+		gates.MaxDuplicateRate = &duplicates.Float64
+	}
+	return gates, nil
+}
+
+// SetQualityGates сохраняет пороги качества базы
+func (db *ServiceDB) SetQualityGates(gates *QualityGates) error {
+	if err := gates.Validate(); err != nil {
+		return err
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO quality_gates (database_id, min_classification_coverage, max_duplicate_rate, require_empty_review_queue)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(database_id) DO UPDATE SET
+			min_classification_coverage = excluded.min_classification_coverage,
+			max_duplicate_rate = excluded.max_duplicate_rate,
+			require_empty_review_queue = excluded.require_empty_review_queue,
+			updated_at = CURRENT_TIMESTAMP
+	`, gates.DatabaseID, gates.MinClassificationCoverage, gates.MaxDuplicateRate, gates.RequireEmptyReviewQueue)
+	if err != nil {
+		return fmt.Errorf("failed to set quality gates: %w", err)
+	}
+	return nil
+}
+
+// DeleteQualityGates удаляет пороги качества базы: выгрузка в 1С перестает их проверять
+func (db *ServiceDB) DeleteQualityGates(databaseID int) error {
+	if _, err := db.conn.Exec(`DELETE FROM quality_gates WHERE database_id = ?`, databaseID); err != nil {
+		return fmt.Errorf("failed to delete quality gates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReplaceQualityIssues заменяет открытые проблемы качества выгрузки указанных типов новыми (в одной транзакции).
+// Используется анализаторами, которые при повторном запуске пересчитывают проблемы целиком
+func (db *DB) ReplaceQualityIssues(uploadID int, issueTypes []string, issues []DataQualityIssue) error {
+	if len(issueTypes) == 0 {
+		return fmt.Errorf("issue types are required")
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{uploadID}
+	for _, issueType := range issueTypes {
+		args = append(args, issueType)
+	}
+	_, err = tx.Exec(`
+		DELETE FROM data_quality_issues
+		WHERE upload_id = ? AND status = 'OPEN' AND issue_type IN (?`+strings.Repeat(", ?", len(issueTypes)-1)+`)
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete quality issues: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO data_quality_issues (
+			upload_id, database_id, entity_type, entity_reference,
+			issue_type, issue_severity, field_name, expected_value,
+			actual_value, description, detected_at, status
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare quality issue insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, issue := range issues {
+		_, err := stmt.Exec(issue.UploadID, issue.DatabaseID, issue.EntityType, issue.EntityReference,
+			issue.IssueType, issue.IssueSeverity, issue.FieldName, issue.ExpectedValue,
+			issue.ActualValue, issue.Description, issue.DetectedAt, issue.Status)
+		if err != nil {
+			return fmt.Errorf("failed to save quality issue: %w", err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ScorecardMetric показатель карты качества базы данных
+type ScorecardMetric struct {
+	Name      string   `json:"name"`            // completeness, duplicates, classification, naming
+	Score     *float64 `json:"score"`           // 0..100, nil - показатель не рассчитан (нет данных)
+	Value     float64  `json:"value"`           // Исходное значение: доля заполненных, доля дублей и т.д.
+	Weight    float64  `json:"weight"`          // Вес в итоговой оценке
+	Grade     string   `json:"grade,omitempty"` // A, B, C, D, F
+	Total     int      `json:"total"`           // Элементов в расчете
+	Affected  int      `json:"affected"`        // Элементов с проблемой
+	Available bool     `json:"available"`
+}
+
+// QualityScorecard карта качества базы данных проекта с буквенной оценкой
+type QualityScorecard struct {
+	ID            int               `json:"id"`
+	DatabaseID    int               `json:"database_id"`
+	Score         float64           `json:"score"` // Взвешенная итоговая оценка 0..100
+	Grade         string            `json:"grade"`
+	Metrics       []ScorecardMetric `json:"metrics"`
+	ItemsAnalyzed int               `json:"items_analyzed"`
+	ComputedAt    time.Time         `json:"computed_at"`
+}
+
+// ScorecardSourceItem код и наименование исходного элемента справочника
+type ScorecardSourceItem struct {
+	Code string
+	Name string
+}
+
+// CreateQualityScorecardsTable создает таблицу истории карт качества баз данных
+func CreateQualityScorecardsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS quality_scorecards (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			database_id INTEGER NOT NULL,
+			score REAL NOT NULL,
+			grade TEXT NOT NULL,
+			metrics TEXT NOT NULL,
+			items_analyzed INTEGER NOT NULL DEFAULT 0,
+			computed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_quality_scorecards_database ON quality_scorecards(database_id, computed_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create quality_scorecards table: %w", err)
+	}
+	return nil
+}
+
+// SaveQualityScorecard сохраняет карту качества в историю
+func (db *ServiceDB) SaveQualityScorecard(scorecard *QualityScorecard) error {
+	metrics, err := json.Marshal(scorecard.Metrics)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scorecard metrics: %w", err)
+	}
+	if scorecard.ComputedAt.IsZero() {
+		scorecard.ComputedAt = time.Now()
+	}
+
+	result, err := db.conn.Exec(`
+		INSERT INTO quality_scorecards (database_id, score, grade, metrics, items_analyzed, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, scorecard.DatabaseID, scorecard.Score, scorecard.Grade, string(metrics), scorecard.ItemsAnalyzed, scorecard.ComputedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save quality scorecard: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get quality scorecard id: %w", err)
+	}
+	scorecard.ID = int(id)
+	return nil
+}
+
+// GetQualityScorecards возвращает историю карт качества базы данных, начиная с последней
+func (db *ServiceDB) GetQualityScorecards(databaseID, limit int) ([]*QualityScorecard, error) {
+	if limit <= 0 {
+		limit = 30
+	}
+	rows, err := db.conn.Query(`
+		SELECT id, database_id, score, grade, metrics, items_analyzed, computed_at
+		FROM quality_scorecards
+		WHERE database_id = ?
+		ORDER BY computed_at DESC, id DESC
+		LIMIT ?
+	`, databaseID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quality scorecards: %w", err)
+	}
+	defer rows.Close()
+
+	scorecards := []*QualityScorecard{}
+	for rows.Next() {
+		scorecard := &QualityScorecard{}
+		var metrics string
+		if err := rows.Scan(&scorecard.ID, &scorecard.DatabaseID, &scorecard.Score, &scorecard.Grade,
+			&metrics, &scorecard.ItemsAnalyzed, &scorecard.ComputedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan quality scorecard: %w", err)
+		}
+		if err := json.Unmarshal([]byte(metrics), &scorecard.Metrics); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal scorecard metrics: %w", err)
+		}
+		scorecards = append(scorecards, scorecard)
+	}
+	return scorecards, rows.Err()
+}
+
+// GetScorecardSourceItems возвращает коды и наименования исходных элементов: справочники,
+// номенклатура и динамические таблицы справочников
+func (db *DB) GetScorecardSourceItems() ([]ScorecardSourceItem, error) {
+	queries := []string{}
+	for _, source := range []struct{ table, query string }{
+		{"catalog_items", "SELECT COALESCE(code, ''), COALESCE(name, '') FROM catalog_items"},
+		{"nomenclature_items", "SELECT COALESCE(nomenclature_code, ''), COALESCE(nomenclature_name, '') FROM nomenclature_items"},
+	} {
+		exists, err := tableExists(db.conn, source.table)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			queries = append(queries, source.query)
+		}
+	}
+
+	tables, err := catalogTables(db.conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, tableName := range tables {
+		queries = append(queries, fmt.Sprintf("SELECT COALESCE(code, ''), COALESCE(name, '') FROM %s", tableName))
+	}
+
+	var items []ScorecardSourceItem
+	for _, query := range queries {
+		rows, err := db.conn.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get scorecard source items: %w", err)
+		}
+		for rows.Next() {
+			var item ScorecardSourceItem
+			if err := rows.Scan(&item.Code, &item.Name); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan scorecard source item: %w", err)
+			}
+			items = append(items, item)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// GetClassificationCoverage возвращает число нормализованных записей и записей с кодом КПВЭД
+func (db *DB) GetClassificationCoverage() (total, classified int, err error) {
+	exists, err := tableExists(db.conn, "normalized_data")
+	if err != nil || !exists {
+		return 0, 0, err
+	}
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN kpved_code IS NOT NULL AND TRIM(kpved_code) != '' THEN 1 ELSE 0 END), 0)
+		FROM normalized_data
+	`).Scan(&total, &classified)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get classification coverage: %w", err)
+	}
+	return total, classified, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// openReadOnlyPool открывает отдельный пул соединений только для чтения.
+// По умолчанию это та же SQLite БД в режиме mode=ro; если задан ReadDSN,
+// используется он (например, реплика при переходе на Postgres).
+// Для in-memory БД пул не создается - отдельное соединение увидело бы пустую БД.
+func openReadOnlyPool(dbPath string, config DBConfig) (*sql.DB, error) {
+	if config.ReadPoolMaxOpenConns <= 0 {
+		return nil, nil
+	}
+
+	dsn := config.ReadDSN
+	if dsn == "" {
+		if dbPath == "" || dbPath == ":memory:" || strings.Contains(dbPath, "mode=memory") {
+			return nil, nil
+		}
+		dsn = readOnlyDSN(dbPath)
+	}
+
+	conn, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read-only pool: %w", err)
+	}
+
+	conn.SetMaxOpenConns(config.ReadPoolMaxOpenConns)
+	conn.SetMaxIdleConns(config.ReadPoolMaxOpenConns)
+	if config.ConnMaxLifetime > 0 {
+		conn.SetConnMaxLifetime(config.ConnMaxLifetime)
+	} else {
+		conn.SetConnMaxLifetime(5 * time.Minute)
+	}
+
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ping read-only pool: %w", err)
+	}
+
+	return conn, nil
+}
+
+// readOnlyDSN формирует DSN SQLite в режиме только для чтения
+func readOnlyDSN(dbPath string) string {
+	if strings.HasPrefix(dbPath, "file:") {
+		if strings.Contains(dbPath, "?") {
+			return dbPath + "&mode=ro"
+		}
+		return dbPath + "?mode=ro"
+	}
+	return "file:" + dbPath + "?mode=ro"
+}
+
+// ReadOnly возвращает представление БД, выполняющее запросы через пул только для чтения.
+// Используется тяжелыми аналитическими запросами и экспортом, чтобы не конкурировать с загрузкой.
+// Если пул чтения не настроен, возвращается сама БД.
+func (db *DB) ReadOnly() *DB {
+	if db.readOnly != nil {
+		return db.readOnly
+	}
+	return db
+}
+
+// PoolStats статистика пула соединений
+type PoolStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDurationMs     int64   `json:"wait_duration_ms"`
+	Utilization        float64 `json:"utilization"` // Доля занятых соединений от максимума
+}
+
+// newPoolStats формирует статистику пула из sql.DBStats
+func newPoolStats(stats sql.DBStats) PoolStats {
+	result := PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+	}
+	if stats.MaxOpenConnections > 0 {
+		result.Utilization = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+	return result
+}
+
+// PoolStats возвращает статистику основного пула и пула чтения (nil, если не настроен)
+func (db *DB) PoolStats() (PoolStats, *PoolStats) {
+	writeStats := newPoolStats(db.conn.Stats())
+	if db.readOnly == nil {
+		return writeStats, nil
+	}
+	readStats := newPoolStats(db.readOnly.conn.Stats())
+	return writeStats, &readStats
+}
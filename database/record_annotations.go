@@ -0,0 +1,350 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Типы записей, к которым относятся комментарии и теги
+const (
+	RecordTargetItem  = "item"  // Нормализованная запись, ключ - normalized_data.id
+	RecordTargetGroup = "group" // Группа дубликатов, ключ - normalized_reference
+)
+
+// IsValidRecordTarget проверяет тип записи комментария или тега
+func IsValidRecordTarget(targetType string) bool {
+	return targetType == RecordTargetItem || targetType == RecordTargetGroup
+}
+
+// RecordComment комментарий аналитика к нормализованной записи или группе дубликатов
+type RecordComment struct {
+	ID         int       `json:"id"`
+	ProjectID  int       `json:"project_id"`
+	TargetType string    `json:"target_type"`
+	TargetKey  string    `json:"target_key"`
+	Author     string    `json:"author,omitempty"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// RecordTag тег нормализованной записи или группы дубликатов ("уточнить у клиента", "сверить со складом")
+type RecordTag struct {
+	ID         int       `json:"id"`
+	ProjectID  int       `json:"project_id"`
+	TargetType string    `json:"target_type"`
+	TargetKey  string    `json:"target_key"`
+	Tag        string    `json:"tag"`
+	CreatedBy  string    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RecordAnnotationFilter фильтр комментариев и тегов; пустые значения не ограничивают выборку
+type RecordAnnotationFilter struct {
+	TargetType string
+	TargetKey  string
+	Tag        string // Только для тегов
+}
+
+// TaggedRecords записи проекта с тегом: ID нормализованных записей и ссылки групп
+type TaggedRecords struct {
+	ItemIDs   []int
+	GroupRefs []string
+}
+
+// Empty сообщает, что тегом не отмечено ни одной записи
+func (t *TaggedRecords) Empty() bool {
+	return len(t.ItemIDs) == 0 && len(t.GroupRefs) == 0
+}
+
+// CreateRecordAnnotationTables создает таблицы комментариев и тегов записей проектов
+func CreateRecordAnnotationTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS record_comments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			project_id INTEGER NOT NULL,
+			target_type TEXT NOT NULL,
+			target_key TEXT NOT NULL,
+			author TEXT NOT NULL DEFAULT '',
+			body TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_record_comments_target ON record_comments(project_id, target_type, target_key);
+
+		CREATE TABLE IF NOT EXISTS record_tags (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			project_id INTEGER NOT NULL,
+			target_type TEXT NOT NULL,
+			target_key TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(project_id, target_type, target_key, tag),
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_record_tags_tag ON record_tags(project_id, tag);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create record annotation tables: %w", err)
+	}
+	return nil
+}
+
+// NormalizeRecordTag приводит тег к хранимому виду: без лишних пробелов, в нижнем регистре
+func NormalizeRecordTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// validateRecordTarget проверяет тип и ключ записи
+func validateRecordTarget(targetType, targetKey string) error {
+	if !IsValidRecordTarget(targetType) {
+		return fmt.Errorf("invalid target type %q", targetType)
+	}
+	if targetKey == "" {
+		return fmt.Errorf("target key is required")
+	}
+	if targetType == RecordTargetItem {
+		if id, err := strconv.Atoi(targetKey); err != nil || id <= 0 {
+			return fmt.Errorf("invalid normalized item ID %q", targetKey)
+		}
+	}
+	return nil
+}
+
+// annotationConditions строит условия выборки по фильтру
+func annotationConditions(projectID int, filter RecordAnnotationFilter) (string, []interface{}) {
+	conditions := []string{"project_id = ?"}
+	args := []interface{}{projectID}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetKey != "" {
+		conditions = append(conditions, "target_key = ?")
+		args = append(args, filter.TargetKey)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// CreateRecordComment добавляет комментарий к записи проекта
+func (db *ServiceDB) CreateRecordComment(comment *RecordComment) error {
+	comment.TargetKey = strings.TrimSpace(comment.TargetKey)
+	comment.Body = strings.TrimSpace(comment.Body)
+	if err := validateRecordTarget(comment.TargetType, comment.TargetKey); err != nil {
+		return err
+	}
+	if comment.Body == "" {
+		return fmt.Errorf("comment body is required")
+	}
+	now := time.Now()
+	result, err := db.conn.Exec(`
+		INSERT INTO record_comments (project_id, target_type, target_key, author, body, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, comment.ProjectID, comment.TargetType, comment.TargetKey, comment.Author, comment.Body, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get comment id: %w", err)
+	}
+	comment.ID = int(id)
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	return nil
+}
+
+// GetRecordComment возвращает комментарий проекта
+func (db *ServiceDB) GetRecordComment(projectID, id int) (*RecordComment, error) {
+	comment := &RecordComment{}
+	err := db.conn.QueryRow(`
+		SELECT id, project_id, target_type, target_key, author, body, created_at, updated_at
+		FROM record_comments
+		WHERE id = ? AND project_id = ?
+	`, id, projectID).Scan(&comment.ID, &comment.ProjectID, &comment.TargetType, &comment.TargetKey,
+		&comment.Author, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("comment not found")
+		}
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return comment, nil
+}
+
+// GetRecordComments возвращает комментарии проекта, новые в конце
+func (db *ServiceDB) GetRecordComments(projectID int, filter RecordAnnotationFilter) ([]*RecordComment, error) {
+	where, args := annotationConditions(projectID, filter)
+	rows, err := db.conn.Query(`
+		SELECT id, project_id, target_type, target_key, author, body, created_at, updated_at
+		FROM record_comments
+		WHERE `+where+`
+		ORDER BY created_at, id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []*RecordComment{}
+	for rows.Next() {
+		comment := &RecordComment{}
+		if err := rows.Scan(&comment.ID, &comment.ProjectID, &comment.TargetType, &comment.TargetKey,
+			&comment.Author, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// UpdateRecordComment изменяет текст комментария проекта
+func (db *ServiceDB) UpdateRecordComment(comment *RecordComment) error {
+	comment.Body = strings.TrimSpace(comment.Body)
+	if comment.Body == "" {
+		return fmt.Errorf("comment body is required")
+	}
+	now := time.Now()
+	result, err := db.conn.Exec(`
+		UPDATE record_comments SET body = ?, updated_at = ? WHERE id = ? AND project_id = ?
+	`, comment.Body, now, comment.ID, comment.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to update comment: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("comment not found")
+	}
+	comment.UpdatedAt = now
+	return nil
+}
+
+// DeleteRecordComment удаляет комментарий проекта
+func (db *ServiceDB) DeleteRecordComment(projectID, id int) error {
+	result, err := db.conn.Exec(`DELETE FROM record_comments WHERE id = ? AND project_id = ?`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("comment not found")
+	}
+	return nil
+}
+
+// AddRecordTag отмечает запись проекта тегом; повторная отметка тем же тегом возвращает существующий тег
+func (db *ServiceDB) AddRecordTag(tag *RecordTag) error {
+	tag.TargetKey = strings.TrimSpace(tag.TargetKey)
+	tag.Tag = NormalizeRecordTag(tag.Tag)
+	if err := validateRecordTarget(tag.TargetType, tag.TargetKey); err != nil {
+		return err
+	}
+	if tag.Tag == "" {
+		return fmt.Errorf("tag is required")
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO record_tags (project_id, target_type, target_key, tag, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(project_id, target_type, target_key, tag) DO NOTHING
+	`, tag.ProjectID, tag.TargetType, tag.TargetKey, tag.Tag, tag.CreatedBy, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add tag: %w", err)
+	}
+	err = db.conn.QueryRow(`
+		SELECT id, created_by, created_at FROM record_tags
+		WHERE project_id = ? AND target_type = ? AND target_key = ? AND tag = ?
+	`, tag.ProjectID, tag.TargetType, tag.TargetKey, tag.Tag).Scan(&tag.ID, &tag.CreatedBy, &tag.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to get tag: %w", err)
+	}
+	return nil
+}
+
+// GetRecordTags возвращает теги записей проекта
+func (db *ServiceDB) GetRecordTags(projectID int, filter RecordAnnotationFilter) ([]*RecordTag, error) {
+	where, args := annotationConditions(projectID, filter)
+	if tag := NormalizeRecordTag(filter.Tag); tag != "" {
+		where += " AND tag = ?"
+		args = append(args, tag)
+	}
+	rows, err := db.conn.Query(`
+		SELECT id, project_id, target_type, target_key, tag, created_by, created_at
+		FROM record_tags
+		WHERE `+where+`
+		ORDER BY tag, target_type, target_key
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*RecordTag{}
+	for rows.Next() {
+		tag := &RecordTag{}
+		if err := rows.Scan(&tag.ID, &tag.ProjectID, &tag.TargetType, &tag.TargetKey, &tag.Tag,
+			&tag.CreatedBy, &tag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// DeleteRecordTag снимает тег с записи проекта
+func (db *ServiceDB) DeleteRecordTag(projectID, id int) error {
+	result, err := db.conn.Exec(`DELETE FROM record_tags WHERE id = ? AND project_id = ?`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("tag not found")
+	}
+	return nil
+}
+
+// GetTaggedRecords возвращает записи и группы проекта, отмеченные тегом
+func (db *ServiceDB) GetTaggedRecords(projectID int, tag string) (*TaggedRecords, error) {
+	tags, err := db.GetRecordTags(projectID, RecordAnnotationFilter{Tag: tag})
+	if err != nil {
+		return nil, err
+	}
+	tagged := &TaggedRecords{}
+	for _, t := range tags {
+		switch t.TargetType {
+		case RecordTargetItem:
+			if id, err := strconv.Atoi(t.TargetKey); err == nil {
+				tagged.ItemIDs = append(tagged.ItemIDs, id)
+			}
+		case RecordTargetGroup:
+			tagged.GroupRefs = append(tagged.GroupRefs, t.TargetKey)
+		}
+	}
+	return tagged, nil
+}
+
+// GetRecordTagCounts возвращает число отмеченных записей по тегам проекта
+func (db *ServiceDB) GetRecordTagCounts(projectID int) (map[string]int, error) {
+	rows, err := db.conn.Query(`
+		SELECT tag, COUNT(*) FROM record_tags WHERE project_id = ? GROUP BY tag
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var tag string
+		var count int
+		if err := rows.Scan(&tag, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag count: %w", err)
+		}
+		counts[tag] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecordAnnotations(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	invalid := []struct {
+		name string
+		tag  RecordTag
+	}{
+		{"unknown target", RecordTag{ProjectID: project.ID, TargetType: "upload", TargetKey: "1", Tag: "x"}},
+		{"item key is not an ID", RecordTag{ProjectID: project.ID, TargetType: RecordTargetItem, TargetKey: "abc", Tag: "x"}},
+		{"empty tag", RecordTag{ProjectID: project.ID, TargetType: RecordTargetGroup, TargetKey: "ref", Tag: "  "}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.AddRecordTag(&tt.tag); err == nil {
+				t.Error("AddRecordTag() expected error")
+			}
+		})
+	}
+
+	first := &RecordTag{ProjectID: project.ID, TargetType: RecordTargetItem, TargetKey: "7", Tag: " Ask  Client "}
+	if err := db.AddRecordTag(first); err != nil || first.Tag != "ask client" {
+		t.Fatalf("AddRecordTag() = %q, %v", first.Tag, err)
+	}
+	repeated := &RecordTag{ProjectID: project.ID, TargetType: RecordTargetItem, TargetKey: "7", Tag: "ask client"}
+	if err := db.AddRecordTag(repeated); err != nil || repeated.ID != first.ID {
+		t.Errorf("repeated AddRecordTag() = %d, %v, want existing tag %d", repeated.ID, err, first.ID)
+	}
+	for _, tag := range []*RecordTag{
+		{ProjectID: project.ID, TargetType: RecordTargetGroup, TargetKey: "bolt-m8", Tag: "ask client"},
+		{ProjectID: project.ID, TargetType: RecordTargetGroup, TargetKey: "bolt-m8", Tag: "verify with warehouse"},
+	} {
+		if err := db.AddRecordTag(tag); err != nil {
+			t.Fatalf("AddRecordTag() error: %v", err)
+		}
+	}
+
+	tagged, err := db.GetTaggedRecords(project.ID, "Ask client")
+	if err != nil {
+		t.Fatalf("GetTaggedRecords() error: %v", err)
+	}
+	if !reflect.DeepEqual(tagged, &TaggedRecords{ItemIDs: []int{7}, GroupRefs: []string{"bolt-m8"}}) {
+		t.Errorf("GetTaggedRecords() = %+v", tagged)
+	}
+	counts, err := db.GetRecordTagCounts(project.ID)
+	if err != nil || !reflect.DeepEqual(counts, map[string]int{"ask client": 2, "verify with warehouse": 1}) {
+		t.Errorf("GetRecordTagCounts() = %v, %v", counts, err)
+	}
+	if err := db.DeleteRecordTag(project.ID+1, first.ID); err == nil {
+		t.Error("DeleteRecordTag() of another project expected error")
+	}
+
+	comment := &RecordComment{ProjectID: project.ID, TargetType: RecordTargetGroup, TargetKey: "bolt-m8", Author: "analyst", Body: "Уточнить размер"}
+	if err := db.CreateRecordComment(comment); err != nil {
+		t.Fatalf("CreateRecordComment() error: %v", err)
+	}
+	comment.Body = "Уточнить размер у клиента"
+	if err := db.UpdateRecordComment(comment); err != nil {
+		t.Fatalf("UpdateRecordComment() error: %v", err)
+	}
+	comments, err := db.GetRecordComments(project.ID, RecordAnnotationFilter{TargetType: RecordTargetGroup, TargetKey: "bolt-m8"})
+	if err != nil || len(comments) != 1 || comments[0].Body != "Уточнить размер у клиента" {
+		t.Errorf("GetRecordComments() = %+v, %v", comments, err)
+	}
+	if err := db.DeleteRecordComment(project.ID, comment.ID); err != nil {
+		t.Errorf("DeleteRecordComment() error: %v", err)
+	}
+	if _, err := db.GetRecordComment(project.ID, comment.ID); err == nil {
+		t.Error("GetRecordComment() after delete expected error")
+	}
+}
@@ -0,0 +1,614 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// Действия правил профилей скрытия колонок
+const (
+	RedactionHide      = "hide" // Поле не передается получателю
+	RedactionMask      = "mask" // Значение поля заменяется RedactionMaskValue
+	RedactionMaskValue = "***"
+)
+
+// Поля, к которым применяются правила профилей скрытия; реквизиты и константы - с префиксами
+const (
+	RedactionFieldCode       = "code"
+	RedactionFieldName       = "name"
+	RedactionFieldTableParts = "table_parts" // Табличные части целиком, только hide
+	RedactionAttributePrefix = "attributes."
+	RedactionConstantPrefix  = "constants."
+)
+
+// Получатели, которым назначаются профили скрытия
+const (
+	RedactionSubjectRole = "role"
+	RedactionSubjectUser = "user"
+)
+
+// ErrRedactionProfileNotFound профиль скрытия не найден
+var ErrRedactionProfileNotFound = apperrors.NotFound("redaction_profile_not_found", "redaction profile not found")
+
+// RedactionRule правило профиля: скрыть или замаскировать поле
+type RedactionRule struct {
+	Field  string `json:"field"`
+	Action string `json:"action"`
+}
+
+// RedactionProfile именованный набор скрываемых и маскируемых полей данных выгрузок.
+// Профиль назначается роли или пользователю и применяется к ответам /data, /stream, /documents, поиску
+// и истории элементов справочников, задачам экспорта
+type RedactionProfile struct {
+	ID          int             `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Rules       []RedactionRule `json:"rules"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// RedactionAssignment назначение профиля роли или пользователю
+type RedactionAssignment struct {
+	SubjectType string    `json:"subject_type"`
+	Subject     string    `json:"subject"`
+	ProfileID   int       `json:"profile_id"`
+	ProfileName string    `json:"profile_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateRedactionProfilesTables создает таблицы профилей скрытия колонок и их назначений
+func CreateRedactionProfilesTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS redaction_profiles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			description TEXT NOT NULL DEFAULT '',
+			rules TEXT NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS redaction_assignments (
+			subject_type TEXT NOT NULL,
+			subject TEXT NOT NULL COLLATE NOCASE,
+			profile_id INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY(subject_type, subject),
+			FOREIGN KEY(profile_id) REFERENCES redaction_profiles(id) ON DELETE CASCADE
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create redaction profiles tables: %w", err)
+	}
+	return nil
+}
+
+// ValidateRedactionRules проверяет поля и действия правил и убирает повторы полей (побеждает hide)
+func ValidateRedactionRules(rules []RedactionRule) ([]RedactionRule, error) {
+	actions := make(map[string]string)
+	var order []string
+	for _, rule := range rules {
+		field := strings.TrimSpace(rule.Field)
+		action := strings.ToLower(strings.TrimSpace(rule.Action))
+		switch {
+		case field == RedactionFieldCode, field == RedactionFieldName, field == RedactionFieldTableParts:
+		case strings.HasPrefix(field, RedactionAttributePrefix) && len(field) > len(RedactionAttributePrefix):
+		case strings.HasPrefix(field, RedactionConstantPrefix) && len(field) > len(RedactionConstantPrefix):
+		default:
+			return nil, apperrors.Validation("invalid_redaction_rule", fmt.Sprintf("unknown field: %q", rule.Field))
+		}
+		if action != RedactionHide && action != RedactionMask {
+			return nil, apperrors.Validation("invalid_redaction_rule", fmt.Sprintf("field %s: action must be hide or mask", field))
+		}
+		if field == RedactionFieldTableParts && action == RedactionMask {
+			return nil, apperrors.Validation("invalid_redaction_rule", "table_parts can only be hidden")
+		}
+		if _, ok := actions[field]; !ok {
+			order = append(order, field)
+		}
+		if actions[field] != RedactionHide {
+			actions[field] = action
+		}
+	}
+	result := make([]RedactionRule, 0, len(order))
+	for _, field := range order {
+		result = append(result, RedactionRule{Field: field, Action: actions[field]})
+	}
+	return result, nil
+}
+
+// CreateRedactionProfile создает профиль скрытия
+func (db *ServiceDB) CreateRedactionProfile(profile *RedactionProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return apperrors.Validation("invalid_redaction_profile", "name is required")
+	}
+	rules, err := ValidateRedactionRules(profile.Rules)
+	if err != nil {
+		return err
+	}
+	profile.Rules = rules
+	data, err := json.Marshal(profile.Rules)
+	if err != nil {
+		return fmt.Errorf("failed to marshal redaction rules: %w", err)
+	}
+
+	now := time.Now().UTC()
+	result, err := db.conn.Exec(`
+		INSERT INTO redaction_profiles (name, description, rules, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, profile.Name, profile.Description, string(data), now, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return apperrors.Conflict("redaction_profile_exists", fmt.Sprintf("redaction profile %s already exists", profile.Name))
+		}
+		return fmt.Errorf("failed to create redaction profile: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get redaction profile id: %w", err)
+	}
+	profile.ID = int(id)
+	profile.CreatedAt, profile.UpdatedAt = now, now
+	return nil
+}
+
+// UpdateRedactionProfile заменяет описание и правила профиля
+func (db *ServiceDB) UpdateRedactionProfile(id int, description string, rules []RedactionRule) (*RedactionProfile, error) {
+	rules, err := ValidateRedactionRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal redaction rules: %w", err)
+	}
+	result, err := db.conn.Exec(`UPDATE redaction_profiles SET description = ?, rules = ?, updated_at = ? WHERE id = ?`,
+		description, string(data), time.Now().UTC(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update redaction profile: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, ErrRedactionProfileNotFound
+	}
+	return db.GetRedactionProfile(id)
+}
+
+// DeleteRedactionProfile удаляет профиль вместе с его назначениями
+func (db *ServiceDB) DeleteRedactionProfile(id int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM redaction_assignments WHERE profile_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete redaction assignments: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM redaction_profiles WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete redaction profile: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrRedactionProfileNotFound
+	}
+	return tx.Commit()
+}
+
+// GetRedactionProfile возвращает профиль скрытия
+func (db *ServiceDB) GetRedactionProfile(id int) (*RedactionProfile, error) {
+	return db.getRedactionProfile(`WHERE id = ?`, id)
+}
+
+// GetRedactionProfileByName возвращает профиль скрытия по имени без учета регистра
+func (db *ServiceDB) GetRedactionProfileByName(name string) (*RedactionProfile, error) {
+	return db.getRedactionProfile(`WHERE name = ?`, strings.TrimSpace(name))
+}
+
+func (db *ServiceDB) getRedactionProfile(where string, args ...interface{}) (*RedactionProfile, error) {
+	profiles, err := db.queryRedactionProfiles(where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, ErrRedactionProfileNotFound
+	}
+	return profiles[0], nil
+}
+
+// ListRedactionProfiles возвращает профили скрытия по имени
+func (db *ServiceDB) ListRedactionProfiles() ([]*RedactionProfile, error) {
+	return db.queryRedactionProfiles(`ORDER BY name`)
+}
+
+func (db *ServiceDB) queryRedactionProfiles(where string, args ...interface{}) ([]*RedactionProfile, error) {
+	rows, err := db.conn.Query(`SELECT id, name, description, rules, created_at, updated_at FROM redaction_profiles `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redaction profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []*RedactionProfile
+	for rows.Next() {
+		profile := &RedactionProfile{}
+		var rules string
+		if err := rows.Scan(&profile.ID, &profile.Name, &profile.Description, &rules, &profile.CreatedAt, &profile.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan redaction profile: %w", err)
+		}
+		if err := json.Unmarshal([]byte(rules), &profile.Rules); err != nil {
+			return nil, fmt.Errorf("failed to parse rules of redaction profile %d: %w", profile.ID, err)
+		}
+		profiles = append(profiles, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate redaction profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// AssignRedactionProfile назначает профиль роли или пользователю; profileID 0 снимает назначение
+func (db *ServiceDB) AssignRedactionProfile(subjectType, subject string, profileID int) error {
+	subject = strings.TrimSpace(subject)
+	switch subjectType {
+	case RedactionSubjectRole:
+		if !ValidRole(subject) {
+			return apperrors.Validation("invalid_role", fmt.Sprintf("unknown role: %s", subject))
+		}
+	case RedactionSubjectUser:
+		if subject == "" {
+			return apperrors.Validation("invalid_redaction_assignment", "subject is required")
+		}
+	default:
+		return apperrors.Validation("invalid_redaction_assignment", "subject_type must be role or user")
+	}
+
+	if profileID == 0 {
+		if _, err := db.conn.Exec(`DELETE FROM redaction_assignments WHERE subject_type = ? AND subject = ?`, subjectType, subject); err != nil {
+			return fmt.Errorf("failed to delete redaction assignment: %w", err)
+		}
+		return nil
+	}
+	if _, err := db.GetRedactionProfile(profileID); err != nil {
+		return err
+	}
+	_, err := db.conn.Exec(`
+		INSERT INTO redaction_assignments (subject_type, subject, profile_id, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(subject_type, subject) DO UPDATE SET profile_id = excluded.profile_id, created_at = excluded.created_at
+	`, subjectType, subject, profileID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to assign redaction profile: %w", err)
+	}
+	return nil
+}
+
+// ListRedactionAssignments возвращает назначения профилей
+func (db *ServiceDB) ListRedactionAssignments() ([]*RedactionAssignment, error) {
+	rows, err := db.conn.Query(`
+		SELECT a.subject_type, a.subject, a.profile_id, p.name, a.created_at
+		FROM redaction_assignments a
+		JOIN redaction_profiles p ON p.id = a.profile_id
+		ORDER BY a.subject_type, a.subject
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redaction assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var assignments []*RedactionAssignment
+	for rows.Next() {
+		assignment := &RedactionAssignment{}
+		if err := rows.Scan(&assignment.SubjectType, &assignment.Subject, &assignment.ProfileID,
+			&assignment.ProfileName, &assignment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan redaction assignment: %w", err)
+		}
+		assignments = append(assignments, assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate redaction assignments: %w", err)
+	}
+	return assignments, nil
+}
+
+// ResolveRedactionProfile возвращает профиль пользователя: назначенный ему лично, иначе назначенный его роли.
+// nil - данные передаются без скрытия
+func (db *ServiceDB) ResolveRedactionProfile(username, role string) (*RedactionProfile, error) {
+	var profileID int
+	err := db.conn.QueryRow(`
+		SELECT profile_id FROM redaction_assignments
+		WHERE (subject_type = ? AND subject = ?) OR (subject_type = ? AND subject = ?)
+		ORDER BY CASE subject_type WHEN ? THEN 0 ELSE 1 END
+		LIMIT 1
+	`, RedactionSubjectUser, username, RedactionSubjectRole, role, RedactionSubjectUser).Scan(&profileID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve redaction profile: %w", err)
+	}
+	return db.GetRedactionProfile(profileID)
+}
+
+// MergeRedactionProfiles объединяет правила профилей: поле скрывается или маскируется, если этого
+// требует хотя бы один профиль (hide сильнее mask). nil профили пропускаются
+func MergeRedactionProfiles(profiles ...*RedactionProfile) *RedactionProfile {
+	var merged *RedactionProfile
+	var names []string
+	for _, profile := range profiles {
+		if profile == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &RedactionProfile{ID: profile.ID}
+		} else {
+			merged.ID = 0
+		}
+		names = append(names, profile.Name)
+		merged.Rules = append(merged.Rules, profile.Rules...)
+	}
+	if merged == nil {
+		return nil
+	}
+	// Правила уже проверены при сохранении профилей, здесь только объединяются повторы
+	merged.Rules, _ = ValidateRedactionRules(merged.Rules)
+	merged.Name = strings.Join(names, "+")
+	return merged
+}
+
+// action возвращает действие профиля для поля; пусто - поле передается как есть
+func (p *RedactionProfile) action(field string) string {
+	if p == nil {
+		return ""
+	}
+	for _, rule := range p.Rules {
+		if rule.Field == field {
+			return rule.Action
+		}
+	}
+	return ""
+}
+
+// Redacts проверяет, что профиль скрывает или маскирует поле; nil профиль ничего не скрывает
+func (p *RedactionProfile) Redacts(field string) bool {
+	return p.action(field) != ""
+}
+
+// prefixed возвращает действия правил с префиксом по имени без префикса
+func (p *RedactionProfile) prefixed(prefix string) map[string]string {
+	if p == nil {
+		return nil
+	}
+	actions := make(map[string]string)
+	for _, rule := range p.Rules {
+		if strings.HasPrefix(rule.Field, prefix) {
+			actions[strings.TrimPrefix(rule.Field, prefix)] = rule.Action
+		}
+	}
+	return actions
+}
+
+// redactValue применяет действие к значению поля
+func redactValue(action, value string) string {
+	switch action {
+	case RedactionHide:
+		return ""
+	case RedactionMask:
+		if value == "" {
+			return ""
+		}
+		return RedactionMaskValue
+	}
+	return value
+}
+
+// RedactCatalogItem скрывает поля элемента справочника по профилю; nil профиль ничего не меняет.
+// Реквизиты должны быть уже расшифрованы
+func (p *RedactionProfile) RedactCatalogItem(item *CatalogItem) {
+	if p == nil || item == nil {
+		return
+	}
+	item.Code = redactValue(p.action(RedactionFieldCode), item.Code)
+	item.Name = redactValue(p.action(RedactionFieldName), item.Name)
+	item.Attributes = RedactAttributes(item.Attributes, p.prefixed(RedactionAttributePrefix))
+	if p.action(RedactionFieldTableParts) != "" {
+		item.TableParts = ""
+	}
+}
+
+// RedactCatalogItems скрывает поля элементов справочников по профилю
+func (p *RedactionProfile) RedactCatalogItems(items []*CatalogItem) {
+	for _, item := range items {
+		p.RedactCatalogItem(item)
+	}
+}
+
+// RedactNomenclatureItems скрывает поля номенклатуры по профилю: код и наименование номенклатуры,
+// реквизиты и табличные части
+func (p *RedactionProfile) RedactNomenclatureItems(items []*NomenclatureItem) {
+	if p == nil {
+		return
+	}
+	attributes := p.prefixed(RedactionAttributePrefix)
+	for _, item := range items {
+		item.NomenclatureCode = redactValue(p.action(RedactionFieldCode), item.NomenclatureCode)
+		item.NomenclatureName = redactValue(p.action(RedactionFieldName), item.NomenclatureName)
+		item.AttributesXML = RedactAttributes(item.AttributesXML, attributes)
+		if p.action(RedactionFieldTableParts) != "" {
+			item.TablePartsXML = ""
+		}
+	}
+}
+
+// RedactUploadDocuments скрывает поля документов по профилю: правило code применяется к номеру документа,
+// реквизиты и табличные части - как у элементов справочников. Реквизиты должны быть уже расшифрованы
+func (p *RedactionProfile) RedactUploadDocuments(documents []*UploadDocument) {
+	if p == nil {
+		return
+	}
+	attributes := p.prefixed(RedactionAttributePrefix)
+	for _, doc := range documents {
+		doc.Number = redactValue(p.action(RedactionFieldCode), doc.Number)
+		doc.AttributesXML = RedactAttributes(doc.AttributesXML, attributes)
+		if p.action(RedactionFieldTableParts) != "" {
+			doc.TablePartsXML = ""
+		}
+	}
+}
+
+// RedactCatalogItemVersions скрывает поля версий элемента справочника по профилю: состояние элемента
+// и изменения скрытых полей. Скрытое поле удаляется из изменений, маскированное - маскируется в обоих значениях
+func (p *RedactionProfile) RedactCatalogItemVersions(versions []*CatalogItemVersion) {
+	if p == nil {
+		return
+	}
+	attributes := p.prefixed(RedactionAttributePrefix)
+	for _, version := range versions {
+		version.Code = redactValue(p.action(RedactionFieldCode), version.Code)
+		version.Name = redactValue(p.action(RedactionFieldName), version.Name)
+		for name, action := range attributes {
+			value, ok := version.Attributes[name]
+			if !ok {
+				continue
+			}
+			if action == RedactionHide {
+				delete(version.Attributes, name)
+			} else {
+				version.Attributes[name] = redactValue(action, value)
+			}
+		}
+		for field, change := range version.ChangedFields {
+			switch action := p.action(field); action {
+			case "":
+			case RedactionHide:
+				delete(version.ChangedFields, field)
+			default:
+				version.ChangedFields[field] = CatalogItemFieldChange{Old: redactValue(action, change.Old), New: redactValue(action, change.New)}
+			}
+		}
+	}
+}
+
+// RedactConstant скрывает значение константы по профилю; сама константа остается в выдаче
+func (p *RedactionProfile) RedactConstant(constant *Constant) {
+	action := p.action(RedactionConstantPrefix + constant.Name)
+	if action == "" {
+		return
+	}
+	constant.Value = redactValue(action, constant.Value)
+	constant.TypedValue = nil
+	if action == RedactionHide {
+		constant.ValueKind = ""
+	}
+}
+
+// RedactAttributes скрывает реквизиты в JSON-объекте или последовательности XML-элементов <Имя>значение</Имя>:
+// hide удаляет реквизит, mask заменяет значение. Если реквизиты не разбираются, они не передаются вовсе
+func RedactAttributes(attributes string, actions map[string]string) string {
+	if len(actions) == 0 || strings.TrimSpace(attributes) == "" {
+		return attributes
+	}
+
+	trimmed := strings.TrimSpace(attributes)
+	if strings.HasPrefix(trimmed, "{") {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(trimmed), &raw); err != nil {
+			return ""
+		}
+		for name, action := range actions {
+			if _, ok := raw[name]; !ok {
+				continue
+			}
+			if action == RedactionHide {
+				delete(raw, name)
+			} else {
+				raw[name] = json.RawMessage(`"` + RedactionMaskValue + `"`)
+			}
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+
+	if strings.Contains(attributes, "&lt;") && !strings.Contains(attributes, "<") {
+		attributes = html.UnescapeString(attributes)
+	}
+	redacted, err := redactAttributesXML(attributes, actions)
+	if err != nil {
+		return ""
+	}
+	return redacted
+}
+
+// redactAttributesXML вырезает или маскирует элементы верхнего уровня по позициям в исходной строке,
+// не перекодируя остальной XML
+func redactAttributesXML(attributes string, actions map[string]string) (string, error) {
+	const wrapper = "<root>"
+	decoder := xml.NewDecoder(strings.NewReader(wrapper + attributes + "</root>"))
+
+	type span struct {
+		start, contentStart, contentEnd, end int
+		name, action                         string
+	}
+	var (
+		spans   []span
+		current *span
+		depth   int
+	)
+	for {
+		offset := int(decoder.InputOffset()) - len(wrapper)
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				if action, ok := actions[t.Name.Local]; ok {
+					current = &span{start: offset, contentStart: int(decoder.InputOffset()) - len(wrapper), name: t.Name.Local, action: action}
+				}
+			}
+		case xml.EndElement:
+			if depth == 2 && current != nil {
+				current.contentEnd = offset
+				// У пустого элемента <Имя/> закрывающий тег не занимает места: содержимое пустое
+				current.end = int(decoder.InputOffset()) - len(wrapper)
+				spans = append(spans, *current)
+				current = nil
+			}
+			depth--
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var result strings.Builder
+	position := 0
+	for _, s := range spans {
+		result.WriteString(attributes[position:s.start])
+		if s.action == RedactionMask && s.contentEnd > s.contentStart {
+			result.WriteString(attributes[s.start:s.contentStart])
+			result.WriteString(RedactionMaskValue)
+			result.WriteString(attributes[s.contentEnd:s.end])
+		} else if s.action == RedactionMask {
+			result.WriteString(attributes[s.start:s.end])
+		}
+		position = s.end
+	}
+	result.WriteString(attributes[position:])
+	return result.String(), nil
+}
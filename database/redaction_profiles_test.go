@@ -0,0 +1,155 @@
+package database
+
+import (
+	"testing"
+
+	"httpserver/apperrors"
+)
+
+func TestRedactionProfiles(t *testing.T) {
+	serviceDB, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	contractor := &RedactionProfile{Name: "contractor", Rules: []RedactionRule{
+		{Field: "attributes.ЦенаПоставщика", Action: "hide"},
+		{Field: "code", Action: "mask"},
+		{Field: "code", Action: "HIDE"},
+	}}
+	if err := serviceDB.CreateRedactionProfile(contractor); err != nil {
+		t.Fatalf("CreateRedactionProfile() error = %v", err)
+	}
+	if len(contractor.Rules) != 2 || contractor.Rules[1] != (RedactionRule{Field: "code", Action: RedactionHide}) {
+		t.Errorf("rules = %+v, want duplicates merged with hide", contractor.Rules)
+	}
+	viewers := &RedactionProfile{Name: "viewers", Rules: []RedactionRule{{Field: "constants.Пароль", Action: "mask"}}}
+	if err := serviceDB.CreateRedactionProfile(viewers); err != nil {
+		t.Fatalf("CreateRedactionProfile() error = %v", err)
+	}
+
+	invalid := []struct {
+		name     string
+		profile  *RedactionProfile
+		wantCode string
+	}{
+		{"duplicate name", &RedactionProfile{Name: "CONTRACTOR"}, "redaction_profile_exists"},
+		{"unknown field", &RedactionProfile{Name: "x", Rules: []RedactionRule{{Field: "price", Action: "hide"}}}, "invalid_redaction_rule"},
+		{"unknown action", &RedactionProfile{Name: "x", Rules: []RedactionRule{{Field: "name", Action: "drop"}}}, "invalid_redaction_rule"},
+		{"masked table parts", &RedactionProfile{Name: "x", Rules: []RedactionRule{{Field: "table_parts", Action: "mask"}}}, "invalid_redaction_rule"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := serviceDB.CreateRedactionProfile(tt.profile); apperrors.CodeOf(err) != tt.wantCode {
+				t.Errorf("CreateRedactionProfile() error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+
+	if err := serviceDB.AssignRedactionProfile(RedactionSubjectRole, RoleViewer, viewers.ID); err != nil {
+		t.Fatalf("AssignRedactionProfile(role) error = %v", err)
+	}
+	if err := serviceDB.AssignRedactionProfile(RedactionSubjectUser, "podryadchik", contractor.ID); err != nil {
+		t.Fatalf("AssignRedactionProfile(user) error = %v", err)
+	}
+	if err := serviceDB.AssignRedactionProfile(RedactionSubjectRole, "owner", viewers.ID); apperrors.CodeOf(err) != "invalid_role" {
+		t.Errorf("AssignRedactionProfile(unknown role) error = %v", err)
+	}
+
+	resolveTests := []struct {
+		name     string
+		username string
+		role     string
+		want     string
+	}{
+		{"user assignment wins over role", "Podryadchik", RoleViewer, "contractor"},
+		{"role assignment", "ivanov", RoleViewer, "viewers"},
+		{"no assignment", "ivanov", RoleOperator, ""},
+	}
+	for _, tt := range resolveTests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := serviceDB.ResolveRedactionProfile(tt.username, tt.role)
+			if err != nil {
+				t.Fatalf("ResolveRedactionProfile() error = %v", err)
+			}
+			got := ""
+			if profile != nil {
+				got = profile.Name
+			}
+			if got != tt.want {
+				t.Errorf("profile = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	merged := MergeRedactionProfiles(nil, viewers, contractor)
+	if merged.Name != "viewers+contractor" || len(merged.Rules) != 3 {
+		t.Errorf("merged = %+v", merged)
+	}
+	if MergeRedactionProfiles(nil, nil) != nil {
+		t.Error("MergeRedactionProfiles(nil) != nil")
+	}
+
+	if err := serviceDB.DeleteRedactionProfile(contractor.ID); err != nil {
+		t.Fatalf("DeleteRedactionProfile() error = %v", err)
+	}
+	assignments, err := serviceDB.ListRedactionAssignments()
+	if err != nil || len(assignments) != 1 || assignments[0].ProfileName != "viewers" {
+		t.Errorf("assignments after delete = %+v, %v", assignments, err)
+	}
+}
+
+func TestRedactAttributes(t *testing.T) {
+	actions := map[string]string{"Цена": RedactionHide, "Поставщик": RedactionMask, "Пусто": RedactionMask}
+	tests := []struct {
+		name       string
+		attributes string
+		want       string
+	}{
+		{"xml", `<Артикул>A-1</Артикул><Цена>100</Цена><Поставщик><Ссылка>x</Ссылка></Поставщик><Пусто/>`,
+			`<Артикул>A-1</Артикул><Поставщик>***</Поставщик><Пусто/>`},
+		{"xml with whitespace", "\n  <Цена>1</Цена>\n  <Вес>2</Вес>", "\n  \n  <Вес>2</Вес>"},
+		{"escaped xml", `&lt;Цена&gt;1&lt;/Цена&gt;&lt;Вес&gt;2&lt;/Вес&gt;`, `<Вес>2</Вес>`},
+		{"json", `{"Цена": 100, "Поставщик": "ООО", "Вес": 2}`, `{"Вес":2,"Поставщик":"***"}`},
+		{"no matching attributes", `<Вес>2</Вес>`, `<Вес>2</Вес>`},
+		{"malformed is dropped", `<Цена>1</Вес>`, ``},
+		{"empty", ``, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactAttributes(tt.attributes, actions); got != tt.want {
+				t.Errorf("RedactAttributes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	profile := &RedactionProfile{Rules: []RedactionRule{
+		{Field: "name", Action: RedactionMask},
+		{Field: "table_parts", Action: RedactionHide},
+		{Field: "constants.Пароль", Action: RedactionHide},
+	}}
+	item := &CatalogItem{Code: "001", Name: "Болт", Attributes: "<Вес>1</Вес>", TableParts: "<Строки/>"}
+	profile.RedactCatalogItem(item)
+	if item.Code != "001" || item.Name != RedactionMaskValue || item.Attributes != "<Вес>1</Вес>" || item.TableParts != "" {
+		t.Errorf("RedactCatalogItem() = %+v", item)
+	}
+	constant := &Constant{Name: "Пароль", Value: "secret", ValueKind: "string", TypedValue: "secret"}
+	profile.RedactConstant(constant)
+	if constant.Value != "" || constant.TypedValue != nil || constant.ValueKind != "" {
+		t.Errorf("RedactConstant() = %+v", constant)
+	}
+	documents := []*UploadDocument{{Number: "0001", AttributesXML: "<Цена>100</Цена><Вес>1</Вес>", TablePartsXML: "<Товары/>"}}
+	(&RedactionProfile{Rules: []RedactionRule{
+		{Field: "code", Action: RedactionMask},
+		{Field: "attributes.Цена", Action: RedactionHide},
+		{Field: "table_parts", Action: RedactionHide},
+	}}).RedactUploadDocuments(documents)
+	if doc := documents[0]; doc.Number != RedactionMaskValue || doc.AttributesXML != "<Вес>1</Вес>" || doc.TablePartsXML != "" {
+		t.Errorf("RedactUploadDocuments() = %+v", doc)
+	}
+	var none *RedactionProfile
+	none.RedactCatalogItem(item)
+	none.RedactUploadDocuments(documents)
+	none.RedactConstant(&Constant{Name: "Пароль"})
+}
@@ -0,0 +1,399 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Статусы запусков отчетов по расписанию
+const (
+	ReportRunStatusRunning = "running"
+	ReportRunStatusSuccess = "success"
+	ReportRunStatusFailed  = "failed"
+)
+
+// ReportSchedule расписание формирования и рассылки отчетов по проекту
+type ReportSchedule struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	ProjectID  int        `json:"project_id"`
+	DatabaseID int        `json:"database_id,omitempty"` // 0 - все активные базы проекта
+	Cron       string     `json:"cron"`
+	Templates  []string   `json:"templates"`            // Шаблоны отчетов: quality, normalization, kpved или пользовательские
+	Recipients []string   `json:"recipients,omitempty"` // Адреса электронной почты
+	WebhookURL string     `json:"webhook_url,omitempty"`
+	Enabled    bool       `json:"enabled"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt  *time.Time `json:"next_run_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ReportArtifact сформированный файл отчета
+type ReportArtifact struct {
+	Template   string `json:"template"`
+	DatabaseID int    `json:"database_id,omitempty"`
+	Database   string `json:"database"`
+	Path       string `json:"path"`
+	StorageKey string `json:"storage_key,omitempty"` // Ключ в хранилище артефактов (после архивирования)
+	Size       int64  `json:"size"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ReportRun запуск формирования отчетов по расписанию
+type ReportRun struct {
+	ID             int              `json:"id"`
+	ScheduleID     int              `json:"schedule_id"`
+	ProjectID      int              `json:"project_id"`
+	Status         string           `json:"status"`
+	Artifacts      []ReportArtifact `json:"artifacts"`
+	DeliveryStatus string           `json:"delivery_status,omitempty"` // Итог рассылки по каналам
+	Error          string           `json:"error,omitempty"`
+	StartedAt      time.Time        `json:"started_at"`
+	FinishedAt     *time.Time       `json:"finished_at,omitempty"`
+}
+
+// ReportRunFilter фильтр истории запусков; пустые значения не ограничивают выборку
+type ReportRunFilter struct {
+	ScheduleID int
+	ProjectID  int
+	Limit      int
+}
+
+// CreateReportSchedulesTables создает таблицы расписаний отчетов и истории запусков
+func CreateReportSchedulesTables(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS report_schedules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			project_id INTEGER NOT NULL,
+			database_id INTEGER NOT NULL DEFAULT 0,
+			cron TEXT NOT NULL,
+			templates TEXT NOT NULL,
+			recipients TEXT,
+			webhook_url TEXT,
+			enabled BOOLEAN DEFAULT TRUE,
+			last_run_at TIMESTAMP,
+			next_run_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_report_schedules_next_run ON report_schedules(enabled, next_run_at);
+
+		CREATE TABLE IF NOT EXISTS report_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			schedule_id INTEGER NOT NULL,
+			project_id INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			artifacts TEXT,
+			delivery_status TEXT,
+			error TEXT,
+			started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			finished_at TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_report_runs_schedule ON report_runs(schedule_id, started_at);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create report schedules tables: %w", err)
+	}
+
+	return nil
+}
+
+// encode сериализует списки шаблонов и получателей расписания для хранения
+func (s *ReportSchedule) encode() (templates, recipients string, err error) {
+	templatesData, err := json.Marshal(s.Templates)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal templates: %w", err)
+	}
+	recipientsData, err := json.Marshal(s.Recipients)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal recipients: %w", err)
+	}
+	return string(templatesData), string(recipientsData), nil
+}
+
+// CreateReportSchedule сохраняет новое расписание
+func (db *ServiceDB) CreateReportSchedule(schedule *ReportSchedule) (*ReportSchedule, error) {
+	templates, recipients, err := schedule.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := db.conn.Exec(`
+		INSERT INTO report_schedules (name, project_id, database_id, cron, templates, recipients, webhook_url, enabled, next_run_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, schedule.Name, schedule.ProjectID, schedule.DatabaseID, schedule.Cron, templates, recipients,
+		schedule.WebhookURL, schedule.Enabled, schedule.NextRunAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report schedule: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report schedule id: %w", err)
+	}
+
+	return db.GetReportSchedule(int(id))
+}
+
+// UpdateReportSchedule обновляет расписание
+func (db *ServiceDB) UpdateReportSchedule(schedule *ReportSchedule) error {
+	templates, recipients, err := schedule.encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.conn.Exec(`
+		UPDATE report_schedules
+		SET name = ?, project_id = ?, database_id = ?, cron = ?, templates = ?, recipients = ?, webhook_url = ?,
+		    enabled = ?, next_run_at = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, schedule.Name, schedule.ProjectID, schedule.DatabaseID, schedule.Cron, templates, recipients,
+		schedule.WebhookURL, schedule.Enabled, schedule.NextRunAt, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update report schedule: %w", err)
+	}
+	return nil
+}
+
+// DeleteReportSchedule удаляет расписание; история запусков сохраняется
+func (db *ServiceDB) DeleteReportSchedule(id int) error {
+	_, err := db.conn.Exec("DELETE FROM report_schedules WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete report schedule: %w", err)
+	}
+	return nil
+}
+
+// MarkReportScheduleRun фиксирует время запуска и следующий запуск расписания
+func (db *ServiceDB) MarkReportScheduleRun(id int, lastRunAt time.Time, nextRunAt *time.Time) error {
+	_, err := db.conn.Exec(`
+		UPDATE report_schedules SET last_run_at = ?, next_run_at = ? WHERE id = ?
+	`, lastRunAt, nextRunAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark report schedule run: %w", err)
+	}
+	return nil
+}
+
+// scanReportSchedule читает расписание из строки результата
+func scanReportSchedule(scanner rowScanner) (*ReportSchedule, error) {
+	schedule := &ReportSchedule{}
+	var templates, recipients, webhookURL sql.NullString
+	var lastRunAt, nextRunAt sql.NullTime
+	if err := scanner.Scan(&schedule.ID, &schedule.Name, &schedule.ProjectID, &schedule.DatabaseID, &schedule.Cron,
+		&templates, &recipients, &webhookURL, &schedule.Enabled, &lastRunAt, &nextRunAt,
+		&schedule.CreatedAt, &schedule.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	schedule.WebhookURL = webhookURL.String
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+	if nextRunAt.Valid {
+		schedule.NextRunAt = &nextRunAt.Time
+	}
+	if templates.String != "" {
+		if err := json.Unmarshal([]byte(templates.String), &schedule.Templates); err != nil {
+			return nil, fmt.Errorf("failed to parse templates of report schedule %d: %w", schedule.ID, err)
+		}
+	}
+	if recipients.String != "" {
+		if err := json.Unmarshal([]byte(recipients.String), &schedule.Recipients); err != nil {
+			return nil, fmt.Errorf("failed to parse recipients of report schedule %d: %w", schedule.ID, err)
+		}
+	}
+
+	return schedule, nil
+}
+
+const reportScheduleSelect = `
+	SELECT id, name, project_id, database_id, cron, templates, recipients, webhook_url, enabled,
+	       last_run_at, next_run_at, created_at, updated_at
+	FROM report_schedules
+`
+
+// GetReportSchedule получает расписание по ID (nil, если не найдено)
+func (db *ServiceDB) GetReportSchedule(id int) (*ReportSchedule, error) {
+	schedule, err := scanReportSchedule(db.conn.QueryRow(reportScheduleSelect+" WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get report schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// GetReportSchedules возвращает расписания проекта (projectID = 0 - все расписания)
+func (db *ServiceDB) GetReportSchedules(projectID int) ([]*ReportSchedule, error) {
+	query := reportScheduleSelect
+	args := []interface{}{}
+	if projectID > 0 {
+		query += " WHERE project_id = ?"
+		args = append(args, projectID)
+	}
+	return db.queryReportSchedules(query+" ORDER BY id", args...)
+}
+
+// GetDueReportSchedules возвращает включенные расписания, время запуска которых наступило
+func (db *ServiceDB) GetDueReportSchedules(now time.Time) ([]*ReportSchedule, error) {
+	return db.queryReportSchedules(reportScheduleSelect+`
+		WHERE enabled = TRUE AND next_run_at IS NOT NULL AND next_run_at <= ?
+		ORDER BY next_run_at
+	`, now)
+}
+
+// queryReportSchedules выполняет запрос списка расписаний
+func (db *ServiceDB) queryReportSchedules(query string, args ...interface{}) ([]*ReportSchedule, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []*ReportSchedule{}
+	for rows.Next() {
+		schedule, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// CreateReportRun создает запись о начале запуска
+func (db *ServiceDB) CreateReportRun(scheduleID, projectID int) (*ReportRun, error) {
+	startedAt := time.Now()
+	result, err := db.conn.Exec(`
+		INSERT INTO report_runs (schedule_id, project_id, status, started_at) VALUES (?, ?, ?, ?)
+	`, scheduleID, projectID, ReportRunStatusRunning, startedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report run: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report run id: %w", err)
+	}
+
+	return &ReportRun{
+		ID:         int(id),
+		ScheduleID: scheduleID,
+		ProjectID:  projectID,
+		Status:     ReportRunStatusRunning,
+		Artifacts:  []ReportArtifact{},
+		StartedAt:  startedAt,
+	}, nil
+}
+
+// FinishReportRun сохраняет результат запуска
+func (db *ServiceDB) FinishReportRun(run *ReportRun) error {
+	artifacts, err := json.Marshal(run.Artifacts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal artifacts: %w", err)
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	_, err = db.conn.Exec(`
+		UPDATE report_runs
+		SET status = ?, artifacts = ?, delivery_status = ?, error = ?, finished_at = ?
+		WHERE id = ?
+	`, run.Status, string(artifacts), run.DeliveryStatus, run.Error, finishedAt, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to finish report run: %w", err)
+	}
+	return nil
+}
+
+// scanReportRun читает запуск из строки результата
+func scanReportRun(scanner rowScanner) (*ReportRun, error) {
+	run := &ReportRun{Artifacts: []ReportArtifact{}}
+	var artifacts, deliveryStatus, runError sql.NullString
+	var finishedAt sql.NullTime
+	if err := scanner.Scan(&run.ID, &run.ScheduleID, &run.ProjectID, &run.Status, &artifacts,
+		&deliveryStatus, &runError, &run.StartedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+
+	run.DeliveryStatus = deliveryStatus.String
+	run.Error = runError.String
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	if artifacts.String != "" {
+		if err := json.Unmarshal([]byte(artifacts.String), &run.Artifacts); err != nil {
+			return nil, fmt.Errorf("failed to parse artifacts of report run %d: %w", run.ID, err)
+		}
+	}
+	return run, nil
+}
+
+const reportRunSelect = `
+	SELECT id, schedule_id, project_id, status, artifacts, delivery_status, error, started_at, finished_at
+	FROM report_runs
+`
+
+// GetReportRun получает запуск по ID (nil, если не найден)
+func (db *ServiceDB) GetReportRun(id int) (*ReportRun, error) {
+	run, err := scanReportRun(db.conn.QueryRow(reportRunSelect+" WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get report run: %w", err)
+	}
+	return run, nil
+}
+
+// GetReportRuns возвращает историю запусков, начиная с последних
+func (db *ServiceDB) GetReportRuns(filter ReportRunFilter) ([]*ReportRun, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.ScheduleID > 0 {
+		conditions = append(conditions, "schedule_id = ?")
+		args = append(args, filter.ScheduleID)
+	}
+	if filter.ProjectID > 0 {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, filter.ProjectID)
+	}
+
+	query := reportRunSelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*ReportRun{}
+	for rows.Next() {
+		run, err := scanReportRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReportTemplate пользовательский шаблон отчета, хранимый в сервисной БД
+type ReportTemplate struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	Content      string    `json:"content"`             // Текст шаблона html/template
+	ClientID     int       `json:"client_id,omitempty"` // Клиент, для которого оформлен шаблон
+	LogoURL      string    `json:"logo_url,omitempty"`
+	PrimaryColor string    `json:"primary_color,omitempty"`
+	Footer       string    `json:"footer,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CreateReportTemplatesTable создает таблицу шаблонов отчетов
+func CreateReportTemplatesTable(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS report_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			description TEXT,
+			content TEXT NOT NULL,
+			client_id INTEGER NOT NULL DEFAULT 0,
+			logo_url TEXT,
+			primary_color TEXT,
+			footer TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create report_templates table: %w", err)
+	}
+
+	return nil
+}
+
+// CreateReportTemplate сохраняет новый шаблон отчета
+func (db *ServiceDB) CreateReportTemplate(tpl *ReportTemplate) (*ReportTemplate, error) {
+	_, err := db.conn.Exec(`
+		INSERT INTO report_templates (name, description, content, client_id, logo_url, primary_color, footer)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, tpl.Name, tpl.Description, tpl.Content, tpl.ClientID, tpl.LogoURL, tpl.PrimaryColor, tpl.Footer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report template: %w", err)
+	}
+
+	return db.GetReportTemplate(tpl.Name)
+}
+
+// UpdateReportTemplate обновляет шаблон отчета по имени
+func (db *ServiceDB) UpdateReportTemplate(tpl *ReportTemplate) error {
+	_, err := db.conn.Exec(`
+		UPDATE report_templates
+		SET description = ?, content = ?, client_id = ?, logo_url = ?, primary_color = ?, footer = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+	`, tpl.Description, tpl.Content, tpl.ClientID, tpl.LogoURL, tpl.PrimaryColor, tpl.Footer, tpl.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update report template: %w", err)
+	}
+	return nil
+}
+
+// DeleteReportTemplate удаляет шаблон отчета по имени
+func (db *ServiceDB) DeleteReportTemplate(name string) error {
+	_, err := db.conn.Exec("DELETE FROM report_templates WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete report template: %w", err)
+	}
+	return nil
+}
+
+// scanReportTemplate читает шаблон отчета из строки результата
+func scanReportTemplate(scanner rowScanner) (*ReportTemplate, error) {
+	tpl := &ReportTemplate{}
+	var description, logoURL, primaryColor, footer sql.NullString
+	if err := scanner.Scan(&tpl.ID, &tpl.Name, &description, &tpl.Content, &tpl.ClientID,
+		&logoURL, &primaryColor, &footer, &tpl.CreatedAt, &tpl.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	tpl.Description = description.String
+	tpl.LogoURL = logoURL.String
+	tpl.PrimaryColor = primaryColor.String
+	tpl.Footer = footer.String
+	return tpl, nil
+}
+
+const reportTemplateSelect = `
+	SELECT id, name, description, content, client_id, logo_url, primary_color, footer, created_at, updated_at
+	FROM report_templates
+`
+
+// GetReportTemplate получает шаблон отчета по имени (nil, если не найден)
+func (db *ServiceDB) GetReportTemplate(name string) (*ReportTemplate, error) {
+	tpl, err := scanReportTemplate(db.conn.QueryRow(reportTemplateSelect+" WHERE name = ?", name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get report template: %w", err)
+	}
+	return tpl, nil
+}
+
+// GetReportTemplates возвращает все шаблоны отчетов
+func (db *ServiceDB) GetReportTemplates() ([]*ReportTemplate, error) {
+	rows, err := db.conn.Query(reportTemplateSelect + " ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []*ReportTemplate{}
+	for rows.Next() {
+		tpl, err := scanReportTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report template: %w", err)
+		}
+		templates = append(templates, tpl)
+	}
+
+	return templates, rows.Err()
+}
@@ -0,0 +1,316 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecordTargetClassification проверка классификации нормализованной записи, ключ - normalized_data.id
+const RecordTargetClassification = "classification"
+
+// Состояния задачи проверки
+const (
+	ReviewStateNew        = "new"
+	ReviewStateInProgress = "in_progress"
+	ReviewStateDone       = "done"
+	ReviewStateEscalated  = "escalated"
+)
+
+// reviewTransitions допустимые переходы состояний: эскалировать можно из любого незавершенного состояния,
+// завершенную задачу можно вернуть в работу
+var reviewTransitions = map[string][]string{
+	ReviewStateNew:        {ReviewStateInProgress, ReviewStateDone, ReviewStateEscalated},
+	ReviewStateInProgress: {ReviewStateNew, ReviewStateDone, ReviewStateEscalated},
+	ReviewStateEscalated:  {ReviewStateInProgress, ReviewStateDone},
+	ReviewStateDone:       {ReviewStateInProgress},
+}
+
+// IsValidReviewState проверяет состояние задачи проверки
+func IsValidReviewState(state string) bool {
+	_, ok := reviewTransitions[state]
+	return ok
+}
+
+// CanTransitionReviewState проверяет допустимость перехода задачи из состояния from в to
+func CanTransitionReviewState(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range reviewTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ReviewTask задача проверки записи, группы дубликатов или классификации, назначенная пользователю
+type ReviewTask struct {
+	ID          int        `json:"id"`
+	ProjectID   int        `json:"project_id"`
+	TargetType  string     `json:"target_type"`
+	TargetKey   string     `json:"target_key"`
+	Title       string     `json:"title,omitempty"`
+	Assignee    string     `json:"assignee,omitempty"` // Пустой - задача не назначена
+	State       string     `json:"state"`
+	Note        string     `json:"note,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// ReviewTaskFilter фильтр задач проверки; пустые значения не ограничивают выборку
+type ReviewTaskFilter struct {
+	ProjectID  int
+	Assignee   string
+	States     []string
+	TargetType string
+}
+
+// ReviewUserStats статистика выполнения задач проверки пользователем
+type ReviewUserStats struct {
+	Assignee       string  `json:"assignee"`
+	Total          int     `json:"total"`
+	New            int     `json:"new"`
+	InProgress     int     `json:"in_progress"`
+	Done           int     `json:"done"`
+	Escalated      int     `json:"escalated"`
+	CompletionRate float64 `json:"completion_rate"`   // Доля завершенных задач
+	AvgHoursToDone float64 `json:"avg_hours_to_done"` // Среднее время от создания до завершения
+}
+
+// CreateReviewTasksTable создает таблицу задач проверки
+func CreateReviewTasksTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS review_tasks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			project_id INTEGER NOT NULL,
+			target_type TEXT NOT NULL,
+			target_key TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			assignee TEXT NOT NULL DEFAULT '',
+			state TEXT NOT NULL DEFAULT 'new',
+			note TEXT NOT NULL DEFAULT '',
+			created_by TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			completed_at TIMESTAMP,
+			UNIQUE(project_id, target_type, target_key),
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_review_tasks_assignee ON review_tasks(assignee, state);
+		CREATE INDEX IF NOT EXISTS idx_review_tasks_project ON review_tasks(project_id, state);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create review_tasks table: %w", err)
+	}
+	return nil
+}
+
+// validateReviewTarget проверяет тип и ключ записи задачи: к записям и группам добавляется проверка классификации
+func validateReviewTarget(targetType, targetKey string) error {
+	if targetType == RecordTargetClassification {
+		return validateRecordTarget(RecordTargetItem, targetKey)
+	}
+	return validateRecordTarget(targetType, targetKey)
+}
+
+const reviewTaskColumns = `id, project_id, target_type, target_key, title, assignee, state, note, created_by,
+	created_at, updated_at, completed_at`
+
+// scanReviewTask читает задачу из строки выборки
+func scanReviewTask(scanner interface{ Scan(...interface{}) error }) (*ReviewTask, error) {
+	task := &ReviewTask{}
+	var completedAt sql.NullTime
+	if err := scanner.Scan(&task.ID, &task.ProjectID, &task.TargetType, &task.TargetKey, &task.Title, &task.Assignee,
+		&task.State, &task.Note, &task.CreatedBy, &task.CreatedAt, &task.UpdatedAt, &completedAt); err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		task.CompletedAt = &completedAt.Time
+	}
+	return task, nil
+}
+
+// CreateReviewTask создает задачу проверки; на запись проекта может быть только одна задача
+func (db *ServiceDB) CreateReviewTask(task *ReviewTask) error {
+	task.TargetKey = strings.TrimSpace(task.TargetKey)
+	task.Title = strings.TrimSpace(task.Title)
+	task.Assignee = strings.TrimSpace(task.Assignee)
+	if err := validateReviewTarget(task.TargetType, task.TargetKey); err != nil {
+		return err
+	}
+	if task.State == "" {
+		task.State = ReviewStateNew
+	}
+	if !IsValidReviewState(task.State) {
+		return fmt.Errorf("invalid review state %q", task.State)
+	}
+
+	now := time.Now()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	task.CompletedAt = nil
+	if task.State == ReviewStateDone {
+		task.CompletedAt = &now
+	}
+	result, err := db.conn.Exec(`
+		INSERT INTO review_tasks (project_id, target_type, target_key, title, assignee, state, note, created_by,
+			created_at, updated_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, task.ProjectID, task.TargetType, task.TargetKey, task.Title, task.Assignee, task.State, task.Note, task.CreatedBy,
+		now, now, task.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create review task: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get review task id: %w", err)
+	}
+	task.ID = int(id)
+	return nil
+}
+
+// GetReviewTask возвращает задачу проверки проекта
+func (db *ServiceDB) GetReviewTask(projectID, id int) (*ReviewTask, error) {
+	task, err := scanReviewTask(db.conn.QueryRow(`
+		SELECT `+reviewTaskColumns+` FROM review_tasks WHERE id = ? AND project_id = ?
+	`, id, projectID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("review task not found")
+		}
+		return nil, fmt.Errorf("failed to get review task: %w", err)
+	}
+	return task, nil
+}
+
+// GetReviewTasks возвращает задачи по фильтру: сначала эскалированные, затем старые
+func (db *ServiceDB) GetReviewTasks(filter ReviewTaskFilter) ([]*ReviewTask, error) {
+	conditions := []string{"1=1"}
+	var args []interface{}
+	if filter.ProjectID > 0 {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, filter.ProjectID)
+	}
+	if filter.Assignee != "" {
+		conditions = append(conditions, "assignee = ?")
+		args = append(args, filter.Assignee)
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if len(filter.States) > 0 {
+		conditions = append(conditions, fmt.Sprintf("state IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(filter.States)), ",")))
+		for _, state := range filter.States {
+			args = append(args, state)
+		}
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT `+reviewTaskColumns+`
+		FROM review_tasks
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY CASE state WHEN 'escalated' THEN 0 WHEN 'in_progress' THEN 1 WHEN 'new' THEN 2 ELSE 3 END, created_at, id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []*ReviewTask{}
+	for rows.Next() {
+		task, err := scanReviewTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+// UpdateReviewTask сохраняет назначение, состояние и примечание задачи.
+// Переход состояния проверяется относительно сохраненной задачи; при завершении фиксируется время
+func (db *ServiceDB) UpdateReviewTask(task *ReviewTask) error {
+	current, err := db.GetReviewTask(task.ProjectID, task.ID)
+	if err != nil {
+		return err
+	}
+	task.Assignee = strings.TrimSpace(task.Assignee)
+	if !IsValidReviewState(task.State) {
+		return fmt.Errorf("invalid review state %q", task.State)
+	}
+	if !CanTransitionReviewState(current.State, task.State) {
+		return fmt.Errorf("cannot change review state from %s to %s", current.State, task.State)
+	}
+
+	now := time.Now()
+	task.CompletedAt = current.CompletedAt
+	switch {
+	case task.State == ReviewStateDone && current.State != ReviewStateDone:
+		task.CompletedAt = &now
+	case task.State != ReviewStateDone:
+		task.CompletedAt = nil
+	}
+	_, err = db.conn.Exec(`
+		UPDATE review_tasks SET assignee = ?, state = ?, note = ?, updated_at = ?, completed_at = ?
+		WHERE id = ? AND project_id = ?
+	`, task.Assignee, task.State, task.Note, now, task.CompletedAt, task.ID, task.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to update review task: %w", err)
+	}
+	task.UpdatedAt = now
+	return nil
+}
+
+// DeleteReviewTask удаляет задачу проверки проекта
+func (db *ServiceDB) DeleteReviewTask(projectID, id int) error {
+	result, err := db.conn.Exec(`DELETE FROM review_tasks WHERE id = ? AND project_id = ?`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete review task: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("review task not found")
+	}
+	return nil
+}
+
+// GetReviewUserStats возвращает статистику выполнения задач проекта по исполнителям;
+// неназначенные задачи учитываются под пустым именем
+func (db *ServiceDB) GetReviewUserStats(projectID int) ([]*ReviewUserStats, error) {
+	rows, err := db.conn.Query(`
+		SELECT assignee, COUNT(*),
+		       SUM(CASE WHEN state = 'new' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN state = 'in_progress' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN state = 'done' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN state = 'escalated' THEN 1 ELSE 0 END),
+		       COALESCE(AVG(CASE WHEN state = 'done' AND completed_at IS NOT NULL
+		                         THEN (julianday(completed_at) - julianday(created_at)) * 24 END), 0)
+		FROM review_tasks
+		WHERE project_id = ?
+		GROUP BY assignee
+		ORDER BY assignee
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review statistics: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []*ReviewUserStats{}
+	for rows.Next() {
+		s := &ReviewUserStats{}
+		if err := rows.Scan(&s.Assignee, &s.Total, &s.New, &s.InProgress, &s.Done, &s.Escalated, &s.AvgHoursToDone); err != nil {
+			return nil, fmt.Errorf("failed to scan review statistics: %w", err)
+		}
+		if s.Total > 0 {
+			s.CompletionRate = float64(s.Done) / float64(s.Total)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,121 @@
+package database
+
+import "testing"
+
+func TestReviewTasks(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	invalid := []struct {
+		name string
+		task ReviewTask
+	}{
+		{"unknown target", ReviewTask{ProjectID: project.ID, TargetType: "upload", TargetKey: "1"}},
+		{"classification key is not an ID", ReviewTask{ProjectID: project.ID, TargetType: RecordTargetClassification, TargetKey: "abc"}},
+		{"unknown state", ReviewTask{ProjectID: project.ID, TargetType: RecordTargetItem, TargetKey: "1", State: "paused"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.CreateReviewTask(&tt.task); err == nil {
+				t.Error("CreateReviewTask() expected error")
+			}
+		})
+	}
+
+	tasks := []*ReviewTask{
+		{ProjectID: project.ID, TargetType: RecordTargetItem, TargetKey: "1", Assignee: "anna"},
+		{ProjectID: project.ID, TargetType: RecordTargetGroup, TargetKey: "ref-1", Assignee: "anna"},
+		{ProjectID: project.ID, TargetType: RecordTargetClassification, TargetKey: "1", Assignee: "oleg"},
+	}
+	for _, task := range tasks {
+		if err := db.CreateReviewTask(task); err != nil {
+			t.Fatalf("CreateReviewTask() error = %v", err)
+		}
+		if task.State != ReviewStateNew {
+			t.Errorf("new task state = %s, want %s", task.State, ReviewStateNew)
+		}
+	}
+	duplicate := &ReviewTask{ProjectID: project.ID, TargetType: RecordTargetItem, TargetKey: "1"}
+	if err := db.CreateReviewTask(duplicate); err == nil {
+		t.Error("CreateReviewTask() for the same record expected error")
+	}
+
+	transitions := []struct {
+		name    string
+		state   string
+		wantErr bool
+	}{
+		{"start", ReviewStateInProgress, false},
+		{"finish", ReviewStateDone, false},
+		{"escalate done task", ReviewStateEscalated, true},
+		{"reopen", ReviewStateInProgress, false},
+		{"finish again", ReviewStateDone, false},
+	}
+	for _, tt := range transitions {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := db.GetReviewTask(project.ID, tasks[0].ID)
+			if err != nil {
+				t.Fatalf("GetReviewTask() error = %v", err)
+			}
+			task.State = tt.state
+			err = db.UpdateReviewTask(task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateReviewTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (task.CompletedAt != nil) != (tt.state == ReviewStateDone) {
+				t.Errorf("completed_at = %v for state %s", task.CompletedAt, tt.state)
+			}
+		})
+	}
+
+	escalated := tasks[2]
+	escalated.State = ReviewStateEscalated
+	if err := db.UpdateReviewTask(escalated); err != nil {
+		t.Fatalf("UpdateReviewTask() error = %v", err)
+	}
+
+	queue, err := db.GetReviewTasks(ReviewTaskFilter{Assignee: "anna", States: []string{ReviewStateNew, ReviewStateInProgress, ReviewStateEscalated}})
+	if err != nil {
+		t.Fatalf("GetReviewTasks() error = %v", err)
+	}
+	if len(queue) != 1 || queue[0].ID != tasks[1].ID {
+		t.Errorf("open tasks of anna = %+v, want only group task", queue)
+	}
+	all, err := db.GetReviewTasks(ReviewTaskFilter{ProjectID: project.ID})
+	if err != nil {
+		t.Fatalf("GetReviewTasks() error = %v", err)
+	}
+	if len(all) != 3 || all[0].State != ReviewStateEscalated {
+		t.Errorf("project tasks = %d, first state = %s; want escalated first", len(all), all[0].State)
+	}
+
+	stats, err := db.GetReviewUserStats(project.ID)
+	if err != nil {
+		t.Fatalf("GetReviewUserStats() error = %v", err)
+	}
+	if len(stats) != 2 || stats[0].Assignee != "anna" || stats[0].Done != 1 || stats[0].New != 1 || stats[0].CompletionRate != 0.5 {
+		t.Errorf("stats[anna] = %+v", stats[0])
+	}
+	if stats[1].Assignee != "oleg" || stats[1].Escalated != 1 || stats[1].CompletionRate != 0 {
+		t.Errorf("stats[oleg] = %+v", stats[1])
+	}
+
+	if err := db.DeleteReviewTask(project.ID, tasks[1].ID); err != nil {
+		t.Fatalf("DeleteReviewTask() error = %v", err)
+	}
+	if err := db.DeleteReviewTask(project.ID, tasks[1].ID); err == nil {
+		t.Error("DeleteReviewTask() of deleted task expected error")
+	}
+}
@@ -0,0 +1,211 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SavedViewSort поле сортировки сохраненного представления
+type SavedViewSort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// SavedView сохраненное представление (пресет фильтров) браузера нормализованных данных
+type SavedView struct {
+	ID          int             `json:"id"`
+	Name        string          `json:"name"`
+	Owner       string          `json:"owner"`                // Пользователь; пустой - общее представление
+	ProjectID   int             `json:"project_id,omitempty"` // 0 - не привязано к проекту
+	Description string          `json:"description,omitempty"`
+	Filters     json.RawMessage `json:"filters,omitempty"` // Фильтры в формате сервера
+	Sort        []SavedViewSort `json:"sort,omitempty"`
+	Columns     []string        `json:"columns,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// SavedViewFilter фильтр списка представлений; пустые значения не ограничивают выборку
+type SavedViewFilter struct {
+	Owner     string
+	ProjectID int
+}
+
+// CreateSavedViewsTable создает таблицу сохраненных представлений
+func CreateSavedViewsTable(db *sql.DB) error {
+	schema := `
+		CREATE TABLE IF NOT EXISTS saved_views (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			owner TEXT NOT NULL DEFAULT '',
+			project_id INTEGER NOT NULL DEFAULT 0,
+			description TEXT,
+			filters TEXT,
+			sort TEXT,
+			columns TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(owner, project_id, name)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_saved_views_owner_project ON saved_views(owner, project_id);
+	`
+
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create saved_views table: %w", err)
+	}
+
+	return nil
+}
+
+// encode сериализует фильтры, сортировку и колонки представления для хранения
+func (v *SavedView) encode() (filters, sort, columns string, err error) {
+	if len(v.Filters) > 0 {
+		filters = string(v.Filters)
+	}
+	sortData, err := json.Marshal(v.Sort)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to marshal sort: %w", err)
+	}
+	columnsData, err := json.Marshal(v.Columns)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to marshal columns: %w", err)
+	}
+	return filters, string(sortData), string(columnsData), nil
+}
+
+// CreateSavedView сохраняет новое представление
+func (db *ServiceDB) CreateSavedView(view *SavedView) (*SavedView, error) {
+	filters, sort, columns, err := view.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := db.conn.Exec(`
+		INSERT INTO saved_views (name, owner, project_id, description, filters, sort, columns)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, view.Name, view.Owner, view.ProjectID, view.Description, filters, sort, columns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saved view: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved view id: %w", err)
+	}
+
+	return db.GetSavedView(int(id))
+}
+
+// UpdateSavedView обновляет представление
+func (db *ServiceDB) UpdateSavedView(view *SavedView) error {
+	filters, sort, columns, err := view.encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.conn.Exec(`
+		UPDATE saved_views
+		SET name = ?, owner = ?, project_id = ?, description = ?, filters = ?, sort = ?, columns = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, view.Name, view.Owner, view.ProjectID, view.Description, filters, sort, columns, view.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update saved view: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSavedView удаляет представление
+func (db *ServiceDB) DeleteSavedView(id int) error {
+	_, err := db.conn.Exec("DELETE FROM saved_views WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved view: %w", err)
+	}
+	return nil
+}
+
+// scanSavedView читает представление из строки результата
+func scanSavedView(scanner rowScanner) (*SavedView, error) {
+	view := &SavedView{}
+	var description, filters, sort, columns sql.NullString
+	if err := scanner.Scan(&view.ID, &view.Name, &view.Owner, &view.ProjectID, &description,
+		&filters, &sort, &columns, &view.CreatedAt, &view.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	view.Description = description.String
+	if filters.String != "" {
+		view.Filters = json.RawMessage(filters.String)
+	}
+	if sort.String != "" {
+		if err := json.Unmarshal([]byte(sort.String), &view.Sort); err != nil {
+			return nil, fmt.Errorf("failed to parse sort of saved view %d: %w", view.ID, err)
+		}
+	}
+	if columns.String != "" {
+		if err := json.Unmarshal([]byte(columns.String), &view.Columns); err != nil {
+			return nil, fmt.Errorf("failed to parse columns of saved view %d: %w", view.ID, err)
+		}
+	}
+
+	return view, nil
+}
+
+const savedViewSelect = `
+	SELECT id, name, owner, project_id, description, filters, sort, columns, created_at, updated_at
+	FROM saved_views
+`
+
+// GetSavedView получает представление по ID (nil, если не найдено)
+func (db *ServiceDB) GetSavedView(id int) (*SavedView, error) {
+	view, err := scanSavedView(db.conn.QueryRow(savedViewSelect+" WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get saved view: %w", err)
+	}
+	return view, nil
+}
+
+// GetSavedViews возвращает представления пользователя и проекта вместе с общими
+func (db *ServiceDB) GetSavedViews(filter SavedViewFilter) ([]*SavedView, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if filter.Owner != "" {
+		conditions = append(conditions, "(owner = ? OR owner = '')")
+		args = append(args, filter.Owner)
+	}
+	if filter.ProjectID > 0 {
+		conditions = append(conditions, "(project_id = ? OR project_id = 0)")
+		args = append(args, filter.ProjectID)
+	}
+
+	query := savedViewSelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY name, id"
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []*SavedView{}
+	for rows.Next() {
+		view, err := scanSavedView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved view: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	return views, rows.Err()
+}
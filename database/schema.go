@@ -99,6 +99,11 @@ func InitSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to migrate uploads table: %w", err)
 	}
 
+	// Добавляем типизированные значения констант
+	if err := MigrateConstantsTypedValues(db); err != nil {
+		return fmt.Errorf("failed to migrate constants table: %w", err)
+	}
+
 	// Создаем индексы на мигрированные поля после миграции
 	indexesAfterMigration := []string{
 		`CREATE INDEX IF NOT EXISTS idx_uploads_database_id ON uploads(database_id)`,
@@ -154,6 +159,11 @@ func InitSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to create normalized_item_attributes table: %w", err)
 	}
 
+	// Создаем таблицу событий происхождения нормализованных записей
+	if err := CreateNormalizedItemLineageTable(db); err != nil {
+		return fmt.Errorf("failed to create normalized_item_lineage table: %w", err)
+	}
+
 	// Добавляем КПВЭД поля в normalized_data
 	if err := MigrateNormalizedDataKpvedFields(db); err != nil {
 		return fmt.Errorf("failed to migrate KPVED fields: %w", err)
@@ -164,16 +174,116 @@ func InitSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to migrate quality fields: %w", err)
 	}
 
+	// Добавляем версию записи для оптимистичной блокировки при ручном редактировании
+	if err := MigrateNormalizedDataVersionFields(db); err != nil {
+		return fmt.Errorf("failed to migrate version fields: %w", err)
+	}
+
+	// Создаем таблицы калибровки уверенности и политик порогов
+	if err := CreateCalibrationTables(db); err != nil {
+		return fmt.Errorf("failed to create calibration tables: %w", err)
+	}
+
+	// Добавляем колонку обозначений стандартов (ГОСТ, ТУ, DIN) в normalized_data
+	if err := MigrateNormalizedDataStandardFields(db); err != nil {
+		return fmt.Errorf("failed to migrate standard fields: %w", err)
+	}
+
+	// Добавляем хеш входных данных классификации для пропуска неизмененных записей при повторных запусках
+	if err := MigrateNormalizedDataClassificationHash(db); err != nil {
+		return fmt.Errorf("failed to migrate normalized_data classification hash: %w", err)
+	}
+
+	// Создаем журнал изменений normalized_data для инкрементальной синхронизации (после миграций полей)
+	if err := CreateNormalizedChangesJournal(db); err != nil {
+		return fmt.Errorf("failed to create normalized changes journal: %w", err)
+	}
+
+	// Создаем журнал запусков классификации КПВЭД для продолжения после перезапуска сервера
+	if err := CreateKpvedRunTables(db); err != nil {
+		return fmt.Errorf("failed to create kpved run tables: %w", err)
+	}
+
+	// Создаем таблицу заданий заполнения производных колонок
+	if err := CreateBackfillJobsTable(db); err != nil {
+		return fmt.Errorf("failed to create backfill jobs table: %w", err)
+	}
+
+	// Создаем таблицы A/B экспериментов моделей и промптов классификации
+	if err := CreateClassificationExperimentTables(db); err != nil {
+		return fmt.Errorf("failed to create classification experiment tables: %w", err)
+	}
+
+	// Создаем очередь повторной классификации записей, завершившейся ошибкой
+	if err := CreateClassificationRetriesTable(db); err != nil {
+		return fmt.Errorf("failed to create classification retries table: %w", err)
+	}
+
+	// Создаем таблицу отметок обратных выгрузок для отбора измененных записей
+	if err := CreateExportCursorsTable(db); err != nil {
+		return fmt.Errorf("failed to create export cursors table: %w", err)
+	}
+
 	// Создаем таблицы системы качества (DQAS)
 	if err := CreateQualityAssessmentsTables(db); err != nil {
 		return fmt.Errorf("failed to create quality assessment tables: %w", err)
 	}
 
+	// Добавляем статус нарушений качества для массовых действий (принять, отклонить, исправить)
+	if err := MigrateQualityViolationStatus(db); err != nil {
+		return fmt.Errorf("failed to migrate quality violation status: %w", err)
+	}
+
 	// Создаем таблицы для анализа качества данных
 	if err := CreateDataQualityTables(db); err != nil {
 		return fmt.Errorf("failed to create data quality tables: %w", err)
 	}
 
+	// Создаем таблицу справочников выгрузки для идемпотентного счетчика total_catalogs
+	if err := CreateUploadCatalogsTable(db); err != nil {
+		return fmt.Errorf("failed to create upload_catalogs table: %w", err)
+	}
+
+	// Создаем таблицы сверки полноты выгрузки при завершении
+	if err := CreateUploadCompletenessTables(db); err != nil {
+		return fmt.Errorf("failed to create upload completeness tables: %w", err)
+	}
+
+	// Создаем таблицу иерархии групп справочников
+	if err := CreateCatalogHierarchyTable(db); err != nil {
+		return fmt.Errorf("failed to create catalog_hierarchy table: %w", err)
+	}
+
+	// Создаем таблицу вложений элементов справочников
+	if err := CreateUploadAttachmentsTable(db); err != nil {
+		return fmt.Errorf("failed to create upload_attachments table: %w", err)
+	}
+
+	// Создаем таблицы видов документов и документов выгрузок
+	if err := CreateUploadDocumentsTables(db); err != nil {
+		return fmt.Errorf("failed to create upload_documents tables: %w", err)
+	}
+
+	// Создаем таблицу учета прогонов классификации по подтвержденным решениям
+	if err := CreateHistoricalClassificationRunsTable(db); err != nil {
+		return fmt.Errorf("failed to create historical_classification_runs table: %w", err)
+	}
+
+	// Создаем таблицы сверок nomenclature_items с catalog_items
+	if err := CreateNomenclatureReconciliationTables(db); err != nil {
+		return fmt.Errorf("failed to create nomenclature reconciliation tables: %w", err)
+	}
+
+	// Создаем таблицу связей нечетких дублей для графа кластеров
+	if err := CreateDuplicateEdgesTable(db); err != nil {
+		return fmt.Errorf("failed to create duplicate_edges table: %w", err)
+	}
+
+	// Создаем таблицу решений проверки предложений исправления наименований
+	if err := CreatePatternReviewDecisionsTable(db); err != nil {
+		return fmt.Errorf("failed to create pattern_review_decisions table: %w", err)
+	}
+
 	// Создаем таблицы для срезов данных
 	// CreateSnapshotTables должна быть определена в другом месте или закомментирована
 	// if err := CreateSnapshotTables(db); err != nil {
@@ -213,6 +323,8 @@ func MigrateUploadsTable(db *sql.DB) error {
 		`ALTER TABLE uploads ADD COLUMN programmer_name VARCHAR(255)`,
 		`ALTER TABLE uploads ADD COLUMN upload_purpose TEXT`,
 		`ALTER TABLE uploads ADD COLUMN parent_upload_id INTEGER`,
+		// Версия протокола выгрузки, согласованная при рукопожатии
+		`ALTER TABLE uploads ADD COLUMN protocol_version INTEGER`,
 	}
 
 	for _, migration := range migrations {
@@ -567,6 +679,25 @@ func InitServiceSchema(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_kpved_code ON kpved_classifier(code);
 	CREATE INDEX IF NOT EXISTS idx_kpved_parent ON kpved_classifier(parent_code);
 	CREATE INDEX IF NOT EXISTS idx_kpved_level ON kpved_classifier(level);
+
+	-- Таблица дневных сводок по выгрузкам (клиент/проект/база данных за день)
+	CREATE TABLE IF NOT EXISTS upload_daily_rollups (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		day TEXT NOT NULL, -- YYYY-MM-DD
+		client_id INTEGER NOT NULL DEFAULT 0,
+		project_id INTEGER NOT NULL DEFAULT 0,
+		database_id INTEGER NOT NULL DEFAULT 0,
+		uploads_count INTEGER DEFAULT 0,
+		items_ingested INTEGER DEFAULT 0,
+		normalized_items INTEGER DEFAULT 0,
+		classified_items INTEGER DEFAULT 0,
+		error_count INTEGER DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(day, client_id, project_id, database_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_upload_rollups_day ON upload_daily_rollups(day);
+	CREATE INDEX IF NOT EXISTS idx_upload_rollups_client_project ON upload_daily_rollups(client_id, project_id);
 	`
 
 	_, err := db.Exec(schema)
@@ -574,6 +705,150 @@ func InitServiceSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to create service schema: %w", err)
 	}
 
+	// Создаем таблицу сохраненных представлений браузера нормализованных данных
+	if err := CreateSavedViewsTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу пользовательских шаблонов отчетов
+	if err := CreateReportTemplatesTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы расписаний отчетов и истории их запусков
+	if err := CreateReportSchedulesTables(db); err != nil {
+		return err
+	}
+
+	// Добавляем язык клиента для локализации ответов и отчетов
+	if err := MigrateClientLanguage(db); err != nil {
+		return err
+	}
+
+	// Добавляем второй язык отчетов клиента и переводы наименований категорий классификаторов
+	if err := MigrateClientReportLanguage(db); err != nil {
+		return err
+	}
+	if err := CreateCategoryNameTranslationsTable(db); err != nil {
+		return err
+	}
+
+	// Добавляем проектам атрибуты, передаваемые AI вместе с наименованием
+	if err := MigrateProjectPromptAttributes(db); err != nil {
+		return err
+	}
+
+	// Создаем настройки метрик сходства проектов и подтвержденные пары дубликатов
+	if err := CreateSimilarityConfigTables(db); err != nil {
+		return err
+	}
+
+	// Создаем журнал сообщений очереди приема выгрузок
+	if err := CreateIngestQueueTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу ограничений скорости приема выгрузок по клиентам
+	if err := CreateClientIngestLimitsTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу ключей шифрования данных проектов
+	if err := CreateProjectEncryptionTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу истории карт качества баз данных
+	if err := CreateQualityScorecardsTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу порогов качества баз данных перед выгрузкой в 1С
+	if err := CreateQualityGatesTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу словарей нормализации проектов
+	if err := CreateNormalizationDictionariesTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы комментариев и тегов записей проектов
+	if err := CreateRecordAnnotationTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу задач проверки записей проектов
+	if err := CreateReviewTasksTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы файлов соответствий кодов клиентов
+	if err := CreateClientCodeMappingTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу журнала аудита
+	if err := CreateAuditLogTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы пользователей веб-интерфейса и их сессий
+	if err := CreateUsersTables(db); err != nil {
+		return err
+	}
+	if err := MigrateUserRoles(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы профилей скрытия колонок и их назначений ролям и пользователям
+	if err := CreateRedactionProfilesTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу истории событий нормализации
+	if err := CreateNormalizationEventsTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу индекса расположения выгрузок
+	if err := CreateUploadIndexTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу фоновых задач
+	if err := CreateBackgroundJobsTable(db); err != nil {
+		return err
+	}
+	if err := MigrateBackgroundJobLeases(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу задач выгрузки
+	if err := CreateExportJobsTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы экземпляров сервера и ретрансляции событий между ними
+	if err := CreateClusterTables(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу отчетов о сбоях воркеров
+	if err := CreateCrashReportsTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицу журнала медленных запросов
+	if err := CreateSlowRequestsTable(db); err != nil {
+		return err
+	}
+
+	// Создаем таблицы статистики приема по справочникам и хронологии выгрузок
+	if err := CreateIngestStatsTables(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
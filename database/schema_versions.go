@@ -190,6 +190,11 @@ func CreateClassificationTables(db *sql.DB) error {
 		}
 	}
 
+	// Узлы деревьев классификаторов хранятся строками
+	if err := CreateClassifierNodesTable(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -16,6 +16,10 @@ type DBConfig struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// Пул только для чтения для тяжелых аналитических запросов (0 - отключен)
+	ReadPoolMaxOpenConns int
+	// DSN пула чтения (реплика); по умолчанию та же SQLite в режиме read-only
+	ReadDSN string
 }
 
 // ServiceDB обертка для работы с сервисной базой данных
@@ -217,6 +221,19 @@ func (db *ServiceDB) GetClient(id int) (*Client, error) {
 	return client, nil
 }
 
+// GetClientByName возвращает клиента по наименованию; nil, если клиента нет
+func (db *ServiceDB) GetClientByName(name string) (*Client, error) {
+	var id int
+	err := db.conn.QueryRow(`SELECT id FROM clients WHERE name = ? ORDER BY id LIMIT 1`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find client: %w", err)
+	}
+	return db.GetClient(id)
+}
+
 // UpdateClient обновляет информацию о клиенте
 func (db *ServiceDB) UpdateClient(id int, name, legalName, description, contactEmail, contactPhone, taxID, status string) error {
 	query := `
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SimilarityMetricWeight метрика сходства наименований и ее вес в итоговой оценке
+type SimilarityMetricWeight struct {
+	Metric string  `json:"metric"`
+	Weight float64 `json:"weight"`
+}
+
+// SimilarityConfig настройка нечеткого сопоставителя проекта: итоговое сходство - взвешенное
+// среднее выбранных метрик, дубликатами считаются пары не ниже порога
+type SimilarityConfig struct {
+	ProjectID int                      `json:"project_id"`
+	Metrics   []SimilarityMetricWeight `json:"metrics"`
+	Threshold float64                  `json:"threshold"`
+	UpdatedAt *time.Time               `json:"updated_at,omitempty"`
+}
+
+// DuplicatePairLabel подтвержденная пара наименований: дубликат или разные позиции.
+// Используется для калибровки метрик сходства
+type DuplicatePairLabel struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"project_id"`
+	NameA       string    `json:"name_a"`
+	NameB       string    `json:"name_b"`
+	IsDuplicate bool      `json:"is_duplicate"`
+	LabeledBy   string    `json:"labeled_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateSimilarityConfigTables создает таблицы настроек сходства проектов и подтвержденных пар
+func CreateSimilarityConfigTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS project_similarity_configs (
+			project_id INTEGER PRIMARY KEY,
+			metrics TEXT NOT NULL,
+			threshold REAL NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE IF NOT EXISTS duplicate_pair_labels (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			project_id INTEGER NOT NULL,
+			name_a TEXT NOT NULL,
+			name_b TEXT NOT NULL,
+			is_duplicate INTEGER NOT NULL,
+			labeled_by TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(project_id, name_a, name_b),
+			FOREIGN KEY(project_id) REFERENCES client_projects(id) ON DELETE CASCADE
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create similarity config tables: %w", err)
+	}
+	return nil
+}
+
+// GetProjectSimilarityConfig возвращает настройку сходства проекта; nil - проект использует настройку по умолчанию
+func (db *ServiceDB) GetProjectSimilarityConfig(projectID int) (*SimilarityConfig, error) {
+	config := &SimilarityConfig{ProjectID: projectID}
+	var metrics string
+	var updatedAt time.Time
+	err := db.conn.QueryRow(`
+		SELECT metrics, threshold, updated_at FROM project_similarity_configs WHERE project_id = ?
+	`, projectID).Scan(&metrics, &config.Threshold, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project similarity config: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metrics), &config.Metrics); err != nil {
+		return nil, fmt.Errorf("failed to decode project similarity metrics: %w", err)
+	}
+	config.UpdatedAt = &updatedAt
+	return config, nil
+}
+
+// SaveProjectSimilarityConfig создает или заменяет настройку сходства проекта.
+// Допустимость метрик проверяет вызывающий код, знающий доступные метрики
+func (db *ServiceDB) SaveProjectSimilarityConfig(config SimilarityConfig) error {
+	metrics, err := json.Marshal(config.Metrics)
+	if err != nil {
+		return fmt.Errorf("failed to encode project similarity metrics: %w", err)
+	}
+	_, err = db.conn.Exec(`
+		INSERT INTO project_similarity_configs (project_id, metrics, threshold, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(project_id) DO UPDATE SET
+			metrics = excluded.metrics,
+			threshold = excluded.threshold,
+			updated_at = CURRENT_TIMESTAMP
+	`, config.ProjectID, string(metrics), config.Threshold)
+	if err != nil {
+		return fmt.Errorf("failed to save project similarity config: %w", err)
+	}
+	return nil
+}
+
+// DeleteProjectSimilarityConfig удаляет настройку проекта, возвращая его к настройке по умолчанию
+func (db *ServiceDB) DeleteProjectSimilarityConfig(projectID int) error {
+	if _, err := db.conn.Exec(`DELETE FROM project_similarity_configs WHERE project_id = ?`, projectID); err != nil {
+		return fmt.Errorf("failed to delete project similarity config: %w", err)
+	}
+	return nil
+}
+
+// SaveDuplicatePairLabels сохраняет подтвержденные пары проекта. Пара не зависит от порядка
+// наименований; повторная отметка пары заменяет прежнюю. Возвращает количество сохраненных пар
+func (db *ServiceDB) SaveDuplicatePairLabels(projectID int, labels []DuplicatePairLabel, labeledBy string) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO duplicate_pair_labels (project_id, name_a, name_b, is_duplicate, labeled_by)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(project_id, name_a, name_b) DO UPDATE SET
+			is_duplicate = excluded.is_duplicate,
+			labeled_by = excluded.labeled_by,
+			created_at = CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare pair label insert: %w", err)
+	}
+	defer stmt.Close()
+
+	saved := 0
+	for _, label := range labels {
+		a, b := strings.TrimSpace(label.NameA), strings.TrimSpace(label.NameB)
+		if a == "" || b == "" {
+			return 0, fmt.Errorf("pair names must not be empty")
+		}
+		if a > b {
+			a, b = b, a
+		}
+		if _, err := stmt.Exec(projectID, a, b, label.IsDuplicate, labeledBy); err != nil {
+			return 0, fmt.Errorf("failed to save pair label: %w", err)
+		}
+		saved++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit pair labels: %w", err)
+	}
+	return saved, nil
+}
+
+// GetDuplicatePairLabels возвращает подтвержденные пары проекта, новые первыми; limit <= 0 - все
+func (db *ServiceDB) GetDuplicatePairLabels(projectID, limit int) ([]DuplicatePairLabel, error) {
+	query := `
+		SELECT id, project_id, name_a, name_b, is_duplicate, COALESCE(labeled_by, ''), created_at
+		FROM duplicate_pair_labels
+		WHERE project_id = ?
+		ORDER BY id DESC
+	`
+	args := []interface{}{projectID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pair labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := []DuplicatePairLabel{}
+	for rows.Next() {
+		var label DuplicatePairLabel
+		if err := rows.Scan(&label.ID, &label.ProjectID, &label.NameA, &label.NameB,
+			&label.IsDuplicate, &label.LabeledBy, &label.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pair label: %w", err)
+		}
+		labels = append(labels, label)
+	}
+	return labels, rows.Err()
+}
+
+// DeleteDuplicatePairLabel удаляет подтвержденную пару проекта
+func (db *ServiceDB) DeleteDuplicatePairLabel(projectID, id int) error {
+	result, err := db.conn.Exec(`DELETE FROM duplicate_pair_labels WHERE id = ? AND project_id = ?`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete pair label: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("pair label not found")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProjectSimilarityConfig(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	if config, err := db.GetProjectSimilarityConfig(project.ID); err != nil || config != nil {
+		t.Fatalf("GetProjectSimilarityConfig() = %+v, %v, want nil", config, err)
+	}
+
+	tests := []struct {
+		name   string
+		config SimilarityConfig
+	}{
+		{"create", SimilarityConfig{ProjectID: project.ID, Threshold: 0.8, Metrics: []SimilarityMetricWeight{{Metric: "jaro_winkler", Weight: 2}, {Metric: "token_set_ratio", Weight: 1}}}},
+		{"replace", SimilarityConfig{ProjectID: project.ID, Threshold: 0.9, Metrics: []SimilarityMetricWeight{{Metric: "levenshtein", Weight: 1}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := db.SaveProjectSimilarityConfig(tt.config); err != nil {
+				t.Fatalf("SaveProjectSimilarityConfig() error = %v", err)
+			}
+			got, err := db.GetProjectSimilarityConfig(project.ID)
+			if err != nil || got == nil {
+				t.Fatalf("GetProjectSimilarityConfig() = %+v, %v", got, err)
+			}
+			if got.Threshold != tt.config.Threshold || !reflect.DeepEqual(got.Metrics, tt.config.Metrics) || got.UpdatedAt == nil {
+				t.Errorf("GetProjectSimilarityConfig() = %+v, want %+v", got, tt.config)
+			}
+		})
+	}
+
+	if err := db.DeleteProjectSimilarityConfig(project.ID); err != nil {
+		t.Fatalf("DeleteProjectSimilarityConfig() error = %v", err)
+	}
+	if config, err := db.GetProjectSimilarityConfig(project.ID); err != nil || config != nil {
+		t.Errorf("GetProjectSimilarityConfig() after delete = %+v, %v, want nil", config, err)
+	}
+}
+
+func TestDuplicatePairLabels(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer db.Close()
+
+	client, err := db.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := db.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	saved, err := db.SaveDuplicatePairLabels(project.ID, []DuplicatePairLabel{
+		{NameA: "Болт М10", NameB: "Болт M10", IsDuplicate: true},
+		{NameA: "Болт М10", NameB: "Гайка М10", IsDuplicate: false},
+	}, "аналитик")
+	if err != nil || saved != 2 {
+		t.Fatalf("SaveDuplicatePairLabels() = %d, %v", saved, err)
+	}
+	// Та же пара в обратном порядке заменяет прежнюю отметку
+	if _, err := db.SaveDuplicatePairLabels(project.ID, []DuplicatePairLabel{{NameA: "Гайка М10", NameB: " Болт М10 ", IsDuplicate: true}}, "эксперт"); err != nil {
+		t.Fatalf("SaveDuplicatePairLabels() error = %v", err)
+	}
+	if _, err := db.SaveDuplicatePairLabels(project.ID, []DuplicatePairLabel{{NameA: "Болт", NameB: " "}}, ""); err == nil {
+		t.Errorf("SaveDuplicatePairLabels() with empty name: want error")
+	}
+
+	labels, err := db.GetDuplicatePairLabels(project.ID, 0)
+	if err != nil || len(labels) != 2 {
+		t.Fatalf("GetDuplicatePairLabels() = %+v, %v, want 2 pairs", labels, err)
+	}
+	duplicates := 0
+	for _, label := range labels {
+		if label.IsDuplicate {
+			duplicates++
+		}
+	}
+	if duplicates != 2 {
+		t.Errorf("duplicates = %d, want relabeled pair to be a duplicate", duplicates)
+	}
+
+	if err := db.DeleteDuplicatePairLabel(project.ID, labels[0].ID); err != nil {
+		t.Fatalf("DeleteDuplicatePairLabel() error = %v", err)
+	}
+	if err := db.DeleteDuplicatePairLabel(project.ID, labels[0].ID); err == nil {
+		t.Errorf("DeleteDuplicatePairLabel() of deleted pair: want error")
+	}
+	if labels, _ := db.GetDuplicatePairLabels(project.ID, 0); len(labels) != 1 {
+		t.Errorf("GetDuplicatePairLabels() after delete = %d pairs, want 1", len(labels))
+	}
+}
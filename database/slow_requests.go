@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SlowRequest запрос, выполнявшийся дольше порога медленных запросов
+type SlowRequest struct {
+	ID         int       `json:"id"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	Path       string    `json:"path"`
+	UploadUUID string    `json:"upload_uuid,omitempty"`
+	Status     int       `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SlowRequestFilter условия выборки журнала медленных запросов; пустые поля не ограничивают выборку
+type SlowRequestFilter struct {
+	Route      string
+	UploadUUID string
+	Limit      int
+}
+
+// CreateSlowRequestsTable создает таблицу журнала медленных запросов
+func CreateSlowRequestsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS slow_requests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			method TEXT NOT NULL,
+			route TEXT NOT NULL,
+			path TEXT NOT NULL,
+			upload_uuid TEXT NOT NULL DEFAULT '',
+			status INTEGER NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			trace_id TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_slow_requests_route ON slow_requests(route);
+		CREATE INDEX IF NOT EXISTS idx_slow_requests_upload ON slow_requests(upload_uuid);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create slow_requests table: %w", err)
+	}
+	return nil
+}
+
+// RecordSlowRequest сохраняет медленный запрос и удаляет записи сверх последних keep (keep <= 0 - без ограничения)
+func (db *ServiceDB) RecordSlowRequest(request *SlowRequest, keep int) error {
+	if request.CreatedAt.IsZero() {
+		request.CreatedAt = time.Now()
+	}
+	result, err := db.conn.Exec(`
+		INSERT INTO slow_requests (method, route, path, upload_uuid, status, duration_ms, trace_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, request.Method, request.Route, request.Path, request.UploadUUID, request.Status, request.DurationMs, request.TraceID, request.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record slow request: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get slow request id: %w", err)
+	}
+	request.ID = int(id)
+
+	if keep > 0 {
+		if _, err := db.conn.Exec(`DELETE FROM slow_requests WHERE id <= ?`, id-int64(keep)); err != nil {
+			return fmt.Errorf("failed to trim slow requests: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetSlowRequests возвращает последние медленные запросы по фильтру
+func (db *ServiceDB) GetSlowRequests(filter SlowRequestFilter) ([]*SlowRequest, error) {
+	query := `SELECT id, method, route, path, upload_uuid, status, duration_ms, trace_id, created_at FROM slow_requests WHERE 1=1`
+	var args []interface{}
+	if filter.Route != "" {
+		query += " AND route = ?"
+		args = append(args, filter.Route)
+	}
+	if filter.UploadUUID != "" {
+		query += " AND upload_uuid = ?"
+		args = append(args, filter.UploadUUID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slow requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []*SlowRequest{}
+	for rows.Next() {
+		request := &SlowRequest{}
+		if err := rows.Scan(&request.ID, &request.Method, &request.Route, &request.Path, &request.UploadUUID,
+			&request.Status, &request.DurationMs, &request.TraceID, &request.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan slow request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
@@ -0,0 +1,57 @@
+package database
+
+import "testing"
+
+func TestSlowRequests(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	requests := []*SlowRequest{
+		{Method: "POST", Route: "/catalog/items", Path: "/catalog/items", UploadUUID: "u-1", Status: 200, DurationMs: 2500},
+		{Method: "POST", Route: "/catalog/items", Path: "/catalog/items", UploadUUID: "u-2", Status: 500, DurationMs: 3100},
+		{Method: "GET", Route: "/api/uploads/", Path: "/api/uploads/u-2/data", UploadUUID: "u-2", Status: 200, DurationMs: 4000},
+	}
+	for _, request := range requests {
+		if err := db.RecordSlowRequest(request, 0); err != nil {
+			t.Fatalf("RecordSlowRequest() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter SlowRequestFilter
+		want   int
+	}{
+		{"all", SlowRequestFilter{Limit: 10}, 3},
+		{"route", SlowRequestFilter{Route: "/catalog/items", Limit: 10}, 2},
+		{"upload", SlowRequestFilter{UploadUUID: "u-2", Limit: 10}, 2},
+		{"route and upload", SlowRequestFilter{Route: "/catalog/items", UploadUUID: "u-1", Limit: 10}, 1},
+		{"limit", SlowRequestFilter{Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.GetSlowRequests(tt.filter)
+			if err != nil {
+				t.Fatalf("GetSlowRequests() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("GetSlowRequests(%+v) = %d requests, want %d", tt.filter, len(got), tt.want)
+			}
+		})
+	}
+
+	// Журнал хранит только последние keep записей
+	if err := db.RecordSlowRequest(&SlowRequest{Method: "GET", Route: "/stats", Path: "/stats", Status: 200, DurationMs: 5000}, 2); err != nil {
+		t.Fatalf("RecordSlowRequest() error = %v", err)
+	}
+	got, err := db.GetSlowRequests(SlowRequestFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetSlowRequests() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Route != "/stats" || got[1].DurationMs != 4000 {
+		t.Errorf("requests after trim = %+v, want two latest", got)
+	}
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StandardReferencesSeparator разделитель обозначений стандартов в колонке standard_references
+const StandardReferencesSeparator = "; "
+
+// StandardItem элемент, ссылающийся на стандарт
+type StandardItem struct {
+	ID             int    `json:"id"`
+	Code           string `json:"code"`
+	SourceName     string `json:"source_name"`
+	NormalizedName string `json:"normalized_name"`
+	Category       string `json:"category"`
+}
+
+// StandardGroup элементы, сгруппированные по обозначению стандарта
+type StandardGroup struct {
+	Designation string         `json:"designation"`
+	Type        string         `json:"type"`
+	ItemCount   int            `json:"item_count"`
+	Items       []StandardItem `json:"items"`
+}
+
+// InvalidStandardReference обозначение стандарта, не прошедшее проверку формата
+type InvalidStandardReference struct {
+	NormalizedItemID int    `json:"normalized_item_id"`
+	SourceName       string `json:"source_name"`
+	Designation      string `json:"designation"`
+	OriginalText     string `json:"original_text"`
+}
+
+// MigrateNormalizedDataStandardFields добавляет в normalized_data колонку обозначений стандартов
+func MigrateNormalizedDataStandardFields(db *sql.DB) error {
+	migrations := []string{
+		`ALTER TABLE normalized_data ADD COLUMN standard_references TEXT DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_normalized_standard_references ON normalized_data(standard_references)`,
+	}
+	for _, migration := range migrations {
+		if _, err := db.Exec(migration); err != nil {
+			errStr := strings.ToLower(err.Error())
+			if !strings.Contains(errStr, "duplicate column") &&
+				!strings.Contains(errStr, "already exists") {
+				return fmt.Errorf("migration failed: %s, error: %w", migration, err)
+			}
+		}
+	}
+	return nil
+}
+
+// standardType возвращает тип стандарта из нормализованного обозначения: "ГОСТ Р 52857.1-2007" -> "ГОСТ Р"
+func standardType(designation string) string {
+	if i := strings.LastIndex(designation, " "); i > 0 {
+		return designation[:i]
+	}
+	return designation
+}
+
+// GetStandardGroups группирует нормализованные элементы по обозначениям стандартов.
+// filterType фильтрует по типу (ГОСТ, ТУ, DIN...), itemsPerGroup ограничивает число элементов в группе
+func (db *DB) GetStandardGroups(filterType string, itemsPerGroup int) ([]*StandardGroup, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, COALESCE(code, ''), COALESCE(source_name, ''), COALESCE(normalized_name, ''),
+		       COALESCE(category, ''), standard_references
+		FROM normalized_data
+		WHERE standard_references IS NOT NULL AND standard_references != ''
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get standard references: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[string]*StandardGroup)
+	for rows.Next() {
+		var item StandardItem
+		var references string
+		if err := rows.Scan(&item.ID, &item.Code, &item.SourceName, &item.NormalizedName, &item.Category, &references); err != nil {
+			return nil, fmt.Errorf("failed to scan standard reference: %w", err)
+		}
+		for _, designation := range strings.Split(references, StandardReferencesSeparator) {
+			if designation == "" || (filterType != "" && !strings.EqualFold(standardType(designation), filterType)) {
+				continue
+			}
+			group, ok := groups[designation]
+			if !ok {
+				group = &StandardGroup{Designation: designation, Type: standardType(designation), Items: []StandardItem{}}
+				groups[designation] = group
+			}
+			group.ItemCount++
+			if itemsPerGroup <= 0 || len(group.Items) < itemsPerGroup {
+				group.Items = append(group.Items, item)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]*StandardGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ItemCount != result[j].ItemCount {
+			return result[i].ItemCount > result[j].ItemCount
+		}
+		return result[i].Designation < result[j].Designation
+	})
+	return result, nil
+}
+
+// GetInvalidStandardReferences возвращает обозначения стандартов с ошибкой формата
+func (db *DB) GetInvalidStandardReferences(limit int) ([]InvalidStandardReference, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := db.conn.Query(`
+		SELECT a.normalized_item_id, COALESCE(n.source_name, ''), a.attribute_value, COALESCE(a.original_text, '')
+		FROM normalized_item_attributes a
+		JOIN normalized_data n ON n.id = a.normalized_item_id
+		WHERE a.attribute_type = 'standard_invalid'
+		ORDER BY a.id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invalid standard references: %w", err)
+	}
+	defer rows.Close()
+
+	refs := []InvalidStandardReference{}
+	for rows.Next() {
+		var ref InvalidStandardReference
+		if err := rows.Scan(&ref.NormalizedItemID, &ref.SourceName, &ref.Designation, &ref.OriginalText); err != nil {
+			return nil, fmt.Errorf("failed to scan invalid standard reference: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestStandardGroups(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.InsertNormalizedItemsWithAttributesBatch([]*NormalizedItem{
+		{SourceReference: "r1", SourceName: "Болт ГОСТ 7798-70", Code: "c1", NormalizedName: "болт", Category: "крепеж", StandardReferences: "ГОСТ 7798-70"},
+		{SourceReference: "r2", SourceName: "Болт DIN 933 ГОСТ 7798-70", Code: "c2", NormalizedName: "болт", Category: "крепеж", StandardReferences: "ГОСТ 7798-70; DIN 933"},
+		{SourceReference: "r3", SourceName: "Гайка ГОСТ 5915", Code: "c3", NormalizedName: "гайка", Category: "крепеж"},
+	}, map[string][]*ItemAttribute{
+		"c3": {{AttributeType: "standard_invalid", AttributeName: "ГОСТ", AttributeValue: "ГОСТ 5915", OriginalText: "ГОСТ 5915", Confidence: 0.5}},
+	})
+	if err != nil {
+		t.Fatalf("InsertNormalizedItemsWithAttributesBatch() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		filterType    string
+		itemsPerGroup int
+		want          map[string]int
+		wantItems     int
+	}{
+		{"all standards", "", 0, map[string]int{"ГОСТ 7798-70": 2, "DIN 933": 1}, 2},
+		{"filter by type", "din", 0, map[string]int{"DIN 933": 1}, 1},
+		{"items per group limit", "ГОСТ", 1, map[string]int{"ГОСТ 7798-70": 2}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := db.GetStandardGroups(tt.filterType, tt.itemsPerGroup)
+			if err != nil {
+				t.Fatalf("GetStandardGroups() error = %v", err)
+			}
+			if len(groups) != len(tt.want) {
+				t.Fatalf("GetStandardGroups() = %d groups, want %d", len(groups), len(tt.want))
+			}
+			for _, group := range groups {
+				if group.ItemCount != tt.want[group.Designation] {
+					t.Errorf("group %q item_count = %d, want %d", group.Designation, group.ItemCount, tt.want[group.Designation])
+				}
+			}
+			if len(groups[0].Items) != tt.wantItems {
+				t.Errorf("first group items = %d, want %d", len(groups[0].Items), tt.wantItems)
+			}
+		})
+	}
+
+	invalid, err := db.GetInvalidStandardReferences(10)
+	if err != nil || len(invalid) != 1 || invalid[0].SourceName != "Гайка ГОСТ 5915" {
+		t.Errorf("GetInvalidStandardReferences() = %+v, error = %v", invalid, err)
+	}
+}
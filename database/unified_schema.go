@@ -31,7 +31,8 @@ func InitUnifiedSchema(db *sql.DB) error {
 		iteration_label TEXT,
 		programmer_name TEXT,
 		upload_purpose TEXT,
-		parent_upload_id INTEGER
+		parent_upload_id INTEGER,
+		protocol_version INTEGER
 	);
 
 	-- Таблица констант (общая для всех выгрузок)
@@ -46,6 +47,21 @@ func InitUnifiedSchema(db *sql.DB) error {
 		FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
 	);
 
+	-- Таблица номенклатуры с характеристиками (пакеты /api/v1/upload/nomenclature/batch)
+	CREATE TABLE IF NOT EXISTS nomenclature_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		upload_id INTEGER NOT NULL,
+		nomenclature_reference TEXT NOT NULL,
+		nomenclature_code TEXT,
+		nomenclature_name TEXT,
+		characteristic_reference TEXT,
+		characteristic_name TEXT,
+		attributes_xml TEXT,
+		table_parts_xml TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+	);
+
 	-- Таблица маппинга справочников на имена таблиц
 	CREATE TABLE IF NOT EXISTS catalog_mappings (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -58,6 +74,7 @@ func InitUnifiedSchema(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_uploads_uuid ON uploads(upload_uuid);
 	CREATE INDEX IF NOT EXISTS idx_uploads_config ON uploads(config_name);
 	CREATE INDEX IF NOT EXISTS idx_constants_upload_id ON constants(upload_id);
+	CREATE INDEX IF NOT EXISTS idx_nomenclature_items_upload_id ON nomenclature_items(upload_id);
 	CREATE INDEX IF NOT EXISTS idx_catalog_mappings_name ON catalog_mappings(catalog_name);
 	CREATE INDEX IF NOT EXISTS idx_catalog_mappings_table ON catalog_mappings(table_name);
 	`
@@ -67,6 +84,57 @@ func InitUnifiedSchema(db *sql.DB) error {
 		return fmt.Errorf("failed to initialize unified schema: %w", err)
 	}
 
+	if err := CreateCatalogColumnMappingsTable(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := CreateCatalogMetadataTables(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	// Проблемы качества нужны для записи нарушений метаданных при загрузке элементов
+	if err := CreateDataQualityTables(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := MigrateConstantsTypedValues(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := CreateUploadCatalogsTable(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := CreateUploadCompletenessTables(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := CreateCatalogHierarchyTable(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := CreateUploadAttachmentsTable(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := CreateUploadDocumentsTables(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	if err := CreateDuplicateEdgesTable(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	// История версий элементов справочников по выгрузкам одной базы 1С
+	if err := CreateCatalogItemVersionsTable(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
+	// Поля выгрузок, добавленные после создания единой БД (версия протокола)
+	if err := MigrateUploadsTable(db); err != nil {
+		return fmt.Errorf("failed to initialize unified schema: %w", err)
+	}
+
 	return nil
 }
 
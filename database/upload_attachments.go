@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UploadAttachment вложение элемента справочника выгрузки (изображение, PDF спецификации).
+// Файл хранится в хранилище артефактов по ключу StorageKey, в БД - только сведения о нем
+type UploadAttachment struct {
+	ID          int       `json:"id"`
+	UploadID    int       `json:"upload_id"`
+	CatalogName string    `json:"catalog_name"`
+	Reference   string    `json:"reference"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"storage_key"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateUploadAttachmentsTable создает таблицу вложений элементов справочников выгрузок
+func CreateUploadAttachmentsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			upload_id INTEGER NOT NULL,
+			catalog_name TEXT NOT NULL,
+			reference TEXT NOT NULL,
+			file_name TEXT NOT NULL,
+			content_type TEXT NOT NULL DEFAULT '',
+			size INTEGER NOT NULL DEFAULT 0,
+			sha256 TEXT NOT NULL,
+			storage_key TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(upload_id, catalog_name, reference, file_name),
+			FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_upload_attachments_item ON upload_attachments(upload_id, catalog_name, reference);
+		CREATE INDEX IF NOT EXISTS idx_upload_attachments_storage_key ON upload_attachments(storage_key);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create upload attachments table: %w", err)
+	}
+	return nil
+}
+
+// SaveUploadAttachment сохраняет сведения о вложении. Повторная отправка файла с тем же именем для элемента
+// заменяет прежнее вложение; replacedKey - ключ прежнего файла, если он отличается от нового
+func (db *DB) SaveUploadAttachment(attachment *UploadAttachment) (replacedKey string, err error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousKey string
+	err = tx.QueryRow(`
+		SELECT storage_key FROM upload_attachments
+		WHERE upload_id = ? AND catalog_name = ? AND reference = ? AND file_name = ?
+	`, attachment.UploadID, attachment.CatalogName, attachment.Reference, attachment.FileName).Scan(&previousKey)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to check attachment: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO upload_attachments (upload_id, catalog_name, reference, file_name, content_type, size, sha256, storage_key, description)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(upload_id, catalog_name, reference, file_name) DO UPDATE SET
+			content_type = excluded.content_type, size = excluded.size, sha256 = excluded.sha256,
+			storage_key = excluded.storage_key, description = excluded.description, created_at = CURRENT_TIMESTAMP
+	`, attachment.UploadID, attachment.CatalogName, attachment.Reference, attachment.FileName, attachment.ContentType,
+		attachment.Size, attachment.SHA256, attachment.StorageKey, attachment.Description); err != nil {
+		return "", fmt.Errorf("failed to save attachment: %w", err)
+	}
+
+	if err := tx.QueryRow(`
+		SELECT id, created_at FROM upload_attachments
+		WHERE upload_id = ? AND catalog_name = ? AND reference = ? AND file_name = ?
+	`, attachment.UploadID, attachment.CatalogName, attachment.Reference, attachment.FileName).Scan(&attachment.ID, &attachment.CreatedAt); err != nil {
+		return "", fmt.Errorf("failed to read saved attachment: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if previousKey == attachment.StorageKey {
+		previousKey = ""
+	}
+	return previousKey, nil
+}
+
+// scanUploadAttachments читает строки вложений
+func scanUploadAttachments(rows *sql.Rows) ([]*UploadAttachment, error) {
+	defer rows.Close()
+	attachments := []*UploadAttachment{}
+	for rows.Next() {
+		a := &UploadAttachment{}
+		if err := rows.Scan(&a.ID, &a.UploadID, &a.CatalogName, &a.Reference, &a.FileName, &a.ContentType,
+			&a.Size, &a.SHA256, &a.StorageKey, &a.Description, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+const uploadAttachmentColumns = `id, upload_id, catalog_name, reference, file_name, content_type, size, sha256, storage_key, description, created_at`
+
+// GetUploadAttachments возвращает вложения выгрузки; пустые catalogName и reference не ограничивают выборку
+func (db *DB) GetUploadAttachments(uploadID int, catalogName, reference string) ([]*UploadAttachment, error) {
+	query := `SELECT ` + uploadAttachmentColumns + ` FROM upload_attachments WHERE upload_id = ?`
+	args := []interface{}{uploadID}
+	if catalogName != "" {
+		query += ` AND catalog_name = ?`
+		args = append(args, catalogName)
+	}
+	if reference != "" {
+		query += ` AND reference = ?`
+		args = append(args, reference)
+	}
+	rows, err := db.conn.Query(query+` ORDER BY catalog_name, reference, file_name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	return scanUploadAttachments(rows)
+}
+
+// GetUploadAttachment возвращает вложение выгрузки по ID; sql.ErrNoRows, если его нет
+func (db *DB) GetUploadAttachment(uploadID, id int) (*UploadAttachment, error) {
+	rows, err := db.conn.Query(`SELECT `+uploadAttachmentColumns+` FROM upload_attachments WHERE upload_id = ? AND id = ?`, uploadID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	attachments, err := scanUploadAttachments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return attachments[0], nil
+}
+
+// CountAttachmentsByStorageKey возвращает число вложений, ссылающихся на файл хранилища
+func (db *DB) CountAttachmentsByStorageKey(storageKey string) (int, error) {
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM upload_attachments WHERE storage_key = ?`, storageKey).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count attachments: %w", err)
+	}
+	return count, nil
+}
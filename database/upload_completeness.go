@@ -0,0 +1,288 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Статусы завершенной выгрузки: без расхождений и с расхождениями сверки полноты
+const (
+	UploadStatusCompleted             = "completed"
+	UploadStatusCompletedWithWarnings = "completed_with_warnings"
+)
+
+// Виды объявленных 1С количеств выгрузки
+const (
+	expectedKindConstants = "constants"
+	expectedKindCatalog   = "catalog"
+)
+
+// Виды расхождений сверки полноты выгрузки
+const (
+	DiscrepancyConstantsCount = "constants_count"
+	DiscrepancyItemsCount     = "items_count"
+	DiscrepancyMissingBatches = "missing_batches"
+)
+
+// CompletenessDiscrepancy расхождение между объявленными 1С и принятыми сервером данными выгрузки
+type CompletenessDiscrepancy struct {
+	Kind           string `json:"kind"`
+	Catalog        string `json:"catalog,omitempty"`
+	Expected       int    `json:"expected"`
+	Actual         int    `json:"actual"`
+	MissingBatches []int  `json:"missing_batches,omitempty"`
+	Message        string `json:"message"`
+}
+
+// CreateUploadCompletenessTables создает таблицы объявленных количеств и принятых пакетов выгрузки
+func CreateUploadCompletenessTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_expected_counts (
+			upload_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			expected_count INTEGER NOT NULL,
+			declared_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (upload_id, kind, name),
+			FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE IF NOT EXISTS upload_batches (
+			upload_id INTEGER NOT NULL,
+			catalog_name TEXT NOT NULL,
+			batch_number INTEGER NOT NULL,
+			item_count INTEGER NOT NULL DEFAULT 0,
+			received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (upload_id, catalog_name, batch_number),
+			FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create upload completeness tables: %w", err)
+	}
+	return nil
+}
+
+// SetUploadExpectedCounts сохраняет объявленные 1С количества: констант (nil - не объявлено)
+// и элементов по справочникам. Повторное объявление заменяет прежнее значение
+func (db *DB) SetUploadExpectedCounts(uploadID int, constants *int, catalogItems map[string]int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upsert := `
+		INSERT INTO upload_expected_counts (upload_id, kind, name, expected_count) VALUES (?, ?, ?, ?)
+		ON CONFLICT(upload_id, kind, name) DO UPDATE SET expected_count = excluded.expected_count, declared_at = CURRENT_TIMESTAMP
+	`
+	if constants != nil {
+		if _, err := tx.Exec(upsert, uploadID, expectedKindConstants, "", *constants); err != nil {
+			return fmt.Errorf("failed to save expected constants count: %w", err)
+		}
+	}
+	for catalogName, count := range catalogItems {
+		if _, err := tx.Exec(upsert, uploadID, expectedKindCatalog, catalogName, count); err != nil {
+			return fmt.Errorf("failed to save expected items count of catalog %s: %w", catalogName, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RecordUploadBatch отмечает прием пакета справочника с номером batchNumber. Повторная отправка
+// пакета (например, после обрыва связи) не создает дубликата
+func (db *DB) RecordUploadBatch(uploadID int, catalogName string, batchNumber, itemCount int) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO upload_batches (upload_id, catalog_name, batch_number, item_count) VALUES (?, ?, ?, ?)
+		ON CONFLICT(upload_id, catalog_name, batch_number) DO UPDATE SET item_count = excluded.item_count, received_at = CURRENT_TIMESTAMP
+	`, uploadID, catalogName, batchNumber, itemCount)
+	if err != nil {
+		return fmt.Errorf("failed to record upload batch: %w", err)
+	}
+	return nil
+}
+
+// CheckUploadCompleteness сверяет выгрузку с объявленным 1С: число констант, число сохраненных элементов
+// по справочникам и непрерывность номеров пакетов. Пустой результат - расхождений нет
+func (db *DB) CheckUploadCompleteness(uploadID int) ([]CompletenessDiscrepancy, error) {
+	discrepancies := []CompletenessDiscrepancy{}
+
+	expected, err := db.getUploadExpectedCounts(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if len(expected) > 0 {
+		itemCounts, err := countUploadCatalogItems(db.conn, uploadID)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range expected {
+			switch e.kind {
+			case expectedKindConstants:
+				var actual int
+				if err := db.conn.QueryRow("SELECT COUNT(*) FROM constants WHERE upload_id = ?", uploadID).Scan(&actual); err != nil {
+					return nil, fmt.Errorf("failed to count constants: %w", err)
+				}
+				if actual != e.count {
+					discrepancies = append(discrepancies, CompletenessDiscrepancy{
+						Kind: DiscrepancyConstantsCount, Expected: e.count, Actual: actual,
+						Message: fmt.Sprintf("expected %d constants, received %d", e.count, actual),
+					})
+				}
+			case expectedKindCatalog:
+				if actual := itemCounts[e.name]; actual != e.count {
+					discrepancies = append(discrepancies, CompletenessDiscrepancy{
+						Kind: DiscrepancyItemsCount, Catalog: e.name, Expected: e.count, Actual: actual,
+						Message: fmt.Sprintf("catalog %s: expected %d items, stored %d", e.name, e.count, actual),
+					})
+				}
+			}
+		}
+	}
+
+	gaps, err := db.getUploadBatchGaps(uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return append(discrepancies, gaps...), nil
+}
+
+// expectedCount объявленное количество выгрузки
+type expectedCount struct {
+	kind  string
+	name  string
+	count int
+}
+
+// getUploadExpectedCounts возвращает объявленные количества выгрузки: сначала константы, затем справочники по имени
+func (db *DB) getUploadExpectedCounts(uploadID int) ([]expectedCount, error) {
+	rows, err := db.conn.Query(`
+		SELECT kind, name, expected_count FROM upload_expected_counts
+		WHERE upload_id = ? ORDER BY kind DESC, name
+	`, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expected counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []expectedCount
+	for rows.Next() {
+		var c expectedCount
+		if err := rows.Scan(&c.kind, &c.name, &c.count); err != nil {
+			return nil, fmt.Errorf("failed to scan expected count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// getUploadBatchGaps находит пропущенные номера пакетов по справочникам выгрузки (от 1 до наибольшего принятого)
+func (db *DB) getUploadBatchGaps(uploadID int) ([]CompletenessDiscrepancy, error) {
+	rows, err := db.conn.Query(`
+		SELECT catalog_name, batch_number FROM upload_batches
+		WHERE upload_id = ? ORDER BY catalog_name, batch_number
+	`, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload batches: %w", err)
+	}
+	defer rows.Close()
+
+	received := make(map[string][]int)
+	var catalogs []string
+	for rows.Next() {
+		var catalogName string
+		var number int
+		if err := rows.Scan(&catalogName, &number); err != nil {
+			return nil, fmt.Errorf("failed to scan upload batch: %w", err)
+		}
+		if _, ok := received[catalogName]; !ok {
+			catalogs = append(catalogs, catalogName)
+		}
+		received[catalogName] = append(received[catalogName], number)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var gaps []CompletenessDiscrepancy
+	for _, catalogName := range catalogs {
+		numbers := received[catalogName]
+		last := numbers[len(numbers)-1]
+		var missing []int
+		next := 1
+		for _, number := range numbers {
+			for ; next < number; next++ {
+				missing = append(missing, next)
+			}
+			next = number + 1
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		gaps = append(gaps, CompletenessDiscrepancy{
+			Kind: DiscrepancyMissingBatches, Catalog: catalogName, Expected: last, Actual: len(numbers), MissingBatches: missing,
+			Message: fmt.Sprintf("catalog %s: received %d of %d batches, missing %v", catalogName, len(numbers), last, missing),
+		})
+	}
+	return gaps, nil
+}
+
+// countUploadCatalogItems подсчитывает сохраненные элементы выгрузки по справочникам:
+// в старой таблице catalog_items и в динамических таблицах единой БД
+func countUploadCatalogItems(q queryer, uploadID int) (map[string]int, error) {
+	counts := make(map[string]int)
+
+	if exists, err := tableExists(q, "catalog_items"); err != nil {
+		return nil, err
+	} else if exists {
+		rows, err := q.Query(`
+			SELECT c.name, COUNT(ci.id) FROM catalogs c
+			INNER JOIN catalog_items ci ON ci.catalog_id = c.id
+			WHERE c.upload_id = ? GROUP BY c.name
+		`, uploadID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count catalog items: %w", err)
+		}
+		for rows.Next() {
+			var name string
+			var count int
+			if err := rows.Scan(&name, &count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan catalog items count: %w", err)
+			}
+			counts[name] += count
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to count catalog items: %w", err)
+		}
+	}
+
+	tables, err := catalogTables(q)
+	if err != nil {
+		return nil, err
+	}
+	for catalogName, tableName := range tables {
+		var count int
+		if err := q.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE upload_id = ?", tableName), uploadID).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count items in %s: %w", tableName, err)
+		}
+		counts[catalogName] += count
+	}
+	return counts, nil
+}
+
+// CompleteUploadWithWarnings завершает выгрузку со статусом completed_with_warnings
+func (db *DB) CompleteUploadWithWarnings(uploadID int) error {
+	_, err := db.conn.Exec(`
+		UPDATE uploads SET completed_at = CURRENT_TIMESTAMP, status = ? WHERE id = ?
+	`, UploadStatusCompletedWithWarnings, uploadID)
+	if err != nil {
+		return fmt.Errorf("failed to complete upload: %w", err)
+	}
+	db.InvalidateUpload(uploadID)
+	return nil
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckUploadCompleteness(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name      string
+		constants *int
+		expected  map[string]int
+		batches   []int
+		wantKinds []string
+		wantGaps  []int
+	}{
+		{"nothing declared", nil, nil, nil, []string{}, nil},
+		{"declared counts match", intPtr(1), map[string]int{"Номенклатура": 3}, []int{1, 2}, []string{}, nil},
+		{"constants missing", intPtr(2), nil, nil, []string{DiscrepancyConstantsCount}, nil},
+		{"items missing", nil, map[string]int{"Номенклатура": 5}, nil, []string{DiscrepancyItemsCount}, nil},
+		{"catalog not received", nil, map[string]int{"Склады": 1}, nil, []string{DiscrepancyItemsCount}, nil},
+		{"batch gaps", nil, nil, []int{1, 4, 2, 6}, []string{DiscrepancyMissingBatches}, []int{3, 5}},
+		{"first batch lost", nil, nil, []int{2}, []string{DiscrepancyMissingBatches}, []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+			if err != nil {
+				t.Fatalf("Failed to create unified DB: %v", err)
+			}
+			defer db.Close()
+
+			upload, err := db.CreateUpload("uuid-completeness-"+tt.name, "8.3", "УправлениеТорговлей")
+			if err != nil {
+				t.Fatalf("Failed to create upload: %v", err)
+			}
+			if err := db.AddConstant(upload.ID, "ОсновнаяВалюта", "Основная валюта", "Строка", "KZT"); err != nil {
+				t.Fatalf("AddConstant failed: %v", err)
+			}
+			tableName, err := GetOrCreateCatalogTable(db.conn, "Номенклатура")
+			if err != nil {
+				t.Fatalf("GetOrCreateCatalogTable failed: %v", err)
+			}
+			for _, ref := range []string{"ref-1", "ref-2", "ref-3"} {
+				if err := db.AddCatalogItemToTable(tableName, upload.ID, ref, ref, "Молоток", "", ""); err != nil {
+					t.Fatalf("AddCatalogItemToTable failed: %v", err)
+				}
+			}
+
+			if err := db.SetUploadExpectedCounts(upload.ID, tt.constants, tt.expected); err != nil {
+				t.Fatalf("SetUploadExpectedCounts failed: %v", err)
+			}
+			for _, number := range tt.batches {
+				if err := db.RecordUploadBatch(upload.ID, "Номенклатура", number, 1); err != nil {
+					t.Fatalf("RecordUploadBatch failed: %v", err)
+				}
+			}
+			// Повторная отправка пакета не влияет на сверку
+			if len(tt.batches) > 0 {
+				if err := db.RecordUploadBatch(upload.ID, "Номенклатура", tt.batches[0], 1); err != nil {
+					t.Fatalf("RecordUploadBatch (resend) failed: %v", err)
+				}
+			}
+
+			discrepancies, err := db.CheckUploadCompleteness(upload.ID)
+			if err != nil {
+				t.Fatalf("CheckUploadCompleteness failed: %v", err)
+			}
+			kinds := []string{}
+			var gaps []int
+			for _, d := range discrepancies {
+				kinds = append(kinds, d.Kind)
+				if d.Kind == DiscrepancyMissingBatches {
+					gaps = d.MissingBatches
+				}
+			}
+			if !reflect.DeepEqual(kinds, tt.wantKinds) {
+				t.Errorf("discrepancies = %+v, want kinds %v", discrepancies, tt.wantKinds)
+			}
+			if !reflect.DeepEqual(gaps, tt.wantGaps) {
+				t.Errorf("missing batches = %v, want %v", gaps, tt.wantGaps)
+			}
+		})
+	}
+}
+
+func TestCompleteUploadWithWarnings(t *testing.T) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("uuid-warnings", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	if err := db.CompleteUploadWithWarnings(upload.ID); err != nil {
+		t.Fatalf("CompleteUploadWithWarnings failed: %v", err)
+	}
+	completed, err := db.GetUploadByUUID("uuid-warnings")
+	if err != nil {
+		t.Fatalf("GetUploadByUUID failed: %v", err)
+	}
+	if completed.Status != UploadStatusCompletedWithWarnings || completed.CompletedAt == nil {
+		t.Errorf("upload = status %q, completed_at %v; want %s with completion time", completed.Status, completed.CompletedAt, UploadStatusCompletedWithWarnings)
+	}
+}
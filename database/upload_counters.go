@@ -0,0 +1,302 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// UploadCounters сводные счетчики выгрузки
+type UploadCounters struct {
+	Constants int `json:"constants"`
+	Catalogs  int `json:"catalogs"`
+	Items     int `json:"items"`
+	Documents int `json:"documents"`
+}
+
+// UploadCountersRecount результат пересчета счетчиков выгрузки
+type UploadCountersRecount struct {
+	UploadID   int            `json:"upload_id"`
+	UploadUUID string         `json:"upload_uuid"`
+	Stored     UploadCounters `json:"stored"`
+	Computed   UploadCounters `json:"computed"`
+	Drifted    bool           `json:"drifted"`
+}
+
+// queryer общий интерфейс *sql.DB и *sql.Tx для запросов чтения
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// CreateUploadCatalogsTable создает таблицу справочников, зарегистрированных в выгрузке.
+// Уникальность (upload_id, catalog_name) не дает повторной отправке метаданных увеличить total_catalogs
+func CreateUploadCatalogsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_catalogs (
+			upload_id INTEGER NOT NULL,
+			catalog_name TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (upload_id, catalog_name),
+			FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create upload_catalogs table: %w", err)
+	}
+	return nil
+}
+
+// RegisterUploadCatalog регистрирует справочник в выгрузке и увеличивает total_catalogs
+// в той же транзакции, только если справочник зарегистрирован впервые
+func (db *DB) RegisterUploadCatalog(uploadID int, catalogName string) (bool, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT OR IGNORE INTO upload_catalogs (upload_id, catalog_name) VALUES (?, ?)`, uploadID, catalogName)
+	if err != nil {
+		return false, fmt.Errorf("failed to register catalog: %w", err)
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to register catalog: %w", err)
+	}
+	if added == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec("UPDATE uploads SET total_catalogs = total_catalogs + 1 WHERE id = ?", uploadID); err != nil {
+		return false, fmt.Errorf("failed to update catalogs counter: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	db.adjustCachedUploadCounters(uploadID, 0, 1, 0)
+	return true, nil
+}
+
+// ComputeUploadCounters подсчитывает счетчики выгрузки по фактическим данным
+func (db *DB) ComputeUploadCounters(uploadID int) (*UploadCounters, error) {
+	counters, _, err := computeUploadCounters(db.conn, uploadID)
+	return counters, err
+}
+
+// computeUploadCounters подсчитывает константы, справочники, элементы и документы выгрузки.
+// Элементы: номенклатура, элементы старой таблицы catalog_items и строки динамических таблиц справочников.
+// Справочники: объединение таблицы catalogs, зарегистрированных справочников и динамических таблиц с данными
+func computeUploadCounters(q queryer, uploadID int) (*UploadCounters, []string, error) {
+	counters := &UploadCounters{}
+
+	if err := q.QueryRow("SELECT COUNT(*) FROM constants WHERE upload_id = ?", uploadID).Scan(&counters.Constants); err != nil {
+		return nil, nil, fmt.Errorf("failed to count constants: %w", err)
+	}
+	if err := q.QueryRow("SELECT COUNT(*) FROM nomenclature_items WHERE upload_id = ?", uploadID).Scan(&counters.Items); err != nil {
+		return nil, nil, fmt.Errorf("failed to count nomenclature items: %w", err)
+	}
+
+	catalogNames := make(map[string]bool)
+	nameQueries := []struct {
+		table string
+		query string
+	}{
+		{"catalogs", "SELECT name FROM catalogs WHERE upload_id = ?"},
+		{"upload_catalogs", "SELECT catalog_name FROM upload_catalogs WHERE upload_id = ?"},
+	}
+	for _, nq := range nameQueries {
+		exists, err := tableExists(q, nq.table)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !exists {
+			continue
+		}
+		rows, err := q.Query(nq.query, uploadID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read catalogs from %s: %w", nq.table, err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan catalog name: %w", err)
+			}
+			catalogNames[name] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read catalogs from %s: %w", nq.table, err)
+		}
+	}
+
+	if exists, err := tableExists(q, "catalog_items"); err != nil {
+		return nil, nil, err
+	} else if exists {
+		var legacyItems int
+		err := q.QueryRow(`
+			SELECT COUNT(*) FROM catalog_items ci
+			INNER JOIN catalogs c ON c.id = ci.catalog_id
+			WHERE c.upload_id = ?
+		`, uploadID).Scan(&legacyItems)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to count catalog items: %w", err)
+		}
+		counters.Items += legacyItems
+	}
+
+	if exists, err := tableExists(q, "upload_documents"); err != nil {
+		return nil, nil, err
+	} else if exists {
+		if err := q.QueryRow("SELECT COUNT(*) FROM upload_documents WHERE upload_id = ?", uploadID).Scan(&counters.Documents); err != nil {
+			return nil, nil, fmt.Errorf("failed to count documents: %w", err)
+		}
+	}
+
+	tables, err := catalogTables(q)
+	if err != nil {
+		return nil, nil, err
+	}
+	for catalogName, tableName := range tables {
+		var count int
+		if err := q.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE upload_id = ?", tableName), uploadID).Scan(&count); err != nil {
+			return nil, nil, fmt.Errorf("failed to count items in %s: %w", tableName, err)
+		}
+		counters.Items += count
+		if count > 0 {
+			catalogNames[catalogName] = true
+		}
+	}
+
+	names := make([]string, 0, len(catalogNames))
+	for name := range catalogNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	counters.Catalogs = len(names)
+	return counters, names, nil
+}
+
+// tableExists проверяет наличие таблицы
+func tableExists(q queryer, tableName string) (bool, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", tableName).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", tableName, err)
+	}
+	return count > 0, nil
+}
+
+// catalogTables возвращает маппинг справочников на существующие динамические таблицы
+func catalogTables(q queryer) (map[string]string, error) {
+	tables := make(map[string]string)
+	if exists, err := tableExists(q, "catalog_mappings"); err != nil || !exists {
+		return tables, err
+	}
+	rows, err := q.Query(`
+		SELECT m.catalog_name, m.table_name FROM catalog_mappings m
+		INNER JOIN sqlite_master t ON t.type = 'table' AND t.name = m.table_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog mappings: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var catalogName, tableName string
+		if err := rows.Scan(&catalogName, &tableName); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog mapping: %w", err)
+		}
+		if isValidTableName(tableName) {
+			tables[catalogName] = tableName
+		}
+	}
+	return tables, rows.Err()
+}
+
+// RecountUploadCounters пересчитывает счетчики выгрузки по фактическим данным и исправляет расхождение.
+// Подсчет и обновление выполняются в одной транзакции, чтобы параллельная запись не потерялась
+func (db *DB) RecountUploadCounters(uploadID int) (*UploadCountersRecount, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	recount := &UploadCountersRecount{UploadID: uploadID}
+	err = tx.QueryRow(`
+		SELECT upload_uuid, COALESCE(total_constants, 0), COALESCE(total_catalogs, 0), COALESCE(total_items, 0)
+		FROM uploads WHERE id = ?
+	`, uploadID).Scan(&recount.UploadUUID, &recount.Stored.Constants, &recount.Stored.Catalogs, &recount.Stored.Items)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("upload %d not found", uploadID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload counters: %w", err)
+	}
+
+	computed, catalogNames, err := computeUploadCounters(tx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	recount.Computed = *computed
+	// Счетчик документов в uploads не хранится, поэтому расхождением не считается
+	recount.Stored.Documents = computed.Documents
+	recount.Drifted = recount.Stored != recount.Computed
+
+	// Регистрируем найденные справочники, чтобы повторные метаданные не увеличили счетчик снова
+	for _, name := range catalogNames {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO upload_catalogs (upload_id, catalog_name) VALUES (?, ?)`, uploadID, name); err != nil {
+			return nil, fmt.Errorf("failed to register catalog: %w", err)
+		}
+	}
+
+	if recount.Drifted {
+		_, err = tx.Exec(`
+			UPDATE uploads SET total_constants = ?, total_catalogs = ?, total_items = ? WHERE id = ?
+		`, computed.Constants, computed.Catalogs, computed.Items, uploadID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update upload counters: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if recount.Drifted {
+		db.InvalidateUpload(uploadID)
+	}
+	return recount, nil
+}
+
+// RecountAllUploadCounters пересчитывает счетчики всех выгрузок БД и возвращает выгрузки с расхождением
+func (db *DB) RecountAllUploadCounters() ([]*UploadCountersRecount, error) {
+	rows, err := db.conn.Query("SELECT id FROM uploads ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	var uploadIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan upload id: %w", err)
+		}
+		uploadIDs = append(uploadIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	drifted := []*UploadCountersRecount{}
+	for _, id := range uploadIDs {
+		recount, err := db.RecountUploadCounters(id)
+		if err != nil {
+			return drifted, err
+		}
+		if recount.Drifted {
+			drifted = append(drifted, recount)
+		}
+	}
+	return drifted, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestUploadCounters(t *testing.T) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("uuid-counters", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+	if err := db.AddConstant(upload.ID, "ОсновнаяВалюта", "", "string", "RUB"); err != nil {
+		t.Fatalf("AddConstant() error = %v", err)
+	}
+	tableName, err := GetOrCreateCatalogTable(db.GetDB(), "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetOrCreateCatalogTable() error = %v", err)
+	}
+
+	// Повторная отправка метаданных справочника не увеличивает счетчик
+	for i, want := range []bool{true, false} {
+		added, err := db.RegisterUploadCatalog(upload.ID, "Номенклатура")
+		if err != nil || added != want {
+			t.Errorf("RegisterUploadCatalog() #%d = %v, %v; want %v", i+1, added, err, want)
+		}
+	}
+
+	inserted, itemErrors, err := db.AddCatalogItemsToTable(tableName, upload.ID, []CatalogTableItem{
+		{Reference: "ref-1", Code: "1", Name: "Болт М8"},
+		{Reference: "ref-2", Code: "2", Name: "Гайка М8"},
+		{Reference: "ref-3", Code: "3", Name: "Шайба 8"},
+	})
+	if err != nil || inserted != 3 || len(itemErrors) != 3 || itemErrors[0] != nil {
+		t.Fatalf("AddCatalogItemsToTable() = %d, %v, %v", inserted, itemErrors, err)
+	}
+
+	want := UploadCounters{Constants: 1, Catalogs: 1, Items: 3}
+	stored, err := db.GetUploadByUUID(upload.UploadUUID)
+	if err != nil {
+		t.Fatalf("GetUploadByUUID() error = %v", err)
+	}
+	if got := (UploadCounters{Constants: stored.TotalConstants, Catalogs: stored.TotalCatalogs, Items: stored.TotalItems}); got != want {
+		t.Errorf("stored counters = %+v, want %+v", got, want)
+	}
+
+	// Имитируем расхождение после сбоя записи
+	if _, err := db.Exec("UPDATE uploads SET total_catalogs = 5, total_items = 100 WHERE id = ?", upload.ID); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	db.InvalidateUpload(upload.ID)
+
+	computed, err := db.ComputeUploadCounters(upload.ID)
+	if err != nil || *computed != want {
+		t.Errorf("ComputeUploadCounters() = %+v, %v; want %+v", computed, err, want)
+	}
+
+	recounts := []struct {
+		name        string
+		wantDrifted bool
+		wantStored  UploadCounters
+	}{
+		{"drift fixed", true, UploadCounters{Constants: 1, Catalogs: 5, Items: 100}},
+		{"no drift", false, want},
+	}
+	for _, tt := range recounts {
+		t.Run(tt.name, func(t *testing.T) {
+			recount, err := db.RecountUploadCounters(upload.ID)
+			if err != nil {
+				t.Fatalf("RecountUploadCounters() error = %v", err)
+			}
+			if recount.Drifted != tt.wantDrifted || recount.Stored != tt.wantStored || recount.Computed != want {
+				t.Errorf("RecountUploadCounters() = %+v", recount)
+			}
+		})
+	}
+
+	fixed, err := db.GetUploadByUUID(upload.UploadUUID)
+	if err != nil || fixed.TotalCatalogs != 1 || fixed.TotalItems != 3 {
+		t.Errorf("upload after recount = %+v, %v", fixed, err)
+	}
+	if drifted, err := db.RecountAllUploadCounters(); err != nil || len(drifted) != 0 {
+		t.Errorf("RecountAllUploadCounters() = %v, %v; want no drift", drifted, err)
+	}
+}
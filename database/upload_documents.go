@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UploadDocumentType вид документа, зарегистрированный в выгрузке
+type UploadDocumentType struct {
+	ID        int       `json:"id"`
+	UploadID  int       `json:"upload_id"`
+	Name      string    `json:"name"`
+	Synonym   string    `json:"synonym"`
+	Documents int       `json:"documents"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UploadDocument документ выгрузки: шапка, реквизиты и табличные части в XML, как их передала 1С
+type UploadDocument struct {
+	ID            int       `json:"id"`
+	UploadID      int       `json:"upload_id"`
+	DocumentType  string    `json:"document_type"`
+	Reference     string    `json:"reference"`
+	Number        string    `json:"number"`
+	Date          string    `json:"date"`
+	Posted        bool      `json:"posted"`
+	AttributesXML string    `json:"attributes_xml"`
+	TablePartsXML string    `json:"table_parts_xml"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CreateUploadDocumentsTables создает таблицы видов документов и документов выгрузок
+func CreateUploadDocumentsTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_document_types (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			upload_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			synonym TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(upload_id, name),
+			FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE
+		);
+
+		CREATE TABLE IF NOT EXISTS upload_documents (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			upload_id INTEGER NOT NULL,
+			document_type_id INTEGER NOT NULL,
+			reference TEXT NOT NULL,
+			number TEXT NOT NULL DEFAULT '',
+			date TEXT NOT NULL DEFAULT '',
+			posted BOOLEAN NOT NULL DEFAULT 0,
+			attributes_xml TEXT NOT NULL DEFAULT '',
+			table_parts_xml TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(document_type_id, reference),
+			FOREIGN KEY(upload_id) REFERENCES uploads(id) ON DELETE CASCADE,
+			FOREIGN KEY(document_type_id) REFERENCES upload_document_types(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_upload_documents_upload ON upload_documents(upload_id, document_type_id);
+		CREATE INDEX IF NOT EXISTS idx_upload_documents_date ON upload_documents(upload_id, date);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create upload documents tables: %w", err)
+	}
+	return nil
+}
+
+// AddUploadDocuments регистрирует вид документа в выгрузке и сохраняет пакет его документов в одной транзакции.
+// Повторная отправка документа с той же ссылкой заменяет прежние данные, поэтому счетчик документов не растет
+func (db *DB) AddUploadDocuments(uploadID int, documentType, synonym string, documents []*UploadDocument) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO upload_document_types (upload_id, name, synonym) VALUES (?, ?, ?)
+		ON CONFLICT(upload_id, name) DO UPDATE SET
+			synonym = CASE WHEN excluded.synonym != '' THEN excluded.synonym ELSE upload_document_types.synonym END
+	`, uploadID, documentType, synonym); err != nil {
+		return fmt.Errorf("failed to register document type: %w", err)
+	}
+	var typeID int
+	if err := tx.QueryRow(`SELECT id FROM upload_document_types WHERE upload_id = ? AND name = ?`, uploadID, documentType).Scan(&typeID); err != nil {
+		return fmt.Errorf("failed to get document type: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO upload_documents (upload_id, document_type_id, reference, number, date, posted, attributes_xml, table_parts_xml)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(document_type_id, reference) DO UPDATE SET
+			number = excluded.number, date = excluded.date, posted = excluded.posted,
+			attributes_xml = excluded.attributes_xml, table_parts_xml = excluded.table_parts_xml, created_at = CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare document insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, doc := range documents {
+		if _, err := stmt.Exec(uploadID, typeID, doc.Reference, doc.Number, doc.Date, doc.Posted, doc.AttributesXML, doc.TablePartsXML); err != nil {
+			return fmt.Errorf("failed to save document %s: %w", doc.Reference, err)
+		}
+		doc.UploadID = uploadID
+		doc.DocumentType = documentType
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetUploadDocumentTypes возвращает виды документов выгрузки с числом документов каждого вида
+func (db *DB) GetUploadDocumentTypes(uploadID int) ([]*UploadDocumentType, error) {
+	rows, err := db.conn.Query(`
+		SELECT t.id, t.upload_id, t.name, t.synonym, COUNT(d.id), t.created_at
+		FROM upload_document_types t
+		LEFT JOIN upload_documents d ON d.document_type_id = t.id
+		WHERE t.upload_id = ?
+		GROUP BY t.id
+		ORDER BY t.name
+	`, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document types: %w", err)
+	}
+	defer rows.Close()
+
+	types := []*UploadDocumentType{}
+	for rows.Next() {
+		t := &UploadDocumentType{}
+		if err := rows.Scan(&t.ID, &t.UploadID, &t.Name, &t.Synonym, &t.Documents, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document type: %w", err)
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+const uploadDocumentColumns = `d.id, d.upload_id, t.name, d.reference, d.number, d.date, d.posted, d.attributes_xml, d.table_parts_xml, d.created_at`
+
+// scanUploadDocuments читает строки документов
+func scanUploadDocuments(rows *sql.Rows) ([]*UploadDocument, error) {
+	defer rows.Close()
+	documents := []*UploadDocument{}
+	for rows.Next() {
+		d := &UploadDocument{}
+		if err := rows.Scan(&d.ID, &d.UploadID, &d.DocumentType, &d.Reference, &d.Number, &d.Date, &d.Posted,
+			&d.AttributesXML, &d.TablePartsXML, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
+
+// GetUploadDocuments возвращает страницу документов выгрузки и их общее число; пустой documentType
+// не ограничивает выборку
+func (db *DB) GetUploadDocuments(uploadID int, documentType string, offset, limit int) ([]*UploadDocument, int, error) {
+	where := ` FROM upload_documents d INNER JOIN upload_document_types t ON t.id = d.document_type_id WHERE d.upload_id = ?`
+	args := []interface{}{uploadID}
+	if documentType != "" {
+		where += ` AND t.name = ?`
+		args = append(args, documentType)
+	}
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*)`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	rows, err := db.conn.Query(`SELECT `+uploadDocumentColumns+where+` ORDER BY t.name, d.date, d.id LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get documents: %w", err)
+	}
+	documents, err := scanUploadDocuments(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return documents, total, nil
+}
+
+// StreamUploadDocumentsContext передает документы выгрузки пакетами в порядке сохранения
+func (db *DB) StreamUploadDocumentsContext(ctx context.Context, uploadID int, batchSize int, handler func([]*UploadDocument) error) error {
+	if handler == nil {
+		return fmt.Errorf("handler is required")
+	}
+	if batchSize <= 0 {
+		batchSize = defaultExportBatchSize
+	}
+
+	lastID := 0
+	for {
+		rows, err := db.QueryContext(ctx, `
+			SELECT `+uploadDocumentColumns+`
+			FROM upload_documents d INNER JOIN upload_document_types t ON t.id = d.document_type_id
+			WHERE d.upload_id = ? AND d.id > ?
+			ORDER BY d.id
+			LIMIT ?
+		`, uploadID, lastID, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get documents batch: %w", err)
+		}
+		documents, err := scanUploadDocuments(rows)
+		if err != nil {
+			return err
+		}
+		if len(documents) == 0 {
+			return nil
+		}
+		if err := handler(documents); err != nil {
+			return err
+		}
+		if len(documents) < batchSize {
+			return nil
+		}
+		lastID = documents[len(documents)-1].ID
+	}
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUploadDocuments(t *testing.T) {
+	db, err := NewUnifiedDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	upload, err := db.CreateUpload("uuid-documents", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("Failed to create upload: %v", err)
+	}
+
+	batches := []struct {
+		documentType string
+		synonym      string
+		documents    []*UploadDocument
+	}{
+		{"ПоступлениеТоваровУслуг", "Поступление товаров и услуг", []*UploadDocument{
+			{Reference: "doc-1", Number: "0001", Date: "2024-01-10T10:00:00", Posted: true, TablePartsXML: "<Товары/>"},
+			{Reference: "doc-2", Number: "0002", Date: "2024-01-11T10:00:00"},
+		}},
+		{"РеализацияТоваровУслуг", "", []*UploadDocument{
+			{Reference: "doc-3", Number: "0001", Date: "2024-01-12T10:00:00", Posted: true},
+		}},
+		// Повторная отправка заменяет документ и не теряет представление вида документа
+		{"ПоступлениеТоваровУслуг", "", []*UploadDocument{
+			{Reference: "doc-2", Number: "0002", Date: "2024-01-11T10:00:00", Posted: true},
+		}},
+	}
+	for _, b := range batches {
+		if err := db.AddUploadDocuments(upload.ID, b.documentType, b.synonym, b.documents); err != nil {
+			t.Fatalf("AddUploadDocuments(%s) error = %v", b.documentType, err)
+		}
+	}
+
+	types, err := db.GetUploadDocumentTypes(upload.ID)
+	if err != nil {
+		t.Fatalf("GetUploadDocumentTypes() error = %v", err)
+	}
+	if len(types) != 2 || types[0].Name != "ПоступлениеТоваровУслуг" || types[0].Documents != 2 ||
+		types[0].Synonym != "Поступление товаров и услуг" || types[1].Documents != 1 {
+		t.Errorf("document types = %+v, want 2 receipts with synonym and 1 sale", types)
+	}
+
+	tests := []struct {
+		name         string
+		documentType string
+		offset       int
+		limit        int
+		wantTotal    int
+		wantRefs     []string
+	}{
+		{"all", "", 0, 10, 3, []string{"doc-1", "doc-2", "doc-3"}},
+		{"by type", "РеализацияТоваровУслуг", 0, 10, 1, []string{"doc-3"}},
+		{"page", "", 1, 1, 3, []string{"doc-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, total, err := db.GetUploadDocuments(upload.ID, tt.documentType, tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("GetUploadDocuments() error = %v", err)
+			}
+			if total != tt.wantTotal || len(documents) != len(tt.wantRefs) {
+				t.Fatalf("GetUploadDocuments() = %d documents, total %d; want %d, total %d", len(documents), total, len(tt.wantRefs), tt.wantTotal)
+			}
+			for i, ref := range tt.wantRefs {
+				if documents[i].Reference != ref {
+					t.Errorf("document #%d = %s, want %s", i, documents[i].Reference, ref)
+				}
+			}
+		})
+	}
+
+	documents, _, err := db.GetUploadDocuments(upload.ID, "ПоступлениеТоваровУслуг", 0, 10)
+	if err != nil || len(documents) != 2 || !documents[1].Posted || documents[0].TablePartsXML != "<Товары/>" {
+		t.Errorf("receipts = %+v, %v; want doc-2 posted after resend", documents, err)
+	}
+
+	var streamed int
+	err = db.StreamUploadDocumentsContext(context.Background(), upload.ID, 2, func(batch []*UploadDocument) error {
+		streamed += len(batch)
+		return nil
+	})
+	if err != nil || streamed != 3 {
+		t.Errorf("StreamUploadDocumentsContext() streamed %d, err = %v; want 3", streamed, err)
+	}
+
+	counters, err := db.ComputeUploadCounters(upload.ID)
+	if err != nil || counters.Documents != 3 {
+		t.Errorf("ComputeUploadCounters() = %+v, %v; want 3 documents", counters, err)
+	}
+	recount, err := db.RecountUploadCounters(upload.ID)
+	if err != nil || recount.Drifted {
+		t.Errorf("RecountUploadCounters() = %+v, %v; documents must not count as drift", recount, err)
+	}
+}
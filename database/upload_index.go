@@ -0,0 +1,175 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UploadLocation запись индекса upload_uuid -> файл БД, в которой хранится выгрузка
+type UploadLocation struct {
+	UploadUUID        string    `json:"upload_uuid"`
+	FilePath          string    `json:"file_path"`
+	ProjectDatabaseID *int      `json:"project_database_id,omitempty"`
+	IndexedAt         time.Time `json:"indexed_at"`
+}
+
+// CreateUploadIndexTable создает таблицу индекса расположения выгрузок
+func CreateUploadIndexTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS upload_db_index (
+			upload_uuid TEXT PRIMARY KEY,
+			file_path TEXT NOT NULL,
+			project_database_id INTEGER,
+			indexed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_upload_db_index_path ON upload_db_index(file_path);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create upload_db_index table: %w", err)
+	}
+	return nil
+}
+
+// RegisterUploadLocation сохраняет файл БД выгрузки, заменяя прежнее расположение
+func (db *ServiceDB) RegisterUploadLocation(uploadUUID, filePath string, projectDatabaseID *int) error {
+	if uploadUUID == "" || filePath == "" {
+		return fmt.Errorf("upload uuid and file path are required")
+	}
+	_, err := db.conn.Exec(`
+		INSERT OR REPLACE INTO upload_db_index (upload_uuid, file_path, project_database_id, indexed_at)
+		VALUES (?, ?, ?, ?)
+	`, uploadUUID, filePath, projectDatabaseID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to register upload location: %w", err)
+	}
+	return nil
+}
+
+// IndexUploadLocations добавляет в индекс выгрузки из файла БД, уже проиндексированные не изменяются.
+// Возвращает число добавленных записей
+func (db *ServiceDB) IndexUploadLocations(filePath string, projectDatabaseID *int, uploadUUIDs []string) (int, error) {
+	if len(uploadUUIDs) == 0 {
+		return 0, nil
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO upload_db_index (upload_uuid, file_path, project_database_id, indexed_at)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare upload index statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	added := 0
+	for _, uploadUUID := range uploadUUIDs {
+		result, err := stmt.Exec(uploadUUID, filePath, projectDatabaseID, now)
+		if err != nil {
+			return 0, fmt.Errorf("failed to index upload %s: %w", uploadUUID, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit upload index: %w", err)
+	}
+	return added, nil
+}
+
+// GetUploadLocation возвращает расположение выгрузки или nil, если выгрузка не проиндексирована
+func (db *ServiceDB) GetUploadLocation(uploadUUID string) (*UploadLocation, error) {
+	location := &UploadLocation{}
+	var projectDatabaseID sql.NullInt64
+	err := db.conn.QueryRow(`
+		SELECT upload_uuid, file_path, project_database_id, indexed_at
+		FROM upload_db_index WHERE upload_uuid = ?
+	`, uploadUUID).Scan(&location.UploadUUID, &location.FilePath, &projectDatabaseID, &location.IndexedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload location: %w", err)
+	}
+	if projectDatabaseID.Valid {
+		id := int(projectDatabaseID.Int64)
+		location.ProjectDatabaseID = &id
+	}
+	return location, nil
+}
+
+// DeleteUploadLocation удаляет выгрузку из индекса
+func (db *ServiceDB) DeleteUploadLocation(uploadUUID string) error {
+	if _, err := db.conn.Exec(`DELETE FROM upload_db_index WHERE upload_uuid = ?`, uploadUUID); err != nil {
+		return fmt.Errorf("failed to delete upload location: %w", err)
+	}
+	return nil
+}
+
+// CountUploadLocations возвращает число проиндексированных выгрузок
+func (db *ServiceDB) CountUploadLocations() (int, error) {
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM upload_db_index`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count upload locations: %w", err)
+	}
+	return count, nil
+}
+
+// GetAllProjectDatabases возвращает базы данных всех проектов
+func (db *ServiceDB) GetAllProjectDatabases() ([]*ProjectDatabase, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, client_project_id, name, file_path, description, is_active,
+		       file_size, last_used_at, created_at, updated_at
+		FROM project_databases
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project databases: %w", err)
+	}
+	defer rows.Close()
+
+	var databases []*ProjectDatabase
+	for rows.Next() {
+		projectDB := &ProjectDatabase{}
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(
+			&projectDB.ID, &projectDB.ClientProjectID, &projectDB.Name, &projectDB.FilePath,
+			&projectDB.Description, &projectDB.IsActive, &projectDB.FileSize, &lastUsedAt,
+			&projectDB.CreatedAt, &projectDB.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan project database: %w", err)
+		}
+		if lastUsedAt.Valid {
+			projectDB.LastUsedAt = &lastUsedAt.Time
+		}
+		databases = append(databases, projectDB)
+	}
+	return databases, rows.Err()
+}
+
+// GetUploadUUIDs возвращает UUID всех выгрузок БД
+func (db *DB) GetUploadUUIDs() ([]string, error) {
+	rows, err := db.conn.Query(`SELECT upload_uuid FROM uploads`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload uuids: %w", err)
+	}
+	defer rows.Close()
+
+	var uuids []string
+	for rows.Next() {
+		var uploadUUID string
+		if err := rows.Scan(&uploadUUID); err != nil {
+			return nil, fmt.Errorf("failed to scan upload uuid: %w", err)
+		}
+		uuids = append(uuids, uploadUUID)
+	}
+	return uuids, rows.Err()
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUploadIndex(t *testing.T) {
+	db, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer db.Close()
+
+	projectDatabaseID := 7
+	if err := db.RegisterUploadLocation("u-1", "unified.db", &projectDatabaseID); err != nil {
+		t.Fatalf("RegisterUploadLocation() error = %v", err)
+	}
+	if err := db.RegisterUploadLocation("", "unified.db", nil); err == nil {
+		t.Error("RegisterUploadLocation() must reject empty uuid")
+	}
+
+	added, err := db.IndexUploadLocations("project.db", nil, []string{"u-1", "u-2", "u-3"})
+	if err != nil {
+		t.Fatalf("IndexUploadLocations() error = %v", err)
+	}
+	if added != 2 {
+		t.Errorf("IndexUploadLocations() added = %d, want 2", added)
+	}
+
+	tests := []struct {
+		uuid      string
+		path      string
+		projectDB *int
+	}{
+		{"u-1", "unified.db", &projectDatabaseID},
+		{"u-2", "project.db", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uuid, func(t *testing.T) {
+			location, err := db.GetUploadLocation(tt.uuid)
+			if err != nil || location == nil {
+				t.Fatalf("GetUploadLocation() = %v, %v", location, err)
+			}
+			if location.FilePath != tt.path || !reflect.DeepEqual(location.ProjectDatabaseID, tt.projectDB) {
+				t.Errorf("location = %+v, want path %s", location, tt.path)
+			}
+		})
+	}
+
+	if err := db.DeleteUploadLocation("u-2"); err != nil {
+		t.Fatalf("DeleteUploadLocation() error = %v", err)
+	}
+	if location, err := db.GetUploadLocation("u-2"); err != nil || location != nil {
+		t.Errorf("GetUploadLocation(deleted) = %v, %v", location, err)
+	}
+	if count, err := db.CountUploadLocations(); err != nil || count != 2 {
+		t.Errorf("CountUploadLocations() = %d, %v, want 2", count, err)
+	}
+}
+
+func TestGetUploadUUIDs(t *testing.T) {
+	db, err := NewDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	for _, uploadUUID := range []string{"a", "b"} {
+		if _, err := db.CreateUpload(uploadUUID, "8.3", "УТ"); err != nil {
+			t.Fatalf("CreateUpload() error = %v", err)
+		}
+	}
+	uuids, err := db.GetUploadUUIDs()
+	if err != nil || len(uuids) != 2 {
+		t.Errorf("GetUploadUUIDs() = %v, %v", uuids, err)
+	}
+}
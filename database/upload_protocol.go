@@ -0,0 +1,26 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SetUploadProtocolVersion сохраняет версию протокола выгрузки, согласованную при рукопожатии
+func (db *DB) SetUploadProtocolVersion(uploadID, version int) error {
+	_, err := db.conn.Exec(`UPDATE uploads SET protocol_version = ? WHERE id = ?`, version, uploadID)
+	if err != nil {
+		return fmt.Errorf("failed to set upload protocol version: %w", err)
+	}
+	return nil
+}
+
+// GetUploadProtocolVersion возвращает версию протокола выгрузки; 0 - версия не сохранена
+// (выгрузка создана до появления версий протокола)
+func (db *DB) GetUploadProtocolVersion(uploadID int) (int, error) {
+	var version sql.NullInt64
+	err := db.conn.QueryRow(`SELECT protocol_version FROM uploads WHERE id = ?`, uploadID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get upload protocol version: %w", err)
+	}
+	return int(version.Int64), nil
+}
@@ -0,0 +1,327 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UploadDailyRollup дневная сводка по выгрузкам клиента/проекта/базы данных
+type UploadDailyRollup struct {
+	Day             string    `json:"day"` // YYYY-MM-DD
+	ClientID        int       `json:"client_id"`
+	ProjectID       int       `json:"project_id"`
+	DatabaseID      int       `json:"database_id"`
+	UploadsCount    int       `json:"uploads_count"`
+	ItemsIngested   int       `json:"items_ingested"`
+	NormalizedItems int       `json:"normalized_items"`
+	ClassifiedItems int       `json:"classified_items"`
+	ErrorCount      int       `json:"error_count"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// UploadRollupPoint точка временного ряда сводок (агрегат за день)
+type UploadRollupPoint struct {
+	Day                    string  `json:"day"`
+	UploadsCount           int     `json:"uploads_count"`
+	ItemsIngested          int     `json:"items_ingested"`
+	NormalizedItems        int     `json:"normalized_items"`
+	ClassifiedItems        int     `json:"classified_items"`
+	ErrorCount             int     `json:"error_count"`
+	NormalizationCoverage  float64 `json:"normalization_coverage"`
+	ClassificationCoverage float64 `json:"classification_coverage"`
+}
+
+// UploadRollupFilter параметры выборки временного ряда сводок
+type UploadRollupFilter struct {
+	ClientID  *int
+	ProjectID *int
+	From      *time.Time
+	To        *time.Time
+}
+
+// rollupKey ключ группировки сводки
+type rollupKey struct {
+	day                             string
+	clientID, projectID, databaseID int
+}
+
+// ComputeUploadDailyRollups рассчитывает дневные сводки по выгрузкам, начавшимся не раньше since.
+// Покрытие нормализацией и классификацией считается по элементам справочников, для которых
+// есть запись в normalized_data (классифицированные - с заполненным kpved_code).
+// Ошибки - неуспешные выгрузки и проблемы качества с критичностью CRITICAL/HIGH.
+func (db *DB) ComputeUploadDailyRollups(since time.Time) ([]UploadDailyRollup, error) {
+	sinceStr := since.UTC().Format(sqliteTimestampLayout)
+	rollups := make(map[rollupKey]*UploadDailyRollup)
+	order := make([]rollupKey, 0)
+
+	get := func(key rollupKey) *UploadDailyRollup {
+		rollup, ok := rollups[key]
+		if !ok {
+			rollup = &UploadDailyRollup{
+				Day:        key.day,
+				ClientID:   key.clientID,
+				ProjectID:  key.projectID,
+				DatabaseID: key.databaseID,
+			}
+			rollups[key] = rollup
+			order = append(order, key)
+		}
+		return rollup
+	}
+
+	const groupColumns = `date(u.started_at), COALESCE(u.client_id, 0), COALESCE(u.project_id, 0), COALESCE(u.database_id, 0)`
+
+	// 1. Количество выгрузок, элементов и неуспешных выгрузок
+	rows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT %s,
+		       COUNT(*),
+		       COALESCE(SUM(u.total_items), 0),
+		       SUM(CASE WHEN u.status IN ('failed', 'error') THEN 1 ELSE 0 END)
+		FROM uploads u
+		WHERE u.started_at >= ?
+		GROUP BY 1, 2, 3, 4
+	`, groupColumns), sinceStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate uploads: %w", err)
+	}
+	for rows.Next() {
+		var key rollupKey
+		var uploadsCount, items, failed int
+		if err := rows.Scan(&key.day, &key.clientID, &key.projectID, &key.databaseID, &uploadsCount, &items, &failed); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan uploads aggregate: %w", err)
+		}
+		rollup := get(key)
+		rollup.UploadsCount = uploadsCount
+		rollup.ItemsIngested = items
+		rollup.ErrorCount += failed
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating uploads aggregate: %w", err)
+	}
+	rows.Close()
+
+	hasCatalogs, err := TableExists(db.conn, "catalogs")
+	if err != nil {
+		return nil, err
+	}
+	hasNormalized, err := TableExists(db.conn, "normalized_data")
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Покрытие нормализацией и классификацией
+	if hasCatalogs && hasNormalized {
+		// Индекс нужен для EXISTS-подзапросов по source_reference
+		if _, err := db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_normalized_source_reference ON normalized_data(source_reference)`); err != nil {
+			return nil, fmt.Errorf("failed to create source_reference index: %w", err)
+		}
+
+		rows, err := db.conn.Query(fmt.Sprintf(`
+			SELECT %s,
+			       SUM(CASE WHEN EXISTS (
+			           SELECT 1 FROM normalized_data nd WHERE nd.source_reference = ci.reference
+			       ) THEN 1 ELSE 0 END),
+			       SUM(CASE WHEN EXISTS (
+			           SELECT 1 FROM normalized_data nd
+			           WHERE nd.source_reference = ci.reference AND COALESCE(nd.kpved_code, '') != ''
+			       ) THEN 1 ELSE 0 END)
+			FROM catalog_items ci
+			JOIN catalogs c ON c.id = ci.catalog_id
+			JOIN uploads u ON u.id = c.upload_id
+			WHERE u.started_at >= ?
+			GROUP BY 1, 2, 3, 4
+		`, groupColumns), sinceStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate coverage: %w", err)
+		}
+		for rows.Next() {
+			var key rollupKey
+			var normalized, classified int
+			if err := rows.Scan(&key.day, &key.clientID, &key.projectID, &key.databaseID, &normalized, &classified); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan coverage aggregate: %w", err)
+			}
+			rollup := get(key)
+			rollup.NormalizedItems = normalized
+			rollup.ClassifiedItems = classified
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error iterating coverage aggregate: %w", err)
+		}
+		rows.Close()
+	}
+
+	// 3. Критичные проблемы качества
+	hasIssues, err := TableExists(db.conn, "data_quality_issues")
+	if err != nil {
+		return nil, err
+	}
+	if hasIssues {
+		rows, err := db.conn.Query(fmt.Sprintf(`
+			SELECT %s, COUNT(*)
+			FROM data_quality_issues q
+			JOIN uploads u ON u.id = q.upload_id
+			WHERE u.started_at >= ? AND q.issue_severity IN ('CRITICAL', 'HIGH')
+			GROUP BY 1, 2, 3, 4
+		`, groupColumns), sinceStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to aggregate quality issues: %w", err)
+		}
+		for rows.Next() {
+			var key rollupKey
+			var issues int
+			if err := rows.Scan(&key.day, &key.clientID, &key.projectID, &key.databaseID, &issues); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan quality issues aggregate: %w", err)
+			}
+			get(key).ErrorCount += issues
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error iterating quality issues aggregate: %w", err)
+		}
+		rows.Close()
+	}
+
+	result := make([]UploadDailyRollup, 0, len(order))
+	for _, key := range order {
+		result = append(result, *rollups[key])
+	}
+	return result, nil
+}
+
+// MergeUploadDailyRollups суммирует сводки нескольких БД выгрузок по ключу (день, клиент, проект, база данных).
+// Сводка в service.db заменяется целиком, поэтому перед сохранением счетчики всех источников складываются
+func MergeUploadDailyRollups(sources ...[]UploadDailyRollup) []UploadDailyRollup {
+	merged := make(map[rollupKey]*UploadDailyRollup)
+	order := make([]rollupKey, 0)
+	for _, rollups := range sources {
+		for _, rollup := range rollups {
+			key := rollupKey{day: rollup.Day, clientID: rollup.ClientID, projectID: rollup.ProjectID, databaseID: rollup.DatabaseID}
+			total, ok := merged[key]
+			if !ok {
+				copied := rollup
+				merged[key] = &copied
+				order = append(order, key)
+				continue
+			}
+			total.UploadsCount += rollup.UploadsCount
+			total.ItemsIngested += rollup.ItemsIngested
+			total.NormalizedItems += rollup.NormalizedItems
+			total.ClassifiedItems += rollup.ClassifiedItems
+			total.ErrorCount += rollup.ErrorCount
+		}
+	}
+
+	result := make([]UploadDailyRollup, 0, len(order))
+	for _, key := range order {
+		result = append(result, *merged[key])
+	}
+	return result
+}
+
+// SaveUploadDailyRollups сохраняет (заменяет) дневные сводки
+func (db *ServiceDB) SaveUploadDailyRollups(rollups []UploadDailyRollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO upload_daily_rollups
+			(day, client_id, project_id, database_id, uploads_count, items_ingested,
+			 normalized_items, classified_items, error_count, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(day, client_id, project_id, database_id) DO UPDATE SET
+			uploads_count = excluded.uploads_count,
+			items_ingested = excluded.items_ingested,
+			normalized_items = excluded.normalized_items,
+			classified_items = excluded.classified_items,
+			error_count = excluded.error_count,
+			updated_at = CURRENT_TIMESTAMP
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare rollup statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rollup := range rollups {
+		if _, err := stmt.Exec(rollup.Day, rollup.ClientID, rollup.ProjectID, rollup.DatabaseID,
+			rollup.UploadsCount, rollup.ItemsIngested, rollup.NormalizedItems,
+			rollup.ClassifiedItems, rollup.ErrorCount); err != nil {
+			return fmt.Errorf("failed to save rollup for %s: %w", rollup.Day, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUploadRollupSeries возвращает временной ряд сводок по дням (суммы по всем подходящим клиентам/проектам)
+func (db *ServiceDB) GetUploadRollupSeries(filter UploadRollupFilter) ([]UploadRollupPoint, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.ClientID != nil {
+		conditions = append(conditions, "client_id = ?")
+		args = append(args, *filter.ClientID)
+	}
+	if filter.ProjectID != nil {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, *filter.ProjectID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "day >= ?")
+		args = append(args, filter.From.UTC().Format("2006-01-02"))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "day <= ?")
+		args = append(args, filter.To.UTC().Format("2006-01-02"))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT day, SUM(uploads_count), SUM(items_ingested), SUM(normalized_items),
+		       SUM(classified_items), SUM(error_count)
+		FROM upload_daily_rollups
+		%s
+		GROUP BY day
+		ORDER BY day ASC
+	`, whereClause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollup series: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]UploadRollupPoint, 0)
+	for rows.Next() {
+		var point UploadRollupPoint
+		if err := rows.Scan(&point.Day, &point.UploadsCount, &point.ItemsIngested,
+			&point.NormalizedItems, &point.ClassifiedItems, &point.ErrorCount); err != nil {
+			return nil, fmt.Errorf("failed to scan rollup point: %w", err)
+		}
+		if point.ItemsIngested > 0 {
+			point.NormalizationCoverage = float64(point.NormalizedItems) / float64(point.ItemsIngested)
+			point.ClassificationCoverage = float64(point.ClassifiedItems) / float64(point.ItemsIngested)
+		}
+		points = append(points, point)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rollup series: %w", err)
+	}
+
+	return points, nil
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestMergeUploadDailyRollups(t *testing.T) {
+	uploadsDB := []UploadDailyRollup{
+		{Day: "2024-03-01", ClientID: 1, ProjectID: 2, DatabaseID: 3, UploadsCount: 2, ItemsIngested: 100, NormalizedItems: 40, ErrorCount: 1},
+		{Day: "2024-03-02", ClientID: 1, ProjectID: 2, DatabaseID: 3, UploadsCount: 1, ItemsIngested: 10},
+	}
+	unifiedDB := []UploadDailyRollup{
+		{Day: "2024-03-01", ClientID: 1, ProjectID: 2, DatabaseID: 3, UploadsCount: 1, ItemsIngested: 50, ClassifiedItems: 5, ErrorCount: 2},
+		{Day: "2024-03-01", ClientID: 1, ProjectID: 2, DatabaseID: 4, UploadsCount: 1, ItemsIngested: 7},
+	}
+
+	merged := MergeUploadDailyRollups(uploadsDB, unifiedDB)
+	want := []UploadDailyRollup{
+		{Day: "2024-03-01", ClientID: 1, ProjectID: 2, DatabaseID: 3, UploadsCount: 3, ItemsIngested: 150, NormalizedItems: 40, ClassifiedItems: 5, ErrorCount: 3},
+		{Day: "2024-03-02", ClientID: 1, ProjectID: 2, DatabaseID: 3, UploadsCount: 1, ItemsIngested: 10},
+		{Day: "2024-03-01", ClientID: 1, ProjectID: 2, DatabaseID: 4, UploadsCount: 1, ItemsIngested: 7},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("MergeUploadDailyRollups() = %+v, want %+v", merged, want)
+	}
+	for i := range want {
+		if merged[i] != want[i] {
+			t.Errorf("rollup #%d = %+v, want %+v", i, merged[i], want[i])
+		}
+	}
+	// Исходные сводки не изменяются
+	if uploadsDB[0].UploadsCount != 2 {
+		t.Errorf("source rollup modified: %+v", uploadsDB[0])
+	}
+}
@@ -0,0 +1,556 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"httpserver/apperrors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Способы входа пользователя веб-интерфейса
+const (
+	AuthProviderPassword = "password" // Имя пользователя и пароль
+	AuthProviderOIDC     = "oidc"     // Внешний провайдер OpenID Connect
+	AuthProviderLocal    = "local"    // Аварийная учетная запись администратора из конфигурации
+	AuthProviderService  = "service"  // Сервисная учетная запись интеграций: доступ только по токену API
+)
+
+// Роли пользователей веб-интерфейса в порядке возрастания прав
+const (
+	RoleViewer   = "viewer"   // Просмотр данных
+	RoleOperator = "operator" // Изменение данных: загрузка, нормализация, исправления, экспорт
+	RoleAdmin    = "admin"    // Управление пользователями и настройками сервера
+)
+
+// roleRanks ранги ролей для сравнения прав
+var roleRanks = map[string]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// ValidRole проверяет, известна ли роль
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// RoleAllows проверяет, что роль дает права не ниже required
+func RoleAllows(role, required string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[required]
+}
+
+// HigherRole возвращает роль с большими правами; неизвестные роли не учитываются
+func HigherRole(a, b string) string {
+	if roleRanks[b] > roleRanks[a] {
+		return b
+	}
+	return a
+}
+
+// MinPasswordLength минимальная длина пароля пользователя
+const MinPasswordLength = 8
+
+// MaxPasswordLength максимальная длина пароля в байтах: bcrypt не учитывает байты после 72-го
+const MaxPasswordLength = 72
+
+// passwordHashCost стоимость bcrypt для новых паролей; стоимость хранится в хеше,
+// поэтому ее можно повышать без сброса существующих паролей
+const passwordHashCost = 12
+
+// ErrInvalidCredentials неверное имя пользователя или пароль (не уточняется, что именно)
+var ErrInvalidCredentials = apperrors.Unauthorized("invalid_credentials", "invalid username or password")
+
+// User учетная запись пользователя веб-интерфейса
+type User struct {
+	ID           int        `json:"id"`
+	Username     string     `json:"username"`
+	DisplayName  string     `json:"display_name"`
+	Email        string     `json:"email,omitempty"`
+	AuthProvider string     `json:"auth_provider"`
+	Role         string     `json:"role"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+}
+
+// UserSession сессия входа пользователя. В БД хранится только хеш токена сессии
+type UserSession struct {
+	UserID    int       `json:"user_id"`
+	CSRFToken string    `json:"csrf_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	User      *User     `json:"user"`
+}
+
+// CreateUsersTables создает таблицы пользователей веб-интерфейса и их сессий
+func CreateUsersTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+			display_name TEXT NOT NULL DEFAULT '',
+			email TEXT NOT NULL DEFAULT '',
+			password_hash TEXT NOT NULL DEFAULT '',
+			auth_provider TEXT NOT NULL DEFAULT 'password',
+			external_subject TEXT,
+			preferences TEXT NOT NULL DEFAULT '{}',
+			is_active INTEGER NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_login_at TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_users_external_subject ON users(auth_provider, external_subject)
+			WHERE external_subject IS NOT NULL;
+
+		CREATE TABLE IF NOT EXISTS user_sessions (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			csrf_token TEXT NOT NULL,
+			remote_addr TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL,
+			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id);
+		CREATE INDEX IF NOT EXISTS idx_user_sessions_expires ON user_sessions(expires_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create users tables: %w", err)
+	}
+	return nil
+}
+
+// MigrateUserRoles добавляет колонку роли в таблицу users. Пользователи, созданные до появления ролей,
+// получают роль администратора, чтобы сохранить прежний доступ
+func MigrateUserRoles(db *sql.DB) error {
+	_, err := db.Exec(`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'admin'`)
+	if err != nil {
+		errStr := strings.ToLower(err.Error())
+		if !strings.Contains(errStr, "duplicate column") &&
+			!strings.Contains(errStr, "already exists") {
+			return fmt.Errorf("failed to add users.role column: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateRole проверяет роль; пустая роль заменяется ролью просмотра
+func validateRole(role string) (string, error) {
+	if role == "" {
+		return RoleViewer, nil
+	}
+	if !ValidRole(role) {
+		return "", apperrors.Validation("invalid_role", fmt.Sprintf("unknown role %s", role))
+	}
+	return role, nil
+}
+
+// userColumns колонки пользователя в порядке, ожидаемом scanUser
+const userColumns = `id, username, display_name, email, auth_provider, role, is_active, created_at, last_login_at`
+
+// scanUser сканирует строку с колонками userColumns
+func scanUser(scanner rowScanner) (*User, error) {
+	user := &User{}
+	var lastLogin sql.NullTime
+	if err := scanner.Scan(&user.ID, &user.Username, &user.DisplayName, &user.Email, &user.AuthProvider, &user.Role,
+		&user.IsActive, &user.CreatedAt, &lastLogin); err != nil {
+		return nil, err
+	}
+	if lastLogin.Valid {
+		user.LastLoginAt = &lastLogin.Time
+	}
+	return user, nil
+}
+
+// HashPassword хеширует пароль bcrypt со случайной солью
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// VerifyPassword проверяет пароль по хешу HashPassword за время, не зависящее от совпадения
+func VerifyPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// ValidPasswordHash проверяет, что строка является хешем HashPassword
+func ValidPasswordHash(hash string) bool {
+	_, err := bcrypt.Cost([]byte(hash))
+	return err == nil
+}
+
+// dummyPasswordHash хеш для проверки пароля несуществующего пользователя, чтобы время ответа
+// не выдавало наличие учетной записи. Рассчитывается при первом использовании
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("dummy-password")
+	return hash
+})
+
+// validateUsername проверяет имя пользователя
+func validateUsername(username string) error {
+	if username == "" || len(username) > 100 || strings.ContainsAny(username, " \t\r\n") {
+		return apperrors.Validation("invalid_username", "username must be 1-100 characters without spaces")
+	}
+	return nil
+}
+
+// CreateUser создает пользователя со входом по паролю; пустая роль - просмотр
+func (db *ServiceDB) CreateUser(username, password, displayName, email, role string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if err := validateUsername(username); err != nil {
+		return nil, err
+	}
+	role, err := validateRole(role)
+	if err != nil {
+		return nil, err
+	}
+	if len(password) < MinPasswordLength {
+		return nil, apperrors.Validation("weak_password", fmt.Sprintf("password must be at least %d characters", MinPasswordLength))
+	}
+	if len(password) > MaxPasswordLength {
+		return nil, apperrors.Validation("password_too_long", fmt.Sprintf("password must be at most %d bytes", MaxPasswordLength))
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return db.insertUser(username, displayName, email, hash, AuthProviderPassword, role, nil)
+}
+
+// CreateServiceAccount создает сервисную учетную запись без пароля: войти по паролю под ней нельзя,
+// доступ выдается токенами CreateUserSession; пустая роль - просмотр
+func (db *ServiceDB) CreateServiceAccount(username, displayName, role string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if err := validateUsername(username); err != nil {
+		return nil, err
+	}
+	role, err := validateRole(role)
+	if err != nil {
+		return nil, err
+	}
+	return db.insertUser(username, displayName, "", "", AuthProviderService, role, nil)
+}
+
+// insertUser добавляет пользователя; занятое имя - конфликт
+func (db *ServiceDB) insertUser(username, displayName, email, passwordHash, provider, role string, subject *string) (*User, error) {
+	if displayName == "" {
+		displayName = username
+	}
+	result, err := db.conn.Exec(`
+		INSERT INTO users (username, display_name, email, password_hash, auth_provider, role, external_subject)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, username, displayName, email, passwordHash, provider, role, subject)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, apperrors.Conflict("username_taken", fmt.Sprintf("username %s is already taken", username))
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user id: %w", err)
+	}
+	return db.GetUser(int(id))
+}
+
+// GetUser возвращает пользователя по ID
+func (db *ServiceDB) GetUser(id int) (*User, error) {
+	user, err := scanUser(db.conn.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.NotFound("user_not_found", fmt.Sprintf("user %d not found", id))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// ListUsers возвращает всех пользователей
+func (db *ServiceDB) ListUsers() ([]*User, error) {
+	rows, err := db.conn.Query(`SELECT ` + userColumns + ` FROM users ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// CountUsers возвращает количество пользователей
+func (db *ServiceDB) CountUsers() (int, error) {
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// AuthenticateUser проверяет имя пользователя и пароль активного пользователя
+func (db *ServiceDB) AuthenticateUser(username, password string) (*User, error) {
+	var id int
+	var hash string
+	err := db.conn.QueryRow(`
+		SELECT id, password_hash FROM users
+		WHERE username = ? AND auth_provider = ? AND is_active = 1
+	`, strings.TrimSpace(username), AuthProviderPassword).Scan(&id, &hash)
+	if err == sql.ErrNoRows {
+		VerifyPassword(dummyPasswordHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !VerifyPassword(hash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return db.GetUser(id)
+}
+
+// UpsertOIDCUser возвращает пользователя внешнего провайдера по subject, создавая его при первом входе.
+// Имя пользователя - preferred_username, email или subject. Роль определяется группами провайдера
+// и обновляется при каждом входе
+func (db *ServiceDB) UpsertOIDCUser(subject, username, email, displayName, role string) (*User, error) {
+	if subject == "" {
+		return nil, apperrors.Validation("invalid_oidc_subject", "OIDC subject is empty")
+	}
+	if !ValidRole(role) {
+		return nil, apperrors.Validation("invalid_role", fmt.Sprintf("unknown role %s", role))
+	}
+	var id int
+	var active bool
+	err := db.conn.QueryRow(`SELECT id, is_active FROM users WHERE auth_provider = ? AND external_subject = ?`,
+		AuthProviderOIDC, subject).Scan(&id, &active)
+	switch {
+	case err == sql.ErrNoRows:
+		for _, candidate := range []string{username, email, subject} {
+			if candidate = strings.TrimSpace(candidate); validateUsername(candidate) == nil {
+				return db.insertUser(candidate, displayName, email, "", AuthProviderOIDC, role, &subject)
+			}
+		}
+		return nil, apperrors.Validation("invalid_username", "OIDC user has no usable username")
+	case err != nil:
+		return nil, fmt.Errorf("failed to get OIDC user: %w", err)
+	case !active:
+		return nil, ErrInvalidCredentials
+	}
+	if _, err := db.conn.Exec(`UPDATE users SET email = ?, display_name = COALESCE(NULLIF(?, ''), display_name), role = ? WHERE id = ?`,
+		email, displayName, role, id); err != nil {
+		return nil, fmt.Errorf("failed to update OIDC user: %w", err)
+	}
+	return db.GetUser(id)
+}
+
+// EnsureLocalAdmin возвращает аварийную учетную запись администратора, создавая ее при первом входе.
+// Пароль такой учетной записи хранится в конфигурации, а не в БД; имя не может совпадать
+// с пользователем другого способа входа
+func (db *ServiceDB) EnsureLocalAdmin(username string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if err := validateUsername(username); err != nil {
+		return nil, err
+	}
+	var id int
+	var provider string
+	err := db.conn.QueryRow(`SELECT id, auth_provider FROM users WHERE username = ?`, username).Scan(&id, &provider)
+	switch {
+	case err == sql.ErrNoRows:
+		return db.insertUser(username, "", "", "", AuthProviderLocal, RoleAdmin, nil)
+	case err != nil:
+		return nil, fmt.Errorf("failed to get local admin: %w", err)
+	case provider != AuthProviderLocal:
+		return nil, apperrors.Conflict("username_taken", fmt.Sprintf("username %s is already taken", username))
+	}
+	// Аварийная учетная запись всегда активна и имеет права администратора
+	if _, err := db.conn.Exec(`UPDATE users SET role = ?, is_active = 1 WHERE id = ?`, RoleAdmin, id); err != nil {
+		return nil, fmt.Errorf("failed to update local admin: %w", err)
+	}
+	return db.GetUser(id)
+}
+
+// SetUserActive включает или блокирует пользователя; у заблокированного удаляются сессии
+func (db *ServiceDB) SetUserActive(id int, active bool) error {
+	result, err := db.conn.Exec(`UPDATE users SET is_active = ? WHERE id = ?`, active, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apperrors.NotFound("user_not_found", fmt.Sprintf("user %d not found", id))
+	}
+	if !active {
+		if _, err := db.conn.Exec(`DELETE FROM user_sessions WHERE user_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete user sessions: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetUserRole меняет роль пользователя
+func (db *ServiceDB) SetUserRole(id int, role string) error {
+	if !ValidRole(role) {
+		return apperrors.Validation("invalid_role", fmt.Sprintf("unknown role %s", role))
+	}
+	result, err := db.conn.Exec(`UPDATE users SET role = ? WHERE id = ?`, role, id)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return apperrors.NotFound("user_not_found", fmt.Sprintf("user %d not found", id))
+	}
+	return nil
+}
+
+// GetUserPreferences возвращает настройки веб-интерфейса пользователя
+func (db *ServiceDB) GetUserPreferences(userID int) (map[string]interface{}, error) {
+	var data string
+	err := db.conn.QueryRow(`SELECT preferences FROM users WHERE id = ?`, userID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.NotFound("user_not_found", fmt.Sprintf("user %d not found", userID))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+	preferences := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(data), &preferences); err != nil {
+		return nil, fmt.Errorf("failed to decode user preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// SetUserPreferences заменяет настройки веб-интерфейса пользователя
+func (db *ServiceDB) SetUserPreferences(userID int, preferences map[string]interface{}) error {
+	if preferences == nil {
+		preferences = map[string]interface{}{}
+	}
+	data, err := json.Marshal(preferences)
+	if err != nil {
+		return apperrors.Wrap(apperrors.KindValidation, "invalid_preferences", err)
+	}
+	if _, err := db.conn.Exec(`UPDATE users SET preferences = ? WHERE id = ?`, string(data), userID); err != nil {
+		return fmt.Errorf("failed to set user preferences: %w", err)
+	}
+	return nil
+}
+
+// randomToken возвращает случайный токен в hex
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// sessionTokenHash хеш токена сессии для хранения в БД
+func sessionTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateUserSession создает сессию пользователя и отмечает время входа.
+// Возвращает токен сессии для cookie: в БД он не сохраняется
+func (db *ServiceDB) CreateUserSession(userID int, ttl time.Duration, remoteAddr, userAgent string) (string, *UserSession, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Истекшие сессии удаляются при каждом входе
+	if _, err := tx.Exec(`DELETE FROM user_sessions WHERE expires_at <= ?`, now.Format(sqliteTimestampLayout)); err != nil {
+		return "", nil, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO user_sessions (token_hash, user_id, csrf_token, remote_addr, user_agent, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, sessionTokenHash(token), userID, csrfToken, remoteAddr, userAgent,
+		now.Format(sqliteTimestampLayout), expiresAt.Format(sqliteTimestampLayout)); err != nil {
+		return "", nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE users SET last_login_at = ? WHERE id = ?`, now.Format(sqliteTimestampLayout), userID); err != nil {
+		return "", nil, fmt.Errorf("failed to update last login: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", nil, fmt.Errorf("failed to commit session: %w", err)
+	}
+
+	session, err := db.GetUserSession(token)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// GetUserSession возвращает действующую сессию активного пользователя по токену; nil, если
+// сессии нет или она истекла
+func (db *ServiceDB) GetUserSession(token string) (*UserSession, error) {
+	session := &UserSession{}
+	row := db.conn.QueryRow(`
+		SELECT s.user_id, s.csrf_token, s.expires_at, s.created_at,
+		       u.id, u.username, u.display_name, u.email, u.auth_provider, u.role, u.is_active, u.created_at, u.last_login_at
+		FROM user_sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ? AND s.expires_at > ? AND u.is_active = 1
+	`, sessionTokenHash(token), time.Now().UTC().Format(sqliteTimestampLayout))
+	user := &User{}
+	var lastLogin sql.NullTime
+	err := row.Scan(&session.UserID, &session.CSRFToken, &session.ExpiresAt, &session.CreatedAt,
+		&user.ID, &user.Username, &user.DisplayName, &user.Email, &user.AuthProvider, &user.Role, &user.IsActive, &user.CreatedAt, &lastLogin)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if lastLogin.Valid {
+		user.LastLoginAt = &lastLogin.Time
+	}
+	session.User = user
+	return session, nil
+}
+
+// HasActiveSession проверяет, что у пользователя есть действующая сессия или токен API
+func (db *ServiceDB) HasActiveSession(userID int) (bool, error) {
+	var exists bool
+	err := db.conn.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_sessions WHERE user_id = ? AND expires_at > ?)`,
+		userID, time.Now().UTC().Format(sqliteTimestampLayout)).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check sessions: %w", err)
+	}
+	return exists, nil
+}
+
+// DeleteUserSession завершает сессию по токену
+func (db *ServiceDB) DeleteUserSession(token string) error {
+	if _, err := db.conn.Exec(`DELETE FROM user_sessions WHERE token_hash = ?`, sessionTokenHash(token)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
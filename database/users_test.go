@@ -0,0 +1,184 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+func TestUserAuthentication(t *testing.T) {
+	serviceDB, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	user, err := serviceDB.CreateUser("ivanov", "s3cret-pass", "", "ivanov@example.com", "")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.DisplayName != "ivanov" || user.AuthProvider != AuthProviderPassword || user.Role != RoleViewer || !user.IsActive {
+		t.Errorf("user = %+v", user)
+	}
+
+	createTests := []struct {
+		name     string
+		username string
+		password string
+		role     string
+		wantCode string
+	}{
+		{"duplicate username ignores case", "IVANOV", "another-pass", "", "username_taken"},
+		{"short password", "petrov", "short", "", "weak_password"},
+		{"username with spaces", "petr petrov", "long-enough", "", "invalid_username"},
+		{"unknown role", "petrov", "long-enough", "owner", "invalid_role"},
+	}
+	for _, tt := range createTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := serviceDB.CreateUser(tt.username, tt.password, "", "", tt.role); apperrors.CodeOf(err) != tt.wantCode {
+				t.Errorf("CreateUser() error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+
+	authTests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{"valid", "ivanov", "s3cret-pass", false},
+		{"wrong password", "ivanov", "wrong-pass", true},
+		{"unknown user", "sidorov", "s3cret-pass", true},
+	}
+	for _, tt := range authTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := serviceDB.AuthenticateUser(tt.username, tt.password)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("AuthenticateUser() error = %v, want ErrInvalidCredentials", err)
+				}
+				return
+			}
+			if err != nil || got.ID != user.ID {
+				t.Errorf("AuthenticateUser() = %+v, %v", got, err)
+			}
+		})
+	}
+
+	token, session, err := serviceDB.CreateUserSession(user.ID, time.Hour, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("CreateUserSession() error = %v", err)
+	}
+	if session.User.Username != "ivanov" || session.CSRFToken == "" || session.User.LastLoginAt == nil {
+		t.Errorf("session = %+v", session)
+	}
+	if got, _ := serviceDB.GetUserSession(token); got == nil || got.UserID != user.ID {
+		t.Errorf("GetUserSession() = %+v", got)
+	}
+	if got, _ := serviceDB.GetUserSession("unknown"); got != nil {
+		t.Errorf("GetUserSession(unknown) = %+v, want nil", got)
+	}
+	expired, _, _ := serviceDB.CreateUserSession(user.ID, -time.Minute, "", "")
+	if got, _ := serviceDB.GetUserSession(expired); got != nil {
+		t.Errorf("GetUserSession(expired) = %+v, want nil", got)
+	}
+
+	if err := serviceDB.SetUserPreferences(user.ID, map[string]interface{}{"theme": "dark"}); err != nil {
+		t.Fatalf("SetUserPreferences() error = %v", err)
+	}
+	if preferences, _ := serviceDB.GetUserPreferences(user.ID); preferences["theme"] != "dark" {
+		t.Errorf("GetUserPreferences() = %v", preferences)
+	}
+
+	if err := serviceDB.SetUserRole(user.ID, RoleOperator); err != nil {
+		t.Fatalf("SetUserRole() error = %v", err)
+	}
+	if got, _ := serviceDB.GetUserSession(token); got == nil || got.User.Role != RoleOperator {
+		t.Errorf("session user after SetUserRole() = %+v", got)
+	}
+
+	// Блокировка пользователя завершает его сессии
+	if err := serviceDB.SetUserActive(user.ID, false); err != nil {
+		t.Fatalf("SetUserActive() error = %v", err)
+	}
+	if got, _ := serviceDB.GetUserSession(token); got != nil {
+		t.Error("session of blocked user is still valid")
+	}
+	if _, err := serviceDB.AuthenticateUser("ivanov", "s3cret-pass"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("AuthenticateUser(blocked) error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestUpsertOIDCUser(t *testing.T) {
+	serviceDB, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	first, err := serviceDB.UpsertOIDCUser("sub-1", "ivanov", "ivanov@example.com", "Иванов И.И.", RoleViewer)
+	if err != nil {
+		t.Fatalf("UpsertOIDCUser() error = %v", err)
+	}
+	// Роль обновляется по группам провайдера при каждом входе
+	again, err := serviceDB.UpsertOIDCUser("sub-1", "ivanov", "new@example.com", "", RoleAdmin)
+	if err != nil || again.ID != first.ID || again.Email != "new@example.com" || again.DisplayName != "Иванов И.И." || again.Role != RoleAdmin {
+		t.Errorf("second UpsertOIDCUser() = %+v, %v", again, err)
+	}
+	if _, err := serviceDB.UpsertOIDCUser("sub-2", "ivanov", "", "", RoleViewer); apperrors.CodeOf(err) != "username_taken" {
+		t.Errorf("UpsertOIDCUser(taken username) error = %v, want username_taken", err)
+	}
+	// Вход через OIDC не дает войти по паролю
+	if _, err := serviceDB.AuthenticateUser("ivanov", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("AuthenticateUser(oidc user) error = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestEnsureLocalAdmin(t *testing.T) {
+	serviceDB, err := NewServiceDBWithConfig(":memory:", DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	admin, err := serviceDB.EnsureLocalAdmin("breakglass")
+	if err != nil || admin.AuthProvider != AuthProviderLocal || admin.Role != RoleAdmin {
+		t.Fatalf("EnsureLocalAdmin() = %+v, %v", admin, err)
+	}
+	// Повторный вход восстанавливает права, даже если учетную запись заблокировали
+	serviceDB.SetUserActive(admin.ID, false)
+	serviceDB.SetUserRole(admin.ID, RoleViewer)
+	again, err := serviceDB.EnsureLocalAdmin("BreakGlass")
+	if err != nil || again.ID != admin.ID || again.Role != RoleAdmin || !again.IsActive {
+		t.Errorf("second EnsureLocalAdmin() = %+v, %v", again, err)
+	}
+
+	serviceDB.CreateUser("ivanov", "s3cret-pass", "", "", "")
+	if _, err := serviceDB.EnsureLocalAdmin("ivanov"); apperrors.CodeOf(err) != "username_taken" {
+		t.Errorf("EnsureLocalAdmin(password user) error = %v, want username_taken", err)
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{RoleAdmin, RoleOperator, true},
+		{RoleOperator, RoleOperator, true},
+		{RoleViewer, RoleOperator, false},
+		{"", RoleViewer, false},
+		{"owner", RoleViewer, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+tt.required, func(t *testing.T) {
+			if got := RoleAllows(tt.role, tt.required); got != tt.want {
+				t.Errorf("RoleAllows(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+			}
+		})
+	}
+}
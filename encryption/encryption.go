@@ -0,0 +1,243 @@
+// Package encryption шифрование данных выгрузок на уровне приложения (AES-256-GCM).
+//
+// Значения шифруются ключом данных проекта и хранятся строкой "enc:v1:<ключ>:<base64(nonce|шифротекст)>",
+// поэтому по значению видно, что оно зашифровано и каким ключом. Ключи данных проектов хранятся в service.db
+// только в обернутом виде: их шифрует мастер-ключ из конфигурации развертывания (ENCRYPTION_MASTER_KEYS).
+// Несколько мастер-ключей позволяют сменить ключ: новые ключи данных оборачиваются текущим,
+// ранее обернутые читаются своим
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KeySize размер ключей AES-256 в байтах
+const KeySize = 32
+
+// sealedPrefix префикс зашифрованного значения
+const sealedPrefix = "enc:v1:"
+
+// wrapAAD связанные данные обертки ключа данных: обернутый ключ нельзя выдать за зашифрованное значение
+const wrapAAD = "data-key"
+
+// digestLabel назначение ключа хешей, выводимого из ключа данных
+const digestLabel = "value-digest"
+
+var (
+	// ErrUnknownKey ключ, которым зашифровано значение, недоступен
+	ErrUnknownKey = errors.New("encryption key is not available")
+	// ErrInvalidValue значение повреждено или зашифровано другим ключом
+	ErrInvalidValue = errors.New("invalid encrypted value")
+)
+
+// Keyring мастер-ключи развертывания по идентификаторам
+type Keyring struct {
+	keys    map[string][]byte
+	current string
+}
+
+// ParseKeyring разбирает мастер-ключи "id:base64,id2:base64" (ключи по 32 байта). currentID выбирает ключ
+// для обертки новых ключей данных; пустой - первый в списке. Пустая строка ключей - шифрование не настроено (nil)
+func ParseKeyring(spec, currentID string) (*Keyring, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	keyring := &Keyring{keys: make(map[string][]byte)}
+	for _, item := range strings.Split(spec, ",") {
+		id, encoded, found := strings.Cut(strings.TrimSpace(item), ":")
+		id = strings.TrimSpace(id)
+		if !found || id == "" {
+			return nil, fmt.Errorf("master key %q: expected id:base64", item)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("master key %s: invalid base64: %w", id, err)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("master key %s: expected %d bytes, got %d", id, KeySize, len(key))
+		}
+		if _, ok := keyring.keys[id]; ok {
+			return nil, fmt.Errorf("master key %s: duplicate id", id)
+		}
+		keyring.keys[id] = key
+		if keyring.current == "" {
+			keyring.current = id
+		}
+	}
+	if currentID = strings.TrimSpace(currentID); currentID != "" {
+		if _, ok := keyring.keys[currentID]; !ok {
+			return nil, fmt.Errorf("current master key %s is not in the key list", currentID)
+		}
+		keyring.current = currentID
+	}
+	return keyring, nil
+}
+
+// CurrentID идентификатор мастер-ключа для обертки новых ключей данных
+func (k *Keyring) CurrentID() string {
+	return k.current
+}
+
+// IDs идентификаторы мастер-ключей по алфавиту
+func (k *Keyring) IDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// WrapKey шифрует ключ данных текущим мастер-ключом
+func (k *Keyring) WrapKey(dataKey []byte) (masterID, wrapped string, err error) {
+	sealed, err := seal(k.keys[k.current], dataKey, []byte(wrapAAD))
+	if err != nil {
+		return "", "", err
+	}
+	return k.current, base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// UnwrapKey расшифровывает ключ данных мастер-ключом masterID
+func (k *Keyring) UnwrapKey(masterID, wrapped string) ([]byte, error) {
+	masterKey, ok := k.keys[masterID]
+	if !ok {
+		return nil, fmt.Errorf("master key %s: %w", masterID, ErrUnknownKey)
+	}
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+	dataKey, err := open(masterKey, data, []byte(wrapAAD))
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) != KeySize {
+		return nil, ErrInvalidValue
+	}
+	return dataKey, nil
+}
+
+// GenerateKey создает случайный ключ данных
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// DigestKey выводит из ключа данных ключ HMAC для хешей значений: хеши одного проекта сравнимы
+// между собой, но без ключа данных значение по хешу не подобрать
+func DigestKey(dataKey []byte) []byte {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte(digestLabel))
+	return mac.Sum(nil)
+}
+
+// Seal шифрует значение ключом данных keyRef. Пустое значение не шифруется
+func Seal(key []byte, keyRef, plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	if keyRef == "" || strings.Contains(keyRef, ":") {
+		return "", fmt.Errorf("invalid key reference %q", keyRef)
+	}
+	sealed, err := seal(key, []byte(plaintext), []byte(keyRef))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + keyRef + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает значение, зашифрованное Seal. Незашифрованное значение возвращается как есть
+func Open(key []byte, value string) (string, error) {
+	keyRef, payload, ok := parse(value)
+	if !ok {
+		if IsSealed(value) {
+			return "", ErrInvalidValue
+		}
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidValue
+	}
+	plaintext, err := open(key, data, []byte(keyRef))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed проверяет, что значение зашифровано
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// KeyRef возвращает ссылку на ключ данных зашифрованного значения
+func KeyRef(value string) (string, bool) {
+	keyRef, _, ok := parse(value)
+	return keyRef, ok
+}
+
+// parse разделяет зашифрованное значение на ссылку на ключ и base64 шифротекста
+func parse(value string) (keyRef, payload string, ok bool) {
+	if !IsSealed(value) {
+		return "", "", false
+	}
+	keyRef, payload, found := strings.Cut(value[len(sealedPrefix):], ":")
+	if !found || keyRef == "" || payload == "" {
+		return "", "", false
+	}
+	return keyRef, payload, true
+}
+
+// seal шифрует AES-256-GCM со случайным nonce перед шифротекстом
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// open расшифровывает результат seal
+func open(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrInvalidValue
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, ErrInvalidValue
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
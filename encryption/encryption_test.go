@@ -0,0 +1,146 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, KeySize))
+}
+
+func TestParseKeyring(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		current     string
+		wantCurrent string
+		wantErr     string
+	}{
+		{"not configured", "", "", "", ""},
+		{"first key is current", "k1:" + testKey(1) + ", k2:" + testKey(2), "", "k1", ""},
+		{"explicit current", "k1:" + testKey(1) + ",k2:" + testKey(2), "k2", "k2", ""},
+		{"unknown current", "k1:" + testKey(1), "k9", "", "not in the key list"},
+		{"missing id", testKey(1), "", "", "expected id:base64"},
+		{"short key", "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), "", "", "expected 32 bytes"},
+		{"duplicate id", "k1:" + testKey(1) + ",k1:" + testKey(2), "", "", "duplicate id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring(tt.spec, tt.current)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseKeyring() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKeyring() error = %v", err)
+			}
+			if tt.wantCurrent == "" {
+				if keyring != nil {
+					t.Errorf("keyring = %+v, want nil", keyring)
+				}
+				return
+			}
+			if keyring.CurrentID() != tt.wantCurrent {
+				t.Errorf("current = %s, want %s", keyring.CurrentID(), tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestWrapKeyRotation(t *testing.T) {
+	old, err := ParseKeyring("k1:"+testKey(1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterID, wrapped, err := old.WrapKey(dataKey)
+	if err != nil || masterID != "k1" {
+		t.Fatalf("WrapKey() = %s, %v", masterID, err)
+	}
+
+	// После смены мастер-ключа ранее обернутые ключи читаются прежним ключом из списка
+	rotated, err := ParseKeyring("k1:"+testKey(1)+",k2:"+testKey(2), "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		keyring  *Keyring
+		masterID string
+		wrapped  string
+		wantErr  error
+	}{
+		{"same keyring", old, "k1", wrapped, nil},
+		{"after rotation", rotated, "k1", wrapped, nil},
+		{"wrong master key", rotated, "k2", wrapped, ErrInvalidValue},
+		{"removed master key", old, "k2", wrapped, ErrUnknownKey},
+		{"corrupted", old, "k1", wrapped[:len(wrapped)-4] + "AAAA", ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.UnwrapKey(tt.masterID, tt.wrapped)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UnwrapKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && !bytes.Equal(got, dataKey) {
+				t.Errorf("unwrapped key differs from the original")
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	key, _ := GenerateKey()
+	otherKey, _ := GenerateKey()
+	plaintext := "<Артикул>MS-500</Артикул>"
+	sealed, err := Seal(key, "p7", plaintext)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "MS-500") {
+		t.Fatalf("sealed value = %q", sealed)
+	}
+	if ref, ok := KeyRef(sealed); !ok || ref != "p7" {
+		t.Errorf("KeyRef() = %q, %v", ref, ok)
+	}
+	// Шифротекст нельзя перенести под ссылку другого ключа
+	moved := strings.Replace(sealed, ":p7:", ":p8:", 1)
+
+	tests := []struct {
+		name    string
+		key     []byte
+		value   string
+		want    string
+		wantErr error
+	}{
+		{"round trip", key, sealed, plaintext, nil},
+		{"plain value passes through", key, "plain", "plain", nil},
+		{"wrong key", otherKey, sealed, "", ErrInvalidValue},
+		{"key reference tampered", key, moved, "", ErrInvalidValue},
+		{"truncated", key, "enc:v1:p7", "", ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Open(tt.key, tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Open() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if empty, err := Seal(key, "p7", ""); err != nil || empty != "" {
+		t.Errorf("Seal(empty) = %q, %v; want empty value unencrypted", empty, err)
+	}
+}
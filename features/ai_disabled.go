@@ -0,0 +1,6 @@
+//go:build no_ai
+
+package features
+
+// AI сборка содержит обращения к AI провайдерам
+const AI = false
@@ -0,0 +1,53 @@
+// Package features описывает подсистемы, включенные в бинарный файл при сборке.
+//
+// Профили сборки задаются build-тегами:
+//
+//	full      go build main.go                              GUI (fyne) и AI подсистемы; окно открывается при USE_GUI=true
+//	headless  go build -tags no_gui main_no_gui.go          HTTP сервер без fyne; статистика пишется в лог
+//	no-ai     go build -tags no_gui,no_ai main_no_gui.go    сервер без обращений к AI провайдерам: нормализация
+//	                                                        только по правилам, AI эндпоинты отвечают 503 ai_disabled
+//	cli       go build -tags no_gui,no_ai ./cmd/<tool>      утилиты командной строки без GUI и AI
+//
+// Тег no_gui исключает пакет gui и зависимость fyne. Тег no_ai подменяет реализацию запросов к AI провайдерам
+// (nomenclature.AIClient, ArliaiClient) заглушкой, отвечающей ErrAIDisabled, поэтому код запросов не компилируется.
+// Итоговый набор подсистем сообщает GET /api/config
+package features
+
+import "httpserver/apperrors"
+
+// ErrAIDisabled возвращается вместо обращения к AI провайдеру в сборке с тегом no_ai
+var ErrAIDisabled = apperrors.Unavailable("ai_disabled", "AI subsystem is not included in this build (built with -tags no_ai)")
+
+// Flags подсистемы, включенные при сборке
+type Flags struct {
+	Profile string   `json:"profile"`
+	GUI     bool     `json:"gui"`
+	AI      bool     `json:"ai"`
+	Tags    []string `json:"tags"`
+}
+
+// Current возвращает подсистемы текущей сборки
+func Current() Flags {
+	flags := Flags{Profile: Profile(), GUI: GUI, AI: AI, Tags: []string{}}
+	if !GUI {
+		flags.Tags = append(flags.Tags, "no_gui")
+	}
+	if !AI {
+		flags.Tags = append(flags.Tags, "no_ai")
+	}
+	return flags
+}
+
+// Profile возвращает имя профиля сборки
+func Profile() string {
+	switch {
+	case GUI && AI:
+		return "full"
+	case AI:
+		return "headless"
+	case GUI:
+		return "gui-no-ai"
+	default:
+		return "no-ai"
+	}
+}
@@ -0,0 +1,38 @@
+package features
+
+import (
+	"testing"
+
+	"httpserver/apperrors"
+)
+
+func TestCurrent(t *testing.T) {
+	flags := Current()
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"gui flag", flags.GUI == GUI},
+		{"ai flag", flags.AI == AI},
+		{"profile", flags.Profile == Profile() && flags.Profile != ""},
+		{"tags", len(flags.Tags) == boolCount(!GUI, !AI)},
+		{"disabled error kind", apperrors.KindOf(ErrAIDisabled) == apperrors.KindUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.ok {
+				t.Errorf("Current() = %+v", flags)
+			}
+		})
+	}
+}
+
+func boolCount(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,6 @@
+//go:build !no_gui
+
+package features
+
+// GUI сборка содержит графический интерфейс (пакет gui, fyne)
+const GUI = true
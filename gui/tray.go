@@ -0,0 +1,85 @@
+//go:build !no_gui
+
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"httpserver/i18n"
+)
+
+// TrayActions действия меню в области уведомлений
+type TrayActions struct {
+	// StopAfterUploads останавливает сервер после завершения текущих выгрузок; вызывается один раз
+	StopAfterUploads func()
+}
+
+// tray меню в области уведомлений; nil, если платформа не поддерживает область уведомлений
+type tray struct {
+	menu       *fyne.Menu
+	statusItem *fyne.MenuItem
+	stopItem   *fyne.MenuItem
+}
+
+// EnableTray отвязывает работу сервера от окна: закрытие окна скрывает его, а не завершает приложение.
+// Если платформа поддерживает область уведомлений, окно сворачивается в нее, меню показывает статус
+// и позволяет остановить сервер после текущей выгрузки. Возвращает false без области уведомлений:
+// скрытое окно тогда не вернуть, сервер останавливается сигналом (Ctrl+C)
+func (w *Window) EnableTray(actions TrayActions) bool {
+	desk, ok := w.app.(desktop.App)
+	if !ok {
+		w.window.SetCloseIntercept(func() {
+			w.window.Hide()
+			w.AddLog(i18n.T(w.lang, "gui.hidden_no_tray"))
+		})
+		return false
+	}
+
+	t := &tray{
+		statusItem: fyne.NewMenuItem(i18n.T(w.lang, "gui.tray_status", i18n.T(w.lang, "gui.running"), 0), nil),
+	}
+	t.statusItem.Disabled = true
+	t.stopItem = fyne.NewMenuItem(i18n.T(w.lang, "gui.tray_stop_after_upload"), func() {
+		t.stopItem.Disabled = true
+		t.menu.Refresh()
+		w.SetStatus(i18n.T(w.lang, "gui.stopping_after_uploads"))
+		if actions.StopAfterUploads != nil {
+			go actions.StopAfterUploads()
+		}
+	})
+	quitItem := fyne.NewMenuItem(i18n.T(w.lang, "gui.tray_quit"), nil)
+	quitItem.IsQuit = true
+	t.menu = fyne.NewMenu(i18n.T(w.lang, "gui.title"),
+		t.statusItem,
+		fyne.NewMenuItem(i18n.T(w.lang, "gui.tray_show"), w.window.Show),
+		fyne.NewMenuItemSeparator(),
+		t.stopItem,
+		quitItem,
+	)
+	desk.SetSystemTrayMenu(t.menu)
+	desk.SetSystemTrayWindow(w.window)
+
+	w.tray = t
+	w.window.SetCloseIntercept(func() {
+		w.window.Hide()
+		w.AddLog(i18n.T(w.lang, "gui.hidden_to_tray"))
+	})
+	return true
+}
+
+// updateTrayStatus обновляет строку статуса в меню области уведомлений
+func (w *Window) updateTrayStatus(state string, activeUploads interface{}) {
+	if w.tray == nil {
+		return
+	}
+	label := i18n.T(w.lang, "gui.tray_status", state, activeUploads)
+	fyne.Do(func() {
+		w.tray.statusItem.Label = label
+		w.tray.menu.Refresh()
+	})
+}
+
+// Quit завершает приложение: ShowAndRun возвращает управление, после чего сервер останавливается
+func (w *Window) Quit() {
+	fyne.Do(w.app.Quit)
+}
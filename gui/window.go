@@ -1,3 +1,5 @@
+//go:build !no_gui
+
 package gui
 
 import (
@@ -10,6 +12,7 @@ import (
 	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/widget"
 	"fyne.io/fyne/v2"
+	"httpserver/i18n"
 	"httpserver/server"
 )
 
@@ -20,6 +23,7 @@ type Window struct {
 	logList    *widget.List
 	statsLabel *widget.Label
 	statusLabel *widget.Label
+	lang       string // Язык интерфейса (APP_LANGUAGE)
 	
 	// Данные
 	logData    binding.StringList
@@ -28,17 +32,22 @@ type Window struct {
 	
 	// Каналы
 	logChan    <-chan server.LogEntry
+
+	// Меню в области уведомлений (EnableTray)
+	tray *tray
 }
 
 // NewWindow создает новое окно
 func NewWindow(logChan <-chan server.LogEntry) *Window {
+	lang := i18n.FromEnv()
 	myApp := app.New()
-	myWindow := myApp.NewWindow("1C HTTP Server")
+	myWindow := myApp.NewWindow(i18n.T(lang, "gui.title"))
 	myWindow.Resize(fyne.NewSize(800, 600))
 	
 	w := &Window{
 		app:        myApp,
 		window:     myWindow,
+		lang:       lang,
 		logData:    binding.NewStringList(),
 		statsData:  binding.NewString(),
 		statusData: binding.NewString(),
@@ -54,19 +63,19 @@ func NewWindow(logChan <-chan server.LogEntry) *Window {
 // setupUI настраивает пользовательский интерфейс
 func (w *Window) setupUI() {
 	// Заголовок
-	title := widget.NewLabel("1C HTTP Server")
+	title := widget.NewLabel(i18n.T(w.lang, "gui.title"))
 	title.TextStyle.Bold = true
 	
 	// Статус сервера
 	w.statusLabel = widget.NewLabelWithData(w.statusData)
-	w.statusData.Set("Сервер запущен")
+	w.statusData.Set(i18n.T(w.lang, "gui.server_running"))
 	
 	// Статистика
 	w.statsLabel = widget.NewLabelWithData(w.statsData)
-	w.statsData.Set("Статистика загружается...")
+	w.statsData.Set(i18n.T(w.lang, "gui.stats_loading"))
 	
 	// Лог запросов
-	logTitle := widget.NewLabel("Лог запросов:")
+	logTitle := widget.NewLabel(i18n.T(w.lang, "gui.request_log"))
 	logTitle.TextStyle.Bold = true
 	
 	w.logList = widget.NewListWithData(
@@ -81,15 +90,15 @@ func (w *Window) setupUI() {
 	)
 	
 	// Кнопки управления
-	refreshBtn := widget.NewButton("Обновить статистику", w.refreshStats)
-	clearLogBtn := widget.NewButton("Очистить лог", w.clearLog)
+	refreshBtn := widget.NewButton(i18n.T(w.lang, "gui.refresh_stats"), w.refreshStats)
+	clearLogBtn := widget.NewButton(i18n.T(w.lang, "gui.clear_log"), w.clearLog)
 	
 	// Компоновка
 	statusContainer := container.NewVBox(
-		widget.NewLabel("Статус:"),
+		widget.NewLabel(i18n.T(w.lang, "gui.status")),
 		w.statusLabel,
 		widget.NewSeparator(),
-		widget.NewLabel("Статистика:"),
+		widget.NewLabel(i18n.T(w.lang, "gui.stats")),
 		w.statsLabel,
 		widget.NewSeparator(),
 		container.NewHBox(refreshBtn, clearLogBtn),
@@ -137,16 +146,12 @@ func (w *Window) startListeners() {
 
 // updateStats обновляет статистику
 func (w *Window) updateStats(stats server.ServerStats) {
-	statsText := fmt.Sprintf(`Статус: %s
-Последняя активность: %s
-
-Общая статистика:
-• Всего выгрузок: %v
-• Активных выгрузок: %v
-• Всего констант: %v
-• Всего справочников: %v
-• Всего элементов: %v`,
-		map[bool]string{true: "Работает", false: "Остановлен"}[stats.IsRunning],
+	state := i18n.T(w.lang, "gui.stopped")
+	if stats.IsRunning {
+		state = i18n.T(w.lang, "gui.running")
+	}
+	statsText := i18n.T(w.lang, "gui.stats_text",
+		state,
 		stats.LastActivity.Format("15:04:05"),
 		stats.TotalStats["total_uploads"],
 		stats.TotalStats["active_uploads"],
@@ -155,16 +160,7 @@ func (w *Window) updateStats(stats server.ServerStats) {
 		stats.TotalStats["total_items"])
 	
 	if stats.CurrentUpload != nil {
-		statsText += fmt.Sprintf(`
-
-Текущая выгрузка:
-• UUID: %s
-• Статус: %s
-• Версия 1С: %s
-• Конфигурация: %s
-• Константы: %d
-• Справочники: %d
-• Элементы: %d`,
+		statsText += i18n.T(w.lang, "gui.current_upload",
 			stats.CurrentUpload.UploadUUID,
 			stats.CurrentUpload.Status,
 			stats.CurrentUpload.Version1C,
@@ -175,6 +171,7 @@ func (w *Window) updateStats(stats server.ServerStats) {
 	}
 	
 	w.statsData.Set(statsText)
+	w.updateTrayStatus(state, stats.TotalStats["active_uploads"])
 }
 
 // refreshStats обновляет статистику
@@ -189,7 +186,7 @@ func (w *Window) clearLog() {
 	log.Println("Лог очищен")
 }
 
-// ShowAndRun показывает окно и запускает приложение
+// ShowAndRun показывает окно и запускает приложение; возвращает управление после Quit
 func (w *Window) ShowAndRun() {
 	w.window.ShowAndRun()
 }
@@ -0,0 +1,179 @@
+// Package i18n содержит каталоги сообщений (ru, en, kk) для ответов API, отчетов и GUI
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые языки
+const (
+	Russian = "ru"
+	English = "en"
+	Kazakh  = "kk"
+
+	// Default язык по умолчанию (исходный язык сообщений)
+	Default = Russian
+)
+
+// supported поддерживаемые языки в порядке отображения
+var supported = []string{Russian, English, Kazakh}
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// catalog каталог сообщений одного языка
+type catalog struct {
+	Name     string            `json:"name"`     // Название языка на самом языке
+	Messages map[string]string `json:"messages"` // Сообщения по ключам
+	Errors   map[string]string `json:"errors"`   // Переводы исходных текстов ошибок API
+}
+
+var catalogs = loadCatalogs()
+
+// loadCatalogs загружает встроенные каталоги; ошибка в каталоге - ошибка сборки, поэтому паника
+func loadCatalogs() map[string]*catalog {
+	result := make(map[string]*catalog, len(supported))
+	for _, lang := range supported {
+		data, err := localesFS.ReadFile("locales/" + lang + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog %s: %v", lang, err))
+		}
+		c := &catalog{}
+		if err := json.Unmarshal(data, c); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", lang, err))
+		}
+		result[lang] = c
+	}
+	return result
+}
+
+// Language описание поддерживаемого языка
+type Language struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// Languages возвращает поддерживаемые языки
+func Languages() []Language {
+	languages := make([]Language, 0, len(supported))
+	for _, lang := range supported {
+		languages = append(languages, Language{Code: lang, Name: catalogs[lang].Name})
+	}
+	return languages
+}
+
+// Normalize приводит тег языка (ru-RU, EN, kk_KZ) к поддерживаемому коду; пустая строка, если язык не поддерживается
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if idx := strings.IndexAny(lang, "-_"); idx >= 0 {
+		lang = lang[:idx]
+	}
+	if _, ok := catalogs[lang]; ok {
+		return lang
+	}
+	return ""
+}
+
+// ParseAcceptLanguage выбирает поддерживаемый язык из заголовка Accept-Language с учетом весов q
+func ParseAcceptLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := Normalize(fields[0])
+		if lang == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = value
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// T возвращает сообщение по ключу на языке lang (с откатом на язык по умолчанию); args подставляются через fmt
+func T(lang, key string, args ...interface{}) string {
+	message, ok := lookup(lang, key)
+	if !ok {
+		message = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// lookup ищет сообщение в каталоге языка, затем в каталоге по умолчанию
+func lookup(lang, key string) (string, bool) {
+	if c, ok := catalogs[Normalize(lang)]; ok {
+		if message, ok := c.Messages[key]; ok {
+			return message, true
+		}
+	}
+	message, ok := catalogs[Default].Messages[key]
+	return message, ok
+}
+
+// TranslateError переводит исходный текст ошибки API. Для сообщений вида "Текст: подробности"
+// переводится часть до двоеточия; неизвестные сообщения возвращаются без изменений.
+func TranslateError(lang, message string) string {
+	c, ok := catalogs[Normalize(lang)]
+	if !ok {
+		return message
+	}
+	if translated, ok := c.Errors[message]; ok {
+		return translated
+	}
+	if idx := strings.Index(message, ": "); idx > 0 {
+		if translated, ok := c.Errors[message[:idx]]; ok {
+			return translated + message[idx:]
+		}
+	}
+	return message
+}
+
+type contextKey struct{}
+
+// WithLanguage сохраняет язык в контексте
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext возвращает язык из контекста или язык по умолчанию
+func FromContext(ctx context.Context) string {
+	if lang, ok := ctx.Value(contextKey{}).(string); ok && lang != "" {
+		return lang
+	}
+	return Default
+}
+
+// FromEnv возвращает язык приложения из переменной окружения APP_LANGUAGE или язык по умолчанию
+func FromEnv() string {
+	if lang := Normalize(os.Getenv("APP_LANGUAGE")); lang != "" {
+		return lang
+	}
+	return Default
+}
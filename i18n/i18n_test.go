@@ -0,0 +1,94 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogsComplete(t *testing.T) {
+	for _, lang := range supported {
+		if lang == Default {
+			continue
+		}
+		t.Run(lang, func(t *testing.T) {
+			for key, message := range catalogs[Default].Messages {
+				translated, ok := catalogs[lang].Messages[key]
+				if !ok {
+					t.Errorf("missing message %q", key)
+					continue
+				}
+				if strings.Count(translated, "%") != strings.Count(message, "%") {
+					t.Errorf("message %q: placeholders differ: %q vs %q", key, translated, message)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"en-US,en;q=0.9", English},
+		{"kk-KZ", Kazakh},
+		{"de-DE,fr;q=0.8", ""},
+		{"de-DE,en;q=0.5,kk;q=0.7", Kazakh},
+		{"ru;q=0, en", English},
+		{"RU_ru", Russian},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header); got != tt.want {
+				t.Errorf("ParseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		key  string
+		args []interface{}
+		want string
+	}{
+		{"russian", Russian, "report.total", nil, "Итого"},
+		{"english", English, "report.total", nil, "Total"},
+		{"unknown language falls back to default", "de", "report.total", nil, "Итого"},
+		{"unknown key", English, "no.such.key", nil, "no.such.key"},
+		{"arguments", English, "report.top_categories", []interface{}{20}, "Top 20 categories"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := T(tt.lang, tt.key, tt.args...); got != tt.want {
+				t.Errorf("T() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name    string
+		lang    string
+		message string
+		want    string
+	}{
+		{"exact", Russian, "Project not found", "Проект не найден"},
+		{"with details", Kazakh, "Failed to open database: disk I/O error", "Дерекқорды ашу мүмкін болмады: disk I/O error"},
+		{"russian source", English, "Переклассификация уже выполняется", "Reclassification is already running"},
+		{"unknown message", Russian, "Something odd happened", "Something odd happened"},
+		{"english keeps source", English, "Project not found", "Project not found"},
+		{"unsupported language", "de", "Project not found", "Project not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TranslateError(tt.lang, tt.message); got != tt.want {
+				t.Errorf("TranslateError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"httpserver/client"
+	"httpserver/database"
+	"httpserver/server"
+)
+
+// TestClientUploadRoundTrip проходит полный цикл выгрузки через пакет client, сверку и чтение данных
+func TestClientUploadRoundTrip(t *testing.T) {
+	protocol, unifiedDB := newProtocolServer(t)
+	api, err := client.New(client.Config{BaseURL: protocol.baseURL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	upload, err := api.StartUpload(ctx, client.HandshakeRequest{
+		DatabaseID:   "7",
+		Version1C:    "8.3.24",
+		ConfigName:   "УправлениеТорговлей",
+		ComputerName: "BUH-01",
+	})
+	if err != nil {
+		t.Fatalf("StartUpload() error = %v", err)
+	}
+	if !upload.Response.Success || upload.UUID() == "" || upload.Response.ProtocolVersion != client.ProtocolVersionCurrent {
+		t.Fatalf("handshake response = %+v", upload.Response)
+	}
+
+	if _, err := upload.SendMetadata(ctx, &client.MetadataRequest{Catalogs: []client.MetadataCatalog{{
+		Name:       "Номенклатура",
+		Attributes: []client.MetadataAttribute{{Name: "Артикул", Type: "Строка", Length: 25}},
+	}}}); err != nil {
+		t.Fatalf("SendMetadata() error = %v", err)
+	}
+	if _, err := upload.SendConstant(ctx, &client.ConstantRequest{
+		Name: "ОсновнаяВалюта", Synonym: "Основная валюта", Type: "Строка", Value: client.ConstantValue{Content: "KZT"},
+	}); err != nil {
+		t.Fatalf("SendConstant() error = %v", err)
+	}
+	if _, err := upload.SendCatalogMeta(ctx, &client.CatalogMetaRequest{Name: "Номенклатура", Synonym: "Номенклатура"}); err != nil {
+		t.Fatalf("SendCatalogMeta() error = %v", err)
+	}
+	items, err := upload.SendCatalogItems(ctx, "Номенклатура", []client.CatalogItem{
+		{Reference: "ref-1", Code: "00001", Name: "Молоток слесарный", Attributes: client.XMLContent{Content: "<Артикул>A-1</Артикул>"}},
+		{Reference: "ref-2", Code: "00002", Name: "Болт М8х40", Attributes: client.XMLContent{Content: "<Артикул>A-2</Артикул>"}},
+	})
+	if err != nil || items.ProcessedCount != 2 || items.FailedCount != 0 {
+		t.Fatalf("SendCatalogItems() = %+v, error = %v", items, err)
+	}
+	if _, err := upload.SendCatalogItem(ctx, &client.CatalogItemRequest{
+		CatalogName: "Номенклатура", Reference: "ref-3", Code: "00003", Name: "Лампа LED E27",
+	}); err != nil {
+		t.Fatalf("SendCatalogItem() error = %v", err)
+	}
+	if _, err := upload.Complete(ctx); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	assertUploadCounters(t, unifiedDB, upload.UUID(), 1, 1, 3)
+
+	// Сверка по элементам справочников: получатель досылает недостающие ID
+	t.Run("verify", func(t *testing.T) {
+		first, err := api.VerifyUpload(ctx, upload.UUID(), nil)
+		if err != nil || first.IsComplete || first.ExpectedTotal != 3 || len(first.MissingIDs) != 3 {
+			t.Fatalf("VerifyUpload(nil) = %+v, error = %v", first, err)
+		}
+		second, err := api.VerifyUpload(ctx, upload.UUID(), first.MissingIDs)
+		if err != nil || !second.IsComplete || len(second.MissingIDs) != 0 {
+			t.Errorf("VerifyUpload(all) = %+v, error = %v", second, err)
+		}
+	})
+
+	t.Run("data retrieval", func(t *testing.T) {
+		list, err := api.ListUploads(ctx, client.UploadListOptions{Status: "completed", Limit: 10})
+		if err != nil || list.Limit != 10 {
+			t.Errorf("ListUploads() = %+v, error = %v", list, err)
+		}
+		data, err := api.GetUploadData(ctx, upload.UUID(), client.DataOptions{Type: "constants", Page: 1, Limit: 50})
+		if err != nil {
+			t.Fatalf("GetUploadData() error = %v", err)
+		}
+		if data.UploadUUID != upload.UUID() || data.Type != "constants" || data.Page != 1 || data.Limit != 50 {
+			t.Errorf("GetUploadData() = %+v", data)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := api.GetUpload(ctx, "missing-upload"); !client.IsStatus(err, http.StatusNotFound) {
+			t.Errorf("GetUpload(missing) error = %v, want 404", err)
+		}
+		_, err := api.Handshake(ctx, &client.HandshakeRequest{ConfigName: "УТ", ProtocolVersion: "abc"})
+		if !client.IsStatus(err, http.StatusBadRequest) {
+			t.Errorf("Handshake(invalid version) error = %v, want 400", err)
+		}
+	})
+}
+
+// TestClientUploadCompleteness проверяет сверку полноты при /complete: объявленные количества и непрерывность пакетов
+func TestClientUploadCompleteness(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		wantStatus string
+		wantCode   int
+	}{
+		{"warn", server.UploadCompletenessWarn, database.UploadStatusCompletedWithWarnings, http.StatusOK},
+		{"strict", server.UploadCompletenessStrict, "in_progress", http.StatusConflict},
+		{"off", server.UploadCompletenessOff, "completed", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol, unifiedDB := newProtocolServer(t, func(c *server.Config) { c.UploadCompletenessMode = tt.mode })
+			api, err := client.New(client.Config{BaseURL: protocol.baseURL})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			ctx := context.Background()
+
+			upload, err := api.StartUpload(ctx, client.HandshakeRequest{DatabaseID: "7", Version1C: "8.3.24", ConfigName: "УправлениеТорговлей"})
+			if err != nil {
+				t.Fatalf("StartUpload() error = %v", err)
+			}
+			expectedItems, expectedConstants := 3, 0
+			if _, err := upload.SendMetadata(ctx, &client.MetadataRequest{
+				ExpectedConstants: &expectedConstants,
+				Catalogs:          []client.MetadataCatalog{{Name: "Номенклатура", ExpectedItems: &expectedItems}},
+			}); err != nil {
+				t.Fatalf("SendMetadata() error = %v", err)
+			}
+			// Пакет 2 потерян: приняты пакеты 1 и 3
+			for _, batch := range []int{1, 3} {
+				ref := fmt.Sprintf("ref-%d", batch)
+				if _, err := upload.SendCatalogItemsBatch(ctx, "Номенклатура", batch, []client.CatalogItem{{Reference: ref, Code: ref, Name: "Молоток"}}); err != nil {
+					t.Fatalf("SendCatalogItemsBatch(%d) error = %v", batch, err)
+				}
+			}
+
+			resp, err := upload.Complete(ctx)
+			var discrepancies []client.CompletenessDiscrepancy
+			if tt.wantCode == http.StatusConflict {
+				var apiErr *client.APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || apiErr.Code != "upload_incomplete" {
+					t.Fatalf("Complete() error = %v, want 409 upload_incomplete", err)
+				}
+				discrepancies = apiErr.Discrepancies
+			} else {
+				if err != nil {
+					t.Fatalf("Complete() error = %v", err)
+				}
+				if resp.Status != tt.wantStatus {
+					t.Errorf("Complete() status = %q, want %q", resp.Status, tt.wantStatus)
+				}
+				discrepancies = resp.Discrepancies
+			}
+
+			if tt.mode != server.UploadCompletenessOff {
+				if len(discrepancies) != 2 ||
+					discrepancies[0].Kind != client.DiscrepancyItemsCount || discrepancies[0].Expected != 3 || discrepancies[0].Actual != 2 ||
+					discrepancies[1].Kind != client.DiscrepancyMissingBatches || len(discrepancies[1].MissingBatches) != 1 || discrepancies[1].MissingBatches[0] != 2 {
+					t.Errorf("discrepancies = %+v, want items count 3/2 and missing batch 2", discrepancies)
+				}
+			} else if len(discrepancies) != 0 {
+				t.Errorf("discrepancies = %+v, want none with check disabled", discrepancies)
+			}
+
+			stored, err := unifiedDB.GetUploadByUUID(upload.UUID())
+			if err != nil || stored.Status != tt.wantStatus {
+				t.Fatalf("stored upload status = %v (err %v), want %s", stored, err, tt.wantStatus)
+			}
+			if tt.wantCode != http.StatusConflict {
+				return
+			}
+			// После досылки пропущенного пакета завершение проходит
+			if _, err := upload.SendCatalogItemsBatch(ctx, "Номенклатура", 2, []client.CatalogItem{{Reference: "ref-2", Code: "ref-2", Name: "Молоток"}}); err != nil {
+				t.Fatalf("SendCatalogItemsBatch(2) error = %v", err)
+			}
+			resp, err = upload.Complete(ctx)
+			if err != nil || resp.Status != "completed" || len(resp.Discrepancies) != 0 {
+				t.Errorf("Complete() after resend = %+v, error = %v", resp, err)
+			}
+		})
+	}
+}
@@ -60,7 +60,7 @@ func TestFullNormalizationFlow(t *testing.T) {
 		NormalizerEventsBufferSize: 100,
 	}
 
-	srv := server.NewServerWithConfig(db, db, serviceDB, ":memory:", ":memory:", config)
+	srv := server.NewServerWithConfig(db, db, serviceDB, nil, ":memory:", ":memory:", config)
 
 	// Шаг 1: Начинаем нормализацию через API
 	t.Run("StartNormalization", func(t *testing.T) {
@@ -199,7 +199,7 @@ func TestNormalizationWithRevert(t *testing.T) {
 		NormalizerEventsBufferSize: 100,
 	}
 
-	srv := server.NewServerWithConfig(db, db, serviceDB, ":memory:", ":memory:", config)
+	srv := server.NewServerWithConfig(db, db, serviceDB, nil, ":memory:", ":memory:", config)
 
 	// Создаем сессию и применяем паттерны
 	var itemID int
@@ -319,7 +319,7 @@ func TestClassificationIntegration(t *testing.T) {
 		NormalizerEventsBufferSize: 100,
 	}
 
-	srv := server.NewServerWithConfig(db, db, serviceDB, ":memory:", ":memory:", config)
+	srv := server.NewServerWithConfig(db, db, serviceDB, nil, ":memory:", ":memory:", config)
 
 	reqBody := map[string]interface{}{
 		"name": "молоток",
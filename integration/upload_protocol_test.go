@@ -0,0 +1,280 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"httpserver/database"
+	"httpserver/server"
+)
+
+// protocolClient имитирует обработку выгрузки 1С: отправляет XML-запросы протокола на тестовый сервер
+type protocolClient struct {
+	t       *testing.T
+	baseURL string
+}
+
+// post отправляет XML и возвращает тело ответа, проверяя статус 200
+func (c *protocolClient) post(path, body string) []byte {
+	c.t.Helper()
+	resp, err := http.Post(c.baseURL+path, "application/xml; charset=utf-8", bytes.NewBufferString(xml.Header+body))
+	if err != nil {
+		c.t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		c.t.Fatalf("POST %s status = %d: %s", path, resp.StatusCode, data)
+	}
+	return data
+}
+
+// handshake выполняет рукопожатие и возвращает upload_uuid
+func (c *protocolClient) handshake(path string) string {
+	c.t.Helper()
+	var resp server.HandshakeResponse
+	body := c.post(path, `<handshake>
+		<database_id>7</database_id>
+		<version_1c>8.3.24</version_1c>
+		<config_name>УправлениеТорговлей</config_name>
+		<computer_name>BUH-01</computer_name>
+		<user_name>Иванов</user_name>
+		<timestamp>2024-05-01T10:00:00</timestamp>
+	</handshake>`)
+	if err := xml.Unmarshal(body, &resp); err != nil || !resp.Success || resp.UploadUUID == "" {
+		c.t.Fatalf("unexpected handshake response (%v): %s", err, body)
+	}
+	return resp.UploadUUID
+}
+
+// verify выполняет сверку переданных элементов через /api/uploads/{uuid}/verify
+func (c *protocolClient) verify(uploadUUID string, receivedIDs []int) server.VerifyResponse {
+	c.t.Helper()
+	payload, _ := json.Marshal(server.VerifyRequest{ReceivedIDs: receivedIDs})
+	resp, err := http.Post(c.baseURL+"/api/uploads/"+uploadUUID+"/verify", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		c.t.Fatalf("verify: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		c.t.Fatalf("verify status = %d: %s", resp.StatusCode, data)
+	}
+	var result server.VerifyResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		c.t.Fatalf("failed to decode verify response: %v", err)
+	}
+	return result
+}
+
+// newProtocolServer поднимает сервер с реальными БД в файлах временного каталога;
+// configure меняют конфигурацию сервера перед запуском
+func newProtocolServer(t *testing.T, configure ...func(*server.Config)) (*protocolClient, *database.DB) {
+	dir := t.TempDir()
+	db, err := database.NewDB(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	serviceDB, err := database.NewServiceDB(filepath.Join(dir, "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	unifiedDB, err := database.NewUnifiedDBWithConfig(filepath.Join(dir, "unified_catalogs.db"), database.DBConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+
+	config := &server.Config{
+		Port:                       "0",
+		DatabasePath:               filepath.Join(dir, "data.db"),
+		UnifiedCatalogsDBPath:      filepath.Join(dir, "unified_catalogs.db"),
+		LogBufferSize:              1000,
+		NormalizerEventsBufferSize: 100,
+	}
+	for _, fn := range configure {
+		fn(config)
+	}
+	srv := server.NewServerWithConfig(db, db, serviceDB, unifiedDB, config.DatabasePath, config.DatabasePath, config)
+	ts := httptest.NewServer(srv)
+
+	t.Cleanup(func() {
+		ts.Close()
+		unifiedDB.Close()
+		serviceDB.Close()
+		db.Close()
+	})
+	return &protocolClient{t: t, baseURL: ts.URL}, unifiedDB
+}
+
+// waitQualityMetrics ждет, пока фоновый анализ качества запишет метрики выгрузки
+func waitQualityMetrics(t *testing.T, db *database.DB, uploadID int) []database.DataQualityMetric {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		metrics, err := db.GetQualityMetrics(uploadID)
+		if err == nil && len(metrics) > 0 {
+			return metrics
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("quality analysis was not triggered for upload %d (err: %v)", uploadID, err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// assertUploadCounters проверяет статус и счетчики выгрузки в единой БД
+func assertUploadCounters(t *testing.T, db *database.DB, uploadUUID string, constants, catalogs, items int) *database.Upload {
+	t.Helper()
+	upload, err := db.GetUploadByUUID(uploadUUID)
+	if err != nil {
+		t.Fatalf("GetUploadByUUID() error = %v", err)
+	}
+	if upload.Status != "completed" {
+		t.Errorf("status = %q, want completed", upload.Status)
+	}
+	if upload.TotalConstants != constants || upload.TotalCatalogs != catalogs || upload.TotalItems != items {
+		t.Errorf("counters = constants %d, catalogs %d, items %d; want %d, %d, %d",
+			upload.TotalConstants, upload.TotalCatalogs, upload.TotalItems, constants, catalogs, items)
+	}
+	if upload.DatabaseID == nil || *upload.DatabaseID != 7 {
+		t.Errorf("database_id = %v, want 7", upload.DatabaseID)
+	}
+	return upload
+}
+
+// TestUploadProtocolLegacyEndpoints проходит полный цикл выгрузки справочников по старым эндпоинтам
+func TestUploadProtocolLegacyEndpoints(t *testing.T) {
+	client, unifiedDB := newProtocolServer(t)
+
+	uploadUUID := client.handshake("/handshake")
+	uuid := "<upload_uuid>" + uploadUUID + "</upload_uuid>"
+
+	client.post("/metadata", `<metadata>`+uuid+`
+		<version_1c>8.3.24</version_1c>
+		<config_name>УправлениеТорговлей</config_name>
+		<catalogs><catalog>
+			<name>Номенклатура</name>
+			<attributes>
+				<attribute><name>Артикул</name><type>Строка</type><length>25</length></attribute>
+				<attribute><name>Вес</name><type>Число</type><precision>15</precision><scale>3</scale></attribute>
+			</attributes>
+		</catalog></catalogs>
+	</metadata>`)
+
+	for i, constant := range []string{"ОсновнаяВалюта", "ИспользоватьХарактеристики"} {
+		client.post("/constant", fmt.Sprintf(`<constant>%s<name>%s</name><synonym>%s</synonym><type>Строка</type><value>%d</value></constant>`,
+			uuid, constant, constant, i))
+	}
+
+	client.post("/catalog/meta", `<catalog_meta>`+uuid+`<name>Номенклатура</name><synonym>Номенклатура</synonym>
+		<indexed_attributes><attribute>Артикул</attribute></indexed_attributes></catalog_meta>`)
+
+	// Два пакета /catalog/items и одиночный /catalog/item
+	batches := [][]string{{"Молоток слесарный", "Болт М8х40", "Кабель ВВГнг 3х2,5"}, {"Краска ПФ-115", "Перчатки х/б"}}
+	n := 0
+	for _, batch := range batches {
+		var items bytes.Buffer
+		for _, name := range batch {
+			n++
+			fmt.Fprintf(&items, `<item><reference>ref-%d</reference><code>%05d</code><name>%s</name>
+				<attributes_xml><Артикул>A-%d</Артикул><Вес>1,5</Вес></attributes_xml><table_parts></table_parts></item>`, n, n, name, n)
+		}
+		var resp server.CatalogItemsResponse
+		body := client.post("/catalog/items", `<catalog_items>`+uuid+`<catalog_name>Номенклатура</catalog_name><items>`+items.String()+`</items></catalog_items>`)
+		if err := xml.Unmarshal(body, &resp); err != nil || resp.ProcessedCount != len(batch) || resp.FailedCount != 0 {
+			t.Fatalf("unexpected /catalog/items response (%v): %s", err, body)
+		}
+	}
+	client.post("/catalog/item", `<catalog_item>`+uuid+`<catalog_name>Номенклатура</catalog_name>
+		<reference>ref-6</reference><code>00006</code><name>Лампа LED E27</name>
+		<attributes_xml><Артикул>A-6</Артикул></attributes_xml></catalog_item>`)
+
+	client.post("/complete", `<complete>`+uuid+`</complete>`)
+
+	t.Run("counters", func(t *testing.T) {
+		assertUploadCounters(t, unifiedDB, uploadUUID, 2, 1, 6)
+	})
+
+	t.Run("dynamic catalog table", func(t *testing.T) {
+		tableName, err := database.GetCatalogTableName(unifiedDB.GetDB(), "Номенклатура")
+		if err != nil {
+			t.Fatalf("catalog table not registered: %v", err)
+		}
+		var count int
+		var article string
+		if err := unifiedDB.QueryRow(fmt.Sprintf("SELECT COUNT(*), MAX(%s) FROM %s", database.CatalogAttributeColumnName("Артикул"), tableName)).Scan(&count, &article); err != nil {
+			t.Fatalf("failed to query %s: %v", tableName, err)
+		}
+		if count != 6 || article != "A-6" {
+			t.Errorf("%s: count = %d, max article = %q; want 6, A-6", tableName, count, article)
+		}
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		first := client.verify(uploadUUID, nil)
+		if first.IsComplete || first.ExpectedTotal == 0 || len(first.MissingIDs) != first.ExpectedTotal {
+			t.Fatalf("verify without received ids = %+v", first)
+		}
+		second := client.verify(uploadUUID, first.MissingIDs)
+		if !second.IsComplete || len(second.MissingIDs) != 0 {
+			t.Errorf("verify after resend = %+v, want complete", second)
+		}
+	})
+
+	t.Run("quality analysis", func(t *testing.T) {
+		upload := assertUploadCounters(t, unifiedDB, uploadUUID, 2, 1, 6)
+		waitQualityMetrics(t, unifiedDB, upload.ID)
+	})
+}
+
+// TestUploadProtocolV1Endpoints проходит цикл выгрузки номенклатуры по эндпоинтам /api/v1
+func TestUploadProtocolV1Endpoints(t *testing.T) {
+	client, unifiedDB := newProtocolServer(t)
+
+	uploadUUID := client.handshake("/api/v1/upload/handshake")
+	uuid := "<upload_uuid>" + uploadUUID + "</upload_uuid>"
+
+	client.post("/api/v1/upload/metadata", `<metadata>`+uuid+`<version_1c>8.3.24</version_1c><config_name>УправлениеТорговлей</config_name></metadata>`)
+
+	tests := []struct {
+		name  string
+		items []string
+	}{
+		{"first batch", []string{"Молоток слесарный", "Молоток слесарный.", "Болт М8х40"}},
+		{"second batch", []string{"Кабель ВВГнг 3х2,5", ""}},
+	}
+	n := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items bytes.Buffer
+			for _, name := range tt.items {
+				n++
+				fmt.Fprintf(&items, `<item><nomenclature_reference>nom-%d</nomenclature_reference><nomenclature_code>%05d</nomenclature_code>
+					<nomenclature_name>%s</nomenclature_name><characteristic_name>Базовая</characteristic_name></item>`, n, n, name)
+			}
+			var resp server.NomenclatureBatchResponse
+			body := client.post("/api/v1/upload/nomenclature/batch", `<nomenclature_batch>`+uuid+`<items>`+items.String()+`</items></nomenclature_batch>`)
+			if err := xml.Unmarshal(body, &resp); err != nil || resp.ProcessedCount != len(tt.items) {
+				t.Fatalf("unexpected nomenclature batch response (%v): %s", err, body)
+			}
+		})
+	}
+
+	client.post("/complete", `<complete>`+uuid+`</complete>`)
+
+	upload := assertUploadCounters(t, unifiedDB, uploadUUID, 0, 0, 5)
+	metrics := waitQualityMetrics(t, unifiedDB, upload.ID)
+	for _, metric := range metrics {
+		if metric.DatabaseID != 7 {
+			t.Errorf("metric %s database_id = %d, want 7", metric.MetricName, metric.DatabaseID)
+		}
+	}
+}
@@ -12,12 +12,19 @@ import (
 	"time"
 
 	"httpserver/database"
+	"httpserver/features"
 	"httpserver/gui"
+	"httpserver/i18n"
 	"httpserver/server"
 )
 
+// trayStopMaxWait наибольшее ожидание текущих выгрузок при остановке из области уведомлений:
+// выгрузка, прерванная на стороне 1С, остается незавершенной
+const trayStopMaxWait = 30 * time.Minute
+
 func main() {
 	log.Println("Запуск 1C HTTP Server...")
+	log.Printf("Профиль сборки: %s (GUI: %v, AI: %v)", features.Profile(), features.GUI, features.AI)
 
 	// Загружаем конфигурацию
 	config, err := server.LoadConfig()
@@ -28,7 +35,7 @@ func main() {
 	// Определяем путь к основной БД
 	// Используем 1c_data.db если существует, иначе data.db
 	dbPath := config.DatabasePath
-	if _, err := os.Stat("1c_data.db"); err == nil {
+	if _, err := os.Stat("1c_data.db"); err == nil && !config.DemoMode {
 		dbPath = "1c_data.db"
 		log.Printf("Используется существующая база данных: %s", dbPath)
 	}
@@ -38,6 +45,8 @@ func main() {
 		MaxOpenConns:    config.MaxOpenConns,
 		MaxIdleConns:    config.MaxIdleConns,
 		ConnMaxLifetime: config.ConnMaxLifetime,
+
+		ReadPoolMaxOpenConns: config.ReadPoolMaxOpenConns,
 	}
 
 	// Создаем базу данных
@@ -84,6 +93,17 @@ func main() {
 	if useGUI {
 		// Создаем GUI окно только если явно указано
 		window = gui.NewWindow(srv.GetLogChannel())
+		// Закрытие окна не останавливает сервер: окно сворачивается в область уведомлений
+		window.EnableTray(gui.TrayActions{
+			StopAfterUploads: func() {
+				waitCtx, cancelWait := context.WithTimeout(context.Background(), trayStopMaxWait)
+				defer cancelWait()
+				if err := srv.WaitCurrentUploads(waitCtx, 2*time.Second); err != nil {
+					log.Printf("Остановка без ожидания оставшихся выгрузок: %v", err)
+				}
+				window.Quit()
+			},
+		})
 	}
 
 	// Запускаем сервер в отдельной горутине
@@ -119,11 +139,12 @@ func main() {
 				}
 
 			case <-cleanupTicker.C:
-				// Очищаем метрики старше 7 дней
-				if err := db.CleanOldMetrics(7); err != nil {
+				// Очищаем метрики старше срока хранения (METRICS_RETENTION_DAYS, перезагружается без перезапуска)
+				retentionDays := config.MetricsRetentionDays
+				if err := db.CleanOldMetrics(retentionDays); err != nil {
 					log.Printf("⚠ Ошибка очистки старых метрик: %v", err)
 				} else {
-					log.Printf("✓ Старые метрики очищены (retention: 7 дней)")
+					log.Printf("✓ Старые метрики очищены (retention: %d дней)", retentionDays)
 				}
 			}
 		}
@@ -174,7 +195,7 @@ func main() {
 		<-sigChan
 		log.Println("Получен сигнал завершения...")
 		if useGUI && window != nil {
-			window.SetStatus("Сервер останавливается...")
+			window.SetStatus(i18n.T(i18n.FromEnv(), "gui.server_stopping"))
 		}
 
 		// Graceful shutdown
@@ -191,8 +212,13 @@ func main() {
 
 	if useGUI && window != nil {
 		log.Println("Открывается GUI интерфейс...")
-		// Показываем GUI и блокируем выполнение
+		// Показываем GUI и блокируем выполнение до выхода из меню области уведомлений
 		window.ShowAndRun()
+
+		log.Println("Приложение закрыто, остановка сервера...")
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("Ошибка при остановке сервера: %v", err)
+		}
 	} else {
 		log.Println("Режим без GUI (для контейнера)")
 		log.Println("Для остановки нажмите Ctrl+C")
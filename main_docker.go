@@ -12,11 +12,13 @@ import (
 	"time"
 
 	"httpserver/database"
+	"httpserver/features"
 	"httpserver/server"
 )
 
 func main() {
 	log.Println("Запуск 1C HTTP Server (Docker режим без GUI)...")
+	log.Printf("Профиль сборки: %s (GUI: %v, AI: %v)", features.Profile(), features.GUI, features.AI)
 	
 	// Загружаем конфигурацию
 	config, err := server.LoadConfig()
@@ -27,7 +29,7 @@ func main() {
 	// Определяем путь к основной БД
 	// Используем 1c_data.db если существует, иначе data.db
 	dbPath := config.DatabasePath
-	if _, err := os.Stat("1c_data.db"); err == nil {
+	if _, err := os.Stat("1c_data.db"); err == nil && !config.DemoMode {
 		dbPath = "1c_data.db"
 		log.Printf("Используется существующая база данных: %s", dbPath)
 	}
@@ -37,6 +39,8 @@ func main() {
 		MaxOpenConns:    config.MaxOpenConns,
 		MaxIdleConns:    config.MaxIdleConns,
 		ConnMaxLifetime: config.ConnMaxLifetime,
+
+		ReadPoolMaxOpenConns: config.ReadPoolMaxOpenConns,
 	}
 	
 	// Создаем базу данных
@@ -1,12 +1,11 @@
 package nomenclature
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,14 +34,25 @@ type CircuitBreaker struct {
 	lastFailureTime time.Time     // Время последней ошибки
 }
 
+// aiTransport выполняет запросы клиента к AI провайдеру. Реализация выбирается при сборке:
+// providerTransport (ai_transport.go) отправляет запросы, в сборке с тегом no_ai disabledTransport
+// (ai_transport_disabled.go) отвечает ErrAIDisabled, и код запросов в бинарный файл не входит
+type aiTransport interface {
+	processProduct(c *AIClient, productName, systemPrompt string) (*AIProcessingResult, error)
+	completion(c *AIClient, systemPrompt, userPrompt string) (string, error)
+}
+
 // AIClient клиент для работы с Arliai API
 type AIClient struct {
 	apiKey         string
 	baseURL        string
+	modelMu        sync.RWMutex      // Модель и ключ могут меняться во время работы (горячая перезагрузка конфигурации)
 	model          string
 	httpClient     *http.Client
 	rateLimiter    *rate.Limiter     // Rate limiter для защиты от превышения квот API
 	circuitBreaker *CircuitBreaker   // Circuit breaker для защиты от каскадных сбоев
+	scheduler      *AIScheduler      // Общий бюджет запросов всех задач процесса
+	job            string            // Задача, от имени которой клиент расходует общий бюджет
 }
 
 // AIRequest структура запроса к API
@@ -71,6 +81,17 @@ type AIResponse struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 	} `json:"error,omitempty"`
+	Usage *struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage,omitempty"`
+}
+
+// totalTokens возвращает расход токенов из ответа; 0, если провайдер его не сообщил
+func (r *AIResponse) totalTokens() int {
+	if r.Usage == nil {
+		return 0
+	}
+	return r.Usage.TotalTokens
 }
 
 // AIProcessingResult результат обработки ИИ
@@ -99,7 +120,7 @@ func NewAIClient(apiKey, model string) *AIClient {
 		timeout:          30 * time.Second,
 	}
 
-	return &AIClient{
+	client := &AIClient{
 		apiKey:  apiKey,
 		baseURL: "https://api.arliai.com/v1/chat/completions",
 		model:   model,
@@ -108,98 +129,32 @@ func NewAIClient(apiKey, model string) *AIClient {
 		},
 		rateLimiter:    limiter,
 		circuitBreaker: breaker,
-	}
-}
-
-// ProcessProduct отправляет запрос к API для обработки товара
-func (c *AIClient) ProcessProduct(productName, systemPrompt string) (*AIProcessingResult, error) {
-	// Проверяем Circuit Breaker перед запросом
-	if !c.circuitBreaker.canProceed() {
-		return nil, fmt.Errorf("circuit breaker is open (state: %s), API calls are temporarily blocked", c.circuitBreaker.getState())
-	}
-
-	messages := []Message{
-		{
-			Role:    "system",
-			Content: systemPrompt,
-		},
-		{
-			Role:    "user",
-			Content: fmt.Sprintf("НАИМЕНОВАНИЕ ТОВАРА ДЛЯ ОБРАБОТКИ: \"%s\"", productName),
-		},
-	}
-
-	request := AIRequest{
-		Model:       c.model,
-		Messages:    messages,
-		Temperature: 0.3,
-		MaxTokens:   1024,
-		Stream:      false,
-	}
-
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	// Применяем rate limiting перед запросом
-	ctx := context.Background()
-	if err := c.rateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %v", err)
+		scheduler:      SharedAIScheduler(),
+		job:            DefaultAIJob,
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		// Записываем ошибку в Circuit Breaker
-		c.circuitBreaker.recordFailure()
-		return nil, fmt.Errorf("API request failed: %v", err)
+	// AI_PROVIDER=fake - запросы обслуживает фейковый провайдер (тесты и нагрузочное тестирование)
+	if FakeProviderEnabled() {
+		client.UseFakeProvider(SharedFakeProvider())
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.circuitBreaker.recordFailure()
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		c.circuitBreaker.recordFailure()
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var aiResp AIResponse
-	if err := json.Unmarshal(body, &aiResp); err != nil {
-		c.circuitBreaker.recordFailure()
-		return nil, fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	if aiResp.Error != nil {
-		c.circuitBreaker.recordFailure()
-		return nil, fmt.Errorf("API error: %s (type: %s)", aiResp.Error.Message, aiResp.Error.Type)
-	}
-
-	if len(aiResp.Choices) == 0 {
-		c.circuitBreaker.recordFailure()
-		return nil, fmt.Errorf("no choices in response")
-	}
+	return client
+}
 
-	result, err := c.parseAIResponse(aiResp.Choices[0].Message.Content, productName)
-	if err != nil {
-		c.circuitBreaker.recordFailure()
-		return nil, err
+// UseFakeProvider направляет запросы клиента в фейковый провайдер.
+// Квоты реального API к фейковому провайдеру не относятся, поэтому rate limiter и общий бюджет снимаются.
+func (c *AIClient) UseFakeProvider(provider *FakeProvider) {
+	c.httpClient = &http.Client{
+		Timeout:   c.httpClient.Timeout,
+		Transport: provider,
 	}
+	c.rateLimiter.SetLimit(rate.Inf)
+	c.scheduler = nil
+}
 
-	// Успешный запрос - записываем в Circuit Breaker
-	c.circuitBreaker.recordSuccess()
-	return result, nil
+// ProcessProduct отправляет запрос к API для обработки товара
+func (c *AIClient) ProcessProduct(productName, systemPrompt string) (*AIProcessingResult, error) {
+	return transport.processProduct(c, productName, systemPrompt)
 }
 
 // parseAIResponse парсит ответ от ИИ
@@ -250,88 +205,7 @@ func (c *AIClient) cleanJSONResponse(content string) string {
 // GetCompletion универсальный метод для получения ответа от AI
 // Возвращает очищенный JSON ответ для дальнейшей обработки
 func (c *AIClient) GetCompletion(systemPrompt, userPrompt string) (string, error) {
-	// Проверяем Circuit Breaker перед запросом
-	if !c.circuitBreaker.canProceed() {
-		return "", fmt.Errorf("circuit breaker is open (state: %s), API calls are temporarily blocked", c.circuitBreaker.getState())
-	}
-
-	messages := []Message{
-		{
-			Role:    "system",
-			Content: systemPrompt,
-		},
-		{
-			Role:    "user",
-			Content: userPrompt,
-		},
-	}
-
-	request := AIRequest{
-		Model:       c.model,
-		Messages:    messages,
-		Temperature: 0.3,
-		MaxTokens:   1024,
-		Stream:      false,
-	}
-
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %v", err)
-	}
-
-	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	// Применяем rate limiting перед запросом
-	ctx := context.Background()
-	if err := c.rateLimiter.Wait(ctx); err != nil {
-		return "", fmt.Errorf("rate limiter error: %v", err)
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		c.circuitBreaker.recordFailure()
-		return "", fmt.Errorf("API request failed: %v", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.circuitBreaker.recordFailure()
-		return "", fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		c.circuitBreaker.recordFailure()
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var aiResp AIResponse
-	if err := json.Unmarshal(body, &aiResp); err != nil {
-		c.circuitBreaker.recordFailure()
-		return "", fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	if aiResp.Error != nil {
-		c.circuitBreaker.recordFailure()
-		return "", fmt.Errorf("API error: %s (type: %s)", aiResp.Error.Message, aiResp.Error.Type)
-	}
-
-	if len(aiResp.Choices) == 0 {
-		c.circuitBreaker.recordFailure()
-		return "", fmt.Errorf("no choices in response")
-	}
-
-	// Успешный запрос - записываем в Circuit Breaker
-	c.circuitBreaker.recordSuccess()
-
-	// Возвращаем очищенный ответ
-	return c.cleanJSONResponse(aiResp.Choices[0].Message.Content), nil
+	return transport.completion(c, systemPrompt, userPrompt)
 }
 
 // --- Circuit Breaker методы ---
@@ -433,6 +307,107 @@ func (cb *CircuitBreaker) getState() string {
 	}
 }
 
+// Model возвращает имя модели, используемой клиентом
+func (c *AIClient) Model() string {
+	if c == nil {
+		return ""
+	}
+	c.modelMu.RLock()
+	defer c.modelMu.RUnlock()
+	return c.model
+}
+
+// WithModel возвращает клиент для другой модели с общими ключом, rate limiter и circuit breaker
+// (используется цепочками моделей, чтобы эскалация не обходила лимиты API)
+func (c *AIClient) WithModel(model string) *AIClient {
+	return &AIClient{
+		apiKey:         c.currentAPIKey(),
+		baseURL:        c.baseURL,
+		model:          model,
+		httpClient:     c.httpClient,
+		rateLimiter:    c.rateLimiter,
+		circuitBreaker: c.circuitBreaker,
+		scheduler:      c.scheduler,
+		job:            c.Job(),
+	}
+}
+
+// SetJob задает задачу, от имени которой клиент получает долю общего бюджета запросов
+func (c *AIClient) SetJob(job string) {
+	if job == "" {
+		job = DefaultAIJob
+	}
+	c.modelMu.Lock()
+	c.job = job
+	c.modelMu.Unlock()
+}
+
+// Job возвращает задачу клиента в общем планировщике запросов
+func (c *AIClient) Job() string {
+	c.modelMu.RLock()
+	defer c.modelMu.RUnlock()
+	return c.job
+}
+
+// acquireBudget ждет доли общего бюджета под запрос. Оценка токенов - промпт (~4 символа на токен)
+// плюс max_tokens, как их резервирует провайдер
+func (c *AIClient) acquireBudget(ctx context.Context, request AIRequest) (*AIGrant, error) {
+	if c.scheduler == nil {
+		return nil, nil
+	}
+	tokens := request.MaxTokens
+	for _, message := range request.Messages {
+		tokens += len([]rune(message.Content)) / 4
+	}
+	return c.scheduler.Acquire(ctx, c.Job(), tokens)
+}
+
+// retryAfter возвращает задержку из заголовка Retry-After ответа 429; 0, если заголовка нет
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After")))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// SetModel переключает модель; применяется со следующего запроса, текущие запросы не прерываются
+func (c *AIClient) SetModel(model string) {
+	if model == "" {
+		return
+	}
+	c.modelMu.Lock()
+	c.model = model
+	c.modelMu.Unlock()
+}
+
+// SetAPIKey заменяет API ключ; применяется со следующего запроса
+func (c *AIClient) SetAPIKey(apiKey string) {
+	c.modelMu.Lock()
+	c.apiKey = apiKey
+	c.modelMu.Unlock()
+}
+
+// currentAPIKey возвращает действующий API ключ
+func (c *AIClient) currentAPIKey() string {
+	c.modelMu.RLock()
+	defer c.modelMu.RUnlock()
+	return c.apiKey
+}
+
+// SetRateLimit изменяет лимит запросов в минуту на лету (burst сохраняется)
+func (c *AIClient) SetRateLimit(requestsPerMinute int) {
+	if requestsPerMinute <= 0 {
+		return
+	}
+	c.rateLimiter.SetLimit(rate.Limit(float64(requestsPerMinute) / 60))
+}
+
+// RateLimit возвращает текущий лимит запросов в минуту
+func (c *AIClient) RateLimit() float64 {
+	return float64(c.rateLimiter.Limit()) * 60
+}
+
 // GetCircuitBreakerState возвращает детальное состояние Circuit Breaker для мониторинга
 func (c *AIClient) GetCircuitBreakerState() map[string]interface{} {
 	if c.circuitBreaker == nil {
@@ -0,0 +1,38 @@
+//go:build no_ai
+
+package nomenclature
+
+import (
+	"errors"
+	"testing"
+
+	"httpserver/features"
+)
+
+func TestAIClientDisabledBuild(t *testing.T) {
+	client, provider := newFakeClient(FakeProviderConfig{})
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"process product", func() error {
+			_, err := client.ProcessProduct("Болт М10", "system")
+			return err
+		}},
+		{"completion", func() error {
+			_, err := client.GetCompletion("system", "user")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, features.ErrAIDisabled) {
+				t.Errorf("error = %v, want ErrAIDisabled", err)
+			}
+		})
+	}
+	if got := provider.Stats().Requests; got != 0 {
+		t.Errorf("provider requests = %d, want 0", got)
+	}
+}
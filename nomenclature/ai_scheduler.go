@@ -0,0 +1,422 @@
+package nomenclature
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultAIJob задача, к которой относятся запросы клиента без явной метки
+const DefaultAIJob = "default"
+
+const (
+	aiBudgetWindow        = time.Minute     // Окно учета бюджета запросов и токенов
+	aiThrottlePause       = 5 * time.Second // Пауза после 429, если провайдер не прислал Retry-After
+	aiSchedulerMinWait    = 5 * time.Millisecond
+	aiSchedulerIdleWait   = time.Second
+	aiSchedulerJobIdleTTL = 10 * time.Minute // Неактивные задачи убираются из телеметрии
+)
+
+// AISchedulerConfig глобальный бюджет запросов к AI провайдеру; 0 - без ограничения
+type AISchedulerConfig struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	TokensPerMinute   int `json:"tokens_per_minute"`
+}
+
+// AISchedulerJobStats телеметрия задачи в планировщике
+type AISchedulerJobStats struct {
+	Job              string  `json:"job"`
+	Waiting          int     `json:"waiting"`
+	Granted          int64   `json:"granted"`
+	Tokens           int64   `json:"tokens"`
+	RequestsInWindow int     `json:"requests_last_minute"`
+	TokensInWindow   int     `json:"tokens_last_minute"`
+	Share            float64 `json:"share"` // Доля запросов задачи за последнюю минуту
+	Throttled        int64   `json:"throttled"`
+	AvgWaitMs        float64 `json:"avg_wait_ms"`
+}
+
+// AISchedulerStats телеметрия глобального бюджета AI запросов
+type AISchedulerStats struct {
+	RequestsPerMinute int                    `json:"requests_per_minute"`
+	TokensPerMinute   int                    `json:"tokens_per_minute"`
+	RequestsInWindow  int                    `json:"requests_last_minute"`
+	TokensInWindow    int                    `json:"tokens_last_minute"`
+	RequestsRemaining int                    `json:"requests_remaining"` // -1 - без ограничения
+	TokensRemaining   int                    `json:"tokens_remaining"`   // -1 - без ограничения
+	Waiting           int                    `json:"waiting"`
+	Throttled         int64                  `json:"throttled"`
+	PausedUntil       *time.Time             `json:"paused_until,omitempty"`
+	Jobs              []*AISchedulerJobStats `json:"jobs"`
+}
+
+// aiBudgetEvent выданный запрос в окне учета
+type aiBudgetEvent struct {
+	at     time.Time
+	job    *aiSchedulerJob
+	tokens int
+	active bool // false - событие вышло из окна
+}
+
+// aiWaiter запрос, ожидающий бюджета
+type aiWaiter struct {
+	tokens  int
+	ready   chan struct{}
+	event   *aiBudgetEvent
+	granted bool
+}
+
+// aiSchedulerJob очередь и счетчики задачи
+type aiSchedulerJob struct {
+	name             string
+	queue            []*aiWaiter
+	granted          int64
+	tokens           int64
+	throttled        int64
+	waitTotal        time.Duration
+	requestsInWindow int
+	tokensInWindow   int
+	lastGrant        time.Time
+	lastActivity     time.Time
+}
+
+// AIScheduler распределяет общий бюджет запросов и токенов в минуту между задачами,
+// одновременно работающими с AI провайдером (нормализация, классификация КПВЭД и т.д.).
+// Свободный бюджет получает задача с наименьшим числом запросов за последнюю минуту,
+// поэтому одна задача с большим числом воркеров не вытесняет остальные.
+type AIScheduler struct {
+	mu          sync.Mutex
+	config      AISchedulerConfig
+	jobs        map[string]*aiSchedulerJob
+	events      []*aiBudgetEvent
+	tokens      int
+	waiting     int
+	throttled   int64
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+// NewAIScheduler создает планировщик с заданным бюджетом
+func NewAIScheduler(config AISchedulerConfig) *AIScheduler {
+	return &AIScheduler{
+		config: normalizeAISchedulerConfig(config),
+		jobs:   make(map[string]*aiSchedulerJob),
+		now:    time.Now,
+	}
+}
+
+// AISchedulerConfigFromEnv читает бюджет из AI_BUDGET_RPM и AI_BUDGET_TPM
+func AISchedulerConfigFromEnv() AISchedulerConfig {
+	var config AISchedulerConfig
+	if n, err := strconv.Atoi(os.Getenv("AI_BUDGET_RPM")); err == nil {
+		config.RequestsPerMinute = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("AI_BUDGET_TPM")); err == nil {
+		config.TokensPerMinute = n
+	}
+	return config
+}
+
+var (
+	sharedAISchedulerOnce sync.Once
+	sharedAIScheduler     *AIScheduler
+)
+
+// SharedAIScheduler возвращает общий для процесса планировщик AI запросов, настроенный из окружения.
+// Через него проходят запросы всех AI клиентов, чтобы параллельные задачи не превышали квоты провайдера
+func SharedAIScheduler() *AIScheduler {
+	sharedAISchedulerOnce.Do(func() {
+		sharedAIScheduler = NewAIScheduler(AISchedulerConfigFromEnv())
+	})
+	return sharedAIScheduler
+}
+
+// normalizeAISchedulerConfig приводит отрицательные значения к "без ограничения"
+func normalizeAISchedulerConfig(config AISchedulerConfig) AISchedulerConfig {
+	if config.RequestsPerMinute < 0 {
+		config.RequestsPerMinute = 0
+	}
+	if config.TokensPerMinute < 0 {
+		config.TokensPerMinute = 0
+	}
+	return config
+}
+
+// Config возвращает текущий бюджет
+func (s *AIScheduler) Config() AISchedulerConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// SetConfig изменяет бюджет на лету; ожидающие запросы пересчитываются при следующей проверке
+func (s *AIScheduler) SetConfig(config AISchedulerConfig) {
+	s.mu.Lock()
+	s.config = normalizeAISchedulerConfig(config)
+	s.dispatchLocked(s.now())
+	s.mu.Unlock()
+}
+
+// AIGrant разрешение на один запрос к провайдеру
+type AIGrant struct {
+	scheduler *AIScheduler
+	job       *aiSchedulerJob
+	event     *aiBudgetEvent
+}
+
+// Acquire ждет бюджета на запрос задачи job с оценкой tokens токенов
+func (s *AIScheduler) Acquire(ctx context.Context, job string, tokens int) (*AIGrant, error) {
+	if job == "" {
+		job = DefaultAIJob
+	}
+	if tokens < 0 {
+		tokens = 0
+	}
+
+	s.mu.Lock()
+	start := s.now()
+	j := s.jobLocked(job, start)
+	w := &aiWaiter{tokens: tokens, ready: make(chan struct{})}
+	j.queue = append(j.queue, w)
+	s.waiting++
+	wait := s.dispatchLocked(start)
+	s.mu.Unlock()
+
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-w.ready:
+			timer.Stop()
+			return s.granted(j, w, start), nil
+		case <-ctx.Done():
+			timer.Stop()
+			s.mu.Lock()
+			if w.granted {
+				s.mu.Unlock()
+				return s.granted(j, w, start), nil
+			}
+			s.removeWaiterLocked(j, w)
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		case <-timer.C:
+			s.mu.Lock()
+			wait = s.dispatchLocked(s.now())
+			s.mu.Unlock()
+		}
+	}
+}
+
+// granted фиксирует время ожидания выданного запроса
+func (s *AIScheduler) granted(j *aiSchedulerJob, w *aiWaiter, start time.Time) *AIGrant {
+	s.mu.Lock()
+	j.waitTotal += s.now().Sub(start)
+	s.mu.Unlock()
+	return &AIGrant{scheduler: s, job: j, event: w.event}
+}
+
+// Complete уточняет расход токенов по ответу провайдера (0 - оценка остается) и учитывает 429:
+// все задачи приостанавливаются на retryAfter (по умолчанию aiThrottlePause)
+func (g *AIGrant) Complete(actualTokens, statusCode int, retryAfter time.Duration) {
+	if g == nil {
+		return
+	}
+	s := g.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if actualTokens > 0 && actualTokens != g.event.tokens {
+		delta := actualTokens - g.event.tokens
+		g.job.tokens += int64(delta)
+		if g.event.active {
+			s.tokens += delta
+			g.job.tokensInWindow += delta
+		}
+		g.event.tokens = actualTokens
+	}
+	if statusCode == 429 {
+		g.job.throttled++
+		s.throttled++
+		if retryAfter <= 0 {
+			retryAfter = aiThrottlePause
+		}
+		if until := s.now().Add(retryAfter); until.After(s.pausedUntil) {
+			s.pausedUntil = until
+		}
+	}
+}
+
+// jobLocked возвращает задачу, создавая ее при первом запросе
+func (s *AIScheduler) jobLocked(name string, now time.Time) *aiSchedulerJob {
+	j, ok := s.jobs[name]
+	if !ok {
+		j = &aiSchedulerJob{name: name}
+		s.jobs[name] = j
+	}
+	j.lastActivity = now
+	return j
+}
+
+// removeWaiterLocked убирает из очереди запрос, который перестали ждать
+func (s *AIScheduler) removeWaiterLocked(j *aiSchedulerJob, w *aiWaiter) {
+	for i, queued := range j.queue {
+		if queued == w {
+			j.queue = append(j.queue[:i], j.queue[i+1:]...)
+			s.waiting--
+			return
+		}
+	}
+}
+
+// pruneLocked убирает из окна устаревшие события и давно неактивные задачи
+func (s *AIScheduler) pruneLocked(now time.Time) {
+	cutoff := now.Add(-aiBudgetWindow)
+	i := 0
+	for ; i < len(s.events) && !s.events[i].at.After(cutoff); i++ {
+		event := s.events[i]
+		event.active = false
+		s.tokens -= event.tokens
+		event.job.requestsInWindow--
+		event.job.tokensInWindow -= event.tokens
+	}
+	s.events = s.events[i:]
+
+	for name, j := range s.jobs {
+		if len(j.queue) == 0 && j.requestsInWindow == 0 && now.Sub(j.lastActivity) > aiSchedulerJobIdleTTL {
+			delete(s.jobs, name)
+		}
+	}
+}
+
+// nextJobLocked выбирает задачу с ожидающими запросами и наименьшей долей бюджета за окно
+func (s *AIScheduler) nextJobLocked() *aiSchedulerJob {
+	var next *aiSchedulerJob
+	for _, j := range s.jobs {
+		if len(j.queue) == 0 {
+			continue
+		}
+		if next == nil ||
+			j.requestsInWindow < next.requestsInWindow ||
+			(j.requestsInWindow == next.requestsInWindow && j.lastGrant.Before(next.lastGrant)) ||
+			(j.requestsInWindow == next.requestsInWindow && j.lastGrant.Equal(next.lastGrant) && j.name < next.name) {
+			next = j
+		}
+	}
+	return next
+}
+
+// dispatchLocked выдает запросы, пока хватает бюджета, и возвращает время до следующей проверки
+func (s *AIScheduler) dispatchLocked(now time.Time) time.Duration {
+	s.pruneLocked(now)
+	for {
+		j := s.nextJobLocked()
+		if j == nil {
+			return aiSchedulerIdleWait
+		}
+		if now.Before(s.pausedUntil) {
+			return clampSchedulerWait(s.pausedUntil.Sub(now))
+		}
+		w := j.queue[0]
+		if wait := s.budgetWaitLocked(now, w.tokens); wait > 0 {
+			return clampSchedulerWait(wait)
+		}
+
+		event := &aiBudgetEvent{at: now, job: j, tokens: w.tokens, active: true}
+		s.events = append(s.events, event)
+		s.tokens += w.tokens
+		j.queue = j.queue[1:]
+		j.granted++
+		j.tokens += int64(w.tokens)
+		j.requestsInWindow++
+		j.tokensInWindow += w.tokens
+		j.lastGrant = now
+		j.lastActivity = now
+		s.waiting--
+		w.event = event
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// budgetWaitLocked возвращает, сколько ждать освобождения бюджета под запрос; 0 - бюджета достаточно.
+// Запрос крупнее всего бюджета токенов пропускается, когда окно пусто, иначе он не выполнился бы никогда
+func (s *AIScheduler) budgetWaitLocked(now time.Time, tokens int) time.Duration {
+	var wait time.Duration
+	if rpm := s.config.RequestsPerMinute; rpm > 0 && len(s.events) >= rpm {
+		wait = s.events[len(s.events)-rpm].at.Add(aiBudgetWindow).Sub(now)
+	}
+	if tpm := s.config.TokensPerMinute; tpm > 0 && s.tokens > 0 && s.tokens+tokens > tpm {
+		freed := 0
+		for _, event := range s.events {
+			freed += event.tokens
+			if s.tokens-freed+tokens <= tpm || freed == s.tokens {
+				if tokenWait := event.at.Add(aiBudgetWindow).Sub(now); tokenWait > wait {
+					wait = tokenWait
+				}
+				break
+			}
+		}
+	}
+	return wait
+}
+
+// clampSchedulerWait ограничивает интервал повторной проверки снизу
+func clampSchedulerWait(wait time.Duration) time.Duration {
+	if wait < aiSchedulerMinWait {
+		return aiSchedulerMinWait
+	}
+	return wait
+}
+
+// Stats возвращает телеметрию бюджета и задач
+func (s *AIScheduler) Stats() AISchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.pruneLocked(now)
+	stats := AISchedulerStats{
+		RequestsPerMinute: s.config.RequestsPerMinute,
+		TokensPerMinute:   s.config.TokensPerMinute,
+		RequestsInWindow:  len(s.events),
+		TokensInWindow:    s.tokens,
+		RequestsRemaining: -1,
+		TokensRemaining:   -1,
+		Waiting:           s.waiting,
+		Throttled:         s.throttled,
+		Jobs:              make([]*AISchedulerJobStats, 0, len(s.jobs)),
+	}
+	if s.config.RequestsPerMinute > 0 {
+		stats.RequestsRemaining = max(s.config.RequestsPerMinute-len(s.events), 0)
+	}
+	if s.config.TokensPerMinute > 0 {
+		stats.TokensRemaining = max(s.config.TokensPerMinute-s.tokens, 0)
+	}
+	if now.Before(s.pausedUntil) {
+		paused := s.pausedUntil
+		stats.PausedUntil = &paused
+	}
+
+	for _, j := range s.jobs {
+		job := &AISchedulerJobStats{
+			Job:              j.name,
+			Waiting:          len(j.queue),
+			Granted:          j.granted,
+			Tokens:           j.tokens,
+			RequestsInWindow: j.requestsInWindow,
+			TokensInWindow:   j.tokensInWindow,
+			Throttled:        j.throttled,
+		}
+		if len(s.events) > 0 {
+			job.Share = float64(j.requestsInWindow) / float64(len(s.events))
+		}
+		if j.granted > 0 {
+			job.AvgWaitMs = float64(j.waitTotal.Milliseconds()) / float64(j.granted)
+		}
+		stats.Jobs = append(stats.Jobs, job)
+	}
+	sort.Slice(stats.Jobs, func(i, k int) bool { return stats.Jobs[i].Job < stats.Jobs[k].Job })
+	return stats
+}
@@ -0,0 +1,134 @@
+package nomenclature
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestScheduler создает планировщик с управляемыми часами
+func newTestScheduler(config AISchedulerConfig) (*AIScheduler, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewAIScheduler(config)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestAISchedulerBudget(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      AISchedulerConfig
+		tokens      []int
+		wantGranted int
+	}{
+		{"unlimited", AISchedulerConfig{}, []int{100, 100, 100}, 3},
+		{"requests per minute", AISchedulerConfig{RequestsPerMinute: 2}, []int{1, 1, 1}, 2},
+		{"tokens per minute", AISchedulerConfig{TokensPerMinute: 250}, []int{100, 100, 100}, 2},
+		{"oversized request in empty window", AISchedulerConfig{TokensPerMinute: 50}, []int{100, 100}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestScheduler(tt.config)
+			granted := 0
+			for _, tokens := range tt.tokens {
+				ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+				if _, err := s.Acquire(ctx, "job", tokens); err == nil {
+					granted++
+				}
+				cancel()
+			}
+			if granted != tt.wantGranted {
+				t.Errorf("granted = %d, want %d", granted, tt.wantGranted)
+			}
+			if stats := s.Stats(); stats.Waiting != 0 {
+				t.Errorf("waiting after cancel = %d, want 0", stats.Waiting)
+			}
+		})
+	}
+}
+
+func TestAISchedulerFairShare(t *testing.T) {
+	s, now := newTestScheduler(AISchedulerConfig{RequestsPerMinute: 4})
+
+	// Нормализация успела израсходовать половину бюджета до старта классификации
+	for i := 0; i < 2; i++ {
+		if _, err := s.Acquire(context.Background(), "normalization", 1); err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+	}
+
+	// Бюджет исчерпан, обе задачи ждут; освободившийся запрос достается классификации,
+	// у которой меньше запросов в окне, хотя нормализация встала в очередь раньше
+	s.SetConfig(AISchedulerConfig{RequestsPerMinute: 2})
+	results := make(chan string, 4)
+	for i, job := range []string{"normalization", "normalization", "kpved", "kpved"} {
+		go func(job string) {
+			if _, err := s.Acquire(context.Background(), job, 1); err == nil {
+				results <- job
+			}
+		}(job)
+		waitFor(t, func() bool { return s.Stats().Waiting == i+1 })
+	}
+
+	s.SetConfig(AISchedulerConfig{RequestsPerMinute: 3})
+	if job := <-results; job != "kpved" {
+		t.Errorf("granted job = %s, want kpved", job)
+	}
+
+	stats := s.Stats()
+	if stats.RequestsRemaining != 0 || stats.Waiting != 3 || len(stats.Jobs) != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	// После окна бюджет делится поровну
+	s.mu.Lock()
+	*now = now.Add(aiBudgetWindow + time.Second)
+	s.config.RequestsPerMinute = 2
+	s.dispatchLocked(*now)
+	s.mu.Unlock()
+	got := map[string]int{}
+	for i := 0; i < 2; i++ {
+		got[<-results]++
+	}
+	if got["kpved"] != 1 || got["normalization"] != 1 {
+		t.Errorf("granted after window = %v, want one request per job", got)
+	}
+}
+
+func TestAISchedulerThrottlePause(t *testing.T) {
+	s, now := newTestScheduler(AISchedulerConfig{})
+	grant, err := s.Acquire(context.Background(), "normalization", 100)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	grant.Complete(40, 429, 30*time.Second)
+
+	stats := s.Stats()
+	if stats.Throttled != 1 || stats.PausedUntil == nil || stats.TokensInWindow != 40 {
+		t.Fatalf("stats after 429 = %+v", stats)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Acquire(ctx, "kpved", 1); err == nil {
+		t.Error("Acquire() during pause expected timeout")
+	}
+
+	s.mu.Lock()
+	*now = now.Add(31 * time.Second)
+	s.mu.Unlock()
+	if _, err := s.Acquire(context.Background(), "kpved", 1); err != nil {
+		t.Errorf("Acquire() after pause error = %v", err)
+	}
+}
+
+// waitFor ждет выполнения условия не дольше секунды
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
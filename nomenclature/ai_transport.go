@@ -0,0 +1,229 @@
+//go:build !no_ai
+
+package nomenclature
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"httpserver/tracing"
+)
+
+// transport в полной сборке отправляет запросы AI провайдеру
+var transport aiTransport = providerTransport{}
+
+// providerTransport выполняет запросы к API провайдера с учетом rate limiter, общего бюджета и Circuit Breaker
+type providerTransport struct{}
+
+// processProduct отправляет запрос к API для обработки товара
+func (providerTransport) processProduct(c *AIClient, productName, systemPrompt string) (*AIProcessingResult, error) {
+	// Проверяем Circuit Breaker перед запросом
+	if !c.circuitBreaker.canProceed() {
+		return nil, fmt.Errorf("circuit breaker is open (state: %s), API calls are temporarily blocked", c.circuitBreaker.getState())
+	}
+
+	messages := []Message{
+		{
+			Role:    "system",
+			Content: systemPrompt,
+		},
+		{
+			Role:    "user",
+			Content: fmt.Sprintf("НАИМЕНОВАНИЕ ТОВАРА ДЛЯ ОБРАБОТКИ: \"%s\"", productName),
+		},
+	}
+
+	request := AIRequest{
+		Model:       c.Model(),
+		Messages:    messages,
+		Temperature: 0.3,
+		MaxTokens:   1024,
+		Stream:      false,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.currentAPIKey())
+
+	// Применяем rate limiting перед запросом
+	ctx := context.Background()
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter error: %v", err)
+	}
+	grant, err := c.acquireBudget(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("ai scheduler error: %v", err)
+	}
+
+	resp, err := c.do(req, "process_product")
+	if err != nil {
+		// Записываем ошибку в Circuit Breaker
+		c.circuitBreaker.recordFailure()
+		return nil, fmt.Errorf("API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.circuitBreaker.recordFailure()
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		grant.Complete(0, resp.StatusCode, retryAfter(resp))
+		c.circuitBreaker.recordFailure()
+		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var aiResp AIResponse
+	if err := json.Unmarshal(body, &aiResp); err != nil {
+		c.circuitBreaker.recordFailure()
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	grant.Complete(aiResp.totalTokens(), resp.StatusCode, 0)
+
+	if aiResp.Error != nil {
+		c.circuitBreaker.recordFailure()
+		return nil, fmt.Errorf("API error: %s (type: %s)", aiResp.Error.Message, aiResp.Error.Type)
+	}
+
+	if len(aiResp.Choices) == 0 {
+		c.circuitBreaker.recordFailure()
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	result, err := c.parseAIResponse(aiResp.Choices[0].Message.Content, productName)
+	if err != nil {
+		c.circuitBreaker.recordFailure()
+		return nil, err
+	}
+
+	// Успешный запрос - записываем в Circuit Breaker
+	c.circuitBreaker.recordSuccess()
+	return result, nil
+}
+
+// completion отправляет запрос с системным и пользовательским промптом и возвращает очищенный ответ
+func (providerTransport) completion(c *AIClient, systemPrompt, userPrompt string) (string, error) {
+	// Проверяем Circuit Breaker перед запросом
+	if !c.circuitBreaker.canProceed() {
+		return "", fmt.Errorf("circuit breaker is open (state: %s), API calls are temporarily blocked", c.circuitBreaker.getState())
+	}
+
+	messages := []Message{
+		{
+			Role:    "system",
+			Content: systemPrompt,
+		},
+		{
+			Role:    "user",
+			Content: userPrompt,
+		},
+	}
+
+	request := AIRequest{
+		Model:       c.Model(),
+		Messages:    messages,
+		Temperature: 0.3,
+		MaxTokens:   1024,
+		Stream:      false,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.currentAPIKey())
+
+	// Применяем rate limiting перед запросом
+	ctx := context.Background()
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return "", fmt.Errorf("rate limiter error: %v", err)
+	}
+	grant, err := c.acquireBudget(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("ai scheduler error: %v", err)
+	}
+
+	resp, err := c.do(req, "completion")
+	if err != nil {
+		c.circuitBreaker.recordFailure()
+		return "", fmt.Errorf("API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.circuitBreaker.recordFailure()
+		return "", fmt.Errorf("failed to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		grant.Complete(0, resp.StatusCode, retryAfter(resp))
+		c.circuitBreaker.recordFailure()
+		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var aiResp AIResponse
+	if err := json.Unmarshal(body, &aiResp); err != nil {
+		c.circuitBreaker.recordFailure()
+		return "", fmt.Errorf("failed to decode response: %v", err)
+	}
+	grant.Complete(aiResp.totalTokens(), resp.StatusCode, 0)
+
+	if aiResp.Error != nil {
+		c.circuitBreaker.recordFailure()
+		return "", fmt.Errorf("API error: %s (type: %s)", aiResp.Error.Message, aiResp.Error.Type)
+	}
+
+	if len(aiResp.Choices) == 0 {
+		c.circuitBreaker.recordFailure()
+		return "", fmt.Errorf("no choices in response")
+	}
+
+	// Успешный запрос - записываем в Circuit Breaker
+	c.circuitBreaker.recordSuccess()
+
+	// Возвращаем очищенный ответ
+	return c.cleanJSONResponse(aiResp.Choices[0].Message.Content), nil
+}
+
+// do выполняет запрос к API провайдера в клиентском спане трассировки
+func (c *AIClient) do(req *http.Request, operation string) (*http.Response, error) {
+	_, span := tracing.StartKind(req.Context(), "ai "+operation, tracing.SpanKindClient,
+		tracing.Attr("ai.model", c.Model()),
+		tracing.Attr("ai.operation", operation),
+	)
+	defer span.End()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttributes(tracing.Attr("http.status_code", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		span.RecordError(fmt.Errorf("API returned status %d", resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,19 @@
+//go:build no_ai
+
+package nomenclature
+
+import "httpserver/features"
+
+// transport в сборке без AI не выполняет запросы: они не влияют на Circuit Breaker и общий бюджет
+var transport aiTransport = disabledTransport{}
+
+// disabledTransport заглушка запросов к AI провайдеру
+type disabledTransport struct{}
+
+func (disabledTransport) processProduct(c *AIClient, productName, systemPrompt string) (*AIProcessingResult, error) {
+	return nil, features.ErrAIDisabled
+}
+
+func (disabledTransport) completion(c *AIClient, systemPrompt, userPrompt string) (string, error) {
+	return "", features.ErrAIDisabled
+}
@@ -0,0 +1,348 @@
+package nomenclature
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FakeProviderName значение AI_PROVIDER, включающее фейковый AI провайдер
+const FakeProviderName = "fake"
+
+// FakeProviderAPIKey ключ API, который подставляется, если фейковый провайдер включен без ARLIAI_API_KEY
+const FakeProviderAPIKey = "fake-provider-key"
+
+// FakeProviderConfig настройки фейкового AI провайдера для тестов и нагрузочного тестирования
+type FakeProviderConfig struct {
+	Latency       time.Duration // Базовая задержка ответа
+	LatencyJitter time.Duration // Случайная добавка к задержке (0..LatencyJitter)
+	ErrorRate     float64       // Доля ответов с HTTP ошибкой (500/429)
+	MalformedRate float64       // Доля ответов с некорректным содержимым
+	Seed          int64         // Seed генератора для воспроизводимых сценариев отказов
+}
+
+// FakeProviderStats счетчики фейкового провайдера
+type FakeProviderStats struct {
+	Requests  int64 `json:"requests"`
+	Errors    int64 `json:"errors"`
+	Malformed int64 `json:"malformed"`
+}
+
+// FakeProvider имитирует OpenAI-совместимый API Arliai без сетевых запросов.
+// Ответы детерминированы по тексту промпта, поэтому пайплайны классификации и нормализации
+// можно проверять в интеграционных тестах без реальных ключей и расходов.
+type FakeProvider struct {
+	config FakeProviderConfig
+
+	mu  sync.Mutex
+	rng *rand.Rand
+
+	requests  int64
+	errors    int64
+	malformed int64
+}
+
+// NewFakeProvider создает фейковый AI провайдер
+func NewFakeProvider(config FakeProviderConfig) *FakeProvider {
+	return &FakeProvider{
+		config: config,
+		rng:    rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// FakeProviderEnabled проверяет, выбран ли фейковый провайдер через AI_PROVIDER
+func FakeProviderEnabled() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("AI_PROVIDER")), FakeProviderName)
+}
+
+// FakeProviderConfigFromEnv читает настройки фейкового провайдера из переменных окружения:
+// AI_FAKE_LATENCY, AI_FAKE_LATENCY_JITTER, AI_FAKE_ERROR_RATE, AI_FAKE_MALFORMED_RATE, AI_FAKE_SEED
+func FakeProviderConfigFromEnv() FakeProviderConfig {
+	config := FakeProviderConfig{Seed: 1}
+	if d, err := time.ParseDuration(os.Getenv("AI_FAKE_LATENCY")); err == nil {
+		config.Latency = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("AI_FAKE_LATENCY_JITTER")); err == nil {
+		config.LatencyJitter = d
+	}
+	if f, err := strconv.ParseFloat(os.Getenv("AI_FAKE_ERROR_RATE"), 64); err == nil {
+		config.ErrorRate = f
+	}
+	if f, err := strconv.ParseFloat(os.Getenv("AI_FAKE_MALFORMED_RATE"), 64); err == nil {
+		config.MalformedRate = f
+	}
+	if n, err := strconv.ParseInt(os.Getenv("AI_FAKE_SEED"), 10, 64); err == nil {
+		config.Seed = n
+	}
+	return config
+}
+
+var (
+	sharedFakeProviderOnce sync.Once
+	sharedFakeProvider     *FakeProvider
+)
+
+// SharedFakeProvider возвращает общий для процесса фейковый провайдер, настроенный из окружения.
+// Общий экземпляр нужен, чтобы сценарий отказов и счетчики не зависели от числа созданных клиентов.
+func SharedFakeProvider() *FakeProvider {
+	sharedFakeProviderOnce.Do(func() {
+		sharedFakeProvider = NewFakeProvider(FakeProviderConfigFromEnv())
+	})
+	return sharedFakeProvider
+}
+
+// Stats возвращает счетчики запросов фейкового провайдера
+func (p *FakeProvider) Stats() FakeProviderStats {
+	return FakeProviderStats{
+		Requests:  atomic.LoadInt64(&p.requests),
+		Errors:    atomic.LoadInt64(&p.errors),
+		Malformed: atomic.LoadInt64(&p.malformed),
+	}
+}
+
+// fakeOutcome результат розыгрыша отказа для одного запроса
+type fakeOutcome int
+
+const (
+	fakeOutcomeOK fakeOutcome = iota
+	fakeOutcomeError
+	fakeOutcomeMalformed
+)
+
+// roll определяет исход запроса и задержку по генератору провайдера
+func (p *FakeProvider) roll() (fakeOutcome, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delay := p.config.Latency
+	if p.config.LatencyJitter > 0 {
+		delay += time.Duration(p.rng.Int63n(int64(p.config.LatencyJitter) + 1))
+	}
+
+	r := p.rng.Float64()
+	switch {
+	case r < p.config.ErrorRate:
+		return fakeOutcomeError, delay
+	case r < p.config.ErrorRate+p.config.MalformedRate:
+		return fakeOutcomeMalformed, delay
+	default:
+		return fakeOutcomeOK, delay
+	}
+}
+
+// RoundTrip реализует http.RoundTripper
+func (p *FakeProvider) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestNum := atomic.AddInt64(&p.requests, 1)
+	outcome, delay := p.roll()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	switch {
+	case strings.HasSuffix(path, "/health"):
+		return fakeJSONResponse(req, http.StatusOK, map[string]interface{}{
+			"status": "ok", "model": "fake", "version": "fake",
+		}), nil
+	case strings.HasSuffix(path, "/models"):
+		return fakeJSONResponse(req, http.StatusOK, map[string]interface{}{
+			"models": []map[string]interface{}{{"id": "fake", "name": "Fake", "speed": "fast", "quality": "test", "status": "active"}},
+		}), nil
+	}
+
+	switch outcome {
+	case fakeOutcomeError:
+		atomic.AddInt64(&p.errors, 1)
+		// Чередуем перегрузку и внутреннюю ошибку, чтобы проверять обе ветки обработки
+		status := http.StatusInternalServerError
+		if requestNum%2 == 0 {
+			status = http.StatusTooManyRequests
+		}
+		return fakeJSONResponse(req, status, map[string]interface{}{
+			"error": map[string]string{"message": "fake provider injected failure", "type": "fake_error"},
+		}), nil
+	case fakeOutcomeMalformed:
+		atomic.AddInt64(&p.malformed, 1)
+		return fakeChatResponse(req, `{"normalized_name": "обрезанный ответ", "confid`), nil
+	}
+
+	var chatRequest AIRequest
+	if req.Body != nil {
+		if err := json.NewDecoder(req.Body).Decode(&chatRequest); err != nil {
+			return fakeJSONResponse(req, http.StatusBadRequest, map[string]interface{}{
+				"error": map[string]string{"message": err.Error(), "type": "invalid_request"},
+			}), nil
+		}
+	}
+
+	var systemPrompt, userPrompt string
+	for _, message := range chatRequest.Messages {
+		switch message.Role {
+		case "system":
+			systemPrompt = message.Content
+		case "user":
+			userPrompt = message.Content
+		}
+	}
+
+	return fakeChatResponse(req, fakeCompletion(systemPrompt, userPrompt)), nil
+}
+
+// fakeChatResponse формирует ответ в формате chat completions
+func fakeChatResponse(req *http.Request, content string) *http.Response {
+	return fakeJSONResponse(req, http.StatusOK, map[string]interface{}{
+		"choices": []map[string]interface{}{
+			{"message": map[string]string{"role": "assistant", "content": content}},
+		},
+	})
+}
+
+// fakeJSONResponse формирует HTTP ответ с JSON телом
+func fakeJSONResponse(req *http.Request, status int, payload interface{}) *http.Response {
+	body, _ := json.Marshal(payload)
+	return &http.Response{
+		StatusCode:    status,
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+var (
+	fakeNamePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`НАИМЕНОВАНИЕ ТОВАРА ДЛЯ ОБРАБОТКИ: "(.+)"`),
+		regexp.MustCompile(`названия товара: "(.+)"`),
+		regexp.MustCompile(`Нормализуй наименование: (.+)`),
+		regexp.MustCompile(`Объект: (.+)`),
+		regexp.MustCompile(`Название: (.+)`),
+	}
+	fakeBatchLinePattern  = regexp.MustCompile(`(?m)^(\d+)\. (.+)$`)
+	fakeKpvedLinePattern  = regexp.MustCompile(`(?m)^- ([0-9A-Z][0-9A-Z.]*): (.+)$`)
+	fakeCategoryLineRegex = regexp.MustCompile(`^(\s*)- (.+) \(ID: [^)]*\)$`)
+)
+
+// fakeKpvedFallback коды КПВЭД, если промпт не содержит кандидатов
+var fakeKpvedFallback = [][2]string{
+	{"25.11.1", "Конструкции металлические и их части"},
+	{"27.32.1", "Провода и кабели электронные и электрические прочие"},
+	{"28.14.1", "Краны, клапаны, вентили и аналогичная арматура"},
+	{"22.21.2", "Трубы, трубки и шланги пластмассовые"},
+}
+
+// fakeResult ответ фейкового провайдера. Содержит поля всех форматов, которые ожидают
+// нормализатор, классификатор КПВЭД (плоский и иерархический) и классификатор категорий.
+type fakeResult struct {
+	Index           *int     `json:"index,omitempty"`
+	NormalizedName  string   `json:"normalized_name"`
+	Category        string   `json:"category"`
+	KpvedCode       string   `json:"kpved_code"`
+	KpvedName       string   `json:"kpved_name"`
+	KpvedConfidence float64  `json:"kpved_confidence"`
+	SelectedCode    string   `json:"selected_code"`
+	CategoryPath    []string `json:"category_path"`
+	Confidence      float64  `json:"confidence"`
+	Reasoning       string   `json:"reasoning"`
+}
+
+// fakeCompletion строит детерминированный ответ по промптам
+func fakeCompletion(systemPrompt, userPrompt string) string {
+	if strings.Contains(systemPrompt, "JSON массива") {
+		var results []fakeResult
+		for _, match := range fakeBatchLinePattern.FindAllStringSubmatch(userPrompt, -1) {
+			index, _ := strconv.Atoi(match[1])
+			result := fakeResultFor(match[2], systemPrompt, userPrompt)
+			result.Index = &index
+			results = append(results, result)
+		}
+		data, _ := json.Marshal(results)
+		return string(data)
+	}
+
+	name := strings.TrimSpace(strings.SplitN(userPrompt, "\n", 2)[0])
+	for _, pattern := range fakeNamePatterns {
+		if match := pattern.FindStringSubmatch(userPrompt); match != nil {
+			name = strings.TrimSpace(match[1])
+			break
+		}
+	}
+	data, _ := json.Marshal(fakeResultFor(name, systemPrompt, userPrompt))
+	return string(data)
+}
+
+// fakeResultFor формирует ответ для одного наименования
+func fakeResultFor(name, systemPrompt, userPrompt string) fakeResult {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	hash := int(h.Sum32() & 0x7fffffff)
+
+	normalized := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if normalized == "" {
+		normalized = "неизвестный товар"
+	}
+
+	code, codeName := fakeKpvedFallback[hash%len(fakeKpvedFallback)][0], fakeKpvedFallback[hash%len(fakeKpvedFallback)][1]
+	if candidates := fakeKpvedLinePattern.FindAllStringSubmatch(systemPrompt, -1); len(candidates) > 0 {
+		candidate := candidates[hash%len(candidates)]
+		code, codeName = candidate[1], strings.TrimSpace(candidate[2])
+	}
+
+	confidence := 0.7 + float64(hash%30)/100
+	return fakeResult{
+		NormalizedName:  normalized,
+		Category:        codeName,
+		KpvedCode:       code,
+		KpvedName:       codeName,
+		KpvedConfidence: confidence,
+		SelectedCode:    code,
+		CategoryPath:    fakeCategoryPath(userPrompt, hash, codeName),
+		Confidence:      confidence,
+		Reasoning:       "fake provider: детерминированный ответ",
+	}
+}
+
+// fakeCategoryPath выбирает путь категории из дерева классификатора в промпте
+func fakeCategoryPath(prompt string, hash int, fallback string) []string {
+	type line struct {
+		indent int
+		name   string
+	}
+	var lines []line
+	for _, raw := range strings.Split(prompt, "\n") {
+		if match := fakeCategoryLineRegex.FindStringSubmatch(raw); match != nil {
+			lines = append(lines, line{indent: len(match[1]), name: strings.TrimSpace(match[2])})
+		}
+	}
+	if len(lines) == 0 {
+		return []string{fallback}
+	}
+
+	// Поднимаемся от выбранной строки к корню по уменьшению отступа
+	selected := hash % len(lines)
+	path := []string{lines[selected].name}
+	indent := lines[selected].indent
+	for i := selected - 1; i >= 0 && indent > 0; i-- {
+		if lines[i].indent < indent {
+			path = append([]string{lines[i].name}, path...)
+			indent = lines[i].indent
+		}
+	}
+	return path
+}
@@ -0,0 +1,162 @@
+package nomenclature
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/features"
+)
+
+func newFakeClient(config FakeProviderConfig) (*AIClient, *FakeProvider) {
+	provider := NewFakeProvider(config)
+	client := NewAIClient("test-key", "fake")
+	client.UseFakeProvider(provider)
+	return client, provider
+}
+
+func TestFakeProviderResponses(t *testing.T) {
+	if !features.AI {
+		t.Skip("AI subsystem is not included in this build")
+	}
+	client, _ := newFakeClient(FakeProviderConfig{})
+
+	tests := []struct {
+		name   string
+		system string
+		user   string
+		check  func(t *testing.T, content string)
+	}{
+		{
+			name:   "normalization",
+			system: "Нормализуй наименование",
+			user:   `НАИМЕНОВАНИЕ ТОВАРА ДЛЯ ОБРАБОТКИ: "Кабель  КВВГ 4х1,5"`,
+			check: func(t *testing.T, content string) {
+				var result fakeResult
+				if err := json.Unmarshal([]byte(content), &result); err != nil {
+					t.Fatalf("invalid json: %v", err)
+				}
+				if result.NormalizedName != "кабель кввг 4х1,5" || result.KpvedCode == "" || result.KpvedName == "" {
+					t.Errorf("unexpected result: %+v", result)
+				}
+			},
+		},
+		{
+			name:   "hierarchical level picks candidate",
+			system: "Выбери код:\n- 25.11: Конструкции\n- 27.32: Кабели\n",
+			user:   "Объект: кабель\nКатегория: кабели",
+			check: func(t *testing.T, content string) {
+				var result fakeResult
+				json.Unmarshal([]byte(content), &result)
+				if result.SelectedCode != "25.11" && result.SelectedCode != "27.32" {
+					t.Errorf("selected_code = %q, want one of candidates", result.SelectedCode)
+				}
+			},
+		},
+		{
+			name:   "category path from classifier tree",
+			system: "Отвечай только в формате JSON.",
+			user:   "ОБЪЕКТ:\nНазвание: болт\n\nКЛАССИФИКАТОР КАТЕГОРИЙ:\n- Товары (ID: 1)\n  - Крепеж (ID: 2)\n",
+			check: func(t *testing.T, content string) {
+				var result fakeResult
+				json.Unmarshal([]byte(content), &result)
+				if len(result.CategoryPath) == 0 || result.CategoryPath[0] != "Товары" {
+					t.Errorf("category_path = %v, want path from root", result.CategoryPath)
+				}
+			},
+		},
+		{
+			name:   "batch",
+			system: "Возвращай результат в формате JSON массива.",
+			user:   "Наименования:\n0. Болт М10\n1. Гайка М10\n",
+			check: func(t *testing.T, content string) {
+				var results []fakeResult
+				if err := json.Unmarshal([]byte(content), &results); err != nil {
+					t.Fatalf("invalid json array: %v", err)
+				}
+				if len(results) != 2 || *results[1].Index != 1 || results[1].NormalizedName != "гайка м10" {
+					t.Errorf("unexpected batch: %s", content)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := client.GetCompletion(tt.system, tt.user)
+			if err != nil {
+				t.Fatalf("GetCompletion() error = %v", err)
+			}
+			again, _ := client.GetCompletion(tt.system, tt.user)
+			if content != again {
+				t.Errorf("responses are not deterministic: %q != %q", content, again)
+			}
+			tt.check(t, content)
+		})
+	}
+
+	result, err := client.ProcessProduct("Труба ПНД 32", "system")
+	if err != nil {
+		t.Fatalf("ProcessProduct() error = %v", err)
+	}
+	if result.NormalizedName != "труба пнд 32" {
+		t.Errorf("NormalizedName = %q", result.NormalizedName)
+	}
+}
+
+func TestFakeProviderFaultInjection(t *testing.T) {
+	if !features.AI {
+		t.Skip("AI subsystem is not included in this build")
+	}
+	tests := []struct {
+		name      string
+		config    FakeProviderConfig
+		wantErr   string
+		wantStats FakeProviderStats
+	}{
+		{"errors", FakeProviderConfig{ErrorRate: 1}, "API returned status", FakeProviderStats{Requests: 1, Errors: 1}},
+		{"malformed", FakeProviderConfig{MalformedRate: 1}, "failed to parse AI response", FakeProviderStats{Requests: 1, Malformed: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, provider := newFakeClient(tt.config)
+			_, err := client.ProcessProduct("Болт", "system")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ProcessProduct() error = %v, want %q", err, tt.wantErr)
+			}
+			if got := provider.Stats(); got != tt.wantStats {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.wantStats)
+			}
+		})
+	}
+
+	t.Run("latency", func(t *testing.T) {
+		client, _ := newFakeClient(FakeProviderConfig{Latency: 30 * time.Millisecond})
+		start := time.Now()
+		if _, err := client.GetCompletion("system", "Объект: болт"); err != nil {
+			t.Fatalf("GetCompletion() error = %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+			t.Errorf("elapsed = %v, want at least configured latency", elapsed)
+		}
+	})
+
+	t.Run("seeded failures are reproducible", func(t *testing.T) {
+		sequence := func() []bool {
+			client, _ := newFakeClient(FakeProviderConfig{ErrorRate: 0.5, Seed: 42})
+			var failed []bool
+			for i := 0; i < 10; i++ {
+				_, err := client.GetCompletion("system", "Объект: болт")
+				failed = append(failed, err != nil)
+			}
+			return failed
+		}
+		first, second := sequence(), sequence()
+		for i := range first {
+			if first[i] != second[i] {
+				t.Fatalf("failure sequences differ: %v vs %v", first, second)
+			}
+		}
+	})
+}
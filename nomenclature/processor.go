@@ -65,6 +65,7 @@ func NewProcessor(cfg Config) (*NomenclatureProcessor, error) {
 		aiClient: NewAIClient(apiKey, cfg.AIModel),
 		stats:    &ProcessingStats{},
 	}
+	processor.aiClient.SetJob("nomenclature_processor")
 
 	// Создаем системный промт
 	processor.createSystemPrompt()
@@ -345,3 +346,10 @@ func (p *NomenclatureProcessor) Close() error {
 	return nil
 }
 
+// AIClient возвращает AI клиент процессора (для применения изменений конфигурации на лету)
+func (p *NomenclatureProcessor) AIClient() *AIClient {
+	if p == nil {
+		return nil
+	}
+	return p.aiClient
+}
@@ -20,6 +20,7 @@ type AIResult struct {
 	Category       string  `json:"category"`
 	Confidence     float64 `json:"confidence"`
 	Reasoning      string  `json:"reasoning"`
+	Model          string  `json:"-"` // Модель, чей ответ принят (заполняется нормализатором)
 }
 
 // AIStats статистика работы AI нормализатора
@@ -38,6 +39,7 @@ type AINormalizer struct {
 	cache          *AICache
 	statsCollector *StatsCollector
 	systemPrompt   string
+	dictionaries   *Dictionaries // Словари проекта, добавляемые в системный промпт
 	stats          *AIStats // старая статистика для совместимости
 	batchProcessor *BatchProcessor // Батчевый процессор для группировки AI запросов
 	batchEnabled   bool // Флаг включения батчевой обработки
@@ -56,6 +58,7 @@ func NewAINormalizer(apiKey string, model ...string) *AINormalizer {
 		}
 	}
 	client := nomenclature.NewAIClient(apiKey, modelName)
+	client.SetJob("normalization")
 
 	// Создаем кеш с TTL 1 час и макс. 10000 записей
 	cache := NewAICache(1*time.Hour, 10000)
@@ -141,6 +144,34 @@ func NewAINormalizer(apiKey string, model ...string) *AINormalizer {
 	}
 }
 
+// WithModel возвращает AI нормализатор для другой модели (шаг цепочки моделей).
+// Клиент разделяет rate limiter с исходным, кэш собственный, батчевая обработка не используется.
+func (a *AINormalizer) WithModel(model string) *AINormalizer {
+	return &AINormalizer{
+		aiClient:       a.aiClient.WithModel(model),
+		cache:          NewAICache(time.Hour, 10000),
+		statsCollector: a.statsCollector,
+		systemPrompt:   a.systemPrompt,
+		dictionaries:   a.dictionaries,
+		stats:          a.stats,
+	}
+}
+
+// SetDictionaries задает словари проекта (сокращения, стоп-слова, защищенные токены) для системного промпта.
+// Кэш очищается: ответы, полученные с другими словарями, могут отличаться
+func (a *AINormalizer) SetDictionaries(dictionaries *Dictionaries) {
+	a.dictionaries = dictionaries
+	a.cache.Clear()
+}
+
+// buildSystemPrompt возвращает системный промпт с разделом словарей проекта
+func (a *AINormalizer) buildSystemPrompt() string {
+	if a.dictionaries == nil {
+		return a.systemPrompt
+	}
+	return a.systemPrompt + a.dictionaries.PromptSection()
+}
+
 // EnableBatchProcessing включает батчевую обработку AI запросов
 // batchSize - количество элементов в одном батче
 // flushInterval - интервал автоматической обработки накопленных запросов
@@ -158,10 +189,20 @@ func (a *AINormalizer) EnableBatchProcessing(batchSize int, flushInterval time.D
 
 // NormalizeWithAI нормализует название товара с помощью AI
 func (a *AINormalizer) NormalizeWithAI(name string) (*AIResult, error) {
+	return a.NormalizeWithAttributes(name, nil)
+}
+
+// NormalizeWithAttributes нормализует название товара с помощью AI, передавая в промпте атрибуты элемента
+// (производитель, артикул, ГОСТ). Ответы кэшируются отдельно для каждого набора атрибутов;
+// запросы с атрибутами не группируются батчевым процессором
+func (a *AINormalizer) NormalizeWithAttributes(name string, attributes []PromptAttribute) (*AIResult, error) {
 	startTime := time.Now()
 
 	// Проверяем кэш (case-insensitive)
 	sourceName := strings.ToLower(strings.TrimSpace(name))
+	if key := promptAttributesKey(attributes); key != "" {
+		sourceName += "\x00" + key
+	}
 
 	if cached, exists := a.cache.Get(sourceName); exists {
 		// Кеш hit
@@ -184,7 +225,7 @@ func (a *AINormalizer) NormalizeWithAI(name string) (*AIResult, error) {
 	a.statsCollector.RecordCacheAccess(false, cacheStats.Entries, cacheStats.MemoryUsageB)
 
 	// Используем батчевую обработку если включена
-	if a.batchEnabled && a.batchProcessor != nil {
+	if a.batchEnabled && a.batchProcessor != nil && len(attributes) == 0 {
 		result := a.batchProcessor.Add(name)
 
 		duration := time.Since(startTime)
@@ -213,8 +254,8 @@ func (a *AINormalizer) NormalizeWithAI(name string) (*AIResult, error) {
 	}
 
 	// Отправляем запрос к AI
-	userPrompt := fmt.Sprintf("НАИМЕНОВАНИЕ ТОВАРА ДЛЯ ОБРАБОТКИ: \"%s\"", name)
-	response, err := a.aiClient.GetCompletion(a.systemPrompt, userPrompt)
+	userPrompt := fmt.Sprintf("НАИМЕНОВАНИЕ ТОВАРА ДЛЯ ОБРАБОТКИ: \"%s\"", name) + promptAttributesSection(attributes)
+	response, err := a.aiClient.GetCompletion(a.buildSystemPrompt(), userPrompt)
 
 	duration := time.Since(startTime)
 
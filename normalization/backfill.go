@@ -0,0 +1,183 @@
+package normalization
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"httpserver/database"
+)
+
+// Параметры заданий заполнения по умолчанию
+const (
+	DefaultBackfillBatchSize = 1000
+	MaxBackfillBatchSize     = 50000
+)
+
+// BackfillStage этап преобразования, вычисляющий производную колонку normalized_data по записи.
+// Новые производные поля (единицы, бренды, размеры) регистрируются этапом вместо отдельной утилиты в cmd/
+type BackfillStage interface {
+	Name() string
+	Column() string
+	Description() string
+	Transform(row database.BackfillRow) (string, error)
+}
+
+var (
+	backfillStagesMu sync.RWMutex
+	backfillStages   = map[string]BackfillStage{
+		"standard_references": standardReferencesStage{},
+	}
+)
+
+// RegisterBackfillStage добавляет этап заполнения или заменяет этап с тем же именем
+func RegisterBackfillStage(stage BackfillStage) {
+	backfillStagesMu.Lock()
+	defer backfillStagesMu.Unlock()
+	backfillStages[stage.Name()] = stage
+}
+
+// LookupBackfillStage возвращает этап заполнения по имени
+func LookupBackfillStage(name string) (BackfillStage, bool) {
+	backfillStagesMu.RLock()
+	defer backfillStagesMu.RUnlock()
+	stage, ok := backfillStages[name]
+	return stage, ok
+}
+
+// BackfillStages возвращает зарегистрированные этапы по имени
+func BackfillStages() []BackfillStage {
+	backfillStagesMu.RLock()
+	defer backfillStagesMu.RUnlock()
+	stages := make([]BackfillStage, 0, len(backfillStages))
+	for _, stage := range backfillStages {
+		stages = append(stages, stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i].Name() < stages[j].Name() })
+	return stages
+}
+
+// standardReferencesStage обозначения стандартов из исходного наименования, как при нормализации
+type standardReferencesStage struct{}
+
+func (standardReferencesStage) Name() string   { return "standard_references" }
+func (standardReferencesStage) Column() string { return "standard_references" }
+func (standardReferencesStage) Description() string {
+	return "Обозначения стандартов (ГОСТ, ТУ, DIN, ISO) из исходного наименования"
+}
+func (standardReferencesStage) Transform(row database.BackfillRow) (string, error) {
+	return StandardReferencesValue(ExtractStandards(row.SourceName)), nil
+}
+
+// NewBackfillJob создает задание заполнения колонки этапа в области scope
+func NewBackfillJob(db *database.DB, stageName string, scope database.BackfillScope, batchSize int, throttle time.Duration) (*database.BackfillJob, error) {
+	stage, ok := LookupBackfillStage(stageName)
+	if !ok {
+		return nil, fmt.Errorf("unknown backfill stage: %s", stageName)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBackfillBatchSize
+	}
+	if batchSize > MaxBackfillBatchSize {
+		batchSize = MaxBackfillBatchSize
+	}
+	if throttle < 0 {
+		throttle = 0
+	}
+
+	job := &database.BackfillJob{
+		Stage:      stage.Name(),
+		Column:     stage.Column(),
+		Scope:      scope,
+		BatchSize:  batchSize,
+		ThrottleMs: int(throttle / time.Millisecond),
+	}
+	if err := db.CreateBackfillJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// RunBackfill выполняет задание пакетами с позиции job.LastID, сохраняя прогресс после каждого пакета.
+// Отмена ctx приостанавливает задание: повторный запуск продолжит его со следующего пакета.
+// Ошибки преобразования отдельных записей считаются в Failed и не останавливают задание
+func RunBackfill(ctx context.Context, db *database.DB, job *database.BackfillJob, progress func(*database.BackfillJob)) error {
+	stage, ok := LookupBackfillStage(job.Stage)
+	if !ok {
+		return fmt.Errorf("unknown backfill stage: %s", job.Stage)
+	}
+
+	fail := func(err error) error {
+		job.Status = database.BackfillFailed
+		job.LastError = err.Error()
+		if saveErr := db.SaveBackfillProgress(job); saveErr != nil {
+			return fmt.Errorf("%w (progress not saved: %v)", err, saveErr)
+		}
+		return err
+	}
+
+	job.Status = database.BackfillRunning
+	if err := db.SaveBackfillProgress(job); err != nil {
+		return err
+	}
+
+	for {
+		if ctx.Err() != nil {
+			job.Status = database.BackfillPaused
+			if err := db.SaveBackfillProgress(job); err != nil {
+				return err
+			}
+			return ctx.Err()
+		}
+
+		batch, err := db.GetBackfillBatch(job)
+		if err != nil {
+			return fail(err)
+		}
+		if len(batch) == 0 {
+			job.Status = database.BackfillCompleted
+			if err := db.SaveBackfillProgress(job); err != nil {
+				return err
+			}
+			if progress != nil {
+				progress(job)
+			}
+			return nil
+		}
+
+		values := make(map[int]string)
+		for _, row := range batch {
+			value, err := stage.Transform(row)
+			if err != nil {
+				job.Failed++
+				job.LastError = fmt.Sprintf("item %d: %v", row.ID, err)
+				continue
+			}
+			if value != row.Value {
+				values[row.ID] = value
+			}
+		}
+		if err := db.ApplyBackfillBatch(job.Column, values); err != nil {
+			return fail(err)
+		}
+
+		job.LastID = batch[len(batch)-1].ID
+		job.Processed += len(batch)
+		job.Updated += len(values)
+		if err := db.SaveBackfillProgress(job); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(job)
+		}
+
+		if job.ThrottleMs > 0 {
+			select {
+			case <-time.After(time.Duration(job.ThrottleMs) * time.Millisecond):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package normalization
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"httpserver/database"
+)
+
+// failingBackfillStage этап, не преобразующий записи без исходного наименования
+type failingBackfillStage struct{}
+
+func (failingBackfillStage) Name() string        { return "test_failing" }
+func (failingBackfillStage) Column() string      { return "ai_reasoning" }
+func (failingBackfillStage) Description() string { return "" }
+func (failingBackfillStage) Transform(row database.BackfillRow) (string, error) {
+	if row.SourceName == "" {
+		return "", errors.New("empty source name")
+	}
+	return "len:" + row.SourceName, nil
+}
+
+func TestRunBackfill(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO normalized_data (id, source_reference, source_name, code, normalized_name, category, standard_references)
+		VALUES (1, 'r1', 'Болт М10 ГОСТ 7798-70', '1', 'болт', 'крепеж', ''),
+		       (2, 'r2', 'Гайка М10 DIN 934', '2', 'гайка', 'крепеж', ''),
+		       (3, 'r3', 'Шайба', '3', 'шайба', 'крепеж', ''),
+		       (4, 'r4', 'Труба ТУ 14-3-1128-2000', '4', 'труба', 'металлопрокат', 'устарело'),
+		       (5, 'r5', '', '5', 'пусто', 'прочее', '')`); err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+
+	t.Run("unknown stage", func(t *testing.T) {
+		if _, err := NewBackfillJob(db, "missing", database.BackfillScope{}, 10, 0); err == nil {
+			t.Error("NewBackfillJob(missing) error = nil, want unknown stage")
+		}
+	})
+
+	t.Run("resume after cancel", func(t *testing.T) {
+		job, err := NewBackfillJob(db, "standard_references", database.BackfillScope{}, 2, 0)
+		if err != nil {
+			t.Fatalf("NewBackfillJob() error = %v", err)
+		}
+		if job.Total != 5 || job.BatchSize != 2 {
+			t.Fatalf("job = %+v, want 5 rows in batches of 2", job)
+		}
+
+		// Отмена после первого пакета: задание приостанавливается на сохраненной позиции
+		ctx, cancel := context.WithCancel(context.Background())
+		err = RunBackfill(ctx, db, job, func(*database.BackfillJob) { cancel() })
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("RunBackfill() error = %v, want context.Canceled", err)
+		}
+		saved, err := db.GetBackfillJob(job.ID)
+		if err != nil || saved.Status != database.BackfillPaused || saved.LastID != 2 || saved.Processed != 2 {
+			t.Fatalf("paused job = %+v, %v, want paused at id 2", saved, err)
+		}
+
+		if err := RunBackfill(context.Background(), db, saved, nil); err != nil {
+			t.Fatalf("RunBackfill() resume error = %v", err)
+		}
+		if saved.Status != database.BackfillCompleted || saved.Processed != 5 || saved.Updated != 3 || saved.FinishedAt == nil {
+			t.Errorf("completed job = %+v, want 5 processed, 3 updated", saved)
+		}
+
+		var value string
+		if err := db.QueryRow(`SELECT standard_references FROM normalized_data WHERE id = 4`).Scan(&value); err != nil || value != "ТУ 14-3-1128-2000" {
+			t.Errorf("standard_references = %q, %v, want recomputed value", value, err)
+		}
+	})
+
+	t.Run("scope and row errors", func(t *testing.T) {
+		RegisterBackfillStage(failingBackfillStage{})
+		scope := database.BackfillScope{IDFrom: 3, OnlyEmpty: true}
+		job, err := NewBackfillJob(db, "test_failing", scope, 0, 0)
+		if err != nil {
+			t.Fatalf("NewBackfillJob() error = %v", err)
+		}
+		if job.Total != 3 || job.BatchSize != DefaultBackfillBatchSize {
+			t.Fatalf("job = %+v, want 3 rows with default batch", job)
+		}
+		if err := RunBackfill(context.Background(), db, job, nil); err != nil {
+			t.Fatalf("RunBackfill() error = %v", err)
+		}
+		if job.Processed != 3 || job.Updated != 2 || job.Failed != 1 || job.LastError == "" {
+			t.Errorf("job = %+v, want 2 updated and 1 failed", job)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package normalization
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"httpserver/database"
+)
+
+const (
+	calibrationBinCount   = 10              // Количество интервалов гистограммы уверенности
+	minCalibrationSamples = 30              // Меньше исходов - уверенность модели используется как есть
+	maxCalibrationSamples = 5000            // Учитываются только последние исходы
+	calibrationCurveTTL   = 5 * time.Minute // Период переобучения кривых
+)
+
+// CalibrationBin интервал кривой калибровки
+type CalibrationBin struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	MeanConfidence float64 `json:"mean_confidence"` // Средняя уверенность модели в интервале
+	Accuracy       float64 `json:"accuracy"`        // Доля верных ответов (после сглаживания)
+	Count          int     `json:"count"`
+}
+
+// CalibrationCurve кривая калибровки модели: уверенность модели -> фактическая точность
+type CalibrationCurve struct {
+	Model    string           `json:"model"`
+	Samples  int              `json:"samples"`
+	Bins     []CalibrationBin `json:"bins"`
+	Active   bool             `json:"active"` // Достаточно исходов для применения
+	FittedAt time.Time        `json:"fitted_at"`
+}
+
+// ConfidenceEvaluation результат калибровки и применения политики порогов
+type ConfidenceEvaluation struct {
+	Model         string                    `json:"model"`
+	RawConfidence float64                   `json:"raw_confidence"`
+	Confidence    float64                   `json:"confidence"` // Откалиброванная уверенность
+	Decision      string                    `json:"decision"`
+	Policy        database.ConfidencePolicy `json:"policy"`
+}
+
+// FitCalibrationCurve строит кривую калибровки по проверенным исходам:
+// гистограмма по интервалам уверенности со сглаживанием Лапласа и
+// изотонической регрессией (точность не убывает с ростом уверенности)
+func FitCalibrationCurve(model string, outcomes []database.ClassificationOutcome) *CalibrationCurve {
+	curve := &CalibrationCurve{
+		Model:    model,
+		Samples:  len(outcomes),
+		Bins:     []CalibrationBin{},
+		Active:   len(outcomes) >= minCalibrationSamples,
+		FittedAt: time.Now(),
+	}
+
+	type binStats struct {
+		sumConfidence float64
+		correct       int
+		count         int
+	}
+	stats := make([]binStats, calibrationBinCount)
+	for _, outcome := range outcomes {
+		confidence := clampConfidence(outcome.RawConfidence)
+		index := int(confidence * calibrationBinCount)
+		if index >= calibrationBinCount {
+			index = calibrationBinCount - 1
+		}
+		stats[index].sumConfidence += confidence
+		stats[index].count++
+		if outcome.Correct {
+			stats[index].correct++
+		}
+	}
+
+	for i, s := range stats {
+		if s.count == 0 {
+			continue
+		}
+		curve.Bins = append(curve.Bins, CalibrationBin{
+			Lower:          float64(i) / calibrationBinCount,
+			Upper:          float64(i+1) / calibrationBinCount,
+			MeanConfidence: s.sumConfidence / float64(s.count),
+			Accuracy:       (float64(s.correct) + 1) / (float64(s.count) + 2),
+			Count:          s.count,
+		})
+	}
+
+	poolAdjacentViolators(curve.Bins)
+	return curve
+}
+
+// poolAdjacentViolators делает точность интервалов неубывающей, объединяя нарушающие соседние интервалы
+func poolAdjacentViolators(bins []CalibrationBin) {
+	type block struct {
+		start, end int
+		weight     float64
+		value      float64
+	}
+	var blocks []block
+	for i, bin := range bins {
+		blocks = append(blocks, block{start: i, end: i, weight: float64(bin.Count), value: bin.Accuracy})
+		for len(blocks) > 1 && blocks[len(blocks)-2].value > blocks[len(blocks)-1].value {
+			last, prev := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			merged := block{
+				start:  prev.start,
+				end:    last.end,
+				weight: prev.weight + last.weight,
+				value:  (prev.value*prev.weight + last.value*last.weight) / (prev.weight + last.weight),
+			}
+			blocks = append(blocks[:len(blocks)-2], merged)
+		}
+	}
+	for _, b := range blocks {
+		for i := b.start; i <= b.end; i++ {
+			bins[i].Accuracy = b.value
+		}
+	}
+}
+
+// Calibrate переводит уверенность модели в откалиброванную (линейная интерполяция между интервалами).
+// Если исходов недостаточно, уверенность возвращается без изменений.
+func (c *CalibrationCurve) Calibrate(raw float64) float64 {
+	raw = clampConfidence(raw)
+	if c == nil || !c.Active || len(c.Bins) == 0 {
+		return raw
+	}
+
+	bins := c.Bins
+	if raw <= bins[0].MeanConfidence {
+		return bins[0].Accuracy
+	}
+	last := bins[len(bins)-1]
+	if raw >= last.MeanConfidence {
+		return last.Accuracy
+	}
+
+	i := sort.Search(len(bins), func(i int) bool { return bins[i].MeanConfidence >= raw })
+	left, right := bins[i-1], bins[i]
+	span := right.MeanConfidence - left.MeanConfidence
+	if span <= 0 {
+		return right.Accuracy
+	}
+	ratio := (raw - left.MeanConfidence) / span
+	return left.Accuracy + ratio*(right.Accuracy-left.Accuracy)
+}
+
+// clampConfidence ограничивает уверенность интервалом [0, 1]
+func clampConfidence(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
+
+// Calibrator калибрует уверенность моделей и применяет политики порогов проектов.
+// Кривые кэшируются и переобучаются по истечении TTL или после Invalidate.
+type Calibrator struct {
+	db     *database.DB
+	mu     sync.RWMutex
+	curves map[string]*CalibrationCurve
+}
+
+// NewCalibrator создает калибратор, использующий исходы и политики из БД
+func NewCalibrator(db *database.DB) *Calibrator {
+	return &Calibrator{
+		db:     db,
+		curves: make(map[string]*CalibrationCurve),
+	}
+}
+
+// Curve возвращает кривую калибровки модели (при необходимости переобучает ее)
+func (c *Calibrator) Curve(model string) (*CalibrationCurve, error) {
+	c.mu.RLock()
+	curve, ok := c.curves[model]
+	c.mu.RUnlock()
+	if ok && time.Since(curve.FittedAt) < calibrationCurveTTL {
+		return curve, nil
+	}
+
+	outcomes, err := c.currentDB().GetClassificationOutcomes(model, maxCalibrationSamples)
+	if err != nil {
+		return nil, err
+	}
+	curve = FitCalibrationCurve(model, outcomes)
+
+	c.mu.Lock()
+	c.curves[model] = curve
+	c.mu.Unlock()
+
+	return curve, nil
+}
+
+// SetDB переключает калибратор на другую БД и сбрасывает кэш кривых
+func (c *Calibrator) SetDB(db *database.DB) {
+	c.mu.Lock()
+	c.db = db
+	c.curves = make(map[string]*CalibrationCurve)
+	c.mu.Unlock()
+}
+
+// currentDB возвращает БД калибратора
+func (c *Calibrator) currentDB() *database.DB {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db
+}
+
+// Invalidate сбрасывает кэш кривых (вызывается после записи новых исходов)
+func (c *Calibrator) Invalidate() {
+	c.mu.Lock()
+	c.curves = make(map[string]*CalibrationCurve)
+	c.mu.Unlock()
+}
+
+// Evaluate калибрует уверенность модели и принимает решение по политике проекта.
+// При ошибках чтения БД используется исходная уверенность и политика по умолчанию.
+func (c *Calibrator) Evaluate(model string, projectID int, raw float64) ConfidenceEvaluation {
+	evaluation := ConfidenceEvaluation{
+		Model:         model,
+		RawConfidence: raw,
+		Confidence:    clampConfidence(raw),
+		Policy:        database.DefaultConfidencePolicy(),
+	}
+	if c == nil {
+		evaluation.Decision = evaluation.Policy.Decide(evaluation.Confidence)
+		return evaluation
+	}
+
+	if curve, err := c.Curve(model); err != nil {
+		log.Printf("[Calibration] Не удалось получить кривую калибровки модели %s: %v", model, err)
+	} else {
+		evaluation.Confidence = curve.Calibrate(raw)
+	}
+
+	if policy, err := c.currentDB().GetConfidencePolicy(projectID); err != nil {
+		log.Printf("[Calibration] Не удалось получить политику порогов проекта %d: %v", projectID, err)
+	} else {
+		evaluation.Policy = policy
+	}
+
+	evaluation.Decision = evaluation.Policy.Decide(evaluation.Confidence)
+	return evaluation
+}
+
+// worseConfidenceDecision возвращает более строгое из двух решений (reject > review > accept)
+func worseConfidenceDecision(a, b string) string {
+	rank := map[string]int{
+		database.ConfidenceDecisionAccept: 1,
+		database.ConfidenceDecisionReview: 2,
+		database.ConfidenceDecisionReject: 3,
+	}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,185 @@
+package normalization
+
+import (
+	"math"
+	"testing"
+
+	"httpserver/database"
+)
+
+// syntheticOutcomes генерирует исходы, в которых модель переоценивает себя:
+// фактическая точность равна половине заявленной уверенности
+func syntheticOutcomes(perBin int) []database.ClassificationOutcome {
+	var outcomes []database.ClassificationOutcome
+	for bin := 0; bin < calibrationBinCount; bin++ {
+		confidence := (float64(bin) + 0.5) / calibrationBinCount
+		correct := int(float64(perBin) * confidence / 2)
+		for i := 0; i < perBin; i++ {
+			outcomes = append(outcomes, database.ClassificationOutcome{
+				Model:         "test-model",
+				RawConfidence: confidence,
+				Correct:       i < correct,
+			})
+		}
+	}
+	return outcomes
+}
+
+func TestFitCalibrationCurve(t *testing.T) {
+	curve := FitCalibrationCurve("test-model", syntheticOutcomes(20))
+	if !curve.Active || len(curve.Bins) != calibrationBinCount {
+		t.Fatalf("Expected active curve with %d bins, got active=%v bins=%d", calibrationBinCount, curve.Active, len(curve.Bins))
+	}
+	for i := 1; i < len(curve.Bins); i++ {
+		if curve.Bins[i].Accuracy < curve.Bins[i-1].Accuracy {
+			t.Fatalf("Accuracy must be non-decreasing: bin %d = %.3f < bin %d = %.3f",
+				i, curve.Bins[i].Accuracy, i-1, curve.Bins[i-1].Accuracy)
+		}
+	}
+
+	tests := []struct {
+		name string
+		raw  float64
+		min  float64
+		max  float64
+	}{
+		{"overconfident high", 0.95, 0.4, 0.55},
+		{"overconfident middle", 0.6, 0.25, 0.4},
+		{"interpolated", 0.5, 0.2, 0.35},
+		{"clamped above", 1.5, 0.4, 0.55},
+		{"clamped below", -1, 0, 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := curve.Calibrate(tt.raw)
+			if got < tt.min || got > tt.max {
+				t.Errorf("Calibrate(%.2f) = %.3f, want within [%.2f, %.2f]", tt.raw, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestFitCalibrationCurveInsufficientSamples(t *testing.T) {
+	outcomes := syntheticOutcomes(2)
+	curve := FitCalibrationCurve("test-model", outcomes[:minCalibrationSamples-1])
+	if curve.Active {
+		t.Fatal("Expected inactive curve below minimum samples")
+	}
+	if got := curve.Calibrate(0.9); got != 0.9 {
+		t.Errorf("Inactive curve must return raw confidence, got %.3f", got)
+	}
+
+	var nilCurve *CalibrationCurve
+	if got := nilCurve.Calibrate(0.7); got != 0.7 {
+		t.Errorf("Nil curve must return raw confidence, got %.3f", got)
+	}
+}
+
+func TestPoolAdjacentViolators(t *testing.T) {
+	bins := []CalibrationBin{
+		{Accuracy: 0.2, Count: 10},
+		{Accuracy: 0.6, Count: 10},
+		{Accuracy: 0.4, Count: 30},
+		{Accuracy: 0.9, Count: 10},
+	}
+	poolAdjacentViolators(bins)
+
+	want := []float64{0.2, 0.45, 0.45, 0.9}
+	for i, w := range want {
+		if math.Abs(bins[i].Accuracy-w) > 1e-9 {
+			t.Errorf("bin %d accuracy = %.3f, want %.3f", i, bins[i].Accuracy, w)
+		}
+	}
+}
+
+func TestConfidencePolicyDecide(t *testing.T) {
+	policy := database.ConfidencePolicy{AcceptThreshold: 0.8, ReviewThreshold: 0.5}
+	tests := []struct {
+		confidence float64
+		want       string
+	}{
+		{0.95, database.ConfidenceDecisionAccept},
+		{0.8, database.ConfidenceDecisionAccept},
+		{0.79, database.ConfidenceDecisionReview},
+		{0.5, database.ConfidenceDecisionReview},
+		{0.49, database.ConfidenceDecisionReject},
+	}
+	for _, tt := range tests {
+		if got := policy.Decide(tt.confidence); got != tt.want {
+			t.Errorf("Decide(%.2f) = %s, want %s", tt.confidence, got, tt.want)
+		}
+	}
+
+	if err := (database.ConfidencePolicy{AcceptThreshold: 0.4, ReviewThreshold: 0.6}).Validate(); err == nil {
+		t.Error("Expected error when review threshold exceeds accept threshold")
+	}
+	if got := worseConfidenceDecision(database.ConfidenceDecisionReview, database.ConfidenceDecisionAccept); got != database.ConfidenceDecisionReview {
+		t.Errorf("worseConfidenceDecision(review, accept) = %s, want review", got)
+	}
+	if got := worseConfidenceDecision("", database.ConfidenceDecisionAccept); got != database.ConfidenceDecisionAccept {
+		t.Errorf("worseConfidenceDecision(\"\", accept) = %s, want accept", got)
+	}
+}
+
+func TestCalibratorEvaluate(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	calibrator := NewCalibrator(db)
+
+	// Без политик используется политика по умолчанию, без исходов - исходная уверенность
+	evaluation := calibrator.Evaluate("test-model", 7, 0.9)
+	if evaluation.Confidence != 0.9 || evaluation.Decision != database.ConfidenceDecisionAccept {
+		t.Fatalf("Unexpected default evaluation: %+v", evaluation)
+	}
+
+	if err := db.SaveConfidencePolicy(database.ConfidencePolicy{ProjectID: 0, AcceptThreshold: 0.95, ReviewThreshold: 0.6}); err != nil {
+		t.Fatalf("SaveConfidencePolicy() error = %v", err)
+	}
+	if err := db.SaveConfidencePolicy(database.ConfidencePolicy{ProjectID: 7, AcceptThreshold: 0.8, ReviewThreshold: 0.3}); err != nil {
+		t.Fatalf("SaveConfidencePolicy() error = %v", err)
+	}
+	if got := calibrator.Evaluate("test-model", 7, 0.9).Decision; got != database.ConfidenceDecisionAccept {
+		t.Errorf("Project policy decision = %s, want accept", got)
+	}
+	if got := calibrator.Evaluate("test-model", 8, 0.9).Decision; got != database.ConfidenceDecisionReview {
+		t.Errorf("Global policy decision = %s, want review", got)
+	}
+
+	// Исходы записываются по группе с сохраненной моделью и исходной уверенностью
+	if _, err := db.Exec(`
+		INSERT INTO normalized_data (code, normalized_name, category, kpved_code, kpved_confidence, kpved_raw_confidence, kpved_model, confidence_decision)
+		VALUES ('1', 'болт', 'крепеж', '25.94', 0.9, 0.9, 'test-model', 'accept')
+	`); err != nil {
+		t.Fatalf("Failed to seed normalized_data: %v", err)
+	}
+	for i, correct := range []bool{true, true} {
+		recorded, err := db.RecordGroupOutcome("болт", "крепеж", correct)
+		if err != nil {
+			t.Fatalf("RecordGroupOutcome() error = %v", err)
+		}
+		if recorded != (i == 0) {
+			t.Errorf("RecordGroupOutcome() call %d recorded = %v", i, recorded)
+		}
+		if _, err := db.Exec(`UPDATE normalized_data SET validation_status = 'correct'`); err != nil {
+			t.Fatalf("Failed to mark group: %v", err)
+		}
+	}
+
+	outcomes, err := db.GetClassificationOutcomes("test-model", 10)
+	if err != nil {
+		t.Fatalf("GetClassificationOutcomes() error = %v", err)
+	}
+	if len(outcomes) != 1 || !outcomes[0].Correct || outcomes[0].RawConfidence != 0.9 {
+		t.Errorf("Unexpected outcomes: %+v", outcomes)
+	}
+
+	// Нулевой калибратор безопасен и применяет политику по умолчанию
+	var nilCalibrator *Calibrator
+	if got := nilCalibrator.Evaluate("test-model", 0, 0.3).Decision; got != database.ConfidenceDecisionReject {
+		t.Errorf("Nil calibrator decision = %s, want reject", got)
+	}
+}
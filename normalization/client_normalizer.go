@@ -1,6 +1,7 @@
 package normalization
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -21,6 +22,23 @@ type ClientNormalizationResult struct {
 	AIEnhancedItems     int
 	BasicNormalizedItems int
 	NewBenchmarksCreated int
+	ClientMappingMatches int
+	TotalItems          int // Записей на входе; TotalProcessed меньше при остановке
+}
+
+// CheckpointStatus прогресс нормализации в формате checkpoint фоновой задачи
+func (r *ClientNormalizationResult) CheckpointStatus() map[string]interface{} {
+	progressPercent := 0.0
+	if r.TotalItems > 0 {
+		progressPercent = float64(r.TotalProcessed) / float64(r.TotalItems) * 100.0
+	}
+	return map[string]interface{}{
+		"enabled":          true,
+		"active":           false,
+		"processed_count":  r.TotalProcessed,
+		"total_count":      r.TotalItems,
+		"progress_percent": progressPercent,
+	}
 }
 
 // ClientNormalizer нормализатор с поддержкой клиентских эталонов
@@ -64,6 +82,24 @@ func NewClientNormalizerWithConfig(clientID, projectID int, db *database.DB, ser
 		MaxRetries:     3,
 	}
 	normalizer.basicNormalizer = NewNormalizer(db, events, aiConfig)
+	normalizer.basicNormalizer.SetProjectID(projectID)
+	if serviceDB != nil {
+		if dictionaries, err := LoadProjectDictionaries(serviceDB, projectID); err != nil {
+			log.Printf("Не удалось загрузить словари проекта %d: %v", projectID, err)
+		} else {
+			normalizer.basicNormalizer.SetDictionaries(dictionaries)
+		}
+		if attributes, err := serviceDB.GetProjectPromptAttributes(projectID); err != nil {
+			log.Printf("Не удалось загрузить атрибуты промпта проекта %d: %v", projectID, err)
+		} else {
+			normalizer.basicNormalizer.SetPromptAttributes(attributes)
+		}
+		if mappings, err := LoadProjectCodeMappings(serviceDB, projectID); err != nil {
+			log.Printf("Не удалось загрузить соответствия кодов проекта %d: %v", projectID, err)
+		} else {
+			normalizer.basicNormalizer.SetCodeMappings(mappings)
+		}
+	}
 
 	// Инициализация AI клиента
 	var apiKey, model string
@@ -87,17 +123,37 @@ func NewClientNormalizerWithConfig(clientID, projectID int, db *database.DB, ser
 	
 	if apiKey != "" {
 		normalizer.aiClient = nomenclature.NewAIClient(apiKey, model)
+		normalizer.aiClient.SetJob("client_normalization")
 	}
 
 	return normalizer
 }
 
+// AIClients возвращает AI клиенты, используемые нормализатором
+func (c *ClientNormalizer) AIClients() []*nomenclature.AIClient {
+	var clients []*nomenclature.AIClient
+	if c.aiClient != nil {
+		clients = append(clients, c.aiClient)
+	}
+	if client := c.basicNormalizer.AIClient(); client != nil {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
 // ProcessWithClientBenchmarks выполняет нормализацию с использованием эталонов клиента
 func (c *ClientNormalizer) ProcessWithClientBenchmarks(items []*database.CatalogItem) (*ClientNormalizationResult, error) {
+	return c.ProcessWithClientBenchmarksContext(context.Background(), items)
+}
+
+// ProcessWithClientBenchmarksContext выполняет нормализацию с возможностью остановки через ctx.
+// Отмена проверяется перед каждой записью; при остановке возвращается результат по уже обработанным записям
+func (c *ClientNormalizer) ProcessWithClientBenchmarksContext(ctx context.Context, items []*database.CatalogItem) (*ClientNormalizationResult, error) {
 	result := &ClientNormalizationResult{
 		ClientID:     c.clientID,
 		ProjectID:    c.projectID,
 		ProcessedAt:  time.Now(),
+		TotalItems:   len(items),
 	}
 
 	c.sendEvent("Начало нормализации с использованием эталонов клиента...")
@@ -107,6 +163,22 @@ func (c *ClientNormalizer) ProcessWithClientBenchmarks(items []*database.Catalog
 	processedCount := 0
 
 	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			result.TotalProcessed = processedCount
+			result.TotalGroups = len(groups)
+			c.sendEvent(fmt.Sprintf("Нормализация остановлена. Обработано %d из %d записей", processedCount, len(items)))
+			return result, err
+		}
+
+		// 0. Соответствия кодов из файлов клиента имеют приоритет над эталонами и правилами
+		if groupCode := c.basicNormalizer.codeMappings.GroupCode(item.Code); groupCode != "" {
+			result.ClientMappingMatches++
+			key := fmt.Sprintf("mapping|%s", groupCode)
+			groups[key] = append(groups[key], item)
+			processedCount++
+			continue
+		}
+
 		// 1. Проверка против эталонов клиента
 		benchmark, found := c.benchmarkStore.FindBenchmark(item.Name)
 		if found {
@@ -134,15 +206,20 @@ func (c *ClientNormalizer) ProcessWithClientBenchmarks(items []*database.Catalog
 		// 3. AI-усиление если требуется
 		if c.basicNormalizer.useAI && c.basicNormalizer.aiNormalizer != nil && 
 		   c.basicNormalizer.aiNormalizer.RequiresAI(item.Name, category) {
-			aiResult, err := c.basicNormalizer.processWithAI(item.Name)
-			if err == nil && aiResult.Confidence >= c.basicNormalizer.aiConfig.MinConfidence {
+			aiResult, _, err := c.basicNormalizer.processWithAIChain(item.Name, c.basicNormalizer.itemPromptAttributes(item))
+			var evaluation ConfidenceEvaluation
+			if err == nil {
+				evaluation = c.basicNormalizer.calibrator.Evaluate(aiResult.Model, c.projectID, aiResult.Confidence)
+			}
+			if err == nil && evaluation.Decision != database.ConfidenceDecisionReject &&
+				evaluation.Confidence >= c.basicNormalizer.aiConfig.MinConfidence {
 				category = aiResult.Category
 				normalizedName = aiResult.NormalizedName
-				aiConfidence = aiResult.Confidence
+				aiConfidence = evaluation.Confidence
 				result.AIEnhancedItems++
 
-				// Сохраняем как потенциальный эталон
-				if aiConfidence >= 0.9 {
+				// Сохраняем как потенциальный эталон только автоматически принятые результаты
+				if aiConfidence >= 0.9 && evaluation.Decision == database.ConfidenceDecisionAccept {
 					if err := c.benchmarkStore.SavePotentialBenchmark(
 						item.Name,
 						normalizedName,
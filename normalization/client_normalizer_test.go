@@ -0,0 +1,26 @@
+package normalization
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestProcessWithClientBenchmarksContextCancel(t *testing.T) {
+	t.Setenv("ARLIAI_API_KEY", "")
+	normalizer := NewClientNormalizer(1, 1, nil, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	items := []*database.CatalogItem{{Name: "Болт М8"}, {Name: "Гайка М8"}}
+	result, err := normalizer.ProcessWithClientBenchmarksContext(ctx, items)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ProcessWithClientBenchmarksContext() error = %v, want context.Canceled", err)
+	}
+	checkpoint := result.CheckpointStatus()
+	if checkpoint["processed_count"] != 0 || checkpoint["total_count"] != 2 {
+		t.Errorf("checkpoint = %v, want 0 of 2 processed", checkpoint)
+	}
+}
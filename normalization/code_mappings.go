@@ -0,0 +1,319 @@
+package normalization
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"httpserver/database"
+)
+
+// Типы конфликтов соответствий клиента с объединениями, найденными системой
+const (
+	MappingConflictSystemSplit  = "system_split"  // Клиент объединяет коды, а система разнесла их по разным группам
+	MappingConflictSystemMerged = "system_merged" // Система объединила коды, которые клиент сопоставил разным новым кодам
+)
+
+// mappingColumnAliases названия колонок заголовка файла соответствий
+var mappingColumnAliases = map[string][]string{
+	"source": {"source_code", "old_code", "source", "старый код", "код клиента", "исходный код"},
+	"target": {"target_code", "new_code", "target", "новый код", "эталонный код"},
+	"name":   {"target_name", "new_name", "name", "наименование", "новое наименование"},
+}
+
+// CodeMappingConflict расхождение соответствия клиента с группами нормализации
+type CodeMappingConflict struct {
+	Type        string            `json:"type"`
+	Codes       []string          `json:"codes"`
+	Targets     map[string]string `json:"targets"`      // Код -> новый код по соответствию клиента
+	SystemGroup map[string]string `json:"system_group"` // Код -> группа нормализации (normalized_reference)
+	Message     string            `json:"message"`
+}
+
+// ParseCodeMappingRows разбирает строки файла соответствий и проверяет их согласованность внутри файла.
+// Колонки определяются по заголовку; без заголовка: старый код, новый код, наименование
+func ParseCodeMappingRows(rows [][]string) []*database.ClientCodeMapping {
+	columns := map[string]int{"source": 0, "target": 1, "name": 2}
+	start := 0
+	if len(rows) > 0 {
+		if header, ok := mappingHeaderColumns(rows[0]); ok {
+			columns = header
+			start = 1
+		}
+	}
+
+	cell := func(row []string, column string) string {
+		index, ok := columns[column]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	var mappings []*database.ClientCodeMapping
+	firstRow := make(map[string]*database.ClientCodeMapping)
+	for i := start; i < len(rows); i++ {
+		row := rows[i]
+		mapping := &database.ClientCodeMapping{
+			RowNumber:  i + 1,
+			SourceCode: cell(row, "source"),
+			TargetCode: cell(row, "target"),
+			TargetName: cell(row, "name"),
+			Status:     database.CodeMappingStatusValid,
+		}
+		if mapping.SourceCode == "" && mapping.TargetCode == "" && mapping.TargetName == "" {
+			continue
+		}
+
+		switch first, duplicate := firstRow[mapping.SourceCode]; {
+		case mapping.SourceCode == "" || mapping.TargetCode == "":
+			mapping.Status = database.CodeMappingStatusInvalid
+			mapping.Issue = "source and target codes are required"
+		case mapping.SourceCode == mapping.TargetCode:
+			mapping.Status = database.CodeMappingStatusInvalid
+			mapping.Issue = "source code maps to itself"
+		case duplicate && first.TargetCode != mapping.TargetCode:
+			mapping.Status = database.CodeMappingStatusInvalid
+			mapping.Issue = fmt.Sprintf("conflicts with row %d mapping to %s", first.RowNumber, first.TargetCode)
+		case duplicate:
+			mapping.Status = database.CodeMappingStatusInvalid
+			mapping.Issue = fmt.Sprintf("duplicate of row %d", first.RowNumber)
+		default:
+			firstRow[mapping.SourceCode] = mapping
+		}
+		mappings = append(mappings, mapping)
+	}
+
+	// Цепочки A -> B -> C допустимы и разрешаются до конечного кода, циклы отклоняются
+	for _, mapping := range mappings {
+		if mapping.Status != database.CodeMappingStatusValid {
+			continue
+		}
+		next, chained := firstRow[mapping.TargetCode]
+		if !chained {
+			continue
+		}
+		if _, cycle := resolveMappingChain(firstRow, mapping.SourceCode); cycle {
+			mapping.Status = database.CodeMappingStatusInvalid
+			mapping.Issue = "mapping cycle"
+			continue
+		}
+		mapping.Status = database.CodeMappingStatusWarning
+		mapping.Issue = fmt.Sprintf("target code is remapped in row %d", next.RowNumber)
+	}
+	return mappings
+}
+
+// mappingHeaderColumns определяет колонки по строке заголовка
+func mappingHeaderColumns(row []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, value := range row {
+		value = strings.ToLower(strings.TrimSpace(value))
+		for column, aliases := range mappingColumnAliases {
+			if _, found := columns[column]; found {
+				continue
+			}
+			for _, alias := range aliases {
+				if value == alias {
+					columns[column] = i
+					break
+				}
+			}
+		}
+	}
+	_, hasSource := columns["source"]
+	_, hasTarget := columns["target"]
+	return columns, hasSource && hasTarget
+}
+
+// resolveMappingChain следует по цепочке соответствий до конечного кода
+func resolveMappingChain(bySource map[string]*database.ClientCodeMapping, code string) (string, bool) {
+	visited := map[string]bool{code: true}
+	for {
+		mapping, ok := bySource[code]
+		if !ok {
+			return code, false
+		}
+		code = mapping.TargetCode
+		if visited[code] {
+			return code, true
+		}
+		visited[code] = true
+	}
+}
+
+// ValidateCodeMappingReferences проверяет коды соответствий по данным проекта:
+// отсутствующий старый код или новый код без наименования отмечаются предупреждением
+func ValidateCodeMappingReferences(mappings []*database.ClientCodeMapping, existing map[string]bool) {
+	for _, mapping := range mappings {
+		if mapping.Status == database.CodeMappingStatusInvalid {
+			continue
+		}
+		var issues []string
+		if mapping.Issue != "" {
+			issues = append(issues, mapping.Issue)
+		}
+		if !existing[mapping.SourceCode] {
+			issues = append(issues, "source code not found in project data")
+		}
+		if !existing[mapping.TargetCode] && mapping.TargetName == "" {
+			issues = append(issues, "target code not found in project data and no target name given")
+		}
+		if len(issues) > 0 {
+			mapping.Status = database.CodeMappingStatusWarning
+			mapping.Issue = strings.Join(issues, "; ")
+		}
+	}
+}
+
+// CodeMappingCodes возвращает уникальные старые и новые коды соответствий
+func CodeMappingCodes(mappings []*database.ClientCodeMapping) []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, mapping := range mappings {
+		for _, code := range []string{mapping.SourceCode, mapping.TargetCode} {
+			if code != "" && !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	return codes
+}
+
+// CodeMappings применяемые соответствия кодов клиента для этапа перед нормализацией
+type CodeMappings struct {
+	bySource map[string]*database.ClientCodeMapping
+	targets  map[string]bool
+}
+
+// NewCodeMappings создает набор соответствий; неприменяемые строки пропускаются
+func NewCodeMappings(mappings []*database.ClientCodeMapping) *CodeMappings {
+	m := &CodeMappings{
+		bySource: make(map[string]*database.ClientCodeMapping),
+		targets:  make(map[string]bool),
+	}
+	for _, mapping := range mappings {
+		if mapping.IsApplicable() {
+			m.bySource[mapping.SourceCode] = mapping
+		}
+	}
+	for source := range m.bySource {
+		if target, cycle := resolveMappingChain(m.bySource, source); !cycle {
+			m.targets[target] = true
+		}
+	}
+	return m
+}
+
+// LoadProjectCodeMappings загружает соответствия проекта из сервисной БД
+func LoadProjectCodeMappings(serviceDB *database.ServiceDB, projectID int) (*CodeMappings, error) {
+	mappings, err := serviceDB.GetActiveClientCodeMappings(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return NewCodeMappings(mappings), nil
+}
+
+// Len возвращает число применяемых соответствий
+func (m *CodeMappings) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.bySource)
+}
+
+// Lookup возвращает соответствие для старого кода
+func (m *CodeMappings) Lookup(code string) (*database.ClientCodeMapping, bool) {
+	if m == nil || code == "" {
+		return nil, false
+	}
+	mapping, ok := m.bySource[code]
+	return mapping, ok
+}
+
+// GroupCode возвращает конечный новый код, объединяющий запись с другими записями по соответствиям клиента.
+// Для кода, который сам является новым кодом, возвращается он же; для кода вне соответствий - пустая строка
+func (m *CodeMappings) GroupCode(code string) string {
+	if m == nil || code == "" {
+		return ""
+	}
+	if _, ok := m.bySource[code]; ok {
+		if target, cycle := resolveMappingChain(m.bySource, code); !cycle {
+			return target
+		}
+		return ""
+	}
+	if m.targets[code] {
+		return code
+	}
+	return ""
+}
+
+// FindCodeMappingConflicts сравнивает соответствия клиента с группами нормализации (код -> normalized_reference)
+func FindCodeMappingConflicts(mappings *CodeMappings, references map[string]string) []CodeMappingConflict {
+	conflicts := []CodeMappingConflict{}
+	if mappings.Len() == 0 {
+		return conflicts
+	}
+
+	sources := make([]string, 0, len(mappings.bySource))
+	for source := range mappings.bySource {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	// Клиент объединяет старый и новый код, а система разнесла их по разным группам
+	for _, source := range sources {
+		target := mappings.GroupCode(source)
+		sourceGroup, sourceFound := references[source]
+		targetGroup, targetFound := references[target]
+		if target == "" || !sourceFound || !targetFound || sourceGroup == targetGroup {
+			continue
+		}
+		conflicts = append(conflicts, CodeMappingConflict{
+			Type:        MappingConflictSystemSplit,
+			Codes:       []string{source, target},
+			Targets:     map[string]string{source: target},
+			SystemGroup: map[string]string{source: sourceGroup, target: targetGroup},
+			Message:     fmt.Sprintf("Код %s сопоставлен клиентом коду %s, но система отнесла их к разным группам", source, target),
+		})
+	}
+
+	// Система объединила коды, которые клиент сопоставил разным новым кодам
+	byGroup := make(map[string][]string)
+	for _, source := range sources {
+		if group, ok := references[source]; ok && mappings.GroupCode(source) != "" {
+			byGroup[group] = append(byGroup[group], source)
+		}
+	}
+	groups := make([]string, 0, len(byGroup))
+	for group := range byGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		codes := byGroup[group]
+		targets := make(map[string]string, len(codes))
+		distinct := make(map[string]bool)
+		for _, code := range codes {
+			targets[code] = mappings.GroupCode(code)
+			distinct[targets[code]] = true
+		}
+		if len(distinct) < 2 {
+			continue
+		}
+		systemGroup := make(map[string]string, len(codes))
+		for _, code := range codes {
+			systemGroup[code] = group
+		}
+		conflicts = append(conflicts, CodeMappingConflict{
+			Type:        MappingConflictSystemMerged,
+			Codes:       codes,
+			Targets:     targets,
+			SystemGroup: systemGroup,
+			Message:     fmt.Sprintf("Система объединила в группу %q коды, которые клиент сопоставил %d разным кодам", group, len(distinct)),
+		})
+	}
+	return conflicts
+}
@@ -0,0 +1,144 @@
+package normalization
+
+import (
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestParseCodeMappingRows(t *testing.T) {
+	rows := [][]string{
+		{"Наименование", "Старый код", "Новый код"},
+		{"Болт М8", "A-1", "N-1"},
+		{"", "A-2", "N-1"},
+		{"", "A-1", "N-1"},
+		{"", "A-2", "N-2"},
+		{"", "A-3", "A-3"},
+		{"", "", "N-5"},
+		{"", "", ""},
+		{"", "A-4", "A-5"},
+		{"", "A-5", "N-5"},
+		{"", "C-1", "C-2"},
+		{"", "C-2", "C-1"},
+	}
+	mappings := ParseCodeMappingRows(rows)
+
+	want := []struct {
+		row    int
+		source string
+		status string
+	}{
+		{2, "A-1", database.CodeMappingStatusValid},
+		{3, "A-2", database.CodeMappingStatusValid},
+		{4, "A-1", database.CodeMappingStatusInvalid},
+		{5, "A-2", database.CodeMappingStatusInvalid},
+		{6, "A-3", database.CodeMappingStatusInvalid},
+		{7, "", database.CodeMappingStatusInvalid},
+		{9, "A-4", database.CodeMappingStatusWarning},
+		{10, "A-5", database.CodeMappingStatusValid},
+		{11, "C-1", database.CodeMappingStatusInvalid},
+		{12, "C-2", database.CodeMappingStatusInvalid},
+	}
+	if len(mappings) != len(want) {
+		t.Fatalf("ParseCodeMappingRows() = %d rows, want %d", len(mappings), len(want))
+	}
+	for i, w := range want {
+		m := mappings[i]
+		if m.RowNumber != w.row || m.SourceCode != w.source || m.Status != w.status {
+			t.Errorf("mapping[%d] = row %d %q %s (%s), want row %d %q %s", i, m.RowNumber, m.SourceCode, m.Status, m.Issue, w.row, w.source, w.status)
+		}
+		if m.Status != database.CodeMappingStatusValid && m.Issue == "" {
+			t.Errorf("mapping[%d] has status %s without issue", i, m.Status)
+		}
+	}
+	if mappings[0].TargetName != "Болт М8" {
+		t.Errorf("target name = %q, want column detected by header", mappings[0].TargetName)
+	}
+
+	noHeader := ParseCodeMappingRows([][]string{{"X-1", "Y-1", "Гайка"}})
+	if len(noHeader) != 1 || noHeader[0].TargetCode != "Y-1" || noHeader[0].TargetName != "Гайка" {
+		t.Errorf("ParseCodeMappingRows() without header = %+v", noHeader)
+	}
+}
+
+func TestValidateCodeMappingReferences(t *testing.T) {
+	mappings := ParseCodeMappingRows([][]string{
+		{"A-1", "N-1"},
+		{"A-2", "N-2", "Новое наименование"},
+		{"A-3", "N-3"},
+	})
+	ValidateCodeMappingReferences(mappings, map[string]bool{"A-1": true, "N-1": true, "A-2": true})
+
+	tests := []struct {
+		status string
+		issue  bool
+	}{
+		{database.CodeMappingStatusValid, false},
+		{database.CodeMappingStatusValid, false},
+		{database.CodeMappingStatusWarning, true},
+	}
+	for i, tt := range tests {
+		if mappings[i].Status != tt.status || (mappings[i].Issue != "") != tt.issue {
+			t.Errorf("mapping[%d] = %s %q, want %s", i, mappings[i].Status, mappings[i].Issue, tt.status)
+		}
+	}
+}
+
+func TestCodeMappingsGroupCode(t *testing.T) {
+	mappings := NewCodeMappings(ParseCodeMappingRows([][]string{
+		{"A-1", "N-1"},
+		{"A-2", "A-3"},
+		{"A-3", "N-3"},
+		{"B-1", "", ""},
+	}))
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"A-1", "N-1"},
+		{"N-1", "N-1"},
+		{"A-2", "N-3"},
+		{"A-3", "N-3"},
+		{"B-1", ""},
+		{"unknown", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := mappings.GroupCode(tt.code); got != tt.want {
+				t.Errorf("GroupCode(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+
+	var empty *CodeMappings
+	if empty.GroupCode("A-1") != "" || empty.Len() != 0 {
+		t.Error("nil CodeMappings must not map codes")
+	}
+}
+
+func TestFindCodeMappingConflicts(t *testing.T) {
+	mappings := NewCodeMappings(ParseCodeMappingRows([][]string{
+		{"A-1", "N-1"},
+		{"A-2", "N-2"},
+		{"A-3", "N-3"},
+		{"A-4", "N-4"},
+	}))
+	references := map[string]string{
+		"A-1": "болт м8", "N-1": "болт м10", // Клиент объединяет, система разделила
+		"A-2": "гайка", "A-3": "гайка", // Система объединила коды с разными новыми кодами
+		"A-4": "шайба", "N-4": "шайба", // Согласовано
+	}
+
+	conflicts := FindCodeMappingConflicts(mappings, references)
+	if len(conflicts) != 2 {
+		t.Fatalf("FindCodeMappingConflicts() = %+v, want 2 conflicts", conflicts)
+	}
+	if conflicts[0].Type != MappingConflictSystemSplit || conflicts[0].Codes[0] != "A-1" {
+		t.Errorf("conflict[0] = %+v, want system_split for A-1", conflicts[0])
+	}
+	if conflicts[1].Type != MappingConflictSystemMerged || len(conflicts[1].Codes) != 2 || conflicts[1].SystemGroup["A-2"] != "гайка" {
+		t.Errorf("conflict[1] = %+v, want system_merged for A-2 and A-3", conflicts[1])
+	}
+}
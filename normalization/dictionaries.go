@@ -0,0 +1,189 @@
+package normalization
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"httpserver/database"
+)
+
+// defaultStopWords стоп-слова, не учитываемые при сравнении наименований
+var defaultStopWords = []string{
+	"и", "в", "на", "с", "для", "по", "из", "к", "от", "о", "а", "но", "или", "то", "что",
+}
+
+// defaultDictionaries словари по умолчанию для кода без привязки к проекту
+var defaultDictionaries = DefaultDictionaries()
+
+// Dictionaries словари нормализации: сокращения, стоп-слова и защищенные токены.
+// Стандартные стоп-слова дополняются записями проекта
+type Dictionaries struct {
+	abbreviations map[string]string // Сокращение в нижнем регистре без точки -> полная форма
+	stopWords     map[string]bool
+	projectStops  []string // Стоп-слова из словаря проекта
+	protected     []string
+	protectedRe   []*regexp.Regexp
+}
+
+// DefaultDictionaries создает словари только со стандартными стоп-словами
+func DefaultDictionaries() *Dictionaries {
+	return NewDictionaries(nil)
+}
+
+// NewDictionaries создает словари из стандартных стоп-слов и записей словаря проекта
+func NewDictionaries(entries []*database.NormalizationDictionaryEntry) *Dictionaries {
+	d := &Dictionaries{
+		abbreviations: make(map[string]string),
+		stopWords:     make(map[string]bool, len(defaultStopWords)),
+	}
+	for _, word := range defaultStopWords {
+		d.stopWords[word] = true
+	}
+
+	for _, entry := range entries {
+		term := strings.TrimSpace(entry.Term)
+		if term == "" {
+			continue
+		}
+		switch entry.Kind {
+		case database.DictionaryKindAbbreviation:
+			if replacement := strings.TrimSpace(entry.Replacement); replacement != "" {
+				d.abbreviations[abbreviationKey(term)] = replacement
+			}
+		case database.DictionaryKindStopWord:
+			d.stopWords[strings.ToLower(term)] = true
+			d.projectStops = append(d.projectStops, term)
+		case database.DictionaryKindProtected:
+			d.protected = append(d.protected, term)
+			// Границы слова задаются явно: \b в Go не учитывает кириллицу
+			d.protectedRe = append(d.protectedRe,
+				regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(`+regexp.QuoteMeta(term)+`)(?:$|[^\p{L}\p{N}])`))
+		}
+	}
+	return d
+}
+
+// LoadProjectDictionaries загружает словари проекта из сервисной БД
+func LoadProjectDictionaries(serviceDB *database.ServiceDB, projectID int) (*Dictionaries, error) {
+	entries, err := serviceDB.GetNormalizationDictionaryEntries(projectID, "")
+	if err != nil {
+		return nil, err
+	}
+	return NewDictionaries(entries), nil
+}
+
+// abbreviationKey приводит сокращение к ключу словаря
+func abbreviationKey(term string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(term)), ".")
+}
+
+// IsStopWord проверяет, является ли слово стоп-словом
+func (d *Dictionaries) IsStopWord(word string) bool {
+	return d.stopWords[strings.ToLower(word)]
+}
+
+// HasAbbreviations проверяет наличие сокращений в словаре
+func (d *Dictionaries) HasAbbreviations() bool {
+	return len(d.abbreviations) > 0
+}
+
+// ExpandAbbreviation возвращает полную форму сокращения с сохранением заглавной первой буквы
+func (d *Dictionaries) ExpandAbbreviation(word string) (string, bool) {
+	expansion, ok := d.abbreviations[abbreviationKey(word)]
+	if !ok {
+		return word, false
+	}
+	runes := []rune(word)
+	if len(runes) > 0 && unicode.IsUpper(runes[0]) {
+		expanded := []rune(expansion)
+		expanded[0] = unicode.ToUpper(expanded[0])
+		expansion = string(expanded)
+	}
+	return expansion, true
+}
+
+// ProtectedSpans возвращает байтовые интервалы защищенных токенов в наименовании
+func (d *Dictionaries) ProtectedSpans(name string) [][2]int {
+	var spans [][2]int
+	for _, re := range d.protectedRe {
+		for _, match := range re.FindAllStringSubmatchIndex(name, -1) {
+			spans = append(spans, [2]int{match[2], match[3]})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+	return spans
+}
+
+// IsProtectedSpan проверяет, пересекается ли интервал с защищенным токеном
+func (d *Dictionaries) IsProtectedSpan(name string, start, end int) bool {
+	for _, span := range d.ProtectedSpans(name) {
+		if start < span[1] && end > span[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// maskProtected заменяет защищенные токены метками из символов частной области Unicode,
+// которые не затрагиваются правилами исправления. restore возвращает токены на место
+func (d *Dictionaries) maskProtected(name string) (masked string, restore func(string) string) {
+	spans := d.ProtectedSpans(name)
+	if len(spans) == 0 {
+		return name, func(s string) string { return s }
+	}
+
+	var b strings.Builder
+	tokens := make(map[string]string)
+	last := 0
+	for _, span := range spans {
+		if span[0] < last {
+			continue // Пересекающиеся токены
+		}
+		placeholder := string(rune(0xE000 + len(tokens)))
+		tokens[placeholder] = name[span[0]:span[1]]
+		b.WriteString(name[last:span[0]])
+		b.WriteString(placeholder)
+		last = span[1]
+	}
+	b.WriteString(name[last:])
+
+	return b.String(), func(s string) string {
+		for placeholder, token := range tokens {
+			s = strings.ReplaceAll(s, placeholder, token)
+		}
+		return s
+	}
+}
+
+// PromptSection возвращает раздел промпта AI со словарями проекта.
+// Стандартные стоп-слова в промпт не попадают: предлоги нужны в наименованиях
+func (d *Dictionaries) PromptSection() string {
+	if len(d.abbreviations) == 0 && len(d.protected) == 0 && len(d.projectStops) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nСЛОВАРИ ПРОЕКТА:\n")
+	if len(d.abbreviations) > 0 {
+		keys := make([]string, 0, len(d.abbreviations))
+		for key := range d.abbreviations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b.WriteString("- Сокращения (раскрывай до полной формы):")
+		for _, key := range keys {
+			fmt.Fprintf(&b, " %s → %s;", key, d.abbreviations[key])
+		}
+		b.WriteString("\n")
+	}
+	if len(d.protected) > 0 {
+		fmt.Fprintf(&b, "- Защищенные токены (НЕ ИЗМЕНЯЙ, сохраняй написание как есть): %s\n", strings.Join(d.protected, ", "))
+	}
+	if len(d.projectStops) > 0 {
+		fmt.Fprintf(&b, "- Служебные слова (удаляй из нормализованного имени): %s\n", strings.Join(d.projectStops, ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
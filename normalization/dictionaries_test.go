@@ -0,0 +1,91 @@
+package normalization
+
+import (
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestPatternDetectorDictionaries(t *testing.T) {
+	dictionaries := NewDictionaries([]*database.NormalizationDictionaryEntry{
+		{Kind: database.DictionaryKindAbbreviation, Term: "нерж.", Replacement: "нержавеющий"},
+		{Kind: database.DictionaryKindAbbreviation, Term: "оцинк", Replacement: "оцинкованный"},
+		{Kind: database.DictionaryKindStopWord, Term: "шт"},
+		{Kind: database.DictionaryKindProtected, Term: "ВВГнг"},
+	})
+
+	tests := []struct {
+		name              string
+		input             string
+		want              string
+		wantAbbreviations int
+	}{
+		{"abbreviation with dot", "Лист нерж. полированный", "Лист нержавеющий полированный", 1},
+		{"capitalized abbreviation", "Нерж лист", "Нержавеющий лист", 1},
+		{"abbreviation inside word is ignored", "Оцинковка", "Оцинковка", 0},
+		{"protected mixed case token kept", "Кабель ВВГнг", "Кабель ВВГнг", 0},
+		{"protected token next to abbreviation", "Кабель ВВГнг оцинк", "Кабель ВВГнг оцинкованный", 1},
+	}
+
+	detector := NewPatternDetector()
+	detector.SetDictionaries(dictionaries)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := detector.DetectPatterns(tt.input)
+			abbreviations := 0
+			for _, match := range matches {
+				if match.Type == PatternAbbreviation {
+					abbreviations++
+				}
+				if strings.Contains(match.MatchedText, "ВВГнг") {
+					t.Errorf("match %+v covers protected token", match)
+				}
+			}
+			if abbreviations != tt.wantAbbreviations {
+				t.Errorf("abbreviation matches = %d, want %d", abbreviations, tt.wantAbbreviations)
+			}
+			if got := detector.ApplyFixes(tt.input, matches); got != tt.want {
+				t.Errorf("ApplyFixes(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	if !dictionaries.IsStopWord("ШТ") || !dictionaries.IsStopWord("для") {
+		t.Error("project and default stop-words expected")
+	}
+
+	// Без словаря проекта правило сокращений снимается
+	detector.SetDictionaries(nil)
+	for _, match := range detector.DetectPatterns("Лист нерж.") {
+		if match.Type == PatternAbbreviation {
+			t.Errorf("unexpected abbreviation match %+v", match)
+		}
+	}
+}
+
+func TestDictionariesPromptSection(t *testing.T) {
+	if section := DefaultDictionaries().PromptSection(); section != "" {
+		t.Errorf("default dictionaries prompt section = %q, want empty", section)
+	}
+
+	section := NewDictionaries([]*database.NormalizationDictionaryEntry{
+		{Kind: database.DictionaryKindAbbreviation, Term: "нерж", Replacement: "нержавеющий"},
+		{Kind: database.DictionaryKindProtected, Term: "ВВГнг"},
+		{Kind: database.DictionaryKindStopWord, Term: "шт"},
+	}).PromptSection()
+	for _, want := range []string{"нерж → нержавеющий", "ВВГнг", "шт"} {
+		if !strings.Contains(section, want) {
+			t.Errorf("prompt section %q does not contain %q", section, want)
+		}
+	}
+
+	normalizer := NewAINormalizer("test-key")
+	base := normalizer.buildSystemPrompt()
+	normalizer.SetDictionaries(NewDictionaries([]*database.NormalizationDictionaryEntry{
+		{Kind: database.DictionaryKindProtected, Term: "ВВГнг"},
+	}))
+	if got := normalizer.buildSystemPrompt(); !strings.HasPrefix(got, base) || !strings.Contains(got, "ВВГнг") {
+		t.Errorf("system prompt does not include project dictionaries: %q", got[len(base):])
+	}
+}
@@ -0,0 +1,139 @@
+package normalization
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"httpserver/database"
+)
+
+// AttributeTypeDimensionTuple тип атрибута для размеров в каноническом виде
+const AttributeTypeDimensionTuple = "dimension_tuple"
+
+// Dimension размеры из наименования: "20х30х2", "30*20*2 мм", "1,5x2 м"
+type Dimension struct {
+	Values    []float64 `json:"values"`         // Значения в мм по убыванию
+	Unit      string    `json:"unit,omitempty"` // Единица в исходном наименовании; без единицы размеры считаются заданными в мм
+	Original  string    `json:"original"`
+	Canonical string    `json:"canonical"` // Значения в мм по убыванию через "x": "30x20x2"
+	Position  int       `json:"position"`
+}
+
+// dimensionRegex находит 2-3 числа, разделенные x/х/*/×, с необязательной единицей после последнего числа.
+// Перед размером не должно быть буквы или цифры, чтобы не разбирать резьбу M8x40 и артикулы
+var dimensionRegex = regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}.,])((\d+(?:[.,]\d+)?)\s*[xх*×]\s*(\d+(?:[.,]\d+)?)(?:\s*[xх*×]\s*(\d+(?:[.,]\d+)?))?(?:\s*(мм|см|м|mm|cm|m))?)`)
+
+// dimensionUnitScale множитель перевода единицы размера в мм
+var dimensionUnitScale = map[string]float64{
+	"мм": 1, "mm": 1,
+	"см": 10, "cm": 10,
+	"м": 1000, "m": 1000,
+}
+
+// ParseDimensions извлекает размеры из наименования и приводит их к мм и порядку по убыванию
+func ParseDimensions(name string) []Dimension {
+	var dimensions []Dimension
+	for _, match := range dimensionRegex.FindAllStringSubmatchIndex(name, -1) {
+		start, end := match[2], match[3]
+		unit := ""
+		if match[10] >= 0 {
+			// Единица относится к размеру, только если за ней не продолжается слово: "2 метра" не "2 м"
+			if next, _ := utf8.DecodeRuneInString(name[match[11]:]); match[11] < len(name) && unicode.IsLetter(next) {
+				end = strings.LastIndexFunc(name[:match[10]], func(r rune) bool { return !unicode.IsSpace(r) }) + 1
+			} else {
+				unit = strings.ToLower(name[match[10]:match[11]])
+			}
+		}
+
+		scale := 1.0
+		if unit != "" {
+			scale = dimensionUnitScale[unit]
+		}
+		var values []float64
+		for _, group := range [][2]int{{match[4], match[5]}, {match[6], match[7]}, {match[8], match[9]}} {
+			if group[0] < 0 {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.Replace(name[group[0]:group[1]], ",", ".", 1), 64)
+			if err != nil || value <= 0 {
+				values = nil
+				break
+			}
+			values = append(values, value*scale)
+		}
+		if len(values) < 2 {
+			continue
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(values)))
+
+		dimensions = append(dimensions, Dimension{
+			Values:    values,
+			Unit:      unit,
+			Original:  name[start:end],
+			Canonical: canonicalDimension(values),
+			Position:  start,
+		})
+	}
+	return dimensions
+}
+
+// canonicalDimension форматирует значения размеров через "x"
+func canonicalDimension(values []float64) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strings.Join(parts, "x")
+}
+
+// CanonicalizeDimensions заменяет размеры в наименовании каноническим видом:
+// "Уголок 20х30х2" и "Уголок 30*20*2 мм" дают "Уголок 30x20x2"
+func CanonicalizeDimensions(name string) string {
+	dimensions := ParseDimensions(name)
+	if len(dimensions) == 0 {
+		return name
+	}
+	var b strings.Builder
+	last := 0
+	for _, dimension := range dimensions {
+		b.WriteString(name[last:dimension.Position])
+		b.WriteString(dimension.Canonical)
+		last = dimension.Position + len(dimension.Original)
+	}
+	b.WriteString(name[last:])
+	return b.String()
+}
+
+// DimensionKey возвращает ключ набора размеров наименования для сравнения; пустой, если размеров нет
+func DimensionKey(dimensions []Dimension) string {
+	keys := make([]string, 0, len(dimensions))
+	seen := make(map[string]bool)
+	for _, dimension := range dimensions {
+		if !seen[dimension.Canonical] {
+			seen[dimension.Canonical] = true
+			keys = append(keys, dimension.Canonical)
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ";")
+}
+
+// DimensionAttributes возвращает размеры как атрибуты элемента в каноническом виде
+func DimensionAttributes(dimensions []Dimension) []*database.ItemAttribute {
+	attributes := make([]*database.ItemAttribute, 0, len(dimensions))
+	for _, dimension := range dimensions {
+		attributes = append(attributes, &database.ItemAttribute{
+			AttributeType:  AttributeTypeDimensionTuple,
+			AttributeName:  strconv.Itoa(len(dimension.Values)) + "d",
+			AttributeValue: dimension.Canonical,
+			Unit:           "мм",
+			OriginalText:   dimension.Original,
+			Confidence:     0.95,
+		})
+	}
+	return attributes
+}
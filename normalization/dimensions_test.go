@@ -0,0 +1,46 @@
+package normalization
+
+import (
+	"testing"
+)
+
+func TestParseDimensions(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		canonical []string
+		original  []string
+		units     []string
+	}{
+		{"cyrillic separator", "Уголок 20х30х2", []string{"30x20x2"}, []string{"20х30х2"}, []string{""}},
+		{"asterisk with unit", "Уголок 30*20*2 мм", []string{"30x20x2"}, []string{"30*20*2 мм"}, []string{"мм"}},
+		{"centimeters converted", "Плита 60x40 см", []string{"600x400"}, []string{"60x40 см"}, []string{"см"}},
+		{"decimal comma and meters", "Лист 1,5х2м", []string{"2000x1500"}, []string{"1,5х2м"}, []string{"м"}},
+		{"unit prefix of word ignored", "Труба 20x2 метра", []string{"20x2"}, []string{"20x2"}, []string{""}},
+		{"thread is not a dimension", "Болт М8х40", nil, nil, nil},
+		{"two tuples", "Переходник 50х40 / 40x20", []string{"50x40", "40x20"}, []string{"50х40", "40x20"}, []string{"", ""}},
+		{"single number", "Кабель 100м", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dimensions := ParseDimensions(tt.input)
+			if len(dimensions) != len(tt.canonical) {
+				t.Fatalf("ParseDimensions(%q) = %+v, want %v", tt.input, dimensions, tt.canonical)
+			}
+			for i, dimension := range dimensions {
+				if dimension.Canonical != tt.canonical[i] || dimension.Original != tt.original[i] || dimension.Unit != tt.units[i] {
+					t.Errorf("dimension[%d] = %q from %q unit %q, want %q from %q unit %q", i,
+						dimension.Canonical, dimension.Original, dimension.Unit, tt.canonical[i], tt.original[i], tt.units[i])
+				}
+			}
+		})
+	}
+
+	if a, b := CanonicalizeDimensions("Уголок 20х30х2"), CanonicalizeDimensions("Уголок 30*20*2 мм"); a != b || a != "Уголок 30x20x2" {
+		t.Errorf("CanonicalizeDimensions() = %q and %q, want equal canonical names", a, b)
+	}
+	if key := DimensionKey(ParseDimensions("Переходник 50х40 / 40x20 / 40*50")); key != "40x20;50x40" {
+		t.Errorf("DimensionKey() = %q, want sorted unique tuples", key)
+	}
+}
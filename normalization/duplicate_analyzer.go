@@ -701,13 +701,6 @@ func tokenizeWithOptions(text string, useStopWords bool) []string {
 	// Разбиваем на слова
 	words := strings.Fields(text)
 
-	// Фильтруем стоп-слова только если useStopWords == false
-	stopWords := map[string]bool{
-		"и": true, "в": true, "на": true, "с": true, "для": true,
-		"по": true, "из": true, "к": true, "от": true, "о": true,
-		"а": true, "но": true, "или": true, "то": true, "что": true,
-	}
-
 	var tokens []string
 	for _, word := range words {
 		// Пропускаем короткие слова (меньше 2 символов)
@@ -715,7 +708,7 @@ func tokenizeWithOptions(text string, useStopWords bool) []string {
 			continue
 		}
 		// Пропускаем стоп-слова только если useStopWords == false
-		if !useStopWords && stopWords[word] {
+		if !useStopWords && defaultDictionaries.IsStopWord(word) {
 			continue
 		}
 		tokens = append(tokens, word)
@@ -33,6 +33,9 @@ type HierarchicalResult struct {
 	TotalDuration   int64                `json:"total_duration_ms"`
 	CacheHits       int                  `json:"cache_hits"`
 	AICallsCount    int                  `json:"ai_calls_count"`
+	Model           string               `json:"model,omitempty"`       // Модель, чей ответ принят (для цепочек моделей)
+	ModelChain      []ModelChainAttempt  `json:"model_chain,omitempty"` // Учет шагов цепочки моделей
+	Historical      *HistoricalMatch     `json:"historical,omitempty"`  // Подтвержденное решение, примененное вместо AI
 }
 
 // AIResponse ответ от AI
@@ -606,6 +609,44 @@ func (h *HierarchicalClassifier) GetCacheStats() map[string]int {
 	}
 }
 
+// Model возвращает модель AI, используемую классификатором (для калибровки уверенности)
+func (h *HierarchicalClassifier) Model() string {
+	return h.aiClient.Model()
+}
+
+// WithAIClient возвращает копию классификатора с другим AI клиентом.
+// Дерево КПВЭД и вспомогательные классификаторы общие, кэши результатов - собственные.
+func (h *HierarchicalClassifier) WithAIClient(aiClient *nomenclature.AIClient) *HierarchicalClassifier {
+	return &HierarchicalClassifier{
+		tree:                   h.tree,
+		db:                     h.db,
+		aiClient:               aiClient,
+		promptBuilder:          h.promptBuilder,
+		cache:                  &sync.Map{},
+		baseWordCache:          &sync.Map{},
+		keywordClassifier:      h.keywordClassifier,
+		productServiceDetector: h.productServiceDetector,
+		contextEnricher:        h.contextEnricher,
+		minConfidence:          h.minConfidence,
+	}
+}
+
+// WithPromptInstructions возвращает копию классификатора, добавляющую указания к системным промптам.
+// Используется вариантами экспериментов; кэши результатов копии собственные
+func (h *HierarchicalClassifier) WithPromptInstructions(instructions string) *HierarchicalClassifier {
+	variant := h.WithAIClient(h.aiClient)
+	variant.promptBuilder = h.promptBuilder.WithInstructions(instructions)
+	return variant
+}
+
+// AIClient возвращает AI клиент классификатора
+func (h *HierarchicalClassifier) AIClient() *nomenclature.AIClient {
+	if h == nil {
+		return nil
+	}
+	return h.aiClient
+}
+
 // SetMinConfidence устанавливает минимальный порог уверенности
 func (h *HierarchicalClassifier) SetMinConfidence(confidence float64) {
 	if confidence >= 0 && confidence <= 1 {
@@ -0,0 +1,215 @@
+package normalization
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// HistoricalModel модель (kpved_model) для результатов, взятых из подтвержденных решений, а не от AI
+const HistoricalModel = "historical"
+
+// DefaultHistoricalMinSimilarity минимальное сходство наименований для применения подтвержденного решения
+const DefaultHistoricalMinSimilarity = 0.85
+
+// historicalNGramSize длина символьных n-грамм наименования
+const historicalNGramSize = 3
+
+// HistoricalDecision подтвержденная классификация наименования
+type HistoricalDecision struct {
+	NormalizedName string
+	KpvedCode      string
+	KpvedName      string
+}
+
+// HistoricalMatch найденное подтвержденное решение для наименования
+type HistoricalMatch struct {
+	KpvedCode   string  `json:"kpved_code"`
+	KpvedName   string  `json:"kpved_name"`
+	Similarity  float64 `json:"similarity"`
+	MatchedName string  `json:"matched_name"` // Наименование подтвержденной записи
+}
+
+// historicalEntry наименование с подтвержденной категорией
+type historicalEntry struct {
+	name      string
+	kpvedCode string
+	kpvedName string
+	grams     map[string]bool
+}
+
+// HistoricalIndex поиск подтвержденных решений по n-граммам нормализованного наименования
+type HistoricalIndex struct {
+	entries       []historicalEntry
+	postings      map[string][]int
+	minSimilarity float64
+}
+
+// NewHistoricalIndex строит индекс по подтвержденным решениям. Если одно наименование подтверждено
+// с разными кодами, берется код с наибольшим числом подтверждений, при равенстве наименование пропускается
+func NewHistoricalIndex(decisions []HistoricalDecision, minSimilarity float64) *HistoricalIndex {
+	if minSimilarity <= 0 || minSimilarity > 1 {
+		minSimilarity = DefaultHistoricalMinSimilarity
+	}
+
+	type vote struct {
+		kpvedName string
+		count     int
+	}
+	votes := make(map[string]map[string]*vote)
+	for _, decision := range decisions {
+		name := normalizeHistoricalName(decision.NormalizedName)
+		if name == "" || decision.KpvedCode == "" {
+			continue
+		}
+		codes, ok := votes[name]
+		if !ok {
+			codes = make(map[string]*vote)
+			votes[name] = codes
+		}
+		if v, ok := codes[decision.KpvedCode]; ok {
+			v.count++
+		} else {
+			codes[decision.KpvedCode] = &vote{kpvedName: decision.KpvedName, count: 1}
+		}
+	}
+
+	index := &HistoricalIndex{postings: make(map[string][]int), minSimilarity: minSimilarity}
+	for name, codes := range votes {
+		bestCode, bestCount, tie := "", 0, false
+		for code, v := range codes {
+			switch {
+			case v.count > bestCount:
+				bestCode, bestCount, tie = code, v.count, false
+			case v.count == bestCount:
+				tie = true
+			}
+		}
+		if tie {
+			continue
+		}
+
+		entry := historicalEntry{name: name, kpvedCode: bestCode, kpvedName: codes[bestCode].kpvedName, grams: nameNGrams(name)}
+		for gram := range entry.grams {
+			index.postings[gram] = append(index.postings[gram], len(index.entries))
+		}
+		index.entries = append(index.entries, entry)
+	}
+	return index
+}
+
+// Size возвращает число наименований в индексе
+func (ix *HistoricalIndex) Size() int {
+	if ix == nil {
+		return 0
+	}
+	return len(ix.entries)
+}
+
+// Lookup ищет подтвержденное решение для наименования по коэффициенту Дайса над n-граммами.
+// Если лучшее сходство дают наименования с разными кодами, решение считается неоднозначным
+func (ix *HistoricalIndex) Lookup(normalizedName string) (*HistoricalMatch, bool) {
+	if ix == nil || len(ix.entries) == 0 {
+		return nil, false
+	}
+	grams := nameNGrams(normalizeHistoricalName(normalizedName))
+	if len(grams) == 0 {
+		return nil, false
+	}
+
+	shared := make(map[int]int)
+	for gram := range grams {
+		for _, i := range ix.postings[gram] {
+			shared[i]++
+		}
+	}
+
+	var best *historicalEntry
+	bestScore, ambiguous := 0.0, false
+	for i, count := range shared {
+		entry := &ix.entries[i]
+		score := 2 * float64(count) / float64(len(grams)+len(entry.grams))
+		switch {
+		case score > bestScore+1e-9:
+			best, bestScore, ambiguous = entry, score, false
+		case math.Abs(score-bestScore) <= 1e-9 && best != nil && entry.kpvedCode != best.kpvedCode:
+			ambiguous = true
+		}
+	}
+	if best == nil || ambiguous || bestScore < ix.minSimilarity {
+		return nil, false
+	}
+	return &HistoricalMatch{
+		KpvedCode:   best.kpvedCode,
+		KpvedName:   best.kpvedName,
+		Similarity:  math.Round(bestScore*1000) / 1000,
+		MatchedName: best.name,
+	}, true
+}
+
+// normalizeHistoricalName приводит наименование к нижнему регистру с одиночными пробелами
+func normalizeHistoricalName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// nameNGrams возвращает символьные n-граммы наименования, дополненного пробелами по краям
+func nameNGrams(name string) map[string]bool {
+	grams := make(map[string]bool)
+	if name == "" {
+		return grams
+	}
+	runes := []rune(" " + name + " ")
+	for i := 0; i+historicalNGramSize <= len(runes); i++ {
+		grams[string(runes[i:i+historicalNGramSize])] = true
+	}
+	return grams
+}
+
+// HistoricalUsage учет применения подтвержденных решений за прогон
+type HistoricalUsage struct {
+	IndexSize    int     `json:"index_size"`     // Подтвержденных наименований в индексе
+	Hits         int     `json:"hits"`           // Элементы, классифицированные по подтвержденным решениям
+	Misses       int     `json:"misses"`         // Элементы, для которых решение не найдено
+	AIClassified int     `json:"ai_classified"`  // Элементы, классифицированные AI
+	AICalls      int     `json:"ai_calls"`       // AI вызовы за прогон
+	AvgAICalls   float64 `json:"avg_ai_calls"`   // Среднее число AI вызовов на элемент, классифицированный AI
+	SavedAICalls int     `json:"saved_ai_calls"` // Оценка сэкономленных AI вызовов
+}
+
+// historicalStats потокобезопасный учет применения подтвержденных решений
+type historicalStats struct {
+	mu    sync.Mutex
+	usage HistoricalUsage
+}
+
+// record учитывает результат классификации одного элемента
+func (s *historicalStats) record(hit, miss bool, aiCalls int, aiClassified bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hit {
+		s.usage.Hits++
+	}
+	if miss {
+		s.usage.Misses++
+	}
+	if aiClassified {
+		s.usage.AIClassified++
+	}
+	s.usage.AICalls += aiCalls
+}
+
+// snapshot возвращает учет с оценкой сэкономленных вызовов: каждое попадание экономит
+// среднее число AI вызовов элемента этого прогона (не меньше одного)
+func (s *historicalStats) snapshot() HistoricalUsage {
+	s.mu.Lock()
+	usage := s.usage
+	s.mu.Unlock()
+
+	perItem := 1.0
+	if usage.AIClassified > 0 {
+		usage.AvgAICalls = math.Round(float64(usage.AICalls)/float64(usage.AIClassified)*100) / 100
+		perItem = math.Max(perItem, float64(usage.AICalls)/float64(usage.AIClassified))
+	}
+	usage.SavedAICalls = int(math.Round(float64(usage.Hits) * perItem))
+	return usage
+}
@@ -0,0 +1,78 @@
+package normalization
+
+import "testing"
+
+func TestHistoricalIndexLookup(t *testing.T) {
+	index := NewHistoricalIndex([]HistoricalDecision{
+		{NormalizedName: "Болт оцинкованный М8", KpvedCode: "25.94.11", KpvedName: "Болты"},
+		{NormalizedName: "болт оцинкованный м8", KpvedCode: "25.94.11", KpvedName: "Болты"},
+		{NormalizedName: "болт оцинкованный м8", KpvedCode: "25.93.14", KpvedName: "Гвозди"},
+		{NormalizedName: "кабель силовой", KpvedCode: "27.32.13", KpvedName: "Кабели"},
+		{NormalizedName: "кабель силовой", KpvedCode: "27.32.14", KpvedName: "Провода"},
+		{NormalizedName: "перчатки рабочие", KpvedCode: "14.12.30", KpvedName: "Спецодежда"},
+	}, 0.85)
+
+	if got := index.Size(); got != 2 {
+		t.Errorf("Size() = %d, want 2 (tie on кабель силовой skipped)", got)
+	}
+
+	tests := []struct {
+		name     string
+		lookup   string
+		wantCode string
+		wantHit  bool
+	}{
+		{"exact name majority vote", "болт оцинкованный м8", "25.94.11", true},
+		{"case and spaces ignored", "  БОЛТ  оцинкованный М8 ", "25.94.11", true},
+		{"similar name", "перчатки рабочие.", "14.12.30", true},
+		{"ambiguous name not indexed", "кабель силовой", "", false},
+		{"dissimilar name", "болт", "", false},
+		{"empty name", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, ok := index.Lookup(tt.lookup)
+			if ok != tt.wantHit {
+				t.Fatalf("Lookup(%q) hit = %v, want %v (match %+v)", tt.lookup, ok, tt.wantHit, match)
+			}
+			if ok && (match.KpvedCode != tt.wantCode || match.Similarity < 0.85 || match.Similarity > 1) {
+				t.Errorf("Lookup(%q) = %+v, want code %s", tt.lookup, match, tt.wantCode)
+			}
+		})
+	}
+
+	var empty *HistoricalIndex
+	if _, ok := empty.Lookup("болт"); ok || empty.Size() != 0 {
+		t.Error("nil index must not match")
+	}
+}
+
+func TestChainedClassifierHistoricalHit(t *testing.T) {
+	chain := &ChainedClassifier{
+		steps: []ModelChainStep{{Model: "cheap"}},
+		stats: NewModelChainStats(),
+		history: &historicalStats{usage: HistoricalUsage{
+			// Предыдущие элементы прогона классифицированы AI в среднем за 3 вызова
+			Misses: 2, AIClassified: 2, AICalls: 6,
+		}},
+	}
+	chain.WithHistorical(NewHistoricalIndex([]HistoricalDecision{
+		{NormalizedName: "перчатки рабочие", KpvedCode: "14.12.30", KpvedName: "Спецодежда"},
+	}, 0))
+
+	result, err := chain.Classify("перчатки рабочие", "")
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if result.Model != HistoricalModel || result.FinalCode != "14.12.30" || result.Historical == nil || result.FinalConfidence != 1 {
+		t.Errorf("Classify() = %+v, want historical result", result)
+	}
+
+	usage := chain.HistoricalUsage()
+	if usage.Hits != 1 || usage.IndexSize != 1 || usage.AvgAICalls != 3 || usage.SavedAICalls != 3 {
+		t.Errorf("HistoricalUsage() = %+v, want 1 hit saving 3 AI calls", usage)
+	}
+	if chain.Usage()[HistoricalModel].Calls != 1 {
+		t.Errorf("Usage() = %+v, want historical step recorded", chain.Usage())
+	}
+}
@@ -44,6 +44,7 @@ func NewKpvedClassifier(db *database.DB, apiKey string, kpvedFilePath string, mo
 		kpvedProcessor: nomenclature.NewKpvedProcessor(),
 		aiClient:       nomenclature.NewAIClient(apiKey, modelName),
 	}
+	classifier.aiClient.SetJob("kpved_classification")
 
 	// Загружаем справочник КПВЭД для AI промптов
 	if err := classifier.kpvedProcessor.LoadKpved(kpvedFilePath); err != nil {
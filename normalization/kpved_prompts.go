@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+// HierarchicalPromptVersion версия промптов иерархической классификации. Входит в хеш входных данных
+// классификации: при изменении промптов ее нужно увеличить, чтобы повторный запуск переклассифицировал записи
+const HierarchicalPromptVersion = "1"
+
 // ClassificationPrompt промпт для классификации
 type ClassificationPrompt struct {
 	System string
@@ -13,7 +17,8 @@ type ClassificationPrompt struct {
 
 // PromptBuilder строитель промптов для классификации
 type PromptBuilder struct {
-	tree *KpvedTree
+	tree         *KpvedTree
+	instructions string // Дополнительные указания, добавляемые к системному промпту (варианты экспериментов)
 }
 
 // NewPromptBuilder создает новый строитель промптов
@@ -21,6 +26,11 @@ func NewPromptBuilder(tree *KpvedTree) *PromptBuilder {
 	return &PromptBuilder{tree: tree}
 }
 
+// WithInstructions возвращает копию строителя, добавляющую указания к системному промпту каждого уровня
+func (pb *PromptBuilder) WithInstructions(instructions string) *PromptBuilder {
+	return &PromptBuilder{tree: pb.tree, instructions: strings.TrimSpace(instructions)}
+}
+
 // BuildLevelPrompt строит промпт для указанного уровня
 func (pb *PromptBuilder) BuildLevelPrompt(
 	normalizedName string,
@@ -39,18 +49,21 @@ func (pb *PromptBuilder) BuildLevelPromptWithType(
 	candidates []*KpvedNode,
 	objectType string, // "product", "service", или ""
 ) *ClassificationPrompt {
+	var prompt *ClassificationPrompt
 	switch level {
-	case LevelSection:
-		return pb.buildSectionPrompt(normalizedName, category, candidates, objectType)
 	case LevelClass:
-		return pb.buildClassPrompt(normalizedName, category, candidates, objectType)
+		prompt = pb.buildClassPrompt(normalizedName, category, candidates, objectType)
 	case LevelSubclass:
-		return pb.buildSubclassPrompt(normalizedName, category, candidates, objectType)
+		prompt = pb.buildSubclassPrompt(normalizedName, category, candidates, objectType)
 	case LevelGroup:
-		return pb.buildGroupPrompt(normalizedName, category, candidates, objectType)
+		prompt = pb.buildGroupPrompt(normalizedName, category, candidates, objectType)
 	default:
-		return pb.buildSectionPrompt(normalizedName, category, candidates, objectType)
+		prompt = pb.buildSectionPrompt(normalizedName, category, candidates, objectType)
+	}
+	if pb.instructions != "" {
+		prompt.System += "\n\n" + pb.instructions
 	}
+	return prompt
 }
 
 // buildSectionPrompt строит промпт для уровня секций
@@ -0,0 +1,265 @@
+package normalization
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Задачи, для которых настраиваются цепочки моделей
+const (
+	ModelChainTaskClassification = "classification"
+	ModelChainTaskNormalization  = "normalization"
+)
+
+// Исходы шага цепочки моделей
+const (
+	ChainOutcomeAccepted      = "accepted"       // Уверенность не ниже порога шага
+	ChainOutcomeLowConfidence = "low_confidence" // Эскалация на следующую модель
+	ChainOutcomeError         = "error"          // Ошибка вызова или разбора ответа, эскалация
+	ChainOutcomeBestEffort    = "best_effort"    // Ни один шаг не прошел порог, взят лучший результат
+)
+
+// ModelChainStep шаг цепочки: модель и порог уверенности для принятия ее ответа
+type ModelChainStep struct {
+	Model         string  `json:"model"`
+	MinConfidence float64 `json:"min_confidence"` // Ниже - эскалация на следующую модель
+}
+
+// ModelChainAttempt учет одного шага цепочки для конкретного элемента
+type ModelChainAttempt struct {
+	Model      string  `json:"model"`
+	Outcome    string  `json:"outcome"`
+	Confidence float64 `json:"confidence"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs int64   `json:"duration_ms"`
+	AICalls    int     `json:"ai_calls"`
+}
+
+// ValidateModelChain проверяет шаги цепочки
+func ValidateModelChain(steps []ModelChainStep) error {
+	for i, step := range steps {
+		if step.Model == "" {
+			return fmt.Errorf("step %d: model is required", i+1)
+		}
+		if step.MinConfidence < 0 || step.MinConfidence > 1 {
+			return fmt.Errorf("step %d: min_confidence must be within [0, 1]", i+1)
+		}
+	}
+	return nil
+}
+
+// ModelUsage статистика использования модели в цепочке
+type ModelUsage struct {
+	Calls         int   `json:"calls"`
+	Accepted      int   `json:"accepted"`
+	BestEffort    int   `json:"best_effort"`
+	LowConfidence int   `json:"low_confidence"`
+	Errors        int   `json:"errors"`
+	AICalls       int   `json:"ai_calls"`
+	DurationMs    int64 `json:"duration_ms"`
+}
+
+// ModelChainStats потокобезопасная статистика шагов цепочки по моделям
+type ModelChainStats struct {
+	mu    sync.Mutex
+	usage map[string]*ModelUsage
+}
+
+// NewModelChainStats создает пустую статистику
+func NewModelChainStats() *ModelChainStats {
+	return &ModelChainStats{usage: make(map[string]*ModelUsage)}
+}
+
+// Record учитывает шаги цепочки одного элемента
+func (s *ModelChainStats) Record(attempts []ModelChainAttempt) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, attempt := range attempts {
+		usage, ok := s.usage[attempt.Model]
+		if !ok {
+			usage = &ModelUsage{}
+			s.usage[attempt.Model] = usage
+		}
+		usage.Calls++
+		usage.AICalls += attempt.AICalls
+		usage.DurationMs += attempt.DurationMs
+		switch attempt.Outcome {
+		case ChainOutcomeAccepted:
+			usage.Accepted++
+		case ChainOutcomeBestEffort:
+			usage.BestEffort++
+		case ChainOutcomeLowConfidence:
+			usage.LowConfidence++
+		case ChainOutcomeError:
+			usage.Errors++
+		}
+	}
+}
+
+// Snapshot возвращает копию статистики
+func (s *ModelChainStats) Snapshot() map[string]ModelUsage {
+	result := make(map[string]ModelUsage)
+	if s == nil {
+		return result
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for model, usage := range s.usage {
+		result[model] = *usage
+	}
+	return result
+}
+
+// runModelChain выполняет шаги по порядку, пока уверенность не достигнет порога шага.
+// call возвращает уверенность и число AI вызовов шага. Возвращает индекс выбранного шага
+// (лучший по уверенности, если порог не прошел ни один) или -1, если все шаги завершились ошибкой.
+func runModelChain(steps []ModelChainStep, call func(i int) (float64, int, error)) (int, []ModelChainAttempt, error) {
+	attempts := make([]ModelChainAttempt, 0, len(steps))
+	best := -1
+	var lastErr error
+
+	for i, step := range steps {
+		start := time.Now()
+		confidence, aiCalls, err := call(i)
+		attempt := ModelChainAttempt{
+			Model:      step.Model,
+			Confidence: confidence,
+			DurationMs: time.Since(start).Milliseconds(),
+			AICalls:    aiCalls,
+		}
+
+		switch {
+		case err != nil:
+			attempt.Outcome = ChainOutcomeError
+			attempt.Error = err.Error()
+			lastErr = err
+		case confidence >= step.MinConfidence:
+			attempt.Outcome = ChainOutcomeAccepted
+			attempts = append(attempts, attempt)
+			return i, attempts, nil
+		default:
+			attempt.Outcome = ChainOutcomeLowConfidence
+			if best < 0 || confidence > attempts[best].Confidence {
+				best = len(attempts)
+			}
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if best < 0 {
+		return -1, attempts, lastErr
+	}
+	// Шаги выполняются без пропусков, поэтому индекс попытки совпадает с индексом шага
+	attempts[best].Outcome = ChainOutcomeBestEffort
+	return best, attempts, nil
+}
+
+// ChainedClassifier иерархический классификатор с цепочкой моделей:
+// при низкой уверенности или ошибке запрос эскалируется на следующую модель
+type ChainedClassifier struct {
+	steps       []ModelChainStep
+	classifiers []*HierarchicalClassifier
+	stats       *ModelChainStats
+	historical  *HistoricalIndex
+	history     *historicalStats
+}
+
+// NewChainedClassifier создает цепочку на основе классификатора base.
+// Без шагов цепочка состоит из одной модели base без порога.
+func NewChainedClassifier(base *HierarchicalClassifier, steps []ModelChainStep) *ChainedClassifier {
+	chain := &ChainedClassifier{stats: NewModelChainStats(), history: &historicalStats{}}
+	if len(steps) == 0 {
+		chain.steps = []ModelChainStep{{Model: base.Model()}}
+		chain.classifiers = []*HierarchicalClassifier{base}
+		return chain
+	}
+
+	for _, step := range steps {
+		chain.steps = append(chain.steps, step)
+		chain.classifiers = append(chain.classifiers, base.WithAIClient(base.AIClient().WithModel(step.Model)))
+	}
+	return chain
+}
+
+// WithHistorical включает применение подтвержденных решений до обращения к моделям цепочки
+func (c *ChainedClassifier) WithHistorical(index *HistoricalIndex) *ChainedClassifier {
+	c.historical = index
+	return c
+}
+
+// Classify классифицирует по шагам цепочки и возвращает принятый результат с учетом шагов.
+// Если найдено подтвержденное решение для похожего наименования, AI не вызывается
+func (c *ChainedClassifier) Classify(normalizedName, category string) (*HierarchicalResult, error) {
+	if match, ok := c.historical.Lookup(normalizedName); ok {
+		attempts := []ModelChainAttempt{{Model: HistoricalModel, Outcome: ChainOutcomeAccepted, Confidence: match.Similarity}}
+		c.stats.Record(attempts)
+		c.history.record(true, false, 0, false)
+		return &HierarchicalResult{
+			FinalCode:       match.KpvedCode,
+			FinalName:       match.KpvedName,
+			FinalConfidence: match.Similarity,
+			Model:           HistoricalModel,
+			ModelChain:      attempts,
+			Historical:      match,
+		}, nil
+	}
+
+	results := make([]*HierarchicalResult, len(c.steps))
+	selected, attempts, err := runModelChain(c.steps, func(i int) (float64, int, error) {
+		result, err := c.classifiers[i].Classify(normalizedName, category)
+		if err != nil {
+			return 0, 0, err
+		}
+		results[i] = result
+		return result.FinalConfidence, result.AICallsCount, nil
+	})
+	c.stats.Record(attempts)
+	aiCalls := 0
+	for _, attempt := range attempts {
+		aiCalls += attempt.AICalls
+	}
+	c.history.record(false, c.historical != nil, aiCalls, selected >= 0)
+	if selected < 0 {
+		return nil, err
+	}
+
+	// Копия, чтобы не изменять результат в кэше классификатора шага
+	result := *results[selected]
+	result.Model = c.steps[selected].Model
+	result.ModelChain = attempts
+	return &result, nil
+}
+
+// Model возвращает модель первого шага
+func (c *ChainedClassifier) Model() string {
+	return c.steps[0].Model
+}
+
+// ContentKey возвращает версию промптов и шаги цепочки для хеша входных данных классификации:
+// смена моделей или порогов эскалации меняет результат так же, как изменение наименования
+func (c *ChainedClassifier) ContentKey() string {
+	return fmt.Sprintf("%s|%v", HierarchicalPromptVersion, c.steps)
+}
+
+// Steps возвращает шаги цепочки
+func (c *ChainedClassifier) Steps() []ModelChainStep {
+	return append([]ModelChainStep{}, c.steps...)
+}
+
+// Usage возвращает статистику шагов цепочки по моделям
+func (c *ChainedClassifier) Usage() map[string]ModelUsage {
+	return c.stats.Snapshot()
+}
+
+// HistoricalUsage возвращает учет применения подтвержденных решений и сэкономленных AI вызовов
+func (c *ChainedClassifier) HistoricalUsage() HistoricalUsage {
+	usage := c.history.snapshot()
+	usage.IndexSize = c.historical.Size()
+	return usage
+}
@@ -0,0 +1,106 @@
+package normalization
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunModelChain(t *testing.T) {
+	steps := []ModelChainStep{
+		{Model: "cheap", MinConfidence: 0.8},
+		{Model: "medium", MinConfidence: 0.8},
+		{Model: "strong", MinConfidence: 0.9},
+	}
+
+	type stepResult struct {
+		confidence float64
+		err        error
+	}
+	tests := []struct {
+		name         string
+		results      []stepResult
+		wantSelected int
+		wantOutcomes []string
+		wantErr      bool
+	}{
+		{
+			name:         "first step accepted",
+			results:      []stepResult{{0.9, nil}},
+			wantSelected: 0,
+			wantOutcomes: []string{ChainOutcomeAccepted},
+		},
+		{
+			name:         "escalate on low confidence",
+			results:      []stepResult{{0.5, nil}, {0.85, nil}},
+			wantSelected: 1,
+			wantOutcomes: []string{ChainOutcomeLowConfidence, ChainOutcomeAccepted},
+		},
+		{
+			name:         "escalate on error",
+			results:      []stepResult{{0, errors.New("parse failed")}, {0.95, nil}},
+			wantSelected: 1,
+			wantOutcomes: []string{ChainOutcomeError, ChainOutcomeAccepted},
+		},
+		{
+			name:         "best effort when no step passes",
+			results:      []stepResult{{0.6, nil}, {0.7, nil}, {0, errors.New("timeout")}},
+			wantSelected: 1,
+			wantOutcomes: []string{ChainOutcomeLowConfidence, ChainOutcomeBestEffort, ChainOutcomeError},
+		},
+		{
+			name:         "all steps failed",
+			results:      []stepResult{{0, errors.New("a")}, {0, errors.New("b")}, {0, errors.New("c")}},
+			wantSelected: -1,
+			wantOutcomes: []string{ChainOutcomeError, ChainOutcomeError, ChainOutcomeError},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, attempts, err := runModelChain(steps, func(i int) (float64, int, error) {
+				return tt.results[i].confidence, 1, tt.results[i].err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runModelChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if selected != tt.wantSelected {
+				t.Errorf("selected = %d, want %d", selected, tt.wantSelected)
+			}
+			if len(attempts) != len(tt.wantOutcomes) {
+				t.Fatalf("attempts = %d, want %d", len(attempts), len(tt.wantOutcomes))
+			}
+			for i, want := range tt.wantOutcomes {
+				if attempts[i].Outcome != want || attempts[i].Model != steps[i].Model {
+					t.Errorf("attempt %d = %s/%s, want %s/%s", i, attempts[i].Model, attempts[i].Outcome, steps[i].Model, want)
+				}
+			}
+		})
+	}
+}
+
+func TestModelChainStats(t *testing.T) {
+	stats := NewModelChainStats()
+	stats.Record([]ModelChainAttempt{
+		{Model: "cheap", Outcome: ChainOutcomeLowConfidence, AICalls: 3},
+		{Model: "strong", Outcome: ChainOutcomeAccepted, AICalls: 2},
+	})
+	stats.Record([]ModelChainAttempt{
+		{Model: "cheap", Outcome: ChainOutcomeAccepted, AICalls: 3},
+	})
+
+	usage := stats.Snapshot()
+	if got := usage["cheap"]; got.Calls != 2 || got.Accepted != 1 || got.LowConfidence != 1 || got.AICalls != 6 {
+		t.Errorf("unexpected cheap usage: %+v", got)
+	}
+	if got := usage["strong"]; got.Calls != 1 || got.Accepted != 1 {
+		t.Errorf("unexpected strong usage: %+v", got)
+	}
+
+	if err := ValidateModelChain([]ModelChainStep{{Model: "cheap", MinConfidence: 1.5}}); err == nil {
+		t.Error("Expected error for min_confidence out of range")
+	}
+	if err := ValidateModelChain([]ModelChainStep{{MinConfidence: 0.5}}); err == nil {
+		t.Error("Expected error for empty model")
+	}
+}
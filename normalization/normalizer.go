@@ -1,14 +1,19 @@
 package normalization
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"httpserver/database"
+	"httpserver/nomenclature"
+	"httpserver/tracing"
 )
 
 // AIConfig конфигурация для AI обработки
@@ -42,6 +47,7 @@ type Normalizer struct {
 	nameNormalizer         *NameNormalizer
 	aiNormalizer           *AINormalizer
 	hierarchicalClassifier *HierarchicalClassifier
+	calibrator             *Calibrator
 	events                 chan<- string
 	useAI                  bool
 	aiConfig               *AIConfig
@@ -54,6 +60,19 @@ type Normalizer struct {
 	enableCheckpoints bool
 	checkpointDir     string
 	currentCheckpoint *NormalizationCheckpoint // Текущий checkpoint для мониторинга
+	// Проект, политика порогов уверенности которого применяется при сохранении
+	projectID int
+	// Цепочка моделей AI нормализации (пустая - используется одна модель aiNormalizer)
+	aiChainMu    sync.RWMutex
+	aiChain      []ModelChainStep
+	aiChainSteps []*AINormalizer
+	aiChainStats *ModelChainStats
+	// Соответствия кодов клиента, применяемые до нормализации по правилам
+	codeMappings *CodeMappings
+	// Атрибуты элемента, передаваемые AI вместе с наименованием (настройка проекта)
+	promptAttributes []string
+	// Пути групп 1С исходных элементов по ссылке (если выбран атрибут промпта Группа1С)
+	groupPaths map[string]string
 }
 
 // groupKey ключ для группировки записей
@@ -72,6 +91,10 @@ type groupValue struct {
 	kpvedName       string
 	kpvedConfidence float64
 	attributes      map[string][]*database.ItemAttribute // code -> attributes
+	// Калибровка уверенности и решение политики порогов
+	kpvedRawConfidence float64
+	kpvedModel         string
+	decision           string
 }
 
 // NewNormalizer создает новый нормализатор
@@ -80,6 +103,8 @@ func NewNormalizer(db *database.DB, events chan<- string, aiConfig *AIConfig) *N
 		db:              db,
 		categorizer:     NewCategorizer(),
 		nameNormalizer:  NewNameNormalizer(),
+		calibrator:      NewCalibrator(db),
+		aiChainStats:    NewModelChainStats(),
 		events:          events,
 		useAI:           aiConfig != nil && aiConfig.Enabled,
 		aiConfig:        aiConfig,
@@ -136,6 +161,108 @@ func NewNormalizer(db *database.DB, events chan<- string, aiConfig *AIConfig) *N
 	return normalizer
 }
 
+// SetDB переключает нормализатор на другую БД (нормализация в этот момент не должна выполняться)
+func (n *Normalizer) SetDB(db *database.DB) {
+	n.db = db
+}
+
+// SetCalibrator устанавливает общий калибратор уверенности (например, разделяемый с сервером)
+func (n *Normalizer) SetCalibrator(calibrator *Calibrator) {
+	n.calibrator = calibrator
+}
+
+// AIClient возвращает AI клиент нормализатора (nil, если AI не настроен)
+func (n *Normalizer) AIClient() *nomenclature.AIClient {
+	if n == nil || n.aiNormalizer == nil {
+		return nil
+	}
+	return n.aiNormalizer.aiClient
+}
+
+// SetModelChain устанавливает цепочку моделей AI нормализации (nil - одна модель по умолчанию)
+func (n *Normalizer) SetModelChain(steps []ModelChainStep) {
+	n.aiChainMu.Lock()
+	defer n.aiChainMu.Unlock()
+
+	n.aiChain = nil
+	n.aiChainSteps = nil
+	if n.aiNormalizer == nil || len(steps) == 0 {
+		return
+	}
+
+	for _, step := range steps {
+		n.aiChain = append(n.aiChain, step)
+		n.aiChainSteps = append(n.aiChainSteps, n.aiNormalizer.WithModel(step.Model))
+	}
+	log.Printf("Цепочка моделей AI нормализации: %d шагов", len(steps))
+}
+
+// ModelChainUsage возвращает статистику шагов цепочки моделей по моделям
+func (n *Normalizer) ModelChainUsage() map[string]ModelUsage {
+	if n == nil {
+		return nil
+	}
+	n.aiChainMu.RLock()
+	defer n.aiChainMu.RUnlock()
+	return n.aiChainStats.Snapshot()
+}
+
+// SetProjectID устанавливает проект, политика порогов которого применяется при сохранении
+func (n *Normalizer) SetProjectID(projectID int) {
+	n.projectID = projectID
+}
+
+// SetDictionaries передает словари проекта AI нормализатору
+func (n *Normalizer) SetDictionaries(dictionaries *Dictionaries) {
+	if n.aiNormalizer != nil {
+		n.aiNormalizer.SetDictionaries(dictionaries)
+	}
+}
+
+// SetPromptAttributes задает атрибуты элемента (реквизиты источника или извлеченные из наименования),
+// передаваемые AI вместе с наименованием; пустой список - только наименование
+func (n *Normalizer) SetPromptAttributes(attributes []string) {
+	n.promptAttributes = attributes
+}
+
+// itemPromptAttributes отбирает атрибуты элемента для промпта AI по настройкам проекта
+func (n *Normalizer) itemPromptAttributes(item *database.CatalogItem) []PromptAttribute {
+	if len(n.promptAttributes) == 0 {
+		return nil
+	}
+	_, extracted := n.nameNormalizer.ExtractAttributes(RemoveStandards(item.Name))
+	extracted = append(extracted, StandardAttributes(ExtractStandards(item.Name))...)
+	values := database.ExtractAttributeValues(item.Attributes)
+	if path, ok := n.groupPaths[item.Reference]; ok {
+		values[database.CatalogGroupPathAttribute] = path
+	}
+	return SelectPromptAttributes(n.promptAttributes, values, extracted)
+}
+
+// usesGroupPaths проверяет, что путь групп 1С выбран в атрибутах промпта проекта
+func (n *Normalizer) usesGroupPaths() bool {
+	for _, attribute := range n.promptAttributes {
+		if strings.EqualFold(attribute, database.CatalogGroupPathAttribute) {
+			return true
+		}
+	}
+	return false
+}
+
+// classificationContext дополняет категорию путем групп 1С элемента - подсказкой для классификации КПВЭД
+func (n *Normalizer) classificationContext(item *database.CatalogItem, category string) string {
+	if path := n.groupPaths[item.Reference]; path != "" {
+		return category + "; группа 1С: " + path
+	}
+	return category
+}
+
+// SetCodeMappings устанавливает соответствия кодов клиента: записи с сопоставленными кодами
+// объединяются в группу нового кода до нормализации по правилам
+func (n *Normalizer) SetCodeMappings(mappings *CodeMappings) {
+	n.codeMappings = mappings
+}
+
 // SetSourceConfig устанавливает конфигурацию источника данных
 func (n *Normalizer) SetSourceConfig(tableName, referenceCol, codeCol, nameCol string) {
 	n.sourceTable = tableName
@@ -165,11 +292,36 @@ func (n *Normalizer) sendEvent(message string) {
 
 // ProcessNormalization выполняет полный процесс нормализации данных
 func (n *Normalizer) ProcessNormalization() error {
+	return n.ProcessNormalizationContext(context.Background())
+}
+
+// ProcessNormalizationContext выполняет нормализацию с возможностью остановки через ctx.
+// Отмена проверяется перед каждой записью и каждым пакетом вставки: уже вставленные пакеты
+// сохраняются, прогресс записывается в checkpoint
+func (n *Normalizer) ProcessNormalizationContext(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "normalization", tracing.Attr("normalization.source_table", n.sourceTable))
+	defer span.End()
+	err := n.processNormalization(ctx)
+	span.RecordError(err)
+	return err
+}
+
+// processNormalization выполняет этапы нормализации; каждый этап (очистка, загрузка, группировка, вставка)
+// записывается отдельным спаном трассировки
+func (n *Normalizer) processNormalization(ctx context.Context) error {
+	var stage *tracing.Span
+	nextStage := func(name string) {
+		stage.End()
+		_, stage = tracing.Start(ctx, "normalization."+name)
+	}
+	defer func() { stage.End() }()
+
 	startTime := time.Now()
 	n.sendEvent("Начало нормализации данных...")
 	log.Printf("Начало нормализации данных...")
 
 	// 1. Очищаем старые записи
+	nextStage("clean")
 	n.sendEvent("Очистка старых записей из catalog_items...")
 	log.Printf("Очистка старых записей из catalog_items...")
 	if err := n.db.CleanOldCatalogItems(); err != nil {
@@ -180,6 +332,7 @@ func (n *Normalizer) ProcessNormalization() error {
 	log.Printf("Очистка завершена")
 
 	// 2. Получаем все записи из указанной таблицы
+	nextStage("load")
 	n.sendEvent(fmt.Sprintf("Получение всех записей из %s...", n.sourceTable))
 	log.Printf("Получение всех записей из %s (ref=%s, code=%s, name=%s)...",
 		n.sourceTable, n.referenceColumn, n.codeColumn, n.nameColumn)
@@ -191,6 +344,23 @@ func (n *Normalizer) ProcessNormalization() error {
 	n.sendEvent(fmt.Sprintf("Получено %d записей из %s", len(items), n.sourceTable))
 	log.Printf("Получено %d записей из %s", len(items), n.sourceTable)
 
+	// Реквизиты источника нужны только для атрибутов промпта AI
+	if len(n.promptAttributes) > 0 {
+		sourceAttributes, err := n.db.GetCatalogItemAttributesFromTable(n.sourceTable, n.referenceColumn)
+		if err != nil {
+			log.Printf("Реквизиты источника недоступны, в промпт AI попадут только атрибуты из наименований: %v", err)
+		}
+		for _, item := range items {
+			item.Attributes = sourceAttributes[item.Reference]
+		}
+	}
+	n.groupPaths = nil
+	if n.usesGroupPaths() {
+		if n.groupPaths, err = n.db.GetCatalogGroupPaths(); err != nil {
+			log.Printf("Иерархия групп 1С недоступна, классификация выполняется без пути групп: %v", err)
+		}
+	}
+
 	// CHECKPOINT: Инициализация checkpoint для отслеживания прогресса
 	checkpoint := &NormalizationCheckpoint{
 		ProcessedCount:  0,
@@ -202,38 +372,123 @@ func (n *Normalizer) ProcessNormalization() error {
 		BatchSize:       1000,
 	}
 	n.currentCheckpoint = checkpoint // Сохраняем для мониторинга
+	if err := n.checkInterrupted(ctx, checkpoint); err != nil {
+		return err
+	}
 
 	// 3. Группируем записи по (категория, normalized_name)
+	nextStage("group")
+	stage.SetAttributes(tracing.Attr("normalization.items", len(items)))
 	n.sendEvent("Группировка записей...")
 	log.Printf("Группировка записей...")
 
 	groups := make(map[groupKey]*groupValue)
+	// События происхождения для каждой исходной записи
+	itemLineage := make(map[*database.CatalogItem][]*database.LineageRecord)
+	// Обозначения стандартов (ГОСТ, ТУ, DIN) для каждой исходной записи
+	itemStandards := make(map[*database.CatalogItem]string)
+	// Ключи групп новых кодов из соответствий клиента
+	mappedGroupKeys := make(map[string]groupKey)
 	processedCount := 0
 	aiProcessedCount := 0
 
 	for _, item := range items {
+		if err := n.checkInterrupted(ctx, checkpoint); err != nil {
+			return err
+		}
 		// Базовая нормализация (правила) с извлечением атрибутов
 		category := n.categorizer.Categorize(item.Name)
-		normalizedName, attributes := n.nameNormalizer.ExtractAttributes(item.Name)
+		// Обозначения стандартов выносятся в отдельную колонку и не участвуют в нормализации имени
+		standards := ExtractStandards(item.Name)
+		normalizedName, attributes := n.nameNormalizer.ExtractAttributes(RemoveStandards(item.Name))
+		attributes = append(attributes, StandardAttributes(standards)...)
+		attributes = append(attributes, DimensionAttributes(ParseDimensions(item.Name))...)
 		if normalizedName == "" {
 			normalizedName = item.Name // Используем исходное имя, если нормализация дала пустую строку
 		}
+		// Соответствие клиента задает группу записи, наименование нового кода заменяет нормализованное
+		mapping, mapped := n.codeMappings.Lookup(item.Code)
+		mappedGroupCode := n.codeMappings.GroupCode(item.Code)
+		if mapped && mapping.TargetName != "" {
+			if name := n.nameNormalizer.NormalizeName(mapping.TargetName); name != "" {
+				normalizedName = name
+			}
+		}
 		aiConfidence := 0.0
 		aiReasoning := ""
+		aiDecision := ""
 		processingLevel := "basic"
 
-		// AI обработка если требуется
-		if n.useAI && n.aiNormalizer != nil && n.aiNormalizer.RequiresAI(item.Name, category) {
-			aiResult, err := n.processWithAI(item.Name)
+		lineage := []*database.LineageRecord{
+			database.NewLineageRecord(database.LineageEventSource, n.sourceTable, item.ID, map[string]interface{}{
+				"reference":      item.Reference,
+				"code":           item.Code,
+				"name":           item.Name,
+				"run_started_at": startTime.Format(time.RFC3339),
+			}),
+			database.NewLineageRecord(database.LineageEventRules, "name_normalizer", item.ID, map[string]interface{}{
+				"input_name":           item.Name,
+				"normalized_name":      normalizedName,
+				"category":             category,
+				"attributes_extracted": len(attributes),
+				"standard_references":  StandardReferencesValue(standards),
+			}),
+		}
+		if mapped {
+			lineage = append(lineage, database.NewLineageRecord(database.LineageEventClientMapping, "client_code_mapping", item.ID, map[string]interface{}{
+				"source_code": mapping.SourceCode,
+				"target_code": mapping.TargetCode,
+				"group_code":  mappedGroupCode,
+				"target_name": mapping.TargetName,
+				"file_id":     mapping.FileID,
+				"row_number":  mapping.RowNumber,
+			}))
+		}
+
+		// AI обработка если требуется; для записей с соответствием клиента группа уже определена
+		if !mapped && n.useAI && n.aiNormalizer != nil && n.aiNormalizer.RequiresAI(item.Name, category) {
+			aiResult, chainAttempts, err := n.processWithAIChain(item.Name, n.itemPromptAttributes(item))
+			var evaluation ConfidenceEvaluation
+			if err == nil {
+				// Калибруем уверенность модели и применяем политику порогов проекта
+				evaluation = n.calibrator.Evaluate(aiResult.Model, n.projectID, aiResult.Confidence)
+			}
+			aiAccepted := err == nil && evaluation.Decision != database.ConfidenceDecisionReject &&
+				evaluation.Confidence >= n.aiConfig.MinConfidence
+			if err != nil {
+				lineage = append(lineage, database.NewLineageRecord(database.LineageEventAICall, "ai_normalizer", item.ID, map[string]interface{}{
+					"status":      "error",
+					"error":       err.Error(),
+					"model_chain": chainAttempts,
+				}))
+			} else {
+				status := "applied"
+				if !aiAccepted {
+					status = "rejected_low_confidence"
+				}
+				lineage = append(lineage, database.NewLineageRecord(database.LineageEventAICall, "ai_normalizer", item.ID, map[string]interface{}{
+					"status":          status,
+					"normalized_name": aiResult.NormalizedName,
+					"category":        aiResult.Category,
+					"confidence":      evaluation.Confidence,
+					"raw_confidence":  aiResult.Confidence,
+					"decision":        evaluation.Decision,
+					"reasoning":       aiResult.Reasoning,
+					"model":           aiResult.Model,
+					"model_chain":     chainAttempts,
+				}))
+			}
+
 			if err != nil {
 				n.sendEvent(fmt.Sprintf("⚠ AI ошибка для '%s': %v, используем правила", item.Name, err))
 				log.Printf("AI ошибка для '%s': %v, используем правила", item.Name, err)
-			} else if aiResult.Confidence >= n.aiConfig.MinConfidence {
-				// Используем результат AI если уверенность достаточная
+			} else if aiAccepted {
+				// Используем результат AI если откалиброванная уверенность достаточная
 				category = aiResult.Category
 				normalizedName = aiResult.NormalizedName
-				aiConfidence = aiResult.Confidence
+				aiConfidence = evaluation.Confidence
 				aiReasoning = aiResult.Reasoning
+				aiDecision = evaluation.Decision
 				processingLevel = "ai_enhanced"
 				aiProcessedCount++
 
@@ -241,13 +496,16 @@ func (n *Normalizer) ProcessNormalization() error {
 					n.sendEvent(fmt.Sprintf("🤖 AI обработано %d записей", aiProcessedCount))
 				}
 			} else {
-				n.sendEvent(fmt.Sprintf("⚠ AI низкая уверенность (%.2f) для '%s', используем правила", aiResult.Confidence, item.Name))
+				n.sendEvent(fmt.Sprintf("⚠ AI низкая уверенность (%.2f) для '%s', используем правила", evaluation.Confidence, item.Name))
 			}
 		}
 
 		// Создаем ключ группы ДО КПВЭД классификации, чтобы избежать изменения ключа
 		// Сначала определяем базовую категорию и нормализованное имя
 		key := groupKey{category: category, normalizedName: normalizedName}
+		if mappedKey, ok := mappedGroupKeys[mappedGroupCode]; ok {
+			key = mappedKey
+		}
 		group, exists := groups[key]
 
 		// КПВЭД классификация выполняется ТОЛЬКО для новых групп
@@ -255,18 +513,28 @@ func (n *Normalizer) ProcessNormalization() error {
 		kpvedCode := ""
 		kpvedName := ""
 		kpvedConfidence := 0.0
+		kpvedRawConfidence := 0.0
+		kpvedModel := ""
+		decision := aiDecision
 
 		if !exists {
 			// Для новой группы выполняем иерархическую КПВЭД классификацию
 			// Используем результат КПВЭД как категорию вместо простого Categorizer
 			if n.hierarchicalClassifier != nil {
-				kpvedResult, err := n.hierarchicalClassifier.Classify(normalizedName, category)
+				kpvedResult, err := n.hierarchicalClassifier.Classify(normalizedName, n.classificationContext(item, category))
 				if err != nil {
 					log.Printf("Warning: Hierarchical KPVED classification failed for '%s': %v, используем простую категорию", normalizedName, err)
 				} else {
-					kpvedCode = kpvedResult.FinalCode
-					kpvedName = kpvedResult.FinalName
-					kpvedConfidence = kpvedResult.FinalConfidence
+					// Калибруем уверенность и применяем политику порогов: отклоненный код не сохраняется
+					evaluation := n.calibrator.Evaluate(n.hierarchicalClassifier.Model(), n.projectID, kpvedResult.FinalConfidence)
+					kpvedModel = evaluation.Model
+					kpvedRawConfidence = kpvedResult.FinalConfidence
+					kpvedConfidence = evaluation.Confidence
+					decision = worseConfidenceDecision(decision, evaluation.Decision)
+					if evaluation.Decision != database.ConfidenceDecisionReject {
+						kpvedCode = kpvedResult.FinalCode
+						kpvedName = kpvedResult.FinalName
+					}
 					
 					// Используем название из КПВЭД как категорию, если уверенность достаточна
 					// Но только если это не изменит ключ группы (чтобы не создавать дубликаты)
@@ -296,6 +564,10 @@ func (n *Normalizer) ProcessNormalization() error {
 				kpvedName:       kpvedName,
 				kpvedConfidence: kpvedConfidence,
 				attributes:      make(map[string][]*database.ItemAttribute),
+
+				kpvedRawConfidence: kpvedRawConfidence,
+				kpvedModel:         kpvedModel,
+				decision:           decision,
 			}
 			groups[key] = group
 		} else {
@@ -307,6 +579,11 @@ func (n *Normalizer) ProcessNormalization() error {
 
 		// Добавляем запись в группу
 		group.items = append(group.items, item)
+		if mappedGroupCode != "" {
+			mappedGroupKeys[mappedGroupCode] = key
+		}
+		itemLineage[item] = lineage
+		itemStandards[item] = StandardReferencesValue(standards)
 		// Сохраняем атрибуты для этого элемента
 		if len(attributes) > 0 {
 			group.attributes[item.Code] = attributes
@@ -329,6 +606,8 @@ func (n *Normalizer) ProcessNormalization() error {
 	log.Print(message)
 
 	// 4. Вставляем нормализованные данные в БД
+	nextStage("insert")
+	stage.SetAttributes(tracing.Attr("normalization.groups", len(groups)))
 	n.sendEvent("Вставка нормализованных данных...")
 	log.Printf("Вставка нормализованных данных...")
 	totalInserted := 0
@@ -373,6 +652,28 @@ func (n *Normalizer) ProcessNormalization() error {
 				KpvedCode:           group.kpvedCode,
 				KpvedName:           group.kpvedName,
 				KpvedConfidence:     group.kpvedConfidence,
+				KpvedRawConfidence:  group.kpvedRawConfidence,
+				KpvedModel:          group.kpvedModel,
+				ConfidenceDecision:  group.decision,
+				StandardReferences:  itemStandards[item],
+				Lineage:             itemLineage[item],
+			}
+			if group.kpvedCode != "" {
+				normalizedItem.Lineage = append(normalizedItem.Lineage, database.NewLineageRecord(database.LineageEventClassification, "kpved_hierarchical", item.ID, map[string]interface{}{
+					"kpved_code":       group.kpvedCode,
+					"kpved_name":       group.kpvedName,
+					"kpved_confidence": group.kpvedConfidence,
+					"raw_confidence":   group.kpvedRawConfidence,
+					"model":            group.kpvedModel,
+					"decision":         group.decision,
+				}))
+			}
+			if mergedCount > 1 {
+				normalizedItem.Lineage = append(normalizedItem.Lineage, database.NewLineageRecord(database.LineageEventMerge, "normalization_group", item.ID, map[string]interface{}{
+					"group_size":      mergedCount,
+					"normalized_name": key.normalizedName,
+					"category":        key.category,
+				}))
 			}
 
 			batch = append(batch, normalizedItem)
@@ -383,6 +684,10 @@ func (n *Normalizer) ProcessNormalization() error {
 
 			// Вставляем пакетом для производительности
 			if len(batch) >= batchSize {
+				checkpoint.ProcessedCount = totalInserted
+				if err := n.checkInterrupted(ctx, checkpoint); err != nil {
+					return err
+				}
 				// ФИЛЬТРАЦИЯ ДУБЛИКАТОВ: проверяем батч на дубликаты с данными в БД
 				// Дубликаты с confidence >= 0.95 будут удалены из батча
 				// Вместо вставки дубликата увеличивается merged_count существующей записи
@@ -450,6 +755,10 @@ func (n *Normalizer) ProcessNormalization() error {
 
 	// Вставляем оставшиеся записи
 	if len(batch) > 0 {
+		checkpoint.ProcessedCount = totalInserted
+		if err := n.checkInterrupted(ctx, checkpoint); err != nil {
+			return err
+		}
 		// ФИЛЬТРАЦИЯ ДУБЛИКАТОВ для финального батча
 		filteredBatch, err := n.filterDuplicatesFromBatch(batch)
 		if err != nil {
@@ -534,7 +843,46 @@ func (n *Normalizer) countGroups(groups map[groupKey]*groupValue) int {
 }
 
 // processWithAI обрабатывает название с помощью AI с retry logic
-func (n *Normalizer) processWithAI(name string) (*AIResult, error) {
+func (n *Normalizer) processWithAI(name string, attributes []PromptAttribute) (*AIResult, error) {
+	return n.processWithAINormalizer(n.aiNormalizer, name, attributes)
+}
+
+// processWithAIChain обрабатывает название по цепочке моделей: при низкой уверенности
+// или ошибке запрос эскалируется на следующую модель. Без цепочки - одна модель по умолчанию.
+func (n *Normalizer) processWithAIChain(name string, attributes []PromptAttribute) (*AIResult, []ModelChainAttempt, error) {
+	n.aiChainMu.RLock()
+	steps, normalizers, stats := n.aiChain, n.aiChainSteps, n.aiChainStats
+	n.aiChainMu.RUnlock()
+
+	if len(steps) == 0 {
+		result, err := n.processWithAI(name, attributes)
+		if result != nil {
+			result.Model = n.aiNormalizer.aiClient.Model()
+		}
+		return result, nil, err
+	}
+
+	results := make([]*AIResult, len(steps))
+	selected, attempts, err := runModelChain(steps, func(i int) (float64, int, error) {
+		result, err := n.processWithAINormalizer(normalizers[i], name, attributes)
+		if err != nil {
+			return 0, 1, err
+		}
+		results[i] = result
+		return result.Confidence, 1, nil
+	})
+	stats.Record(attempts)
+	if selected < 0 {
+		return nil, attempts, err
+	}
+
+	result := results[selected]
+	result.Model = steps[selected].Model
+	return result, attempts, nil
+}
+
+// processWithAINormalizer вызывает AI нормализатор с повторными попытками
+func (n *Normalizer) processWithAINormalizer(aiNormalizer *AINormalizer, name string, attributes []PromptAttribute) (*AIResult, error) {
 	var lastErr error
 	maxRetries := n.aiConfig.MaxRetries
 	if maxRetries == 0 {
@@ -547,7 +895,7 @@ func (n *Normalizer) processWithAI(name string) (*AIResult, error) {
 			time.Sleep(n.aiConfig.RateLimitDelay)
 		}
 
-		result, err := n.aiNormalizer.NormalizeWithAI(name)
+		result, err := aiNormalizer.NormalizeWithAttributes(name, attributes)
 		if err == nil {
 			return result, nil
 		}
@@ -676,6 +1024,16 @@ func (n *Normalizer) filterDuplicatesFromBatch(batch []*database.NormalizedItem)
 							log.Printf("Найден дубликат: '%s' (батч) совпадает с записью ID=%d в БД (confidence=%.2f). Merged_count увеличен.", batchItem.NormalizedName, existingItem.ID, group.Confidence)
 						}
 
+						// Переносим происхождение дубликата на существующую запись
+						mergeLineage := append(batchItem.Lineage, database.NewLineageRecord(database.LineageEventMerge, "duplicate_filter", 0, map[string]interface{}{
+							"source_name": batchItem.SourceName,
+							"source_code": batchItem.Code,
+							"confidence":  group.Confidence,
+						}))
+						if err := n.db.AddNormalizedItemLineage(existingItem.ID, mergeLineage); err != nil {
+							log.Printf("ПРЕДУПРЕЖДЕНИЕ: не удалось сохранить происхождение для записи %d: %v", existingItem.ID, err)
+						}
+
 						// Прерываем внутренний цикл, т.к. дубликат уже найден
 						break
 					}
@@ -709,6 +1067,19 @@ func (n *Normalizer) filterDuplicatesFromBatch(batch []*database.NormalizedItem)
 
 // --- Методы для работы с checkpoints ---
 
+// checkInterrupted при отмене ctx сохраняет checkpoint и возвращает ошибку остановки
+func (n *Normalizer) checkInterrupted(ctx context.Context, checkpoint *NormalizationCheckpoint) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	n.currentCheckpoint = checkpoint
+	if err := n.saveCheckpoint(checkpoint); err != nil {
+		log.Printf("⚠ Предупреждение: не удалось сохранить checkpoint при остановке: %v", err)
+	}
+	n.sendEvent(fmt.Sprintf("Нормализация остановлена: вставлено %d из %d записей", checkpoint.ProcessedCount, checkpoint.TotalCount))
+	return fmt.Errorf("normalization interrupted: %w", ctx.Err())
+}
+
 // getCheckpointPath возвращает путь к файлу checkpoint для указанного uploadID
 func (n *Normalizer) getCheckpointPath(uploadID int) string {
 	return filepath.Join(n.checkpointDir, fmt.Sprintf("checkpoint_%d.json", uploadID))
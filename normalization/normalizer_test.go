@@ -1,6 +1,9 @@
 package normalization
 
 import (
+	"context"
+	"errors"
+	"os"
 	"testing"
 	"time"
 
@@ -267,3 +270,24 @@ func TestNormalizerCountGroups(t *testing.T) {
 	}
 }
 
+func TestProcessNormalizationContextCancelled(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create test DB: %v", err)
+	}
+	defer db.Close()
+
+	normalizer := NewNormalizer(db, nil, nil)
+	normalizer.checkpointDir = t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = normalizer.ProcessNormalizationContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ProcessNormalizationContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(normalizer.getCheckpointPath(1)); err != nil {
+		t.Errorf("checkpoint must be saved on cancel: %v", err)
+	}
+}
@@ -134,6 +134,7 @@ func (pai *PatternAIIntegrator) buildAIPrompt(originalName string, matches []Pat
 	prompt.WriteString("- Имеет правильный регистр\n")
 	prompt.WriteString("- Не содержит лишних пробелов и специальных символов\n")
 	prompt.WriteString("- Понятен и стандартизирован\n")
+	prompt.WriteString(pai.patternDetector.dictionaries.PromptSection())
 
 	return prompt.String()
 }
@@ -49,6 +49,7 @@ type PatternDetector struct {
 	patterns []PatternRule
 	parser   *StatefulParser   // Stateful парсер для контекстной детекции
 	analyzer *PatternAnalyzer  // Анализатор статистики паттернов
+	dictionaries *Dictionaries // Словари сокращений и защищенных токенов
 }
 
 // PatternRule правило для обнаружения паттерна
@@ -59,6 +60,7 @@ type PatternRule struct {
 	Severity    string
 	AutoFixable bool
 	FixFunc     func(string, *regexp.Regexp) string // Функция исправления
+	Accept      func(string) bool // Дополнительная проверка найденного текста (nil - принимается любой)
 	Confidence  float64
 }
 
@@ -68,11 +70,51 @@ func NewPatternDetector() *PatternDetector {
 		patterns: make([]PatternRule, 0),
 		parser:   NewStatefulParser(),
 		analyzer: NewPatternAnalyzer(100), // Топ-100 паттернов
+		dictionaries: defaultDictionaries,
 	}
 	detector.registerDefaultPatterns()
 	return detector
 }
 
+// SetDictionaries задает словари проекта: сокращения раскрываются правилом PatternAbbreviation,
+// защищенные токены не попадают в найденные паттерны и не изменяются исправлениями
+func (pd *PatternDetector) SetDictionaries(dictionaries *Dictionaries) {
+	if dictionaries == nil {
+		dictionaries = defaultDictionaries
+	}
+	pd.dictionaries = dictionaries
+
+	// Правило сокращений зависит от словаря, поэтому пересоздается
+	rules := pd.patterns[:0]
+	for _, rule := range pd.patterns {
+		if rule.Type != PatternAbbreviation {
+			rules = append(rules, rule)
+		}
+	}
+	pd.patterns = rules
+	if !dictionaries.HasAbbreviations() {
+		return
+	}
+
+	expand := func(word string) string {
+		expanded, _ := dictionaries.ExpandAbbreviation(word)
+		return expanded
+	}
+	pd.patterns = append(pd.patterns, PatternRule{
+		Type:        PatternAbbreviation,
+		Regex:       regexp.MustCompile(`[\p{L}\p{N}]+\.?`),
+		Description: "Сокращение из словаря проекта",
+		Severity:    "low",
+		AutoFixable: true,
+		FixFunc:     func(s string, r *regexp.Regexp) string { return r.ReplaceAllStringFunc(s, expand) },
+		Accept: func(s string) bool {
+			_, ok := dictionaries.ExpandAbbreviation(s)
+			return ok
+		},
+		Confidence: 0.9,
+	})
+}
+
 // registerDefaultPatterns регистрирует стандартные паттерны
 func (pd *PatternDetector) registerDefaultPatterns() {
 	// Технические коды (ER-00013004, ABC-12345)
@@ -271,6 +313,14 @@ func (pd *PatternDetector) DetectPatterns(name string) []PatternMatch {
 				end := match[1]
 				matchedText := name[start:end]
 
+				if rule.Accept != nil && !rule.Accept(matchedText) {
+					continue
+				}
+				// Защищенные токены словаря не считаются проблемой
+				if pd.dictionaries.IsProtectedSpan(name, start, end) {
+					continue
+				}
+
 				var suggestedFix string
 				if rule.FixFunc != nil {
 					suggestedFix = rule.FixFunc(matchedText, rule.Regex)
@@ -298,7 +348,8 @@ func (pd *PatternDetector) DetectPatterns(name string) []PatternMatch {
 
 // ApplyFixes применяет все автоприменяемые исправления
 func (pd *PatternDetector) ApplyFixes(name string, matches []PatternMatch) string {
-	fixed := name
+	// Защищенные токены подменяются метками на время исправлений
+	fixed, restore := pd.dictionaries.maskProtected(name)
 
 	// Применяем исправления для каждого типа паттерна только один раз
 	appliedTypes := make(map[PatternType]bool)
@@ -318,7 +369,7 @@ func (pd *PatternDetector) ApplyFixes(name string, matches []PatternMatch) strin
 	fixed = strings.Join(strings.Fields(fixed), " ")
 	fixed = strings.TrimSpace(fixed)
 
-	return fixed
+	return restore(fixed)
 }
 
 // findRuleByType находит правило по типу
@@ -0,0 +1,184 @@
+package normalization
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"httpserver/database"
+)
+
+// PromptAttribute атрибут элемента, передаваемый AI вместе с наименованием
+type PromptAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SelectPromptAttributes отбирает атрибуты элемента, выбранные в настройках проекта.
+// Имя из настроек сравнивается без учета регистра с реквизитами источника (например "Производитель")
+// и с типом или именем извлеченного из наименования атрибута ("article_code", "ГОСТ").
+// Реквизит источника важнее извлеченного атрибута с тем же именем; пустые значения пропускаются
+func SelectPromptAttributes(selected []string, sourceValues map[string]string, extracted []*database.ItemAttribute) []PromptAttribute {
+	if len(selected) == 0 {
+		return nil
+	}
+	source := make(map[string]string, len(sourceValues))
+	for name, value := range sourceValues {
+		source[strings.ToLower(name)] = value
+	}
+
+	var attributes []PromptAttribute
+	for _, name := range selected {
+		key := strings.ToLower(name)
+		if value := strings.TrimSpace(source[key]); value != "" {
+			attributes = append(attributes, PromptAttribute{Name: name, Value: value})
+			continue
+		}
+		var values []string
+		for _, attribute := range extracted {
+			if attribute == nil || attribute.AttributeValue == "" {
+				continue
+			}
+			if strings.ToLower(attribute.AttributeType) == key || strings.ToLower(attribute.AttributeName) == key {
+				values = append(values, attribute.AttributeValue)
+			}
+		}
+		if len(values) > 0 {
+			attributes = append(attributes, PromptAttribute{Name: name, Value: strings.Join(values, ", ")})
+		}
+	}
+	return attributes
+}
+
+// promptAttributesSection раздел пользовательского промпта с атрибутами элемента
+func promptAttributesSection(attributes []PromptAttribute) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nАТРИБУТЫ ТОВАРА (используй для понимания, что это за товар; не добавляй их в наименование):\n")
+	for _, attribute := range attributes {
+		fmt.Fprintf(&b, "- %s: %s\n", attribute.Name, attribute.Value)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// promptAttributesKey ключ кэша для набора атрибутов; порядок атрибутов не влияет на ключ
+func promptAttributesKey(attributes []PromptAttribute) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+	parts := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		parts[i] = strings.ToLower(attribute.Name) + "=" + strings.ToLower(attribute.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\x00")
+}
+
+// PromptEvaluationCase эталонный пример для оценки промптов: исходное наименование,
+// атрибуты элемента и ожидаемый результат
+type PromptEvaluationCase struct {
+	Name             string            `json:"name"`
+	Attributes       []PromptAttribute `json:"attributes,omitempty"`
+	ExpectedName     string            `json:"expected_name"`
+	ExpectedCategory string            `json:"expected_category,omitempty"`
+}
+
+// PromptEvaluationCasesFromBenchmarks формирует примеры оценки из эталонов проекта: атрибуты отбираются
+// из реквизитов эталона и из его исходного наименования так же, как при нормализации
+func PromptEvaluationCasesFromBenchmarks(benchmarks []*database.ClientBenchmark, selected []string) []PromptEvaluationCase {
+	nameNormalizer := NewNameNormalizer()
+	cases := make([]PromptEvaluationCase, 0, len(benchmarks))
+	for _, benchmark := range benchmarks {
+		_, extracted := nameNormalizer.ExtractAttributes(RemoveStandards(benchmark.OriginalName))
+		extracted = append(extracted, StandardAttributes(ExtractStandards(benchmark.OriginalName))...)
+		cases = append(cases, PromptEvaluationCase{
+			Name:             benchmark.OriginalName,
+			Attributes:       SelectPromptAttributes(selected, database.ExtractAttributeValues(benchmark.Attributes), extracted),
+			ExpectedName:     benchmark.NormalizedName,
+			ExpectedCategory: benchmark.Category,
+		})
+	}
+	return cases
+}
+
+// PromptEvaluation точность нормализации на наборе эталонов
+type PromptEvaluation struct {
+	Cases            int     `json:"cases"`
+	Errors           int     `json:"errors"`
+	NameMatches      int     `json:"name_matches"`
+	CategoryMatches  int     `json:"category_matches"`
+	CategoryCases    int     `json:"category_cases"` // Эталонов с ожидаемой категорией
+	NameAccuracy     float64 `json:"name_accuracy"`
+	CategoryAccuracy float64 `json:"category_accuracy"`
+}
+
+// PromptAttributesComparison сравнение точности без атрибутов и с атрибутами в промпте
+type PromptAttributesComparison struct {
+	WithoutAttributes PromptEvaluation `json:"without_attributes"`
+	WithAttributes    PromptEvaluation `json:"with_attributes"`
+	NameAccuracyDelta float64          `json:"name_accuracy_delta"`
+	// Эталоны, результат которых изменился при добавлении атрибутов (исправлен или испорчен)
+	Improved  []string `json:"improved"`
+	Regressed []string `json:"regressed"`
+}
+
+// PromptNormalizeFunc нормализует наименование с атрибутами (nil - без атрибутов)
+type PromptNormalizeFunc func(name string, attributes []PromptAttribute) (*AIResult, error)
+
+// EvaluatePromptAttributes прогоняет эталоны без атрибутов и с атрибутами и сравнивает точность.
+// Наименования сравниваются без учета регистра и лишних пробелов
+func EvaluatePromptAttributes(cases []PromptEvaluationCase, normalize PromptNormalizeFunc) *PromptAttributesComparison {
+	comparison := &PromptAttributesComparison{Improved: []string{}, Regressed: []string{}}
+	for _, c := range cases {
+		without := evaluatePromptCase(&comparison.WithoutAttributes, c, normalize, nil)
+		with := evaluatePromptCase(&comparison.WithAttributes, c, normalize, c.Attributes)
+		switch {
+		case with && !without:
+			comparison.Improved = append(comparison.Improved, c.Name)
+		case without && !with:
+			comparison.Regressed = append(comparison.Regressed, c.Name)
+		}
+	}
+	comparison.WithoutAttributes.finish()
+	comparison.WithAttributes.finish()
+	comparison.NameAccuracyDelta = comparison.WithAttributes.NameAccuracy - comparison.WithoutAttributes.NameAccuracy
+	return comparison
+}
+
+// evaluatePromptCase учитывает результат одного эталона; возвращает, совпало ли наименование
+func evaluatePromptCase(evaluation *PromptEvaluation, c PromptEvaluationCase, normalize PromptNormalizeFunc, attributes []PromptAttribute) bool {
+	evaluation.Cases++
+	if c.ExpectedCategory != "" {
+		evaluation.CategoryCases++
+	}
+	result, err := normalize(c.Name, attributes)
+	if err != nil || result == nil {
+		evaluation.Errors++
+		return false
+	}
+	if c.ExpectedCategory != "" && sameNormalizedText(result.Category, c.ExpectedCategory) {
+		evaluation.CategoryMatches++
+	}
+	if sameNormalizedText(result.NormalizedName, c.ExpectedName) {
+		evaluation.NameMatches++
+		return true
+	}
+	return false
+}
+
+// finish рассчитывает доли совпадений
+func (e *PromptEvaluation) finish() {
+	if e.Cases > 0 {
+		e.NameAccuracy = float64(e.NameMatches) / float64(e.Cases)
+	}
+	if e.CategoryCases > 0 {
+		e.CategoryAccuracy = float64(e.CategoryMatches) / float64(e.CategoryCases)
+	}
+}
+
+// sameNormalizedText сравнивает наименования без учета регистра и лишних пробелов
+func sameNormalizedText(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
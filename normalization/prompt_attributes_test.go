@@ -0,0 +1,106 @@
+package normalization
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestSelectPromptAttributes(t *testing.T) {
+	extracted := []*database.ItemAttribute{
+		{AttributeType: "article_code", AttributeName: "article", AttributeValue: "ER-00013004"},
+		{AttributeType: AttributeTypeStandard, AttributeName: "ГОСТ", AttributeValue: "ГОСТ 7798-70"},
+		{AttributeType: AttributeTypeStandard, AttributeName: "ТУ", AttributeValue: "ТУ 14-1-2000"},
+	}
+	source := map[string]string{"Производитель": "Bosch", "Артикул": " 2608 ", "Страна": ""}
+
+	tests := []struct {
+		name     string
+		selected []string
+		want     []PromptAttribute
+	}{
+		{"nothing selected", nil, nil},
+		{"source attribute ignores case", []string{"производитель"}, []PromptAttribute{{"производитель", "Bosch"}}},
+		{"source wins over extracted", []string{"Артикул", "article_code"}, []PromptAttribute{{"Артикул", "2608"}, {"article_code", "ER-00013004"}}},
+		{"extracted by name", []string{"гост"}, []PromptAttribute{{"гост", "ГОСТ 7798-70"}}},
+		{"extracted by type joins values", []string{"standard"}, []PromptAttribute{{"standard", "ГОСТ 7798-70, ТУ 14-1-2000"}}},
+		{"empty and missing skipped", []string{"Страна", "Цвет"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SelectPromptAttributes(tt.selected, source, extracted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectPromptAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptAttributesPrompt(t *testing.T) {
+	attributes := []PromptAttribute{{"Производитель", "Bosch"}, {"ГОСТ", "ГОСТ 7798-70"}}
+	section := promptAttributesSection(attributes)
+	if !strings.Contains(section, "- Производитель: Bosch") || !strings.Contains(section, "- ГОСТ: ГОСТ 7798-70") {
+		t.Errorf("promptAttributesSection() = %q", section)
+	}
+	if promptAttributesSection(nil) != "" || promptAttributesKey(nil) != "" {
+		t.Error("empty attributes must not change prompt or cache key")
+	}
+	reversed := []PromptAttribute{attributes[1], attributes[0]}
+	if promptAttributesKey(attributes) != promptAttributesKey(reversed) {
+		t.Error("promptAttributesKey() depends on attribute order")
+	}
+}
+
+func TestEvaluatePromptAttributes(t *testing.T) {
+	cases := []PromptEvaluationCase{
+		// Без производителя модель не отличает картридж принтера от фильтра
+		{Name: "Картридж HP 12A", Attributes: []PromptAttribute{{"Производитель", "HP"}}, ExpectedName: "картридж для принтера", ExpectedCategory: "расходники"},
+		{Name: "Болт М8", ExpectedName: "болт", ExpectedCategory: "стройматериалы"},
+		{Name: "Лампа", Attributes: []PromptAttribute{{"Производитель", "Osram"}}, ExpectedName: "лампа светодиодная"},
+		{Name: "Ошибка", ExpectedName: "ошибка"},
+	}
+	normalize := func(name string, attributes []PromptAttribute) (*AIResult, error) {
+		switch name {
+		case "Картридж HP 12A":
+			if len(attributes) > 0 {
+				return &AIResult{NormalizedName: "Картридж  для принтера", Category: "расходники"}, nil
+			}
+			return &AIResult{NormalizedName: "картридж фильтра", Category: "сантехника"}, nil
+		case "Болт М8":
+			return &AIResult{NormalizedName: "болт", Category: "Стройматериалы"}, nil
+		case "Лампа":
+			if len(attributes) > 0 {
+				return &AIResult{NormalizedName: "лампа osram"}, nil
+			}
+			return &AIResult{NormalizedName: "лампа светодиодная"}, nil
+		}
+		return nil, errors.New("ai unavailable")
+	}
+
+	got := EvaluatePromptAttributes(cases, normalize)
+	if got.WithoutAttributes.NameMatches != 2 || got.WithAttributes.NameMatches != 2 || got.WithAttributes.Errors != 1 {
+		t.Errorf("evaluations = %+v / %+v", got.WithoutAttributes, got.WithAttributes)
+	}
+	if got.WithoutAttributes.CategoryAccuracy != 0.5 || got.WithAttributes.CategoryAccuracy != 1 || got.WithAttributes.CategoryCases != 2 {
+		t.Errorf("category accuracy = %v / %v", got.WithoutAttributes.CategoryAccuracy, got.WithAttributes.CategoryAccuracy)
+	}
+	if !reflect.DeepEqual(got.Improved, []string{"Картридж HP 12A"}) || !reflect.DeepEqual(got.Regressed, []string{"Лампа"}) {
+		t.Errorf("improved = %v, regressed = %v", got.Improved, got.Regressed)
+	}
+	if got.NameAccuracyDelta != 0 {
+		t.Errorf("NameAccuracyDelta = %v, want 0", got.NameAccuracyDelta)
+	}
+}
+
+func TestPromptEvaluationCasesFromBenchmarks(t *testing.T) {
+	benchmarks := []*database.ClientBenchmark{
+		{OriginalName: "Болт М8 ГОСТ 7798-70", NormalizedName: "болт", Category: "крепеж", Attributes: `{"Производитель": "Метиз"}`},
+	}
+	cases := PromptEvaluationCasesFromBenchmarks(benchmarks, []string{"Производитель", "ГОСТ"})
+	want := []PromptAttribute{{"Производитель", "Метиз"}, {"ГОСТ", "ГОСТ 7798-70"}}
+	if len(cases) != 1 || cases[0].ExpectedName != "болт" || !reflect.DeepEqual(cases[0].Attributes, want) {
+		t.Errorf("PromptEvaluationCasesFromBenchmarks() = %+v", cases)
+	}
+}
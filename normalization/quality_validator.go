@@ -292,18 +292,12 @@ func extractKeyWords(text string) []string {
 	words := strings.Fields(strings.ToLower(text))
 	keyWords := make([]string, 0)
 
-	// Фильтруем короткие слова и стоп-слова
-	stopWords := map[string]bool{
-		"и": true, "в": true, "на": true, "с": true, "для": true,
-		"по": true, "из": true, "к": true, "от": true,
-	}
-
 	for _, word := range words {
 		// Убираем пунктуацию
 		word = strings.Trim(word, ".,!?;:-\"'")
 
 		// Пропускаем короткие слова и стоп-слова
-		if len(word) < 3 || stopWords[word] {
+		if len(word) < 3 || defaultDictionaries.IsStopWord(word) {
 			continue
 		}
 
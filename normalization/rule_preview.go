@@ -0,0 +1,170 @@
+package normalization
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"httpserver/database"
+)
+
+// Типы правил-кандидатов для предварительного просмотра
+const (
+	RuleTypeRegex        = "regex"        // Замена по регулярному выражению
+	RuleTypeAbbreviation = "abbreviation" // Раскрытие сокращения (как запись словаря)
+	RuleTypeStopWord     = "stop_word"    // Удаление слова (как запись словаря)
+)
+
+// CandidateRule правило нормализации, проверяемое до включения
+type CandidateRule struct {
+	Type            string `json:"type"`
+	Pattern         string `json:"pattern,omitempty"`     // regex: регулярное выражение
+	Replacement     string `json:"replacement,omitempty"` // regex: замена, допускает $1
+	CaseInsensitive bool   `json:"case_insensitive,omitempty"`
+	Term            string `json:"term,omitempty"`      // abbreviation, stop_word: слово
+	Expansion       string `json:"expansion,omitempty"` // abbreviation: полная форма
+}
+
+// wordBoundary граница слова: \b в Go не учитывает кириллицу
+const wordBoundary = `[^\p{L}\p{N}]`
+
+// Compile возвращает функцию применения правила к наименованию
+func (r CandidateRule) Compile() (func(string) string, error) {
+	var (
+		expr        string
+		replacement string
+	)
+	switch r.Type {
+	case RuleTypeRegex:
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("pattern is required")
+		}
+		expr, replacement = r.Pattern, r.Replacement
+	case RuleTypeAbbreviation, RuleTypeStopWord:
+		term := strings.TrimSpace(r.Term)
+		if term == "" {
+			return nil, fmt.Errorf("term is required")
+		}
+		if r.Type == RuleTypeAbbreviation {
+			if strings.TrimSpace(r.Expansion) == "" {
+				return nil, fmt.Errorf("expansion is required")
+			}
+			replacement = "${1}" + strings.ReplaceAll(strings.TrimSpace(r.Expansion), "$", "$$") + "${2}"
+		} else {
+			replacement = "${1}${2}"
+		}
+		expr = `(^|` + wordBoundary + `)` + regexp.QuoteMeta(term) + `(` + wordBoundary + `|$)`
+		r.CaseInsensitive = true
+	default:
+		return nil, fmt.Errorf("unknown rule type: %q", r.Type)
+	}
+
+	if r.CaseInsensitive {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return func(name string) string {
+		// Соседние вхождения делят границу слова, поэтому замена повторяется до устойчивого результата
+		result := name
+		for i := 0; i < 5; i++ {
+			next := re.ReplaceAllString(result, replacement)
+			if next == result {
+				break
+			}
+			result = next
+		}
+		return strings.Join(strings.Fields(result), " ")
+	}, nil
+}
+
+// RulePreviewSample группа, наименование которой меняет правило
+type RulePreviewSample struct {
+	Category string `json:"category"`
+	Before   string `json:"before"`
+	After    string `json:"after"`
+	Items    int    `json:"items"`
+}
+
+// RuleCollisionSource исходное наименование, совпадающее после применения правила с другими
+type RuleCollisionSource struct {
+	Name    string `json:"name"`
+	Items   int    `json:"items"`
+	Changed bool   `json:"changed"` // false - наименование не затронуто правилом, к нему сводятся измененные
+}
+
+// RuleCollision разные наименования одной категории, которые станут одинаковыми
+type RuleCollision struct {
+	Category string                `json:"category"`
+	After    string                `json:"after"`
+	Items    int                   `json:"items"`
+	Sources  []RuleCollisionSource `json:"sources"`
+}
+
+// RulePreview результат применения правила ко всем группам без изменения данных
+type RulePreview struct {
+	TotalGroups   int                 `json:"total_groups"`
+	TotalItems    int                 `json:"total_items"`
+	MatchedGroups int                 `json:"matched_groups"`
+	MatchedItems  int                 `json:"matched_items"`
+	Samples       []RulePreviewSample `json:"samples"`
+	Collisions    []RuleCollision     `json:"collisions"`
+}
+
+// PreviewRule применяет правило к группам наименований и находит коллизии: наименования одной категории,
+// различные до правила и одинаковые после, если хотя бы одно из них изменено правилом.
+// Образцы отсортированы по числу записей, коллизии - по числу затронутых записей
+func PreviewRule(groups []database.NormalizedNameGroup, apply func(string) string) *RulePreview {
+	preview := &RulePreview{Samples: []RulePreviewSample{}, Collisions: []RuleCollision{}}
+
+	type collisionKey struct{ category, after string }
+	targets := make(map[collisionKey][]RuleCollisionSource)
+	changedTargets := make(map[collisionKey]bool)
+	var order []collisionKey
+
+	for _, group := range groups {
+		preview.TotalGroups++
+		preview.TotalItems += group.Items
+
+		after := apply(group.Name)
+		changed := after != group.Name
+		if changed {
+			preview.MatchedGroups++
+			preview.MatchedItems += group.Items
+			preview.Samples = append(preview.Samples, RulePreviewSample{
+				Category: group.Category, Before: group.Name, After: after, Items: group.Items,
+			})
+		}
+
+		key := collisionKey{group.Category, strings.ToLower(after)}
+		if _, ok := targets[key]; !ok {
+			order = append(order, key)
+		}
+		targets[key] = append(targets[key], RuleCollisionSource{Name: group.Name, Items: group.Items, Changed: changed})
+		if changed {
+			changedTargets[key] = true
+		}
+	}
+
+	for _, key := range order {
+		sources := targets[key]
+		if len(sources) < 2 || !changedTargets[key] {
+			continue
+		}
+		collision := RuleCollision{Category: key.category, Sources: sources}
+		for _, source := range sources {
+			collision.Items += source.Items
+			if collision.After == "" || !source.Changed {
+				collision.After = apply(source.Name)
+			}
+		}
+		preview.Collisions = append(preview.Collisions, collision)
+	}
+
+	sort.SliceStable(preview.Samples, func(i, j int) bool { return preview.Samples[i].Items > preview.Samples[j].Items })
+	sort.SliceStable(preview.Collisions, func(i, j int) bool { return preview.Collisions[i].Items > preview.Collisions[j].Items })
+	return preview
+}
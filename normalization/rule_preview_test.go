@@ -0,0 +1,77 @@
+package normalization
+
+import (
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestCandidateRuleCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    CandidateRule
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"regex", CandidateRule{Type: RuleTypeRegex, Pattern: `(\d+)\s*мм`, Replacement: "${1}мм"}, "болт 10 мм", "болт 10мм", false},
+		{"regex case insensitive", CandidateRule{Type: RuleTypeRegex, Pattern: `БОЛТ`, Replacement: "винт", CaseInsensitive: true}, "болт м10", "винт м10", false},
+		{"abbreviation whole word", CandidateRule{Type: RuleTypeAbbreviation, Term: "шт", Expansion: "штука"}, "шт гайка штанга шт", "штука гайка штанга штука", false},
+		{"stop word", CandidateRule{Type: RuleTypeStopWord, Term: "новый"}, "Новый болт новый м10", "болт м10", false},
+		{"invalid regex", CandidateRule{Type: RuleTypeRegex, Pattern: `(`}, "", "", true},
+		{"missing term", CandidateRule{Type: RuleTypeStopWord}, "", "", true},
+		{"unknown type", CandidateRule{Type: "synonym", Term: "x"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apply, err := tt.rule.Compile()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := apply(tt.input); got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewRule(t *testing.T) {
+	apply, err := CandidateRule{Type: RuleTypeStopWord, Term: "новый"}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	groups := []database.NormalizedNameGroup{
+		{Name: "болт м10", Category: "крепеж", Items: 5},
+		{Name: "новый болт м10", Category: "крепеж", Items: 2},
+		{Name: "болт новый м10", Category: "крепеж", Items: 1},
+		{Name: "новый кабель", Category: "электрика", Items: 3},
+		{Name: "кабель", Category: "провода", Items: 4},
+		{Name: "гайка", Category: "крепеж", Items: 7},
+	}
+
+	preview := PreviewRule(groups, apply)
+	if preview.TotalGroups != 6 || preview.TotalItems != 22 {
+		t.Errorf("totals = %d/%d, want 6/22", preview.TotalGroups, preview.TotalItems)
+	}
+	if preview.MatchedGroups != 3 || preview.MatchedItems != 6 {
+		t.Errorf("matched = %d/%d, want 3/6", preview.MatchedGroups, preview.MatchedItems)
+	}
+	if len(preview.Samples) != 3 || preview.Samples[0].Before != "новый кабель" || preview.Samples[0].After != "кабель" {
+		t.Errorf("samples = %+v", preview.Samples)
+	}
+
+	// Кабель в другой категории коллизией не считается
+	if len(preview.Collisions) != 1 {
+		t.Fatalf("collisions = %+v, want 1", preview.Collisions)
+	}
+	collision := preview.Collisions[0]
+	if collision.After != "болт м10" || collision.Items != 8 || len(collision.Sources) != 3 {
+		t.Errorf("collision = %+v", collision)
+	}
+	if collision.Sources[0].Changed {
+		t.Errorf("existing group %q marked as changed", collision.Sources[0].Name)
+	}
+}
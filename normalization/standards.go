@@ -0,0 +1,158 @@
+package normalization
+
+import (
+	"regexp"
+	"strings"
+
+	"httpserver/database"
+)
+
+// StandardReference ссылка на стандарт или технические условия в наименовании (ГОСТ, ТУ, DIN, ISO)
+type StandardReference struct {
+	Type        string `json:"type"`        // ГОСТ, ГОСТ Р, ГОСТ Р ИСО, ТУ, СТО, ОСТ, DIN, DIN EN, DIN EN ISO, ISO
+	Number      string `json:"number"`      // Номер без пробелов: 7798-70, 2296-001-12345678-2005
+	Designation string `json:"designation"` // Нормализованное обозначение: "ГОСТ 7798-70"
+	Original    string `json:"original"`    // Текст в исходном наименовании
+	Position    int    `json:"position"`
+	Valid       bool   `json:"valid"`
+	Issue       string `json:"issue,omitempty"` // Причина, по которой обозначение не прошло проверку формата
+}
+
+// Типы атрибутов для обозначений стандартов
+const (
+	AttributeTypeStandard        = "standard"
+	AttributeTypeStandardInvalid = "standard_invalid"
+)
+
+// standardRegex находит обозначения стандартов. Граница слова задается явно: \b в Go не учитывает кириллицу
+var standardRegex = regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(ГОСТ\s*Р\s*ИСО|ГОСТ\s*Р|ГОСТ|GOST|ТУ|СТО|ОСТ|DIN\s*EN\s*ISO|DIN\s*EN|DIN|ISO)\s*№?\s*(\d[\d.]*(?:\s*[-–—]\s*[\d.]+)*(?:\s*:\s*\d{4})?)`)
+
+var (
+	standardSpacesRegex = regexp.MustCompile(`\s+`)
+	// ГОСТ 7798-70, ГОСТ Р 52857.1-2007: номер и год принятия
+	gostNumberRegex = regexp.MustCompile(`^\d+(\.\d+)*-(\d{2}|\d{4})$`)
+	// ТУ 14-3-1128-2000, ТУ 22.29.29-001-12345678-2019: не меньше трех частей, последняя - год
+	tuNumberRegex = regexp.MustCompile(`^\d+(\.\d+)*(-\d+){1,}-(\d{2}|\d{4})$`)
+	// СТО 00044434-001-2005, ОСТ 26-2043-91
+	organizationNumberRegex = regexp.MustCompile(`^\d+(\.\d+)*(-\d+)*-(\d{2}|\d{4})$`)
+	// DIN 933, ISO 4017:2014, DIN EN ISO 10684
+	internationalNumberRegex = regexp.MustCompile(`^\d+(\.\d+)?(-\d+)*(:\d{4})?$`)
+)
+
+// ExtractStandards извлекает обозначения стандартов из наименования, нормализует пробелы и проверяет формат
+func ExtractStandards(name string) []StandardReference {
+	var refs []StandardReference
+	for _, match := range standardRegex.FindAllStringSubmatchIndex(name, -1) {
+		start, end := match[2], match[5]
+		original := strings.TrimRight(name[start:end], ".")
+
+		ref := StandardReference{
+			Type:     normalizeStandardType(name[match[2]:match[3]]),
+			Number:   normalizeStandardNumber(name[match[4]:match[5]]),
+			Original: original,
+			Position: start,
+		}
+		ref.Designation = ref.Type + " " + ref.Number
+		ref.Valid, ref.Issue = validateStandardNumber(ref.Type, ref.Number)
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// RemoveStandards удаляет обозначения стандартов из наименования перед нормализацией правилами
+func RemoveStandards(name string) string {
+	matches := standardRegex.FindAllStringSubmatchIndex(name, -1)
+	if len(matches) == 0 {
+		return name
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		b.WriteString(name[last:match[2]])
+		last = match[5]
+	}
+	b.WriteString(name[last:])
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// StandardReferencesValue возвращает значение колонки standard_references: корректные обозначения без повторов через "; "
+func StandardReferencesValue(refs []StandardReference) string {
+	var designations []string
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Valid && !seen[ref.Designation] {
+			seen[ref.Designation] = true
+			designations = append(designations, ref.Designation)
+		}
+	}
+	return strings.Join(designations, database.StandardReferencesSeparator)
+}
+
+// StandardAttributes возвращает обозначения стандартов как атрибуты элемента.
+// Обозначения с ошибкой формата сохраняются с отдельным типом для ручной проверки
+func StandardAttributes(refs []StandardReference) []*database.ItemAttribute {
+	attributes := make([]*database.ItemAttribute, 0, len(refs))
+	for _, ref := range refs {
+		attribute := &database.ItemAttribute{
+			AttributeType:  AttributeTypeStandard,
+			AttributeName:  ref.Type,
+			AttributeValue: ref.Designation,
+			OriginalText:   ref.Original,
+			Confidence:     0.95,
+		}
+		if !ref.Valid {
+			attribute.AttributeType = AttributeTypeStandardInvalid
+			attribute.Confidence = 0.5
+		}
+		attributes = append(attributes, attribute)
+	}
+	return attributes
+}
+
+// normalizeStandardType приводит тип стандарта к каноническому написанию
+func normalizeStandardType(value string) string {
+	value = strings.ToUpper(standardSpacesRegex.ReplaceAllString(strings.TrimSpace(value), ""))
+	switch value {
+	case "GOST":
+		return "ГОСТ"
+	case "ГОСТР":
+		return "ГОСТ Р"
+	case "ГОСТРИСО":
+		return "ГОСТ Р ИСО"
+	case "DINEN":
+		return "DIN EN"
+	case "DINENISO":
+		return "DIN EN ISO"
+	}
+	return value
+}
+
+// normalizeStandardNumber убирает пробелы в номере и заменяет длинные тире дефисом
+func normalizeStandardNumber(value string) string {
+	value = standardSpacesRegex.ReplaceAllString(value, "")
+	value = strings.NewReplacer("–", "-", "—", "-").Replace(value)
+	return strings.TrimRight(value, ".")
+}
+
+// validateStandardNumber проверяет формат номера для типа стандарта
+func validateStandardNumber(standardType, number string) (bool, string) {
+	switch standardType {
+	case "ГОСТ", "ГОСТ Р", "ГОСТ Р ИСО":
+		if !gostNumberRegex.MatchString(number) {
+			return false, "ожидается номер и год принятия: ГОСТ 7798-70"
+		}
+	case "ТУ":
+		if !tuNumberRegex.MatchString(number) {
+			return false, "ожидается код, номер предприятия и год: ТУ 14-3-1128-2000"
+		}
+	case "СТО", "ОСТ":
+		if !organizationNumberRegex.MatchString(number) {
+			return false, "ожидается номер и год: " + standardType + " 26-2043-91"
+		}
+	default:
+		if !internationalNumberRegex.MatchString(number) {
+			return false, "ожидается номер и необязательный год: ISO 4017:2014"
+		}
+	}
+	return true, ""
+}
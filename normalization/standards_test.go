@@ -0,0 +1,53 @@
+package normalization
+
+import (
+	"testing"
+)
+
+func TestExtractStandards(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		designation []string
+		valid       []bool
+		remaining   string
+	}{
+		{"gost with spaces around dash", "Болт М8х40 ГОСТ 7798 - 70", []string{"ГОСТ 7798-70"}, []bool{true}, "Болт М8х40"},
+		{"gost r lowercase", "Труба гост р 52134-2003.", []string{"ГОСТ Р 52134-2003"}, []bool{true}, "Труба"},
+		{"latin gost and number sign", "Лист GOST №19903-2015", []string{"ГОСТ 19903-2015"}, []bool{true}, "Лист"},
+		{"tu designation", "Краска ТУ 2312-001-12345678-2005", []string{"ТУ 2312-001-12345678-2005"}, []bool{true}, "Краска"},
+		{"din and iso", "Винт DIN933 / ISO 4017:2014", []string{"DIN 933", "ISO 4017:2014"}, []bool{true, true}, "Винт /"},
+		{"gost without year is invalid", "Гайка ГОСТ 5915", []string{"ГОСТ 5915"}, []bool{false}, "Гайка"},
+		{"tu without year is invalid", "Клей ТУ 2385", []string{"ТУ 2385"}, []bool{false}, "Клей"},
+		{"standard prefix inside word ignored", "Стол офисный 1200", nil, nil, "Стол офисный 1200"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs := ExtractStandards(tt.input)
+			if len(refs) != len(tt.designation) {
+				t.Fatalf("ExtractStandards(%q) = %+v, want %v", tt.input, refs, tt.designation)
+			}
+			for i, ref := range refs {
+				if ref.Designation != tt.designation[i] || ref.Valid != tt.valid[i] {
+					t.Errorf("ref[%d] = %q valid %v, want %q valid %v", i, ref.Designation, ref.Valid, tt.designation[i], tt.valid[i])
+				}
+				if !ref.Valid && ref.Issue == "" {
+					t.Errorf("ref[%d] invalid without issue", i)
+				}
+			}
+			if got := RemoveStandards(tt.input); got != tt.remaining {
+				t.Errorf("RemoveStandards(%q) = %q, want %q", tt.input, got, tt.remaining)
+			}
+		})
+	}
+
+	refs := ExtractStandards("Болт ГОСТ 7798-70, гост 7798 - 70, ГОСТ 5915")
+	if got := StandardReferencesValue(refs); got != "ГОСТ 7798-70" {
+		t.Errorf("StandardReferencesValue() = %q, want only valid designations without repeats", got)
+	}
+	attributes := StandardAttributes(refs)
+	if len(attributes) != 3 || attributes[2].AttributeType != AttributeTypeStandardInvalid {
+		t.Errorf("StandardAttributes() = %+v", attributes)
+	}
+}
@@ -0,0 +1,322 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// File содержимое файла Parquet, прочитанного Read
+type File struct {
+	Columns   []Column
+	Rows      [][]interface{} // Значения по колонкам: nil, string, int64, float64, bool, time.Time (UTC)
+	RowGroups int
+	Metadata  map[string]string
+}
+
+// Read читает файл целиком. Поддерживаются файлы, которые записывает Writer: плоская схема,
+// PLAIN кодирование, страницы данных v1 без сжатия или со сжатием gzip
+func Read(data []byte) (*File, error) {
+	if len(data) < 12 || string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		return nil, errors.New("parquet: not a parquet file")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if footerLen <= 0 || footerLen > len(data)-12 {
+		return nil, errors.New("parquet: invalid footer length")
+	}
+	meta, err := readThriftStruct(bytes.NewReader(data[len(data)-8-footerLen : len(data)-8]))
+	if err != nil {
+		return nil, fmt.Errorf("parquet: decode footer: %w", err)
+	}
+
+	schema, _ := meta[2].([]interface{})
+	if len(schema) < 2 {
+		return nil, errors.New("parquet: empty schema")
+	}
+	file := &File{Metadata: map[string]string{}}
+	for _, element := range schema[1:] {
+		fields, _ := element.(map[int16]interface{})
+		name, _ := fields[4].(string)
+		physical, _ := fields[1].(int64)
+		converted, hasConverted := fields[6].(int64)
+		columnType, err := columnTypeOf(physical, converted, hasConverted)
+		if err != nil {
+			return nil, fmt.Errorf("parquet: column %q: %w", name, err)
+		}
+		file.Columns = append(file.Columns, Column{Name: name, Type: columnType})
+	}
+	if kv, ok := meta[5].([]interface{}); ok {
+		for _, entry := range kv {
+			fields, _ := entry.(map[int16]interface{})
+			key, _ := fields[1].(string)
+			value, _ := fields[2].(string)
+			file.Metadata[key] = value
+		}
+	}
+
+	groups, _ := meta[4].([]interface{})
+	file.RowGroups = len(groups)
+	for _, group := range groups {
+		groupFields, _ := group.(map[int16]interface{})
+		chunks, _ := groupFields[1].([]interface{})
+		if len(chunks) != len(file.Columns) {
+			return nil, errors.New("parquet: row group does not match schema")
+		}
+		numRows, _ := groupFields[3].(int64)
+		groupRows := make([][]interface{}, numRows)
+		for i := range groupRows {
+			groupRows[i] = make([]interface{}, len(file.Columns))
+		}
+		for c, chunk := range chunks {
+			values, err := readColumnChunk(data, chunk, file.Columns[c].Type)
+			if err != nil {
+				return nil, fmt.Errorf("parquet: column %q: %w", file.Columns[c].Name, err)
+			}
+			if int64(len(values)) != numRows {
+				return nil, fmt.Errorf("parquet: column %q has %d values, row group has %d rows", file.Columns[c].Name, len(values), numRows)
+			}
+			for r, value := range values {
+				groupRows[r][c] = value
+			}
+		}
+		file.Rows = append(file.Rows, groupRows...)
+	}
+	return file, nil
+}
+
+// columnTypeOf тип колонки по физическому типу и ConvertedType
+func columnTypeOf(physical, converted int64, hasConverted bool) (Type, error) {
+	switch {
+	case physical == physicalByteArray:
+		return String, nil
+	case physical == physicalInt64 && hasConverted && converted == convertedTimestampMillis:
+		return Timestamp, nil
+	case physical == physicalInt64:
+		return Int64, nil
+	case physical == physicalDouble:
+		return Double, nil
+	case physical == physicalBoolean:
+		return Boolean, nil
+	}
+	return 0, fmt.Errorf("unsupported physical type %d", physical)
+}
+
+// readColumnChunk читает значения всех страниц фрагмента колонки
+func readColumnChunk(data []byte, chunk interface{}, columnType Type) ([]interface{}, error) {
+	chunkFields, _ := chunk.(map[int16]interface{})
+	meta, _ := chunkFields[3].(map[int16]interface{})
+	offset, _ := meta[9].(int64)
+	size, _ := meta[7].(int64)
+	codec, _ := meta[4].(int64)
+	if offset < 4 || size < 0 || offset+size > int64(len(data)) {
+		return nil, errors.New("invalid column chunk bounds")
+	}
+
+	var values []interface{}
+	reader := bytes.NewReader(data[offset : offset+size])
+	for reader.Len() > 0 {
+		header, err := readThriftStruct(reader)
+		if err != nil {
+			return nil, fmt.Errorf("decode page header: %w", err)
+		}
+		if pageType, _ := header[1].(int64); pageType != pageTypeData {
+			return nil, fmt.Errorf("unsupported page type %d", pageType)
+		}
+		compressedSize, _ := header[3].(int64)
+		page := make([]byte, compressedSize)
+		if _, err := io.ReadFull(reader, page); err != nil {
+			return nil, err
+		}
+		switch codec {
+		case compressionNone:
+		case compressionGzip:
+			gz, err := gzip.NewReader(bytes.NewReader(page))
+			if err != nil {
+				return nil, err
+			}
+			if page, err = io.ReadAll(gz); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported codec %d", codec)
+		}
+		dataHeader, _ := header[5].(map[int16]interface{})
+		numValues, _ := dataHeader[1].(int64)
+		pageValues, err := decodePage(page, columnType, int(numValues))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, pageValues...)
+	}
+	return values, nil
+}
+
+// decodePage декодирует страницу данных: уровни определения и PLAIN значения
+func decodePage(page []byte, columnType Type, numValues int) ([]interface{}, error) {
+	errCorrupt := errors.New("corrupt data page")
+	if len(page) < 4 {
+		return nil, errCorrupt
+	}
+	levelsLen := int(binary.LittleEndian.Uint32(page))
+	if levelsLen > len(page)-4 {
+		return nil, errCorrupt
+	}
+	defined, err := decodeLevels(bytes.NewReader(page[4:4+levelsLen]), numValues)
+	if err != nil {
+		return nil, err
+	}
+	values := page[4+levelsLen:]
+
+	result := make([]interface{}, numValues)
+	pos, boolIndex := 0, 0
+	for i := range result {
+		if !defined[i] {
+			continue
+		}
+		switch columnType {
+		case String:
+			if pos+4 > len(values) {
+				return nil, errCorrupt
+			}
+			n := int(binary.LittleEndian.Uint32(values[pos:]))
+			if pos+4+n > len(values) {
+				return nil, errCorrupt
+			}
+			result[i] = string(values[pos+4 : pos+4+n])
+			pos += 4 + n
+		case Boolean:
+			if boolIndex/8 >= len(values) {
+				return nil, errCorrupt
+			}
+			result[i] = values[boolIndex/8]&(1<<(boolIndex%8)) != 0
+			boolIndex++
+		default:
+			if pos+8 > len(values) {
+				return nil, errCorrupt
+			}
+			bits := binary.LittleEndian.Uint64(values[pos:])
+			pos += 8
+			switch columnType {
+			case Double:
+				result[i] = math.Float64frombits(bits)
+			case Timestamp:
+				result[i] = time.UnixMilli(int64(bits)).UTC()
+			default:
+				result[i] = int64(bits)
+			}
+		}
+	}
+	return result, nil
+}
+
+// decodeLevels декодирует уровни определения разрядности 1 (гибрид RLE/bit-packing)
+func decodeLevels(r *bytes.Reader, numValues int) ([]bool, error) {
+	defined := make([]bool, 0, numValues)
+	for len(defined) < numValues {
+		header, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("decode levels: %w", err)
+		}
+		if header&1 == 0 {
+			v, err := r.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("decode levels: %w", err)
+			}
+			for n := uint64(0); n < header>>1; n++ {
+				defined = append(defined, v == 1)
+			}
+			continue
+		}
+		for g := uint64(0); g < header>>1; g++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("decode levels: %w", err)
+			}
+			for bit := 0; bit < 8; bit++ {
+				defined = append(defined, b&(1<<bit) != 0)
+			}
+		}
+	}
+	return defined[:numValues], nil
+}
+
+// readThriftStruct разбирает структуру компактного протокола Thrift в карту номер поля -> значение
+// (i32/i64 - int64, binary - string, list - []interface{}, struct - map[int16]interface{})
+func readThriftStruct(r *bytes.Reader) (map[int16]interface{}, error) {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return fields, nil
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := binary.ReadVarint(r)
+			if err != nil {
+				return nil, err
+			}
+			id = int16(v)
+		}
+		value, err := readThriftValue(r, header&0x0F)
+		if err != nil {
+			return nil, err
+		}
+		fields[id] = value
+		last = id
+	}
+}
+
+func readThriftValue(r *bytes.Reader, fieldType byte) (interface{}, error) {
+	switch fieldType {
+	case thriftBoolTrue:
+		return true, nil
+	case thriftBoolFalse:
+		return false, nil
+	case thriftI32, thriftI64:
+		return binary.ReadVarint(r)
+	case thriftBinary:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(r, buf)
+		return string(buf), err
+	case thriftStruct:
+		return readThriftStruct(r)
+	case thriftList:
+		header, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = binary.ReadUvarint(r); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			if items[i], err = readThriftValue(r, header&0x0F); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported thrift type %d", fieldType)
+}
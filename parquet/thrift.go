@@ -0,0 +1,113 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Типы полей компактного протокола Thrift, которым сериализуются метаданные Parquet
+const (
+	thriftBoolTrue  byte = 1
+	thriftBoolFalse byte = 2
+	thriftI32       byte = 5
+	thriftI64       byte = 6
+	thriftBinary    byte = 8
+	thriftList      byte = 9
+	thriftStruct    byte = 12
+)
+
+// thriftWriter кодировщик компактного протокола Thrift (только используемые метаданными Parquet типы)
+type thriftWriter struct {
+	buf bytes.Buffer
+	// Номер последнего записанного поля для каждой открытой структуры (заголовки полей кодируются дельтой)
+	lastField []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastField: []int16{0}}
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	t.buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) fieldHeader(id int16, fieldType byte) {
+	top := len(t.lastField) - 1
+	if delta := id - t.lastField[top]; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | fieldType)
+	} else {
+		t.buf.WriteByte(fieldType)
+		t.zigzag(int64(id))
+	}
+	t.lastField[top] = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
+
+// beginStruct открывает вложенную структуру в поле id
+func (t *thriftWriter) beginStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.lastField = append(t.lastField, 0)
+}
+
+// beginElement открывает структуру - элемент списка (без заголовка поля)
+func (t *thriftWriter) beginElement() {
+	t.lastField = append(t.lastField, 0)
+}
+
+// endStruct закрывает текущую структуру
+func (t *thriftWriter) endStruct() {
+	t.buf.WriteByte(0)
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+// beginList записывает заголовок списка из size элементов; элементы пишутся следом
+func (t *thriftWriter) beginList(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	t.buf.WriteByte(0xF0 | elemType)
+	t.varint(uint64(size))
+}
+
+func (t *thriftWriter) listI32(id int16, values ...int32) {
+	t.beginList(id, thriftI32, len(values))
+	for _, v := range values {
+		t.zigzag(int64(v))
+	}
+}
+
+func (t *thriftWriter) listBinary(id int16, values ...string) {
+	t.beginList(id, thriftBinary, len(values))
+	for _, v := range values {
+		t.varint(uint64(len(v)))
+		t.buf.WriteString(v)
+	}
+}
+
+// bytes завершает сообщение верхнего уровня и возвращает его
+func (t *thriftWriter) bytes() []byte {
+	t.endStruct()
+	return t.buf.Bytes()
+}
@@ -0,0 +1,553 @@
+// Package parquet записывает таблицы в формате Apache Parquet для загрузки в аналитические
+// системы (Spark, DuckDB). Поддерживается плоская схема из необязательных (nullable) колонок
+// строкового, целого, вещественного, логического типов и отметок времени; значения кодируются
+// PLAIN, уровни определения - RLE, страницы сжимаются gzip.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// Type тип колонки
+type Type int
+
+const (
+	String    Type = iota // BYTE_ARRAY (UTF8)
+	Int64                 // INT64
+	Double                // DOUBLE
+	Boolean               // BOOLEAN
+	Timestamp             // INT64 (TIMESTAMP_MILLIS, UTC)
+)
+
+// Codec сжатие страниц
+type Codec int
+
+const (
+	CodecGzip Codec = iota // По умолчанию: поддерживается всеми читателями
+	CodecNone
+)
+
+// Размеры по умолчанию: группа строк ~64 МБ несжатых данных (миллионы строк укладываются
+// в десятки групп, которые читаются параллельно), страница ~1 МБ
+const (
+	DefaultRowGroupSize = 64 << 20
+	DefaultPageSize     = 1 << 20
+)
+
+// Значения перечислений формата (parquet.thrift)
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	compressionNone = 0
+	compressionGzip = 2
+
+	pageTypeData = 0
+)
+
+const magic = "PAR1"
+
+// ErrClosed запись в закрытый Writer
+var ErrClosed = errors.New("parquet: writer is closed")
+
+// Column колонка схемы; все колонки допускают NULL
+type Column struct {
+	Name string
+	Type Type
+}
+
+// Options параметры записи; нулевые значения заменяются значениями по умолчанию
+type Options struct {
+	Codec        Codec
+	RowGroupSize int               // Несжатый объем группы строк в байтах
+	PageSize     int               // Несжатый объем страницы в байтах
+	Metadata     map[string]string // Произвольные пары ключ-значение в метаданных файла
+}
+
+// Writer потоковая запись строк. Строки буферизуются в памяти в пределах группы строк
+// (сжатыми страницами), после заполнения группа записывается в w
+type Writer struct {
+	out       *countingWriter
+	columns   []Column
+	options   Options
+	chunks    []*columnChunk
+	groupRows int64
+	rows      int64
+	groups    []rowGroup
+	gzip      *gzip.Writer
+	closed    bool
+}
+
+// rowGroup метаданные записанной группы строк
+type rowGroup struct {
+	columns   []chunkMeta
+	totalSize int64
+	rows      int64
+}
+
+type chunkMeta struct {
+	dataPageOffset   int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// columnChunk данные колонки в текущей группе строк
+type columnChunk struct {
+	column Column
+	// Текущая страница: PLAIN значения (для Boolean - bools) и признаки наличия значения
+	values  bytes.Buffer
+	bools   []bool
+	defined []bool
+	// Завершенные страницы группы (заголовок + данные)
+	pages            bytes.Buffer
+	numValues        int64
+	uncompressedSize int64
+}
+
+// NewWriter начинает файл Parquet со схемой columns
+func NewWriter(w io.Writer, columns []Column, options Options) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: schema must contain at least one column")
+	}
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if column.Name == "" {
+			return nil, errors.New("parquet: column name is required")
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("parquet: duplicate column %q", column.Name)
+		}
+		if column.Type < String || column.Type > Timestamp {
+			return nil, fmt.Errorf("parquet: column %q has unsupported type %d", column.Name, column.Type)
+		}
+		seen[column.Name] = true
+	}
+	if options.RowGroupSize <= 0 {
+		options.RowGroupSize = DefaultRowGroupSize
+	}
+	if options.PageSize <= 0 {
+		options.PageSize = DefaultPageSize
+	}
+
+	pw := &Writer{
+		out:     &countingWriter{w: w},
+		columns: append([]Column(nil), columns...),
+		options: options,
+		chunks:  make([]*columnChunk, len(columns)),
+	}
+	for i, column := range pw.columns {
+		pw.chunks[i] = &columnChunk{column: column}
+	}
+	if options.Codec == CodecGzip {
+		pw.gzip = gzip.NewWriter(io.Discard)
+	}
+	if _, err := pw.out.Write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Columns возвращает схему файла
+func (w *Writer) Columns() []Column {
+	return w.columns
+}
+
+// Rows возвращает число записанных строк
+func (w *Writer) Rows() int64 {
+	return w.rows
+}
+
+// Write добавляет строку. Значения по порядку колонок: nil - NULL; String - string;
+// Int64 - int, int32, int64; Double - float64, float32, int, int64; Boolean - bool;
+// Timestamp - time.Time (нулевое время - NULL)
+func (w *Writer) Write(row []interface{}) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, schema has %d columns", len(row), len(w.columns))
+	}
+	// Строка проверяется целиком до записи, чтобы колонки группы не разошлись по числу значений
+	for i, value := range row {
+		if err := checkValue(w.columns[i], value); err != nil {
+			return err
+		}
+	}
+
+	var groupSize int64
+	for i, value := range row {
+		chunk := w.chunks[i]
+		chunk.append(value)
+		if chunk.pageSize() >= w.options.PageSize {
+			if err := w.flushPage(chunk); err != nil {
+				return err
+			}
+		}
+		groupSize += chunk.uncompressedSize + int64(chunk.pageSize())
+	}
+	w.groupRows++
+	w.rows++
+
+	if groupSize >= int64(w.options.RowGroupSize) {
+		return w.flushRowGroup()
+	}
+	return nil
+}
+
+// Close записывает последнюю группу строк и метаданные файла. Нижележащий io.Writer не закрывается
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.flushRowGroup(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	footer := w.fileMetadata()
+	if _, err := w.out.Write(footer); err != nil {
+		return err
+	}
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	if _, err := w.out.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := w.out.Write([]byte(magic))
+	return err
+}
+
+// checkValue проверяет соответствие значения типу колонки
+func checkValue(column Column, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	ok := false
+	switch column.Type {
+	case String:
+		_, ok = value.(string)
+	case Int64:
+		switch value.(type) {
+		case int, int32, int64:
+			ok = true
+		}
+	case Double:
+		switch value.(type) {
+		case float64, float32, int, int64:
+			ok = true
+		}
+	case Boolean:
+		_, ok = value.(bool)
+	case Timestamp:
+		_, ok = value.(time.Time)
+	}
+	if !ok {
+		return fmt.Errorf("parquet: column %q: unsupported value %T", column.Name, value)
+	}
+	return nil
+}
+
+// append добавляет проверенное значение в текущую страницу
+func (c *columnChunk) append(value interface{}) {
+	if t, ok := value.(time.Time); ok && t.IsZero() {
+		value = nil
+	}
+	c.defined = append(c.defined, value != nil)
+	if value == nil {
+		return
+	}
+
+	var buf [8]byte
+	switch c.column.Type {
+	case String:
+		s := value.(string)
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(s)))
+		c.values.Write(buf[:4])
+		c.values.WriteString(s)
+	case Int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(toInt64(value)))
+		c.values.Write(buf[:])
+	case Double:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(toFloat64(value)))
+		c.values.Write(buf[:])
+	case Boolean:
+		c.bools = append(c.bools, value.(bool))
+	case Timestamp:
+		binary.LittleEndian.PutUint64(buf[:], uint64(value.(time.Time).UnixMilli()))
+		c.values.Write(buf[:])
+	}
+}
+
+// pageSize оценка несжатого объема текущей страницы
+func (c *columnChunk) pageSize() int {
+	return c.values.Len() + (len(c.bools)+7)/8 + len(c.defined)/8
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	default:
+		return value.(int64)
+	}
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	default:
+		return value.(float64)
+	}
+}
+
+// flushPage завершает текущую страницу колонки: уровни определения, значения, сжатие и заголовок
+func (w *Writer) flushPage(c *columnChunk) error {
+	if len(c.defined) == 0 {
+		return nil
+	}
+
+	var page bytes.Buffer
+	levels := encodeLevels(c.defined)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(levels)))
+	page.Write(length[:])
+	page.Write(levels)
+	if c.column.Type == Boolean {
+		page.Write(packBools(c.bools))
+	} else {
+		page.Write(c.values.Bytes())
+	}
+
+	data := page.Bytes()
+	uncompressedSize := len(data)
+	if w.gzip != nil {
+		var compressed bytes.Buffer
+		w.gzip.Reset(&compressed)
+		if _, err := w.gzip.Write(data); err != nil {
+			return err
+		}
+		if err := w.gzip.Close(); err != nil {
+			return err
+		}
+		data = compressed.Bytes()
+	}
+
+	header := pageHeader(uncompressedSize, len(data), len(c.defined))
+	c.pages.Write(header)
+	c.pages.Write(data)
+	c.numValues += int64(len(c.defined))
+	c.uncompressedSize += int64(len(header) + uncompressedSize)
+
+	c.values.Reset()
+	c.bools = c.bools[:0]
+	c.defined = c.defined[:0]
+	return nil
+}
+
+// flushRowGroup записывает накопленные страницы всех колонок как группу строк
+func (w *Writer) flushRowGroup() error {
+	if w.groupRows == 0 {
+		return nil
+	}
+	group := rowGroup{rows: w.groupRows, columns: make([]chunkMeta, len(w.chunks))}
+	for i, chunk := range w.chunks {
+		if err := w.flushPage(chunk); err != nil {
+			return err
+		}
+		meta := chunkMeta{
+			dataPageOffset:   w.out.n,
+			numValues:        chunk.numValues,
+			uncompressedSize: chunk.uncompressedSize,
+			compressedSize:   int64(chunk.pages.Len()),
+		}
+		if _, err := w.out.Write(chunk.pages.Bytes()); err != nil {
+			return err
+		}
+		group.columns[i] = meta
+		group.totalSize += meta.uncompressedSize
+
+		chunk.pages.Reset()
+		chunk.numValues = 0
+		chunk.uncompressedSize = 0
+	}
+	w.groups = append(w.groups, group)
+	w.groupRows = 0
+	return nil
+}
+
+// encodeLevels кодирует уровни определения (разрядность 1) гибридным RLE/bit-packing:
+// одно RLE повторение, если все значения одинаковы, иначе один bit-packed блок
+func encodeLevels(defined []bool) []byte {
+	var out bytes.Buffer
+	var tmp [binary.MaxVarintLen64]byte
+	same := true
+	for _, d := range defined {
+		if d != defined[0] {
+			same = false
+			break
+		}
+	}
+	if same {
+		out.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(defined))<<1)])
+		if defined[0] {
+			out.WriteByte(1)
+		} else {
+			out.WriteByte(0)
+		}
+		return out.Bytes()
+	}
+	groups := (len(defined) + 7) / 8
+	out.Write(tmp[:binary.PutUvarint(tmp[:], uint64(groups)<<1|1)])
+	out.Write(packBools(defined))
+	return out.Bytes()
+}
+
+// packBools упаковывает значения по биту, начиная с младшего
+func packBools(values []bool) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+// pageHeader сериализует PageHeader страницы данных
+func pageHeader(uncompressedSize, compressedSize, numValues int) []byte {
+	t := newThriftWriter()
+	t.i32(1, pageTypeData)
+	t.i32(2, int32(uncompressedSize))
+	t.i32(3, int32(compressedSize))
+	t.beginStruct(5)
+	t.i32(1, int32(numValues))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.endStruct()
+	return t.bytes()
+}
+
+// fileMetadata сериализует FileMetaData: схему, группы строк и пользовательские метаданные
+func (w *Writer) fileMetadata() []byte {
+	codec := int32(compressionNone)
+	if w.gzip != nil {
+		codec = compressionGzip
+	}
+
+	t := newThriftWriter()
+	t.i32(1, 1)
+	t.beginList(2, thriftStruct, len(w.columns)+1)
+	t.beginElement()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(w.columns)))
+	t.endStruct()
+	for _, column := range w.columns {
+		physical, converted := physicalType(column.Type)
+		t.beginElement()
+		t.i32(1, physical)
+		t.i32(3, repetitionOptional)
+		t.binary(4, column.Name)
+		if converted >= 0 {
+			t.i32(6, converted)
+		}
+		t.endStruct()
+	}
+	t.i64(3, w.rows)
+
+	t.beginList(4, thriftStruct, len(w.groups))
+	for _, group := range w.groups {
+		t.beginElement()
+		t.beginList(1, thriftStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			physical, _ := physicalType(w.columns[i].Type)
+			t.beginElement()
+			t.i64(2, chunk.dataPageOffset)
+			t.beginStruct(3)
+			t.i32(1, physical)
+			t.listI32(2, encodingPlain, encodingRLE)
+			t.listBinary(3, w.columns[i].Name)
+			t.i32(4, codec)
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.uncompressedSize)
+			t.i64(7, chunk.compressedSize)
+			t.i64(9, chunk.dataPageOffset)
+			t.endStruct()
+			t.endStruct()
+		}
+		t.i64(2, group.totalSize)
+		t.i64(3, group.rows)
+		t.endStruct()
+	}
+
+	if len(w.options.Metadata) > 0 {
+		keys := make([]string, 0, len(w.options.Metadata))
+		for key := range w.options.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		t.beginList(5, thriftStruct, len(keys))
+		for _, key := range keys {
+			t.beginElement()
+			t.binary(1, key)
+			t.binary(2, w.options.Metadata[key])
+			t.endStruct()
+		}
+	}
+	t.binary(6, "httpserver parquet writer")
+	return t.bytes()
+}
+
+// physicalType физический тип и ConvertedType колонки (-1 - без ConvertedType)
+func physicalType(columnType Type) (int32, int32) {
+	switch columnType {
+	case Int64:
+		return physicalInt64, -1
+	case Double:
+		return physicalDouble, -1
+	case Boolean:
+		return physicalBoolean, -1
+	case Timestamp:
+		return physicalInt64, convertedTimestampMillis
+	default:
+		return physicalByteArray, convertedUTF8
+	}
+}
+
+// countingWriter считает записанные байты для смещений страниц
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
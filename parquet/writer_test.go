@@ -0,0 +1,132 @@
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	columns := []Column{
+		{Name: "name", Type: String},
+		{Name: "id", Type: Int64},
+		{Name: "price", Type: Double},
+		{Name: "active", Type: Boolean},
+		{Name: "created_at", Type: Timestamp},
+	}
+
+	tests := []struct {
+		name    string
+		options Options
+		rows    int
+		groups  int // -1 - несколько групп
+	}{
+		{"gzip single group", Options{}, 100, 1},
+		{"uncompressed small pages", Options{Codec: CodecNone, PageSize: 64}, 100, 1},
+		{"many row groups", Options{RowGroupSize: 2048, PageSize: 256}, 1000, -1},
+		{"empty file", Options{}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, columns, tt.options)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			var want [][]interface{}
+			for i := 0; i < tt.rows; i++ {
+				at := created.Add(time.Duration(i) * time.Minute)
+				row := []interface{}{fmt.Sprintf("Товар %d", i), i, float64(i) / 4, i%3 == 0, at}
+				expected := []interface{}{row[0], int64(i), row[2], row[3], at}
+				// Значения колонок периодически пропускаются (NULL)
+				for c := range row {
+					if i%(c+5) == 0 {
+						row[c], expected[c] = nil, nil
+					}
+				}
+				want = append(want, expected)
+				if err := w.Write(row); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			file, err := Read(buf.Bytes())
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !reflect.DeepEqual(file.Columns, columns) {
+				t.Errorf("columns = %v, want %v", file.Columns, columns)
+			}
+			if tt.groups >= 0 && file.RowGroups != tt.groups {
+				t.Errorf("row groups = %d, want %d", file.RowGroups, tt.groups)
+			}
+			if tt.groups < 0 && file.RowGroups < 2 {
+				t.Errorf("row groups = %d, want several", file.RowGroups)
+			}
+			if len(file.Rows) != tt.rows {
+				t.Fatalf("rows = %d, want %d", len(file.Rows), tt.rows)
+			}
+			for i := range want {
+				if !reflect.DeepEqual(file.Rows[i], want[i]) {
+					t.Fatalf("row %d = %v, want %v", i, file.Rows[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriterValidation(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{{Name: "code", Type: String}, {Name: "created_at", Type: Timestamp}},
+		Options{Metadata: map[string]string{"source": "catalog_items"}})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		row     []interface{}
+		wantErr bool
+	}{
+		{"zero time is null", []interface{}{"A-1", time.Time{}}, false},
+		{"wrong type", []interface{}{1, nil}, true},
+		{"wrong width", []interface{}{"A-2"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := w.Write(tt.row); (err != nil) != tt.wantErr {
+				t.Errorf("Write() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := w.Write([]interface{}{"A-3", nil}); err != ErrClosed {
+		t.Errorf("Write() after Close error = %v, want ErrClosed", err)
+	}
+
+	file, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(file.Rows) != 1 || file.Rows[0][1] != nil || file.Metadata["source"] != "catalog_items" {
+		t.Errorf("file = %+v, want one row with NULL time and source metadata", file)
+	}
+
+	for _, columns := range [][]Column{nil, {{Name: "a"}, {Name: "a"}}, {{Name: ""}}, {{Name: "a", Type: Type(42)}}} {
+		if _, err := NewWriter(io.Discard, columns, Options{}); err == nil {
+			t.Errorf("NewWriter(%v) must fail", columns)
+		}
+	}
+	if _, err := Read([]byte("PAR1")); err == nil {
+		t.Error("Read() must reject truncated file")
+	}
+}
@@ -0,0 +1,504 @@
+package provisioning
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"httpserver/database"
+)
+
+// Действия плана провижининга
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionRotate    = "rotate" // Выпуск нового токена API ключа, у которого не осталось действующих токенов
+)
+
+// Change изменение одного объекта: что будет (план) или было (применение) сделано
+type Change struct {
+	Action string   `json:"action"`
+	Kind   string   `json:"kind"` // client, project, database, rule, classifier, api_key
+	Key    string   `json:"key"`
+	Fields []string `json:"fields,omitempty"` // Измененные поля: "name: old -> new"
+}
+
+// Plan план или результат применения файла провижининга
+type Plan struct {
+	Applied   bool              `json:"applied"`
+	Changes   []*Change         `json:"changes"`
+	Create    int               `json:"create"`
+	Update    int               `json:"update"`
+	Unchanged int               `json:"unchanged"`
+	Tokens    map[string]string `json:"tokens,omitempty"` // Токены созданных и перевыпущенных API ключей, показываются один раз
+}
+
+// HasChanges проверяет, что применение плана что-то изменит
+func (p *Plan) HasChanges() bool {
+	return p.Create+p.Update > 0
+}
+
+func (p *Plan) add(action, kind, key string, fields []string) {
+	p.Changes = append(p.Changes, &Change{Action: action, Kind: kind, Key: key, Fields: fields})
+	switch action {
+	case ActionCreate:
+		p.Create++
+	case ActionUpdate, ActionRotate:
+		p.Update++
+	default:
+		p.Unchanged++
+	}
+}
+
+// Provisioner применяет файл провижининга к сервисной БД; основная БД нужна для назначения классификаторов
+type Provisioner struct {
+	serviceDB *database.ServiceDB
+	db        *database.DB
+	actor     string
+}
+
+// NewProvisioner создает провижинер; actor записывается как автор созданных клиентов
+func NewProvisioner(serviceDB *database.ServiceDB, db *database.DB, actor string) *Provisioner {
+	if actor == "" {
+		actor = "provisioning"
+	}
+	return &Provisioner{serviceDB: serviceDB, db: db, actor: actor}
+}
+
+// Plan сравнивает файл с текущим состоянием без изменений
+func (p *Provisioner) Plan(spec *Spec) (*Plan, error) {
+	return p.run(spec, false)
+}
+
+// Apply приводит состояние к файлу. Повторное применение того же файла ничего не меняет,
+// поэтому после ошибки применение можно просто повторить
+func (p *Provisioner) Apply(spec *Spec) (*Plan, error) {
+	return p.run(spec, true)
+}
+
+// run обходит файл и для каждого объекта определяет действие; при apply выполняет его
+func (p *Provisioner) run(spec *Spec, apply bool) (*Plan, error) {
+	plan := &Plan{Applied: apply, Changes: []*Change{}}
+	if p.db == nil {
+		for _, client := range spec.Clients {
+			for _, project := range client.Projects {
+				if len(project.Classifiers) > 0 {
+					return nil, fmt.Errorf("classifier assignments require the main database")
+				}
+			}
+		}
+	}
+
+	for _, client := range spec.Clients {
+		if err := p.provisionClient(plan, client, apply); err != nil {
+			return plan, err
+		}
+	}
+	for _, key := range spec.APIKeys {
+		if err := p.provisionAPIKey(plan, key, apply); err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+// diffField добавляет поле в список изменений, если задано и отличается от текущего
+func diffField(fields *[]string, name, current, desired string) {
+	if desired != "" && desired != current {
+		*fields = append(*fields, fmt.Sprintf("%s: %q -> %q", name, current, desired))
+	}
+}
+
+// pick возвращает значение из файла, а если оно не задано - текущее
+func pick(current, desired string) string {
+	if desired == "" {
+		return current
+	}
+	return desired
+}
+
+func (p *Provisioner) provisionClient(plan *Plan, spec ClientSpec, apply bool) error {
+	key := spec.Name
+	existing, err := p.serviceDB.GetClientByName(spec.Name)
+	if err != nil {
+		return err
+	}
+
+	clientID := 0
+	if existing == nil {
+		plan.add(ActionCreate, "client", key, nil)
+		if apply {
+			client, err := p.serviceDB.CreateClient(spec.Name, spec.LegalName, spec.Description, spec.ContactEmail,
+				spec.ContactPhone, spec.TaxID, p.actor)
+			if err != nil {
+				return fmt.Errorf("client %q: %w", key, err)
+			}
+			clientID = client.ID
+			if spec.Language != "" {
+				if err := p.serviceDB.SetClientLanguage(clientID, spec.Language); err != nil {
+					return fmt.Errorf("client %q: %w", key, err)
+				}
+			}
+		}
+	} else {
+		clientID = existing.ID
+		language, err := p.serviceDB.GetClientLanguage(clientID)
+		if err != nil {
+			return err
+		}
+		var fields []string
+		diffField(&fields, "legal_name", existing.LegalName, spec.LegalName)
+		diffField(&fields, "description", existing.Description, spec.Description)
+		diffField(&fields, "contact_email", existing.ContactEmail, spec.ContactEmail)
+		diffField(&fields, "contact_phone", existing.ContactPhone, spec.ContactPhone)
+		diffField(&fields, "tax_id", existing.TaxID, spec.TaxID)
+		diffField(&fields, "language", language, spec.Language)
+		if len(fields) == 0 {
+			plan.add(ActionUnchanged, "client", key, nil)
+		} else {
+			plan.add(ActionUpdate, "client", key, fields)
+			if apply {
+				if err := p.serviceDB.UpdateClient(clientID, existing.Name, pick(existing.LegalName, spec.LegalName),
+					pick(existing.Description, spec.Description), pick(existing.ContactEmail, spec.ContactEmail),
+					pick(existing.ContactPhone, spec.ContactPhone), pick(existing.TaxID, spec.TaxID), existing.Status); err != nil {
+					return fmt.Errorf("client %q: %w", key, err)
+				}
+				if spec.Language != "" && spec.Language != language {
+					if err := p.serviceDB.SetClientLanguage(clientID, spec.Language); err != nil {
+						return fmt.Errorf("client %q: %w", key, err)
+					}
+				}
+			}
+		}
+	}
+
+	var projects []*database.ClientProject
+	if clientID > 0 {
+		if projects, err = p.serviceDB.GetClientProjects(clientID); err != nil {
+			return err
+		}
+	}
+	for _, project := range spec.Projects {
+		var existingProject *database.ClientProject
+		for _, candidate := range projects {
+			if candidate.Name == project.Name {
+				existingProject = candidate
+				break
+			}
+		}
+		if err := p.provisionProject(plan, clientID, key, existingProject, project, apply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Provisioner) provisionProject(plan *Plan, clientID int, clientKey string, existing *database.ClientProject, spec ProjectSpec, apply bool) error {
+	key := clientKey + "/" + spec.Name
+
+	projectID := 0
+	if existing == nil {
+		plan.add(ActionCreate, "project", key, nil)
+		if apply {
+			project, err := p.serviceDB.CreateClientProject(clientID, spec.Name, spec.Type, spec.Description,
+				spec.SourceSystem, spec.TargetQualityScore)
+			if err != nil {
+				return fmt.Errorf("project %q: %w", key, err)
+			}
+			projectID = project.ID
+		}
+	} else {
+		projectID = existing.ID
+		var fields []string
+		diffField(&fields, "type", existing.ProjectType, spec.Type)
+		diffField(&fields, "description", existing.Description, spec.Description)
+		diffField(&fields, "source_system", existing.SourceSystem, spec.SourceSystem)
+		score := existing.TargetQualityScore
+		if spec.TargetQualityScore > 0 && spec.TargetQualityScore != score {
+			fields = append(fields, fmt.Sprintf("target_quality_score: %g -> %g", score, spec.TargetQualityScore))
+			score = spec.TargetQualityScore
+		}
+		if len(fields) == 0 {
+			plan.add(ActionUnchanged, "project", key, nil)
+		} else {
+			plan.add(ActionUpdate, "project", key, fields)
+			if apply {
+				if err := p.serviceDB.UpdateClientProject(projectID, existing.Name, pick(existing.ProjectType, spec.Type),
+					pick(existing.Description, spec.Description), pick(existing.SourceSystem, spec.SourceSystem),
+					existing.Status, score); err != nil {
+					return fmt.Errorf("project %q: %w", key, err)
+				}
+			}
+		}
+	}
+
+	if err := p.provisionDatabases(plan, projectID, key, spec.Databases, apply); err != nil {
+		return err
+	}
+	if err := p.provisionRules(plan, projectID, key, spec.Rules, apply); err != nil {
+		return err
+	}
+	return p.provisionClassifiers(plan, clientID, projectID, key, spec.Classifiers, apply)
+}
+
+func (p *Provisioner) provisionDatabases(plan *Plan, projectID int, projectKey string, specs []DatabaseSpec, apply bool) error {
+	var existing []*database.ProjectDatabase
+	if projectID > 0 {
+		var err error
+		if existing, err = p.serviceDB.GetProjectDatabases(projectID, false); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range specs {
+		key := projectKey + "/" + spec.Name
+		var current *database.ProjectDatabase
+		for _, candidate := range existing {
+			if candidate.Name == spec.Name {
+				current = candidate
+				break
+			}
+		}
+
+		if current == nil {
+			plan.add(ActionCreate, "database", key, nil)
+			if !apply {
+				continue
+			}
+			var fileSize int64
+			if info, err := os.Stat(spec.Path); err == nil {
+				fileSize = info.Size()
+			}
+			created, err := p.serviceDB.CreateProjectDatabase(projectID, spec.Name, spec.Path, spec.Description, fileSize)
+			if err != nil {
+				return fmt.Errorf("database %q: %w", key, err)
+			}
+			if spec.Active != nil && !*spec.Active {
+				if err := p.serviceDB.UpdateProjectDatabase(created.ID, created.Name, created.FilePath, created.Description, false); err != nil {
+					return fmt.Errorf("database %q: %w", key, err)
+				}
+			}
+			continue
+		}
+
+		var fields []string
+		diffField(&fields, "path", current.FilePath, spec.Path)
+		diffField(&fields, "description", current.Description, spec.Description)
+		active := current.IsActive
+		if spec.Active != nil && *spec.Active != active {
+			fields = append(fields, fmt.Sprintf("active: %t -> %t", active, *spec.Active))
+			active = *spec.Active
+		}
+		if len(fields) == 0 {
+			plan.add(ActionUnchanged, "database", key, nil)
+			continue
+		}
+		plan.add(ActionUpdate, "database", key, fields)
+		if apply {
+			if err := p.serviceDB.UpdateProjectDatabase(current.ID, current.Name, pick(current.FilePath, spec.Path),
+				pick(current.Description, spec.Description), active); err != nil {
+				return fmt.Errorf("database %q: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Provisioner) provisionRules(plan *Plan, projectID int, projectKey string, specs []RuleSpec, apply bool) error {
+	var existing []*database.NormalizationDictionaryEntry
+	if projectID > 0 && len(specs) > 0 {
+		var err error
+		if existing, err = p.serviceDB.GetNormalizationDictionaryEntries(projectID, ""); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range specs {
+		term := strings.TrimSpace(spec.Term)
+		replacement := strings.TrimSpace(spec.Replacement)
+		key := projectKey + "/" + spec.Kind + "/" + term
+		var current *database.NormalizationDictionaryEntry
+		for _, candidate := range existing {
+			if candidate.Kind == spec.Kind && candidate.Term == term {
+				current = candidate
+				break
+			}
+		}
+
+		if current == nil {
+			plan.add(ActionCreate, "rule", key, nil)
+			if apply {
+				entry := &database.NormalizationDictionaryEntry{ProjectID: projectID, Kind: spec.Kind, Term: term, Replacement: replacement}
+				if err := p.serviceDB.CreateNormalizationDictionaryEntry(entry); err != nil {
+					return fmt.Errorf("rule %q: %w", key, err)
+				}
+			}
+			continue
+		}
+
+		if spec.Kind != database.DictionaryKindAbbreviation || current.Replacement == replacement {
+			plan.add(ActionUnchanged, "rule", key, nil)
+			continue
+		}
+		plan.add(ActionUpdate, "rule", key, []string{fmt.Sprintf("replacement: %q -> %q", current.Replacement, replacement)})
+		if apply {
+			current.Replacement = replacement
+			if err := p.serviceDB.UpdateNormalizationDictionaryEntry(current); err != nil {
+				return fmt.Errorf("rule %q: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// provisionClassifiers закрепляет классификаторы категорий за проектом. Классификатор ищется по наименованию:
+// уже назначенный проекту, иначе свободный. Классификатор другого проекта не переназначается
+func (p *Provisioner) provisionClassifiers(plan *Plan, clientID, projectID int, projectKey string, names []string, apply bool) error {
+	if len(names) == 0 {
+		return nil
+	}
+	classifiers, err := p.db.GetCategoryClassifiersByFilter(nil, nil, false)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := projectKey + "/" + name
+		var assigned, free *database.CategoryClassifier
+		found := false
+		for _, classifier := range classifiers {
+			if classifier.Name != name {
+				continue
+			}
+			found = true
+			switch {
+			case classifier.ProjectID != nil && projectID > 0 && *classifier.ProjectID == projectID:
+				assigned = classifier
+			case classifier.ProjectID == nil && free == nil:
+				free = classifier
+			}
+		}
+
+		switch {
+		case assigned != nil:
+			plan.add(ActionUnchanged, "classifier", key, nil)
+		case free != nil:
+			plan.add(ActionUpdate, "classifier", key, []string{fmt.Sprintf("project: none -> %q", projectKey)})
+			if apply {
+				if err := p.db.AssignCategoryClassifier(free.ID, &clientID, &projectID); err != nil {
+					return fmt.Errorf("classifier %q: %w", key, err)
+				}
+				free.ClientID, free.ProjectID = &clientID, &projectID
+			}
+		case found:
+			return fmt.Errorf("classifier %q is assigned to another project", name)
+		default:
+			return fmt.Errorf("classifier %q not found", name)
+		}
+	}
+	return nil
+}
+
+func (p *Provisioner) provisionAPIKey(plan *Plan, spec APIKeySpec, apply bool) error {
+	key := spec.Name
+	role := spec.Role
+	if role == "" {
+		role = database.RoleOperator
+	}
+
+	users, err := p.serviceDB.ListUsers()
+	if err != nil {
+		return err
+	}
+	// API ключ управляет только сервисной учетной записью: пользователь с тем же именем не перехватывается
+	var existing *database.User
+	for _, user := range users {
+		if user.Username != spec.Name {
+			continue
+		}
+		if user.AuthProvider != database.AuthProviderService {
+			return fmt.Errorf("api key %q: username is taken by a %s user", key, user.AuthProvider)
+		}
+		existing = user
+		break
+	}
+
+	if existing == nil {
+		plan.add(ActionCreate, "api_key", key, nil)
+		if !apply {
+			return nil
+		}
+		user, err := p.serviceDB.CreateServiceAccount(spec.Name, spec.DisplayName, role)
+		if err != nil {
+			return fmt.Errorf("api key %q: %w", key, err)
+		}
+		return p.issueAPIKeyToken(plan, spec, user.ID)
+	}
+
+	// Проверяется до изменения: разблокировка учетной записи не возвращает удаленные токены
+	live, err := p.serviceDB.HasActiveSession(existing.ID)
+	if err != nil {
+		return fmt.Errorf("api key %q: %w", key, err)
+	}
+	updated, err := p.updateAPIKey(plan, existing, role, apply)
+	if err != nil {
+		return fmt.Errorf("api key %q: %w", key, err)
+	}
+
+	// Токен показывается только при выпуске: если он истек или отозван, выпускается новый
+	if live && existing.IsActive {
+		if !updated {
+			plan.add(ActionUnchanged, "api_key", key, nil)
+		}
+		return nil
+	}
+	plan.add(ActionRotate, "api_key", key, []string{"token: expired -> new"})
+	if !apply {
+		return nil
+	}
+	return p.issueAPIKeyToken(plan, spec, existing.ID)
+}
+
+// issueAPIKeyToken выпускает токен сервисной учетной записи и добавляет его в план
+func (p *Provisioner) issueAPIKeyToken(plan *Plan, spec APIKeySpec, userID int) error {
+	ttl, _ := spec.ttl()
+	token, _, err := p.serviceDB.CreateUserSession(userID, ttl, "", "provisioning")
+	if err != nil {
+		return fmt.Errorf("api key %q: %w", spec.Name, err)
+	}
+	if plan.Tokens == nil {
+		plan.Tokens = map[string]string{}
+	}
+	plan.Tokens[spec.Name] = token
+	return nil
+}
+
+// updateAPIKey приводит роль и активность сервисной учетной записи к файлу; false - изменений нет
+func (p *Provisioner) updateAPIKey(plan *Plan, existing *database.User, role string, apply bool) (bool, error) {
+	var fields []string
+	if existing.Role != role {
+		fields = append(fields, fmt.Sprintf("role: %s -> %s", existing.Role, role))
+	}
+	if !existing.IsActive {
+		fields = append(fields, "active: false -> true")
+	}
+	if len(fields) == 0 {
+		return false, nil
+	}
+	plan.add(ActionUpdate, "api_key", existing.Username, fields)
+	if apply {
+		if existing.Role != role {
+			if err := p.serviceDB.SetUserRole(existing.ID, role); err != nil {
+				return true, err
+			}
+		}
+		if !existing.IsActive {
+			if err := p.serviceDB.SetUserActive(existing.ID, true); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
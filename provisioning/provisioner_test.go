@@ -0,0 +1,215 @@
+package provisioning
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+const testSpec = `
+clients:
+  - name: Acme
+    legal_name: Acme LLC
+    language: kk
+    projects:
+      - name: Nomenclature
+        type: normalization
+        source_system: 1C
+        target_quality_score: 0.9
+        databases:
+          - name: main
+            path: /data/acme.db
+        rules:
+          - kind: abbreviation
+            term: эл.
+            replacement: электрический
+          - kind: stop_word
+            term: прочее
+        classifiers: [Acme categories]
+api_keys:
+  - name: acme-1c
+    ttl: 720h
+`
+
+func TestProvisionerPlanAndApply(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer serviceDB.Close()
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "data.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	classifier, err := db.CreateCategoryClassifier(&database.CategoryClassifier{Name: "Acme categories", MaxDepth: 3, TreeStructure: "{}", IsActive: true})
+	if err != nil {
+		t.Fatalf("CreateCategoryClassifier() error = %v", err)
+	}
+
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("ParseSpec() error = %v", err)
+	}
+	p := NewProvisioner(serviceDB, db, "test")
+
+	plan, err := p.Plan(spec)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if plan.Create != 6 || plan.Update != 1 || plan.Applied {
+		t.Fatalf("plan = %+v, want 6 creates and classifier assignment", plan)
+	}
+	if client, _ := serviceDB.GetClientByName("Acme"); client != nil {
+		t.Fatal("Plan() must not create clients")
+	}
+
+	applied, err := p.Apply(spec)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if applied.Create != 6 || len(applied.Tokens) != 1 || applied.Tokens["acme-1c"] == "" {
+		t.Fatalf("applied = %+v", applied)
+	}
+	session, err := serviceDB.GetUserSession(applied.Tokens["acme-1c"])
+	if err != nil || session == nil || session.User.Role != database.RoleOperator || session.User.AuthProvider != database.AuthProviderService {
+		t.Errorf("api key session = %+v, err = %v", session, err)
+	}
+	client, _ := serviceDB.GetClientByName("Acme")
+	if lang, _ := serviceDB.GetClientLanguage(client.ID); lang != "kk" {
+		t.Errorf("client language = %q, want kk", lang)
+	}
+	assigned, _ := db.GetCategoryClassifier(classifier.ID)
+	if assigned.ProjectID == nil || assigned.ClientID == nil || *assigned.ClientID != client.ID {
+		t.Errorf("classifier assignment = %+v", assigned)
+	}
+
+	// Повторное применение ничего не меняет
+	again, err := p.Plan(spec)
+	if err != nil {
+		t.Fatalf("Plan() after apply error = %v", err)
+	}
+	if again.HasChanges() || again.Unchanged != 7 {
+		t.Errorf("plan after apply = %+v, want everything unchanged", again)
+	}
+
+	changes := []struct {
+		name       string
+		spec       string
+		wantKey    string
+		wantFields string
+	}{
+		{"project score", strings.Replace(testSpec, "0.9", "0.95", 1), "Acme/Nomenclature", "target_quality_score: 0.9 -> 0.95"},
+		{"database path", strings.Replace(testSpec, "/data/acme.db", "/data/acme2.db", 1), "Acme/Nomenclature/main", `path: "/data/acme.db" -> "/data/acme2.db"`},
+		{"rule replacement", strings.Replace(testSpec, "электрический", "электро", 1), "Acme/Nomenclature/abbreviation/эл.", "replacement"},
+		{"api key role", testSpec + "    role: viewer\n", "acme-1c", "role: operator -> viewer"},
+	}
+	for _, tt := range changes {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSpec([]byte(tt.spec))
+			if err != nil {
+				t.Fatalf("ParseSpec() error = %v", err)
+			}
+			plan, err := p.Plan(spec)
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+			if plan.Update != 1 || plan.Create != 0 {
+				t.Fatalf("plan = %d creates, %d updates, want one update", plan.Create, plan.Update)
+			}
+			for _, change := range plan.Changes {
+				if change.Action == ActionUpdate && (change.Key != tt.wantKey || !strings.Contains(strings.Join(change.Fields, ";"), tt.wantFields)) {
+					t.Errorf("update = %+v, want %s with %s", change, tt.wantKey, tt.wantFields)
+				}
+			}
+		})
+	}
+}
+
+func TestProvisionAPIKeyRotate(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer serviceDB.Close()
+	spec, err := ParseSpec([]byte("api_keys:\n  - name: acme-1c\n"))
+	if err != nil {
+		t.Fatalf("ParseSpec() error = %v", err)
+	}
+	p := NewProvisioner(serviceDB, nil, "test")
+	created, err := p.Apply(spec)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// Отозванный токен перевыпускается при следующем применении
+	serviceDB.DeleteUserSession(created.Tokens["acme-1c"])
+	plan, err := p.Plan(spec)
+	if err != nil || plan.Update != 1 || plan.Changes[0].Action != ActionRotate || len(plan.Tokens) != 0 {
+		t.Fatalf("Plan() = %+v, %v; want rotate without token", plan, err)
+	}
+	rotated, err := p.Apply(spec)
+	if err != nil || rotated.Tokens["acme-1c"] == "" {
+		t.Fatalf("Apply() = %+v, %v; want new token", rotated, err)
+	}
+	if session, _ := serviceDB.GetUserSession(rotated.Tokens["acme-1c"]); session == nil {
+		t.Error("rotated token is not a live session")
+	}
+	if again, _ := p.Plan(spec); again.HasChanges() {
+		t.Errorf("plan after rotate = %+v, want unchanged", again)
+	}
+}
+
+func TestParseSpecValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"unknown field", "clients:\n  - name: A\n    colour: red\n", "unknown field"},
+		{"project without type", "clients:\n  - name: A\n    projects:\n      - name: P\n", "name and type are required"},
+		{"abbreviation without replacement", "clients:\n  - name: A\n    projects:\n      - name: P\n        type: normalization\n        rules:\n          - kind: abbreviation\n            term: x\n", "requires replacement"},
+		{"invalid role", "api_keys:\n  - name: k\n    role: root\n", "invalid role"},
+		{"invalid ttl", "api_keys:\n  - name: k\n    ttl: forever\n", "invalid ttl"},
+		{"json", `{"clients": [{"name": "A"}]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSpec([]byte(tt.input))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseSpec() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSpec() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvisionAPIKeyOwnUsersOnly(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create ServiceDB: %v", err)
+	}
+	defer serviceDB.Close()
+	if _, err := serviceDB.CreateUser("ivanov", "s3cret-pass", "", "", database.RoleAdmin); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	spec, err := ParseSpec([]byte("api_keys:\n  - name: ivanov\n    role: viewer\n"))
+	if err != nil {
+		t.Fatalf("ParseSpec() error = %v", err)
+	}
+	// Пользователь веб-интерфейса с тем же именем не становится сервисной учетной записью
+	if _, err := NewProvisioner(serviceDB, nil, "test").Apply(spec); err == nil || !strings.Contains(err.Error(), "username is taken") {
+		t.Fatalf("Apply() error = %v, want username taken", err)
+	}
+	if user, _ := serviceDB.GetUser(1); user.Role != database.RoleAdmin {
+		t.Errorf("user role = %s, want admin unchanged", user.Role)
+	}
+}
@@ -0,0 +1,209 @@
+package provisioning
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/i18n"
+)
+
+// DefaultAPIKeyTTL срок действия токена сервисной учетной записи по умолчанию
+const DefaultAPIKeyTTL = 365 * 24 * time.Hour
+
+// Spec декларативное описание клиентов, проектов и доступа к API.
+// Применение только добавляет и обновляет: объекты, отсутствующие в файле, не удаляются
+type Spec struct {
+	Clients []ClientSpec `json:"clients"`
+	APIKeys []APIKeySpec `json:"api_keys"`
+}
+
+// ClientSpec клиент; ключ - наименование. Пустые поля не изменяют сохраненные значения
+type ClientSpec struct {
+	Name         string        `json:"name"`
+	LegalName    string        `json:"legal_name"`
+	Description  string        `json:"description"`
+	ContactEmail string        `json:"contact_email"`
+	ContactPhone string        `json:"contact_phone"`
+	TaxID        string        `json:"tax_id"`
+	Language     string        `json:"language"`
+	Projects     []ProjectSpec `json:"projects"`
+}
+
+// ProjectSpec проект клиента; ключ - наименование в пределах клиента
+type ProjectSpec struct {
+	Name               string         `json:"name"`
+	Type               string         `json:"type"`
+	Description        string         `json:"description"`
+	SourceSystem       string         `json:"source_system"`
+	TargetQualityScore float64        `json:"target_quality_score"`
+	Databases          []DatabaseSpec `json:"databases"`
+	Rules              []RuleSpec     `json:"rules"`
+	Classifiers        []string       `json:"classifiers"` // Наименования классификаторов категорий
+}
+
+// DatabaseSpec база данных проекта; ключ - наименование в пределах проекта
+type DatabaseSpec struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	Active      *bool  `json:"active"`
+}
+
+// RuleSpec правило словаря нормализации проекта (сокращение, стоп-слово, защищенный токен)
+type RuleSpec struct {
+	Kind        string `json:"kind"`
+	Term        string `json:"term"`
+	Replacement string `json:"replacement"`
+}
+
+// APIKeySpec сервисная учетная запись с bearer токеном для интеграций (обмен с 1С, скрипты)
+type APIKeySpec struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Role        string `json:"role"`
+	TTL         string `json:"ttl"` // Срок действия токена, например 8760h
+}
+
+// ParseSpec разбирает файл провижининга в YAML или JSON и проверяет его
+func ParseSpec(data []byte) (*Spec, error) {
+	trimmed := bytes.TrimSpace(data)
+	var raw interface{}
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	} else {
+		var err error
+		if raw, err = parseYAML(trimmed); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+
+	// Структура проверяется через JSON: неизвестные поля - опечатки в файле
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provisioning file: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	spec := &Spec{}
+	if err := decoder.Decode(spec); err != nil {
+		return nil, fmt.Errorf("invalid provisioning file: %w", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Validate проверяет обязательные поля и уникальность ключей; возвращает все найденные ошибки
+func (spec *Spec) Validate() error {
+	var issues []string
+	addf := func(format string, args ...interface{}) {
+		issues = append(issues, fmt.Sprintf(format, args...))
+	}
+
+	clients := map[string]bool{}
+	for i := range spec.Clients {
+		client := &spec.Clients[i]
+		client.Name = strings.TrimSpace(client.Name)
+		if client.Name == "" {
+			addf("clients[%d]: name is required", i)
+			continue
+		}
+		if clients[client.Name] {
+			addf("client %q: duplicate name", client.Name)
+		}
+		clients[client.Name] = true
+		if client.Language != "" {
+			lang := i18n.Normalize(client.Language)
+			if lang == "" {
+				addf("client %q: unsupported language %s", client.Name, client.Language)
+			}
+			client.Language = lang
+		}
+
+		projects := map[string]bool{}
+		for j := range client.Projects {
+			project := &client.Projects[j]
+			project.Name = strings.TrimSpace(project.Name)
+			key := client.Name + "/" + project.Name
+			if project.Name == "" || project.Type == "" {
+				addf("client %q projects[%d]: name and type are required", client.Name, j)
+				continue
+			}
+			if projects[project.Name] {
+				addf("project %q: duplicate name", key)
+			}
+			projects[project.Name] = true
+			if project.TargetQualityScore < 0 || project.TargetQualityScore > 1 {
+				addf("project %q: target_quality_score must be between 0 and 1", key)
+			}
+
+			databases := map[string]bool{}
+			for k, db := range project.Databases {
+				if strings.TrimSpace(db.Name) == "" || strings.TrimSpace(db.Path) == "" {
+					addf("project %q databases[%d]: name and path are required", key, k)
+					continue
+				}
+				if databases[db.Name] {
+					addf("database %q: duplicate name", key+"/"+db.Name)
+				}
+				databases[db.Name] = true
+			}
+			for k, rule := range project.Rules {
+				if !database.IsValidDictionaryKind(rule.Kind) {
+					addf("project %q rules[%d]: invalid kind %q", key, k, rule.Kind)
+				} else if strings.TrimSpace(rule.Term) == "" {
+					addf("project %q rules[%d]: term is required", key, k)
+				} else if rule.Kind == database.DictionaryKindAbbreviation && strings.TrimSpace(rule.Replacement) == "" {
+					addf("project %q rules[%d]: abbreviation %q requires replacement", key, k, rule.Term)
+				}
+			}
+			for k, name := range project.Classifiers {
+				if strings.TrimSpace(name) == "" {
+					addf("project %q classifiers[%d]: name is required", key, k)
+				}
+			}
+		}
+	}
+
+	keys := map[string]bool{}
+	for i, key := range spec.APIKeys {
+		if strings.TrimSpace(key.Name) == "" {
+			addf("api_keys[%d]: name is required", i)
+			continue
+		}
+		if keys[key.Name] {
+			addf("api key %q: duplicate name", key.Name)
+		}
+		keys[key.Name] = true
+		if key.Role != "" && !database.ValidRole(key.Role) {
+			addf("api key %q: invalid role %q", key.Name, key.Role)
+		}
+		if _, err := key.ttl(); err != nil {
+			addf("api key %q: %v", key.Name, err)
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("invalid provisioning file: %s", strings.Join(issues, "; "))
+	}
+	return nil
+}
+
+// ttl возвращает срок действия токена; пустое значение - DefaultAPIKeyTTL
+func (key APIKeySpec) ttl() (time.Duration, error) {
+	if key.TTL == "" {
+		return DefaultAPIKeyTTL, nil
+	}
+	ttl, err := time.ParseDuration(key.TTL)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl %q", key.TTL)
+	}
+	return ttl, nil
+}
@@ -0,0 +1,314 @@
+package provisioning
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine значимая строка YAML: отступ и текст без комментария
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// parseYAML разбирает подмножество YAML, достаточное для файла провижининга:
+// блочные словари и списки, однострочные скаляры в кавычках и без, строчные списки [a, b].
+// Якоря, теги и многострочные скаляры (| и >) не поддерживаются - о них сообщается ошибкой
+func parseYAML(data []byte) (interface{}, error) {
+	lines, err := yamlLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	p := &yamlParser{lines: lines}
+	value, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return value, nil
+}
+
+// yamlLines выделяет значимые строки: без пустых, комментариев и маркеров документа
+func yamlLines(text string) ([]*yamlLine, error) {
+	var lines []*yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		content := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		content = strings.TrimSpace(stripYAMLComment(content))
+		if content == "" || content == "---" || content == "..." {
+			continue
+		}
+		lines = append(lines, &yamlLine{number: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: content})
+	}
+	return lines, nil
+}
+
+// stripYAMLComment убирает комментарий: # в начале строки или после пробела, вне кавычек
+func stripYAMLComment(text string) string {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && opensYAMLQuote(text, i):
+			quote = r
+		case r == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// opensYAMLQuote проверяет, что кавычка в позиции i начинает значение, а не стоит внутри слова (O'Brien)
+func opensYAMLQuote(text string, i int) bool {
+	return i == 0 || strings.ContainsRune(" :[,-", rune(text[i-1]))
+}
+
+// yamlParser рекурсивный разбор строк с учетом отступов
+type yamlParser struct {
+	lines []*yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos < len(p.lines) {
+		line = p.lines[p.pos].number
+	} else if len(p.lines) > 0 {
+		line = p.lines[len(p.lines)-1].number
+	}
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// isSequenceItem проверяет, что строка - элемент блочного списка
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// parseBlock разбирает словарь или список, начинающийся с текущей строки с отступом indent
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isSequenceItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+// parseNested разбирает значение ключа или элемента, записанное со следующей строки.
+// Список может начинаться на том же отступе, что и ключ словаря (allowSameIndentSequence)
+func (p *yamlParser) parseNested(indent int, allowSameIndentSequence bool) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	switch {
+	case next.indent > indent:
+		return p.parseBlock(next.indent)
+	case next.indent == indent && allowSameIndentSequence && isSequenceItem(next.text):
+		return p.parseSequence(indent)
+	}
+	return nil, nil
+}
+
+// parseMapping разбирает блочный словарь
+func (p *yamlParser) parseMapping(indent int) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isSequenceItem(line.text) {
+			return nil, p.errorf("unexpected list item in mapping")
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, p.errorf("expected \"key: value\"")
+		}
+		if _, exists := result[key]; exists {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+
+		var value interface{}
+		var err error
+		if rest == "" {
+			value, err = p.parseNested(indent, true)
+		} else {
+			value, err = parseYAMLValue(rest)
+			if err != nil {
+				err = fmt.Errorf("line %d: %w", line.number, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return result, nil
+}
+
+// parseSequence разбирает блочный список; "- key: value" начинает словарь с отступом после "- "
+func (p *yamlParser) parseSequence(indent int) ([]interface{}, error) {
+	result := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+
+		if rest == "" {
+			p.pos++
+			value, err := p.parseNested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+		if _, _, ok := splitYAMLKey(rest); ok && !strings.HasPrefix(rest, "[") && !strings.HasPrefix(rest, "{") {
+			// Словарь внутри элемента: первая пара на строке "-", остальные - с отступом ее ключа
+			itemIndent := indent + len(line.text) - len(rest)
+			p.lines[p.pos] = &yamlLine{number: line.number, indent: itemIndent, text: rest}
+			value, err := p.parseMapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		value, err := parseYAMLValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.number, err)
+		}
+		result = append(result, value)
+		p.pos++
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return result, nil
+}
+
+// splitYAMLKey делит "key: value" по первому двоеточию вне кавычек, за которым следует пробел или конец строки
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && i == 0:
+			quote = r
+		case r == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			key = strings.TrimSpace(text[:i])
+			if unquoted, err := unquoteYAML(key); err == nil {
+				key = unquoted
+			}
+			return key, strings.TrimSpace(text[i+1:]), key != ""
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLValue разбирает однострочное значение: строчный список или скаляр
+func parseYAMLValue(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		return nil, fmt.Errorf("multi-line scalars are not supported")
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") || strings.HasPrefix(text, "!"):
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported")
+	case text == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(text, "{"):
+		return nil, fmt.Errorf("flow mappings are not supported, use block mappings")
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		items := []interface{}{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, item := range splitFlowItems(inner) {
+			value, err := parseYAMLScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	}
+	return parseYAMLScalar(text)
+}
+
+// splitFlowItems делит содержимое [a, "b, c"] по запятым вне кавычек
+func splitFlowItems(text string) []string {
+	var items []string
+	var quote rune
+	start := 0
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && opensYAMLQuote(text, i):
+			quote = r
+		case r == ',':
+			items = append(items, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, text[start:])
+}
+
+// parseYAMLScalar разбирает скаляр: строку в кавычках, null, bool, число или строку без кавычек
+func parseYAMLScalar(text string) (interface{}, error) {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		return unquoteYAML(text)
+	}
+	switch strings.ToLower(text) {
+	case "", "~", "null":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
+
+// unquoteYAML снимает кавычки: "..." с экранированием как в Go, '...' с удвоенной кавычкой
+func unquoteYAML(text string) (string, error) {
+	switch {
+	case len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"':
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted string %s", text)
+		}
+		return value, nil
+	case len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'':
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'"):
+		return "", fmt.Errorf("unterminated quoted string %s", text)
+	}
+	return text, nil
+}
@@ -0,0 +1,74 @@
+package provisioning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "nested mappings and sequences",
+			input: `
+# клиенты
+clients:
+  - name: "ООО Ромашка"   # комментарий
+    tax_id: '7701'
+    projects:
+    - name: Nomenclature
+      target_quality_score: 0.9
+      classifiers: [KPVED custom, "Склад, материалы"]
+      rules:
+        - kind: abbreviation
+          term: эл.
+          replacement: O'Brien # апостроф внутри слова
+api_keys: []
+`,
+			want: map[string]interface{}{
+				"clients": []interface{}{
+					map[string]interface{}{
+						"name":   "ООО Ромашка",
+						"tax_id": "7701",
+						"projects": []interface{}{
+							map[string]interface{}{
+								"name":                 "Nomenclature",
+								"target_quality_score": 0.9,
+								"classifiers":          []interface{}{"KPVED custom", "Склад, материалы"},
+								"rules": []interface{}{
+									map[string]interface{}{"kind": "abbreviation", "term": "эл.", "replacement": "O'Brien"},
+								},
+							},
+						},
+					},
+				},
+				"api_keys": []interface{}{},
+			},
+		},
+		{
+			name:  "scalars",
+			input: "a: true\nb: 12\nc: ~\nd: http://host:8080/x\ne: \"line\\n\"\n",
+			want:  map[string]interface{}{"a": true, "b": int64(12), "c": nil, "d": "http://host:8080/x", "e": "line\n"},
+		},
+		{name: "multi-line scalar", input: "description: |\n  text\n", wantErr: true},
+		{name: "anchor", input: "a: &x 1\n", wantErr: true},
+		{name: "duplicate key", input: "a: 1\na: 2\n", wantErr: true},
+		{name: "tab indentation", input: "a:\n\tb: 1\n", wantErr: true},
+		{name: "bad indentation", input: "a: 1\n   b: 2\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,7 +9,8 @@ import (
 
 // QualityAnalyzer основной анализатор качества данных
 type QualityAnalyzer struct {
-	db *database.DB
+	db         *database.DB
+	similarity *database.SimilarityConfig // Настройка сходства проекта для поиска нечетких дубликатов
 }
 
 // NewQualityAnalyzer создает новый анализатор качества
@@ -17,6 +18,11 @@ func NewQualityAnalyzer(db *database.DB) *QualityAnalyzer {
 	return &QualityAnalyzer{db: db}
 }
 
+// WithSimilarityConfig возвращает анализатор, ищущий нечеткие дубликаты по настройке сходства проекта
+func (qa *QualityAnalyzer) WithSimilarityConfig(config database.SimilarityConfig) *QualityAnalyzer {
+	return &QualityAnalyzer{db: qa.db, similarity: &config}
+}
+
 // AnalyzeUpload запускает полный анализ качества для выгрузки
 func (qa *QualityAnalyzer) AnalyzeUpload(uploadID int, databaseID int) error {
 	log.Printf("Starting quality analysis for upload %d, database %d", uploadID, databaseID)
@@ -33,6 +39,11 @@ func (qa *QualityAnalyzer) AnalyzeUpload(uploadID int, databaseID int) error {
 		// Продолжаем анализ
 	}
 
+	// Ссылочная целостность реквизитов справочников
+	if err := qa.analyzeReferenceIntegrity(uploadID, databaseID); err != nil {
+		log.Printf("Error analyzing reference integrity: %v", err)
+	}
+
 	// Поиск нечетких дубликатов по наименованию
 	if err := qa.findFuzzyDuplicates(uploadID, databaseID); err != nil {
 		log.Printf("Error finding fuzzy duplicates: %v", err)
@@ -54,7 +65,15 @@ func (qa *QualityAnalyzer) AnalyzeUpload(uploadID int, databaseID int) error {
 
 // findFuzzyDuplicates находит нечеткие дубликаты по наименованию
 func (qa *QualityAnalyzer) findFuzzyDuplicates(uploadID int, databaseID int) error {
-	fuzzyMatcher := NewFuzzyMatcher(qa.db, 0.85)
+	fuzzyMatcher := NewFuzzyMatcher(qa.db, DefaultFuzzyThreshold)
+	if qa.similarity != nil {
+		configured, err := NewFuzzyMatcherWithConfig(qa.db, *qa.similarity)
+		if err != nil {
+			log.Printf("Invalid similarity config of project %d, using default: %v", qa.similarity.ProjectID, err)
+		} else {
+			fuzzyMatcher = configured
+		}
+	}
 	groups, err := fuzzyMatcher.FindDuplicateNames(uploadID, databaseID)
 	if err != nil {
 		return fmt.Errorf("failed to find fuzzy duplicates: %w", err)
@@ -0,0 +1,114 @@
+package quality
+
+import (
+	"sort"
+
+	"httpserver/database"
+)
+
+// DuplicateGraphNode элемент кластера дублей
+type DuplicateGraphNode struct {
+	ID       string `json:"id"` // Ссылка элемента
+	Name     string `json:"name"`
+	Category string `json:"category,omitempty"`
+	Cluster  string `json:"cluster"`
+	Degree   int    `json:"degree"`
+}
+
+// DuplicateGraphEdge связь похожих элементов с весом, равным сходству
+type DuplicateGraphEdge struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Weight float64 `json:"weight"`
+}
+
+// DuplicateGraph страница кластеров дублей в виде графа
+type DuplicateGraph struct {
+	Nodes            []DuplicateGraphNode `json:"nodes"`
+	Edges            []DuplicateGraphEdge `json:"edges"`
+	TotalClusters    int                  `json:"total_clusters"`
+	ClustersReturned int                  `json:"clusters_returned"`
+	Truncated        bool                 `json:"truncated"` // Страница обрезана по лимиту узлов
+}
+
+// duplicateCluster связи одного кластера и число его элементов
+type duplicateCluster struct {
+	key   string
+	edges []database.DuplicateEdge
+	size  int
+}
+
+// BuildDuplicateGraph строит граф из сохраненных связей дублей без пересчета сходства.
+// Кластеры упорядочены по размеру, страница задается offset/limit по кластерам,
+// maxNodes ограничивает число узлов в ответе
+func BuildDuplicateGraph(edges []database.DuplicateEdge, offset, limit, maxNodes int) *DuplicateGraph {
+	clusters := []*duplicateCluster{}
+	byKey := make(map[string]*duplicateCluster)
+	for _, edge := range edges {
+		cluster, ok := byKey[edge.ClusterKey]
+		if !ok {
+			cluster = &duplicateCluster{key: edge.ClusterKey, size: 1}
+			byKey[edge.ClusterKey] = cluster
+			clusters = append(clusters, cluster)
+		}
+		cluster.edges = append(cluster.edges, edge)
+		cluster.size++
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if clusters[i].size != clusters[j].size {
+			return clusters[i].size > clusters[j].size
+		}
+		return clusters[i].key < clusters[j].key
+	})
+
+	graph := &DuplicateGraph{
+		Nodes:         []DuplicateGraphNode{},
+		Edges:         []DuplicateGraphEdge{},
+		TotalClusters: len(clusters),
+	}
+	if offset >= len(clusters) {
+		return graph
+	}
+	page := clusters[offset:]
+	if limit > 0 && len(page) > limit {
+		page = page[:limit]
+	}
+
+	nodeIndex := make(map[string]int)
+	addNode := func(id, name, category, cluster string) {
+		if i, ok := nodeIndex[id]; ok {
+			graph.Nodes[i].Degree++
+			return
+		}
+		nodeIndex[id] = len(graph.Nodes)
+		graph.Nodes = append(graph.Nodes, DuplicateGraphNode{ID: id, Name: name, Category: category, Cluster: cluster, Degree: 1})
+	}
+
+	for _, cluster := range page {
+		for _, edge := range cluster.edges {
+			newNodes := 0
+			for _, id := range []string{edge.SourceReference, edge.TargetReference} {
+				if _, ok := nodeIndex[id]; !ok {
+					newNodes++
+				}
+			}
+			if maxNodes > 0 && len(graph.Nodes)+newNodes > maxNodes {
+				graph.Truncated = true
+				// Кластер больше лимита отдается частично, чтобы постраничный обход не останавливался на нем
+				if graph.ClustersReturned == 0 {
+					graph.ClustersReturned = 1
+				}
+				return graph
+			}
+			addNode(edge.SourceReference, edge.SourceName, edge.SourceCategory, cluster.key)
+			addNode(edge.TargetReference, edge.TargetName, edge.TargetCategory, cluster.key)
+			graph.Edges = append(graph.Edges, DuplicateGraphEdge{
+				Source: edge.SourceReference,
+				Target: edge.TargetReference,
+				Weight: edge.Similarity,
+			})
+		}
+		graph.ClustersReturned++
+	}
+	return graph
+}
@@ -0,0 +1,97 @@
+package quality
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestBuildDuplicateGraph(t *testing.T) {
+	edges := DuplicateEdges([]DuplicateGroup{
+		{
+			Items:        []DuplicateItem{{Reference: "a1", Name: "Болт М8"}, {Reference: "a2", Name: "Болт М8."}, {Reference: "a3", Name: "болт м8"}},
+			Similarity:   0.9,
+			Similarities: []float64{1, 0.9, 1},
+		},
+		{
+			Items:        []DuplicateItem{{Reference: "b1", Name: "Гайка"}, {Reference: "b2", Name: "Гайка."}},
+			Similarity:   0.85,
+			Similarities: []float64{1, 0.85},
+		},
+	})
+	if len(edges) != 3 || edges[0].ClusterKey != "a1" || edges[0].Similarity != 0.9 {
+		t.Fatalf("DuplicateEdges() = %+v", edges)
+	}
+
+	tests := []struct {
+		name          string
+		offset        int
+		limit         int
+		maxNodes      int
+		wantNodes     int
+		wantEdges     int
+		wantClusters  int
+		wantTruncated bool
+	}{
+		{"all clusters", 0, 10, 100, 5, 3, 2, false},
+		{"largest cluster first page", 0, 1, 100, 3, 2, 1, false},
+		{"second page", 1, 1, 100, 2, 1, 1, false},
+		{"offset past end", 5, 10, 100, 0, 0, 0, false},
+		{"node limit stops before next cluster", 0, 10, 4, 3, 2, 1, true},
+		{"oversized cluster returned partially", 0, 10, 2, 2, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := BuildDuplicateGraph(edges, tt.offset, tt.limit, tt.maxNodes)
+			if len(graph.Nodes) != tt.wantNodes || len(graph.Edges) != tt.wantEdges ||
+				graph.ClustersReturned != tt.wantClusters || graph.Truncated != tt.wantTruncated || graph.TotalClusters != 2 {
+				t.Errorf("BuildDuplicateGraph() = %d nodes, %d edges, %d/%d clusters, truncated %v",
+					len(graph.Nodes), len(graph.Edges), graph.ClustersReturned, graph.TotalClusters, graph.Truncated)
+			}
+		})
+	}
+
+	graph := BuildDuplicateGraph(edges, 0, 1, 100)
+	if graph.Nodes[0].ID != "a1" || graph.Nodes[0].Degree != 2 || graph.Edges[0].Weight != 0.9 {
+		t.Errorf("first cluster = %+v %+v, want anchor a1 with degree 2", graph.Nodes, graph.Edges)
+	}
+}
+
+func TestFindDuplicateNamesStoresEdges(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO uploads (upload_uuid) VALUES ('u-1')`); err != nil {
+		t.Fatalf("insert upload: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO nomenclature_items (upload_id, nomenclature_reference, nomenclature_name)
+		VALUES (1, 'r1', 'Молоток слесарный 500г'), (1, 'r2', 'Молоток слесарный 500г.'), (1, 'r3', 'Кабель ВВГнг 3х2,5')`); err != nil {
+		t.Fatalf("insert nomenclature: %v", err)
+	}
+
+	groups, err := NewFuzzyMatcher(db, 0.9).FindDuplicateNames(1, 7)
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("FindDuplicateNames() = %d groups, error = %v", len(groups), err)
+	}
+
+	edges, err := db.GetDuplicateEdges(database.DuplicateEdgeFilter{UploadID: 1})
+	if err != nil {
+		t.Fatalf("GetDuplicateEdges() error = %v", err)
+	}
+	if len(edges) != 1 || edges[0].Similarity != groups[0].Similarities[1] {
+		t.Errorf("stored edges = %+v, want one edge with similarity from matcher", edges)
+	}
+
+	// Категория, которой нет ни у одного элемента, отфильтровывает все кластеры
+	if edges, _ := db.GetDuplicateEdges(database.DuplicateEdgeFilter{UploadID: 1, Category: "Крепеж"}); len(edges) != 0 {
+		t.Errorf("edges for missing category = %+v", edges)
+	}
+}
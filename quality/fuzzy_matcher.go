@@ -7,20 +7,34 @@ import (
 	"time"
 
 	"httpserver/database"
+	"httpserver/normalization"
 )
 
 // DuplicateGroup группа потенциальных дубликатов
 type DuplicateGroup struct {
-	Items      []DuplicateItem
-	Similarity float64
+	Items        []DuplicateItem
+	Similarity   float64
+	Similarities []float64 // Сходство каждого элемента с первым (опорным) элементом группы
 }
 
 // DuplicateItem элемент в группе дубликатов
 type DuplicateItem struct {
 	Reference string
 	Name      string
+	Category  string
+
+	// Подготовленные для сравнения наименование и ключ размеров, см. prepareMatchItem
+	matchName    string
+	dimensionKey string
 }
 
+// DefaultFuzzyThreshold порог сходства наименований по умолчанию
+const DefaultFuzzyThreshold = 0.85
+
+// DimensionMatchBoost прибавка к сходству наименований с одинаковыми размерами:
+// совпадение размеров - сильный признак дубликата
+const DimensionMatchBoost = 0.1
+
 // FuzzyMatcher нечеткий сопоставитель для поиска дубликатов
 type FuzzyMatcher struct {
 	db                *database.DB
@@ -28,19 +42,37 @@ type FuzzyMatcher struct {
 	maxComparisons    int // Максимальное количество сравнений на элемент
 	batchSize         int // Размер батча для обработки
 	prefixLength      int // Длина префикса для предварительной фильтрации
+	scorer            *SimilarityScorer
 }
 
 // NewFuzzyMatcher создает новый нечеткий сопоставитель
 func NewFuzzyMatcher(db *database.DB, threshold float64) *FuzzyMatcher {
 	if threshold <= 0 {
-		threshold = 0.85 // Порог по умолчанию
+		threshold = DefaultFuzzyThreshold
+	}
+	config := DefaultSimilarityConfig()
+	config.Threshold = threshold
+	scorer, _ := NewSimilarityScorer(config)
+	return newFuzzyMatcher(db, scorer)
+}
+
+// NewFuzzyMatcherWithConfig создает нечеткий сопоставитель с метриками, весами и порогом настройки проекта
+func NewFuzzyMatcherWithConfig(db *database.DB, config database.SimilarityConfig) (*FuzzyMatcher, error) {
+	scorer, err := NewSimilarityScorer(config)
+	if err != nil {
+		return nil, err
 	}
+	return newFuzzyMatcher(db, scorer), nil
+}
+
+func newFuzzyMatcher(db *database.DB, scorer *SimilarityScorer) *FuzzyMatcher {
 	return &FuzzyMatcher{
 		db:             db,
-		threshold:      threshold,
+		threshold:      scorer.Threshold(),
 		maxComparisons: 1000, // Максимум 1000 сравнений на элемент
 		batchSize:      500,  // Обрабатываем по 500 элементов за раз
 		prefixLength:   3,    // Используем первые 3 символа для фильтрации
+		scorer:         scorer,
 	}
 }
 
@@ -50,15 +82,25 @@ func (fm *FuzzyMatcher) FindDuplicateNames(uploadID int, databaseID int) ([]Dupl
 	log.Printf("Starting fuzzy duplicate search for upload %d", uploadID)
 	startTime := time.Now()
 
-	// Получаем все наименования номенклатуры
+	// Получаем все наименования номенклатуры с категорией верхнего уровня, если номенклатура классифицирована
 	query := `
-		SELECT DISTINCT nomenclature_reference, nomenclature_name 
+		SELECT DISTINCT nomenclature_reference, nomenclature_name, COALESCE(category_level1, '')
 		FROM nomenclature_items 
 		WHERE upload_id = ? AND nomenclature_name IS NOT NULL AND nomenclature_name != ''
 		ORDER BY nomenclature_name
 	`
 
 	rows, err := fm.db.Query(query, uploadID)
+	if err != nil {
+		// Колонок категорий нет в БД, где номенклатура не классифицировалась
+		query = `
+			SELECT DISTINCT nomenclature_reference, nomenclature_name, ''
+			FROM nomenclature_items 
+			WHERE upload_id = ? AND nomenclature_name IS NOT NULL AND nomenclature_name != ''
+			ORDER BY nomenclature_name
+		`
+		rows, err = fm.db.Query(query, uploadID)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query nomenclature items: %w", err)
 	}
@@ -67,7 +109,7 @@ func (fm *FuzzyMatcher) FindDuplicateNames(uploadID int, databaseID int) ([]Dupl
 	items := make([]DuplicateItem, 0)
 	for rows.Next() {
 		var item DuplicateItem
-		if err := rows.Scan(&item.Reference, &item.Name); err != nil {
+		if err := rows.Scan(&item.Reference, &item.Name, &item.Category); err != nil {
 			continue
 		}
 		items = append(items, item)
@@ -87,6 +129,11 @@ func (fm *FuzzyMatcher) FindDuplicateNames(uploadID int, databaseID int) ([]Dupl
 	log.Printf("Fuzzy duplicate search completed: found %d groups in %v (%.2f items/sec)",
 		len(groups), elapsed, float64(totalItems)/elapsed.Seconds())
 
+	// Сохраняем связи для графа кластеров, чтобы не пересчитывать сходство при просмотре
+	if err := fm.db.ReplaceDuplicateEdges(uploadID, databaseID, DuplicateEdges(groups)); err != nil {
+		log.Printf("Error saving fuzzy duplicate edges: %v", err)
+	}
+
 	// Сохранение найденных проблем
 	for _, group := range groups {
 		if len(group.Items) > 1 {
@@ -116,8 +163,37 @@ func (fm *FuzzyMatcher) FindDuplicateNames(uploadID int, databaseID int) ([]Dupl
 	return groups, nil
 }
 
+// DuplicateEdges преобразует группы в связи опорного элемента группы с остальными элементами
+func DuplicateEdges(groups []DuplicateGroup) []database.DuplicateEdge {
+	edges := make([]database.DuplicateEdge, 0)
+	for _, group := range groups {
+		if len(group.Items) < 2 {
+			continue
+		}
+		anchor := group.Items[0]
+		for i, item := range group.Items[1:] {
+			similarity := group.Similarity
+			if i+1 < len(group.Similarities) {
+				similarity = group.Similarities[i+1]
+			}
+			edges = append(edges, database.DuplicateEdge{
+				ClusterKey:      anchor.Reference,
+				SourceReference: anchor.Reference,
+				SourceName:      anchor.Name,
+				SourceCategory:  anchor.Category,
+				TargetReference: item.Reference,
+				TargetName:      item.Name,
+				TargetCategory:  item.Category,
+				Similarity:      similarity,
+			})
+		}
+	}
+	return edges
+}
+
 // findDuplicates находит дубликаты в списке элементов (старый метод O(n²))
 func (fm *FuzzyMatcher) findDuplicates(items []DuplicateItem) []DuplicateGroup {
+	items = prepareMatchItems(items)
 	groups := []DuplicateGroup{}
 	processed := make(map[string]bool)
 
@@ -127,8 +203,9 @@ func (fm *FuzzyMatcher) findDuplicates(items []DuplicateItem) []DuplicateGroup {
 		}
 
 		group := DuplicateGroup{
-			Items:      []DuplicateItem{item1},
-			Similarity: 1.0,
+			Items:        []DuplicateItem{item1},
+			Similarity:   1.0,
+			Similarities: []float64{1.0},
 		}
 
 		for j, item2 := range items {
@@ -136,9 +213,10 @@ func (fm *FuzzyMatcher) findDuplicates(items []DuplicateItem) []DuplicateGroup {
 				continue
 			}
 
-			similarity := fm.calculateSimilarity(item1.Name, item2.Name)
+			similarity := fm.itemSimilarity(item1, item2)
 			if similarity >= fm.threshold {
 				group.Items = append(group.Items, item2)
+				group.Similarities = append(group.Similarities, similarity)
 				if similarity < group.Similarity {
 					group.Similarity = similarity
 				}
@@ -158,6 +236,7 @@ func (fm *FuzzyMatcher) findDuplicates(items []DuplicateItem) []DuplicateGroup {
 
 // findDuplicatesOptimized находит дубликаты с оптимизацией через предварительную фильтрацию
 func (fm *FuzzyMatcher) findDuplicatesOptimized(items []DuplicateItem) []DuplicateGroup {
+	items = prepareMatchItems(items)
 	groups := []DuplicateGroup{}
 	processed := make(map[string]bool)
 
@@ -187,8 +266,9 @@ func (fm *FuzzyMatcher) findDuplicatesOptimized(items []DuplicateItem) []Duplica
 		}
 
 		group := DuplicateGroup{
-			Items:      []DuplicateItem{item1},
-			Similarity: 1.0,
+			Items:        []DuplicateItem{item1},
+			Similarity:   1.0,
+			Similarities: []float64{1.0},
 		}
 
 		comparisons := 0
@@ -197,11 +277,12 @@ func (fm *FuzzyMatcher) findDuplicatesOptimized(items []DuplicateItem) []Duplica
 				continue
 			}
 
-			similarity := fm.calculateSimilarity(item1.Name, candidate.Name)
+			similarity := fm.itemSimilarity(item1, candidate)
 			comparisons++
 
 			if similarity >= fm.threshold {
 				group.Items = append(group.Items, candidate)
+				group.Similarities = append(group.Similarities, similarity)
 				if similarity < group.Similarity {
 					group.Similarity = similarity
 				}
@@ -277,25 +358,75 @@ func (fm *FuzzyMatcher) prefixSimilarity(p1, p2 string) float64 {
 	return float64(matches) / float64(len(p1))
 }
 
-// calculateSimilarity рассчитывает схожесть двух строк используя алгоритм Левенштейна
+// calculateSimilarity рассчитывает схожесть двух строк по метрикам сопоставителя
 func (fm *FuzzyMatcher) calculateSimilarity(s1, s2 string) float64 {
-	// Нормализация строк
-	norm1 := strings.ToLower(strings.TrimSpace(s1))
-	norm2 := strings.ToLower(strings.TrimSpace(s2))
+	return fm.itemSimilarity(prepareMatchItem(DuplicateItem{Name: s1}), prepareMatchItem(DuplicateItem{Name: s2}))
+}
+
+// itemSimilarity рассчитывает сходство подготовленных элементов с учетом совпадения размеров
+func (fm *FuzzyMatcher) itemSimilarity(item1, item2 DuplicateItem) float64 {
+	similarity := fm.scorer.Score(item1.matchName, item2.matchName)
+	return applyDimensionBoost(similarity, dimensionsEqual(item1.dimensionKey, item2.dimensionKey))
+}
+
+// prepareMatchItems подготавливает элементы к сравнению один раз перед поиском дубликатов
+func prepareMatchItems(items []DuplicateItem) []DuplicateItem {
+	prepared := make([]DuplicateItem, len(items))
+	for i, item := range items {
+		prepared[i] = prepareMatchItem(item)
+	}
+	return prepared
+}
 
+// prepareMatchItem заполняет нормализованное наименование и ключ размеров элемента
+func prepareMatchItem(item DuplicateItem) DuplicateItem {
+	item.matchName = matcherNormalize(item.Name)
+	item.dimensionKey = normalization.DimensionKey(normalization.ParseDimensions(item.Name))
+	return item
+}
+
+// dimensionsEqual проверяет, что у обоих наименований есть размеры и они совпадают
+func dimensionsEqual(key1, key2 string) bool {
+	return key1 != "" && key1 == key2
+}
+
+// applyDimensionBoost повышает сходство наименований с одинаковыми размерами
+func applyDimensionBoost(similarity float64, equal bool) float64 {
+	if !equal {
+		return similarity
+	}
+	if similarity += DimensionMatchBoost; similarity > 1 {
+		similarity = 1
+	}
+	return similarity
+}
+
+// editSimilarity рассчитывает сходство по расстоянию Левенштейна и возвращает составляющие расчета
+func editSimilarity(s1, s2 string) (similarity float64, distance, maxLen int) {
+	return normalizedEditSimilarity(matcherNormalize(s1), matcherNormalize(s2))
+}
+
+// normalizedEditSimilarity рассчитывает сходство уже нормализованных наименований
+func normalizedEditSimilarity(norm1, norm2 string) (similarity float64, distance, maxLen int) {
 	if norm1 == norm2 {
-		return 1.0
+		return 1.0, 0, max(len(norm1), len(norm2))
 	}
 
 	// Расчет расстояния Левенштейна
-	distance := levenshteinDistance(norm1, norm2)
-	maxLen := max(len(norm1), len(norm2))
+	distance = levenshteinDistance(norm1, norm2)
+	maxLen = max(len(norm1), len(norm2))
 
 	if maxLen == 0 {
-		return 1.0
+		return 1.0, distance, maxLen
 	}
 
-	return 1.0 - float64(distance)/float64(maxLen)
+	return 1.0 - float64(distance)/float64(maxLen), distance, maxLen
+}
+
+// matcherNormalize приводит наименование к виду, в котором его сравнивает сопоставитель:
+// нижний регистр и размеры в каноническом виде ("30*20*2 мм" -> "30x20x2")
+func matcherNormalize(name string) string {
+	return normalization.CanonicalizeDimensions(strings.ToLower(strings.TrimSpace(name)))
 }
 
 // levenshteinDistance рассчитывает расстояние Левенштейна между двумя строками
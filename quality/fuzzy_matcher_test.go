@@ -0,0 +1,88 @@
+package quality
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+// generateDuplicateItems строит синтетическую номенклатуру, где каждый duplicateEvery-й элемент - опечатка предыдущего
+func generateDuplicateItems(count, duplicateEvery int) []DuplicateItem {
+	names := []string{"Молоток слесарный", "Болт М8х40 оцинкованный", "Кабель ВВГнг 3х2,5", "Краска ПФ-115 белая", "Перчатки х/б"}
+	items := make([]DuplicateItem, 0, count)
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("%s %d", names[i%len(names)], i/duplicateEvery)
+		if i%duplicateEvery == duplicateEvery-1 {
+			name += "."
+		}
+		items = append(items, DuplicateItem{Reference: fmt.Sprintf("ref-%d", i), Name: name})
+	}
+	return items
+}
+
+func TestFindDuplicatesOptimized(t *testing.T) {
+	fm := NewFuzzyMatcher(nil, 0.9)
+	groups := fm.findDuplicatesOptimized([]DuplicateItem{
+		{Reference: "1", Name: "Молоток слесарный 500г"},
+		{Reference: "2", Name: "молоток слесарный 500г."},
+		{Reference: "3", Name: "Кабель ВВГнг 3х2,5"},
+	})
+	if len(groups) != 1 || len(groups[0].Items) != 2 {
+		t.Fatalf("findDuplicatesOptimized() = %+v, want one group of 2 items", groups)
+	}
+}
+
+func BenchmarkCalculateSimilarity(b *testing.B) {
+	fm := NewFuzzyMatcher(nil, 0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fm.calculateSimilarity("Болт М8х40 оцинкованный DIN 933", "Болт M8x40 оцинк. DIN933")
+	}
+}
+
+func BenchmarkFindDuplicatesOptimized(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	fm := NewFuzzyMatcher(nil, 0)
+	for _, count := range []int{1000, 5000} {
+		items := generateDuplicateItems(count, 10)
+		b.Run(fmt.Sprintf("items=%d", count), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				fm.findDuplicatesOptimized(items)
+			}
+		})
+	}
+}
+
+func TestCalculateSimilarityDimensions(t *testing.T) {
+	fm := NewFuzzyMatcher(nil, 0)
+	tests := []struct {
+		name  string
+		a, b  string
+		match bool
+	}{
+		{"same dimensions in different notation", "Уголок стальной 20х30х2", "Уголок стальной 30*20*2 мм", true},
+		{"equal dimensions strengthen close names", "Уголок стальной 20х30х2", "Уголок стальн. 20x30x2", true},
+		{"dimensions alone are not enough", "Уголок 20х30х2", "Швеллер 30*20*2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similarity := fm.calculateSimilarity(tt.a, tt.b)
+			if (similarity >= DefaultFuzzyThreshold) != tt.match {
+				t.Errorf("calculateSimilarity(%q, %q) = %.3f, want match %v", tt.a, tt.b, similarity, tt.match)
+			}
+		})
+	}
+
+	edit, _, _ := editSimilarity("Уголок стальной 20х30х2", "Уголок стальн. 20x30x2")
+	if boosted := fm.calculateSimilarity("Уголок стальной 20х30х2", "Уголок стальн. 20x30x2"); boosted <= edit {
+		t.Errorf("dimension-equal similarity %.3f not above edit similarity %.3f", boosted, edit)
+	}
+	edit, _, _ = editSimilarity("Уголок 20х30х2", "Уголок 40х40х4")
+	if similarity := fm.calculateSimilarity("Уголок 20х30х2", "Уголок 40х40х4"); similarity != edit {
+		t.Errorf("different dimensions similarity %.3f, want edit similarity %.3f without boost", similarity, edit)
+	}
+}
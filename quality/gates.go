@@ -0,0 +1,120 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"httpserver/database"
+)
+
+// Пороги качества перед выгрузкой в 1С
+const (
+	GateClassificationCoverage = "classification_coverage" // Доля классифицированных нормализованных записей
+	GateDuplicateRate          = "duplicate_rate"          // Доля дублей по наименованию
+	GateReviewQueue            = "review_queue"            // Групп, ожидающих проверки
+)
+
+// GateResult результат проверки одного порога
+type GateResult struct {
+	Name      string  `json:"name"`
+	Passed    bool    `json:"passed"`
+	Threshold float64 `json:"threshold"`
+	Actual    float64 `json:"actual"`
+	Available bool    `json:"available"` // false - нет данных для расчета показателя
+	Message   string  `json:"message"`
+}
+
+// GateReport результат проверки порогов качества базы данных
+type GateReport struct {
+	DatabaseID  int                   `json:"database_id"`
+	Configured  bool                  `json:"configured"` // Пороги настроены и проверяются перед выгрузкой в 1С
+	Passed      bool                  `json:"passed"`
+	Gates       []GateResult          `json:"gates"`
+	Config      database.QualityGates `json:"config"`
+	EvaluatedAt time.Time             `json:"evaluated_at"`
+}
+
+// EvaluateDatabaseGates рассчитывает показатели базы проекта и проверяет по ним пороги
+func EvaluateDatabaseGates(db *database.DB, gates database.QualityGates) (*GateReport, error) {
+	scorecard, err := BuildScorecard(db, gates.DatabaseID)
+	if err != nil {
+		return nil, err
+	}
+	reviewQueue := 0
+	if gates.RequireEmptyReviewQueue {
+		if _, reviewQueue, err = db.GetReviewQueue(1, 0); err != nil {
+			return nil, err
+		}
+	}
+	return EvaluateGates(gates, scorecard, reviewQueue), nil
+}
+
+// EvaluateGates проверяет пороги по карте качества и размеру очереди проверки.
+// Без нормализованных данных порог покрытия классификацией не выполнен; без элементов дублей нет
+func EvaluateGates(gates database.QualityGates, scorecard *database.QualityScorecard, reviewQueue int) *GateReport {
+	report := &GateReport{DatabaseID: gates.DatabaseID, Passed: true, Gates: []GateResult{}, Config: gates, EvaluatedAt: time.Now()}
+	metrics := make(map[string]database.ScorecardMetric, len(scorecard.Metrics))
+	for _, metric := range scorecard.Metrics {
+		metrics[metric.Name] = metric
+	}
+
+	if gates.MinClassificationCoverage != nil {
+		metric := metrics[ScorecardClassification]
+		result := GateResult{Name: GateClassificationCoverage, Threshold: *gates.MinClassificationCoverage, Available: metric.Available}
+		if metric.Available {
+			result.Actual = roundRate(1 - metric.Value)
+			result.Passed = result.Actual >= result.Threshold
+			result.Message = fmt.Sprintf("Классифицировано %.2f%%, требуется не менее %.2f%%", result.Actual*100, result.Threshold*100)
+		} else {
+			result.Message = "Нет нормализованных данных"
+		}
+		report.add(result)
+	}
+
+	if gates.MaxDuplicateRate != nil {
+		metric := metrics[ScorecardDuplicates]
+		result := GateResult{Name: GateDuplicateRate, Threshold: *gates.MaxDuplicateRate, Available: metric.Available, Passed: true}
+		if metric.Available {
+			result.Actual = metric.Value
+			result.Passed = result.Actual <= result.Threshold
+			result.Message = fmt.Sprintf("Дублей %.2f%%, допускается не более %.2f%%", result.Actual*100, result.Threshold*100)
+		} else {
+			result.Message = "Нет элементов для проверки"
+		}
+		report.add(result)
+	}
+
+	if gates.RequireEmptyReviewQueue {
+		report.add(GateResult{
+			Name:      GateReviewQueue,
+			Actual:    float64(reviewQueue),
+			Available: true,
+			Passed:    reviewQueue == 0,
+			Message:   fmt.Sprintf("Групп на проверке: %d", reviewQueue),
+		})
+	}
+	return report
+}
+
+// add добавляет результат проверки порога в отчет
+func (r *GateReport) add(result GateResult) {
+	r.Gates = append(r.Gates, result)
+	r.Passed = r.Passed && result.Passed
+}
+
+// Failed возвращает названия невыполненных порогов
+func (r *GateReport) Failed() []string {
+	failed := []string{}
+	for _, gate := range r.Gates {
+		if !gate.Passed {
+			failed = append(failed, gate.Name)
+		}
+	}
+	return failed
+}
+
+// roundRate округляет долю до сотых процента
+func roundRate(rate float64) float64 {
+	return math.Round(rate*10000) / 10000
+}
@@ -0,0 +1,46 @@
+package quality
+
+import (
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestEvaluateGates(t *testing.T) {
+	items := []database.ScorecardSourceItem{
+		{Code: "1", Name: "Болт М8"}, {Code: "2", Name: "Болт М8"}, {Code: "3", Name: "Гайка М8"}, {Code: "4", Name: "Шайба"},
+	}
+	noDuplicates := func() *float64 { v := 0.0; return &v }
+	defaults := database.DefaultQualityGates(1)
+
+	tests := []struct {
+		name            string
+		gates           database.QualityGates
+		normalizedTotal int
+		classified      int
+		reviewQueue     int
+		wantPassed      bool
+		wantFailed      string
+	}{
+		{"defaults failed on duplicates", defaults, 100, 99, 0, false, GateDuplicateRate},
+		{"low coverage", defaults, 100, 90, 0, false, GateClassificationCoverage + "," + GateDuplicateRate},
+		{"review queue not empty", database.QualityGates{RequireEmptyReviewQueue: true}, 100, 100, 3, false, GateReviewQueue},
+		{"no normalized data", database.QualityGates{MinClassificationCoverage: defaults.MinClassificationCoverage}, 0, 0, 0, false, GateClassificationCoverage},
+		{"only coverage checked", database.QualityGates{MinClassificationCoverage: defaults.MinClassificationCoverage}, 100, 95, 0, true, ""},
+		{"no gates", database.QualityGates{}, 0, 0, 5, true, ""},
+		{"strict duplicates", database.QualityGates{MaxDuplicateRate: noDuplicates()}, 0, 0, 0, false, GateDuplicateRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scorecard := ComputeScorecard(1, items, tt.normalizedTotal, tt.classified)
+			report := EvaluateGates(tt.gates, scorecard, tt.reviewQueue)
+			if report.Passed != tt.wantPassed {
+				t.Errorf("Passed = %v, want %v: %+v", report.Passed, tt.wantPassed, report.Gates)
+			}
+			if got := strings.Join(report.Failed(), ","); got != tt.wantFailed {
+				t.Errorf("Failed() = %q, want %q", got, tt.wantFailed)
+			}
+		})
+	}
+}
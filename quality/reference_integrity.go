@@ -0,0 +1,267 @@
+package quality
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// Типы проблем ссылочной целостности
+const (
+	IssueDanglingReference     = "dangling_reference"      // Ссылка на элемент, которого нет в выгрузке
+	IssueReferenceWrongCatalog = "reference_wrong_catalog" // Ссылка ведет в другой справочник, чем объявлено в метаданных
+)
+
+// MaxReferenceProblems сколько проблем возвращается в отчете; в проблемы качества записываются все
+const MaxReferenceProblems = 1000
+
+// Справочники, элементы которых выгружаются в таблицу номенклатуры
+const (
+	nomenclatureCatalog   = "Номенклатура"
+	characteristicCatalog = "ХарактеристикиНоменклатуры"
+)
+
+// referencePattern ссылка 1С (УникальныйИдентификатор)
+var referencePattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// emptyReference пустая ссылка 1С - незаполненный реквизит, а не битая ссылка
+const emptyReference = "00000000-0000-0000-0000-000000000000"
+
+// CatalogIntegrity ссылочная целостность реквизитов одного справочника
+type CatalogIntegrity struct {
+	CatalogName  string  `json:"catalog_name"`
+	Items        int     `json:"items"`
+	References   int     `json:"references"`    // Реквизитов со ссылками
+	Resolved     int     `json:"resolved"`      // Ссылок на элементы выгрузки
+	Dangling     int     `json:"dangling"`      // Ссылок на отсутствующие элементы
+	WrongCatalog int     `json:"wrong_catalog"` // Ссылок на элементы другого справочника
+	Unchecked    int     `json:"unchecked"`     // Ссылок на справочники, не вошедшие в выгрузку
+	Score        float64 `json:"score"`         // Доля корректных среди проверенных ссылок, 0-100
+}
+
+// ReferenceProblem битая ссылка в реквизите элемента
+type ReferenceProblem struct {
+	Type            string `json:"type"`
+	CatalogName     string `json:"catalog_name"`
+	ItemReference   string `json:"item_reference"`
+	Attribute       string `json:"attribute"`
+	Value           string `json:"value"`
+	ExpectedCatalog string `json:"expected_catalog,omitempty"`
+	ActualCatalog   string `json:"actual_catalog,omitempty"`
+}
+
+// ReferenceIntegrityReport результат анализа ссылочной целостности выгрузки
+type ReferenceIntegrityReport struct {
+	UploadID   int                `json:"upload_id"`
+	References int                `json:"references"`
+	Dangling   int                `json:"dangling"`
+	Score      float64            `json:"score"`
+	Catalogs   []CatalogIntegrity `json:"catalogs"`
+	Problems   []ReferenceProblem `json:"problems"`
+	Truncated  bool               `json:"truncated"` // Проблем больше, чем MaxReferenceProblems
+
+	all []ReferenceProblem
+}
+
+// integrityScore доля корректных ссылок среди проверенных; без ссылок целостность полная
+func integrityScore(checked, broken int) float64 {
+	if checked <= 0 {
+		return 100
+	}
+	return validateMetricValue(float64(checked-broken) / float64(checked) * 100)
+}
+
+// referenceTargetCatalog возвращает справочник из типа 1С вида СправочникСсылка.Имя
+func referenceTargetCatalog(typeName string) string {
+	if name, ok := strings.CutPrefix(typeName, "СправочникСсылка."); ok {
+		return name
+	}
+	return ""
+}
+
+// AnalyzeReferenceIntegrity находит в реквизитах элементов справочников ссылки (UUID) и разрешает их
+// по элементам всех справочников и номенклатуре выгрузки. Если в метаданных реквизита объявлен
+// справочник, которого нет в выгрузке, ссылка считается непроверяемой
+func AnalyzeReferenceIntegrity(ctx context.Context, db *database.DB, uploadID int) (*ReferenceIntegrityReport, error) {
+	// Все ссылки выгрузки -> справочник
+	targets := make(map[string]string)
+	err := db.StreamCatalogItemsContext(ctx, uploadID, nil, 0, func(items []*database.CatalogItem) error {
+		for _, item := range items {
+			targets[strings.ToLower(item.Reference)] = item.CatalogName
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect references: %w", err)
+	}
+	err = db.StreamNomenclatureItemsContext(ctx, uploadID, 0, func(items []*database.NomenclatureItem) error {
+		for _, item := range items {
+			targets[strings.ToLower(item.NomenclatureReference)] = nomenclatureCatalog
+			if item.CharacteristicReference != "" {
+				targets[strings.ToLower(item.CharacteristicReference)] = characteristicCatalog
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect nomenclature references: %w", err)
+	}
+	exported := make(map[string]bool)
+	for _, catalog := range targets {
+		exported[catalog] = true
+	}
+
+	metadata, err := database.GetUploadCatalogMetadata(db.GetDB(), uploadID)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]map[string]string) // справочник -> реквизит -> справочник ссылки
+	for _, catalog := range metadata {
+		declared[catalog.CatalogName] = make(map[string]string)
+		for _, attr := range catalog.Attributes {
+			if target := referenceTargetCatalog(attr.TypeName); target != "" {
+				declared[catalog.CatalogName][attr.Name] = target
+			}
+		}
+	}
+
+	report := &ReferenceIntegrityReport{UploadID: uploadID, Catalogs: make([]CatalogIntegrity, 0), Problems: make([]ReferenceProblem, 0)}
+	catalogs := make(map[string]*CatalogIntegrity)
+	err = db.StreamCatalogItemsContext(ctx, uploadID, nil, 0, func(items []*database.CatalogItem) error {
+		for _, item := range items {
+			stats := catalogs[item.CatalogName]
+			if stats == nil {
+				stats = &CatalogIntegrity{CatalogName: item.CatalogName}
+				catalogs[item.CatalogName] = stats
+			}
+			stats.Items++
+
+			values := database.ExtractAttributeValues(item.Attributes)
+			names := make([]string, 0, len(values))
+			for name := range values {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				value := strings.TrimSpace(values[name])
+				if !referencePattern.MatchString(value) || value == emptyReference {
+					continue
+				}
+				stats.References++
+				expected := declared[item.CatalogName][name]
+				actual, found := targets[strings.ToLower(value)]
+				problem := ReferenceProblem{CatalogName: item.CatalogName, ItemReference: item.Reference, Attribute: name,
+					Value: value, ExpectedCatalog: expected, ActualCatalog: actual}
+				switch {
+				case found && (expected == "" || expected == actual):
+					stats.Resolved++
+				case found:
+					stats.WrongCatalog++
+					problem.Type = IssueReferenceWrongCatalog
+					report.all = append(report.all, problem)
+				case expected != "" && !exported[expected]:
+					stats.Unchecked++
+				default:
+					stats.Dangling++
+					problem.Type = IssueDanglingReference
+					report.all = append(report.all, problem)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check references: %w", err)
+	}
+
+	checked := 0
+	for _, stats := range catalogs {
+		stats.Score = integrityScore(stats.References-stats.Unchecked, stats.Dangling+stats.WrongCatalog)
+		report.References += stats.References
+		report.Dangling += stats.Dangling
+		checked += stats.References - stats.Unchecked
+		report.Catalogs = append(report.Catalogs, *stats)
+	}
+	sort.Slice(report.Catalogs, func(i, j int) bool { return report.Catalogs[i].CatalogName < report.Catalogs[j].CatalogName })
+	report.Score = integrityScore(checked, len(report.all))
+	report.Problems = report.all
+	if len(report.Problems) > MaxReferenceProblems {
+		report.Problems = report.Problems[:MaxReferenceProblems]
+		report.Truncated = true
+	}
+	return report, nil
+}
+
+// RecordReferenceIntegrity сохраняет битые ссылки как проблемы качества (заменяя найденные прошлым анализом)
+// и метрику ссылочной целостности с оценками по справочникам
+func (qa *QualityAnalyzer) RecordReferenceIntegrity(report *ReferenceIntegrityReport, databaseID int) error {
+	now := time.Now()
+	issues := make([]database.DataQualityIssue, 0, len(report.all))
+	for _, problem := range report.all {
+		issue := database.DataQualityIssue{
+			UploadID:        report.UploadID,
+			DatabaseID:      databaseID,
+			EntityType:      "catalog_item",
+			EntityReference: problem.ItemReference,
+			IssueType:       problem.Type,
+			IssueSeverity:   "HIGH",
+			FieldName:       problem.Attribute,
+			ExpectedValue:   problem.ExpectedCatalog,
+			ActualValue:     problem.Value,
+			Description:     fmt.Sprintf("Справочник %s: реквизит %s ссылается на отсутствующий в выгрузке элемент %s", problem.CatalogName, problem.Attribute, problem.Value),
+			DetectedAt:      now,
+			Status:          "OPEN",
+		}
+		if problem.Type == IssueReferenceWrongCatalog {
+			issue.IssueSeverity = "MEDIUM"
+			issue.Description = fmt.Sprintf("Справочник %s: реквизит %s ссылается на элемент справочника %s вместо %s", problem.CatalogName, problem.Attribute, problem.ActualCatalog, problem.ExpectedCatalog)
+		}
+		issues = append(issues, issue)
+	}
+	if err := qa.db.ReplaceQualityIssues(report.UploadID, []string{IssueDanglingReference, IssueReferenceWrongCatalog}, issues); err != nil {
+		return err
+	}
+
+	scores := make(map[string]interface{}, len(report.Catalogs))
+	for _, catalog := range report.Catalogs {
+		scores[catalog.CatalogName] = catalog.Score
+	}
+	metric := database.DataQualityMetric{
+		UploadID:       report.UploadID,
+		DatabaseID:     databaseID,
+		MetricCategory: "consistency",
+		MetricName:     "reference_integrity",
+		MetricValue:    report.Score,
+		MeasuredAt:     now,
+		Details: map[string]interface{}{
+			"references":     report.References,
+			"broken":         len(report.all),
+			"catalog_scores": scores,
+		},
+	}
+	switch {
+	case report.Score >= 95:
+		metric.Status = "PASS"
+	case report.Score >= 80:
+		metric.Status = "WARNING"
+	default:
+		metric.Status = "FAIL"
+	}
+	return qa.db.SaveQualityMetric(&metric)
+}
+
+// analyzeReferenceIntegrity проверяет ссылочную целостность в составе полного анализа качества
+func (qa *QualityAnalyzer) analyzeReferenceIntegrity(uploadID int, databaseID int) error {
+	report, err := AnalyzeReferenceIntegrity(context.Background(), qa.db, uploadID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Reference integrity for upload %d: %d references, %d broken, score %.2f", uploadID, report.References, len(report.all), report.Score)
+	return qa.RecordReferenceIntegrity(report, databaseID)
+}
@@ -0,0 +1,84 @@
+package quality
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestReferenceIntegrity(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "upload.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+
+	const (
+		unitRef      = "7d3c8b1e-0a4f-11ee-8c90-0242ac120002"
+		vendorRef    = "7d3c8b1e-0a4f-11ee-8c90-0242ac120003"
+		missingRef   = "7d3c8b1e-0a4f-11ee-8c90-0242ac120099"
+		warehouseRef = "7d3c8b1e-0a4f-11ee-8c90-0242ac120100"
+	)
+	upload, _ := db.CreateUpload("uuid-integrity", "8.3", "УправлениеТорговлей")
+	units, _ := db.AddCatalog(upload.ID, "ЕдиницыИзмерения", "")
+	vendors, _ := db.AddCatalog(upload.ID, "Производители", "")
+	goods, _ := db.AddCatalog(upload.ID, "Товары", "")
+	db.AddCatalogItem(units.ID, unitRef, "796", "шт", "", "")
+	db.AddCatalogItem(vendors.ID, vendorRef, "001", "Бош", "", "")
+	db.AddCatalogItem(goods.ID, "ref-1", "1", "Дрель",
+		"<ЕдиницаИзмерения>"+unitRef+"</ЕдиницаИзмерения><Производитель>"+vendorRef+"</Производитель><Склад>"+warehouseRef+"</Склад>", "")
+	db.AddCatalogItem(goods.ID, "ref-2", "2", "Перфоратор",
+		"<ЕдиницаИзмерения>"+missingRef+"</ЕдиницаИзмерения><Производитель>"+unitRef+"</Производитель><Артикул>A-2</Артикул>", "")
+	db.AddCatalogItem(goods.ID, "ref-3", "3", "Шуруповерт", "<ЕдиницаИзмерения>00000000-0000-0000-0000-000000000000</ЕдиницаИзмерения>", "")
+	err = database.SaveCatalogMetadata(db.GetDB(), &database.CatalogMetadata{
+		UploadID:    upload.ID,
+		CatalogName: "Товары",
+		Attributes: []database.CatalogAttributeDefinition{
+			{Name: "Производитель", TypeName: "СправочникСсылка.Производители", Kind: database.ConstantKindReference},
+			{Name: "Склад", TypeName: "СправочникСсылка.Склады", Kind: database.ConstantKindReference},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SaveCatalogMetadata() error = %v", err)
+	}
+
+	report, err := AnalyzeReferenceIntegrity(context.Background(), db, upload.ID)
+	if err != nil {
+		t.Fatalf("AnalyzeReferenceIntegrity() error = %v", err)
+	}
+	var goodsStats CatalogIntegrity
+	for _, catalog := range report.Catalogs {
+		if catalog.CatalogName == "Товары" {
+			goodsStats = catalog
+		}
+	}
+	want := CatalogIntegrity{CatalogName: "Товары", Items: 3, References: 5, Resolved: 2, Dangling: 1, WrongCatalog: 1, Unchecked: 1, Score: 50}
+	if goodsStats != want {
+		t.Errorf("Товары = %+v, want %+v", goodsStats, want)
+	}
+	if len(report.Problems) != 2 || report.Dangling != 1 || report.Score != 50 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// Повторная запись заменяет проблемы прошлого анализа
+	analyzer := NewQualityAnalyzer(db)
+	for i := 0; i < 2; i++ {
+		if err := analyzer.RecordReferenceIntegrity(report, 0); err != nil {
+			t.Fatalf("RecordReferenceIntegrity() error = %v", err)
+		}
+	}
+	issues, total, err := db.GetQualityIssues(upload.ID, nil, 10, 0)
+	if err != nil {
+		t.Fatalf("GetQualityIssues() error = %v", err)
+	}
+	if total != 2 {
+		t.Fatalf("issues = %+v, want dangling and wrong catalog", issues)
+	}
+	for _, issue := range issues {
+		if issue.EntityReference != "ref-2" || (issue.IssueType != IssueDanglingReference && issue.IssueType != IssueReferenceWrongCatalog) {
+			t.Errorf("unexpected issue %+v", issue)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"httpserver/database"
+)
+
+// Показатели карты качества базы данных
+const (
+	ScorecardCompleteness   = "completeness"   // Заполненность кода и наименования
+	ScorecardDuplicates     = "duplicates"     // Доля дублей по наименованию
+	ScorecardClassification = "classification" // Покрытие классификацией КПВЭД
+	ScorecardNaming         = "naming"         // Единообразие написания наименований
+)
+
+// GradeThresholds минимальные оценки (0..100) для букв A–D, ниже D - F
+type GradeThresholds struct {
+	A, B, C, D float64
+}
+
+// Grade возвращает буквенную оценку для балла
+func (t GradeThresholds) Grade(score float64) string {
+	switch {
+	case score >= t.A:
+		return "A"
+	case score >= t.B:
+		return "B"
+	case score >= t.C:
+		return "C"
+	case score >= t.D:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// scorecardMetricConfig вес и пороги показателя
+type scorecardMetricConfig struct {
+	name       string
+	weight     float64
+	thresholds GradeThresholds
+}
+
+// scorecardMetrics показатели в порядке вывода; дубли оцениваются строже остальных
+var scorecardMetrics = []scorecardMetricConfig{
+	{ScorecardCompleteness, 0.30, GradeThresholds{A: 95, B: 90, C: 80, D: 70}},
+	{ScorecardDuplicates, 0.25, GradeThresholds{A: 98, B: 95, C: 90, D: 80}},
+	{ScorecardClassification, 0.25, GradeThresholds{A: 95, B: 85, C: 70, D: 50}},
+	{ScorecardNaming, 0.20, GradeThresholds{A: 95, B: 90, C: 80, D: 70}},
+}
+
+// ScorecardGradeThresholds пороги итоговой оценки
+var ScorecardGradeThresholds = GradeThresholds{A: 90, B: 80, C: 70, D: 60}
+
+// BuildScorecard рассчитывает карту качества по данным базы проекта
+func BuildScorecard(db *database.DB, databaseID int) (*database.QualityScorecard, error) {
+	items, err := db.GetScorecardSourceItems()
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect source items: %w", err)
+	}
+	normalizedTotal, classified, err := db.GetClassificationCoverage()
+	if err != nil {
+		return nil, err
+	}
+	return ComputeScorecard(databaseID, items, normalizedTotal, classified), nil
+}
+
+// ComputeScorecard рассчитывает показатели и взвешенную итоговую оценку.
+// Показатели без данных не участвуют в итоговой оценке, веса остальных нормируются
+func ComputeScorecard(databaseID int, items []database.ScorecardSourceItem, normalizedTotal, classified int) *database.QualityScorecard {
+	var incomplete, named, inconsistent int
+	names := make(map[string]bool)
+	for _, item := range items {
+		name := strings.TrimSpace(item.Name)
+		if name == "" || strings.TrimSpace(item.Code) == "" {
+			incomplete++
+		}
+		if name == "" {
+			continue
+		}
+		named++
+		names[strings.Join(strings.Fields(strings.ToLower(name)), " ")] = true
+		if !consistentName(item.Name) {
+			inconsistent++
+		}
+	}
+
+	// Значение показателя - доля элементов с проблемой
+	type measurement struct{ total, affected int }
+	measurements := map[string]measurement{
+		ScorecardCompleteness:   {len(items), incomplete},
+		ScorecardDuplicates:     {named, named - len(names)},
+		ScorecardClassification: {normalizedTotal, normalizedTotal - classified},
+		ScorecardNaming:         {named, inconsistent},
+	}
+
+	scorecard := &database.QualityScorecard{
+		DatabaseID:    databaseID,
+		ItemsAnalyzed: len(items),
+		ComputedAt:    time.Now(),
+	}
+	var weighted, weights float64
+	for _, config := range scorecardMetrics {
+		m := measurements[config.name]
+		metric := database.ScorecardMetric{Name: config.name, Weight: config.weight, Total: m.total, Affected: m.affected}
+		if m.total > 0 {
+			rate := float64(m.affected) / float64(m.total)
+			score := roundScore(100 * (1 - rate))
+			metric.Value = math.Round(rate*10000) / 10000
+			metric.Score = &score
+			metric.Grade = config.thresholds.Grade(score)
+			metric.Available = true
+			weighted += score * config.weight
+			weights += config.weight
+		}
+		scorecard.Metrics = append(scorecard.Metrics, metric)
+	}
+
+	if weights > 0 {
+		scorecard.Score = roundScore(weighted / weights)
+		scorecard.Grade = ScorecardGradeThresholds.Grade(scorecard.Score)
+	} else {
+		scorecard.Grade = "F"
+	}
+	return scorecard
+}
+
+// consistentName проверяет единообразие написания: без лишних пробелов, с буквами и не целиком заглавными
+func consistentName(name string) bool {
+	if name != strings.TrimSpace(name) || strings.Contains(name, "  ") {
+		return false
+	}
+	letters, upper := 0, 0
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters == 0 {
+		return false
+	}
+	return letters <= 3 || upper < letters
+}
+
+// roundScore округляет балл до десятых
+func roundScore(score float64) float64 {
+	return math.Round(score*10) / 10
+}
@@ -0,0 +1,78 @@
+package quality
+
+import (
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestGradeThresholds(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{100, "A"}, {90, "A"}, {89.9, "B"}, {75, "C"}, {60, "D"}, {59.9, "F"}, {0, "F"},
+	}
+	for _, tt := range tests {
+		if got := ScorecardGradeThresholds.Grade(tt.score); got != tt.want {
+			t.Errorf("Grade(%v) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestComputeScorecard(t *testing.T) {
+	tests := []struct {
+		name            string
+		items           []database.ScorecardSourceItem
+		normalizedTotal int
+		classified      int
+		wantScore       float64
+		wantGrade       string
+		wantMetrics     map[string]string // показатель -> оценка, "" - нет данных
+	}{
+		{
+			name: "clean database",
+			items: []database.ScorecardSourceItem{
+				{Code: "1", Name: "Болт М8"}, {Code: "2", Name: "Гайка М8"}, {Code: "3", Name: "Шайба"},
+			},
+			normalizedTotal: 3,
+			classified:      3,
+			wantScore:       100,
+			wantGrade:       "A",
+			wantMetrics:     map[string]string{ScorecardCompleteness: "A", ScorecardDuplicates: "A", ScorecardClassification: "A", ScorecardNaming: "A"},
+		},
+		{
+			name: "duplicates and sloppy names without normalization",
+			items: []database.ScorecardSourceItem{
+				{Code: "1", Name: "Болт М8"}, {Code: "2", Name: "болт  м8"}, {Code: "", Name: "ГАЙКА М8"}, {Code: "4", Name: "Шайба"},
+			},
+			// completeness 75 (0.3), duplicates 75 (0.25), naming 50 (0.2) -> 68.3
+			wantScore:   68.3,
+			wantGrade:   "D",
+			wantMetrics: map[string]string{ScorecardCompleteness: "D", ScorecardDuplicates: "F", ScorecardClassification: "", ScorecardNaming: "F"},
+		},
+		{
+			name:        "empty database",
+			wantGrade:   "F",
+			wantMetrics: map[string]string{ScorecardCompleteness: "", ScorecardDuplicates: "", ScorecardClassification: "", ScorecardNaming: ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scorecard := ComputeScorecard(7, tt.items, tt.normalizedTotal, tt.classified)
+			if scorecard.DatabaseID != 7 || scorecard.Score != tt.wantScore || scorecard.Grade != tt.wantGrade {
+				t.Errorf("ComputeScorecard() = %.1f %s, want %.1f %s", scorecard.Score, scorecard.Grade, tt.wantScore, tt.wantGrade)
+			}
+			if len(scorecard.Metrics) != len(tt.wantMetrics) {
+				t.Fatalf("metrics = %d, want %d", len(scorecard.Metrics), len(tt.wantMetrics))
+			}
+			for _, metric := range scorecard.Metrics {
+				want := tt.wantMetrics[metric.Name]
+				if metric.Grade != want || metric.Available != (want != "") {
+					t.Errorf("metric %s = %+v, want grade %q", metric.Name, metric, want)
+				}
+			}
+		})
+	}
+}
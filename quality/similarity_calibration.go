@@ -0,0 +1,165 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"httpserver/database"
+)
+
+// CombinedMetric имя оценки настройки проекта (взвешенного среднего метрик) в результатах калибровки
+const CombinedMetric = "combined"
+
+// MetricCalibration качество метрики на подтвержденных парах: точность и полнота при пороге настройки
+// и порог с наибольшей F1. Дубликатом считается пара со сходством не ниже порога
+type MetricCalibration struct {
+	Metric        string                            `json:"metric"`
+	Weights       []database.SimilarityMetricWeight `json:"weights,omitempty"`
+	Threshold     float64                           `json:"threshold"`
+	Precision     float64                           `json:"precision"`
+	Recall        float64                           `json:"recall"`
+	F1            float64                           `json:"f1"`
+	BestThreshold float64                           `json:"best_threshold"`
+	BestPrecision float64                           `json:"best_precision"`
+	BestRecall    float64                           `json:"best_recall"`
+	BestF1        float64                           `json:"best_f1"`
+	MeanDuplicate float64                           `json:"mean_duplicate"` // Среднее сходство дубликатов
+	MeanDistinct  float64                           `json:"mean_distinct"`  // Среднее сходство разных позиций
+}
+
+// SimilarityCalibration результат оценки метрик на подтвержденных парах проекта
+type SimilarityCalibration struct {
+	Pairs       int                 `json:"pairs"`
+	Duplicates  int                 `json:"duplicates"`
+	Distinct    int                 `json:"distinct"`
+	Metrics     []MetricCalibration `json:"metrics"`
+	Recommended string              `json:"recommended"` // Метрика с наибольшей F1 при подобранном пороге
+}
+
+// scoredPair сходство подтвержденной пары
+type scoredPair struct {
+	score     float64
+	duplicate bool
+}
+
+// CalibrateSimilarity оценивает каждую доступную метрику и настройку проекта на подтвержденных парах.
+// Сходство считается так же, как у сопоставителя, включая прибавку за совпадение размеров
+func CalibrateSimilarity(labels []database.DuplicatePairLabel, config database.SimilarityConfig) (*SimilarityCalibration, error) {
+	combined, err := NewSimilarityScorer(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no labeled pairs to calibrate on")
+	}
+
+	calibration := &SimilarityCalibration{Pairs: len(labels), Metrics: []MetricCalibration{}}
+	items := make([][2]DuplicateItem, len(labels))
+	for i, label := range labels {
+		items[i] = [2]DuplicateItem{prepareMatchItem(DuplicateItem{Name: label.NameA}), prepareMatchItem(DuplicateItem{Name: label.NameB})}
+		if label.IsDuplicate {
+			calibration.Duplicates++
+		} else {
+			calibration.Distinct++
+		}
+	}
+
+	evaluate := func(name string, scorer *SimilarityScorer) MetricCalibration {
+		fm := newFuzzyMatcher(nil, scorer)
+		pairs := make([]scoredPair, len(labels))
+		for i, pair := range items {
+			pairs[i] = scoredPair{score: fm.itemSimilarity(pair[0], pair[1]), duplicate: labels[i].IsDuplicate}
+		}
+		return calibrateScores(name, pairs, config.Threshold)
+	}
+
+	for _, name := range SimilarityMetricNames() {
+		scorer, err := NewSimilarityScorer(database.SimilarityConfig{
+			Metrics:   []database.SimilarityMetricWeight{{Metric: name, Weight: 1}},
+			Threshold: config.Threshold,
+		})
+		if err != nil {
+			return nil, err
+		}
+		calibration.Metrics = append(calibration.Metrics, evaluate(name, scorer))
+	}
+	result := evaluate(CombinedMetric, combined)
+	result.Weights = config.Metrics
+	calibration.Metrics = append(calibration.Metrics, result)
+
+	best := calibration.Metrics[0]
+	for _, metric := range calibration.Metrics[1:] {
+		if metric.BestF1 > best.BestF1 {
+			best = metric
+		}
+	}
+	calibration.Recommended = best.Metric
+	return calibration, nil
+}
+
+// calibrateScores рассчитывает точность и полноту при пороге threshold и подбирает порог
+// с наибольшей F1 среди значений сходства пар; при равной F1 выбирается более строгий порог
+func calibrateScores(name string, pairs []scoredPair, threshold float64) MetricCalibration {
+	result := MetricCalibration{Metric: name, Threshold: threshold}
+	var duplicateSum, distinctSum float64
+	var duplicates, distinct int
+	for _, pair := range pairs {
+		if pair.duplicate {
+			duplicateSum += pair.score
+			duplicates++
+		} else {
+			distinctSum += pair.score
+			distinct++
+		}
+	}
+	if duplicates > 0 {
+		result.MeanDuplicate = roundSimilarity(duplicateSum / float64(duplicates))
+	}
+	if distinct > 0 {
+		result.MeanDistinct = roundSimilarity(distinctSum / float64(distinct))
+	}
+
+	result.Precision, result.Recall, result.F1 = classificationScores(pairs, threshold)
+
+	candidates := make([]float64, 0, len(pairs))
+	for _, pair := range pairs {
+		candidates = append(candidates, pair.score)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(candidates)))
+	result.BestThreshold = threshold
+	result.BestPrecision, result.BestRecall, result.BestF1 = result.Precision, result.Recall, result.F1
+	for _, candidate := range candidates {
+		// Порог округляется вниз, чтобы пара с этим сходством оставалась дубликатом
+		candidate = math.Floor(candidate*1000) / 1000
+		precision, recall, f1 := classificationScores(pairs, candidate)
+		if f1 > result.BestF1 {
+			result.BestThreshold = candidate
+			result.BestPrecision, result.BestRecall, result.BestF1 = precision, recall, f1
+		}
+	}
+	return result
+}
+
+// classificationScores рассчитывает точность, полноту и F1 разделения пар порогом
+func classificationScores(pairs []scoredPair, threshold float64) (precision, recall, f1 float64) {
+	var truePositive, falsePositive, falseNegative int
+	for _, pair := range pairs {
+		predicted := pair.score >= threshold
+		switch {
+		case predicted && pair.duplicate:
+			truePositive++
+		case predicted:
+			falsePositive++
+		case pair.duplicate:
+			falseNegative++
+		}
+	}
+	if truePositive == 0 {
+		return 0, 0, 0
+	}
+	precision = float64(truePositive) / float64(truePositive+falsePositive)
+	recall = float64(truePositive) / float64(truePositive+falseNegative)
+	f1 = 2 * precision * recall / (precision + recall)
+	return roundSimilarity(precision), roundSimilarity(recall), roundSimilarity(f1)
+}
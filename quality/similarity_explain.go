@@ -0,0 +1,256 @@
+package quality
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// SimilarityExplanation разбор сходства двух наименований.
+// Score рассчитывается тем же кодом, что и у нечеткого сопоставителя, остальные показатели поясняют его
+type SimilarityExplanation struct {
+	A           string  `json:"a"`
+	B           string  `json:"b"`
+	NormalizedA string  `json:"normalized_a"`
+	NormalizedB string  `json:"normalized_b"`
+	Score       float64 `json:"score"`
+	Threshold   float64 `json:"threshold"`
+	Matched     bool    `json:"matched"` // Сопоставитель объединит наименования в группу дублей
+
+	EditDistance       EditDistanceBreakdown `json:"edit_distance"`
+	TokenOverlap       TokenOverlap          `json:"token_overlap"`
+	Transliteration    []TokenMatch          `json:"transliteration_matches"`
+	AttributeAgreement AttributeAgreement    `json:"attribute_agreement"`
+	Dimensions         DimensionComparison   `json:"dimensions"`
+}
+
+// DimensionComparison сравнение размеров наименований в каноническом виде
+type DimensionComparison struct {
+	DimensionsA []string `json:"dimensions_a"`
+	DimensionsB []string `json:"dimensions_b"`
+	Equal       bool     `json:"equal"`
+	Boost       float64  `json:"boost"` // Прибавка к сходству за совпадение размеров
+}
+
+// EditDistanceBreakdown составляющие оценки по расстоянию Левенштейна
+type EditDistanceBreakdown struct {
+	Distance   int     `json:"distance"`
+	MaxLength  int     `json:"max_length"` // Длина более длинной строки, как ее считает сопоставитель
+	Similarity float64 `json:"similarity"`
+}
+
+// TokenOverlap пересечение слов наименований
+type TokenOverlap struct {
+	TokensA []string `json:"tokens_a"`
+	TokensB []string `json:"tokens_b"`
+	Shared  []string `json:"shared"`
+	Jaccard float64  `json:"jaccard"`
+}
+
+// TokenMatch пара слов, совпадающих только после транслитерации или замены похожих латинских букв
+type TokenMatch struct {
+	A    string `json:"a"`
+	B    string `json:"b"`
+	Kind string `json:"kind"` // homoglyph, transliteration
+}
+
+// AttributeAgreement совпадение характеристик (слов с цифрами: размеры, масса, артикулы)
+type AttributeAgreement struct {
+	AttributesA []string `json:"attributes_a"`
+	AttributesB []string `json:"attributes_b"`
+	Agreed      []string `json:"agreed"`
+	Conflicting []string `json:"conflicting"`
+	Agreement   float64  `json:"agreement"` // 1 - характеристики совпадают или отсутствуют
+}
+
+// homoglyphs латинские буквы, совпадающие по написанию с кириллическими
+var homoglyphs = map[rune]rune{
+	'a': 'а', 'c': 'с', 'e': 'е', 'k': 'к', 'm': 'м', 'o': 'о', 'p': 'р', 't': 'т', 'x': 'х', 'y': 'у', 'b': 'в', 'h': 'н',
+}
+
+// cyrillicToLatin упрощенная транслитерация кириллицы
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i", 'й': "i",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+}
+
+// ExplainSimilarity раскладывает сходство двух наименований на составляющие.
+// threshold <= 0 означает порог сопоставителя по умолчанию
+func ExplainSimilarity(a, b string, threshold float64) *SimilarityExplanation {
+	if threshold <= 0 {
+		threshold = DefaultFuzzyThreshold
+	}
+	itemA, itemB := prepareMatchItem(DuplicateItem{Name: a}), prepareMatchItem(DuplicateItem{Name: b})
+	similarity, distance, maxLen := normalizedEditSimilarity(itemA.matchName, itemB.matchName)
+	dimensionsMatch := dimensionsEqual(itemA.dimensionKey, itemB.dimensionKey)
+	score := applyDimensionBoost(similarity, dimensionsMatch)
+	explanation := &SimilarityExplanation{
+		A:           a,
+		B:           b,
+		NormalizedA: itemA.matchName,
+		NormalizedB: itemB.matchName,
+		Score:       roundSimilarity(score),
+		Threshold:   threshold,
+		Matched:     score >= threshold,
+		Dimensions: DimensionComparison{
+			DimensionsA: dimensionList(itemA.dimensionKey),
+			DimensionsB: dimensionList(itemB.dimensionKey),
+			Equal:       dimensionsMatch,
+			Boost:       roundSimilarity(score - similarity),
+		},
+		EditDistance: EditDistanceBreakdown{
+			Distance:   distance,
+			MaxLength:  maxLen,
+			Similarity: roundSimilarity(similarity),
+		},
+		Transliteration: []TokenMatch{},
+	}
+
+	tokensA, tokensB := similarityTokens(a), similarityTokens(b)
+	explanation.TokenOverlap = tokenOverlap(tokensA, tokensB)
+
+	// Пары слов, которые расходятся только из-за раскладки или транслитерации
+	shared := toSet(explanation.TokenOverlap.Shared)
+	for _, tokenA := range tokensA {
+		if shared[tokenA] {
+			continue
+		}
+		for _, tokenB := range tokensB {
+			if shared[tokenB] {
+				continue
+			}
+			if kind := transliterationMatch(tokenA, tokenB); kind != "" {
+				explanation.Transliteration = append(explanation.Transliteration, TokenMatch{A: tokenA, B: tokenB, Kind: kind})
+			}
+		}
+	}
+
+	explanation.AttributeAgreement = attributeAgreement(tokensA, tokensB)
+	return explanation
+}
+
+// dimensionList разбивает ключ размеров на список
+func dimensionList(key string) []string {
+	if key == "" {
+		return []string{}
+	}
+	return strings.Split(key, ";")
+}
+
+// similarityTokens разбивает наименование на слова в нижнем регистре без повторов
+func similarityTokens(name string) []string {
+	return splitTokens(matcherNormalize(name))
+}
+
+// tokenOverlap рассчитывает коэффициент Жаккара по словам
+func tokenOverlap(tokensA, tokensB []string) TokenOverlap {
+	overlap := TokenOverlap{TokensA: tokensA, TokensB: tokensB, Shared: []string{}}
+	setB := toSet(tokensB)
+	for _, token := range tokensA {
+		if setB[token] {
+			overlap.Shared = append(overlap.Shared, token)
+		}
+	}
+	union := len(tokensA) + len(tokensB) - len(overlap.Shared)
+	if union > 0 {
+		overlap.Jaccard = roundSimilarity(float64(len(overlap.Shared)) / float64(union))
+	}
+	return overlap
+}
+
+// transliterationMatch определяет, совпадают ли слова после замены похожих букв или транслитерации
+func transliterationMatch(a, b string) string {
+	if a == b {
+		return ""
+	}
+	if replaceHomoglyphs(a) == replaceHomoglyphs(b) {
+		return "homoglyph"
+	}
+	if transliterate(a) == transliterate(b) {
+		return "transliteration"
+	}
+	return ""
+}
+
+// attributeAgreement сравнивает характеристики наименований с учетом похожих латинских букв
+func attributeAgreement(tokensA, tokensB []string) AttributeAgreement {
+	agreement := AttributeAgreement{AttributesA: []string{}, AttributesB: []string{}, Agreed: []string{}, Conflicting: []string{}, Agreement: 1}
+	keysB := make(map[string]bool)
+	for _, token := range tokensB {
+		if hasDigit(token) {
+			agreement.AttributesB = append(agreement.AttributesB, token)
+			keysB[attributeKey(token)] = true
+		}
+	}
+	keysA := make(map[string]bool)
+	for _, token := range tokensA {
+		if !hasDigit(token) {
+			continue
+		}
+		agreement.AttributesA = append(agreement.AttributesA, token)
+		keysA[attributeKey(token)] = true
+		if keysB[attributeKey(token)] {
+			agreement.Agreed = append(agreement.Agreed, token)
+		} else {
+			agreement.Conflicting = append(agreement.Conflicting, token)
+		}
+	}
+	for _, token := range agreement.AttributesB {
+		if !keysA[attributeKey(token)] {
+			agreement.Conflicting = append(agreement.Conflicting, token)
+		}
+	}
+
+	if total := len(agreement.Agreed) + len(agreement.Conflicting); total > 0 {
+		agreement.Agreement = roundSimilarity(float64(len(agreement.Agreed)) / float64(total))
+	}
+	return agreement
+}
+
+// attributeKey приводит характеристику к виду для сравнения: кириллица вместо похожей латиницы, точка вместо запятой
+func attributeKey(token string) string {
+	return strings.ReplaceAll(replaceHomoglyphs(token), ",", ".")
+}
+
+// replaceHomoglyphs заменяет латинские буквы похожими кириллическими
+func replaceHomoglyphs(token string) string {
+	return strings.Map(func(r rune) rune {
+		if cyr, ok := homoglyphs[r]; ok {
+			return cyr
+		}
+		return r
+	}, token)
+}
+
+// transliterate переводит кириллицу в латиницу
+func transliterate(token string) string {
+	var b strings.Builder
+	for _, r := range token {
+		if lat, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(lat)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// hasDigit проверяет наличие цифры в слове
+func hasDigit(token string) bool {
+	return strings.IndexFunc(token, unicode.IsDigit) >= 0
+}
+
+// toSet возвращает множество строк
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// roundSimilarity округляет сходство до тысячных
+func roundSimilarity(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
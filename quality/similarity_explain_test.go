@@ -0,0 +1,65 @@
+package quality
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplainSimilarity(t *testing.T) {
+	tests := []struct {
+		name            string
+		a, b            string
+		wantShared      []string
+		wantTranslit    []TokenMatch
+		wantAgreement   float64
+		wantConflicting []string
+	}{
+		{
+			name:          "latin homoglyphs in size",
+			a:             "Болт M8x40",
+			b:             "болт М8х40",
+			wantShared:    []string{"болт"},
+			wantTranslit:  []TokenMatch{{A: "m8x40", B: "м8х40", Kind: "homoglyph"}},
+			wantAgreement: 1,
+		},
+		{
+			name:          "transliterated word",
+			a:             "Bolt оцинкованный",
+			b:             "Болт оцинкованный",
+			wantShared:    []string{"оцинкованный"},
+			wantTranslit:  []TokenMatch{{A: "bolt", B: "болт", Kind: "transliteration"}},
+			wantAgreement: 1,
+		},
+		{
+			name:            "conflicting attributes",
+			a:               "Молоток 500г",
+			b:               "Молоток 300г",
+			wantShared:      []string{"молоток"},
+			wantTranslit:    []TokenMatch{},
+			wantAgreement:   0,
+			wantConflicting: []string{"500г", "300г"},
+		},
+	}
+
+	fm := NewFuzzyMatcher(nil, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExplainSimilarity(tt.a, tt.b, 0)
+			if want := roundSimilarity(fm.calculateSimilarity(tt.a, tt.b)); got.Score != want || got.Matched != (fm.calculateSimilarity(tt.a, tt.b) >= DefaultFuzzyThreshold) {
+				t.Errorf("Score = %v matched %v, want matcher score %v", got.Score, got.Matched, want)
+			}
+			if !reflect.DeepEqual(got.TokenOverlap.Shared, tt.wantShared) {
+				t.Errorf("Shared = %v, want %v", got.TokenOverlap.Shared, tt.wantShared)
+			}
+			if !reflect.DeepEqual(got.Transliteration, tt.wantTranslit) {
+				t.Errorf("Transliteration = %+v, want %+v", got.Transliteration, tt.wantTranslit)
+			}
+			if got.AttributeAgreement.Agreement != tt.wantAgreement {
+				t.Errorf("Agreement = %v, want %v", got.AttributeAgreement.Agreement, tt.wantAgreement)
+			}
+			if len(tt.wantConflicting) > 0 && !reflect.DeepEqual(got.AttributeAgreement.Conflicting, tt.wantConflicting) {
+				t.Errorf("Conflicting = %v, want %v", got.AttributeAgreement.Conflicting, tt.wantConflicting)
+			}
+		})
+	}
+}
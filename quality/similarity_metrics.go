@@ -0,0 +1,316 @@
+package quality
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"httpserver/database"
+)
+
+// Встроенные метрики сходства наименований
+const (
+	MetricLevenshtein     = "levenshtein"      // Доля совпадения по расстоянию Левенштейна
+	MetricJaroWinkler     = "jaro_winkler"     // Джаро-Винклер: устойчив к опечаткам, ценит общий префикс
+	MetricTokenSetRatio   = "token_set_ratio"  // Сравнение множеств слов: не зависит от порядка и повторов слов
+	MetricEmbeddingCosine = "embedding_cosine" // Косинус векторных представлений наименований
+)
+
+// SimilarityMetric стратегия расчета сходства двух наименований, приведенных matcherNormalize.
+// Возвращает значение от 0 (разные) до 1 (совпадают)
+type SimilarityMetric interface {
+	Name() string
+	Similarity(a, b string) float64
+}
+
+// Embedder строит векторное представление наименования для метрики embedding_cosine
+type Embedder func(text string) []float64
+
+var (
+	similarityMetricsMu sync.RWMutex
+	similarityMetrics   = map[string]SimilarityMetric{
+		MetricLevenshtein:     levenshteinMetric{},
+		MetricJaroWinkler:     jaroWinklerMetric{},
+		MetricTokenSetRatio:   tokenSetRatioMetric{},
+		MetricEmbeddingCosine: NewEmbeddingCosineMetric(NgramEmbedder(3, 256)),
+	}
+)
+
+// RegisterSimilarityMetric добавляет метрику или заменяет встроенную с тем же именем,
+// например embedding_cosine с векторами внешней модели вместо n-грамм
+func RegisterSimilarityMetric(metric SimilarityMetric) {
+	similarityMetricsMu.Lock()
+	defer similarityMetricsMu.Unlock()
+	similarityMetrics[metric.Name()] = metric
+}
+
+// LookupSimilarityMetric возвращает метрику по имени
+func LookupSimilarityMetric(name string) (SimilarityMetric, bool) {
+	similarityMetricsMu.RLock()
+	defer similarityMetricsMu.RUnlock()
+	metric, ok := similarityMetrics[name]
+	return metric, ok
+}
+
+// SimilarityMetricNames возвращает имена доступных метрик по алфавиту
+func SimilarityMetricNames() []string {
+	similarityMetricsMu.RLock()
+	defer similarityMetricsMu.RUnlock()
+	names := make([]string, 0, len(similarityMetrics))
+	for name := range similarityMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultSimilarityConfig настройка по умолчанию: только расстояние Левенштейна с порогом DefaultFuzzyThreshold,
+// как у сопоставителя до появления выбора метрик
+func DefaultSimilarityConfig() database.SimilarityConfig {
+	return database.SimilarityConfig{
+		Metrics:   []database.SimilarityMetricWeight{{Metric: MetricLevenshtein, Weight: 1}},
+		Threshold: DefaultFuzzyThreshold,
+	}
+}
+
+// ValidateSimilarityConfig проверяет, что метрики известны, не повторяются и имеют положительный вес,
+// а порог лежит в (0, 1]
+func ValidateSimilarityConfig(config database.SimilarityConfig) error {
+	if len(config.Metrics) == 0 {
+		return fmt.Errorf("at least one similarity metric is required")
+	}
+	if config.Threshold <= 0 || config.Threshold > 1 {
+		return fmt.Errorf("threshold must be within (0, 1]")
+	}
+	seen := make(map[string]bool)
+	for _, weighted := range config.Metrics {
+		if _, ok := LookupSimilarityMetric(weighted.Metric); !ok {
+			return fmt.Errorf("unknown similarity metric %q: expected one of %s", weighted.Metric, strings.Join(SimilarityMetricNames(), ", "))
+		}
+		if seen[weighted.Metric] {
+			return fmt.Errorf("similarity metric %q is listed twice", weighted.Metric)
+		}
+		seen[weighted.Metric] = true
+		if weighted.Weight <= 0 || math.IsInf(weighted.Weight, 0) || math.IsNaN(weighted.Weight) {
+			return fmt.Errorf("weight of similarity metric %q must be positive", weighted.Metric)
+		}
+	}
+	return nil
+}
+
+// SimilarityScorer рассчитывает итоговое сходство как взвешенное среднее метрик настройки
+type SimilarityScorer struct {
+	metrics   []SimilarityMetric
+	weights   []float64
+	total     float64
+	threshold float64
+}
+
+// NewSimilarityScorer создает оценщик сходства по настройке проекта
+func NewSimilarityScorer(config database.SimilarityConfig) (*SimilarityScorer, error) {
+	if err := ValidateSimilarityConfig(config); err != nil {
+		return nil, err
+	}
+	scorer := &SimilarityScorer{threshold: config.Threshold}
+	for _, weighted := range config.Metrics {
+		metric, _ := LookupSimilarityMetric(weighted.Metric)
+		scorer.metrics = append(scorer.metrics, metric)
+		scorer.weights = append(scorer.weights, weighted.Weight)
+		scorer.total += weighted.Weight
+	}
+	return scorer, nil
+}
+
+// Threshold возвращает порог, начиная с которого пара считается дубликатом
+func (sc *SimilarityScorer) Threshold() float64 {
+	return sc.threshold
+}
+
+// Score рассчитывает сходство наименований, приведенных matcherNormalize
+func (sc *SimilarityScorer) Score(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	var score float64
+	for i, metric := range sc.metrics {
+		score += metric.Similarity(a, b) * sc.weights[i]
+	}
+	return score / sc.total
+}
+
+// levenshteinMetric сходство по расстоянию Левенштейна относительно длины более длинной строки
+type levenshteinMetric struct{}
+
+func (levenshteinMetric) Name() string { return MetricLevenshtein }
+
+func (levenshteinMetric) Similarity(a, b string) float64 {
+	similarity, _, _ := normalizedEditSimilarity(a, b)
+	return similarity
+}
+
+// jaroWinklerMetric сходство Джаро-Винклера с бонусом за общий префикс до 4 символов
+type jaroWinklerMetric struct{}
+
+func (jaroWinklerMetric) Name() string { return MetricJaroWinkler }
+
+func (jaroWinklerMetric) Similarity(a, b string) float64 {
+	r1, r2 := []rune(a), []rune(b)
+	jaro := jaroSimilarity(r1, r2)
+	prefix := 0
+	for prefix < min(min(len(r1), len(r2)), 4) && r1[prefix] == r2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// jaroSimilarity рассчитывает сходство Джаро
+func jaroSimilarity(r1, r2 []rune) float64 {
+	if len(r1) == 0 && len(r2) == 0 {
+		return 1
+	}
+	if len(r1) == 0 || len(r2) == 0 {
+		return 0
+	}
+	window := max(len(r1), len(r2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matched1 := make([]bool, len(r1))
+	matched2 := make([]bool, len(r2))
+	matches := 0
+	for i := range r1 {
+		for j := max(0, i-window); j < min(len(r2), i+window+1); j++ {
+			if !matched2[j] && r1[i] == r2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range r1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if r1[i] != r2[j] {
+			transpositions++
+		}
+		j++
+	}
+	m := float64(matches)
+	return (m/float64(len(r1)) + m/float64(len(r2)) + (m-float64(transpositions)/2)/m) / 3
+}
+
+// tokenSetRatioMetric сравнивает общие слова с каждым из наименований и берет лучшее сходство:
+// наименования из одних и тех же слов в другом порядке или с добавленными словами получают высокую оценку
+type tokenSetRatioMetric struct{}
+
+func (tokenSetRatioMetric) Name() string { return MetricTokenSetRatio }
+
+func (tokenSetRatioMetric) Similarity(a, b string) float64 {
+	tokensA, tokensB := splitTokens(a), splitTokens(b)
+	setB := toSet(tokensB)
+	setA := toSet(tokensA)
+	var shared, onlyA, onlyB []string
+	for _, token := range tokensA {
+		if setB[token] {
+			shared = append(shared, token)
+		} else {
+			onlyA = append(onlyA, token)
+		}
+	}
+	for _, token := range tokensB {
+		if !setA[token] {
+			onlyB = append(onlyB, token)
+		}
+	}
+	sort.Strings(shared)
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+
+	base := strings.Join(shared, " ")
+	withA := strings.TrimSpace(base + " " + strings.Join(onlyA, " "))
+	withB := strings.TrimSpace(base + " " + strings.Join(onlyB, " "))
+	ratio := func(s1, s2 string) float64 {
+		similarity, _, _ := normalizedEditSimilarity(s1, s2)
+		return similarity
+	}
+	best := ratio(withA, withB)
+	if base != "" {
+		best = math.Max(best, math.Max(ratio(base, withA), ratio(base, withB)))
+	}
+	return best
+}
+
+// embeddingCosineMetric косинусное сходство векторных представлений наименований
+type embeddingCosineMetric struct {
+	embed Embedder
+}
+
+// NewEmbeddingCosineMetric создает метрику embedding_cosine с заданным построителем векторов
+func NewEmbeddingCosineMetric(embed Embedder) SimilarityMetric {
+	return embeddingCosineMetric{embed: embed}
+}
+
+func (embeddingCosineMetric) Name() string { return MetricEmbeddingCosine }
+
+func (m embeddingCosineMetric) Similarity(a, b string) float64 {
+	va, vb := m.embed(a), m.embed(b)
+	if len(va) != len(vb) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range va {
+		dot += va[i] * vb[i]
+		normA += va[i] * va[i]
+		normB += vb[i] * vb[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, dot/math.Sqrt(normA*normB)))
+}
+
+// NgramEmbedder строит вектор из символьных n-грамм, хешированных в dims измерений.
+// Не требует внешней модели и используется для embedding_cosine по умолчанию
+func NgramEmbedder(n, dims int) Embedder {
+	return func(text string) []float64 {
+		vector := make([]float64, dims)
+		runes := []rune(" " + text + " ")
+		for i := 0; i+n <= len(runes); i++ {
+			hash := fnv.New32a()
+			hash.Write([]byte(string(runes[i : i+n])))
+			vector[hash.Sum32()%uint32(dims)]++
+		}
+		return vector
+	}
+}
+
+// splitTokens разбивает нормализованное наименование на слова без повторов
+func splitTokens(normalized string) []string {
+	fields := strings.FieldsFunc(normalized, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != ',' && r != '.' && r != '/'
+	})
+	tokens := []string{}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		token := strings.Trim(field, ",./")
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
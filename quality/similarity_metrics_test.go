@@ -0,0 +1,148 @@
+package quality
+
+import (
+	"math"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestSimilarityMetrics(t *testing.T) {
+	tests := []struct {
+		metric string
+		a, b   string
+		want   float64
+	}{
+		{MetricLevenshtein, "болт м10", "болт м10", 1},
+		{MetricLevenshtein, "bolt", "bolt m10", 0.5},
+		{MetricJaroWinkler, "martha", "marhta", 0.961},
+		{MetricJaroWinkler, "болт", "гайка", 0},
+		{MetricTokenSetRatio, "болт м10 оцинкованный", "оцинкованный болт м10", 1},
+		{MetricTokenSetRatio, "болт м10", "болт м10 din 933", 1},
+		{MetricEmbeddingCosine, "кабель ввг", "кабель ввг", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric+" "+tt.a+" / "+tt.b, func(t *testing.T) {
+			metric, ok := LookupSimilarityMetric(tt.metric)
+			if !ok {
+				t.Fatalf("LookupSimilarityMetric(%q) not found", tt.metric)
+			}
+			if got := roundSimilarity(metric.Similarity(tt.a, tt.b)); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("%s.Similarity() = %v, want %v", tt.metric, got, tt.want)
+			}
+		})
+	}
+
+	embedding, _ := LookupSimilarityMetric(MetricEmbeddingCosine)
+	if close, far := embedding.Similarity("кабель ввгнг 3x2.5", "кабель ввг 3x2.5"), embedding.Similarity("кабель ввгнг 3x2.5", "краска белая"); close <= far {
+		t.Errorf("embedding_cosine: similar names %.3f should score above different names %.3f", close, far)
+	}
+}
+
+func TestValidateSimilarityConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  database.SimilarityConfig
+		wantErr bool
+	}{
+		{"default", DefaultSimilarityConfig(), false},
+		{"weighted mix", database.SimilarityConfig{Threshold: 0.8, Metrics: []database.SimilarityMetricWeight{{Metric: MetricJaroWinkler, Weight: 2}, {Metric: MetricTokenSetRatio, Weight: 1}}}, false},
+		{"no metrics", database.SimilarityConfig{Threshold: 0.8}, true},
+		{"unknown metric", database.SimilarityConfig{Threshold: 0.8, Metrics: []database.SimilarityMetricWeight{{Metric: "soundex", Weight: 1}}}, true},
+		{"duplicate metric", database.SimilarityConfig{Threshold: 0.8, Metrics: []database.SimilarityMetricWeight{{Metric: MetricLevenshtein, Weight: 1}, {Metric: MetricLevenshtein, Weight: 1}}}, true},
+		{"zero weight", database.SimilarityConfig{Threshold: 0.8, Metrics: []database.SimilarityMetricWeight{{Metric: MetricLevenshtein, Weight: 0}}}, true},
+		{"threshold above one", database.SimilarityConfig{Threshold: 1.5, Metrics: []database.SimilarityMetricWeight{{Metric: MetricLevenshtein, Weight: 1}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateSimilarityConfig(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSimilarityConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSimilarityScorerWeights(t *testing.T) {
+	a, b := matcherNormalize("Болт М10 оцинкованный"), matcherNormalize("оцинкованный болт м10")
+
+	// Настройка по умолчанию дает ту же оценку, что и сопоставитель до выбора метрик
+	fm := NewFuzzyMatcher(nil, 0)
+	similarity, _, _ := normalizedEditSimilarity(a, b)
+	if got := fm.scorer.Score(a, b); got != similarity {
+		t.Errorf("default Score() = %v, want levenshtein %v", got, similarity)
+	}
+
+	levenshtein, _ := LookupSimilarityMetric(MetricLevenshtein)
+	tokenSet, _ := LookupSimilarityMetric(MetricTokenSetRatio)
+	scorer, err := NewSimilarityScorer(database.SimilarityConfig{Threshold: 0.8, Metrics: []database.SimilarityMetricWeight{
+		{Metric: MetricLevenshtein, Weight: 1},
+		{Metric: MetricTokenSetRatio, Weight: 3},
+	}})
+	if err != nil {
+		t.Fatalf("NewSimilarityScorer() error = %v", err)
+	}
+	want := (levenshtein.Similarity(a, b) + 3*tokenSet.Similarity(a, b)) / 4
+	if got := scorer.Score(a, b); math.Abs(got-want) > 1e-9 {
+		t.Errorf("weighted Score() = %v, want %v", got, want)
+	}
+
+	// Перестановка слов: дубликат для token_set_ratio, но не для расстояния Левенштейна
+	matcher, err := NewFuzzyMatcherWithConfig(nil, database.SimilarityConfig{Threshold: 0.9, Metrics: []database.SimilarityMetricWeight{{Metric: MetricTokenSetRatio, Weight: 1}}})
+	if err != nil {
+		t.Fatalf("NewFuzzyMatcherWithConfig() error = %v", err)
+	}
+	items := []DuplicateItem{{Reference: "1", Name: "Болт М10 оцинкованный"}, {Reference: "2", Name: "оцинкованный болт м10"}}
+	if groups := matcher.findDuplicates(items); len(groups) != 1 {
+		t.Errorf("token_set_ratio matcher groups = %d, want 1", len(groups))
+	}
+	if groups := NewFuzzyMatcher(nil, 0.9).findDuplicates(items); len(groups) != 0 {
+		t.Errorf("levenshtein matcher groups = %d, want 0", len(groups))
+	}
+}
+
+func TestCalibrateSimilarity(t *testing.T) {
+	labels := []database.DuplicatePairLabel{
+		{NameA: "Болт М10 оцинкованный", NameB: "оцинкованный болт М10", IsDuplicate: true},
+		{NameA: "Кабель ВВГнг 3х2,5", NameB: "ВВГнг кабель 3х2,5", IsDuplicate: true},
+		{NameA: "Молоток слесарный", NameB: "Молоток слесарный.", IsDuplicate: true},
+		{NameA: "Болт М10", NameB: "Гайка М10", IsDuplicate: false},
+		{NameA: "Краска белая", NameB: "Краска черная", IsDuplicate: false},
+	}
+
+	if _, err := CalibrateSimilarity(nil, DefaultSimilarityConfig()); err == nil {
+		t.Errorf("CalibrateSimilarity() without pairs: want error")
+	}
+
+	calibration, err := CalibrateSimilarity(labels, DefaultSimilarityConfig())
+	if err != nil {
+		t.Fatalf("CalibrateSimilarity() error = %v", err)
+	}
+	if calibration.Pairs != 5 || calibration.Duplicates != 3 || calibration.Distinct != 2 {
+		t.Errorf("counts = %d/%d/%d, want 5/3/2", calibration.Pairs, calibration.Duplicates, calibration.Distinct)
+	}
+	if len(calibration.Metrics) != len(SimilarityMetricNames())+1 {
+		t.Fatalf("metrics = %d, want every metric and combined", len(calibration.Metrics))
+	}
+
+	results := make(map[string]MetricCalibration)
+	for _, metric := range calibration.Metrics {
+		results[metric.Metric] = metric
+		if metric.BestF1 < metric.F1 {
+			t.Errorf("%s: best F1 %.3f below F1 at configured threshold %.3f", metric.Metric, metric.BestF1, metric.F1)
+		}
+	}
+	tokenSet := results[MetricTokenSetRatio]
+	if tokenSet.BestF1 != 1 || tokenSet.MeanDuplicate <= tokenSet.MeanDistinct {
+		t.Errorf("token_set_ratio = %+v, want perfect separation", tokenSet)
+	}
+	// Порог 0.85 отсекает перестановки слов, поэтому полнота Левенштейна ниже единицы
+	if levenshtein := results[MetricLevenshtein]; levenshtein.Recall >= 1 {
+		t.Errorf("levenshtein recall = %v, want reordered names missed at default threshold", levenshtein.Recall)
+	}
+	if combined := results[CombinedMetric]; combined.F1 != results[MetricLevenshtein].F1 || len(combined.Weights) != 1 {
+		t.Errorf("combined = %+v, want default config to match levenshtein", combined)
+	}
+	if results[calibration.Recommended].BestF1 != 1 {
+		t.Errorf("recommended %s has best F1 %.3f", calibration.Recommended, results[calibration.Recommended].BestF1)
+	}
+}
@@ -0,0 +1,329 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	natsMaxReconnectDelay = 30 * time.Second // Максимальная пауза между попытками переподключения
+	natsPendingLimit      = 1024             // Сообщения, прочитанные, но еще не обработанные
+)
+
+// NATSConsumer потребитель NATS по текстовому протоколу клиента.
+// Доставка "хотя бы один раз" обеспечивается push-потребителем JetStream с явным
+// подтверждением (ack policy explicit), у которого deliver subject совпадает с Subject:
+// сообщение подтверждается (+ACK) только после обработки. Сообщения core NATS без
+// ответного адреса подтверждать некуда, для них гарантируется лишь доставка "не более одного раза".
+type NATSConsumer struct {
+	config    Config
+	address   string
+	useTLS    bool
+	user      string
+	password  string
+	token     string
+	connected atomic.Bool
+}
+
+// NewNATSConsumer создает потребителя NATS
+func NewNATSConsumer(config Config) (*NATSConsumer, error) {
+	if config.URL == "" {
+		config.URL = "nats://localhost:4222"
+	}
+	if config.Subject == "" {
+		return nil, errors.New("queue subject is required")
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 5 * time.Second
+	}
+	parsed, err := url.Parse(config.URL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid NATS url: %s", config.URL)
+	}
+
+	c := &NATSConsumer{config: config, address: parsed.Host}
+	switch parsed.Scheme {
+	case "nats":
+	case "tls":
+		c.useTLS = true
+	default:
+		return nil, fmt.Errorf("unsupported NATS url scheme: %s", parsed.Scheme)
+	}
+	if parsed.Port() == "" {
+		c.address = net.JoinHostPort(parsed.Hostname(), "4222")
+	}
+	if parsed.User != nil {
+		if password, ok := parsed.User.Password(); ok {
+			c.user, c.password = parsed.User.Username(), password
+		} else {
+			c.token = parsed.User.Username()
+		}
+	}
+	return c, nil
+}
+
+// Backend возвращает тип брокера
+func (c *NATSConsumer) Backend() string {
+	return BackendNATS
+}
+
+// Connected сообщает, установлено ли соединение с сервером NATS
+func (c *NATSConsumer) Connected() bool {
+	return c.connected.Load()
+}
+
+// Run получает сообщения до отмены ctx, переподключаясь при обрывах соединения
+func (c *NATSConsumer) Run(ctx context.Context, handler Handler) error {
+	delay := time.Second
+	for {
+		started := time.Now()
+		err := c.session(ctx, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(started) > natsMaxReconnectDelay {
+			delay = time.Second
+		}
+		log.Printf("NATS consumer disconnected: %v, reconnecting in %v", err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+		if delay *= 2; delay > natsMaxReconnectDelay {
+			delay = natsMaxReconnectDelay
+		}
+	}
+}
+
+// natsConn соединение с сервером NATS; запись защищена мьютексом
+type natsConn struct {
+	conn net.Conn
+	mu   sync.Mutex
+	w    *bufio.Writer
+}
+
+// send записывает команду протокола и сбрасывает буфер
+func (n *natsConn) send(format string, args ...interface{}) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fmt.Fprintf(n.w, format, args...)
+	return n.w.Flush()
+}
+
+// publish публикует сообщение (используется для подтверждений JetStream)
+func (n *natsConn) publish(subject string, data []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fmt.Fprintf(n.w, "PUB %s %d\r\n", subject, len(data))
+	n.w.Write(data)
+	n.w.WriteString("\r\n")
+	return n.w.Flush()
+}
+
+// natsDelivery сообщение вместе с адресом подтверждения
+type natsDelivery struct {
+	msg   *Message
+	reply string
+}
+
+// session устанавливает соединение, подписывается на тему и обрабатывает сообщения до обрыва
+func (c *NATSConsumer) session(ctx context.Context, handler Handler) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		host, _, _ := net.SplitHostPort(c.address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.address)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer c.connected.Store(false)
+
+	n := &natsConn{conn: conn, w: bufio.NewWriter(conn)}
+	r := bufio.NewReader(conn)
+	line, err := readNATSLine(r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting: %s", line)
+	}
+
+	connect, _ := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       "httpserver-ingest",
+		"lang":       "go",
+		"version":    "1.0",
+		"protocol":   1,
+		"headers":    true,
+		"user":       c.user,
+		"pass":       c.password,
+		"auth_token": c.token,
+	})
+	subject := c.config.Subject
+	if c.config.Group != "" {
+		subject += " " + c.config.Group
+	}
+	if err := n.send("CONNECT %s\r\nSUB %s 1\r\nPING\r\n", connect, subject); err != nil {
+		return err
+	}
+
+	// Закрываем соединение при отмене контекста, чтобы прервать чтение
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	// Чтение идет отдельно от обработки, чтобы сервер получал PONG во время долгой обработки пакета
+	deliveries := make(chan natsDelivery, natsPendingLimit)
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- c.readLoop(r, n, deliveries, stop)
+	}()
+
+	for {
+		select {
+		case err := <-readErr:
+			return err
+		case d := <-deliveries:
+			if err := c.dispatch(ctx, n, handler, d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// dispatch передает сообщение обработчику и подтверждает его по результату
+func (c *NATSConsumer) dispatch(ctx context.Context, n *natsConn, handler Handler, d natsDelivery) error {
+	err := handler(ctx, d.msg)
+	if !strings.HasPrefix(d.reply, "$JS.ACK.") {
+		return nil
+	}
+	switch {
+	case err == nil:
+		return n.publish(d.reply, []byte("+ACK"))
+	case IsPermanent(err):
+		return n.publish(d.reply, []byte("+TERM"))
+	default:
+		return n.publish(d.reply, []byte(fmt.Sprintf(`-NAK {"delay": %d}`, c.config.RetryDelay.Nanoseconds())))
+	}
+}
+
+// readLoop разбирает входящие команды протокола
+func (c *NATSConsumer) readLoop(r *bufio.Reader, n *natsConn, deliveries chan<- natsDelivery, stop <-chan struct{}) error {
+	for {
+		line, err := readNATSLine(r)
+		if err != nil {
+			return err
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "PING":
+			if err := n.send("PONG\r\n"); err != nil {
+				return err
+			}
+		case "PONG":
+			c.connected.Store(true)
+		case "+OK", "INFO":
+		case "-ERR":
+			return fmt.Errorf("NATS error: %s", strings.Trim(args, "' "))
+		case "MSG", "HMSG":
+			d, err := readNATSMessage(r, strings.ToUpper(op) == "HMSG", strings.Fields(args))
+			if err != nil {
+				return err
+			}
+			if d.msg == nil {
+				// Служебное сообщение JetStream: на запрос управления потоком отвечаем пустым сообщением
+				if d.reply != "" {
+					if err := n.publish(d.reply, nil); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			select {
+			case deliveries <- d:
+			case <-stop:
+				return nil
+			}
+		default:
+			return fmt.Errorf("unexpected NATS operation: %s", op)
+		}
+	}
+}
+
+// readNATSMessage читает тело MSG/HMSG. Для служебных сообщений JetStream (heartbeat,
+// flow control) возвращает доставку без сообщения.
+func readNATSMessage(r *bufio.Reader, withHeaders bool, fields []string) (natsDelivery, error) {
+	var d natsDelivery
+	minFields := 3
+	if withHeaders {
+		minFields = 4
+	}
+	if len(fields) < minFields || len(fields) > minFields+1 {
+		return d, fmt.Errorf("malformed NATS message header: %v", fields)
+	}
+	if len(fields) == minFields+1 {
+		d.reply = fields[2]
+	}
+	total, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || total < 0 {
+		return d, fmt.Errorf("malformed NATS message size: %v", fields)
+	}
+	headerSize := 0
+	if withHeaders {
+		if headerSize, err = strconv.Atoi(fields[len(fields)-2]); err != nil || headerSize > total {
+			return d, fmt.Errorf("malformed NATS header size: %v", fields)
+		}
+	}
+
+	payload := make([]byte, total+2)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return d, err
+	}
+	headers, data := string(payload[:headerSize]), payload[headerSize:total]
+	if withHeaders && strings.HasPrefix(headers, "NATS/1.0 100") {
+		return d, nil
+	}
+
+	d.msg = &Message{Subject: fields[0], Data: data}
+	// $JS.ACK.<stream>.<consumer>.<delivered>.<stream seq>.<consumer seq>.<timestamp>.<pending>
+	if tokens := strings.Split(d.reply, "."); len(tokens) >= 9 && tokens[0] == "$JS" {
+		delivered, _ := strconv.Atoi(tokens[4])
+		d.msg.Redelivery = delivered > 1
+	}
+	return d, nil
+}
+
+// readNATSLine читает строку протокола без завершающего \r\n
+func readNATSLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
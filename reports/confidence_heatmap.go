@@ -0,0 +1,185 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"httpserver/database"
+)
+
+// UnknownHeatmapCatalog справочник записей, исходный элемент которых не найден в catalog_items
+const UnknownHeatmapCatalog = "(unknown)"
+
+// ConfidenceHeatmapCell ячейка тепловой карты: записи раздела КПВЭД из одного исходного справочника
+type ConfidenceHeatmapCell struct {
+	Section            string  `json:"section"`
+	Catalog            string  `json:"catalog"`
+	Items              int     `json:"items"`
+	AvgConfidence      float64 `json:"avg_confidence"`
+	MedianConfidence   float64 `json:"median_confidence"`
+	LowConfidenceItems int     `json:"low_confidence_items"` // Ниже порога автоматического принятия
+}
+
+// ConfidenceHeatmapAxis строка или столбец тепловой карты с итогами
+type ConfidenceHeatmapAxis struct {
+	Code             string  `json:"code"`
+	Name             string  `json:"name,omitempty"`
+	Items            int     `json:"items"`
+	AvgConfidence    float64 `json:"avg_confidence"`
+	MedianConfidence float64 `json:"median_confidence"`
+}
+
+// ConfidenceHeatmap уверенность классификации по разделам КПВЭД (строки) и исходным справочникам (столбцы).
+// Cells содержит только непустые ячейки
+type ConfidenceHeatmap struct {
+	GeneratedAt   time.Time               `json:"generated_at"`
+	LowConfidence float64                 `json:"low_confidence"`
+	TotalItems    int                     `json:"total_items"` // Классифицированные записи
+	AvgConfidence float64                 `json:"avg_confidence"`
+	Sections      []ConfidenceHeatmapAxis `json:"sections"`
+	Catalogs      []ConfidenceHeatmapAxis `json:"catalogs"`
+	Cells         []ConfidenceHeatmapCell `json:"cells"`
+}
+
+// BuildConfidenceHeatmap строит тепловую карту уверенности по всем классифицированным записям
+// normalized_data базы data; classifier - база с классификатором КПВЭД (может быть nil)
+func BuildConfidenceHeatmap(data, classifier *sql.DB) (*ConfidenceHeatmap, error) {
+	return buildConfidenceHeatmap(data, classifier, "1=1", nil)
+}
+
+// buildConfidenceHeatmap строит тепловую карту по записям, удовлетворяющим условию where.
+// Справочник записи определяется по source_reference через catalog_items; записи читаются
+// упорядоченными по уверенности, чтобы медианы считались без сортировки в памяти
+func buildConfidenceHeatmap(data, classifier *sql.DB, where string, args []interface{}) (*ConfidenceHeatmap, error) {
+	names, err := loadKpvedClassifierNames(classifier)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := data.Query(`
+		SELECT CASE WHEN INSTR(kpved_code, '.') = 0 AND LENGTH(kpved_code) = 1 THEN kpved_code ELSE SUBSTR(kpved_code, 1, 2) END,
+		       COALESCE((
+		           SELECT c.name FROM catalog_items ci JOIN catalogs c ON c.id = ci.catalog_id
+		           WHERE ci.reference = normalized_data.source_reference
+		           ORDER BY ci.id DESC LIMIT 1
+		       ), ''),
+		       COALESCE(kpved_confidence, 0)
+		FROM normalized_data
+		WHERE kpved_code IS NOT NULL AND TRIM(kpved_code) != '' AND `+where+`
+		ORDER BY COALESCE(kpved_confidence, 0)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load confidence heatmap data: %w", err)
+	}
+	defer rows.Close()
+
+	heatmap := &ConfidenceHeatmap{
+		GeneratedAt:   time.Now(),
+		LowConfidence: database.DefaultAcceptThreshold,
+		Sections:      []ConfidenceHeatmapAxis{},
+		Catalogs:      []ConfidenceHeatmapAxis{},
+		Cells:         []ConfidenceHeatmapCell{},
+	}
+	type cellKey struct{ section, catalog string }
+	cells := map[cellKey][]float64{}
+	sections := map[string][]float64{}
+	catalogs := map[string][]float64{}
+	var confidenceSum float64
+	for rows.Next() {
+		var class, catalog string
+		var confidence float64
+		if err := rows.Scan(&class, &catalog, &confidence); err != nil {
+			return nil, fmt.Errorf("failed to scan confidence heatmap row: %w", err)
+		}
+		if catalog == "" {
+			catalog = UnknownHeatmapCatalog
+		}
+		section := names.section(class)
+		key := cellKey{section, catalog}
+		cells[key] = append(cells[key], confidence)
+		sections[section] = append(sections[section], confidence)
+		catalogs[catalog] = append(catalogs[catalog], confidence)
+		heatmap.TotalItems++
+		confidenceSum += confidence
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate confidence heatmap rows: %w", err)
+	}
+	if heatmap.TotalItems > 0 {
+		heatmap.AvgConfidence = round2(confidenceSum / float64(heatmap.TotalItems))
+	}
+
+	for key, values := range cells {
+		avg, median := confidenceAvgMedian(values)
+		low := sort.SearchFloat64s(values, heatmap.LowConfidence)
+		heatmap.Cells = append(heatmap.Cells, ConfidenceHeatmapCell{
+			Section: key.section, Catalog: key.catalog, Items: len(values),
+			AvgConfidence: avg, MedianConfidence: median, LowConfidenceItems: low,
+		})
+	}
+	sort.Slice(heatmap.Cells, func(i, j int) bool {
+		if heatmap.Cells[i].Section != heatmap.Cells[j].Section {
+			return heatmap.Cells[i].Section < heatmap.Cells[j].Section
+		}
+		return heatmap.Cells[i].Catalog < heatmap.Cells[j].Catalog
+	})
+	for code, values := range sections {
+		avg, median := confidenceAvgMedian(values)
+		heatmap.Sections = append(heatmap.Sections, ConfidenceHeatmapAxis{
+			Code: code, Name: names.names[code], Items: len(values), AvgConfidence: avg, MedianConfidence: median,
+		})
+	}
+	sort.Slice(heatmap.Sections, func(i, j int) bool { return heatmap.Sections[i].Code < heatmap.Sections[j].Code })
+	for code, values := range catalogs {
+		avg, median := confidenceAvgMedian(values)
+		heatmap.Catalogs = append(heatmap.Catalogs, ConfidenceHeatmapAxis{
+			Code: code, Items: len(values), AvgConfidence: avg, MedianConfidence: median,
+		})
+	}
+	sort.Slice(heatmap.Catalogs, func(i, j int) bool { return heatmap.Catalogs[i].Code < heatmap.Catalogs[j].Code })
+	return heatmap, nil
+}
+
+// Cell возвращает ячейку раздела и справочника (nil - записей нет)
+func (h *ConfidenceHeatmap) Cell(section, catalog string) *ConfidenceHeatmapCell {
+	i := sort.Search(len(h.Cells), func(i int) bool {
+		c := h.Cells[i]
+		return c.Section > section || (c.Section == section && c.Catalog >= catalog)
+	})
+	if i < len(h.Cells) && h.Cells[i].Section == section && h.Cells[i].Catalog == catalog {
+		return &h.Cells[i]
+	}
+	return nil
+}
+
+// confidenceAvgMedian среднее и медиана упорядоченных по возрастанию значений уверенности
+func confidenceAvgMedian(sorted []float64) (avg, median float64) {
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mid := len(sorted) / 2
+	median = sorted[mid]
+	if len(sorted)%2 == 0 {
+		median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return round2(sum / float64(len(sorted))), round2(median)
+}
+
+// ConfidenceHeatmapTable ячейки тепловой карты таблицей для выгрузки в CSV/TSV
+func ConfidenceHeatmapTable(heatmap *ConfidenceHeatmap) *Table {
+	sectionNames := map[string]string{}
+	for _, section := range heatmap.Sections {
+		sectionNames[section.Code] = section.Name
+	}
+	table := NewTable("section", "section_name", "catalog", "items", "avg_confidence", "median_confidence", "low_confidence_items")
+	for _, c := range heatmap.Cells {
+		table.Add(c.Section, sectionNames[c.Section], c.Catalog, c.Items, c.AvgConfidence, c.MedianConfidence, c.LowConfidenceItems)
+	}
+	return table
+}
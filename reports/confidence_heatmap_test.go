@@ -0,0 +1,112 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestBuildConfidenceHeatmap(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+		INSERT INTO uploads (upload_uuid, version_1c, config_name) VALUES ('heatmap-1', '8.3', 'УТ'), ('heatmap-2', '8.3', 'УТ');
+		INSERT INTO catalogs (upload_id, name, synonym) VALUES (1, 'Номенклатура', ''), (2, 'Материалы', '');
+		INSERT INTO catalog_items (catalog_id, reference, code, name) VALUES
+			(1, 'ref-1', '1', 'Болт М8'), (1, 'ref-2', '2', 'Гайка М8'), (1, 'ref-3', '3', 'Молоко'), (2, 'ref-4', '4', 'Лист');
+		INSERT INTO normalized_data (code, source_reference, source_name, normalized_name, category, kpved_code, kpved_confidence) VALUES
+			('1', 'ref-1', 'Болт М8', 'болт м8', 'крепеж', '25.94.11', 0.95),
+			('2', 'ref-2', 'Гайка М8', 'гайка м8', 'крепеж', '25.94.12', 0.6),
+			('3', 'ref-3', 'Молоко', 'молоко', 'продукты', '10.51.11', 0.9),
+			('4', 'ref-4', 'Лист', 'лист', 'металл', '25.11', 0.7),
+			('5', 'ref-5', 'Уголь', 'уголь', 'сырье', '05.10', 0.8),
+			('6', 'ref-6', 'Кабель', 'кабель', 'электрика', NULL, 0)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to seed heatmap data: %v", err)
+	}
+
+	heatmap, err := BuildConfidenceHeatmap(db.GetDB(), nil)
+	if err != nil {
+		t.Fatalf("BuildConfidenceHeatmap() error = %v", err)
+	}
+	if heatmap.TotalItems != 5 || heatmap.AvgConfidence != 0.79 {
+		t.Errorf("totals = %d items, %v avg, want 5 items, 0.79 avg", heatmap.TotalItems, heatmap.AvgConfidence)
+	}
+	if got := fmt.Sprint(heatmap.Cells); got != "[{B (unknown) 1 0.8 0.8 1} {C Материалы 1 0.7 0.7 1} {C Номенклатура 3 0.82 0.9 1}]" {
+		t.Errorf("cells = %s", got)
+	}
+	if got := fmt.Sprint(heatmap.Sections); got != "[{B  1 0.8 0.8} {C  4 0.79 0.8}]" {
+		t.Errorf("sections = %s", got)
+	}
+
+	tests := []struct {
+		section, catalog string
+		wantItems        int
+	}{
+		{"C", "Номенклатура", 3},
+		{"B", UnknownHeatmapCatalog, 1},
+		{"B", "Номенклатура", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section+"/"+tt.catalog, func(t *testing.T) {
+			cell := heatmap.Cell(tt.section, tt.catalog)
+			if (cell == nil) != (tt.wantItems == 0) || (cell != nil && cell.Items != tt.wantItems) {
+				t.Errorf("Cell() = %+v, want %d items", cell, tt.wantItems)
+			}
+		})
+	}
+
+	// Встроенный отчет по разделам КПВЭД выводит тепловую карту
+	engine := NewEngine("", nil)
+	tpl, err := engine.Lookup("kpved")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := engine.Render(&buf, tpl, &ReportContext{Stats: NewProvider(db.GetDB(), nil)}); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{"Уверенность по разделам и справочникам", "<th>Номенклатура</th>", `class="low">0.70 / 0.70 (1)`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+
+	// Тепловая карта выгрузки учитывает только записи ее справочников
+	scoped, err := NewProvider(db.GetDB(), nil).ForUpload(2).ConfidenceHeatmap()
+	if err != nil {
+		t.Fatalf("ConfidenceHeatmap() error = %v", err)
+	}
+	if got := fmt.Sprint(scoped.Cells); got != "[{C Материалы 1 0.7 0.7 1}]" {
+		t.Errorf("upload cells = %s", got)
+	}
+}
+
+func TestConfidenceAvgMedian(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []float64
+		wantAvg    float64
+		wantMedian float64
+	}{
+		{"empty", nil, 0, 0},
+		{"odd", []float64{0.5, 0.7, 0.9}, 0.7, 0.7},
+		{"even", []float64{0.2, 0.4, 0.8, 1}, 0.6, 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avg, median := confidenceAvgMedian(tt.values)
+			if avg != tt.wantAvg || median != tt.wantMedian {
+				t.Errorf("confidenceAvgMedian() = %v, %v, want %v, %v", avg, median, tt.wantAvg, tt.wantMedian)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package reports
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField допустимый диапазон поля cron-выражения
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// cronMacros сокращенные расписания
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// CronSchedule разобранное cron-выражение из пяти полей: минута, час, день месяца, месяц, день недели
+type CronSchedule struct {
+	fields [5]map[int]bool
+	// Как в cron: если ограничены и день месяца, и день недели, достаточно совпадения любого из них
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// ParseCron разбирает cron-выражение; поддерживаются *, списки, диапазоны, шаги и макросы @daily и др.
+func ParseCron(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d", len(parts))
+	}
+
+	schedule := &CronSchedule{
+		anyDayOfMonth: parts[2] == "*",
+		anyDayOfWeek:  parts[4] == "*",
+	}
+	for i, part := range parts {
+		values, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		schedule.fields[i] = values
+	}
+	return schedule, nil
+}
+
+// parseCronField разбирает одно поле cron-выражения
+func parseCronField(part string, field cronField) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, item := range strings.Split(part, ",") {
+		step := 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(item[idx+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %s field: %s", field.name, item)
+			}
+			item = item[:idx]
+		}
+
+		from, to := field.min, field.max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value in %s field: %s", field.name, item)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid range in %s field: %s", field.name, item)
+				}
+			} else if step > 1 {
+				to = field.max
+			}
+		}
+		// Воскресенье допускается и как 7
+		if field.name == "day of week" && to == 7 {
+			values[0] = true
+			if from == 7 {
+				continue
+			}
+			to = 6
+		}
+		if from < field.min || to > field.max || from > to {
+			return nil, fmt.Errorf("%s field out of range %d-%d: %s", field.name, field.min, field.max, item)
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// dayMatches проверяет день месяца и день недели
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dayOfMonth := c.fields[2][t.Day()]
+	dayOfWeek := c.fields[4][int(t.Weekday())]
+	switch {
+	case c.anyDayOfMonth && c.anyDayOfWeek:
+		return true
+	case c.anyDayOfMonth:
+		return dayOfWeek
+	case c.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
+
+// Next возвращает ближайшее время запуска строго после after (нулевое время, если его нет в течение 5 лет)
+func (c *CronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !c.fields[3][int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.fields[1][t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.fields[0][t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
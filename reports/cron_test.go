@@ -0,0 +1,48 @@
+package reports
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	after := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC) // Понедельник
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 8 * * 0", time.Date(2024, 1, 21, 8, 0, 0, 0, time.UTC)},
+		{"0 8 * * 7", time.Date(2024, 1, 21, 8, 0, 0, 0, time.UTC)},
+		{"30 6 1 * *", time.Date(2024, 2, 1, 6, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// День месяца или день недели: 20-е число либо ближайшая среда
+		{"0 12 20 * 3", time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC)},
+		{"15,45 10 * * *", time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron() error = %v", err)
+			}
+			if got := schedule.Next(after); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) must fail", expr)
+		}
+	}
+
+	schedule, _ := ParseCron("0 0 30 2 *")
+	if got := schedule.Next(after); !got.IsZero() {
+		t.Errorf("Next() for impossible date = %v, want zero", got)
+	}
+}
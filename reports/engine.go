@@ -0,0 +1,256 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/i18n"
+)
+
+// Источники шаблонов отчетов
+const (
+	TemplateSourceBuiltin  = "builtin"
+	TemplateSourceFile     = "file"
+	TemplateSourceDatabase = "database"
+)
+
+// templateFileExt расширение файлов шаблонов в каталоге на диске
+const templateFileExt = ".html"
+
+var templateNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+var (
+	// ErrTemplateNotFound шаблон не зарегистрирован ни в одном источнике
+	ErrTemplateNotFound = errors.New("report template not found")
+	// ErrInvalidTemplateName недопустимое имя шаблона
+	ErrInvalidTemplateName = errors.New("template name must contain only letters, digits, '-' and '_' (up to 64 characters)")
+)
+
+// Branding оформление отчета для клиента
+type Branding struct {
+	ClientName   string `json:"client_name,omitempty"`
+	LogoURL      string `json:"logo_url,omitempty"`
+	PrimaryColor string `json:"primary_color,omitempty"`
+	Footer       string `json:"footer,omitempty"`
+}
+
+// Color возвращает основной цвет оформления
+func (b Branding) Color() string {
+	if b.PrimaryColor == "" {
+		return DefaultPrimaryColor
+	}
+	return b.PrimaryColor
+}
+
+// merge заполняет пустые поля значениями по умолчанию из defaults
+func (b *Branding) merge(defaults Branding) {
+	if b.ClientName == "" {
+		b.ClientName = defaults.ClientName
+	}
+	if b.LogoURL == "" {
+		b.LogoURL = defaults.LogoURL
+	}
+	if b.PrimaryColor == "" {
+		b.PrimaryColor = defaults.PrimaryColor
+	}
+	if b.Footer == "" {
+		b.Footer = defaults.Footer
+	}
+}
+
+// ReportContext данные, передаваемые в шаблон отчета
+type ReportContext struct {
+	Title       string
+	GeneratedAt time.Time
+	Database    string // Имя файла базы данных
+	Upload      string // UUID выгрузки, если отчет построен по выгрузке
+	Lang        string // Язык подписей отчета (по умолчанию i18n.Default)
+	Branding    Branding
+	Stats       *Provider
+}
+
+// TemplateInfo описание зарегистрированного шаблона
+type TemplateInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source"`
+	ClientID    int    `json:"client_id,omitempty"`
+}
+
+// Template разобранный шаблон отчета
+type Template struct {
+	TemplateInfo
+	Title    string   // Заголовок по умолчанию (для встроенных шаблонов - ключ каталога сообщений)
+	Branding Branding // Оформление, сохраненное вместе с шаблоном
+
+	tmpl *template.Template
+}
+
+// TemplateStore хранилище пользовательских шаблонов (сервисная БД)
+type TemplateStore interface {
+	GetReportTemplate(name string) (*database.ReportTemplate, error)
+	GetReportTemplates() ([]*database.ReportTemplate, error)
+}
+
+// Engine движок отчетов: ищет шаблон в сервисной БД, затем в каталоге на диске, затем среди встроенных
+type Engine struct {
+	dir   string
+	store TemplateStore
+}
+
+// NewEngine создает движок отчетов; dir и store необязательны
+func NewEngine(dir string, store TemplateStore) *Engine {
+	return &Engine{dir: dir, store: store}
+}
+
+// ValidateTemplateName проверяет имя шаблона
+func ValidateTemplateName(name string) error {
+	if !templateNameRegex.MatchString(name) {
+		return ErrInvalidTemplateName
+	}
+	return nil
+}
+
+// ParseTemplate разбирает текст шаблона вместе с общими фрагментами
+func ParseTemplate(name, content string) (*template.Template, error) {
+	base, err := baseTemplates.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone base templates: %w", err)
+	}
+	tmpl, err := base.New(name).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Lookup находит и разбирает шаблон по имени
+func (e *Engine) Lookup(name string) (*Template, error) {
+	if err := ValidateTemplateName(name); err != nil {
+		return nil, err
+	}
+
+	if e.store != nil {
+		stored, err := e.store.GetReportTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			return e.parse(TemplateInfo{
+				Name:        stored.Name,
+				Description: stored.Description,
+				Source:      TemplateSourceDatabase,
+				ClientID:    stored.ClientID,
+			}, stored.Content, Branding{
+				LogoURL:      stored.LogoURL,
+				PrimaryColor: stored.PrimaryColor,
+				Footer:       stored.Footer,
+			})
+		}
+	}
+
+	if e.dir != "" {
+		content, err := os.ReadFile(filepath.Join(e.dir, name+templateFileExt))
+		if err == nil {
+			return e.parse(TemplateInfo{Name: name, Source: TemplateSourceFile}, string(content), Branding{})
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+	}
+
+	if builtin, ok := builtinTemplates[name]; ok {
+		tpl, err := e.parse(TemplateInfo{Name: name, Description: builtin.description, Source: TemplateSourceBuiltin}, builtin.content, Branding{})
+		if err != nil {
+			return nil, err
+		}
+		tpl.Title = builtin.title
+		return tpl, nil
+	}
+
+	return nil, ErrTemplateNotFound
+}
+
+// parse разбирает шаблон из любого источника
+func (e *Engine) parse(info TemplateInfo, content string, branding Branding) (*Template, error) {
+	tmpl, err := ParseTemplate(info.Name, content)
+	if err != nil {
+		return nil, err
+	}
+	title := info.Description
+	if title == "" {
+		title = info.Name
+	}
+	return &Template{TemplateInfo: info, Title: title, Branding: branding, tmpl: tmpl}, nil
+}
+
+// Templates возвращает все доступные шаблоны; при совпадении имен показывается шаблон с наивысшим приоритетом
+func (e *Engine) Templates() ([]TemplateInfo, error) {
+	byName := map[string]TemplateInfo{}
+	for name, builtin := range builtinTemplates {
+		byName[name] = TemplateInfo{Name: name, Description: builtin.description, Source: TemplateSourceBuiltin}
+	}
+
+	if e.dir != "" {
+		entries, err := os.ReadDir(e.dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read templates directory: %w", err)
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), templateFileExt)
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), templateFileExt) || ValidateTemplateName(name) != nil {
+				continue
+			}
+			byName[name] = TemplateInfo{Name: name, Source: TemplateSourceFile}
+		}
+	}
+
+	if e.store != nil {
+		stored, err := e.store.GetReportTemplates()
+		if err != nil {
+			return nil, err
+		}
+		for _, tpl := range stored {
+			byName[tpl.Name] = TemplateInfo{Name: tpl.Name, Description: tpl.Description, Source: TemplateSourceDatabase, ClientID: tpl.ClientID}
+		}
+	}
+
+	templates := make([]TemplateInfo, 0, len(byName))
+	for _, info := range byName {
+		templates = append(templates, info)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// Render выполняет шаблон на языке ctx.Lang; незаданные заголовок, время и оформление берутся из шаблона
+func (e *Engine) Render(w io.Writer, tpl *Template, ctx *ReportContext) error {
+	if ctx.Lang = i18n.Normalize(ctx.Lang); ctx.Lang == "" {
+		ctx.Lang = i18n.Default
+	}
+	if ctx.Title == "" {
+		ctx.Title = i18n.T(ctx.Lang, tpl.Title)
+	}
+	if ctx.GeneratedAt.IsZero() {
+		ctx.GeneratedAt = time.Now()
+	}
+	ctx.Branding.merge(tpl.Branding)
+
+	tmpl, err := localize(tpl.tmpl, ctx.Lang)
+	if err != nil {
+		return fmt.Errorf("failed to prepare report %s: %w", tpl.Name, err)
+	}
+	if err := tmpl.Execute(w, ctx); err != nil {
+		return fmt.Errorf("failed to render report %s: %w", tpl.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package reports
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestProviderSummary(t *testing.T) {
+	db := newKpvedReportTestDB(t)
+	defer db.Close()
+
+	provider := NewProvider(db.GetDB(), nil)
+	summary, err := provider.Summary()
+	if err != nil {
+		t.Fatalf("Summary() error = %v", err)
+	}
+	if summary.NormalizedCount != 5 || summary.KpvedCount != 4 || summary.KpvedPercent != 80 ||
+		summary.UniqueCategories != 4 || summary.ChangedCount != 5 || summary.MergedCount != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	categories, err := provider.TopCategories(1)
+	if err != nil {
+		t.Fatalf("TopCategories() error = %v", err)
+	}
+	if len(categories) != 1 || categories[0].Name != "крепеж" || categories[0].Count != 2 || categories[0].Percent != 40 {
+		t.Errorf("unexpected top categories: %+v", categories)
+	}
+
+	// Выгрузка без элементов справочников не содержит нормализованных записей
+	scoped, err := provider.ForUpload(42).Summary()
+	if err != nil {
+		t.Fatalf("Summary() for upload error = %v", err)
+	}
+	if scoped.NormalizedCount != 0 || scoped.TotalItems != 0 {
+		t.Errorf("unexpected upload summary: %+v", scoped)
+	}
+}
+
+func TestEngineTemplates(t *testing.T) {
+	db := newKpvedReportTestDB(t)
+	defer db.Close()
+
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+
+	dir := t.TempDir()
+	custom := `<h1>{{.Branding.ClientName}}: {{.Title}}</h1>{{with .Stats.Summary}}<p>{{.NormalizedCount}}</p>{{end}}{{template "report_footer" .}}`
+	if err := os.WriteFile(filepath.Join(dir, "custom.html"), []byte(custom), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if _, err := serviceDB.CreateReportTemplate(&database.ReportTemplate{
+		Name:         "kpved",
+		Description:  "Брендированный КПВЭД",
+		Content:      `<style>th { background: {{.Branding.Color}}; }</style>{{template "kpved_content" .Stats.Kpved}}{{template "report_footer" .}}`,
+		PrimaryColor: "#ff0000",
+		Footer:       "ООО Ромашка",
+	}); err != nil {
+		t.Fatalf("CreateReportTemplate() error = %v", err)
+	}
+
+	engine := NewEngine(dir, serviceDB)
+	templates, err := engine.Templates()
+	if err != nil {
+		t.Fatalf("Templates() error = %v", err)
+	}
+	sources := map[string]string{}
+	for _, info := range templates {
+		sources[info.Name] = info.Source
+	}
+	want := map[string]string{"custom": TemplateSourceFile, "kpved": TemplateSourceDatabase, "normalization": TemplateSourceBuiltin, "quality": TemplateSourceBuiltin}
+	if len(sources) != len(want) {
+		t.Errorf("unexpected templates: %+v", templates)
+	}
+	for name, source := range want {
+		if sources[name] != source {
+			t.Errorf("template %s source = %s, want %s", name, sources[name], source)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		lang     string
+		branding Branding
+		contains []string
+	}{
+		{"normalization", "", Branding{ClientName: "Клиент"}, []string{"Отчет о нормализации и классификации", "Клиент", "болт м8", DefaultPrimaryColor}},
+		{"custom", "", Branding{ClientName: "Клиент", Footer: "Подвал"}, []string{"<h1>Клиент: custom</h1>", "<p>5</p>", "Подвал"}},
+		{"kpved", "", Branding{}, []string{"#ff0000", "ООО Ромашка", "25.94.11"}},
+		{"quality", "", Branding{}, []string{"Отчет о качестве нормализованных данных", "accept", "Открытых нарушений нет"}},
+		{"quality", "en", Branding{}, []string{"Normalized data quality report", `<html lang="en">`, "No open violations"}},
+		{"normalization", "kk", Branding{}, []string{"Қалыпқа келтіру және жіктеу туралы есеп", "Үздік 20 санат"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := engine.Lookup(tt.name)
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			var buf bytes.Buffer
+			ctx := &ReportContext{Lang: tt.lang, Branding: tt.branding, Stats: NewProvider(db.GetDB(), nil)}
+			if err := engine.Render(&buf, tpl, ctx); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("report does not contain %q", s)
+				}
+			}
+		})
+	}
+
+	if _, err := engine.Lookup("missing"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Lookup(missing) error = %v, want ErrTemplateNotFound", err)
+	}
+	if _, err := engine.Lookup("../secret"); !errors.Is(err, ErrInvalidTemplateName) {
+		t.Errorf("Lookup(../secret) error = %v, want ErrInvalidTemplateName", err)
+	}
+	if _, err := ParseTemplate("broken", "{{.Title"); err == nil {
+		t.Error("ParseTemplate() must fail on invalid syntax")
+	}
+}
+
+func TestProviderInspectionTables(t *testing.T) {
+	db := newKpvedReportTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO uploads (upload_uuid, version_1c, config_name) VALUES ('inspection', '8.3', 'УТ');
+		INSERT INTO catalogs (upload_id, name, synonym) VALUES (1, 'Номенклатура', 'Номенклатура');
+		INSERT INTO catalog_items (catalog_id, reference, code, name, normalized_name, category_original, category_level1, category_level2, classification_strategy, classification_confidence) VALUES
+			(1, 'ref-1', '1', 'Болт М8', 'болт м8', '["Крепеж","Болты"]', 'Крепеж', 'Болты', 'keyword', 0.9),
+			(1, 'ref-2', '2', 'Гайка М8', 'гайка м8', '', 'Крепеж', '', 'keyword', 0.7),
+			(1, 'ref-3', '3', 'Кабель', NULL, '', NULL, NULL, NULL, 0)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to seed catalog_items: %v", err)
+	}
+
+	provider := NewProvider(db.GetDB(), nil)
+	tests := []struct {
+		dataset   string
+		wantRows  int
+		wantFirst []interface{}
+	}{
+		{DatasetKpvedCodes, 2, []interface{}{"05.10", "Уголь каменный", 1}},
+		{DatasetClassified, 2, []interface{}{1, "1", "Болт М8", "Крепеж / Болты", "Крепеж", "Болты", "keyword", 0.9}},
+		{DatasetClassificationCategories, 1, []interface{}{"Крепеж", 2, 100.0}},
+		{DatasetNormalizedNames, 2, []interface{}{1, "1", "Болт М8", "болт м8", "Крепеж", "Болты"}},
+		{DatasetNormalized, 2, nil},
+		{DatasetSummary, 14, []interface{}{"total_items", 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dataset, func(t *testing.T) {
+			table, err := provider.InspectionTable(tt.dataset, 2)
+			if err != nil {
+				t.Fatalf("InspectionTable() error = %v", err)
+			}
+			if len(table.Rows) != tt.wantRows {
+				t.Fatalf("rows = %d, want %d: %v", len(table.Rows), tt.wantRows, table.Rows)
+			}
+			if tt.wantFirst != nil && fmt.Sprint(table.Rows[0]) != fmt.Sprint(tt.wantFirst) {
+				t.Errorf("first row = %v, want %v", table.Rows[0], tt.wantFirst)
+			}
+		})
+	}
+
+	if _, err := provider.InspectionTable("unknown", 10); err == nil {
+		t.Error("InspectionTable(unknown) error = nil")
+	}
+}
@@ -0,0 +1,382 @@
+package reports
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Наборы данных инспекций для утилит show_* и /api/reports/inspection. Состав колонок
+// каждого набора постоянный, чтобы выгрузки можно было обрабатывать скриптами и таблицами
+const (
+	DatasetSummary                  = "summary"                   // Общая статистика: метрика и значение
+	DatasetNormalized               = "normalized"                // Нормализованные записи
+	DatasetNormalizedNames          = "normalized-names"          // Элементы справочников с измененным наименованием
+	DatasetCategories               = "categories"                // Категории нормализованных записей
+	DatasetExamples                 = "examples"                  // Примеры нормализации по категориям
+	DatasetKpvedCodes               = "kpved-codes"               // Самые частые коды КПВЭД
+	DatasetClassified               = "classified"                // Классифицированные элементы справочников
+	DatasetClassificationCategories = "classification-categories" // Категории первого уровня элементов справочников
+	DatasetConfidenceHeatmap        = "confidence-heatmap"        // Уверенность по разделам КПВЭД и справочникам
+)
+
+// InspectionDatasets наборы данных инспекций в порядке вывода в справке
+var InspectionDatasets = []string{
+	DatasetSummary, DatasetNormalized, DatasetNormalizedNames, DatasetCategories, DatasetExamples,
+	DatasetKpvedCodes, DatasetClassified, DatasetClassificationCategories, DatasetConfidenceHeatmap,
+}
+
+// IsInspectionDataset проверяет, известен ли набор данных
+func IsInspectionDataset(dataset string) bool {
+	for _, known := range InspectionDatasets {
+		if dataset == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizedRecord нормализованная запись
+type NormalizedRecord struct {
+	ID                  int       `json:"id"`
+	SourceReference     string    `json:"source_reference"`
+	SourceName          string    `json:"source_name"`
+	Code                string    `json:"code"`
+	NormalizedName      string    `json:"normalized_name"`
+	NormalizedReference string    `json:"normalized_reference"`
+	Category            string    `json:"category"`
+	MergedCount         int       `json:"merged_count"`
+	AIConfidence        float64   `json:"ai_confidence"`
+	ProcessingLevel     string    `json:"processing_level"`
+	KpvedCode           string    `json:"kpved_code"`
+	KpvedName           string    `json:"kpved_name"`
+	KpvedConfidence     float64   `json:"kpved_confidence"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// NormalizedName элемент справочника, наименование которого изменено нормализацией
+type NormalizedName struct {
+	ID             int    `json:"id"`
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	NormalizedName string `json:"normalized_name"`
+	CategoryLevel1 string `json:"category_level1"`
+	CategoryLevel2 string `json:"category_level2"`
+}
+
+// ClassifiedItem классифицированный элемент справочника
+type ClassifiedItem struct {
+	ID             int     `json:"id"`
+	Code           string  `json:"code"`
+	Name           string  `json:"name"`
+	CategoryPath   string  `json:"category_path"` // Полный путь категории через " / "
+	CategoryLevel1 string  `json:"category_level1"`
+	CategoryLevel2 string  `json:"category_level2"`
+	Strategy       string  `json:"strategy"`
+	Confidence     float64 `json:"confidence"`
+}
+
+// CatalogClassificationSummary статистика классификации элементов справочников
+type CatalogClassificationSummary struct {
+	TotalItems        int     `json:"total_items"`
+	ClassifiedCount   int     `json:"classified_count"`
+	ClassifiedPercent float64 `json:"classified_percent"`
+	AvgConfidence     float64 `json:"avg_confidence"`
+	NormalizedNames   int     `json:"normalized_names"` // Элементы с заполненным нормализованным наименованием
+}
+
+// KpvedCodeStat число записей с кодом КПВЭД
+type KpvedCodeStat struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NormalizedRecords возвращает нормализованные записи по порядку ID
+func (p *Provider) NormalizedRecords(limit int) ([]NormalizedRecord, error) {
+	where, _, args := p.scope()
+	rows, err := p.data.Query(`
+		SELECT id, COALESCE(source_reference, ''), COALESCE(source_name, ''), COALESCE(code, ''),
+		       COALESCE(normalized_name, ''), COALESCE(normalized_reference, ''), COALESCE(category, ''),
+		       COALESCE(merged_count, 0), COALESCE(ai_confidence, 0), COALESCE(processing_level, ''),
+		       COALESCE(kpved_code, ''), COALESCE(kpved_name, ''), COALESCE(kpved_confidence, 0), created_at
+		FROM normalized_data
+		WHERE `+where+`
+		ORDER BY id
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get normalized records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []NormalizedRecord{}
+	for rows.Next() {
+		var record NormalizedRecord
+		var createdAt sql.NullTime
+		if err := rows.Scan(&record.ID, &record.SourceReference, &record.SourceName, &record.Code,
+			&record.NormalizedName, &record.NormalizedReference, &record.Category, &record.MergedCount,
+			&record.AIConfidence, &record.ProcessingLevel, &record.KpvedCode, &record.KpvedName,
+			&record.KpvedConfidence, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan normalized record: %w", err)
+		}
+		record.CreatedAt = createdAt.Time
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// NormalizedNames возвращает элементы справочников, наименование которых изменено нормализацией
+func (p *Provider) NormalizedNames(limit int) ([]NormalizedName, error) {
+	_, itemsWhere, args := p.scope()
+	rows, err := p.data.Query(`
+		SELECT id, COALESCE(code, ''), COALESCE(name, ''), normalized_name,
+		       COALESCE(category_level1, ''), COALESCE(category_level2, '')
+		FROM catalog_items
+		WHERE normalized_name IS NOT NULL AND normalized_name != '' AND normalized_name != name AND `+itemsWhere+`
+		ORDER BY id
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get normalized names: %w", err)
+	}
+	defer rows.Close()
+
+	names := []NormalizedName{}
+	for rows.Next() {
+		var name NormalizedName
+		if err := rows.Scan(&name.ID, &name.Code, &name.Name, &name.NormalizedName, &name.CategoryLevel1, &name.CategoryLevel2); err != nil {
+			return nil, fmt.Errorf("failed to scan normalized name: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// categoryPath представляет путь категории из category_original (JSON массив) строкой через " / "
+func categoryPath(original string) string {
+	var path []string
+	if err := json.Unmarshal([]byte(original), &path); err != nil {
+		return original
+	}
+	return strings.Join(path, " / ")
+}
+
+// ClassifiedItems возвращает классифицированные элементы справочников
+func (p *Provider) ClassifiedItems(limit int) ([]ClassifiedItem, error) {
+	_, itemsWhere, args := p.scope()
+	rows, err := p.data.Query(`
+		SELECT id, COALESCE(code, ''), COALESCE(name, ''), COALESCE(category_original, ''),
+		       category_level1, COALESCE(category_level2, ''), COALESCE(classification_strategy, ''),
+		       COALESCE(classification_confidence, 0)
+		FROM catalog_items
+		WHERE category_level1 IS NOT NULL AND category_level1 != '' AND `+itemsWhere+`
+		ORDER BY id
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classified items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []ClassifiedItem{}
+	for rows.Next() {
+		var item ClassifiedItem
+		if err := rows.Scan(&item.ID, &item.Code, &item.Name, &item.CategoryPath, &item.CategoryLevel1,
+			&item.CategoryLevel2, &item.Strategy, &item.Confidence); err != nil {
+			return nil, fmt.Errorf("failed to scan classified item: %w", err)
+		}
+		item.CategoryPath = categoryPath(item.CategoryPath)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// CatalogClassification возвращает статистику классификации элементов справочников
+func (p *Provider) CatalogClassification() (*CatalogClassificationSummary, error) {
+	_, itemsWhere, args := p.scope()
+	summary := &CatalogClassificationSummary{}
+	err := p.data.QueryRow(`
+		SELECT COUNT(*),
+		       COALESCE(SUM(CASE WHEN category_level1 IS NOT NULL AND category_level1 != '' THEN 1 ELSE 0 END), 0),
+		       COALESCE(AVG(CASE WHEN classification_confidence > 0 THEN classification_confidence END), 0),
+		       COALESCE(SUM(CASE WHEN normalized_name IS NOT NULL AND normalized_name != '' THEN 1 ELSE 0 END), 0)
+		FROM catalog_items
+		WHERE `+itemsWhere, args...).Scan(&summary.TotalItems, &summary.ClassifiedCount, &summary.AvgConfidence, &summary.NormalizedNames)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog classification summary: %w", err)
+	}
+	summary.ClassifiedPercent = round2(percent(summary.ClassifiedCount, summary.TotalItems))
+	return summary, nil
+}
+
+// ClassificationCategories возвращает самые крупные категории первого уровня элементов справочников
+func (p *Provider) ClassificationCategories(limit int) ([]CategoryStat, error) {
+	summary, err := p.CatalogClassification()
+	if err != nil {
+		return nil, err
+	}
+
+	_, itemsWhere, args := p.scope()
+	rows, err := p.data.Query(`
+		SELECT category_level1, COUNT(*) AS count
+		FROM catalog_items
+		WHERE category_level1 IS NOT NULL AND category_level1 != '' AND `+itemsWhere+`
+		GROUP BY category_level1
+		ORDER BY count DESC, category_level1
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get classification categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []CategoryStat{}
+	for rows.Next() {
+		var category CategoryStat
+		if err := rows.Scan(&category.Name, &category.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		category.Percent = round2(percent(category.Count, summary.ClassifiedCount))
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// TopKpvedCodes возвращает самые частые коды КПВЭД нормализованных записей
+func (p *Provider) TopKpvedCodes(limit int) ([]KpvedCodeStat, error) {
+	where, _, args := p.scope()
+	rows, err := p.data.Query(`
+		SELECT kpved_code, COALESCE(kpved_name, ''), COUNT(*) AS count
+		FROM normalized_data
+		WHERE kpved_code IS NOT NULL AND kpved_code != '' AND `+where+`
+		GROUP BY kpved_code, kpved_name
+		ORDER BY count DESC, kpved_code
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top KPVED codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []KpvedCodeStat{}
+	for rows.Next() {
+		var code KpvedCodeStat
+		if err := rows.Scan(&code.Code, &code.Name, &code.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan KPVED code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+// InspectionTable возвращает набор данных инспекции таблицей; limit ограничивает число строк
+// списков (для summary не используется)
+func (p *Provider) InspectionTable(dataset string, limit int) (*Table, error) {
+	switch dataset {
+	case DatasetSummary:
+		summary, err := p.Summary()
+		if err != nil {
+			return nil, err
+		}
+		catalog, err := p.CatalogClassification()
+		if err != nil {
+			return nil, err
+		}
+		table := NewTable("metric", "value")
+		table.Add("total_items", summary.TotalItems)
+		table.Add("normalized_count", summary.NormalizedCount)
+		table.Add("normalized_percent", summary.NormalizedPercent)
+		table.Add("changed_count", summary.ChangedCount)
+		table.Add("changed_percent", summary.ChangedPercent)
+		table.Add("merged_count", summary.MergedCount)
+		table.Add("unique_categories", summary.UniqueCategories)
+		table.Add("avg_ai_confidence", summary.AvgAIConfidence)
+		table.Add("kpved_count", summary.KpvedCount)
+		table.Add("kpved_percent", summary.KpvedPercent)
+		table.Add("classified_items", catalog.ClassifiedCount)
+		table.Add("classified_percent", catalog.ClassifiedPercent)
+		table.Add("avg_classification_confidence", catalog.AvgConfidence)
+		table.Add("normalized_names", catalog.NormalizedNames)
+		return table, nil
+	case DatasetNormalized:
+		records, err := p.NormalizedRecords(limit)
+		if err != nil {
+			return nil, err
+		}
+		table := NewTable("id", "source_reference", "source_name", "code", "normalized_name", "normalized_reference",
+			"category", "merged_count", "ai_confidence", "processing_level", "kpved_code", "kpved_name",
+			"kpved_confidence", "created_at")
+		for _, r := range records {
+			table.Add(r.ID, r.SourceReference, r.SourceName, r.Code, r.NormalizedName, r.NormalizedReference,
+				r.Category, r.MergedCount, r.AIConfidence, r.ProcessingLevel, r.KpvedCode, r.KpvedName,
+				r.KpvedConfidence, r.CreatedAt)
+		}
+		return table, nil
+	case DatasetNormalizedNames:
+		names, err := p.NormalizedNames(limit)
+		if err != nil {
+			return nil, err
+		}
+		table := NewTable("id", "code", "name", "normalized_name", "category_level1", "category_level2")
+		for _, n := range names {
+			table.Add(n.ID, n.Code, n.Name, n.NormalizedName, n.CategoryLevel1, n.CategoryLevel2)
+		}
+		return table, nil
+	case DatasetCategories, DatasetClassificationCategories:
+		var categories []CategoryStat
+		var err error
+		if dataset == DatasetCategories {
+			categories, err = p.TopCategories(limit)
+		} else {
+			categories, err = p.ClassificationCategories(limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		table := NewTable("category", "count", "percent")
+		for _, c := range categories {
+			table.Add(c.Name, c.Count, c.Percent)
+		}
+		return table, nil
+	case DatasetExamples:
+		examples, err := p.Examples(limit)
+		if err != nil {
+			return nil, err
+		}
+		table := NewTable("category", "source_name", "normalized_name", "code")
+		for _, e := range examples {
+			table.Add(e.Category, e.Source, e.Normalized, e.Code)
+		}
+		return table, nil
+	case DatasetKpvedCodes:
+		codes, err := p.TopKpvedCodes(limit)
+		if err != nil {
+			return nil, err
+		}
+		table := NewTable("kpved_code", "kpved_name", "count")
+		for _, c := range codes {
+			table.Add(c.Code, c.Name, c.Count)
+		}
+		return table, nil
+	case DatasetClassified:
+		items, err := p.ClassifiedItems(limit)
+		if err != nil {
+			return nil, err
+		}
+		table := NewTable("id", "code", "name", "category_path", "category_level1", "category_level2", "strategy", "confidence")
+		for _, i := range items {
+			table.Add(i.ID, i.Code, i.Name, i.CategoryPath, i.CategoryLevel1, i.CategoryLevel2, i.Strategy, i.Confidence)
+		}
+		return table, nil
+	case DatasetConfidenceHeatmap:
+		heatmap, err := p.ConfidenceHeatmap()
+		if err != nil {
+			return nil, err
+		}
+		return ConfidenceHeatmapTable(heatmap), nil
+	}
+	return nil, fmt.Errorf("unknown inspection dataset %q", dataset)
+}
@@ -0,0 +1,411 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+	"httpserver/i18n"
+)
+
+// DefaultKpvedExamplesPerClass число примеров на класс КПВЭД по умолчанию
+const DefaultKpvedExamplesPerClass = 5
+
+// kpvedSectionRanges разделы КПВЭД по диапазонам двузначных классов
+// (используются, если классификатор не загружен)
+var kpvedSectionRanges = []struct {
+	section  string
+	from, to int
+}{
+	{"A", 1, 3}, {"B", 5, 9}, {"C", 10, 33}, {"D", 35, 35}, {"E", 36, 39},
+	{"F", 41, 43}, {"G", 45, 47}, {"H", 49, 53}, {"I", 55, 56}, {"J", 58, 63},
+	{"K", 64, 66}, {"L", 68, 68}, {"M", 69, 75}, {"N", 77, 82}, {"O", 84, 84},
+	{"P", 85, 85}, {"Q", 86, 88}, {"R", 90, 93}, {"S", 94, 96}, {"T", 97, 98},
+	{"U", 99, 99},
+}
+
+// KpvedReportExample пример классифицированной записи
+type KpvedReportExample struct {
+	ID                  int     `json:"id"`
+	SourceName          string  `json:"source_name"`
+	NormalizedName      string  `json:"normalized_name"`
+	KpvedCode           string  `json:"kpved_code"`
+	KpvedName           string  `json:"kpved_name"`
+	KpvedNameTranslated string  `json:"kpved_name_translated,omitempty"` // Наименование кода на втором языке отчета
+	Confidence          float64 `json:"confidence"`
+	MergedCount         int     `json:"merged_count"`
+}
+
+// KpvedClassStats статистика класса КПВЭД (двузначный код)
+type KpvedClassStats struct {
+	Code               string               `json:"code"`
+	Name               string               `json:"name"`
+	NameTranslated     string               `json:"name_translated,omitempty"` // Наименование на втором языке отчета
+	Items              int                  `json:"items"`
+	MergedItems        int                  `json:"merged_items"` // Сумма merged_count - число исходных записей
+	Percent            float64              `json:"percent"`      // Доля от всех нормализованных записей
+	SectionPercent     float64              `json:"section_percent"`
+	AvgConfidence      float64              `json:"avg_confidence"`
+	LowConfidenceItems int                  `json:"low_confidence_items"` // Ниже порога автоматического принятия
+	DistinctCodes      int                  `json:"distinct_codes"`
+	Examples           []KpvedReportExample `json:"examples"`
+}
+
+// KpvedSectionStats статистика раздела КПВЭД с подытогами по классам
+type KpvedSectionStats struct {
+	Code               string            `json:"code"`
+	Name               string            `json:"name"`
+	NameTranslated     string            `json:"name_translated,omitempty"`
+	Items              int               `json:"items"`
+	MergedItems        int               `json:"merged_items"`
+	Percent            float64           `json:"percent"`
+	AvgConfidence      float64           `json:"avg_confidence"`
+	LowConfidenceItems int               `json:"low_confidence_items"`
+	Classes            []KpvedClassStats `json:"classes"`
+}
+
+// KpvedReport отчет о классификации, сгруппированный по разделам и классам КПВЭД
+type KpvedReport struct {
+	GeneratedAt        time.Time           `json:"generated_at"`
+	Database           string              `json:"database,omitempty"`
+	TotalItems         int                 `json:"total_items"`
+	ClassifiedItems    int                 `json:"classified_items"`
+	Coverage           float64             `json:"coverage"` // Процент классифицированных записей
+	AvgConfidence      float64             `json:"avg_confidence"`
+	LowConfidenceItems int                 `json:"low_confidence_items"`
+	Sections           []KpvedSectionStats `json:"sections"`
+	Unclassified       int                 `json:"unclassified"`
+	// Второй язык наименований разделов и классов; коды без перевода выводятся с исходным наименованием
+	SecondLanguage      string   `json:"second_language,omitempty"`
+	MissingTranslations []string `json:"missing_translations,omitempty"`
+}
+
+// KpvedReportOptions параметры построения отчета
+type KpvedReportOptions struct {
+	Database         string  // Имя базы данных для заголовка отчета
+	ExamplesPerClass int     // 0 - DefaultKpvedExamplesPerClass, отрицательное - без примеров
+	LowConfidence    float64 // 0 - database.DefaultAcceptThreshold
+	SecondLanguage   string  // Язык перевода наименований из category_name_translations ("" - без перевода)
+}
+
+// kpvedClassifierNames коды и наименования разделов и классов из классификатора
+type kpvedClassifierNames struct {
+	names   map[string]string
+	parents map[string]string
+}
+
+// loadKpvedClassifierNames загружает разделы и классы классификатора (nil db - пустой справочник)
+func loadKpvedClassifierNames(db *sql.DB) (*kpvedClassifierNames, error) {
+	result := &kpvedClassifierNames{names: map[string]string{}, parents: map[string]string{}}
+	if db == nil {
+		return result, nil
+	}
+
+	rows, err := db.Query(`SELECT code, name, COALESCE(parent_code, '') FROM kpved_classifier WHERE LENGTH(code) <= 2`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load KPVED classifier: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code, name, parent string
+		if err := rows.Scan(&code, &name, &parent); err != nil {
+			return nil, fmt.Errorf("failed to scan KPVED classifier: %w", err)
+		}
+		result.names[code] = name
+		if parent != "" {
+			result.parents[code] = parent
+		}
+	}
+	return result, rows.Err()
+}
+
+// section возвращает раздел класса: из классификатора, иначе по диапазонам классов
+func (n *kpvedClassifierNames) section(classCode string) string {
+	if parent, ok := n.parents[classCode]; ok {
+		return parent
+	}
+	if len(classCode) == 1 {
+		return classCode
+	}
+	if number, err := strconv.Atoi(classCode); err == nil {
+		for _, r := range kpvedSectionRanges {
+			if number >= r.from && number <= r.to {
+				return r.section
+			}
+		}
+	}
+	return "?"
+}
+
+// percent возвращает долю part от total в процентах
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+// BuildKpvedReport строит отчет по normalized_data базы data, группируя записи по разделам
+// и классам КПВЭД. Наименования разделов и классов берутся из classifier (может быть nil).
+func BuildKpvedReport(data *sql.DB, classifier *sql.DB, options KpvedReportOptions) (*KpvedReport, error) {
+	if options.ExamplesPerClass == 0 {
+		options.ExamplesPerClass = DefaultKpvedExamplesPerClass
+	}
+	if options.LowConfidence <= 0 {
+		options.LowConfidence = database.DefaultAcceptThreshold
+	}
+
+	names, err := loadKpvedClassifierNames(classifier)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &KpvedReport{
+		GeneratedAt: time.Now(),
+		Database:    options.Database,
+		Sections:    []KpvedSectionStats{},
+	}
+	if err := data.QueryRow("SELECT COUNT(*) FROM normalized_data").Scan(&report.TotalItems); err != nil {
+		return nil, fmt.Errorf("failed to count normalized items: %w", err)
+	}
+
+	// Класс - первые два символа кода (или буква раздела, если классифицировано только до раздела)
+	const classExpr = `CASE WHEN INSTR(kpved_code, '.') = 0 AND LENGTH(kpved_code) = 1 THEN kpved_code ELSE SUBSTR(kpved_code, 1, 2) END`
+	const classifiedCond = `kpved_code IS NOT NULL AND TRIM(kpved_code) != ''`
+
+	rows, err := data.Query(fmt.Sprintf(`
+		SELECT %s AS class_code, COUNT(*), COALESCE(SUM(merged_count), 0),
+		       COALESCE(AVG(kpved_confidence), 0),
+		       SUM(CASE WHEN COALESCE(kpved_confidence, 0) < ? THEN 1 ELSE 0 END),
+		       COUNT(DISTINCT kpved_code)
+		FROM normalized_data
+		WHERE %s
+		GROUP BY class_code
+	`, classExpr, classifiedCond), options.LowConfidence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate KPVED classes: %w", err)
+	}
+
+	sections := map[string]*KpvedSectionStats{}
+	var confidenceSum float64
+	for rows.Next() {
+		var class KpvedClassStats
+		if err := rows.Scan(&class.Code, &class.Items, &class.MergedItems, &class.AvgConfidence,
+			&class.LowConfidenceItems, &class.DistinctCodes); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan KPVED class: %w", err)
+		}
+		class.Name = names.names[class.Code]
+		class.Examples = []KpvedReportExample{}
+
+		sectionCode := names.section(class.Code)
+		section, ok := sections[sectionCode]
+		if !ok {
+			section = &KpvedSectionStats{Code: sectionCode, Name: names.names[sectionCode]}
+			sections[sectionCode] = section
+		}
+		section.Items += class.Items
+		section.MergedItems += class.MergedItems
+		section.LowConfidenceItems += class.LowConfidenceItems
+		section.AvgConfidence += class.AvgConfidence * float64(class.Items)
+		section.Classes = append(section.Classes, class)
+
+		report.ClassifiedItems += class.Items
+		report.LowConfidenceItems += class.LowConfidenceItems
+		confidenceSum += class.AvgConfidence * float64(class.Items)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate KPVED classes: %w", err)
+	}
+
+	report.Unclassified = report.TotalItems - report.ClassifiedItems
+	report.Coverage = percent(report.ClassifiedItems, report.TotalItems)
+	if report.ClassifiedItems > 0 {
+		report.AvgConfidence = confidenceSum / float64(report.ClassifiedItems)
+	}
+
+	for _, section := range sections {
+		if section.Items > 0 {
+			section.AvgConfidence /= float64(section.Items)
+		}
+		section.Percent = percent(section.Items, report.TotalItems)
+		for i := range section.Classes {
+			section.Classes[i].Percent = percent(section.Classes[i].Items, report.TotalItems)
+			section.Classes[i].SectionPercent = percent(section.Classes[i].Items, section.Items)
+		}
+		sort.Slice(section.Classes, func(i, j int) bool { return section.Classes[i].Code < section.Classes[j].Code })
+		report.Sections = append(report.Sections, *section)
+	}
+	sort.Slice(report.Sections, func(i, j int) bool { return report.Sections[i].Code < report.Sections[j].Code })
+
+	if options.ExamplesPerClass > 0 && report.ClassifiedItems > 0 {
+		if err := loadKpvedReportExamples(data, report, classExpr, classifiedCond, options.ExamplesPerClass); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.SecondLanguage != "" {
+		translations, err := database.LoadCategoryNameTranslations(classifier, database.KpvedTranslationClassifier, options.SecondLanguage)
+		if err != nil {
+			return nil, err
+		}
+		translateKpvedReport(report, options.SecondLanguage, translations)
+	}
+
+	return report, nil
+}
+
+// translateKpvedReport заполняет наименования на втором языке. Если перевода нет, используется исходное
+// наименование, а код попадает в список MissingTranslations
+func translateKpvedReport(report *KpvedReport, language string, translations map[string]string) {
+	report.SecondLanguage = language
+	missing := map[string]bool{}
+	translate := func(code, name string) string {
+		if translated, ok := translations[code]; ok && translated != "" {
+			return translated
+		}
+		missing[code] = true
+		return name
+	}
+
+	for s := range report.Sections {
+		section := &report.Sections[s]
+		section.NameTranslated = translate(section.Code, section.Name)
+		for c := range section.Classes {
+			class := &section.Classes[c]
+			class.NameTranslated = translate(class.Code, class.Name)
+			for e := range class.Examples {
+				example := &class.Examples[e]
+				example.KpvedNameTranslated = translate(example.KpvedCode, example.KpvedName)
+			}
+		}
+	}
+
+	for code := range missing {
+		report.MissingTranslations = append(report.MissingTranslations, code)
+	}
+	sort.Strings(report.MissingTranslations)
+}
+
+// loadKpvedReportExamples добавляет к классам примеры записей с наибольшей уверенностью
+func loadKpvedReportExamples(data *sql.DB, report *KpvedReport, classExpr, classifiedCond string, perClass int) error {
+	rows, err := data.Query(fmt.Sprintf(`
+		SELECT class_code, id, source_name, normalized_name, kpved_code, kpved_name, confidence, merged_count
+		FROM (
+			SELECT %s AS class_code, id, COALESCE(source_name, '') AS source_name,
+			       COALESCE(normalized_name, '') AS normalized_name, kpved_code,
+			       COALESCE(kpved_name, '') AS kpved_name, COALESCE(kpved_confidence, 0) AS confidence,
+			       COALESCE(merged_count, 1) AS merged_count,
+			       ROW_NUMBER() OVER (PARTITION BY %s ORDER BY kpved_confidence DESC, merged_count DESC, id) AS rn
+			FROM normalized_data
+			WHERE %s
+		)
+		WHERE rn <= ?
+		ORDER BY class_code, rn
+	`, classExpr, classExpr, classifiedCond), perClass)
+	if err != nil {
+		return fmt.Errorf("failed to get KPVED examples: %w", err)
+	}
+	defer rows.Close()
+
+	examples := map[string][]KpvedReportExample{}
+	for rows.Next() {
+		var classCode string
+		var example KpvedReportExample
+		if err := rows.Scan(&classCode, &example.ID, &example.SourceName, &example.NormalizedName,
+			&example.KpvedCode, &example.KpvedName, &example.Confidence, &example.MergedCount); err != nil {
+			return fmt.Errorf("failed to scan KPVED example: %w", err)
+		}
+		examples[classCode] = append(examples[classCode], example)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for s := range report.Sections {
+		for c := range report.Sections[s].Classes {
+			class := &report.Sections[s].Classes[c]
+			if list, ok := examples[class.Code]; ok {
+				class.Examples = list
+			}
+		}
+	}
+	return nil
+}
+
+// KpvedReportSheets возвращает листы XLSX: разделы, классы с подытогами и примеры; подписи на языке lang
+func KpvedReportSheets(report *KpvedReport, lang string) []XLSXSheet {
+	t := func(key string, args ...interface{}) string { return i18n.T(lang, key, args...) }
+
+	summary := [][]interface{}{
+		{t("report.metric"), t("report.value")},
+		{t("report.generated"), report.GeneratedAt.Format("2006-01-02 15:04:05")},
+		{t("report.total_records"), report.TotalItems},
+		{t("report.classified"), report.ClassifiedItems},
+		{t("report.coverage_percent"), round2(report.Coverage)},
+		{t("report.avg_confidence"), round2(report.AvgConfidence)},
+		{t("report.low_confidence"), report.LowConfidenceItems},
+		{t("report.without_kpved"), report.Unclassified},
+	}
+	if report.Database != "" {
+		summary = append(summary, []interface{}{t("report.database"), report.Database})
+	}
+
+	sections := [][]interface{}{{t("report.section"), t("report.name"), t("report.records"), t("report.source_records"),
+		t("report.percent_of_total"), t("report.avg_confidence"), t("report.low_confidence"), t("report.classes")}}
+	classes := [][]interface{}{{t("report.section"), t("report.class"), t("report.name"), t("report.records"), t("report.source_records"),
+		t("report.percent_of_total"), t("report.percent_of_section"), t("report.avg_confidence"), t("report.low_confidence"), t("report.codes")}}
+	examples := [][]interface{}{{t("report.section"), t("report.class"), t("report.kpved_code"), t("report.kpved_name"),
+		t("report.source_name"), t("report.normalized_name"), t("report.confidence"), t("report.merged")}}
+
+	// В двуязычном отчете колонка перевода следует сразу за исходным наименованием
+	bilingual := report.SecondLanguage != ""
+	withTranslation := func(row []interface{}, at int, value interface{}) []interface{} {
+		if !bilingual {
+			return row
+		}
+		result := make([]interface{}, 0, len(row)+1)
+		result = append(result, row[:at+1]...)
+		result = append(result, value)
+		return append(result, row[at+1:]...)
+	}
+	if bilingual {
+		sections[0] = withTranslation(sections[0], 1, t("report.name_translated", report.SecondLanguage))
+		classes[0] = withTranslation(classes[0], 2, t("report.name_translated", report.SecondLanguage))
+		examples[0] = withTranslation(examples[0], 3, t("report.kpved_name_translated", report.SecondLanguage))
+	}
+
+	for _, section := range report.Sections {
+		sections = append(sections, withTranslation([]interface{}{section.Code, section.Name, section.Items, section.MergedItems,
+			round2(section.Percent), round2(section.AvgConfidence), section.LowConfidenceItems, len(section.Classes)}, 1, section.NameTranslated))
+		for _, class := range section.Classes {
+			classes = append(classes, withTranslation([]interface{}{section.Code, class.Code, class.Name, class.Items, class.MergedItems,
+				round2(class.Percent), round2(class.SectionPercent), round2(class.AvgConfidence), class.LowConfidenceItems, class.DistinctCodes}, 2, class.NameTranslated))
+			for _, example := range class.Examples {
+				examples = append(examples, withTranslation([]interface{}{section.Code, class.Code, example.KpvedCode, example.KpvedName,
+					example.SourceName, example.NormalizedName, round2(example.Confidence), example.MergedCount}, 3, example.KpvedNameTranslated))
+			}
+		}
+		// Подытог раздела
+		classes = append(classes, withTranslation([]interface{}{section.Code, t("report.total"), section.Name, section.Items, section.MergedItems,
+			round2(section.Percent), 100.0, round2(section.AvgConfidence), section.LowConfidenceItems, nil}, 2, section.NameTranslated))
+	}
+
+	return []XLSXSheet{
+		{Name: t("report.sheet.summary"), Rows: summary},
+		{Name: t("report.sheet.sections"), Rows: sections},
+		{Name: t("report.sheet.classes"), Rows: classes},
+		{Name: t("report.sheet.examples"), Rows: examples},
+	}
+}
+
+// round2 округляет до сотых для табличного вывода
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,46 @@
+package reports
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// kpvedReportTemplate HTML отчета по разделам КПВЭД: разделы с подытогами, классы и раскрываемые примеры.
+// Таблицы вынесены в общий фрагмент kpved_content, который доступен и шаблонам движка отчетов.
+const kpvedReportTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{t "report.kpved.title"}}</title>
+    {{template "report_styles" "` + DefaultPrimaryColor + `"}}
+</head>
+<body>
+    <div class="container">
+        <h1>{{t "report.kpved.title"}}</h1>
+        <div class="timestamp">{{t "report.generated"}}: {{.GeneratedAt.Format "2006-01-02 15:04:05"}}{{if .Database}} | {{t "report.database"}}: {{.Database}}{{end}}</div>
+        {{template "kpved_content" .}}
+    </div>
+</body>
+</html>`
+
+// kpvedReportHTML разобранный шаблон; не выполняется напрямую, а клонируется под язык отчета
+var kpvedReportHTML = template.Must(template.Must(baseTemplates.Clone()).New("kpved_report").Parse(kpvedReportTemplate))
+
+// RenderKpvedReportHTML записывает отчет в HTML на языке lang
+func RenderKpvedReportHTML(w io.Writer, report *KpvedReport, lang string) error {
+	tmpl, err := localize(kpvedReportHTML, lang)
+	if err != nil {
+		return fmt.Errorf("failed to prepare KPVED report: %w", err)
+	}
+	if err := tmpl.Execute(w, report); err != nil {
+		return fmt.Errorf("failed to render KPVED report: %w", err)
+	}
+	return nil
+}
+
+// RenderKpvedReportXLSX записывает отчет в XLSX на языке lang
+func RenderKpvedReportXLSX(w io.Writer, report *KpvedReport, lang string) error {
+	return WriteXLSX(w, KpvedReportSheets(report, lang))
+}
@@ -0,0 +1,230 @@
+package reports
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func newKpvedReportTestDB(t *testing.T) *database.DB {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO normalized_data (code, source_name, normalized_name, category, kpved_code, kpved_name, kpved_confidence, merged_count) VALUES
+			('1', 'Болт М8', 'болт м8', 'крепеж', '25.94.11', 'Болты', 0.95, 3),
+			('2', 'Гайка М8', 'гайка м8', 'крепеж', '25.94.12', 'Гайки', 0.6, 1),
+			('3', 'Молоко 3,2%', 'молоко', 'продукты', '10.51.11', 'Молоко', 0.9, 1),
+			('4', 'Уголь', 'уголь', 'сырье', '05.10', 'Уголь каменный', 0.8, 1),
+			('5', 'Кабель ВВГ', 'кабель ввг', 'электрика', NULL, NULL, 0, 1)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to seed normalized_data: %v", err)
+	}
+	return db
+}
+
+func TestBuildKpvedReport(t *testing.T) {
+	db := newKpvedReportTestDB(t)
+	defer db.Close()
+
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	if _, err := serviceDB.Exec(`
+		INSERT INTO kpved_classifier (code, name, parent_code, level) VALUES
+			('C', 'Продукция обрабатывающей промышленности', NULL, 1),
+			('25', 'Изделия металлические готовые', 'C', 2)
+	`); err != nil {
+		t.Fatalf("Failed to seed classifier: %v", err)
+	}
+
+	report, err := BuildKpvedReport(db.GetDB(), serviceDB.GetDB(), KpvedReportOptions{ExamplesPerClass: 1})
+	if err != nil {
+		t.Fatalf("BuildKpvedReport() error = %v", err)
+	}
+
+	if report.TotalItems != 5 || report.ClassifiedItems != 4 || report.Unclassified != 1 || report.Coverage != 80 {
+		t.Errorf("unexpected totals: %+v", report)
+	}
+	if report.LowConfidenceItems != 2 {
+		t.Errorf("low confidence items = %d, want 2", report.LowConfidenceItems)
+	}
+
+	// Раздел B определяется по диапазону классов, раздел C - по классификатору
+	if len(report.Sections) != 2 || report.Sections[0].Code != "B" || report.Sections[1].Code != "C" {
+		t.Fatalf("unexpected sections: %+v", report.Sections)
+	}
+	manufacturing := report.Sections[1]
+	if manufacturing.Name == "" || manufacturing.Items != 3 || manufacturing.MergedItems != 5 || len(manufacturing.Classes) != 2 {
+		t.Errorf("unexpected section C: %+v", manufacturing)
+	}
+	metal := manufacturing.Classes[1]
+	if metal.Code != "25" || metal.Name != "Изделия металлические готовые" || metal.Items != 2 || metal.DistinctCodes != 2 {
+		t.Errorf("unexpected class 25: %+v", metal)
+	}
+	if len(metal.Examples) != 1 || metal.Examples[0].KpvedCode != "25.94.11" {
+		t.Errorf("expected the most confident example, got %+v", metal.Examples)
+	}
+
+	var html bytes.Buffer
+	if err := RenderKpvedReportHTML(&html, report, ""); err != nil {
+		t.Fatalf("RenderKpvedReportHTML() error = %v", err)
+	}
+	if !strings.Contains(html.String(), "Изделия металлические готовые") || !strings.Contains(html.String(), "<details>") {
+		t.Error("HTML report does not contain class names and examples")
+	}
+}
+
+func TestBuildKpvedReportSecondLanguage(t *testing.T) {
+	db := newKpvedReportTestDB(t)
+	defer db.Close()
+
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	if _, err := serviceDB.Exec(`
+		INSERT INTO kpved_classifier (code, name, parent_code, level) VALUES
+			('C', 'Продукция обрабатывающей промышленности', NULL, 1),
+			('25', 'Изделия металлические готовые', 'C', 2)
+	`); err != nil {
+		t.Fatalf("Failed to seed classifier: %v", err)
+	}
+	if _, err := serviceDB.ImportCategoryNameTranslations(database.KpvedTranslationClassifier, []*database.CategoryNameTranslation{
+		{Code: "C", Language: "kk", Name: "Өңдеу өнеркәсібінің өнімдері"},
+		{Code: "25", Language: "kk", Name: "Дайын металл бұйымдары"},
+	}); err != nil {
+		t.Fatalf("Failed to seed translations: %v", err)
+	}
+
+	report, err := BuildKpvedReport(db.GetDB(), serviceDB.GetDB(), KpvedReportOptions{ExamplesPerClass: 1, SecondLanguage: "kk"})
+	if err != nil {
+		t.Fatalf("BuildKpvedReport() error = %v", err)
+	}
+
+	manufacturing := report.Sections[1]
+	if manufacturing.NameTranslated != "Өңдеу өнеркәсібінің өнімдері" {
+		t.Errorf("section C translation = %q", manufacturing.NameTranslated)
+	}
+	tests := []struct {
+		name  string
+		class KpvedClassStats
+		want  string
+	}{
+		{"translated", manufacturing.Classes[1], "Дайын металл бұйымдары"},
+		{"falls back to source name", manufacturing.Classes[0], manufacturing.Classes[0].Name},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.class.NameTranslated != tt.want {
+				t.Errorf("class %s translation = %q, want %q", tt.class.Code, tt.class.NameTranslated, tt.want)
+			}
+		})
+	}
+	if report.SecondLanguage != "kk" || len(report.MissingTranslations) == 0 {
+		t.Errorf("expected missing translations to be reported: %+v", report.MissingTranslations)
+	}
+
+	sheets := KpvedReportSheets(report, "ru")
+	classes := sheets[2].Rows
+	if len(classes[0]) != 11 || classes[0][3] != "Наименование (kk)" {
+		t.Errorf("unexpected classes header: %v", classes[0])
+	}
+
+	var html bytes.Buffer
+	if err := RenderKpvedReportHTML(&html, report, "ru"); err != nil {
+		t.Fatalf("RenderKpvedReportHTML() error = %v", err)
+	}
+	if !strings.Contains(html.String(), "Дайын металл бұйымдары") || !strings.Contains(html.String(), "Изделия металлические готовые") {
+		t.Error("HTML report must contain names in both languages")
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXLSX(&buf, []XLSXSheet{{
+		Name: "Классы: итоги",
+		Rows: [][]interface{}{{"Код", "Записей"}, {"25 & <26>", 3}, {nil, 0.5}},
+	}})
+	if err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Result is not a zip archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "xl/workbook.xml", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="Классы  итоги"`) {
+		t.Errorf("sheet name was not sanitized: %s", files["xl/workbook.xml"])
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet, "25 &amp; &lt;26&gt;") || !strings.Contains(sheet, `<c r="B2"><v>3</v></c>`) {
+		t.Errorf("unexpected sheet content: %s", sheet)
+	}
+	if strings.Contains(sheet, `r="A3"`) {
+		t.Error("nil cells must be skipped")
+	}
+
+	if got := xlsxColumnName(27); got != "AB" {
+		t.Errorf("xlsxColumnName(27) = %s, want AB", got)
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteXLSX(&buf, []XLSXSheet{{
+		Name: "Соответствия",
+		Rows: [][]interface{}{{"Старый код", "Новый код"}, {"A-1 & <2>", 42}, {nil, "B"}},
+	}})
+	if err != nil {
+		t.Fatalf("WriteXLSX() error = %v", err)
+	}
+
+	rows, err := ReadXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadXLSX() error = %v", err)
+	}
+	want := [][]string{{"Старый код", "Новый код"}, {"A-1 & <2>", "42"}, {"", "B"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ReadXLSX() = %q, want %q", rows, want)
+	}
+
+	if _, err := ReadXLSX(strings.NewReader("not a zip"), 9); err == nil {
+		t.Error("ReadXLSX() must fail for non-xlsx input")
+	}
+}
+
+func TestXLSXColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z3": 25, "AA10": 26, "AB2": 27} {
+		if got := xlsxColumnIndex(ref); got != want {
+			t.Errorf("xlsxColumnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package reports
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"httpserver/database"
+)
+
+// NormalizationSummary общая статистика нормализации и классификации
+type NormalizationSummary struct {
+	TotalItems        int     `json:"total_items"` // Исходные элементы справочников
+	NormalizedCount   int     `json:"normalized_count"`
+	NormalizedPercent float64 `json:"normalized_percent"`
+	KpvedCount        int     `json:"kpved_count"`
+	KpvedPercent      float64 `json:"kpved_percent"`
+	UniqueCategories  int     `json:"unique_categories"`
+	ChangedCount      int     `json:"changed_count"` // Наименование изменено нормализацией
+	ChangedPercent    float64 `json:"changed_percent"`
+	MergedCount       int     `json:"merged_count"`      // Записи, объединившие дубликаты
+	AvgAIConfidence   float64 `json:"avg_ai_confidence"` // Средняя уверенность AI по записям, обработанным AI
+}
+
+// CategoryStat число записей категории
+type CategoryStat struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// NormalizationExample пример нормализованной записи
+type NormalizationExample struct {
+	Source     string `json:"source"`
+	Normalized string `json:"normalized"`
+	Category   string `json:"category"`
+	Code       string `json:"code"`
+}
+
+// Provider слой данных отчетов: стандартные статистические запросы к базе нормализованных данных.
+// Методы вызываются из шаблонов, поэтому выполняются только запросы используемых разделов;
+// результаты кэшируются на время построения отчета.
+type Provider struct {
+	data           *sql.DB
+	classifier     *sql.DB
+	uploadID       int
+	secondLanguage string // Второй язык наименований категорий в отчетах
+
+	mu      sync.Mutex
+	summary *NormalizationSummary
+	kpved   *KpvedReport
+	heatmap *ConfidenceHeatmap
+}
+
+// NewProvider создает слой данных по базе data; classifier - база с классификатором КПВЭД (может быть nil)
+func NewProvider(data, classifier *sql.DB) *Provider {
+	return &Provider{data: data, classifier: classifier}
+}
+
+// ForUpload ограничивает статистику исходными элементами выгрузки
+func (p *Provider) ForUpload(uploadID int) *Provider {
+	return &Provider{data: p.data, classifier: p.classifier, uploadID: uploadID, secondLanguage: p.secondLanguage}
+}
+
+// WithSecondLanguage добавляет в отчеты наименования категорий на втором языке ("" - без перевода)
+func (p *Provider) WithSecondLanguage(lang string) *Provider {
+	return &Provider{data: p.data, classifier: p.classifier, uploadID: p.uploadID, secondLanguage: lang}
+}
+
+// scope возвращает условие для normalized_data и исходных элементов с учетом выгрузки
+func (p *Provider) scope() (normalized string, items string, args []interface{}) {
+	if p.uploadID == 0 {
+		return "1=1", "1=1", nil
+	}
+	return `source_reference IN (
+			SELECT ci.reference FROM catalog_items ci JOIN catalogs c ON c.id = ci.catalog_id WHERE c.upload_id = ?
+		)`,
+		`catalog_id IN (SELECT id FROM catalogs WHERE upload_id = ?)`,
+		[]interface{}{p.uploadID}
+}
+
+// Summary возвращает общую статистику нормализации
+func (p *Provider) Summary() (*NormalizationSummary, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.summary != nil {
+		return p.summary, nil
+	}
+
+	where, itemsWhere, args := p.scope()
+	summary := &NormalizationSummary{}
+	queries := []struct {
+		query  string
+		target *int
+	}{
+		{"SELECT COUNT(*) FROM catalog_items WHERE " + itemsWhere, &summary.TotalItems},
+		{"SELECT COUNT(*) FROM normalized_data WHERE " + where, &summary.NormalizedCount},
+		{"SELECT COUNT(*) FROM normalized_data WHERE kpved_code IS NOT NULL AND kpved_code != '' AND " + where, &summary.KpvedCount},
+		{"SELECT COUNT(DISTINCT category) FROM normalized_data WHERE category IS NOT NULL AND category != '' AND " + where, &summary.UniqueCategories},
+		{"SELECT COUNT(*) FROM normalized_data WHERE source_name != normalized_name AND normalized_name IS NOT NULL AND normalized_name != '' AND " + where, &summary.ChangedCount},
+		{"SELECT COUNT(*) FROM normalized_data WHERE merged_count > 1 AND " + where, &summary.MergedCount},
+	}
+	for _, q := range queries {
+		if err := p.data.QueryRow(q.query, args...).Scan(q.target); err != nil {
+			return nil, fmt.Errorf("failed to collect normalization summary: %w", err)
+		}
+	}
+
+	if err := p.data.QueryRow("SELECT COALESCE(AVG(ai_confidence), 0) FROM normalized_data WHERE ai_confidence > 0 AND "+where, args...).Scan(&summary.AvgAIConfidence); err != nil {
+		return nil, fmt.Errorf("failed to collect normalization summary: %w", err)
+	}
+
+	summary.NormalizedPercent = round2(percent(summary.NormalizedCount, summary.TotalItems))
+	summary.KpvedPercent = round2(percent(summary.KpvedCount, summary.NormalizedCount))
+	summary.ChangedPercent = round2(percent(summary.ChangedCount, summary.NormalizedCount))
+
+	p.summary = summary
+	return summary, nil
+}
+
+// TopCategories возвращает самые крупные категории
+func (p *Provider) TopCategories(limit int) ([]CategoryStat, error) {
+	summary, err := p.Summary()
+	if err != nil {
+		return nil, err
+	}
+
+	where, _, args := p.scope()
+	rows, err := p.data.Query(`
+		SELECT category, COUNT(*) AS count
+		FROM normalized_data
+		WHERE category IS NOT NULL AND category != '' AND `+where+`
+		GROUP BY category
+		ORDER BY count DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []CategoryStat{}
+	for rows.Next() {
+		var category CategoryStat
+		if err := rows.Scan(&category.Name, &category.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		category.Percent = round2(percent(category.Count, summary.NormalizedCount))
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// Examples возвращает примеры нормализованных записей
+func (p *Provider) Examples(limit int) ([]NormalizationExample, error) {
+	where, _, args := p.scope()
+	rows, err := p.data.Query(`
+		SELECT COALESCE(source_name, ''), COALESCE(normalized_name, ''), category, COALESCE(code, '')
+		FROM normalized_data
+		WHERE category IS NOT NULL AND category != '' AND `+where+`
+		ORDER BY category, id
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get examples: %w", err)
+	}
+	defer rows.Close()
+
+	examples := []NormalizationExample{}
+	for rows.Next() {
+		var example NormalizationExample
+		if err := rows.Scan(&example.Source, &example.Normalized, &example.Category, &example.Code); err != nil {
+			return nil, fmt.Errorf("failed to scan example: %w", err)
+		}
+		examples = append(examples, example)
+	}
+	return examples, rows.Err()
+}
+
+// Kpved возвращает отчет по разделам и классам КПВЭД
+func (p *Provider) Kpved() (*KpvedReport, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.kpved != nil {
+		return p.kpved, nil
+	}
+
+	report, err := BuildKpvedReport(p.data, p.classifier, KpvedReportOptions{SecondLanguage: p.secondLanguage})
+	if err != nil {
+		return nil, err
+	}
+	p.kpved = report
+	return report, nil
+}
+
+// ConfidenceHeatmap возвращает тепловую карту уверенности классификации по разделам КПВЭД и справочникам
+func (p *Provider) ConfidenceHeatmap() (*ConfidenceHeatmap, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.heatmap != nil {
+		return p.heatmap, nil
+	}
+
+	where, _, args := p.scope()
+	heatmap, err := buildConfidenceHeatmap(p.data, p.classifier, where, args)
+	if err != nil {
+		return nil, err
+	}
+	p.heatmap = heatmap
+	return heatmap, nil
+}
+
+// QualityOverview сводка качества нормализованных данных
+type QualityOverview struct {
+	AvgQualityScore float64        `json:"avg_quality_score"`
+	LowQualityItems int            `json:"low_quality_items"` // quality_score ниже LowQualityThreshold
+	Decisions       map[string]int `json:"decisions"`         // Решения политики порогов уверенности
+	OpenViolations  map[string]int `json:"open_violations"`   // Неисправленные нарушения по важности
+	DuplicateGroups int            `json:"duplicate_groups"`  // Необъединенные группы дубликатов
+}
+
+// LowQualityThreshold граница низкой оценки качества записи
+const LowQualityThreshold = 0.5
+
+// Quality возвращает сводку качества: оценки, решения по уверенности, нарушения и дубликаты
+func (p *Provider) Quality() (*QualityOverview, error) {
+	where, _, args := p.scope()
+	overview := &QualityOverview{
+		Decisions: map[string]int{
+			database.ConfidenceDecisionAccept: 0,
+			database.ConfidenceDecisionReview: 0,
+			database.ConfidenceDecisionReject: 0,
+		},
+		OpenViolations: map[string]int{},
+	}
+
+	if err := p.data.QueryRow(`
+		SELECT COALESCE(AVG(quality_score), 0), COALESCE(SUM(CASE WHEN quality_score < ? THEN 1 ELSE 0 END), 0)
+		FROM normalized_data
+		WHERE `+where, append([]interface{}{LowQualityThreshold}, args...)...).Scan(&overview.AvgQualityScore, &overview.LowQualityItems); err != nil {
+		return nil, fmt.Errorf("failed to get quality scores: %w", err)
+	}
+	overview.AvgQualityScore = round2(overview.AvgQualityScore)
+
+	if err := p.scanCounts(overview.Decisions, `
+		SELECT COALESCE(NULLIF(confidence_decision, ''), 'none'), COUNT(*)
+		FROM normalized_data
+		WHERE `+where+`
+		GROUP BY 1
+	`, args...); err != nil {
+		return nil, fmt.Errorf("failed to get confidence decisions: %w", err)
+	}
+
+	if err := p.scanCounts(overview.OpenViolations, `
+		SELECT severity, COUNT(*)
+		FROM quality_violations
+		WHERE resolved_at IS NULL AND normalized_item_id IN (SELECT id FROM normalized_data WHERE `+where+`)
+		GROUP BY severity
+	`, args...); err != nil {
+		return nil, fmt.Errorf("failed to get quality violations: %w", err)
+	}
+
+	if err := p.data.QueryRow("SELECT COUNT(*) FROM duplicate_groups WHERE merged = FALSE").Scan(&overview.DuplicateGroups); err != nil {
+		return nil, fmt.Errorf("failed to count duplicate groups: %w", err)
+	}
+
+	return overview, nil
+}
+
+// scanCounts заполняет result парами ключ-количество из запроса
+func (p *Provider) scanCounts(result map[string]int, query string, args ...interface{}) error {
+	rows, err := p.data.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return err
+		}
+		result[key] = count
+	}
+	return rows.Err()
+}
@@ -0,0 +1,153 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Форматы вывода табличных данных инспекций
+const (
+	FormatText = "text" // Выровненные колонки для терминала
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+	FormatJSON = "json" // Массив объектов с полями в порядке колонок
+)
+
+// ParseOutputFormat проверяет формат вывода; пустой формат - текст
+func ParseOutputFormat(format string) (string, error) {
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatCSV, FormatTSV, FormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported output format %q: use text, csv, tsv or json", format)
+}
+
+// Table набор строк с постоянным составом колонок. Значения - строки, числа, bool, время или nil
+type Table struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// NewTable создает таблицу с колонками columns
+func NewTable(columns ...string) *Table {
+	return &Table{Columns: columns, Rows: [][]interface{}{}}
+}
+
+// Add добавляет строку; число значений должно совпадать с числом колонок
+func (t *Table) Add(values ...interface{}) {
+	if len(values) != len(t.Columns) {
+		panic(fmt.Sprintf("table row has %d values, want %d", len(values), len(t.Columns)))
+	}
+	t.Rows = append(t.Rows, values)
+}
+
+// formatTableValue представляет значение ячейки строкой
+func formatTableValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// WriteTable записывает таблицу в формате format. CSV экранирует значения по RFC 4180;
+// в TSV табуляции и переводы строк внутри значений заменяются пробелами
+func WriteTable(w io.Writer, format string, table *Table) error {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		writer.Write(table.Columns)
+		for _, row := range table.Rows {
+			record := make([]string, len(row))
+			for i, value := range row {
+				record[i] = formatTableValue(value)
+			}
+			writer.Write(record)
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatTSV:
+		replacer := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+		var buf bytes.Buffer
+		buf.WriteString(strings.Join(table.Columns, "\t") + "\n")
+		for _, row := range table.Rows {
+			for i, value := range row {
+				if i > 0 {
+					buf.WriteByte('\t')
+				}
+				buf.WriteString(replacer.Replace(formatTableValue(value)))
+			}
+			buf.WriteByte('\n')
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	case FormatJSON:
+		return writeTableJSON(w, table)
+	case FormatText, "":
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(table.Columns, "\t"))
+		for _, row := range table.Rows {
+			values := make([]string, len(row))
+			for i, value := range row {
+				values[i] = strings.ReplaceAll(formatTableValue(value), "\t", " ")
+			}
+			fmt.Fprintln(writer, strings.Join(values, "\t"))
+		}
+		return writer.Flush()
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// writeTableJSON записывает строки массивом объектов, сохраняя порядок колонок
+func writeTableJSON(w io.Writer, table *Table) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, row := range table.Rows {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for j, value := range row {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			// Пустое время выводится как null, как пустая ячейка в CSV
+			if t, ok := value.(time.Time); ok && t.IsZero() {
+				value = nil
+			}
+			key, _ := json.Marshal(table.Columns[j])
+			data, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode column %s: %w", table.Columns[j], err)
+			}
+			buf.Write(key)
+			buf.WriteString(": ")
+			buf.Write(data)
+		}
+		buf.WriteString("}")
+	}
+	if len(table.Rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
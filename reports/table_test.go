@@ -0,0 +1,60 @@
+package reports
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteTable(t *testing.T) {
+	table := NewTable("id", "name", "confidence", "created_at")
+	table.Add(1, "Болт, М8", 0.95, time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	table.Add(2, "Гайка\t\"М8\"", nil, time.Time{})
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatCSV, "id,name,confidence,created_at\n1,\"Болт, М8\",0.95,2024-01-15T10:00:00Z\n2,\"Гайка\t\"\"М8\"\"\",,\n"},
+		{FormatTSV, "id\tname\tconfidence\tcreated_at\n1\tБолт, М8\t0.95\t2024-01-15T10:00:00Z\n2\tГайка \"М8\"\t\t\n"},
+		{FormatJSON, "[\n  {\"id\": 1, \"name\": \"Болт, М8\", \"confidence\": 0.95, \"created_at\": \"2024-01-15T10:00:00Z\"},\n" +
+			"  {\"id\": 2, \"name\": \"Гайка\\t\\\"М8\\\"\", \"confidence\": null, \"created_at\": null}\n]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTable(&buf, tt.format, table); err != nil {
+				t.Fatalf("WriteTable() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteTable() =\n%q\nwant\n%q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, FormatJSON, NewTable("id")); err != nil || buf.String() != "[]\n" {
+		t.Errorf("WriteTable(empty json) = %q, %v", buf.String(), err)
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", FormatText, false},
+		{"CSV", FormatCSV, false},
+		{"tsv", FormatTSV, false},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseOutputFormat(tt.value)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("ParseOutputFormat(%q) = %q, %v", tt.value, got, err)
+			}
+		})
+	}
+}
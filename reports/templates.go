@@ -0,0 +1,321 @@
+package reports
+
+import (
+	"fmt"
+	"html/template"
+
+	"httpserver/i18n"
+)
+
+// DefaultPrimaryColor основной цвет оформления отчетов без брендирования
+const DefaultPrimaryColor = "#3498db"
+
+// reportFuncs функции, доступные во всех шаблонах отчетов
+var reportFuncs = template.FuncMap{
+	"num": func(value float64) string { return fmt.Sprintf("%.2f", value) },
+	"add": func(a, b int) int { return a + b },
+	// t и lang переопределяются при рендеринге под язык отчета (см. localize)
+	"t":    func(key string, args ...interface{}) string { return i18n.T(i18n.Default, key, args...) },
+	"lang": func() string { return i18n.Default },
+}
+
+// localize возвращает копию шаблона, в которой подписи выводятся на языке lang
+func localize(tmpl *template.Template, lang string) (*template.Template, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return clone.Funcs(template.FuncMap{
+		"t":    func(key string, args ...interface{}) string { return i18n.T(lang, key, args...) },
+		"lang": func() string { return lang },
+	}), nil
+}
+
+// reportPartials общие фрагменты шаблонов: стили, шапка с брендированием, подвал и таблицы КПВЭД.
+// Пользовательские шаблоны могут подключать их через {{template "имя" .}}, а подписи выводить через {{t "ключ"}}.
+const reportPartials = `
+{{define "report_styles"}}<style>
+    body { font-family: Arial, sans-serif; margin: 20px; background: #f5f5f5; }
+    .container { max-width: 1200px; margin: 0 auto; background: white; padding: 30px; border-radius: 10px; box-shadow: 0 2px 10px rgba(0,0,0,0.1); }
+    h1 { color: #2c3e50; border-bottom: 3px solid {{.}}; padding-bottom: 10px; }
+    h2 { color: #34495e; margin-top: 30px; }
+    .brand { display: flex; align-items: center; gap: 15px; color: #7f8c8d; }
+    .brand img { max-height: 50px; }
+    .stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(180px, 1fr)); gap: 20px; margin: 20px 0; }
+    .stat-card { background: #ecf0f1; padding: 20px; border-radius: 8px; text-align: center; }
+    .stat-value { font-size: 2em; font-weight: bold; color: {{.}}; }
+    .stat-label { color: #7f8c8d; margin-top: 5px; }
+    table { width: 100%; border-collapse: collapse; margin: 10px 0 20px; }
+    th, td { padding: 8px 12px; text-align: left; border-bottom: 1px solid #ddd; vertical-align: top; }
+    th { background: {{.}}; color: white; }
+    tr.subtotal td { font-weight: bold; background: #ecf0f1; }
+    .low { color: #c0392b; }
+    details summary { cursor: pointer; color: #2980b9; }
+    details ul { margin: 5px 0; padding-left: 20px; }
+    .code { font-family: monospace; }
+    .example { background: #fff; padding: 15px; margin: 10px 0; border-left: 4px solid {{.}}; border-radius: 4px; }
+    .category { color: #27ae60; font-weight: bold; }
+    .progress { background: #ecf0f1; border-radius: 10px; height: 30px; margin: 10px 0; overflow: hidden; }
+    .progress-bar { background: {{.}}; height: 100%; display: flex; align-items: center; justify-content: center; color: white; font-weight: bold; }
+    .timestamp { color: #95a5a6; font-size: 0.9em; margin-top: 20px; }
+    .footer { color: #95a5a6; font-size: 0.9em; margin-top: 30px; border-top: 1px solid #ddd; padding-top: 10px; }
+</style>{{end}}
+
+{{define "report_header"}}
+    {{if or .Branding.LogoURL .Branding.ClientName}}<div class="brand">{{if .Branding.LogoURL}}<img src="{{.Branding.LogoURL}}" alt="{{.Branding.ClientName}}">{{end}}{{if .Branding.ClientName}}<span>{{.Branding.ClientName}}</span>{{end}}</div>{{end}}
+    <h1>{{.Title}}</h1>
+    <div class="timestamp">{{t "report.generated"}}: {{.GeneratedAt.Format "2006-01-02 15:04:05"}}{{if .Database}} | {{t "report.database"}}: {{.Database}}{{end}}{{if .Upload}} | {{t "report.upload"}}: {{.Upload}}{{end}}</div>
+{{end}}
+
+{{define "report_footer"}}{{if .Branding.Footer}}<div class="footer">{{.Branding.Footer}}</div>{{end}}{{end}}
+
+{{define "kpved_content"}}
+        <div class="stats">
+            <div class="stat-card"><div class="stat-value">{{.TotalItems}}</div><div class="stat-label">{{t "report.total_records"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{.ClassifiedItems}}</div><div class="stat-label">{{t "report.classified"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{num .Coverage}}%</div><div class="stat-label">{{t "report.coverage"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{num .AvgConfidence}}</div><div class="stat-label">{{t "report.avg_confidence"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{.Unclassified}}</div><div class="stat-label">{{t "report.without_kpved"}}</div></div>
+        </div>
+
+        <h2>{{t "report.sections"}}</h2>
+        <table>
+            <thead><tr><th>{{t "report.section"}}</th><th>{{t "report.name"}}</th>{{if .SecondLanguage}}<th>{{t "report.name_translated" .SecondLanguage}}</th>{{end}}<th>{{t "report.records"}}</th><th>{{t "report.percent_of_total"}}</th><th>{{t "report.avg_confidence"}}</th><th>{{t "report.low_confidence"}}</th></tr></thead>
+            <tbody>
+            {{range .Sections}}
+                <tr><td class="code">{{.Code}}</td><td>{{.Name}}</td>{{if $.SecondLanguage}}<td>{{.NameTranslated}}</td>{{end}}<td>{{.Items}}</td><td>{{num .Percent}}%</td><td>{{num .AvgConfidence}}</td><td{{if .LowConfidenceItems}} class="low"{{end}}>{{.LowConfidenceItems}}</td></tr>
+            {{end}}
+            </tbody>
+        </table>
+
+        {{range .Sections}}
+        <h2>{{t "report.section"}} {{.Code}}{{if .Name}}. {{.Name}}{{end}}{{if and $.SecondLanguage (ne .NameTranslated .Name)}} / {{.NameTranslated}}{{end}}</h2>
+        <table>
+            <thead><tr><th>{{t "report.class"}}</th><th>{{t "report.name"}}</th>{{if $.SecondLanguage}}<th>{{t "report.name_translated" $.SecondLanguage}}</th>{{end}}<th>{{t "report.records"}}</th><th>{{t "report.percent_of_total"}}</th><th>{{t "report.percent_of_section"}}</th><th>{{t "report.avg_confidence"}}</th><th>{{t "report.low_confidence"}}</th><th>{{t "report.examples"}}</th></tr></thead>
+            <tbody>
+            {{range .Classes}}
+                <tr>
+                    <td class="code">{{.Code}}</td>
+                    <td>{{.Name}}</td>
+                    {{if $.SecondLanguage}}<td>{{.NameTranslated}}</td>{{end}}
+                    <td>{{.Items}}</td>
+                    <td>{{num .Percent}}%</td>
+                    <td>{{num .SectionPercent}}%</td>
+                    <td>{{num .AvgConfidence}}</td>
+                    <td{{if .LowConfidenceItems}} class="low"{{end}}>{{.LowConfidenceItems}}</td>
+                    <td>{{if .Examples}}<details><summary>{{t "report.examples_count" (len .Examples)}}</summary><ul>
+                        {{range .Examples}}<li><span class="code">{{.KpvedCode}}</span> {{.NormalizedName}}{{if ne .SourceName .NormalizedName}} <small>({{.SourceName}})</small>{{end}} - {{num .Confidence}}</li>{{end}}
+                    </ul></details>{{end}}</td>
+                </tr>
+            {{end}}
+                <tr class="subtotal"><td>{{t "report.total"}}</td><td></td>{{if $.SecondLanguage}}<td></td>{{end}}<td>{{.Items}}</td><td>{{num .Percent}}%</td><td>100%</td><td>{{num .AvgConfidence}}</td><td>{{.LowConfidenceItems}}</td><td></td></tr>
+            </tbody>
+        </table>
+        {{end}}
+{{end}}
+
+{{define "confidence_heatmap"}}{{if .Cells}}
+        <h2>{{t "report.confidence_heatmap"}}</h2>
+        <p class="timestamp">{{t "report.heatmap_hint"}}</p>
+        <table>
+            <thead><tr><th>{{t "report.section"}}</th>{{range .Catalogs}}<th>{{.Code}}</th>{{end}}<th>{{t "report.total"}}</th></tr></thead>
+            <tbody>
+            {{range $section := .Sections}}
+                <tr><td class="code" title="{{.Name}}">{{.Code}}</td>{{range $.Catalogs}}{{with $.Cell $section.Code .Code}}<td{{if lt .AvgConfidence $.LowConfidence}} class="low"{{end}}>{{num .AvgConfidence}} / {{num .MedianConfidence}} ({{.Items}})</td>{{else}}<td></td>{{end}}{{end}}<td>{{num .AvgConfidence}} / {{num .MedianConfidence}} ({{.Items}})</td></tr>
+            {{end}}
+                <tr class="subtotal"><td>{{t "report.total"}}</td>{{range .Catalogs}}<td>{{num .AvgConfidence}} / {{num .MedianConfidence}} ({{.Items}})</td>{{end}}<td>{{num .AvgConfidence}} ({{.TotalItems}})</td></tr>
+            </tbody>
+        </table>
+{{end}}{{end}}
+`
+
+// baseTemplates набор общих фрагментов, от которого клонируется каждый шаблон отчета
+var baseTemplates = template.Must(template.New("report_partials").Funcs(reportFuncs).Parse(reportPartials))
+
+// normalizationReportTemplate встроенный отчет о нормализации и классификации
+const normalizationReportTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    {{template "report_styles" .Branding.Color}}
+</head>
+<body>
+    <div class="container">
+        {{template "report_header" .}}
+
+        {{with .Stats.Summary}}
+        <h2>📈 {{t "report.overall_stats"}}</h2>
+        <div class="stats">
+            <div class="stat-card">
+                <div class="stat-value">{{.TotalItems}}</div>
+                <div class="stat-label">{{t "report.total_items"}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-value">{{.NormalizedCount}}</div>
+                <div class="stat-label">{{t "report.normalized"}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-value">{{.UniqueCategories}}</div>
+                <div class="stat-label">{{t "report.unique_categories"}}</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-value">{{.KpvedCount}}</div>
+                <div class="stat-label">{{t "report.with_kpved"}}</div>
+            </div>
+        </div>
+
+        <h2>📋 {{t "report.normalization_progress"}}</h2>
+        <div class="progress">
+            <div class="progress-bar" style="width: {{.NormalizedPercent}}%">{{num .NormalizedPercent}}%</div>
+        </div>
+
+        <h2>🏷️ {{t "report.kpved_progress"}}</h2>
+        <div class="progress">
+            <div class="progress-bar" style="width: {{.KpvedPercent}}%">{{num .KpvedPercent}}%</div>
+        </div>
+        {{end}}
+
+        <h2>📊 {{t "report.top_categories" 20}}</h2>
+        <table>
+            <thead>
+                <tr>
+                    <th>{{t "report.number"}}</th>
+                    <th>{{t "report.category"}}</th>
+                    <th>{{t "report.count"}}</th>
+                    <th>{{t "report.percent"}}</th>
+                </tr>
+            </thead>
+            <tbody>
+                {{range $i, $cat := .Stats.TopCategories 20}}
+                <tr>
+                    <td>{{add $i 1}}</td>
+                    <td>{{$cat.Name}}</td>
+                    <td>{{$cat.Count}}</td>
+                    <td>{{printf "%.1f" $cat.Percent}}%</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+
+        <h2>📦 {{t "report.normalized_examples"}}</h2>
+        {{range .Stats.Examples 30}}
+        <div class="example">
+            <strong>{{t "report.source"}}:</strong> {{.Source}}<br>
+            <strong>{{t "report.normalized_value"}}:</strong> {{.Normalized}}<br>
+            <span class="category">{{t "report.category"}}:</span> {{.Category}} | <strong>{{t "report.code"}}:</strong> {{.Code}}
+        </div>
+        {{end}}
+
+        {{with .Stats.Summary}}
+        <h2>✨ {{t "report.additional_stats"}}</h2>
+        <ul>
+            <li>{{t "report.changed_names"}}: {{.ChangedCount}} ({{printf "%.1f" .ChangedPercent}}%)</li>
+            <li>{{t "report.merged_records"}}: {{.MergedCount}}</li>
+        </ul>
+        {{end}}
+
+        {{template "report_footer" .}}
+    </div>
+</body>
+</html>`
+
+// kpvedSectionsReportTemplate встроенный отчет по разделам КПВЭД с брендированием
+const kpvedSectionsReportTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    {{template "report_styles" .Branding.Color}}
+</head>
+<body>
+    <div class="container">
+        {{template "report_header" .}}
+        {{template "kpved_content" .Stats.Kpved}}
+        {{template "confidence_heatmap" .Stats.ConfidenceHeatmap}}
+        {{template "report_footer" .}}
+    </div>
+</body>
+</html>`
+
+// qualityReportTemplate встроенный отчет о качестве нормализованных данных
+const qualityReportTemplate = `<!DOCTYPE html>
+<html lang="{{lang}}">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    {{template "report_styles" .Branding.Color}}
+</head>
+<body>
+    <div class="container">
+        {{template "report_header" .}}
+
+        {{with .Stats.Summary}}
+        <div class="stats">
+            <div class="stat-card"><div class="stat-value">{{.NormalizedCount}}</div><div class="stat-label">{{t "report.normalized"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{num .KpvedPercent}}%</div><div class="stat-label">{{t "report.with_kpved"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{.MergedCount}}</div><div class="stat-label">{{t "report.merged_records"}}</div></div>
+        </div>
+        {{end}}
+
+        {{with .Stats.Quality}}
+        <div class="stats">
+            <div class="stat-card"><div class="stat-value">{{num .AvgQualityScore}}</div><div class="stat-label">{{t "report.avg_quality"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{.LowQualityItems}}</div><div class="stat-label">{{t "report.low_quality"}}</div></div>
+            <div class="stat-card"><div class="stat-value">{{.DuplicateGroups}}</div><div class="stat-label">{{t "report.duplicate_groups"}}</div></div>
+        </div>
+
+        <h2>{{t "report.decisions"}}</h2>
+        <table>
+            <thead><tr><th>{{t "report.decision"}}</th><th>{{t "report.records"}}</th></tr></thead>
+            <tbody>
+            {{range $decision, $count := .Decisions}}<tr><td>{{$decision}}</td><td>{{$count}}</td></tr>{{end}}
+            </tbody>
+        </table>
+
+        <h2>{{t "report.open_violations"}}</h2>
+        {{if .OpenViolations}}
+        <table>
+            <thead><tr><th>{{t "report.severity"}}</th><th>{{t "report.violations"}}</th></tr></thead>
+            <tbody>
+            {{range $severity, $count := .OpenViolations}}<tr><td{{if or (eq $severity "error") (eq $severity "critical")}} class="low"{{end}}>{{$severity}}</td><td>{{$count}}</td></tr>{{end}}
+            </tbody>
+        </table>
+        {{else}}
+        <p>{{t "report.no_violations"}}</p>
+        {{end}}
+        {{end}}
+
+        {{template "report_footer" .}}
+    </div>
+</body>
+</html>`
+
+// builtinTemplate встроенный шаблон отчета
+type builtinTemplate struct {
+	description string
+	title       string // ключ каталога сообщений для заголовка отчета
+	content     string
+}
+
+// builtinTemplates встроенные шаблоны (kpved - отчет о классификации); шаблоны с тем же именем на диске или в сервисной БД их переопределяют
+var builtinTemplates = map[string]builtinTemplate{
+	"normalization": {
+		description: "Отчет о нормализации и классификации",
+		title:       "report.normalization.title",
+		content:     normalizationReportTemplate,
+	},
+	"kpved": {
+		description: "Отчет о классификации по разделам и классам КПВЭД",
+		title:       "report.kpved.title",
+		content:     kpvedSectionsReportTemplate,
+	},
+	"quality": {
+		description: "Отчет о качестве нормализованных данных",
+		title:       "report.quality.title",
+		content:     qualityReportTemplate,
+	},
+}
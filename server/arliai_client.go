@@ -2,18 +2,20 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"time"
+
+	"httpserver/nomenclature"
 )
 
 // ArliaiClient клиент для работы с Arliai API
 type ArliaiClient struct {
 	baseURL    string
+	apiKeyMu   sync.RWMutex
 	apiKey     string
 	httpClient *http.Client
 	retryConfig RetryConfig
@@ -88,7 +90,7 @@ func NewArliaiClient() *ArliaiClient {
 		log.Printf("Warning: Arliai configuration validation failed: %v", err)
 	}
 
-	return &ArliaiClient{
+	client := &ArliaiClient{
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
@@ -101,171 +103,45 @@ func NewArliaiClient() *ArliaiClient {
 			BackoffMultiplier: 2.0,
 		},
 	}
-}
-
-// CheckConnection проверяет подключение к Arliai API с повторными попытками
-func (c *ArliaiClient) CheckConnection(ctx context.Context, requestID string) (*ArliaiStatusResponse, error) {
-	startTime := time.Now()
-	
-	url := fmt.Sprintf("%s/health", c.baseURL)
-	
-	var lastErr error
-	delay := c.retryConfig.InitialDelay
-	
-	for attempt := 0; attempt <= c.retryConfig.MaxRetries; attempt++ {
-		if attempt > 0 {
-			log.Printf("[%s] Retry attempt %d/%d after %v", requestID, attempt, c.retryConfig.MaxRetries, delay)
-			select {
-			case <-ctx.Done():
-				return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
-			case <-time.After(delay):
-			}
-			delay = time.Duration(float64(delay) * c.retryConfig.BackoffMultiplier)
-			if delay > c.retryConfig.MaxDelay {
-				delay = c.retryConfig.MaxDelay
-			}
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		if c.apiKey != "" {
-			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
-		}
-		req.Header.Set("X-Request-ID", requestID)
-		req.Header.Set("Content-Type", "application/json")
 
-		log.Printf("[%s] Checking Arliai connection (attempt %d/%d)", requestID, attempt+1, c.retryConfig.MaxRetries+1)
-		
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			log.Printf("[%s] Connection check failed: %v", requestID, lastErr)
-			continue
-		}
+	// Проверки статуса и список моделей тоже обслуживает фейковый провайдер
+	if nomenclature.FakeProviderEnabled() {
+		client.httpClient.Transport = nomenclature.SharedFakeProvider()
+	}
 
-		duration := time.Since(startTime)
-		
-		if resp.StatusCode == http.StatusOK {
-			defer resp.Body.Close()
-			
-			var statusResp ArliaiStatusResponse
-			if err := json.NewDecoder(resp.Body).Decode(&statusResp); err != nil {
-				lastErr = fmt.Errorf("failed to decode response: %w", err)
-				log.Printf("[%s] Failed to decode response: %v", requestID, lastErr)
-				resp.Body.Close()
-				continue
-			}
+	return client
+}
 
-			statusResp.Timestamp = time.Now()
-			log.Printf("[%s] Connection check successful (duration: %v, status: %s)", requestID, duration, statusResp.Status)
-			return &statusResp, nil
-		}
+// SetAPIKey заменяет API ключ; применяется со следующего запроса
+func (c *ArliaiClient) SetAPIKey(apiKey string) {
+	c.apiKeyMu.Lock()
+	c.apiKey = apiKey
+	c.apiKeyMu.Unlock()
+}
 
-		resp.Body.Close()
-		
-		if resp.StatusCode >= 500 {
-			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
-			log.Printf("[%s] Server error %d, will retry", requestID, resp.StatusCode)
-			continue
-		}
+// currentAPIKey возвращает действующий API ключ
+func (c *ArliaiClient) currentAPIKey() string {
+	c.apiKeyMu.RLock()
+	defer c.apiKeyMu.RUnlock()
+	return c.apiKey
+}
 
-		// 4xx ошибки не повторяем
-		lastErr = fmt.Errorf("client error: %d", resp.StatusCode)
-		log.Printf("[%s] Client error %d, not retrying", requestID, resp.StatusCode)
-		break
-	}
+// arliaiTransport выполняет запросы ArliaiClient к провайдеру. Реализация выбирается при сборке:
+// arliaiHTTPTransport (arliai_transport.go) опрашивает API, в сборке с тегом no_ai arliaiDisabledTransport
+// (arliai_transport_disabled.go) отвечает ErrAIDisabled без кода запросов
+type arliaiTransport interface {
+	checkConnection(c *ArliaiClient, ctx context.Context, requestID string) (*ArliaiStatusResponse, error)
+	models(c *ArliaiClient, ctx context.Context, requestID string) ([]ArliaiModel, error)
+}
 
-	return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
+// CheckConnection проверяет подключение к Arliai API с повторными попытками
+func (c *ArliaiClient) CheckConnection(ctx context.Context, requestID string) (*ArliaiStatusResponse, error) {
+	return arliaiProvider.checkConnection(c, ctx, requestID)
 }
 
 // GetModels получает список моделей с повторными попытками
 func (c *ArliaiClient) GetModels(ctx context.Context, requestID string) ([]ArliaiModel, error) {
-	startTime := time.Now()
-	
-	url := fmt.Sprintf("%s/models", c.baseURL)
-	
-	var lastErr error
-	delay := c.retryConfig.InitialDelay
-	
-	for attempt := 0; attempt <= c.retryConfig.MaxRetries; attempt++ {
-		if attempt > 0 {
-			log.Printf("[%s] Retry attempt %d/%d for models after %v", requestID, attempt, c.retryConfig.MaxRetries, delay)
-			select {
-			case <-ctx.Done():
-				return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
-			case <-time.After(delay):
-			}
-			delay = time.Duration(float64(delay) * c.retryConfig.BackoffMultiplier)
-			if delay > c.retryConfig.MaxDelay {
-				delay = c.retryConfig.MaxDelay
-			}
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-
-		if c.apiKey != "" {
-			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
-		}
-		req.Header.Set("X-Request-ID", requestID)
-		req.Header.Set("Content-Type", "application/json")
-
-		log.Printf("[%s] Fetching models (attempt %d/%d)", requestID, attempt+1, c.retryConfig.MaxRetries+1)
-		
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			lastErr = fmt.Errorf("request failed: %w", err)
-			log.Printf("[%s] Models fetch failed: %v", requestID, lastErr)
-			continue
-		}
-
-		duration := time.Since(startTime)
-		
-		if resp.StatusCode == http.StatusOK {
-			defer resp.Body.Close()
-			
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				lastErr = fmt.Errorf("failed to read response: %w", err)
-				log.Printf("[%s] Failed to read response: %v", requestID, lastErr)
-				continue
-			}
-
-			var modelsResp ArliaiModelsResponse
-			if err := json.Unmarshal(body, &modelsResp); err != nil {
-				// Пробуем альтернативный формат (прямой массив)
-				var models []ArliaiModel
-				if err2 := json.Unmarshal(body, &models); err2 != nil {
-					lastErr = fmt.Errorf("failed to decode response: %w (also tried array format: %v)", err, err2)
-					log.Printf("[%s] Failed to decode models response: %v", requestID, lastErr)
-					continue
-				}
-				modelsResp.Models = models
-			}
-
-			log.Printf("[%s] Models fetched successfully (duration: %v, count: %d)", requestID, duration, len(modelsResp.Models))
-			return modelsResp.Models, nil
-		}
-
-		resp.Body.Close()
-		
-		if resp.StatusCode >= 500 {
-			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
-			log.Printf("[%s] Server error %d, will retry", requestID, resp.StatusCode)
-			continue
-		}
-
-		lastErr = fmt.Errorf("client error: %d", resp.StatusCode)
-		log.Printf("[%s] Client error %d, not retrying", requestID, resp.StatusCode)
-		break
-	}
-
-	return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
+	return arliaiProvider.models(c, ctx, requestID)
 }
 
 // GenerateTraceID генерирует уникальный trace ID
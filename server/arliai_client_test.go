@@ -6,9 +6,14 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"httpserver/features"
 )
 
 func TestArliaiClient_CheckConnection(t *testing.T) {
+	if !features.AI {
+		t.Skip("AI subsystem is not included in this build")
+	}
 	// Мок сервер для тестирования
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/health" {
@@ -50,6 +55,9 @@ func TestArliaiClient_CheckConnection(t *testing.T) {
 }
 
 func TestArliaiClient_CheckConnection_Retry(t *testing.T) {
+	if !features.AI {
+		t.Skip("AI subsystem is not included in this build")
+	}
 	attempts := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		attempts++
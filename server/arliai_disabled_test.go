@@ -0,0 +1,22 @@
+//go:build no_ai
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"httpserver/features"
+)
+
+func TestArliaiClientDisabledBuild(t *testing.T) {
+	client := NewArliaiClient()
+
+	if _, err := client.CheckConnection(context.Background(), "req-1"); !errors.Is(err, features.ErrAIDisabled) {
+		t.Errorf("CheckConnection() error = %v, want ErrAIDisabled", err)
+	}
+	if _, err := client.GetModels(context.Background(), "req-2"); !errors.Is(err, features.ErrAIDisabled) {
+		t.Errorf("GetModels() error = %v, want ErrAIDisabled", err)
+	}
+}
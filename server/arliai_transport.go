@@ -0,0 +1,184 @@
+//go:build !no_ai
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// arliaiProvider в полной сборке опрашивает Arliai API
+var arliaiProvider arliaiTransport = arliaiHTTPTransport{}
+
+// arliaiHTTPTransport выполняет запросы к Arliai API с повторными попытками по retryConfig клиента
+type arliaiHTTPTransport struct{}
+
+// checkConnection проверяет подключение к Arliai API с повторными попытками
+func (arliaiHTTPTransport) checkConnection(c *ArliaiClient, ctx context.Context, requestID string) (*ArliaiStatusResponse, error) {
+	startTime := time.Now()
+
+	url := fmt.Sprintf("%s/health", c.baseURL)
+
+	var lastErr error
+	delay := c.retryConfig.InitialDelay
+
+	for attempt := 0; attempt <= c.retryConfig.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("[%s] Retry attempt %d/%d after %v", requestID, attempt, c.retryConfig.MaxRetries, delay)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+			delay = time.Duration(float64(delay) * c.retryConfig.BackoffMultiplier)
+			if delay > c.retryConfig.MaxDelay {
+				delay = c.retryConfig.MaxDelay
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		if apiKey := c.currentAPIKey(); apiKey != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+		}
+		req.Header.Set("X-Request-ID", requestID)
+		req.Header.Set("Content-Type", "application/json")
+
+		log.Printf("[%s] Checking Arliai connection (attempt %d/%d)", requestID, attempt+1, c.retryConfig.MaxRetries+1)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("request failed: %w", err)
+			log.Printf("[%s] Connection check failed: %v", requestID, lastErr)
+			continue
+		}
+
+		duration := time.Since(startTime)
+
+		if resp.StatusCode == http.StatusOK {
+			defer resp.Body.Close()
+
+			var statusResp ArliaiStatusResponse
+			if err := json.NewDecoder(resp.Body).Decode(&statusResp); err != nil {
+				lastErr = fmt.Errorf("failed to decode response: %w", err)
+				log.Printf("[%s] Failed to decode response: %v", requestID, lastErr)
+				resp.Body.Close()
+				continue
+			}
+
+			statusResp.Timestamp = time.Now()
+			log.Printf("[%s] Connection check successful (duration: %v, status: %s)", requestID, duration, statusResp.Status)
+			return &statusResp, nil
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
+			log.Printf("[%s] Server error %d, will retry", requestID, resp.StatusCode)
+			continue
+		}
+
+		// 4xx ошибки не повторяем
+		lastErr = fmt.Errorf("client error: %d", resp.StatusCode)
+		log.Printf("[%s] Client error %d, not retrying", requestID, resp.StatusCode)
+		break
+	}
+
+	return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
+}
+
+// models получает список моделей с повторными попытками
+func (arliaiHTTPTransport) models(c *ArliaiClient, ctx context.Context, requestID string) ([]ArliaiModel, error) {
+	startTime := time.Now()
+
+	url := fmt.Sprintf("%s/models", c.baseURL)
+
+	var lastErr error
+	delay := c.retryConfig.InitialDelay
+
+	for attempt := 0; attempt <= c.retryConfig.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("[%s] Retry attempt %d/%d for models after %v", requestID, attempt, c.retryConfig.MaxRetries, delay)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("context cancelled: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+			delay = time.Duration(float64(delay) * c.retryConfig.BackoffMultiplier)
+			if delay > c.retryConfig.MaxDelay {
+				delay = c.retryConfig.MaxDelay
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		if apiKey := c.currentAPIKey(); apiKey != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+		}
+		req.Header.Set("X-Request-ID", requestID)
+		req.Header.Set("Content-Type", "application/json")
+
+		log.Printf("[%s] Fetching models (attempt %d/%d)", requestID, attempt+1, c.retryConfig.MaxRetries+1)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("request failed: %w", err)
+			log.Printf("[%s] Models fetch failed: %v", requestID, lastErr)
+			continue
+		}
+
+		duration := time.Since(startTime)
+
+		if resp.StatusCode == http.StatusOK {
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				lastErr = fmt.Errorf("failed to read response: %w", err)
+				log.Printf("[%s] Failed to read response: %v", requestID, lastErr)
+				continue
+			}
+
+			var modelsResp ArliaiModelsResponse
+			if err := json.Unmarshal(body, &modelsResp); err != nil {
+				// Пробуем альтернативный формат (прямой массив)
+				var models []ArliaiModel
+				if err2 := json.Unmarshal(body, &models); err2 != nil {
+					lastErr = fmt.Errorf("failed to decode response: %w (also tried array format: %v)", err, err2)
+					log.Printf("[%s] Failed to decode models response: %v", requestID, lastErr)
+					continue
+				}
+				modelsResp.Models = models
+			}
+
+			log.Printf("[%s] Models fetched successfully (duration: %v, count: %d)", requestID, duration, len(modelsResp.Models))
+			return modelsResp.Models, nil
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
+			log.Printf("[%s] Server error %d, will retry", requestID, resp.StatusCode)
+			continue
+		}
+
+		lastErr = fmt.Errorf("client error: %d", resp.StatusCode)
+		log.Printf("[%s] Client error %d, not retrying", requestID, resp.StatusCode)
+		break
+	}
+
+	return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
+}
@@ -0,0 +1,23 @@
+//go:build no_ai
+
+package server
+
+import (
+	"context"
+
+	"httpserver/features"
+)
+
+// arliaiProvider в сборке без AI провайдер не опрашивает
+var arliaiProvider arliaiTransport = arliaiDisabledTransport{}
+
+// arliaiDisabledTransport заглушка запросов к Arliai API
+type arliaiDisabledTransport struct{}
+
+func (arliaiDisabledTransport) checkConnection(c *ArliaiClient, ctx context.Context, requestID string) (*ArliaiStatusResponse, error) {
+	return nil, features.ErrAIDisabled
+}
+
+func (arliaiDisabledTransport) models(c *ArliaiClient, ctx context.Context, requestID string) ([]ArliaiModel, error) {
+	return nil, features.ErrAIDisabled
+}
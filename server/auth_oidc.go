@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// OIDCConfig настройки входа через провайдера OpenID Connect (Keycloak, ADFS, Azure AD и т.п.)
+type OIDCConfig struct {
+	IssuerURL    string // Адрес провайдера, по нему читается /.well-known/openid-configuration
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // Адрес /api/auth/oidc/callback сервера, зарегистрированный у провайдера
+	GroupsClaim  string // Поле userinfo со списком групп пользователя
+	// Роли сервера для групп провайдера; пользователь получает наибольшую роль из своих групп
+	RoleMapping map[string]string
+	DefaultRole string // Роль пользователя без сопоставленных групп; пустая - вход запрещен
+}
+
+// Enabled проверяет, настроен ли вход через OIDC
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+// parseRoleMapping разбирает сопоставление групп и ролей вида "nsi-admins=admin;nsi-operators=operator".
+// Разделитель пар - ";", так как имена групп (DN) могут содержать запятые и "="
+func parseRoleMapping(value string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(value, ";") {
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			continue
+		}
+		if group := strings.TrimSpace(pair[:i]); group != "" {
+			mapping[group] = strings.TrimSpace(pair[i+1:])
+		}
+	}
+	return mapping
+}
+
+// roleForGroups возвращает роль пользователя по группам провайдера; пустая строка - нет роли
+func (c OIDCConfig) roleForGroups(groups []string) string {
+	role := ""
+	for _, group := range groups {
+		role = database.HigherRole(role, c.RoleMapping[group])
+	}
+	if role == "" {
+		return c.DefaultRole
+	}
+	return role
+}
+
+// oidcTimeout время ожидания ответа провайдера OIDC
+const oidcTimeout = 10 * time.Second
+
+// oidcDiscovery адреса провайдера из /.well-known/openid-configuration
+type oidcDiscovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// oidcUserInfo сведения о пользователе из userinfo провайдера
+type oidcUserInfo struct {
+	Subject           string
+	PreferredUsername string
+	Email             string
+	Name              string
+	Groups            []string
+}
+
+// claimStrings возвращает значения поля userinfo: массив строк или строку с группами через запятую
+func claimStrings(value interface{}) []string {
+	var values []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "" {
+				values = append(values, str)
+			}
+		}
+	case string:
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// oidcClient вход через OIDC по коду авторизации с PKCE. Пользователь определяется запросом
+// userinfo с полученным токеном доступа, поэтому подпись ID токена не проверяется
+type oidcClient struct {
+	config     OIDCConfig
+	httpClient *http.Client
+}
+
+// newOIDCClient создает клиента провайдера OIDC
+func newOIDCClient(config OIDCConfig) *oidcClient {
+	return &oidcClient{config: config, httpClient: &http.Client{Timeout: oidcTimeout}}
+}
+
+// discover читает адреса провайдера
+func (c *oidcClient) discover(ctx context.Context) (*oidcDiscovery, error) {
+	endpoint := strings.TrimRight(c.config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var discovery oidcDiscovery
+	if err := c.getJSON(ctx, endpoint, "", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("OIDC provider configuration is incomplete")
+	}
+	return &discovery, nil
+}
+
+// newOIDCVerifier возвращает случайные state и code_verifier PKCE
+func newOIDCVerifier() (string, string, error) {
+	buf := make([]byte, 64)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate OIDC state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf[:32]), base64.RawURLEncoding.EncodeToString(buf[32:]), nil
+}
+
+// authorizationURL адрес страницы входа провайдера
+func (c *oidcClient) authorizationURL(discovery *oidcDiscovery, state, verifier string) string {
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.config.ClientID},
+		"redirect_uri":          {c.config.RedirectURL},
+		"scope":                 {"openid profile email"},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// exchange обменивает код авторизации на токен доступа и возвращает сведения о пользователе
+func (c *oidcClient) exchange(ctx context.Context, discovery *oidcDiscovery, code, verifier string) (*oidcUserInfo, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.config.RedirectURL},
+		"client_id":     {c.config.ClientID},
+		"code_verifier": {verifier},
+	}
+	if c.config.ClientSecret != "" {
+		form.Set("client_secret", c.config.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.doJSON(req, &token); err != nil {
+		return nil, fmt.Errorf("failed to exchange OIDC code: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("OIDC provider returned no access token")
+	}
+	return c.userInfo(ctx, discovery, token.AccessToken)
+}
+
+// userInfo возвращает сведения о пользователе по токену доступа провайдера
+func (c *oidcClient) userInfo(ctx context.Context, discovery *oidcDiscovery, accessToken string) (*oidcUserInfo, error) {
+	var claims map[string]interface{}
+	if err := c.getJSON(ctx, discovery.UserinfoEndpoint, accessToken, &claims); err != nil {
+		return nil, fmt.Errorf("failed to get OIDC user info: %w", err)
+	}
+	claim := func(name string) string {
+		value, _ := claims[name].(string)
+		return value
+	}
+	info := &oidcUserInfo{
+		Subject:           claim("sub"),
+		PreferredUsername: claim("preferred_username"),
+		Email:             claim("email"),
+		Name:              claim("name"),
+	}
+	if c.config.GroupsClaim != "" {
+		info.Groups = claimStrings(claims[c.config.GroupsClaim])
+	}
+	if info.Subject == "" {
+		return nil, fmt.Errorf("OIDC user info has no subject")
+	}
+	return info, nil
+}
+
+// getJSON выполняет GET запрос к провайдеру, при наличии токена - с авторизацией Bearer
+func (c *oidcClient) getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return c.doJSON(req, out)
+}
+
+// doJSON выполняет запрос и разбирает JSON ответ
+func (c *oidcClient) doJSON(req *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
@@ -4,13 +4,24 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"httpserver/database"
+	"httpserver/encryption"
+	"httpserver/nomenclature"
+	"httpserver/queue"
+	"httpserver/storage"
+	"httpserver/tracing"
 )
 
 // Config конфигурация сервера
 type Config struct {
 	// Сервер
 	Port string
+	// Демо-режим: отдельные демо БД с образцами данных (клиент, проект, классификатор, синтетическая выгрузка),
+	// фейковый AI провайдер и все подсистемы включены
+	DemoMode bool
 
 	// Базы данных
 	DatabasePath           string
@@ -21,45 +32,308 @@ type Config struct {
 	// AI конфигурация
 	ArliaiAPIKey string
 	ArliaiModel  string
+	// AI провайдер: arliai (по умолчанию) или fake - детерминированный фейковый провайдер
+	// для тестов без реальных ключей (настраивается через AI_FAKE_*)
+	AIProvider string
+	// Глобальный максимум AI воркеров (0 - значение из конфигурации воркеров)
+	AIMaxWorkers int
 
 	// Connection pooling
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// Пул только для чтения для аналитики и экспорта (0 - отключен)
+	ReadPoolMaxOpenConns int
+	// Кэш БД выгрузок: ограничение одновременно открытых файлов (0 - без ограничения), закрытие по простою (0 - отключено)
+	// и задержка закрытия вытесненных БД для уже начатых запросов (0 - закрываются сразу)
+	UploadDBMaxOpen     int
+	UploadDBIdleTimeout time.Duration
+	UploadDBCloseGrace  time.Duration
+	// Тестовые выгрузки (sandbox в рукопожатии): время жизни временной БД и ограничение одновременных выгрузок
+	SandboxUploadTTL  time.Duration
+	SandboxMaxUploads int
+	// Ограничение времени запросов к БД в рамках HTTP запроса (0 - без ограничения)
+	DBQueryTimeout time.Duration
+	// Ожидание завершения фоновых задач при остановке сервера
+	ShutdownGracePeriod time.Duration
+	// Перезапуск долгоживущих фоновых воркеров после паники
+	RestartCrashedWorkers bool
+	// Продолжение запусков классификации КПВЭД, прерванных остановкой сервера, сразу после старта
+	KpvedAutoResume bool
+	// Повтор классификации записей, завершившейся временной ошибкой: интервал проверки очереди
+	// (0 - повторы отключены), задержка первого повтора (удваивается с каждой попыткой) и число попыток
+	ClassificationRetryInterval    time.Duration
+	ClassificationRetryBaseDelay   time.Duration
+	ClassificationRetryMaxAttempts int
+
+	// Несколько экземпляров сервера с общей service.db: идентификатор экземпляра (пустой - hostname-pid),
+	// интервал heartbeat и срок аренды фоновых задач, не продленной heartbeat
+	InstanceID               string
+	ClusterHeartbeatInterval time.Duration
+	ClusterLeaseTTL          time.Duration
+	// Ретрансляция событий SSE (нормализация, мониторинг) между экземплярами через service.db
+	ClusterEventRelay    bool
+	ClusterRelayInterval time.Duration
+
+	// Запросы дольше порога попадают в журнал медленных запросов (0 - журнал отключен)
+	SlowRequestThreshold time.Duration
+	// Число хранимых записей журнала медленных запросов
+	SlowRequestLogSize int
 
 	// Логирование
 	LogBufferSize int
+	LogLevel      string // debug, info, warn, error
+
+	// Срок хранения сохраненных метрик производительности в днях
+	MetricsRetentionDays int
+
+	// Глубина запросов состояния нормализованных данных на момент времени (as_of) в днях, 0 - без ограничения
+	TimeTravelRetentionDays int
+
+	// Срок хранения журнала изменений нормализованных данных (/api/normalized/changes) в днях, 0 - без очистки.
+	// Курсоры старше очищенной части журнала считаются устаревшими
+	NormalizedChangesRetentionDays int
 
 	// Нормализация
 	NormalizerEventsBufferSize int
+	// Число последних событий нормализации, хранимых в истории service.db (0 - история не ведется)
+	NormalizerEventsHistoryLimit int
+
+	// Классификация по подтвержденным решениям перед вызовом AI
+	HistoricalClassificationEnabled bool
+	HistoricalMinSimilarity         float64 // Минимальное сходство наименований (0..1)
+
+	// Аналитика
+	UploadRollupInterval  time.Duration // Интервал пересчета дневных сводок по выгрузкам
+	UploadRecountInterval time.Duration // Интервал сверки счетчиков выгрузок с фактическими данными (0 - отключено)
+
+	// Отчеты
+	ReportTemplatesDir      string        // Каталог пользовательских шаблонов отчетов (*.html)
+	ReportArtifactsDir      string        // Каталог отчетов, сформированных по расписанию
+	ReportSchedulerInterval time.Duration // Интервал проверки расписаний отчетов (0 - планировщик отключен)
+
+	// Хранилище крупных артефактов (отчеты, резервные копии): local или s3 (AWS S3, MinIO)
+	Storage           storage.Config
+	StoragePresignTTL time.Duration // Срок действия ссылок на скачивание
+
+	// Максимальный размер файла вложения элемента справочника (изображение, PDF спецификации), МБ
+	AttachmentMaxSizeMB int
+
+	// Проверка элементов справочников по метаданным 1С при загрузке:
+	// off - не проверять, warn - логировать, record - записывать проблемы качества, strict - дополнительно отклонять элемент
+	IngestValidationMode string
+
+	// Сверка полноты выгрузки при /complete (объявленные 1С количества, непрерывность пакетов):
+	// off - не проверять, warn - завершать со статусом completed_with_warnings, strict - отклонять завершение
+	UploadCompletenessMode string
+
+	// Webhook для событий выгрузок (аномалии статистики приема), пустой - не отправлять
+	EventsWebhookURL string
+
+	// Минимальная поддерживаемая версия протокола выгрузки, клиентам старых версий отвечается 426 (0 - минимальная версия сервера)
+	MinProtocolVersion int
+
+	// Асинхронный прием пакетов выгрузок из очереди сообщений (пустой backend - отключен)
+	IngestQueue queue.Config
+
+	// Трассировка запросов с экспортом в коллектор OpenTelemetry (пустой endpoint - отключена)
+	Tracing tracing.Config
+
+	// Ограничения скорости приема выгрузок для клиентов без собственных ограничений (0 - без ограничения)
+	IngestItemsPerSecond float64
+	IngestMBPerSecond    float64
+
+	// Учетные записи веб-интерфейса: срок сессии и флаг Secure для cookie сессии (при работе через HTTPS)
+	SessionTTL          time.Duration
+	SessionCookieSecure bool
+	// Вход через OpenID Connect (пустой issuer - отключен)
+	OIDC OIDCConfig
+	// Только вход через SSO: вход по паролю пользователей из БД отключен, остается аварийная учетная запись
+	SSOOnly bool
+	// Аварийная учетная запись администратора на случай недоступности провайдера SSO:
+	// имя и хеш пароля (cmd/hash_password), задаются для каждой установки
+	BreakGlassUsername     string
+	BreakGlassPasswordHash string
+	// Токен установки для создания первого администратора через POST /api/users (пустой -
+	// первый администратор создается только с аварийной учетной записью)
+	AuthSetupToken string
+
+	// Шифрование данных выгрузок проектов: мастер-ключи "id:base64,..." (пустые - шифрование недоступно),
+	// мастер-ключ для новых ключей данных (пустой - первый) и минимальная роль, получающая данные расшифрованными
+	EncryptionMasterKeys  string
+	EncryptionMasterKeyID string
+	EncryptionDecryptRole string
+
+	// Профиль скрытия колонок для запросов без сессии: обработки 1С, скрипты (пустой - данные без скрытия)
+	RedactionDefaultProfile string
+
+	// Почта для рассылки отчетов
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
 }
 
-// LoadConfig загружает конфигурацию из переменных окружения
+// LoadConfig загружает конфигурацию из переменных окружения. Если задан CONFIG_FILE,
+// переменные из файла (KEY=VALUE) предварительно переопределяют окружение процесса
 func LoadConfig() (*Config, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadEnvFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	demoMode := getEnvBool("DEMO_MODE", false)
 	config := &Config{
 		// Сервер
-		Port: getEnv("SERVER_PORT", "9999"),
+		Port:     getEnv("SERVER_PORT", "9999"),
+		DemoMode: demoMode,
 
 		// Базы данных
-		DatabasePath:           getEnv("DATABASE_PATH", "data.db"),
-		NormalizedDatabasePath: getEnv("NORMALIZED_DATABASE_PATH", "normalized_data.db"),
-		ServiceDatabasePath:    getEnv("SERVICE_DATABASE_PATH", "service.db"),
-		UnifiedCatalogsDBPath:  getEnv("UNIFIED_CATALOGS_DB_PATH", "unified_catalogs.db"),
+		DatabasePath:           getEnv("DATABASE_PATH", defaultDBPath(demoMode, "data.db")),
+		NormalizedDatabasePath: getEnv("NORMALIZED_DATABASE_PATH", defaultDBPath(demoMode, "normalized_data.db")),
+		ServiceDatabasePath:    getEnv("SERVICE_DATABASE_PATH", defaultDBPath(demoMode, "service.db")),
+		UnifiedCatalogsDBPath:  getEnv("UNIFIED_CATALOGS_DB_PATH", defaultDBPath(demoMode, "unified_catalogs.db")),
 
 		// AI конфигурация
 		ArliaiAPIKey: os.Getenv("ARLIAI_API_KEY"),
 		ArliaiModel:  getEnv("ARLIAI_MODEL", "GLM-4.5-Air"),
+		AIProvider:   getEnv("AI_PROVIDER", "arliai"),
+		AIMaxWorkers: getEnvInt("AI_MAX_WORKERS", 0),
 
 		// Connection pooling
 		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
 		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
 		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
 
+		ReadPoolMaxOpenConns: getEnvInt("DB_READ_POOL_MAX_CONNS", 10),
+
+		UploadDBMaxOpen:     getEnvInt("UPLOAD_DB_MAX_OPEN", 32),
+		UploadDBIdleTimeout: getEnvDuration("UPLOAD_DB_IDLE_TIMEOUT", 10*time.Minute),
+		UploadDBCloseGrace:  getEnvDuration("UPLOAD_DB_CLOSE_GRACE", time.Minute),
+
+		SandboxUploadTTL:  getEnvDuration("SANDBOX_UPLOAD_TTL", time.Hour),
+		SandboxMaxUploads: getEnvInt("SANDBOX_MAX_UPLOADS", 20),
+
+		DBQueryTimeout: getEnvDuration("DB_QUERY_TIMEOUT", 30*time.Second),
+
+		ShutdownGracePeriod:   getEnvDuration("SHUTDOWN_GRACE_PERIOD", 30*time.Second),
+		RestartCrashedWorkers: getEnvBool("RESTART_CRASHED_WORKERS", true),
+		KpvedAutoResume:       getEnvBool("KPVED_AUTO_RESUME", false),
+		SlowRequestThreshold:  getEnvDuration("SLOW_REQUEST_THRESHOLD", 2*time.Second),
+		SlowRequestLogSize:    getEnvInt("SLOW_REQUEST_LOG_SIZE", 10000),
+
+		ClassificationRetryInterval:    getEnvDuration("CLASSIFICATION_RETRY_INTERVAL", time.Minute),
+		ClassificationRetryBaseDelay:   getEnvDuration("CLASSIFICATION_RETRY_BASE_DELAY", time.Minute),
+		ClassificationRetryMaxAttempts: getEnvInt("CLASSIFICATION_RETRY_MAX_ATTEMPTS", 5),
+
+		InstanceID:               os.Getenv("INSTANCE_ID"),
+		ClusterHeartbeatInterval: getEnvDuration("CLUSTER_HEARTBEAT_INTERVAL", 10*time.Second),
+		ClusterLeaseTTL:          getEnvDuration("CLUSTER_LEASE_TTL", 45*time.Second),
+		ClusterEventRelay:        getEnvBool("CLUSTER_EVENT_RELAY", false),
+		ClusterRelayInterval:     getEnvDuration("CLUSTER_RELAY_INTERVAL", time.Second),
+
 		// Логирование
 		LogBufferSize: getEnvInt("LOG_BUFFER_SIZE", 100),
+		LogLevel:      strings.ToLower(getEnv("LOG_LEVEL", LogLevelInfo)),
+
+		MetricsRetentionDays:    getEnvInt("METRICS_RETENTION_DAYS", 7),
+		TimeTravelRetentionDays: getEnvInt("TIME_TRAVEL_RETENTION_DAYS", 90),
+
+		NormalizedChangesRetentionDays: getEnvInt("NORMALIZED_CHANGES_RETENTION_DAYS", 30),
 
 		// Нормализация
-		NormalizerEventsBufferSize: getEnvInt("NORMALIZER_EVENTS_BUFFER_SIZE", 100),
+		NormalizerEventsBufferSize:   getEnvInt("NORMALIZER_EVENTS_BUFFER_SIZE", 100),
+		NormalizerEventsHistoryLimit: getEnvInt("NORMALIZER_EVENTS_HISTORY_LIMIT", 10000),
+
+		HistoricalClassificationEnabled: getEnvBool("HISTORICAL_CLASSIFICATION_ENABLED", true),
+		HistoricalMinSimilarity:         getEnvFloat("HISTORICAL_MIN_SIMILARITY", 0.85),
+
+		// Аналитика
+		UploadRollupInterval:  getEnvDuration("UPLOAD_ROLLUP_INTERVAL", 15*time.Minute),
+		UploadRecountInterval: getEnvDuration("UPLOAD_RECOUNT_INTERVAL", 6*time.Hour),
+
+		// Отчеты
+		ReportTemplatesDir:      getEnv("REPORT_TEMPLATES_DIR", "report_templates"),
+		ReportArtifactsDir:      getEnv("REPORT_ARTIFACTS_DIR", "report_artifacts"),
+		ReportSchedulerInterval: getEnvDuration("REPORT_SCHEDULER_INTERVAL", time.Minute),
+
+		Storage: storage.Config{
+			Backend:         getEnv("STORAGE_BACKEND", storage.BackendLocal),
+			LocalDir:        getEnv("STORAGE_LOCAL_DIR", "storage"),
+			LocalSigningKey: os.Getenv("STORAGE_SIGNING_KEY"),
+			S3Endpoint:      os.Getenv("STORAGE_S3_ENDPOINT"),
+			S3Region:        getEnv("STORAGE_S3_REGION", "us-east-1"),
+			S3Bucket:        os.Getenv("STORAGE_S3_BUCKET"),
+			S3AccessKey:     os.Getenv("STORAGE_S3_ACCESS_KEY"),
+			S3SecretKey:     os.Getenv("STORAGE_S3_SECRET_KEY"),
+			S3PathStyle:     getEnvBool("STORAGE_S3_PATH_STYLE", false),
+			S3Prefix:        os.Getenv("STORAGE_S3_PREFIX"),
+		},
+		StoragePresignTTL: getEnvDuration("STORAGE_PRESIGN_TTL", storage.DefaultPresignTTL),
+
+		AttachmentMaxSizeMB: getEnvInt("ATTACHMENT_MAX_SIZE_MB", 50),
+
+		IngestValidationMode: getEnv("INGEST_VALIDATION_MODE", IngestValidationWarn),
+
+		UploadCompletenessMode: getEnv("UPLOAD_COMPLETENESS_MODE", UploadCompletenessWarn),
+
+		EventsWebhookURL: os.Getenv("EVENTS_WEBHOOK_URL"),
+
+		MinProtocolVersion: getEnvInt("MIN_PROTOCOL_VERSION", MinSupportedProtocolVersion),
+
+		IngestQueue: queue.Config{
+			Backend:    os.Getenv("INGEST_QUEUE_BACKEND"),
+			URL:        getEnv("INGEST_QUEUE_URL", "nats://localhost:4222"),
+			Subject:    getEnv("INGEST_QUEUE_SUBJECT", "httpserver.ingest"),
+			Group:      getEnv("INGEST_QUEUE_GROUP", "httpserver"),
+			RetryDelay: getEnvDuration("INGEST_QUEUE_RETRY_DELAY", 5*time.Second),
+		},
+		Tracing: tracing.Config{
+			Endpoint:      getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")),
+			ServiceName:   getEnv("OTEL_SERVICE_NAME", "httpserver"),
+			Headers:       tracing.ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+			SampleRatio:   getEnvFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+			BatchSize:     getEnvInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", tracing.DefaultBatchSize),
+			QueueSize:     getEnvInt("OTEL_BSP_MAX_QUEUE_SIZE", tracing.DefaultQueueSize),
+			FlushInterval: time.Duration(getEnvInt("OTEL_BSP_SCHEDULE_DELAY", 5000)) * time.Millisecond,
+		},
+		IngestItemsPerSecond: getEnvFloat("INGEST_ITEMS_PER_SECOND", 0),
+		IngestMBPerSecond:    getEnvFloat("INGEST_MB_PER_SECOND", 0),
+
+		SessionTTL:          getEnvDuration("SESSION_TTL", 12*time.Hour),
+		SessionCookieSecure: getEnvBool("SESSION_COOKIE_SECURE", false),
+		OIDC: OIDCConfig{
+			IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			GroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			RoleMapping:  parseRoleMapping(os.Getenv("OIDC_ROLE_MAPPING")),
+			DefaultRole:  getEnv("OIDC_DEFAULT_ROLE", database.RoleViewer),
+		},
+		SSOOnly:                getEnvBool("AUTH_SSO_ONLY", false),
+		BreakGlassUsername:     os.Getenv("BREAK_GLASS_USERNAME"),
+		BreakGlassPasswordHash: os.Getenv("BREAK_GLASS_PASSWORD_HASH"),
+		AuthSetupToken:         os.Getenv("AUTH_SETUP_TOKEN"),
+
+		EncryptionMasterKeys:  os.Getenv("ENCRYPTION_MASTER_KEYS"),
+		EncryptionMasterKeyID: os.Getenv("ENCRYPTION_MASTER_KEY_ID"),
+		EncryptionDecryptRole: getEnv("ENCRYPTION_DECRYPT_ROLE", database.RoleOperator),
+
+		RedactionDefaultProfile: os.Getenv("REDACTION_DEFAULT_PROFILE"),
+
+		// Почта для рассылки отчетов
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     os.Getenv("SMTP_FROM"),
+	}
+
+	if config.DemoMode {
+		config.applyDemoMode()
 	}
 
 	// Валидация
@@ -67,9 +341,34 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	// Фейковому провайдеру ключ не нужен, но обработчики проверяют ARLIAI_API_KEY перед запуском AI
+	if config.AIProvider == nomenclature.FakeProviderName && config.ArliaiAPIKey == "" {
+		config.ArliaiAPIKey = nomenclature.FakeProviderAPIKey
+		os.Setenv("ARLIAI_API_KEY", config.ArliaiAPIKey)
+	}
+
 	return config, nil
 }
 
+// defaultDBPath путь к БД по умолчанию; в демо-режиме демо БД не смешиваются с рабочими
+func defaultDBPath(demoMode bool, name string) string {
+	if demoMode {
+		return "demo_" + name
+	}
+	return name
+}
+
+// applyDemoMode включает фейковый AI провайдер и подсистемы, которые можно отключить переменными окружения
+func (c *Config) applyDemoMode() {
+	c.AIProvider = nomenclature.FakeProviderName
+	os.Setenv("AI_PROVIDER", nomenclature.FakeProviderName)
+	c.HistoricalClassificationEnabled = true
+	c.RestartCrashedWorkers = true
+	if c.SlowRequestThreshold <= 0 {
+		c.SlowRequestThreshold = 2 * time.Second
+	}
+}
+
 // Validate валидирует конфигурацию
 func (c *Config) Validate() error {
 	if c.Port == "" {
@@ -92,6 +391,89 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("unified catalogs database path is required")
 	}
 
+	switch c.AIProvider {
+	case "", "arliai", nomenclature.FakeProviderName:
+	default:
+		return fmt.Errorf("unsupported AI provider: %s", c.AIProvider)
+	}
+
+	switch c.Storage.Backend {
+	case "", storage.BackendLocal:
+	case storage.BackendS3:
+		if c.Storage.S3Bucket == "" {
+			return fmt.Errorf("STORAGE_S3_BUCKET is required for s3 storage")
+		}
+	default:
+		return fmt.Errorf("unsupported storage backend: %s", c.Storage.Backend)
+	}
+
+	if c.MinProtocolVersion < 0 || c.MinProtocolVersion > ProtocolVersionCurrent {
+		return fmt.Errorf("min protocol version must be between %d and %d", MinSupportedProtocolVersion, ProtocolVersionCurrent)
+	}
+
+	if c.SessionTTL < 0 {
+		return fmt.Errorf("session TTL cannot be negative")
+	}
+
+	if c.OIDC.IssuerURL != "" && (c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "") {
+		return fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required for OIDC login")
+	}
+
+	for group, role := range c.OIDC.RoleMapping {
+		if !database.ValidRole(role) {
+			return fmt.Errorf("OIDC role mapping for group %s has unknown role %s", group, role)
+		}
+	}
+
+	if c.OIDC.DefaultRole != "" && !database.ValidRole(c.OIDC.DefaultRole) {
+		return fmt.Errorf("unknown OIDC default role: %s", c.OIDC.DefaultRole)
+	}
+
+	if (c.BreakGlassUsername == "") != (c.BreakGlassPasswordHash == "") {
+		return fmt.Errorf("BREAK_GLASS_USERNAME and BREAK_GLASS_PASSWORD_HASH must be set together")
+	}
+
+	if c.BreakGlassPasswordHash != "" && !database.ValidPasswordHash(c.BreakGlassPasswordHash) {
+		return fmt.Errorf("BREAK_GLASS_PASSWORD_HASH must be generated by cmd/hash_password")
+	}
+
+	if c.SSOOnly && !c.OIDC.Enabled() {
+		return fmt.Errorf("AUTH_SSO_ONLY requires OIDC login to be configured")
+	}
+
+	if _, err := encryption.ParseKeyring(c.EncryptionMasterKeys, c.EncryptionMasterKeyID); err != nil {
+		return fmt.Errorf("invalid ENCRYPTION_MASTER_KEYS: %w", err)
+	}
+	if c.EncryptionDecryptRole != "" && !database.ValidRole(c.EncryptionDecryptRole) {
+		return fmt.Errorf("invalid ENCRYPTION_DECRYPT_ROLE: %s", c.EncryptionDecryptRole)
+	}
+
+	if c.IngestItemsPerSecond < 0 || c.IngestMBPerSecond < 0 {
+		return fmt.Errorf("ingest rate limits cannot be negative")
+	}
+
+	switch c.IngestQueue.Backend {
+	case "", queue.BackendNATS:
+	default:
+		return fmt.Errorf("unsupported ingest queue backend: %s", c.IngestQueue.Backend)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio must be between 0 and 1")
+	}
+
+	switch c.IngestValidationMode {
+	case "", IngestValidationOff, IngestValidationWarn, IngestValidationRecord, IngestValidationStrict:
+	default:
+		return fmt.Errorf("unsupported ingest validation mode: %s", c.IngestValidationMode)
+	}
+
+	switch c.UploadCompletenessMode {
+	case "", UploadCompletenessOff, UploadCompletenessWarn, UploadCompletenessStrict:
+	default:
+		return fmt.Errorf("unsupported upload completeness mode: %s", c.UploadCompletenessMode)
+	}
+
 	if c.MaxOpenConns <= 0 {
 		return fmt.Errorf("max open connections must be greater than 0")
 	}
@@ -104,6 +486,68 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max idle connections cannot be greater than max open connections")
 	}
 
+	if c.UploadDBMaxOpen < 0 {
+		return fmt.Errorf("upload database limit cannot be negative")
+	}
+
+	if c.UploadDBCloseGrace < 0 {
+		return fmt.Errorf("upload database close grace cannot be negative")
+	}
+
+	if c.SandboxUploadTTL < 0 {
+		return fmt.Errorf("sandbox upload TTL cannot be negative")
+	}
+
+	if c.SandboxMaxUploads < 0 {
+		return fmt.Errorf("sandbox upload limit cannot be negative")
+	}
+
+	if c.DBQueryTimeout < 0 {
+		return fmt.Errorf("database query timeout cannot be negative")
+	}
+
+	if c.ShutdownGracePeriod < 0 {
+		return fmt.Errorf("shutdown grace period cannot be negative")
+	}
+
+	if c.ClusterHeartbeatInterval <= 0 || c.ClusterLeaseTTL <= c.ClusterHeartbeatInterval {
+		return fmt.Errorf("cluster lease TTL must be greater than heartbeat interval")
+	}
+
+	if c.ClusterEventRelay && c.ClusterRelayInterval <= 0 {
+		return fmt.Errorf("cluster relay interval must be greater than 0")
+	}
+
+	if c.SlowRequestThreshold < 0 {
+		return fmt.Errorf("slow request threshold cannot be negative")
+	}
+
+	switch c.LogLevel {
+	case "", LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		return fmt.Errorf("unsupported log level: %s", c.LogLevel)
+	}
+
+	if c.AIMaxWorkers < 0 || c.AIMaxWorkers > 100 {
+		return fmt.Errorf("AI max workers must be between 0 and 100")
+	}
+
+	if c.MetricsRetentionDays <= 0 {
+		return fmt.Errorf("metrics retention must be greater than 0 days")
+	}
+
+	if c.TimeTravelRetentionDays < 0 {
+		return fmt.Errorf("time travel retention must not be negative")
+	}
+
+	if c.NormalizedChangesRetentionDays < 0 {
+		return fmt.Errorf("normalized changes retention must not be negative")
+	}
+
+	if c.AttachmentMaxSizeMB < 0 {
+		return fmt.Errorf("attachment max size must not be negative")
+	}
+
 	return nil
 }
 
@@ -125,6 +569,26 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvBool получает переменную окружения как bool или возвращает значение по умолчанию
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvFloat получает переменную окружения как float64 или возвращает значение по умолчанию
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 // getEnvDuration получает переменную окружения как Duration или возвращает значение по умолчанию
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
@@ -135,3 +599,30 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+// loadEnvFile устанавливает переменные окружения из файла: строки KEY=VALUE,
+// комментарии "#", необязательный префикс "export " и кавычки вокруг значения
+func loadEnvFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("config file %s:%d: expected KEY=VALUE", path, i+1)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("config file %s:%d: %w", path, i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"httpserver/database"
+)
+
+// Типы задач обратной выгрузки
+const (
+	ExportTypeProtocol = "protocol" // Протокол загрузки выгрузок (handshake/metadata/.../complete) на другой сервер
+	ExportTypeOData    = "odata"    // Запись элементов напрямую в опубликованный OData интерфейс 1С
+	ExportTypeParquet  = "parquet"  // Файлы Parquet для аналитики в хранилище артефактов
+	ExportTypePull     = "pull"     // Обработка 1С сама забирает отобранные данные через /api/1c/import/*
+)
+
+// ExportConnector исходящий коннектор для выгрузки данных во внешнюю систему
+type ExportConnector interface {
+	// Open проверяет доступность внешней системы и авторизацию
+	Open(ctx context.Context) error
+	// SendCatalogItems передает пачку элементов одного справочника и возвращает число принятых
+	SendCatalogItems(ctx context.Context, catalogName string, items []*database.CatalogItem) (int, error)
+	// SendNomenclature передает пачку номенклатуры и возвращает число принятых
+	SendNomenclature(ctx context.Context, items []*database.NomenclatureItem) (int, error)
+	// Close завершает выгрузку
+	Close(ctx context.Context) error
+}
+
+// newExportConnector создает коннектор для задачи экспорта
+func (s *Server) newExportConnector(job *ExportJob, uploadDB *database.DB, upload *database.Upload) (ExportConnector, error) {
+	client := &http.Client{Timeout: job.Timeout}
+	switch job.Type {
+	case ExportTypeOData:
+		if job.odata == nil {
+			return nil, fmt.Errorf("odata configuration is required")
+		}
+		metadata := catalogMetadataCache{}
+		lookup := func(catalogName string) *database.CatalogMetadata {
+			return metadata.get(uploadDB, upload.ID, catalogName)
+		}
+		return NewODataConnector(*job.odata, client, lookup), nil
+	default:
+		return nil, fmt.Errorf("unsupported export type: %s", job.Type)
+	}
+}
+
+// runConnectorExportJob выполняет задачу экспорта через исходящий коннектор
+func (s *Server) runConnectorExportJob(job *ExportJob, upload *database.Upload) {
+	job.markRunning()
+
+	uploadDB, release, err := s.acquireUploadDatabase(upload.UploadUUID)
+	if err != nil {
+		job.markFailed(fmt.Errorf("failed to find upload database: %w", err))
+		return
+	}
+	defer release()
+	uploadDB = uploadDB.ReadOnly()
+
+	connector, err := s.newExportConnector(job, uploadDB, upload)
+	if err != nil {
+		job.markFailed(err)
+		s.logExportError(job, err, "connector")
+		return
+	}
+
+	ctx := context.Background()
+	if err := connector.Open(ctx); err != nil {
+		job.markFailed(err)
+		s.logExportError(job, err, "open")
+		return
+	}
+	job.setHandshakeDone()
+
+	if job.Options.IncludeCatalogs {
+		seenCatalogs := make(map[string]struct{})
+		err = uploadDB.StreamCatalogItems(upload.ID, job.Options.CatalogNames, job.Options.BatchSize, func(items []*database.CatalogItem) error {
+			if err := s.openCatalogItems(items, job.decrypt); err != nil {
+				return err
+			}
+			job.redaction.RedactCatalogItems(items)
+			// Коннектор принимает элементы одного справочника: делим батч на подряд идущие группы
+			for start := 0; start < len(items); {
+				end := start
+				for end < len(items) && items[end].CatalogName == items[start].CatalogName {
+					end++
+				}
+				catalogName := items[start].CatalogName
+				if _, ok := seenCatalogs[catalogName]; !ok {
+					seenCatalogs[catalogName] = struct{}{}
+					job.addCatalogs(1)
+				}
+				sent, err := connector.SendCatalogItems(ctx, catalogName, items[start:end])
+				job.addCatalogItems(sent)
+				if err != nil {
+					return err
+				}
+				start = end
+			}
+			return nil
+		})
+		if err != nil {
+			job.markFailed(err)
+			s.logExportError(job, err, "catalog_items")
+			return
+		}
+	}
+
+	if job.Options.IncludeNomenclature {
+		err = uploadDB.StreamNomenclatureItems(upload.ID, job.Options.BatchSize, func(items []*database.NomenclatureItem) error {
+			if err := s.openNomenclatureItems(items, job.decrypt); err != nil {
+				return err
+			}
+			job.redaction.RedactNomenclatureItems(items)
+			sent, err := connector.SendNomenclature(ctx, items)
+			job.addNomenclature(sent)
+			return err
+		})
+		if err != nil {
+			job.markFailed(err)
+			s.logExportError(job, err, "nomenclature")
+			return
+		}
+	}
+
+	if err := connector.Close(ctx); err != nil {
+		job.markFailed(err)
+		s.logExportError(job, err, "close")
+		return
+	}
+	job.markCompleteDispatched()
+	job.markCompleted()
+
+	s.log(LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "INFO",
+		Message:    fmt.Sprintf("Export job %s (%s) finished", job.ID, job.Type),
+		UploadUUID: job.UploadUUID,
+		Endpoint:   "/api/uploads/{uuid}/export",
+	})
+}
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// Режимы проверки контракта данных перед выгрузкой
+const (
+	ExportContractBlock = "block" // Задача завершается ошибкой при нарушениях (по умолчанию)
+	ExportContractWarn  = "warn"  // Нарушения попадают в отчет задачи, выгрузка продолжается
+	ExportContractOff   = "off"   // Не проверять
+)
+
+// ExportContractConfig настройки контракта в запросе на экспорт
+type ExportContractConfig struct {
+	Mode           string   `json:"mode,omitempty"`
+	RequiredFields []string `json:"required_fields,omitempty"` // По умолчанию reference, code, name
+	MaxNameLength  *int     `json:"max_name_length,omitempty"` // По умолчанию 100, 0 - не ограничено
+	MaxCodeLength  int      `json:"max_code_length,omitempty"`
+}
+
+// ExportContractView сводка проверки контракта в ответе API задачи экспорта
+type ExportContractView struct {
+	Mode          string         `json:"mode"`
+	Checked       int            `json:"checked"`
+	Violations    int            `json:"violations"`
+	ByRule        map[string]int `json:"by_rule"`
+	Truncated     bool           `json:"truncated,omitempty"`
+	ViolationsURL string         `json:"violations_url,omitempty"`
+}
+
+// exportContract разбирает настройки контракта. Коды КПВЭД сверяются с загруженным классификатором
+func (s *Server) exportContract(config *ExportContractConfig) (string, database.ExportContract, error) {
+	contract := database.DefaultExportContract()
+	mode := ExportContractBlock
+	if config != nil {
+		if config.Mode != "" {
+			mode = strings.ToLower(strings.TrimSpace(config.Mode))
+		}
+		if len(config.RequiredFields) > 0 {
+			contract.RequiredFields = config.RequiredFields
+		}
+		if config.MaxNameLength != nil {
+			contract.MaxNameLength = *config.MaxNameLength
+		}
+		contract.MaxCodeLength = config.MaxCodeLength
+	}
+	switch mode {
+	case ExportContractBlock, ExportContractWarn, ExportContractOff:
+	default:
+		return "", contract, fmt.Errorf("unknown contract mode: %s", mode)
+	}
+	if err := database.ValidateContractFields(contract.RequiredFields); err != nil {
+		return "", contract, err
+	}
+	if contract.MaxNameLength < 0 || contract.MaxCodeLength < 0 {
+		return "", contract, fmt.Errorf("contract length limits must not be negative")
+	}
+	contract.KpvedCodes = s.activeKpvedCodes()
+	return mode, contract, nil
+}
+
+// activeKpvedCodes возвращает коды загруженного классификатора КПВЭД; nil, если классификатор не загружен
+func (s *Server) activeKpvedCodes() map[string]bool {
+	if s.serviceDB == nil {
+		return nil
+	}
+	if err := s.kpvedTree.ensureLoaded(s.serviceDB.GetDB()); err != nil {
+		return nil
+	}
+	s.kpvedTree.mu.RLock()
+	defer s.kpvedTree.mu.RUnlock()
+	if len(s.kpvedTree.nodes) == 0 {
+		return nil
+	}
+	codes := make(map[string]bool, len(s.kpvedTree.nodes))
+	for code := range s.kpvedTree.nodes {
+		codes[code] = true
+	}
+	return codes
+}
+
+// validateExportContract проверяет по контракту данные, которые выгружает задача: элементы справочников
+// и нормализованные записи. Константы и номенклатура с характеристиками контрактом не проверяются
+func (s *Server) validateExportContract(ctx context.Context, uploadDB *database.DB, upload *database.Upload, options ExportOptions, contract database.ExportContract) (*database.ContractReport, error) {
+	report := database.NewContractReport()
+	if options.IncludeCatalogs {
+		if err := uploadDB.ValidateCatalogItemsContract(ctx, upload.ID, options.CatalogNames, contract, report); err != nil {
+			return nil, err
+		}
+	}
+	if options.IncludeNormalized && s.normalizedDB != nil {
+		if err := s.normalizedDB.ValidateNormalizedContract(ctx, contract, report); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// checkExportContract проверяет контракт перед выполнением задачи. Возвращает false,
+// если задача остановлена: проверка не удалась или есть нарушения в режиме block
+func (s *Server) checkExportContract(ctx context.Context, job *ExportJob, upload *database.Upload) bool {
+	if job.contractMode == ExportContractOff {
+		return true
+	}
+	uploadDB, release, err := s.acquireUploadDatabase(upload.UploadUUID)
+	if err != nil {
+		job.markFailed(fmt.Errorf("failed to find upload database: %w", err))
+		return false
+	}
+	defer release()
+	report, err := s.validateExportContract(ctx, uploadDB.ReadOnly(), upload, job.Options, job.contract)
+	if err != nil {
+		job.markFailed(fmt.Errorf("failed to validate export contract: %w", err))
+		s.logExportError(job, err, "contract")
+		return false
+	}
+	job.setContractReport(report)
+	if report.Violations == 0 {
+		return true
+	}
+
+	if job.contractMode == ExportContractBlock {
+		err := fmt.Errorf("export contract violated: %d violations", report.Violations)
+		job.markFailed(err)
+		s.logExportError(job, err, "contract")
+		return false
+	}
+	s.logCtx(ctx, LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "WARNING",
+		Message:    fmt.Sprintf("Export job %s continues with %d contract violations", job.ID, report.Violations),
+		UploadUUID: job.UploadUUID,
+		Endpoint:   "/api/uploads/{uuid}/export",
+	})
+	return true
+}
+
+func (job *ExportJob) setContractReport(report *database.ContractReport) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.contractReport = report
+}
+
+// contractView возвращает сводку проверки контракта (вызывается под job.mu)
+func (job *ExportJob) contractView() *ExportContractView {
+	if job.contractReport == nil {
+		return nil
+	}
+	view := &ExportContractView{
+		Mode:       job.contractMode,
+		Checked:    job.contractReport.Checked,
+		Violations: job.contractReport.Violations,
+		ByRule:     make(map[string]int, len(job.contractReport.ByRule)),
+		Truncated:  job.contractReport.Truncated,
+	}
+	for rule, count := range job.contractReport.ByRule {
+		view.ByRule[rule] = count
+	}
+	if view.Violations > 0 {
+		view.ViolationsURL = fmt.Sprintf("/api/exports/%s/violations", job.ID)
+	}
+	return view
+}
+
+// handleUploadExportValidate проверяет контракт без запуска выгрузки.
+// POST /api/uploads/{uuid}/export/validate (тело как у запроса на экспорт); ?format=csv - список нарушений файлом
+func (s *Server) handleUploadExportValidate(w http.ResponseWriter, r *http.Request, upload *database.Upload) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Invalid payload: %v", err), http.StatusBadRequest)
+		return
+	}
+	options, err := normalizeExportOptions(payload)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, contract, err := s.exportContract(payload.Contract)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uploadDB, err := s.getUploadDatabase(upload.UploadUUID)
+	if err != nil {
+		s.writeAPIError(w, "Upload database not found", err)
+		return
+	}
+	report, err := s.validateExportContract(r.Context(), uploadDB.ReadOnly(), upload, options, contract)
+	if err != nil {
+		s.writeAPIError(w, "Failed to validate export contract", err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeContractViolationsCSV(w, fmt.Sprintf("contract_%s.csv", upload.UploadUUID), report.Items)
+		return
+	}
+	s.writeJSONResponse(w, report, http.StatusOK)
+}
+
+// handleExportViolations отдает список нарушений контракта задачи экспорта.
+// GET /api/exports/{id}/violations (CSV); ?format=json - в JSON
+func (s *Server) handleExportViolations(w http.ResponseWriter, r *http.Request, job *ExportJob) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job.mu.RLock()
+	report := job.contractReport
+	job.mu.RUnlock()
+	if report == nil {
+		s.writeJSONError(w, "Export contract was not checked", http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		s.writeJSONResponse(w, report, http.StatusOK)
+		return
+	}
+	writeContractViolationsCSV(w, fmt.Sprintf("contract_%s.csv", job.ID), report.Items)
+}
+
+// writeContractViolationsCSV записывает нарушения контракта файлом CSV
+func writeContractViolationsCSV(w http.ResponseWriter, filename string, violations []database.ContractViolation) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	// UTF-8 BOM для корректного отображения в Excel
+	w.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	writer.Write([]string{"Источник", "Справочник", "ID", "Ссылка", "Код", "Поле", "Правило", "Значение", "Сообщение"})
+	for _, v := range violations {
+		writer.Write([]string{v.Source, v.Scope, strconv.Itoa(v.ItemID), v.Reference, v.Code, v.Field, v.Rule, v.Value, v.Message})
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+	"httpserver/storage"
+)
+
+func TestExportContract(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "upload.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+	upload, _ := db.CreateUpload("uuid-contract", "8.3", "УправлениеТорговлей")
+	catalog, _ := db.AddCatalog(upload.ID, "Номенклатура", "")
+	db.AddCatalogItem(catalog.ID, "ref-1", "001", "Болт М10", "", "")
+	db.AddCatalogItem(catalog.ID, "ref-2", "001", "Гайка М10", "", "")
+
+	s := &Server{
+		logChan:    make(chan LogEntry, 100),
+		exportJobs: make(map[string]*ExportJob),
+		config:     &Config{Storage: storage.Config{LocalDir: t.TempDir()}},
+	}
+	s.uploadDBs.putShared(upload.UploadUUID, db)
+
+	t.Run("validate endpoint", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s.handleUploadExportValidate(rec, httptest.NewRequest(http.MethodPost, "/api/uploads/uuid-contract/export/validate", strings.NewReader(`{}`)), upload)
+		var report database.ContractReport
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+		if report.Checked != 2 || report.ByRule[database.ContractRuleDuplicateCode] != 1 {
+			t.Errorf("report = %+v", report)
+		}
+
+		rec = httptest.NewRecorder()
+		s.handleUploadExportValidate(rec, httptest.NewRequest(http.MethodPost, "/?format=csv", strings.NewReader(`{"contract":{"max_name_length":5}}`)), upload)
+		if lines := strings.Count(rec.Body.String(), "\n"); lines != 4 || !strings.Contains(rec.Body.String(), database.ContractRuleNameTooLong) {
+			t.Errorf("csv has %d lines: %s", lines, rec.Body.String())
+		}
+
+		rec = httptest.NewRecorder()
+		s.handleUploadExportValidate(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"contract":{"required_fields":["price"]}}`)), upload)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("unknown field: status = %d, want 400", rec.Code)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		mode       string
+		wantStatus ExportStatus
+	}{
+		{"block", ExportContractBlock, ExportStatusFailed},
+		{"warn", ExportContractWarn, ExportStatusFinished},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			body := `{"type":"parquet","contract":{"mode":"` + tt.mode + `"}}`
+			s.handleUploadExport(rec, httptest.NewRequest(http.MethodPost, "/api/uploads/uuid-contract/export", strings.NewReader(body)), upload)
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var view ExportJobView
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				view = s.getAllExportJobs()[0]
+				if view.Status == ExportStatusFinished || view.Status == ExportStatusFailed {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if view.Status != tt.wantStatus {
+				t.Fatalf("job status = %s (%s), want %s", view.Status, view.Error, tt.wantStatus)
+			}
+			if view.Contract == nil || view.Contract.Violations != 1 || view.Contract.ViolationsURL == "" {
+				t.Fatalf("contract = %+v", view.Contract)
+			}
+
+			rec = httptest.NewRecorder()
+			s.handleExportRoutes(rec, httptest.NewRequest(http.MethodGet, view.Contract.ViolationsURL, nil))
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "ref-2") {
+				t.Errorf("violations: status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"httpserver/database"
+
+	"github.com/google/uuid"
+)
+
+// ODataNomenclatureCatalog справочник 1С, в который выгружается номенклатура
+const ODataNomenclatureCatalog = "Номенклатура"
+
+// ODataConfig настройки выгрузки в опубликованный OData интерфейс 1С
+// (например, http://host/base/odata/standard.odata)
+type ODataConfig struct {
+	ServiceURL   string                  `json:"service_url"`
+	Username     string                  `json:"username,omitempty"`
+	Password     string                  `json:"password,omitempty"`
+	Token        string                  `json:"token,omitempty"`         // Bearer токен вместо basic-авторизации
+	DisableBatch bool                    `json:"disable_batch,omitempty"` // Отправлять элементы отдельными POST вместо $batch
+	Mappings     map[string]ODataMapping `json:"mappings,omitempty"`      // Ключ - имя справочника выгрузки
+}
+
+// ODataMapping сопоставление справочника выгрузки с набором сущностей OData.
+// Fields: свойство OData -> источник (code, name, reference или attr:<Реквизит>).
+// Если Fields не заданы, выгружаются Code и Description.
+type ODataMapping struct {
+	EntitySet string            `json:"entity_set,omitempty"` // По умолчанию Catalog_<Имя справочника>
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+// defaultODataFields сопоставление полей по умолчанию
+var defaultODataFields = map[string]string{
+	"Code":        "code",
+	"Description": "name",
+}
+
+// odataEntity элемент, подготовленный к отправке в OData
+type odataEntity struct {
+	Reference  string
+	Code       string
+	Name       string
+	Attributes string
+}
+
+// ODataConnector исходящий коннектор в OData интерфейс 1С
+type ODataConnector struct {
+	config   ODataConfig
+	client   *http.Client
+	metadata func(catalogName string) *database.CatalogMetadata
+}
+
+// NewODataConnector создает OData коннектор. metadata (может быть nil) используется
+// для приведения реквизитов к объявленным в 1С типам.
+func NewODataConnector(config ODataConfig, client *http.Client, metadata func(catalogName string) *database.CatalogMetadata) *ODataConnector {
+	config.ServiceURL = strings.TrimRight(config.ServiceURL, "/")
+	return &ODataConnector{config: config, client: client, metadata: metadata}
+}
+
+// Open проверяет доступность сервиса и авторизацию запросом служебного документа
+func (c *ODataConnector) Open(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, c.config.ServiceURL+"/?$format=json", "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return odataStatusError("service document", resp)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// SendCatalogItems передает элементы справочника
+func (c *ODataConnector) SendCatalogItems(ctx context.Context, catalogName string, items []*database.CatalogItem) (int, error) {
+	entities := make([]odataEntity, len(items))
+	for i, item := range items {
+		entities[i] = odataEntity{Reference: item.Reference, Code: item.Code, Name: item.Name, Attributes: item.Attributes}
+	}
+	return c.send(ctx, catalogName, entities)
+}
+
+// SendNomenclature передает номенклатуру в справочник Номенклатура
+func (c *ODataConnector) SendNomenclature(ctx context.Context, items []*database.NomenclatureItem) (int, error) {
+	entities := make([]odataEntity, len(items))
+	for i, item := range items {
+		entities[i] = odataEntity{
+			Reference:  item.NomenclatureReference,
+			Code:       item.NomenclatureCode,
+			Name:       item.NomenclatureName,
+			Attributes: item.AttributesXML,
+		}
+	}
+	return c.send(ctx, ODataNomenclatureCatalog, entities)
+}
+
+// Close ничего не делает: OData не требует завершения выгрузки
+func (c *ODataConnector) Close(ctx context.Context) error {
+	return nil
+}
+
+// entitySet возвращает имя набора сущностей для справочника
+func (c *ODataConnector) entitySet(catalogName string) string {
+	if mapping, ok := c.config.Mappings[catalogName]; ok && mapping.EntitySet != "" {
+		return mapping.EntitySet
+	}
+	return "Catalog_" + catalogName
+}
+
+// entityBody формирует JSON тело сущности по сопоставлению полей
+func (c *ODataConnector) entityBody(catalogName string, entity odataEntity) ([]byte, error) {
+	fields := defaultODataFields
+	if mapping, ok := c.config.Mappings[catalogName]; ok && len(mapping.Fields) > 0 {
+		fields = mapping.Fields
+	}
+
+	var attributes map[string]interface{}
+	body := make(map[string]interface{}, len(fields))
+	for property, source := range fields {
+		switch {
+		case source == "code":
+			body[property] = entity.Code
+		case source == "name":
+			body[property] = entity.Name
+		case source == "reference":
+			body[property] = entity.Reference
+		case strings.HasPrefix(source, "attr:"):
+			if attributes == nil {
+				attributes = c.attributeValues(catalogName, entity.Attributes)
+			}
+			if value, ok := attributes[strings.TrimPrefix(source, "attr:")]; ok {
+				body[property] = value
+			}
+		default:
+			return nil, fmt.Errorf("unknown odata field source %q for %s", source, property)
+		}
+	}
+	return json.Marshal(body)
+}
+
+// attributeValues извлекает реквизиты элемента и приводит их к типам из метаданных 1С
+func (c *ODataConnector) attributeValues(catalogName, attributes string) map[string]interface{} {
+	values := database.ExtractAttributeValues(attributes)
+	if c.metadata != nil {
+		if metadata := c.metadata(catalogName); metadata != nil {
+			return metadata.CastAttributeValues(values)
+		}
+	}
+	typed := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		typed[name] = value
+	}
+	return typed
+}
+
+// send отправляет сущности одним $batch запросом или поштучно
+func (c *ODataConnector) send(ctx context.Context, catalogName string, entities []odataEntity) (int, error) {
+	if len(entities) == 0 {
+		return 0, nil
+	}
+	entitySet := c.entitySet(catalogName)
+	bodies := make([][]byte, len(entities))
+	for i, entity := range entities {
+		body, err := c.entityBody(catalogName, entity)
+		if err != nil {
+			return 0, err
+		}
+		bodies[i] = body
+	}
+
+	if c.config.DisableBatch {
+		for i, body := range bodies {
+			if err := c.post(ctx, entitySet, body); err != nil {
+				return i, fmt.Errorf("failed to send %s item %s: %w", catalogName, entities[i].Reference, err)
+			}
+		}
+		return len(bodies), nil
+	}
+
+	if err := c.postBatch(ctx, entitySet, bodies); err != nil {
+		return 0, fmt.Errorf("failed to send %s batch: %w", catalogName, err)
+	}
+	return len(bodies), nil
+}
+
+// post создает одну сущность
+func (c *ODataConnector) post(ctx context.Context, entitySet string, body []byte) error {
+	resp, err := c.do(ctx, http.MethodPost, c.config.ServiceURL+"/"+url.PathEscape(entitySet)+"?$format=json", "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return odataStatusError(entitySet, resp)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// odataBatchStatus строка статуса вложенного ответа в multipart ответе $batch
+var odataBatchStatus = regexp.MustCompile(`HTTP/1\.[01] (\d{3})([^\r\n]*)`)
+
+// postBatch создает сущности одним $batch запросом в рамках одного набора изменений:
+// 1С применяет набор изменений транзакционно, поэтому пачка принимается целиком или отклоняется
+func (c *ODataConnector) postBatch(ctx context.Context, entitySet string, bodies [][]byte) error {
+	batchBoundary := "batch_" + uuid.New().String()
+	changesetBoundary := "changeset_" + uuid.New().String()
+
+	var payload bytes.Buffer
+	fmt.Fprintf(&payload, "--%s\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n", batchBoundary, changesetBoundary)
+	for i, body := range bodies {
+		fmt.Fprintf(&payload, "--%s\r\nContent-Type: application/http\r\nContent-Transfer-Encoding: binary\r\nContent-ID: %d\r\n\r\n", changesetBoundary, i+1)
+		fmt.Fprintf(&payload, "POST %s?$format=json HTTP/1.1\r\nContent-Type: application/json\r\n\r\n", url.PathEscape(entitySet))
+		payload.Write(body)
+		payload.WriteString("\r\n")
+	}
+	fmt.Fprintf(&payload, "--%s--\r\n--%s--\r\n", changesetBoundary, batchBoundary)
+
+	resp, err := c.do(ctx, http.MethodPost, c.config.ServiceURL+"/$batch", "multipart/mixed; boundary="+batchBoundary, payload.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return odataStatusError("$batch", resp)
+	}
+
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read $batch response: %w", err)
+	}
+	statuses := odataBatchStatus.FindAllStringSubmatch(string(response), -1)
+	if len(statuses) == 0 {
+		return errors.New("$batch response contains no operation results")
+	}
+	created := 0
+	for _, status := range statuses {
+		code, _ := strconv.Atoi(status[1])
+		if code >= 300 {
+			return fmt.Errorf("$batch operation failed with %d%s", code, status[2])
+		}
+		created++
+	}
+	if created != len(bodies) {
+		return fmt.Errorf("$batch confirmed %d of %d operations", created, len(bodies))
+	}
+	return nil
+}
+
+// do выполняет запрос с авторизацией
+func (c *ODataConnector) do(ctx context.Context, method, endpoint, contentType string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	} else if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", endpoint, err)
+	}
+	return resp, nil
+}
+
+// odataStatusError формирует ошибку по неуспешному ответу OData
+func odataStatusError(operation string, resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	return fmt.Errorf("odata %s responded with %d: %s", operation, resp.StatusCode, strings.TrimSpace(string(message)))
+}
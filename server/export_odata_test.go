@@ -0,0 +1,198 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+// fakeOData имитирует опубликованный OData интерфейс 1С
+type fakeOData struct {
+	mu       sync.Mutex
+	requests []string // METHOD путь
+	entities []string // Тела созданных сущностей
+	failWith int      // Статус, которым отклоняются операции $batch
+}
+
+func (f *fakeOData) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.Method == http.MethodGet:
+		fmt.Fprint(w, `{"value":[]}`)
+	case strings.HasSuffix(r.URL.Path, "/$batch"):
+		operations := regexp.MustCompile(`(?s)POST (\S+)\?\$format=json HTTP/1\.1\r\nContent-Type: application/json\r\n\r\n(\{.*?\})\r\n`).FindAllStringSubmatch(string(body), -1)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=batchresponse")
+		fmt.Fprint(w, "--batchresponse\r\nContent-Type: multipart/mixed; boundary=changesetresponse\r\n\r\n")
+		if f.failWith != 0 {
+			fmt.Fprintf(w, "--changesetresponse\r\nContent-Type: application/http\r\n\r\nHTTP/1.1 %d Bad Request\r\n\r\n", f.failWith)
+		} else {
+			for _, op := range operations {
+				entitySet, _ := url.PathUnescape(op[1])
+				f.entities = append(f.entities, entitySet+" "+op[2])
+				fmt.Fprint(w, "--changesetresponse\r\nContent-Type: application/http\r\n\r\nHTTP/1.1 201 Created\r\n\r\n{}\r\n")
+			}
+		}
+		fmt.Fprint(w, "--changesetresponse--\r\n--batchresponse--\r\n")
+	default:
+		f.entities = append(f.entities, strings.TrimPrefix(r.URL.Path, "/odata/")+" "+string(body))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func TestODataConnectorSend(t *testing.T) {
+	items := []*database.CatalogItem{
+		{Reference: "ref-1", Code: "001", Name: "Болт", Attributes: "<Артикул>A-1</Артикул><Вес>1,5</Вес>"},
+		{Reference: "ref-2", Code: "002", Name: "Гайка", Attributes: "<Артикул>A-2</Артикул>"},
+	}
+	metadata := &database.CatalogMetadata{
+		CatalogName: "Номенклатура",
+		Attributes:  []database.CatalogAttributeDefinition{database.NewCatalogAttributeDefinition("Вес", "", "Число", 0, 15, 3, false)},
+	}
+
+	tests := []struct {
+		name         string
+		config       ODataConfig
+		failWith     int
+		wantErr      bool
+		wantSent     int
+		wantRequests int
+		wantEntity   string
+	}{
+		{
+			name:         "batch with default mapping",
+			config:       ODataConfig{Username: "admin", Password: "secret"},
+			wantSent:     2,
+			wantRequests: 2,
+			wantEntity:   `Catalog_Номенклатура {"Code":"001","Description":"Болт"}`,
+		},
+		{
+			name: "single posts with custom mapping",
+			config: ODataConfig{Username: "admin", Password: "secret", DisableBatch: true, Mappings: map[string]ODataMapping{
+				"Номенклатура": {EntitySet: "Catalog_Товары", Fields: map[string]string{"Description": "name", "Артикул": "attr:Артикул", "Вес": "attr:Вес"}},
+			}},
+			wantSent:     2,
+			wantRequests: 3,
+			wantEntity:   `Catalog_Товары {"Description":"Болт","Артикул":"A-1","Вес":1.5}`,
+		},
+		{
+			name:         "rejected changeset",
+			config:       ODataConfig{Username: "admin", Password: "secret"},
+			failWith:     http.StatusBadRequest,
+			wantErr:      true,
+			wantRequests: 2,
+		},
+		{
+			name:         "wrong credentials",
+			config:       ODataConfig{Username: "admin", Password: "wrong"},
+			wantErr:      true,
+			wantRequests: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeOData{failWith: tt.failWith}
+			ts := httptest.NewServer(service)
+			defer ts.Close()
+
+			tt.config.ServiceURL = ts.URL + "/odata/"
+			connector := NewODataConnector(tt.config, ts.Client(), func(string) *database.CatalogMetadata { return metadata })
+
+			err := connector.Open(t.Context())
+			sent := 0
+			if err == nil {
+				sent, err = connector.SendCatalogItems(t.Context(), "Номенклатура", items)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sent != tt.wantSent {
+				t.Errorf("sent = %d, want %d", sent, tt.wantSent)
+			}
+			if len(service.requests) != tt.wantRequests {
+				t.Errorf("requests = %v, want %d", service.requests, tt.wantRequests)
+			}
+			if tt.wantEntity != "" && (len(service.entities) == 0 || service.entities[0] != tt.wantEntity) {
+				t.Errorf("entities = %v, want first %s", service.entities, tt.wantEntity)
+			}
+		})
+	}
+}
+
+func TestODataExportJob(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "upload.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+	upload, _ := db.CreateUpload("uuid-odata", "8.3", "УправлениеТорговлей")
+	catalog, _ := db.AddCatalog(upload.ID, "Контрагенты", "")
+	db.AddCatalogItem(catalog.ID, "ref-1", "001", "ТОО Ромашка", "", "")
+	db.AddCatalogItem(catalog.ID, "ref-2", "002", "ИП Иванов", "", "")
+	db.AddNomenclatureItem(upload.ID, "nom-1", "N1", "Болт М10", "", "", "", "")
+
+	service := &fakeOData{}
+	ts := httptest.NewServer(service)
+	defer ts.Close()
+
+	s := &Server{
+		logChan:    make(chan LogEntry, 100),
+		exportJobs: make(map[string]*ExportJob),
+	}
+	s.uploadDBs.putShared(upload.UploadUUID, db)
+
+	body := fmt.Sprintf(`{"type":"odata","odata":{"service_url":%q,"username":"admin","password":"secret"}}`, ts.URL+"/odata")
+	rec := httptest.NewRecorder()
+	s.handleUploadExport(rec, httptest.NewRequest(http.MethodPost, "/api/uploads/uuid-odata/export", strings.NewReader(body)), upload)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("job view exposes credentials: %s", rec.Body.String())
+	}
+
+	var view ExportJobView
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		view = s.getAllExportJobs()[0]
+		if view.Status == ExportStatusFinished || view.Status == ExportStatusFailed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if view.Status != ExportStatusFinished {
+		t.Fatalf("job status = %s (%s)", view.Status, view.Error)
+	}
+	if view.Type != ExportTypeOData || view.Progress.CatalogsSent != 1 || view.Progress.CatalogItemsSent != 2 || view.Progress.NomenclatureSent != 1 {
+		t.Errorf("unexpected job view: %+v", view)
+	}
+	if len(service.entities) != 3 || !strings.HasPrefix(service.entities[2], "Catalog_Номенклатура ") {
+		t.Errorf("entities = %v", service.entities)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleUploadExport(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"type":"odata","include":["constants"],"odata":{"service_url":"http://1c/odata"}}`)), upload)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("constants via odata: status = %d, want 400", rec.Code)
+	}
+}
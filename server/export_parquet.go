@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/parquet"
+)
+
+const parquetContentType = "application/vnd.apache.parquet"
+
+// ParquetExportConfig настройки выгрузки в Parquet
+type ParquetExportConfig struct {
+	Codec          string `json:"codec,omitempty"`             // gzip (по умолчанию) или none
+	RowGroupSizeMB int    `json:"row_group_size_mb,omitempty"` // Размер группы строк; по умолчанию 64 МБ
+}
+
+// ExportArtifact файл, сформированный задачей экспорта и сохраненный в хранилище артефактов
+type ExportArtifact struct {
+	Name        string `json:"name"`
+	Key         string `json:"key"`
+	Rows        int64  `json:"rows"`
+	Size        int64  `json:"size"`
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// ParquetOptions преобразует настройки запроса в параметры записи файла
+func (c *ParquetExportConfig) ParquetOptions() (parquet.Options, error) {
+	var options parquet.Options
+	if c == nil {
+		return options, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(c.Codec)) {
+	case "", "gzip":
+		options.Codec = parquet.CodecGzip
+	case "none":
+		options.Codec = parquet.CodecNone
+	default:
+		return options, fmt.Errorf("unknown parquet codec: %s", c.Codec)
+	}
+	if c.RowGroupSizeMB < 0 || c.RowGroupSizeMB > 1024 {
+		return options, fmt.Errorf("row_group_size_mb must be between 1 and 1024")
+	}
+	options.RowGroupSize = c.RowGroupSizeMB << 20
+	return options, nil
+}
+
+func (job *ExportJob) addNormalized(delta int) {
+	if delta == 0 {
+		return
+	}
+	job.mu.Lock()
+	job.Progress.NormalizedSent += delta
+	job.mu.Unlock()
+}
+
+func (job *ExportJob) addArtifact(artifact ExportArtifact) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Artifacts = append(job.Artifacts, artifact)
+}
+
+// runParquetExportJob выгружает элементы справочников и нормализованные данные в файлы Parquet
+// и сохраняет их в хранилище артефактов
+func (s *Server) runParquetExportJob(ctx context.Context, job *ExportJob, upload *database.Upload) {
+	job.markRunning()
+
+	uploadDB, release, err := s.acquireUploadDatabase(upload.UploadUUID)
+	if err != nil {
+		job.markFailed(fmt.Errorf("failed to find upload database: %w", err))
+		return
+	}
+	defer release()
+	uploadDB = uploadDB.ReadOnly()
+
+	if job.Options.IncludeCatalogs {
+		export := database.ParquetExport{Options: job.parquet, BatchSize: job.Options.BatchSize, Progress: job.addCatalogItems}
+		if job.redaction != nil {
+			export.Redact = job.redaction.RedactCatalogItem
+		}
+		err := s.writeParquetArtifact(job, "catalog_items", func(file *os.File) (int64, error) {
+			return uploadDB.ExportCatalogItemsParquet(ctx, upload.ID, job.Options.CatalogNames, file, export)
+		})
+		if err != nil {
+			job.markFailed(err)
+			s.logExportError(job, err, "catalog_items")
+			return
+		}
+	}
+
+	if job.Options.IncludeNormalized {
+		if s.normalizedDB == nil {
+			err := fmt.Errorf("normalized database is not available")
+			job.markFailed(err)
+			s.logExportError(job, err, "normalized")
+			return
+		}
+		export := database.ParquetExport{Options: job.parquet, BatchSize: job.Options.BatchSize, Progress: job.addNormalized}
+		err := s.writeParquetArtifact(job, "normalized_data", func(file *os.File) (int64, error) {
+			return s.normalizedDB.ExportNormalizedDataParquet(ctx, file, export)
+		})
+		if err != nil {
+			job.markFailed(err)
+			s.logExportError(job, err, "normalized")
+			return
+		}
+	}
+	job.markCompleted()
+
+	s.logCtx(ctx, LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "INFO",
+		Message:    fmt.Sprintf("Export job %s (%s) finished", job.ID, job.Type),
+		UploadUUID: job.UploadUUID,
+		Endpoint:   "/api/uploads/{uuid}/export",
+	})
+}
+
+// writeParquetArtifact записывает файл во временный каталог и переносит его в хранилище артефактов
+func (s *Server) writeParquetArtifact(job *ExportJob, name string, write func(*os.File) (int64, error)) error {
+	file, err := os.CreateTemp("", "export-*.parquet")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	rows, err := write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%s/%s/%s.parquet", job.UploadUUID, job.ID, name)
+	size, err := s.storeArtifactFile(file.Name(), key, parquetContentType)
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	job.addArtifact(ExportArtifact{Name: name, Key: key, Rows: rows, Size: size, DownloadURL: s.presignArtifact(key)})
+	return nil
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+	"httpserver/parquet"
+	"httpserver/storage"
+)
+
+func TestParquetExportJob(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "upload.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+	upload, _ := db.CreateUpload("uuid-parquet", "8.3", "УправлениеТорговлей")
+	catalog, _ := db.AddCatalog(upload.ID, "Номенклатура", "")
+	db.AddCatalogItem(catalog.ID, "ref-1", "001", "Болт М10", "<Артикул>A-1</Артикул>", "")
+	db.AddCatalogItem(catalog.ID, "ref-2", "002", "Гайка М10", "", "")
+
+	normalizedDB, err := database.NewDB(filepath.Join(t.TempDir(), "normalized.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer normalizedDB.Close()
+	normalizedDB.InsertNormalizedItem("ref-1", "Болт М10", "001", "болт", "болт м10", "крепеж", 1)
+
+	s := &Server{
+		logChan:      make(chan LogEntry, 100),
+		exportJobs:   make(map[string]*ExportJob),
+		normalizedDB: normalizedDB,
+		config:       &Config{Storage: storage.Config{LocalDir: t.TempDir()}},
+	}
+	s.uploadDBs.putShared(upload.UploadUUID, db)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFiles  map[string]int64 // Имя артефакта -> число строк
+	}{
+		{"catalogs by default", `{"type":"parquet"}`, http.StatusAccepted, map[string]int64{"catalog_items": 2}},
+		{"catalogs and normalized", `{"type":"parquet","include":["catalogs","normalized"],"parquet":{"codec":"none"}}`, http.StatusAccepted,
+			map[string]int64{"catalog_items": 2, "normalized_data": 1}},
+		{"unsupported include", `{"type":"parquet","include":["constants"]}`, http.StatusBadRequest, nil},
+		{"unknown codec", `{"type":"parquet","parquet":{"codec":"zstd"}}`, http.StatusBadRequest, nil},
+		{"normalized via protocol", `{"target_url":"http://remote","include":["normalized"]}`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleUploadExport(rec, httptest.NewRequest(http.MethodPost, "/api/uploads/uuid-parquet/export", strings.NewReader(tt.body)), upload)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				return
+			}
+
+			var view ExportJobView
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				view = s.getAllExportJobs()[0]
+				if view.Status == ExportStatusFinished || view.Status == ExportStatusFailed {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if view.Status != ExportStatusFinished {
+				t.Fatalf("job status = %s (%s)", view.Status, view.Error)
+			}
+			if len(view.Artifacts) != len(tt.wantFiles) {
+				t.Fatalf("artifacts = %+v, want %v", view.Artifacts, tt.wantFiles)
+			}
+			for _, artifact := range view.Artifacts {
+				reader, err := s.artifactStorage().Get(context.Background(), artifact.Key)
+				if err != nil {
+					t.Fatalf("Get(%s) error = %v", artifact.Key, err)
+				}
+				data, _ := io.ReadAll(reader)
+				reader.Close()
+				file, err := parquet.Read(data)
+				if err != nil {
+					t.Fatalf("parquet.Read(%s) error = %v", artifact.Key, err)
+				}
+				if want := tt.wantFiles[artifact.Name]; artifact.Rows != want || int64(len(file.Rows)) != want || artifact.DownloadURL == "" {
+					t.Errorf("artifact %+v has %d rows, want %d", artifact, len(file.Rows), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+)
+
+// pullExportSelection возвращает задачу обратной выгрузки, забираемую обработкой 1С, и ее отбор.
+// Отбор рассчитывается при первом запросе и используется до завершения выгрузки
+func (s *Server) pullExportSelection(exportID, uploadUUID string) (*ExportJob, *exportSelection, error) {
+	job := s.getExportJob(strings.TrimSpace(exportID))
+	if job == nil || job.Type != ExportTypePull {
+		return nil, nil, apperrors.NotFound("export_not_found", "pull export job not found")
+	}
+	if job.UploadUUID != uploadUUID {
+		return nil, nil, apperrors.Validation("export_upload_mismatch", "export job belongs to another upload")
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.Status == ExportStatusFinished || job.Status == ExportStatusFailed {
+		return nil, nil, apperrors.Conflict("export_finished", fmt.Sprintf("export job is already %s", job.Status))
+	}
+	if job.selection == nil {
+		selection, err := s.prepareExportSelection(job)
+		if err != nil {
+			return nil, nil, err
+		}
+		job.selection = selection
+	}
+	return job, job.selection, nil
+}
+
+// pullExportModuleCode формирует область модуля обработки 1С для выгрузки pull: идентификатор задачи,
+// выбранные справочники и тела запросов к /api/1c/import/* с отбором задачи
+func pullExportModuleCode(job *ExportJob) string {
+	quote := func(value string) string {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+
+	var catalogs strings.Builder
+	for _, name := range job.Options.CatalogNames {
+		catalogs.WriteString("\tСправочники.Добавить(" + quote(name) + ");\n")
+	}
+
+	return `#Область ОбратнаяВыгрузка
+
+// Идентификатор задачи обратной выгрузки, для которой сформирована обработка
+Функция ИдентификаторОбратнойВыгрузки() Экспорт
+	Возврат ` + quote(job.ID) + `;
+КонецФункции
+
+// Справочники, выбранные для обратной выгрузки; пустой массив - все справочники выгрузки
+Функция СправочникиОбратнойВыгрузки() Экспорт
+	Справочники = Новый Массив;
+` + catalogs.String() + `	Возврат Справочники;
+КонецФункции
+
+// Элемент отбора задачи для XML-запросов загрузки; сервер применяет по нему справочники, поля и фильтры задачи
+Функция ЭлементОтбораОбратнойВыгрузки() Экспорт
+	Возврат "<export_id>" + ИдентификаторОбратнойВыгрузки() + "</export_id>";
+КонецФункции
+
+// Тело запроса /api/1c/import/handshake
+Функция ЗапросРукопожатияОбратнойВыгрузки(ИмяБазы) Экспорт
+	Возврат "<import_handshake><db_name>" + ЭкранироватьXML(ИмяБазы) + "</db_name>"
+		+ ЭлементОтбораОбратнойВыгрузки() + "</import_handshake>";
+КонецФункции
+
+// Тело запроса /api/1c/import/get-constants
+Функция ЗапросКонстантОбратнойВыгрузки(ИмяБазы, Смещение, Лимит) Экспорт
+	Возврат "<import_get_constants><db_name>" + ЭкранироватьXML(ИмяБазы) + "</db_name>"
+		+ "<offset>" + Формат(Смещение, "ЧН=0; ЧГ=0") + "</offset><limit>" + Формат(Лимит, "ЧГ=0") + "</limit>"
+		+ ЭлементОтбораОбратнойВыгрузки() + "</import_get_constants>";
+КонецФункции
+
+// Тело запроса /api/1c/import/get-catalog
+Функция ЗапросСправочникаОбратнойВыгрузки(ИмяБазы, ИмяСправочника, Смещение, Лимит) Экспорт
+	Возврат "<import_get_catalog><db_name>" + ЭкранироватьXML(ИмяБазы) + "</db_name>"
+		+ "<catalog_name>" + ЭкранироватьXML(ИмяСправочника) + "</catalog_name>"
+		+ "<offset>" + Формат(Смещение, "ЧН=0; ЧГ=0") + "</offset><limit>" + Формат(Лимит, "ЧГ=0") + "</limit>"
+		+ ЭлементОтбораОбратнойВыгрузки() + "</import_get_catalog>";
+КонецФункции
+
+// Тело запроса /api/1c/import/complete; завершает задачу и фиксирует отметку для отбора измененных
+Функция ЗапросЗавершенияОбратнойВыгрузки(ИмяБазы) Экспорт
+	Возврат "<import_complete><db_name>" + ЭкранироватьXML(ИмяБазы) + "</db_name>"
+		+ ЭлементОтбораОбратнойВыгрузки() + "</import_complete>";
+КонецФункции
+
+#КонецОбласти`
+}
+
+// startPull отмечает рукопожатие обработки 1С; первое рукопожатие переводит задачу в выполнение
+func (job *ExportJob) startPull() {
+	job.mu.Lock()
+	started := job.Status == ExportStatusPending
+	if started {
+		now := time.Now()
+		job.Status = ExportStatusRunning
+		job.StartedAt = &now
+	}
+	job.Progress.Handshake = true
+	job.mu.Unlock()
+	if started {
+		job.statusChanged()
+	}
+}
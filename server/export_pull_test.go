@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestPullExportSelection(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+
+	const uploadUUID = "11111111-2222-3333-4444-555555555555"
+	upload, err := db.CreateUpload(uploadUUID, "8.3", "Бухгалтерия")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	for _, name := range []string{"Номенклатура", "Контрагенты"} {
+		catalog, err := db.AddCatalog(upload.ID, name, name)
+		if err != nil {
+			t.Fatalf("AddCatalog() error = %v", err)
+		}
+		for _, ref := range []string{"ref-1", "ref-2"} {
+			attributes := map[string]interface{}{"Артикул": "A-" + ref}
+			if err := db.AddCatalogItem(catalog.ID, name+"-"+ref, ref, "Элемент "+ref, attributes, nil); err != nil {
+				t.Fatalf("AddCatalogItem() error = %v", err)
+			}
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code)
+		VALUES ('Номенклатура-ref-1', 'Элемент ref-1', 'ref-1', 'элемент', 'прочее', '01.11')`); err != nil {
+		t.Fatalf("Failed to insert normalized item: %v", err)
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), db: db, unifiedCatalogsDB: db, exportJobs: make(map[string]*ExportJob)}
+	newPullJob := func(filter *ExportFilter) *ExportJob {
+		job := newExportJob(uploadUUID, "", ExportOptions{
+			IncludeCatalogs: true,
+			CatalogNames:    []string{"Номенклатура"},
+			Fields:          []string{exportFieldTableParts},
+			Filter:          filter,
+		}, defaultExportTimeout)
+		job.Type = ExportTypePull
+		s.exportJobs[job.ID] = job
+		return job
+	}
+	post := func(handler http.HandlerFunc, body string, out interface{}) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/api/1c/import", strings.NewReader(body)))
+		if out != nil && rec.Code == http.StatusOK {
+			if err := xml.Unmarshal(rec.Body.Bytes(), out); err != nil {
+				t.Fatalf("xml.Unmarshal() error = %v: %s", err, rec.Body.String())
+			}
+		}
+		return rec.Code
+	}
+	getCatalog := func(job *ExportJob, catalogName string) (ImportGetCatalogResponse, int) {
+		var resp ImportGetCatalogResponse
+		status := post(s.handle1CImportGetCatalog, `<import_get_catalog><db_name>`+uploadUUID+`</db_name><catalog_name>`+catalogName+
+			`</catalog_name><export_id>`+job.ID+`</export_id></import_get_catalog>`, &resp)
+		return resp, status
+	}
+
+	job := newPullJob(&ExportFilter{OnlyNormalized: true, ChangedSincePrevious: true})
+
+	var handshake ImportHandshakeResponse
+	if status := post(s.handle1CImportHandshake, `<import_handshake><db_name>`+uploadUUID+`</db_name><export_id>`+job.ID+`</export_id></import_handshake>`, &handshake); status != http.StatusOK {
+		t.Fatalf("handshake status = %d", status)
+	}
+	if len(handshake.Catalogs) != 1 || handshake.Catalogs[0].Name != "Номенклатура" || handshake.ConstantsCount != 0 {
+		t.Fatalf("handshake = %+v, want only selected catalog", handshake)
+	}
+	if view := job.snapshot(); view.Status != ExportStatusRunning || !view.Progress.Handshake {
+		t.Fatalf("job after handshake = %+v, want running", view)
+	}
+
+	if _, status := getCatalog(job, "Контрагенты"); status != http.StatusBadRequest {
+		t.Errorf("unselected catalog status = %d, want 400", status)
+	}
+	catalog, status := getCatalog(job, "Номенклатура")
+	if status != http.StatusOK || len(catalog.Items) != 1 || catalog.Skipped != 1 || catalog.Total != 2 {
+		t.Fatalf("catalog = %+v (status %d), want one normalized item of two", catalog, status)
+	}
+	if item := catalog.Items[0]; item.Reference != "Номенклатура-ref-1" || item.AttributesXML != "" {
+		t.Errorf("item = %+v, want normalized item without attributes", item)
+	}
+
+	if status := post(s.handle1CImportComplete, `<import_complete><db_name>`+uploadUUID+`</db_name><export_id>`+job.ID+`</export_id></import_complete>`, nil); status != http.StatusOK {
+		t.Fatalf("complete status = %d", status)
+	}
+	if view := job.snapshot(); view.Status != ExportStatusFinished || view.Progress.CatalogItemsSent != 1 || view.Progress.ItemsSkipped != 1 {
+		t.Fatalf("job after complete = %+v, want completed", view)
+	}
+	if _, status := getCatalog(job, "Номенклатура"); status != http.StatusConflict {
+		t.Errorf("completed job status = %d, want 409", status)
+	}
+	cursor, err := db.GetExportCursor(uploadUUID, exportPullTarget)
+	if err != nil || cursor == nil {
+		t.Fatalf("GetExportCursor() = %+v, %v, want saved cursor", cursor, err)
+	}
+
+	// Следующая выгрузка передает только элементы, изменившиеся после предыдущей
+	next := newPullJob(&ExportFilter{ChangedSincePrevious: true})
+	if catalog, _ := getCatalog(next, "Номенклатура"); len(catalog.Items) != 0 {
+		t.Fatalf("unchanged catalog = %+v, want no items", catalog.Items)
+	}
+	if _, err := db.Exec(`UPDATE normalized_data SET kpved_code = '01.12' WHERE source_reference = 'Номенклатура-ref-1'`); err != nil {
+		t.Fatalf("Failed to update normalized item: %v", err)
+	}
+	changed := newPullJob(&ExportFilter{ChangedSincePrevious: true})
+	if catalog, _ := getCatalog(changed, "Номенклатура"); len(catalog.Items) != 1 || catalog.Items[0].Reference != "Номенклатура-ref-1" {
+		t.Fatalf("changed catalog = %+v, want changed item", catalog.Items)
+	}
+
+	// Задача pull, сохраненная в service.db, обслуживается другим экземпляром сервера
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+	s.serviceDB = serviceDB
+	shared := newExportJob(uploadUUID, "", ExportOptions{IncludeCatalogs: true, CatalogNames: []string{"Контрагенты"}}, defaultExportTimeout)
+	shared.Type = ExportTypePull
+	shared.redaction = &database.RedactionProfile{Name: "partner"}
+	s.registerExportJob(shared)
+
+	other := &Server{logChan: make(chan LogEntry, 100), db: db, unifiedCatalogsDB: db, serviceDB: serviceDB, exportJobs: make(map[string]*ExportJob)}
+	handshake = ImportHandshakeResponse{}
+	if status := post(other.handle1CImportHandshake, `<import_handshake><db_name>`+uploadUUID+`</db_name><export_id>`+shared.ID+`</export_id></import_handshake>`, &handshake); status != http.StatusOK {
+		t.Fatalf("handshake on other instance status = %d", status)
+	}
+	if len(handshake.Catalogs) != 1 || handshake.Catalogs[0].Name != "Контрагенты" {
+		t.Errorf("handshake on other instance = %+v, want selected catalog", handshake)
+	}
+	restored := other.getExportJob(shared.ID)
+	if restored == nil || !restored.restored || restored.redaction == nil || restored.redaction.Name != "partner" {
+		t.Fatalf("restored job = %+v, want job with redaction profile", restored)
+	}
+	if status := post(other.handle1CImportComplete, `<import_complete><db_name>`+uploadUUID+`</db_name><export_id>`+shared.ID+`</export_id></import_complete>`, nil); status != http.StatusOK {
+		t.Fatalf("complete on other instance status = %d", status)
+	}
+	if job := s.getExportJob(shared.ID); job.snapshot().Status != ExportStatusFinished {
+		t.Errorf("job on creating instance = %s, want completed by other instance", job.snapshot().Status)
+	}
+	views := other.getExportJobsByUpload(uploadUUID)
+	if len(views) != 1 || views[0].ID != shared.ID || views[0].Status != ExportStatusFinished {
+		t.Errorf("other instance export jobs = %+v, want shared completed job", views)
+	}
+}
+
+func TestPullExportModuleCode(t *testing.T) {
+	job := newExportJob("uuid-1", "", ExportOptions{CatalogNames: []string{`Товары "Опт"`}}, defaultExportTimeout)
+	job.Type = ExportTypePull
+
+	code := pullExportModuleCode(job)
+	for _, want := range []string{
+		`Возврат "` + job.ID + `";`,
+		`Справочники.Добавить("Товары ""Опт""");`,
+		"#Область ОбратнаяВыгрузка",
+		"#КонецОбласти",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("module code does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// Наборы полей элементов обратной выгрузки; ссылка, код и наименование передаются всегда
+const (
+	exportFieldAttributes = "attributes"  // Реквизиты элемента
+	exportFieldTableParts = "table_parts" // Табличные части элемента
+)
+
+// exportPullTarget приемник в отметках выгрузок, забираемых обработкой 1С
+const exportPullTarget = "pull"
+
+// ExportFilter отбор элементов обратной выгрузки по нормализованным данным
+type ExportFilter struct {
+	OnlyNormalized       bool `json:"only_normalized"`        // Только элементы, попавшие в normalized_data
+	OnlyClassified       bool `json:"only_classified"`        // Только элементы с кодом КПВЭД
+	ChangedSincePrevious bool `json:"changed_since_previous"` // Только элементы, измененные после предыдущей выгрузки в тот же приемник
+}
+
+// active проверяет, что задан хотя бы один отбор
+func (f *ExportFilter) active() bool {
+	return f != nil && (f.OnlyNormalized || f.OnlyClassified || f.ChangedSincePrevious)
+}
+
+// normalizeExportFields проверяет наборы полей и убирает повторы; пустой список - все поля
+func normalizeExportFields(fields []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		clean := strings.ToLower(strings.TrimSpace(field))
+		switch clean {
+		case "":
+			continue
+		case exportFieldAttributes, exportFieldTableParts:
+		default:
+			return nil, fmt.Errorf("unknown field set: %s", field)
+		}
+		if !seen[clean] {
+			seen[clean] = true
+			result = append(result, clean)
+		}
+	}
+	return result, nil
+}
+
+// exportSelection отбор элементов задачи обратной выгрузки, рассчитанный при ее запуске
+type exportSelection struct {
+	catalogs   map[string]bool // Выбранные справочники; пустой - все
+	references map[string]bool // Ссылки, прошедшие отбор по нормализации; nil - без отбора
+	changed    map[string]bool // Ссылки, нормализованные данные которых изменились после предыдущей выгрузки
+	since      *time.Time      // Время предыдущей выгрузки; nil - отбор по изменениям не применяется
+	attributes bool
+	tableParts bool
+	target     string
+	headSeq    int64 // Позиция журнала изменений на момент запуска, сохраняется в отметку выгрузки
+}
+
+// exportCursorTarget приемник задачи в отметках выгрузок
+func exportCursorTarget(job *ExportJob) string {
+	if job.Type == ExportTypePull {
+		return exportPullTarget
+	}
+	return job.TargetURL
+}
+
+// prepareExportSelection рассчитывает отбор задачи: ссылки нормализованных и классифицированных
+// элементов и изменения после предыдущей выгрузки в тот же приемник. Позиция журнала фиксируется
+// до чтения изменений, поэтому изменения во время выгрузки попадут в следующую
+func (s *Server) prepareExportSelection(job *ExportJob) (*exportSelection, error) {
+	options := job.Options
+	selection := &exportSelection{
+		catalogs:   make(map[string]bool, len(options.CatalogNames)),
+		attributes: len(options.Fields) == 0,
+		tableParts: len(options.Fields) == 0,
+		target:     exportCursorTarget(job),
+	}
+	for _, name := range options.CatalogNames {
+		selection.catalogs[name] = true
+	}
+	for _, field := range options.Fields {
+		switch field {
+		case exportFieldAttributes:
+			selection.attributes = true
+		case exportFieldTableParts:
+			selection.tableParts = true
+		}
+	}
+	if s.db == nil {
+		if options.Filter.active() {
+			return nil, fmt.Errorf("normalized database is not available for export filters")
+		}
+		return selection, nil
+	}
+
+	headSeq, err := s.db.GetNormalizedChangesHead()
+	if err != nil {
+		return nil, err
+	}
+	selection.headSeq = headSeq
+
+	filter := options.Filter
+	if filter == nil {
+		return selection, nil
+	}
+	if filter.OnlyNormalized || filter.OnlyClassified {
+		if selection.references, err = s.db.GetNormalizedSourceReferences(filter.OnlyClassified); err != nil {
+			return nil, err
+		}
+	}
+	if filter.ChangedSincePrevious {
+		cursor, err := s.db.GetExportCursor(job.UploadUUID, selection.target)
+		if err != nil {
+			return nil, err
+		}
+		// Курсор предыдущей выгрузки старше очищенной части журнала: изменения с него не восстановить
+		if cursor != nil {
+			floor, err := s.db.GetNormalizedChangesFloor()
+			if err != nil {
+				return nil, err
+			}
+			if cursor.ChangeSeq < floor {
+				cursor = nil
+			}
+		}
+		// Без предыдущей выгрузки изменившимися считаются все элементы
+		if cursor != nil {
+			if selection.changed, err = s.db.GetChangedSourceReferences(cursor.ChangeSeq); err != nil {
+				return nil, err
+			}
+			since := cursor.ExportedAt
+			selection.since = &since
+		}
+	}
+	return selection, nil
+}
+
+// catalogAllowed проверяет, что справочник выбран для выгрузки
+func (sel *exportSelection) catalogAllowed(name string) bool {
+	return sel == nil || len(sel.catalogs) == 0 || sel.catalogs[name]
+}
+
+// allows проверяет, что элемент проходит отбор: по нормализации и по изменениям. Измененным
+// считается элемент, загруженный после предыдущей выгрузки или с измененными нормализованными данными
+func (sel *exportSelection) allows(reference string, createdAt time.Time) bool {
+	if sel == nil {
+		return true
+	}
+	if sel.references != nil && !sel.references[reference] {
+		return false
+	}
+	if sel.since != nil && !createdAt.After(*sel.since) && !sel.changed[reference] {
+		return false
+	}
+	return true
+}
+
+// filterCatalogItems оставляет элементы, прошедшие отбор, без невыбранных наборов полей.
+// Возвращает число пропущенных элементов
+func (sel *exportSelection) filterCatalogItems(items []*database.CatalogItem) ([]*database.CatalogItem, int) {
+	if sel == nil {
+		return items, 0
+	}
+	selected := items[:0]
+	for _, item := range items {
+		if !sel.allows(item.Reference, item.CreatedAt) {
+			continue
+		}
+		if !sel.attributes {
+			item.Attributes = ""
+		}
+		if !sel.tableParts {
+			item.TableParts = ""
+		}
+		selected = append(selected, item)
+	}
+	return selected, len(items) - len(selected)
+}
+
+// filterNomenclatureItems оставляет номенклатуру, прошедшую отбор, без невыбранных наборов полей.
+// Возвращает число пропущенных элементов
+func (sel *exportSelection) filterNomenclatureItems(items []*database.NomenclatureItem) ([]*database.NomenclatureItem, int) {
+	if sel == nil {
+		return items, 0
+	}
+	selected := items[:0]
+	for _, item := range items {
+		if !sel.allows(item.NomenclatureReference, item.CreatedAt) {
+			continue
+		}
+		if !sel.attributes {
+			item.AttributesXML = ""
+		}
+		if !sel.tableParts {
+			item.TablePartsXML = ""
+		}
+		selected = append(selected, item)
+	}
+	return selected, len(items) - len(selected)
+}
+
+// commitExportSelection сохраняет отметку успешной выгрузки в приемник. Ошибка не прерывает
+// выгрузку: без отметки следующая выгрузка с отбором по изменениям передаст больше элементов
+func (s *Server) commitExportSelection(job *ExportJob, sel *exportSelection) {
+	if sel == nil || s.db == nil {
+		return
+	}
+	cursor := database.ExportCursor{
+		UploadUUID: job.UploadUUID,
+		Target:     sel.target,
+		ChangeSeq:  sel.headSeq,
+		ExportedAt: time.Now(),
+	}
+	if err := s.db.SaveExportCursor(cursor); err != nil {
+		s.logExportError(job, err, "cursor")
+	}
+}
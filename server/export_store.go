@@ -0,0 +1,232 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"httpserver/database"
+)
+
+// exportJobsHistoryLimit число сохраненных задач выгрузки, возвращаемых в списках API
+const exportJobsHistoryLimit = 500
+
+// exportJobState параметры задачи выгрузки, по которым экземпляр сервера восстанавливает ее из service.db.
+// Настройки OData содержат учетные данные и не сохраняются
+type exportJobState struct {
+	Options        ExportOptions              `json:"options"`
+	Timeout        time.Duration              `json:"timeout"`
+	ContractMode   string                     `json:"contract_mode,omitempty"`
+	Contract       database.ExportContract    `json:"contract"`
+	ContractReport *database.ContractReport   `json:"contract_report,omitempty"`
+	Redaction      *database.RedactionProfile `json:"redaction,omitempty"`
+	Decrypt        bool                       `json:"decrypt,omitempty"`
+}
+
+// registerExportJob добавляет задачу в реестр экземпляра и сохраняет ее в service.db. Сохраненная задача
+// pull обслуживается любым экземпляром, поэтому маршрутизация запросов обработки 1С не обязана быть липкой
+func (s *Server) registerExportJob(job *ExportJob) {
+	if s.serviceDB != nil {
+		job.persist = s.saveExportJob
+	}
+	s.exportJobsMutex.Lock()
+	s.exportJobs[job.ID] = job
+	s.exportJobsMutex.Unlock()
+	s.saveExportJob(job)
+}
+
+// saveExportJob сохраняет представление и параметры задачи в service.db
+func (s *Server) saveExportJob(job *ExportJob) {
+	if s.serviceDB == nil {
+		return
+	}
+	view := job.snapshot()
+	job.mu.RLock()
+	state := exportJobState{
+		Options:        job.Options,
+		Timeout:        job.Timeout,
+		ContractMode:   job.contractMode,
+		Contract:       job.contract,
+		ContractReport: job.contractReport,
+		Redaction:      job.redaction,
+		Decrypt:        job.decrypt,
+	}
+	stateJSON, err := json.Marshal(state)
+	job.mu.RUnlock()
+	if err != nil {
+		log.Printf("Не удалось сохранить задачу выгрузки %s: %v", job.ID, err)
+		return
+	}
+	viewJSON, err := json.Marshal(view)
+	if err != nil {
+		log.Printf("Не удалось сохранить задачу выгрузки %s: %v", job.ID, err)
+		return
+	}
+
+	record := &database.ExportJobRecord{
+		ID:         view.ID,
+		UploadUUID: view.UploadUUID,
+		Type:       view.Type,
+		Status:     string(view.Status),
+		Error:      view.Error,
+		InstanceID: s.instanceID,
+		View:       string(viewJSON),
+		State:      string(stateJSON),
+		CreatedAt:  view.CreatedAt,
+	}
+	if err := s.serviceDB.SaveExportJob(record); err != nil {
+		log.Printf("Не удалось сохранить задачу выгрузки %s: %v", job.ID, err)
+	}
+}
+
+// restoreExportJob восстанавливает задачу из service.db. Задача pull продолжает обслуживаться восстановившим
+// ее экземпляром; остальные задачи выполняет создавший их экземпляр, восстановленная копия служит для просмотра
+func restoreExportJob(record *database.ExportJobRecord) (*ExportJob, error) {
+	var view ExportJobView
+	if err := json.Unmarshal([]byte(record.View), &view); err != nil {
+		return nil, fmt.Errorf("invalid export job view: %w", err)
+	}
+	var state exportJobState
+	if err := json.Unmarshal([]byte(record.State), &state); err != nil {
+		return nil, fmt.Errorf("invalid export job state: %w", err)
+	}
+
+	return &ExportJob{
+		ID:               record.ID,
+		Type:             record.Type,
+		UploadUUID:       record.UploadUUID,
+		RemoteUploadUUID: view.RemoteUploadUUID,
+		TargetURL:        view.TargetURL,
+		Status:           ExportStatus(record.Status),
+		Error:            record.Error,
+		CreatedAt:        view.CreatedAt,
+		StartedAt:        view.StartedAt,
+		FinishedAt:       view.FinishedAt,
+		Progress:         view.Progress,
+		Options:          state.Options,
+		Timeout:          state.Timeout,
+		Artifacts:        view.Artifacts,
+		contractMode:     state.ContractMode,
+		contract:         state.Contract,
+		contractReport:   state.ContractReport,
+		redaction:        state.Redaction,
+		decrypt:          state.Decrypt,
+		restored:         true,
+	}, nil
+}
+
+// loadExportJob восстанавливает задачу, созданную другим экземпляром или до перезапуска сервера
+func (s *Server) loadExportJob(id string) *ExportJob {
+	if s.serviceDB == nil || id == "" {
+		return nil
+	}
+	record, err := s.serviceDB.GetExportJob(id)
+	if err != nil {
+		log.Printf("Не удалось загрузить задачу выгрузки %s: %v", id, err)
+		return nil
+	}
+	if record == nil {
+		return nil
+	}
+	job, err := restoreExportJob(record)
+	if err != nil {
+		log.Printf("Не удалось восстановить задачу выгрузки %s: %v", id, err)
+		return nil
+	}
+	job.persist = s.saveExportJob
+
+	s.exportJobsMutex.Lock()
+	defer s.exportJobsMutex.Unlock()
+	if existing := s.exportJobs[id]; existing != nil {
+		return existing
+	}
+	s.exportJobs[id] = job
+	return job
+}
+
+// sharedAcrossInstances задачу могут продвигать другие экземпляры: задачу pull забирает обработка 1С
+// через любой экземпляр, восстановленную задачу выполняет создавший ее экземпляр
+func (job *ExportJob) sharedAcrossInstances() bool {
+	return job.restored || job.Type == ExportTypePull
+}
+
+// refreshSharedExportJob обновляет незавершенную задачу из service.db: задачу мог продвинуть или завершить
+// другой экземпляр. Прогресс задачи pull, обслуживаемой и этим экземпляром, заменяется только при ее завершении
+func (s *Server) refreshSharedExportJob(job *ExportJob) {
+	job.mu.RLock()
+	done := job.Status == ExportStatusFinished || job.Status == ExportStatusFailed
+	job.mu.RUnlock()
+	if done || s.serviceDB == nil {
+		return
+	}
+
+	record, err := s.serviceDB.GetExportJob(job.ID)
+	if err != nil || record == nil {
+		return
+	}
+	status := ExportStatus(record.Status)
+	if job.Type == ExportTypePull && status != ExportStatusFinished && status != ExportStatusFailed {
+		return
+	}
+	stored, err := restoreExportJob(record)
+	if err != nil {
+		return
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Status = stored.Status
+	job.Error = stored.Error
+	job.StartedAt = stored.StartedAt
+	job.FinishedAt = stored.FinishedAt
+	job.Progress = stored.Progress
+	job.RemoteUploadUUID = stored.RemoteUploadUUID
+	job.Artifacts = stored.Artifacts
+	job.contractReport = stored.contractReport
+}
+
+// exportJobViews возвращает задачи экземпляра и сохраненные задачи других экземпляров,
+// новые первыми; uploadUUID пустой - задачи всех выгрузок
+func (s *Server) exportJobViews(uploadUUID string) []ExportJobView {
+	s.exportJobsMutex.RLock()
+	local := make(map[string]*ExportJob, len(s.exportJobs))
+	for id, job := range s.exportJobs {
+		if uploadUUID == "" || job.UploadUUID == uploadUUID {
+			local[id] = job
+		}
+	}
+	s.exportJobsMutex.RUnlock()
+
+	views := make([]ExportJobView, 0, len(local))
+	for _, job := range local {
+		if job.sharedAcrossInstances() {
+			s.refreshSharedExportJob(job)
+		}
+		views = append(views, job.snapshot())
+	}
+
+	if s.serviceDB != nil {
+		records, err := s.serviceDB.GetExportJobs(uploadUUID, exportJobsHistoryLimit)
+		if err != nil {
+			log.Printf("Не удалось загрузить задачи выгрузки: %v", err)
+		}
+		for _, record := range records {
+			if local[record.ID] != nil {
+				continue
+			}
+			job, err := restoreExportJob(record)
+			if err != nil {
+				log.Printf("Не удалось восстановить задачу выгрузки %s: %v", record.ID, err)
+				continue
+			}
+			views = append(views, job.snapshot())
+		}
+	}
+
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].CreatedAt.After(views[j].CreatedAt)
+	})
+	return views
+}
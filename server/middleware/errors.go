@@ -5,21 +5,30 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"httpserver/apperrors"
 )
 
 // ErrorResponse структура ответа об ошибке
 type ErrorResponse struct {
 	Error     string `json:"error"`
+	Code      string `json:"code"` // Машиночитаемый код ошибки
 	Timestamp string `json:"timestamp"`
 }
 
-// WriteJSONError записывает JSON ошибку
+// WriteJSONError записывает JSON ошибку, код ошибки определяется по HTTP статусу
 func WriteJSONError(w http.ResponseWriter, message string, statusCode int) {
+	WriteJSONErrorCode(w, message, apperrors.CodeForStatus(statusCode), statusCode)
+}
+
+// WriteJSONErrorCode записывает JSON ошибку с заданным машиночитаемым кодом
+func WriteJSONErrorCode(w http.ResponseWriter, message, code string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	
 	response := ErrorResponse{
-		Error:     message,
+		Error:     localizeError(w, message),
+		Code:      code,
 		Timestamp: time.Now().Format(time.RFC3339),
 	}
 	
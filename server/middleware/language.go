@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"httpserver/i18n"
+)
+
+// languageWriter ResponseWriter с выбранным для запроса языком ответа
+type languageWriter struct {
+	http.ResponseWriter
+	lang string
+}
+
+// Flush пробрасывает Flush для SSE обработчиков
+func (w *languageWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap возвращает исходный ResponseWriter (для http.ResponseController)
+func (w *languageWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WithLanguage привязывает язык ответа к ResponseWriter и выставляет Content-Language
+func WithLanguage(w http.ResponseWriter, lang string) http.ResponseWriter {
+	w.Header().Set("Content-Language", lang)
+	return &languageWriter{ResponseWriter: w, lang: lang}
+}
+
+// LanguageOf возвращает язык, явно выбранный для ответа, или пустую строку
+func LanguageOf(w http.ResponseWriter) string {
+	if lw, ok := w.(*languageWriter); ok {
+		return lw.lang
+	}
+	return ""
+}
+
+// localizeError переводит сообщение об ошибке на язык ответа, если он выбран
+func localizeError(w http.ResponseWriter, message string) string {
+	if lang := LanguageOf(w); lang != "" {
+		return i18n.TranslateError(lang, message)
+	}
+	return message
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"httpserver/tracing"
 )
 
 // SecurityHeadersMiddleware добавляет заголовки безопасности
@@ -21,7 +23,7 @@ func SecurityHeadersMiddleware(next http.Handler) http.Handler {
 			// В продакшене здесь должна быть проверка разрешенных origins
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, X-CSRF-Token")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Max-Age", "3600")
 		}
@@ -57,6 +59,9 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startTime := time.Now()
 		requestID := r.Header.Get("X-Request-ID")
+		if traceID := tracing.TraceIDFromContext(r.Context()); traceID != "" {
+			requestID += " trace=" + traceID
+		}
 		
 		// Логируем входящий запрос
 		log.Printf("[%s] %s %s from %s", requestID, r.Method, r.URL.Path, r.RemoteAddr)
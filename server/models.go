@@ -1,312 +1,47 @@
 package server
 
 import (
-	"encoding/xml"
-	"io"
-	"strings"
 	"time"
 
+	"httpserver/client"
 	"httpserver/database"
 )
 
-// HandshakeRequest запрос рукопожатия
-type HandshakeRequest struct {
-	XMLName         xml.Name `xml:"handshake"`
-	DatabaseID      string   `xml:"database_id,omitempty"`
-	Version1C       string   `xml:"version_1c"`
-	ConfigName      string   `xml:"config_name"`
-	ConfigVersion   string   `xml:"config_version,omitempty"`
-	ComputerName    string   `xml:"computer_name,omitempty"`
-	UserName        string   `xml:"user_name,omitempty"`
-	Timestamp       string   `xml:"timestamp"`
-	UploadType      string   `xml:"upload_type,omitempty"` // Тип выгружаемых данных (Номенклатура, Контрагенты, ПолнаяВыгрузка и т.д.)
-	// Поля для итераций
-	IterationNumber int    `xml:"iteration_number,omitempty"`
-	IterationLabel  string `xml:"iteration_label,omitempty"`
-	ProgrammerName  string `xml:"programmer_name,omitempty"`
-	UploadPurpose   string `xml:"upload_purpose,omitempty"`
-	ParentUploadID  string `xml:"parent_upload_id,omitempty"` // UUID родительской выгрузки
-}
-
-// HandshakeResponse ответ на рукопожатие
-type HandshakeResponse struct {
-	XMLName      xml.Name `xml:"handshake_response"`
-	Success      bool     `xml:"success"`
-	UploadUUID   string   `xml:"upload_uuid"`
-	ClientName   string   `xml:"client_name,omitempty"`
-	ProjectName  string   `xml:"project_name,omitempty"`
-	DatabaseName string   `xml:"database_name,omitempty"`
-	DatabasePath string   `xml:"database_path,omitempty"` // Путь к созданной БД
-	DatabaseID   int      `xml:"database_id,omitempty"`   // ID в service.db (если зарегистрирована)
-	Message      string   `xml:"message"`
-	Timestamp    string   `xml:"timestamp"`
-}
-
-// MetadataRequest запрос метаинформации
-type MetadataRequest struct {
-	XMLName       xml.Name `xml:"metadata"`
-	UploadUUID    string   `xml:"upload_uuid"`
-	DatabaseID    string   `xml:"database_id,omitempty"`
-	Version1C     string   `xml:"version_1c"`
-	ConfigName    string   `xml:"config_name"`
-	ConfigVersion string   `xml:"config_version,omitempty"`
-	ComputerName  string   `xml:"computer_name,omitempty"`
-	UserName      string   `xml:"user_name,omitempty"`
-	Timestamp     string   `xml:"timestamp"`
-}
-
-// MetadataResponse ответ на метаинформацию
-type MetadataResponse struct {
-	XMLName     xml.Name `xml:"metadata_response"`
-	Success     bool     `xml:"success"`
-	Message     string   `xml:"message"`
-	Timestamp   string   `xml:"timestamp"`
-}
-
-// ConstantValue обертка для значения константы с поддержкой вложенного XML
-type ConstantValue struct {
-	Content string
-}
-
-// UnmarshalXML кастомный парсер для получения всего содержимого тега value
-func (cv *ConstantValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	var content strings.Builder
-	depth := 0
-	
-	for {
-		token, err := d.Token()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
-		
-		switch t := token.(type) {
-		case xml.StartElement:
-			depth++
-			// Записываем открывающий тег
-			content.WriteString("<" + t.Name.Local)
-			for _, attr := range t.Attr {
-				escaped := strings.ReplaceAll(attr.Value, "&", "&amp;")
-				escaped = strings.ReplaceAll(escaped, "<", "&lt;")
-				escaped = strings.ReplaceAll(escaped, ">", "&gt;")
-				escaped = strings.ReplaceAll(escaped, "\"", "&quot;")
-				content.WriteString(" " + attr.Name.Local + "=\"" + escaped + "\"")
-			}
-			content.WriteString(">")
-		case xml.EndElement:
-			if t.Name == start.Name && depth == 0 {
-				// Это закрывающий тег value - завершаем
-				cv.Content = content.String()
-				return nil
-			}
-			depth--
-			content.WriteString("</" + t.Name.Local + ">")
-		case xml.CharData:
-			// Записываем текстовое содержимое (уже экранировано парсером)
-			content.Write(t)
-		}
-	}
-	
-	cv.Content = content.String()
-	return nil
-}
-
-// XMLContent обертка для XML содержимого (для attributes_xml и table_parts)
-type XMLContent struct {
-	Content string
-}
-
-// UnmarshalXML кастомный парсер для получения всего содержимого XML тега
-func (xc *XMLContent) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	var content strings.Builder
-	depth := 0
-	
-	for {
-		token, err := d.Token()
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
-		}
-		
-		switch t := token.(type) {
-		case xml.StartElement:
-			depth++
-			// Записываем открывающий тег
-			content.WriteString("<" + t.Name.Local)
-			for _, attr := range t.Attr {
-				// Атрибуты уже экранированы в XML, просто записываем их
-				content.WriteString(" " + attr.Name.Local + "=\"" + attr.Value + "\"")
-			}
-			content.WriteString(">")
-		case xml.EndElement:
-			if t.Name == start.Name && depth == 0 {
-				// Это закрывающий тег - завершаем
-				xc.Content = content.String()
-				return nil
-			}
-			depth--
-			content.WriteString("</" + t.Name.Local + ">")
-		case xml.CharData:
-			// Записываем текстовое содержимое
-			content.Write(t)
-		}
-	}
-	
-	xc.Content = content.String()
-	return nil
-}
-
-// String возвращает содержимое как строку
-func (xc *XMLContent) String() string {
-	return xc.Content
-}
-
-// ConstantRequest запрос константы
-type ConstantRequest struct {
-	XMLName     xml.Name      `xml:"constant"`
-	UploadUUID  string        `xml:"upload_uuid"`
-	Name        string        `xml:"name"`
-	Synonym     string        `xml:"synonym"`
-	Type        string        `xml:"type"`
-	Value       ConstantValue  `xml:"value"` // Используем структуру с кастомным UnmarshalXML для получения всего содержимого тега value
-	Timestamp   string        `xml:"timestamp"`
-}
-
-// ConstantResponse ответ на константу
-type ConstantResponse struct {
-	XMLName     xml.Name `xml:"constant_response"`
-	Success     bool     `xml:"success"`
-	Message     string   `xml:"message"`
-	Timestamp   string   `xml:"timestamp"`
-}
-
-// CatalogMetaRequest запрос метаданных справочника
-type CatalogMetaRequest struct {
-	XMLName     xml.Name `xml:"catalog_meta"`
-	UploadUUID  string   `xml:"upload_uuid"`
-	Name        string   `xml:"name"`
-	Synonym     string   `xml:"synonym"`
-	Timestamp   string   `xml:"timestamp"`
-}
-
-// CatalogMetaResponse ответ на метаданные справочника
-type CatalogMetaResponse struct {
-	XMLName     xml.Name `xml:"catalog_meta_response"`
-	Success     bool     `xml:"success"`
-	CatalogID   int      `xml:"catalog_id"`
-	Message     string   `xml:"message"`
-	Timestamp   string   `xml:"timestamp"`
-}
-
-// CatalogItemRequest запрос элемента справочника
-type CatalogItemRequest struct {
-	XMLName     xml.Name   `xml:"catalog_item"`
-	UploadUUID  string     `xml:"upload_uuid"`
-	CatalogName string     `xml:"catalog_name"`
-	Reference   string     `xml:"reference"`
-	Code        string     `xml:"code"`
-	Name        string     `xml:"name"`
-	Attributes  XMLContent `xml:"attributes_xml"`  // XML строка - используем кастомный парсер
-	TableParts  XMLContent `xml:"table_parts"`     // XML строка - используем кастомный парсер
-	Timestamp   string     `xml:"timestamp"`
-}
-
-// CatalogItemResponse ответ на элемент справочника
-type CatalogItemResponse struct {
-	XMLName     xml.Name `xml:"catalog_item_response"`
-	Success     bool     `xml:"success"`
-	Message     string   `xml:"message"`
-	Timestamp   string   `xml:"timestamp"`
-}
-
-// CatalogItem элемент справочника для пакетной выгрузки
-type CatalogItem struct {
-	XMLName     xml.Name   `xml:"item"`
-	Reference   string     `xml:"reference"`
-	Code        string     `xml:"code"`
-	Name        string     `xml:"name"`
-	Attributes  XMLContent `xml:"attributes_xml"`  // XML строка - используем кастомный парсер
-	TableParts  XMLContent `xml:"table_parts"`     // XML строка - используем кастомный парсер
-	Timestamp   string     `xml:"timestamp"`
-}
-
-// CatalogItemsRequest запрос пакетной загрузки элементов справочника
-type CatalogItemsRequest struct {
-	XMLName     xml.Name      `xml:"catalog_items"`
-	UploadUUID  string        `xml:"upload_uuid"`
-	CatalogName string        `xml:"catalog_name"`
-	Items       []CatalogItem `xml:"items>item"`
-}
-
-// CatalogItemsResponse ответ на пакетную загрузку элементов справочника
-type CatalogItemsResponse struct {
-	XMLName        xml.Name `xml:"catalog_items_response"`
-	Success        bool     `xml:"success"`
-	ProcessedCount int      `xml:"processed_count"`
-	FailedCount    int      `xml:"failed_count"`
-	Message        string   `xml:"message"`
-	Timestamp      string   `xml:"timestamp"`
-}
-
-// NomenclatureItem элемент номенклатуры с характеристикой для пакетной загрузки
-type NomenclatureItem struct {
-	XMLName                xml.Name `xml:"item"`
-	NomenclatureReference  string   `xml:"nomenclature_reference"`
-	NomenclatureCode       string   `xml:"nomenclature_code"`
-	NomenclatureName       string   `xml:"nomenclature_name"`
-	CharacteristicReference string  `xml:"characteristic_reference,omitempty"`
-	CharacteristicName     string   `xml:"characteristic_name,omitempty"`
-	Attributes             string   `xml:"attributes"`
-	TableParts             string   `xml:"table_parts"`
-	Timestamp              string   `xml:"timestamp,omitempty"`
-}
-
-// NomenclatureBatchRequest запрос пакетной загрузки номенклатуры с характеристиками
-type NomenclatureBatchRequest struct {
-	XMLName     xml.Name          `xml:"nomenclature_batch"`
-	UploadUUID  string            `xml:"upload_uuid"`
-	DatabaseID  string            `xml:"database_id,omitempty"`
-	Items       []NomenclatureItem `xml:"items>item"`
-	Timestamp   string            `xml:"timestamp,omitempty"`
-}
-
-// NomenclatureBatchResponse ответ на пакетную загрузку номенклатуры
-type NomenclatureBatchResponse struct {
-	XMLName        xml.Name `xml:"nomenclature_batch_response"`
-	Success        bool     `xml:"success"`
-	ProcessedCount int      `xml:"processed_count"`
-	FailedCount    int      `xml:"failed_count"`
-	Message        string   `xml:"message"`
-	Timestamp      string   `xml:"timestamp"`
-}
-
-// CompleteRequest запрос завершения выгрузки
-type CompleteRequest struct {
-	XMLName     xml.Name `xml:"complete"`
-	UploadUUID  string   `xml:"upload_uuid"`
-	Timestamp   string   `xml:"timestamp"`
-}
-
-// CompleteResponse ответ на завершение выгрузки
-type CompleteResponse struct {
-	XMLName     xml.Name `xml:"complete_response"`
-	Success     bool     `xml:"success"`
-	Message     string   `xml:"message"`
-	Timestamp   string   `xml:"timestamp"`
-}
-
-// ErrorResponse общий ответ об ошибке
-type ErrorResponse struct {
-	XMLName     xml.Name `xml:"error_response"`
-	Success     bool     `xml:"success"`
-	Error       string   `xml:"error"`
-	Message     string   `xml:"message"`
-	Timestamp   string   `xml:"timestamp"`
-}
+// Структуры протокола выгрузки из 1С определены в клиентском пакете client: сервер и клиенты используют одни типы
+type (
+	HandshakeRequest          = client.HandshakeRequest
+	HandshakeResponse         = client.HandshakeResponse
+	MetadataRequest           = client.MetadataRequest
+	MetadataAttribute         = client.MetadataAttribute
+	MetadataTablePart         = client.MetadataTablePart
+	MetadataCatalog           = client.MetadataCatalog
+	MetadataResponse          = client.MetadataResponse
+	ConstantValue             = client.ConstantValue
+	XMLContent                = client.XMLContent
+	ConstantRequest           = client.ConstantRequest
+	ConstantResponse          = client.ConstantResponse
+	CatalogMetaRequest        = client.CatalogMetaRequest
+	CatalogMetaResponse       = client.CatalogMetaResponse
+	CatalogItemRequest        = client.CatalogItemRequest
+	CatalogItemResponse       = client.CatalogItemResponse
+	CatalogItem               = client.CatalogItem
+	CatalogItemsRequest       = client.CatalogItemsRequest
+	CatalogItemsResponse      = client.CatalogItemsResponse
+	CatalogAttachmentRequest  = client.CatalogAttachmentRequest
+	CatalogAttachmentResponse = client.CatalogAttachmentResponse
+	DocumentItemRequest       = client.DocumentItemRequest
+	DocumentItemResponse      = client.DocumentItemResponse
+	DocumentItem              = client.DocumentItem
+	DocumentItemsRequest      = client.DocumentItemsRequest
+	DocumentItemsResponse     = client.DocumentItemsResponse
+	NomenclatureItem          = client.NomenclatureItem
+	NomenclatureBatchRequest  = client.NomenclatureBatchRequest
+	NomenclatureBatchResponse = client.NomenclatureBatchResponse
+	CompleteRequest           = client.CompleteRequest
+	CompleteResponse          = client.CompleteResponse
+	SandboxCounts             = client.SandboxCounts
+	ErrorResponse             = client.ErrorResponse
+)
 
 // ServerStats статистика сервера
 type ServerStats struct {
@@ -335,81 +70,20 @@ type LogEntry struct {
 	Message     string    `json:"message"`
 	UploadUUID  string    `json:"upload_uuid,omitempty"`
 	Endpoint    string    `json:"endpoint,omitempty"`
-}
-
-// API Models для получения данных
-
-// UploadListItem краткая информация о выгрузке для списка
-type UploadListItem struct {
-	UploadUUID     string     `json:"upload_uuid"`
-	StartedAt      time.Time  `json:"started_at"`
-	CompletedAt    *time.Time `json:"completed_at,omitempty"`
-	Status         string     `json:"status"`
-	Version1C      string     `json:"version_1c"`
-	ConfigName     string     `json:"config_name"`
-	TotalConstants int        `json:"total_constants"`
-	TotalCatalogs  int        `json:"total_catalogs"`
-	TotalItems     int        `json:"total_items"`
-}
-
-// CatalogInfo информация о справочнике с количеством элементов
-type CatalogInfo struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Synonym   string    `json:"synonym"`
-	ItemCount int       `json:"item_count"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
-// UploadDetails детальная информация о выгрузке
-type UploadDetails struct {
-	UploadUUID     string        `json:"upload_uuid"`
-	StartedAt      time.Time     `json:"started_at"`
-	CompletedAt    *time.Time    `json:"completed_at,omitempty"`
-	Status         string        `json:"status"`
-	Version1C      string        `json:"version_1c"`
-	ConfigName     string        `json:"config_name"`
-	TotalConstants int           `json:"total_constants"`
-	TotalCatalogs  int           `json:"total_catalogs"`
-	TotalItems     int           `json:"total_items"`
-	Catalogs       []CatalogInfo `json:"catalogs"`
-	Constants      []interface{} `json:"constants"`
-}
-
-// DataItem элемент данных для API ответа
-type DataItem struct {
-	XMLName   xml.Name   `xml:"item"`
-	Type      string     `xml:"type,attr"` // "constant" или "catalog_item"
-	ID        int        `xml:"id,attr"`
-	Data      string     `xml:",innerxml"` // XML данные как строка
-	CreatedAt time.Time  `xml:"created_at,attr"`
-}
-
-// DataResponse ответ с данными выгрузки
-type DataResponse struct {
-	XMLName   xml.Name   `xml:"data_response"`
-	UploadUUID string     `xml:"upload_uuid"`
-	Type       string     `xml:"type"` // "all", "constants", "catalogs"
-	Page       int        `xml:"page"`
-	Limit      int        `xml:"limit"`
-	Total      int        `xml:"total"`
-	Items      []DataItem `xml:"items>item"`
-}
-
-// VerifyRequest запрос на проверку передачи
-type VerifyRequest struct {
-	ReceivedIDs []int `json:"received_ids"`
-}
-
-// VerifyResponse ответ на проверку передачи
-type VerifyResponse struct {
-	UploadUUID      string   `json:"upload_uuid"`
-	ExpectedTotal   int      `json:"expected_total"`
-	ReceivedCount   int      `json:"received_count"`
-	MissingIDs      []int    `json:"missing_ids,omitempty"`
-	IsComplete      bool     `json:"is_complete"`
-	Message         string   `json:"message"`
-}
+	TraceID     string    `json:"trace_id,omitempty"`
+}
+
+// API Models для получения данных (определены в пакете client)
+type (
+	UploadListItem   = client.UploadListItem
+	CatalogInfo      = client.CatalogInfo
+	UploadDetails    = client.UploadDetails
+	DocumentTypeInfo = client.DocumentTypeInfo
+	DataItem         = client.DataItem
+	DataResponse     = client.DataResponse
+	VerifyRequest    = client.VerifyRequest
+	VerifyResponse   = client.VerifyResponse
+)
 
 // NomenclatureProcessingResponse ответ на запрос запуска обработки номенклатуры
 type NomenclatureProcessingResponse struct {
@@ -608,6 +282,7 @@ type QualityDashboard struct {
 	TopIssues       []database.DataQualityIssue `json:"top_issues"`
 	MetricsByEntity map[string]EntityMetrics  `json:"metrics_by_entity"`
 	Recommendations []Recommendation          `json:"recommendations,omitempty"`
+	TagCounts       map[string]int            `json:"tag_counts,omitempty"` // Отмеченные записи по тегам проекта базы
 }
 
 // EntityMetrics метрики по типу сущности
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/client"
+	"httpserver/database"
+)
+
+// Версии протокола выгрузки из 1С (описаны в пакете client). Обработка и сервер обновляются независимо, поэтому
+// сервер принимает все версии от MinSupportedProtocolVersion и разбирает пакеты по правилам версии клиента
+const (
+	ProtocolVersionLegacy      = client.ProtocolVersionLegacy
+	ProtocolVersionUnversioned = client.ProtocolVersionUnversioned
+	ProtocolVersionCurrent     = client.ProtocolVersionCurrent
+
+	// MinSupportedProtocolVersion минимальная версия, которую умеет разбирать сервер
+	MinSupportedProtocolVersion = ProtocolVersionLegacy
+)
+
+// ProtocolVersionInfo строка матрицы совместимости версий протокола выгрузки
+type ProtocolVersionInfo struct {
+	Version     int      `json:"version"`
+	Description string   `json:"description"`
+	Features    []string `json:"features"`
+	Supported   bool     `json:"supported"`  // Не ниже минимальной версии, заданной MIN_PROTOCOL_VERSION
+	Deprecated  bool     `json:"deprecated"` // Принимается, но обработку нужно обновить
+}
+
+// protocolVersions матрица совместимости: что передает обработка каждой версии
+var protocolVersions = []ProtocolVersionInfo{
+	{
+		Version:     ProtocolVersionLegacy,
+		Description: "Первые обработки: реквизиты элемента в <attributes>, табличные части в <tabular_sections>",
+		Features:    []string{"catalog_items", "constants"},
+		Deprecated:  true,
+	},
+	{
+		Version:     ProtocolVersionUnversioned,
+		Description: "Обработки без protocol_version в рукопожатии: реквизиты в <attributes_xml>, пакетная номенклатура",
+		Features:    []string{"catalog_items", "constants", "nomenclature_batch", "iterations"},
+	},
+	{
+		Version:     ProtocolVersionCurrent,
+		Description: "UUID выгрузки от клиента, описания справочников и продвигаемые реквизиты",
+		Features:    []string{"catalog_items", "constants", "nomenclature_batch", "iterations", "client_upload_uuid", "catalog_metadata", "indexed_attributes"},
+	},
+}
+
+// minProtocolVersion минимальная версия протокола, принимаемая сервером
+func (s *Server) minProtocolVersion() int {
+	if s.currentConfig() != nil && s.currentConfig().MinProtocolVersion > MinSupportedProtocolVersion {
+		return s.currentConfig().MinProtocolVersion
+	}
+	return MinSupportedProtocolVersion
+}
+
+// protocolCompatibility возвращает матрицу совместимости с учетом минимальной версии сервера
+func (s *Server) protocolCompatibility() []ProtocolVersionInfo {
+	minVersion := s.minProtocolVersion()
+	matrix := make([]ProtocolVersionInfo, len(protocolVersions))
+	for i, info := range protocolVersions {
+		info.Supported = info.Version >= minVersion
+		matrix[i] = info
+	}
+	return matrix
+}
+
+// isProtocolDeprecated проверяет, устарела ли версия протокола
+func isProtocolDeprecated(version int) bool {
+	for _, info := range protocolVersions {
+		if info.Version == version {
+			return info.Deprecated
+		}
+	}
+	return false
+}
+
+// upgradeRequiredError версия протокола клиента ниже минимальной поддерживаемой
+type upgradeRequiredError struct {
+	Version    int
+	MinVersion int
+}
+
+func (e *upgradeRequiredError) Error() string {
+	return fmt.Sprintf("protocol version %d is no longer supported, minimum supported version is %d", e.Version, e.MinVersion)
+}
+
+// negotiateProtocolVersion согласует версию протокола рукопожатия: пустая версия - обработка
+// без поддержки версий, версия новее серверной понижается до текущей, версия ниже минимальной отклоняется
+func (s *Server) negotiateProtocolVersion(requested string) (int, error) {
+	requested = strings.TrimSpace(requested)
+	if requested == "" {
+		requested = strconv.Itoa(ProtocolVersionUnversioned)
+	}
+	version, err := strconv.Atoi(requested)
+	if err != nil || version <= 0 {
+		return 0, apperrors.Validation("invalid_protocol_version", fmt.Sprintf("invalid protocol_version %q", requested))
+	}
+	if minVersion := s.minProtocolVersion(); version < minVersion {
+		return 0, &upgradeRequiredError{Version: version, MinVersion: minVersion}
+	}
+	if version > ProtocolVersionCurrent {
+		version = ProtocolVersionCurrent
+	}
+	return version, nil
+}
+
+// writeUpgradeRequired отвечает 426 Upgrade Required с минимальной поддерживаемой и текущей версиями протокола
+func (s *Server) writeUpgradeRequired(w http.ResponseWriter, upgrade *upgradeRequiredError) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusUpgradeRequired)
+	xmlData, _ := xml.MarshalIndent(ErrorResponse{
+		Success:            false,
+		Error:              upgrade.Error(),
+		Code:               "upgrade_required",
+		Message:            fmt.Sprintf("Версия протокола обработки %d не поддерживается, обновите обработку до версии не ниже %d", upgrade.Version, upgrade.MinVersion),
+		Timestamp:          time.Now().Format(time.RFC3339),
+		MinProtocolVersion: upgrade.MinVersion,
+		ProtocolVersion:    ProtocolVersionCurrent,
+	}, "", "  ")
+	w.Write([]byte(xml.Header))
+	w.Write(xmlData)
+}
+
+// writeProtocolVersionError отвечает на ошибку согласования версии протокола: 426 для устаревшей версии,
+// остальные ошибки - по виду ошибки
+func (s *Server) writeProtocolVersionError(w http.ResponseWriter, err error) {
+	var upgrade *upgradeRequiredError
+	if errors.As(err, &upgrade) {
+		s.writeUpgradeRequired(w, upgrade)
+		return
+	}
+	s.writeErrorResponse(w, "Invalid protocol version", err)
+}
+
+// uploadProtocolVersion возвращает версию протокола выгрузки. Выгрузки, начатые до появления версий,
+// считаются обработками без protocol_version. Если минимальная версия повышена после рукопожатия,
+// пакет отклоняется с 426; возвращает false, если ответ уже записан
+func (s *Server) uploadProtocolVersion(w http.ResponseWriter, uploadDB *database.DB, upload *database.Upload) (int, bool) {
+	version, err := uploadDB.GetUploadProtocolVersion(upload.ID)
+	if err != nil {
+		s.writeErrorResponse(w, "Failed to get upload protocol version", err)
+		return 0, false
+	}
+	if version == 0 {
+		version = ProtocolVersionUnversioned
+	}
+	if minVersion := s.minProtocolVersion(); version < minVersion {
+		s.writeUpgradeRequired(w, &upgradeRequiredError{Version: version, MinVersion: minVersion})
+		return 0, false
+	}
+	return version, true
+}
+
+// legacyCatalogItem элемент справочника протокола версии 1
+type legacyCatalogItem struct {
+	Reference  string     `xml:"reference"`
+	Code       string     `xml:"code"`
+	Name       string     `xml:"name"`
+	Attributes XMLContent `xml:"attributes"`
+	TableParts XMLContent `xml:"tabular_sections"`
+	Timestamp  string     `xml:"timestamp"`
+}
+
+// legacyCatalogItemRequest запрос /catalog/item протокола версии 1
+type legacyCatalogItemRequest struct {
+	XMLName     xml.Name `xml:"catalog_item"`
+	UploadUUID  string   `xml:"upload_uuid"`
+	CatalogName string   `xml:"catalog_name"`
+	legacyCatalogItem
+}
+
+// legacyCatalogItemsRequest запрос /catalog/items протокола версии 1
+type legacyCatalogItemsRequest struct {
+	XMLName     xml.Name            `xml:"catalog_items"`
+	UploadUUID  string              `xml:"upload_uuid"`
+	CatalogName string              `xml:"catalog_name"`
+	Items       []legacyCatalogItem `xml:"items>item"`
+}
+
+// adaptCatalogItemRequest разбирает /catalog/item по правилам версии протокола выгрузки;
+// возвращает false, если ответ уже записан
+func (s *Server) adaptCatalogItemRequest(w http.ResponseWriter, uploadDB *database.DB, upload *database.Upload, body []byte, req *CatalogItemRequest) bool {
+	version, ok := s.uploadProtocolVersion(w, uploadDB, upload)
+	if !ok || version != ProtocolVersionLegacy {
+		return ok
+	}
+	var legacy legacyCatalogItemRequest
+	if err := xml.Unmarshal(body, &legacy); err != nil {
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
+		return false
+	}
+	req.Reference = legacy.Reference
+	req.Code = legacy.Code
+	req.Name = legacy.Name
+	req.Attributes = legacy.Attributes
+	req.TableParts = legacy.TableParts
+	req.Timestamp = legacy.Timestamp
+	return true
+}
+
+// adaptCatalogItemsRequest разбирает /catalog/items по правилам версии протокола выгрузки;
+// возвращает false, если ответ уже записан
+func (s *Server) adaptCatalogItemsRequest(w http.ResponseWriter, uploadDB *database.DB, upload *database.Upload, body []byte, req *CatalogItemsRequest) bool {
+	version, ok := s.uploadProtocolVersion(w, uploadDB, upload)
+	if !ok || version != ProtocolVersionLegacy {
+		return ok
+	}
+	var legacy legacyCatalogItemsRequest
+	if err := xml.Unmarshal(body, &legacy); err != nil {
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
+		return false
+	}
+	req.Items = make([]CatalogItem, len(legacy.Items))
+	for i, item := range legacy.Items {
+		req.Items[i] = CatalogItem{
+			Reference:  item.Reference,
+			Code:       item.Code,
+			Name:       item.Name,
+			Attributes: item.Attributes,
+			TableParts: item.TableParts,
+			Timestamp:  item.Timestamp,
+		}
+	}
+	return true
+}
+
+// handleProtocolVersions матрица совместимости версий протокола выгрузки
+// GET /api/v1/upload/protocol
+func (s *Server) handleProtocolVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"current_version":     ProtocolVersionCurrent,
+		"min_version":         s.minProtocolVersion(),
+		"unversioned_version": ProtocolVersionUnversioned,
+		"versions":            s.protocolCompatibility(),
+	}, http.StatusOK)
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		minVersion  int
+		requested   string
+		want        int
+		wantUpgrade bool
+		wantErr     bool
+	}{
+		{"unversioned client", 0, "", ProtocolVersionUnversioned, false, false},
+		{"legacy client", 0, "1", ProtocolVersionLegacy, false, false},
+		{"current client", 0, " 3 ", ProtocolVersionCurrent, false, false},
+		{"newer client downgraded", 0, "7", ProtocolVersionCurrent, false, false},
+		{"below raised minimum", 2, "1", 0, true, true},
+		{"unversioned meets raised minimum", 2, "", ProtocolVersionUnversioned, false, false},
+		{"not a number", 0, "v3", 0, false, true},
+		{"zero", 0, "0", 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{config: &Config{MinProtocolVersion: tt.minVersion}}
+			got, err := s.negotiateProtocolVersion(tt.requested)
+			var upgrade *upgradeRequiredError
+			if (err != nil) != tt.wantErr || errors.As(err, &upgrade) != tt.wantUpgrade {
+				t.Fatalf("negotiateProtocolVersion(%q) error = %v, wantErr %v, wantUpgrade %v", tt.requested, err, tt.wantErr, tt.wantUpgrade)
+			}
+			if got != tt.want {
+				t.Errorf("negotiateProtocolVersion(%q) = %d, want %d", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyProtocolUpload(t *testing.T) {
+	unifiedPath := filepath.Join(t.TempDir(), "unified.db")
+	db, err := database.NewUnifiedDBWithConfig(unifiedPath, database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	s := &Server{db: db, unifiedCatalogsDB: db, config: &Config{UnifiedCatalogsDBPath: unifiedPath}, logChan: make(chan LogEntry, 100)}
+
+	handshake := func(version string) *httptest.ResponseRecorder {
+		body := "<handshake><version_1c>8.3</version_1c><config_name>УТ</config_name>"
+		if version != "" {
+			body += "<protocol_version>" + version + "</protocol_version>"
+		}
+		rec := httptest.NewRecorder()
+		s.handleHandshake(rec, httptest.NewRequest(http.MethodPost, "/handshake", strings.NewReader(body+"</handshake>")))
+		return rec
+	}
+
+	rec := handshake("1")
+	var response HandshakeResponse
+	if err := xml.Unmarshal(rec.Body.Bytes(), &response); err != nil || !response.Success {
+		t.Fatalf("handshake status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if response.ProtocolVersion != ProtocolVersionLegacy || !response.ProtocolDeprecated || response.MinProtocolVersion != MinSupportedProtocolVersion {
+		t.Errorf("handshake response = %+v, want deprecated legacy protocol", response)
+	}
+
+	// Реквизиты обработки версии 1 приходят в <attributes> и <tabular_sections>
+	rec = httptest.NewRecorder()
+	s.handleCatalogItems(rec, httptest.NewRequest(http.MethodPost, "/catalog/items", strings.NewReader(
+		"<catalog_items><upload_uuid>"+response.UploadUUID+"</upload_uuid><catalog_name>Товары</catalog_name><items>"+
+			"<item><reference>ref-1</reference><code>1</code><name>Дрель</name><attributes><Реквизит Имя=\"Артикул\">A-1</Реквизит></attributes>"+
+			"<tabular_sections><Штрихкоды/></tabular_sections></item></items></catalog_items>")))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("catalog items status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	tableName, err := database.GetCatalogTableName(db.GetDB(), "Товары")
+	if err != nil {
+		t.Fatalf("GetCatalogTableName() error = %v", err)
+	}
+	var attributes, tableParts string
+	if err := db.QueryRow("SELECT attributes_xml, table_parts_xml FROM "+tableName+" WHERE reference = 'ref-1'").Scan(&attributes, &tableParts); err != nil {
+		t.Fatalf("Failed to read catalog item: %v", err)
+	}
+	if !strings.Contains(attributes, "A-1") || !strings.Contains(tableParts, "Штрихкоды") {
+		t.Errorf("attributes = %q, table parts = %q", attributes, tableParts)
+	}
+
+	// Повышение минимальной версии отклоняет пакеты начатой выгрузки и новые рукопожатия
+	s.config.MinProtocolVersion = ProtocolVersionUnversioned
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"handshake": handshake("1"),
+		"catalog item": func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			s.handleCatalogItem(rec, httptest.NewRequest(http.MethodPost, "/catalog/item", strings.NewReader(
+				"<catalog_item><upload_uuid>"+response.UploadUUID+"</upload_uuid><catalog_name>Товары</catalog_name><reference>ref-2</reference></catalog_item>")))
+			return rec
+		}(),
+	} {
+		var errResponse ErrorResponse
+		xml.Unmarshal(rec.Body.Bytes(), &errResponse)
+		if rec.Code != http.StatusUpgradeRequired || errResponse.Code != "upgrade_required" || errResponse.MinProtocolVersion != ProtocolVersionUnversioned {
+			t.Errorf("%s: status = %d, body = %s, want 426 upgrade_required", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = handshake("")
+	var unversioned HandshakeResponse
+	if err := xml.Unmarshal(rec.Body.Bytes(), &unversioned); err != nil || unversioned.ProtocolVersion != ProtocolVersionUnversioned || unversioned.ProtocolDeprecated {
+		t.Errorf("unversioned handshake status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,15 +14,25 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"httpserver/apperrors"
+	"httpserver/client"
 	"httpserver/database"
+	"httpserver/encryption"
+	"httpserver/features"
 	"httpserver/nomenclature"
 	"httpserver/normalization"
 	"httpserver/quality"
+	"httpserver/queue"
+	"httpserver/reports"
+	"httpserver/storage"
+	"httpserver/tracing"
 	"httpserver/server/middleware"
 
 	"github.com/google/uuid"
@@ -68,12 +79,79 @@ type Server struct {
 	// Флаг остановки воркеров КПВЭД классификации
 	kpvedWorkersStopped   bool
 	kpvedWorkersStopMutex sync.RWMutex
-	// Кэш БД для выгрузок (ключ - upload_uuid)
-	uploadDBs      map[string]*database.DB
-	uploadDBsMutex sync.RWMutex
-	// Обратная выгрузка
+	// Кэш дерева классификатора КПВЭД для ленивой загрузки и автодополнения
+	kpvedTree kpvedTreeCache
+	// Кэш БД для выгрузок (ключ - upload_uuid) с вытеснением и закрытием по простою
+	uploadDBs uploadDBCache
+	// Тестовые выгрузки во временных БД в памяти (ключ - upload_uuid)
+	sandboxUploads sandboxUploadRegistry
+	// Обратная выгрузка: задачи экземпляра и восстановленные из service.db
 	exportJobs      map[string]*ExportJob
 	exportJobsMutex sync.RWMutex
+	// Задачи выборочной переклассификации КПВЭД
+	reclassifyJobs      map[string]*ReclassifyJob
+	reclassifyJobsMutex sync.RWMutex
+	// Выполняющиеся задания заполнения производных колонок: отмена по id задания
+	backfillJobs      map[int]context.CancelFunc
+	backfillJobsMutex sync.Mutex
+	// Задачи массовой обработки нарушений качества
+	qualityBulkJobs      map[string]*QualityBulkJob
+	qualityBulkJobsMutex sync.RWMutex
+	// Калибровка уверенности и политики порогов
+	calibrator *normalization.Calibrator
+	// AI клиенты запущенных задач (для применения изменений конфигурации на лету)
+	aiClients      map[*nomenclature.AIClient]string
+	aiClientsMutex sync.Mutex
+	// Подписчики SSE потока мониторинга на события
+	monitoringSubscribers      map[chan []byte]struct{}
+	monitoringSubscribersMutex sync.Mutex
+	// Экземпляр сервера в кластере с общей service.db
+	instanceID string
+	// События нормализации для SSE после записи в историю, при ретрансляции - всех экземпляров
+	// (nil - сервер не запущен, SSE читает normalizerEvents напрямую)
+	normalizerStreamEvents chan string
+	// Фоновая задача последнего запуска нормализации, к которой относятся события в истории
+	normalizerSessionID atomic.Int64
+	// Движок отчетов по шаблонам
+	reportEngine *reports.Engine
+	// Хранилище крупных артефактов (отчеты, резервные копии), создается при первом обращении
+	storage     storage.Storage
+	storageOnce sync.Once
+	// Прием пакетов выгрузок из очереди сообщений
+	ingestQueue      queue.Consumer
+	ingestQueueStats ingestQueueStats
+	// Ограничение скорости приема выгрузок по клиентам
+	ingestThrottle ingestThrottler
+	// Учет изменяющих запросов для безопасного переключения БД
+	dbWriteGate dbWriteGate
+	// Заполнение индекса расположения выгрузок
+	uploadIndexBackfill uploadIndexBackfill
+	// Фоновые задачи, ожидаемые при остановке сервера
+	jobs backgroundJobs
+	// Сведения о файлах БД для списка баз, пересчитываемые при изменении файла
+	databaseStats databaseStatsCache
+	// Тепловые карты уверенности классификации, пересчитываемые при изменении файла БД
+	confidenceHeatmap confidenceHeatmapCache
+	// Число перехваченных паник фоновых воркеров с запуска сервера
+	workerCrashes atomic.Int64
+	// Минимальный уровень записей лога (logLevelRank), меняется при перезагрузке конфигурации
+	logLevel atomic.Int32
+	// Новые выгрузки не принимаются: сервер остановится после завершения текущих
+	uploadsClosed atomic.Bool
+	// Исключает одновременные перезагрузки конфигурации
+	configMu sync.Mutex
+	// Конфигурация после перезагрузки: неизменяемый снимок, заменяемый целиком (nil - действует config)
+	liveConfig atomic.Pointer[Config]
+	// В служебной БД появились пользователи: запросы без сессии отклоняются
+	authEnforced atomic.Bool
+	// Гистограммы задержек эндпоинтов с запуска сервера
+	endpointLatency endpointLatency
+	// Экспорт трассировки в коллектор OpenTelemetry (nil - трассировка отключена)
+	tracer *tracing.Tracer
+	// Мастер-ключи шифрования данных проектов (nil - шифрование не настроено) и кэш ключей данных проектов
+	encryptionKeyring *encryption.Keyring
+	projectKeys       map[int]*projectKey
+	projectKeysMutex  sync.RWMutex
 }
 
 // QualityAnalysisStatus статус анализа качества
@@ -124,6 +202,11 @@ func NewServerWithConfig(db *database.DB, normalizedDB *database.DB, serviceDB *
 	// Создаем нормализатор
 	normalizer := normalization.NewNormalizer(db, normalizerEvents, aiConfig)
 
+	// Калибратор разделяется с нормализатором, чтобы новые исходы сразу сбрасывали кэш кривых
+	calibrator := normalization.NewCalibrator(db)
+	normalizer.SetCalibrator(calibrator)
+	normalizer.SetModelChain(workerConfigManager.GetModelChain(normalization.ModelChainTaskNormalization))
+
 	// Создаем анализатор качества
 	qualityAnalyzer := quality.NewQualityAnalyzer(db)
 
@@ -131,6 +214,15 @@ func NewServerWithConfig(db *database.DB, normalizedDB *database.DB, serviceDB *
 	arliaiClient := NewArliaiClient()
 	arliaiCache := NewArliaiCache()
 
+	// Демо-режим: образцы данных загружаются до инициализации классификатора КПВЭД
+	if config.DemoMode {
+		if result, err := seedDemoData(db, serviceDB, unifiedCatalogsDB, config.UnifiedCatalogsDBPath); err != nil {
+			log.Printf("Ошибка загрузки демо-данных: %v", err)
+		} else {
+			logDemoSeed(result)
+		}
+	}
+
 	// Инициализируем KPVED hierarchical classifier
 	var hierarchicalClassifier *normalization.HierarchicalClassifier
 
@@ -141,8 +233,9 @@ func NewServerWithConfig(db *database.DB, normalizedDB *database.DB, serviceDB *
 		model = "GLM-4.5-Air" // По умолчанию
 	}
 
-	if apiKey != "" && serviceDB != nil {
+	if features.AI && apiKey != "" && serviceDB != nil {
 		aiClient := nomenclature.NewAIClient(apiKey, model)
+		aiClient.SetJob("kpved_classifier")
 		var err error
 		hierarchicalClassifier, err = normalization.NewHierarchicalClassifier(serviceDB, aiClient)
 		if err != nil {
@@ -153,7 +246,9 @@ func NewServerWithConfig(db *database.DB, normalizedDB *database.DB, serviceDB *
 			log.Printf("KPVED hierarchical classifier initialized successfully")
 		}
 	} else {
-		if apiKey == "" {
+		if !features.AI {
+			log.Printf("KPVED classification is not available: server built without AI (-tags no_ai)")
+		} else if apiKey == "" {
 			log.Printf("Warning: ARLIAI_API_KEY not set. KPVED classification will be disabled.")
 		}
 		if serviceDB == nil {
@@ -161,7 +256,7 @@ func NewServerWithConfig(db *database.DB, normalizedDB *database.DB, serviceDB *
 		}
 	}
 
-	return &Server{
+	s := &Server{
 		db:                      db,
 		normalizedDB:            normalizedDB,
 		serviceDB:               serviceDB,
@@ -184,9 +279,60 @@ func NewServerWithConfig(db *database.DB, normalizedDB *database.DB, serviceDB *
 		hierarchicalClassifier:  hierarchicalClassifier,
 		kpvedCurrentTasks:       make(map[int]*classificationTask),
 		kpvedWorkersStopped:     false,
-		uploadDBs:               make(map[string]*database.DB),
 		exportJobs:              make(map[string]*ExportJob),
+		reclassifyJobs:          make(map[string]*ReclassifyJob),
+		backfillJobs:            make(map[int]context.CancelFunc),
+		qualityBulkJobs:         make(map[string]*QualityBulkJob),
+		calibrator:              calibrator,
+		aiClients:               make(map[*nomenclature.AIClient]string),
+		monitoringSubscribers:   make(map[chan []byte]struct{}),
+	}
+
+	// Пользовательские шаблоны отчетов хранятся в сервисной БД и в каталоге на диске
+	var templateStore reports.TemplateStore
+	if serviceDB != nil {
+		templateStore = serviceDB
+	}
+	s.reportEngine = reports.NewEngine(config.ReportTemplatesDir, templateStore)
+
+	s.uploadDBs.configure(config.UploadDBMaxOpen, config.UploadDBIdleTimeout, config.UploadDBCloseGrace, func(path string) (*database.DB, error) {
+		return database.NewDBWithConfig(path, s.databaseConfig())
+	})
+
+	if config.IngestQueue.Backend != "" {
+		if consumer, err := queue.New(config.IngestQueue); err != nil {
+			log.Printf("Ошибка настройки очереди приема выгрузок: %v", err)
+		} else {
+			s.ingestQueue = consumer
+		}
+	}
+
+	if config.Tracing.Endpoint != "" {
+		if tracer, err := tracing.New(config.Tracing); err != nil {
+			log.Printf("Ошибка настройки трассировки: %v", err)
+		} else {
+			s.tracer = tracer
+			tracing.SetGlobal(tracer)
+		}
+	}
+
+	if keyring, err := encryption.ParseKeyring(config.EncryptionMasterKeys, config.EncryptionMasterKeyID); err != nil {
+		log.Printf("Ошибка настройки мастер-ключей шифрования: %v", err)
+	} else {
+		s.encryptionKeyring = keyring
+	}
+
+	s.logLevel.Store(logLevelRank(config.LogLevel))
+	if config.AIMaxWorkers > 0 {
+		if err := workerConfigManager.SetGlobalMaxWorkers(config.AIMaxWorkers); err != nil {
+			log.Printf("Ошибка установки AI_MAX_WORKERS: %v", err)
+		}
 	}
+
+	// Изменения конфигурации воркеров применяются к уже запущенным задачам
+	workerConfigManager.Subscribe(s.applyWorkerConfigChange)
+
+	return s
 }
 
 // Start запускает HTTP сервер
@@ -194,17 +340,65 @@ func (s *Server) Start() error {
 	s.log(LogEntry{
 		Timestamp: time.Now(),
 		Level:     "INFO",
-		Message:   fmt.Sprintf("Starting server on port %s", s.config.Port),
+		Message:   fmt.Sprintf("Starting server on port %s", s.currentConfig().Port),
 	})
 
 	// Получаем настроенный handler
 	handler := s.setupMux()
 
+	// Поток событий нормализации для SSE создается до ретрансляции событий других экземпляров
+	s.normalizerStreamEvents = make(chan string, cap(s.normalizerEvents))
+
+	// Регистрация экземпляра в кластере до проверки незавершенных задач: задачи других экземпляров не затрагиваются
+	s.startCluster()
+
+	// Запись событий нормализации в историю и передача их SSE подписчикам и другим экземплярам
+	go s.superviseWorker("normalizer_events", s.runNormalizerEventsPump)
+
+	// Задачи, прерванные при предыдущей остановке сервера
+	s.reportUnfinishedJobs()
+
+	// Запуски классификации КПВЭД, прерванные при предыдущей остановке сервера
+	s.interruptKpvedRuns()
+
+	// Задания заполнения производных колонок, прерванные при предыдущей остановке сервера
+	s.interruptBackfillJobs()
+
+	// Фоновый пересчет дневных сводок по выгрузкам
+	go s.superviseWorker("upload_rollups", s.runUploadRollupsLoop)
+
+	// Периодическая сверка счетчиков выгрузок
+	go s.superviseWorker("upload_counters_recount", s.runUploadCountersRecountLoop)
+
+	// Формирование и рассылка отчетов по расписанию
+	go s.superviseWorker("report_scheduler", s.runReportSchedulerLoop)
+
+	// Асинхронный прием пакетов выгрузок из очереди сообщений
+	go s.superviseWorker("ingest_queue", s.runIngestQueueLoop)
+
+	// Повтор классификации записей, завершившейся временной ошибкой
+	go s.superviseWorker("classification_retry", s.runClassificationRetryLoop)
+
+	// Закрытие БД выгрузок, не использовавшихся дольше таймаута простоя
+	go s.superviseWorker("upload_db_cache_janitor", s.runUploadDBCacheJanitor)
+
+	// Удаление тестовых выгрузок с истекшим сроком жизни
+	go s.superviseWorker("sandbox_uploads_janitor", s.runSandboxUploadsJanitor)
+
+	// Очистка журнала изменений нормализованных данных по сроку хранения
+	go s.superviseWorker("normalized_changes_janitor", s.runNormalizedChangesJanitor)
+
+	// Перезагрузка конфигурации по SIGHUP
+	go s.watchReloadSignal()
+
+	// Заполнение индекса расположения выгрузок для выгрузок, созданных до его появления
+	go s.runIsolated("upload_index_backfill", nil, nil, false, s.runUploadIndexBackfill)
+
 	// Создаем HTTP сервер с увеличенными таймаутами для длительных операций
 	// ReadTimeout и WriteTimeout установлены для защиты от зависших соединений
 	// Но для операций классификации КПВЭД нужны большие значения
 	s.httpServer = &http.Server{
-		Addr:         ":" + s.config.Port,
+		Addr:         ":" + s.currentConfig().Port,
 		Handler:      handler,
 		ReadTimeout:  30 * time.Minute,  // Увеличен для длительных операций классификации
 		WriteTimeout: 30 * time.Minute,  // Увеличен для длительных операций классификации
@@ -222,7 +416,7 @@ func (s *Server) Start() error {
 // setupMux настраивает маршруты и возвращает http.Handler
 // Используется как в Start(), так и в ServeHTTP() для тестов
 func (s *Server) setupMux() http.Handler {
-	mux := http.NewServeMux()
+	mux := newRouteRecorder()
 
 	// Регистрируем обработчики для 1С (старые эндпоинты для обратной совместимости)
 	mux.HandleFunc("/handshake", s.handleHandshake)
@@ -231,12 +425,16 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/catalog/meta", s.handleCatalogMeta)
 	mux.HandleFunc("/catalog/item", s.handleCatalogItem)
 	mux.HandleFunc("/catalog/items", s.handleCatalogItems)
+	mux.HandleFunc("/catalog/attachment", s.handleCatalogAttachment)
+	mux.HandleFunc("/document/item", s.handleDocumentItem)
+	mux.HandleFunc("/document/items", s.handleDocumentItems)
 	mux.HandleFunc("/complete", s.handleComplete)
 	mux.HandleFunc("/stats", s.handleStats)
 	mux.HandleFunc("/health", s.handleHealth)
 
 	// Регистрируем новые API v1 эндпоинты
 	mux.HandleFunc("/api/v1/upload/handshake", s.handleHandshake)
+	mux.HandleFunc("/api/v1/upload/protocol", s.handleProtocolVersions)
 	mux.HandleFunc("/api/v1/upload/metadata", s.handleMetadata)
 	mux.HandleFunc("/api/v1/upload/nomenclature/batch", s.handleNomenclatureBatch)
 	mux.HandleFunc("/api/v1/health", s.handleHealth)
@@ -253,9 +451,23 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/exports", s.handleExportsRoot)
 	mux.HandleFunc("/api/exports/", s.handleExportRoutes)
 
+	// Профили скрытия колонок для получателей данных
+	mux.HandleFunc("/api/redaction/profiles", s.handleRedactionProfiles)
+	mux.HandleFunc("/api/redaction/profiles/", s.handleRedactionProfileRoutes)
+	mux.HandleFunc("/api/redaction/assignments", s.handleRedactionAssignments)
+
+	// Маппинги реквизитов справочников на индексируемые колонки
+	mux.HandleFunc("/api/catalogs/column-mappings", s.handleCatalogColumnMappings)
+	mux.HandleFunc("/api/catalogs/items/search", s.handleCatalogItemsSearch)
+	mux.HandleFunc("/api/catalogs/items/history", s.handleCatalogItemHistory)
+
 	// Регистрируем API эндпоинты для нормализованной БД
 	mux.HandleFunc("/api/normalized/uploads", s.handleNormalizedListUploads)
 	mux.HandleFunc("/api/normalized/uploads/", s.handleNormalizedUploadRoutes)
+	mux.HandleFunc("/api/normalized/items/", s.handleNormalizedItemRoutes)
+	mux.HandleFunc("/api/normalized/changes", s.handleNormalizedChanges)
+	mux.HandleFunc("/api/normalized/views", s.handleNormalizedViews)
+	mux.HandleFunc("/api/normalized/views/", s.handleNormalizedViewRoutes)
 
 	// Регистрируем эндпоинты для приема нормализованных данных
 	mux.HandleFunc("/api/normalized/upload/handshake", s.handleNormalizedHandshake)
@@ -277,6 +489,10 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/nomenclature/status", s.getNomenclatureStatus)
 	mux.HandleFunc("/api/nomenclature/recent", s.getNomenclatureRecentRecords)
 	mux.HandleFunc("/api/nomenclature/pending", s.getNomenclaturePendingRecords)
+	mux.HandleFunc("/api/nomenclature/reconciliation", s.handleNomenclatureReconciliations)
+	mux.HandleFunc("/api/nomenclature/reconciliation/", s.handleNomenclatureReconciliationRoutes)
+	mux.HandleFunc("/api/sandbox/uploads", s.handleSandboxUploads)
+	mux.HandleFunc("/api/sandbox/uploads/", s.handleSandboxUploadRoutes)
 	mux.HandleFunc("/nomenclature/status", s.serveNomenclatureStatusPage)
 
 	// Регистрируем эндпоинты для нормализации данных
@@ -289,6 +505,9 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/normalization/group-items", s.handleNormalizationGroupItems)
 	mux.HandleFunc("/api/normalization/item-attributes/", s.handleNormalizationItemAttributes)
 	mux.HandleFunc("/api/normalization/export-group", s.handleNormalizationExportGroup)
+	mux.HandleFunc("/api/normalization/rules/preview", s.handleRulePreview)
+	mux.HandleFunc("/api/normalization/standards", s.handleStandardsReport)
+	mux.HandleFunc("/api/normalization/standards/extract", s.handleStandardsExtract)
 
 	// Регистрируем эндпоинты для конфигурации нормализации
 	mux.HandleFunc("/api/normalization/config", s.handleNormalizationConfig)
@@ -305,14 +524,33 @@ func (s *Server) setupMux() http.Handler {
 	// Регистрируем эндпоинты для КПВЭД классификатора
 	mux.HandleFunc("/api/kpved/hierarchy", s.handleKpvedHierarchy)
 	mux.HandleFunc("/api/kpved/search", s.handleKpvedSearch)
+	mux.HandleFunc("/api/kpved/autocomplete", s.handleKpvedAutocomplete)
 	mux.HandleFunc("/api/kpved/stats", s.handleKpvedStats)
+	mux.HandleFunc("/api/reports/kpved", s.handleKpvedReport)
+	mux.HandleFunc("/api/reports/kpved/heatmap", s.handleConfidenceHeatmap)
+	mux.HandleFunc("/api/reports/inspection/", s.handleInspectionReport)
+	mux.HandleFunc("/api/reports/templates", s.handleReportTemplates)
+	mux.HandleFunc("/api/reports/templates/", s.handleReportTemplate)
+	mux.HandleFunc("/api/reports/render", s.handleRenderReport)
+	mux.HandleFunc("/api/reports/schedules", s.handleReportSchedules)
+	mux.HandleFunc("/api/reports/schedules/", s.handleReportScheduleRoutes)
+	mux.HandleFunc("/api/reports/runs", s.handleReportRuns)
+	mux.HandleFunc("/api/reports/runs/", s.handleReportRunRoutes)
+	mux.HandleFunc("/api/storage/objects/", s.handleStorageObject)
+	mux.HandleFunc("/api/backups", s.handleBackups)
+	mux.HandleFunc("/api/ingest/queue", s.handleIngestQueueStatus)
+	mux.HandleFunc("/api/i18n/languages", s.handleLanguages)
 	mux.HandleFunc("/api/kpved/load", s.handleKpvedLoad)
 	// mux.HandleFunc("/api/kpved/load-from-file", s.handleKpvedLoadFromFile) // Метод не реализован
 	mux.HandleFunc("/api/kpved/classify-test", s.handleKpvedClassifyTest)
 	mux.HandleFunc("/api/kpved/classify-hierarchical", s.handleKpvedClassifyHierarchical)
 	mux.HandleFunc("/api/kpved/reclassify", s.handleKpvedReclassify)
 	mux.HandleFunc("/api/kpved/reclassify-hierarchical", s.handleKpvedReclassifyHierarchical)
+	mux.HandleFunc("/api/kpved/reclassify/scoped", s.handleKpvedReclassifyScoped)
+	mux.HandleFunc("/api/kpved/reclassify/jobs", s.handleKpvedReclassifyJobs)
+	mux.HandleFunc("/api/kpved/reclassify/jobs/", s.handleKpvedReclassifyJobRoutes)
 	mux.HandleFunc("/api/kpved/current-tasks", s.handleKpvedCurrentTasks)
+	mux.HandleFunc("/api/kpved/historical/report", s.handleHistoricalClassificationReport)
 
 	// Регистрируем эндпоинты для управления классификацией
 	mux.HandleFunc("/api/kpved/reset", s.handleResetClassification)
@@ -321,22 +559,36 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/kpved/reset-low-confidence", s.handleResetLowConfidence)
 	mux.HandleFunc("/api/kpved/mark-incorrect", s.handleMarkIncorrect)
 	mux.HandleFunc("/api/kpved/mark-correct", s.handleMarkCorrect)
+	mux.HandleFunc("/api/kpved/corrections/import", s.handleImportClassificationCorrections)
 	mux.HandleFunc("/api/kpved/workers/status", s.handleKpvedWorkersStatus)
 	mux.HandleFunc("/api/kpved/workers/stop", s.handleKpvedWorkersStop)
 	mux.HandleFunc("/api/kpved/workers/resume", s.handleKpvedWorkersResume)
 	mux.HandleFunc("/api/kpved/workers/start", s.handleKpvedWorkersResume)
+	mux.HandleFunc("/api/kpved/runs", s.handleKpvedRuns)
+	mux.HandleFunc("/api/kpved/runs/", s.handleKpvedRunRoutes)
 	mux.HandleFunc("/api/kpved/stats/classification", s.handleKpvedStatsGeneral)
 	mux.HandleFunc("/api/kpved/stats/by-category", s.handleKpvedStatsByCategory)
 	mux.HandleFunc("/api/kpved/stats/incorrect", s.handleKpvedStatsIncorrect)
 
+	// Калибровка уверенности и политики порогов
+	mux.HandleFunc("/api/calibration/curves", s.handleCalibrationCurves)
+	mux.HandleFunc("/api/calibration/policies", s.handleCalibrationPolicies)
+	mux.HandleFunc("/api/calibration/review-queue", s.handleCalibrationReviewQueue)
+	mux.HandleFunc("/api/calibration/evaluate", s.handleCalibrationEvaluate)
+
 	// Регистрируем эндпоинты для качества нормализации
 	mux.HandleFunc("/api/quality/stats", s.handleQualityStats)
 	mux.HandleFunc("/api/quality/item/", s.handleQualityItemDetail)
 	mux.HandleFunc("/api/quality/violations", s.handleQualityViolations)
 	mux.HandleFunc("/api/quality/violations/", s.handleQualityViolationDetail)
+	mux.HandleFunc("/api/quality/violations/bulk", s.handleQualityViolationsBulk)
+	mux.HandleFunc("/api/quality/bulk-jobs", s.handleQualityBulkJobs)
+	mux.HandleFunc("/api/quality/bulk-jobs/", s.handleQualityBulkJobDetail)
 	mux.HandleFunc("/api/quality/suggestions", s.handleQualitySuggestions)
 	mux.HandleFunc("/api/quality/suggestions/", s.handleQualitySuggestionAction)
 	mux.HandleFunc("/api/quality/duplicates", s.handleQualityDuplicates)
+	mux.HandleFunc("/api/quality/duplicates/graph", s.handleQualityDuplicateGraph)
+	mux.HandleFunc("/api/quality/similarity", s.handleQualitySimilarity)
 	mux.HandleFunc("/api/quality/duplicates/", s.handleQualityDuplicateAction)
 	mux.HandleFunc("/api/quality/assess", s.handleQualityAssess)
 	mux.HandleFunc("/api/quality/analyze", s.handleQualityAnalyze)
@@ -352,11 +604,18 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/normalization/apply-patterns", s.handleApplyPatterns)
 	mux.HandleFunc("/api/normalization/apply-ai", s.handleApplyAI)
 	mux.HandleFunc("/api/normalization/history", s.handleGetSessionHistory)
+	mux.HandleFunc("/api/normalization/events/history", s.handleNormalizationEventsHistory)
 	mux.HandleFunc("/api/normalization/revert", s.handleRevertStage)
 	mux.HandleFunc("/api/normalization/apply-categorization", s.handleApplyCategorization)
 
 	// Регистрируем эндпоинты для классификации
 	mux.HandleFunc("/api/classification/classify", s.handleClassifyItem)
+	mux.HandleFunc("/api/classification/retries", s.handleClassificationRetries)
+	mux.HandleFunc("/api/classification/experiments", s.handleClassificationExperiments)
+	mux.HandleFunc("/api/classification/experiments/", s.handleClassificationExperimentRoutes)
+	mux.HandleFunc("/api/backfill/stages", s.handleBackfillStages)
+	mux.HandleFunc("/api/backfill/jobs", s.handleBackfillJobs)
+	mux.HandleFunc("/api/backfill/jobs/", s.handleBackfillJobRoutes)
 	mux.HandleFunc("/api/classification/classify-item", s.handleClassifyItemDirect)
 	mux.HandleFunc("/api/classification/strategies", s.handleGetStrategies)
 	mux.HandleFunc("/api/classification/strategies/configure", s.handleConfigureStrategy)
@@ -364,6 +623,9 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/classification/strategies/create", s.handleCreateOrUpdateClientStrategy)
 	mux.HandleFunc("/api/classification/available", s.handleGetAvailableStrategies)
 	mux.HandleFunc("/api/classification/classifiers", s.handleGetClassifiers)
+	mux.HandleFunc("/api/classification/classifiers/", s.handleClassifierRoutes)
+	mux.HandleFunc("/api/classification/translations/", s.handleCategoryTranslations)
+	mux.HandleFunc("/api/review-tasks/my", s.handleMyReviewTasks)
 
 	// Регистрируем эндпоинты для переклассификации
 	mux.HandleFunc("/api/reclassification/start", s.handleReclassificationStart)
@@ -375,8 +637,12 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/monitoring/metrics", s.handleMonitoringMetrics)
 	mux.HandleFunc("/api/monitoring/cache", s.handleMonitoringCache)
 	mux.HandleFunc("/api/monitoring/ai", s.handleMonitoringAI)
+	mux.HandleFunc("/api/monitoring/ai/budget", s.handleAIBudget)
 	mux.HandleFunc("/api/monitoring/history", s.handleMonitoringHistory)
 	mux.HandleFunc("/api/monitoring/events", s.handleMonitoringEvents)
+	mux.HandleFunc("/api/monitoring/crashes", s.handleMonitoringCrashes)
+	mux.HandleFunc("/api/monitoring/latency", s.handleMonitoringLatency)
+	mux.HandleFunc("/api/monitoring/slow", s.handleMonitoringSlow)
 
 	// Регистрируем эндпоинты для управления воркерами и моделями
 	mux.HandleFunc("/api/workers/config", s.handleGetWorkerConfig)
@@ -390,9 +656,13 @@ func (s *Server) setupMux() http.Handler {
 	mux.HandleFunc("/api/databases/list", s.handleDatabasesList)
 	mux.HandleFunc("/api/databases/find", s.handleFindDatabase)
 	mux.HandleFunc("/api/database/switch", s.handleDatabaseSwitch)
+	mux.HandleFunc("/api/audit", s.handleAuditLog)
+	mux.HandleFunc("/api/jobs", s.handleBackgroundJobs)
+	mux.HandleFunc("/api/cluster/instances", s.handleClusterInstances)
 	mux.HandleFunc("/api/databases/analytics", s.handleDatabaseAnalytics)
 	mux.HandleFunc("/api/databases/analytics/", s.handleDatabaseAnalytics)
 	mux.HandleFunc("/api/databases/history/", s.handleDatabaseHistory)
+	mux.HandleFunc("/api/databases/", s.handleDatabaseRoutes)
 
 	// Регистрируем эндпоинты для работы с клиентами
 	mux.HandleFunc("/api/clients", s.handleClients)
@@ -401,6 +671,21 @@ func (s *Server) setupMux() http.Handler {
 	// Регистрируем эндпоинт для генерации XML обработки 1С
 	mux.HandleFunc("/api/1c/processing/xml", s.handle1CProcessingXML)
 
+	// Профиль сборки и включенные подсистемы
+	mux.HandleFunc("/api/config", s.handleConfig)
+	mux.HandleFunc("/api/admin/reload", s.handleAdminReload)
+	mux.HandleFunc("/api/admin/provision", s.handleAdminProvision)
+
+	// Учетные записи и сессии веб-интерфейса
+	mux.HandleFunc("/api/auth/", s.handleAuthRoutes)
+	mux.HandleFunc("/api/users", s.handleUsers)
+	mux.HandleFunc("/api/users/", s.handleUsers)
+
+	// Регистрируем эндпоинт со списком маршрутов (используется http_checker -self-check)
+	mux.HandleFunc("/api/routes", func(w http.ResponseWriter, r *http.Request) {
+		s.handleRoutes(w, r, mux)
+	})
+
 	// Статический контент для GUI (регистрируем последним)
 	// Используем префикс, чтобы не перехватывать API запросы
 	staticFS := http.FileServer(http.Dir("./static/"))
@@ -417,10 +702,18 @@ func (s *Server) setupMux() http.Handler {
 	})
 
 	// Применяем middleware в правильном порядке после регистрации всех маршрутов
-	// Порядок важен: сначала SecurityHeaders, затем RequestID, затем Logging, затем существующие middleware
-	handler := SecurityHeadersMiddleware(mux)
+	// Порядок важен: сначала выбор языка и SecurityHeaders, затем RequestID, затем Logging, затем существующие middleware
+	handler := SecurityHeadersMiddleware(s.sessionMiddleware(s.languageMiddleware(s.dbWriteGateMiddleware(mux))))
 	handler = RequestIDMiddleware(handler)
 	handler = LoggingMiddleware(handler)
+	// Имя спана и ключ гистограммы задержек - шаблон маршрута, чтобы идентификаторы в пути не порождали отдельных операций
+	route := func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+	handler = s.latencyMiddleware(handler, route)
+	// Трассировка оборачивает логирование и учет задержек, чтобы их записи содержали trace id
+	handler = tracing.Middleware(handler, route)
 	handler = middleware.CORS(handler)
 	handler = middleware.RecoverMiddleware(handler)
 
@@ -441,22 +734,55 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		Message:   "Shutting down server...",
 	})
 
+	// Останавливаем фоновые задачи
+	select {
+	case <-s.shutdownChan:
+	default:
+		close(s.shutdownChan)
+	}
+
+	var err error
 	if s.httpServer != nil {
-		return s.httpServer.Shutdown(ctx)
+		err = s.httpServer.Shutdown(ctx)
 	}
-	return nil
+	// Дожидаемся фоновых задач до закрытия БД, чтобы они не были прерваны посреди записи
+	s.shutdownBackgroundJobs()
+	s.stopCluster()
+	s.uploadDBs.closeAll()
+	s.sandboxUploads.closeAll()
+	// Спаны остановленных задач отправляются в коллектор последними
+	if s.tracer != nil {
+		if traceErr := s.tracer.Shutdown(ctx); traceErr != nil {
+			log.Printf("Не удалось отправить оставшиеся спаны: %v", traceErr)
+		}
+		tracing.SetGlobal(nil)
+	}
+	return err
 }
 
 // log отправляет запись в лог
 func (s *Server) log(entry LogEntry) {
+	if logLevelRank(entry.Level) < s.logLevel.Load() {
+		return
+	}
 	select {
 	case s.logChan <- entry:
 	default:
 		// Если канал полон, пропускаем запись
 	}
+	if entry.TraceID != "" {
+		log.Printf("[%s] %s: %s trace=%s", entry.Level, entry.Timestamp.Format("15:04:05"), entry.Message, entry.TraceID)
+		return
+	}
 	log.Printf("[%s] %s: %s", entry.Level, entry.Timestamp.Format("15:04:05"), entry.Message)
 }
 
+// logCtx отправляет запись в лог с идентификатором трассировки ctx
+func (s *Server) logCtx(ctx context.Context, entry LogEntry) {
+	entry.TraceID = tracing.TraceIDFromContext(ctx)
+	s.log(entry)
+}
+
 // writeXMLResponse записывает XML ответ
 func (s *Server) writeXMLResponse(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
@@ -472,14 +798,20 @@ func (s *Server) writeXMLResponse(w http.ResponseWriter, data interface{}) {
 	w.Write(xmlData)
 }
 
-// writeErrorResponse записывает ошибку в XML формате
+// writeErrorResponse записывает ошибку в XML формате, HTTP статус определяется видом ошибки (apperrors)
 func (s *Server) writeErrorResponse(w http.ResponseWriter, message string, err error) {
+	s.writeErrorResponseWithStatus(w, apperrors.HTTPStatus(err), message, err)
+}
+
+// writeErrorResponseWithStatus записывает XML ответ с ошибкой и заданным HTTP статусом
+func (s *Server) writeErrorResponseWithStatus(w http.ResponseWriter, statusCode int, message string, err error) {
 	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	w.WriteHeader(http.StatusInternalServerError)
+	w.WriteHeader(statusCode)
 
 	response := ErrorResponse{
 		Success:   false,
 		Error:     err.Error(),
+		Code:      errorCode(err, statusCode),
 		Message:   message,
 		Timestamp: time.Now().Format(time.RFC3339),
 	}
@@ -547,64 +879,46 @@ func generateDatabaseFileName(uploadType, configName, computerName, userName str
 
 // getUploadDatabase получает БД для выгрузки из кэша или открывает её по пути
 func (s *Server) getUploadDatabase(uploadUUID string) (*database.DB, error) {
-	s.uploadDBsMutex.RLock()
-	uploadDB, exists := s.uploadDBs[uploadUUID]
-	s.uploadDBsMutex.RUnlock()
+	// Тестовая выгрузка целиком пишется во временную БД
+	if sandbox, ok := s.sandboxUploads.get(uploadUUID); ok {
+		return sandbox.db, nil
+	}
 
-	if exists && uploadDB != nil {
+	if uploadDB, exists := s.uploadDBs.get(uploadUUID); exists && uploadDB != nil {
 		return uploadDB, nil
 	}
 
-	// Если БД нет в кэше, пытаемся найти её через service.db
-	// Ищем все БД в service.db и проверяем каждую на наличие upload с таким UUID
+	// Расположение выгрузки берется из индекса service.db, заполняемого при рукопожатии
 	if s.serviceDB != nil {
-		// Получаем все проекты
-		clients, err := s.serviceDB.GetClientsWithStats()
-		if err == nil {
-			for _, clientMap := range clients {
-				clientIDFloat, ok := clientMap["id"].(float64)
-				if !ok {
-					continue
-				}
-				clientID := int(clientIDFloat)
-				projects, err := s.serviceDB.GetClientProjects(clientID)
-				if err == nil {
-					for _, project := range projects {
-						databases, err := s.serviceDB.GetProjectDatabases(project.ID, false)
-						if err == nil {
-							for _, dbInfo := range databases {
-								// Проверяем, существует ли файл БД
-								if _, err := os.Stat(dbInfo.FilePath); err == nil {
-									// Открываем БД и проверяем наличие upload
-									tempDB, err := database.NewDB(dbInfo.FilePath)
-									if err == nil {
-										upload, err := tempDB.GetUploadByUUID(uploadUUID)
-										tempDB.Close()
-										if err == nil && upload != nil {
-											// Нашли БД! Открываем её и сохраняем в кэш
-											uploadDB, err := database.NewDBWithConfig(dbInfo.FilePath, database.DBConfig{
-												MaxOpenConns:    s.config.MaxOpenConns,
-												MaxIdleConns:    s.config.MaxIdleConns,
-												ConnMaxLifetime: s.config.ConnMaxLifetime,
-											})
-											if err == nil {
-												s.uploadDBsMutex.Lock()
-												s.uploadDBs[uploadUUID] = uploadDB
-												s.uploadDBsMutex.Unlock()
-												return uploadDB, nil
-											}
-										}
-									}
-								}
-							}
-						}
-					}
-				}
-			}
+		location, err := s.serviceDB.GetUploadLocation(uploadUUID)
+		if err != nil {
+			return nil, err
+		}
+		if location != nil {
+			return s.openIndexedUploadDB(location)
+		}
+	}
+
+	// Выгрузка, еще не попавшая в индекс, ищется только в единой БД
+	if s.unifiedCatalogsDB != nil {
+		if _, err := s.unifiedCatalogsDB.GetUploadByUUID(uploadUUID); err == nil {
+			s.registerUploadLocation(uploadUUID, s.unifiedCatalogsDBPath(), nil)
+			s.uploadDBs.putShared(uploadUUID, s.unifiedCatalogsDB)
+			return s.unifiedCatalogsDB, nil
 		}
 	}
 
-	return nil, fmt.Errorf("database for upload %s not found in cache or service.db", uploadUUID)
+	return nil, apperrors.NotFound("upload_not_found", fmt.Sprintf("database for upload %s not found in cache or service.db", uploadUUID))
+}
+
+// acquireUploadDatabase возвращает БД выгрузки для долгой операции (потоковая отдача, экспорт, пересчет).
+// Пока не вызвана release, вытесненная из кэша БД не закрывается
+func (s *Server) acquireUploadDatabase(uploadUUID string) (*database.DB, func(), error) {
+	uploadDB, err := s.getUploadDatabase(uploadUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return uploadDB, s.uploadDBs.retain(uploadDB), nil
 }
 
 // handleHandshake обрабатывает рукопожатие
@@ -613,31 +927,43 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if s.uploadsClosed.Load() {
+		s.writeErrorResponse(w, "Server is stopping, new uploads are not accepted",
+			apperrors.Unavailable("uploads_closed", "server is stopping after current uploads"))
+		return
+	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req HandshakeRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
 	// Валидация обязательных полей
 	if req.Version1C == "" {
-		s.writeErrorResponse(w, "Missing required field: version_1c", fmt.Errorf("version_1c is required"))
+		s.writeErrorResponse(w, "Missing required field: version_1c", apperrors.Validation("missing_field", "version_1c is required"))
 		return
 	}
 	if req.ConfigName == "" {
-		s.writeErrorResponse(w, "Missing required field: config_name", fmt.Errorf("config_name is required"))
+		s.writeErrorResponse(w, "Missing required field: config_name", apperrors.Validation("missing_field", "config_name is required"))
+		return
+	}
+
+	// Согласуем версию протокола: устаревшей обработке отвечаем 426 с минимальной поддерживаемой версией
+	protocolVersion, err := s.negotiateProtocolVersion(req.ProtocolVersion)
+	if err != nil {
+		s.writeProtocolVersionError(w, err)
 		return
 	}
 
 	// Логирование всех полей итераций для отладки
-	s.log(LogEntry{
+	s.logCtx(r.Context(), LogEntry{
 		Timestamp: time.Now(),
 		Level:     "DEBUG",
 		Message: fmt.Sprintf("Handshake request received - Version1C: %s, ConfigName: %s, DatabaseID: %s, IterationNumber: %d, IterationLabel: %s, ProgrammerName: %s, UploadPurpose: %s, ParentUploadID: %s",
@@ -647,6 +973,52 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 
 	// Создаем новую выгрузку
 	uploadUUID := uuid.New().String()
+	if req.UploadUUID != "" {
+		parsed, err := uuid.Parse(req.UploadUUID)
+		if err != nil {
+			s.writeErrorResponseWithStatus(w, http.StatusBadRequest, "Invalid upload_uuid", err)
+			return
+		}
+		uploadUUID = parsed.String()
+		noteRequestUpload(r, uploadUUID)
+		if s.unifiedCatalogsDB != nil {
+			if _, err := s.unifiedCatalogsDB.GetUploadByUUID(uploadUUID); err == nil {
+				s.writeErrorResponseWithStatus(w, http.StatusConflict, "Upload already exists", fmt.Errorf("upload %s already exists", uploadUUID))
+				return
+			}
+		}
+	}
+
+	// Тестовая выгрузка: отдельная БД в памяти без определения базы данных проекта
+	if req.Sandbox {
+		sandbox, upload, err := s.createSandboxUpload(uploadUUID, &req, protocolVersion)
+		if err != nil {
+			s.writeErrorResponse(w, "Failed to create sandbox upload", err)
+			return
+		}
+		s.logCtx(r.Context(), LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message: fmt.Sprintf("Sandbox handshake successful for upload %s (config: %s, iteration_number: %d, protocol_version: %d, expires_at: %s)",
+				uploadUUID, req.ConfigName, upload.IterationNumber, protocolVersion, sandbox.expiresAt.Format(time.RFC3339)),
+			UploadUUID: uploadUUID,
+			Endpoint:   "/handshake",
+		})
+		s.writeXMLResponse(w, HandshakeResponse{
+			Success:      true,
+			UploadUUID:   uploadUUID,
+			DatabaseName: "sandbox",
+			Message:      "Handshake successful",
+			Timestamp:    time.Now().Format(time.RFC3339),
+
+			ProtocolVersion:    protocolVersion,
+			MinProtocolVersion: s.minProtocolVersion(),
+			ProtocolDeprecated: isProtocolDeprecated(protocolVersion),
+			Sandbox:            true,
+			SandboxExpiresAt:   sandbox.expiresAt.Format(time.RFC3339),
+		})
+		return
+	}
 
 	// Определяем parent_upload_id если указан ParentUploadID (UUID) - ищем в текущей БД сервера
 	var parentUploadID *int
@@ -664,6 +1036,7 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 	var clientName, projectName string
 	var identifiedBy string            // Для логирования способа идентификации
 	var similarUpload *database.Upload // Для хранения похожей выгрузки
+	var projectArchived bool           // База данных принадлежит архивному проекту
 
 	if req.DatabaseID != "" {
 		// Приоритет 1: Прямой database_id из запроса
@@ -680,6 +1053,7 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 					project, err := s.serviceDB.GetClientProject(dbInfo.ClientProjectID)
 					if err == nil && project != nil {
 						projectName = project.Name
+						projectArchived = project.Status == database.ProjectStatusArchived
 
 						// Получаем информацию о клиенте
 						client, err := s.serviceDB.GetClient(project.ClientID)
@@ -713,6 +1087,7 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 					project, err := s.serviceDB.GetClientProject(dbInfo.ClientProjectID)
 					if err == nil && project != nil {
 						projectName = project.Name
+						projectArchived = project.Status == database.ProjectStatusArchived
 
 						// Получаем информацию о клиенте
 						client, err := s.serviceDB.GetClient(project.ClientID)
@@ -724,7 +1099,7 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Логируем успешную автоматическую идентификацию
-			s.log(LogEntry{
+			s.logCtx(r.Context(), LogEntry{
 				Timestamp: time.Now(),
 				Level:     "INFO",
 				Message: fmt.Sprintf("Auto-identified database_id=%d from similar upload (computer=%s, config=%s, version=%s)",
@@ -735,7 +1110,7 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 		} else {
 			identifiedBy = "none"
 			// Логируем, что автоматическая идентификация не удалась
-			s.log(LogEntry{
+			s.logCtx(r.Context(), LogEntry{
 				Timestamp: time.Now(),
 				Level:     "INFO",
 				Message: fmt.Sprintf("Could not auto-identify database (computer=%s, config=%s, version=%s)",
@@ -746,6 +1121,12 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Архивный проект заморожен, выгрузки в его базы данных не принимаются
+	if projectArchived {
+		s.writeErrorResponse(w, "Project is archived", database.ErrProjectArchived)
+		return
+	}
+
 	// Определяем тип выгружаемых данных (для логирования)
 	uploadType := req.UploadType
 	if uploadType == "" {
@@ -761,7 +1142,7 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 	// НОВАЯ ЛОГИКА: Создаем выгрузку в ЕДИНОЙ БД (s.unifiedCatalogsDB)
 	// Вместо создания отдельного файла БД для каждой выгрузки
 	if s.unifiedCatalogsDB == nil {
-		s.writeErrorResponse(w, "Unified catalogs database not initialized", fmt.Errorf("unifiedCatalogsDB is nil"))
+		s.writeErrorResponse(w, "Unified catalogs database not initialized", apperrors.Unavailable("database_unavailable", "unifiedCatalogsDB is nil"))
 		return
 	}
 
@@ -774,11 +1155,14 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 		s.writeErrorResponse(w, "Failed to create upload", err)
 		return
 	}
+	if err := s.unifiedCatalogsDB.SetUploadProtocolVersion(upload.ID, protocolVersion); err != nil {
+		s.writeErrorResponse(w, "Failed to save upload protocol version", err)
+		return
+	}
 
 	// Сохраняем ссылку на единую БД в кэш (для совместимости с существующим кодом)
-	s.uploadDBsMutex.Lock()
-	s.uploadDBs[uploadUUID] = s.unifiedCatalogsDB
-	s.uploadDBsMutex.Unlock()
+	s.uploadDBs.putShared(uploadUUID, s.unifiedCatalogsDB)
+	s.registerUploadLocation(uploadUUID, s.unifiedCatalogsDBPath(), databaseID)
 
 	// Нет необходимости регистрировать новый файл БД в service.db,
 	// так как теперь все данные в одной БД
@@ -821,9 +1205,10 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 				SET client_id = ?, project_id = ? 
 				WHERE id = ?
 			`, clientID, projectID, upload.ID)
+			s.unifiedCatalogsDB.InvalidateUpload(upload.ID)
 			if err != nil {
 				// Логируем ошибку, но не прерываем процесс
-				s.log(LogEntry{
+				s.logCtx(r.Context(), LogEntry{
 					Timestamp:  time.Now(),
 					Level:      "WARNING",
 					Message:    fmt.Sprintf("Failed to update cached client_id and project_id: %v", err),
@@ -834,11 +1219,11 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	s.log(LogEntry{
+	s.logCtx(r.Context(), LogEntry{
 		Timestamp: time.Now(),
 		Level:     "INFO",
-		Message: fmt.Sprintf("Handshake successful for upload %s (unified_db, database_id: %v, identified_by: %s, iteration_number: %d, iteration_label: %s, programmer: %s, purpose: %s, parent_upload_id: %v, upload_type: %s)",
-			uploadUUID, databaseID, identifiedBy, upload.IterationNumber, upload.IterationLabel, upload.ProgrammerName, upload.UploadPurpose, upload.ParentUploadID, uploadType),
+		Message: fmt.Sprintf("Handshake successful for upload %s (unified_db, database_id: %v, identified_by: %s, iteration_number: %d, iteration_label: %s, programmer: %s, purpose: %s, parent_upload_id: %v, upload_type: %s, protocol_version: %d)",
+			uploadUUID, databaseID, identifiedBy, upload.IterationNumber, upload.IterationLabel, upload.ProgrammerName, upload.UploadPurpose, upload.ParentUploadID, uploadType, protocolVersion),
 		UploadUUID: uploadUUID,
 		Endpoint:   "/handshake",
 	})
@@ -849,10 +1234,14 @@ func (s *Server) handleHandshake(w http.ResponseWriter, r *http.Request) {
 		ClientName:   clientName,
 		ProjectName:  projectName,
 		DatabaseName: "unified_catalogs.db", // Теперь всегда единая БД
-		DatabasePath: s.config.UnifiedCatalogsDBPath,
+		DatabasePath: s.currentConfig().UnifiedCatalogsDBPath,
 		DatabaseID:   0, // Нет отдельного database_id для файла
 		Message:      "Handshake successful",
 		Timestamp:    time.Now().Format(time.RFC3339),
+
+		ProtocolVersion:    protocolVersion,
+		MinProtocolVersion: s.minProtocolVersion(),
+		ProtocolDeprecated: isProtocolDeprecated(protocolVersion),
 	}
 
 	s.writeXMLResponse(w, response)
@@ -867,17 +1256,18 @@ func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req MetadataRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
 	// Получаем БД для этой выгрузки
+	noteRequestUpload(r, req.UploadUUID)
 	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
@@ -885,16 +1275,30 @@ func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверяем существование выгрузки
-	_, err = uploadDB.GetUploadByUUID(req.UploadUUID)
+	upload, err := uploadDB.GetUploadByUUID(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, "Upload not found", err)
 		return
 	}
 
+	// Сохраняем описания справочников: реквизиты, типы и табличные части
+	for _, catalog := range req.Catalogs {
+		if err := database.SaveCatalogMetadata(uploadDB.GetDB(), toCatalogMetadata(catalog, upload.ID)); err != nil {
+			s.writeErrorResponse(w, fmt.Sprintf("Failed to save metadata of catalog '%s'", catalog.Name), err)
+			return
+		}
+	}
+
+	// Объявленные количества сверяются с принятыми данными при завершении выгрузки
+	if err := saveExpectedCounts(uploadDB, upload.ID, &req); err != nil {
+		s.writeErrorResponse(w, "Failed to save expected counts", err)
+		return
+	}
+
 	s.log(LogEntry{
 		Timestamp:  time.Now(),
 		Level:      "INFO",
-		Message:    "Metadata received successfully",
+		Message:    fmt.Sprintf("Metadata received successfully (catalogs described: %d)", len(req.Catalogs)),
 		UploadUUID: req.UploadUUID,
 		Endpoint:   "/metadata",
 	})
@@ -917,7 +1321,7 @@ func (s *Server) handleConstant(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
@@ -927,7 +1331,7 @@ func (s *Server) handleConstant(w http.ResponseWriter, r *http.Request) {
 	if len(bodyPreview) > 500 {
 		bodyPreview = bodyPreview[:500] + "..."
 	}
-	s.log(LogEntry{
+	s.logCtx(r.Context(), LogEntry{
 		Timestamp: time.Now(),
 		Level:     "DEBUG",
 		Message:   fmt.Sprintf("Received constant XML (length: %d): %s", len(bodyStr), bodyPreview),
@@ -936,18 +1340,18 @@ func (s *Server) handleConstant(w http.ResponseWriter, r *http.Request) {
 
 	var req ConstantRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.log(LogEntry{
+		s.logCtx(r.Context(), LogEntry{
 			Timestamp: time.Now(),
 			Level:     "ERROR",
 			Message:   fmt.Sprintf("Failed to parse XML: %v, body preview: %s", err, bodyPreview),
 			Endpoint:  "/constant",
 		})
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
 	// Логирование распарсенных данных
-	s.log(LogEntry{
+	s.logCtx(r.Context(), LogEntry{
 		Timestamp: time.Now(),
 		Level:     "DEBUG",
 		Message:   fmt.Sprintf("Parsed constant - Name: %s, Type: %s, Value.Content length: %d, Value.Content: %s", req.Name, req.Type, len(req.Value.Content), req.Value.Content),
@@ -955,6 +1359,7 @@ func (s *Server) handleConstant(w http.ResponseWriter, r *http.Request) {
 	})
 
 	// Получаем БД для этой выгрузки
+	noteRequestUpload(r, req.UploadUUID)
 	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
@@ -982,7 +1387,7 @@ func (s *Server) handleConstant(w http.ResponseWriter, r *http.Request) {
 		valuePreview = valuePreview[:100] + "..."
 	}
 
-	s.log(LogEntry{
+	s.logCtx(r.Context(), LogEntry{
 		Timestamp:  time.Now(),
 		Level:      "INFO",
 		Message:    fmt.Sprintf("Constant '%s' (type: %s) added successfully, value preview: %s", req.Name, req.Type, valuePreview),
@@ -1008,17 +1413,18 @@ func (s *Server) handleCatalogMeta(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req CatalogMetaRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
 	// Получаем БД для этой выгрузки (теперь всегда единая БД)
+	noteRequestUpload(r, req.UploadUUID)
 	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
@@ -1032,6 +1438,30 @@ func (s *Server) handleCatalogMeta(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Описание реквизитов может прийти вместе с метаданными справочника
+	if len(req.Attributes) > 0 || len(req.TableParts) > 0 {
+		catalog := MetadataCatalog{Name: req.Name, Synonym: req.Synonym, Attributes: req.Attributes, TableParts: req.TableParts}
+		if err := database.SaveCatalogMetadata(uploadDB.GetDB(), toCatalogMetadata(catalog, upload.ID)); err != nil {
+			s.writeErrorResponse(w, fmt.Sprintf("Failed to save metadata of catalog '%s'", req.Name), err)
+			return
+		}
+	}
+
+	// Реквизиты, объявленные в метаданных справочника, продвигаем в типизированные колонки
+	// (тип колонки берется из описания реквизита, если оно было прислано)
+	metadata, _ := database.GetCatalogMetadata(uploadDB.GetDB(), upload.ID, req.Name)
+	for _, attributeName := range req.IndexedAttributes {
+		columnType := "text"
+		if metadata != nil {
+			if attr, ok := metadata.Attribute(attributeName); ok {
+				columnType = attr.ColumnType()
+			}
+		}
+		if _, err := database.SaveCatalogColumnMapping(uploadDB.GetDB(), req.Name, attributeName, columnType, "metadata"); err != nil {
+			log.Printf("Warning: Failed to save column mapping %s.%s: %v", req.Name, attributeName, err)
+		}
+	}
+
 	// НОВАЯ ЛОГИКА: Получаем или создаём таблицу для этого справочника
 	tableName, err := database.GetOrCreateCatalogTable(uploadDB.GetDB(), req.Name)
 	if err != nil {
@@ -1044,9 +1474,8 @@ func (s *Server) handleCatalogMeta(w http.ResponseWriter, r *http.Request) {
 	// Сохраняем маппинг (если ещё не сохранён)
 	// GetOrCreateCatalogTable уже сохраняет маппинг внутри
 
-	// Обновляем счётчик справочников
-	_, err = uploadDB.Exec("UPDATE uploads SET total_catalogs = total_catalogs + 1 WHERE id = ?", upload.ID)
-	if err != nil {
+	// Регистрируем справочник в выгрузке: повторная отправка метаданных счётчик не увеличивает
+	if _, err := uploadDB.RegisterUploadCatalog(upload.ID, req.Name); err != nil {
 		log.Printf("Warning: Failed to update catalogs counter: %v", err)
 	}
 
@@ -1077,7 +1506,7 @@ func (s *Server) handleCatalogItem(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
@@ -1100,7 +1529,7 @@ func (s *Server) handleCatalogItem(w http.ResponseWriter, r *http.Request) {
 	var req CatalogItemRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
 		log.Printf("[DEBUG] ✗ ОШИБКА парсинга XML: %v", err)
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -1139,6 +1568,7 @@ func (s *Server) handleCatalogItem(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[DEBUG] Timestamp: %s", req.Timestamp)
 
 	// Получаем БД для этой выгрузки (теперь всегда единая БД)
+	noteRequestUpload(r, req.UploadUUID)
 	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
@@ -1152,6 +1582,16 @@ func (s *Server) handleCatalogItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Элемент разбирается по правилам версии протокола выгрузки
+	if !s.adaptCatalogItemRequest(w, uploadDB, upload, body, &req) {
+		return
+	}
+
+	// Ограничение скорости приема данных клиента
+	if !s.allowIngest(w, upload, 1, len(body)) {
+		return
+	}
+
 	// НОВАЯ ЛОГИКА: Получаем имя таблицы для этого справочника
 	tableName, err := database.GetCatalogTableName(uploadDB.GetDB(), req.CatalogName)
 	if err != nil {
@@ -1185,12 +1625,40 @@ func (s *Server) handleCatalogItem(w http.ResponseWriter, r *http.Request) {
 		}())
 	log.Printf("[DEBUG] tableParts для сохранения (длина: %d)", len(tablePartsStr))
 	
+	// Сверяем реквизиты с объявленными метаданными справочника (поведение задает IngestValidationMode)
+	if violations, reject := s.validateCatalogItem(uploadDB, upload, req.CatalogName, req.Reference, attrsStr); reject {
+		s.writeErrorResponseWithStatus(w, http.StatusUnprocessableEntity, "Catalog item does not match metadata",
+			fmt.Errorf("%s", formatAttributeViolations(violations)))
+		return
+	}
+
+	// Положение в иерархии групп определяется до шифрования реквизитов
+	hierarchyNode := catalogHierarchyNode(req.Reference, req.Code, req.Name, req.ParentReference, req.IsGroup, attrsStr)
+
+	// Реквизиты шифруются после проверки, если для проекта выгрузки включено шифрование
+	item, err := s.sealCatalogTableItem(upload, database.CatalogTableItem{
+		Reference:     req.Reference,
+		Code:          req.Code,
+		Name:          req.Name,
+		AttributesXML: attrsStr,
+		TablePartsXML: tablePartsStr,
+	})
+	if err != nil {
+		s.writeErrorResponse(w, "Failed to encrypt catalog item", err)
+		return
+	}
+
 	// Используем новую функцию для вставки в динамическую таблицу
-	if err := uploadDB.AddCatalogItemToTable(tableName, upload.ID, req.Reference, req.Code, req.Name, attrsStr, tablePartsStr); err != nil {
+	inserted, itemErrors, err := uploadDB.AddCatalogItemsToTable(tableName, upload.ID, []database.CatalogTableItem{item})
+	if err == nil && inserted == 0 {
+		err = itemErrors[0]
+	}
+	if err != nil {
 		log.Printf("[DEBUG] ✗ ОШИБКА при сохранении в БД: %v", err)
 		s.writeErrorResponse(w, "Failed to add catalog item", err)
 		return
 	}
+	s.saveCatalogHierarchy(uploadDB, upload, req.CatalogName, []database.CatalogHierarchyNode{hierarchyNode})
 
 	log.Printf("[DEBUG] ✓ Элемент успешно сохранен в таблицу %s", tableName)
 	log.Printf("[DEBUG] ========================================")
@@ -1221,7 +1689,7 @@ func (s *Server) handleCatalogItems(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
@@ -1244,7 +1712,7 @@ func (s *Server) handleCatalogItems(w http.ResponseWriter, r *http.Request) {
 	var req CatalogItemsRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
 		log.Printf("[DEBUG] ✗ ОШИБКА парсинга XML: %v", err)
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -1281,6 +1749,7 @@ func (s *Server) handleCatalogItems(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Получаем БД для этой выгрузки
+	noteRequestUpload(r, req.UploadUUID)
 	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
@@ -1294,11 +1763,20 @@ func (s *Server) handleCatalogItems(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Находим справочник по имени
-	var catalogID int
-	err = uploadDB.QueryRow("SELECT id FROM catalogs WHERE upload_id = ? AND name = ?", upload.ID, req.CatalogName).Scan(&catalogID)
+	// Пакет разбирается по правилам версии протокола выгрузки
+	if !s.adaptCatalogItemsRequest(w, uploadDB, upload, body, &req) {
+		return
+	}
+
+	// Ограничение скорости приема данных клиента
+	if !s.allowIngest(w, upload, len(req.Items), len(body)) {
+		return
+	}
+
+	// Получаем таблицу справочника в единой БД (создаём, если пропустили /catalog/meta)
+	tableName, err := database.GetOrCreateCatalogTable(uploadDB.GetDB(), req.CatalogName)
 	if err != nil {
-		s.writeErrorResponse(w, "Catalog not found", err)
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to get/create catalog table: %v", err), err)
 		return
 	}
 
@@ -1309,8 +1787,11 @@ func (s *Server) handleCatalogItems(w http.ResponseWriter, r *http.Request) {
 	itemsWithoutAttrs := 0
 
 	log.Printf("[DEBUG] --- Сохранение элементов в БД ---")
-	log.Printf("[DEBUG] catalogID: %d", catalogID)
+	log.Printf("[DEBUG] tableName: %s", tableName)
 	
+	batch := make([]database.CatalogTableItem, 0, len(req.Items))
+	batchIndexes := make([]int, 0, len(req.Items))
+	hierarchyNodes := make([]database.CatalogHierarchyNode, 0, len(req.Items))
 	for i, item := range req.Items {
 		itemAttrsStr := item.Attributes.Content
 		itemTablePartsStr := item.TableParts.Content
@@ -1340,22 +1821,73 @@ func (s *Server) handleCatalogItems(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		
-		if err := uploadDB.AddCatalogItem(catalogID, item.Reference, item.Code, item.Name, itemAttrsStr, itemTablePartsStr); err != nil {
+		if violations, reject := s.validateCatalogItem(uploadDB, upload, req.CatalogName, item.Reference, itemAttrsStr); reject {
 			failedCount++
-			log.Printf("[DEBUG]   ✗ ОШИБКА при сохранении элемента #%d: %v", i+1, err)
-			s.log(LogEntry{
+			s.logCtx(r.Context(), LogEntry{
 				Timestamp:  time.Now(),
 				Level:      "ERROR",
-				Message:    fmt.Sprintf("Failed to add catalog item '%s': %v", item.Name, err),
+				Message:    fmt.Sprintf("Catalog item '%s' rejected: %s", item.Name, formatAttributeViolations(violations)),
 				UploadUUID: req.UploadUUID,
 				Endpoint:   "/catalog/items",
 			})
-		} else {
-			processedCount++
-			if i < 3 {
-				log.Printf("[DEBUG]   ✓ Элемент #%d успешно сохранен", i+1)
+			continue
+		}
+		hierarchyNodes = append(hierarchyNodes, catalogHierarchyNode(item.Reference, item.Code, item.Name, item.ParentReference, item.IsGroup, itemAttrsStr))
+		tableItem, err := s.sealCatalogTableItem(upload, database.CatalogTableItem{
+			Reference:     item.Reference,
+			Code:          item.Code,
+			Name:          item.Name,
+			AttributesXML: itemAttrsStr,
+			TablePartsXML: itemTablePartsStr,
+		})
+		if err != nil {
+			s.writeErrorResponse(w, "Failed to encrypt catalog items", err)
+			return
+		}
+
+		batch = append(batch, tableItem)
+		batchIndexes = append(batchIndexes, i)
+	}
+
+	// Сохраняем принятые элементы одной транзакцией вместе со счётчиком total_items
+	inserted, itemErrors, err := uploadDB.AddCatalogItemsToTable(tableName, upload.ID, batch)
+	if err != nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to save catalog items: %v", err), err)
+		return
+	}
+	processedCount = inserted
+	if inserted > 0 {
+		saved := make([]database.CatalogHierarchyNode, 0, inserted)
+		for i, node := range hierarchyNodes {
+			if itemErrors[i] == nil {
+				saved = append(saved, node)
 			}
 		}
+		s.saveCatalogHierarchy(uploadDB, upload, req.CatalogName, saved)
+		if _, err := uploadDB.RegisterUploadCatalog(upload.ID, req.CatalogName); err != nil {
+			log.Printf("Warning: Failed to update catalogs counter: %v", err)
+		}
+	}
+	// Номер пакета нужен для проверки непрерывности пакетов при завершении выгрузки
+	if req.BatchNumber > 0 {
+		if err := uploadDB.RecordUploadBatch(upload.ID, req.CatalogName, req.BatchNumber, processedCount); err != nil {
+			log.Printf("Warning: Failed to record batch %d of catalog %s: %v", req.BatchNumber, req.CatalogName, err)
+		}
+	}
+	for j, itemErr := range itemErrors {
+		if itemErr == nil {
+			continue
+		}
+		failedCount++
+		item := req.Items[batchIndexes[j]]
+		log.Printf("[DEBUG]   ✗ ОШИБКА при сохранении элемента #%d: %v", batchIndexes[j]+1, itemErr)
+		s.logCtx(r.Context(), LogEntry{
+			Timestamp:  time.Now(),
+			Level:      "ERROR",
+			Message:    fmt.Sprintf("Failed to add catalog item '%s': %v", item.Name, itemErr),
+			UploadUUID: req.UploadUUID,
+			Endpoint:   "/catalog/items",
+		})
 	}
 	
 	log.Printf("[DEBUG] --- Статистика пакета ---")
@@ -1366,7 +1898,7 @@ func (s *Server) handleCatalogItems(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[DEBUG] Ошибок: %d", failedCount)
 	log.Printf("[DEBUG] ========================================")
 
-	s.log(LogEntry{
+	s.logCtx(r.Context(), LogEntry{
 		Timestamp:  time.Now(),
 		Level:      "INFO",
 		Message:    fmt.Sprintf("Batch catalog items processed: %d successful, %d failed", processedCount, failedCount),
@@ -1394,17 +1926,18 @@ func (s *Server) handleNomenclatureBatch(w http.ResponseWriter, r *http.Request)
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req NomenclatureBatchRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
 	// Получаем БД для этой выгрузки
+	noteRequestUpload(r, req.UploadUUID)
 	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
@@ -1418,6 +1951,11 @@ func (s *Server) handleNomenclatureBatch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Ограничение скорости приема данных клиента
+	if !s.allowIngest(w, upload, len(req.Items), len(body)) {
+		return
+	}
+
 	// Преобразуем элементы в формат для базы данных
 	nomenclatureItems := make([]database.NomenclatureItem, 0, len(req.Items))
 	for _, item := range req.Items {
@@ -1430,6 +1968,11 @@ func (s *Server) handleNomenclatureBatch(w http.ResponseWriter, r *http.Request)
 			AttributesXML:           item.Attributes,
 			TablePartsXML:           item.TableParts,
 		})
+		last := &nomenclatureItems[len(nomenclatureItems)-1]
+		if err := s.sealUploadValues(upload, &last.AttributesXML, &last.TablePartsXML); err != nil {
+			s.writeErrorResponse(w, "Failed to encrypt nomenclature items", err)
+			return
+		}
 	}
 
 	// Добавляем пакет элементов номенклатуры
@@ -1468,17 +2011,18 @@ func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req CompleteRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
 	// Получаем БД для этой выгрузки
+	noteRequestUpload(r, req.UploadUUID)
 	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
 	if err != nil {
 		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
@@ -1492,8 +2036,38 @@ func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Сверяем полноту выгрузки с объявленными 1С количествами и номерами пакетов
+	var discrepancies []database.CompletenessDiscrepancy
+	if mode := s.uploadCompletenessMode(); mode != UploadCompletenessOff {
+		discrepancies, err = uploadDB.CheckUploadCompleteness(upload.ID)
+		if err != nil {
+			s.writeErrorResponse(w, "Failed to check upload completeness", err)
+			return
+		}
+		if len(discrepancies) > 0 {
+			s.log(LogEntry{
+				Timestamp:  time.Now(),
+				Level:      "WARN",
+				Message:    fmt.Sprintf("Upload %s completeness check found %d discrepancies (mode %s): %s", req.UploadUUID, len(discrepancies), mode, discrepancies[0].Message),
+				UploadUUID: req.UploadUUID,
+				Endpoint:   "/complete",
+			})
+			if mode == UploadCompletenessStrict {
+				s.writeUploadIncomplete(w, discrepancies)
+				return
+			}
+		}
+	}
+
 	// Завершаем выгрузку
-	if err := uploadDB.CompleteUpload(upload.ID); err != nil {
+	status := database.UploadStatusCompleted
+	if len(discrepancies) > 0 {
+		status = database.UploadStatusCompletedWithWarnings
+		err = uploadDB.CompleteUploadWithWarnings(upload.ID)
+	} else {
+		err = uploadDB.CompleteUpload(upload.ID)
+	}
+	if err != nil {
 		s.writeErrorResponse(w, "Failed to complete upload", err)
 		return
 	}
@@ -1501,13 +2075,53 @@ func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
 	s.log(LogEntry{
 		Timestamp:  time.Now(),
 		Level:      "INFO",
-		Message:    fmt.Sprintf("Upload %s completed successfully", req.UploadUUID),
+		Message:    fmt.Sprintf("Upload %s completed with status %s", req.UploadUUID, status),
 		UploadUUID: req.UploadUUID,
 		Endpoint:   "/complete",
 	})
 
+	// Тестовая выгрузка не попадает в сводки и анализ качества: возвращаем принятые количества для сверки
+	if _, ok := s.sandboxUploads.get(req.UploadUUID); ok {
+		counts, err := uploadDB.ComputeUploadCounters(upload.ID)
+		if err != nil {
+			s.writeErrorResponse(w, "Failed to count sandbox upload", err)
+			return
+		}
+		response := CompleteResponse{
+			Success:       true,
+			Message:       "Sandbox upload completed successfully",
+			Timestamp:     time.Now().Format(time.RFC3339),
+			Status:        status,
+			SandboxCounts: &SandboxCounts{Constants: counts.Constants, Catalogs: counts.Catalogs, Items: counts.Items, Documents: counts.Documents},
+		}
+		if len(discrepancies) > 0 {
+			response.Message = fmt.Sprintf("Sandbox upload completed with %d discrepancies", len(discrepancies))
+			response.Discrepancies = toProtocolDiscrepancies(discrepancies)
+		}
+		s.writeXMLResponse(w, response)
+		return
+	}
+
+	// Обновляем дневную сводку по выгрузкам за день начала выгрузки; сводка суммируется по всем БД выгрузок,
+	// иначе счетчики этой БД заменили бы выгрузки того же дня из других БД
+	go func() {
+		defer s.recoverWorker("upload_rollups_refresh", nil)
+		if err := s.refreshAllUploadRollups(upload.StartedAt); err != nil {
+			log.Printf("Failed to refresh upload rollups for %s: %v", req.UploadUUID, err)
+		}
+	}()
+
+	// Считаем статистику приема по справочникам и сравниваем с прошлой выгрузкой базы
+	go func() {
+		defer s.recoverWorker("upload_ingest_stats", nil)
+		if _, err := s.recordIngestStats(context.Background(), uploadDB, upload); err != nil {
+			log.Printf("Failed to record ingest stats for %s: %v", req.UploadUUID, err)
+		}
+	}()
+
 	// Запускаем анализ качества в фоне
 	go func() {
+		defer s.recoverWorker(jobKindQualityAnalysis, nil)
 		databaseID := 0
 		if upload.DatabaseID != nil {
 			databaseID = *upload.DatabaseID
@@ -1515,11 +2129,17 @@ func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
 
 		if databaseID > 0 {
 			log.Printf("Starting quality analysis for upload %s (ID: %d, Database: %d)", req.UploadUUID, upload.ID, databaseID)
-			if err := s.qualityAnalyzer.AnalyzeUpload(upload.ID, databaseID); err != nil {
+			if err := s.uploadQualityAnalyzer(uploadDB, upload).AnalyzeUpload(upload.ID, databaseID); err != nil {
 				log.Printf("Quality analysis failed for upload %s: %v", req.UploadUUID, err)
 			} else {
 				log.Printf("Quality analysis completed for upload %s", req.UploadUUID)
 			}
+			// Фиксируем карту качества базы после каждой завершенной выгрузки
+			if s.serviceDB != nil {
+				if _, _, err := s.refreshDatabaseScorecard(databaseID); err != nil {
+					log.Printf("Failed to refresh scorecard for database %d: %v", databaseID, err)
+				}
+			}
 		} else {
 			log.Printf("Skipping quality analysis for upload %s: database_id not set", req.UploadUUID)
 		}
@@ -1529,11 +2149,24 @@ func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
 		Success:   true,
 		Message:   "Upload completed successfully",
 		Timestamp: time.Now().Format(time.RFC3339),
+		Status:    status,
+	}
+	if len(discrepancies) > 0 {
+		response.Message = fmt.Sprintf("Upload completed with %d discrepancies", len(discrepancies))
+		response.Discrepancies = toProtocolDiscrepancies(discrepancies)
 	}
 
 	s.writeXMLResponse(w, response)
 }
 
+// qualityAnalyzerFor возвращает анализатор качества для БД, в которой хранятся данные выгрузки
+func (s *Server) qualityAnalyzerFor(uploadDB *database.DB) *quality.QualityAnalyzer {
+	if uploadDB == nil || uploadDB == s.db {
+		return s.qualityAnalyzer
+	}
+	return quality.NewQualityAnalyzer(uploadDB)
+}
+
 // handleStats обрабатывает запрос статистики
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -1572,7 +2205,7 @@ func (s *Server) handleDatabaseV1Routes(w http.ResponseWriter, r *http.Request)
 	// Парсим database_id (может быть строкой или числом)
 	databaseID, err := strconv.Atoi(path)
 	if err != nil {
-		s.writeErrorResponse(w, "Invalid database ID", err)
+		s.writeErrorResponse(w, "Invalid database ID", apperrors.Wrap(apperrors.KindValidation, "invalid_database_id", err))
 		return
 	}
 
@@ -1583,7 +2216,7 @@ func (s *Server) handleDatabaseV1Routes(w http.ResponseWriter, r *http.Request)
 
 	// Получаем информацию о базе данных
 	if s.serviceDB == nil {
-		s.writeErrorResponse(w, "Service database not available", fmt.Errorf("serviceDB is nil"))
+		s.writeErrorResponse(w, "Service database not available", apperrors.Unavailable("database_unavailable", "serviceDB is nil"))
 		return
 	}
 
@@ -1594,7 +2227,7 @@ func (s *Server) handleDatabaseV1Routes(w http.ResponseWriter, r *http.Request)
 	}
 
 	if dbInfo == nil {
-		s.writeErrorResponse(w, "Database not found", fmt.Errorf("database with ID %d not found", databaseID))
+		s.writeErrorResponse(w, "Database not found", apperrors.NotFound("database_not_found", fmt.Sprintf("database with ID %d not found", databaseID)))
 		return
 	}
 
@@ -1717,6 +2350,32 @@ func (s *Server) GetCheckpointStatus() map[string]interface{} {
 	return s.normalizer.GetCheckpointStatus()
 }
 
+// GetDatabasePoolStats возвращает утилизацию пулов соединений (основного и только для чтения) по БД
+func (s *Server) GetDatabasePoolStats() map[string]interface{} {
+	pools := make(map[string]interface{})
+
+	s.dbMutex.RLock()
+	dbs := map[string]*database.DB{
+		"main":             s.db,
+		"normalized":       s.normalizedDB,
+		"unified_catalogs": s.unifiedCatalogsDB,
+	}
+	s.dbMutex.RUnlock()
+
+	for name, db := range dbs {
+		if db == nil {
+			continue
+		}
+		writeStats, readStats := db.PoolStats()
+		pools[name] = map[string]interface{}{
+			"write":     writeStats,
+			"read_only": readStats,
+		}
+	}
+
+	return pools
+}
+
 // CollectMetricsSnapshot собирает текущий снимок метрик производительности
 func (s *Server) CollectMetricsSnapshot() *database.PerformanceMetricsSnapshot {
 	// Рассчитываем uptime
@@ -1754,11 +2413,17 @@ func (s *Server) CollectMetricsSnapshot() *database.PerformanceMetricsSnapshot {
 		checkpointProgress = progress
 	}
 
+	// Детальные метрики: перцентили задержек по эндпоинтам
+	metricData := ""
+	if data, err := json.Marshal(map[string]interface{}{"endpoint_latency": s.endpointLatency.snapshot("", false)}); err == nil {
+		metricData = string(data)
+	}
+
 	// Создаем snapshot с минимальными данными
 	snapshot := &database.PerformanceMetricsSnapshot{
 		Timestamp:           time.Now(),
 		MetricType:          "all", // Общие метрики
-		MetricData:          "",    // TODO: JSON с детальными метриками
+		MetricData:          metricData,
 		UptimeSeconds:       int(uptime),
 		Throughput:          throughput,
 		AISuccessRate:       aiSuccessRate,
@@ -1785,14 +2450,36 @@ func (s *Server) writeJSONError(w http.ResponseWriter, message string, statusCod
 	middleware.WriteJSONError(w, message, statusCode)
 }
 
+// writeAPIError записывает JSON ошибку, HTTP статус и код определяются видом ошибки (apperrors)
+func (s *Server) writeAPIError(w http.ResponseWriter, message string, err error) {
+	status := apperrors.HTTPStatus(err)
+	middleware.WriteJSONErrorCode(w, message, errorCode(err, status), status)
+}
+
+// errorCode возвращает код типизированной ошибки или код по HTTP статусу
+func errorCode(err error, statusCode int) string {
+	if kind := apperrors.KindOf(err); kind != "" && kind != apperrors.KindInternal {
+		return apperrors.CodeOf(err)
+	}
+	return apperrors.CodeForStatus(statusCode)
+}
+
 // handleListUploads обрабатывает запрос списка выгрузок
+// Поддерживает пагинацию (limit, offset), сортировку (sort_by, order) и фильтры
+// (status, client_id, project_id, database_id, config_name, from, to), выполняемые на стороне SQL
 func (s *Server) handleListUploads(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	uploads, err := s.db.GetAllUploads()
+	filter, err := parseUploadListFilter(r.URL.Query())
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	uploads, total, err := s.db.ReadOnly().GetUploadsFiltered(filter)
 	if err != nil {
 		s.writeJSONError(w, fmt.Sprintf("Failed to get uploads: %v", err), http.StatusInternalServerError)
 		return
@@ -1816,18 +2503,120 @@ func (s *Server) handleListUploads(w http.ResponseWriter, r *http.Request) {
 	s.log(LogEntry{
 		Timestamp: time.Now(),
 		Level:     "INFO",
-		Message:   fmt.Sprintf("List uploads requested, returned %d uploads", len(items)),
+		Message:   fmt.Sprintf("List uploads requested, returned %d of %d uploads", len(items), total),
 		Endpoint:  "/api/uploads",
 	})
 
 	s.writeJSONResponse(w, map[string]interface{}{
 		"uploads": items,
-		"total":   len(items),
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
 	}, http.StatusOK)
 }
 
-// handleUploadRoutes обрабатывает маршруты с UUID выгрузки
-func (s *Server) handleUploadRoutes(w http.ResponseWriter, r *http.Request) {
+const (
+	defaultUploadsPageLimit = 100
+	maxUploadsPageLimit     = 1000
+)
+
+// parseUploadListFilter разбирает параметры запроса списка выгрузок
+func parseUploadListFilter(query url.Values) (database.UploadListFilter, error) {
+	filter := database.UploadListFilter{
+		Status:     strings.TrimSpace(query.Get("status")),
+		ConfigName: strings.TrimSpace(query.Get("config_name")),
+		SortBy:     "started_at",
+		SortDesc:   true,
+		Limit:      defaultUploadsPageLimit,
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return filter, fmt.Errorf("invalid limit: %s", limitStr)
+		}
+		if limit > maxUploadsPageLimit {
+			limit = maxUploadsPageLimit
+		}
+		filter.Limit = limit
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("invalid offset: %s", offsetStr)
+		}
+		filter.Offset = offset
+	}
+
+	if sortBy := query.Get("sort_by"); sortBy != "" {
+		switch sortBy {
+		case "started_at", "status", "config_name":
+			filter.SortBy = sortBy
+		default:
+			return filter, fmt.Errorf("invalid sort_by: %s (allowed: started_at, status, config_name)", sortBy)
+		}
+	}
+
+	switch strings.ToLower(query.Get("order")) {
+	case "", "desc":
+		filter.SortDesc = true
+	case "asc":
+		filter.SortDesc = false
+	default:
+		return filter, fmt.Errorf("invalid order: %s (allowed: asc, desc)", query.Get("order"))
+	}
+
+	intParams := map[string]**int{
+		"client_id":   &filter.ClientID,
+		"project_id":  &filter.ProjectID,
+		"database_id": &filter.DatabaseID,
+	}
+	for name, target := range intParams {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: %s", name, value)
+			}
+			*target = &id
+		}
+	}
+
+	dateParams := map[string]**time.Time{
+		"from": &filter.StartedFrom,
+		"to":   &filter.StartedTo,
+	}
+	for name, target := range dateParams {
+		if value := query.Get(name); value != "" {
+			t, err := parseDateParam(value, name == "to")
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: %s (expected RFC3339 or YYYY-MM-DD)", name, value)
+			}
+			*target = &t
+		}
+	}
+
+	return filter, nil
+}
+
+// parseDateParam разбирает дату в формате RFC3339 или YYYY-MM-DD
+// Для верхней границы диапазона дата без времени трактуется как конец дня
+func parseDateParam(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
+
+// handleUploadRoutes обрабатывает маршруты с UUID выгрузки
+func (s *Server) handleUploadRoutes(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/uploads/")
 	parts := strings.Split(path, "/")
 
@@ -1836,14 +2625,28 @@ func (s *Server) handleUploadRoutes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// POST /api/uploads/recount - пересчет счетчиков всех выгрузок
+	if len(parts) == 1 && parts[0] == "recount" {
+		s.handleRecountAllUploadCounters(w, r)
+		return
+	}
+
+	// GET/POST /api/uploads/index - состояние и перестроение индекса расположения выгрузок
+	if len(parts) == 1 && parts[0] == "index" {
+		s.handleUploadIndex(w, r)
+		return
+	}
+
 	uuid := parts[0]
 
-	// Получаем БД для этой выгрузки
-	uploadDB, err := s.getUploadDatabase(uuid)
+	// Получаем БД для этой выгрузки; потоковая отдача и пересчет могут идти дольше задержки закрытия
+	// вытесненной БД, поэтому БД захватывается до конца запроса
+	uploadDB, release, err := s.acquireUploadDatabase(uuid)
 	if err != nil {
 		s.writeJSONError(w, fmt.Sprintf("Upload database not found: %v", err), http.StatusNotFound)
 		return
 	}
+	defer release()
 
 	// Получаем выгрузку из БД upload
 	upload, err := uploadDB.GetUploadByUUID(uuid)
@@ -1873,9 +2676,42 @@ func (s *Server) handleUploadRoutes(w http.ResponseWriter, r *http.Request) {
 		case "exports":
 			// GET /api/uploads/{uuid}/exports - список задач экспорта
 			s.handleUploadExportsList(w, r, upload)
+		case "schema":
+			// GET /api/uploads/{uuid}/schema - метаданные справочников выгрузки
+			s.handleUploadSchema(w, r, uploadDB, upload)
+		case "recount":
+			// POST /api/uploads/{uuid}/recount - пересчет счетчиков выгрузки
+			s.handleRecountUploadCounters(w, r, uploadDB, upload)
+		case "integrity":
+			// GET/POST /api/uploads/{uuid}/integrity - ссылочная целостность справочников
+			s.handleUploadReferenceIntegrity(w, r, uploadDB, upload)
+		case "stats":
+			// GET/POST /api/uploads/{uuid}/stats - статистика приема по справочникам и аномалии
+			s.handleUploadIngestStats(w, r, uploadDB, upload)
+		case "timeline":
+			// GET /api/uploads/{uuid}/timeline - хронология событий выгрузки
+			s.handleUploadTimeline(w, r, upload)
+		case "normalized":
+			// GET /api/uploads/{uuid}/normalized?as_of= - нормализованные записи выгрузки на момент времени
+			s.handleUploadNormalizedAsOf(w, r, upload)
+		case "attachments":
+			// GET /api/uploads/{uuid}/attachments - вложения элементов справочников
+			s.handleUploadAttachments(w, r, uploadDB, upload)
+		case "documents":
+			// GET /api/uploads/{uuid}/documents - виды документов и документы выгрузки
+			s.handleUploadDocuments(w, r, uploadDB, upload)
 		default:
 			http.NotFound(w, r)
 		}
+	} else if len(parts) == 3 && parts[1] == "attachments" {
+		// GET /api/uploads/{uuid}/attachments/{id} - файл вложения
+		s.handleUploadAttachment(w, r, uploadDB, upload, parts[2])
+	} else if len(parts) == 4 && parts[1] == "catalogs" && parts[3] == "tree" {
+		// GET /api/uploads/{uuid}/catalogs/{name}/tree - дерево групп 1С справочника
+		s.handleUploadCatalogTree(w, r, uploadDB, upload, parts[2])
+	} else if len(parts) == 3 && parts[1] == "export" && parts[2] == "validate" {
+		// POST /api/uploads/{uuid}/export/validate - проверка контракта данных перед выгрузкой
+		s.handleUploadExportValidate(w, r, upload)
 	} else {
 		http.NotFound(w, r)
 	}
@@ -1974,7 +2810,7 @@ func (s *Server) handleGetUpload(w http.ResponseWriter, r *http.Request, upload
 	// Получаем БД для этой выгрузки
 	uploadDB, err := s.getUploadDatabase(upload.UploadUUID)
 	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get upload database: %v", err), http.StatusInternalServerError)
+		s.writeAPIError(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
 		return
 	}
 
@@ -2029,6 +2865,17 @@ func (s *Server) handleGetUpload(w http.ResponseWriter, r *http.Request, upload
 		Catalogs:       catalogInfos,
 		Constants:      constantData,
 	}
+	details.DocumentTypes, details.TotalDocuments = uploadDocumentTypes(uploadDB, upload.ID)
+
+	// Рядом с сохраненными счетчиками показываем подсчитанные по данным, чтобы было видно расхождение
+	if computed, err := uploadDB.ComputeUploadCounters(upload.ID); err == nil {
+		details.ComputedCounts = (*client.UploadCounters)(computed)
+		details.CountersDrift = computed.Constants != upload.TotalConstants ||
+			computed.Catalogs != upload.TotalCatalogs ||
+			computed.Items != upload.TotalItems
+	} else {
+		log.Printf("Warning: Failed to compute counters of upload %s: %v", upload.UploadUUID, err)
+	}
 
 	s.log(LogEntry{
 		Timestamp:  time.Now(),
@@ -2048,6 +2895,30 @@ func (s *Server) handleGetUploadData(w http.ResponseWriter, r *http.Request, upl
 		return
 	}
 
+	// Получаем БД для этой выгрузки
+	uploadDB, err := s.getUploadDatabase(upload.UploadUUID)
+	if err != nil {
+		s.writeAPIError(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
+		return
+	}
+
+	s.writeUploadData(w, r, uploadDB, upload, "Upload data", "/api/uploads/{uuid}/data")
+}
+
+// writeUploadData отдает страницу данных выгрузки из db, кодируя элементы потоково в ответ.
+// Для type=all константы идут перед элементами справочников; из БД читаются только элементы страницы
+func (s *Server) writeUploadData(w http.ResponseWriter, r *http.Request, db *database.DB, upload *database.Upload, logPrefix, endpoint string) {
+	// Запросы к БД прерываются при отключении клиента и по таймауту DBQueryTimeout
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
+	// Скрытые профилем пользователя поля не передаются
+	redaction, err := s.requestRedaction(r)
+	if err != nil {
+		s.writeAPIError(w, "Failed to resolve redaction profile", err)
+		return
+	}
+
 	// Парсим query параметры
 	dataType := r.URL.Query().Get("type")
 	if dataType == "" {
@@ -2078,167 +2949,74 @@ func (s *Server) handleGetUploadData(w http.ResponseWriter, r *http.Request, upl
 
 	offset := (page - 1) * limit
 
-	var responseItems []DataItem
+	var constants []*database.Constant
+	var catalogItems []*database.CatalogItem
 	var total int
 
-	// Функция для экранирования XML
-	escapeXML := func(s string) string {
-		s = strings.ReplaceAll(s, "&", "&amp;")
-		s = strings.ReplaceAll(s, "<", "&lt;")
-		s = strings.ReplaceAll(s, ">", "&gt;")
-		s = strings.ReplaceAll(s, "\"", "&quot;")
-		s = strings.ReplaceAll(s, "'", "&apos;")
-		return s
-	}
-
-	// Получаем БД для этой выгрузки
-	uploadDB, err := s.getUploadDatabase(upload.UploadUUID)
-	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get upload database: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Получаем данные в зависимости от типа
-	if dataType == "constants" {
-		constants, err := uploadDB.GetConstantsByUpload(upload.ID)
+	// Любой тип, кроме constants и catalogs, обрабатывается как all
+	if dataType != "catalogs" {
+		allConstants, err := db.GetConstantsByUploadContext(ctx, upload.ID)
 		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get constants: %v", err), http.StatusInternalServerError)
+			s.writeDBError(w, r, "Failed to get constants", err)
 			return
 		}
+		total = len(allConstants)
+		constants = allConstants[min(offset, total):min(offset+limit, total)]
+	}
 
-		total = len(constants)
-
-		// Применяем пагинацию для констант
-		start := offset
-		end := offset + limit
-		if start > len(constants) {
-			start = len(constants)
-		}
-		if end > len(constants) {
-			end = len(constants)
-		}
-
-		for i := start; i < end; i++ {
-			constData := constants[i]
-			// Формируем XML для константы - включаем все поля из БД
-			dataXML := fmt.Sprintf(`<constant><id>%d</id><upload_id>%d</upload_id><name>%s</name><synonym>%s</synonym><type>%s</type><value>%s</value><created_at>%s</created_at></constant>`,
-				constData.ID, constData.UploadID, escapeXML(constData.Name), escapeXML(constData.Synonym),
-				escapeXML(constData.Type), escapeXML(constData.Value), constData.CreatedAt.Format(time.RFC3339))
-
-			responseItems = append(responseItems, DataItem{
-				Type:      "constant",
-				ID:        constData.ID,
-				Data:      dataXML,
-				CreatedAt: constData.CreatedAt,
-			})
+	if dataType != "constants" {
+		itemOffset, itemLimit := offset, limit
+		if dataType != "catalogs" {
+			itemOffset = max(offset-total, 0)
+			itemLimit = limit - len(constants)
 		}
-	} else if dataType == "catalogs" {
-		catalogItems, itemTotal, err := uploadDB.GetCatalogItemsByUpload(upload.ID, catalogNames, offset, limit)
+		// limit 0 означает выборку без ограничения, поэтому для страницы, заполненной константами,
+		// запрашивается один элемент: он нужен только для общего количества
+		items, itemTotal, err := db.GetCatalogItemsByUploadContext(ctx, upload.ID, catalogNames, itemOffset, max(itemLimit, 1))
 		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get catalog items: %v", err), http.StatusInternalServerError)
+			s.writeDBError(w, r, "Failed to get catalog items", err)
 			return
 		}
-
-		total = itemTotal
-
-		for _, itemData := range catalogItems {
-			// Формируем XML для элемента справочника
-			// Включаем все поля из БД: id, catalog_id, catalog_name, reference, code, name, attributes_xml, table_parts_xml, created_at
-			// attributes_xml и table_parts_xml уже содержат XML, вставляем их как есть (innerXML)
-			dataXML := fmt.Sprintf(`<catalog_item><id>%d</id><catalog_id>%d</catalog_id><catalog_name>%s</catalog_name><reference>%s</reference><code>%s</code><name>%s</name><attributes_xml>%s</attributes_xml><table_parts_xml>%s</table_parts_xml><created_at>%s</created_at></catalog_item>`,
-				itemData.ID, itemData.CatalogID, escapeXML(itemData.CatalogName),
-				escapeXML(itemData.Reference), escapeXML(itemData.Code), escapeXML(itemData.Name),
-				itemData.Attributes, itemData.TableParts, itemData.CreatedAt.Format(time.RFC3339))
-
-			responseItems = append(responseItems, DataItem{
-				Type:      "catalog_item",
-				ID:        itemData.ID,
-				Data:      dataXML,
-				CreatedAt: itemData.CreatedAt,
-			})
-		}
-	} else { // dataType == "all"
-		// Для "all" сначала получаем все константы и элементы
-		constants, err := s.db.GetConstantsByUpload(upload.ID)
-		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get constants: %v", err), http.StatusInternalServerError)
-			return
+		total += itemTotal
+		if itemLimit > 0 {
+			catalogItems = items
 		}
-
-		catalogItems, itemTotal, err := s.db.GetCatalogItemsByUpload(upload.ID, catalogNames, 0, 0)
-		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get catalog items: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		total = len(constants) + itemTotal
-
-		// Объединяем все элементы и применяем пагинацию
-		allItems := make([]DataItem, 0, total)
-
-		// Добавляем константы - включаем все поля из БД
-		for _, constant := range constants {
-			dataXML := fmt.Sprintf(`<constant><id>%d</id><upload_id>%d</upload_id><name>%s</name><synonym>%s</synonym><type>%s</type><value>%s</value><created_at>%s</created_at></constant>`,
-				constant.ID, constant.UploadID, escapeXML(constant.Name), escapeXML(constant.Synonym),
-				escapeXML(constant.Type), escapeXML(constant.Value), constant.CreatedAt.Format(time.RFC3339))
-
-			allItems = append(allItems, DataItem{
-				Type:      "constant",
-				ID:        constant.ID,
-				Data:      dataXML,
-				CreatedAt: constant.CreatedAt,
-			})
-		}
-
-		// Добавляем элементы справочников
-		for _, itemData := range catalogItems {
-			// Включаем все поля из БД: id, catalog_id, catalog_name, reference, code, name, attributes_xml, table_parts_xml, created_at
-			// attributes_xml и table_parts_xml уже содержат XML, вставляем их как есть (innerXML)
-			dataXML := fmt.Sprintf(`<catalog_item><id>%d</id><catalog_id>%d</catalog_id><catalog_name>%s</catalog_name><reference>%s</reference><code>%s</code><name>%s</name><attributes_xml>%s</attributes_xml><table_parts_xml>%s</table_parts_xml><created_at>%s</created_at></catalog_item>`,
-				itemData.ID, itemData.CatalogID, escapeXML(itemData.CatalogName),
-				escapeXML(itemData.Reference), escapeXML(itemData.Code), escapeXML(itemData.Name),
-				itemData.Attributes, itemData.TableParts, itemData.CreatedAt.Format(time.RFC3339))
-
-			allItems = append(allItems, DataItem{
-				Type:      "catalog_item",
-				ID:        itemData.ID,
-				Data:      dataXML,
-				CreatedAt: itemData.CreatedAt,
-			})
-		}
-
-		// Применяем пагинацию
-		start := offset
-		end := offset + limit
-		if start > len(allItems) {
-			start = len(allItems)
-		}
-		if end > len(allItems) {
-			end = len(allItems)
-		}
-
-		responseItems = allItems[start:end]
 	}
 
-	// Формируем XML ответ
-	response := DataResponse{
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	sw := newXMLStreamWriter(w)
+	err = sw.beginDataResponse(DataResponse{
 		UploadUUID: upload.UploadUUID,
 		Type:       dataType,
 		Page:       page,
 		Limit:      limit,
 		Total:      total,
-		Items:      responseItems,
+	})
+	for i := 0; err == nil && i < len(constants); i++ {
+		err = sw.writeDataItem(newConstantDataItem(constants[i], redaction))
+	}
+	for i := 0; err == nil && i < len(catalogItems); i++ {
+		err = sw.writeDataItem(s.newCatalogDataItem(r, catalogItems[i], redaction))
+	}
+	if err == nil {
+		err = sw.endDataResponse()
+	}
+	if err != nil {
+		// Заголовки уже отправлены: клиент получит оборванный XML
+		log.Printf("Ошибка отправки данных выгрузки %s: %v", upload.UploadUUID, err)
+		return
 	}
 
 	s.log(LogEntry{
 		Timestamp:  time.Now(),
 		Level:      "INFO",
-		Message:    fmt.Sprintf("Upload data requested for %s, type=%s, returned %d items", upload.UploadUUID, dataType, len(responseItems)),
+		Message:    fmt.Sprintf("%s requested for %s, type=%s, returned %d items", logPrefix, upload.UploadUUID, dataType, len(constants)+len(catalogItems)),
 		UploadUUID: upload.UploadUUID,
-		Endpoint:   "/api/uploads/{uuid}/data",
+		Endpoint:   endpoint,
 	})
-
-	s.writeXMLResponse(w, response)
+	s.recordRedactionUsage(requestActor(r, "", "api"), redaction, endpoint, "upload:"+upload.UploadUUID)
 }
 
 // handleStreamUploadData обрабатывает потоковую отправку данных через SSE
@@ -2283,16 +3061,6 @@ func (s *Server) handleStreamUploadData(w http.ResponseWriter, r *http.Request,
 		Endpoint:   "/api/uploads/{uuid}/stream",
 	})
 
-	// Функция для экранирования XML
-	escapeXML := func(s string) string {
-		s = strings.ReplaceAll(s, "&", "&amp;")
-		s = strings.ReplaceAll(s, "<", "&lt;")
-		s = strings.ReplaceAll(s, ">", "&gt;")
-		s = strings.ReplaceAll(s, "\"", "&quot;")
-		s = strings.ReplaceAll(s, "'", "&apos;")
-		return s
-	}
-
 	// Получаем БД для этой выгрузки
 	uploadDB, err := s.getUploadDatabase(upload.UploadUUID)
 	if err != nil {
@@ -2301,75 +3069,60 @@ func (s *Server) handleStreamUploadData(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	s.streamUploadDataEvents(w, r, uploadDB, upload, dataType, catalogNames)
+}
+
+// streamUploadDataEvents отправляет данные выгрузки из db SSE событиями по мере чтения из БД:
+// элементы справочников читаются батчами, события сбрасываются клиенту каждые streamFlushEvery элементов
+func (s *Server) streamUploadDataEvents(w http.ResponseWriter, r *http.Request, db *database.DB, upload *database.Upload, dataType string, catalogNames []string) {
+	// Потоковое чтение прекращается при отключении клиента
+	ctx := r.Context()
+	sw := newXMLStreamWriter(w)
+
+	// Скрытые профилем пользователя поля не передаются; если профиль не получен, поток не начинается
+	redaction, err := s.requestRedaction(r)
+	if err != nil {
+		log.Printf("Ошибка получения профиля скрытия для потока выгрузки %s: %v", upload.UploadUUID, err)
+		sw.writeEventData(`{"type":"error","message":"Failed to resolve redaction profile"}`)
+		return
+	}
+	endpoint := strings.Replace(r.URL.Path, upload.UploadUUID, "{uuid}", 1)
+	s.recordRedactionUsage(requestActor(r, "", "api"), redaction, endpoint, "upload:"+upload.UploadUUID)
+
 	// Отправляем константы
 	if dataType == "constants" || dataType == "all" {
-		constants, err := uploadDB.GetConstantsByUpload(upload.ID)
+		constants, err := db.GetConstantsByUploadContext(ctx, upload.ID)
 		if err == nil {
 			for _, constant := range constants {
-				// Формируем XML для константы - включаем все поля из БД
-				dataXML := fmt.Sprintf(`<constant><id>%d</id><upload_id>%d</upload_id><name>%s</name><synonym>%s</synonym><type>%s</type><value>%s</value><created_at>%s</created_at></constant>`,
-					constant.ID, constant.UploadID, escapeXML(constant.Name), escapeXML(constant.Synonym),
-					escapeXML(constant.Type), escapeXML(constant.Value), constant.CreatedAt.Format(time.RFC3339))
-
-				item := DataItem{
-					Type:      "constant",
-					ID:        constant.ID,
-					Data:      dataXML,
-					CreatedAt: constant.CreatedAt,
+				if err := sw.writeEvent(newConstantDataItem(constant, redaction)); err != nil {
+					return
 				}
-
-				// Отправляем как XML
-				xmlData, _ := xml.Marshal(item)
-				fmt.Fprintf(w, "data: %s\n\n", string(xmlData))
-				flusher.Flush()
 			}
 		}
 	}
 
 	// Отправляем элементы справочников
 	if dataType == "catalogs" || dataType == "all" {
-		offset := 0
-		limit := 100
-
-		for {
-			items, _, err := uploadDB.GetCatalogItemsByUpload(upload.ID, catalogNames, offset, limit)
-			if err != nil || len(items) == 0 {
-				break
-			}
-
-			for _, itemData := range items {
-				// Формируем XML для элемента справочника
-				// Включаем все поля из БД: id, catalog_id, catalog_name, reference, code, name, attributes_xml, table_parts_xml, created_at
-				// attributes_xml и table_parts_xml уже содержат XML, вставляем их как есть (innerXML)
-				dataXML := fmt.Sprintf(`<catalog_item><id>%d</id><catalog_id>%d</catalog_id><catalog_name>%s</catalog_name><reference>%s</reference><code>%s</code><name>%s</name><attributes_xml>%s</attributes_xml><table_parts_xml>%s</table_parts_xml><created_at>%s</created_at></catalog_item>`,
-					itemData.ID, itemData.CatalogID, escapeXML(itemData.CatalogName),
-					escapeXML(itemData.Reference), escapeXML(itemData.Code), escapeXML(itemData.Name),
-					itemData.Attributes, itemData.TableParts, itemData.CreatedAt.Format(time.RFC3339))
-
-				dataItem := DataItem{
-					Type:      "catalog_item",
-					ID:        itemData.ID,
-					Data:      dataXML,
-					CreatedAt: itemData.CreatedAt,
+		err := db.StreamCatalogItemsContext(ctx, upload.ID, catalogNames, streamFlushEvery, func(items []*database.CatalogItem) error {
+			for _, item := range items {
+				if err := sw.writeEvent(s.newCatalogDataItem(r, item, redaction)); err != nil {
+					return err
 				}
-
-				// Отправляем как XML
-				xmlData, _ := xml.Marshal(dataItem)
-				fmt.Fprintf(w, "data: %s\n\n", string(xmlData))
-				flusher.Flush()
-			}
-
-			if len(items) < limit {
-				break
 			}
-
-			offset += limit
+			return nil
+		})
+		if err != nil {
+			log.Printf("Ошибка потоковой отправки элементов справочников выгрузки %s: %v", upload.UploadUUID, err)
 		}
 	}
 
+	// Клиент отключился - завершающее сообщение не отправляется
+	if ctx.Err() != nil {
+		return
+	}
+
 	// Отправляем завершающее сообщение
-	fmt.Fprintf(w, "data: {\"type\":\"complete\"}\n\n")
-	flusher.Flush()
+	sw.writeEventData(`{"type":"complete"}`)
 }
 
 // handleVerifyUpload обрабатывает проверку успешной передачи
@@ -2379,6 +3132,10 @@ func (s *Server) handleVerifyUpload(w http.ResponseWriter, r *http.Request, uplo
 		return
 	}
 
+	// Запросы к БД прерываются при отключении клиента и по таймауту DBQueryTimeout
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
 	var req VerifyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.writeJSONError(w, "Failed to parse request body", http.StatusBadRequest)
@@ -2394,21 +3151,21 @@ func (s *Server) handleVerifyUpload(w http.ResponseWriter, r *http.Request, uplo
 	// Получаем БД для этой выгрузки
 	uploadDB, err := s.getUploadDatabase(upload.UploadUUID)
 	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get upload database: %v", err), http.StatusInternalServerError)
+		s.writeAPIError(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
 		return
 	}
 
 	// Получаем все константы
-	constants, err := uploadDB.GetConstantsByUpload(upload.ID)
+	constants, err := uploadDB.GetConstantsByUploadContext(ctx, upload.ID)
 	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get constants: %v", err), http.StatusInternalServerError)
+		s.writeDBError(w, r, "Failed to get constants", err)
 		return
 	}
 
 	// Получаем все элементы справочников
-	catalogItems, _, err := uploadDB.GetCatalogItemsByUpload(upload.ID, nil, 0, 0)
+	catalogItems, _, err := uploadDB.GetCatalogItemsByUploadContext(ctx, upload.ID, nil, 0, 0)
 	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get catalog items: %v", err), http.StatusInternalServerError)
+		s.writeDBError(w, r, "Failed to get catalog items", err)
 		return
 	}
 
@@ -2457,270 +3214,87 @@ func (s *Server) handleVerifyUpload(w http.ResponseWriter, r *http.Request, uplo
 		Endpoint:   "/api/uploads/{uuid}/verify",
 	})
 
-	s.writeJSONResponse(w, response, http.StatusOK)
-}
-
-// handleGetUploadNormalized обрабатывает запрос детальной информации о выгрузке из нормализованной БД
-func (s *Server) handleGetUploadNormalized(w http.ResponseWriter, r *http.Request, upload *database.Upload) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Получаем детали выгрузки из нормализованной БД
-	_, catalogs, constants, err := s.normalizedDB.GetUploadDetails(upload.UploadUUID)
-	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get normalized upload details: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Получаем количество элементов для каждого справочника
-	itemCounts, err := s.normalizedDB.GetCatalogItemCountByCatalog(upload.ID)
-	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get catalog item counts: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	catalogInfos := make([]CatalogInfo, len(catalogs))
-	for i, catalog := range catalogs {
-		catalogInfos[i] = CatalogInfo{
-			ID:        catalog.ID,
-			Name:      catalog.Name,
-			Synonym:   catalog.Synonym,
-			ItemCount: itemCounts[catalog.ID],
-			CreatedAt: catalog.CreatedAt,
-		}
-	}
-
-	// Преобразуем константы в интерфейсы
-	constantData := make([]interface{}, len(constants))
-	for i, constant := range constants {
-		constantData[i] = map[string]interface{}{
-			"id":         constant.ID,
-			"name":       constant.Name,
-			"synonym":    constant.Synonym,
-			"type":       constant.Type,
-			"value":      constant.Value,
-			"created_at": constant.CreatedAt,
-		}
-	}
-
-	details := UploadDetails{
-		UploadUUID:     upload.UploadUUID,
-		StartedAt:      upload.StartedAt,
-		CompletedAt:    upload.CompletedAt,
-		Status:         upload.Status,
-		Version1C:      upload.Version1C,
-		ConfigName:     upload.ConfigName,
-		TotalConstants: upload.TotalConstants,
-		TotalCatalogs:  upload.TotalCatalogs,
-		TotalItems:     upload.TotalItems,
-		Catalogs:       catalogInfos,
-		Constants:      constantData,
-	}
-
-	s.log(LogEntry{
-		Timestamp:  time.Now(),
-		Level:      "INFO",
-		Message:    fmt.Sprintf("Normalized upload details requested for %s", upload.UploadUUID),
-		UploadUUID: upload.UploadUUID,
-		Endpoint:   "/api/normalized/uploads/{uuid}",
-	})
-
-	s.writeJSONResponse(w, details, http.StatusOK)
-}
-
-// handleGetUploadDataNormalized обрабатывает запрос данных выгрузки из нормализованной БД
-func (s *Server) handleGetUploadDataNormalized(w http.ResponseWriter, r *http.Request, upload *database.Upload) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Парсим query параметры
-	dataType := r.URL.Query().Get("type")
-	if dataType == "" {
-		dataType = "all"
-	}
-
-	catalogNamesStr := r.URL.Query().Get("catalog_names")
-	var catalogNames []string
-	if catalogNamesStr != "" {
-		catalogNames = strings.Split(catalogNamesStr, ",")
-		for i := range catalogNames {
-			catalogNames[i] = strings.TrimSpace(catalogNames[i])
-		}
-	}
-
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
-
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	if limit < 1 {
-		limit = 100
-	}
-	if limit > 1000 {
-		limit = 1000
-	}
-
-	offset := (page - 1) * limit
-
-	var responseItems []DataItem
-	var total int
-
-	// Функция для экранирования XML
-	escapeXML := func(s string) string {
-		s = strings.ReplaceAll(s, "&", "&amp;")
-		s = strings.ReplaceAll(s, "<", "&lt;")
-		s = strings.ReplaceAll(s, ">", "&gt;")
-		s = strings.ReplaceAll(s, "\"", "&quot;")
-		s = strings.ReplaceAll(s, "'", "&apos;")
-		return s
-	}
-
-	// Получаем данные в зависимости от типа из нормализованной БД
-	if dataType == "constants" {
-		constants, err := s.normalizedDB.GetConstantsByUpload(upload.ID)
-		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get constants: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		total = len(constants)
-
-		// Применяем пагинацию для констант
-		start := offset
-		end := offset + limit
-		if start > len(constants) {
-			start = len(constants)
-		}
-		if end > len(constants) {
-			end = len(constants)
-		}
-
-		for i := start; i < end; i++ {
-			constData := constants[i]
-			// Формируем XML для константы - включаем все поля из БД
-			dataXML := fmt.Sprintf(`<constant><id>%d</id><upload_id>%d</upload_id><name>%s</name><synonym>%s</synonym><type>%s</type><value>%s</value><created_at>%s</created_at></constant>`,
-				constData.ID, constData.UploadID, escapeXML(constData.Name), escapeXML(constData.Synonym),
-				escapeXML(constData.Type), escapeXML(constData.Value), constData.CreatedAt.Format(time.RFC3339))
-
-			responseItems = append(responseItems, DataItem{
-				Type:      "constant",
-				ID:        constData.ID,
-				Data:      dataXML,
-				CreatedAt: constData.CreatedAt,
-			})
-		}
-	} else if dataType == "catalogs" {
-		catalogItems, itemTotal, err := s.normalizedDB.GetCatalogItemsByUpload(upload.ID, catalogNames, offset, limit)
-		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get catalog items: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		total = itemTotal
-
-		for _, itemData := range catalogItems {
-			// Формируем XML для элемента справочника
-			// Включаем все поля из БД: id, catalog_id, catalog_name, reference, code, name, attributes_xml, table_parts_xml, created_at
-			// attributes_xml и table_parts_xml уже содержат XML, вставляем их как есть (innerXML)
-			dataXML := fmt.Sprintf(`<catalog_item><id>%d</id><catalog_id>%d</catalog_id><catalog_name>%s</catalog_name><reference>%s</reference><code>%s</code><name>%s</name><attributes_xml>%s</attributes_xml><table_parts_xml>%s</table_parts_xml><created_at>%s</created_at></catalog_item>`,
-				itemData.ID, itemData.CatalogID, escapeXML(itemData.CatalogName),
-				escapeXML(itemData.Reference), escapeXML(itemData.Code), escapeXML(itemData.Name),
-				itemData.Attributes, itemData.TableParts, itemData.CreatedAt.Format(time.RFC3339))
-
-			responseItems = append(responseItems, DataItem{
-				Type:      "catalog_item",
-				ID:        itemData.ID,
-				Data:      dataXML,
-				CreatedAt: itemData.CreatedAt,
-			})
-		}
-	} else { // dataType == "all"
-		// Для "all" сначала получаем все константы и элементы
-		constants, err := s.normalizedDB.GetConstantsByUpload(upload.ID)
-		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get constants: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		catalogItems, itemTotal, err := s.normalizedDB.GetCatalogItemsByUpload(upload.ID, catalogNames, 0, 0)
-		if err != nil {
-			s.writeJSONError(w, fmt.Sprintf("Failed to get catalog items: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		total = len(constants) + itemTotal
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
 
-		// Объединяем все элементы и применяем пагинацию
-		allItems := make([]DataItem, 0, total)
+// handleGetUploadNormalized обрабатывает запрос детальной информации о выгрузке из нормализованной БД
+func (s *Server) handleGetUploadNormalized(w http.ResponseWriter, r *http.Request, upload *database.Upload) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-		// Добавляем константы - включаем все поля из БД
-		for _, constant := range constants {
-			dataXML := fmt.Sprintf(`<constant><id>%d</id><upload_id>%d</upload_id><name>%s</name><synonym>%s</synonym><type>%s</type><value>%s</value><created_at>%s</created_at></constant>`,
-				constant.ID, constant.UploadID, escapeXML(constant.Name), escapeXML(constant.Synonym),
-				escapeXML(constant.Type), escapeXML(constant.Value), constant.CreatedAt.Format(time.RFC3339))
+	// Получаем детали выгрузки из нормализованной БД
+	_, catalogs, constants, err := s.normalizedDB.GetUploadDetails(upload.UploadUUID)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get normalized upload details: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-			allItems = append(allItems, DataItem{
-				Type:      "constant",
-				ID:        constant.ID,
-				Data:      dataXML,
-				CreatedAt: constant.CreatedAt,
-			})
-		}
+	// Получаем количество элементов для каждого справочника
+	itemCounts, err := s.normalizedDB.GetCatalogItemCountByCatalog(upload.ID)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get catalog item counts: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-		// Добавляем элементы справочников
-		for _, itemData := range catalogItems {
-			// Включаем все поля из БД: id, catalog_id, catalog_name, reference, code, name, attributes_xml, table_parts_xml, created_at
-			// attributes_xml и table_parts_xml уже содержат XML, вставляем их как есть (innerXML)
-			dataXML := fmt.Sprintf(`<catalog_item><id>%d</id><catalog_id>%d</catalog_id><catalog_name>%s</catalog_name><reference>%s</reference><code>%s</code><name>%s</name><attributes_xml>%s</attributes_xml><table_parts_xml>%s</table_parts_xml><created_at>%s</created_at></catalog_item>`,
-				itemData.ID, itemData.CatalogID, escapeXML(itemData.CatalogName),
-				escapeXML(itemData.Reference), escapeXML(itemData.Code), escapeXML(itemData.Name),
-				itemData.Attributes, itemData.TableParts, itemData.CreatedAt.Format(time.RFC3339))
-
-			allItems = append(allItems, DataItem{
-				Type:      "catalog_item",
-				ID:        itemData.ID,
-				Data:      dataXML,
-				CreatedAt: itemData.CreatedAt,
-			})
+	catalogInfos := make([]CatalogInfo, len(catalogs))
+	for i, catalog := range catalogs {
+		catalogInfos[i] = CatalogInfo{
+			ID:        catalog.ID,
+			Name:      catalog.Name,
+			Synonym:   catalog.Synonym,
+			ItemCount: itemCounts[catalog.ID],
+			CreatedAt: catalog.CreatedAt,
 		}
+	}
 
-		// Применяем пагинацию
-		start := offset
-		end := offset + limit
-		if start > len(allItems) {
-			start = len(allItems)
-		}
-		if end > len(allItems) {
-			end = len(allItems)
+	// Преобразуем константы в интерфейсы
+	constantData := make([]interface{}, len(constants))
+	for i, constant := range constants {
+		constantData[i] = map[string]interface{}{
+			"id":         constant.ID,
+			"name":       constant.Name,
+			"synonym":    constant.Synonym,
+			"type":       constant.Type,
+			"value":      constant.Value,
+			"created_at": constant.CreatedAt,
 		}
-
-		responseItems = allItems[start:end]
 	}
 
-	// Формируем XML ответ
-	response := DataResponse{
-		UploadUUID: upload.UploadUUID,
-		Type:       dataType,
-		Page:       page,
-		Limit:      limit,
-		Total:      total,
-		Items:      responseItems,
+	details := UploadDetails{
+		UploadUUID:     upload.UploadUUID,
+		StartedAt:      upload.StartedAt,
+		CompletedAt:    upload.CompletedAt,
+		Status:         upload.Status,
+		Version1C:      upload.Version1C,
+		ConfigName:     upload.ConfigName,
+		TotalConstants: upload.TotalConstants,
+		TotalCatalogs:  upload.TotalCatalogs,
+		TotalItems:     upload.TotalItems,
+		Catalogs:       catalogInfos,
+		Constants:      constantData,
 	}
 
 	s.log(LogEntry{
 		Timestamp:  time.Now(),
 		Level:      "INFO",
-		Message:    fmt.Sprintf("Normalized upload data requested for %s, type=%s, returned %d items", upload.UploadUUID, dataType, len(responseItems)),
+		Message:    fmt.Sprintf("Normalized upload details requested for %s", upload.UploadUUID),
 		UploadUUID: upload.UploadUUID,
-		Endpoint:   "/api/normalized/uploads/{uuid}/data",
+		Endpoint:   "/api/normalized/uploads/{uuid}",
 	})
 
-	s.writeXMLResponse(w, response)
+	s.writeJSONResponse(w, details, http.StatusOK)
+}
+
+// handleGetUploadDataNormalized обрабатывает запрос данных выгрузки из нормализованной БД
+func (s *Server) handleGetUploadDataNormalized(w http.ResponseWriter, r *http.Request, upload *database.Upload) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.writeUploadData(w, r, s.normalizedDB, upload, "Normalized upload data", "/api/normalized/uploads/{uuid}/data")
 }
 
 // handleStreamUploadDataNormalized обрабатывает потоковую отправку данных из нормализованной БД через SSE
@@ -2751,8 +3325,7 @@ func (s *Server) handleStreamUploadDataNormalized(w http.ResponseWriter, r *http
 	w.Header().Set("Connection", "keep-alive")
 	w.WriteHeader(http.StatusOK)
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
+	if _, ok := w.(http.Flusher); !ok {
 		s.writeJSONError(w, "Streaming not supported", http.StatusInternalServerError)
 		return
 	}
@@ -2765,85 +3338,7 @@ func (s *Server) handleStreamUploadDataNormalized(w http.ResponseWriter, r *http
 		Endpoint:   "/api/normalized/uploads/{uuid}/stream",
 	})
 
-	// Функция для экранирования XML
-	escapeXML := func(s string) string {
-		s = strings.ReplaceAll(s, "&", "&amp;")
-		s = strings.ReplaceAll(s, "<", "&lt;")
-		s = strings.ReplaceAll(s, ">", "&gt;")
-		s = strings.ReplaceAll(s, "\"", "&quot;")
-		s = strings.ReplaceAll(s, "'", "&apos;")
-		return s
-	}
-
-	// Отправляем константы из нормализованной БД
-	if dataType == "constants" || dataType == "all" {
-		constants, err := s.normalizedDB.GetConstantsByUpload(upload.ID)
-		if err == nil {
-			for _, constant := range constants {
-				// Формируем XML для константы - включаем все поля из БД
-				dataXML := fmt.Sprintf(`<constant><id>%d</id><upload_id>%d</upload_id><name>%s</name><synonym>%s</synonym><type>%s</type><value>%s</value><created_at>%s</created_at></constant>`,
-					constant.ID, constant.UploadID, escapeXML(constant.Name), escapeXML(constant.Synonym),
-					escapeXML(constant.Type), escapeXML(constant.Value), constant.CreatedAt.Format(time.RFC3339))
-
-				item := DataItem{
-					Type:      "constant",
-					ID:        constant.ID,
-					Data:      dataXML,
-					CreatedAt: constant.CreatedAt,
-				}
-
-				// Отправляем как XML
-				xmlData, _ := xml.Marshal(item)
-				fmt.Fprintf(w, "data: %s\n\n", string(xmlData))
-				flusher.Flush()
-			}
-		}
-	}
-
-	// Отправляем элементы справочников из нормализованной БД
-	if dataType == "catalogs" || dataType == "all" {
-		offset := 0
-		limit := 100
-
-		for {
-			items, _, err := s.normalizedDB.GetCatalogItemsByUpload(upload.ID, catalogNames, offset, limit)
-			if err != nil || len(items) == 0 {
-				break
-			}
-
-			for _, itemData := range items {
-				// Формируем XML для элемента справочника
-				// Включаем все поля из БД: id, catalog_id, catalog_name, reference, code, name, attributes_xml, table_parts_xml, created_at
-				// attributes_xml и table_parts_xml уже содержат XML, вставляем их как есть (innerXML)
-				dataXML := fmt.Sprintf(`<catalog_item><id>%d</id><catalog_id>%d</catalog_id><catalog_name>%s</catalog_name><reference>%s</reference><code>%s</code><name>%s</name><attributes_xml>%s</attributes_xml><table_parts_xml>%s</table_parts_xml><created_at>%s</created_at></catalog_item>`,
-					itemData.ID, itemData.CatalogID, escapeXML(itemData.CatalogName),
-					escapeXML(itemData.Reference), escapeXML(itemData.Code), escapeXML(itemData.Name),
-					itemData.Attributes, itemData.TableParts, itemData.CreatedAt.Format(time.RFC3339))
-
-				dataItem := DataItem{
-					Type:      "catalog_item",
-					ID:        itemData.ID,
-					Data:      dataXML,
-					CreatedAt: itemData.CreatedAt,
-				}
-
-				// Отправляем как XML
-				xmlData, _ := xml.Marshal(dataItem)
-				fmt.Fprintf(w, "data: %s\n\n", string(xmlData))
-				flusher.Flush()
-			}
-
-			if len(items) < limit {
-				break
-			}
-
-			offset += limit
-		}
-	}
-
-	// Отправляем завершающее сообщение
-	fmt.Fprintf(w, "data: {\"type\":\"complete\"}\n\n")
-	flusher.Flush()
+	s.streamUploadDataEvents(w, r, s.normalizedDB, upload, dataType, catalogNames)
 }
 
 // handleVerifyUploadNormalized обрабатывает проверку успешной передачи для нормализованной БД
@@ -2853,6 +3348,10 @@ func (s *Server) handleVerifyUploadNormalized(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Запросы к БД прерываются при отключении клиента и по таймауту DBQueryTimeout
+	ctx, cancel := s.dbContext(r)
+	defer cancel()
+
 	var req VerifyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.writeJSONError(w, "Failed to parse request body", http.StatusBadRequest)
@@ -2866,16 +3365,16 @@ func (s *Server) handleVerifyUploadNormalized(w http.ResponseWriter, r *http.Req
 	}
 
 	// Получаем все константы
-	constants, err := s.normalizedDB.GetConstantsByUpload(upload.ID)
+	constants, err := s.normalizedDB.GetConstantsByUploadContext(ctx, upload.ID)
 	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get constants: %v", err), http.StatusInternalServerError)
+		s.writeDBError(w, r, "Failed to get constants", err)
 		return
 	}
 
 	// Получаем все элементы справочников
-	catalogItems, _, err := s.normalizedDB.GetCatalogItemsByUpload(upload.ID, nil, 0, 0)
+	catalogItems, _, err := s.normalizedDB.GetCatalogItemsByUploadContext(ctx, upload.ID, nil, 0, 0)
 	if err != nil {
-		s.writeJSONError(w, fmt.Sprintf("Failed to get catalog items: %v", err), http.StatusInternalServerError)
+		s.writeDBError(w, r, "Failed to get catalog items", err)
 		return
 	}
 
@@ -2936,13 +3435,13 @@ func (s *Server) handleNormalizedHandshake(w http.ResponseWriter, r *http.Reques
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req HandshakeRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -2981,13 +3480,13 @@ func (s *Server) handleNormalizedMetadata(w http.ResponseWriter, r *http.Request
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req MetadataRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -3024,13 +3523,13 @@ func (s *Server) handleNormalizedConstant(w http.ResponseWriter, r *http.Request
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req ConstantRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -3075,13 +3574,13 @@ func (s *Server) handleNormalizedCatalogMeta(w http.ResponseWriter, r *http.Requ
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req CatalogMetaRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -3126,13 +3625,13 @@ func (s *Server) handleNormalizedCatalogItem(w http.ResponseWriter, r *http.Requ
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req CatalogItemRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -3184,13 +3683,13 @@ func (s *Server) handleNormalizedComplete(w http.ResponseWriter, r *http.Request
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req CompleteRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -3282,6 +3781,7 @@ func (s *Server) startNomenclatureProcessing(w http.ResponseWriter, r *http.Requ
 			// Не очищаем сразу, оставляем для просмотра итогов до следующего запуска
 			s.processorMutex.Unlock()
 		}()
+		defer s.recoverWorker("nomenclature_processor", nil)
 		if err := processor.ProcessAll(); err != nil {
 			log.Printf("Ошибка обработки номенклатуры: %v", err)
 		}
@@ -3468,6 +3968,7 @@ func (s *Server) handleNormalizeStart(w http.ResponseWriter, r *http.Request) {
 			config.CodeColumn,
 			config.NameColumn,
 		)
+		normalizerToUse.SetModelChain(s.workerConfigManager.GetModelChain(normalization.ModelChainTaskNormalization))
 		log.Printf("Создан временный normalizer для БД: %s (с WorkerConfigManager)", req.Database)
 	} else {
 		// Используем стандартный normalizer
@@ -3475,9 +3976,25 @@ func (s *Server) handleNormalizeStart(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Используется стандартный normalizer")
 	}
 
+	job, err := s.startBackgroundJob(r.Context(), jobKindNormalization, config.SourceTable)
+	if err != nil {
+		if tempDB != nil {
+			tempDB.Close()
+		}
+		s.normalizerMutex.Lock()
+		s.normalizerRunning = false
+		s.normalizerMutex.Unlock()
+		s.writeAPIError(w, "Failed to start normalization", err)
+		return
+	}
+	s.normalizerSessionID.Store(int64(job.ID))
+
 	// Запускаем нормализацию в горутине
 	go func() {
+		var jobErr error
 		defer func() {
+			s.finishBackgroundJob(job, jobErr)
+
 			// Закрываем временную БД если она была открыта
 			if tempDB != nil {
 				tempDB.Close()
@@ -3489,6 +4006,7 @@ func (s *Server) handleNormalizeStart(w http.ResponseWriter, r *http.Request) {
 			s.normalizerMutex.Unlock()
 			log.Println("Процесс нормализации завершен, флаг isRunning сброшен")
 		}()
+		defer s.recoverWorker(jobKindNormalization, job)
 
 		log.Println("Запуск процесса нормализации в горутине...")
 		s.normalizerEvents <- "Начало нормализации данных..."
@@ -3512,7 +4030,9 @@ func (s *Server) handleNormalizeStart(w http.ResponseWriter, r *http.Request) {
 			}
 		}()
 
-		if err := normalizerToUse.ProcessNormalization(); err != nil {
+		jobErr = normalizerToUse.ProcessNormalizationContext(job.ctx)
+		job.setCheckpoint(normalizerToUse.GetCheckpointStatus())
+		if err := jobErr; err != nil {
 			log.Printf("Ошибка нормализации данных: %v", err)
 			s.normalizerEvents <- fmt.Sprintf("Ошибка нормализации: %v", err)
 			s.normalizerMutex.Lock()
@@ -3546,6 +4066,12 @@ func (s *Server) handleNormalizeStart(w http.ResponseWriter, r *http.Request) {
 						dbToUse = tempDB
 					}
 
+					// Цепочка моделей классификации из конфигурации воркеров, подтвержденные решения применяются до AI
+					chain := normalization.NewChainedClassifier(classifier, s.workerConfigManager.GetModelChain(normalization.ModelChainTaskClassification)).
+						WithHistorical(s.historicalIndex(dbToUse))
+					classify := s.experimentClassify(classifier, chain.Classify)
+					kpvedStartedAt := time.Now()
+
 					// Получаем записи без КПВЭД классификации
 					rows, err := dbToUse.Query(`
 						SELECT id, normalized_name, category
@@ -3588,26 +4114,14 @@ func (s *Server) handleNormalizeStart(w http.ResponseWriter, r *http.Request) {
 							classified := 0
 							failed := 0
 							for i, record := range recordsToClassify {
-								// Классифицируем запись
-								result, err := classifier.Classify(record.NormalizedName, record.Category)
-								if err != nil {
+								// Классифицируем запись; ошибки попадают в очередь повторов
+								if err := s.classifyNormalizedItem(dbToUse, classify, record.ID, record.NormalizedName, record.Category); err != nil {
 									log.Printf("Ошибка классификации записи %d: %v", record.ID, err)
+									s.recordClassificationFailure(dbToUse, record.ID, record.NormalizedName, record.Category, err)
 									failed++
 									continue
 								}
-
-								// Обновляем запись с результатами классификации
-								_, err = dbToUse.Exec(`
-									UPDATE normalized_data
-									SET kpved_code = ?, kpved_name = ?, kpved_confidence = ?
-									WHERE id = ?
-								`, result.FinalCode, result.FinalName, result.FinalConfidence, record.ID)
-
-								if err != nil {
-									log.Printf("Ошибка обновления КПВЭД для записи %d: %v", record.ID, err)
-									failed++
-									continue
-								}
+								s.resolveClassificationRetry(dbToUse, record.ID)
 
 								classified++
 
@@ -3620,6 +4134,11 @@ func (s *Server) handleNormalizeStart(w http.ResponseWriter, r *http.Request) {
 							}
 
 							log.Printf("КПВЭД классификация завершена: классифицировано %d из %d записей (ошибок: %d)", classified, totalToClassify, failed)
+							for model, usage := range chain.Usage() {
+								log.Printf("КПВЭД модель %s: вызовов %d, принято %d, эскалаций %d, ошибок %d",
+									model, usage.Calls, usage.Accepted+usage.BestEffort, usage.LowConfidence, usage.Errors)
+							}
+							s.recordHistoricalRun(historicalRunNormalization, kpvedStartedAt, totalToClassify, chain)
 							s.normalizerEvents <- fmt.Sprintf("КПВЭД классификация завершена: %d/%d (ошибок: %d)", classified, totalToClassify, failed)
 						}
 					}
@@ -3675,7 +4194,7 @@ func (s *Server) handleNormalizationEvents(w http.ResponseWriter, r *http.Reques
 
 	for {
 		select {
-		case event := <-s.normalizerEvents:
+		case event := <-s.normalizerStream():
 			// Форматируем событие как JSON
 			eventJSON := fmt.Sprintf("{\"type\":\"log\",\"message\":%q,\"timestamp\":%q}",
 				event, time.Now().Format(time.RFC3339))
@@ -3715,6 +4234,8 @@ type NormalizationStatus struct {
 	KpvedClassified int      `json:"kpvedClassified,omitempty"` // количество классифицированных групп по КПВЭД
 	KpvedTotal      int      `json:"kpvedTotal,omitempty"`      // общее количество групп для КПВЭД
 	KpvedProgress   float64  `json:"kpvedProgress,omitempty"`   // процент классифицированных групп по КПВЭД
+	// Учет цепочки моделей AI нормализации по моделям
+	ModelUsage map[string]normalization.ModelUsage `json:"modelUsage,omitempty"`
 }
 
 // handleNormalizationStatus возвращает текущий статус нормализации
@@ -3825,6 +4346,7 @@ func (s *Server) handleNormalizationStatus(w http.ResponseWriter, r *http.Reques
 	}
 
 	status := NormalizationStatus{
+		ModelUsage:      s.normalizer.ModelChainUsage(),
 		IsRunning:       isRunning,
 		Progress:        progressPercent,
 		Processed:       processed,
@@ -3899,6 +4421,9 @@ func (s *Server) handleNormalizationStats(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Тяжелые агрегаты считаем через пул только для чтения
+	readDB := s.db.ReadOnly()
+
 	// Получаем статистику из normalized_data
 	// Статистика показывает количество исправленных элементов (каждая запись - это исправленный элемент
 	// с разложенными по колонкам/атрибутам размерами, брендами и т.д.)
@@ -3908,14 +4433,14 @@ func (s *Server) handleNormalizationStats(w http.ResponseWriter, r *http.Request
 	var categoryStats map[string]int = make(map[string]int)
 
 	// Считаем все исправленные элементы (записи в normalized_data)
-	err := s.db.QueryRow("SELECT COUNT(*) FROM normalized_data").Scan(&totalItems)
+	err := readDB.QueryRow("SELECT COUNT(*) FROM normalized_data").Scan(&totalItems)
 	if err != nil {
 		log.Printf("Ошибка получения количества исправленных элементов: %v", err)
 		totalItems = 0
 	}
 
 	// Считаем количество элементов, у которых есть извлеченные атрибуты (размеры, бренды и т.д.)
-	err = s.db.QueryRow(`
+	err = readDB.QueryRow(`
 		SELECT COUNT(DISTINCT normalized_item_id) 
 		FROM normalized_item_attributes
 	`).Scan(&totalItemsWithAttributes)
@@ -3926,13 +4451,13 @@ func (s *Server) handleNormalizationStats(w http.ResponseWriter, r *http.Request
 	}
 
 	// Получаем время последней нормализации
-	err = s.db.QueryRow("SELECT MAX(created_at) FROM normalized_data").Scan(&lastNormalizedAt)
+	err = readDB.QueryRow("SELECT MAX(created_at) FROM normalized_data").Scan(&lastNormalizedAt)
 	if err != nil {
 		log.Printf("Ошибка получения времени последней нормализации: %v", err)
 	}
 
 	// Получаем статистику по категориям (из поля category)
-	rows, err := s.db.Query(`
+	rows, err := readDB.Query(`
 		SELECT 
 			category,
 			COUNT(*) as count
@@ -3962,7 +4487,7 @@ func (s *Server) handleNormalizationStats(w http.ResponseWriter, r *http.Request
 	// Вычисляем количество объединенных элементов (дубликатов, которые были объединены)
 	// mergedItems = общее количество - количество уникальных групп по normalized_reference
 	var uniqueGroups int
-	err = s.db.QueryRow("SELECT COUNT(DISTINCT normalized_reference) FROM normalized_data").Scan(&uniqueGroups)
+	err = readDB.QueryRow("SELECT COUNT(DISTINCT normalized_reference) FROM normalized_data").Scan(&uniqueGroups)
 	if err != nil {
 		log.Printf("Ошибка получения количества уникальных групп: %v", err)
 		uniqueGroups = 0
@@ -4005,6 +4530,14 @@ func (s *Server) handleNormalizationGroups(w http.ResponseWriter, r *http.Reques
 	kpvedCode := query.Get("kpved_code")
 	includeAI := query.Get("include_ai") == "true"
 
+	// Фильтр по тегу проекта: группы с отмеченными записями или отмеченные целиком
+	projectID, _ := strconv.Atoi(query.Get("project_id"))
+	tagCondition, tagArgs, err := s.taggedRecordsCondition(projectID, query.Get("tag"))
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Значения по умолчанию
 	page := 1
 	limit := 20
@@ -4077,6 +4610,13 @@ func (s *Server) handleNormalizationGroups(w http.ResponseWriter, r *http.Reques
 		countArgs = append(countArgs, kpvedCode)
 	}
 
+	if tagCondition != "1=1" {
+		baseQuery += " AND " + tagCondition
+		countQuery += " AND " + tagCondition
+		args = append(args, tagArgs...)
+		countArgs = append(countArgs, tagArgs...)
+	}
+
 	// Группировка и сортировка для основного запроса
 	baseQuery += " GROUP BY normalized_name, normalized_reference, category"
 	baseQuery += " ORDER BY merged_count DESC, normalized_name ASC"
@@ -4088,7 +4628,7 @@ func (s *Server) handleNormalizationGroups(w http.ResponseWriter, r *http.Reques
 
 	// Получаем общее количество групп
 	var totalGroups int
-	err := s.db.QueryRow(countQuery, countArgs...).Scan(&totalGroups)
+	err = s.db.QueryRow(countQuery, countArgs...).Scan(&totalGroups)
 	if err != nil {
 		log.Printf("Ошибка получения количества групп: %v", err)
 		http.Error(w, "Failed to count groups", http.StatusInternalServerError)
@@ -4413,6 +4953,10 @@ func (s *Server) handleNormalizationExportGroup(w http.ResponseWriter, r *http.R
 	if format == "" {
 		format = "csv"
 	}
+	if format != "csv" && format != "json" {
+		http.Error(w, "Invalid format. Supported formats: csv, json", http.StatusBadRequest)
+		return
+	}
 
 	// Получаем данные группы
 	sqlQuery := `
@@ -4446,10 +4990,9 @@ func (s *Server) handleNormalizationExportGroup(w http.ResponseWriter, r *http.R
 		ProcessingLevel     *string   `json:"processing_level,omitempty"`
 	}
 
-	items := []ExportItem{}
-	for rows.Next() {
+	scanItem := func() (ExportItem, error) {
 		var item ExportItem
-		if err := rows.Scan(
+		err := rows.Scan(
 			&item.ID,
 			&item.SourceReference,
 			&item.SourceName,
@@ -4461,15 +5004,8 @@ func (s *Server) handleNormalizationExportGroup(w http.ResponseWriter, r *http.R
 			&item.AIConfidence,
 			&item.AIReasoning,
 			&item.ProcessingLevel,
-		); err != nil {
-			log.Printf("Ошибка сканирования записи для экспорта: %v", err)
-			continue
-		}
-		items = append(items, item)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Printf("Ошибка при итерации по записям: %v", err)
+		)
+		return item, err
 	}
 
 	// Формируем имя файла
@@ -4477,7 +5013,8 @@ func (s *Server) handleNormalizationExportGroup(w http.ResponseWriter, r *http.R
 	filename := fmt.Sprintf("group_%s_%s_%s.%s", normalizedName, category, timestamp, format)
 
 	if format == "csv" {
-		// Экспорт в CSV
+		// Экспорт в CSV: строки пишутся по мере чтения из БД и сбрасываются клиенту
+		// каждые streamFlushEvery строк, не накапливаясь в памяти
 		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
@@ -4486,6 +5023,7 @@ func (s *Server) handleNormalizationExportGroup(w http.ResponseWriter, r *http.R
 
 		writer := csv.NewWriter(w)
 		defer writer.Flush()
+		flusher, _ := w.(http.Flusher)
 
 		// Заголовки
 		headers := []string{
@@ -4496,7 +5034,14 @@ func (s *Server) handleNormalizationExportGroup(w http.ResponseWriter, r *http.R
 		writer.Write(headers)
 
 		// Данные
-		for _, item := range items {
+		written := 0
+		for rows.Next() {
+			item, err := scanItem()
+			if err != nil {
+				log.Printf("Ошибка сканирования записи для экспорта: %v", err)
+				continue
+			}
+
 			confidence := ""
 			if item.AIConfidence != nil {
 				confidence = fmt.Sprintf("%.2f", *item.AIConfidence)
@@ -4519,25 +5064,53 @@ func (s *Server) handleNormalizationExportGroup(w http.ResponseWriter, r *http.R
 				processingLevel,
 				item.CreatedAt.Format("2006-01-02 15:04:05"),
 			}
-			writer.Write(record)
+			if err := writer.Write(record); err != nil {
+				log.Printf("Ошибка записи экспорта группы: %v", err)
+				return
+			}
+
+			written++
+			if written%streamFlushEvery == 0 {
+				writer.Flush()
+				if flusher != nil {
+					flusher.Flush()
+				}
+			}
 		}
-	} else if format == "json" {
-		// Экспорт в JSON
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
-		exportData := map[string]interface{}{
-			"group_name":  normalizedName,
-			"category":    category,
-			"export_date": time.Now().Format(time.RFC3339),
-			"item_count":  len(items),
-			"items":       items,
+		if err := rows.Err(); err != nil {
+			log.Printf("Ошибка при итерации по записям: %v", err)
 		}
+		return
+	}
 
-		json.NewEncoder(w).Encode(exportData)
-	} else {
-		http.Error(w, "Invalid format. Supported formats: csv, json", http.StatusBadRequest)
+	items := []ExportItem{}
+	for rows.Next() {
+		item, err := scanItem()
+		if err != nil {
+			log.Printf("Ошибка сканирования записи для экспорта: %v", err)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Ошибка при итерации по записям: %v", err)
+	}
+
+	// Экспорт в JSON
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	exportData := map[string]interface{}{
+		"group_name":  normalizedName,
+		"category":    category,
+		"export_date": time.Now().Format(time.RFC3339),
+		"item_count":  len(items),
+		"items":       items,
 	}
+
+	json.NewEncoder(w).Encode(exportData)
 }
 
 // handleDatabaseInfo возвращает информацию о текущей базе данных
@@ -4623,6 +5196,24 @@ func (s *Server) handleDatabasesList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// 5. Базы данных проектов из сервисной БД, в том числе файлы, которых больше нет на диске
+	projectDatabases := make(map[string]*database.ProjectDatabase) // absPath -> база проекта
+	if s.serviceDB != nil {
+		registered, err := s.serviceDB.GetAllProjectDatabases()
+		if err != nil {
+			log.Printf("Ошибка получения баз данных проектов: %v", err)
+		}
+		for _, projectDB := range registered {
+			if projectDB.FilePath == "" {
+				continue
+			}
+			allFiles = append(allFiles, projectDB.FilePath)
+			if absPath, err := filepath.Abs(projectDB.FilePath); err == nil {
+				projectDatabases[filepath.Clean(absPath)] = projectDB
+			}
+		}
+	}
+
 	// Убираем дубликаты по абсолютному пути
 	fileMap := make(map[string]string) // absPath -> original path
 	uniqueFiles := []string{}
@@ -4651,26 +5242,49 @@ func (s *Server) handleDatabasesList(w http.ResponseWriter, r *http.Request) {
 	for _, path := range fileMap {
 		uniqueFiles = append(uniqueFiles, path)
 	}
+	sort.Strings(uniqueFiles)
 
 	databases := []map[string]interface{}{}
 	s.dbMutex.RLock()
 	currentDB := s.currentDBPath
 	s.dbMutex.RUnlock()
 
+	// Сведения о файлах берутся из кеша и пересчитываются только для измененных файлов
+	listed := make(map[string]bool, len(uniqueFiles))
+	cachedCount := 0
 	for _, file := range uniqueFiles {
+		listed[file] = true
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			absPath = file
+		}
+		projectDB := projectDatabases[filepath.Clean(absPath)]
+
 		fileInfo, err := os.Stat(file)
 		if err != nil {
+			// Файл базы проекта удален или перемещен
+			if projectDB == nil {
+				continue
+			}
+			databases = append(databases, map[string]interface{}{
+				"name":                file,
+				"path":                file,
+				"is_current":          file == currentDB,
+				"type":                "unknown",
+				"project_database_id": projectDB.ID,
+				"client_project_id":   projectDB.ClientProjectID,
+				"health":              databaseHealth(nil, true),
+			})
 			continue
 		}
 
 		isCurrent := file == currentDB
 
-		// Определяем тип базы данных
-		dbType, err := database.DetectDatabaseType(file)
-		if err != nil {
-			log.Printf("Ошибка определения типа БД %s: %v", file, err)
-			dbType = "unknown"
+		entry, cached := s.databaseStats.get(file, fileInfo)
+		if cached {
+			cachedCount++
 		}
+		dbType := entry.dbType
 
 		// Получаем метаданные из serviceDB
 		var metadata *database.DatabaseMetadata
@@ -4686,12 +5300,23 @@ func (s *Server) handleDatabasesList(w http.ResponseWriter, r *http.Request) {
 		}
 
 		dbInfo := map[string]interface{}{
-			"name":        file,
-			"path":        file,
-			"size":        fileInfo.Size(),
-			"modified_at": fileInfo.ModTime(),
-			"is_current":  isCurrent,
-			"type":        dbType,
+			"name":              file,
+			"path":              file,
+			"size":              fileInfo.Size(),
+			"modified_at":       fileInfo.ModTime(),
+			"is_current":        isCurrent,
+			"type":              dbType,
+			"table_count":       entry.tableCount,
+			"total_rows":        entry.totalRows,
+			"health":            databaseHealth(entry, false),
+			"stats_computed_at": entry.computedAt,
+		}
+		if entry.stats != nil {
+			dbInfo["stats"] = entry.stats
+		}
+		if projectDB != nil {
+			dbInfo["project_database_id"] = projectDB.ID
+			dbInfo["client_project_id"] = projectDB.ClientProjectID
 		}
 
 		// Добавляем информацию из метаданных
@@ -4701,24 +5326,15 @@ func (s *Server) handleDatabasesList(w http.ResponseWriter, r *http.Request) {
 			dbInfo["description"] = metadata.Description
 		}
 
-		// Получаем базовую статистику (количество таблиц)
-		tableStats, err := database.GetTableStats(file)
-		if err == nil {
-			var totalRows int64
-			for _, stat := range tableStats {
-				totalRows += stat.RowCount
-			}
-			dbInfo["table_count"] = len(tableStats)
-			dbInfo["total_rows"] = totalRows
-		}
-
 		databases = append(databases, dbInfo)
 	}
+	s.databaseStats.retain(listed)
 
 	response := map[string]interface{}{
-		"databases": databases,
-		"current":   currentDB,
-		"total":     len(databases),
+		"databases":    databases,
+		"current":      currentDB,
+		"total":        len(databases),
+		"stats_cached": cachedCount,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -4726,6 +5342,7 @@ func (s *Server) handleDatabasesList(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleDatabaseAnalytics возвращает детальную аналитику базы данных
+// Без указания пути возвращает временной ряд дневных сводок по выгрузкам (см. handleUploadRollupSeries)
 func (s *Server) handleDatabaseAnalytics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -4743,8 +5360,9 @@ func (s *Server) handleDatabaseAnalytics(w http.ResponseWriter, r *http.Request)
 		dbPath = strings.TrimSuffix(dbPath, "/")
 	}
 
-	if dbPath == "" {
-		s.writeJSONError(w, "Database path is required", http.StatusBadRequest)
+	// Без пути возвращаем временной ряд дневных сводок по выгрузкам
+	if dbPath == "" || dbPath == "/api/databases/analytics" {
+		s.handleUploadRollupSeries(w, r)
 		return
 	}
 
@@ -4907,86 +5525,6 @@ func (s *Server) handleFindDatabase(w http.ResponseWriter, r *http.Request) {
 	s.writeJSONResponse(w, response, http.StatusOK)
 }
 
-// handleDatabaseSwitch переключает текущую базу данных
-func (s *Server) handleDatabaseSwitch(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Проверяем, что нормализация не запущена
-	s.normalizerMutex.RLock()
-	isRunning := s.normalizerRunning
-	s.normalizerMutex.RUnlock()
-
-	if isRunning {
-		http.Error(w, "Cannot switch database while normalization is running", http.StatusBadRequest)
-		return
-	}
-
-	// Читаем запрос
-	var request struct {
-		Path string `json:"path"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	if request.Path == "" {
-		http.Error(w, "Database path is required", http.StatusBadRequest)
-		return
-	}
-
-	// Проверяем, что файл существует
-	if _, err := os.Stat(request.Path); os.IsNotExist(err) {
-		http.Error(w, "Database file not found", http.StatusNotFound)
-		return
-	}
-
-	s.dbMutex.Lock()
-	defer s.dbMutex.Unlock()
-
-	// Закрываем текущую БД
-	if err := s.db.Close(); err != nil {
-		log.Printf("Ошибка закрытия текущей БД: %v", err)
-		http.Error(w, "Failed to close current database", http.StatusInternalServerError)
-		return
-	}
-
-	// Открываем новую БД
-	newDB, err := database.NewDB(request.Path)
-	if err != nil {
-		log.Printf("Ошибка открытия новой БД: %v", err)
-		// Пытаемся восстановить старую БД
-		oldDB, restoreErr := database.NewDB(s.currentDBPath)
-		if restoreErr != nil {
-			log.Printf("КРИТИЧЕСКАЯ ОШИБКА: не удалось восстановить старую БД: %v", restoreErr)
-			http.Error(w, "Failed to open new database and restore failed", http.StatusInternalServerError)
-			return
-		}
-		s.db = oldDB
-		http.Error(w, "Failed to open new database", http.StatusInternalServerError)
-		return
-	}
-
-	// Успешно переключились
-	s.db = newDB
-	s.currentDBPath = request.Path
-
-	log.Printf("База данных переключена на: %s", request.Path)
-
-	response := map[string]interface{}{
-		"status":  "success",
-		"message": "Database switched successfully",
-		"path":    request.Path,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
 // getNomenclatureStatus возвращает статус обработки номенклатуры
 func (s *Server) getNomenclatureStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -5799,6 +6337,14 @@ func (s *Server) handleClientRoutes(w http.ResponseWriter, r *http.Request) {
 	// Обработка вложенных маршрутов
 	if len(parts) > 1 {
 		switch parts[1] {
+		case "language":
+			// GET/PUT /api/clients/{id}/language
+			s.handleClientLanguage(w, r, clientID)
+			return
+		case "ingest-limits":
+			// GET/PUT/DELETE /api/clients/{id}/ingest-limits
+			s.handleClientIngestLimits(w, r, clientID)
+			return
 		case "projects":
 			if len(parts) == 2 {
 				// GET/POST /api/clients/{id}/projects
@@ -5819,6 +6365,17 @@ func (s *Server) handleClientRoutes(w http.ResponseWriter, r *http.Request) {
 					return
 				}
 
+				// Архивный проект доступен только на чтение
+				if s.rejectArchivedProjectWrite(w, r, projectID, parts[3:]) {
+					return
+				}
+
+				if len(parts) == 4 && isProjectLifecycleAction(parts[3]) {
+					// POST /api/clients/{id}/projects/{projectId}/archive|restore|clone
+					s.handleProjectLifecycle(w, r, clientID, projectID, parts[3])
+					return
+				}
+
 				if len(parts) == 3 {
 					// GET/PUT/DELETE /api/clients/{id}/projects/{projectId}
 					switch r.Method {
@@ -5846,6 +6403,114 @@ func (s *Server) handleClientRoutes(w http.ResponseWriter, r *http.Request) {
 					return
 				}
 
+				if parts[3] == "dictionaries" && len(parts) <= 5 {
+					// GET/POST /api/clients/{id}/projects/{projectId}/dictionaries
+					// GET/PUT/DELETE /api/clients/{id}/projects/{projectId}/dictionaries/{entryId}
+					entryID := 0
+					if len(parts) == 5 {
+						if entryID, err = strconv.Atoi(parts[4]); err != nil {
+							http.Error(w, "Invalid dictionary entry ID", http.StatusBadRequest)
+							return
+						}
+					}
+					s.handleProjectDictionaries(w, r, clientID, projectID, entryID)
+					return
+				}
+
+				if parts[3] == "prompt-attributes" && len(parts) <= 5 {
+					// GET/PUT /api/clients/{id}/projects/{projectId}/prompt-attributes
+					// POST /api/clients/{id}/projects/{projectId}/prompt-attributes/evaluate
+					action := ""
+					if len(parts) == 5 {
+						action = parts[4]
+					}
+					s.handleProjectPromptAttributes(w, r, clientID, projectID, action)
+					return
+				}
+
+				if parts[3] == "similarity" && len(parts) <= 6 {
+					// GET/PUT/DELETE /api/clients/{id}/projects/{projectId}/similarity
+					// GET/POST /api/clients/{id}/projects/{projectId}/similarity/pairs
+					// DELETE /api/clients/{id}/projects/{projectId}/similarity/pairs/{pairId}
+					// POST /api/clients/{id}/projects/{projectId}/similarity/calibrate
+					s.handleProjectSimilarity(w, r, clientID, projectID, parts[4:])
+					return
+				}
+
+				if parts[3] == "encryption" && len(parts) <= 5 {
+					// GET/PUT /api/clients/{id}/projects/{projectId}/encryption
+					// POST /api/clients/{id}/projects/{projectId}/encryption/rewrap
+					action := ""
+					if len(parts) == 5 {
+						action = parts[4]
+					}
+					s.handleProjectEncryption(w, r, clientID, projectID, action)
+					return
+				}
+
+				if parts[3] == "comments" && len(parts) <= 5 {
+					// GET/POST /api/clients/{id}/projects/{projectId}/comments
+					// GET/PUT/DELETE /api/clients/{id}/projects/{projectId}/comments/{commentId}
+					commentID := 0
+					if len(parts) == 5 {
+						if commentID, err = strconv.Atoi(parts[4]); err != nil {
+							http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+							return
+						}
+					}
+					s.handleProjectComments(w, r, clientID, projectID, commentID)
+					return
+				}
+
+				if parts[3] == "tags" && len(parts) <= 5 {
+					// GET/POST /api/clients/{id}/projects/{projectId}/tags
+					// GET /api/clients/{id}/projects/{projectId}/tags/counts
+					// DELETE /api/clients/{id}/projects/{projectId}/tags/{tagId}
+					action := ""
+					if len(parts) == 5 {
+						action = parts[4]
+					}
+					s.handleProjectTags(w, r, clientID, projectID, action)
+					return
+				}
+
+				if parts[3] == "review-tasks" && len(parts) <= 5 {
+					// GET/POST /api/clients/{id}/projects/{projectId}/review-tasks
+					// GET /api/clients/{id}/projects/{projectId}/review-tasks/stats
+					// GET/PATCH/DELETE /api/clients/{id}/projects/{projectId}/review-tasks/{taskId}
+					action := ""
+					if len(parts) == 5 {
+						action = parts[4]
+					}
+					s.handleProjectReviewTasks(w, r, clientID, projectID, action)
+					return
+				}
+
+				// GET /api/clients/{id}/projects/{projectId}/constants/drift
+				if parts[3] == "constants" && len(parts) == 5 && parts[4] == "drift" {
+					s.handleProjectConstantDrift(w, r, clientID, projectID)
+					return
+				}
+
+				if parts[3] == "mappings" && len(parts) <= 5 {
+					// GET /api/clients/{id}/projects/{projectId}/mappings/conflicts
+					if len(parts) == 5 && parts[4] == "conflicts" {
+						s.handleProjectCodeMappingConflicts(w, r, clientID, projectID)
+						return
+					}
+					// GET/POST /api/clients/{id}/projects/{projectId}/mappings
+					// GET/DELETE /api/clients/{id}/projects/{projectId}/mappings/{fileId}
+					fileID := 0
+					if len(parts) == 5 {
+						if fileID, err = strconv.Atoi(parts[4]); err != nil {
+							http.Error(w, "Invalid mapping file ID", http.StatusBadRequest)
+							return
+						}
+					}
+					s.handleProjectCodeMappings(w, r, clientID, projectID, fileID)
+					return
+				}
+
 				// Обработка /api/clients/{id}/projects/{projectId}/databases
 				if parts[3] == "databases" {
 					if len(parts) == 4 {
@@ -5987,7 +6652,7 @@ func (s *Server) handleGetClient(w http.ResponseWriter, r *http.Request, clientI
 		return
 	}
 
-	projects, err := s.serviceDB.GetClientProjects(clientID)
+	projects, err := s.serviceDB.ListClientProjects(clientID, false)
 	if err != nil {
 		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -6063,7 +6728,7 @@ func (s *Server) handleUpdateClient(w http.ResponseWriter, r *http.Request, clie
 
 	client, err := s.serviceDB.GetClient(clientID)
 	if err != nil {
-		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		s.writeAPIError(w, err.Error(), err)
 		return
 	}
 
@@ -6081,8 +6746,10 @@ func (s *Server) handleDeleteClient(w http.ResponseWriter, r *http.Request, clie
 }
 
 // handleGetClientProjects получает проекты клиента
+// Архивные проекты возвращаются только с include_archived=true
 func (s *Server) handleGetClientProjects(w http.ResponseWriter, r *http.Request, clientID int) {
-	projects, err := s.serviceDB.GetClientProjects(clientID)
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+	projects, err := s.serviceDB.ListClientProjects(clientID, includeArchived)
 	if err != nil {
 		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -6202,6 +6869,7 @@ func (s *Server) handleGetClientProject(w http.ResponseWriter, r *http.Request,
 			"total_benchmarks":    totalBenchmarks,
 			"approved_benchmarks": approvedBenchmarks,
 			"avg_quality_score":   avgQuality,
+			"tag_counts":          s.projectTagCounts(projectID),
 		},
 	}
 
@@ -6250,7 +6918,7 @@ func (s *Server) handleUpdateClientProject(w http.ResponseWriter, r *http.Reques
 	// Получаем обновленный проект
 	updated, err := s.serviceDB.GetClientProject(projectID)
 	if err != nil {
-		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		s.writeAPIError(w, err.Error(), err)
 		return
 	}
 
@@ -6488,7 +7156,7 @@ func (s *Server) handleGetProjectDatabase(w http.ResponseWriter, r *http.Request
 
 	database, err := s.serviceDB.GetProjectDatabase(dbID)
 	if err != nil {
-		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		s.writeAPIError(w, err.Error(), err)
 		return
 	}
 
@@ -6532,7 +7200,7 @@ func (s *Server) handleUpdateProjectDatabase(w http.ResponseWriter, r *http.Requ
 
 	database, err := s.serviceDB.GetProjectDatabase(dbID)
 	if err != nil {
-		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		s.writeAPIError(w, err.Error(), err)
 		return
 	}
 
@@ -6554,7 +7222,7 @@ func (s *Server) handleUpdateProjectDatabase(w http.ResponseWriter, r *http.Requ
 
 	updatedDatabase, err := s.serviceDB.GetProjectDatabase(dbID)
 	if err != nil {
-		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		s.writeAPIError(w, err.Error(), err)
 		return
 	}
 
@@ -6576,7 +7244,7 @@ func (s *Server) handleDeleteProjectDatabase(w http.ResponseWriter, r *http.Requ
 
 	database, err := s.serviceDB.GetProjectDatabase(dbID)
 	if err != nil {
-		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		s.writeAPIError(w, err.Error(), err)
 		return
 	}
 
@@ -6658,18 +7326,40 @@ func (s *Server) handleStartClientNormalization(w http.ResponseWriter, r *http.R
 	// Передаем workerConfigManager для получения правильной модели
 	clientNormalizer := normalization.NewClientNormalizerWithConfig(clientID, projectID, sourceDB, s.serviceDB, s.normalizerEvents, s.workerConfigManager)
 
+	job, err := s.startBackgroundJob(r.Context(), jobKindClientNormalization, fmt.Sprintf("project %d", projectID))
+	if err != nil {
+		sourceDB.Close()
+		s.writeAPIError(w, "Failed to start normalization", err)
+		return
+	}
+	s.normalizerSessionID.Store(int64(job.ID))
+
 	// Запускаем нормализацию в отдельной горутине
 	s.normalizerRunning = true
+	var unregisterClients []func()
+	for _, client := range clientNormalizer.AIClients() {
+		unregisterClients = append(unregisterClients, s.registerAIClient(client, fmt.Sprintf("client_normalization:%d", projectID)))
+	}
 	go func() {
+		var jobErr error
 		defer func() {
+			s.finishBackgroundJob(job, jobErr)
+			for _, unregister := range unregisterClients {
+				unregister()
+			}
 			s.normalizerMutex.Lock()
 			s.normalizerRunning = false
 			s.normalizerMutex.Unlock()
 			sourceDB.Close() // Закрываем БД после завершения
 			log.Printf("Normalization completed for project %d", projectID)
 		}()
+		defer s.recoverWorker(jobKindClientNormalization, job)
 
-		_, err := clientNormalizer.ProcessWithClientBenchmarks(items)
+		result, err := clientNormalizer.ProcessWithClientBenchmarksContext(job.ctx, items)
+		if result != nil {
+			job.setCheckpoint(result.CheckpointStatus())
+		}
+		jobErr = err
 		if err != nil {
 			select {
 			case s.normalizerEvents <- fmt.Sprintf("Ошибка нормализации: %v", err):
@@ -6748,7 +7438,7 @@ func (s *Server) handleQualityStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Используем db, так как записи сохраняются туда (консистентно с handleMonitoringMetrics)
-	stats, err := s.db.GetQualityStats()
+	stats, err := s.db.ReadOnly().GetQualityStats()
 	if err != nil {
 		log.Printf("Error getting quality stats: %v", err)
 		s.writeJSONError(w, fmt.Sprintf("Failed to get quality stats: %v", err), http.StatusInternalServerError)
@@ -6777,7 +7467,7 @@ func (s *Server) handleMonitoringMetrics(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Получаем статистику качества из БД (используем db, так как записи сохраняются туда)
-	qualityStatsMap, err := s.db.GetQualityStats()
+	qualityStatsMap, err := s.db.ReadOnly().GetQualityStats()
 	if err != nil {
 		log.Printf("Error getting quality stats: %v", err)
 		qualityStatsMap = make(map[string]interface{})
@@ -6909,6 +7599,12 @@ func (s *Server) handleMonitoringMetrics(w http.ResponseWriter, r *http.Request)
 	// Добавляем статус Checkpoint
 	summary["checkpoint"] = s.GetCheckpointStatus()
 
+	// Добавляем утилизацию пулов соединений БД
+	summary["database_pools"] = s.GetDatabasePoolStats()
+
+	// Добавляем метрики кэша БД выгрузок
+	summary["upload_db_cache"] = s.uploadDBs.Stats()
+
 	s.writeJSONResponse(w, summary, http.StatusOK)
 }
 
@@ -6933,6 +7629,19 @@ func (s *Server) handleMonitoringCache(w http.ResponseWriter, r *http.Request) {
 		"memory_usage_kb": float64(cacheStats.MemoryUsageB) / 1024.0,
 	}
 
+	// Кеши справочных запросов к БД (выгрузки, маппинг справочник -> таблица)
+	lookups := make(map[string]interface{})
+	for _, stats := range database.GetLookupCacheStats() {
+		lookups[stats.Name] = map[string]interface{}{
+			"hits":         stats.Hits,
+			"misses":       stats.Misses,
+			"hit_rate_pct": stats.HitRate * 100.0,
+			"size":         stats.Size,
+			"capacity":     stats.Capacity,
+		}
+	}
+	response["lookups"] = lookups
+
 	s.writeJSONResponse(w, response, http.StatusOK)
 }
 
@@ -6975,6 +7684,7 @@ func (s *Server) handleMonitoringAI(w http.ResponseWriter, r *http.Request) {
 		"errors":         errors,
 		"avg_latency_ms": avgLatencyMs,
 		"cache_hit_rate": cacheHitRate,
+		"budget":         nomenclature.SharedAIScheduler().Stats(),
 	}
 
 	s.writeJSONResponse(w, stats, http.StatusOK)
@@ -7067,6 +7777,10 @@ func (s *Server) handleMonitoringEvents(w http.ResponseWriter, r *http.Request)
 	fmt.Fprintf(w, "data: %s\n\n", "{\"type\":\"connected\",\"message\":\"Connected to monitoring events\"}")
 	flusher.Flush()
 
+	// Подписываемся на события (например, изменение конфигурации воркеров)
+	events, unsubscribe := s.subscribeMonitoringEvents()
+	defer unsubscribe()
+
 	// Создаем тикер для периодической отправки метрик
 	metricsTicker := time.NewTicker(5 * time.Second)
 	defer metricsTicker.Stop()
@@ -7103,6 +7817,13 @@ func (s *Server) handleMonitoringEvents(w http.ResponseWriter, r *http.Request)
 				}
 			}
 
+		case event := <-events:
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", string(event)); err != nil {
+				log.Printf("Ошибка отправки SSE события: %v", err)
+				return
+			}
+			flusher.Flush()
+
 		case <-heartbeatTicker.C:
 			// Отправляем heartbeat для поддержания соединения
 			if _, err := fmt.Fprintf(w, ": heartbeat\n\n"); err != nil {
@@ -7335,88 +8056,6 @@ func (s *Server) handleNormalizationColumns(w http.ResponseWriter, r *http.Reque
 	s.writeJSONResponse(w, columns, http.StatusOK)
 }
 
-// handleKpvedHierarchy возвращает иерархию КПВЭД классификатора
-func (s *Server) handleKpvedHierarchy(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Получаем параметры
-	parentCode := r.URL.Query().Get("parent")
-	level := r.URL.Query().Get("level")
-	// Используем сервисную БД для классификатора КПВЭД
-	db := s.serviceDB.GetDB()
-
-	// Строим запрос
-	query := "SELECT code, name, parent_code, level FROM kpved_classifier WHERE 1=1"
-	args := []interface{}{}
-
-	if parentCode != "" {
-		query += " AND parent_code = ?"
-		args = append(args, parentCode)
-	} else if level != "" {
-		// Если указан уровень, но нет родителя - показываем этот уровень
-		query += " AND level = ?"
-		levelInt, _ := strconv.Atoi(level)
-		args = append(args, levelInt)
-	} else {
-		// По умолчанию показываем верхний уровень (секции A-Z, level = 1)
-		// Секции имеют parent_code = NULL или parent_code = ''
-		query += " AND level = 1 AND (parent_code IS NULL OR parent_code = '')"
-	}
-
-	query += " ORDER BY code"
-
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		log.Printf("Error querying kpved hierarchy: %v", err)
-		s.writeJSONError(w, "Failed to fetch KPVED hierarchy", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	nodes := []map[string]interface{}{}
-	for rows.Next() {
-		var code, name string
-		var parentCode sql.NullString
-		var level int
-
-		if err := rows.Scan(&code, &name, &parentCode, &level); err != nil {
-			log.Printf("Error scanning kpved row: %v", err)
-			continue
-		}
-
-		// Проверяем, есть ли дочерние узлы
-		var hasChildren bool
-		childQuery := "SELECT COUNT(*) FROM kpved_classifier WHERE parent_code = ?"
-		var childCount int
-		if err := db.QueryRow(childQuery, code).Scan(&childCount); err == nil {
-			hasChildren = childCount > 0
-		}
-
-		node := map[string]interface{}{
-			"code":         code,
-			"name":         name,
-			"level":        level,
-			"has_children": hasChildren,
-		}
-		if parentCode.Valid {
-			node["parent_code"] = parentCode.String
-		}
-
-		nodes = append(nodes, node)
-	}
-
-	// Формируем ответ в формате, ожидаемом фронтендом
-	response := map[string]interface{}{
-		"nodes": nodes,
-		"total": len(nodes),
-	}
-
-	s.writeJSONResponse(w, response, http.StatusOK)
-}
-
 // handleKpvedSearch выполняет поиск по КПВЭД классификатору
 func (s *Server) handleKpvedSearch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -7581,6 +8220,7 @@ func (s *Server) handleKpvedLoad(w http.ResponseWriter, r *http.Request) {
 		s.writeJSONError(w, fmt.Sprintf("Failed to load KPVED: %v", err), http.StatusInternalServerError)
 		return
 	}
+	s.kpvedTree.invalidate()
 
 	// Получаем статистику после загрузки
 	var totalCodes int
@@ -7717,13 +8357,23 @@ func (s *Server) handleKpvedReclassify(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
+		// Калибруем уверенность, отклоненные политикой результаты не сохраняем
+		evaluation := s.calibrator.Evaluate(model, 0, result.KpvedConfidence)
+		if evaluation.Decision == database.ConfidenceDecisionReject {
+			log.Printf("Classification of '%s' rejected by confidence policy (%.2f)", normalizedName, evaluation.Confidence)
+			failed++
+			continue
+		}
+
 		// Обновляем все записи в этой группе
 		updateQuery := `
 			UPDATE normalized_data
-			SET kpved_code = ?, kpved_name = ?, kpved_confidence = ?
+			SET kpved_code = ?, kpved_name = ?, kpved_confidence = ?,
+			    kpved_raw_confidence = ?, kpved_model = ?, confidence_decision = ?
 			WHERE normalized_name = ? AND category = ?
 		`
-		_, err = s.db.Exec(updateQuery, result.KpvedCode, result.KpvedName, result.KpvedConfidence, normalizedName, category)
+		_, err = s.db.Exec(updateQuery, result.KpvedCode, result.KpvedName, evaluation.Confidence,
+			result.KpvedConfidence, evaluation.Model, evaluation.Decision, normalizedName, category)
 		if err != nil {
 			log.Printf("Failed to update group '%s': %v", normalizedName, err)
 			failed++
@@ -7762,10 +8412,7 @@ func (s *Server) handleKpvedClassifyHierarchical(w http.ResponseWriter, r *http.
 	}
 
 	// Читаем тело запроса
-	var req struct {
-		NormalizedName string `json:"normalized_name"`
-		Category       string `json:"category"`
-	}
+	var req client.KpvedClassifyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -7850,6 +8497,13 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 		req.Limit = 10 // По умолчанию 10 групп
 	}
 
+	s.reclassifyKpvedHierarchical(w, req.Limit, 0, false)
+}
+
+// reclassifyKpvedHierarchical классифицирует группы без кода КПВЭД и пишет сводку в w.
+// Новый запуск (resumeRunID = 0) сохраняет список групп в журнал запусков, продолжение
+// классифицирует необработанные группы запуска resumeRunID, с retryFailed - и группы с ошибкой
+func (s *Server) reclassifyKpvedHierarchical(w http.ResponseWriter, limit, resumeRunID int, retryFailed bool) {
 	// Получаем API ключ и модель из WorkerConfigManager
 	apiKey, model, err := s.workerConfigManager.GetModelAndAPIKey()
 	if err != nil {
@@ -7881,7 +8535,7 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 		log.Printf("[KPVED] Error checking kpved_classifier table: %v", err)
 	} else if !kpvedTableExists {
 		log.Printf("[KPVED] ERROR: Table kpved_classifier does not exist in service DB!")
-		http.Error(w, "KPVED classifier table not found", http.StatusInternalServerError)
+		s.writeJSONError(w, "KPVED classifier table not found", http.StatusServiceUnavailable)
 		return
 	}
 
@@ -7910,20 +8564,24 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 		LIMIT ?
 	`
 
-	limitValue := req.Limit
+	limitValue := limit
 	if limitValue == 0 {
 		limitValue = 1000000 // Большое число для "все"
 	}
 
-	log.Printf("[KPVED] Querying groups without KPVED classification (limit: %d, sorted by merged_count DESC)...", limitValue)
-	// ВАЖНО: normalized_data находится в основной БД (s.db), а не в normalizedDB
-	rows, err := s.db.Query(query, limitValue)
-	if err != nil {
-		log.Printf("[KPVED] Error querying groups: %v", err)
-		http.Error(w, "Failed to query groups", http.StatusInternalServerError)
-		return
+	// Продолжение запуска берет группы из журнала запусков
+	var rows *sql.Rows
+	if resumeRunID == 0 {
+		log.Printf("[KPVED] Querying groups without KPVED classification (limit: %d, sorted by merged_count DESC)...", limitValue)
+		// ВАЖНО: normalized_data находится в основной БД (s.db), а не в normalizedDB
+		rows, err = s.db.Query(query, limitValue)
+		if err != nil {
+			log.Printf("[KPVED] Error querying groups: %v", err)
+			http.Error(w, "Failed to query groups", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
 	}
-	defer rows.Close()
 
 	// Создаем AI клиент и иерархический классификатор
 	log.Printf("[KPVED] Creating hierarchical classifier with API key (length: %d) and model: %s", len(apiKey), model)
@@ -7951,6 +8609,7 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 	// Это важно, так как rate limiter работает на уровне экземпляра AIClient
 	aiClient := nomenclature.NewAIClient(apiKey, model)
 	log.Printf("[KPVED] Created AI client instance (rate limiter: 1 req/sec, burst: 5)")
+	defer s.registerAIClient(aiClient, "kpved_reclassify_hierarchical")()
 	
 	hierarchicalClassifier, err := normalization.NewHierarchicalClassifier(s.serviceDB, aiClient)
 	if err != nil {
@@ -7961,6 +8620,13 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 	}
 	log.Printf("[KPVED] Hierarchical classifier created successfully (will be shared by all workers)")
 
+	// Цепочка моделей: дешевая модель первой, эскалация на более сильную при низкой уверенности или ошибке
+	// Подтвержденные решения применяются до обращения к AI
+	chain := normalization.NewChainedClassifier(hierarchicalClassifier, s.workerConfigManager.GetModelChain(normalization.ModelChainTaskClassification)).
+		WithHistorical(s.historicalIndex(s.db))
+	classify := s.experimentClassify(hierarchicalClassifier, chain.Classify)
+	reclassifyStartedAt := time.Now()
+
 	// Получаем статистику дерева КПВЭД
 	cacheStats := hierarchicalClassifier.GetCacheStats()
 	log.Printf("[KPVED] Hierarchical classifier created successfully. Cache stats: %+v", cacheStats)
@@ -7981,8 +8647,8 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 		log.Printf("[KPVED] Total groups without KPVED: %d (limit: %d)", totalGroupsWithoutKpved, limitValue)
 	}
 
-	// Проверяем на пустой результат
-	if totalGroupsWithoutKpved == 0 {
+	// Проверяем на пустой результат; группы продолжаемого запуска, уже получившие код, отмечаются ниже
+	if totalGroupsWithoutKpved == 0 && resumeRunID == 0 {
 		log.Printf("[KPVED] WARNING: No groups found without KPVED classification!")
 		// Проверяем, есть ли вообще группы с KPVED
 		var groupsWithKpved int
@@ -8024,7 +8690,7 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 	}
 
 	// Функция для retry UPDATE запросов с экспоненциальной задержкой и таймаутом
-	retryUpdate := func(query string, args ...interface{}) (sql.Result, error) {
+	retryUpdate := func(exec func(ctx context.Context) (sql.Result, error)) (sql.Result, error) {
 		maxRetries := 5
 		baseDelay := 50 * time.Millisecond
 		queryTimeout := 10 * time.Second // Таймаут для каждого запроса
@@ -8034,7 +8700,7 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 			ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
 
 			// Выполняем запрос с таймаутом
-			result, err := s.db.GetDB().ExecContext(ctx, query, args...)
+			result, err := exec(ctx)
 			cancel() // Освобождаем ресурсы context сразу после использования
 
 			if err == nil {
@@ -8069,34 +8735,63 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 		return nil, fmt.Errorf("failed after %d retries", maxRetries)
 	}
 
-	// Собираем все задачи в слайс
+	// Собираем все задачи в слайс. Новый запуск сохраняет группы в журнал запусков с отметкой pending:
+	// после перезапуска сервера запуск продолжается с групп, которые не получили код
 	var tasks []classificationTask
-	index := 0
-	for rows.Next() {
-		var normalizedName, category string
-		var mergedCount int
-		if err := rows.Scan(&normalizedName, &category, &mergedCount); err != nil {
-			log.Printf("[KPVED] Error scanning row: %v", err)
-			continue
+	runID := resumeRunID
+	if resumeRunID == 0 {
+		index := 0
+		for rows.Next() {
+			var normalizedName, category string
+			var mergedCount int
+			if err := rows.Scan(&normalizedName, &category, &mergedCount); err != nil {
+				log.Printf("[KPVED] Error scanning row: %v", err)
+				continue
+			}
+			tasks = append(tasks, classificationTask{
+				normalizedName: normalizedName,
+				category:       category,
+				mergedCount:    mergedCount,
+				index:          index,
+			})
+			index++
 		}
-		tasks = append(tasks, classificationTask{
-			normalizedName: normalizedName,
-			category:       category,
-			mergedCount:    mergedCount,
-			index:          index,
-		})
-		index++
-	}
 
-	if err := rows.Err(); err != nil {
-		log.Printf("[KPVED] Error iterating rows: %v", err)
-		http.Error(w, "Failed to read groups from database", http.StatusInternalServerError)
-		return
+		if err := rows.Err(); err != nil {
+			log.Printf("[KPVED] Error iterating rows: %v", err)
+			http.Error(w, "Failed to read groups from database", http.StatusInternalServerError)
+			return
+		}
+
+		if len(tasks) > 0 {
+			runID, err = s.db.CreateKpvedRun(model, limit, kpvedRunItems(tasks))
+			if err != nil {
+				log.Printf("[KPVED] Error saving classification run: %v", err)
+				http.Error(w, "Failed to save classification run", http.StatusInternalServerError)
+				return
+			}
+			log.Printf("[KPVED] Classification run %d started for %d groups", runID, len(tasks))
+		}
+	} else {
+		items, err := s.db.ResumeKpvedRun(resumeRunID, retryFailed)
+		if err != nil {
+			log.Printf("[KPVED] Error resuming classification run %d: %v", resumeRunID, err)
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		tasks = kpvedRunTasks(items)
+		log.Printf("[KPVED] Classification run %d resumed for %d pending groups", resumeRunID, len(tasks))
 	}
 
 	if len(tasks) == 0 {
 		log.Printf("[KPVED] No tasks to process")
+		if runID > 0 {
+			if err := s.db.FinishKpvedRun(runID, database.KpvedRunCompleted); err != nil {
+				log.Printf("[KPVED] Error finishing classification run %d: %v", runID, err)
+			}
+		}
 		response := map[string]interface{}{
+			"run_id":         runID,
 			"classified":     0,
 			"failed":         0,
 			"total_duration": 0,
@@ -8182,189 +8877,226 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 	s.kpvedWorkersStopped = false
 	s.kpvedWorkersStopMutex.Unlock()
 
-	for i := 0; i < maxWorkers; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for task := range taskChan {
-				// Проверяем флаг остановки перед обработкой задачи
-				s.kpvedWorkersStopMutex.RLock()
-				stopped := s.kpvedWorkersStopped
-				s.kpvedWorkersStopMutex.RUnlock()
-
-				if stopped {
-					log.Printf("[KPVED Worker %d] Stopped by user, skipping task %d: '%s'", workerID, task.index, task.normalizedName)
-					// Удаляем задачу из отслеживания
-					s.kpvedCurrentTasksMutex.Lock()
-					delete(s.kpvedCurrentTasks, workerID)
-					s.kpvedCurrentTasksMutex.Unlock()
-					// Отправляем результат с ошибкой остановки
-					resultChan <- classificationResult{
-						task: task,
-						err:  fmt.Errorf("worker stopped by user"),
-					}
-					continue
-				}
-
-				// Обновляем текущую задачу для этого воркера
+	// Паника при обработке задачи не останавливает классификацию: задача считается неудачной,
+	// воркер перезапускается и продолжает разбирать очередь
+	var runWorker func(workerID int)
+	runWorker = func(workerID int) {
+		defer wg.Done()
+		defer s.recoverTask("kpved_worker", func() map[string]interface{} {
+			return s.kpvedWorkerCrashDetails(workerID)
+		}, func() {
+			if task := s.takeKpvedCurrentTask(workerID); task != nil {
+				resultChan <- classificationResult{task: *task, err: fmt.Errorf("worker panic while classifying task")}
+			}
+			wg.Add(1)
+			go runWorker(workerID)
+		})
+		for task := range taskChan {
+			// Проверяем флаг остановки перед обработкой задачи
+			s.kpvedWorkersStopMutex.RLock()
+			stopped := s.kpvedWorkersStopped
+			s.kpvedWorkersStopMutex.RUnlock()
+
+			if stopped {
+				log.Printf("[KPVED Worker %d] Stopped by user, skipping task %d: '%s'", workerID, task.index, task.normalizedName)
+				// Удаляем задачу из отслеживания
 				s.kpvedCurrentTasksMutex.Lock()
-				s.kpvedCurrentTasks[workerID] = &task
+				delete(s.kpvedCurrentTasks, workerID)
 				s.kpvedCurrentTasksMutex.Unlock()
-
-				// Логируем каждую задачу для отслеживания параллелизма (только каждую 100-ю для уменьшения логов)
-				if task.index%100 == 0 {
-					log.Printf("[KPVED Worker %d] Processing task %d: '%s' in category '%s' (merged_count: %d)",
-						workerID, task.index, task.normalizedName, task.category, task.mergedCount)
+				// Отправляем результат с ошибкой остановки
+				resultChan <- classificationResult{
+					task: task,
+					err:  errKpvedWorkerStopped,
 				}
+				continue
+			}
 
-				// Классифицируем с иерархическим подходом
-				// Логируем только каждую 10-ю задачу для уменьшения объема логов
-				if task.index%10 == 0 {
-					log.Printf("[KPVED Worker %d] Starting classification for '%s' (category: '%s')", workerID, task.normalizedName, task.category)
-				}
+			// Обновляем текущую задачу для этого воркера
+			s.kpvedCurrentTasksMutex.Lock()
+			s.kpvedCurrentTasks[workerID] = &task
+			s.kpvedCurrentTasksMutex.Unlock()
+
+			// Логируем каждую задачу для отслеживания параллелизма (только каждую 100-ю для уменьшения логов)
+			if task.index%100 == 0 {
+				log.Printf("[KPVED Worker %d] Processing task %d: '%s' in category '%s' (merged_count: %d)",
+					workerID, task.index, task.normalizedName, task.category, task.mergedCount)
+			}
+
+			// Классифицируем с иерархическим подходом
+			// Логируем только каждую 10-ю задачу для уменьшения объема логов
+			if task.index%10 == 0 {
+				log.Printf("[KPVED Worker %d] Starting classification for '%s' (category: '%s')", workerID, task.normalizedName, task.category)
+			}
 
-				result, err := hierarchicalClassifier.Classify(task.normalizedName, task.category)
+			result, err := classify(task.normalizedName, task.category)
 
+			if err != nil {
+				// Проверяем тип ошибки
+				errStr := err.Error()
+				isRateLimit := strings.Contains(errStr, "rate limit") ||
+					strings.Contains(errStr, "too many requests") ||
+					strings.Contains(errStr, "429") ||
+					strings.Contains(errStr, "quota exceeded") ||
+					strings.Contains(errStr, "exceeded the maximum number of parallel requests")
+
+				isCircuitBreakerOpen := strings.Contains(errStr, "circuit breaker is open")
+
+				// Если circuit breaker открыт, ждем пока он закроется
+				if isCircuitBreakerOpen {
+					log.Printf("[KPVED Worker %d] Circuit breaker is open, waiting for recovery (task: '%s')...", workerID, task.normalizedName)
+					// Ждем 5 секунд перед повторной попыткой
+					time.Sleep(5 * time.Second)
+					// Пытаемся еще раз
+					retryResult, retryErr := classify(task.normalizedName, task.category)
+					if retryErr == nil {
+						// Успешно после retry - используем результат
+						result = retryResult
+						err = nil
+					} else {
+						errStr = retryErr.Error()
+						isCircuitBreakerOpen = strings.Contains(errStr, "circuit breaker is open")
+						if isCircuitBreakerOpen {
+							log.Printf("[KPVED Worker %d] Circuit breaker still open after retry, waiting 10 more seconds...", workerID)
+							time.Sleep(10 * time.Second)
+							// Последняя попытка
+							finalResult, finalErr := classify(task.normalizedName, task.category)
+							if finalErr == nil {
+								// Успешно после последней попытки
+								result = finalResult
+								err = nil
+							} else {
+								// Все попытки неудачны
+								err = finalErr
+							}
+						} else {
+							// Другая ошибка после retry
+							err = retryErr
+						}
+					}
+				}
+				
+				// Если после всех retry все еще есть ошибка, обрабатываем ее
 				if err != nil {
-					// Проверяем тип ошибки
-					errStr := err.Error()
-					isRateLimit := strings.Contains(errStr, "rate limit") ||
+					// Обновляем errStr после возможных retry
+					errStr = err.Error()
+					isRateLimit = strings.Contains(errStr, "rate limit") ||
 						strings.Contains(errStr, "too many requests") ||
 						strings.Contains(errStr, "429") ||
 						strings.Contains(errStr, "quota exceeded") ||
 						strings.Contains(errStr, "exceeded the maximum number of parallel requests")
 
-					isCircuitBreakerOpen := strings.Contains(errStr, "circuit breaker is open")
-
-					// Если circuit breaker открыт, ждем пока он закроется
-					if isCircuitBreakerOpen {
-						log.Printf("[KPVED Worker %d] Circuit breaker is open, waiting for recovery (task: '%s')...", workerID, task.normalizedName)
-						// Ждем 5 секунд перед повторной попыткой
-						time.Sleep(5 * time.Second)
-						// Пытаемся еще раз
-						retryResult, retryErr := hierarchicalClassifier.Classify(task.normalizedName, task.category)
-						if retryErr == nil {
-							// Успешно после retry - используем результат
-							result = retryResult
-							err = nil
-						} else {
-							errStr = retryErr.Error()
-							isCircuitBreakerOpen = strings.Contains(errStr, "circuit breaker is open")
-							if isCircuitBreakerOpen {
-								log.Printf("[KPVED Worker %d] Circuit breaker still open after retry, waiting 10 more seconds...", workerID)
-								time.Sleep(10 * time.Second)
-								// Последняя попытка
-								finalResult, finalErr := hierarchicalClassifier.Classify(task.normalizedName, task.category)
-								if finalErr == nil {
-									// Успешно после последней попытки
-									result = finalResult
-									err = nil
-								} else {
-									// Все попытки неудачны
-									err = finalErr
-								}
-							} else {
-								// Другая ошибка после retry
-								err = retryErr
-							}
-						}
+					// Если это rate limit или превышение параллельных запросов, делаем паузу
+					if isRateLimit {
+						log.Printf("[KPVED Worker %d] Rate limit detected, pausing for 3 seconds before retry...", workerID)
+						time.Sleep(3 * time.Second)
 					}
 					
-					// Если после всех retry все еще есть ошибка, обрабатываем ее
-					if err != nil {
-						// Обновляем errStr после возможных retry
-						errStr = err.Error()
-						isRateLimit = strings.Contains(errStr, "rate limit") ||
-							strings.Contains(errStr, "too many requests") ||
-							strings.Contains(errStr, "429") ||
-							strings.Contains(errStr, "quota exceeded") ||
-							strings.Contains(errStr, "exceeded the maximum number of parallel requests")
-
-						// Если это rate limit или превышение параллельных запросов, делаем паузу
-						if isRateLimit {
-							log.Printf("[KPVED Worker %d] Rate limit detected, pausing for 3 seconds before retry...", workerID)
-							time.Sleep(3 * time.Second)
-						}
-						
-						// Добавляем небольшую задержку между запросами для предотвращения перегрузки API
-						// Это особенно важно при использовании 1 воркера
-						time.Sleep(100 * time.Millisecond)
-
-						// Удаляем задачу из отслеживания при ошибке
-						s.kpvedCurrentTasksMutex.Lock()
-						delete(s.kpvedCurrentTasks, workerID)
-						s.kpvedCurrentTasksMutex.Unlock()
-
-						// Детальное логирование ошибки
-						log.Printf("[KPVED Worker %d] ERROR classifying '%s' (category: '%s', merged_count: %d): %v",
-							workerID, task.normalizedName, task.category, task.mergedCount, err)
-						// Пытаемся извлечь более детальную информацию об ошибке
-						if isRateLimit {
-							log.Printf("[KPVED Worker %d]   -> Rate limit error - Arliai API limit reached, paused and will retry", workerID)
-						} else if strings.Contains(errStr, "ai call failed") {
-							log.Printf("[KPVED Worker %d]   -> AI call failed - check API key, network connection, or rate limits", workerID)
-						} else if strings.Contains(errStr, "no candidates found") {
-							log.Printf("[KPVED Worker %d]   -> No candidates found in KPVED tree - check classifier data", workerID)
-						} else if strings.Contains(errStr, "json unmarshal") {
-							log.Printf("[KPVED Worker %d]   -> JSON parsing error - AI response format issue", workerID)
-						} else if strings.Contains(errStr, "timeout") {
-							log.Printf("[KPVED Worker %d]   -> Timeout error - AI service may be slow", workerID)
-						}
+					// Добавляем небольшую задержку между запросами для предотвращения перегрузки API
+					// Это особенно важно при использовании 1 воркера
+					time.Sleep(100 * time.Millisecond)
 
-						resultChan <- classificationResult{
-							task: task,
-							err:  err,
-						}
-						continue
-					}
-				} // конец первого if err != nil
-
-				// Обновляем все записи в этой группе с retry логикой
-				// ВАЖНО: normalized_data находится в основной БД (s.db), а не в normalizedDB
-				updateQuery := `
-					UPDATE normalized_data
-					SET kpved_code = ?, kpved_name = ?, kpved_confidence = ?
-					WHERE normalized_name = ? AND category = ?
-				`
-				updateResult, err := retryUpdate(updateQuery, result.FinalCode, result.FinalName, result.FinalConfidence, task.normalizedName, task.category)
-				if err != nil {
-					// Удаляем задачу из отслеживания при ошибке обновления
+					// Удаляем задачу из отслеживания при ошибке
 					s.kpvedCurrentTasksMutex.Lock()
 					delete(s.kpvedCurrentTasks, workerID)
 					s.kpvedCurrentTasksMutex.Unlock()
 
-					log.Printf("[KPVED Worker %d] Failed to update group '%s' (category: '%s') after retries: %v", workerID, task.normalizedName, task.category, err)
+					// Детальное логирование ошибки
+					log.Printf("[KPVED Worker %d] ERROR classifying '%s' (category: '%s', merged_count: %d): %v",
+						workerID, task.normalizedName, task.category, task.mergedCount, err)
+					// Пытаемся извлечь более детальную информацию об ошибке
+					if isRateLimit {
+						log.Printf("[KPVED Worker %d]   -> Rate limit error - Arliai API limit reached, paused and will retry", workerID)
+					} else if strings.Contains(errStr, "ai call failed") {
+						log.Printf("[KPVED Worker %d]   -> AI call failed - check API key, network connection, or rate limits", workerID)
+					} else if strings.Contains(errStr, "no candidates found") {
+						log.Printf("[KPVED Worker %d]   -> No candidates found in KPVED tree - check classifier data", workerID)
+					} else if strings.Contains(errStr, "json unmarshal") {
+						log.Printf("[KPVED Worker %d]   -> JSON parsing error - AI response format issue", workerID)
+					} else if strings.Contains(errStr, "timeout") {
+						log.Printf("[KPVED Worker %d]   -> Timeout error - AI service may be slow", workerID)
+					}
+
 					resultChan <- classificationResult{
 						task: task,
 						err:  err,
 					}
 					continue
 				}
+			} // конец первого if err != nil
 
-				rowsAffected, _ := updateResult.RowsAffected()
-				if rowsAffected == 0 {
-					log.Printf("[KPVED Worker %d] WARNING: Update query affected 0 rows for group '%s' (category: '%s')", workerID, task.normalizedName, task.category)
-				} else {
-					log.Printf("[KPVED Worker %d] Updated %d rows for group '%s' (category: '%s') -> KPVED: %s (%s, confidence: %.2f)",
-						workerID, rowsAffected, task.normalizedName, task.category, result.FinalCode, result.FinalName, result.FinalConfidence)
+			// Калибруем уверенность: отклоненный политикой результат считается ошибкой классификации
+			evaluation := s.calibrator.Evaluate(result.Model, 0, result.FinalConfidence)
+			if evaluation.Decision == database.ConfidenceDecisionReject {
+				s.kpvedCurrentTasksMutex.Lock()
+				delete(s.kpvedCurrentTasks, workerID)
+				s.kpvedCurrentTasksMutex.Unlock()
+
+				log.Printf("[KPVED Worker %d] Result for '%s' rejected by confidence policy (%.2f < %.2f)",
+					workerID, task.normalizedName, evaluation.Confidence, evaluation.Policy.ReviewThreshold)
+				resultChan <- classificationResult{
+					task: task,
+					err:  fmt.Errorf("rejected by confidence policy: calibrated confidence %.2f", evaluation.Confidence),
 				}
+				continue
+			}
 
-				// Удаляем задачу из отслеживания после успешного завершения
+			// Обновляем все записи в этой группе с retry логикой; код и отметка группы в журнале запуска
+			// записываются одной транзакцией
+			// ВАЖНО: normalized_data находится в основной БД (s.db), а не в normalizedDB
+			updateResult, err := retryUpdate(func(ctx context.Context) (sql.Result, error) {
+				return s.db.ApplyKpvedRunResult(ctx, runID, database.KpvedRunResult{
+					NormalizedName:     task.normalizedName,
+					Category:           task.category,
+					Code:               result.FinalCode,
+					Name:               result.FinalName,
+					Confidence:         evaluation.Confidence,
+					RawConfidence:      result.FinalConfidence,
+					Model:              evaluation.Model,
+					ConfidenceDecision: evaluation.Decision,
+				})
+			})
+			if err != nil {
+				// Удаляем задачу из отслеживания при ошибке обновления
 				s.kpvedCurrentTasksMutex.Lock()
 				delete(s.kpvedCurrentTasks, workerID)
 				s.kpvedCurrentTasksMutex.Unlock()
 
+				log.Printf("[KPVED Worker %d] Failed to update group '%s' (category: '%s') after retries: %v", workerID, task.normalizedName, task.category, err)
 				resultChan <- classificationResult{
-					task:         task,
-					result:       result,
-					rowsAffected: rowsAffected,
+					task: task,
+					err:  err,
 				}
-				
-				// Добавляем задержку после успешной классификации для предотвращения перегрузки API
-				// Это особенно важно при использовании 1 воркера, чтобы не превысить rate limits
-				time.Sleep(200 * time.Millisecond)
-			} // конец for task := range taskChan
-		}(i) // конец go func(workerID int)
+				continue
+			}
+
+			rowsAffected, _ := updateResult.RowsAffected()
+			if rowsAffected == 0 {
+				log.Printf("[KPVED Worker %d] WARNING: Update query affected 0 rows for group '%s' (category: '%s')", workerID, task.normalizedName, task.category)
+			} else {
+				log.Printf("[KPVED Worker %d] Updated %d rows for group '%s' (category: '%s') -> KPVED: %s (%s, confidence: %.2f)",
+					workerID, rowsAffected, task.normalizedName, task.category, result.FinalCode, result.FinalName, result.FinalConfidence)
+			}
+
+			// Удаляем задачу из отслеживания после успешного завершения
+			s.kpvedCurrentTasksMutex.Lock()
+			delete(s.kpvedCurrentTasks, workerID)
+			s.kpvedCurrentTasksMutex.Unlock()
+
+			resultChan <- classificationResult{
+				task:         task,
+				result:       result,
+				rowsAffected: rowsAffected,
+			}
+			
+			// Добавляем задержку после успешной классификации для предотвращения перегрузки API
+			// Это особенно важно при использовании 1 воркера, чтобы не превысить rate limits
+			time.Sleep(200 * time.Millisecond)
+		} // конец for task := range taskChan
+	} // конец runWorker
+
+	for i := 0; i < maxWorkers; i++ {
+		wg.Add(1)
+		go runWorker(i)
 	}
 
 	// Отправляем задачи в канал в отдельной горутине
@@ -8424,6 +9156,13 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 				}
 			}
 
+			// Группы, пропущенные после остановки воркеров, остаются в очереди запуска
+			if !errors.Is(res.err, errKpvedWorkerStopped) {
+				if err := s.db.MarkKpvedRunItemFailed(runID, res.task.normalizedName, res.task.category, errorMsg); err != nil {
+					log.Printf("[KPVED] Error marking group '%s' failed in run %d: %v", res.task.normalizedName, runID, err)
+				}
+			}
+
 			// Добавляем детальную информацию об ошибке в результаты
 			results = append(results, map[string]interface{}{
 				"normalized_name": res.task.normalizedName,
@@ -8446,6 +9185,8 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 				"steps":            len(res.result.Steps),
 				"duration_ms":      res.result.TotalDuration,
 				"ai_calls":         res.result.AICallsCount,
+				"model":            res.result.Model,
+				"model_chain":      res.result.ModelChain,
 			})
 
 			// Промежуточное обновление статуса каждые 20 групп для отображения прогресса на фронтенде
@@ -8489,11 +9230,28 @@ func (s *Server) handleKpvedReclassifyHierarchical(w http.ResponseWriter, r *htt
 		"results":        results,
 		"message":        message,
 		"total_groups":   len(tasks),
+		"model_chain":    chain.Steps(),
+		"model_usage":    chain.Usage(), // Учет по моделям с учетом эскалаций
+		"historical":     chain.HistoricalUsage(),
 	}
+	s.recordHistoricalRun(historicalRunReclassify, reclassifyStartedAt, len(tasks), chain)
 
 	log.Printf("[KPVED] Hierarchical reclassification completed: %d classified, %d failed out of %d total, avg %dms/item",
 		classified, failed, len(tasks), avgDuration)
 
+	// Запуск, остановленный пользователем, можно продолжить с пропущенных групп
+	runStatus := database.KpvedRunCompleted
+	s.kpvedWorkersStopMutex.RLock()
+	if s.kpvedWorkersStopped {
+		runStatus = database.KpvedRunStopped
+	}
+	s.kpvedWorkersStopMutex.RUnlock()
+	if err := s.db.FinishKpvedRun(runID, runStatus); err != nil {
+		log.Printf("[KPVED] Error finishing classification run %d: %v", runID, err)
+	}
+	response["run_id"] = runID
+	response["run_status"] = runStatus
+
 	// Очищаем отслеживание задач после завершения всей классификации
 	s.kpvedCurrentTasksMutex.Lock()
 	s.kpvedCurrentTasks = make(map[int]*classificationTask)
@@ -8861,10 +9619,21 @@ func (s *Server) handleQualityAnalysis(w http.ResponseWriter, r *http.Request, u
 		return
 	}
 
+	job, err := s.startBackgroundJob(r.Context(), jobKindQualityAnalysis, uploadUUID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to start quality analysis", err)
+		return
+	}
+
 	// Запускаем анализ в фоне
 	go func() {
+		var err error
+		defer func() { s.finishBackgroundJob(job, err) }()
+		defer s.recoverWorker(jobKindQualityAnalysis, job)
+
 		log.Printf("Starting quality analysis for upload %s (ID: %d, Database: %d)", uploadUUID, upload.ID, databaseID)
-		if err := s.qualityAnalyzer.AnalyzeUpload(upload.ID, databaseID); err != nil {
+		err = s.uploadQualityAnalyzer(s.db, upload).AnalyzeUpload(upload.ID, databaseID)
+		if err != nil {
 			log.Printf("Quality analysis failed for upload %s: %v", uploadUUID, err)
 		} else {
 			log.Printf("Quality analysis completed for upload %s", uploadUUID)
@@ -8989,6 +9758,12 @@ func (s *Server) handleQualityDashboard(w http.ResponseWriter, r *http.Request,
 		TopIssues:       topIssues,
 		MetricsByEntity: metricsByEntity,
 	}
+	// Теги проекта, которому принадлежит база
+	if s.serviceDB != nil {
+		if dbInfo, err := s.serviceDB.GetProjectDatabase(databaseID); err == nil && dbInfo != nil {
+			dashboard.TagCounts = s.projectTagCounts(dbInfo.ClientProjectID)
+		}
+	}
 
 	s.writeJSONResponse(w, dashboard, http.StatusOK)
 }
@@ -9065,7 +9840,7 @@ func (s *Server) handle1CProcessingXML(w http.ResponseWriter, r *http.Request) {
 	// Получаем рабочую директорию (директорию, откуда запущен сервер)
 	workDir, err := os.Getwd()
 	if err != nil {
-		s.log(LogEntry{
+		s.logCtx(r.Context(), LogEntry{
 			Timestamp: time.Now(),
 			Level:     "ERROR",
 			Message:   fmt.Sprintf("Failed to get working directory: %v", err),
@@ -9079,7 +9854,7 @@ func (s *Server) handle1CProcessingXML(w http.ResponseWriter, r *http.Request) {
 	modulePath := filepath.Join(workDir, "1c_processing", "Module", "Module.bsl")
 	moduleCode, err := os.ReadFile(modulePath)
 	if err != nil {
-		s.log(LogEntry{
+		s.logCtx(r.Context(), LogEntry{
 			Timestamp: time.Now(),
 			Level:     "ERROR",
 			Message:   fmt.Sprintf("Failed to read Module.bsl from %s: %v", modulePath, err),
@@ -9094,7 +9869,7 @@ func (s *Server) handle1CProcessingXML(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Расширения могут отсутствовать, используем пустую строку
 		extensionsCode = []byte("")
-		s.log(LogEntry{
+		s.logCtx(r.Context(), LogEntry{
 			Timestamp: time.Now(),
 			Level:     "WARN",
 			Message:   fmt.Sprintf("Extensions file not found at %s, using empty: %v", extensionsPath, err),
@@ -9107,7 +9882,7 @@ func (s *Server) handle1CProcessingXML(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Файл может отсутствовать, используем пустую строку
 		exportFunctionsCode = []byte("")
-		s.log(LogEntry{
+		s.logCtx(r.Context(), LogEntry{
 			Timestamp: time.Now(),
 			Level:     "WARN",
 			Message:   fmt.Sprintf("Export functions file not found, using empty: %v", err),
@@ -9140,6 +9915,16 @@ func (s *Server) handle1CProcessingXML(w http.ResponseWriter, r *http.Request) {
 		fullModuleCode += "\n\n" + string(extensionsCode)
 	}
 
+	// Обработка для выгрузки pull передает идентификатор задачи в запросах загрузки
+	if exportID := strings.TrimSpace(r.URL.Query().Get("export_id")); exportID != "" {
+		job := s.getExportJob(exportID)
+		if job == nil || job.Type != ExportTypePull {
+			http.Error(w, "Pull export job not found", http.StatusNotFound)
+			return
+		}
+		fullModuleCode += "\n\n" + pullExportModuleCode(job)
+	}
+
 	// Генерируем UUID для обработки
 	processingUUID := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", ""))
 
@@ -9228,7 +10013,7 @@ func (s *Server) handle1CProcessingXML(w http.ResponseWriter, r *http.Request) {
 
 	// Отправляем XML
 	if _, err := w.Write([]byte(xmlContent)); err != nil {
-		s.log(LogEntry{
+		s.logCtx(r.Context(), LogEntry{
 			Timestamp: time.Now(),
 			Level:     "ERROR",
 			Message:   fmt.Sprintf("Failed to write XML response: %v", err),
@@ -9237,7 +10022,7 @@ func (s *Server) handle1CProcessingXML(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.log(LogEntry{
+	s.logCtx(r.Context(), LogEntry{
 		Timestamp: time.Now(),
 		Level:     "INFO",
 		Message:   fmt.Sprintf("Generated 1C processing XML (UUID: %s, module size: %d chars)", processingUUID, len(fullModuleCode)),
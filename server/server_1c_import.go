@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"httpserver/apperrors"
 	"httpserver/database"
 )
 
@@ -44,6 +45,7 @@ type ImportHandshakeRequest struct {
 	XMLName    xml.Name   `xml:"import_handshake"`
 	DBName     string     `xml:"db_name"`
 	ClientInfo ClientInfo `xml:"client_info"`
+	ExportID   string     `xml:"export_id,omitempty"` // Задача обратной выгрузки pull: отбор справочников, полей и элементов
 }
 
 // ClientInfo информация о клиенте
@@ -73,10 +75,11 @@ type ImportCatalogInfo struct {
 
 // ImportGetConstantsRequest запрос на получение констант
 type ImportGetConstantsRequest struct {
-	XMLName xml.Name `xml:"import_get_constants"`
-	DBName  string   `xml:"db_name"`
-	Offset  int      `xml:"offset,omitempty"`
-	Limit   int      `xml:"limit,omitempty"`
+	XMLName  xml.Name `xml:"import_get_constants"`
+	DBName   string   `xml:"db_name"`
+	Offset   int      `xml:"offset,omitempty"`
+	Limit    int      `xml:"limit,omitempty"`
+	ExportID string   `xml:"export_id,omitempty"`
 }
 
 // ImportGetConstantsResponse ответ с константами
@@ -95,8 +98,10 @@ type ConstantForImport struct {
 	Name      string `xml:"name"`
 	Synonym   string `xml:"synonym"`
 	Type      string `xml:"type"`
-	Value     string `xml:"value"`
-	CreatedAt string `xml:"created_at"`
+	Value      string `xml:"value"`
+	ValueKind  string `xml:"value_kind,omitempty"`
+	TypedValue string `xml:"typed_value,omitempty"`
+	CreatedAt  string `xml:"created_at"`
 }
 
 // ImportGetCatalogRequest запрос на получение справочника
@@ -106,6 +111,7 @@ type ImportGetCatalogRequest struct {
 	CatalogName string   `xml:"catalog_name"`
 	Offset      int      `xml:"offset,omitempty"`
 	Limit       int      `xml:"limit,omitempty"`
+	ExportID    string   `xml:"export_id,omitempty"`
 }
 
 // ImportGetCatalogResponse ответ с элементами справочника
@@ -114,9 +120,10 @@ type ImportGetCatalogResponse struct {
 	Success     bool                  `xml:"success"`
 	CatalogName string                `xml:"catalog_name"`
 	Items       []CatalogItemForImport `xml:"items>item"`
-	Total       int                   `xml:"total"`
+	Total       int                   `xml:"total"` // Элементов справочника до отбора задачи: граница постраничного чтения
 	Offset      int                   `xml:"offset"`
 	Limit       int                   `xml:"limit"`
+	Skipped     int                   `xml:"skipped,omitempty"` // Элементы страницы, не прошедшие отбор задачи
 	Message     string                `xml:"message,omitempty"`
 }
 
@@ -132,8 +139,9 @@ type CatalogItemForImport struct {
 
 // ImportCompleteRequest запрос на завершение импорта
 type ImportCompleteRequest struct {
-	XMLName xml.Name `xml:"import_complete"`
-	DBName  string   `xml:"db_name"`
+	XMLName  xml.Name `xml:"import_complete"`
+	DBName   string   `xml:"db_name"`
+	ExportID string   `xml:"export_id,omitempty"`
 }
 
 // ImportCompleteResponse ответ на завершение импорта
@@ -211,13 +219,13 @@ func (s *Server) handle1CImportHandshake(w http.ResponseWriter, r *http.Request)
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req ImportHandshakeRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -246,6 +254,17 @@ func (s *Server) handle1CImportHandshake(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Выгрузка pull: в ответе только выбранные задачей справочники
+	var pullJob *ExportJob
+	var selection *exportSelection
+	if req.ExportID != "" {
+		if pullJob, selection, err = s.pullExportSelection(req.ExportID, uploadUUID); err != nil {
+			s.writeErrorResponse(w, "Export job is not available", err)
+			return
+		}
+		pullJob.startPull()
+	}
+
 	// Получаем информацию о БД (справочники и константы)
 	catalogs, err := db.GetAllCatalogs()
 	if err != nil {
@@ -270,10 +289,16 @@ func (s *Server) handle1CImportHandshake(w http.ResponseWriter, r *http.Request)
 	if err == nil {
 		constantsCount = upload.TotalConstants
 	}
+	if pullJob != nil && !pullJob.Options.IncludeConstants {
+		constantsCount = 0
+	}
 
 	// Формируем список справочников с количеством элементов
 	var catalogInfos []ImportCatalogInfo
 	for _, catalog := range catalogs {
+		if pullJob != nil && (!pullJob.Options.IncludeCatalogs || !selection.catalogAllowed(catalog.Name)) {
+			continue
+		}
 		itemCount, err := db.GetCatalogItemsCount(catalog.ID)
 		if err != nil {
 			itemCount = 0
@@ -314,13 +339,13 @@ func (s *Server) handle1CImportGetConstants(w http.ResponseWriter, r *http.Reque
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req ImportGetConstantsRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -361,6 +386,25 @@ func (s *Server) handle1CImportGetConstants(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// Выгрузка pull без констант возвращает пустой список
+	var pullJob *ExportJob
+	if req.ExportID != "" {
+		if pullJob, _, err = s.pullExportSelection(req.ExportID, uploadUUID); err != nil {
+			s.writeErrorResponse(w, "Export job is not available", err)
+			return
+		}
+		if !pullJob.Options.IncludeConstants {
+			s.writeXMLResponse(w, ImportGetConstantsResponse{Success: true, Offset: req.Offset, Limit: req.Limit})
+			return
+		}
+	}
+
+	redaction, err := s.importRedaction(r, pullJob, "upload:"+uploadUUID)
+	if err != nil {
+		s.writeErrorResponse(w, "Failed to resolve redaction profile", err)
+		return
+	}
+
 	// Получаем константы с пагинацией
 	constants, err := db.GetConstantsByUploadWithPagination(upload.ID, req.Limit, req.Offset)
 	if err != nil {
@@ -375,14 +419,20 @@ func (s *Server) handle1CImportGetConstants(w http.ResponseWriter, r *http.Reque
 	// Форматируем константы для ответа
 	var constantsForImport []ConstantForImport
 	for _, c := range constants {
+		redaction.RedactConstant(c)
 		constantsForImport = append(constantsForImport, ConstantForImport{
-			Name:      c.Name,
-			Synonym:   c.Synonym,
-			Type:      c.Type,
-			Value:     c.Value,
-			CreatedAt: c.CreatedAt.Format(time.RFC3339),
+			Name:       c.Name,
+			Synonym:    c.Synonym,
+			Type:       c.Type,
+			Value:      c.Value,
+			ValueKind:  c.ValueKind,
+			TypedValue: database.FormatConstantTypedValue(c.TypedValue),
+			CreatedAt:  c.CreatedAt.Format(time.RFC3339),
 		})
 	}
+	if pullJob != nil {
+		pullJob.addConstants(len(constantsForImport))
+	}
 
 	response := ImportGetConstantsResponse{
 		Success:   true,
@@ -412,13 +462,13 @@ func (s *Server) handle1CImportGetCatalog(w http.ResponseWriter, r *http.Request
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req ImportGetCatalogRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -459,6 +509,26 @@ func (s *Server) handle1CImportGetCatalog(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Выгрузка pull: только выбранные справочники и элементы, прошедшие отбор задачи
+	var pullJob *ExportJob
+	var selection *exportSelection
+	if req.ExportID != "" {
+		if pullJob, selection, err = s.pullExportSelection(req.ExportID, uploadUUID); err != nil {
+			s.writeErrorResponse(w, "Export job is not available", err)
+			return
+		}
+		if !pullJob.Options.IncludeCatalogs || !selection.catalogAllowed(req.CatalogName) {
+			s.writeErrorResponse(w, "Catalog is not selected for export",
+				apperrors.Validation("catalog_not_selected", fmt.Sprintf("catalog %s is not selected for export", req.CatalogName)))
+			return
+		}
+	}
+	redaction, err := s.importRedaction(r, pullJob, "upload:"+uploadUUID)
+	if err != nil {
+		s.writeErrorResponse(w, "Failed to resolve redaction profile", err)
+		return
+	}
+
 	// НОВАЯ ЛОГИКА: Получаем имя таблицы для справочника
 	tableName, err := database.GetCatalogTableName(db.GetDB(), req.CatalogName)
 	if err != nil {
@@ -491,6 +561,13 @@ func (s *Server) handle1CImportGetCatalog(w http.ResponseWriter, r *http.Request
 			return
 		}
 
+		items, skipped := selection.filterCatalogItems(items)
+		if pullJob != nil {
+			pullJob.addCatalogItems(len(items))
+			pullJob.addSkipped(skipped)
+		}
+		redaction.RedactCatalogItems(items)
+
 		// Форматируем элементы для ответа
 		var itemsForImport []CatalogItemForImport
 		for _, item := range items {
@@ -511,6 +588,7 @@ func (s *Server) handle1CImportGetCatalog(w http.ResponseWriter, r *http.Request
 			Total:       totalItems,
 			Offset:      req.Offset,
 			Limit:       req.Limit,
+			Skipped:     skipped,
 		}
 
 		s.writeXMLResponse(w, response)
@@ -538,9 +616,18 @@ func (s *Server) handle1CImportGetCatalog(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	items, skipped := selection.filterCatalogItems(items)
+	if pullJob != nil {
+		pullJob.addCatalogItems(len(items))
+		pullJob.addSkipped(skipped)
+	}
+
 	// Форматируем элементы для ответа
 	var itemsForImport []CatalogItemForImport
 	for _, item := range items {
+		item.Attributes = s.revealValue(r, item.Attributes)
+		item.TableParts = s.revealValue(r, item.TableParts)
+		redaction.RedactCatalogItem(item)
 		itemsForImport = append(itemsForImport, CatalogItemForImport{
 			Reference:     item.Reference,
 			Code:          item.Code,
@@ -558,6 +645,7 @@ func (s *Server) handle1CImportGetCatalog(w http.ResponseWriter, r *http.Request
 		Total:       totalItems,
 		Offset:      req.Offset,
 		Limit:       req.Limit,
+		Skipped:     skipped,
 	}
 
 	s.log(LogEntry{
@@ -580,13 +668,13 @@ func (s *Server) handle1CImportComplete(w http.ResponseWriter, r *http.Request)
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		s.writeErrorResponse(w, "Failed to read request body", err)
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
 		return
 	}
 
 	var req ImportCompleteRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
-		s.writeErrorResponse(w, "Failed to parse XML", err)
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
 		return
 	}
 
@@ -597,6 +685,27 @@ func (s *Server) handle1CImportComplete(w http.ResponseWriter, r *http.Request)
 		Endpoint:  "/api/1c/import/complete",
 	})
 
+	// Выгрузка pull завершается и фиксирует отметку для отбора измененных элементов
+	if req.ExportID != "" {
+		_, uploadUUID, err := s.openDatabaseByName(req.DBName)
+		if err != nil {
+			s.writeXMLResponse(w, ImportCompleteResponse{
+				Success:   false,
+				Message:   fmt.Sprintf("Failed to open database: %v", err),
+				Timestamp: time.Now().Format(time.RFC3339),
+			})
+			return
+		}
+		job, selection, err := s.pullExportSelection(req.ExportID, uploadUUID)
+		if err != nil {
+			s.writeErrorResponse(w, "Export job is not available", err)
+			return
+		}
+		job.markCompleteDispatched()
+		s.commitExportSelection(job, selection)
+		job.markCompleted()
+	}
+
 	response := ImportCompleteResponse{
 		Success:   true,
 		Message:   "Import completed successfully",
@@ -836,21 +945,13 @@ func (s *Server) openDatabaseByName(dbName string) (*database.DB, string, error)
 		return nil, "", fmt.Errorf("database file not found: %s", dbName)
 	}
 
-	// Проверяем кэш uploadDBs - может БД уже открыта
-	s.uploadDBsMutex.RLock()
-	for uuid, cachedDB := range s.uploadDBs {
-		// Проверяем что это та же БД (по пути или имени файла)
-		// Для упрощения просто открываем новое подключение
-		_ = uuid
-		_ = cachedDB
-	}
-	s.uploadDBsMutex.RUnlock()
-
 	// Открываем БД
 	dbConfig := database.DBConfig{
-		MaxOpenConns:    s.config.MaxOpenConns,
-		MaxIdleConns:    s.config.MaxIdleConns,
-		ConnMaxLifetime: s.config.ConnMaxLifetime,
+		MaxOpenConns:    s.currentConfig().MaxOpenConns,
+		MaxIdleConns:    s.currentConfig().MaxIdleConns,
+		ConnMaxLifetime: s.currentConfig().ConnMaxLifetime,
+
+		ReadPoolMaxOpenConns: s.currentConfig().ReadPoolMaxOpenConns,
 	}
 
 	db, err := database.NewDBWithConfig(dbPath, dbConfig)
@@ -867,10 +968,9 @@ func (s *Server) openDatabaseByName(dbName string) (*database.DB, string, error)
 
 	uploadUUID = uploads[0].UploadUUID
 
-	// Добавляем в кэш
-	s.uploadDBsMutex.Lock()
-	s.uploadDBs[uploadUUID] = db
-	s.uploadDBsMutex.Unlock()
+	// Добавляем в кэш; если файл уже открыт кэшем, используется открытое подключение
+	db = s.uploadDBs.put(uploadUUID, dbPath, db)
+	s.registerUploadLocation(uploadUUID, dbPath, nil)
 
 	log.Printf("Opened old database file: %s, upload_uuid: %s", dbPath, uploadUUID)
 	return db, uploadUUID, nil
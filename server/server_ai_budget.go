@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"httpserver/nomenclature"
+)
+
+// AIBudgetRequest запрос на изменение общего бюджета AI запросов; 0 - без ограничения
+type AIBudgetRequest struct {
+	RequestsPerMinute *int `json:"requests_per_minute"`
+	TokensPerMinute   *int `json:"tokens_per_minute"`
+}
+
+// handleAIBudget возвращает и изменяет общий бюджет запросов к AI провайдеру,
+// который делят все одновременно работающие задачи
+// GET /api/monitoring/ai/budget
+// PUT /api/monitoring/ai/budget
+func (s *Server) handleAIBudget(w http.ResponseWriter, r *http.Request) {
+	scheduler := nomenclature.SharedAIScheduler()
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSONResponse(w, scheduler.Stats(), http.StatusOK)
+	case http.MethodPut:
+		var req AIBudgetRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		config := scheduler.Config()
+		if req.RequestsPerMinute != nil {
+			config.RequestsPerMinute = *req.RequestsPerMinute
+		}
+		if req.TokensPerMinute != nil {
+			config.TokensPerMinute = *req.TokensPerMinute
+		}
+		if config.RequestsPerMinute < 0 || config.TokensPerMinute < 0 {
+			s.writeJSONError(w, "Budget must not be negative", http.StatusBadRequest)
+			return
+		}
+		scheduler.SetConfig(config)
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message: fmt.Sprintf("Бюджет AI запросов изменен: %d запросов/мин, %d токенов/мин (%s)",
+				config.RequestsPerMinute, config.TokensPerMinute, requestActor(r, "", "unknown")),
+			Endpoint: r.URL.Path,
+		})
+		s.writeJSONResponse(w, scheduler.Stats(), http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+	"httpserver/storage"
+)
+
+// defaultAttachmentMaxSizeMB размер вложения по умолчанию, если ATTACHMENT_MAX_SIZE_MB не задан
+const defaultAttachmentMaxSizeMB = 50
+
+// UploadAttachmentInfo вложение выгрузки со ссылкой на скачивание
+type UploadAttachmentInfo struct {
+	*database.UploadAttachment
+	DownloadURL string `json:"download_url"`
+}
+
+// attachmentMaxSize максимальный размер файла вложения в байтах
+func (s *Server) attachmentMaxSize() int64 {
+	sizeMB := defaultAttachmentMaxSizeMB
+	if s.currentConfig() != nil && s.currentConfig().AttachmentMaxSizeMB > 0 {
+		sizeMB = s.currentConfig().AttachmentMaxSizeMB
+	}
+	return int64(sizeMB) << 20
+}
+
+// attachmentFileName оставляет от имени файла последний элемент пути
+func attachmentFileName(name string) (string, error) {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "", errors.New("file_name is required")
+	}
+	return name, nil
+}
+
+// decodeAttachmentContent декодирует base64 содержимое; 1С разбивает base64 на строки, пробельные символы пропускаются
+func decodeAttachmentContent(content string) ([]byte, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, content)
+	data, err := base64.StdEncoding.DecodeString(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 content: %w", err)
+	}
+	return data, nil
+}
+
+// readAttachmentRequest разбирает вложение из multipart/form-data (файл в поле file) или XML с base64 содержимым
+func readAttachmentRequest(r *http.Request, maxSize int64) (*CatalogAttachmentRequest, []byte, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, nil, fmt.Errorf("invalid multipart form: %w", err)
+		}
+		req := &CatalogAttachmentRequest{
+			UploadUUID:  r.FormValue("upload_uuid"),
+			CatalogName: r.FormValue("catalog_name"),
+			Reference:   r.FormValue("reference"),
+			FileName:    r.FormValue("file_name"),
+			ContentType: r.FormValue("content_type"),
+			Description: r.FormValue("description"),
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, nil, errors.New("attachment file is required in multipart field 'file'")
+		}
+		defer file.Close()
+		if req.FileName == "" {
+			req.FileName = header.Filename
+		}
+		if req.ContentType == "" {
+			req.ContentType = header.Header.Get("Content-Type")
+		}
+		data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read attachment file: %w", err)
+		}
+		return req, data, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	var req CatalogAttachmentRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse XML: %w", err)
+	}
+	data, err := decodeAttachmentContent(req.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Content = ""
+	return &req, data, nil
+}
+
+// handleCatalogAttachment принимает вложение элемента справочника выгрузки: файл сохраняется в хранилище
+// артефактов (локальный каталог или S3), сведения о нем - в БД выгрузки. Одинаковые файлы выгрузки
+// хранятся один раз
+// POST /catalog/attachment
+func (s *Server) handleCatalogAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxSize := s.attachmentMaxSize()
+	// Base64 увеличивает размер на треть, плюс поля запроса
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize/3*4+(1<<20))
+	req, data, err := readAttachmentRequest(r, maxSize)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.writeErrorResponseWithStatus(w, http.StatusRequestEntityTooLarge, "Attachment is too large",
+				fmt.Errorf("attachment exceeds %d MB", maxSize>>20))
+			return
+		}
+		s.writeErrorResponse(w, "Invalid attachment request", apperrors.Wrap(apperrors.KindValidation, "invalid_attachment", err))
+		return
+	}
+	if int64(len(data)) > maxSize {
+		s.writeErrorResponseWithStatus(w, http.StatusRequestEntityTooLarge, "Attachment is too large",
+			fmt.Errorf("attachment exceeds %d MB", maxSize>>20))
+		return
+	}
+
+	fileName, err := attachmentFileName(req.FileName)
+	if err == nil && (req.UploadUUID == "" || req.CatalogName == "" || req.Reference == "") {
+		err = errors.New("upload_uuid, catalog_name and reference are required")
+	}
+	if err == nil && len(data) == 0 {
+		err = errors.New("attachment content is empty")
+	}
+	if err != nil {
+		s.writeErrorResponse(w, "Invalid attachment request", apperrors.Wrap(apperrors.KindValidation, "invalid_attachment", err))
+		return
+	}
+
+	noteRequestUpload(r, req.UploadUUID)
+	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
+	if err != nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
+		return
+	}
+	upload, err := uploadDB.GetUploadByUUID(req.UploadUUID)
+	if err != nil {
+		s.writeErrorResponse(w, "Upload not found", err)
+		return
+	}
+	if !s.allowIngest(w, upload, 1, len(data)) {
+		return
+	}
+
+	// Тип application/octet-stream по умолчанию выставляют multipart клиенты - уточняем по расширению
+	contentType := req.ContentType
+	if contentType == "" || contentType == "application/octet-stream" {
+		if byExtension := mime.TypeByExtension(path.Ext(fileName)); byExtension != "" {
+			contentType = byExtension
+		}
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := &database.UploadAttachment{
+		UploadID:    upload.ID,
+		CatalogName: req.CatalogName,
+		Reference:   req.Reference,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Description: req.Description,
+	}
+	attachment.StorageKey = fmt.Sprintf("attachments/%s/%s", upload.UploadUUID, attachment.SHA256)
+
+	store := s.artifactStorage()
+	if store == nil {
+		s.writeErrorResponseWithStatus(w, http.StatusServiceUnavailable, "Artifact storage is not available",
+			errors.New("artifact storage is not configured"))
+		return
+	}
+	if err := store.Put(r.Context(), attachment.StorageKey, bytes.NewReader(data), attachment.Size, contentType); err != nil {
+		s.writeErrorResponse(w, "Failed to store attachment", err)
+		return
+	}
+
+	replacedKey, err := uploadDB.SaveUploadAttachment(attachment)
+	if err != nil {
+		s.writeErrorResponse(w, "Failed to save attachment", err)
+		return
+	}
+	if replacedKey != "" {
+		s.deleteUnusedAttachmentFile(uploadDB, store, replacedKey)
+	}
+
+	s.log(LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "INFO",
+		Message:    fmt.Sprintf("Attachment '%s' (%d bytes) added to %s item %s", fileName, attachment.Size, req.CatalogName, req.Reference),
+		UploadUUID: req.UploadUUID,
+		Endpoint:   "/catalog/attachment",
+	})
+
+	s.writeXMLResponse(w, CatalogAttachmentResponse{
+		Success:      true,
+		AttachmentID: attachment.ID,
+		Size:         attachment.Size,
+		SHA256:       attachment.SHA256,
+		Message:      "Attachment added successfully",
+		Timestamp:    time.Now().Format(time.RFC3339),
+	})
+}
+
+// deleteUnusedAttachmentFile удаляет замененный файл вложения, если на него больше не ссылаются вложения выгрузки
+func (s *Server) deleteUnusedAttachmentFile(uploadDB *database.DB, store storage.Storage, storageKey string) {
+	count, err := uploadDB.CountAttachmentsByStorageKey(storageKey)
+	if err != nil || count > 0 {
+		return
+	}
+	if err := store.Delete(context.Background(), storageKey); err != nil {
+		log.Printf("Warning: Failed to delete replaced attachment %s: %v", storageKey, err)
+	}
+}
+
+// handleUploadAttachments возвращает вложения элементов выгрузки со ссылками на скачивание
+// GET /api/uploads/{uuid}/attachments?catalog=Номенклатура&reference=...
+func (s *Server) handleUploadAttachments(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	attachments, err := uploadDB.GetUploadAttachments(upload.ID, query.Get("catalog"), query.Get("reference"))
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get attachments: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]UploadAttachmentInfo, 0, len(attachments))
+	for _, attachment := range attachments {
+		items = append(items, UploadAttachmentInfo{
+			UploadAttachment: attachment,
+			DownloadURL:      fmt.Sprintf("/api/uploads/%s/attachments/%d", upload.UploadUUID, attachment.ID),
+		})
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"upload_uuid": upload.UploadUUID,
+		"attachments": items,
+		"total":       len(items),
+	}, http.StatusOK)
+}
+
+// handleUploadAttachment отдает файл вложения выгрузки
+// GET /api/uploads/{uuid}/attachments/{id}
+func (s *Server) handleUploadAttachment(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload, idStr string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id <= 0 {
+		s.writeJSONError(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+	attachment, err := uploadDB.GetUploadAttachment(upload.ID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.writeJSONError(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get attachment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	store := s.artifactStorage()
+	if store == nil {
+		s.writeJSONError(w, "Artifact storage is not available", http.StatusServiceUnavailable)
+		return
+	}
+	reader, err := store.Get(r.Context(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		s.writeJSONError(w, "Attachment file not found in storage", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to read attachment: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	io.Copy(w, reader)
+}
+
+// readAttachmentContent читает файл вложения из хранилища для экспорта
+func (s *Server) readAttachmentContent(ctx context.Context, attachment *database.UploadAttachment) ([]byte, error) {
+	store := s.artifactStorage()
+	if store == nil {
+		return nil, errors.New("artifact storage is not available")
+	}
+	reader, err := store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment %d: %w", attachment.ID, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/storage"
+)
+
+func TestCatalogAttachmentIngest(t *testing.T) {
+	db, err := database.NewUnifiedDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+	upload, err := db.CreateUpload("uuid-attachments", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	s := &Server{
+		db:      db,
+		config:  &Config{AttachmentMaxSizeMB: 1, Storage: storage.Config{LocalDir: t.TempDir()}},
+		logChan: make(chan LogEntry, 100),
+	}
+	s.uploadDBs.putShared(upload.UploadUUID, db)
+
+	xmlRequest := func(reference, fileName, content string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/catalog/attachment", strings.NewReader(`<catalog_attachment>
+			<upload_uuid>uuid-attachments</upload_uuid>
+			<catalog_name>Номенклатура</catalog_name>
+			<reference>`+reference+`</reference>
+			<file_name>`+fileName+`</file_name>
+			<content>`+content+`</content>
+		</catalog_attachment>`))
+	}
+	multipartRequest := func(reference string, data []byte) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("upload_uuid", "uuid-attachments")
+		writer.WriteField("catalog_name", "Номенклатура")
+		writer.WriteField("reference", reference)
+		part, _ := writer.CreateFormFile("file", "spec.pdf")
+		part.Write(data)
+		writer.Close()
+		r := httptest.NewRequest(http.MethodPost, "/catalog/attachment", &body)
+		r.Header.Set("Content-Type", writer.FormDataContentType())
+		return r
+	}
+
+	// 1С разбивает base64 на строки
+	photo := base64.StdEncoding.EncodeToString([]byte("photo-v1"))
+	photoWrapped := photo[:4] + "\r\n" + photo[4:]
+
+	tests := []struct {
+		name       string
+		request    *http.Request
+		wantStatus int
+	}{
+		{"xml base64 with line breaks", xmlRequest("item-1", "photo.png", photoWrapped), http.StatusOK},
+		{"multipart file", multipartRequest("item-1", []byte("%PDF-1.4 spec")), http.StatusOK},
+		{"same file name replaces attachment", xmlRequest("item-1", "photo.png", base64.StdEncoding.EncodeToString([]byte("photo-v2"))), http.StatusOK},
+		{"path in file name is dropped", xmlRequest("item-2", `C:\Фото\photo.png`, photo), http.StatusOK},
+		{"invalid base64", xmlRequest("item-3", "photo.png", "not base64!"), http.StatusBadRequest},
+		{"missing reference", xmlRequest("", "photo.png", photo), http.StatusBadRequest},
+		{"too large", multipartRequest("item-4", bytes.Repeat([]byte("x"), 1<<20+1)), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleCatalogAttachment(rec, tt.request)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	listRec := httptest.NewRecorder()
+	s.handleUploadRoutes(listRec, httptest.NewRequest(http.MethodGet, "/api/uploads/uuid-attachments/attachments?reference=item-1", nil))
+	var list struct {
+		Attachments []UploadAttachmentInfo `json:"attachments"`
+		Total       int                    `json:"total"`
+	}
+	if err := json.NewDecoder(listRec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode attachments: %v", err)
+	}
+	if list.Total != 2 {
+		t.Fatalf("item-1 attachments = %d, want 2", list.Total)
+	}
+	var photoAttachment UploadAttachmentInfo
+	for _, attachment := range list.Attachments {
+		if attachment.FileName == "photo.png" {
+			photoAttachment = attachment
+		}
+		if attachment.FileName == "spec.pdf" && attachment.ContentType != "application/pdf" {
+			t.Errorf("spec.pdf content type = %q", attachment.ContentType)
+		}
+	}
+	if photoAttachment.UploadAttachment == nil {
+		t.Fatalf("photo.png not listed: %+v", list.Attachments)
+	}
+
+	downloadRec := httptest.NewRecorder()
+	s.handleUploadRoutes(downloadRec, httptest.NewRequest(http.MethodGet, photoAttachment.DownloadURL, nil))
+	if downloadRec.Code != http.StatusOK || downloadRec.Body.String() != "photo-v2" {
+		t.Fatalf("download = %d %q, want replaced content", downloadRec.Code, downloadRec.Body.String())
+	}
+
+	// Файл первой версии больше не используется и удален из хранилища
+	objects, err := s.artifactStorage().List(t.Context(), "attachments/uuid-attachments/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 3 {
+		t.Errorf("stored objects = %d, want 3 (photo-v2, spec.pdf, photo-v1 of item-2)", len(objects))
+	}
+}
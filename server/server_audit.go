@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+)
+
+// Ограничения выборки журнала аудита
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+// handleAuditLog возвращает последние записи журнала аудита
+// GET /api/audit?action=database_switch&limit=100
+func (s *Server) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit, err := boundedIntParam(r.URL.Query().Get("limit"), auditDefaultLimit, auditMaxLimit)
+	if err != nil {
+		s.writeJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+	events, err := s.serviceDB.GetAuditEvents(r.URL.Query().Get("action"), limit)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"events": events,
+		"total":  len(events),
+	}, http.StatusOK)
+}
@@ -0,0 +1,711 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/server/middleware"
+)
+
+// Cookie и заголовки сессий веб-интерфейса
+const (
+	sessionCookieName   = "session"
+	csrfCookieName      = "csrf_token" // Доступна скриптам веб-интерфейса для заголовка X-CSRF-Token
+	csrfHeaderName      = "X-CSRF-Token"
+	setupTokenHeader    = "X-Setup-Token"
+	oidcStateCookieName = "oidc_state"
+	defaultSessionTTL   = 12 * time.Hour
+)
+
+// sessionKey ключ контекста с сессией пользователя веб-интерфейса
+type sessionKey struct{}
+
+// sessionFromContext возвращает сессию пользователя запроса; nil для запросов без сессии (обработка 1С, скрипты)
+func sessionFromContext(ctx context.Context) *database.UserSession {
+	session, _ := ctx.Value(sessionKey{}).(*database.UserSession)
+	return session
+}
+
+// requestActor определяет, кого записывать в журнал аудита и происхождение данных: пользователя сессии,
+// иначе переданное в запросе имя, иначе fallback
+func requestActor(r *http.Request, claimed, fallback string) string {
+	if session := sessionFromContext(r.Context()); session != nil {
+		return session.User.Username
+	}
+	if claimed = strings.TrimSpace(claimed); claimed != "" {
+		return claimed
+	}
+	return fallback
+}
+
+// sessionToken возвращает токен сессии запроса: из заголовка Authorization: Bearer (клиенты API)
+// или из cookie сессии (веб-интерфейс); bearer=true для токена из заголовка
+func sessionToken(r *http.Request) (token string, bearer bool) {
+	if scheme, value, found := strings.Cut(r.Header.Get("Authorization"), " "); found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(value), true
+	}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		return cookie.Value, false
+	}
+	return "", false
+}
+
+// isSafeMethod проверяет, что метод не изменяет данные
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// requiredRole роль, необходимая пользователю сессии для запроса: управление пользователями,
+// сервером, профилями скрытия колонок и шифрованием проектов - администратор, изменение данных - оператор, чтение - просмотр
+func requiredRole(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/api/auth/"):
+		return database.RoleViewer
+	case path == "/api/users" || strings.HasPrefix(path, "/api/users/") || strings.HasPrefix(path, "/api/admin/") ||
+		strings.HasPrefix(path, "/api/redaction/"):
+		return database.RoleAdmin
+	case isSafeMethod(r.Method):
+		return database.RoleViewer
+	case strings.HasPrefix(path, "/api/clients/") && (strings.HasSuffix(path, "/encryption") || strings.HasSuffix(path, "/encryption/rewrap")):
+		return database.RoleAdmin
+	}
+	return database.RoleOperator
+}
+
+// oneCEndpoints эндпоинты обмена с обработками 1С вне /api/1c/import/ и /api/normalized/upload/
+var oneCEndpoints = map[string]bool{
+	"/api/v1/upload/handshake":          true,
+	"/api/v1/upload/protocol":           true,
+	"/api/v1/upload/metadata":           true,
+	"/api/v1/upload/nomenclature/batch": true,
+	"/api/v1/health":                    true,
+	"/api/1c/databases":                 true,
+}
+
+// sessionExempt проверяет, что запрос не требует сессии: вход, страницы и статика веб-интерфейса,
+// проверка состояния и протокол обмена с обработками 1С сохраняют прежний доступ
+func sessionExempt(r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case !strings.HasPrefix(path, "/api/"):
+		// /handshake, /catalog/items и другие эндпоинты протокола выгрузки, /health, веб-интерфейс
+		return true
+	case strings.HasPrefix(path, "/api/auth/"), strings.HasPrefix(path, "/api/1c/import/"),
+		strings.HasPrefix(path, "/api/normalized/upload/"):
+		return true
+	}
+	return oneCEndpoints[path]
+}
+
+// authRequired проверяет, что в служебной БД есть пользователи и запросы без сессии нужно отклонять.
+// Появление пользователей запоминается, чтобы не считать их на каждом запросе
+func (s *Server) authRequired() (bool, error) {
+	if s.authEnforced.Load() {
+		return true, nil
+	}
+	count, err := s.serviceDB.CountUsers()
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		s.authEnforced.Store(true)
+	}
+	return count > 0, nil
+}
+
+// serveAnonymous выполняет запрос без сессии. Пока пользователей нет, сервер работает без входа;
+// после появления пользователей такие запросы допускаются только к эндпоинтам из sessionExempt
+func (s *Server) serveAnonymous(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if !sessionExempt(r) {
+		required, err := s.authRequired()
+		if err != nil {
+			log.Printf("Ошибка проверки учетных записей: %v", err)
+			s.writeJSONError(w, "Session store is not available", http.StatusServiceUnavailable)
+			return
+		}
+		if required {
+			middleware.WriteJSONErrorCode(w, "Login required", "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+// sessionMiddleware определяет пользователя по cookie сессии или токену API и проверяет права его роли.
+// Изменяющие запросы с cookie сессии должны передавать CSRF токен сессии в заголовке X-CSRF-Token.
+// Запросы без сессии (нет cookie и токена, сессия истекла) обрабатывает serveAnonymous
+func (s *Server) sessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.serviceDB == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, bearer := sessionToken(r)
+		if token == "" {
+			s.serveAnonymous(w, r, next)
+			return
+		}
+		session, err := s.serviceDB.GetUserSession(token)
+		if err != nil {
+			log.Printf("Ошибка проверки сессии: %v", err)
+			s.writeJSONError(w, "Session store is not available", http.StatusServiceUnavailable)
+			return
+		}
+		if session == nil {
+			if bearer {
+				middleware.WriteJSONErrorCode(w, "Access token is invalid or expired", "invalid_token", http.StatusUnauthorized)
+				return
+			}
+			// Истекшая или завершенная сессия: запрос выполняется без пользователя
+			s.clearSessionCookies(w, r)
+			s.serveAnonymous(w, r, next)
+			return
+		}
+
+		// Токен API передается явно, поэтому CSRF проверяется только для cookie
+		if !bearer && !isSafeMethod(r.Method) && r.URL.Path != "/api/auth/login" {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get(csrfHeaderName)), []byte(session.CSRFToken)) != 1 {
+				middleware.WriteJSONErrorCode(w, "CSRF token is missing or invalid", "csrf_token_invalid", http.StatusForbidden)
+				return
+			}
+		}
+		if !database.RoleAllows(session.User.Role, requiredRole(r)) {
+			middleware.WriteJSONErrorCode(w, fmt.Sprintf("Role %s is not allowed to perform this request", session.User.Role),
+				"forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, session)))
+	})
+}
+
+// sessionTTL срок действия сессии
+func (s *Server) sessionTTL() time.Duration {
+	if s.currentConfig() != nil && s.currentConfig().SessionTTL > 0 {
+		return s.currentConfig().SessionTTL
+	}
+	return defaultSessionTTL
+}
+
+// secureCookies проверяет, нужно ли ставить cookie с флагом Secure
+func (s *Server) secureCookies(r *http.Request) bool {
+	return r.TLS != nil || (s.currentConfig() != nil && s.currentConfig().SessionCookieSecure)
+}
+
+// createSession создает сессию пользователя и записывает вход в журнал аудита
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request, user *database.User, details map[string]interface{}) (string, *database.UserSession, bool) {
+	token, session, err := s.serviceDB.CreateUserSession(user.ID, s.sessionTTL(), r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		s.writeAPIError(w, "Failed to create session", err)
+		return "", nil, false
+	}
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["auth_provider"] = user.AuthProvider
+	details["role"] = user.Role
+	details["remote_addr"] = r.RemoteAddr
+	s.recordUserAudit(database.AuditActionUserLogin, user.Username, user.Username, "success", details)
+	return token, session, true
+}
+
+// startSession создает сессию пользователя веб-интерфейса и устанавливает cookie
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user *database.User, details map[string]interface{}) (*database.UserSession, bool) {
+	token, session, ok := s.createSession(w, r, user, details)
+	if !ok {
+		return nil, false
+	}
+	secure := s.secureCookies(r)
+	http.SetCookie(w, &http.Cookie{
+		Name: sessionCookieName, Value: token, Path: "/", Expires: session.ExpiresAt,
+		HttpOnly: true, Secure: secure, SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name: csrfCookieName, Value: session.CSRFToken, Path: "/", Expires: session.ExpiresAt,
+		Secure: secure, SameSite: http.SameSiteStrictMode,
+	})
+	return session, true
+}
+
+// clearSessionCookies удаляет cookie сессии
+func (s *Server) clearSessionCookies(w http.ResponseWriter, r *http.Request) {
+	secure := s.secureCookies(r)
+	for _, name := range []string{sessionCookieName, csrfCookieName} {
+		http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1, HttpOnly: name == sessionCookieName, Secure: secure})
+	}
+}
+
+// requireSession возвращает сессию запроса или отвечает 401
+func (s *Server) requireSession(w http.ResponseWriter, r *http.Request) *database.UserSession {
+	session := sessionFromContext(r.Context())
+	if session == nil {
+		middleware.WriteJSONErrorCode(w, "Login required", "unauthorized", http.StatusUnauthorized)
+	}
+	return session
+}
+
+// handleAuthRoutes вход, выход и настройки пользователя веб-интерфейса
+// GET /api/auth/providers - доступные способы входа
+// POST /api/auth/login {"username": "ivanov", "password": "..."}
+// POST /api/auth/logout
+// GET /api/auth/me
+// GET/PUT /api/auth/me/preferences
+// GET /api/auth/oidc/login - переход на страницу входа провайдера OIDC
+// GET /api/auth/oidc/callback - возврат от провайдера OIDC
+// POST /api/auth/token {"access_token": "..."} - обмен токена провайдера OIDC на токен API
+func (s *Server) handleAuthRoutes(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/auth"), "/") {
+	case "providers":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		_, oidcEnabled := s.oidcConfig()
+		s.writeJSONResponse(w, map[string]bool{
+			"password": s.currentConfig() == nil || !s.currentConfig().SSOOnly,
+			"oidc":     oidcEnabled,
+		}, http.StatusOK)
+	case "login":
+		s.handleLogin(w, r)
+	case "logout":
+		s.handleLogout(w, r)
+	case "me":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if session := s.requireSession(w, r); session != nil {
+			s.writeJSONResponse(w, session, http.StatusOK)
+		}
+	case "me/preferences":
+		s.handleUserPreferences(w, r)
+	case "oidc/login":
+		s.handleOIDCLogin(w, r)
+	case "oidc/callback":
+		s.handleOIDCCallback(w, r)
+	case "token":
+		s.handleTokenExchange(w, r)
+	default:
+		s.writeJSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// breakGlassLogin проверяет вход аварийной учетной записи администратора из конфигурации
+func (s *Server) breakGlassLogin(username, password string) bool {
+	if s.currentConfig() == nil || s.currentConfig().BreakGlassUsername == "" {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(username), s.currentConfig().BreakGlassUsername) &&
+		database.VerifyPassword(s.currentConfig().BreakGlassPasswordHash, password)
+}
+
+// handleLogin вход по имени пользователя и паролю. В режиме только SSO доступна лишь
+// аварийная учетная запись администратора
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if s.breakGlassLogin(req.Username, req.Password) {
+		user, err := s.serviceDB.EnsureLocalAdmin(s.currentConfig().BreakGlassUsername)
+		if err != nil {
+			s.writeAPIError(w, "Failed to get break-glass account", err)
+			return
+		}
+		if session, ok := s.startSession(w, r, user, map[string]interface{}{"break_glass": true}); ok {
+			s.writeJSONResponse(w, session, http.StatusOK)
+		}
+		return
+	}
+
+	if s.currentConfig() != nil && s.currentConfig().SSOOnly {
+		s.recordUserAudit(database.AuditActionUserLogin, req.Username, req.Username, "failed", map[string]interface{}{
+			"remote_addr": r.RemoteAddr,
+			"error":       "password login is disabled",
+		})
+		middleware.WriteJSONErrorCode(w, "Password login is disabled, use single sign-on", "password_login_disabled", http.StatusForbidden)
+		return
+	}
+
+	user, err := s.serviceDB.AuthenticateUser(req.Username, req.Password)
+	if err != nil {
+		s.recordUserAudit(database.AuditActionUserLogin, req.Username, req.Username, "failed", map[string]interface{}{
+			"remote_addr": r.RemoteAddr,
+		})
+		s.writeAPIError(w, "Invalid username or password", err)
+		return
+	}
+	if session, ok := s.startSession(w, r, user, nil); ok {
+		s.writeJSONResponse(w, session, http.StatusOK)
+	}
+}
+
+// handleLogout завершает сессию пользователя
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if session := sessionFromContext(r.Context()); session != nil {
+		if token, _ := sessionToken(r); token != "" {
+			if err := s.serviceDB.DeleteUserSession(token); err != nil {
+				s.writeAPIError(w, "Failed to end session", err)
+				return
+			}
+		}
+		s.recordUserAudit(database.AuditActionUserLogout, session.User.Username, session.User.Username, "success", nil)
+	}
+	s.clearSessionCookies(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUserPreferences настройки веб-интерфейса текущего пользователя (PUT заменяет их целиком)
+func (s *Server) handleUserPreferences(w http.ResponseWriter, r *http.Request) {
+	session := s.requireSession(w, r)
+	if session == nil {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var preferences map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := s.serviceDB.SetUserPreferences(session.UserID, preferences); err != nil {
+			s.writeAPIError(w, "Failed to save preferences", err)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	preferences, err := s.serviceDB.GetUserPreferences(session.UserID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to get preferences", err)
+		return
+	}
+	s.writeJSONResponse(w, preferences, http.StatusOK)
+}
+
+// oidcConfig возвращает настройки OIDC; ok=false, если вход через OIDC не настроен
+func (s *Server) oidcConfig() (OIDCConfig, bool) {
+	if s.currentConfig() == nil || !s.currentConfig().OIDC.Enabled() {
+		return OIDCConfig{}, false
+	}
+	return s.currentConfig().OIDC, true
+}
+
+// handleOIDCLogin перенаправляет на страницу входа провайдера OIDC. state и code_verifier PKCE
+// сохраняются в cookie до возврата от провайдера
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	config, ok := s.oidcConfig()
+	if !ok {
+		s.writeJSONError(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+	client := newOIDCClient(config)
+	discovery, err := client.discover(r.Context())
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	state, verifier, err := newOIDCVerifier()
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name: oidcStateCookieName, Value: state + "." + verifier, Path: "/api/auth/oidc", MaxAge: 600,
+		HttpOnly: true, Secure: s.secureCookies(r), SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, client.authorizationURL(discovery, state, verifier), http.StatusFound)
+}
+
+// handleOIDCCallback завершает вход через OIDC: проверяет state, получает сведения о пользователе,
+// создает пользователя при первом входе и перенаправляет в веб-интерфейс
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	config, ok := s.oidcConfig()
+	if !ok {
+		s.writeJSONError(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		s.writeJSONError(w, fmt.Sprintf("OIDC login failed: %s", providerErr), http.StatusUnauthorized)
+		return
+	}
+	cookie, err := r.Cookie(oidcStateCookieName)
+	state, verifier, found := strings.Cut(valueOrEmpty(cookie, err), ".")
+	if !found || query.Get("state") == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		s.writeJSONError(w, "Invalid OIDC state", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookieName, Value: "", Path: "/api/auth/oidc", MaxAge: -1})
+
+	client := newOIDCClient(config)
+	discovery, err := client.discover(r.Context())
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	info, err := client.exchange(r.Context(), discovery, query.Get("code"), verifier)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	user, ok := s.oidcUser(w, config, info)
+	if !ok {
+		return
+	}
+	if _, ok := s.startSession(w, r, user, map[string]interface{}{"groups": info.Groups}); ok {
+		http.Redirect(w, r, "/", http.StatusFound)
+	}
+}
+
+// oidcUser сопоставляет группы пользователя провайдера с ролью и возвращает пользователя сервера.
+// Пользователь без роли не допускается
+func (s *Server) oidcUser(w http.ResponseWriter, config OIDCConfig, info *oidcUserInfo) (*database.User, bool) {
+	fail := func(err error) {
+		s.recordUserAudit(database.AuditActionUserLogin, info.Subject, info.PreferredUsername, "failed", map[string]interface{}{
+			"auth_provider": database.AuthProviderOIDC,
+			"groups":        info.Groups,
+			"error":         err.Error(),
+		})
+	}
+	role := config.roleForGroups(info.Groups)
+	if role == "" {
+		fail(fmt.Errorf("no role is mapped to user groups"))
+		middleware.WriteJSONErrorCode(w, "No server role is mapped to your groups", "forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	user, err := s.serviceDB.UpsertOIDCUser(info.Subject, info.PreferredUsername, info.Email, info.Name, role)
+	if err != nil {
+		fail(err)
+		s.writeAPIError(w, "OIDC login failed", err)
+		return nil, false
+	}
+	return user, true
+}
+
+// handleTokenExchange обменивает токен доступа провайдера OIDC на токен API сервера, который
+// передается в заголовке Authorization: Bearer. Срок действия - как у сессии веб-интерфейса
+func (s *Server) handleTokenExchange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	config, ok := s.oidcConfig()
+	if !ok {
+		s.writeJSONError(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+	var req struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AccessToken == "" {
+		s.writeJSONError(w, "access_token is required", http.StatusBadRequest)
+		return
+	}
+
+	client := newOIDCClient(config)
+	discovery, err := client.discover(r.Context())
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	info, err := client.userInfo(r.Context(), discovery, req.AccessToken)
+	if err != nil {
+		middleware.WriteJSONErrorCode(w, err.Error(), "invalid_token", http.StatusUnauthorized)
+		return
+	}
+	user, ok := s.oidcUser(w, config, info)
+	if !ok {
+		return
+	}
+	token, session, ok := s.createSession(w, r, user, map[string]interface{}{"groups": info.Groups, "token_exchange": true})
+	if !ok {
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_at":   session.ExpiresAt,
+		"user":         session.User,
+	}, http.StatusOK)
+}
+
+// valueOrEmpty возвращает значение cookie или пустую строку, если cookie нет
+func valueOrEmpty(cookie *http.Cookie, err error) string {
+	if err != nil || cookie == nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// handleUsers пользователи веб-интерфейса. Управление пользователями доступно администраторам;
+// первого пользователя можно создать без входа, он становится администратором
+// GET /api/users
+// POST /api/users {"username": "ivanov", "password": "...", "display_name": "Иванов И.И.", "email": "...", "role": "operator"}
+// PUT /api/users/{id} {"is_active": false, "role": "viewer"}
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/users"), "/")
+
+	if path == "" && r.Method == http.MethodPost {
+		count, err := s.serviceDB.CountUsers()
+		if err != nil {
+			s.writeAPIError(w, "Failed to count users", err)
+			return
+		}
+		if count > 0 && s.requireSession(w, r) == nil {
+			return
+		}
+		if count == 0 && !s.bootstrapAllowed(r) {
+			middleware.WriteJSONErrorCode(w, "Creating the first administrator requires the setup token or break-glass credentials",
+				"setup_token_required", http.StatusForbidden)
+			return
+		}
+		s.createUser(w, r, count == 0)
+		return
+	}
+
+	if s.requireSession(w, r) == nil {
+		return
+	}
+	switch {
+	case path == "" && r.Method == http.MethodGet:
+		users, err := s.serviceDB.ListUsers()
+		if err != nil {
+			s.writeAPIError(w, "Failed to list users", err)
+			return
+		}
+		s.writeJSONResponse(w, users, http.StatusOK)
+	case path != "" && r.Method == http.MethodPut:
+		userID, err := strconv.Atoi(path)
+		if err != nil || userID <= 0 {
+			s.writeJSONError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		s.updateUser(w, r, userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// bootstrapAllowed проверяет право создать первого администратора: токен установки AUTH_SETUP_TOKEN
+// в заголовке X-Setup-Token или аварийная учетная запись в заголовке Authorization: Basic
+func (s *Server) bootstrapAllowed(r *http.Request) bool {
+	config := s.currentConfig()
+	if config == nil {
+		return false
+	}
+	if token := r.Header.Get(setupTokenHeader); config.AuthSetupToken != "" && token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(config.AuthSetupToken)) == 1
+	}
+	username, password, ok := r.BasicAuth()
+	return ok && s.breakGlassLogin(username, password)
+}
+
+// createUser создает пользователя со входом по паролю; первый пользователь - администратор
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request, first bool) {
+	var req struct {
+		Username    string `json:"username"`
+		Password    string `json:"password"`
+		DisplayName string `json:"display_name"`
+		Email       string `json:"email"`
+		Role        string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if first {
+		req.Role = database.RoleAdmin
+	}
+	user, err := s.serviceDB.CreateUser(req.Username, req.Password, req.DisplayName, req.Email, req.Role)
+	if err != nil {
+		s.writeAPIError(w, "Failed to create user", err)
+		return
+	}
+	s.recordUserAudit(database.AuditActionUserCreate, requestActor(r, "", r.RemoteAddr), user.Username, "success",
+		map[string]interface{}{"role": user.Role})
+	s.writeJSONResponse(w, user, http.StatusCreated)
+}
+
+// updateUser блокирует, разблокирует пользователя или меняет его роль. Свой доступ
+// администратор изменить не может, чтобы не остаться без администраторов
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request, userID int) {
+	var req struct {
+		IsActive *bool   `json:"is_active"`
+		Role     *string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.IsActive == nil && req.Role == nil) {
+		s.writeJSONError(w, "is_active or role is required", http.StatusBadRequest)
+		return
+	}
+	if session := sessionFromContext(r.Context()); session != nil && session.UserID == userID {
+		middleware.WriteJSONErrorCode(w, "You cannot change your own access", "cannot_change_own_access", http.StatusBadRequest)
+		return
+	}
+	if req.Role != nil {
+		if err := s.serviceDB.SetUserRole(userID, *req.Role); err != nil {
+			s.writeAPIError(w, "Failed to update user", err)
+			return
+		}
+	}
+	if req.IsActive != nil {
+		if err := s.serviceDB.SetUserActive(userID, *req.IsActive); err != nil {
+			s.writeAPIError(w, "Failed to update user", err)
+			return
+		}
+	}
+	user, err := s.serviceDB.GetUser(userID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to get user", err)
+		return
+	}
+	s.recordUserAudit(database.AuditActionUserUpdate, requestActor(r, "", r.RemoteAddr), user.Username, "success",
+		map[string]interface{}{"is_active": user.IsActive, "role": user.Role})
+	s.writeJSONResponse(w, user, http.StatusOK)
+}
+
+// recordUserAudit записывает действие с учетной записью в журнал аудита
+func (s *Server) recordUserAudit(action, actor, username, status string, details map[string]interface{}) {
+	err := s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action:  action,
+		Actor:   actor,
+		Target:  "user:" + username,
+		Status:  status,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Ошибка записи действия с учетной записью в журнал аудита: %v", err)
+	}
+}
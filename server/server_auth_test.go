@@ -0,0 +1,398 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+// newAuthTestServer создает сервер со служебной БД и обработчиками входа
+func newAuthTestServer(t *testing.T, config *Config) (*Server, http.Handler) {
+	t.Helper()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	t.Cleanup(func() { serviceDB.Close() })
+
+	s := &Server{logChan: make(chan LogEntry, 100), serviceDB: serviceDB, config: config}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/", s.handleAuthRoutes)
+	mux.HandleFunc("/api/users", s.handleUsers)
+	mux.HandleFunc("/api/users/", s.handleUsers)
+	return s, s.sessionMiddleware(mux)
+}
+
+func TestSessionLogin(t *testing.T) {
+	s, handler := newAuthTestServer(t, &Config{AuthSetupToken: "setup-token"})
+	var cookies []*http.Cookie
+	var csrfToken string
+	do := func(method, path, body string, withCSRF bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(setupTokenHeader, "setup-token")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		if withCSRF {
+			req.Header.Set(csrfHeaderName, csrfToken)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		withCSRF   bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"bootstrap first user", http.MethodPost, "/api/users", `{"username": "ivanov", "password": "s3cret-pass"}`, false, http.StatusCreated, `"role":"admin"`},
+		{"second user requires login", http.MethodPost, "/api/users", `{"username": "petrov", "password": "s3cret-pass"}`, false, http.StatusUnauthorized, "unauthorized"},
+		{"me requires login", http.MethodGet, "/api/auth/me", "", false, http.StatusUnauthorized, ""},
+		{"wrong password", http.MethodPost, "/api/auth/login", `{"username": "ivanov", "password": "wrong-pass"}`, false, http.StatusUnauthorized, "invalid_credentials"},
+		{"login", http.MethodPost, "/api/auth/login", `{"username": "ivanov", "password": "s3cret-pass"}`, false, http.StatusOK, `"csrf_token"`},
+		{"me", http.MethodGet, "/api/auth/me", "", false, http.StatusOK, `"username":"ivanov"`},
+		{"preferences without csrf", http.MethodPut, "/api/auth/me/preferences", `{"theme": "dark"}`, false, http.StatusForbidden, "csrf_token_invalid"},
+		{"preferences", http.MethodPut, "/api/auth/me/preferences", `{"theme": "dark"}`, true, http.StatusOK, `"theme":"dark"`},
+		{"create second user", http.MethodPost, "/api/users", `{"username": "petrov", "password": "s3cret-pass"}`, true, http.StatusCreated, `"role":"viewer"`},
+		{"cannot demote self", http.MethodPut, "/api/users/1", `{"role": "viewer"}`, true, http.StatusBadRequest, "cannot_change_own_access"},
+		{"promote second user", http.MethodPut, "/api/users/2", `{"role": "operator"}`, true, http.StatusOK, `"role":"operator"`},
+		{"list users", http.MethodGet, "/api/users", "", false, http.StatusOK, `"petrov"`},
+		{"logout", http.MethodPost, "/api/auth/logout", "", true, http.StatusNoContent, ""},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.method, tt.path, tt.body, tt.withCSRF)
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d, body = %s, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if tt.name == "login" {
+				cookies = rec.Result().Cookies()
+				var session database.UserSession
+				json.Unmarshal(rec.Body.Bytes(), &session)
+				csrfToken = session.CSRFToken
+			}
+		})
+	}
+
+	// Пользователь сессии записывается в журнал аудита вместо переданного в запросе имени
+	events, _ := s.serviceDB.GetAuditEvents(database.AuditActionUserCreate, 10)
+	if len(events) != 2 || events[0].Actor != "ivanov" || events[0].Target != "user:petrov" {
+		t.Errorf("audit events = %+v, want petrov created by ivanov", events)
+	}
+	// После выхода cookie сессии больше не действует
+	if rec := do(http.MethodGet, "/api/auth/me", "", false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("me after logout status = %d, want 401", rec.Code)
+	}
+}
+
+func TestBootstrapFirstAdmin(t *testing.T) {
+	hash, err := database.HashPassword("emergency-pass")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	tests := []struct {
+		name       string
+		config     *Config
+		setupToken string
+		basicAuth  []string
+		wantStatus int
+	}{
+		{"no setup token configured", nil, "setup-token", nil, http.StatusForbidden},
+		{"missing setup token", &Config{AuthSetupToken: "setup-token"}, "", nil, http.StatusForbidden},
+		{"wrong setup token", &Config{AuthSetupToken: "setup-token"}, "other-token", nil, http.StatusForbidden},
+		{"setup token", &Config{AuthSetupToken: "setup-token"}, "setup-token", nil, http.StatusCreated},
+		{"wrong break-glass password", &Config{BreakGlassUsername: "breakglass", BreakGlassPasswordHash: hash}, "",
+			[]string{"breakglass", "wrong-pass"}, http.StatusForbidden},
+		{"break-glass credentials", &Config{BreakGlassUsername: "breakglass", BreakGlassPasswordHash: hash}, "",
+			[]string{"breakglass", "emergency-pass"}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler := newAuthTestServer(t, tt.config)
+			req := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"username": "ivanov", "password": "s3cret-pass"}`))
+			if tt.setupToken != "" {
+				req.Header.Set(setupTokenHeader, tt.setupToken)
+			}
+			if tt.basicAuth != nil {
+				req.SetBasicAuth(tt.basicAuth[0], tt.basicAuth[1])
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, body = %s, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSessionRequired(t *testing.T) {
+	s, _ := newAuthTestServer(t, nil)
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux.HandleFunc("/api/admin/reload", ok)
+	mux.HandleFunc("/api/v1/upload/handshake", ok)
+	mux.HandleFunc("/handshake", ok)
+	handler := s.sessionMiddleware(mux)
+	do := func(path, cookie string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: cookie})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Пока пользователей нет, сервер работает без входа
+	if code := do("/api/admin/reload", ""); code != http.StatusOK {
+		t.Fatalf("reload without users status = %d, want 200", code)
+	}
+	if _, err := s.serviceDB.CreateUser("ivanov", "s3cret-pass", "", "", database.RoleAdmin); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		cookie     string
+		wantStatus int
+	}{
+		{"no cookie", "/api/admin/reload", "", http.StatusUnauthorized},
+		{"unknown session", "/api/admin/reload", "expired-token", http.StatusUnauthorized},
+		{"1C upload endpoint", "/api/v1/upload/handshake", "", http.StatusOK},
+		{"legacy 1C endpoint", "/handshake", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := do(tt.path, tt.cookie); code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// newOIDCProvider запускает тестовый провайдер OIDC: код good-code и токен access выдают
+// пользователя ivanov из группы nsi-operators
+func newOIDCProvider(t *testing.T) *httptest.Server {
+	t.Helper()
+	var provider *httptest.Server
+	provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"authorization_endpoint": provider.URL + "/auth",
+				"token_endpoint":         provider.URL + "/token",
+				"userinfo_endpoint":      provider.URL + "/userinfo",
+			})
+		case "/token":
+			r.ParseForm()
+			if r.PostForm.Get("code") != "good-code" || r.PostForm.Get("code_verifier") == "" {
+				http.Error(w, "invalid_grant", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "access"})
+		case "/userinfo":
+			if r.Header.Get("Authorization") != "Bearer access" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"sub": "sub-1", "preferred_username": "ivanov", "email": "ivanov@example.com",
+				"groups": []string{"all-staff", "nsi-operators"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(provider.Close)
+	return provider
+}
+
+// newOIDCTestConfig настройки входа через тестовый провайдер
+func newOIDCTestConfig(issuer, defaultRole string) *Config {
+	return &Config{OIDC: OIDCConfig{
+		IssuerURL:   issuer,
+		ClientID:    "httpserver",
+		RedirectURL: "http://localhost/api/auth/oidc/callback",
+		GroupsClaim: "groups",
+		RoleMapping: parseRoleMapping("nsi-admins=admin; nsi-operators=operator"),
+		DefaultRole: defaultRole,
+	}}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newOIDCProvider(t)
+	s, handler := newAuthTestServer(t, newOIDCTestConfig(provider.URL, ""))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("oidc login status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	state := location.Query().Get("state")
+	if location.Query().Get("code_challenge_method") != "S256" || state == "" {
+		t.Fatalf("authorization URL = %s", location)
+	}
+	stateCookies := rec.Result().Cookies()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"state mismatch", "code=good-code&state=other", http.StatusBadRequest},
+		{"rejected code", "code=bad-code&state=" + state, http.StatusUnauthorized},
+		{"success", "code=good-code&state=" + state, http.StatusFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?"+tt.query, nil)
+			for _, cookie := range stateCookies {
+				req.AddCookie(cookie)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+
+	users, _ := s.serviceDB.ListUsers()
+	if len(users) != 1 || users[0].AuthProvider != database.AuthProviderOIDC || users[0].Username != "ivanov" || users[0].Role != database.RoleOperator {
+		t.Errorf("users = %+v, want one OIDC operator ivanov", users)
+	}
+}
+
+func TestOIDCTokenExchange(t *testing.T) {
+	provider := newOIDCProvider(t)
+	_, handler := newAuthTestServer(t, newOIDCTestConfig(provider.URL, ""))
+	do := func(method, path, body, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/auth/token", `{"access_token": "forged"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("exchange of invalid token status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec := do(http.MethodPost, "/api/auth/token", `{"access_token": "access"}`, "")
+	var exchanged struct {
+		AccessToken string         `json:"access_token"`
+		User        *database.User `json:"user"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &exchanged)
+	if rec.Code != http.StatusOK || exchanged.AccessToken == "" || exchanged.User.Role != database.RoleOperator {
+		t.Fatalf("exchange status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"bearer without csrf", http.MethodPut, "/api/auth/me/preferences", "Bearer " + exchanged.AccessToken, http.StatusOK},
+		{"operator cannot manage users", http.MethodGet, "/api/users", "Bearer " + exchanged.AccessToken, http.StatusForbidden},
+		{"invalid bearer", http.MethodGet, "/api/auth/me", "Bearer unknown", http.StatusUnauthorized},
+		{"logout", http.MethodPost, "/api/auth/logout", "Bearer " + exchanged.AccessToken, http.StatusNoContent},
+		{"token revoked", http.MethodGet, "/api/auth/me", "Bearer " + exchanged.AccessToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(tt.method, tt.path, `{}`, tt.authorization); rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestOIDCRoleMapping(t *testing.T) {
+	config := newOIDCTestConfig("http://idp.local", database.RoleViewer).OIDC
+	tests := []struct {
+		name   string
+		groups []string
+		want   string
+	}{
+		{"highest mapped role", []string{"nsi-operators", "nsi-admins"}, database.RoleAdmin},
+		{"unmapped groups use default", []string{"all-staff"}, database.RoleViewer},
+		{"no groups", nil, database.RoleViewer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.roleForGroups(tt.groups); got != tt.want {
+				t.Errorf("roleForGroups(%v) = %q, want %q", tt.groups, got, tt.want)
+			}
+		})
+	}
+
+	config.DefaultRole = ""
+	if got := config.roleForGroups([]string{"all-staff"}); got != "" {
+		t.Errorf("roleForGroups() without default role = %q, want empty", got)
+	}
+	if got := parseRoleMapping("CN=NSI Admins,OU=Groups,DC=corp=admin;broken"); got["CN=NSI Admins,OU=Groups,DC=corp"] != "admin" || len(got) != 1 {
+		t.Errorf("parseRoleMapping() = %v", got)
+	}
+}
+
+func TestBreakGlassLogin(t *testing.T) {
+	hash, err := database.HashPassword("emergency-pass")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	config := newOIDCTestConfig("http://idp.local", database.RoleViewer)
+	config.SSOOnly = true
+	config.BreakGlassUsername = "breakglass"
+	config.BreakGlassPasswordHash = hash
+	s, handler := newAuthTestServer(t, config)
+	if _, err := s.serviceDB.CreateUser("ivanov", "s3cret-pass", "", "", database.RoleAdmin); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"password login disabled", `{"username": "ivanov", "password": "s3cret-pass"}`, http.StatusForbidden, "password_login_disabled"},
+		{"wrong break-glass password", `{"username": "breakglass", "password": "wrong-pass"}`, http.StatusForbidden, "password_login_disabled"},
+		{"break-glass login", `{"username": "breakglass", "password": "emergency-pass"}`, http.StatusOK, `"role":"admin"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d, body = %s, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	events, _ := s.serviceDB.GetAuditEvents(database.AuditActionUserLogin, 10)
+	if len(events) == 0 || events[0].Actor != "breakglass" || events[0].Details["break_glass"] != true {
+		t.Errorf("last login audit event = %+v, want break-glass login", events)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/providers", nil))
+	if !strings.Contains(rec.Body.String(), `"password":false`) || !strings.Contains(rec.Body.String(), `"oidc":true`) {
+		t.Errorf("providers = %s", rec.Body.String())
+	}
+}
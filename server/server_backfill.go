@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+// interruptBackfillJobs приостанавливает задания заполнения, прерванные остановкой сервера
+func (s *Server) interruptBackfillJobs() {
+	if s.normalizedDB == nil {
+		return
+	}
+	count, err := s.normalizedDB.PauseInterruptedBackfillJobs()
+	if err != nil {
+		log.Printf("Ошибка приостановки прерванных заданий заполнения: %v", err)
+		return
+	}
+	if count > 0 {
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "WARN",
+			Message:   fmt.Sprintf("Заданий заполнения, прерванных остановкой сервера: %d, продолжение: POST /api/backfill/jobs/{id}/resume", count),
+		})
+	}
+}
+
+// startBackfillJob запускает задание в фоне; отмена через pauseBackfillJob или остановку сервера.
+// Задание выполняется над копией: переданное значение остается снимком для ответа
+func (s *Server) startBackfillJob(snapshot *database.BackfillJob) error {
+	job := *snapshot
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.backfillJobsMutex.Lock()
+	if _, running := s.backfillJobs[job.ID]; running {
+		s.backfillJobsMutex.Unlock()
+		cancel()
+		return apperrors.Conflict("backfill_running", fmt.Sprintf("backfill job %d is already running", job.ID))
+	}
+	s.backfillJobs[job.ID] = cancel
+	s.backfillJobsMutex.Unlock()
+
+	go func() {
+		defer s.recoverWorker("backfill", nil)
+		defer func() {
+			s.backfillJobsMutex.Lock()
+			delete(s.backfillJobs, job.ID)
+			s.backfillJobsMutex.Unlock()
+			cancel()
+		}()
+		go func() {
+			select {
+			case <-s.shutdownChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		err := normalization.RunBackfill(ctx, s.normalizedDB, &job, nil)
+		level, message := "INFO", fmt.Sprintf("Задание заполнения %d (%s): %s, обработано %d из %d, изменено %d, ошибок %d",
+			job.ID, job.Stage, job.Status, job.Processed, job.Total, job.Updated, job.Failed)
+		if err != nil && ctx.Err() == nil {
+			level, message = "ERROR", fmt.Sprintf("Задание заполнения %d (%s) остановлено ошибкой: %v", job.ID, job.Stage, err)
+		}
+		s.log(LogEntry{Timestamp: time.Now(), Level: level, Message: message, Endpoint: "/api/backfill/jobs"})
+	}()
+	return nil
+}
+
+// pauseBackfillJob останавливает выполняющееся задание; позиция сохраняется после текущего пакета
+func (s *Server) pauseBackfillJob(id int) bool {
+	s.backfillJobsMutex.Lock()
+	defer s.backfillJobsMutex.Unlock()
+	cancel, running := s.backfillJobs[id]
+	if running {
+		cancel()
+	}
+	return running
+}
+
+// createBackfillRequest запрос создания задания заполнения
+type createBackfillRequest struct {
+	Stage      string                 `json:"stage"`
+	Scope      database.BackfillScope `json:"scope"`
+	BatchSize  int                    `json:"batch_size"`
+	ThrottleMs int                    `json:"throttle_ms"`
+}
+
+// handleBackfillStages список зарегистрированных этапов заполнения
+// GET /api/backfill/stages
+func (s *Server) handleBackfillStages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stages := []map[string]string{}
+	for _, stage := range normalization.BackfillStages() {
+		stages = append(stages, map[string]string{
+			"name":        stage.Name(),
+			"column":      stage.Column(),
+			"description": stage.Description(),
+		})
+	}
+	s.writeJSONResponse(w, map[string]interface{}{"stages": stages}, http.StatusOK)
+}
+
+// handleBackfillJobs список заданий заполнения или создание и запуск задания
+// GET /api/backfill/jobs?limit=20
+// POST /api/backfill/jobs {"stage": "standard_references", "scope": {"category": "...", "only_empty": true}, "batch_size": 1000, "throttle_ms": 100}
+func (s *Server) handleBackfillJobs(w http.ResponseWriter, r *http.Request) {
+	if s.normalizedDB == nil {
+		s.writeJSONError(w, "Normalized database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		limit := 20
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 1000 {
+			limit = v
+		}
+		jobs, err := s.normalizedDB.ListBackfillJobs(limit)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if jobs == nil {
+			jobs = []*database.BackfillJob{}
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"jobs":  jobs,
+			"total": len(jobs),
+		}, http.StatusOK)
+	case http.MethodPost:
+		var req createBackfillRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if _, ok := normalization.LookupBackfillStage(req.Stage); !ok {
+			s.writeJSONError(w, fmt.Sprintf("Unknown stage: %s", req.Stage), http.StatusBadRequest)
+			return
+		}
+		if req.BatchSize < 0 || req.ThrottleMs < 0 {
+			s.writeJSONError(w, "batch_size and throttle_ms must not be negative", http.StatusBadRequest)
+			return
+		}
+		job, err := normalization.NewBackfillJob(s.normalizedDB, req.Stage, req.Scope, req.BatchSize,
+			time.Duration(req.ThrottleMs)*time.Millisecond)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		if err := s.startBackfillJob(job); err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, job, http.StatusAccepted)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBackfillJobRoutes прогресс задания, его приостановка и продолжение с сохраненной позиции
+// GET /api/backfill/jobs/{id}
+// POST /api/backfill/jobs/{id}/pause
+// POST /api/backfill/jobs/{id}/resume
+func (s *Server) handleBackfillJobRoutes(w http.ResponseWriter, r *http.Request) {
+	if s.normalizedDB == nil {
+		s.writeJSONError(w, "Normalized database not available", http.StatusServiceUnavailable)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/backfill/jobs/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 {
+		s.writeJSONError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		job, err := s.normalizedDB.GetBackfillJob(id)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, job, http.StatusOK)
+	case len(parts) == 2 && parts[1] == "pause":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !s.pauseBackfillJob(id) {
+			s.writeJSONError(w, fmt.Sprintf("Backfill job %d is not running", id), http.StatusConflict)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{"id": id, "status": "pausing"}, http.StatusAccepted)
+	case len(parts) == 2 && parts[1] == "resume":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		job, err := s.normalizedDB.GetBackfillJob(id)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		if job.Status == database.BackfillCompleted {
+			s.writeJSONError(w, fmt.Sprintf("Backfill job %d is already completed", id), http.StatusConflict)
+			return
+		}
+		if err := s.startBackfillJob(job); err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, job, http.StatusAccepted)
+	default:
+		http.NotFound(w, r)
+	}
+}
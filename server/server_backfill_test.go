@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestBackfillHandlers(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category)
+		VALUES ('r1', 'Болт ГОСТ 7798-70', '1', 'болт', 'крепеж'), ('r2', 'Гайка', '2', 'гайка', 'крепеж')`); err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+
+	s := &Server{
+		logChan:      make(chan LogEntry, 100),
+		normalizedDB: db,
+		config:       &Config{},
+		shutdownChan: make(chan struct{}),
+		backfillJobs: make(map[int]context.CancelFunc),
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleBackfillJobs(rec, httptest.NewRequest(http.MethodPost, "/api/backfill/jobs", strings.NewReader(`{"stage":"standard_references","batch_size":1}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("create status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Ожидаем завершения задания в фоне
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := db.GetBackfillJob(1)
+		if err == nil && job.Status == database.BackfillCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("backfill job not completed: %+v, %v", job, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"stages", http.MethodGet, "/api/backfill/stages", "", http.StatusOK, `"column":"standard_references"`},
+		{"unknown stage", http.MethodPost, "/api/backfill/jobs", `{"stage":"units"}`, http.StatusBadRequest, ""},
+		{"negative batch", http.MethodPost, "/api/backfill/jobs", `{"stage":"standard_references","batch_size":-1}`, http.StatusBadRequest, ""},
+		{"list", http.MethodGet, "/api/backfill/jobs", "", http.StatusOK, `"total":1`},
+		{"progress", http.MethodGet, "/api/backfill/jobs/1", "", http.StatusOK, `"updated":1`},
+		{"missing", http.MethodGet, "/api/backfill/jobs/9", "", http.StatusNotFound, ""},
+		{"pause not running", http.MethodPost, "/api/backfill/jobs/1/pause", "", http.StatusConflict, ""},
+		{"resume completed", http.MethodPost, "/api/backfill/jobs/1/resume", "", http.StatusConflict, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			switch {
+			case tt.path == "/api/backfill/stages":
+				s.handleBackfillStages(rec, req)
+			case tt.path == "/api/backfill/jobs":
+				s.handleBackfillJobs(rec, req)
+			default:
+				s.handleBackfillJobRoutes(rec, req)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+// recordClassificationOutcome записывает проверенный исход группы и сбрасывает кэш кривых калибровки.
+// Ошибки только логируются: пометка группы не должна зависеть от сбора статистики.
+func (s *Server) recordClassificationOutcome(normalizedName, category string, correct bool) {
+	if err := s.db.RecordExperimentFeedback(normalizedName, category, correct); err != nil {
+		log.Printf("[Calibration] Failed to record experiment feedback for %s / %s: %v", normalizedName, category, err)
+	}
+	recorded, err := s.db.RecordGroupOutcome(normalizedName, category, correct)
+	if err != nil {
+		log.Printf("[Calibration] Failed to record outcome for %s / %s: %v", normalizedName, category, err)
+		return
+	}
+	if recorded && s.calibrator != nil {
+		s.calibrator.Invalidate()
+	}
+}
+
+// handleCalibrationCurves возвращает кривые калибровки (всех моделей или ?model=)
+func (s *Server) handleCalibrationCurves(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var models []string
+	if _, ok := r.URL.Query()["model"]; ok {
+		models = []string{r.URL.Query().Get("model")}
+	} else {
+		var err error
+		models, err = s.db.GetOutcomeModels()
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get models: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	curves := []*normalization.CalibrationCurve{}
+	for _, model := range models {
+		curve, err := s.calibrator.Curve(model)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to fit calibration curve: %v", err), http.StatusInternalServerError)
+			return
+		}
+		curves = append(curves, curve)
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"curves": curves,
+		"total":  len(curves),
+	}, http.StatusOK)
+}
+
+// handleCalibrationPolicies возвращает (GET) или сохраняет (PUT/POST) политики порогов уверенности
+func (s *Server) handleCalibrationPolicies(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		policies, err := s.db.GetConfidencePolicies()
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get policies: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"policies": policies,
+			"default":  database.DefaultConfidencePolicy(),
+		}, http.StatusOK)
+
+	case http.MethodPut, http.MethodPost:
+		var policy database.ConfidencePolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if policy.ProjectID < 0 {
+			s.writeJSONError(w, "project_id must not be negative", http.StatusBadRequest)
+			return
+		}
+		if err := policy.Validate(); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.db.SaveConfidencePolicy(policy); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to save policy: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message: fmt.Sprintf("Confidence policy for project %d updated: accept >= %.2f, review >= %.2f",
+				policy.ProjectID, policy.AcceptThreshold, policy.ReviewThreshold),
+			Endpoint: "/api/calibration/policies",
+		})
+
+		saved, err := s.db.GetConfidencePolicy(policy.ProjectID)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get policy: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, saved, http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCalibrationReviewQueue возвращает группы, поставленные политикой на проверку
+func (s *Server) handleCalibrationReviewQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 1000 {
+		limit = v
+	}
+	offset := 0
+	if v, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && v > 0 {
+		offset = v
+	}
+
+	items, total, err := s.db.GetReviewQueue(limit, offset)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get review queue: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"items":  items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	}, http.StatusOK)
+}
+
+// handleCalibrationEvaluate калибрует уверенность и возвращает решение политики (для проверки настроек)
+func (s *Server) handleCalibrationEvaluate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Model      string  `json:"model"`
+		ProjectID  int     `json:"project_id"`
+		Confidence float64 `json:"confidence"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Confidence < 0 || req.Confidence > 1 {
+		s.writeJSONError(w, "confidence must be within [0, 1]", http.StatusBadRequest)
+		return
+	}
+
+	s.writeJSONResponse(w, s.calibrator.Evaluate(req.Model, req.ProjectID, req.Confidence), http.StatusOK)
+}
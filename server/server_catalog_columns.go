@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+	"httpserver/encryption"
+	"httpserver/server/middleware"
+)
+
+// catalogsDB возвращает БД, в которой хранятся динамические таблицы справочников
+func (s *Server) catalogsDB() *database.DB {
+	if s.unifiedCatalogsDB != nil {
+		return s.unifiedCatalogsDB
+	}
+	return s.db
+}
+
+// handleCatalogColumnMappings управляет маппингами реквизитов справочников на индексируемые колонки
+// GET    /api/catalogs/column-mappings?catalog=Номенклатура
+// POST   /api/catalogs/column-mappings {"catalog_name","attribute_name","column_type","backfill"}
+// DELETE /api/catalogs/column-mappings?catalog=Номенклатура&attribute=Производитель
+func (s *Server) handleCatalogColumnMappings(w http.ResponseWriter, r *http.Request) {
+	db := s.catalogsDB()
+	if db == nil {
+		s.writeJSONError(w, "Catalogs database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		mappings, err := database.GetCatalogColumnMappings(db.GetDB(), r.URL.Query().Get("catalog"))
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get column mappings: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"mappings": mappings,
+			"total":    len(mappings),
+		}, http.StatusOK)
+
+	case http.MethodPost:
+		var req struct {
+			CatalogName   string `json:"catalog_name"`
+			AttributeName string `json:"attribute_name"`
+			ColumnType    string `json:"column_type"`
+			Backfill      bool   `json:"backfill"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		mapping, err := database.SaveCatalogColumnMapping(db.GetDB(), req.CatalogName, req.AttributeName, req.ColumnType, "api")
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to save column mapping: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Заполняем колонку для уже загруженных элементов
+		backfilled := 0
+		if req.Backfill {
+			if tableName, err := database.GetCatalogTableName(db.GetDB(), req.CatalogName); err == nil {
+				backfilled, err = database.BackfillCatalogColumn(db.GetDB(), tableName, *mapping, encryption.IsSealed)
+				if err != nil {
+					s.writeJSONError(w, fmt.Sprintf("Failed to backfill column: %v", err), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Column mapping %s.%s -> %s (%s), backfilled %d", mapping.CatalogName, mapping.AttributeName, mapping.ColumnName, mapping.ColumnType, backfilled),
+			Endpoint:  "/api/catalogs/column-mappings",
+		})
+
+		s.writeJSONResponse(w, map[string]interface{}{
+			"mapping":    mapping,
+			"backfilled": backfilled,
+		}, http.StatusCreated)
+
+	case http.MethodDelete:
+		query := r.URL.Query()
+		catalogName := query.Get("catalog")
+		attributeName := query.Get("attribute")
+		if catalogName == "" || attributeName == "" {
+			s.writeJSONError(w, "catalog and attribute parameters are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := database.DeleteCatalogColumnMapping(db.GetDB(), catalogName, attributeName); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		s.writeJSONResponse(w, map[string]interface{}{"success": true}, http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCatalogItemsSearch ищет элементы справочника по значению реквизита
+// GET /api/catalogs/items/search?catalog=Номенклатура&attribute=Производитель&value=X&limit=100&offset=0
+func (s *Server) handleCatalogItemsSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := s.catalogsDB()
+	if db == nil {
+		s.writeJSONError(w, "Catalogs database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	catalogName := query.Get("catalog")
+	attributeName := query.Get("attribute")
+	value := query.Get("value")
+	if catalogName == "" || attributeName == "" {
+		s.writeJSONError(w, "catalog and attribute parameters are required", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	redaction, err := s.requestRedaction(r)
+	if err != nil {
+		s.writeAPIError(w, "Failed to resolve redaction profile", err)
+		return
+	}
+	// Поиск по скрытому реквизиту раскрыл бы его значение
+	if redaction.Redacts(database.RedactionAttributePrefix + attributeName) {
+		middleware.WriteJSONErrorCode(w, "Attribute is hidden by the redaction profile", "attribute_redacted", http.StatusForbidden)
+		return
+	}
+
+	items, indexed, err := database.SearchCatalogItemsByAttribute(db.GetDB(), catalogName, attributeName, value, limit, offset)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to search catalog items: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Реквизиты приводятся к типам из метаданных выгрузки, если они были присланы
+	metadataCache := catalogMetadataCache{}
+	for _, item := range items {
+		uploadID, _ := item["upload_id"].(int)
+		attributes, _ := item["attributes"].(string)
+		// Зашифрованные реквизиты раскрываются только пользователям с правом расшифровки,
+		// скрытые профилем поля не выдаются
+		code, _ := item["code"].(string)
+		name, _ := item["name"].(string)
+		redacted := &database.CatalogItem{Code: code, Name: name, Attributes: s.revealValue(r, attributes)}
+		redaction.RedactCatalogItem(redacted)
+		attributes = redacted.Attributes
+		item["code"], item["name"], item["attributes"] = redacted.Code, redacted.Name, attributes
+		if metadata := metadataCache.get(db, uploadID, catalogName); metadata != nil {
+			item["values"] = metadata.CastAttributeValues(database.ExtractAttributeValues(attributes))
+		}
+	}
+
+	s.recordRedactionUsage(requestActor(r, "", "api"), redaction, "/api/catalogs/items/search", "catalog:"+catalogName)
+	s.writeJSONResponse(w, map[string]interface{}{
+		"items":   items,
+		"total":   len(items),
+		"indexed": indexed,
+	}, http.StatusOK)
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"httpserver/database"
+)
+
+// CatalogTreeResponse дерево групп и элементов справочника выгрузки
+type CatalogTreeResponse struct {
+	UploadUUID  string                      `json:"upload_uuid"`
+	CatalogName string                      `json:"catalog_name"`
+	GroupsOnly  bool                        `json:"groups_only"`
+	Nodes       []*database.CatalogTreeNode `json:"nodes"`
+}
+
+// catalogHierarchyNode определяет положение элемента в иерархии групп 1С: поля протокола
+// parent_reference/is_group, а если они не переданы - реквизиты Родитель и ЭтоГруппа
+func catalogHierarchyNode(reference, code, name, parentReference string, isGroup bool, attributes string) database.CatalogHierarchyNode {
+	if parentReference == "" || !isGroup {
+		values := database.ExtractAttributeValues(attributes)
+		if parentReference == "" {
+			parentReference = values["Родитель"]
+		}
+		if !isGroup {
+			isGroup = database.ParseIsGroup(values["ЭтоГруппа"])
+		}
+	}
+	return database.CatalogHierarchyNode{
+		Reference:       reference,
+		ParentReference: database.NormalizeParentReference(parentReference),
+		IsGroup:         isGroup,
+		Code:            code,
+		Name:            name,
+	}
+}
+
+// saveCatalogHierarchy сохраняет иерархию принятых элементов; ошибка не отменяет прием элементов
+func (s *Server) saveCatalogHierarchy(uploadDB *database.DB, upload *database.Upload, catalogName string, nodes []database.CatalogHierarchyNode) {
+	if err := uploadDB.SaveCatalogHierarchy(upload.ID, catalogName, nodes); err != nil {
+		log.Printf("Warning: Failed to save hierarchy of catalog %s in upload %s: %v", catalogName, upload.UploadUUID, err)
+	}
+}
+
+// handleUploadCatalogTree возвращает дерево групп 1С справочника выгрузки
+// GET /api/uploads/{uuid}/catalogs/{name}/tree?groups_only=true
+func (s *Server) handleUploadCatalogTree(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload, catalogName string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	groupsOnly := r.URL.Query().Get("groups_only") == "true"
+
+	nodes, err := uploadDB.GetCatalogTree(upload.ID, catalogName, groupsOnly)
+	if err != nil {
+		s.writeJSONError(w, "Failed to get catalog tree", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, CatalogTreeResponse{
+		UploadUUID:  upload.UploadUUID,
+		CatalogName: catalogName,
+		GroupsOnly:  groupsOnly,
+		Nodes:       nodes,
+	}, http.StatusOK)
+}
@@ -0,0 +1,32 @@
+package server
+
+import "testing"
+
+func TestCatalogHierarchyNode(t *testing.T) {
+	tests := []struct {
+		name            string
+		parentReference string
+		isGroup         bool
+		attributes      string
+		wantParent      string
+		wantGroup       bool
+	}{
+		{"protocol fields", "g1", true, `<Родитель>other</Родитель>`, "g1", true},
+		{"xml attributes fallback", "", false, `<Родитель>g2</Родитель><ЭтоГруппа>Истина</ЭтоГруппа>`, "g2", true},
+		{"json attributes fallback", "", false, `{"Родитель":"g3","ЭтоГруппа":"false"}`, "g3", false},
+		{"empty reference is root", "00000000-0000-0000-0000-000000000000", false, "", "", false},
+		{"no hierarchy", "", false, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := catalogHierarchyNode("ref", "001", "Болт", tt.parentReference, tt.isGroup, tt.attributes)
+			if node.ParentReference != tt.wantParent || node.IsGroup != tt.wantGroup {
+				t.Errorf("catalogHierarchyNode() = parent %q group %v, want parent %q group %v",
+					node.ParentReference, node.IsGroup, tt.wantParent, tt.wantGroup)
+			}
+			if node.Reference != "ref" || node.Name != "Болт" {
+				t.Errorf("catalogHierarchyNode() lost identity: %+v", node)
+			}
+		})
+	}
+}
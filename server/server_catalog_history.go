@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"httpserver/database"
+)
+
+// handleCatalogItemHistory история изменений элемента справочника по выгрузкам: версии с выгрузкой,
+// состоянием элемента и изменившимися полями (старое и новое значение)
+// GET /api/catalogs/items/history?reference=...&catalog=Номенклатура&database_id=1
+func (s *Server) handleCatalogItemHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	db := s.catalogsDB()
+	if db == nil {
+		s.writeJSONError(w, "Catalogs database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	reference := query.Get("reference")
+	if reference == "" {
+		s.writeJSONError(w, "reference parameter is required", http.StatusBadRequest)
+		return
+	}
+	databaseID := 0
+	if value := query.Get("database_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			s.writeJSONError(w, "Invalid database_id", http.StatusBadRequest)
+			return
+		}
+		databaseID = parsed
+	}
+
+	redaction, err := s.requestRedaction(r)
+	if err != nil {
+		s.writeAPIError(w, "Failed to resolve redaction profile", err)
+		return
+	}
+
+	versions, err := db.GetCatalogItemHistory(reference, query.Get("catalog"), databaseID)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get item history: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if versions == nil {
+		versions = []*database.CatalogItemVersion{}
+	}
+	redaction.RedactCatalogItemVersions(versions)
+	s.recordRedactionUsage(requestActor(r, "", "api"), redaction, "/api/catalogs/items/history", "reference:"+reference)
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"reference": reference,
+		"versions":  versions,
+		"total":     len(versions),
+	}, http.StatusOK)
+}
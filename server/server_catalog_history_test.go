@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestHandleCatalogItemHistory(t *testing.T) {
+	db, err := database.NewUnifiedDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewUnifiedDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+
+	tableName, err := database.GetOrCreateCatalogTable(db.GetDB(), "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetOrCreateCatalogTable() error = %v", err)
+	}
+	for i, name := range []string{"Болт", "Болт М10"} {
+		upload, err := db.CreateUpload([]string{"u1", "u2"}[i], "8.3", "УправлениеТорговлей")
+		if err != nil {
+			t.Fatalf("CreateUpload() error = %v", err)
+		}
+		if err := db.AddCatalogItemToTable(tableName, upload.ID, "ref-1", "0001", name, "", ""); err != nil {
+			t.Fatalf("AddCatalogItemToTable() error = %v", err)
+		}
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), unifiedCatalogsDB: db, config: &Config{}}
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"history", "?reference=ref-1", http.StatusOK, `"name":{"old":"Болт","new":"Болт М10"}`},
+		{"catalog filter", "?reference=ref-1&catalog=Контрагенты", http.StatusOK, `"total":0`},
+		{"missing reference", "", http.StatusBadRequest, ""},
+		{"invalid database", "?reference=ref-1&database_id=x", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleCatalogItemHistory(rec, httptest.NewRequest(http.MethodGet, "/api/catalogs/items/history"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// toCatalogMetadata преобразует описание справочника из XML 1С в метаданные для хранения
+func toCatalogMetadata(c MetadataCatalog, uploadID int) *database.CatalogMetadata {
+	metadata := &database.CatalogMetadata{
+		UploadID:    uploadID,
+		CatalogName: strings.TrimSpace(c.Name),
+		Synonym:     strings.TrimSpace(c.Synonym),
+		Attributes:  convertMetadataAttributes(c.Attributes),
+	}
+	for _, part := range c.TableParts {
+		metadata.TableParts = append(metadata.TableParts, database.CatalogTablePartDefinition{
+			Name:       strings.TrimSpace(part.Name),
+			Synonym:    strings.TrimSpace(part.Synonym),
+			Attributes: convertMetadataAttributes(part.Attributes),
+		})
+	}
+	return metadata
+}
+
+// convertMetadataAttributes преобразует реквизиты из XML 1С
+func convertMetadataAttributes(attributes []MetadataAttribute) []database.CatalogAttributeDefinition {
+	result := make([]database.CatalogAttributeDefinition, 0, len(attributes))
+	for _, attr := range attributes {
+		result = append(result, database.NewCatalogAttributeDefinition(attr.Name, attr.Synonym, attr.Type,
+			attr.Length, attr.Precision, attr.Scale, attr.Required))
+	}
+	return result
+}
+
+// catalogMetadataCache метаданные справочников в рамках одного запроса (ключ - upload_id и имя справочника)
+type catalogMetadataCache map[string]*database.CatalogMetadata
+
+// get возвращает метаданные справочника выгрузки, загружая их при первом обращении
+func (c catalogMetadataCache) get(db *database.DB, uploadID int, catalogName string) *database.CatalogMetadata {
+	key := fmt.Sprintf("%d/%s", uploadID, catalogName)
+	if metadata, ok := c[key]; ok {
+		return metadata
+	}
+	metadata, err := database.GetCatalogMetadata(db.GetDB(), uploadID, catalogName)
+	if err != nil {
+		metadata = nil
+	}
+	c[key] = metadata
+	return metadata
+}
+
+// Режимы проверки элементов справочников по метаданным при загрузке
+const (
+	IngestValidationOff    = "off"    // Не проверять
+	IngestValidationWarn   = "warn"   // Логировать несоответствия
+	IngestValidationRecord = "record" // Записывать несоответствия как проблемы качества
+	IngestValidationStrict = "strict" // Записывать и отклонять элементы с ошибками
+)
+
+// ingestValidationMode возвращает режим проверки элементов при загрузке
+func (s *Server) ingestValidationMode() string {
+	if s.currentConfig() == nil || s.currentConfig().IngestValidationMode == "" {
+		return IngestValidationWarn
+	}
+	return s.currentConfig().IngestValidationMode
+}
+
+// validateCatalogItem сверяет реквизиты элемента с метаданными справочника.
+// Возвращает найденные нарушения и признак того, что элемент нужно отклонить (режим strict).
+// Неизвестные реквизиты не приводят к отклонению: 1С может передавать реквизиты расширений.
+func (s *Server) validateCatalogItem(uploadDB *database.DB, upload *database.Upload, catalogName, reference, attributes string) ([]database.AttributeViolation, bool) {
+	mode := s.ingestValidationMode()
+	if mode == IngestValidationOff {
+		return nil, false
+	}
+
+	metadata, err := database.GetCatalogMetadata(uploadDB.GetDB(), upload.ID, catalogName)
+	if err != nil || metadata == nil {
+		return nil, false
+	}
+
+	violations := metadata.ValidateAttributes(attributes)
+	if len(violations) == 0 {
+		return nil, false
+	}
+
+	s.log(LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "WARNING",
+		Message:    fmt.Sprintf("Catalog item %s (%s) does not match metadata: %s", reference, catalogName, formatAttributeViolations(violations)),
+		UploadUUID: upload.UploadUUID,
+		Endpoint:   "/catalog/item",
+	})
+
+	if mode == IngestValidationRecord || mode == IngestValidationStrict {
+		s.recordAttributeViolations(uploadDB, upload, catalogName, reference, violations)
+	}
+
+	reject := false
+	if mode == IngestValidationStrict {
+		for _, violation := range violations {
+			if violation.Type != database.AttributeViolationUnknown {
+				reject = true
+				break
+			}
+		}
+	}
+	return violations, reject
+}
+
+// recordAttributeViolations сохраняет нарушения как проблемы качества выгрузки
+func (s *Server) recordAttributeViolations(uploadDB *database.DB, upload *database.Upload, catalogName, reference string, violations []database.AttributeViolation) {
+	databaseID := 0
+	if upload.DatabaseID != nil {
+		databaseID = *upload.DatabaseID
+	}
+	for _, violation := range violations {
+		issue := database.DataQualityIssue{
+			UploadID:        upload.ID,
+			DatabaseID:      databaseID,
+			EntityType:      "catalog_item",
+			EntityReference: reference,
+			IssueType:       violation.Type,
+			IssueSeverity:   violation.Severity(),
+			FieldName:       violation.Attribute,
+			ExpectedValue:   violation.Expected,
+			ActualValue:     violation.Actual,
+			Description:     fmt.Sprintf("Элемент справочника %s не соответствует метаданным: %s", catalogName, violation.Message),
+			DetectedAt:      time.Now(),
+			Status:          "OPEN",
+		}
+		if err := uploadDB.SaveQualityIssue(&issue); err != nil {
+			log.Printf("Warning: Failed to save metadata violation for %s: %v", reference, err)
+			return
+		}
+	}
+}
+
+// formatAttributeViolations формирует краткое описание нарушений для лога и ответа
+func formatAttributeViolations(violations []database.AttributeViolation) string {
+	details := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		if violation.Attribute != "" {
+			details = append(details, fmt.Sprintf("%s: %s", violation.Attribute, violation.Message))
+		} else {
+			details = append(details, violation.Message)
+		}
+	}
+	return strings.Join(details, "; ")
+}
+
+// handleUploadSchema возвращает метаданные справочников выгрузки
+// GET /api/uploads/{uuid}/schema?catalog=Номенклатура
+func (s *Server) handleUploadSchema(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	catalogs := []database.CatalogMetadata{}
+	if catalogName := r.URL.Query().Get("catalog"); catalogName != "" {
+		metadata, err := database.GetCatalogMetadata(uploadDB.GetDB(), upload.ID, catalogName)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get catalog metadata: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if metadata == nil {
+			s.writeJSONError(w, "Catalog metadata not found", http.StatusNotFound)
+			return
+		}
+		catalogs = append(catalogs, *metadata)
+	} else {
+		var err error
+		if catalogs, err = database.GetUploadCatalogMetadata(uploadDB.GetDB(), upload.ID); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get catalog metadata: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"upload_uuid": upload.UploadUUID,
+		"catalogs":    catalogs,
+		"total":       len(catalogs),
+	}, http.StatusOK)
+}
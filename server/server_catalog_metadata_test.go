@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestCatalogMetadataIngest(t *testing.T) {
+	db, err := database.NewUnifiedDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer db.Close()
+	upload, err := db.CreateUpload("uuid-metadata", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	s := &Server{
+		db:      db,
+		config:  &Config{IngestValidationMode: IngestValidationStrict},
+		logChan: make(chan LogEntry, 100),
+	}
+	s.uploadDBs.putShared(upload.UploadUUID, db)
+
+	post := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(s.handleMetadata, `<metadata>
+		<upload_uuid>uuid-metadata</upload_uuid>
+		<version_1c>8.3</version_1c>
+		<config_name>УправлениеТорговлей</config_name>
+		<catalogs><catalog>
+			<name>Номенклатура</name>
+			<attributes>
+				<attribute><name>Артикул</name><type>Строка</type><length>10</length><required>true</required></attribute>
+				<attribute><name>Вес</name><type>Число</type><precision>15</precision><scale>3</scale></attribute>
+			</attributes>
+			<table_parts><table_part><name>Штрихкоды</name>
+				<attributes><attribute><name>Штрихкод</name><type>Строка</type></attribute></attributes>
+			</table_part></table_parts>
+		</catalog></catalogs>
+	</metadata>`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /metadata status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	schemaRec := httptest.NewRecorder()
+	s.handleUploadRoutes(schemaRec, httptest.NewRequest(http.MethodGet, "/api/uploads/uuid-metadata/schema", nil))
+	var schema struct {
+		Catalogs []database.CatalogMetadata `json:"catalogs"`
+	}
+	json.NewDecoder(schemaRec.Body).Decode(&schema)
+	if len(schema.Catalogs) != 1 || len(schema.Catalogs[0].Attributes) != 2 || len(schema.Catalogs[0].TableParts) != 1 {
+		t.Fatalf("unexpected schema: %s", schemaRec.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		attributes string
+		wantStatus int
+		wantIssues []string
+	}{
+		{"valid", "&lt;Артикул&gt;A-1&lt;/Артикул&gt;&lt;Вес&gt;1,5&lt;/Вес&gt;", http.StatusOK, nil},
+		{"unknown attribute is recorded", "&lt;Артикул&gt;A-2&lt;/Артикул&gt;&lt;Цвет&gt;красный&lt;/Цвет&gt;", http.StatusOK, []string{database.AttributeViolationUnknown}},
+		{"type mismatch", "&lt;Артикул&gt;A-3&lt;/Артикул&gt;&lt;Вес&gt;тяжелый&lt;/Вес&gt;", http.StatusUnprocessableEntity, []string{database.AttributeViolationTypeMismatch}},
+		{"missing required", "&lt;Вес&gt;2&lt;/Вес&gt;", http.StatusUnprocessableEntity, []string{database.AttributeViolationMissingRequired}},
+		{"malformed xml", "&lt;Артикул&gt;A-5", http.StatusUnprocessableEntity, []string{database.AttributeViolationMalformed}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference := "ref-" + string(rune('a'+i))
+			rec := post(s.handleCatalogItem, `<catalog_item>
+				<upload_uuid>uuid-metadata</upload_uuid>
+				<catalog_name>Номенклатура</catalog_name>
+				<reference>`+reference+`</reference>
+				<name>Товар</name>
+				<attributes_xml>`+tt.attributes+`</attributes_xml>
+			</catalog_item>`)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("POST /catalog/item status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			issues, _, err := db.GetQualityIssues(upload.ID, map[string]interface{}{"entity_type": "catalog_item"}, 0, 0)
+			if err != nil {
+				t.Fatalf("GetQualityIssues() error = %v", err)
+			}
+			var got []string
+			for _, issue := range issues {
+				if issue.EntityReference == reference {
+					got = append(got, issue.IssueType)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantIssues, ",") {
+				t.Errorf("recorded issues = %v, want %v", got, tt.wantIssues)
+			}
+		})
+	}
+}
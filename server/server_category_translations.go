@@ -0,0 +1,176 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/i18n"
+)
+
+// handleCategoryTranslations управляет переводами наименований категорий классификатора.
+// Классификатор - kpved или ID пользовательского классификатора категорий
+// GET /api/classification/translations/{classifier}[?language=kk]
+// POST /api/classification/translations/{classifier}[?language=kk] - импорт CSV/XLSX (multipart поле file)
+// DELETE /api/classification/translations/{classifier}[?language=kk]
+func (s *Server) handleCategoryTranslations(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+	classifier := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/classification/translations/"), "/")
+	if classifier == "" || strings.Contains(classifier, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if classifier != database.KpvedTranslationClassifier {
+		classifierID, err := strconv.Atoi(classifier)
+		if err != nil {
+			s.writeJSONError(w, "Classifier must be kpved or a classifier ID", http.StatusBadRequest)
+			return
+		}
+		if _, err := s.db.GetCategoryClassifier(classifierID); err != nil {
+			s.writeJSONError(w, "Classifier not found", http.StatusNotFound)
+			return
+		}
+	}
+
+	language := ""
+	if raw := r.URL.Query().Get("language"); raw != "" {
+		if language = i18n.Normalize(raw); language == "" {
+			s.writeJSONError(w, fmt.Sprintf("Unsupported language: %s", raw), http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		translations, err := s.serviceDB.ListCategoryNameTranslations(classifier, language)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"classifier":   classifier,
+			"translations": translations,
+			"total":        len(translations),
+		}, http.StatusOK)
+	case http.MethodPost:
+		s.handleImportCategoryTranslations(w, r, classifier, language)
+	case http.MethodDelete:
+		deleted, err := s.serviceDB.DeleteCategoryNameTranslations(classifier, language)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Переводы классификатора %s: удалено %d", classifier, deleted),
+			Endpoint:  r.URL.Path,
+		})
+		s.writeJSONResponse(w, map[string]interface{}{
+			"classifier": classifier,
+			"deleted":    deleted,
+		}, http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleImportCategoryTranslations импортирует файл переводов (код, язык, наименование).
+// Язык из параметра language используется для строк без колонки языка
+func (s *Server) handleImportCategoryTranslations(w http.ResponseWriter, r *http.Request, classifier, language string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMappingFileSize)
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		s.writeJSONError(w, "Translation file is required in multipart field 'file'", http.StatusBadRequest)
+		return
+	}
+	defer upload.Close()
+
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		s.writeJSONError(w, "Failed to read translation file", http.StatusBadRequest)
+		return
+	}
+	rows, _, err := readMappingFile(header.Filename, data)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	parsed, issues := database.ParseCategoryTranslationRows(rows, language)
+	translations := make([]*database.CategoryNameTranslation, 0, len(parsed))
+	for _, translation := range parsed {
+		lang := i18n.Normalize(translation.Language)
+		if lang == "" {
+			issues = append(issues, fmt.Sprintf("code %s: unsupported language %s", translation.Code, translation.Language))
+			continue
+		}
+		translation.Language = lang
+		translations = append(translations, translation)
+	}
+	if len(translations) == 0 {
+		s.writeJSONResponse(w, map[string]interface{}{
+			"error":  "Translation file contains no valid rows",
+			"issues": issues,
+		}, http.StatusBadRequest)
+		return
+	}
+
+	imported, err := s.serviceDB.ImportCategoryNameTranslations(classifier, translations)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message: fmt.Sprintf("Переводы классификатора %s: загружен файл %q (сохранено %d, отклонено %d)",
+			classifier, header.Filename, imported, len(issues)),
+		Endpoint: r.URL.Path,
+	})
+
+	if issues == nil {
+		issues = []string{}
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"classifier": classifier,
+		"imported":   imported,
+		"issues":     issues,
+	}, http.StatusCreated)
+}
+
+// clientReportSecondLanguage возвращает второй язык отчетов клиента или пустую строку
+func (s *Server) clientReportSecondLanguage(clientID int) string {
+	if s.serviceDB == nil || clientID <= 0 {
+		return ""
+	}
+	lang, err := s.serviceDB.GetClientReportSecondLanguage(clientID)
+	if err != nil {
+		return ""
+	}
+	return i18n.Normalize(lang)
+}
+
+// reportSecondLanguage определяет второй язык наименований категорий отчета: параметр second_language,
+// затем настройка клиента. "none" отключает перевод
+func (s *Server) reportSecondLanguage(r *http.Request, clientID int) (string, error) {
+	raw := r.URL.Query().Get("second_language")
+	switch raw {
+	case "":
+		return s.clientReportSecondLanguage(clientID), nil
+	case "none":
+		return "", nil
+	}
+	lang := i18n.Normalize(raw)
+	if lang == "" {
+		return "", fmt.Errorf("unsupported language: %s", raw)
+	}
+	return lang, nil
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"httpserver/classification"
+	"httpserver/client"
 	"httpserver/database"
 	"httpserver/normalization"
 )
@@ -465,14 +466,7 @@ func (s *Server) handleClassifyItemDirect(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var req struct {
-		ItemName   string                 `json:"item_name"`
-		ItemCode   string                 `json:"item_code"`
-		StrategyID string                 `json:"strategy_id"`
-		Context    map[string]interface{} `json:"context,omitempty"`
-		Category   string                 `json:"category,omitempty"`
-	}
-
+	var req client.ClassifyItemRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
 		return
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// correctionColumnAliases названия колонок заголовка файла исправлений классификации
+var correctionColumnAliases = map[string][]string{
+	"reference":  {"reference", "source_reference", "ссылка", "ссылка 1с"},
+	"code":       {"code", "source_code", "код", "код элемента"},
+	"kpved_code": {"kpved_code", "corrected_kpved_code", "kpved", "кпвэд", "код кпвэд", "исправленный код кпвэд", "исправленный кпвэд"},
+	"kpved_name": {"kpved_name", "наименование кпвэд"},
+}
+
+// parseCorrectionRows разбирает строки файла исправлений. Колонки определяются по заголовку;
+// без заголовка: ссылка, код, исправленный код КПВЭД
+func parseCorrectionRows(rows [][]string) []*database.ClassificationCorrection {
+	columns := map[string]int{"reference": 0, "code": 1, "kpved_code": 2}
+	start := 0
+	if len(rows) > 0 {
+		header := make(map[string]int)
+		for i, value := range rows[0] {
+			value = strings.ToLower(strings.TrimSpace(value))
+			for column, aliases := range correctionColumnAliases {
+				if _, found := header[column]; found {
+					continue
+				}
+				for _, alias := range aliases {
+					if value == alias {
+						header[column] = i
+					}
+				}
+			}
+		}
+		if _, ok := header["kpved_code"]; ok {
+			columns = header
+			start = 1
+		}
+	}
+
+	cell := func(row []string, column string) string {
+		index, ok := columns[column]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[index])
+	}
+
+	var corrections []*database.ClassificationCorrection
+	for i := start; i < len(rows); i++ {
+		correction := &database.ClassificationCorrection{
+			RowNumber: i + 1,
+			Reference: cell(rows[i], "reference"),
+			Code:      cell(rows[i], "code"),
+			KpvedCode: cell(rows[i], "kpved_code"),
+			KpvedName: cell(rows[i], "kpved_name"),
+		}
+		if correction.Reference == "" && correction.Code == "" && correction.KpvedCode == "" {
+			continue
+		}
+		corrections = append(corrections, correction)
+	}
+	return corrections
+}
+
+// kpvedClassifierNames возвращает коды загруженного классификатора КПВЭД с наименованиями;
+// nil, если классификатор не загружен
+func (s *Server) kpvedClassifierNames() map[string]string {
+	if s.serviceDB == nil {
+		return nil
+	}
+	if err := s.kpvedTree.ensureLoaded(s.serviceDB.GetDB()); err != nil {
+		return nil
+	}
+	s.kpvedTree.mu.RLock()
+	defer s.kpvedTree.mu.RUnlock()
+	if len(s.kpvedTree.nodes) == 0 {
+		return nil
+	}
+	names := make(map[string]string, len(s.kpvedTree.nodes))
+	for code, node := range s.kpvedTree.nodes {
+		names[code] = node.name
+	}
+	return names
+}
+
+// handleImportClassificationCorrections импортирует проверенный клиентом файл исправлений классификации
+// (multipart поле file, CSV или XLSX: ссылка или код записи -> исправленный код КПВЭД).
+// Коды проверяются по классификатору, исправления применяются в одной транзакции как подтвержденные,
+// записываются в происхождение записей и исходы калибровки. ?dry_run=true - только проверка
+// POST /api/kpved/corrections/import
+func (s *Server) handleImportClassificationCorrections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMappingFileSize)
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		s.writeJSONError(w, "Corrections file is required in multipart field 'file'", http.StatusBadRequest)
+		return
+	}
+	defer upload.Close()
+
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		s.writeJSONError(w, "Failed to read corrections file", http.StatusBadRequest)
+		return
+	}
+	rows, _, err := readMappingFile(header.Filename, data)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	corrections := parseCorrectionRows(rows)
+	if len(corrections) == 0 {
+		s.writeJSONError(w, "Corrections file contains no rows", http.StatusBadRequest)
+		return
+	}
+
+	classifier := s.kpvedClassifierNames()
+	if classifier == nil {
+		s.writeJSONError(w, "KPVED classifier is not loaded, corrections cannot be validated", http.StatusServiceUnavailable)
+		return
+	}
+	database.ValidateClassificationCorrections(corrections, classifier)
+
+	editor := requestActor(r, r.FormValue("editor"), database.ManualEditModel)
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	result, err := s.db.ApplyClassificationCorrections(corrections, editor, dryRun)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to apply corrections: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if !dryRun {
+		if result.OutcomesRecorded > 0 && s.calibrator != nil {
+			s.calibrator.Invalidate()
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message: fmt.Sprintf("Импорт исправлений классификации %q (editor: %s): строк %d, исправлено %d, подтверждено %d, не найдено %d, отклонено %d, записей изменено %d",
+				header.Filename, editor, result.Rows, result.Applied, result.Confirmed, result.NotFound, result.Invalid, result.ItemsUpdated),
+			Endpoint: "/api/kpved/corrections/import",
+		})
+	}
+
+	issues := []*database.ClassificationCorrection{}
+	for _, correction := range corrections {
+		if correction.Status == database.CorrectionStatusInvalid || correction.Status == database.CorrectionStatusNotFound {
+			issues = append(issues, correction)
+		}
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"result": result,
+		"issues": issues,
+	}, http.StatusOK)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestImportClassificationCorrections(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	_, err = serviceDB.GetDB().Exec(`
+		INSERT INTO kpved_classifier (code, name, parent_code, level) VALUES
+			('25', 'Изделия металлические готовые', NULL, 1),
+			('25.9', 'Изделия металлические прочие', '25', 2);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to seed kpved: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, category, kpved_code, kpved_name, kpved_model)
+		VALUES ('r1', 'Болт', 'c1', 'болт м8', 'крепеж', '01.11', 'Зерновые культуры', 'glm')`)
+	if err != nil {
+		t.Fatalf("Failed to insert normalized item: %v", err)
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), db: db, serviceDB: serviceDB}
+
+	tests := []struct {
+		name       string
+		fileName   string
+		content    string
+		query      string
+		wantStatus int
+		wantCode   string
+		wantIssues int
+	}{
+		{"unsupported file", "corrections.pdf", "x", "", http.StatusBadRequest, "01.11", 0},
+		{"dry run", "corrections.csv", "Ссылка;Код КПВЭД\nr1;25.9\nr2;25.9\n", "?dry_run=true", http.StatusOK, "01.11", 1},
+		{"import", "corrections.csv", "Код;Код КПВЭД\nc1;25.9\nc1;99.1\n", "", http.StatusOK, "25.9", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, _ := writer.CreateFormFile("file", tt.fileName)
+			part.Write([]byte(tt.content))
+			writer.Close()
+			req := httptest.NewRequest(http.MethodPost, "/api/kpved/corrections/import"+tt.query, body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rec := httptest.NewRecorder()
+			s.handleImportClassificationCorrections(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK {
+				var resp struct {
+					Issues []database.ClassificationCorrection `json:"issues"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Issues) != tt.wantIssues {
+					t.Errorf("issues = %+v, want %d", resp.Issues, tt.wantIssues)
+				}
+			}
+
+			var code string
+			if err := db.QueryRow(`SELECT kpved_code FROM normalized_data WHERE code = 'c1'`).Scan(&code); err != nil || code != tt.wantCode {
+				t.Errorf("kpved_code = %q, want %q (%v)", code, tt.wantCode, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+// experimentRouter направляет долю вызовов классификации в варианты эксперимента и записывает их результаты.
+// Группа (наименование и категория) всегда попадает в один и тот же вариант, поэтому повторные
+// классификации группы сравнимы между запусками
+type experimentRouter struct {
+	experiment  *database.ClassificationExperiment
+	control     reclassifyFunc
+	variants    []reclassifyFunc
+	totalWeight int
+	record      func(database.ExperimentCall) error
+}
+
+// newExperimentRouter создает маршрутизатор; build возвращает функцию классификации варианта
+func newExperimentRouter(experiment *database.ClassificationExperiment, control reclassifyFunc,
+	build func(database.ExperimentVariant) reclassifyFunc, record func(database.ExperimentCall) error) *experimentRouter {
+	router := &experimentRouter{experiment: experiment, control: control, record: record}
+	for _, variant := range experiment.Variants {
+		router.variants = append(router.variants, build(variant))
+		router.totalWeight += variant.Weight
+	}
+	return router
+}
+
+// assign возвращает индекс варианта для группы или -1, если группа не входит в долю эксперимента
+func (r *experimentRouter) assign(normalizedName, category string) int {
+	if r.totalWeight <= 0 {
+		return -1
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(normalizedName + "\x00" + category))
+	bucket := hash.Sum32()
+	if int(bucket%100) >= r.experiment.TrafficPercent {
+		return -1
+	}
+
+	point := int((bucket / 100) % uint32(r.totalWeight))
+	for i, variant := range r.experiment.Variants {
+		if point < variant.Weight {
+			return i
+		}
+		point -= variant.Weight
+	}
+	return len(r.experiment.Variants) - 1
+}
+
+// classify классифицирует группу вариантом эксперимента или контрольной функцией вне доли эксперимента
+func (r *experimentRouter) classify(normalizedName, category string) (*normalization.HierarchicalResult, error) {
+	index := r.assign(normalizedName, category)
+	if index < 0 {
+		return r.control(normalizedName, category)
+	}
+
+	variant := r.experiment.Variants[index]
+	startedAt := time.Now()
+	result, err := r.variants[index](normalizedName, category)
+
+	call := database.ExperimentCall{
+		ExperimentID:   r.experiment.ID,
+		VariantID:      variant.ID,
+		NormalizedName: normalizedName,
+		Category:       category,
+		DurationMs:     time.Since(startedAt).Milliseconds(),
+	}
+	if err != nil {
+		call.Error = err.Error()
+	} else if result != nil {
+		call.KpvedCode = result.FinalCode
+		call.Confidence = result.FinalConfidence
+		call.Model = result.Model
+		call.AICalls = result.AICallsCount
+	}
+	if recordErr := r.record(call); recordErr != nil {
+		log.Printf("[Experiments] Не удалось записать результат варианта %s эксперимента %d: %v",
+			variant.Name, r.experiment.ID, recordErr)
+	}
+	return result, err
+}
+
+// experimentClassify оборачивает функцию классификации активным экспериментом.
+// Без активного эксперимента возвращает control без изменений
+func (s *Server) experimentClassify(classifier *normalization.HierarchicalClassifier, control reclassifyFunc) reclassifyFunc {
+	if s.db == nil {
+		return control
+	}
+	experiment, err := s.db.GetActiveClassificationExperiment()
+	if err != nil {
+		log.Printf("[Experiments] Ошибка получения активного эксперимента: %v", err)
+		return control
+	}
+	if experiment == nil {
+		return control
+	}
+
+	build := func(variant database.ExperimentVariant) reclassifyFunc {
+		return experimentVariantClassify(classifier, variant, control)
+	}
+	log.Printf("[Experiments] Классификация участвует в эксперименте %q (%d%% групп)", experiment.Name, experiment.TrafficPercent)
+	return newExperimentRouter(experiment, control, build, s.db.RecordExperimentCall).classify
+}
+
+// experimentVariantClassify функция классификации варианта: заданная модель и указания промпта
+// применяются к копии классификатора; контрольный вариант классифицирует функцией control
+func experimentVariantClassify(classifier *normalization.HierarchicalClassifier, variant database.ExperimentVariant, control reclassifyFunc) reclassifyFunc {
+	if classifier == nil || classifier.AIClient() == nil || (variant.Model == "" && variant.PromptInstructions == "") {
+		return control
+	}
+
+	variantClassifier := classifier
+	model := classifier.AIClient().Model()
+	if variant.Model != "" {
+		variantClassifier = variantClassifier.WithAIClient(classifier.AIClient().WithModel(variant.Model))
+		model = variant.Model
+	}
+	if variant.PromptInstructions != "" {
+		variantClassifier = variantClassifier.WithPromptInstructions(variant.PromptInstructions)
+	}
+	return func(normalizedName, category string) (*normalization.HierarchicalResult, error) {
+		result, err := variantClassifier.Classify(normalizedName, category)
+		if result != nil && result.Model == "" {
+			result.Model = model
+		}
+		return result, err
+	}
+}
+
+// createExperimentRequest запрос создания эксперимента
+type createExperimentRequest struct {
+	Name           string                       `json:"name"`
+	TrafficPercent int                          `json:"traffic_percent"`
+	Variants       []database.ExperimentVariant `json:"variants"`
+}
+
+// validate проверяет долю трафика и варианты эксперимента
+func (req *createExperimentRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return apperrors.Validation("invalid_experiment", "name is required")
+	}
+	if req.TrafficPercent < 1 || req.TrafficPercent > 100 {
+		return apperrors.Validation("invalid_experiment", "traffic_percent must be between 1 and 100")
+	}
+	if len(req.Variants) < 2 {
+		return apperrors.Validation("invalid_experiment", "at least two variants are required")
+	}
+	names := make(map[string]bool, len(req.Variants))
+	for i := range req.Variants {
+		variant := &req.Variants[i]
+		variant.Name = strings.TrimSpace(variant.Name)
+		variant.Model = strings.TrimSpace(variant.Model)
+		if variant.Name == "" {
+			return apperrors.Validation("invalid_experiment", "variant name is required")
+		}
+		if names[variant.Name] {
+			return apperrors.Validation("invalid_experiment", fmt.Sprintf("duplicate variant name: %s", variant.Name))
+		}
+		names[variant.Name] = true
+		if variant.Weight <= 0 {
+			return apperrors.Validation("invalid_experiment", fmt.Sprintf("variant %s: weight must be positive", variant.Name))
+		}
+		if variant.CostPerCall < 0 {
+			return apperrors.Validation("invalid_experiment", fmt.Sprintf("variant %s: cost_per_call must not be negative", variant.Name))
+		}
+	}
+	return nil
+}
+
+// handleClassificationExperiments список экспериментов или создание эксперимента
+// GET /api/classification/experiments
+// POST /api/classification/experiments {"name": "...", "traffic_percent": 20, "variants": [{"name": "control", "weight": 1}, {"name": "pro", "model": "...", "weight": 1}]}
+func (s *Server) handleClassificationExperiments(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		experiments, err := s.db.ListClassificationExperiments()
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if experiments == nil {
+			experiments = []*database.ClassificationExperiment{}
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"experiments": experiments,
+			"total":       len(experiments),
+		}, http.StatusOK)
+	case http.MethodPost:
+		var req createExperimentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := req.validate(); err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		experiment := &database.ClassificationExperiment{
+			Name:           req.Name,
+			TrafficPercent: req.TrafficPercent,
+			Variants:       req.Variants,
+		}
+		if err := s.db.CreateClassificationExperiment(experiment); err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, experiment, http.StatusCreated)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleClassificationExperimentRoutes эксперимент, его остановка и отчет по вариантам
+// GET /api/classification/experiments/{id}
+// POST /api/classification/experiments/{id}/stop
+// GET /api/classification/experiments/{id}/report
+func (s *Server) handleClassificationExperimentRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/classification/experiments/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 {
+		s.writeJSONError(w, "Invalid experiment ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		experiment, err := s.db.GetClassificationExperiment(id)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, experiment, http.StatusOK)
+	case len(parts) == 2 && parts[1] == "stop":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		experiment, err := s.db.StopClassificationExperiment(id)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, experiment, http.StatusOK)
+	case len(parts) == 2 && parts[1] == "report":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.getExperimentReport(w, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// getExperimentReport сравнение вариантов эксперимента по точности и стоимости
+func (s *Server) getExperimentReport(w http.ResponseWriter, id int) {
+	experiment, err := s.db.GetClassificationExperiment(id)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+		return
+	}
+	variants, err := s.db.GetExperimentReport(experiment)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"experiment": experiment,
+		"variants":   variants,
+	}, http.StatusOK)
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+func TestExperimentRouter(t *testing.T) {
+	experiment := &database.ClassificationExperiment{
+		ID:             1,
+		TrafficPercent: 30,
+		Variants: []database.ExperimentVariant{
+			{ID: 10, Name: "control", Weight: 1},
+			{ID: 11, Name: "pro", Model: "pro-model", Weight: 3},
+		},
+	}
+	control := func(normalizedName, category string) (*normalization.HierarchicalResult, error) {
+		return &normalization.HierarchicalResult{FinalCode: "control", Model: "flash"}, nil
+	}
+	build := func(variant database.ExperimentVariant) reclassifyFunc {
+		return func(normalizedName, category string) (*normalization.HierarchicalResult, error) {
+			if normalizedName == "сбой" {
+				return nil, errors.New("timeout")
+			}
+			return &normalization.HierarchicalResult{FinalCode: variant.Name, FinalConfidence: 0.9, AICallsCount: 2}, nil
+		}
+	}
+	var calls []database.ExperimentCall
+	record := func(call database.ExperimentCall) error {
+		calls = append(calls, call)
+		return nil
+	}
+	router := newExperimentRouter(experiment, control, build, record)
+
+	const groups = 2000
+	perVariant := make(map[int]int)
+	for i := 0; i < groups; i++ {
+		name := fmt.Sprintf("товар %d", i)
+		index := router.assign(name, "категория")
+		if router.assign(name, "категория") != index {
+			t.Fatalf("assign(%q) is not deterministic", name)
+		}
+		perVariant[index]++
+
+		result, err := router.classify(name, "категория")
+		if err != nil {
+			t.Fatalf("classify() error = %v", err)
+		}
+		want := "control"
+		if index >= 0 {
+			want = experiment.Variants[index].Name
+		}
+		if index == -1 && result.Model != "flash" || result.FinalCode != want {
+			t.Fatalf("classify(%q) = %+v, want code %s", name, result, want)
+		}
+	}
+
+	// Доля эксперимента и веса вариантов соблюдаются с точностью выборки
+	inExperiment := groups - perVariant[-1]
+	if inExperiment < groups*25/100 || inExperiment > groups*35/100 {
+		t.Errorf("groups in experiment = %d of %d, want about 30%%", inExperiment, groups)
+	}
+	if perVariant[1] < perVariant[0]*2 {
+		t.Errorf("variant split = %v, want pro about three times control", perVariant)
+	}
+	if len(calls) != inExperiment {
+		t.Errorf("recorded calls = %d, want %d", len(calls), inExperiment)
+	}
+
+	// Ошибки варианта записываются и возвращаются вызывающему
+	router.experiment.TrafficPercent = 100
+	calls = nil
+	if _, err := router.classify("сбой", ""); err == nil {
+		t.Fatal("classify() error = nil, want variant error")
+	}
+	if len(calls) != 1 || calls[0].Error != "timeout" {
+		t.Errorf("recorded calls = %+v, want failed call", calls)
+	}
+}
+
+func TestClassificationExperimentHandlers(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+	s := &Server{logChan: make(chan LogEntry, 100), db: db, config: &Config{}}
+
+	control := func(normalizedName, category string) (*normalization.HierarchicalResult, error) {
+		return &normalization.HierarchicalResult{FinalCode: "25.94.11"}, nil
+	}
+	if classify := s.experimentClassify(nil, control); classify == nil {
+		t.Fatal("experimentClassify() without experiment = nil, want control")
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"one variant", http.MethodPost, "/api/classification/experiments", `{"name":"a","traffic_percent":10,"variants":[{"name":"control","weight":1}]}`, http.StatusBadRequest, ""},
+		{"invalid traffic", http.MethodPost, "/api/classification/experiments", `{"name":"a","traffic_percent":0,"variants":[{"name":"a","weight":1},{"name":"b","weight":1}]}`, http.StatusBadRequest, ""},
+		{"duplicate variant", http.MethodPost, "/api/classification/experiments", `{"name":"a","traffic_percent":10,"variants":[{"name":"a","weight":1},{"name":"a","weight":1}]}`, http.StatusBadRequest, ""},
+		{"create", http.MethodPost, "/api/classification/experiments", `{"name":"prompt","traffic_percent":100,"variants":[{"name":"control","weight":1},{"name":"strict","prompt_instructions":"Только точные коды","weight":1}]}`, http.StatusCreated, `"status":"active"`},
+		{"second active", http.MethodPost, "/api/classification/experiments", `{"name":"b","traffic_percent":10,"variants":[{"name":"a","weight":1},{"name":"b","weight":1}]}`, http.StatusConflict, ""},
+		{"list", http.MethodGet, "/api/classification/experiments", "", http.StatusOK, `"total":1`},
+		{"get", http.MethodGet, "/api/classification/experiments/1", "", http.StatusOK, `"name":"strict"`},
+		{"missing", http.MethodGet, "/api/classification/experiments/2", "", http.StatusNotFound, ""},
+		{"invalid id", http.MethodGet, "/api/classification/experiments/x", "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if strings.HasPrefix(strings.TrimPrefix(tt.path, "/api/classification/experiments"), "/") {
+				s.handleClassificationExperimentRoutes(rec, req)
+			} else {
+				s.handleClassificationExperiments(rec, req)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	// Без классификатора варианты классифицируют контрольной функцией, вызовы записываются в отчет
+	classify := s.experimentClassify(nil, control)
+	for _, name := range []string{"болт", "гайка", "шайба"} {
+		if _, err := classify(name, "крепеж"); err != nil {
+			t.Fatalf("classify() error = %v", err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.handleClassificationExperimentRoutes(rec, httptest.NewRequest(http.MethodGet, "/api/classification/experiments/1/report", nil))
+	if rec.Code != http.StatusOK || strings.Count(rec.Body.String(), `"calls":`) != 2 {
+		t.Fatalf("report = %d %s, want two variants", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleClassificationExperimentRoutes(rec, httptest.NewRequest(http.MethodPost, "/api/classification/experiments/1/stop", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"stopped"`) {
+		t.Fatalf("stop = %d %s, want stopped", rec.Code, rec.Body.String())
+	}
+}
@@ -271,11 +271,15 @@ func (s *Server) handleMarkIncorrect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Записываем исход для калибровки до сброса классификации
+	s.recordClassificationOutcome(req.NormalizedName, req.Category, false)
+
 	// Помечаем как неправильную и сбрасываем классификацию
 	query := `UPDATE normalized_data 
 		SET validation_status = 'incorrect',
 		    validation_reason = ?,
-		    kpved_code = NULL, kpved_name = NULL, kpved_confidence = 0.0
+		    kpved_code = NULL, kpved_name = NULL, kpved_confidence = 0.0,
+		    kpved_raw_confidence = NULL, kpved_model = NULL, confidence_decision = NULL
 		WHERE normalized_name = ? AND category = ?`
 
 	result, err := s.db.Exec(query, req.Reason, req.NormalizedName, req.Category)
@@ -319,8 +323,12 @@ func (s *Server) handleMarkCorrect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.recordClassificationOutcome(req.NormalizedName, req.Category, true)
+
+	// Подтвержденная классификация больше не требует проверки
 	query := `UPDATE normalized_data 
-		SET validation_status = 'correct'
+		SET validation_status = 'correct',
+		    confidence_decision = CASE WHEN confidence_decision IS NULL THEN NULL ELSE 'accept' END
 		WHERE normalized_name = ? AND category = ?`
 
 	result, err := s.db.Exec(query, req.NormalizedName, req.Category)
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+const (
+	// classificationRetryBatchSize записей очереди, повторяемых за один проход
+	classificationRetryBatchSize = 50
+	// classificationRetryMaxDelay верхняя граница задержки между повторами
+	classificationRetryMaxDelay = 6 * time.Hour
+)
+
+// errClassificationRejected результат классификации отклонен политикой уверенности
+var errClassificationRejected = errors.New("classification rejected by confidence policy")
+
+// errClassificationItemNotFound запись normalized_data удалена до сохранения результата
+var errClassificationItemNotFound = errors.New("normalized item not found")
+
+// classifyNormalizedItem классифицирует запись normalized_data по КПВЭД и сохраняет результат с откалиброванной уверенностью
+func (s *Server) classifyNormalizedItem(db *database.DB, classify reclassifyFunc, itemID int, normalizedName, category string) error {
+	result, err := classify(normalizedName, category)
+	if err != nil {
+		return err
+	}
+
+	// Калибруем уверенность, отклоненные политикой результаты не сохраняем
+	evaluation := s.calibrator.Evaluate(result.Model, 0, result.FinalConfidence)
+	if evaluation.Decision == database.ConfidenceDecisionReject {
+		return errClassificationRejected
+	}
+
+	res, err := db.Exec(`
+		UPDATE normalized_data
+		SET kpved_code = ?, kpved_name = ?, kpved_confidence = ?,
+		    kpved_raw_confidence = ?, kpved_model = ?, confidence_decision = ?
+		WHERE id = ?
+	`, result.FinalCode, result.FinalName, evaluation.Confidence,
+		result.FinalConfidence, evaluation.Model, evaluation.Decision, itemID)
+	if err != nil {
+		return fmt.Errorf("failed to save kpved code: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return errClassificationItemNotFound
+	}
+	return nil
+}
+
+// classificationErrorClass определяет класс ошибки классификации: временные ошибки сети, AI API
+// и блокировки БД повторяются, отклонение политикой уверенности и прочие ошибки - нет
+func classificationErrorClass(err error) string {
+	if errors.Is(err, errClassificationRejected) {
+		return database.ClassificationErrorRejected
+	}
+	if errors.Is(err, errClassificationItemNotFound) {
+		return database.ClassificationErrorPermanent
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return database.ClassificationErrorTransient
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return database.ClassificationErrorTransient
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"timeout", "timed out", "deadline exceeded", "rate limit", "too many requests", "429",
+		"500", "502", "503", "504", "unavailable", "temporarily", "connection reset", "connection refused",
+		"eof", "database is locked", "circuit breaker",
+	} {
+		if strings.Contains(message, marker) {
+			return database.ClassificationErrorTransient
+		}
+	}
+	return database.ClassificationErrorPermanent
+}
+
+// classificationRetryPolicy политика повторов классификации из конфигурации
+func (s *Server) classificationRetryPolicy() database.ClassificationRetryPolicy {
+	policy := database.ClassificationRetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute, MaxDelay: classificationRetryMaxDelay}
+	if s.currentConfig() != nil {
+		if s.currentConfig().ClassificationRetryMaxAttempts > 0 {
+			policy.MaxAttempts = s.currentConfig().ClassificationRetryMaxAttempts
+		}
+		if s.currentConfig().ClassificationRetryBaseDelay > 0 {
+			policy.BaseDelay = s.currentConfig().ClassificationRetryBaseDelay
+		}
+	}
+	return policy
+}
+
+// recordClassificationFailure ставит запись в очередь повторной классификации
+func (s *Server) recordClassificationFailure(db *database.DB, itemID int, normalizedName, category string, classifyErr error) {
+	failure := database.ClassificationFailure{
+		ItemID:         itemID,
+		NormalizedName: normalizedName,
+		Category:       category,
+		ErrorClass:     classificationErrorClass(classifyErr),
+		Error:          classifyErr.Error(),
+	}
+	retry, err := db.RecordClassificationFailure(failure, s.classificationRetryPolicy())
+	if err != nil {
+		log.Printf("[KPVED] Не удалось записать ошибку классификации записи %d в очередь повторов: %v", itemID, err)
+		return
+	}
+	if retry.Status == database.ClassificationRetryFailed {
+		log.Printf("[KPVED] Классификация записи %d завершилась окончательной ошибкой (%s, попыток %d): %v",
+			itemID, retry.ErrorClass, retry.Attempts, classifyErr)
+	}
+}
+
+// resolveClassificationRetry снимает запись с очереди повторов после успешной классификации
+func (s *Server) resolveClassificationRetry(db *database.DB, itemID int) {
+	if err := db.ResolveClassificationRetry(itemID); err != nil {
+		log.Printf("[KPVED] Не удалось отметить запись %d в очереди повторов: %v", itemID, err)
+	}
+}
+
+// runClassificationRetryLoop периодически повторяет классификацию записей, время повтора которых наступило
+func (s *Server) runClassificationRetryLoop() {
+	if s.currentConfig() == nil || s.currentConfig().ClassificationRetryInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.currentConfig().ClassificationRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.kpvedClassifierMutex.RLock()
+			classifier := s.hierarchicalClassifier
+			s.kpvedClassifierMutex.RUnlock()
+			if classifier == nil || s.normalizedDB == nil {
+				continue
+			}
+			chain := normalization.NewChainedClassifier(classifier, s.workerConfigManager.GetModelChain(normalization.ModelChainTaskClassification)).
+				WithHistorical(s.historicalIndex(s.normalizedDB))
+			resolved, failed := s.retryDueClassifications(s.normalizedDB, chain.Classify, time.Now())
+			if resolved+failed > 0 {
+				log.Printf("[KPVED] Повтор классификации: классифицировано %d, ошибок %d", resolved, failed)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// retryDueClassifications повторяет классификацию записей очереди, время повтора которых наступило к now
+func (s *Server) retryDueClassifications(db *database.DB, classify reclassifyFunc, now time.Time) (resolved, failed int) {
+	due, err := db.GetDueClassificationRetries(now, classificationRetryBatchSize)
+	if err != nil {
+		log.Printf("[KPVED] Ошибка чтения очереди повторов классификации: %v", err)
+		return 0, 0
+	}
+	for _, retry := range due {
+		if err := s.classifyNormalizedItem(db, classify, retry.ItemID, retry.NormalizedName, retry.Category); err != nil {
+			s.recordClassificationFailure(db, retry.ItemID, retry.NormalizedName, retry.Category, err)
+			failed++
+			continue
+		}
+		s.resolveClassificationRetry(db, retry.ItemID)
+		resolved++
+	}
+	return resolved, failed
+}
+
+// handleClassificationRetries отчет очереди повторной классификации: количество записей по статусам,
+// окончательные ошибки по классам и записи очереди (по умолчанию - окончательно не классифицированные)
+// GET /api/classification/retries?status=failed|pending|resolved|all&limit=&offset=
+func (s *Server) handleClassificationRetries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.normalizedDB == nil {
+		s.writeJSONError(w, "Normalized database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	status := query.Get("status")
+	switch status {
+	case "":
+		status = database.ClassificationRetryFailed
+	case "all":
+		status = ""
+	case database.ClassificationRetryFailed, database.ClassificationRetryPending, database.ClassificationRetryResolved:
+	default:
+		s.writeJSONError(w, fmt.Sprintf("unknown status: %s", status), http.StatusBadRequest)
+		return
+	}
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			s.writeJSONError(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, 1000)
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			s.writeJSONError(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = parsed
+	}
+
+	counts, failedByClass, err := s.normalizedDB.CountClassificationRetries()
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items, err := s.normalizedDB.ListClassificationRetries(status, limit, offset)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []*database.ClassificationRetry{}
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"counts":          counts,
+		"failed_by_class": failedByClass,
+		"items":           items,
+		"limit":           limit,
+		"offset":          offset,
+	}, http.StatusOK)
+}
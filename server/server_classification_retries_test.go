@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+func TestClassificationErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"rejected", errClassificationRejected, database.ClassificationErrorRejected},
+		{"deleted item", errClassificationItemNotFound, database.ClassificationErrorPermanent},
+		{"deadline", fmt.Errorf("classify: %w", context.DeadlineExceeded), database.ClassificationErrorTransient},
+		{"rate limit", errors.New("API returned status 429: Too Many Requests"), database.ClassificationErrorTransient},
+		{"locked", errors.New("failed to save kpved code: database is locked"), database.ClassificationErrorTransient},
+		{"invalid response", errors.New("invalid JSON in model response"), database.ClassificationErrorPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classificationErrorClass(tt.err); got != tt.want {
+				t.Errorf("classificationErrorClass(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDueClassifications(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`INSERT INTO normalized_data (id, source_reference, source_name, code, normalized_name, category)
+		VALUES (1, 'ref-1', 'Болт', '1', 'болт', 'крепеж'), (2, 'ref-2', 'Гайка', '2', 'гайка', 'крепеж')`); err != nil {
+		t.Fatalf("Failed to insert normalized items: %v", err)
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), normalizedDB: db, config: &Config{ClassificationRetryMaxAttempts: 3, ClassificationRetryBaseDelay: time.Minute}}
+	s.recordClassificationFailure(db, 1, "болт", "крепеж", errors.New("request timeout"))
+	s.recordClassificationFailure(db, 2, "гайка", "крепеж", errors.New("request timeout"))
+
+	classify := func(normalizedName, category string) (*normalization.HierarchicalResult, error) {
+		if normalizedName == "гайка" {
+			return nil, errors.New("unknown category")
+		}
+		return &normalization.HierarchicalResult{FinalCode: "25.94.11", FinalName: "Болты", FinalConfidence: 0.95}, nil
+	}
+	resolved, failed := s.retryDueClassifications(db, classify, time.Now().Add(time.Hour))
+	if resolved != 1 || failed != 1 {
+		t.Fatalf("retryDueClassifications() = %d resolved, %d failed, want 1 and 1", resolved, failed)
+	}
+
+	var code string
+	if err := db.QueryRow(`SELECT kpved_code FROM normalized_data WHERE id = 1`).Scan(&code); err != nil || code != "25.94.11" {
+		t.Errorf("kpved_code = %q, %v, want saved code", code, err)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"failed by default", "", http.StatusOK, `"item_id":2`},
+		{"permanent class", "", http.StatusOK, `"failed_by_class":{"permanent":1}`},
+		{"resolved", "?status=resolved", http.StatusOK, `"item_id":1`},
+		{"invalid status", "?status=lost", http.StatusBadRequest, ""},
+		{"invalid limit", "?limit=0", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleClassificationRetries(rec, httptest.NewRequest(http.MethodGet, "/api/classification/retries"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -2,9 +2,18 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/classification"
+	"httpserver/database"
 )
 
 // handleGetClassifiers возвращает список всех классификаторов
@@ -66,3 +75,168 @@ func (s *Server) handleGetClassifiers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// ClassifierNodeRequest запрос на добавление или изменение категории классификатора.
+// В PATCH поля со значением nil не изменяются, пустой parent_code переносит категорию на верхний уровень
+type ClassifierNodeRequest struct {
+	Code       string  `json:"code"`
+	Name       *string `json:"name"`
+	ParentCode *string `json:"parent_code"`
+}
+
+// ClassifierMergeRequest запрос на слияние категории с другой
+type ClassifierMergeRequest struct {
+	TargetCode string `json:"target_code"`
+	MoveItems  bool   `json:"move_items"` // Перенести классифицированные элементы в целевую категорию
+}
+
+// handleClassifierRoutes редактор дерева пользовательского классификатора
+// GET/POST /api/classification/classifiers/{id}/nodes
+// PATCH/DELETE /api/classification/classifiers/{id}/nodes/{code}
+// POST /api/classification/classifiers/{id}/nodes/{code}/merge
+func (s *Server) handleClassifierRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/classification/classifiers/"), "/"), "/")
+	if len(parts) < 2 || parts[1] != "nodes" || len(parts) > 4 {
+		http.NotFound(w, r)
+		return
+	}
+	classifierID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		s.writeJSONError(w, "Invalid classifier ID", http.StatusBadRequest)
+		return
+	}
+	classifier, err := s.db.GetCategoryClassifier(classifierID)
+	if err != nil {
+		s.writeJSONError(w, "Classifier not found", http.StatusNotFound)
+		return
+	}
+	// Классификаторы, сохраненные только в tree_structure, сначала раскладываем по узлам
+	if classifier.TreeStructure != "" {
+		if _, err := classification.LoadClassifierTree(s.db, classifier); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to load classifier tree: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var code string
+	if len(parts) > 2 {
+		if code, err = url.PathUnescape(parts[2]); err != nil {
+			s.writeJSONError(w, "Invalid node code", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		nodes, err := s.db.GetClassifierNodes(classifierID)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get classifier nodes: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if nodes == nil {
+			nodes = []database.ClassifierNode{}
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"classifier_id": classifierID,
+			"max_depth":     classifier.MaxDepth,
+			"nodes":         nodes,
+			"total":         len(nodes),
+		}, http.StatusOK)
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var req ClassifierNodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Invalid payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		var name, parentCode string
+		if req.Name != nil {
+			name = *req.Name
+		}
+		if req.ParentCode != nil {
+			parentCode = *req.ParentCode
+		}
+		node, err := s.db.AddClassifierNode(classifierID, parentCode, req.Code, name)
+		if err != nil {
+			s.writeClassifierNodeError(w, err)
+			return
+		}
+		s.classifierTreeChanged(classifierID, fmt.Sprintf("Category %s added", node.Path))
+		s.writeJSONResponse(w, node, http.StatusCreated)
+	case len(parts) == 3 && r.Method == http.MethodPatch:
+		var req ClassifierNodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			s.writeJSONError(w, fmt.Sprintf("Invalid payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		var node *database.ClassifierNode
+		if req.Name != nil {
+			if node, err = s.db.RenameClassifierNode(classifierID, code, *req.Name); err != nil {
+				s.writeClassifierNodeError(w, err)
+				return
+			}
+		}
+		if req.ParentCode != nil {
+			if node, err = s.db.MoveClassifierNode(classifierID, code, *req.ParentCode); err != nil {
+				s.writeClassifierNodeError(w, err)
+				return
+			}
+		}
+		if node == nil {
+			s.writeJSONError(w, "Nothing to change: name or parent_code is required", http.StatusBadRequest)
+			return
+		}
+		s.classifierTreeChanged(classifierID, fmt.Sprintf("Category %s updated", node.Path))
+		s.writeJSONResponse(w, node, http.StatusOK)
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		if err := s.db.DeleteClassifierNode(classifierID, code); err != nil {
+			s.writeClassifierNodeError(w, err)
+			return
+		}
+		s.classifierTreeChanged(classifierID, fmt.Sprintf("Category %s deleted", code))
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && parts[3] == "merge" && r.Method == http.MethodPost:
+		var req ClassifierMergeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Invalid payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		result, err := s.db.MergeClassifierNodes(classifierID, code, req.TargetCode, req.MoveItems)
+		if err != nil {
+			s.writeClassifierNodeError(w, err)
+			return
+		}
+		s.classifierTreeChanged(classifierID, fmt.Sprintf("Category %s merged into %s: %d child categories, %d items moved",
+			code, result.Target.Path, result.MovedChildren, result.MovedItems))
+		s.writeJSONResponse(w, result, http.StatusOK)
+	case len(parts) == 4 && parts[3] != "merge":
+		http.NotFound(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeClassifierNodeError отвечает на ошибку редактирования дерева классификатора
+func (s *Server) writeClassifierNodeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, database.ErrClassifierNodeNotFound):
+		s.writeJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, database.ErrClassifierNodeExists):
+		s.writeJSONError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, database.ErrClassifierNodeInvalid):
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+	default:
+		s.writeJSONError(w, fmt.Sprintf("Failed to update classifier: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// classifierTreeChanged пересобирает tree_structure классификатора и записывает изменение в журнал
+func (s *Server) classifierTreeChanged(classifierID int, message string) {
+	if _, err := classification.SyncClassifierTreeStructure(s.db, classifierID); err != nil {
+		log.Printf("Warning: Failed to sync tree of classifier %d: %v", classifierID, err)
+	}
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Classifier %d: %s", classifierID, message),
+		Endpoint:  "/api/classification/classifiers",
+	})
+}
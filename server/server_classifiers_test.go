@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestClassifierNodeRoutes(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	// Классификатор, сохраненный только JSON деревом: узлы раскладываются при первом обращении
+	classifier, err := db.CreateCategoryClassifier(&database.CategoryClassifier{
+		Name:          "Группы клиента",
+		MaxDepth:      2,
+		TreeStructure: `{"id":"root","name":"Группы","path":"Группы","children":[{"id":"tools","name":"Инструмент","path":"Группы / Инструмент","level":1,"parent_id":"root"}]}`,
+		IsActive:      true,
+	})
+	if err != nil {
+		t.Fatalf("CreateCategoryClassifier() error = %v", err)
+	}
+	s := &Server{db: db, logChan: make(chan LogEntry, 100)}
+	base := "/api/classification/classifiers/" + strconv.Itoa(classifier.ID) + "/nodes"
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"add", http.MethodPost, base, `{"parent_code":"tools","code":"hand","name":"Ручной"}`, http.StatusCreated, `"path":"Группы / Инструмент / Ручной"`},
+		{"add too deep", http.MethodPost, base, `{"parent_code":"hand","code":"x","name":"X"}`, http.StatusBadRequest, "max depth"},
+		{"add duplicate", http.MethodPost, base, `{"code":"hand","name":"Ручной"}`, http.StatusConflict, ""},
+		{"rename", http.MethodPatch, base + "/tools", `{"name":"Инструменты"}`, http.StatusOK, `"path":"Группы / Инструменты"`},
+		{"move unknown", http.MethodPatch, base + "/missing", `{"parent_code":""}`, http.StatusNotFound, ""},
+		{"empty patch", http.MethodPatch, base + "/tools", `{}`, http.StatusBadRequest, ""},
+		{"merge", http.MethodPost, base + "/hand/merge", `{"target_code":"tools","move_items":true}`, http.StatusOK, `"moved_items":0`},
+		{"list", http.MethodGet, base, "", http.StatusOK, `"total":2`},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleClassifierRoutes(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s = %d %s, want %d containing %q", tt.method, tt.path, rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	updated, err := db.GetCategoryClassifier(classifier.ID)
+	if err != nil {
+		t.Fatalf("GetCategoryClassifier() error = %v", err)
+	}
+	if !strings.Contains(updated.TreeStructure, "Инструменты") || strings.Contains(updated.TreeStructure, "Ручной") {
+		t.Errorf("tree_structure not synced with nodes: %s", updated.TreeStructure)
+	}
+}
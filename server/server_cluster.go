@@ -0,0 +1,223 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"httpserver/database"
+)
+
+// Параметры кластера для сервера без конфигурации и хранения ретранслируемых событий
+const (
+	clusterDefaultLeaseTTL = 45 * time.Second
+	clusterEventsRetention = time.Hour
+	clusterRelayBatchSize  = 500
+)
+
+// clusterInstanceID идентификатор экземпляра: заданный в конфигурации или hostname-pid
+func clusterInstanceID(configured, hostname string, pid int) string {
+	if configured != "" {
+		return configured
+	}
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	return fmt.Sprintf("%s-%d", hostname, pid)
+}
+
+// clusterLeaseTTL срок аренды фоновых задач экземпляра
+func (s *Server) clusterLeaseTTL() time.Duration {
+	if s.currentConfig() == nil || s.currentConfig().ClusterLeaseTTL <= 0 {
+		return clusterDefaultLeaseTTL
+	}
+	return s.currentConfig().ClusterLeaseTTL
+}
+
+// clusterRelayEnabled проверяет, ретранслируются ли события SSE между экземплярами
+func (s *Server) clusterRelayEnabled() bool {
+	return s.serviceDB != nil && s.instanceID != "" && s.currentConfig() != nil && s.currentConfig().ClusterEventRelay
+}
+
+// startCluster регистрирует экземпляр в service.db, запускает heartbeat (продление аренды задач)
+// и при включенной ретрансляции - обмен событиями SSE с остальными экземплярами
+func (s *Server) startCluster() {
+	if s.serviceDB == nil {
+		return
+	}
+	hostname, _ := os.Hostname()
+	s.instanceID = clusterInstanceID(s.currentConfig().InstanceID, hostname, os.Getpid())
+	instance := &database.ClusterInstance{ID: s.instanceID, Hostname: hostname, PID: os.Getpid()}
+	if err := s.serviceDB.RegisterClusterInstance(instance); err != nil {
+		log.Printf("Не удалось зарегистрировать экземпляр сервера %s: %v", s.instanceID, err)
+	}
+	go s.superviseWorker("cluster_heartbeat", s.runClusterHeartbeatLoop)
+
+	if !s.clusterRelayEnabled() {
+		return
+	}
+	// Новый экземпляр ретранслирует только события, опубликованные после его запуска
+	lastID, err := s.serviceDB.LastClusterEventID()
+	if err != nil {
+		log.Printf("Ошибка получения последнего события кластера: %v", err)
+	}
+	go s.superviseWorker("cluster_event_relay", func() { s.runClusterRelayLoop(&lastID) })
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Экземпляр %s: ретрансляция событий между экземплярами включена", s.instanceID),
+	})
+}
+
+// stopCluster снимает экземпляр с регистрации при штатной остановке. Фоновые задачи к этому моменту
+// завершены или сохранены как прерванные
+func (s *Server) stopCluster() {
+	if s.serviceDB == nil || s.instanceID == "" {
+		return
+	}
+	if err := s.serviceDB.UnregisterClusterInstance(s.instanceID); err != nil {
+		log.Printf("Не удалось снять с регистрации экземпляр сервера %s: %v", s.instanceID, err)
+	}
+}
+
+// runClusterHeartbeatLoop периодически отмечает экземпляр живым, продлевает аренду его задач
+// и удаляет устаревшие ретранслированные события
+func (s *Server) runClusterHeartbeatLoop() {
+	ticker := time.NewTicker(s.currentConfig().ClusterHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.shutdownChan:
+			return
+		}
+
+		registered, err := s.serviceDB.HeartbeatClusterInstance(s.instanceID, s.clusterLeaseTTL())
+		if err != nil {
+			log.Printf("Ошибка heartbeat экземпляра %s: %v", s.instanceID, err)
+			continue
+		}
+		if !registered {
+			select {
+			case <-s.shutdownChan:
+				return
+			default:
+			}
+			hostname, _ := os.Hostname()
+			if err := s.serviceDB.RegisterClusterInstance(&database.ClusterInstance{ID: s.instanceID, Hostname: hostname, PID: os.Getpid()}); err != nil {
+				log.Printf("Не удалось повторно зарегистрировать экземпляр %s: %v", s.instanceID, err)
+			}
+		}
+		if s.clusterRelayEnabled() {
+			if _, err := s.serviceDB.PruneClusterEvents(time.Now().Add(-clusterEventsRetention)); err != nil {
+				log.Printf("Ошибка очистки событий кластера: %v", err)
+			}
+		}
+	}
+}
+
+// runClusterRelayLoop периодически передает локальным SSE подписчикам события других экземпляров
+func (s *Server) runClusterRelayLoop(lastID *int64) {
+	ticker := time.NewTicker(s.currentConfig().ClusterRelayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			*lastID = s.relayClusterEvents(*lastID)
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// relayClusterEvents передает локальным подписчикам события других экземпляров с ID больше lastID.
+// Возвращает ID последнего переданного события
+func (s *Server) relayClusterEvents(lastID int64) int64 {
+	for {
+		events, err := s.serviceDB.GetClusterEventsSince(lastID, s.instanceID, clusterRelayBatchSize)
+		if err != nil {
+			log.Printf("Ошибка получения событий кластера: %v", err)
+			return lastID
+		}
+		for _, event := range events {
+			switch event.Channel {
+			case database.ClusterChannelMonitoring:
+				s.broadcastMonitoringData([]byte(event.Payload))
+			case database.ClusterChannelNormalizer:
+				s.deliverNormalizerEvent(fmt.Sprintf("[%s] %s", event.InstanceID, event.Payload))
+			}
+			lastID = event.ID
+		}
+		if len(events) < clusterRelayBatchSize {
+			return lastID
+		}
+	}
+}
+
+// deliverNormalizerEvent передает событие нормализации локальному SSE потоку (при переполнении событие пропускается)
+func (s *Server) deliverNormalizerEvent(event string) {
+	select {
+	case s.normalizerStreamEvents <- event:
+	default:
+	}
+}
+
+// normalizerStream канал событий нормализации для SSE: при ретрансляции содержит события всех экземпляров
+func (s *Server) normalizerStream() <-chan string {
+	if s.normalizerStreamEvents != nil {
+		return s.normalizerStreamEvents
+	}
+	return s.normalizerEvents
+}
+
+// handleClusterInstances возвращает экземпляры сервера, работающие с общей service.db, и выполняющиеся
+// на них фоновые задачи. Экземпляр без heartbeat дольше срока аренды считается недоступным
+// GET /api/cluster/instances
+func (s *Server) handleClusterInstances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	instances, err := s.serviceDB.GetClusterInstances(s.clusterLeaseTTL())
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	running, err := s.serviceDB.GetBackgroundJobs(database.BackgroundJobRunning, jobsMaxLimit)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jobsByInstance := make(map[string][]*database.BackgroundJob)
+	for _, job := range running {
+		jobsByInstance[job.InstanceID] = append(jobsByInstance[job.InstanceID], job)
+	}
+
+	items := make([]map[string]interface{}, 0, len(instances))
+	for _, instance := range instances {
+		jobs := jobsByInstance[instance.ID]
+		if jobs == nil {
+			jobs = []*database.BackgroundJob{}
+		}
+		items = append(items, map[string]interface{}{
+			"instance": instance,
+			"current":  instance.ID == s.instanceID,
+			"jobs":     jobs,
+		})
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"instance_id": s.instanceID,
+		"event_relay": s.clusterRelayEnabled(),
+		"instances":   items,
+	}, http.StatusOK)
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+// newClusterTestServers создает экземпляры сервера с общей service.db
+func newClusterTestServers(t *testing.T, ids ...string) []*Server {
+	t.Helper()
+	serviceDB, err := database.NewServiceDB(filepath.Join(t.TempDir(), "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	t.Cleanup(func() { serviceDB.Close() })
+
+	servers := make([]*Server, 0, len(ids))
+	for _, id := range ids {
+		s := &Server{
+			serviceDB:              serviceDB,
+			config:                 &Config{InstanceID: id, ClusterEventRelay: true, ClusterLeaseTTL: time.Minute},
+			logChan:                make(chan LogEntry, 10),
+			instanceID:             id,
+			normalizerEvents:       make(chan string, 10),
+			normalizerStreamEvents: make(chan string, 10),
+			monitoringSubscribers:  make(map[chan []byte]struct{}),
+		}
+		if err := serviceDB.RegisterClusterInstance(&database.ClusterInstance{ID: id, Hostname: "host"}); err != nil {
+			t.Fatalf("RegisterClusterInstance() error = %v", err)
+		}
+		servers = append(servers, s)
+	}
+	return servers
+}
+
+func TestClusterJobLeasing(t *testing.T) {
+	servers := newClusterTestServers(t, "node-a", "node-b")
+	a, b := servers[0], servers[1]
+
+	job, err := a.startBackgroundJob(context.Background(), jobKindExport, "job-1")
+	if err != nil {
+		t.Fatalf("startBackgroundJob() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		server   *Server
+		jobName  string
+		wantKind apperrors.Kind
+	}{
+		{"same job on another instance", b, "job-1", apperrors.KindConflict},
+		{"same job on same instance", a, "job-1", apperrors.KindConflict},
+		{"other job on another instance", b, "job-2", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, err := tt.server.startBackgroundJob(context.Background(), jobKindExport, tt.jobName)
+			if apperrors.KindOf(err) != tt.wantKind {
+				t.Fatalf("startBackgroundJob() error = %v, want %q", err, tt.wantKind)
+			}
+			if err == nil {
+				tt.server.finishBackgroundJob(started, nil)
+			}
+		})
+	}
+	if running := b.jobs.list(); len(running) != 0 {
+		t.Errorf("rejected job must not stay registered, running = %d", len(running))
+	}
+
+	// Перезапуск node-b не прерывает задачу node-a
+	b.reportUnfinishedJobs()
+	rec := httptest.NewRecorder()
+	b.handleClusterInstances(rec, httptest.NewRequest(http.MethodGet, "/api/cluster/instances", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		InstanceID string `json:"instance_id"`
+		Instances  []struct {
+			Instance database.ClusterInstance `json:"instance"`
+			Current  bool                     `json:"current"`
+			Jobs     []database.BackgroundJob `json:"jobs"`
+		} `json:"instances"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if response.InstanceID != "node-b" || len(response.Instances) != 2 {
+		t.Fatalf("response = %+v, want two instances seen from node-b", response)
+	}
+	for _, item := range response.Instances {
+		wantJobs := 0
+		if item.Instance.ID == "node-a" {
+			wantJobs = 1
+		}
+		if len(item.Jobs) != wantJobs || item.Current != (item.Instance.ID == "node-b") || !item.Instance.Alive {
+			t.Errorf("instance %s = %+v, want %d running jobs", item.Instance.ID, item, wantJobs)
+		}
+	}
+
+	a.finishBackgroundJob(job, nil)
+	if started, err := b.startBackgroundJob(context.Background(), jobKindExport, "job-1"); err != nil {
+		t.Errorf("startBackgroundJob() after finish error = %v", err)
+	} else {
+		b.finishBackgroundJob(started, nil)
+	}
+}
+
+func TestClusterEventRelay(t *testing.T) {
+	servers := newClusterTestServers(t, "node-a", "node-b")
+	a, b := servers[0], servers[1]
+	events, unsubscribe := b.subscribeMonitoringEvents()
+	defer unsubscribe()
+
+	a.publishMonitoringEvent(map[string]interface{}{"type": "worker_config_changed"})
+	if err := a.serviceDB.PublishClusterEvent(a.instanceID, database.ClusterChannelNormalizer, "Нормализация завершена успешно"); err != nil {
+		t.Fatalf("PublishClusterEvent() error = %v", err)
+	}
+
+	if lastID := b.relayClusterEvents(0); lastID != 2 {
+		t.Errorf("relayClusterEvents() = %d, want 2", lastID)
+	}
+	select {
+	case data := <-events:
+		if string(data) != `{"type":"worker_config_changed"}` {
+			t.Errorf("monitoring event = %s", data)
+		}
+	default:
+		t.Error("monitoring event of node-a was not relayed to node-b")
+	}
+	select {
+	case event := <-b.normalizerStream():
+		if event != "[node-a] Нормализация завершена успешно" {
+			t.Errorf("normalizer event = %q", event)
+		}
+	default:
+		t.Error("normalizer event of node-a was not relayed to node-b")
+	}
+
+	// Собственные события экземпляра не ретранслируются ему повторно
+	if lastID := a.relayClusterEvents(0); lastID != 0 {
+		t.Errorf("relayClusterEvents() on origin = %d, want 0", lastID)
+	}
+}
+
+func TestClusterInstanceID(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		hostname   string
+		want       string
+	}{
+		{"configured", "api-1", "host", "api-1"},
+		{"hostname and pid", "", "host", "host-42"},
+		{"no hostname", "", "", "localhost-42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clusterInstanceID(tt.configured, tt.hostname, 42); got != tt.want {
+				t.Errorf("clusterInstanceID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/normalization"
+	"httpserver/reports"
+)
+
+// maxMappingFileSize ограничивает размер загружаемого файла соответствий
+const maxMappingFileSize = 20 << 20
+
+// handleProjectCodeMappings управляет файлами соответствий кодов клиента (старый код -> новый код)
+// GET/POST /api/clients/{id}/projects/{projectId}/mappings
+// GET/DELETE /api/clients/{id}/projects/{projectId}/mappings/{fileId}[?status=invalid]
+func (s *Server) handleProjectCodeMappings(w http.ResponseWriter, r *http.Request, clientID, projectID, fileID int) {
+	if !s.checkClientProject(w, clientID, projectID) {
+		return
+	}
+
+	if fileID == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			files, err := s.serviceDB.GetClientMappingFiles(projectID)
+			if err != nil {
+				s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			s.writeJSONResponse(w, map[string]interface{}{
+				"project_id": projectID,
+				"files":      files,
+				"total":      len(files),
+			}, http.StatusOK)
+		case http.MethodPost:
+			s.handleUploadCodeMappings(w, r, projectID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	file, err := s.serviceDB.GetClientMappingFile(projectID, fileID)
+	if err != nil {
+		s.writeJSONError(w, "Mapping file not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		status := r.URL.Query().Get("status")
+		mappings, err := s.serviceDB.GetClientCodeMappings(projectID, fileID, status)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"file":     file,
+			"mappings": mappings,
+		}, http.StatusOK)
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteClientMappingFile(projectID, fileID); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Соответствия проекта %d: удален файл %q", projectID, file.FileName),
+			Endpoint:  r.URL.Path,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleUploadCodeMappings принимает файл соответствий (multipart поле file, CSV или XLSX),
+// проверяет его по данным проекта и сохраняет
+func (s *Server) handleUploadCodeMappings(w http.ResponseWriter, r *http.Request, projectID int) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMappingFileSize)
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		s.writeJSONError(w, "Mapping file is required in multipart field 'file'", http.StatusBadRequest)
+		return
+	}
+	defer upload.Close()
+
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		s.writeJSONError(w, "Failed to read mapping file", http.StatusBadRequest)
+		return
+	}
+	rows, format, err := readMappingFile(header.Filename, data)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mappings := normalization.ParseCodeMappingRows(rows)
+	if len(mappings) == 0 {
+		s.writeJSONError(w, "Mapping file contains no rows", http.StatusBadRequest)
+		return
+	}
+	existing, err := s.projectExistingCodes(projectID, normalization.CodeMappingCodes(mappings))
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	normalization.ValidateCodeMappingReferences(mappings, existing)
+
+	file := &database.ClientMappingFile{ProjectID: projectID, FileName: header.Filename, Format: format}
+	if err := s.serviceDB.CreateClientMappingFile(file, mappings); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message: fmt.Sprintf("Соответствия проекта %d: загружен файл %q (строк %d, принято %d, предупреждений %d, отклонено %d)",
+			projectID, file.FileName, file.TotalRows, file.ValidRows, file.WarningRows, file.InvalidRows),
+		Endpoint: r.URL.Path,
+	})
+
+	issues := []*database.ClientCodeMapping{}
+	for _, mapping := range mappings {
+		if mapping.Status != database.CodeMappingStatusValid {
+			issues = append(issues, mapping)
+		}
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"file":   file,
+		"issues": issues,
+	}, http.StatusCreated)
+}
+
+// handleProjectCodeMappingConflicts сравнивает соответствия клиента с объединениями, найденными нормализацией
+// GET /api/clients/{id}/projects/{projectId}/mappings/conflicts
+func (s *Server) handleProjectCodeMappingConflicts(w http.ResponseWriter, r *http.Request, clientID, projectID int) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.checkClientProject(w, clientID, projectID) {
+		return
+	}
+
+	mappings, err := normalization.LoadProjectCodeMappings(s.serviceDB, projectID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	active, err := s.serviceDB.GetActiveClientCodeMappings(projectID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	codes := normalization.CodeMappingCodes(active)
+
+	databases, err := s.serviceDB.GetProjectDatabases(projectID, true)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type databaseConflicts struct {
+		DatabaseID int                                 `json:"database_id"`
+		Database   string                              `json:"database"`
+		Conflicts  []normalization.CodeMappingConflict `json:"conflicts"`
+		Error      string                              `json:"error,omitempty"`
+	}
+	results := make([]databaseConflicts, 0, len(databases))
+	total := 0
+	for _, dbInfo := range databases {
+		result := databaseConflicts{DatabaseID: dbInfo.ID, Database: dbInfo.Name, Conflicts: []normalization.CodeMappingConflict{}}
+		projectDB, err := database.NewDB(dbInfo.FilePath)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		references, err := projectDB.GetNormalizedReferencesByCodes(codes)
+		projectDB.Close()
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Conflicts = normalization.FindCodeMappingConflicts(mappings, references)
+			total += len(result.Conflicts)
+		}
+		results = append(results, result)
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"project_id":      projectID,
+		"active_mappings": mappings.Len(),
+		"databases":       results,
+		"total_conflicts": total,
+	}, http.StatusOK)
+}
+
+// checkClientProject проверяет доступность сервисной БД и принадлежность проекта клиенту
+func (s *Server) checkClientProject(w http.ResponseWriter, clientID, projectID int) bool {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return false
+	}
+	project, err := s.serviceDB.GetClientProject(projectID)
+	if err != nil {
+		s.writeJSONError(w, "Project not found", http.StatusNotFound)
+		return false
+	}
+	if project.ClientID != clientID {
+		s.writeJSONError(w, "Project does not belong to this client", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// projectExistingCodes возвращает коды, найденные в активных базах проекта
+func (s *Server) projectExistingCodes(projectID int, codes []string) (map[string]bool, error) {
+	databases, err := s.serviceDB.GetProjectDatabases(projectID, true)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool)
+	for _, dbInfo := range databases {
+		projectDB, err := database.NewDB(dbInfo.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open project database %s: %w", dbInfo.Name, err)
+		}
+		found, err := projectDB.FindExistingCodes(codes)
+		projectDB.Close()
+		if err != nil {
+			return nil, err
+		}
+		for code := range found {
+			existing[code] = true
+		}
+	}
+	return existing, nil
+}
+
+// readMappingFile читает строки файла соответствий по расширению: .xlsx или CSV (.csv, .tsv, .txt)
+func readMappingFile(fileName string, data []byte) ([][]string, string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xlsx":
+		rows, err := reports.ReadXLSX(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid XLSX file: %w", err)
+		}
+		return rows, "xlsx", nil
+	case ".csv", ".tsv", ".txt":
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comma = detectCSVDelimiter(data)
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid CSV file: %w", err)
+		}
+		return rows, "csv", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported mapping file type %q, expected .csv or .xlsx", filepath.Ext(fileName))
+	}
+}
+
+// detectCSVDelimiter выбирает разделитель по первой строке: табуляция, точка с запятой или запятая
+func detectCSVDelimiter(data []byte) rune {
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	delimiter, best := ',', bytes.Count(firstLine, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if count := bytes.Count(firstLine, []byte(string(candidate))); count > best {
+			delimiter, best = candidate, count
+		}
+	}
+	return delimiter
+}
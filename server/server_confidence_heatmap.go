@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"httpserver/reports"
+)
+
+// confidenceHeatmapCache тепловые карты уверенности по базе и выгрузке. Карта пересчитывается при
+// изменении файла БД (размер или время изменения основного файла и WAL); базы без файла не кешируются
+type confidenceHeatmapCache struct {
+	mu      sync.Mutex
+	entries map[string]*confidenceHeatmapEntry
+}
+
+// confidenceHeatmapEntry закешированная тепловая карта
+type confidenceHeatmapEntry struct {
+	fingerprint string
+	heatmap     *reports.ConfidenceHeatmap
+}
+
+// get возвращает тепловую карту из кеша или строит ее через build; cached - карта взята из кеша
+func (c *confidenceHeatmapCache) get(path string, uploadID int, build func() (*reports.ConfidenceHeatmap, error)) (heatmap *reports.ConfidenceHeatmap, cached bool, err error) {
+	info, statErr := os.Stat(path)
+	if statErr != nil {
+		heatmap, err = build()
+		return heatmap, false, err
+	}
+	key := fmt.Sprintf("%s#%d", path, uploadID)
+	fingerprint := databaseFileFingerprint(path, info)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.fingerprint == fingerprint {
+		return entry.heatmap, true, nil
+	}
+
+	if heatmap, err = build(); err != nil {
+		return nil, false, err
+	}
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*confidenceHeatmapEntry)
+	}
+	c.entries[key] = &confidenceHeatmapEntry{fingerprint: fingerprint, heatmap: heatmap}
+	c.mu.Unlock()
+	return heatmap, false, nil
+}
+
+// handleConfidenceHeatmap возвращает уверенность классификации по разделам КПВЭД и исходным справочникам:
+// среднее, медиану и число записей в каждой ячейке
+// GET /api/reports/kpved/heatmap?format=json|csv|tsv&upload_id=N
+func (s *Server) handleConfidenceHeatmap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	format := reports.FormatJSON
+	if query.Get("format") != "" {
+		var err error
+		if format, err = reports.ParseOutputFormat(query.Get("format")); err != nil || format == reports.FormatText {
+			s.writeJSONError(w, "format must be json, csv or tsv", http.StatusBadRequest)
+			return
+		}
+	}
+	uploadID := 0
+	if uploadIDStr := query.Get("upload_id"); uploadIDStr != "" {
+		var err error
+		if uploadID, err = strconv.Atoi(uploadIDStr); err != nil || uploadID <= 0 {
+			s.writeJSONError(w, "Invalid upload_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var classifier *sql.DB
+	if s.serviceDB != nil {
+		classifier = s.serviceDB.GetDB()
+	}
+	s.dbMutex.RLock()
+	dbPath := s.currentDBPath
+	data := s.db.GetDB()
+	s.dbMutex.RUnlock()
+
+	heatmap, cached, err := s.confidenceHeatmap.get(dbPath, uploadID, func() (*reports.ConfidenceHeatmap, error) {
+		provider := reports.NewProvider(data, classifier)
+		if uploadID > 0 {
+			provider = provider.ForUpload(uploadID)
+		}
+		return provider.ConfidenceHeatmap()
+	})
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to build confidence heatmap: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if cached {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+
+	if format == reports.FormatJSON {
+		s.writeJSONResponse(w, heatmap, http.StatusOK)
+		return
+	}
+	var buf bytes.Buffer
+	if err := reports.WriteTable(&buf, format, reports.ConfidenceHeatmapTable(heatmap)); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", inspectionContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=confidence_heatmap_%s.%s", time.Now().Format("20060102_150405"), format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
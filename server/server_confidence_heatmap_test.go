@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestConfidenceHeatmap(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "heatmap.db")
+	db, err := database.NewDBWithConfig(dbPath, database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`
+		INSERT INTO uploads (upload_uuid, version_1c, config_name) VALUES ('heatmap', '8.3', 'УТ');
+		INSERT INTO catalogs (upload_id, name, synonym) VALUES (1, 'Номенклатура', '');
+		INSERT INTO catalog_items (catalog_id, reference, code, name) VALUES (1, 'ref-1', '1', 'Болт М8'), (1, 'ref-2', '2', 'Гайка М8');
+		INSERT INTO normalized_data (code, source_reference, source_name, normalized_name, category, kpved_code, kpved_confidence) VALUES
+			('1', 'ref-1', 'Болт М8', 'болт м8', 'крепеж', '25.94.11', 0.9),
+			('2', 'ref-2', 'Гайка М8', 'гайка м8', 'крепеж', '25.94.12', 0.5)
+	`); err != nil {
+		t.Fatalf("Failed to seed heatmap data: %v", err)
+	}
+	s := &Server{db: db, currentDBPath: dbPath, logChan: make(chan LogEntry, 10)}
+
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantCache       string
+		wantContentType string
+		wantBody        string
+	}{
+		{"json", "/api/reports/kpved/heatmap", http.StatusOK, "MISS", "application/json", `"median_confidence":0.7`},
+		{"cached", "/api/reports/kpved/heatmap?format=json", http.StatusOK, "HIT", "application/json", `"catalog":"Номенклатура"`},
+		{"csv", "/api/reports/kpved/heatmap?format=csv", http.StatusOK, "HIT", "text/csv", "C,,Номенклатура,2,0.7,0.7,1\n"},
+		{"upload", "/api/reports/kpved/heatmap?upload_id=2", http.StatusOK, "MISS", "application/json", `"total_items":0`},
+		{"text format", "/api/reports/kpved/heatmap?format=text", http.StatusBadRequest, "", "", ""},
+		{"invalid upload", "/api/reports/kpved/heatmap?upload_id=x", http.StatusBadRequest, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleConfidenceHeatmap(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus || rec.Header().Get("X-Cache") != tt.wantCache ||
+				!strings.HasPrefix(rec.Header().Get("Content-Type"), tt.wantContentType) || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d, cache = %s, content type = %s, body = %s",
+					rec.Code, rec.Header().Get("X-Cache"), rec.Header().Get("Content-Type"), rec.Body.String())
+			}
+		})
+	}
+
+	// Изменение базы сбрасывает кеш
+	if _, err := db.Exec(`UPDATE normalized_data SET kpved_confidence = 0.95 WHERE code = '2'`); err != nil {
+		t.Fatalf("Failed to update normalized_data: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.handleConfidenceHeatmap(rec, httptest.NewRequest(http.MethodGet, "/api/reports/kpved/heatmap", nil))
+	if rec.Header().Get("X-Cache") != "MISS" || !strings.Contains(rec.Body.String(), `"median_confidence":0.93`) {
+		t.Errorf("after update cache = %s, body = %s", rec.Header().Get("X-Cache"), rec.Body.String())
+	}
+}
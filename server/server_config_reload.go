@@ -0,0 +1,302 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"httpserver/database"
+)
+
+// Уровни логирования (LOG_LEVEL)
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// logLevelRank порядок уровня записи лога; неизвестные уровни считаются INFO
+func logLevelRank(level string) int32 {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return 0
+	case "WARN", "WARNING":
+		return 2
+	case "ERROR":
+		return 3
+	default:
+		return 1
+	}
+}
+
+// reloadableSettings настройки, применяемые без перезапуска сервера, и действия для их применения.
+// Действие nil - настройка читается из s.currentConfig() при каждом использовании.
+// Остальные поля Config (порт, пути к БД, пулы соединений, хранилище, очередь, трассировка) требуют перезапуска
+var reloadableSettings = map[string]func(s *Server, cfg *Config) error{
+	"LogLevel": func(s *Server, cfg *Config) error {
+		s.logLevel.Store(logLevelRank(cfg.LogLevel))
+		return nil
+	},
+	"IngestItemsPerSecond": (*Server).resetIngestBudgets,
+	"IngestMBPerSecond":    (*Server).resetIngestBudgets,
+	"ArliaiAPIKey":         (*Server).applyAIKey,
+	"ArliaiModel": func(s *Server, cfg *Config) error {
+		if s.workerConfigManager == nil {
+			return nil
+		}
+		return s.workerConfigManager.SetDefaultModel("arliai", cfg.ArliaiModel)
+	},
+	"AIMaxWorkers": func(s *Server, cfg *Config) error {
+		if s.workerConfigManager == nil || cfg.AIMaxWorkers == 0 {
+			return nil
+		}
+		return s.workerConfigManager.SetGlobalMaxWorkers(cfg.AIMaxWorkers)
+	},
+	"MetricsRetentionDays":            nil,
+	"TimeTravelRetentionDays":         nil,
+	"NormalizedChangesRetentionDays":  nil,
+	"SlowRequestThreshold":            nil,
+	"SlowRequestLogSize":              nil,
+	"DBQueryTimeout":                  nil,
+	"ShutdownGracePeriod":             nil,
+	"RestartCrashedWorkers":           nil,
+	"HistoricalClassificationEnabled": nil,
+	"HistoricalMinSimilarity":         nil,
+	"IngestValidationMode":            nil,
+	"UploadCompletenessMode":          nil,
+	"EventsWebhookURL":                nil,
+	"MinProtocolVersion":              nil,
+	"StoragePresignTTL":               nil,
+	"AttachmentMaxSizeMB":             nil,
+	"SessionTTL":                      nil,
+	"SessionCookieSecure":             nil,
+	"OIDC":                            nil,
+	"SSOOnly":                         nil,
+	"BreakGlassUsername":              nil,
+	"BreakGlassPasswordHash":          nil,
+	"AuthSetupToken":                  nil,
+	"RedactionDefaultProfile":         nil,
+	"SMTPHost":                        nil,
+	"SMTPPort":                        nil,
+	"SMTPUsername":                    nil,
+	"SMTPPassword":                    nil,
+	"SMTPFrom":                        nil,
+}
+
+// secretSettings настройки, значения которых не выводятся в отчет о перезагрузке
+var secretSettings = map[string]bool{
+	"ArliaiAPIKey":           true,
+	"SMTPPassword":           true,
+	"Storage":                true,
+	"IngestQueue":            true,
+	"Tracing":                true,
+	"OIDC":                   true,
+	"BreakGlassPasswordHash": true,
+	"AuthSetupToken":         true,
+	"EncryptionMasterKeys":   true,
+}
+
+// ConfigChange изменение одной настройки при перезагрузке конфигурации
+type ConfigChange struct {
+	Setting string `json:"setting"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ConfigReloadResult итог перезагрузки: примененные настройки, настройки, ожидающие перезапуска,
+// и настройки, которые не удалось применить (для них действует прежнее значение)
+type ConfigReloadResult struct {
+	Applied         []ConfigChange `json:"applied"`
+	RestartRequired []ConfigChange `json:"restart_required"`
+	Failed          []ConfigChange `json:"failed"`
+	Timestamp       time.Time      `json:"timestamp"`
+}
+
+// ReloadConfig перечитывает конфигурацию из окружения (и CONFIG_FILE) и применяет перезагружаемые настройки.
+// Невалидная конфигурация не применяется целиком
+func (s *Server) ReloadConfig(actor string) (*ConfigReloadResult, error) {
+	next, err := LoadConfig()
+	if err != nil {
+		s.recordConfigReload(actor, "rejected", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+
+	result := s.applyConfig(next)
+	status := "success"
+	if len(result.Failed) > 0 {
+		status = "partial"
+	}
+	s.recordConfigReload(actor, status, map[string]interface{}{
+		"applied":          result.Applied,
+		"restart_required": result.RestartRequired,
+		"failed":           result.Failed,
+	})
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message: fmt.Sprintf("Конфигурация перезагружена (%s): применено %d, требуют перезапуска %d, ошибок %d",
+			actor, len(result.Applied), len(result.RestartRequired), len(result.Failed)),
+		Endpoint: "/api/admin/reload",
+	})
+	for _, change := range result.RestartRequired {
+		log.Printf("Настройка %s изменена, но применится только после перезапуска сервера", change.Setting)
+	}
+	return result, nil
+}
+
+// currentConfig возвращает действующую конфигурацию. Снимок не изменяется после публикации,
+// поэтому его поля читаются без блокировки
+func (s *Server) currentConfig() *Config {
+	if cfg := s.liveConfig.Load(); cfg != nil {
+		return cfg
+	}
+	return s.config
+}
+
+// applyConfig сравнивает next с текущей конфигурацией и переносит изменившиеся перезагружаемые настройки
+// в рабочую копию конфигурации. После каждой настройки публикуется новый снимок копии, чтобы действие
+// применения и обработчики видели новое значение; опубликованные снимки не изменяются.
+// configMu исключает одновременные перезагрузки
+func (s *Server) applyConfig(next *Config) *ConfigReloadResult {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+
+	result := &ConfigReloadResult{
+		Applied:         []ConfigChange{},
+		RestartRequired: []ConfigChange{},
+		Failed:          []ConfigChange{},
+		Timestamp:       time.Now(),
+	}
+
+	reloaded := *s.currentConfig()
+	current := reflect.ValueOf(&reloaded).Elem()
+	updated := reflect.ValueOf(next).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		name := current.Type().Field(i).Name
+		field, value := current.Field(i), updated.Field(i)
+		if reflect.DeepEqual(field.Interface(), value.Interface()) {
+			continue
+		}
+		change := ConfigChange{Setting: name, Old: settingString(name, field), New: settingString(name, value)}
+
+		apply, reloadable := reloadableSettings[name]
+		if !reloadable {
+			result.RestartRequired = append(result.RestartRequired, change)
+			continue
+		}
+
+		previous := reflect.New(field.Type()).Elem()
+		previous.Set(field)
+		field.Set(value)
+		snapshot := reloaded
+		s.liveConfig.Store(&snapshot)
+		if apply != nil {
+			if err := apply(s, &snapshot); err != nil {
+				field.Set(previous)
+				restored := reloaded
+				s.liveConfig.Store(&restored)
+				change.Error = err.Error()
+				result.Failed = append(result.Failed, change)
+				continue
+			}
+		}
+		result.Applied = append(result.Applied, change)
+	}
+	return result
+}
+
+// settingString значение настройки для отчета; секреты маскируются
+func settingString(name string, value reflect.Value) string {
+	if secretSettings[name] {
+		if value.IsZero() {
+			return ""
+		}
+		return "***"
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// resetIngestBudgets сбрасывает бюджеты приема всех клиентов, чтобы новые ограничения по умолчанию действовали сразу
+func (s *Server) resetIngestBudgets(*Config) error {
+	s.ingestThrottle.mu.Lock()
+	s.ingestThrottle.clients = nil
+	s.ingestThrottle.mu.Unlock()
+	return nil
+}
+
+// applyAIKey передает новый ключ AI провайдера конфигурации воркеров, работающим клиентам
+// и обработчикам, читающим ARLIAI_API_KEY при запуске задач
+func (s *Server) applyAIKey(cfg *Config) error {
+	if s.workerConfigManager != nil {
+		if err := s.workerConfigManager.SetProviderAPIKey("arliai", cfg.ArliaiAPIKey); err != nil {
+			return err
+		}
+	}
+	if err := os.Setenv("ARLIAI_API_KEY", cfg.ArliaiAPIKey); err != nil {
+		return err
+	}
+	for client := range s.liveAIClients() {
+		client.SetAPIKey(cfg.ArliaiAPIKey)
+	}
+	if s.arliaiClient != nil {
+		s.arliaiClient.SetAPIKey(cfg.ArliaiAPIKey)
+	}
+	return nil
+}
+
+// recordConfigReload записывает перезагрузку конфигурации в журнал аудита
+func (s *Server) recordConfigReload(actor, status string, details map[string]interface{}) {
+	if s.serviceDB == nil {
+		return
+	}
+	err := s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action:  database.AuditActionConfigReload,
+		Actor:   actor,
+		Target:  "config",
+		Status:  status,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Ошибка записи перезагрузки конфигурации в журнал аудита: %v", err)
+	}
+}
+
+// watchReloadSignal перезагружает конфигурацию по SIGHUP до остановки сервера
+func (s *Server) watchReloadSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			if _, err := s.ReloadConfig("SIGHUP"); err != nil {
+				log.Printf("Перезагрузка конфигурации по SIGHUP отклонена: %v", err)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// handleAdminReload POST /api/admin/reload - перезагрузка конфигурации без перезапуска сервера
+func (s *Server) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	result, err := s.ReloadConfig(requestActor(r, "", r.RemoteAddr))
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeJSONResponse(w, result, http.StatusOK)
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/nomenclature"
+)
+
+// writeConfigFile записывает CONFIG_FILE и восстанавливает затронутые переменные после теста
+func writeConfigFile(t *testing.T, path, content string, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, os.Getenv(key))
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.env")
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "comments, export and quotes",
+			content: "# log\nexport LOG_LEVEL=debug\n\nSMTP_FROM = \"reports@example.com\"\nSMTP_HOST='smtp.example.com'\n",
+			want:    map[string]string{"LOG_LEVEL": "debug", "SMTP_FROM": "reports@example.com", "SMTP_HOST": "smtp.example.com"},
+		},
+		{
+			name:    "empty value",
+			content: "SMTP_FROM=\n",
+			want:    map[string]string{"SMTP_FROM": ""},
+		},
+		{
+			name:    "missing separator",
+			content: "LOG_LEVEL debug\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfigFile(t, path, tt.content, "LOG_LEVEL", "SMTP_FROM", "SMTP_HOST")
+			err := loadEnvFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadEnvFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for key, want := range tt.want {
+				if got := os.Getenv(key); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleAdminReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.env")
+	keys := []string{"LOG_LEVEL", "SERVER_PORT", "INGEST_ITEMS_PER_SECOND", "ARLIAI_API_KEY"}
+	writeConfigFile(t, path, "LOG_LEVEL=info\nSERVER_PORT=9999\nARLIAI_API_KEY=old-key\n", keys...)
+	t.Setenv("CONFIG_FILE", path)
+
+	s := newCrashTestServer(t)
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	s.config = config
+	s.aiClients = make(map[*nomenclature.AIClient]string)
+	defer s.registerAIClient(nomenclature.NewAIClient("old-key", "GLM-4.5-Air"), "test")()
+
+	writeConfigFile(t, path, "LOG_LEVEL=error\nSERVER_PORT=8080\nINGEST_ITEMS_PER_SECOND=50\nARLIAI_API_KEY=new-key\n")
+	// Обработчики читают конфигурацию во время перезагрузки (проверяется под -race)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = s.currentConfig().IngestItemsPerSecond
+		}
+	}()
+	rec := httptest.NewRecorder()
+	s.handleAdminReload(rec, httptest.NewRequest(http.MethodPost, "/api/admin/reload", nil))
+	<-done
+	if s.config.LogLevel != LogLevelInfo {
+		t.Errorf("startup config log level = %q, want unchanged snapshot", s.config.LogLevel)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var result ConfigReloadResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	applied := map[string]ConfigChange{}
+	for _, change := range result.Applied {
+		applied[change.Setting] = change
+	}
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"log level applied", s.currentConfig().LogLevel, LogLevelError},
+		{"log level filter", s.logLevel.Load(), logLevelRank("ERROR")},
+		{"rate limit applied", s.currentConfig().IngestItemsPerSecond, 50.0},
+		{"api key env", os.Getenv("ARLIAI_API_KEY"), "new-key"},
+		{"api key masked", applied["ArliaiAPIKey"].New, "***"},
+		{"port kept", s.currentConfig().Port, "9999"},
+		{"applied", len(result.Applied), 3},
+		{"restart required", len(result.RestartRequired), 1},
+		{"restart required setting", result.RestartRequired[0].Setting, "Port"},
+		{"failed", len(result.Failed), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+
+	events, err := s.serviceDB.GetAuditEvents(database.AuditActionConfigReload, 10)
+	if err != nil || len(events) != 1 || events[0].Status != "success" {
+		t.Errorf("audit events = %+v, err = %v, want one successful reload", events, err)
+	}
+
+	t.Run("invalid config is rejected", func(t *testing.T) {
+		writeConfigFile(t, path, "LOG_LEVEL=verbose\n")
+		rec := httptest.NewRecorder()
+		s.handleAdminReload(rec, httptest.NewRequest(http.MethodPost, "/api/admin/reload", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+		if s.currentConfig().LogLevel != LogLevelError {
+			t.Errorf("log level = %q, want unchanged", s.currentConfig().LogLevel)
+		}
+	})
+}
+
+func TestLogLevelFilter(t *testing.T) {
+	s := newCrashTestServer(t)
+	s.logLevel.Store(logLevelRank("WARN"))
+
+	for _, level := range []string{"DEBUG", "INFO", "WARNING", "ERROR"} {
+		s.log(LogEntry{Level: level, Message: level})
+	}
+	close(s.logChan)
+	var got []string
+	for entry := range s.logChan {
+		got = append(got, entry.Level)
+	}
+	if len(got) != 2 || got[0] != "WARNING" || got[1] != "ERROR" {
+		t.Errorf("logged levels = %v, want [WARNING ERROR]", got)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"httpserver/database"
+)
+
+// handleProjectConstantDrift матрица расхождений констант между активными базами проекта
+// по их последним завершенным выгрузкам
+// GET /api/clients/{id}/projects/{projectId}/constants/drift?only_drift=true&sensitive=true&format=csv
+func (s *Server) handleProjectConstantDrift(w http.ResponseWriter, r *http.Request, clientID, projectID int) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.checkClientProject(w, clientID, projectID) {
+		return
+	}
+	s.dbMutex.RLock()
+	db := s.db
+	s.dbMutex.RUnlock()
+	if db == nil {
+		s.writeJSONError(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	projectDatabases, err := s.serviceDB.GetProjectDatabases(projectID, true)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	columns := make([]database.ConstantDriftDatabase, 0, len(projectDatabases))
+	for _, dbInfo := range projectDatabases {
+		columns = append(columns, database.ConstantDriftDatabase{DatabaseID: dbInfo.ID, Database: dbInfo.Name})
+	}
+	report := db.ReadOnly().GetConstantDrift(columns)
+
+	query := r.URL.Query()
+	onlyDrift := query.Get("only_drift") == "true"
+	onlySensitive := query.Get("sensitive") == "true"
+	if onlyDrift || onlySensitive {
+		filtered := []database.ConstantDriftRow{}
+		for _, row := range report.Constants {
+			if (onlyDrift && !row.Drift && !row.Missing) || (onlySensitive && len(row.Sensitive) == 0) {
+				continue
+			}
+			filtered = append(filtered, row)
+		}
+		report.Constants = filtered
+	}
+
+	if query.Get("format") == "csv" {
+		writeConstantDriftCSV(w, fmt.Sprintf("constant_drift_project_%d.csv", projectID), report)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"project_id": projectID,
+		"report":     report,
+	}, http.StatusOK)
+}
+
+// writeConstantDriftCSV записывает матрицу расхождений файлом CSV: строка на константу, колонка на базу
+func writeConstantDriftCSV(w http.ResponseWriter, filename string, report *database.ConstantDriftReport) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	// UTF-8 BOM для корректного отображения в Excel
+	w.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+	header := []string{"Константа", "Синоним", "Расхождение", "Отсутствует", "Чувствительная"}
+	for _, column := range report.Databases {
+		header = append(header, column.Database)
+	}
+	writer.Write(header)
+	for _, row := range report.Constants {
+		record := []string{row.Name, row.Synonym, yesNo(row.Drift), yesNo(row.Missing), strings.Join(row.Sensitive, ",")}
+		for _, cell := range row.Values {
+			value := ""
+			if cell.Present {
+				value = database.FormatConstantTypedValue(cell.Value)
+			}
+			record = append(record, value)
+		}
+		writer.Write(record)
+	}
+}
+
+// yesNo значение флага для CSV
+func yesNo(flag bool) string {
+	if flag {
+		return "да"
+	}
+	return "нет"
+}
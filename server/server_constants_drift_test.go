@@ -0,0 +1,72 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestProjectConstantDrift(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer serviceDB.Close()
+
+	client, _ := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	project, _ := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	for i, path := range []string{`D:\exchange`, `\\srv01\exchange`} {
+		dbInfo, err := serviceDB.CreateProjectDatabase(project.ID, fmt.Sprintf("База%d", i), fmt.Sprintf("base%d.db", i), "", 0)
+		if err != nil {
+			t.Fatalf("CreateProjectDatabase() error = %v", err)
+		}
+		upload, _ := db.CreateUploadWithDatabase(fmt.Sprintf("upload-%d", i), "8.3", "УТ", &dbInfo.ID, "", "", "", 1, "", "", "", nil)
+		db.AddConstant(upload.ID, "ПутьКОбмену", "Путь к обмену", "Строка", path)
+		db.AddConstant(upload.ID, "ОсновнаяВалюта", "Основная валюта", "Строка", "RUB")
+		db.CompleteUpload(upload.ID)
+	}
+	s := &Server{logChan: make(chan LogEntry, 100), db: db, serviceDB: serviceDB, config: &Config{}}
+
+	base := fmt.Sprintf("/api/clients/%d/projects/%d/constants/drift", client.ID, project.ID)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   []string
+		notBody    string
+	}{
+		{"matrix", http.MethodGet, base, http.StatusOK, []string{`"drifted":1`, `"name":"ОсновнаяВалюта"`, `"sensitive":["path"]`}, ""},
+		{"only drift", http.MethodGet, base + "?only_drift=true", http.StatusOK, []string{`"name":"ПутьКОбмену"`}, `"name":"ОсновнаяВалюта"`},
+		{"csv", http.MethodGet, base + "?format=csv", http.StatusOK, []string{"Константа,Синоним,Расхождение,Отсутствует,Чувствительная,База0,База1", `ПутьКОбмену,Путь к обмену,да,нет,path,D:\exchange,\\srv01\exchange`}, ""},
+		{"wrong client", http.MethodGet, fmt.Sprintf("/api/clients/%d/projects/%d/constants/drift", client.ID+1, project.ID), http.StatusBadRequest, nil, ""},
+		{"method not allowed", http.MethodPost, base, http.StatusMethodNotAllowed, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleClientRoutes(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			body := rec.Body.String()
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s, want %d", rec.Code, body, tt.wantStatus)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("body = %s, want %q", body, want)
+				}
+			}
+			if tt.notBody != "" && strings.Contains(body, tt.notBody) {
+				t.Errorf("body = %s, must not contain %q", body, tt.notBody)
+			}
+		})
+	}
+}
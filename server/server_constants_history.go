@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// handleDatabaseConstants обрабатывает маршруты констант базы данных (subpath - сегменты после constants)
+// GET /api/databases/{id}/constants/{name}/history
+// GET /api/databases/{id}/constants/changes
+func (s *Server) handleDatabaseConstants(w http.ResponseWriter, r *http.Request, databaseID int, subpath []string) {
+	s.dbMutex.RLock()
+	db := s.db
+	s.dbMutex.RUnlock()
+	if db == nil {
+		s.writeJSONError(w, "Database not available", http.StatusServiceUnavailable)
+		return
+	}
+	db = db.ReadOnly()
+
+	switch {
+	case len(subpath) == 1 && subpath[0] == "changes":
+		report, err := db.GetChangedConstants(databaseID)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to compare constants: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, report, http.StatusOK)
+	case len(subpath) == 2 && subpath[0] != "" && subpath[1] == "history":
+		name := subpath[0]
+		history, err := db.GetConstantHistory(databaseID, name)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get constant history: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if len(history) == 0 {
+			s.writeJSONError(w, fmt.Sprintf("Constant %s not found in uploads of database %d", name, databaseID), http.StatusNotFound)
+			return
+		}
+		changes := 0
+		for _, entry := range history {
+			if entry.Changed {
+				changes++
+			}
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"database_id": databaseID,
+			"name":        name,
+			"history":     history,
+			"total":       len(history),
+			"changes":     changes,
+		}, http.StatusOK)
+	default:
+		s.writeJSONError(w, "Not found", http.StatusNotFound)
+	}
+}
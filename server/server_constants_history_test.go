@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestDatabaseConstantsRoutes(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+
+	databaseID := 3
+	for i, value := range []string{"RUB", "USD"} {
+		upload, _ := db.CreateUploadWithDatabase(fmt.Sprintf("upload-%d", i), "8.3", "УТ", &databaseID, "", "", "", 1, "", "", "", nil)
+		db.AddConstant(upload.ID, "ОсновнаяВалюта", "Основная валюта", "Строка", value)
+		db.CompleteUpload(upload.ID)
+	}
+	s := &Server{db: db, logChan: make(chan LogEntry, 100)}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"history", http.MethodGet, "/api/databases/3/constants/" + url.PathEscape("ОсновнаяВалюта") + "/history", http.StatusOK, `"previous_value":"RUB"`},
+		{"unknown constant", http.MethodGet, "/api/databases/3/constants/Нет/history", http.StatusNotFound, ""},
+		{"changes", http.MethodGet, "/api/databases/3/constants/changes", http.StatusOK, `"change":"changed"`},
+		{"unknown subpath", http.MethodGet, "/api/databases/3/constants", http.StatusNotFound, ""},
+		{"method not allowed", http.MethodPost, "/api/databases/3/constants/changes", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleDatabaseRoutes(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("status = %d, body = %s, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"httpserver/database"
+)
+
+// Ограничения выборки отчетов о сбоях
+const (
+	crashesDefaultLimit = 50
+	crashesMaxLimit     = 500
+)
+
+// Задержка перезапуска упавшего воркера: удваивается после каждого сбоя до максимума
+const (
+	workerRestartDelay    = time.Second
+	workerRestartMaxDelay = time.Minute
+)
+
+// recoverWorker перехватывает панику фоновой горутины, сохраняет отчет о сбое и отмечает задачу
+// как завершившуюся с ошибкой. Вызывается только через defer, последним defer горутины
+func (s *Server) recoverWorker(component string, job *backgroundJob) {
+	if recovered := recover(); recovered != nil {
+		s.reportCrash(component, job, recovered, debug.Stack(), nil, false)
+	}
+}
+
+// recoverTask перехватывает панику воркера очереди задач: details дополняет отчет контекстом задачи,
+// onCrash вызывается после сохранения отчета и перезапускает воркер. Вызывается только через defer
+func (s *Server) recoverTask(component string, details func() map[string]interface{}, onCrash func()) {
+	if recovered := recover(); recovered != nil {
+		s.reportCrash(component, nil, recovered, debug.Stack(), details(), true)
+		onCrash()
+	}
+}
+
+// runIsolated выполняет fn с перехватом паники. details вызывается при сбое и дополняет отчет
+// контекстом выполнявшейся работы. Возвращает true, если fn завершилась паникой
+func (s *Server) runIsolated(component string, job *backgroundJob, details func() map[string]interface{}, restart bool, fn func()) (crashed bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			var extra map[string]interface{}
+			if details != nil {
+				extra = details()
+			}
+			s.reportCrash(component, job, recovered, debug.Stack(), extra, restart)
+			crashed = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// superviseWorker выполняет долгоживущий воркер и перезапускает его после паники, если это разрешено
+// конфигурацией. Перезапуски прекращаются при остановке сервера
+func (s *Server) superviseWorker(component string, run func()) {
+	delay := workerRestartDelay
+	for {
+		restart := s.currentConfig() == nil || s.currentConfig().RestartCrashedWorkers
+		if !s.runIsolated(component, nil, nil, restart, run) || !restart {
+			return
+		}
+		select {
+		case <-s.shutdownChan:
+			return
+		case <-time.After(delay):
+		}
+		log.Printf("Перезапуск воркера %s после сбоя", component)
+		if delay *= 2; delay > workerRestartMaxDelay {
+			delay = workerRestartMaxDelay
+		}
+	}
+}
+
+// reportCrash записывает отчет о сбое в журнал и service.db и отмечает задачу как упавшую
+func (s *Server) reportCrash(component string, job *backgroundJob, recovered interface{}, stack []byte, details map[string]interface{}, restarted bool) {
+	s.workerCrashes.Add(1)
+	report := &database.CrashReport{
+		Component: component,
+		Panic:     fmt.Sprint(recovered),
+		Stack:     string(stack),
+		Details:   details,
+		Restarted: restarted,
+		CreatedAt: time.Now(),
+	}
+	if job != nil {
+		job.markPanicked(fmt.Errorf("panic in %s: %v", component, recovered))
+		report.JobKind = job.Kind
+		report.JobName = job.Name
+		if job.ID != 0 {
+			jobID := job.ID
+			report.JobID = &jobID
+		}
+	}
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "ERROR",
+		Message:   fmt.Sprintf("Паника в воркере %s: %v\n%s", component, recovered, stack),
+	})
+	if s.serviceDB == nil {
+		return
+	}
+	if err := s.serviceDB.RecordCrashReport(report); err != nil {
+		log.Printf("Не удалось сохранить отчет о сбое воркера %s: %v", component, err)
+	}
+}
+
+// handleMonitoringCrashes возвращает отчеты о сбоях фоновых воркеров
+// GET /api/monitoring/crashes?component=kpved_worker&limit=50
+func (s *Server) handleMonitoringCrashes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit, err := boundedIntParam(r.URL.Query().Get("limit"), crashesDefaultLimit, crashesMaxLimit)
+	if err != nil {
+		s.writeJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+	reports, err := s.serviceDB.GetCrashReports(r.URL.Query().Get("component"), limit)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"crashes":             reports,
+		"total":               len(reports),
+		"crashes_since_start": s.workerCrashes.Load(),
+	}, http.StatusOK)
+}
+
+// kpvedWorkerCrashDetails контекст воркера КПВЭД классификации для отчета о сбое
+func (s *Server) kpvedWorkerCrashDetails(workerID int) map[string]interface{} {
+	details := map[string]interface{}{"worker_id": workerID}
+	s.kpvedCurrentTasksMutex.RLock()
+	task := s.kpvedCurrentTasks[workerID]
+	s.kpvedCurrentTasksMutex.RUnlock()
+	if task != nil {
+		details["task_index"] = task.index
+		details["normalized_name"] = task.normalizedName
+		details["category"] = task.category
+	}
+	return details
+}
+
+// takeKpvedCurrentTask снимает с учета и возвращает задачу, выполнявшуюся воркером
+func (s *Server) takeKpvedCurrentTask(workerID int) *classificationTask {
+	s.kpvedCurrentTasksMutex.Lock()
+	defer s.kpvedCurrentTasksMutex.Unlock()
+	task := s.kpvedCurrentTasks[workerID]
+	delete(s.kpvedCurrentTasks, workerID)
+	return task
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"httpserver/database"
+)
+
+func newCrashTestServer(t *testing.T) *Server {
+	t.Helper()
+	serviceDB, err := database.NewServiceDB(filepath.Join(t.TempDir(), "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	t.Cleanup(func() { serviceDB.Close() })
+	return &Server{
+		serviceDB:    serviceDB,
+		config:       &Config{RestartCrashedWorkers: true},
+		logChan:      make(chan LogEntry, 10),
+		shutdownChan: make(chan struct{}),
+	}
+}
+
+func TestRecoverWorkerMarksJobFailed(t *testing.T) {
+	s := newCrashTestServer(t)
+
+	job, err := s.startBackgroundJob(context.Background(), jobKindNormalization, "catalog_items")
+	if err != nil {
+		t.Fatalf("startBackgroundJob() error = %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer s.finishBackgroundJob(job, nil)
+		defer s.recoverWorker(jobKindNormalization, job)
+		var items map[string]int
+		items["boom"]++
+	}()
+	<-done
+
+	history, err := s.serviceDB.GetBackgroundJobs("", 10)
+	if err != nil {
+		t.Fatalf("GetBackgroundJobs() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Status != database.BackgroundJobFailed {
+		t.Fatalf("jobs = %+v, want one failed job", history)
+	}
+
+	crashes, err := s.serviceDB.GetCrashReports(jobKindNormalization, 10)
+	if err != nil {
+		t.Fatalf("GetCrashReports() error = %v", err)
+	}
+	if len(crashes) != 1 || crashes[0].JobID == nil || *crashes[0].JobID != job.ID || crashes[0].Stack == "" {
+		t.Errorf("crashes = %+v, want report with job id %d and stack", crashes, job.ID)
+	}
+}
+
+func TestSuperviseWorker(t *testing.T) {
+	tests := []struct {
+		name      string
+		restart   bool
+		wantRuns  int32
+		wantCrash int64
+	}{
+		{"restart after panic", true, 2, 1},
+		{"no restart", false, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCrashTestServer(t)
+			s.config.RestartCrashedWorkers = tt.restart
+
+			var runs atomic.Int32
+			s.superviseWorker("test_loop", func() {
+				if runs.Add(1) == 1 {
+					panic("first run fails")
+				}
+			})
+
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("runs = %d, want %d", got, tt.wantRuns)
+			}
+			if got := s.workerCrashes.Load(); got != tt.wantCrash {
+				t.Errorf("crashes = %d, want %d", got, tt.wantCrash)
+			}
+		})
+	}
+}
+
+func TestHandleMonitoringCrashes(t *testing.T) {
+	s := newCrashTestServer(t)
+
+	crashed := s.runIsolated("kpved_worker", nil, func() map[string]interface{} {
+		return map[string]interface{}{"worker_id": 1}
+	}, false, func() { panic("classifier failure") })
+	if !crashed {
+		t.Fatal("runIsolated() must report panic")
+	}
+	if s.runIsolated("kpved_worker", nil, nil, false, func() {}) {
+		t.Error("runIsolated() reported panic for normal return")
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleMonitoringCrashes(rec, httptest.NewRequest(http.MethodGet, "/api/monitoring/crashes?component=kpved_worker", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	crashes, err := s.serviceDB.GetCrashReports("kpved_worker", 10)
+	if err != nil {
+		t.Fatalf("GetCrashReports() error = %v", err)
+	}
+	if len(crashes) != 1 || crashes[0].Panic != "classifier failure" || crashes[0].Details["worker_id"] != 1.0 {
+		t.Errorf("crashes = %+v, want one report with worker details", crashes)
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"httpserver/database"
+)
+
+// databaseStatsCache сведения о файлах БД для списка баз. Сведения пересчитываются только для файлов,
+// у которых изменились размер или время изменения (основного файла или WAL), остальные берутся из кеша.
+// Заблокированные и недоступные файлы не кешируются и проверяются при каждом запросе
+type databaseStatsCache struct {
+	mu      sync.Mutex
+	entries map[string]*databaseStatsEntry
+}
+
+// databaseStatsEntry закешированные сведения о файле БД
+type databaseStatsEntry struct {
+	fingerprint string
+	dbType      string
+	tableCount  int
+	totalRows   int64
+	stats       *database.DatabaseFileStats
+	err         string
+	computedAt  time.Time
+}
+
+// databaseFileFingerprint отпечаток состояния файла БД: размер и время изменения основного файла и WAL
+func databaseFileFingerprint(path string, info os.FileInfo) string {
+	fingerprint := fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+	if wal, err := os.Stat(path + "-wal"); err == nil {
+		fingerprint += fmt.Sprintf("|%d:%d", wal.Size(), wal.ModTime().UnixNano())
+	}
+	return fingerprint
+}
+
+// get возвращает сведения о файле, пересчитывая их при изменении файла; cached - сведения взяты из кеша
+func (c *databaseStatsCache) get(path string, info os.FileInfo) (entry *databaseStatsEntry, cached bool) {
+	fingerprint := databaseFileFingerprint(path, info)
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && entry.fingerprint == fingerprint {
+		return entry, true
+	}
+
+	entry = &databaseStatsEntry{fingerprint: fingerprint, computedAt: time.Now()}
+	dbType, err := database.DetectDatabaseType(path)
+	if err != nil {
+		log.Printf("Ошибка определения типа БД %s: %v", path, err)
+		dbType = "unknown"
+	}
+	entry.dbType = dbType
+	if tableStats, err := database.GetTableStats(path); err == nil {
+		entry.tableCount = len(tableStats)
+		for _, stat := range tableStats {
+			entry.totalRows += stat.RowCount
+		}
+	}
+	entry.stats, err = database.InspectDatabaseFile(path)
+	if err != nil {
+		entry.err = err.Error()
+	}
+	if entry.err != "" || (entry.stats != nil && entry.stats.Locked) {
+		return entry, false
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*databaseStatsEntry)
+	}
+	c.entries[path] = entry
+	c.mu.Unlock()
+	return entry, false
+}
+
+// retain удаляет из кеша файлы, которых нет в списке баз
+func (c *databaseStatsCache) retain(paths map[string]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range c.entries {
+		if !paths[path] {
+			delete(c.entries, path)
+		}
+	}
+}
+
+// databaseHealth признаки состояния файла БД для списка баз
+func databaseHealth(entry *databaseStatsEntry, missing bool) map[string]interface{} {
+	health := map[string]interface{}{
+		"missing": missing,
+		"locked":  false,
+	}
+	if entry == nil {
+		return health
+	}
+	if entry.stats != nil {
+		health["locked"] = entry.stats.Locked
+		health["schema_version"] = entry.stats.SchemaVersion
+		health["user_version"] = entry.stats.UserVersion
+	}
+	if entry.err != "" {
+		health["error"] = entry.err
+	}
+	return health
+}
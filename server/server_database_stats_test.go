@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestHandleDatabasesListStats(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.Mkdir("data", 0o755); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	db, err := database.NewDB(filepath.Join("data", "uploads.db"))
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	if _, err := db.CreateUpload("uuid-1", "8.3", "Бухгалтерия"); err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	db.Close()
+
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := serviceDB.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	if _, err := serviceDB.CreateProjectDatabase(project.ID, "Удаленная", filepath.Join(dir, "removed.db"), "", 0); err != nil {
+		t.Fatalf("CreateProjectDatabase() error = %v", err)
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), serviceDB: serviceDB}
+	type entry struct {
+		Path   string                      `json:"path"`
+		Health map[string]interface{}      `json:"health"`
+		Stats  *database.DatabaseFileStats `json:"stats"`
+	}
+	list := func() (entries map[string]entry, cached int) {
+		rec := httptest.NewRecorder()
+		s.handleDatabasesList(rec, httptest.NewRequest(http.MethodGet, "/api/databases/list", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Databases   []entry `json:"databases"`
+			StatsCached int     `json:"stats_cached"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		entries = make(map[string]entry)
+		for _, e := range resp.Databases {
+			entries[filepath.Base(e.Path)] = e
+		}
+		return entries, resp.StatsCached
+	}
+
+	entries, cached := list()
+	if cached != 0 {
+		t.Errorf("first listing stats_cached = %d, want 0", cached)
+	}
+	uploads, ok := entries["uploads.db"]
+	if !ok || uploads.Stats == nil || uploads.Stats.Uploads == nil || *uploads.Stats.Uploads != 1 || uploads.Stats.LastUploadAt == nil {
+		t.Fatalf("uploads.db = %+v, want upload stats", uploads)
+	}
+	if uploads.Health["missing"] != false || uploads.Health["locked"] != false {
+		t.Errorf("uploads.db health = %v", uploads.Health)
+	}
+	if removed, ok := entries["removed.db"]; !ok || removed.Health["missing"] != true {
+		t.Errorf("removed.db = %+v, want missing project database", removed)
+	}
+
+	if _, cached := list(); cached != 1 {
+		t.Errorf("second listing stats_cached = %d, want unchanged file from cache", cached)
+	}
+}
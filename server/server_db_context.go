@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// dbContext возвращает контекст запросов к БД для HTTP запроса: отменяется при отключении клиента
+// и ограничен DBQueryTimeout из конфигурации
+func (s *Server) dbContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if s.currentConfig() == nil || s.currentConfig().DBQueryTimeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), s.currentConfig().DBQueryTimeout)
+}
+
+// writeDBError отвечает на ошибку запроса к БД. Превышение времени запроса возвращается как 504,
+// при отключении клиента ответ не пишется
+func (s *Server) writeDBError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		s.writeJSONError(w, fmt.Sprintf("%s: database query timed out", message), http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled) || r.Context().Err() != nil:
+		s.logCtx(r.Context(), LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Запрос к БД отменен, клиент отключился: %s", message),
+			Endpoint:  r.URL.Path,
+		})
+	default:
+		s.writeAPIError(w, fmt.Sprintf("%s: %v", message, err), err)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDBContext(t *testing.T) {
+	s := &Server{config: &Config{DBQueryTimeout: time.Minute}, logChan: make(chan LogEntry, 10)}
+	req := httptest.NewRequest(http.MethodGet, "/api/uploads/x/data", nil)
+	ctx, cancel := s.dbContext(req)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("dbContext() deadline = %v, %v", deadline, ok)
+	}
+
+	s.config.DBQueryTimeout = 0
+	ctx, cancel = s.dbContext(req)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("dbContext() must not set deadline when timeout is disabled")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("dbContext() cancel must cancel context")
+	}
+}
+
+func TestWriteDBError(t *testing.T) {
+	s := &Server{logChan: make(chan LogEntry, 10)}
+	disconnected, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		status int
+	}{
+		{"timeout", context.Background(), fmt.Errorf("failed to get catalog items: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"client gone", disconnected, fmt.Errorf("failed to get catalog items: %w", context.Canceled), 0},
+		{"other", context.Background(), errors.New("no such table"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/uploads/x/data", nil).WithContext(tt.ctx)
+			s.writeDBError(rec, req, "Failed to get catalog items", tt.err)
+			if tt.status == 0 {
+				if rec.Body.Len() != 0 {
+					t.Errorf("response written for disconnected client: %s", rec.Body.String())
+				}
+				return
+			}
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
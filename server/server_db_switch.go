@@ -0,0 +1,390 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"httpserver/database"
+	"httpserver/quality"
+)
+
+// Ограничения ожидания завершения записей при переключении БД
+const (
+	dbSwitchDefaultDrainTimeout = 30 * time.Second
+	dbSwitchMaxDrainTimeout     = 5 * time.Minute
+	// Предельное ожидание читающих запросов, начатых до переключения, перед закрытием предыдущей БД.
+	// Долгие операции удерживают БД захватом (uploadDBCache.retain), а не читающим запросом
+	dbSwitchRetireTimeout = 5 * time.Minute
+)
+
+// dbGateStreamRoutes потоки событий (SSE): не обращаются к БД, но остаются открытыми, пока подключен клиент,
+// поэтому не учитываются как читающие запросы и не задерживают закрытие предыдущей БД
+var dbGateStreamRoutes = map[string]bool{
+	"/api/normalize/events":        true,
+	"/api/reclassification/events": true,
+	"/api/monitoring/events":       true,
+}
+
+// errDBSwitchInProgress другое переключение БД еще не завершено
+var errDBSwitchInProgress = errors.New("database switch already in progress")
+
+// dbWriteGate учитывает выполняющиеся изменяющие запросы и приостанавливает новые на время переключения БД.
+// Читающие запросы не приостанавливаются, но учитываются по поколениям БД, чтобы предыдущая БД закрывалась
+// только после их завершения. Нулевое значение готово к использованию
+type dbWriteGate struct {
+	mu       sync.Mutex
+	inFlight int
+	draining bool
+	idle     chan struct{} // Закрывается, когда во время ожидания завершается последняя запись
+	resumed  chan struct{} // Закрывается при возобновлении приема записей
+
+	generation uint64
+	readers    map[uint64]int           // Выполняющиеся читающие запросы по поколению БД
+	retired    map[uint64]chan struct{} // Закрываются после последнего читающего запроса выведенного поколения
+}
+
+// enterRead регистрирует читающий запрос текущего поколения БД; leave отмечает его завершение
+func (g *dbWriteGate) enterRead() (leave func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.readers == nil {
+		g.readers = make(map[uint64]int)
+		g.retired = make(map[uint64]chan struct{})
+	}
+	generation := g.generation
+	g.readers[generation]++
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.readers[generation]--; g.readers[generation] > 0 {
+			return
+		}
+		delete(g.readers, generation)
+		if done, ok := g.retired[generation]; ok {
+			delete(g.retired, generation)
+			close(done)
+		}
+	}
+}
+
+// retire начинает новое поколение БД и возвращает канал, закрываемый после завершения читающих запросов,
+// начатых до переключения
+func (g *dbWriteGate) retire() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	previous := g.generation
+	g.generation++
+	done := make(chan struct{})
+	if g.readers[previous] == 0 {
+		close(done)
+		return done
+	}
+	g.retired[previous] = done
+	return done
+}
+
+// enter регистрирует запись; во время переключения ожидает его завершения или отмены запроса
+func (g *dbWriteGate) enter(ctx context.Context) error {
+	g.mu.Lock()
+	for g.draining {
+		resumed := g.resumed
+		g.mu.Unlock()
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		g.mu.Lock()
+	}
+	g.inFlight++
+	g.mu.Unlock()
+	return nil
+}
+
+// leave отмечает завершение записи
+func (g *dbWriteGate) leave() {
+	g.mu.Lock()
+	g.inFlight--
+	if g.draining && g.inFlight == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+	g.mu.Unlock()
+}
+
+// drain приостанавливает новые записи и ждет завершения выполняющихся.
+// После успешного вызова или таймаута прием записей возобновляется через resume
+func (g *dbWriteGate) drain(timeout time.Duration) error {
+	g.mu.Lock()
+	if g.draining {
+		g.mu.Unlock()
+		return errDBSwitchInProgress
+	}
+	g.draining = true
+	g.resumed = make(chan struct{})
+	if g.inFlight == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	g.idle = make(chan struct{})
+	idle, pending := g.idle, g.inFlight
+	g.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%d in-flight writes did not complete within %v", pending, timeout)
+	}
+}
+
+// resume возобновляет прием записей после переключения
+func (g *dbWriteGate) resume() {
+	g.mu.Lock()
+	if g.draining {
+		g.draining = false
+		g.idle = nil
+		close(g.resumed)
+	}
+	g.mu.Unlock()
+}
+
+// dbWriteGateMiddleware учитывает изменяющие запросы, чтобы переключение БД дожидалось их завершения,
+// и читающие, чтобы предыдущая БД не закрывалась под ними
+func (s *Server) dbWriteGateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if !dbGateStreamRoutes[r.URL.Path] {
+				defer s.dbWriteGate.enterRead()()
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.URL.Path == "/api/database/switch" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := s.dbWriteGate.enter(r.Context()); err != nil {
+			s.writeJSONError(w, "Request cancelled while database switch was in progress", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.dbWriteGate.leave()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleDatabaseSwitch переключает текущую базу данных: проверяет файл, дожидается завершения записей,
+// подменяет соединение под dbMutex, сбрасывает кэши и записывает переключение в журнал аудита
+// POST /api/database/switch {"path": "data.db", "actor": "admin", "drain_timeout_seconds": 30}
+func (s *Server) handleDatabaseSwitch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Path                string `json:"path"`
+		Actor               string `json:"actor"`
+		DrainTimeoutSeconds int    `json:"drain_timeout_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Path == "" {
+		s.writeJSONError(w, "Database path is required", http.StatusBadRequest)
+		return
+	}
+	request.Actor = requestActor(r, request.Actor, r.RemoteAddr)
+	drainTimeout := dbSwitchDefaultDrainTimeout
+	if request.DrainTimeoutSeconds > 0 {
+		drainTimeout = time.Duration(request.DrainTimeoutSeconds) * time.Second
+		if drainTimeout > dbSwitchMaxDrainTimeout {
+			drainTimeout = dbSwitchMaxDrainTimeout
+		}
+	}
+
+	// Фоновые задачи (экспорт, переклассификация, массовые операции качества, сверка номенклатуры и др.)
+	// держат ссылки на текущую БД, переключение во время их работы запрещено
+	s.normalizerMutex.RLock()
+	normalizing := s.normalizerRunning
+	s.normalizerMutex.RUnlock()
+	s.qualityAnalysisMutex.RLock()
+	analyzing := s.qualityAnalysisRunning
+	s.qualityAnalysisMutex.RUnlock()
+	if normalizing || analyzing {
+		s.writeJSONError(w, "Cannot switch database while normalization or quality analysis is running", http.StatusConflict)
+		return
+	}
+	if running := s.jobs.list(); len(running) > 0 {
+		kinds := make([]string, 0, len(running))
+		for _, job := range running {
+			kinds = append(kinds, job.Kind)
+		}
+		s.writeJSONError(w, fmt.Sprintf("Cannot switch database while background jobs are running: %s", strings.Join(kinds, ", ")), http.StatusConflict)
+		return
+	}
+
+	s.dbMutex.RLock()
+	previousPath := s.currentDBPath
+	s.dbMutex.RUnlock()
+	if samePath(previousPath, request.Path) {
+		s.writeJSONError(w, "Database is already active", http.StatusBadRequest)
+		return
+	}
+
+	if err := database.ValidateDatabaseFile(request.Path); err != nil {
+		status := http.StatusUnprocessableEntity
+		if err.Error() == "database file not found" {
+			status = http.StatusNotFound
+		}
+		s.recordDatabaseSwitch(request.Actor, previousPath, request.Path, "rejected", map[string]interface{}{"error": err.Error()})
+		s.writeJSONError(w, fmt.Sprintf("Invalid database file: %v", err), status)
+		return
+	}
+
+	drainStarted := time.Now()
+	if err := s.dbWriteGate.drain(drainTimeout); err != nil {
+		if err != errDBSwitchInProgress {
+			s.dbWriteGate.resume()
+		}
+		s.recordDatabaseSwitch(request.Actor, previousPath, request.Path, "rejected", map[string]interface{}{"error": err.Error()})
+		s.writeJSONError(w, fmt.Sprintf("Cannot switch database: %v", err), http.StatusConflict)
+		return
+	}
+	defer s.dbWriteGate.resume()
+	drainDuration := time.Since(drainStarted)
+
+	// Новая БД открывается до закрытия текущей: при ошибке сервер продолжает работать со старой
+	newDB, err := database.NewDBWithConfig(request.Path, s.databaseConfig())
+	if err != nil {
+		log.Printf("Ошибка открытия новой БД: %v", err)
+		s.recordDatabaseSwitch(request.Actor, previousPath, request.Path, "failed", map[string]interface{}{"error": err.Error()})
+		s.writeJSONError(w, fmt.Sprintf("Failed to open new database: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.dbMutex.Lock()
+	oldDB := s.db
+	s.db = newDB
+	s.currentDBPath = request.Path
+	if s.normalizer != nil {
+		s.normalizer.SetDB(newDB)
+	}
+	if s.calibrator != nil {
+		s.calibrator.SetDB(newDB)
+	}
+	s.qualityAnalyzer = quality.NewQualityAnalyzer(newDB)
+	readersDone := s.dbWriteGate.retire()
+	s.dbMutex.Unlock()
+
+	// Предыдущая БД закрывается после завершения запросов, уже получивших на нее ссылку
+	invalidated := s.retireDatabase(oldDB, readersDone)
+
+	details := map[string]interface{}{
+		"drain_ms":                  drainDuration.Milliseconds(),
+		"invalidated_cache_entries": invalidated,
+	}
+	s.recordDatabaseSwitch(request.Actor, previousPath, request.Path, "success", details)
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("База данных переключена: %s -> %s (%s)", previousPath, request.Path, request.Actor),
+		Endpoint:  r.URL.Path,
+	})
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"status":                    "success",
+		"message":                   "Database switched successfully",
+		"path":                      request.Path,
+		"previous_path":             previousPath,
+		"drain_ms":                  drainDuration.Milliseconds(),
+		"invalidated_cache_entries": invalidated,
+	}, http.StatusOK)
+}
+
+// databaseConfig возвращает настройки пулов соединений основной БД из конфигурации сервера
+func (s *Server) databaseConfig() database.DBConfig {
+	if s.currentConfig() == nil {
+		return database.DBConfig{}
+	}
+	cfg := s.currentConfig()
+	return database.DBConfig{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+
+		ReadPoolMaxOpenConns: cfg.ReadPoolMaxOpenConns,
+	}
+}
+
+// retireDatabase удаляет из кэша выгрузок ссылки на выведенную из работы БД и закрывает ее, когда завершатся
+// читающие запросы, начатые до переключения (readersDone), захватившие ее операции (потоковая отдача, пересчет)
+// и истечет задержка для обращений вне HTTP запросов. Читающие запросы ожидаются не дольше dbSwitchRetireTimeout
+func (s *Server) retireDatabase(db *database.DB, readersDone <-chan struct{}) int {
+	if db == nil {
+		return 0
+	}
+	if readersDone != nil {
+		readersDone = readersWithTimeout(readersDone, dbSwitchRetireTimeout)
+	}
+	return s.uploadDBs.retire(db, readersDone)
+}
+
+// readersWithTimeout возвращает канал, закрываемый после завершения читающих запросов, но не позже timeout
+func readersWithTimeout(done <-chan struct{}, timeout time.Duration) <-chan struct{} {
+	limited := make(chan struct{})
+	go func() {
+		defer close(limited)
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			log.Printf("Предыдущая БД закрывается, не дождавшись читающих запросов за %v", timeout)
+		}
+	}()
+	return limited
+}
+
+// recordDatabaseSwitch записывает попытку переключения БД в журнал аудита
+func (s *Server) recordDatabaseSwitch(actor, from, to, status string, details map[string]interface{}) {
+	if s.serviceDB == nil {
+		return
+	}
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	details["from"] = from
+	err := s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action:  database.AuditActionDatabaseSwitch,
+		Actor:   actor,
+		Target:  to,
+		Status:  status,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Ошибка записи переключения БД в журнал аудита: %v", err)
+	}
+}
+
+// samePath проверяет, указывают ли пути на один файл
+func samePath(a, b string) bool {
+	if a == b {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
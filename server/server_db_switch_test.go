@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestDBWriteGate(t *testing.T) {
+	var gate dbWriteGate
+	if err := gate.enter(context.Background()); err != nil {
+		t.Fatalf("enter() error = %v", err)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- gate.drain(time.Second) }()
+
+	// Новая запись ждет окончания переключения
+	entered := make(chan error, 1)
+	time.Sleep(20 * time.Millisecond)
+	go func() { entered <- gate.enter(context.Background()) }()
+	select {
+	case <-drained:
+		t.Fatal("drain() returned before in-flight write completed")
+	case <-entered:
+		t.Fatal("enter() must wait while gate is draining")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	gate.leave()
+	if err := <-drained; err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+	if err := gate.drain(time.Second); err != errDBSwitchInProgress {
+		t.Errorf("second drain() error = %v, want errDBSwitchInProgress", err)
+	}
+	gate.resume()
+	if err := <-entered; err != nil {
+		t.Fatalf("enter() after resume error = %v", err)
+	}
+
+	if err := gate.drain(10 * time.Millisecond); err == nil {
+		t.Error("drain() must time out while write is in flight")
+	}
+	gate.resume()
+	gate.leave()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := gate.drain(time.Second); err != nil {
+		t.Fatalf("drain() without writes error = %v", err)
+	}
+	cancel()
+	if err := gate.enter(ctx); err == nil {
+		t.Error("enter() must fail when request is cancelled during switch")
+	}
+	gate.resume()
+
+	// Ожидание читающих запросов ограничено по времени
+	select {
+	case <-readersWithTimeout(make(chan struct{}), 10*time.Millisecond):
+	case <-time.After(time.Second):
+		t.Error("readersWithTimeout() must close after timeout")
+	}
+}
+
+func TestDBWriteGateMiddlewareStreams(t *testing.T) {
+	s := &Server{}
+	tests := []struct {
+		path        string
+		wantReaders int
+	}{
+		{"/api/uploads", 1},
+		{"/api/monitoring/events", 0},
+		{"/api/normalize/events", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var readers int
+			handler := s.dbWriteGateMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s.dbWriteGate.mu.Lock()
+				readers = s.dbWriteGate.readers[s.dbWriteGate.generation]
+				s.dbWriteGate.mu.Unlock()
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if readers != tt.wantReaders {
+				t.Errorf("readers = %d, want %d", readers, tt.wantReaders)
+			}
+		})
+	}
+}
+
+func TestHandleDatabaseSwitch(t *testing.T) {
+	dir := t.TempDir()
+	serviceDB, err := database.NewServiceDB(filepath.Join(dir, "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+
+	oldPath := filepath.Join(dir, "old.db")
+	oldDB, err := database.NewDB(oldPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	newPath := filepath.Join(dir, "new.db")
+	newDB, err := database.NewDB(newPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	newDB.Close()
+	garbagePath := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbagePath, []byte(strings.Repeat("not a database", 100)), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	s := &Server{
+		db:            oldDB,
+		serviceDB:     serviceDB,
+		currentDBPath: oldPath,
+		config:        &Config{},
+		logChan:       make(chan LogEntry, 10),
+	}
+	s.uploadDBs.putShared("cached", oldDB)
+
+	rejected := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"missing path", `{}`, http.StatusBadRequest},
+		{"already active", `{"path": "` + oldPath + `"}`, http.StatusBadRequest},
+		{"missing file", `{"path": "` + filepath.Join(dir, "missing.db") + `"}`, http.StatusNotFound},
+		{"not a database", `{"path": "` + garbagePath + `"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleDatabaseSwitch(rec, httptest.NewRequest(http.MethodPost, "/api/database/switch", strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	// Фоновая задача держит ссылку на текущую БД
+	exportJob := &backgroundJob{Kind: jobKindExport}
+	s.jobs.add(exportJob)
+	rec := httptest.NewRecorder()
+	s.handleDatabaseSwitch(rec, httptest.NewRequest(http.MethodPost, "/api/database/switch",
+		strings.NewReader(`{"path": "`+newPath+`"}`)))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), jobKindExport) {
+		t.Errorf("switch with running job status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	s.jobs.remove(exportJob)
+
+	// Читающий запрос, начатый до переключения, продолжает работать с предыдущей БД
+	leaveRead := s.dbWriteGate.enterRead()
+	rec = httptest.NewRecorder()
+	s.handleDatabaseSwitch(rec, httptest.NewRequest(http.MethodPost, "/api/database/switch",
+		strings.NewReader(`{"path": "`+newPath+`", "actor": "admin"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("switch status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	s.uploadDBs.mu.Lock()
+	retired := s.uploadDBs.closing[oldDB]
+	s.uploadDBs.mu.Unlock()
+	if retired == nil {
+		t.Fatal("previous database must be closed after in-flight reads")
+	}
+	s.uploadDBs.endGrace(retired)
+	if _, err := oldDB.GetStats(); err != nil {
+		t.Errorf("previous database closed under in-flight read: %v", err)
+	}
+	leaveRead()
+	deadline := time.Now().Add(time.Second)
+	for _, err := oldDB.GetStats(); err == nil && time.Now().Before(deadline); _, err = oldDB.GetStats() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := oldDB.GetStats(); err == nil {
+		t.Error("previous database must be closed after the last read")
+	}
+	if s.currentDBPath != newPath || s.db == oldDB || s.uploadDBs.Stats().Entries != 0 {
+		t.Errorf("switch did not replace handles: path %s, cache %+v", s.currentDBPath, s.uploadDBs.Stats())
+	}
+	if _, err := s.db.GetStats(); err != nil {
+		t.Errorf("new database is not usable: %v", err)
+	}
+	defer s.db.Close()
+
+	events, err := serviceDB.GetAuditEvents(database.AuditActionDatabaseSwitch, 10)
+	if err != nil {
+		t.Fatalf("GetAuditEvents() error = %v", err)
+	}
+	if len(events) != 3 || events[0].Status != "success" || events[0].Actor != "admin" || events[0].Target != newPath ||
+		events[0].Details["from"] != oldPath || events[1].Status != "rejected" {
+		t.Errorf("audit events = %+v", events)
+	}
+}
@@ -0,0 +1,280 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"httpserver/classification"
+	"httpserver/database"
+	"httpserver/provisioning"
+)
+
+//go:embed demo
+var demoFS embed.FS
+
+const (
+	// demoClassifierName классификатор категорий демо-проекта (выборка КПВЭД)
+	demoClassifierName = "КПВЭД (демо)"
+	// demoUploadUUID идентификатор синтетической выгрузки демо-режима
+	demoUploadUUID = "d3e0d3e0-0000-4000-8000-000000000001"
+	// demoCatalogName справочник синтетической выгрузки
+	demoCatalogName = "Номенклатура"
+	// demoActor автор изменений, внесенных демо-режимом
+	demoActor = "demo"
+)
+
+// demoSeedResult что было загружено демо-режимом при запуске
+type demoSeedResult struct {
+	KpvedLoaded      bool               // Выборка КПВЭД загружена в пустой kpved_classifier
+	ClassifierID     int                // Классификатор категорий демо-проекта
+	Provisioning     *provisioning.Plan // План применения демо-клиента, проекта и API ключа
+	DatabaseID       int                // База данных демо-проекта в service.db
+	UploadUUID       string             // Синтетическая выгрузка в единой БД
+	UploadCreated    bool               // Выгрузка создана при этом запуске
+	UploadItemsCount int                // Элементов справочника в созданной выгрузке
+}
+
+// seedDemoData загружает образцы данных демо-режима. Повторный запуск ничего не дублирует:
+// существующие классификатор, клиент, проект и выгрузка остаются как есть
+func seedDemoData(db *database.DB, serviceDB *database.ServiceDB, unifiedDB *database.DB, unifiedDBPath string) (*demoSeedResult, error) {
+	if db == nil || serviceDB == nil || unifiedDB == nil {
+		return nil, fmt.Errorf("demo mode requires main, service and unified catalogs databases")
+	}
+	result := &demoSeedResult{UploadUUID: demoUploadUUID}
+
+	kpvedData, err := demoFS.ReadFile("demo/kpved.txt")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := database.ParseKpved(bytes.NewReader(kpvedData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse demo KPVED: %w", err)
+	}
+	if result.KpvedLoaded, err = seedDemoKpved(serviceDB, entries); err != nil {
+		return result, err
+	}
+	if result.ClassifierID, err = seedDemoClassifier(db, entries); err != nil {
+		return result, err
+	}
+
+	specData, err := demoFS.ReadFile("demo/provisioning.yaml")
+	if err != nil {
+		return result, err
+	}
+	spec, err := provisioning.ParseSpec(specData)
+	if err != nil {
+		return result, fmt.Errorf("invalid demo provisioning spec: %w", err)
+	}
+	// Выгрузка демо-проекта хранится в единой БД справочников
+	spec.Clients[0].Projects[0].Databases[0].Path = unifiedDBPath
+	clientSpec, projectSpec := spec.Clients[0], spec.Clients[0].Projects[0]
+	if result.Provisioning, err = provisioning.NewProvisioner(serviceDB, db, demoActor).Apply(spec); err != nil {
+		return result, fmt.Errorf("failed to provision demo client: %w", err)
+	}
+
+	client, err := serviceDB.GetClientByName(clientSpec.Name)
+	if err != nil || client == nil {
+		return result, fmt.Errorf("demo client %q not found after provisioning: %v", clientSpec.Name, err)
+	}
+	projects, err := serviceDB.GetClientProjects(client.ID)
+	if err != nil {
+		return result, err
+	}
+	var projectID int
+	for _, project := range projects {
+		if project.Name == projectSpec.Name {
+			projectID = project.ID
+		}
+	}
+	databases, err := serviceDB.GetProjectDatabases(projectID, false)
+	if err != nil {
+		return result, err
+	}
+	for _, projectDB := range databases {
+		if projectDB.Name == projectSpec.Databases[0].Name {
+			result.DatabaseID = projectDB.ID
+		}
+	}
+	if result.DatabaseID == 0 {
+		return result, fmt.Errorf("demo project database %q not found after provisioning", projectSpec.Databases[0].Name)
+	}
+
+	result.UploadCreated, result.UploadItemsCount, err = seedDemoUpload(unifiedDB, result.DatabaseID, client.ID, projectID)
+	return result, err
+}
+
+// seedDemoKpved загружает выборку КПВЭД, только если иерархический классификатор еще не загружен
+func seedDemoKpved(serviceDB *database.ServiceDB, entries []database.KpvedEntry) (bool, error) {
+	var count int
+	if err := serviceDB.QueryRow("SELECT COUNT(*) FROM kpved_classifier").Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to count KPVED codes: %w", err)
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err := database.LoadKpvedToDatabase(serviceDB.GetDB(), entries); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// seedDemoClassifier создает классификатор категорий из выборки КПВЭД (если его еще нет) и возвращает его ID
+func seedDemoClassifier(db *database.DB, entries []database.KpvedEntry) (int, error) {
+	classifiers, err := db.GetCategoryClassifiersByFilter(nil, nil, false)
+	if err != nil {
+		return 0, err
+	}
+	for _, classifier := range classifiers {
+		if classifier.Name == demoClassifierName {
+			return classifier.ID, nil
+		}
+	}
+
+	tree := buildDemoClassifierTree(entries)
+	treeJSON, err := json.Marshal(tree)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal demo classifier tree: %w", err)
+	}
+	created, err := db.CreateCategoryClassifier(&database.CategoryClassifier{
+		Name:          demoClassifierName,
+		Description:   "Выборка КПВЭД для демо-режима",
+		MaxDepth:      4,
+		TreeStructure: string(treeJSON),
+		IsActive:      true,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := db.SaveClassifierNodes(created.ID, classification.FlattenTree(tree)); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// buildDemoClassifierTree строит дерево категорий из записей КПВЭД с корнем "root", как cmd/load_kpved
+func buildDemoClassifierTree(entries []database.KpvedEntry) *classification.CategoryNode {
+	childrenOf := make(map[string][]database.KpvedEntry)
+	for _, entry := range entries {
+		childrenOf[entry.ParentCode] = append(childrenOf[entry.ParentCode], entry)
+	}
+
+	var build func(entry database.KpvedEntry, parentPath string) classification.CategoryNode
+	build = func(entry database.KpvedEntry, parentPath string) classification.CategoryNode {
+		node := classification.CategoryNode{
+			ID:       entry.Code,
+			Name:     entry.Name,
+			Path:     parentPath + " / " + entry.Name,
+			Level:    entry.Level + 1,
+			ParentID: entry.ParentCode,
+		}
+		for _, child := range childrenOf[entry.Code] {
+			node.Children = append(node.Children, build(child, node.Path))
+		}
+		return node
+	}
+
+	root := &classification.CategoryNode{ID: "root", Name: "КПВЭД", Path: "КПВЭД"}
+	for _, entry := range childrenOf[""] {
+		root.Children = append(root.Children, build(entry, root.Path))
+	}
+	return root
+}
+
+// seedDemoUpload создает завершенную синтетическую выгрузку справочника номенклатуры, если ее еще нет.
+// Возвращает признак создания и число элементов
+func seedDemoUpload(unifiedDB *database.DB, databaseID, clientID, projectID int) (bool, int, error) {
+	if _, err := unifiedDB.GetUploadByUUID(demoUploadUUID); err == nil {
+		return false, 0, nil
+	}
+
+	items, err := demoCatalogItems()
+	if err != nil {
+		return false, 0, err
+	}
+
+	upload, err := unifiedDB.CreateUploadWithDatabase(demoUploadUUID, "8.3.24", "УправлениеТорговлей", &databaseID,
+		"DEMO", demoActor, "11.5", 1, "Демо", "", "Демонстрационная выгрузка", nil)
+	if err != nil {
+		return false, 0, err
+	}
+	if err := unifiedDB.SetUploadProtocolVersion(upload.ID, ProtocolVersionCurrent); err != nil {
+		return false, 0, err
+	}
+	if _, err := unifiedDB.Exec("UPDATE uploads SET client_id = ?, project_id = ? WHERE id = ?", clientID, projectID, upload.ID); err != nil {
+		return false, 0, fmt.Errorf("failed to link demo upload to project: %w", err)
+	}
+	if err := unifiedDB.AddConstant(upload.ID, "ОсновнаяВалюта", "Основная валюта", "Строка", "KZT"); err != nil {
+		return false, 0, err
+	}
+
+	tableName, err := database.GetOrCreateCatalogTable(unifiedDB.GetDB(), demoCatalogName)
+	if err != nil {
+		return false, 0, err
+	}
+	if _, err := unifiedDB.RegisterUploadCatalog(upload.ID, demoCatalogName); err != nil {
+		return false, 0, err
+	}
+	inserted, itemErrors, err := unifiedDB.AddCatalogItemsToTable(tableName, upload.ID, items)
+	if err != nil {
+		return false, 0, err
+	}
+	if len(itemErrors) > 0 && inserted < len(items) {
+		return false, inserted, fmt.Errorf("failed to add demo catalog items: %v", itemErrors[0])
+	}
+	if err := unifiedDB.CompleteUpload(upload.ID); err != nil {
+		return false, inserted, err
+	}
+	return true, inserted, nil
+}
+
+// demoCatalogItems читает синтетический справочник: код, наименование, артикул, единица измерения
+func demoCatalogItems() ([]database.CatalogTableItem, error) {
+	data, err := demoFS.ReadFile("demo/catalog.tsv")
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.CatalogTableItem
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid demo catalog line: %q", line)
+		}
+		items = append(items, database.CatalogTableItem{
+			Reference:     fmt.Sprintf("d3e0d3e0-0000-4000-8000-0000000%s", fields[0]),
+			Code:          fields[0],
+			Name:          fields[1],
+			AttributesXML: fmt.Sprintf("<Артикул>%s</Артикул><ЕдиницаИзмерения>%s</ЕдиницаИзмерения>", fields[2], fields[3]),
+		})
+	}
+	return items, scanner.Err()
+}
+
+// logDemoSeed сообщает в лог, что загрузил демо-режим. Токен API ключа выводится только при его создании
+func logDemoSeed(result *demoSeedResult) {
+	log.Printf("Демо-режим: данные синтетические, AI провайдер фейковый (ключи не нужны)")
+	if result.KpvedLoaded {
+		log.Printf("Демо-режим: загружена выборка КПВЭД")
+	}
+	log.Printf("Демо-режим: классификатор %q (ID %d), база данных проекта ID %d", demoClassifierName, result.ClassifierID, result.DatabaseID)
+	if result.UploadCreated {
+		log.Printf("Демо-режим: создана выгрузка %s (%d элементов справочника %s)", result.UploadUUID, result.UploadItemsCount, demoCatalogName)
+	} else {
+		log.Printf("Демо-режим: выгрузка %s уже загружена", result.UploadUUID)
+	}
+	if result.Provisioning != nil {
+		for name, token := range result.Provisioning.Tokens {
+			log.Printf("Демо-режим: API ключ %s для обмена с 1С: %s", name, token)
+		}
+	}
+}
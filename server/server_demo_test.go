@@ -0,0 +1,105 @@
+package server
+
+import (
+	"os"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/nomenclature"
+	"httpserver/normalization"
+)
+
+func TestLoadConfigDemoMode(t *testing.T) {
+	for _, key := range []string{"CONFIG_FILE", "DATABASE_PATH", "SERVICE_DATABASE_PATH", "ARLIAI_API_KEY"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("AI_PROVIDER", "arliai")
+	t.Setenv("HISTORICAL_CLASSIFICATION_ENABLED", "false")
+
+	tests := []struct {
+		name         string
+		demo         string
+		wantDBPath   string
+		wantProvider string
+		wantHistory  bool
+	}{
+		{"regular", "false", "data.db", "arliai", false},
+		{"demo", "true", "demo_data.db", nomenclature.FakeProviderName, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEMO_MODE", tt.demo)
+			config, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if config.DatabasePath != tt.wantDBPath || config.AIProvider != tt.wantProvider || config.HistoricalClassificationEnabled != tt.wantHistory {
+				t.Errorf("config = db %q, provider %q, history %v; want %q, %q, %v",
+					config.DatabasePath, config.AIProvider, config.HistoricalClassificationEnabled, tt.wantDBPath, tt.wantProvider, tt.wantHistory)
+			}
+			if tt.demo == "true" && (!nomenclature.FakeProviderEnabled() || os.Getenv("ARLIAI_API_KEY") == "") {
+				t.Errorf("demo mode must enable the fake AI provider with a substitute API key")
+			}
+		})
+	}
+}
+
+func TestSeedDemoData(t *testing.T) {
+	dbConfig := database.DBConfig{MaxOpenConns: 1}
+	db, err := database.NewDBWithConfig(":memory:", dbConfig)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", dbConfig)
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	unifiedDB, err := database.NewUnifiedDBWithConfig(":memory:", dbConfig)
+	if err != nil {
+		t.Fatalf("Failed to create unified database: %v", err)
+	}
+	defer unifiedDB.Close()
+
+	tests := []struct {
+		name        string
+		wantKpved   bool
+		wantUpload  bool
+		wantCreates bool
+	}{
+		{"first start", true, true, true},
+		{"restart", false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := seedDemoData(db, serviceDB, unifiedDB, "demo_unified_catalogs.db")
+			if err != nil {
+				t.Fatalf("seedDemoData failed: %v", err)
+			}
+			if result.KpvedLoaded != tt.wantKpved || result.UploadCreated != tt.wantUpload {
+				t.Errorf("kpved loaded = %v, upload created = %v; want %v, %v", result.KpvedLoaded, result.UploadCreated, tt.wantKpved, tt.wantUpload)
+			}
+			if result.Provisioning.HasChanges() != tt.wantCreates {
+				t.Errorf("provisioning changes = %v, want %v", result.Provisioning.HasChanges(), tt.wantCreates)
+			}
+			if tt.wantUpload && result.UploadItemsCount != 16 {
+				t.Errorf("upload items = %d, want 16", result.UploadItemsCount)
+			}
+			if nodes, err := db.CountClassifierNodes(result.ClassifierID); err != nil || nodes == 0 {
+				t.Errorf("classifier nodes = %d, err = %v", nodes, err)
+			}
+		})
+	}
+
+	upload, err := unifiedDB.GetUploadByUUID(demoUploadUUID)
+	if err != nil {
+		t.Fatalf("demo upload not found: %v", err)
+	}
+	if upload.Status != "completed" || upload.DatabaseID == nil || upload.ClientID == nil {
+		t.Errorf("demo upload = status %q, database %v, client %v; want completed and linked to demo project", upload.Status, upload.DatabaseID, upload.ClientID)
+	}
+	if _, err := normalization.NewHierarchicalClassifier(serviceDB, nil); err != nil {
+		t.Errorf("KPVED classifier is not usable after demo seed: %v", err)
+	}
+}
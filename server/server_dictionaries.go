@@ -0,0 +1,275 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+// handleProjectDictionaries управляет словарями нормализации проекта: сокращения, стоп-слова, защищенные токены
+// GET/POST /api/clients/{id}/projects/{projectId}/dictionaries[?kind=abbreviation]
+// GET/PUT/DELETE /api/clients/{id}/projects/{projectId}/dictionaries/{entryId}
+func (s *Server) handleProjectDictionaries(w http.ResponseWriter, r *http.Request, clientID, projectID, entryID int) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+	project, err := s.serviceDB.GetClientProject(projectID)
+	if err != nil {
+		s.writeJSONError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if project.ClientID != clientID {
+		s.writeJSONError(w, "Project does not belong to this client", http.StatusBadRequest)
+		return
+	}
+
+	if entryID == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			kind := r.URL.Query().Get("kind")
+			if kind != "" && !database.IsValidDictionaryKind(kind) {
+				s.writeJSONError(w, "Invalid dictionary kind", http.StatusBadRequest)
+				return
+			}
+			entries, err := s.serviceDB.GetNormalizationDictionaryEntries(projectID, kind)
+			if err != nil {
+				s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			s.writeJSONResponse(w, map[string]interface{}{
+				"project_id": projectID,
+				"entries":    entries,
+				"total":      len(entries),
+			}, http.StatusOK)
+		case http.MethodPost:
+			var entry database.NormalizationDictionaryEntry
+			if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+				s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			entry.ProjectID = projectID
+			if err := s.serviceDB.CreateNormalizationDictionaryEntry(&entry); err != nil {
+				s.writeDictionaryError(w, err)
+				return
+			}
+			s.log(LogEntry{
+				Timestamp: time.Now(),
+				Level:     "INFO",
+				Message:   fmt.Sprintf("Словарь проекта %d: добавлена запись %s %q", projectID, entry.Kind, entry.Term),
+				Endpoint:  r.URL.Path,
+			})
+			s.writeJSONResponse(w, entry, http.StatusCreated)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	entry, err := s.serviceDB.GetNormalizationDictionaryEntry(projectID, entryID)
+	if err != nil {
+		s.writeJSONError(w, "Dictionary entry not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSONResponse(w, entry, http.StatusOK)
+	case http.MethodPut:
+		var req database.NormalizationDictionaryEntry
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.ID = entry.ID
+		req.ProjectID = projectID
+		req.CreatedAt = entry.CreatedAt
+		if req.Kind == "" {
+			req.Kind = entry.Kind
+		}
+		if err := s.serviceDB.UpdateNormalizationDictionaryEntry(&req); err != nil {
+			s.writeDictionaryError(w, err)
+			return
+		}
+		s.writeJSONResponse(w, req, http.StatusOK)
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteNormalizationDictionaryEntry(projectID, entryID); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Словарь проекта %d: удалена запись %s %q", projectID, entry.Kind, entry.Term),
+			Endpoint:  r.URL.Path,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeDictionaryError возвращает ошибку сохранения записи словаря с подходящим статусом
+func (s *Server) writeDictionaryError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "UNIQUE constraint"):
+		s.writeJSONError(w, "Dictionary entry already exists", http.StatusConflict)
+	case strings.Contains(err.Error(), "not found"):
+		s.writeJSONError(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "failed"):
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+	default:
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// projectDictionaries возвращает словари проекта; без проекта или при ошибке - словари по умолчанию
+func (s *Server) projectDictionaries(projectID int) *normalization.Dictionaries {
+	if projectID <= 0 || s.serviceDB == nil {
+		return normalization.DefaultDictionaries()
+	}
+	dictionaries, err := normalization.LoadProjectDictionaries(s.serviceDB, projectID)
+	if err != nil {
+		log.Printf("Не удалось загрузить словари проекта %d: %v", projectID, err)
+		return normalization.DefaultDictionaries()
+	}
+	return dictionaries
+}
+
+// Ограничения оценки атрибутов промпта: каждый эталон требует двух запросов к AI
+const (
+	defaultPromptEvaluationLimit = 50
+	maxPromptEvaluationLimit     = 500
+)
+
+// handleProjectPromptAttributes управляет атрибутами элемента, передаваемыми AI вместе с наименованием,
+// и оценивает их влияние на точность по подтвержденным эталонам проекта
+// GET/PUT /api/clients/{id}/projects/{projectId}/prompt-attributes
+// POST /api/clients/{id}/projects/{projectId}/prompt-attributes/evaluate
+func (s *Server) handleProjectPromptAttributes(w http.ResponseWriter, r *http.Request, clientID, projectID int, action string) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+	project, err := s.serviceDB.GetClientProject(projectID)
+	if err != nil {
+		s.writeJSONError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if project.ClientID != clientID {
+		s.writeJSONError(w, "Project does not belong to this client", http.StatusBadRequest)
+		return
+	}
+
+	if action == "evaluate" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.evaluateProjectPromptAttributes(w, r, projectID)
+		return
+	}
+	if action != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		attributes, err := s.serviceDB.GetProjectPromptAttributes(projectID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{"project_id": projectID, "attributes": attributes}, http.StatusOK)
+	case http.MethodPut:
+		var req struct {
+			Attributes []string `json:"attributes"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		attributes, err := s.serviceDB.SetProjectPromptAttributes(projectID, req.Attributes)
+		if err != nil {
+			s.writeDictionaryError(w, err)
+			return
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Проект %d: атрибуты промпта AI %v", projectID, attributes),
+			Endpoint:  r.URL.Path,
+		})
+		s.writeJSONResponse(w, map[string]interface{}{"project_id": projectID, "attributes": attributes}, http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// evaluateProjectPromptAttributes сравнивает точность AI нормализации подтвержденных эталонов
+// без атрибутов и с атрибутами проекта (или переданными в запросе для проверки до сохранения)
+func (s *Server) evaluateProjectPromptAttributes(w http.ResponseWriter, r *http.Request, projectID int) {
+	var req struct {
+		Attributes []string `json:"attributes"`
+		Limit      int      `json:"limit"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultPromptEvaluationLimit
+	}
+	if req.Limit > maxPromptEvaluationLimit {
+		req.Limit = maxPromptEvaluationLimit
+	}
+	if req.Attributes == nil {
+		attributes, err := s.serviceDB.GetProjectPromptAttributes(projectID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		req.Attributes = attributes
+	}
+	if len(req.Attributes) == 0 {
+		s.writeJSONError(w, "No prompt attributes to evaluate", http.StatusBadRequest)
+		return
+	}
+
+	apiKey := os.Getenv("ARLIAI_API_KEY")
+	if apiKey == "" {
+		s.writeJSONError(w, "ARLIAI_API_KEY not set", http.StatusBadRequest)
+		return
+	}
+	benchmarks, err := s.serviceDB.GetClientBenchmarks(projectID, "", true)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(benchmarks) > req.Limit {
+		benchmarks = benchmarks[:req.Limit]
+	}
+	if len(benchmarks) == 0 {
+		s.writeJSONError(w, "Project has no approved benchmarks", http.StatusBadRequest)
+		return
+	}
+
+	aiNormalizer := normalization.NewAINormalizer(apiKey, s.getModelFromConfig())
+	cases := normalization.PromptEvaluationCasesFromBenchmarks(benchmarks, req.Attributes)
+	comparison := normalization.EvaluatePromptAttributes(cases, aiNormalizer.NormalizeWithAttributes)
+	s.writeJSONResponse(w, map[string]interface{}{
+		"project_id": projectID,
+		"attributes": req.Attributes,
+		"result":     comparison,
+	}, http.StatusOK)
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+// handleDocumentItem обрабатывает один документ
+// POST /document/item
+func (s *Server) handleDocumentItem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
+		return
+	}
+
+	var req DocumentItemRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
+		return
+	}
+
+	batch := DocumentItemsRequest{
+		UploadUUID:   req.UploadUUID,
+		DocumentType: req.DocumentType,
+		Synonym:      req.Synonym,
+		Items: []DocumentItem{{
+			Reference:  req.Reference,
+			Number:     req.Number,
+			Date:       req.Date,
+			Posted:     req.Posted,
+			Attributes: req.Attributes,
+			TableParts: req.TableParts,
+			Timestamp:  req.Timestamp,
+		}},
+	}
+	processed, _, ok := s.saveDocumentItems(w, r, "/document/item", &batch, len(body))
+	if !ok {
+		return
+	}
+	if processed == 0 {
+		s.writeErrorResponse(w, fmt.Sprintf("Document '%s' rejected", req.Reference),
+			apperrors.Validation("invalid_document", "document reference is required"))
+		return
+	}
+
+	s.writeXMLResponse(w, DocumentItemResponse{
+		Success:   true,
+		Message:   "Document added successfully",
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// handleDocumentItems обрабатывает пакет документов одного вида
+// POST /document/items
+func (s *Server) handleDocumentItems(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeErrorResponse(w, "Failed to read request body", apperrors.Wrap(apperrors.KindValidation, "invalid_body", err))
+		return
+	}
+
+	var req DocumentItemsRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		s.writeErrorResponse(w, "Failed to parse XML", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", err))
+		return
+	}
+
+	processed, failed, ok := s.saveDocumentItems(w, r, "/document/items", &req, len(body))
+	if !ok {
+		return
+	}
+
+	s.writeXMLResponse(w, DocumentItemsResponse{
+		Success:        true,
+		ProcessedCount: processed,
+		FailedCount:    failed,
+		Message:        fmt.Sprintf("Processed %d documents, %d failed", processed, failed),
+		Timestamp:      time.Now().Format(time.RFC3339),
+	})
+}
+
+// saveDocumentItems сохраняет документы пакета в БД выгрузки. Документы без ссылки отклоняются,
+// пакет без вида документа отклоняется целиком. ok=false - ответ об ошибке уже отправлен
+func (s *Server) saveDocumentItems(w http.ResponseWriter, r *http.Request, endpoint string, req *DocumentItemsRequest, bodySize int) (processed, failed int, ok bool) {
+	noteRequestUpload(r, req.UploadUUID)
+	uploadDB, err := s.getUploadDatabase(req.UploadUUID)
+	if err != nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Failed to get upload database: %v", err), err)
+		return 0, 0, false
+	}
+
+	upload, err := uploadDB.GetUploadByUUID(req.UploadUUID)
+	if err != nil {
+		s.writeErrorResponse(w, "Upload not found", err)
+		return 0, 0, false
+	}
+
+	if req.DocumentType == "" {
+		s.writeErrorResponse(w, "Document type is required", apperrors.Validation("invalid_document_type", "document_type is required"))
+		return 0, 0, false
+	}
+
+	// Ограничение скорости приема данных клиента
+	if !s.allowIngest(w, upload, len(req.Items), bodySize) {
+		return 0, 0, false
+	}
+
+	documents := make([]*database.UploadDocument, 0, len(req.Items))
+	for _, item := range req.Items {
+		if item.Reference == "" {
+			failed++
+			s.logCtx(r.Context(), LogEntry{
+				Timestamp:  time.Now(),
+				Level:      "ERROR",
+				Message:    fmt.Sprintf("Document '%s' of type %s rejected: reference is empty", item.Number, req.DocumentType),
+				UploadUUID: req.UploadUUID,
+				Endpoint:   endpoint,
+			})
+			continue
+		}
+		attributes, tableParts := item.Attributes.Content, item.TableParts.Content
+		if err := s.sealUploadValues(upload, &attributes, &tableParts); err != nil {
+			s.writeErrorResponse(w, "Failed to encrypt documents", err)
+			return 0, 0, false
+		}
+		documents = append(documents, &database.UploadDocument{
+			Reference:     item.Reference,
+			Number:        item.Number,
+			Date:          item.Date,
+			Posted:        item.Posted,
+			AttributesXML: attributes,
+			TablePartsXML: tableParts,
+		})
+	}
+
+	if len(documents) > 0 {
+		if err := uploadDB.AddUploadDocuments(upload.ID, req.DocumentType, req.Synonym, documents); err != nil {
+			s.writeErrorResponse(w, fmt.Sprintf("Failed to save documents: %v", err), err)
+			return 0, 0, false
+		}
+	}
+	processed = len(documents)
+
+	s.logCtx(r.Context(), LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "INFO",
+		Message:    fmt.Sprintf("Documents %s processed: %d successful, %d failed", req.DocumentType, processed, failed),
+		UploadUUID: req.UploadUUID,
+		Endpoint:   endpoint,
+	})
+	return processed, failed, true
+}
+
+// handleUploadDocuments виды документов выгрузки и страница документов
+// GET /api/uploads/{uuid}/documents?type=&limit=&offset=
+func (s *Server) handleUploadDocuments(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Скрытые профилем пользователя поля не передаются
+	redaction, err := s.requestRedaction(r)
+	if err != nil {
+		s.writeAPIError(w, "Failed to resolve redaction profile", err)
+		return
+	}
+
+	types, err := uploadDB.GetUploadDocumentTypes(upload.ID)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get document types: %v", err), http.StatusInternalServerError)
+		return
+	}
+	documents, total, err := uploadDB.GetUploadDocuments(upload.ID, query.Get("type"), offset, limit)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get documents: %v", err), http.StatusInternalServerError)
+		return
+	}
+	for _, doc := range documents {
+		doc.AttributesXML = s.revealValue(r, doc.AttributesXML)
+		doc.TablePartsXML = s.revealValue(r, doc.TablePartsXML)
+	}
+	redaction.RedactUploadDocuments(documents)
+	s.recordRedactionUsage(requestActor(r, "", "api"), redaction, "/api/uploads/{uuid}/documents", "upload:"+upload.UploadUUID)
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"upload_uuid":    upload.UploadUUID,
+		"document_types": types,
+		"documents":      documents,
+		"total":          total,
+		"limit":          limit,
+		"offset":         offset,
+	}, http.StatusOK)
+}
+
+// uploadDocumentTypes виды документов выгрузки для деталей выгрузки; ошибка не мешает выдаче остальных данных
+func uploadDocumentTypes(uploadDB *database.DB, uploadID int) ([]DocumentTypeInfo, int) {
+	types, err := uploadDB.GetUploadDocumentTypes(uploadID)
+	if err != nil {
+		log.Printf("Warning: Failed to get document types of upload %d: %v", uploadID, err)
+		return nil, 0
+	}
+	result := make([]DocumentTypeInfo, 0, len(types))
+	total := 0
+	for _, t := range types {
+		result = append(result, DocumentTypeInfo{
+			ID:            t.ID,
+			Name:          t.Name,
+			Synonym:       t.Synonym,
+			DocumentCount: t.Documents,
+			CreatedAt:     t.CreatedAt,
+		})
+		total += t.Documents
+	}
+	return result, total
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestDocumentIngest(t *testing.T) {
+	db, err := database.NewDB(filepath.Join(t.TempDir(), "upload.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	upload, err := db.CreateUpload("uuid-documents", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	s := &Server{db: db, config: &Config{}, logChan: make(chan LogEntry, 100)}
+	s.uploadDBs.putShared(upload.UploadUUID, db)
+
+	tests := []struct {
+		name          string
+		handler       http.HandlerFunc
+		body          string
+		wantStatus    int
+		wantProcessed int
+		wantFailed    int
+	}{
+		{"batch", s.handleDocumentItems, `<document_items><upload_uuid>uuid-documents</upload_uuid>
+			<document_type>ПоступлениеТоваровУслуг</document_type><synonym>Поступление товаров и услуг</synonym><items>
+			<item><reference>doc-1</reference><number>0001</number><date>2024-01-10T10:00:00</date><posted>true</posted>
+				<attributes_xml><Реквизит Имя="Контрагент">ООО Ромашка</Реквизит></attributes_xml>
+				<table_parts><Товары><Строка Номенклатура="Дрель" Количество="2"/></Товары></table_parts></item>
+			<item><reference></reference><number>0002</number></item>
+			</items></document_items>`, http.StatusOK, 1, 1},
+		{"single document", s.handleDocumentItem, `<document_item><upload_uuid>uuid-documents</upload_uuid>
+			<document_type>РеализацияТоваровУслуг</document_type><reference>doc-2</reference><number>0001</number>
+			<date>2024-01-11T10:00:00</date></document_item>`, http.StatusOK, 0, 0},
+		{"missing document type", s.handleDocumentItems, `<document_items><upload_uuid>uuid-documents</upload_uuid>
+			<items><item><reference>doc-3</reference></item></items></document_items>`, http.StatusBadRequest, 0, 0},
+		{"single document without reference", s.handleDocumentItem, `<document_item><upload_uuid>uuid-documents</upload_uuid>
+			<document_type>РеализацияТоваровУслуг</document_type></document_item>`, http.StatusBadRequest, 0, 0},
+		{"unknown upload", s.handleDocumentItems, `<document_items><upload_uuid>missing</upload_uuid>
+			<document_type>ПоступлениеТоваровУслуг</document_type></document_items>`, http.StatusNotFound, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodPost, "/document/items", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantProcessed == 0 && tt.wantFailed == 0 {
+				return
+			}
+			var resp DocumentItemsResponse
+			if err := xml.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if resp.ProcessedCount != tt.wantProcessed || resp.FailedCount != tt.wantFailed {
+				t.Errorf("processed = %d, failed = %d; want %d, %d", resp.ProcessedCount, resp.FailedCount, tt.wantProcessed, tt.wantFailed)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleUploadDocuments(rec, httptest.NewRequest(http.MethodGet, "/api/uploads/uuid-documents/documents?type=ПоступлениеТоваровУслуг", nil), db, upload)
+	var listing struct {
+		DocumentTypes []database.UploadDocumentType `json:"document_types"`
+		Documents     []database.UploadDocument     `json:"documents"`
+		Total         int                           `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("documents status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(listing.DocumentTypes) != 2 || listing.Total != 1 || len(listing.Documents) != 1 {
+		t.Fatalf("documents = %+v, want 2 types and 1 receipt", listing)
+	}
+	if doc := listing.Documents[0]; !doc.Posted || !strings.Contains(doc.TablePartsXML, `Номенклатура="Дрель"`) {
+		t.Errorf("receipt = %+v, want posted document with table parts", doc)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleGetUpload(rec, httptest.NewRequest(http.MethodGet, "/api/uploads/uuid-documents", nil), upload)
+	var details UploadDetails
+	if err := json.Unmarshal(rec.Body.Bytes(), &details); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("upload details status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if details.TotalDocuments != 2 || len(details.DocumentTypes) != 2 || details.ComputedCounts == nil || details.ComputedCounts.Documents != 2 {
+		t.Errorf("upload details documents = %d, types %+v, computed %+v; want 2", details.TotalDocuments, details.DocumentTypes, details.ComputedCounts)
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"httpserver/database"
+	"httpserver/quality"
+)
+
+// Ограничения размера графа кластеров дублей
+const (
+	duplicateGraphDefaultLimit    = 20
+	duplicateGraphMaxLimit        = 200
+	duplicateGraphDefaultMaxNodes = 500
+	duplicateGraphMaxNodes        = 5000
+)
+
+// handleQualityDuplicateGraph возвращает кластеры нечетких дублей выгрузки в виде графа:
+// узлы - элементы, ребра - сходство, найденное при анализе качества
+// GET /api/quality/duplicates/graph?upload_uuid=...&category=...&min_similarity=0.9&limit=20&offset=0&max_nodes=500
+func (s *Server) handleQualityDuplicateGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	uploadUUID := query.Get("upload_uuid")
+	if uploadUUID == "" {
+		s.writeJSONError(w, "upload_uuid is required", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := boundedIntParam(query.Get("limit"), duplicateGraphDefaultLimit, duplicateGraphMaxLimit)
+	if err != nil {
+		s.writeJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+	maxNodes, err := boundedIntParam(query.Get("max_nodes"), duplicateGraphDefaultMaxNodes, duplicateGraphMaxNodes)
+	if err != nil {
+		s.writeJSONError(w, "Invalid max_nodes parameter", http.StatusBadRequest)
+		return
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			s.writeJSONError(w, "Invalid offset parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	minSimilarity := 0.0
+	if value := query.Get("min_similarity"); value != "" {
+		if minSimilarity, err = strconv.ParseFloat(value, 64); err != nil || minSimilarity < 0 || minSimilarity > 1 {
+			s.writeJSONError(w, "Invalid min_similarity parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	uploadDB, err := s.getUploadDatabase(uploadUUID)
+	if err != nil {
+		s.writeJSONError(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+	upload, err := uploadDB.GetUploadByUUID(uploadUUID)
+	if err != nil {
+		s.writeJSONError(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+
+	edges, err := uploadDB.GetDuplicateEdges(database.DuplicateEdgeFilter{
+		UploadID:      upload.ID,
+		Category:      query.Get("category"),
+		MinSimilarity: minSimilarity,
+	})
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get duplicate edges: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	graph := quality.BuildDuplicateGraph(edges, offset, limit, maxNodes)
+	response := map[string]interface{}{
+		"upload_uuid":       uploadUUID,
+		"nodes":             graph.Nodes,
+		"edges":             graph.Edges,
+		"total_clusters":    graph.TotalClusters,
+		"clusters_returned": graph.ClustersReturned,
+		"truncated":         graph.Truncated,
+		"limit":             limit,
+		"offset":            offset,
+		"max_nodes":         maxNodes,
+	}
+	if next := offset + graph.ClustersReturned; next < graph.TotalClusters {
+		response["next_offset"] = next
+	}
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// boundedIntParam разбирает положительный целый параметр со значением по умолчанию и верхней границей
+func boundedIntParam(value string, defaultValue, maxValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if parsed > maxValue {
+		parsed = maxValue
+	}
+	return parsed, nil
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"httpserver/database"
+	"httpserver/encryption"
+)
+
+// encryptedPlaceholder заменяет зашифрованные реквизиты в ответах пользователям без права расшифровки
+const encryptedPlaceholder = "[encrypted]"
+
+// errEncryptionNotConfigured шифрование запрошено, но мастер-ключи развертывания не заданы
+var errEncryptionNotConfigured = errors.New("encryption is not configured: set ENCRYPTION_MASTER_KEYS")
+
+// projectKey ключ данных проекта; key nil - ключ проекту не создавался
+type projectKey struct {
+	enabled bool
+	key     []byte
+}
+
+// projectKeyRef ссылка на ключ данных проекта в зашифрованных значениях
+func projectKeyRef(projectID int) string {
+	return "p" + strconv.Itoa(projectID)
+}
+
+// projectIDFromKeyRef возвращает проект по ссылке на ключ данных
+func projectIDFromKeyRef(keyRef string) (int, bool) {
+	if !strings.HasPrefix(keyRef, "p") {
+		return 0, false
+	}
+	id, err := strconv.Atoi(keyRef[1:])
+	return id, err == nil && id > 0
+}
+
+// getProjectKey возвращает ключ данных проекта из кэша или service.db
+func (s *Server) getProjectKey(projectID int) (*projectKey, error) {
+	s.projectKeysMutex.RLock()
+	cached, ok := s.projectKeys[projectID]
+	s.projectKeysMutex.RUnlock()
+	if ok {
+		return cached, nil
+	}
+	if s.serviceDB == nil {
+		return &projectKey{}, nil
+	}
+
+	enc, err := s.serviceDB.GetProjectEncryption(projectID)
+	if err != nil {
+		return nil, err
+	}
+	loaded := &projectKey{}
+	if enc != nil {
+		if s.encryptionKeyring == nil {
+			if enc.Enabled {
+				return nil, errEncryptionNotConfigured
+			}
+			// Шифрование отключено: прием идет без ключа, ранее зашифрованные данные не читаются
+			return loaded, nil
+		}
+		key, err := s.encryptionKeyring.UnwrapKey(enc.MasterKeyID, enc.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("project %d data key: %w", projectID, err)
+		}
+		loaded = &projectKey{enabled: enc.Enabled, key: key}
+	}
+
+	s.projectKeysMutex.Lock()
+	if s.projectKeys == nil {
+		s.projectKeys = make(map[int]*projectKey)
+	}
+	s.projectKeys[projectID] = loaded
+	s.projectKeysMutex.Unlock()
+	return loaded, nil
+}
+
+// forgetProjectKey сбрасывает кэш ключа проекта после изменения настройки шифрования
+func (s *Server) forgetProjectKey(projectID int) {
+	s.projectKeysMutex.Lock()
+	delete(s.projectKeys, projectID)
+	s.projectKeysMutex.Unlock()
+}
+
+// sealUploadValues шифрует значения в месте хранения, если для проекта выгрузки включено шифрование.
+// Пустые значения не шифруются
+func (s *Server) sealUploadValues(upload *database.Upload, values ...*string) error {
+	if upload == nil || upload.ProjectID == nil {
+		return nil
+	}
+	pk, err := s.getProjectKey(*upload.ProjectID)
+	if err != nil {
+		return err
+	}
+	if !pk.enabled {
+		return nil
+	}
+	keyRef := projectKeyRef(*upload.ProjectID)
+	for _, value := range values {
+		sealed, err := encryption.Seal(pk.key, keyRef, *value)
+		if err != nil {
+			return err
+		}
+		*value = sealed
+	}
+	return nil
+}
+
+// sealCatalogTableItem шифрует реквизиты элемента справочника, сохраняя исходные значения
+// и ключ хешей проекта для истории версий
+func (s *Server) sealCatalogTableItem(upload *database.Upload, item database.CatalogTableItem) (database.CatalogTableItem, error) {
+	attributes, tableParts := item.AttributesXML, item.TablePartsXML
+	if err := s.sealUploadValues(upload, &item.AttributesXML, &item.TablePartsXML); err != nil {
+		return item, err
+	}
+	if item.AttributesXML != attributes || item.TablePartsXML != tableParts {
+		pk, err := s.getProjectKey(*upload.ProjectID)
+		if err != nil {
+			return item, err
+		}
+		item.Sealed = true
+		item.PlainAttributesXML, item.PlainTablePartsXML = attributes, tableParts
+		item.DigestKey = encryption.DigestKey(pk.key)
+	}
+	return item, nil
+}
+
+// openStoredValue расшифровывает сохраненное значение ключом его проекта; незашифрованное возвращается как есть
+func (s *Server) openStoredValue(value string) (string, error) {
+	keyRef, ok := encryption.KeyRef(value)
+	if !ok {
+		return encryption.Open(nil, value)
+	}
+	projectID, ok := projectIDFromKeyRef(keyRef)
+	if !ok {
+		return "", fmt.Errorf("unknown data key %s: %w", keyRef, encryption.ErrUnknownKey)
+	}
+	pk, err := s.getProjectKey(projectID)
+	if err != nil {
+		return "", err
+	}
+	if pk.key == nil {
+		return "", fmt.Errorf("project %d data key: %w", projectID, encryption.ErrUnknownKey)
+	}
+	return encryption.Open(pk.key, value)
+}
+
+// canDecrypt проверяет, что пользователь запроса получает зашифрованные данные расшифрованными.
+// Запросы без сессии (без входа, по токену без пользователя) получают заглушку
+func (s *Server) canDecrypt(r *http.Request) bool {
+	session := sessionFromContext(r.Context())
+	if session == nil {
+		return false
+	}
+	role := database.RoleOperator
+	if s.currentConfig() != nil && s.currentConfig().EncryptionDecryptRole != "" {
+		role = s.currentConfig().EncryptionDecryptRole
+	}
+	return database.RoleAllows(session.User.Role, role)
+}
+
+// revealValue подготавливает сохраненное значение к выдаче в API: расшифровывает для ролей с правом
+// расшифровки, иначе заменяет заглушкой. Незашифрованные значения выдаются как есть
+func (s *Server) revealValue(r *http.Request, value string) string {
+	if !encryption.IsSealed(value) {
+		return value
+	}
+	if !s.canDecrypt(r) {
+		return encryptedPlaceholder
+	}
+	plaintext, err := s.openStoredValue(value)
+	if err != nil {
+		log.Printf("Ошибка расшифровки данных выгрузки: %v", err)
+		return encryptedPlaceholder
+	}
+	return plaintext
+}
+
+// exportValue подготавливает сохраненное значение к экспорту: расшифровывает, если создатель задачи
+// вправе получать расшифрованные данные (decrypt), иначе заменяет заглушкой
+func (s *Server) exportValue(value string, decrypt bool) (string, error) {
+	if !encryption.IsSealed(value) {
+		return value, nil
+	}
+	if !decrypt {
+		return encryptedPlaceholder, nil
+	}
+	return s.openStoredValue(value)
+}
+
+// openCatalogItems подготавливает реквизиты элементов справочников к экспорту (см. exportValue)
+func (s *Server) openCatalogItems(items []*database.CatalogItem, decrypt bool) error {
+	for _, item := range items {
+		var err error
+		if item.Attributes, err = s.exportValue(item.Attributes, decrypt); err != nil {
+			return err
+		}
+		if item.TableParts, err = s.exportValue(item.TableParts, decrypt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openNomenclatureItems подготавливает реквизиты номенклатуры к экспорту (см. exportValue)
+func (s *Server) openNomenclatureItems(items []*database.NomenclatureItem, decrypt bool) error {
+	for _, item := range items {
+		var err error
+		if item.AttributesXML, err = s.exportValue(item.AttributesXML, decrypt); err != nil {
+			return err
+		}
+		if item.TablePartsXML, err = s.exportValue(item.TablePartsXML, decrypt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleProjectEncryption управляет шифрованием данных выгрузок проекта: реквизиты и табличные части
+// элементов справочников и номенклатуры шифруются при приеме. Отключение не расшифровывает
+// сохраненные данные и не удаляет ключ
+// GET/PUT /api/clients/{id}/projects/{projectId}/encryption
+// POST /api/clients/{id}/projects/{projectId}/encryption/rewrap - переобернуть ключ текущим мастер-ключом
+func (s *Server) handleProjectEncryption(w http.ResponseWriter, r *http.Request, clientID, projectID int, action string) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+	project, err := s.serviceDB.GetClientProject(projectID)
+	if err != nil {
+		s.writeJSONError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if project.ClientID != clientID {
+		s.writeJSONError(w, "Project does not belong to this client", http.StatusBadRequest)
+		return
+	}
+	actor := requestActor(r, "", r.RemoteAddr)
+
+	switch {
+	case action == "rewrap":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.rewrapProjectKey(w, projectID, actor)
+		return
+	case action != "":
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			Enabled bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := s.setProjectEncryption(projectID, req.Enabled, actor); err != nil {
+			if errors.Is(err, errEncryptionNotConfigured) {
+				s.writeJSONError(w, err.Error(), http.StatusConflict)
+				return
+			}
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.recordProjectLifecycle(database.AuditActionProjectEncryption, actor, projectID, "success", map[string]interface{}{"enabled": req.Enabled})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeProjectEncryption(w, projectID)
+}
+
+// setProjectEncryption включает или отключает шифрование проекта; ключ данных создается при первом включении
+func (s *Server) setProjectEncryption(projectID int, enabled bool, actor string) error {
+	defer s.forgetProjectKey(projectID)
+	if !enabled {
+		return s.serviceDB.DisableProjectEncryption(projectID, actor)
+	}
+
+	existing, err := s.serviceDB.GetProjectEncryption(projectID)
+	if err != nil {
+		return err
+	}
+	if s.encryptionKeyring == nil {
+		return errEncryptionNotConfigured
+	}
+	if existing != nil {
+		return s.serviceDB.EnableProjectEncryption(projectID, existing.MasterKeyID, existing.WrappedKey, actor)
+	}
+	dataKey, err := encryption.GenerateKey()
+	if err != nil {
+		return err
+	}
+	masterKeyID, wrapped, err := s.encryptionKeyring.WrapKey(dataKey)
+	if err != nil {
+		return err
+	}
+	return s.serviceDB.EnableProjectEncryption(projectID, masterKeyID, wrapped, actor)
+}
+
+// rewrapProjectKey оборачивает ключ данных проекта текущим мастер-ключом: после этого прежний мастер-ключ
+// можно убрать из ENCRYPTION_MASTER_KEYS. Зашифрованные данные не перешифровываются
+func (s *Server) rewrapProjectKey(w http.ResponseWriter, projectID int, actor string) {
+	existing, err := s.serviceDB.GetProjectEncryption(projectID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		s.writeJSONError(w, "Project encryption key not found", http.StatusNotFound)
+		return
+	}
+	if s.encryptionKeyring == nil {
+		s.writeJSONError(w, errEncryptionNotConfigured.Error(), http.StatusConflict)
+		return
+	}
+	dataKey, err := s.encryptionKeyring.UnwrapKey(existing.MasterKeyID, existing.WrappedKey)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	masterKeyID, wrapped, err := s.encryptionKeyring.WrapKey(dataKey)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := s.serviceDB.RewrapProjectEncryptionKey(projectID, masterKeyID, wrapped, actor); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.forgetProjectKey(projectID)
+	s.recordProjectLifecycle(database.AuditActionProjectEncryption, actor, projectID, "success", map[string]interface{}{"master_key_id": masterKeyID})
+	s.writeProjectEncryption(w, projectID)
+}
+
+// writeProjectEncryption отвечает настройкой шифрования проекта
+func (s *Server) writeProjectEncryption(w http.ResponseWriter, projectID int) {
+	enc, err := s.serviceDB.GetProjectEncryption(projectID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if enc == nil {
+		enc = &database.ProjectEncryption{ProjectID: projectID}
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"encryption": enc,
+		"configured": s.encryptionKeyring != nil,
+	}, http.StatusOK)
+}
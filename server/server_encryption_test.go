@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/encryption"
+)
+
+func testMasterKeys(t *testing.T, current string, ids ...string) *encryption.Keyring {
+	t.Helper()
+	var spec []string
+	for _, id := range ids {
+		// Ключ зависит только от идентификатора: один и тот же ключ в разных наборах
+		spec = append(spec, id+":"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte(id[len(id)-1:]), encryption.KeySize)))
+	}
+	keyring, err := encryption.ParseKeyring(strings.Join(spec, ","), current)
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+func TestProjectEncryptionRoutes(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	client, _ := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	project, _ := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	s := &Server{logChan: make(chan LogEntry, 100), serviceDB: serviceDB, config: &Config{}}
+	path := fmt.Sprintf("/api/clients/%d/projects/%d/encryption", client.ID, project.ID)
+
+	steps := []struct {
+		name       string
+		keyring    *encryption.Keyring
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"not configured", nil, http.MethodPut, path, `{"enabled": true}`, http.StatusConflict, "ENCRYPTION_MASTER_KEYS"},
+		{"disabled by default", nil, http.MethodGet, path, "", http.StatusOK, `"enabled":false`},
+		{"enable", testMasterKeys(t, "", "k1"), http.MethodPut, path, `{"enabled": true}`, http.StatusOK, `"master_key_id":"k1"`},
+		{"wrapped key is not exposed", testMasterKeys(t, "", "k1"), http.MethodGet, path, "", http.StatusOK, `"configured":true`},
+		{"rewrap after rotation", testMasterKeys(t, "k2", "k1", "k2"), http.MethodPost, path + "/rewrap", "", http.StatusOK, `"master_key_id":"k2"`},
+		{"old master key removed", testMasterKeys(t, "", "k2"), http.MethodPost, path + "/rewrap", "", http.StatusOK, `"master_key_id":"k2"`},
+		{"unknown action", nil, http.MethodPost, path + "/decrypt", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			s.encryptionKeyring = tt.keyring
+			rec := httptest.NewRecorder()
+			s.handleClientRoutes(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d, body = %s, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+			if strings.Contains(rec.Body.String(), "wrapped") {
+				t.Errorf("response exposes wrapped key: %s", rec.Body.String())
+			}
+		})
+	}
+
+	events, _ := serviceDB.GetAuditEvents(database.AuditActionProjectEncryption, 10)
+	if len(events) != 3 {
+		t.Errorf("audit events = %d, want 3", len(events))
+	}
+}
+
+func TestProjectEncryptionSealReveal(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	client, _ := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	project, _ := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	s := &Server{logChan: make(chan LogEntry, 100), serviceDB: serviceDB, config: &Config{EncryptionDecryptRole: database.RoleOperator}}
+	s.encryptionKeyring = testMasterKeys(t, "", "k1")
+	upload := &database.Upload{ProjectID: &project.ID}
+
+	plain := "<Артикул>MS-500</Артикул>"
+	unsealed := plain
+	if err := s.sealUploadValues(upload, &unsealed); err != nil || unsealed != plain {
+		t.Fatalf("sealUploadValues() before enabling = %q, %v", unsealed, err)
+	}
+	if err := s.setProjectEncryption(project.ID, true, "admin"); err != nil {
+		t.Fatalf("setProjectEncryption() error = %v", err)
+	}
+	sealed, empty := plain, ""
+	if err := s.sealUploadValues(upload, &sealed, &empty); err != nil {
+		t.Fatalf("sealUploadValues() error = %v", err)
+	}
+	if !encryption.IsSealed(sealed) || strings.Contains(sealed, "MS-500") || empty != "" {
+		t.Fatalf("sealed = %q, empty = %q", sealed, empty)
+	}
+
+	// Отключение шифрования не мешает читать ранее зашифрованные данные
+	if err := s.setProjectEncryption(project.ID, false, "admin"); err != nil {
+		t.Fatalf("setProjectEncryption(false) error = %v", err)
+	}
+	afterDisable := plain
+	if err := s.sealUploadValues(upload, &afterDisable); err != nil || afterDisable != plain {
+		t.Fatalf("sealUploadValues() after disabling = %q, %v", afterDisable, err)
+	}
+
+	withRole := func(role string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/api/uploads", nil)
+		if role == "" {
+			return r
+		}
+		session := &database.UserSession{User: &database.User{Username: role, Role: role}}
+		return r.WithContext(context.WithValue(r.Context(), sessionKey{}, session))
+	}
+	tests := []struct {
+		name  string
+		role  string
+		value string
+		want  string
+	}{
+		{"operator decrypts", database.RoleOperator, sealed, plain},
+		{"admin decrypts", database.RoleAdmin, sealed, plain},
+		{"viewer gets placeholder", database.RoleViewer, sealed, encryptedPlaceholder},
+		{"anonymous gets placeholder", "", sealed, encryptedPlaceholder},
+		{"plain value as is", "", plain, plain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.revealValue(withRole(tt.role), tt.value); got != tt.want {
+				t.Errorf("revealValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Экспорт расшифровывает данные, только если создатель задачи вправе их расшифровать
+	for _, decrypt := range []bool{true, false} {
+		want := encryptedPlaceholder
+		if decrypt {
+			want = plain
+		}
+		items := []*database.CatalogItem{{Attributes: sealed, TableParts: plain}}
+		if err := s.openCatalogItems(items, decrypt); err != nil || items[0].Attributes != want || items[0].TableParts != plain {
+			t.Errorf("openCatalogItems(decrypt=%v) = %+v, %v", decrypt, items[0], err)
+		}
+	}
+}
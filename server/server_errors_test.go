@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+func TestWriteErrorResponseStatus(t *testing.T) {
+	s := &Server{logChan: make(chan LogEntry, 10)}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"validation", apperrors.Wrap(apperrors.KindValidation, "invalid_xml", errors.New("EOF")), http.StatusBadRequest, "invalid_xml"},
+		{"not found", apperrors.NotFound("upload_not_found", "upload x not found"), http.StatusNotFound, "upload_not_found"},
+		{"internal", errors.New("disk I/O error"), http.StatusInternalServerError, "internal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.writeErrorResponse(rec, "Request failed", tt.err)
+			var xmlBody ErrorResponse
+			if err := xml.Unmarshal(rec.Body.Bytes(), &xmlBody); err != nil {
+				t.Fatalf("invalid XML body: %v", err)
+			}
+			if rec.Code != tt.status || xmlBody.Code != tt.code {
+				t.Errorf("XML status = %d, code = %s; want %d, %s", rec.Code, xmlBody.Code, tt.status, tt.code)
+			}
+
+			rec = httptest.NewRecorder()
+			s.writeAPIError(rec, "Request failed", tt.err)
+			var jsonBody struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &jsonBody); err != nil {
+				t.Fatalf("invalid JSON body: %v", err)
+			}
+			if rec.Code != tt.status || jsonBody.Code != tt.code {
+				t.Errorf("JSON status = %d, code = %s; want %d, %s", rec.Code, jsonBody.Code, tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestUploadEndpointsNotFound(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	s := &Server{db: db, unifiedCatalogsDB: db, config: &Config{}, logChan: make(chan LogEntry, 10)}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		status  int
+		code    string
+	}{
+		{"malformed XML", s.handleHandshake, "<handshake>", http.StatusBadRequest, "invalid_xml"},
+		{"missing field", s.handleHandshake, "<handshake><config_name>УТ</config_name></handshake>", http.StatusBadRequest, "missing_field"},
+		{"unknown upload", s.handleComplete, "<complete><upload_uuid>0f8fad5b-d9cb-469f-a165-70867728950e</upload_uuid></complete>", http.StatusNotFound, "upload_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			var body ErrorResponse
+			if err := xml.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid XML body %q: %v", rec.Body.String(), err)
+			}
+			if rec.Code != tt.status || body.Code != tt.code {
+				t.Errorf("status = %d, code = %s; want %d, %s", rec.Code, body.Code, tt.status, tt.code)
+			}
+		})
+	}
+}
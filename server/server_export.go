@@ -2,6 +2,8 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -9,12 +11,12 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"httpserver/database"
+	"httpserver/parquet"
 
 	"github.com/google/uuid"
 )
@@ -28,11 +30,20 @@ const (
 
 // ExportRequest описание входящего JSON-запроса на обратную выгрузку.
 type ExportRequest struct {
-	TargetURL      string   `json:"target_url"`
-	Include        []string `json:"include"`
-	CatalogNames   []string `json:"catalog_names"`
-	BatchSize      int      `json:"batch_size"`
-	TimeoutSeconds int      `json:"timeout_seconds"`
+	Type           string       `json:"type"` // protocol (по умолчанию), odata или parquet
+	OData          *ODataConfig `json:"odata,omitempty"`
+	Parquet        *ParquetExportConfig `json:"parquet,omitempty"`
+	Contract       *ExportContractConfig `json:"contract,omitempty"` // Проверка контракта данных перед выгрузкой
+	TargetURL      string       `json:"target_url"`
+	Include        []string     `json:"include"`
+	CatalogNames   []string     `json:"catalog_names"`
+	Fields         []string      `json:"fields"` // Наборы полей: attributes, table_parts; по умолчанию все
+	Filter         *ExportFilter `json:"filter,omitempty"` // Отбор элементов по нормализованным данным и изменениям
+	BatchSize      int          `json:"batch_size"`
+	TimeoutSeconds int          `json:"timeout_seconds"`
+	OverrideGates  bool         `json:"override_gates"` // Запустить выгрузку в 1С при невыполненных порогах качества базы
+	RedactionProfile string     `json:"redaction_profile"` // Профиль скрытия колонок получателя, добавляется к профилю создателя задачи
+	Actor          string       `json:"actor"`
 }
 
 // ExportOptions нормализованные настройки экспорта.
@@ -41,7 +52,12 @@ type ExportOptions struct {
 	IncludeConstants    bool     `json:"include_constants"`
 	IncludeCatalogs     bool     `json:"include_catalogs"`
 	IncludeNomenclature bool     `json:"include_nomenclature"`
+	IncludeNormalized   bool     `json:"include_normalized"` // Нормализованные данные (только для parquet)
+	IncludeAttachments  bool     `json:"include_attachments"` // Вложения элементов справочников (только для protocol)
+	IncludeDocuments    bool     `json:"include_documents"` // Документы выгрузки (только для protocol)
 	CatalogNames        []string `json:"catalog_names,omitempty"`
+	Fields              []string      `json:"fields,omitempty"`
+	Filter              *ExportFilter `json:"filter,omitempty"`
 	BatchSize           int      `json:"batch_size"`
 }
 
@@ -53,6 +69,10 @@ type ExportProgress struct {
 	CatalogsSent       int  `json:"catalogs_sent"`
 	CatalogItemsSent   int  `json:"catalog_items_sent"`
 	NomenclatureSent   int  `json:"nomenclature_sent"`
+	NormalizedSent     int  `json:"normalized_sent"`
+	AttachmentsSent    int  `json:"attachments_sent"`
+	DocumentsSent      int  `json:"documents_sent"`
+	ItemsSkipped       int  `json:"items_skipped"` // Элементы, не прошедшие отбор задачи
 	CompleteDispatched bool `json:"complete_dispatched"`
 }
 
@@ -70,6 +90,7 @@ const (
 type ExportJob struct {
 	mu               sync.RWMutex
 	ID               string
+	Type             string
 	UploadUUID       string
 	RemoteUploadUUID string
 	TargetURL        string
@@ -81,11 +102,23 @@ type ExportJob struct {
 	Progress         ExportProgress
 	Options          ExportOptions
 	Timeout          time.Duration
+	Artifacts        []ExportArtifact
+	odata            *ODataConfig // Настройки OData коннектора (содержат учетные данные, в API не отдаются)
+	parquet          parquet.Options
+	contractMode     string
+	contract         database.ExportContract
+	contractReport   *database.ContractReport
+	selection        *exportSelection // Отбор задачи, забираемой обработкой 1С; рассчитывается при первом запросе
+	redaction        *database.RedactionProfile // Скрываемые поля; nil - данные передаются полностью
+	decrypt          bool                       // Создатель задачи вправе расшифровывать данные; иначе передается заглушка
+	persist          func(*ExportJob)           // Сохраняет задачу в service.db при смене статуса; nil - не сохраняется
+	restored         bool                       // Задача восстановлена из service.db, а не создана этим экземпляром
 }
 
 // ExportJobView DTO для ответа API.
 type ExportJobView struct {
 	ID               string          `json:"id"`
+	Type             string          `json:"type"`
 	UploadUUID       string          `json:"upload_uuid"`
 	RemoteUploadUUID string          `json:"remote_upload_uuid,omitempty"`
 	TargetURL        string          `json:"target_url"`
@@ -96,6 +129,9 @@ type ExportJobView struct {
 	FinishedAt       *time.Time      `json:"finished_at,omitempty"`
 	Progress         ExportProgress  `json:"progress"`
 	Options          ExportOptions   `json:"options"`
+	Artifacts        []ExportArtifact `json:"artifacts,omitempty"`
+	Contract         *ExportContractView `json:"contract,omitempty"`
+	RedactionProfile string          `json:"redaction_profile,omitempty"`
 }
 
 // xmlSuccessResponse упрощенный ответ на XML-запросы.
@@ -162,6 +198,7 @@ type completeExport struct {
 func newExportJob(uploadUUID, targetURL string, options ExportOptions, timeout time.Duration) *ExportJob {
 	return &ExportJob{
 		ID:         uuid.New().String(),
+		Type:       ExportTypeProtocol,
 		UploadUUID: uploadUUID,
 		TargetURL:  targetURL,
 		Status:     ExportStatusPending,
@@ -177,6 +214,7 @@ func (job *ExportJob) snapshot() ExportJobView {
 
 	view := ExportJobView{
 		ID:               job.ID,
+		Type:             job.Type,
 		UploadUUID:       job.UploadUUID,
 		RemoteUploadUUID: job.RemoteUploadUUID,
 		TargetURL:        job.TargetURL,
@@ -185,6 +223,11 @@ func (job *ExportJob) snapshot() ExportJobView {
 		CreatedAt:        job.CreatedAt,
 		Progress:         job.Progress,
 		Options:          job.Options,
+		Artifacts:        append([]ExportArtifact(nil), job.Artifacts...),
+		Contract:         job.contractView(),
+	}
+	if job.redaction != nil {
+		view.RedactionProfile = job.redaction.Name
 	}
 
 	if job.StartedAt != nil {
@@ -201,37 +244,62 @@ func (job *ExportJob) snapshot() ExportJobView {
 		copy(names, job.Options.CatalogNames)
 		view.Options.CatalogNames = names
 	}
+	view.Options.Fields = append([]string(nil), job.Options.Fields...)
+	if job.Options.Filter != nil {
+		filter := *job.Options.Filter
+		view.Options.Filter = &filter
+	}
 
 	return view
 }
 
 func (job *ExportJob) markRunning() {
 	job.mu.Lock()
-	defer job.mu.Unlock()
 	now := time.Now()
 	job.Status = ExportStatusRunning
 	job.StartedAt = &now
 	job.Error = ""
+	job.mu.Unlock()
+	job.statusChanged()
 }
 
 func (job *ExportJob) markFailed(err error) {
 	job.mu.Lock()
-	defer job.mu.Unlock()
 	now := time.Now()
 	job.Status = ExportStatusFailed
 	job.FinishedAt = &now
 	if err != nil {
 		job.Error = err.Error()
 	}
+	job.mu.Unlock()
+	job.statusChanged()
 }
 
-func (job *ExportJob) markCompleted() {
+// statusChanged сохраняет задачу после смены статуса
+func (job *ExportJob) statusChanged() {
+	if job.persist != nil {
+		job.persist(job)
+	}
+}
+
+// failure возвращает ошибку завершившейся с ошибкой задачи
+func (job *ExportJob) failure() error {
 	job.mu.Lock()
 	defer job.mu.Unlock()
+	if job.Status != ExportStatusFailed {
+		return nil
+	}
+	return errors.New(job.Error)
+}
+
+func (job *ExportJob) markCompleted() {
+	job.mu.Lock()
 	now := time.Now()
 	job.Status = ExportStatusFinished
 	job.FinishedAt = &now
 	job.Error = ""
+	job.mu.Unlock()
+	job.statusChanged()
 }
 
 func (job *ExportJob) setRemoteUpload(uuid string) {
@@ -279,6 +347,24 @@ func (job *ExportJob) addCatalogItems(delta int) {
 	job.mu.Unlock()
 }
 
+func (job *ExportJob) addAttachments(delta int) {
+	if delta == 0 {
+		return
+	}
+	job.mu.Lock()
+	job.Progress.AttachmentsSent += delta
+	job.mu.Unlock()
+}
+
+func (job *ExportJob) addDocuments(delta int) {
+	if delta == 0 {
+		return
+	}
+	job.mu.Lock()
+	job.Progress.DocumentsSent += delta
+	job.mu.Unlock()
+}
+
 func (job *ExportJob) addNomenclature(delta int) {
 	if delta == 0 {
 		return
@@ -288,6 +374,15 @@ func (job *ExportJob) addNomenclature(delta int) {
 	job.mu.Unlock()
 }
 
+func (job *ExportJob) addSkipped(delta int) {
+	if delta == 0 {
+		return
+	}
+	job.mu.Lock()
+	job.Progress.ItemsSkipped += delta
+	job.mu.Unlock()
+}
+
 func (job *ExportJob) markCompleteDispatched() {
 	job.mu.Lock()
 	defer job.mu.Unlock()
@@ -308,28 +403,147 @@ func (s *Server) handleUploadExport(w http.ResponseWriter, r *http.Request, uplo
 		return
 	}
 
-	targetURL, err := normalizeTargetURL(payload.TargetURL)
+	exportType := strings.ToLower(strings.TrimSpace(payload.Type))
+	if exportType == "" {
+		exportType = ExportTypeProtocol
+	}
+	if exportType == ExportTypeOData {
+		if payload.OData == nil {
+			s.writeJSONError(w, "odata configuration is required", http.StatusBadRequest)
+			return
+		}
+		payload.TargetURL = payload.OData.ServiceURL
+	} else if exportType != ExportTypeProtocol && exportType != ExportTypeParquet && exportType != ExportTypePull {
+		s.writeJSONError(w, fmt.Sprintf("unknown export type: %s", payload.Type), http.StatusBadRequest)
+		return
+	}
+
+	// Parquet файлы сохраняются в хранилище артефактов, а выгрузку pull забирает обработка 1С - адрес приемника не нужен
+	var targetURL string
+	var err error
+	if exportType != ExportTypeParquet && exportType != ExportTypePull {
+		targetURL, err = normalizeTargetURL(payload.TargetURL)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	options, err := normalizeExportOptions(payload)
 	if err != nil {
 		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if options.IncludeNormalized && exportType != ExportTypeParquet {
+		s.writeJSONError(w, "normalized data can be exported only as parquet", http.StatusBadRequest)
+		return
+	}
+	var parquetOptions parquet.Options
+	if exportType == ExportTypeParquet {
+		// В Parquet выгружаются элементы справочников и нормализованные данные
+		if len(payload.Include) > 0 && (options.IncludeMetadata || options.IncludeConstants || options.IncludeNomenclature || options.IncludeAttachments || options.IncludeDocuments) {
+			s.writeJSONError(w, "parquet export supports only catalogs and normalized", http.StatusBadRequest)
+			return
+		}
+		options.IncludeMetadata = false
+		options.IncludeConstants = false
+		options.IncludeNomenclature = false
+		options.IncludeAttachments = false
+		options.IncludeDocuments = false
+		if parquetOptions, err = payload.Parquet.ParquetOptions(); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if exportType == ExportTypeOData {
+		// OData интерфейс 1С принимает только элементы справочников
+		if len(payload.Include) > 0 && (options.IncludeMetadata || options.IncludeConstants || options.IncludeAttachments || options.IncludeDocuments) {
+			s.writeJSONError(w, "odata export supports only catalogs and nomenclature", http.StatusBadRequest)
+			return
+		}
+		options.IncludeMetadata = false
+		options.IncludeConstants = false
+		options.IncludeAttachments = false
+		options.IncludeDocuments = false
+	}
+	if exportType == ExportTypePull {
+		// Обработка 1С забирает константы и элементы справочников через /api/1c/import/*
+		if len(payload.Include) > 0 && (options.IncludeMetadata || options.IncludeNomenclature || options.IncludeAttachments || options.IncludeDocuments) {
+			s.writeJSONError(w, "pull export supports only constants and catalogs", http.StatusBadRequest)
+			return
+		}
+		if payload.Contract != nil {
+			s.writeJSONError(w, "pull export does not support data contracts", http.StatusBadRequest)
+			return
+		}
+		options.IncludeMetadata = false
+		options.IncludeNomenclature = false
+		options.IncludeAttachments = false
+		options.IncludeDocuments = false
+	}
+	if (exportType == ExportTypeParquet || exportType == ExportTypeOData) && (len(options.Fields) > 0 || options.Filter.active()) {
+		s.writeJSONError(w, fmt.Sprintf("%s export does not support fields and filter", exportType), http.StatusBadRequest)
+		return
+	}
 
-	options, err := normalizeExportOptions(payload)
+	contractMode, contract, err := s.exportContract(payload.Contract)
 	if err != nil {
 		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	redaction, err := s.exportRedaction(r, payload.RedactionProfile)
+	if err != nil {
+		s.writeAPIError(w, err.Error(), err)
+		return
+	}
+
+	// Выгрузка в 1С запускается только при выполненных порогах качества базы
+	if exportType != ExportTypeParquet && !s.checkExportGates(w, r, upload, exportType, payload) {
+		return
+	}
+
 	timeout := defaultExportTimeout
 	if payload.TimeoutSeconds > 0 {
 		timeout = time.Duration(payload.TimeoutSeconds) * time.Second
 	}
 
 	job := newExportJob(upload.UploadUUID, targetURL, options, timeout)
+	job.Type = exportType
+	if exportType == ExportTypeOData {
+		odata := *payload.OData
+		odata.ServiceURL = targetURL
+		job.odata = &odata
+	}
+	job.parquet = parquetOptions
+	job.contractMode = contractMode
+	job.contract = contract
+	job.redaction = redaction
+	job.decrypt = s.canDecrypt(r)
+	s.recordRedactionUsage(requestActor(r, payload.Actor, "api"), redaction, "export:"+exportType, "export:"+job.ID)
+
+	// Выгрузка pull выполняется запросами обработки 1С и остается в ожидании до рукопожатия
+	if exportType == ExportTypePull {
+		s.registerExportJob(job)
+
+		s.log(LogEntry{
+			Timestamp:  time.Now(),
+			Level:      "INFO",
+			Message:    fmt.Sprintf("Pull export job %s created for upload %s", job.ID, job.UploadUUID),
+			UploadUUID: upload.UploadUUID,
+			Endpoint:   "/api/uploads/{uuid}/export",
+		})
+		s.writeJSONResponse(w, job.snapshot(), http.StatusCreated)
+		return
+	}
+
+	bgJob, err := s.startBackgroundJob(r.Context(), jobKindExport, job.ID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to start export", err)
+		return
+	}
 
-	s.exportJobsMutex.Lock()
-	s.exportJobs[job.ID] = job
-	s.exportJobsMutex.Unlock()
+	s.registerExportJob(job)
 
 	s.log(LogEntry{
 		Timestamp: time.Now(),
@@ -339,7 +553,11 @@ func (s *Server) handleUploadExport(w http.ResponseWriter, r *http.Request, uplo
 		Endpoint:  "/api/uploads/{uuid}/export",
 	})
 
-	go s.runExportJob(job, upload)
+	go func() {
+		defer func() { s.finishBackgroundJob(bgJob, job.failure()) }()
+		defer s.recoverWorker(jobKindExport, bgJob)
+		s.runExportJob(bgJob.ctx, job, upload)
+	}()
 
 	s.writeJSONResponse(w, job.snapshot(), http.StatusAccepted)
 }
@@ -383,13 +601,25 @@ func (s *Server) handleExportRoutes(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	jobID, subpath, _ := strings.Cut(path, "/")
 
-	job := s.getExportJob(path)
+	job := s.getExportJob(jobID)
 	if job == nil {
 		s.writeJSONError(w, "Export job not found", http.StatusNotFound)
 		return
 	}
 
+	switch subpath {
+	case "":
+	case "violations":
+		// GET /api/exports/{id}/violations - нарушения контракта данных
+		s.handleExportViolations(w, r, job)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -400,14 +630,36 @@ func (s *Server) handleExportRoutes(w http.ResponseWriter, r *http.Request) {
 
 // --- Export execution ---
 
-func (s *Server) runExportJob(job *ExportJob, upload *database.Upload) {
+func (s *Server) runExportJob(ctx context.Context, job *ExportJob, upload *database.Upload) {
+	if !s.checkExportContract(ctx, job, upload) {
+		return
+	}
+	if job.Type == ExportTypeParquet {
+		s.runParquetExportJob(ctx, job, upload)
+		return
+	}
+	if job.Type != ExportTypeProtocol {
+		s.runConnectorExportJob(job, upload)
+		return
+	}
+
 	job.markRunning()
 
-	uploadDB, err := s.getUploadDatabase(upload.UploadUUID)
+	uploadDB, release, err := s.acquireUploadDatabase(upload.UploadUUID)
 	if err != nil {
 		job.markFailed(fmt.Errorf("failed to find upload database: %w", err))
 		return
 	}
+	defer release()
+	// Экспорт только читает данные - не конкурируем с загрузкой за основной пул
+	uploadDB = uploadDB.ReadOnly()
+
+	selection, err := s.prepareExportSelection(job)
+	if err != nil {
+		job.markFailed(fmt.Errorf("failed to prepare export selection: %w", err))
+		s.logExportError(job, err, "selection")
+		return
+	}
 
 	client := &http.Client{Timeout: job.Timeout}
 	baseURL := job.TargetURL
@@ -431,9 +683,10 @@ func (s *Server) runExportJob(job *ExportJob, upload *database.Upload) {
 	}
 
 	if job.Options.IncludeConstants {
-		err = uploadDB.StreamConstantsByUpload(upload.ID, job.Options.BatchSize, func(batch []*database.Constant) error {
+		err = uploadDB.StreamConstantsByUploadContext(ctx, upload.ID, job.Options.BatchSize, func(batch []*database.Constant) error {
 			sent := 0
 			for _, constant := range batch {
+				job.redaction.RedactConstant(constant)
 				if err := s.sendExportConstant(client, baseURL, remoteUUID, constant); err != nil {
 					return err
 				}
@@ -478,7 +731,14 @@ func (s *Server) runExportJob(job *ExportJob, upload *database.Upload) {
 		}
 		job.addCatalogs(metaSent)
 
-		err = uploadDB.StreamCatalogItems(upload.ID, job.Options.CatalogNames, job.Options.BatchSize, func(items []*database.CatalogItem) error {
+		err = uploadDB.StreamCatalogItemsContext(ctx, upload.ID, job.Options.CatalogNames, job.Options.BatchSize, func(items []*database.CatalogItem) error {
+			// Зашифрованные реквизиты передаются расшифрованными, если создатель задачи вправе их расшифровать
+			items, skipped := selection.filterCatalogItems(items)
+			job.addSkipped(skipped)
+			if err := s.openCatalogItems(items, job.decrypt); err != nil {
+				return err
+			}
+			job.redaction.RedactCatalogItems(items)
 			sent := 0
 			for _, item := range items {
 				if err := s.sendExportCatalogItem(client, baseURL, remoteUUID, item); err != nil {
@@ -496,11 +756,25 @@ func (s *Server) runExportJob(job *ExportJob, upload *database.Upload) {
 		}
 	}
 
+	if job.Options.IncludeAttachments {
+		if err := s.sendExportAttachments(ctx, job, client, baseURL, remoteUUID, uploadDB, upload); err != nil {
+			job.markFailed(err)
+			s.logExportError(job, err, "attachments")
+			return
+		}
+	}
+
 	if job.Options.IncludeNomenclature {
-		err = uploadDB.StreamNomenclatureItems(upload.ID, job.Options.BatchSize, func(items []*database.NomenclatureItem) error {
+		err = uploadDB.StreamNomenclatureItemsContext(ctx, upload.ID, job.Options.BatchSize, func(items []*database.NomenclatureItem) error {
+			items, skipped := selection.filterNomenclatureItems(items)
+			job.addSkipped(skipped)
 			if len(items) == 0 {
 				return nil
 			}
+			if err := s.openNomenclatureItems(items, job.decrypt); err != nil {
+				return err
+			}
+			job.redaction.RedactNomenclatureItems(items)
 			if err := s.sendExportNomenclatureBatch(client, baseURL, remoteUUID, items); err != nil {
 				return err
 			}
@@ -514,15 +788,24 @@ func (s *Server) runExportJob(job *ExportJob, upload *database.Upload) {
 		}
 	}
 
+	if job.Options.IncludeDocuments {
+		if err := s.sendExportDocuments(ctx, job, client, baseURL, remoteUUID, uploadDB, upload); err != nil {
+			job.markFailed(err)
+			s.logExportError(job, err, "documents")
+			return
+		}
+	}
+
 	if err := s.sendExportComplete(client, baseURL, remoteUUID); err != nil {
 		job.markFailed(err)
 		s.logExportError(job, err, "complete")
 		return
 	}
 	job.markCompleteDispatched()
+	s.commitExportSelection(job, selection)
 	job.markCompleted()
 
-	s.log(LogEntry{
+	s.logCtx(ctx, LogEntry{
 		Timestamp: time.Now(),
 		Level:     "INFO",
 		Message:   fmt.Sprintf("Export job %s finished", job.ID),
@@ -563,6 +846,8 @@ func normalizeExportOptions(req ExportRequest) (ExportOptions, error) {
 		IncludeConstants:    true,
 		IncludeCatalogs:     true,
 		IncludeNomenclature: true,
+		IncludeAttachments:  true,
+		IncludeDocuments:    true,
 		BatchSize:           defaultExportBatchSize,
 	}
 
@@ -571,6 +856,8 @@ func normalizeExportOptions(req ExportRequest) (ExportOptions, error) {
 		opts.IncludeConstants = false
 		opts.IncludeCatalogs = false
 		opts.IncludeNomenclature = false
+		opts.IncludeAttachments = false
+		opts.IncludeDocuments = false
 
 		for _, value := range req.Include {
 			switch strings.ToLower(strings.TrimSpace(value)) {
@@ -582,6 +869,12 @@ func normalizeExportOptions(req ExportRequest) (ExportOptions, error) {
 				opts.IncludeCatalogs = true
 			case "nomenclature":
 				opts.IncludeNomenclature = true
+			case "normalized":
+				opts.IncludeNormalized = true
+			case "attachments":
+				opts.IncludeAttachments = true
+			case "documents":
+				opts.IncludeDocuments = true
 			case "":
 				continue
 			default:
@@ -617,47 +910,43 @@ func normalizeExportOptions(req ExportRequest) (ExportOptions, error) {
 		opts.CatalogNames = names
 	}
 
-	return opts, nil
-}
-
-func (s *Server) getExportJobsByUpload(uploadUUID string) []ExportJobView {
-	s.exportJobsMutex.RLock()
-	defer s.exportJobsMutex.RUnlock()
-
-	views := make([]ExportJobView, 0)
-	for _, job := range s.exportJobs {
-		if job.UploadUUID == uploadUUID {
-			views = append(views, job.snapshot())
+	fields, err := normalizeExportFields(req.Fields)
+	if err != nil {
+		return opts, err
+	}
+	opts.Fields = fields
+	if req.Filter.active() {
+		filter := *req.Filter
+		// Классифицированные элементы всегда нормализованы
+		if filter.OnlyClassified {
+			filter.OnlyNormalized = true
 		}
+		opts.Filter = &filter
 	}
 
-	sort.Slice(views, func(i, j int) bool {
-		return views[i].CreatedAt.After(views[j].CreatedAt)
-	})
+	return opts, nil
+}
 
-	return views
+func (s *Server) getExportJobsByUpload(uploadUUID string) []ExportJobView {
+	return s.exportJobViews(uploadUUID)
 }
 
 func (s *Server) getAllExportJobs() []ExportJobView {
-	s.exportJobsMutex.RLock()
-	defer s.exportJobsMutex.RUnlock()
-
-	views := make([]ExportJobView, 0, len(s.exportJobs))
-	for _, job := range s.exportJobs {
-		views = append(views, job.snapshot())
-	}
-
-	sort.Slice(views, func(i, j int) bool {
-		return views[i].CreatedAt.After(views[j].CreatedAt)
-	})
-
-	return views
+	return s.exportJobViews("")
 }
 
+// getExportJob возвращает задачу экземпляра или восстанавливает ее из service.db
 func (s *Server) getExportJob(id string) *ExportJob {
 	s.exportJobsMutex.RLock()
-	defer s.exportJobsMutex.RUnlock()
-	return s.exportJobs[id]
+	job := s.exportJobs[id]
+	s.exportJobsMutex.RUnlock()
+	if job == nil {
+		return s.loadExportJob(id)
+	}
+	if job.sharedAcrossInstances() {
+		s.refreshSharedExportJob(job)
+	}
+	return job
 }
 
 // --- Remote calls ---
@@ -675,6 +964,7 @@ func (s *Server) performExportHandshake(client *http.Client, baseURL string, upl
 		IterationLabel: upload.IterationLabel,
 		ProgrammerName: upload.ProgrammerName,
 		UploadPurpose:  upload.UploadPurpose,
+		ProtocolVersion: fmt.Sprintf("%d", ProtocolVersionCurrent),
 	}
 	if upload.DatabaseID != nil {
 		req.DatabaseID = fmt.Sprintf("%d", *upload.DatabaseID)
@@ -744,6 +1034,47 @@ func (s *Server) sendExportCatalogItem(client *http.Client, baseURL, remoteUUID
 	return s.expectXMLSuccess(client, baseURL+"/catalog/item", req)
 }
 
+// sendExportAttachments передает вложения элементов выгруженных справочников по одному запросу /catalog/attachment
+func (s *Server) sendExportAttachments(ctx context.Context, job *ExportJob, client *http.Client, baseURL, remoteUUID string, uploadDB *database.DB, upload *database.Upload) error {
+	attachments, err := uploadDB.GetUploadAttachments(upload.ID, "", "")
+	if err != nil {
+		return err
+	}
+	catalogFilter := make(map[string]struct{})
+	for _, name := range job.Options.CatalogNames {
+		catalogFilter[name] = struct{}{}
+	}
+	for _, attachment := range attachments {
+		if len(catalogFilter) > 0 {
+			if _, ok := catalogFilter[attachment.CatalogName]; !ok {
+				continue
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		content, err := s.readAttachmentContent(ctx, attachment)
+		if err != nil {
+			return err
+		}
+		req := CatalogAttachmentRequest{
+			UploadUUID:  remoteUUID,
+			CatalogName: attachment.CatalogName,
+			Reference:   attachment.Reference,
+			FileName:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			Description: attachment.Description,
+			Content:     base64.StdEncoding.EncodeToString(content),
+			Timestamp:   attachment.CreatedAt.Format(time.RFC3339),
+		}
+		if err := s.expectXMLSuccess(client, baseURL+"/catalog/attachment", req); err != nil {
+			return err
+		}
+		job.addAttachments(1)
+	}
+	return nil
+}
+
 func (s *Server) sendExportNomenclatureBatch(client *http.Client, baseURL, remoteUUID string, items []*database.NomenclatureItem) error {
 	payload := nomenclatureBatchExport{
 		UploadUUID: remoteUUID,
@@ -764,6 +1095,65 @@ func (s *Server) sendExportNomenclatureBatch(client *http.Client, baseURL, remot
 	return s.expectXMLSuccess(client, baseURL+"/nomenclature/batch", payload)
 }
 
+// sendExportDocuments передает документы выгрузки пакетами /document/items; документы пакета хранилища
+// разбиваются на запросы по виду документа
+func (s *Server) sendExportDocuments(ctx context.Context, job *ExportJob, client *http.Client, baseURL, remoteUUID string, uploadDB *database.DB, upload *database.Upload) error {
+	types, err := uploadDB.GetUploadDocumentTypes(upload.ID)
+	if err != nil {
+		return err
+	}
+	synonyms := make(map[string]string, len(types))
+	for _, t := range types {
+		synonyms[t.Name] = t.Synonym
+	}
+
+	return uploadDB.StreamUploadDocumentsContext(ctx, upload.ID, job.Options.BatchSize, func(documents []*database.UploadDocument) error {
+		var req *DocumentItemsRequest
+		flush := func() error {
+			if req == nil {
+				return nil
+			}
+			if err := s.expectXMLSuccess(client, baseURL+"/document/items", req); err != nil {
+				return err
+			}
+			job.addDocuments(len(req.Items))
+			req = nil
+			return nil
+		}
+		// Зашифрованные реквизиты передаются расшифрованными, если создатель задачи вправе их расшифровать,
+		// скрытые профилем поля - не передаются
+		for _, doc := range documents {
+			if doc.AttributesXML, err = s.exportValue(doc.AttributesXML, job.decrypt); err != nil {
+				return err
+			}
+			if doc.TablePartsXML, err = s.exportValue(doc.TablePartsXML, job.decrypt); err != nil {
+				return err
+			}
+		}
+		job.redaction.RedactUploadDocuments(documents)
+		for _, doc := range documents {
+			if req != nil && req.DocumentType != doc.DocumentType {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if req == nil {
+				req = &DocumentItemsRequest{UploadUUID: remoteUUID, DocumentType: doc.DocumentType, Synonym: synonyms[doc.DocumentType]}
+			}
+			req.Items = append(req.Items, DocumentItem{
+				Reference:  doc.Reference,
+				Number:     doc.Number,
+				Date:       doc.Date,
+				Posted:     doc.Posted,
+				Attributes: XMLContent{Content: doc.AttributesXML},
+				TableParts: XMLContent{Content: doc.TablePartsXML},
+				Timestamp:  time.Now().Format(time.RFC3339),
+			})
+		}
+		return flush()
+	})
+}
+
 func (s *Server) sendExportComplete(client *http.Client, baseURL, remoteUUID string) error {
 	req := completeExport{
 		UploadUUID: remoteUUID,
@@ -43,7 +43,7 @@ func TestNormalizeExportOptions(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if !opts.IncludeMetadata || !opts.IncludeConstants || !opts.IncludeCatalogs || !opts.IncludeNomenclature {
+		if !opts.IncludeMetadata || !opts.IncludeConstants || !opts.IncludeCatalogs || !opts.IncludeNomenclature || !opts.IncludeAttachments {
 			t.Fatalf("expected all includes enabled by default, got %+v", opts)
 		}
 		if opts.BatchSize != defaultExportBatchSize {
@@ -64,8 +64,8 @@ func TestNormalizeExportOptions(t *testing.T) {
 		if !opts.IncludeMetadata || !opts.IncludeCatalogs {
 			t.Fatalf("expected metadata & catalogs enabled")
 		}
-		if opts.IncludeConstants || opts.IncludeNomenclature {
-			t.Fatalf("constants/nomenclature should be disabled")
+		if opts.IncludeConstants || opts.IncludeNomenclature || opts.IncludeAttachments {
+			t.Fatalf("constants/nomenclature/attachments should be disabled")
 		}
 		expectedNames := []string{"Номенклатура", "Контрагенты"}
 		if !reflect.DeepEqual(opts.CatalogNames, expectedNames) {
@@ -100,6 +100,38 @@ func TestNormalizeExportOptions(t *testing.T) {
 			t.Fatalf("expected default batch size, got %d", opts.BatchSize)
 		}
 	})
+
+	t.Run("fields_and_filter", func(t *testing.T) {
+		req := ExportRequest{
+			Fields: []string{" Attributes ", "attributes", ""},
+			Filter: &ExportFilter{OnlyClassified: true},
+		}
+		opts, err := normalizeExportOptions(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(opts.Fields, []string{"attributes"}) {
+			t.Fatalf("unexpected fields: %+v", opts.Fields)
+		}
+		if opts.Filter == nil || !opts.Filter.OnlyClassified || !opts.Filter.OnlyNormalized {
+			t.Fatalf("expected classified filter to imply normalized, got %+v", opts.Filter)
+		}
+
+		opts, err = normalizeExportOptions(ExportRequest{Filter: &ExportFilter{}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.Filter != nil {
+			t.Fatalf("expected empty filter to be dropped, got %+v", opts.Filter)
+		}
+	})
+
+	t.Run("invalid_field_set", func(t *testing.T) {
+		_, err := normalizeExportOptions(ExportRequest{Fields: []string{"owner"}})
+		if err == nil {
+			t.Fatalf("expected error for unknown field set")
+		}
+	})
 }
 
 
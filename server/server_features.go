@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"os"
+
+	"httpserver/features"
+)
+
+// RuntimeFeatures подсистемы, фактически работающие в запущенном сервере: включенные при сборке
+// и настроенные конфигурацией
+type RuntimeFeatures struct {
+	AI               bool   `json:"ai"`                 // Сборка с AI и задан ARLIAI_API_KEY
+	KpvedClassifier  bool   `json:"kpved_classifier"`   // Иерархический классификатор КПВЭД загружен
+	Tracing          bool   `json:"tracing"`            // Спаны отправляются в коллектор OpenTelemetry
+	IngestQueue      string `json:"ingest_queue"`       // Backend очереди приема выгрузок, пустой - отключена
+	Storage          string `json:"storage"`            // Backend хранилища артефактов
+	SlowRequestLog   bool   `json:"slow_request_log"`   // Журнал медленных запросов включен
+	RestartOnCrashes bool   `json:"restart_on_crashes"` // Воркеры перезапускаются после паники
+	Demo             bool   `json:"demo"`               // Демо-режим: синтетические данные и фейковый AI провайдер
+	Encryption       bool   `json:"encryption"`         // Заданы мастер-ключи шифрования данных проектов
+}
+
+// runtimeFeatures собирает состояние подсистем сервера
+func (s *Server) runtimeFeatures() RuntimeFeatures {
+	s.kpvedClassifierMutex.RLock()
+	kpvedLoaded := s.hierarchicalClassifier != nil
+	s.kpvedClassifierMutex.RUnlock()
+
+	return RuntimeFeatures{
+		AI:               features.AI && os.Getenv("ARLIAI_API_KEY") != "",
+		KpvedClassifier:  kpvedLoaded,
+		Tracing:          s.tracer != nil,
+		IngestQueue:      s.currentConfig().IngestQueue.Backend,
+		Storage:          s.currentConfig().Storage.Backend,
+		SlowRequestLog:   s.currentConfig().SlowRequestThreshold > 0,
+		RestartOnCrashes: s.currentConfig().RestartCrashedWorkers,
+		Demo:             s.currentConfig().DemoMode,
+		Encryption:       s.encryptionKeyring != nil,
+	}
+}
+
+// handleConfig GET /api/config - профиль сборки и включенные подсистемы сервера
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"build":    features.Current(),
+		"features": s.runtimeFeatures(),
+		"port":     s.currentConfig().Port,
+	}, http.StatusOK)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"httpserver/features"
+)
+
+func TestHandleConfig(t *testing.T) {
+	s := newCrashTestServer(t)
+	s.config.SlowRequestThreshold = 1
+
+	tests := []struct {
+		name   string
+		method string
+		want   int
+	}{
+		{"get", http.MethodGet, http.StatusOK},
+		{"post", http.MethodPost, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleConfig(rec, httptest.NewRequest(tt.method, "/api/config", nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Build    features.Flags  `json:"build"`
+				Features RuntimeFeatures `json:"features"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Build.Profile != features.Profile() || resp.Build.AI != features.AI || resp.Build.GUI != features.GUI {
+				t.Errorf("build = %+v, want current build flags", resp.Build)
+			}
+			if !resp.Features.SlowRequestLog || resp.Features.Tracing || resp.Features.KpvedClassifier {
+				t.Errorf("features = %+v", resp.Features)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+// Типы прогонов классификации для учета применения подтвержденных решений
+const (
+	historicalRunNormalization = "normalization"
+	historicalRunReclassify    = "reclassify"
+)
+
+// historicalIndex строит индекс подтвержденных классификаций из БД с normalized_data.
+// Возвращает nil, если классификация по подтвержденным решениям отключена или решений нет
+func (s *Server) historicalIndex(db *database.DB) *normalization.HistoricalIndex {
+	if db == nil || (s.currentConfig() != nil && !s.currentConfig().HistoricalClassificationEnabled) {
+		return nil
+	}
+
+	verified, err := db.GetVerifiedClassifications()
+	if err != nil {
+		log.Printf("[KPVED] Не удалось загрузить подтвержденные классификации: %v", err)
+		return nil
+	}
+	if len(verified) == 0 {
+		return nil
+	}
+
+	decisions := make([]normalization.HistoricalDecision, 0, len(verified))
+	for _, v := range verified {
+		decisions = append(decisions, normalization.HistoricalDecision{
+			NormalizedName: v.NormalizedName,
+			KpvedCode:      v.KpvedCode,
+			KpvedName:      v.KpvedName,
+		})
+	}
+
+	minSimilarity := normalization.DefaultHistoricalMinSimilarity
+	if s.currentConfig() != nil {
+		minSimilarity = s.currentConfig().HistoricalMinSimilarity
+	}
+	index := normalization.NewHistoricalIndex(decisions, minSimilarity)
+	log.Printf("[KPVED] Индекс подтвержденных классификаций: %d наименований из %d решений", index.Size(), len(verified))
+	return index
+}
+
+// recordHistoricalRun сохраняет учет применения подтвержденных решений за прогон
+func (s *Server) recordHistoricalRun(runType string, startedAt time.Time, items int, chain *normalization.ChainedClassifier) {
+	usage := chain.HistoricalUsage()
+	if usage.Hits > 0 {
+		log.Printf("[KPVED] По подтвержденным решениям классифицировано %d элементов, сэкономлено AI вызовов: ~%d",
+			usage.Hits, usage.SavedAICalls)
+	}
+	if s.db == nil {
+		return
+	}
+
+	run := &database.HistoricalClassificationRun{
+		RunType:      runType,
+		StartedAt:    startedAt,
+		FinishedAt:   time.Now(),
+		Items:        items,
+		IndexSize:    usage.IndexSize,
+		Hits:         usage.Hits,
+		Misses:       usage.Misses,
+		AIClassified: usage.AIClassified,
+		AICalls:      usage.AICalls,
+		SavedAICalls: usage.SavedAICalls,
+	}
+	if err := s.db.SaveHistoricalClassificationRun(run); err != nil {
+		log.Printf("[KPVED] Не удалось сохранить учет прогона классификации: %v", err)
+	}
+}
+
+// handleHistoricalClassificationReport возвращает отчет о сэкономленных AI вызовах по прогонам
+// GET /api/kpved/historical/report?limit=50
+func (s *Server) handleHistoricalClassificationReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			s.writeJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := s.db.GetHistoricalClassificationRuns(limit)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get classification runs: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	totals := map[string]int{"items": 0, "hits": 0, "ai_calls": 0, "saved_ai_calls": 0}
+	for _, run := range runs {
+		totals["items"] += run.Items
+		totals["hits"] += run.Hits
+		totals["ai_calls"] += run.AICalls
+		totals["saved_ai_calls"] += run.SavedAICalls
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"enabled":    s.currentConfig() == nil || s.currentConfig().HistoricalClassificationEnabled,
+		"runs":       runs,
+		"total_runs": len(runs),
+		"totals":     totals,
+	}, http.StatusOK)
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/i18n"
+	"httpserver/server/middleware"
+)
+
+// languageMiddleware выбирает язык ответа: параметр lang, затем язык клиента (client_id), затем Accept-Language.
+// Если язык не выбран явно, ответы остаются на исходном языке сообщений.
+func (s *Server) languageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := s.resolveRequestLanguage(r)
+		if lang == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(middleware.WithLanguage(w, lang), r.WithContext(i18n.WithLanguage(r.Context(), lang)))
+	})
+}
+
+// resolveRequestLanguage определяет язык запроса; пустая строка, если язык не указан
+func (s *Server) resolveRequestLanguage(r *http.Request) string {
+	query := r.URL.Query()
+	if lang := i18n.Normalize(query.Get("lang")); lang != "" {
+		return lang
+	}
+	if clientID, err := strconv.Atoi(query.Get("client_id")); err == nil && clientID > 0 {
+		if lang := s.clientLanguage(clientID); lang != "" {
+			return lang
+		}
+	}
+	return i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// clientLanguage возвращает язык, настроенный для клиента, или пустую строку
+func (s *Server) clientLanguage(clientID int) string {
+	if s.serviceDB == nil {
+		return ""
+	}
+	lang, err := s.serviceDB.GetClientLanguage(clientID)
+	if err != nil {
+		return ""
+	}
+	return i18n.Normalize(lang)
+}
+
+// requestLanguage возвращает язык запроса или язык по умолчанию
+func requestLanguage(r *http.Request) string {
+	return i18n.FromContext(r.Context())
+}
+
+// handleLanguages возвращает список поддерживаемых языков
+// GET /api/i18n/languages
+func (s *Server) handleLanguages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"languages": i18n.Languages(),
+		"default":   i18n.Default,
+		"current":   requestLanguage(r),
+	}, http.StatusOK)
+}
+
+// ClientLanguageRequest запрос на изменение языка клиента
+type ClientLanguageRequest struct {
+	Language string `json:"language"`
+	// Второй язык наименований категорий в отчетах; nil - не изменять, пустая строка - отчеты на одном языке
+	ReportSecondLanguage *string `json:"report_second_language,omitempty"`
+}
+
+// handleClientLanguage возвращает или изменяет язык клиента и второй язык его отчетов
+// GET/PUT /api/clients/{id}/language
+func (s *Server) handleClientLanguage(w http.ResponseWriter, r *http.Request, clientID int) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if _, err := s.serviceDB.GetClient(clientID); err != nil {
+		s.writeJSONError(w, "Client not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		lang, err := s.serviceDB.GetClientLanguage(clientID)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get client language: %v", err), http.StatusInternalServerError)
+			return
+		}
+		effective := i18n.Normalize(lang)
+		if effective == "" {
+			effective = i18n.Default
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"client_id":              clientID,
+			"language":               lang,
+			"effective":              effective,
+			"report_second_language": s.clientReportSecondLanguage(clientID),
+		}, http.StatusOK)
+
+	case http.MethodPut:
+		var req ClientLanguageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		lang := ""
+		if req.Language != "" {
+			lang = i18n.Normalize(req.Language)
+			if lang == "" {
+				s.writeJSONError(w, fmt.Sprintf("Unsupported language: %s", req.Language), http.StatusBadRequest)
+				return
+			}
+		}
+		secondLanguage := ""
+		if req.ReportSecondLanguage != nil && *req.ReportSecondLanguage != "" {
+			secondLanguage = i18n.Normalize(*req.ReportSecondLanguage)
+			if secondLanguage == "" {
+				s.writeJSONError(w, fmt.Sprintf("Unsupported language: %s", *req.ReportSecondLanguage), http.StatusBadRequest)
+				return
+			}
+		}
+		if err := s.serviceDB.SetClientLanguage(clientID, lang); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to set client language: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if req.ReportSecondLanguage != nil {
+			if err := s.serviceDB.SetClientReportSecondLanguage(clientID, secondLanguage); err != nil {
+				s.writeJSONError(w, fmt.Sprintf("Failed to set client report language: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Язык клиента %d изменен на '%s'", clientID, lang),
+			Endpoint:  r.URL.Path,
+		})
+
+		effective := lang
+		if effective == "" {
+			effective = i18n.Default
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"client_id":              clientID,
+			"language":               lang,
+			"effective":              effective,
+			"report_second_language": s.clientReportSecondLanguage(clientID),
+		}, http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestLanguageMiddleware(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+
+	s := &Server{serviceDB: serviceDB, logChan: make(chan LogEntry, 10)}
+	clientPath := fmt.Sprintf("/api/clients/%d/language", client.ID)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleClientRoutes(rec, httptest.NewRequest(http.MethodPut, clientPath, strings.NewReader(body)))
+		return rec
+	}
+	if rec := put(`{"language":"de"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("PUT unsupported language status = %d, want 400", rec.Code)
+	}
+	rec := put(`{"language":"kk-KZ"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT language status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]interface{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp["language"] != "kk" {
+		t.Fatalf("language = %v, want kk", resp["language"])
+	}
+
+	handler := s.languageMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.writeJSONError(w, "Project not found", http.StatusNotFound)
+	}))
+
+	tests := []struct {
+		name           string
+		target         string
+		acceptLanguage string
+		wantLanguage   string
+		wantError      string
+	}{
+		{"default", "/api/test", "", "", "Project not found"},
+		{"accept language", "/api/test", "ru-RU,ru;q=0.9", "ru", "Проект не найден"},
+		{"query overrides header", "/api/test?lang=en", "ru", "en", "Project not found"},
+		{"client setting", fmt.Sprintf("/api/test?client_id=%d", client.ID), "ru", "kk", "Жоба табылмады"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			json.NewDecoder(rec.Body).Decode(&body)
+			if body.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantError)
+			}
+		})
+	}
+}
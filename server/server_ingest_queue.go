@@ -0,0 +1,248 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"httpserver/database"
+	"httpserver/queue"
+	"httpserver/tracing"
+)
+
+// IngestEnvelope сообщение очереди приема выгрузок: XML тело запроса протокола выгрузки
+// и адрес эндпоинта, которому оно предназначено. Пара (upload_uuid, sequence)
+// уникальна в пределах выгрузки и используется для отбрасывания повторных доставок.
+type IngestEnvelope struct {
+	UploadUUID string `json:"upload_uuid"`
+	Sequence   int64  `json:"sequence"`
+	Endpoint   string `json:"endpoint"` // handshake, metadata, constant, catalog/meta, catalog/item, catalog/items, document/item, document/items, nomenclature/batch, complete
+	Payload    string `json:"payload"`
+}
+
+// ingestClaimTimeout время, после которого захват сообщения без итога обработки считается брошенным
+// (сбой сервера во время обработки) и сообщение обрабатывается при следующей доставке
+const ingestClaimTimeout = 10 * time.Minute
+
+// ingestQueueStats счетчики обработки сообщений очереди
+type ingestQueueStats struct {
+	processed  atomic.Int64
+	duplicates atomic.Int64
+	failed     atomic.Int64
+	retried    atomic.Int64
+}
+
+// ingestQueueHandlers обработчики протокола выгрузки, доступные через очередь
+func (s *Server) ingestQueueHandlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"handshake":          s.handleHandshake,
+		"metadata":           s.handleMetadata,
+		"constant":           s.handleConstant,
+		"catalog/meta":       s.handleCatalogMeta,
+		"catalog/item":       s.handleCatalogItem,
+		"catalog/items":      s.handleCatalogItems,
+		"document/item":      s.handleDocumentItem,
+		"document/items":     s.handleDocumentItems,
+		"nomenclature/batch": s.handleNomenclatureBatch,
+		"complete":           s.handleComplete,
+	}
+}
+
+// runIngestQueueLoop подключается к очереди и обрабатывает сообщения до остановки сервера
+func (s *Server) runIngestQueueLoop() {
+	consumer := s.ingestQueue
+	if consumer == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.shutdownChan
+		cancel()
+	}()
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Ingest queue consumer started (%s, subject %s)", consumer.Backend(), s.currentConfig().IngestQueue.Subject),
+	})
+	if err := consumer.Run(ctx, s.handleIngestQueueMessage); err != nil {
+		log.Printf("Ошибка очереди приема выгрузок: %v", err)
+	}
+}
+
+// handleIngestQueueMessage передает сообщение очереди тому же обработчику, что и HTTP запрос.
+// Перед обработкой сообщение захватывается в журнале по (upload_uuid, sequence), поэтому параллельная
+// повторная доставка не выполняет обработчик второй раз; уже обработанные сообщения отбрасываются.
+func (s *Server) handleIngestQueueMessage(ctx context.Context, msg *queue.Message) error {
+	ctx, span := tracing.StartKind(ctx, "ingest_queue "+msg.Subject, tracing.SpanKindServer,
+		tracing.Attr("messaging.destination", msg.Subject),
+		tracing.Attr("messaging.redelivery", msg.Redelivery),
+	)
+	defer span.End()
+	err := s.processIngestQueueMessage(ctx, msg)
+	span.RecordError(err)
+	return err
+}
+
+// processIngestQueueMessage обрабатывает сообщение очереди в рамках спана handleIngestQueueMessage
+func (s *Server) processIngestQueueMessage(ctx context.Context, msg *queue.Message) error {
+	var envelope IngestEnvelope
+	if err := json.Unmarshal(msg.Data, &envelope); err != nil {
+		s.ingestQueueStats.failed.Add(1)
+		return queue.Permanent(fmt.Errorf("invalid ingest envelope: %w", err))
+	}
+	handler, ok := s.ingestQueueHandlers()[envelope.Endpoint]
+	if err := validateIngestEnvelope(&envelope); err != nil || !ok {
+		if err == nil {
+			err = fmt.Errorf("unsupported endpoint: %s", envelope.Endpoint)
+		}
+		s.ingestQueueStats.failed.Add(1)
+		return queue.Permanent(err)
+	}
+	if s.serviceDB == nil {
+		return errors.New("service database is not available")
+	}
+
+	existing, err := s.serviceDB.ClaimIngestQueueMessage(envelope.UploadUUID, envelope.Sequence, envelope.Endpoint, ingestClaimTimeout)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Status == database.IngestMessageStatusProcessing {
+		// Сообщение обрабатывается другой доставкой: итог станет известен при следующей доставке
+		s.ingestQueueStats.retried.Add(1)
+		return fmt.Errorf("%s #%d of upload %s is being processed", envelope.Endpoint, envelope.Sequence, envelope.UploadUUID)
+	}
+	if existing != nil {
+		s.ingestQueueStats.duplicates.Add(1)
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/"+envelope.Endpoint, strings.NewReader(envelope.Payload))
+	if err != nil {
+		s.releaseIngestClaim(&envelope)
+		return queue.Permanent(err)
+	}
+	rec := &ingestResponseRecorder{header: http.Header{}, status: http.StatusOK}
+	handler(rec, req)
+
+	record := &database.IngestQueueMessage{
+		UploadUUID: envelope.UploadUUID,
+		Sequence:   envelope.Sequence,
+		Endpoint:   envelope.Endpoint,
+		Status:     database.IngestMessageStatusProcessed,
+	}
+	// Повторное рукопожатие после сбоя между обработкой и записью в журнал - выгрузка уже создана
+	alreadyCreated := envelope.Endpoint == "handshake" && rec.status == http.StatusConflict
+	switch {
+	case rec.status < http.StatusMultipleChoices || alreadyCreated:
+		s.ingestQueueStats.processed.Add(1)
+	case rec.status < http.StatusInternalServerError && rec.status != http.StatusTooManyRequests && rec.status != http.StatusNotFound:
+		record.Status = database.IngestMessageStatusFailed
+		record.Error = rec.errorMessage()
+		s.ingestQueueStats.failed.Add(1)
+	default:
+		// Ошибки сервера, превышение ограничения скорости клиента и пакеты выгрузки, рукопожатие
+		// которой еще не обработано, - сообщение будет доставлено повторно
+		s.ingestQueueStats.retried.Add(1)
+		s.releaseIngestClaim(&envelope)
+		return fmt.Errorf("%s #%d of upload %s failed: %s", envelope.Endpoint, envelope.Sequence, envelope.UploadUUID, rec.errorMessage())
+	}
+
+	if err := s.serviceDB.SaveIngestQueueMessage(record); err != nil {
+		return err
+	}
+	if record.Status == database.IngestMessageStatusFailed {
+		return queue.Permanent(fmt.Errorf("%s #%d of upload %s rejected: %s", envelope.Endpoint, envelope.Sequence, envelope.UploadUUID, record.Error))
+	}
+	return nil
+}
+
+// releaseIngestClaim снимает захват сообщения, которое будет обработано при повторной доставке
+func (s *Server) releaseIngestClaim(envelope *IngestEnvelope) {
+	if err := s.serviceDB.ReleaseIngestQueueMessage(envelope.UploadUUID, envelope.Sequence); err != nil {
+		log.Printf("Не удалось снять захват сообщения %s #%d выгрузки %s: %v", envelope.Endpoint, envelope.Sequence, envelope.UploadUUID, err)
+	}
+}
+
+// validateIngestEnvelope проверяет обязательные поля сообщения. Рукопожатие должно
+// задавать тот же upload_uuid, иначе последующие пакеты не найдут выгрузку.
+func validateIngestEnvelope(envelope *IngestEnvelope) error {
+	if envelope.UploadUUID == "" {
+		return errors.New("upload_uuid is required")
+	}
+	if envelope.Sequence <= 0 {
+		return errors.New("sequence must be positive")
+	}
+	if envelope.Endpoint == "handshake" {
+		var handshake HandshakeRequest
+		if err := xml.Unmarshal([]byte(envelope.Payload), &handshake); err != nil {
+			return fmt.Errorf("invalid handshake payload: %w", err)
+		}
+		if !strings.EqualFold(handshake.UploadUUID, envelope.UploadUUID) {
+			return errors.New("handshake payload must set the same upload_uuid as the envelope")
+		}
+	}
+	return nil
+}
+
+// ingestResponseRecorder принимает ответ обработчика протокола выгрузки при обработке сообщения очереди
+type ingestResponseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *ingestResponseRecorder) Header() http.Header         { return r.header }
+func (r *ingestResponseRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *ingestResponseRecorder) WriteHeader(status int)      { r.status = status }
+
+// errorMessage извлекает текст ошибки из XML ответа обработчика
+func (r *ingestResponseRecorder) errorMessage() string {
+	var response ErrorResponse
+	if err := xml.Unmarshal(r.body.Bytes(), &response); err == nil && (response.Message != "" || response.Error != "") {
+		return strings.TrimSuffix(response.Message+": "+response.Error, ": ")
+	}
+	message := strings.TrimSpace(r.body.String())
+	if len(message) > 200 {
+		message = message[:200]
+	}
+	return fmt.Sprintf("HTTP %d %s", r.status, message)
+}
+
+// handleIngestQueueStatus состояние приема из очереди и журнал сообщений выгрузки
+// GET /api/ingest/queue[?upload_uuid=...]
+func (s *Server) handleIngestQueueStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := map[string]interface{}{
+		"enabled":    s.ingestQueue != nil,
+		"backend":    s.currentConfig().IngestQueue.Backend,
+		"subject":    s.currentConfig().IngestQueue.Subject,
+		"connected":  s.ingestQueue != nil && s.ingestQueue.Connected(),
+		"processed":  s.ingestQueueStats.processed.Load(),
+		"duplicates": s.ingestQueueStats.duplicates.Load(),
+		"failed":     s.ingestQueueStats.failed.Load(),
+		"retried":    s.ingestQueueStats.retried.Load(),
+	}
+	if uploadUUID := r.URL.Query().Get("upload_uuid"); uploadUUID != "" && s.serviceDB != nil {
+		messages, err := s.serviceDB.GetIngestQueueMessages(uploadUUID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status["messages"] = messages
+	}
+	s.writeJSONResponse(w, status, http.StatusOK)
+}
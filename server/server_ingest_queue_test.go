@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/queue"
+)
+
+func TestIngestQueueMessages(t *testing.T) {
+	// Завершение выгрузки запускает фоновые задачи, которые могут писать в каталог после теста
+	dir, err := os.MkdirTemp("", "ingest-queue-")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := database.NewDB(filepath.Join(dir, "data.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	serviceDB, err := database.NewServiceDB(filepath.Join(dir, "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer serviceDB.Close()
+	unifiedDB, err := database.NewUnifiedDBWithConfig(filepath.Join(dir, "unified_catalogs.db"), database.DBConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create unified DB: %v", err)
+	}
+	defer unifiedDB.Close()
+
+	config := &Config{Port: "0", UnifiedCatalogsDBPath: filepath.Join(dir, "unified_catalogs.db"), LogBufferSize: 100, NormalizerEventsBufferSize: 10}
+	s := NewServerWithConfig(db, db, serviceDB, unifiedDB, "", "", config)
+
+	const uploadUUID = "0f8fad5b-d9cb-469f-a165-70867728950e"
+	uuid := "<upload_uuid>" + uploadUUID + "</upload_uuid>"
+	message := func(upload string, sequence int64, endpoint, payload string) *queue.Message {
+		data, _ := json.Marshal(IngestEnvelope{UploadUUID: upload, Sequence: sequence, Endpoint: endpoint, Payload: payload})
+		return &queue.Message{Subject: "httpserver.ingest", Data: data}
+	}
+	handshake := message(uploadUUID, 1, "handshake", `<handshake>`+uuid+`<version_1c>8.3.24</version_1c><config_name>УТ</config_name></handshake>`)
+	items := func(sequence int64, names ...string) *queue.Message {
+		payload := `<catalog_items>` + uuid + `<catalog_name>Номенклатура</catalog_name><items>`
+		for i, name := range names {
+			payload += fmt.Sprintf(`<item><reference>ref-%d-%d</reference><code>%d</code><name>%s</name></item>`, sequence, i, i, name)
+		}
+		return message(uploadUUID, sequence, "catalog/items", payload+`</items></catalog_items>`)
+	}
+
+	steps := []struct {
+		name      string
+		msg       *queue.Message
+		wantErr   bool
+		permanent bool
+	}{
+		{"invalid envelope", &queue.Message{Data: []byte("{")}, true, true},
+		{"unsupported endpoint", message(uploadUUID, 1, "upload/drop", ""), true, true},
+		{"missing sequence", message(uploadUUID, 0, "complete", ""), true, true},
+		{"handshake without upload_uuid", message(uploadUUID, 1, "handshake", `<handshake><version_1c>8.3</version_1c><config_name>УТ</config_name></handshake>`), true, true},
+		{"batch before handshake", items(2, "Болт М8"), true, false},
+		{"handshake", handshake, false, false},
+		{"batch", items(2, "Болт М8", "Гайка М8"), false, false},
+		{"redelivered batch", items(2, "Болт М8", "Гайка М8"), false, false},
+		{"second batch", items(3, "Шайба 8"), false, false},
+		{"rejected handshake", message("not-a-uuid", 1, "handshake", `<handshake><upload_uuid>not-a-uuid</upload_uuid><version_1c>8.3</version_1c><config_name>УТ</config_name></handshake>`), true, true},
+		{"complete", message(uploadUUID, 4, "complete", `<complete>`+uuid+`</complete>`), false, false},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.handleIngestQueueMessage(context.Background(), tt.msg)
+			if (err != nil) != tt.wantErr || queue.IsPermanent(err) != tt.permanent {
+				t.Errorf("handleIngestQueueMessage() error = %v, wantErr %v, permanent %v", err, tt.wantErr, tt.permanent)
+			}
+		})
+	}
+
+	upload, err := unifiedDB.GetUploadByUUID(uploadUUID)
+	if err != nil {
+		t.Fatalf("GetUploadByUUID() error = %v", err)
+	}
+	if upload.Status != "completed" || upload.TotalItems != 3 {
+		t.Errorf("upload status = %s, items = %d; want completed, 3 (redelivery must not duplicate items)", upload.Status, upload.TotalItems)
+	}
+
+	// Журнал утерян после создания выгрузки: повторное рукопожатие не создает вторую выгрузку
+	if _, err := serviceDB.GetDB().Exec("DELETE FROM ingest_queue_messages WHERE upload_uuid = ? AND sequence = 1", uploadUUID); err != nil {
+		t.Fatalf("Failed to clear journal: %v", err)
+	}
+	if err := s.handleIngestQueueMessage(context.Background(), handshake); err != nil {
+		t.Errorf("replayed handshake error = %v", err)
+	}
+
+	// Сообщение, захваченное параллельной доставкой, не обрабатывается повторно до истечения захвата
+	if existing, err := serviceDB.ClaimIngestQueueMessage(uploadUUID, 5, "catalog/items", ingestClaimTimeout); err != nil || existing != nil {
+		t.Fatalf("ClaimIngestQueueMessage() = %+v, %v; want claimed", existing, err)
+	}
+	if err := s.handleIngestQueueMessage(context.Background(), items(5, "Винт М6")); err == nil || queue.IsPermanent(err) {
+		t.Errorf("concurrent delivery error = %v, want retryable", err)
+	}
+	if existing, err := serviceDB.ClaimIngestQueueMessage(uploadUUID, 5, "catalog/items", 0); err != nil || existing != nil {
+		t.Errorf("stale claim = %+v, %v; want reclaimed", existing, err)
+	}
+	if err := serviceDB.ReleaseIngestQueueMessage(uploadUUID, 5); err != nil {
+		t.Fatalf("ReleaseIngestQueueMessage() error = %v", err)
+	}
+
+	messages, err := serviceDB.GetIngestQueueMessages(uploadUUID)
+	if err != nil || len(messages) != 4 || messages[0].Endpoint != "handshake" || messages[3].Endpoint != "complete" {
+		t.Fatalf("GetIngestQueueMessages() = %+v, %v; want 4 messages in sequence order", messages, err)
+	}
+	rejected, err := serviceDB.GetIngestQueueMessage("not-a-uuid", 1)
+	if err != nil || rejected == nil || rejected.Status != database.IngestMessageStatusFailed || rejected.Error == "" {
+		t.Errorf("rejected message = %+v, %v", rejected, err)
+	}
+	stats := &s.ingestQueueStats
+	if stats.processed.Load() != 5 || stats.duplicates.Load() != 1 || stats.failed.Load() != 5 || stats.retried.Load() != 2 {
+		t.Errorf("stats = processed %d, duplicates %d, failed %d, retried %d",
+			stats.processed.Load(), stats.duplicates.Load(), stats.failed.Load(), stats.retried.Load())
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/reports"
+)
+
+// eventsWebhookTimeout ограничение времени доставки события выгрузки на webhook
+const eventsWebhookTimeout = 10 * time.Second
+
+// IngestStatsReport статистика приема выгрузки по справочникам в сравнении с прошлой выгрузкой базы
+type IngestStatsReport struct {
+	UploadUUID         string                        `json:"upload_uuid"`
+	DatabaseID         int                           `json:"database_id"`
+	Catalogs           []database.CatalogIngestStats `json:"catalogs"`
+	PreviousUploadUUID string                        `json:"previous_upload_uuid,omitempty"`
+	Previous           []database.CatalogIngestStats `json:"previous,omitempty"`
+	Anomalies          []database.IngestAnomaly      `json:"anomalies"`
+	CollectedAt        time.Time                     `json:"collected_at"`
+}
+
+// IngestAnomalyWebhookPayload событие об аномалиях приема, отправляемое на EventsWebhookURL
+type IngestAnomalyWebhookPayload struct {
+	Event              string                   `json:"event"`
+	UploadUUID         string                   `json:"upload_uuid"`
+	DatabaseID         int                      `json:"database_id"`
+	PreviousUploadUUID string                   `json:"previous_upload_uuid"`
+	Anomalies          []database.IngestAnomaly `json:"anomalies"`
+	Timestamp          time.Time                `json:"timestamp"`
+}
+
+// uploadDatabaseID возвращает ID базы данных проекта выгрузки, 0 - не задан
+func uploadDatabaseID(upload *database.Upload) int {
+	if upload.DatabaseID != nil {
+		return *upload.DatabaseID
+	}
+	return 0
+}
+
+// compareIngestStats дополняет статистику выгрузки прошлой выгрузкой той же базы и найденными аномалиями.
+// Выгрузки без базы данных не сравниваются
+func (s *Server) compareIngestStats(report *IngestStatsReport) error {
+	report.Anomalies = []database.IngestAnomaly{}
+	if report.DatabaseID == 0 {
+		return nil
+	}
+	previous, err := s.serviceDB.GetPreviousIngestStats(report.DatabaseID, report.UploadUUID)
+	if err != nil || previous == nil {
+		return err
+	}
+	report.PreviousUploadUUID = previous.UploadUUID
+	report.Previous = previous.Catalogs
+	report.Anomalies = database.CompareIngestStats(previous.Catalogs, report.Catalogs, database.DefaultIngestAnomalyThresholds)
+	return nil
+}
+
+// recordIngestStats считает и сохраняет статистику приема выгрузки, записывает ее и аномалии
+// в хронологию выгрузки и сообщает об аномалиях в мониторинг и на webhook событий
+func (s *Server) recordIngestStats(ctx context.Context, uploadDB *database.DB, upload *database.Upload) (*IngestStatsReport, error) {
+	if s.serviceDB == nil {
+		return nil, nil
+	}
+	catalogs, err := uploadDB.CollectIngestStats(ctx, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	report := &IngestStatsReport{UploadUUID: upload.UploadUUID, DatabaseID: uploadDatabaseID(upload), Catalogs: catalogs, CollectedAt: time.Now()}
+	if err := s.serviceDB.SaveUploadIngestStats(&database.UploadIngestStats{
+		UploadUUID:  report.UploadUUID,
+		DatabaseID:  report.DatabaseID,
+		Catalogs:    report.Catalogs,
+		CollectedAt: report.CollectedAt,
+	}); err != nil {
+		return nil, err
+	}
+	if err := s.compareIngestStats(report); err != nil {
+		return nil, err
+	}
+
+	items := 0
+	for _, catalog := range catalogs {
+		items += catalog.Items
+	}
+	event := &database.UploadEvent{
+		UploadUUID: report.UploadUUID,
+		DatabaseID: report.DatabaseID,
+		EventType:  database.UploadEventIngestStats,
+		Message:    fmt.Sprintf("Принято %d элементов в %d справочниках", items, len(catalogs)),
+		Details:    map[string]interface{}{"catalogs": catalogs, "previous_upload_uuid": report.PreviousUploadUUID},
+	}
+	if err := s.serviceDB.RecordUploadEvent(event); err != nil {
+		return nil, err
+	}
+	if len(report.Anomalies) == 0 {
+		return report, nil
+	}
+
+	messages := make([]string, 0, len(report.Anomalies))
+	for _, anomaly := range report.Anomalies {
+		err := s.serviceDB.RecordUploadEvent(&database.UploadEvent{
+			UploadUUID: report.UploadUUID,
+			DatabaseID: report.DatabaseID,
+			EventType:  database.UploadEventIngestAnomaly,
+			Severity:   anomaly.Severity,
+			Message:    anomaly.Message,
+			Details: map[string]interface{}{
+				"catalog_name":         anomaly.CatalogName,
+				"kind":                 anomaly.Kind,
+				"previous":             anomaly.Previous,
+				"current":              anomaly.Current,
+				"previous_upload_uuid": report.PreviousUploadUUID,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, anomaly.Message)
+	}
+	s.logCtx(ctx, LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "WARNING",
+		Message:    fmt.Sprintf("Ingest anomalies in upload %s compared to %s: %s", report.UploadUUID, report.PreviousUploadUUID, strings.Join(messages, "; ")),
+		UploadUUID: report.UploadUUID,
+		Endpoint:   "/complete",
+	})
+	s.publishMonitoringEvent(map[string]interface{}{
+		"type":        database.UploadEventIngestAnomaly,
+		"timestamp":   report.CollectedAt.Format(time.RFC3339),
+		"upload_uuid": report.UploadUUID,
+		"database_id": report.DatabaseID,
+		"anomalies":   report.Anomalies,
+	})
+
+	if s.currentConfig() != nil && s.currentConfig().EventsWebhookURL != "" {
+		payload := IngestAnomalyWebhookPayload{
+			Event:              database.UploadEventIngestAnomaly,
+			UploadUUID:         report.UploadUUID,
+			DatabaseID:         report.DatabaseID,
+			PreviousUploadUUID: report.PreviousUploadUUID,
+			Anomalies:          report.Anomalies,
+			Timestamp:          report.CollectedAt,
+		}
+		client := &http.Client{Timeout: eventsWebhookTimeout}
+		if err := reports.PostWebhook(client, s.currentConfig().EventsWebhookURL, payload); err != nil {
+			log.Printf("Failed to deliver ingest anomalies of upload %s to webhook: %v", report.UploadUUID, err)
+		}
+	}
+	return report, nil
+}
+
+// handleUploadIngestStats статистика приема выгрузки по справочникам.
+// GET /api/uploads/{uuid}/stats - сохраненная при завершении выгрузки статистика (или посчитанная на лету) с аномалиями;
+// POST - пересчет статистики с записью в хронологию выгрузки
+func (s *Server) handleUploadIngestStats(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		report, err := s.recordIngestStats(r.Context(), uploadDB, upload)
+		if err != nil {
+			s.writeAPIError(w, "Failed to record ingest stats", err)
+			return
+		}
+		s.writeJSONResponse(w, report, http.StatusOK)
+		return
+	}
+
+	report := &IngestStatsReport{UploadUUID: upload.UploadUUID, DatabaseID: uploadDatabaseID(upload)}
+	saved, err := s.serviceDB.GetUploadIngestStats(upload.UploadUUID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to get ingest stats", err)
+		return
+	}
+	if saved != nil {
+		report.Catalogs = saved.Catalogs
+		report.CollectedAt = saved.CollectedAt
+	} else {
+		report.Catalogs, err = uploadDB.ReadOnly().CollectIngestStats(r.Context(), upload.ID)
+		if err != nil {
+			s.writeAPIError(w, "Failed to collect ingest stats", err)
+			return
+		}
+		report.CollectedAt = time.Now()
+	}
+	if err := s.compareIngestStats(report); err != nil {
+		s.writeAPIError(w, "Failed to compare ingest stats", err)
+		return
+	}
+	s.writeJSONResponse(w, report, http.StatusOK)
+}
+
+// handleUploadTimeline хронология событий выгрузки
+// GET /api/uploads/{uuid}/timeline
+func (s *Server) handleUploadTimeline(w http.ResponseWriter, r *http.Request, upload *database.Upload) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := s.serviceDB.GetUploadEvents(upload.UploadUUID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to get upload timeline", err)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"upload_uuid": upload.UploadUUID,
+		"events":      events,
+	}, http.StatusOK)
+}
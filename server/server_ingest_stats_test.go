@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestUploadIngestStats(t *testing.T) {
+	db, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	defer db.Close()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	var delivered IngestAnomalyWebhookPayload
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &delivered)
+	}))
+	defer webhook.Close()
+
+	s := &Server{
+		logChan:   make(chan LogEntry, 100),
+		serviceDB: serviceDB,
+		config:    &Config{EventsWebhookURL: webhook.URL},
+	}
+
+	databaseID := 3
+	uploads := make([]*database.Upload, 0, 2)
+	for i, items := range []int{800, 120} {
+		upload, err := db.CreateUploadWithDatabase(fmt.Sprintf("uuid-stats-%d", i), "8.3", "УТ", &databaseID, "", "", "", 0, "", "", "", nil)
+		if err != nil {
+			t.Fatalf("CreateUploadWithDatabase() error = %v", err)
+		}
+		catalog, _ := db.AddCatalog(upload.ID, "Номенклатура", "")
+		for j := 0; j < items; j++ {
+			db.AddCatalogItem(catalog.ID, fmt.Sprintf("ref-%d-%d", i, j), fmt.Sprint(j), "Товар", "", "")
+		}
+		uploads = append(uploads, upload)
+	}
+
+	tests := []struct {
+		name          string
+		upload        *database.Upload
+		method        string
+		wantAnomalies int
+		wantEvents    int
+	}{
+		{"first upload", uploads[0], http.MethodPost, 0, 1},
+		{"dropped items", uploads[1], http.MethodPost, 1, 2},
+		{"saved stats", uploads[1], http.MethodGet, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleUploadIngestStats(rec, httptest.NewRequest(tt.method, "/api/uploads/"+tt.upload.UploadUUID+"/stats", nil), db, tt.upload)
+			var report IngestStatsReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil || rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			if len(report.Anomalies) != tt.wantAnomalies {
+				t.Fatalf("anomalies = %+v, want %d", report.Anomalies, tt.wantAnomalies)
+			}
+
+			rec = httptest.NewRecorder()
+			s.handleUploadTimeline(rec, httptest.NewRequest(http.MethodGet, "/api/uploads/"+tt.upload.UploadUUID+"/timeline", nil), tt.upload)
+			var timeline struct {
+				Events []database.UploadEvent `json:"events"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &timeline); err != nil {
+				t.Fatalf("timeline: %s", rec.Body.String())
+			}
+			if len(timeline.Events) != tt.wantEvents {
+				t.Errorf("timeline = %+v, want %d events", timeline.Events, tt.wantEvents)
+			}
+		})
+	}
+
+	if delivered.UploadUUID != uploads[1].UploadUUID || delivered.PreviousUploadUUID != uploads[0].UploadUUID ||
+		len(delivered.Anomalies) != 1 || !strings.Contains(delivered.Anomalies[0].Message, "120 вместо 800") {
+		t.Errorf("webhook payload = %+v", delivered)
+	}
+}
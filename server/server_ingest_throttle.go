@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"httpserver/database"
+)
+
+// ingestLimitsRefresh период перечитывания ограничений клиента из serviceDB
+const ingestLimitsRefresh = 30 * time.Second
+
+// ingestThrottler ограничители скорости приема выгрузок по клиентам (ключ - client_id, 0 - клиент не определен)
+type ingestThrottler struct {
+	mu      sync.Mutex
+	clients map[int]*clientIngestBudget
+}
+
+// clientIngestBudget бюджеты клиента: элементы в секунду и байты в секунду
+type clientIngestBudget struct {
+	limits   database.ClientIngestLimits
+	items    *rate.Limiter
+	bytes    *rate.Limiter
+	loadedAt time.Time
+}
+
+// newIngestLimiter создает ограничитель с запасом на одну секунду; nil - без ограничения
+func newIngestLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), int(math.Max(1, math.Ceil(perSecond))))
+}
+
+// reserve резервирует n единиц бюджета и возвращает задержку до их появления.
+// Пакет больше секундного бюджета расходует весь запас, чтобы крупные пакеты не блокировались навсегда.
+func reserve(limiter *rate.Limiter, n int, now time.Time) (*rate.Reservation, time.Duration) {
+	if limiter == nil || n <= 0 {
+		return nil, 0
+	}
+	if n > limiter.Burst() {
+		n = limiter.Burst()
+	}
+	reservation := limiter.ReserveN(now, n)
+	return reservation, reservation.DelayFrom(now)
+}
+
+// effectiveIngestLimits возвращает ограничения клиента или ограничения по умолчанию из конфигурации
+func (s *Server) effectiveIngestLimits(clientID int) database.ClientIngestLimits {
+	if s.serviceDB != nil && clientID > 0 {
+		if limits, err := s.serviceDB.GetClientIngestLimits(clientID); err == nil && limits != nil {
+			return *limits
+		}
+	}
+	limits := database.ClientIngestLimits{ClientID: clientID}
+	if s.currentConfig() != nil {
+		limits.ItemsPerSecond = s.currentConfig().IngestItemsPerSecond
+		limits.MBPerSecond = s.currentConfig().IngestMBPerSecond
+	}
+	return limits
+}
+
+// ingestBudget возвращает бюджет клиента, пересоздавая ограничители при изменении настроек
+func (s *Server) ingestBudget(clientID int, now time.Time) *clientIngestBudget {
+	t := &s.ingestThrottle
+	t.mu.Lock()
+	budget := t.clients[clientID]
+	fresh := budget != nil && now.Sub(budget.loadedAt) < ingestLimitsRefresh
+	t.mu.Unlock()
+	if fresh {
+		return budget
+	}
+
+	limits := s.effectiveIngestLimits(clientID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.clients == nil {
+		t.clients = make(map[int]*clientIngestBudget)
+	}
+	budget = t.clients[clientID]
+	if budget == nil || budget.limits.ItemsPerSecond != limits.ItemsPerSecond || budget.limits.MBPerSecond != limits.MBPerSecond {
+		budget = &clientIngestBudget{
+			limits: limits,
+			items:  newIngestLimiter(limits.ItemsPerSecond),
+			bytes:  newIngestLimiter(limits.MBPerSecond * 1024 * 1024),
+		}
+		t.clients[clientID] = budget
+	}
+	budget.loadedAt = now
+	return budget
+}
+
+// resetIngestBudget сбрасывает бюджет клиента после изменения его ограничений
+func (s *Server) resetIngestBudget(clientID int) {
+	s.ingestThrottle.mu.Lock()
+	delete(s.ingestThrottle.clients, clientID)
+	s.ingestThrottle.mu.Unlock()
+}
+
+// ingestDelay резервирует бюджет клиента под пакет и возвращает время ожидания, если бюджет исчерпан
+func (s *Server) ingestDelay(clientID, items, size int, now time.Time) time.Duration {
+	budget := s.ingestBudget(clientID, now)
+	itemsReservation, itemsDelay := reserve(budget.items, items, now)
+	bytesReservation, bytesDelay := reserve(budget.bytes, size, now)
+	delay := max(itemsDelay, bytesDelay)
+	if delay > 0 {
+		// Отклоненный пакет не расходует бюджет: клиент пришлет его повторно
+		if itemsReservation != nil {
+			itemsReservation.CancelAt(now)
+		}
+		if bytesReservation != nil {
+			bytesReservation.CancelAt(now)
+		}
+	}
+	return delay
+}
+
+// allowIngest проверяет бюджет клиента выгрузки. При превышении отвечает 429 с Retry-After
+// и retry_after в XML, чтобы обработка 1С повторила пакет позже, и возвращает false.
+func (s *Server) allowIngest(w http.ResponseWriter, upload *database.Upload, items, size int) bool {
+	clientID := 0
+	if upload.ClientID != nil {
+		clientID = *upload.ClientID
+	}
+	delay := s.ingestDelay(clientID, items, size, time.Now())
+	if delay <= 0 {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(delay.Seconds()))
+	s.log(LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "WARNING",
+		Message:    fmt.Sprintf("Ingest rate limit exceeded for client %d: %d items, %d bytes, retry after %ds", clientID, items, size, retryAfter),
+		UploadUUID: upload.UploadUUID,
+	})
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+	xmlData, _ := xml.MarshalIndent(ErrorResponse{
+		Success:    false,
+		Error:      "ingest rate limit exceeded",
+		Code:       "rate_limited",
+		Message:    fmt.Sprintf("Превышено ограничение скорости приема, повторите пакет через %d с", retryAfter),
+		Timestamp:  time.Now().Format(time.RFC3339),
+		RetryAfter: retryAfter,
+	}, "", "  ")
+	w.Write([]byte(xml.Header))
+	w.Write(xmlData)
+	return false
+}
+
+// handleClientIngestLimits ограничения скорости приема выгрузок клиента
+// GET/PUT/DELETE /api/clients/{id}/ingest-limits
+func (s *Server) handleClientIngestLimits(w http.ResponseWriter, r *http.Request, clientID int) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := s.serviceDB.GetClient(clientID); err != nil {
+		s.writeJSONError(w, "Client not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req database.ClientIngestLimits
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.ItemsPerSecond < 0 || req.MBPerSecond < 0 {
+			s.writeJSONError(w, "Limits cannot be negative", http.StatusBadRequest)
+			return
+		}
+		req.ClientID = clientID
+		if err := s.serviceDB.SetClientIngestLimits(&req); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to set ingest limits: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.resetIngestBudget(clientID)
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Ограничения приема клиента %d: %.2f элементов/с, %.2f МБ/с", clientID, req.ItemsPerSecond, req.MBPerSecond),
+			Endpoint:  r.URL.Path,
+		})
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteClientIngestLimits(clientID); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to delete ingest limits: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.resetIngestBudget(clientID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	own, err := s.serviceDB.GetClientIngestLimits(clientID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"client_id": clientID,
+		"limits":    own,
+		"effective": s.effectiveIngestLimits(clientID),
+	}, http.StatusOK)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestIngestDelay(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	if err := serviceDB.SetClientIngestLimits(&database.ClientIngestLimits{ClientID: client.ID, MBPerSecond: 1}); err != nil {
+		t.Fatalf("SetClientIngestLimits() error = %v", err)
+	}
+
+	s := &Server{serviceDB: serviceDB, config: &Config{IngestItemsPerSecond: 10}, logChan: make(chan LogEntry, 10)}
+	now := time.Now()
+	const mb = 1024 * 1024
+
+	steps := []struct {
+		name      string
+		clientID  int
+		items     int
+		size      int
+		at        time.Duration
+		wantDelay bool
+	}{
+		{"default budget", 0, 10, mb, 0, false},
+		{"default budget exhausted", 0, 5, 0, 0, true},
+		{"default budget refilled", 0, 5, 0, time.Second, false},
+		{"oversized batch uses whole budget", 0, 100, 0, 3 * time.Second, false},
+		{"client items unlimited", client.ID, 1000, mb / 2, 0, false},
+		{"client bytes exhausted", client.ID, 1, mb, 0, true},
+		{"rejected batch does not consume budget", client.ID, 1, mb / 2, 0, false},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			delay := s.ingestDelay(tt.clientID, tt.items, tt.size, now.Add(tt.at))
+			if (delay > 0) != tt.wantDelay {
+				t.Errorf("ingestDelay() = %v, want delay %v", delay, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestAllowIngestBackpressure(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	s := &Server{serviceDB: serviceDB, config: &Config{}, logChan: make(chan LogEntry, 10)}
+
+	rec := httptest.NewRecorder()
+	s.handleClientRoutes(rec, httptest.NewRequest(http.MethodPut, "/api/clients/"+strconv.Itoa(client.ID)+"/ingest-limits",
+		strings.NewReader(`{"items_per_second": 0.5}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"items_per_second":0.5`) {
+		t.Fatalf("PUT ingest-limits status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	s.handleClientRoutes(rec, httptest.NewRequest(http.MethodPut, "/api/clients/"+strconv.Itoa(client.ID)+"/ingest-limits",
+		strings.NewReader(`{"mb_per_second": -1}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("negative limit status = %d, want 400", rec.Code)
+	}
+
+	upload := &database.Upload{UploadUUID: "u-1", ClientID: &client.ID}
+	if !s.allowIngest(httptest.NewRecorder(), upload, 1, 100) {
+		t.Fatal("first item must fit into the budget")
+	}
+	rec = httptest.NewRecorder()
+	if s.allowIngest(rec, upload, 1, 100) {
+		t.Fatal("second item must be throttled")
+	}
+
+	var response ErrorResponse
+	if err := xml.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid XML response: %v: %s", err, rec.Body.String())
+	}
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" || response.RetryAfter != 2 || response.Success {
+		t.Errorf("throttled response = %d, Retry-After %q, %+v", rec.Code, rec.Header().Get("Retry-After"), response)
+	}
+
+	// Снятие ограничений применяется сразу, без ожидания перечитывания
+	rec = httptest.NewRecorder()
+	s.handleClientRoutes(rec, httptest.NewRequest(http.MethodDelete, "/api/clients/"+strconv.Itoa(client.ID)+"/ingest-limits", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"limits":null`) {
+		t.Fatalf("DELETE ingest-limits status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !s.allowIngest(httptest.NewRecorder(), upload, 1000, 100) {
+		t.Error("upload must not be throttled after limits are removed")
+	}
+}
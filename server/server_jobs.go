@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+	"httpserver/tracing"
+)
+
+// Виды фоновых задач
+const (
+	jobKindNormalization         = "normalization"
+	jobKindClientNormalization   = "client_normalization"
+	jobKindQualityAnalysis       = "quality_analysis"
+	jobKindReclassification      = "reclassification"
+	jobKindExport                = "export"
+	jobKindQualityBulk           = "quality_bulk"
+	jobKindNomenclatureReconcile = "nomenclature_reconciliation"
+)
+
+// Ограничения выборки истории фоновых задач
+const (
+	jobsDefaultLimit = 50
+	jobsMaxLimit     = 500
+)
+
+// backgroundJob выполняющаяся фоновая задача. ctx отменяется при остановке сервера
+type backgroundJob struct {
+	ID        int       `json:"id,omitempty"` // ID записи в service.db, 0 - запись не сохранена
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	StartedAt time.Time `json:"started_at"`
+	TraceID   string    `json:"trace_id,omitempty"`
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	span   *tracing.Span
+
+	mu         sync.Mutex
+	checkpoint map[string]interface{}
+	panicErr   error
+}
+
+// setCheckpoint запоминает прогресс задачи, сохраняемый при ее остановке
+func (j *backgroundJob) setCheckpoint(checkpoint map[string]interface{}) {
+	j.mu.Lock()
+	j.checkpoint = checkpoint
+	j.mu.Unlock()
+}
+
+// lastCheckpoint возвращает последний сохраненный прогресс
+func (j *backgroundJob) lastCheckpoint() map[string]interface{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.checkpoint
+}
+
+// markPanicked отмечает, что задача завершилась паникой
+func (j *backgroundJob) markPanicked(err error) {
+	j.mu.Lock()
+	j.panicErr = err
+	j.mu.Unlock()
+}
+
+// panicError возвращает ошибку паники задачи или nil
+func (j *backgroundJob) panicError() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.panicErr
+}
+
+// backgroundJobs координатор фоновых задач для корректной остановки сервера. Нулевое значение готово к использованию
+type backgroundJobs struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	stopping bool
+	running  map[*backgroundJob]struct{}
+	// Задачи, не завершенные в предыдущем запуске сервера
+	unfinished []*database.BackgroundJob
+}
+
+// add регистрирует задачу; false, если сервер уже останавливается
+func (c *backgroundJobs) add(job *backgroundJob) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopping {
+		return false
+	}
+	if c.running == nil {
+		c.running = make(map[*backgroundJob]struct{})
+	}
+	c.running[job] = struct{}{}
+	c.wg.Add(1)
+	return true
+}
+
+// remove снимает задачу с учета
+func (c *backgroundJobs) remove(job *backgroundJob) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.running[job]; !ok {
+		return
+	}
+	delete(c.running, job)
+	c.wg.Done()
+}
+
+// list возвращает выполняющиеся задачи в порядке запуска
+func (c *backgroundJobs) list() []*backgroundJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	jobs := make([]*backgroundJob, 0, len(c.running))
+	for job := range c.running {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].StartedAt.Before(jobs[k].StartedAt) })
+	return jobs
+}
+
+// stop запрещает запуск новых задач, отменяет контексты выполняющихся и ждет их завершения
+// не дольше grace. Возвращает задачи, не успевшие завершиться
+func (c *backgroundJobs) stop(grace time.Duration) []*backgroundJob {
+	c.mu.Lock()
+	c.stopping = true
+	for job := range c.running {
+		job.cancel()
+	}
+	c.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return c.list()
+	}
+}
+
+// startBackgroundJob регистрирует фоновую задачу в координаторе и service.db. Задача арендуется экземпляром
+// сервера: если задача того же вида и имени уже выполняется на этом или другом экземпляре, возвращается конфликт.
+// Задача должна быть завершена вызовом finishBackgroundJob
+func (s *Server) startBackgroundJob(parent context.Context, kind, name string) (*backgroundJob, error) {
+	// Задача продолжает трассировку запроса, но не отменяется вместе с ним
+	ctx, span := tracing.Start(tracing.Detach(parent), "job "+kind, tracing.Attr("job.kind", kind), tracing.Attr("job.name", name))
+	ctx, cancel := context.WithCancel(ctx)
+	job := &backgroundJob{Kind: kind, Name: name, StartedAt: time.Now(), TraceID: tracing.TraceIDFromContext(ctx), ctx: ctx, cancel: cancel, span: span}
+	if !s.jobs.add(job) {
+		cancel()
+		span.RecordError(errors.New("server is shutting down"))
+		span.End()
+		return nil, apperrors.Unavailable("shutting_down", "server is shutting down")
+	}
+
+	if s.serviceDB != nil {
+		record := &database.BackgroundJob{Kind: kind, Name: name, InstanceID: s.instanceID, StartedAt: job.StartedAt}
+		err := s.serviceDB.StartBackgroundJob(record, s.clusterLeaseTTL())
+		switch {
+		case errors.Is(err, database.ErrBackgroundJobLeased):
+			s.jobs.remove(job)
+			cancel()
+			span.RecordError(err)
+			span.End()
+			return nil, err
+		case err != nil:
+			log.Printf("Не удалось сохранить фоновую задачу %s: %v", kind, err)
+		default:
+			job.ID = record.ID
+		}
+	}
+	return job, nil
+}
+
+// finishBackgroundJob сохраняет итог фоновой задачи. Задача, остановленная отменой контекста,
+// сохраняется как прерванная вместе с последним checkpoint, завершившаяся паникой - как упавшая
+func (s *Server) finishBackgroundJob(job *backgroundJob, err error) {
+	defer s.jobs.remove(job)
+	defer job.cancel()
+
+	status := database.BackgroundJobCompleted
+	var message string
+	switch {
+	case job.panicError() != nil:
+		status = database.BackgroundJobFailed
+		message = job.panicError().Error()
+	case job.ctx.Err() != nil || errors.Is(err, context.Canceled):
+		status = database.BackgroundJobInterrupted
+		message = "stopped by server shutdown"
+		if err != nil && !errors.Is(err, context.Canceled) {
+			message = err.Error()
+		}
+	case err != nil:
+		status = database.BackgroundJobFailed
+		message = err.Error()
+	}
+	s.persistBackgroundJob(job, status, message)
+
+	job.span.SetAttributes(tracing.Attr("job.status", status))
+	if status != database.BackgroundJobCompleted {
+		job.span.RecordError(errors.New(message))
+	}
+	job.span.End()
+}
+
+// persistBackgroundJob записывает статус задачи в service.db
+func (s *Server) persistBackgroundJob(job *backgroundJob, status, message string) {
+	if s.serviceDB == nil || job.ID == 0 {
+		return
+	}
+	if err := s.serviceDB.FinishBackgroundJob(job.ID, status, message, job.lastCheckpoint()); err != nil {
+		log.Printf("Не удалось сохранить статус фоновой задачи %d: %v", job.ID, err)
+	}
+}
+
+// shutdownBackgroundJobs останавливает фоновые задачи при завершении работы сервера. Задачи,
+// не завершившиеся за период ожидания, сохраняются как прерванные с последним checkpoint
+func (s *Server) shutdownBackgroundJobs() {
+	grace := 30 * time.Second
+	if s.currentConfig() != nil {
+		grace = s.currentConfig().ShutdownGracePeriod
+	}
+
+	for _, job := range s.jobs.stop(grace) {
+		s.persistBackgroundJob(job, database.BackgroundJobInterrupted, "shutdown grace period exceeded")
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "WARN",
+			Message:   fmt.Sprintf("Фоновая задача %s (%s) не завершилась за %v и будет прервана", job.Kind, job.Name, grace),
+		})
+	}
+}
+
+// reportUnfinishedJobs сообщает о фоновых задачах, прерванных при предыдущей остановке сервера
+func (s *Server) reportUnfinishedJobs() {
+	if s.serviceDB == nil {
+		return
+	}
+	jobs, err := s.serviceDB.TakeUnfinishedBackgroundJobs(s.instanceID)
+	if err != nil {
+		log.Printf("Ошибка получения незавершенных фоновых задач: %v", err)
+		return
+	}
+
+	s.jobs.mu.Lock()
+	s.jobs.unfinished = jobs
+	s.jobs.mu.Unlock()
+
+	for _, job := range jobs {
+		// Выгрузка прерванного экземпляра не будет завершена: отмечаем сохраненную задачу выгрузки
+		if job.Kind == jobKindExport {
+			if err := s.serviceDB.UpdateExportJobStatus(job.Name, string(ExportStatusFailed), job.Error); err != nil {
+				log.Printf("Не удалось отметить прерванную задачу выгрузки %s: %v", job.Name, err)
+			}
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "WARN",
+			Message:   fmt.Sprintf("Фоновая задача %s (%s), запущенная %s, не была завершена: %s", job.Kind, job.Name, job.StartedAt.Format(time.RFC3339), job.Error),
+		})
+	}
+}
+
+// handleBackgroundJobs возвращает выполняющиеся фоновые задачи экземпляра, задачи, не завершенные
+// в предыдущем запуске сервера, и историю задач всех экземпляров
+// GET /api/jobs?status=interrupted&limit=50
+func (s *Server) handleBackgroundJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, err := boundedIntParam(r.URL.Query().Get("limit"), jobsDefaultLimit, jobsMaxLimit)
+	if err != nil {
+		s.writeJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+
+	s.jobs.mu.Lock()
+	unfinished := append([]*database.BackgroundJob{}, s.jobs.unfinished...)
+	s.jobs.mu.Unlock()
+
+	response := map[string]interface{}{
+		"instance_id": s.instanceID,
+		"running":     s.jobs.list(),
+		"unfinished":  unfinished,
+	}
+	if s.serviceDB != nil {
+		history, err := s.serviceDB.GetBackgroundJobs(r.URL.Query().Get("status"), limit)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response["history"] = history
+	}
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
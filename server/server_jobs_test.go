@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+func TestBackgroundJobsShutdown(t *testing.T) {
+	serviceDB, err := database.NewServiceDB(filepath.Join(t.TempDir(), "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+
+	s := &Server{
+		serviceDB: serviceDB,
+		config:    &Config{ShutdownGracePeriod: 200 * time.Millisecond},
+		logChan:   make(chan LogEntry, 10),
+	}
+
+	// Задача, завершающаяся по отмене контекста
+	cooperative, err := s.startBackgroundJob(context.Background(), jobKindNormalization, "catalog_items")
+	if err != nil {
+		t.Fatalf("startBackgroundJob() error = %v", err)
+	}
+	go func() {
+		<-cooperative.ctx.Done()
+		cooperative.setCheckpoint(map[string]interface{}{"processed": 10})
+		s.finishBackgroundJob(cooperative, cooperative.ctx.Err())
+	}()
+
+	// Задача, не реагирующая на отмену
+	stuck, err := s.startBackgroundJob(context.Background(), jobKindReclassification, "top_priority")
+	if err != nil {
+		t.Fatalf("startBackgroundJob() error = %v", err)
+	}
+	stuck.setCheckpoint(map[string]interface{}{"processed": 3})
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		<-release
+		s.finishBackgroundJob(stuck, nil)
+	}()
+
+	failed, err := s.startBackgroundJob(context.Background(), jobKindExport, "job-1")
+	if err != nil {
+		t.Fatalf("startBackgroundJob() error = %v", err)
+	}
+	s.finishBackgroundJob(failed, errors.New("target unavailable"))
+
+	started := time.Now()
+	s.shutdownBackgroundJobs()
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("shutdownBackgroundJobs() took %v, grace period is not respected", elapsed)
+	}
+
+	if _, err := s.startBackgroundJob(context.Background(), jobKindExport, "job-2"); apperrors.KindOf(err) != apperrors.KindUnavailable {
+		t.Errorf("startBackgroundJob() after shutdown error = %v, want unavailable", err)
+	}
+
+	history, err := serviceDB.GetBackgroundJobs("", 10)
+	if err != nil {
+		t.Fatalf("GetBackgroundJobs() error = %v", err)
+	}
+	statuses := make(map[int]*database.BackgroundJob)
+	for _, job := range history {
+		statuses[job.ID] = job
+	}
+
+	tests := []struct {
+		name      string
+		job       *backgroundJob
+		status    string
+		processed interface{}
+	}{
+		{"cooperative", cooperative, database.BackgroundJobInterrupted, 10.0},
+		{"stuck", stuck, database.BackgroundJobInterrupted, 3.0},
+		{"failed", failed, database.BackgroundJobFailed, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := statuses[tt.job.ID]
+			if record == nil {
+				t.Fatalf("job %d is not saved", tt.job.ID)
+			}
+			if record.Status != tt.status {
+				t.Errorf("Status = %q, want %q", record.Status, tt.status)
+			}
+			if record.Checkpoint["processed"] != tt.processed {
+				t.Errorf("Checkpoint = %v, want processed %v", record.Checkpoint, tt.processed)
+			}
+		})
+	}
+}
+
+func TestReportUnfinishedJobs(t *testing.T) {
+	serviceDB, err := database.NewServiceDB(filepath.Join(t.TempDir(), "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+
+	// Задача предыдущего запуска, оставшаяся в статусе running
+	if err := serviceDB.StartBackgroundJob(&database.BackgroundJob{Kind: jobKindNormalization, Name: "catalog_items"}, 0); err != nil {
+		t.Fatalf("StartBackgroundJob() error = %v", err)
+	}
+
+	s := &Server{serviceDB: serviceDB, config: &Config{}, logChan: make(chan LogEntry, 10)}
+	s.reportUnfinishedJobs()
+
+	rec := httptest.NewRecorder()
+	s.handleBackgroundJobs(rec, httptest.NewRequest(http.MethodGet, "/api/jobs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(s.jobs.unfinished) != 1 || s.jobs.unfinished[0].Status != database.BackgroundJobInterrupted {
+		t.Errorf("unfinished = %+v, want one interrupted job", s.jobs.unfinished)
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+// errKpvedWorkerStopped группа пропущена воркером после остановки пользователем и остается в очереди запуска
+var errKpvedWorkerStopped = errors.New("worker stopped by user")
+
+// kpvedRunItems преобразует задачи классификации в группы журнала запусков
+func kpvedRunItems(tasks []classificationTask) []database.KpvedRunItem {
+	items := make([]database.KpvedRunItem, len(tasks))
+	for i, task := range tasks {
+		items[i] = database.KpvedRunItem{
+			NormalizedName: task.normalizedName,
+			Category:       task.category,
+			MergedCount:    task.mergedCount,
+		}
+	}
+	return items
+}
+
+// kpvedRunTasks преобразует необработанные группы запуска в задачи классификации
+func kpvedRunTasks(items []database.KpvedRunItem) []classificationTask {
+	tasks := make([]classificationTask, len(items))
+	for i, item := range items {
+		tasks[i] = classificationTask{
+			normalizedName: item.NormalizedName,
+			category:       item.Category,
+			mergedCount:    item.MergedCount,
+			index:          i,
+		}
+	}
+	return tasks
+}
+
+// interruptKpvedRuns отмечает запуски классификации КПВЭД, выполнявшиеся при остановке сервера,
+// как прерванные. При KpvedAutoResume они продолжаются по очереди в фоне, иначе ожидают
+// продолжения через POST /api/kpved/runs/{id}/resume
+func (s *Server) interruptKpvedRuns() {
+	if s.db == nil {
+		return
+	}
+	ids, err := s.db.InterruptKpvedRuns()
+	if err != nil {
+		log.Printf("Ошибка получения прерванных запусков классификации КПВЭД: %v", err)
+		return
+	}
+	for _, id := range ids {
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "WARN",
+			Message:   fmt.Sprintf("Запуск классификации КПВЭД %d прерван остановкой сервера, продолжение: POST /api/kpved/runs/%d/resume", id, id),
+		})
+	}
+	if len(ids) == 0 || s.currentConfig() == nil || !s.currentConfig().KpvedAutoResume {
+		return
+	}
+	go s.superviseWorker("kpved_auto_resume", func() {
+		for _, id := range ids {
+			s.resumeKpvedRunInBackground(id)
+		}
+	})
+}
+
+// resumeKpvedRunInBackground продолжает прерванный запуск без HTTP клиента; сводка пишется в журнал
+func (s *Server) resumeKpvedRunInBackground(id int) {
+	run, err := s.db.GetKpvedRun(id)
+	if err != nil || run.Status != database.KpvedRunInterrupted {
+		return
+	}
+	log.Printf("[KPVED] Auto-resuming classification run %d (%d pending groups)", id, run.Pending)
+	rec := &ingestResponseRecorder{header: http.Header{}, status: http.StatusOK}
+	s.reclassifyKpvedHierarchical(rec, run.Limit, id, false)
+	if rec.status != http.StatusOK {
+		log.Printf("[KPVED] Auto-resume of classification run %d failed: HTTP %d %s", id, rec.status, strings.TrimSpace(rec.body.String()))
+		return
+	}
+	if run, err := s.db.GetKpvedRun(id); err == nil {
+		log.Printf("[KPVED] Classification run %d %s: %d done, %d failed, %d pending", id, run.Status, run.Done, run.Failed, run.Pending)
+	}
+}
+
+// handleKpvedRuns возвращает последние запуски классификации КПВЭД с прогрессом по группам
+// GET /api/kpved/runs?limit=20
+func (s *Server) handleKpvedRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 1000 {
+		limit = v
+	}
+	runs, err := s.db.ListKpvedRuns(limit)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"runs":  runs,
+		"total": len(runs),
+	}, http.StatusOK)
+}
+
+// handleKpvedRunRoutes возвращает запуск с его группами или продолжает его с необработанных групп
+// GET /api/kpved/runs/{id}?status=failed&limit=100
+// POST /api/kpved/runs/{id}/resume {"retry_failed": true}
+func (s *Server) handleKpvedRunRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/kpved/runs/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 {
+		s.writeJSONError(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.getKpvedRun(w, r, id)
+	case len(parts) == 2 && parts[1] == "resume":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.resumeKpvedRun(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// getKpvedRun возвращает запуск и его группы с заданным статусом
+func (s *Server) getKpvedRun(w http.ResponseWriter, r *http.Request, id int) {
+	run, err := s.db.GetKpvedRun(id)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", database.KpvedRunItemPending, database.KpvedRunItemDone, database.KpvedRunItemFailed:
+	default:
+		s.writeJSONError(w, fmt.Sprintf("Invalid item status: %s", status), http.StatusBadRequest)
+		return
+	}
+	limit := 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 10000 {
+		limit = v
+	}
+	items, err := s.db.GetKpvedRunItems(id, status, limit)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"run":   run,
+		"items": items,
+	}, http.StatusOK)
+}
+
+// resumeKpvedRun продолжает остановленный или прерванный запуск: классифицирует группы без отметки
+// о выполнении, с retry_failed - и группы, завершившиеся ошибкой. Ответ совпадает с ответом
+// /api/kpved/reclassify-hierarchical
+func (s *Server) resumeKpvedRun(w http.ResponseWriter, r *http.Request, id int) {
+	var req struct {
+		RetryFailed bool `json:"retry_failed"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	run, err := s.db.GetKpvedRun(id)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+		return
+	}
+	if run.Status == database.KpvedRunRunning {
+		s.writeJSONError(w, database.ErrKpvedRunActive.Error(), http.StatusConflict)
+		return
+	}
+	if run.Pending == 0 && (!req.RetryFailed || run.Failed == 0) {
+		s.writeJSONError(w, "Run has no groups to resume", http.StatusConflict)
+		return
+	}
+	s.reclassifyKpvedHierarchical(w, run.Limit, id, req.RetryFailed)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestHandleKpvedRunRoutes(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+
+	items := []database.KpvedRunItem{{NormalizedName: "болт м10", Category: "крепеж"}}
+	completedID, err := db.CreateKpvedRun("model-a", 1, items)
+	if err != nil {
+		t.Fatalf("CreateKpvedRun() error = %v", err)
+	}
+	if _, err := db.ApplyKpvedRunResult(t.Context(), completedID, database.KpvedRunResult{NormalizedName: "болт м10", Category: "крепеж", Code: "25.94.11"}); err != nil {
+		t.Fatalf("ApplyKpvedRunResult() error = %v", err)
+	}
+	if err := db.FinishKpvedRun(completedID, database.KpvedRunCompleted); err != nil {
+		t.Fatalf("FinishKpvedRun() error = %v", err)
+	}
+	runningID, err := db.CreateKpvedRun("model-a", 1, items)
+	if err != nil {
+		t.Fatalf("CreateKpvedRun() error = %v", err)
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), db: db}
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"list", http.MethodGet, "/api/kpved/runs", http.StatusOK, `"total":2`},
+		{"get", http.MethodGet, "/api/kpved/runs/1?status=done", http.StatusOK, `"kpved_code":"25.94.11"`},
+		{"invalid item status", http.MethodGet, "/api/kpved/runs/1?status=skipped", http.StatusBadRequest, ""},
+		{"missing run", http.MethodGet, "/api/kpved/runs/99", http.StatusNotFound, ""},
+		{"invalid id", http.MethodGet, "/api/kpved/runs/abc", http.StatusBadRequest, ""},
+		{"resume completed run", http.MethodPost, "/api/kpved/runs/1/resume", http.StatusConflict, "no groups to resume"},
+		{"resume running run", http.MethodPost, "/api/kpved/runs/2/resume", http.StatusConflict, "already running"},
+		{"resume missing run", http.MethodPost, "/api/kpved/runs/99/resume", http.StatusNotFound, ""},
+		{"resume with get", http.MethodGet, "/api/kpved/runs/1/resume", http.StatusMethodNotAllowed, ""},
+		{"unknown action", http.MethodPost, "/api/kpved/runs/1/restart", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.path == "/api/kpved/runs" {
+				s.handleKpvedRuns(rec, req)
+			} else {
+				s.handleKpvedRunRoutes(rec, req)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	// Выполнявшийся при остановке запуск прерывается при старте сервера
+	s.interruptKpvedRuns()
+	if run, err := db.GetKpvedRun(runningID); err != nil || run.Status != database.KpvedRunInterrupted {
+		t.Errorf("GetKpvedRun() after interrupt = %+v, %v, want interrupted", run, err)
+	}
+}
@@ -0,0 +1,681 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"httpserver/database"
+	"httpserver/nomenclature"
+	"httpserver/normalization"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultReclassifySampleSize = 20
+	maxReclassifySampleSize     = 200
+)
+
+// ReclassifyScopeFilter фильтр записей normalized_data для выборочной переклассификации.
+// Пустые поля не ограничивают выборку.
+type ReclassifyScopeFilter struct {
+	KpvedCodePrefix  string   `json:"kpved_code_prefix,omitempty"` // Префикс текущего кода КПВЭД
+	CategoryPrefix   string   `json:"category_prefix,omitempty"`   // Префикс категории нормализации
+	MinConfidence    *float64 `json:"min_confidence,omitempty"`
+	MaxConfidence    *float64 `json:"max_confidence,omitempty"`
+	DateFrom         string   `json:"date_from,omitempty"`    // YYYY-MM-DD или RFC3339, по created_at
+	DateTo           string   `json:"date_to,omitempty"`      // Включительно для даты без времени
+	NamePattern      string   `json:"name_pattern,omitempty"` // Подстрока или шаблон с * и ?
+	OnlyUnclassified bool     `json:"only_unclassified,omitempty"`
+}
+
+// ScopedReclassifyRequest запрос выборочной переклассификации
+type ScopedReclassifyRequest struct {
+	Filter     ReclassifyScopeFilter `json:"filter"`
+	Preview    bool                  `json:"preview"`
+	SampleSize int                   `json:"sample_size"`
+	Limit      int                   `json:"limit"` // Максимум групп (0 = все)
+	// Классифицировать и группы, входные данные которых не изменились с прошлой классификации
+	Force bool `json:"force"`
+}
+
+// ReclassifyJobStatus состояние задачи переклассификации
+type ReclassifyJobStatus string
+
+const (
+	ReclassifyJobPending   ReclassifyJobStatus = "pending"
+	ReclassifyJobRunning   ReclassifyJobStatus = "running"
+	ReclassifyJobStopping  ReclassifyJobStatus = "stopping"
+	ReclassifyJobStopped   ReclassifyJobStatus = "stopped"
+	ReclassifyJobFailed    ReclassifyJobStatus = "failed"
+	ReclassifyJobCompleted ReclassifyJobStatus = "completed"
+)
+
+// ReclassifyProgress прогресс задачи переклассификации
+type ReclassifyProgress struct {
+	TotalGroups     int     `json:"total_groups"`
+	ProcessedGroups int     `json:"processed_groups"`
+	Classified      int     `json:"classified"`
+	Failed          int     `json:"failed"`
+	UpdatedItems    int     `json:"updated_items"`
+	Skipped         int     `json:"skipped"` // Групп с неизмененными входными данными
+	CurrentGroup    string  `json:"current_group,omitempty"`
+	Percent         float64 `json:"percent"`
+}
+
+// ReclassifyJob фоновая задача выборочной переклассификации
+type ReclassifyJob struct {
+	mu         sync.RWMutex
+	ID         string
+	Filter     ReclassifyScopeFilter
+	Limit      int
+	Force      bool
+	Status     ReclassifyJobStatus
+	Error      string
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	Progress   ReclassifyProgress
+	stop       bool
+	modelUsage func() map[string]normalization.ModelUsage // Учет цепочки моделей задачи
+	contentKey string                                     // Версия промптов и модели для хеша входных данных
+}
+
+// ReclassifyJobView DTO задачи для ответа API
+type ReclassifyJobView struct {
+	ID         string                              `json:"id"`
+	Filter     ReclassifyScopeFilter               `json:"filter"`
+	Limit      int                                 `json:"limit"`
+	Force      bool                                `json:"force,omitempty"`
+	Status     ReclassifyJobStatus                 `json:"status"`
+	Error      string                              `json:"error,omitempty"`
+	CreatedAt  time.Time                           `json:"created_at"`
+	StartedAt  *time.Time                          `json:"started_at,omitempty"`
+	FinishedAt *time.Time                          `json:"finished_at,omitempty"`
+	Progress   ReclassifyProgress                  `json:"progress"`
+	ModelUsage map[string]normalization.ModelUsage `json:"model_usage,omitempty"`
+}
+
+// reclassifyGroup группа normalized_data (наименование + категория) для переклассификации
+type reclassifyGroup struct {
+	normalizedName string
+	category       string
+	items          int
+	hash           string // Общий хеш входных данных записей группы; пустой, если хеши различаются
+}
+
+// reclassifyFunc классифицирует группу (в рабочем режиме - иерархический классификатор)
+type reclassifyFunc func(normalizedName, category string) (*normalization.HierarchicalResult, error)
+
+func newReclassifyJob(filter ReclassifyScopeFilter, limit int) *ReclassifyJob {
+	return &ReclassifyJob{
+		ID:        uuid.New().String(),
+		Filter:    filter,
+		Limit:     limit,
+		Status:    ReclassifyJobPending,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (job *ReclassifyJob) snapshot() ReclassifyJobView {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+
+	view := ReclassifyJobView{
+		ID:        job.ID,
+		Filter:    job.Filter,
+		Limit:     job.Limit,
+		Force:     job.Force,
+		Status:    job.Status,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		Progress:  job.Progress,
+	}
+	if job.StartedAt != nil {
+		start := *job.StartedAt
+		view.StartedAt = &start
+	}
+	if job.FinishedAt != nil {
+		finish := *job.FinishedAt
+		view.FinishedAt = &finish
+	}
+	if job.modelUsage != nil {
+		view.ModelUsage = job.modelUsage()
+	}
+	if view.Progress.TotalGroups > 0 {
+		view.Progress.Percent = float64(view.Progress.ProcessedGroups) * 100 / float64(view.Progress.TotalGroups)
+	}
+
+	return view
+}
+
+func (job *ReclassifyJob) isActive() bool {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	return job.Status == ReclassifyJobPending || job.Status == ReclassifyJobRunning || job.Status == ReclassifyJobStopping
+}
+
+func (job *ReclassifyJob) requestStop() {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.stop = true
+	if job.Status == ReclassifyJobPending || job.Status == ReclassifyJobRunning {
+		job.Status = ReclassifyJobStopping
+	}
+}
+
+func (job *ReclassifyJob) stopRequested() bool {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+	return job.stop
+}
+
+func (job *ReclassifyJob) markRunning(totalGroups int) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	now := time.Now()
+	job.StartedAt = &now
+	job.Progress.TotalGroups = totalGroups
+	if job.Status == ReclassifyJobPending {
+		job.Status = ReclassifyJobRunning
+	}
+}
+
+func (job *ReclassifyJob) finish(status ReclassifyJobStatus, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	job.Progress.CurrentGroup = ""
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+func (job *ReclassifyJob) setCurrentGroup(name string) {
+	job.mu.Lock()
+	job.Progress.CurrentGroup = name
+	job.mu.Unlock()
+}
+
+func (job *ReclassifyJob) addSkipped() {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Progress.ProcessedGroups++
+	job.Progress.Skipped++
+}
+
+func (job *ReclassifyJob) addResult(classified bool, updatedItems int) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.Progress.ProcessedGroups++
+	if classified {
+		job.Progress.Classified++
+		job.Progress.UpdatedItems += updatedItems
+	} else {
+		job.Progress.Failed++
+	}
+}
+
+// buildReclassifyScopeWhere формирует условие WHERE для фильтра переклассификации
+func buildReclassifyScopeWhere(filter ReclassifyScopeFilter) (string, []interface{}, error) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.OnlyUnclassified {
+		conditions = append(conditions, "(kpved_code IS NULL OR TRIM(kpved_code) = '')")
+	}
+	if prefix := strings.TrimSpace(filter.KpvedCodePrefix); prefix != "" {
+		conditions = append(conditions, `kpved_code LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLikePattern(prefix)+"%")
+	}
+	if prefix := strings.TrimSpace(filter.CategoryPrefix); prefix != "" {
+		conditions = append(conditions, `category LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLikePattern(prefix)+"%")
+	}
+
+	if filter.MinConfidence != nil && filter.MaxConfidence != nil && *filter.MinConfidence > *filter.MaxConfidence {
+		return "", nil, fmt.Errorf("min_confidence must not exceed max_confidence")
+	}
+	if filter.MinConfidence != nil {
+		conditions = append(conditions, "COALESCE(kpved_confidence, 0) >= ?")
+		args = append(args, *filter.MinConfidence)
+	}
+	if filter.MaxConfidence != nil {
+		conditions = append(conditions, "COALESCE(kpved_confidence, 0) <= ?")
+		args = append(args, *filter.MaxConfidence)
+	}
+
+	if filter.DateFrom != "" {
+		from, _, err := parseReclassifyDate(filter.DateFrom)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid date_from: %w", err)
+		}
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, from.Format("2006-01-02 15:04:05"))
+	}
+	if filter.DateTo != "" {
+		to, dateOnly, err := parseReclassifyDate(filter.DateTo)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid date_to: %w", err)
+		}
+		if dateOnly {
+			// Дата без времени включает весь день
+			conditions = append(conditions, "created_at < ?")
+			args = append(args, to.AddDate(0, 0, 1).Format("2006-01-02 15:04:05"))
+		} else {
+			conditions = append(conditions, "created_at <= ?")
+			args = append(args, to.Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	if pattern := strings.TrimSpace(filter.NamePattern); pattern != "" {
+		conditions = append(conditions, `normalized_name LIKE ? ESCAPE '\'`)
+		args = append(args, namePatternToLike(pattern))
+	}
+
+	if len(conditions) == 0 {
+		return "1=1", args, nil
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// parseReclassifyDate разбирает дату фильтра; второй результат - дата указана без времени
+func parseReclassifyDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected YYYY-MM-DD or RFC3339, got %q", value)
+	}
+	return t.UTC(), false, nil
+}
+
+// escapeLikePattern экранирует спецсимволы LIKE
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// namePatternToLike преобразует шаблон наименования в LIKE: * и ? - подстановочные символы,
+// без них шаблон ищется как подстрока
+func namePatternToLike(pattern string) string {
+	escaped := escapeLikePattern(pattern)
+	if !strings.ContainsAny(pattern, "*?") {
+		return "%" + escaped + "%"
+	}
+	return strings.NewReplacer("*", "%", "?", "_").Replace(escaped)
+}
+
+// previewReclassifyScope считает затрагиваемые записи и группы и возвращает примеры записей
+func previewReclassifyScope(db *sql.DB, where string, args []interface{}, sampleSize int) (map[string]interface{}, error) {
+	var items, groups int
+	err := db.QueryRow(fmt.Sprintf(`
+		SELECT COUNT(*), COUNT(DISTINCT normalized_name || '|' || category)
+		FROM normalized_data WHERE %s
+	`, where), args...).Scan(&items, &groups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count affected items: %w", err)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, COALESCE(normalized_name, ''), COALESCE(category, ''), COALESCE(kpved_code, ''),
+		       COALESCE(kpved_name, ''), COALESCE(kpved_confidence, 0), created_at
+		FROM normalized_data WHERE %s
+		ORDER BY id
+		LIMIT ?
+	`, where), append(append([]interface{}{}, args...), sampleSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sample items: %w", err)
+	}
+	defer rows.Close()
+
+	sample := []map[string]interface{}{}
+	for rows.Next() {
+		var id int
+		var name, category, code, kpvedName string
+		var confidence float64
+		var createdAt sql.NullTime
+		if err := rows.Scan(&id, &name, &category, &code, &kpvedName, &confidence, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sample item: %w", err)
+		}
+		item := map[string]interface{}{
+			"id":               id,
+			"normalized_name":  name,
+			"category":         category,
+			"kpved_code":       code,
+			"kpved_name":       kpvedName,
+			"kpved_confidence": confidence,
+		}
+		if createdAt.Valid {
+			item["created_at"] = createdAt.Time
+		}
+		sample = append(sample, item)
+	}
+
+	return map[string]interface{}{
+		"preview":         true,
+		"affected_items":  items,
+		"affected_groups": groups,
+		"sample":          sample,
+	}, rows.Err()
+}
+
+// loadReclassifyGroups возвращает группы, попадающие в фильтр (сначала группы с наибольшим числом дублей)
+func loadReclassifyGroups(db *sql.DB, where string, args []interface{}, limit int) ([]reclassifyGroup, error) {
+	query := fmt.Sprintf(`
+		SELECT COALESCE(normalized_name, ''), COALESCE(category, ''), COUNT(*),
+		       CASE WHEN MIN(COALESCE(classification_hash, '')) = MAX(COALESCE(classification_hash, ''))
+		            THEN MIN(COALESCE(classification_hash, '')) ELSE '' END
+		FROM normalized_data WHERE %s
+		GROUP BY normalized_name, category
+		ORDER BY MAX(merged_count) DESC, normalized_name
+	`, where)
+	queryArgs := append([]interface{}{}, args...)
+	if limit > 0 {
+		query += " LIMIT ?"
+		queryArgs = append(queryArgs, limit)
+	}
+
+	rows, err := db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []reclassifyGroup
+	for rows.Next() {
+		var group reclassifyGroup
+		if err := rows.Scan(&group.normalizedName, &group.category, &group.items, &group.hash); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// handleKpvedReclassifyScoped выборочная переклассификация по фильтру.
+// С preview=true возвращает количество затрагиваемых записей и примеры,
+// иначе запускает фоновую задачу.
+// POST /api/kpved/reclassify/scoped
+func (s *Server) handleKpvedReclassifyScoped(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ScopedReclassifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	where, args, err := buildReclassifyScopeWhere(req.Filter)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ВАЖНО: normalized_data находится в основной БД (s.db)
+	if req.Preview {
+		sampleSize := req.SampleSize
+		if sampleSize <= 0 {
+			sampleSize = defaultReclassifySampleSize
+		}
+		if sampleSize > maxReclassifySampleSize {
+			sampleSize = maxReclassifySampleSize
+		}
+
+		preview, err := previewReclassifyScope(s.db.GetDB(), where, args, sampleSize)
+		if err != nil {
+			log.Printf("[KPVED] Error previewing scoped reclassification: %v", err)
+			s.writeJSONError(w, "Failed to preview reclassification", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, preview, http.StatusOK)
+		return
+	}
+
+	if active := s.getActiveReclassifyJob(); active != nil {
+		s.writeJSONError(w, fmt.Sprintf("Reclassification job %s is already running", active.ID), http.StatusConflict)
+		return
+	}
+
+	apiKey, model, err := s.workerConfigManager.GetModelAndAPIKey()
+	if err != nil || apiKey == "" {
+		s.writeJSONError(w, fmt.Sprintf("AI API key not configured: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Один AI клиент на задачу - rate limiter работает на уровне экземпляра
+	aiClient := nomenclature.NewAIClient(apiKey, model)
+	hierarchicalClassifier, err := normalization.NewHierarchicalClassifier(s.serviceDB, aiClient)
+	if err != nil {
+		log.Printf("[KPVED] Error creating hierarchical classifier: %v", err)
+		s.writeJSONError(w, fmt.Sprintf("Failed to create classifier: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Цепочка моделей классификации из конфигурации воркеров
+	chain := normalization.NewChainedClassifier(hierarchicalClassifier, s.workerConfigManager.GetModelChain(normalization.ModelChainTaskClassification))
+
+	job := newReclassifyJob(req.Filter, req.Limit)
+	job.Force = req.Force
+	job.modelUsage = chain.Usage
+	job.contentKey = chain.ContentKey()
+	s.reclassifyJobsMutex.Lock()
+	s.reclassifyJobs[job.ID] = job
+	s.reclassifyJobsMutex.Unlock()
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Scoped reclassification job %s created (filter: %s)", job.ID, where),
+		Endpoint:  "/api/kpved/reclassify/scoped",
+	})
+
+	unregister := s.registerAIClient(aiClient, "scoped_reclassify:"+job.ID)
+	go func() {
+		defer unregister()
+		defer s.recoverWorker("scoped_reclassify", nil)
+		s.runReclassifyJob(job, where, args, chain.Model(), s.experimentClassify(hierarchicalClassifier, chain.Classify))
+	}()
+
+	s.writeJSONResponse(w, job.snapshot(), http.StatusAccepted)
+}
+
+// handleKpvedReclassifyJobs список задач выборочной переклассификации
+// GET /api/kpved/reclassify/jobs
+func (s *Server) handleKpvedReclassifyJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.reclassifyJobsMutex.RLock()
+	jobs := make([]ReclassifyJobView, 0, len(s.reclassifyJobs))
+	for _, job := range s.reclassifyJobs {
+		jobs = append(jobs, job.snapshot())
+	}
+	s.reclassifyJobsMutex.RUnlock()
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"jobs":  jobs,
+		"total": len(jobs),
+	}, http.StatusOK)
+}
+
+// handleKpvedReclassifyJobRoutes статус и остановка задачи
+// GET /api/kpved/reclassify/jobs/{id}, POST /api/kpved/reclassify/jobs/{id}/stop
+func (s *Server) handleKpvedReclassifyJobRoutes(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/kpved/reclassify/jobs/"), "/")
+	parts := strings.Split(path, "/")
+	if path == "" || len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.reclassifyJobsMutex.RLock()
+	job := s.reclassifyJobs[parts[0]]
+	s.reclassifyJobsMutex.RUnlock()
+	if job == nil {
+		s.writeJSONError(w, "Reclassification job not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeJSONResponse(w, job.snapshot(), http.StatusOK)
+		return
+	}
+
+	if parts[1] != "stop" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job.requestStop()
+	s.writeJSONResponse(w, job.snapshot(), http.StatusOK)
+}
+
+// getActiveReclassifyJob возвращает выполняющуюся задачу переклассификации
+func (s *Server) getActiveReclassifyJob() *ReclassifyJob {
+	s.reclassifyJobsMutex.RLock()
+	defer s.reclassifyJobsMutex.RUnlock()
+	for _, job := range s.reclassifyJobs {
+		if job.isActive() {
+			return job
+		}
+	}
+	return nil
+}
+
+// runReclassifyJob последовательно переклассифицирует группы, попадающие в фильтр.
+// Записи обновляются только в пределах фильтра, для каждой записывается событие происхождения.
+// Группы, все записи которых классифицированы с теми же входными данными, пропускаются, если не задан Force.
+// Уверенность калибруется для модели model, отклоненные политикой порогов результаты не сохраняются.
+func (s *Server) runReclassifyJob(job *ReclassifyJob, where string, args []interface{}, model string, classify reclassifyFunc) {
+	groups, err := loadReclassifyGroups(s.db.GetDB(), where, args, job.Limit)
+	if err != nil {
+		job.finish(ReclassifyJobFailed, err)
+		return
+	}
+	job.markRunning(len(groups))
+	log.Printf("[KPVED] Scoped reclassification job %s started: %d groups", job.ID, len(groups))
+
+	for _, group := range groups {
+		if job.stopRequested() {
+			log.Printf("[KPVED] Scoped reclassification job %s stopped by user", job.ID)
+			job.finish(ReclassifyJobStopped, nil)
+			return
+		}
+		job.setCurrentGroup(group.normalizedName)
+
+		contentHash := database.ClassificationContentHash(job.contentKey, group.normalizedName, group.category)
+		if !job.Force && group.hash == contentHash {
+			job.addSkipped()
+			continue
+		}
+
+		result, err := classify(group.normalizedName, group.category)
+		if err != nil {
+			log.Printf("[KPVED] Job %s: failed to classify '%s': %v", job.ID, group.normalizedName, err)
+			job.addResult(false, 0)
+			continue
+		}
+
+		if result.Model != "" {
+			model = result.Model
+		}
+		evaluation := s.calibrator.Evaluate(model, 0, result.FinalConfidence)
+		if evaluation.Decision == database.ConfidenceDecisionReject {
+			log.Printf("[KPVED] Job %s: result for '%s' rejected by confidence policy (%.2f)", job.ID, group.normalizedName, evaluation.Confidence)
+			job.addResult(false, 0)
+			continue
+		}
+
+		updated, err := s.applyScopedClassification(job.ID, group, where, args, result, evaluation, contentHash)
+		if err != nil {
+			log.Printf("[KPVED] Job %s: failed to update group '%s': %v", job.ID, group.normalizedName, err)
+			job.addResult(false, 0)
+			continue
+		}
+		job.addResult(true, updated)
+	}
+
+	job.finish(ReclassifyJobCompleted, nil)
+	log.Printf("[KPVED] Scoped reclassification job %s completed (skipped unchanged groups: %d)", job.ID, job.snapshot().Progress.Skipped)
+}
+
+// applyScopedClassification сохраняет результат классификации для записей группы в пределах фильтра
+func (s *Server) applyScopedClassification(jobID string, group reclassifyGroup, where string, args []interface{}, result *normalization.HierarchicalResult, evaluation normalization.ConfidenceEvaluation, contentHash string) (int, error) {
+	if result == nil || result.FinalCode == "" {
+		return 0, errors.New("empty classification result")
+	}
+
+	groupArgs := append([]interface{}{group.normalizedName, group.category}, args...)
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT id, COALESCE(kpved_code, ''), COALESCE(kpved_name, '') FROM normalized_data
+		WHERE COALESCE(normalized_name, '') = ? AND COALESCE(category, '') = ? AND (%s)
+	`, where), groupArgs...)
+	if err != nil {
+		return 0, err
+	}
+	type previousClassification struct {
+		id                   int
+		kpvedCode, kpvedName string
+	}
+	var items []previousClassification
+	for rows.Next() {
+		var item previousClassification
+		if err := rows.Scan(&item.id, &item.kpvedCode, &item.kpvedName); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+
+	for _, item := range items {
+		_, err := s.db.Exec(`UPDATE normalized_data SET kpved_code = ?, kpved_name = ?, kpved_confidence = ?,
+			kpved_raw_confidence = ?, kpved_model = ?, confidence_decision = ?, classification_hash = ? WHERE id = ?`,
+			result.FinalCode, result.FinalName, evaluation.Confidence,
+			result.FinalConfidence, evaluation.Model, evaluation.Decision, contentHash, item.id)
+		if err != nil {
+			return 0, err
+		}
+
+		lineage := database.NewLineageRecord(database.LineageEventClassification, "scoped_reclassify", 0, map[string]interface{}{
+			"job_id":     jobID,
+			"kpved_code": result.FinalCode,
+			"kpved_name": result.FinalName,
+			"confidence": evaluation.Confidence,
+			"decision":   evaluation.Decision,
+			// Прежние значения нужны для восстановления состояния записи на момент времени
+			"changes": []database.NormalizedFieldChange{
+				{Field: "kpved_code", Old: item.kpvedCode, New: result.FinalCode},
+				{Field: "kpved_name", Old: item.kpvedName, New: result.FinalName},
+			},
+		})
+		if err := s.db.AddNormalizedItemLineage(item.id, []*database.LineageRecord{lineage}); err != nil {
+			log.Printf("[KPVED] Job %s: failed to record lineage for item %d: %v", jobID, item.id, err)
+		}
+	}
+
+	return len(items), nil
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/normalization"
+)
+
+func newReclassifyTestDB(t *testing.T) *database.DB {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO normalized_data (code, normalized_name, category, kpved_code, kpved_confidence, merged_count, created_at) VALUES
+			('1', 'болт м8', 'крепеж', '25.94', 0.4, 3, '2026-01-10 10:00:00'),
+			('2', 'болт м8', 'крепеж', '25.94', 0.4, 1, '2026-01-10 11:00:00'),
+			('3', 'гайка м8', 'крепеж', '25.94', 0.9, 1, '2026-02-01 09:00:00'),
+			('4', 'молоко 3.2%', 'продукты', '10.51', 0.3, 1, '2026-01-15 12:00:00'),
+			('5', 'кабель ввг', 'электрика', NULL, 0, 1, '2026-01-20 08:00:00')
+	`)
+	if err != nil {
+		t.Fatalf("Failed to seed normalized_data: %v", err)
+	}
+	return db
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestBuildReclassifyScopeWhere(t *testing.T) {
+	db := newReclassifyTestDB(t)
+	defer db.Close()
+
+	tests := []struct {
+		name      string
+		filter    ReclassifyScopeFilter
+		wantItems int
+		wantErr   bool
+	}{
+		{"empty filter", ReclassifyScopeFilter{}, 5, false},
+		{"code prefix", ReclassifyScopeFilter{KpvedCodePrefix: "25.9"}, 3, false},
+		{"category prefix", ReclassifyScopeFilter{CategoryPrefix: "кре"}, 3, false},
+		{"confidence range", ReclassifyScopeFilter{MinConfidence: floatPtr(0.3), MaxConfidence: floatPtr(0.5)}, 3, false},
+		{"date range inclusive", ReclassifyScopeFilter{DateFrom: "2026-01-10", DateTo: "2026-01-15"}, 3, false},
+		{"date rfc3339", ReclassifyScopeFilter{DateTo: "2026-01-10T10:30:00Z"}, 1, false},
+		{"name substring", ReclassifyScopeFilter{NamePattern: "м8"}, 3, false},
+		{"name wildcard", ReclassifyScopeFilter{NamePattern: "болт*"}, 2, false},
+		{"literal percent", ReclassifyScopeFilter{NamePattern: "3.2%"}, 1, false},
+		{"only unclassified", ReclassifyScopeFilter{OnlyUnclassified: true}, 1, false},
+		{"combined", ReclassifyScopeFilter{KpvedCodePrefix: "25", MaxConfidence: floatPtr(0.5)}, 2, false},
+		{"inverted confidence", ReclassifyScopeFilter{MinConfidence: floatPtr(0.9), MaxConfidence: floatPtr(0.1)}, 0, true},
+		{"bad date", ReclassifyScopeFilter{DateFrom: "10.01.2026"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args, err := buildReclassifyScopeWhere(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildReclassifyScopeWhere() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			preview, err := previewReclassifyScope(db.GetDB(), where, args, 2)
+			if err != nil {
+				t.Fatalf("previewReclassifyScope() error = %v", err)
+			}
+			if got := preview["affected_items"].(int); got != tt.wantItems {
+				t.Errorf("affected_items = %d, want %d (where: %s)", got, tt.wantItems, where)
+			}
+			if sample := preview["sample"].([]map[string]interface{}); len(sample) > 2 {
+				t.Errorf("sample size = %d, want at most 2", len(sample))
+			}
+		})
+	}
+}
+
+func TestRunReclassifyJob(t *testing.T) {
+	db := newReclassifyTestDB(t)
+	defer db.Close()
+	s := &Server{db: db}
+
+	filter := ReclassifyScopeFilter{KpvedCodePrefix: "25.94", MaxConfidence: floatPtr(0.5)}
+	where, args, err := buildReclassifyScopeWhere(filter)
+	if err != nil {
+		t.Fatalf("buildReclassifyScopeWhere() error = %v", err)
+	}
+
+	job := newReclassifyJob(filter, 0)
+	classify := func(name, category string) (*normalization.HierarchicalResult, error) {
+		return &normalization.HierarchicalResult{FinalCode: "25.94.11", FinalName: "Болты", FinalConfidence: 0.95}, nil
+	}
+	s.runReclassifyJob(job, where, args, "test-model", classify)
+
+	view := job.snapshot()
+	if view.Status != ReclassifyJobCompleted {
+		t.Fatalf("status = %s, want completed (error: %s)", view.Status, view.Error)
+	}
+	if view.Progress.TotalGroups != 1 || view.Progress.UpdatedItems != 2 || view.Progress.Percent != 100 {
+		t.Errorf("unexpected progress: %+v", view.Progress)
+	}
+
+	// Гайка с высокой уверенностью не попала в фильтр и не должна измениться
+	var code string
+	if err := db.QueryRow(`SELECT kpved_code FROM normalized_data WHERE code = '3'`).Scan(&code); err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	if code != "25.94" {
+		t.Errorf("item outside scope was updated: %s", code)
+	}
+
+	var lineageCount int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM normalized_item_lineage WHERE reference = 'scoped_reclassify'`).Scan(&lineageCount); err != nil {
+		t.Fatalf("Failed to count lineage: %v", err)
+	}
+	if lineageCount != 2 {
+		t.Errorf("lineage records = %d, want 2", lineageCount)
+	}
+
+	var decision, model string
+	if err := db.QueryRow(`SELECT confidence_decision, kpved_model FROM normalized_data WHERE code = '1'`).Scan(&decision, &model); err != nil {
+		t.Fatalf("Failed to read calibration columns: %v", err)
+	}
+	if decision != database.ConfidenceDecisionAccept || model != "test-model" {
+		t.Errorf("calibration columns = (%s, %s), want (accept, test-model)", decision, model)
+	}
+
+	// Результат ниже порога проверки отклоняется политикой и не сохраняется
+	rejected := newReclassifyJob(ReclassifyScopeFilter{}, 0)
+	s.runReclassifyJob(rejected, "category = ?", []interface{}{"продукты"}, "test-model", func(name, category string) (*normalization.HierarchicalResult, error) {
+		return &normalization.HierarchicalResult{FinalCode: "10.99", FinalName: "Прочее", FinalConfidence: 0.2}, nil
+	})
+	if view := rejected.snapshot(); view.Progress.Failed != 1 || view.Progress.UpdatedItems != 0 {
+		t.Errorf("unexpected rejected job progress: %+v", view.Progress)
+	}
+
+	// Остановленная задача не обрабатывает группы
+	stopped := newReclassifyJob(ReclassifyScopeFilter{}, 0)
+	stopped.requestStop()
+	s.runReclassifyJob(stopped, "1=1", nil, "test-model", func(name, category string) (*normalization.HierarchicalResult, error) {
+		return nil, errors.New("should not be called")
+	})
+	if view := stopped.snapshot(); view.Status != ReclassifyJobStopped || view.Progress.ProcessedGroups != 0 {
+		t.Errorf("unexpected stopped job state: %+v", view)
+	}
+}
+
+func TestRunReclassifyJobSkipsUnchangedGroups(t *testing.T) {
+	db := newReclassifyTestDB(t)
+	defer db.Close()
+	s := &Server{db: db}
+
+	where, args, err := buildReclassifyScopeWhere(ReclassifyScopeFilter{CategoryPrefix: "крепеж"})
+	if err != nil {
+		t.Fatalf("buildReclassifyScopeWhere() error = %v", err)
+	}
+	calls := 0
+	classify := func(name, category string) (*normalization.HierarchicalResult, error) {
+		calls++
+		return &normalization.HierarchicalResult{FinalCode: "25.94.11", FinalName: "Болты", FinalConfidence: 0.95}, nil
+	}
+	run := func(contentKey string, force bool) ReclassifyProgress {
+		job := newReclassifyJob(ReclassifyScopeFilter{CategoryPrefix: "крепеж"}, 0)
+		job.contentKey = contentKey
+		job.Force = force
+		s.runReclassifyJob(job, where, args, "test-model", classify)
+		return job.snapshot().Progress
+	}
+
+	tests := []struct {
+		name        string
+		contentKey  string
+		force       bool
+		wantSkipped int
+		wantCalls   int
+	}{
+		{"first run classifies all groups", "v1", false, 0, 2},
+		{"rerun skips unchanged groups", "v1", false, 2, 0},
+		{"force reclassifies unchanged groups", "v1", true, 0, 2},
+		{"changed prompt or model invalidates hashes", "v2", false, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+			progress := run(tt.contentKey, tt.force)
+			if progress.Skipped != tt.wantSkipped || calls != tt.wantCalls || progress.ProcessedGroups != 2 {
+				t.Errorf("progress = %+v, classify calls = %d, want skipped %d and %d calls", progress, calls, tt.wantSkipped, tt.wantCalls)
+			}
+		})
+	}
+
+	// Измененное наименование одной записи делает группу неоднородной: она классифицируется заново
+	if _, err := db.Exec(`UPDATE normalized_data SET classification_hash = '' WHERE code = '2'`); err != nil {
+		t.Fatalf("Failed to reset hash: %v", err)
+	}
+	calls = 0
+	if progress := run("v2", false); progress.Skipped != 1 || calls != 1 {
+		t.Errorf("progress after partial change = %+v, calls = %d, want 1 skipped and 1 call", progress, calls)
+	}
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kpvedTreeNode узел кэшированного дерева КПВЭД
+type kpvedTreeNode struct {
+	code       string
+	name       string
+	nameLower  string
+	parentCode string
+	level      int
+	children   []*kpvedTreeNode
+}
+
+// kpvedTreeCache дерево классификатора КПВЭД в памяти.
+// Загружается из сервисной БД при первом обращении и сбрасывается при перезагрузке классификатора.
+type kpvedTreeCache struct {
+	mu       sync.RWMutex
+	loaded   bool
+	loadedAt time.Time
+	nodes    map[string]*kpvedTreeNode
+	ordered  []*kpvedTreeNode
+	roots    []*kpvedTreeNode
+}
+
+// kpvedAutocompleteItem результат автодополнения с рангом совпадения
+type kpvedAutocompleteItem struct {
+	node  *kpvedTreeNode
+	rank  int
+	match string
+}
+
+// Ранги совпадений для автодополнения (больше - выше в выдаче)
+const (
+	kpvedRankCodeExact  = 100
+	kpvedRankCodePrefix = 80
+	kpvedRankNamePrefix = 60
+	kpvedRankNameWord   = 50
+	kpvedRankNameSubstr = 40
+)
+
+// invalidate сбрасывает кэш дерева
+func (c *kpvedTreeCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loaded = false
+	c.nodes = nil
+	c.ordered = nil
+	c.roots = nil
+}
+
+// ensureLoaded загружает дерево из БД, если оно еще не загружено
+func (c *kpvedTreeCache) ensureLoaded(db *sql.DB) error {
+	c.mu.RLock()
+	loaded := c.loaded
+	c.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loaded {
+		return nil
+	}
+
+	rows, err := db.Query("SELECT code, name, parent_code, level FROM kpved_classifier ORDER BY code")
+	if err != nil {
+		return fmt.Errorf("failed to load kpved tree: %w", err)
+	}
+	defer rows.Close()
+
+	nodes := make(map[string]*kpvedTreeNode)
+	var ordered []*kpvedTreeNode
+	for rows.Next() {
+		var parentCode sql.NullString
+		node := &kpvedTreeNode{}
+		if err := rows.Scan(&node.code, &node.name, &parentCode, &node.level); err != nil {
+			return fmt.Errorf("failed to scan kpved node: %w", err)
+		}
+		node.parentCode = parentCode.String
+		node.nameLower = strings.ToLower(node.name)
+		nodes[node.code] = node
+		ordered = append(ordered, node)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate kpved tree: %w", err)
+	}
+
+	var roots []*kpvedTreeNode
+	for _, node := range ordered {
+		if parent, ok := nodes[node.parentCode]; ok && node.parentCode != "" {
+			parent.children = append(parent.children, node)
+		} else if node.level == 1 {
+			// Верхний уровень - классы без родителя (как и раньше в /api/kpved/hierarchy)
+			roots = append(roots, node)
+		}
+	}
+
+	c.nodes = nodes
+	c.ordered = ordered
+	c.roots = roots
+	c.loaded = true
+	c.loadedAt = time.Now()
+	log.Printf("KPVED tree cached: %d nodes", len(nodes))
+
+	return nil
+}
+
+// children возвращает дочерние узлы (для пустого кода - верхний уровень)
+func (c *kpvedTreeCache) children(parentCode string) []*kpvedTreeNode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if parentCode == "" {
+		return c.roots
+	}
+	if parent, ok := c.nodes[parentCode]; ok {
+		return parent.children
+	}
+	return nil
+}
+
+// atLevel возвращает узлы указанного уровня
+func (c *kpvedTreeCache) atLevel(level int) []*kpvedTreeNode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []*kpvedTreeNode
+	for _, node := range c.ordered {
+		if node.level == level {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// path возвращает коды предков узла от верхнего уровня к ближайшему родителю
+func (c *kpvedTreeCache) path(code string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	path := []string{}
+	node, ok := c.nodes[code]
+	for ok && node.parentCode != "" && len(path) < 16 {
+		path = append([]string{node.parentCode}, path...)
+		node, ok = c.nodes[node.parentCode]
+	}
+	return path
+}
+
+// search ищет узлы по префиксу кода и подстроке наименования с ранжированием
+func (c *kpvedTreeCache) search(query string, limit int) []kpvedAutocompleteItem {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	queryLower := strings.ToLower(strings.TrimSpace(query))
+	codeQuery := strings.ToUpper(strings.ReplaceAll(queryLower, " ", ""))
+	if queryLower == "" {
+		return nil
+	}
+
+	var results []kpvedAutocompleteItem
+	for _, node := range c.ordered {
+		item := kpvedAutocompleteItem{node: node}
+		switch {
+		case node.code == codeQuery:
+			item.rank, item.match = kpvedRankCodeExact, "code"
+		case strings.HasPrefix(node.code, codeQuery):
+			item.rank, item.match = kpvedRankCodePrefix, "code"
+		case strings.HasPrefix(node.nameLower, queryLower):
+			item.rank, item.match = kpvedRankNamePrefix, "name"
+		case strings.Contains(node.nameLower, " "+queryLower):
+			item.rank, item.match = kpvedRankNameWord, "name"
+		case strings.Contains(node.nameLower, queryLower):
+			item.rank, item.match = kpvedRankNameSubstr, "name"
+		default:
+			continue
+		}
+		results = append(results, item)
+	}
+
+	// Выше ранг, затем более общие узлы (короче код), затем по коду
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank > results[j].rank
+		}
+		if len(results[i].node.code) != len(results[j].node.code) {
+			return len(results[i].node.code) < len(results[j].node.code)
+		}
+		return results[i].node.code < results[j].node.code
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// toMap формирует представление узла для ответа API
+func (n *kpvedTreeNode) toMap() map[string]interface{} {
+	node := map[string]interface{}{
+		"code":           n.code,
+		"name":           n.name,
+		"level":          n.level,
+		"has_children":   len(n.children) > 0,
+		"children_count": len(n.children),
+	}
+	if n.parentCode != "" {
+		node["parent_code"] = n.parentCode
+	}
+	return node
+}
+
+// handleKpvedHierarchy возвращает один уровень иерархии КПВЭД (дочерние узлы загружаются лениво)
+// GET /api/kpved/hierarchy?parent=01.1 или ?level=2
+func (s *Server) handleKpvedHierarchy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Используем сервисную БД для классификатора КПВЭД
+	if err := s.kpvedTree.ensureLoaded(s.serviceDB.GetDB()); err != nil {
+		log.Printf("Error loading kpved tree: %v", err)
+		s.writeJSONError(w, "Failed to fetch KPVED hierarchy", http.StatusInternalServerError)
+		return
+	}
+
+	parentCode := r.URL.Query().Get("parent")
+	level := r.URL.Query().Get("level")
+
+	var children []*kpvedTreeNode
+	if parentCode == "" && level != "" {
+		levelInt, err := strconv.Atoi(level)
+		if err != nil {
+			s.writeJSONError(w, "Invalid level", http.StatusBadRequest)
+			return
+		}
+		children = s.kpvedTree.atLevel(levelInt)
+	} else {
+		children = s.kpvedTree.children(parentCode)
+	}
+
+	nodes := make([]map[string]interface{}, 0, len(children))
+	for _, child := range children {
+		nodes = append(nodes, child.toMap())
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"nodes": nodes,
+		"total": len(nodes),
+	}, http.StatusOK)
+}
+
+// handleKpvedAutocomplete автодополнение кодов КПВЭД: префикс кода или подстрока наименования
+// GET /api/kpved/autocomplete?q=01.1&limit=10
+func (s *Server) handleKpvedAutocomplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		s.writeJSONError(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 10
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	if err := s.kpvedTree.ensureLoaded(s.serviceDB.GetDB()); err != nil {
+		log.Printf("Error loading kpved tree: %v", err)
+		s.writeJSONError(w, "Failed to search KPVED", http.StatusInternalServerError)
+		return
+	}
+
+	results := s.kpvedTree.search(query, limit)
+	items := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		item := result.node.toMap()
+		item["match"] = result.match
+		item["rank"] = result.rank
+		item["path"] = s.kpvedTree.path(result.node.code)
+		items = append(items, item)
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"items": items,
+		"total": len(items),
+	}, http.StatusOK)
+}
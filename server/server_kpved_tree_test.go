@@ -0,0 +1,85 @@
+package server
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newKpvedTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE kpved_classifier (code TEXT, name TEXT, parent_code TEXT, level INTEGER);
+		INSERT INTO kpved_classifier VALUES
+			('01', 'Продукция сельского хозяйства', NULL, 1),
+			('01.1', 'Культуры однолетние', '01', 2),
+			('01.11', 'Зерновые культуры', '01.1', 3),
+			('25', 'Изделия металлические готовые', NULL, 1),
+			('25.9', 'Изделия металлические прочие', '25', 2);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to seed kpved: %v", err)
+	}
+	return db
+}
+
+func TestKpvedTreeCache(t *testing.T) {
+	db := newKpvedTestDB(t)
+	defer db.Close()
+
+	var cache kpvedTreeCache
+	if err := cache.ensureLoaded(db); err != nil {
+		t.Fatalf("ensureLoaded failed: %v", err)
+	}
+
+	if roots := cache.children(""); len(roots) != 2 {
+		t.Errorf("expected 2 root nodes, got %d", len(roots))
+	}
+	if children := cache.children("01.1"); len(children) != 1 || children[0].code != "01.11" {
+		t.Errorf("unexpected children of 01.1: %v", children)
+	}
+	if path := cache.path("01.11"); len(path) != 2 || path[0] != "01" || path[1] != "01.1" {
+		t.Errorf("unexpected path: %v", path)
+	}
+
+	tests := []struct {
+		query     string
+		wantFirst string
+		wantTotal int
+	}{
+		{"01", "01", 3},
+		{"01.1", "01.1", 2},
+		{"изделия", "25", 2},
+		{"металлические", "25", 2},
+		{"зерн", "01.11", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results := cache.search(tt.query, 10)
+			if len(results) != tt.wantTotal {
+				t.Fatalf("expected %d results, got %d", tt.wantTotal, len(results))
+			}
+			if results[0].node.code != tt.wantFirst {
+				t.Errorf("expected first result %s, got %s", tt.wantFirst, results[0].node.code)
+			}
+		})
+	}
+
+	// После инвалидации дерево перечитывается из БД
+	if _, err := db.Exec(`INSERT INTO kpved_classifier VALUES ('02', 'Продукция лесоводства', NULL, 1)`); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	cache.invalidate()
+	if err := cache.ensureLoaded(db); err != nil {
+		t.Fatalf("ensureLoaded failed: %v", err)
+	}
+	if roots := cache.children(""); len(roots) != 3 {
+		t.Errorf("expected 3 root nodes after reload, got %d", len(roots))
+	}
+}
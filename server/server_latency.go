@@ -0,0 +1,317 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"httpserver/database"
+	"httpserver/tracing"
+
+	"github.com/google/uuid"
+)
+
+// Ограничения выборки журнала медленных запросов
+const (
+	slowRequestsDefaultLimit = 50
+	slowRequestsMaxLimit     = 1000
+)
+
+// latencyBucketsMs верхние границы корзин гистограммы задержек в миллисекундах; последняя корзина не ограничена
+var latencyBucketsMs = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// latencyHistogram гистограмма задержек одного эндпоинта
+type latencyHistogram struct {
+	counts []int64 // len(latencyBucketsMs)+1, последняя - сверх максимальной границы
+	count  int64
+	sumMs  float64
+	maxMs  float64
+}
+
+func (h *latencyHistogram) observe(ms float64) {
+	if h.counts == nil {
+		h.counts = make([]int64, len(latencyBucketsMs)+1)
+	}
+	h.counts[sort.SearchFloat64s(latencyBucketsMs, ms)]++
+	h.count++
+	h.sumMs += ms
+	if ms > h.maxMs {
+		h.maxMs = ms
+	}
+}
+
+// quantile оценивает квантиль линейной интерполяцией внутри корзины
+func (h *latencyHistogram) quantile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := q * float64(h.count)
+	var cumulative int64
+	for i, c := range h.counts {
+		if c == 0 || float64(cumulative+c) < rank {
+			cumulative += c
+			continue
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = latencyBucketsMs[i-1]
+		}
+		upper := h.maxMs
+		if i < len(latencyBucketsMs) && latencyBucketsMs[i] < upper {
+			upper = latencyBucketsMs[i]
+		}
+		if upper < lower {
+			return upper
+		}
+		return lower + (upper-lower)*(rank-float64(cumulative))/float64(c)
+	}
+	return h.maxMs
+}
+
+// LatencyBucket корзина гистограммы: число запросов не дольше Le мс ("+Inf" - все запросы)
+type LatencyBucket struct {
+	Le    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// EndpointLatency распределение задержек эндпоинта с запуска сервера
+type EndpointLatency struct {
+	Method  string          `json:"method"`
+	Route   string          `json:"route"`
+	Count   int64           `json:"count"`
+	AvgMs   float64         `json:"avg_ms"`
+	P50Ms   float64         `json:"p50_ms"`
+	P95Ms   float64         `json:"p95_ms"`
+	P99Ms   float64         `json:"p99_ms"`
+	MaxMs   float64         `json:"max_ms"`
+	Buckets []LatencyBucket `json:"buckets,omitempty"`
+}
+
+// endpointLatency гистограммы задержек по эндпоинтам. Нулевое значение готово к использованию
+type endpointLatency struct {
+	mu     sync.Mutex
+	routes map[[2]string]*latencyHistogram
+}
+
+func (l *endpointLatency) observe(method, route string, duration time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.routes == nil {
+		l.routes = make(map[[2]string]*latencyHistogram)
+	}
+	key := [2]string{route, method}
+	h := l.routes[key]
+	if h == nil {
+		h = &latencyHistogram{}
+		l.routes[key] = h
+	}
+	h.observe(float64(duration) / float64(time.Millisecond))
+}
+
+// snapshot возвращает распределения, отсортированные по маршруту; route не пустой - только этот маршрут
+func (l *endpointLatency) snapshot(route string, withBuckets bool) []EndpointLatency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make([]EndpointLatency, 0, len(l.routes))
+	for key, h := range l.routes {
+		if route != "" && key[0] != route {
+			continue
+		}
+		item := EndpointLatency{
+			Method: key[1],
+			Route:  key[0],
+			Count:  h.count,
+			AvgMs:  roundMs(h.sumMs / float64(h.count)),
+			P50Ms:  roundMs(h.quantile(0.50)),
+			P95Ms:  roundMs(h.quantile(0.95)),
+			P99Ms:  roundMs(h.quantile(0.99)),
+			MaxMs:  roundMs(h.maxMs),
+		}
+		if withBuckets {
+			var cumulative int64
+			for i, c := range h.counts {
+				cumulative += c
+				le := "+Inf"
+				if i < len(latencyBucketsMs) {
+					le = fmt.Sprint(latencyBucketsMs[i])
+				}
+				item.Buckets = append(item.Buckets, LatencyBucket{Le: le, Count: cumulative})
+			}
+		}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Route != result[j].Route {
+			return result[i].Route < result[j].Route
+		}
+		return result[i].Method < result[j].Method
+	})
+	return result
+}
+
+// roundMs округляет миллисекунды до сотых
+func roundMs(ms float64) float64 {
+	return float64(int64(ms*100+0.5)) / 100
+}
+
+type requestUploadKey struct{}
+
+// requestUpload выгрузка, к которой относится запрос; заполняется обработчиком
+type requestUpload struct {
+	mu   sync.Mutex
+	uuid string
+}
+
+// noteRequestUpload отмечает выгрузку запроса для журнала медленных запросов
+func noteRequestUpload(r *http.Request, uploadUUID string) {
+	if holder, ok := r.Context().Value(requestUploadKey{}).(*requestUpload); ok {
+		holder.mu.Lock()
+		holder.uuid = uploadUUID
+		holder.mu.Unlock()
+	}
+}
+
+// latencyRecorder запоминает код ответа
+type latencyRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *latencyRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush поддерживает потоковые ответы (SSE) через обертку
+func (r *latencyRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter
+func (r *latencyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// latencyMiddleware учитывает задержку запроса в гистограмме маршрута и сохраняет запросы дольше
+// порога в журнал медленных запросов. Потоки SSE не учитываются: их длительность - время подписки
+func (s *Server) latencyMiddleware(next http.Handler, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		holder := &requestUpload{}
+		recorder := &latencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), requestUploadKey{}, holder)))
+		duration := time.Since(start)
+
+		if strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+		pattern := route(r)
+		s.endpointLatency.observe(r.Method, pattern, duration)
+
+		threshold := s.currentConfig().SlowRequestThreshold
+		if threshold <= 0 || duration < threshold {
+			return
+		}
+		holder.mu.Lock()
+		uploadUUID := holder.uuid
+		holder.mu.Unlock()
+		if uploadUUID == "" {
+			uploadUUID = uploadUUIDFromPath(r.URL.Path)
+		}
+		s.recordSlowRequest(&database.SlowRequest{
+			Method:     r.Method,
+			Route:      pattern,
+			Path:       r.URL.Path,
+			UploadUUID: uploadUUID,
+			Status:     recorder.status,
+			DurationMs: duration.Milliseconds(),
+			TraceID:    tracing.TraceIDFromContext(r.Context()),
+		})
+	})
+}
+
+// uploadUUIDFromPath извлекает UUID выгрузки из путей вида /api/uploads/{uuid}/...
+func uploadUUIDFromPath(path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/uploads/")
+	if !ok {
+		return ""
+	}
+	candidate, _, _ := strings.Cut(rest, "/")
+	if _, err := uuid.Parse(candidate); err != nil {
+		return ""
+	}
+	return candidate
+}
+
+// recordSlowRequest пишет медленный запрос в лог и в журнал service.db
+func (s *Server) recordSlowRequest(request *database.SlowRequest) {
+	s.log(LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "WARN",
+		Message:    fmt.Sprintf("Медленный запрос %s %s: %d мс (статус %d)", request.Method, request.Path, request.DurationMs, request.Status),
+		UploadUUID: request.UploadUUID,
+		Endpoint:   request.Route,
+		TraceID:    request.TraceID,
+	})
+	if s.serviceDB == nil {
+		return
+	}
+	if err := s.serviceDB.RecordSlowRequest(request, s.currentConfig().SlowRequestLogSize); err != nil {
+		log.Printf("Не удалось сохранить медленный запрос %s: %v", request.Path, err)
+	}
+}
+
+// handleMonitoringLatency GET /api/monitoring/latency - перцентили задержек по эндпоинтам (?route= - один маршрут)
+func (s *Server) handleMonitoringLatency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	endpoints := s.endpointLatency.snapshot(r.URL.Query().Get("route"), r.URL.Query().Get("buckets") == "true")
+	s.writeJSONResponse(w, map[string]interface{}{
+		"endpoints":         endpoints,
+		"total":             len(endpoints),
+		"slow_threshold_ms": s.currentConfig().SlowRequestThreshold.Milliseconds(),
+		"latency_bucket_ms": latencyBucketsMs,
+		"collected_since":   s.startTime,
+	}, http.StatusOK)
+}
+
+// handleMonitoringSlow GET /api/monitoring/slow - журнал медленных запросов (?route=, ?upload_uuid=, ?limit=)
+func (s *Server) handleMonitoringSlow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit, err := boundedIntParam(r.URL.Query().Get("limit"), slowRequestsDefaultLimit, slowRequestsMaxLimit)
+	if err != nil {
+		s.writeJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+		return
+	}
+	requests, err := s.serviceDB.GetSlowRequests(database.SlowRequestFilter{
+		Route:      r.URL.Query().Get("route"),
+		UploadUUID: r.URL.Query().Get("upload_uuid"),
+		Limit:      limit,
+	})
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"requests":          requests,
+		"total":             len(requests),
+		"slow_threshold_ms": s.currentConfig().SlowRequestThreshold.Milliseconds(),
+	}, http.StatusOK)
+}
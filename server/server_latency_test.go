@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogramQuantile(t *testing.T) {
+	var h latencyHistogram
+	for i := 1; i <= 100; i++ {
+		h.observe(float64(i)) // 1..100 мс
+	}
+	h.observe(4000)
+
+	tests := []struct {
+		name     string
+		q        float64
+		min, max float64
+	}{
+		{"p50", 0.50, 40, 60},
+		{"p95", 0.95, 50, 100},
+		{"p99", 0.99, 50, 100},
+		{"max bucket bounded by observed max", 1, 2500, 4000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.quantile(tt.q); got < tt.min || got > tt.max {
+				t.Errorf("quantile(%v) = %v, want in [%v, %v]", tt.q, got, tt.min, tt.max)
+			}
+		})
+	}
+	if got := (&latencyHistogram{}).quantile(0.5); got != 0 {
+		t.Errorf("empty quantile = %v, want 0", got)
+	}
+}
+
+func TestLatencyMiddleware(t *testing.T) {
+	s := newCrashTestServer(t)
+	s.config.SlowRequestThreshold = time.Nanosecond
+	s.config.SlowRequestLogSize = 100
+
+	const uploadUUID = "5f0c1a2e-1b7d-4c8a-9e3f-2a6b7c8d9e0f"
+	handler := s.latencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/catalog/items":
+			noteRequestUpload(r, uploadUUID)
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/api/monitoring/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+		}
+	}), func(r *http.Request) string { return r.URL.Path })
+
+	for _, path := range []string{"/catalog/items", "/catalog/items", "/stats", "/api/monitoring/events"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	endpoints := s.endpointLatency.snapshot("", true)
+	if len(endpoints) != 2 || endpoints[0].Route != "/catalog/items" || endpoints[0].Count != 2 {
+		t.Fatalf("endpoints = %+v, want /catalog/items x2 and /stats, SSE excluded", endpoints)
+	}
+	if buckets := endpoints[0].Buckets; len(buckets) != len(latencyBucketsMs)+1 || buckets[len(buckets)-1].Count != 2 {
+		t.Errorf("buckets = %+v, want cumulative counts ending with total", buckets)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"all", "", 3},
+		{"by route", "?route=/stats", 1},
+		{"by upload", "?upload_uuid=" + uploadUUID, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleMonitoringSlow(rec, httptest.NewRequest(http.MethodGet, "/api/monitoring/slow"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			var resp struct {
+				Requests []struct {
+					Status int `json:"status"`
+				} `json:"requests"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Requests) != tt.want {
+				t.Errorf("requests = %d, want %d", len(resp.Requests), tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadUUIDFromPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/uploads/5f0c1a2e-1b7d-4c8a-9e3f-2a6b7c8d9e0f/data", "5f0c1a2e-1b7d-4c8a-9e3f-2a6b7c8d9e0f"},
+		{"/api/uploads/5f0c1a2e-1b7d-4c8a-9e3f-2a6b7c8d9e0f", "5f0c1a2e-1b7d-4c8a-9e3f-2a6b7c8d9e0f"},
+		{"/api/uploads/index", ""},
+		{"/api/normalized/uploads/5f0c1a2e-1b7d-4c8a-9e3f-2a6b7c8d9e0f", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := uploadUUIDFromPath(tt.path); got != tt.want {
+				t.Errorf("uploadUUIDFromPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+// nomenclatureReconcileRequest запрос запуска сверки номенклатуры
+type nomenclatureReconcileRequest struct {
+	UploadID int    `json:"upload_id"` // 0 - все выгрузки с данными в обеих таблицах
+	Mode     string `json:"mode"`      // report (по умолчанию) или backfill
+}
+
+// startNomenclatureReconciliation запускает сверку фоновой задачей; одновременно для одной области
+// выполняется только одна сверка
+func (s *Server) startNomenclatureReconciliation(r *http.Request, req nomenclatureReconcileRequest) (*database.NomenclatureReconciliation, error) {
+	run, err := s.db.CreateNomenclatureReconciliation(req.UploadID, req.Mode)
+	if err != nil {
+		return nil, err
+	}
+	name := "all uploads"
+	if run.UploadID > 0 {
+		name = fmt.Sprintf("upload %d", run.UploadID)
+	}
+	job, err := s.startBackgroundJob(r.Context(), jobKindNomenclatureReconcile, name)
+	if err != nil {
+		if finishErr := s.db.FinishNomenclatureReconciliation(run, err); finishErr != nil {
+			s.log(LogEntry{Timestamp: time.Now(), Level: "ERROR", Message: finishErr.Error(), Endpoint: "/api/nomenclature/reconciliation"})
+		}
+		return nil, err
+	}
+
+	snapshot := *run
+	go func() {
+		var runErr error
+		defer func() { s.finishBackgroundJob(job, runErr) }()
+		defer s.recoverWorker(jobKindNomenclatureReconcile, job)
+
+		runErr = s.db.RunNomenclatureReconciliation(job.ctx, run)
+		level, message := "INFO", fmt.Sprintf("Сверка номенклатуры %d (%s): выгрузок %d, совпало %d, нет в catalog_items %d, нет в nomenclature_items %d, различается %d, дополнено %d",
+			run.ID, run.Mode, run.Uploads, run.Matched, run.CatalogMissing, run.NomenclatureMissing, run.Mismatched, run.Backfilled)
+		if runErr != nil {
+			level, message = "ERROR", fmt.Sprintf("Сверка номенклатуры %d остановлена ошибкой: %v", run.ID, runErr)
+		}
+		s.log(LogEntry{Timestamp: time.Now(), Level: level, Message: message, Endpoint: "/api/nomenclature/reconciliation"})
+	}()
+	return &snapshot, nil
+}
+
+// handleNomenclatureReconciliations список сверок nomenclature_items с catalog_items или запуск новой сверки
+// GET /api/nomenclature/reconciliation?limit=20
+// POST /api/nomenclature/reconciliation {"upload_id": 0, "mode": "report|backfill"}
+func (s *Server) handleNomenclatureReconciliations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		limit := 20
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 1000 {
+			limit = v
+		}
+		runs, err := s.db.GetNomenclatureReconciliations(limit)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"reconciliations": runs,
+			"total":           len(runs),
+		}, http.StatusOK)
+	case http.MethodPost:
+		var req nomenclatureReconcileRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		run, err := s.startNomenclatureReconciliation(r, req)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, run, http.StatusAccepted)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleNomenclatureReconciliationRoutes итоги сверки и найденные расхождения
+// GET /api/nomenclature/reconciliation/{id}
+// GET /api/nomenclature/reconciliation/{id}/issues?kind=catalog_missing|nomenclature_missing|mismatch&limit=100&offset=0
+func (s *Server) handleNomenclatureReconciliationRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/nomenclature/reconciliation/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || id <= 0 {
+		s.writeJSONError(w, "Invalid reconciliation ID", http.StatusBadRequest)
+		return
+	}
+	run, err := s.db.GetNomenclatureReconciliation(id)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.writeJSONResponse(w, run, http.StatusOK)
+	case len(parts) == 2 && parts[1] == "issues":
+		query := r.URL.Query()
+		kind := query.Get("kind")
+		switch kind {
+		case "", database.NomenclatureIssueCatalogMissing, database.NomenclatureIssueNomenclatureMissing, database.NomenclatureIssueMismatch:
+		default:
+			s.writeJSONError(w, fmt.Sprintf("Invalid kind: %s", kind), http.StatusBadRequest)
+			return
+		}
+		limit, offset := 100, 0
+		if v, err := strconv.Atoi(query.Get("limit")); err == nil && v > 0 && v <= 1000 {
+			limit = v
+		}
+		if v, err := strconv.Atoi(query.Get("offset")); err == nil && v > 0 {
+			offset = v
+		}
+		issues, total, err := s.db.GetNomenclatureReconciliationIssues(id, kind, limit, offset)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"reconciliation": run,
+			"issues":         issues,
+			"total":          total,
+			"limit":          limit,
+			"offset":         offset,
+		}, http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestNomenclatureReconciliationAPI(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+	upload, err := db.CreateUpload("reconcile", "8.3", "УТ")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO nomenclature_items (upload_id, nomenclature_reference, nomenclature_code, nomenclature_name) VALUES (?, 'ref-1', '001', 'Болт М8');
+		INSERT INTO catalogs (upload_id, name, synonym) VALUES (?, 'Номенклатура', '');
+		INSERT INTO catalog_items (catalog_id, reference, code, name) VALUES (1, 'ref-2', '002', 'Гайка М8');
+	`, upload.ID, upload.ID); err != nil {
+		t.Fatalf("Failed to seed data: %v", err)
+	}
+	s := &Server{db: db, config: &Config{}, logChan: make(chan LogEntry, 10)}
+
+	rec := httptest.NewRecorder()
+	s.handleNomenclatureReconciliations(rec, httptest.NewRequest(http.MethodPost, "/api/nomenclature/reconciliation",
+		strings.NewReader(`{"mode": "backfill"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("POST status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var run database.NomenclatureReconciliation
+	if err := json.Unmarshal(rec.Body.Bytes(), &run); err != nil || run.ID == 0 {
+		t.Fatalf("POST body = %s, error = %v", rec.Body.String(), err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		saved, err := db.GetNomenclatureReconciliation(run.ID)
+		if err != nil {
+			t.Fatalf("GetNomenclatureReconciliation() error = %v", err)
+		}
+		if saved.Status != database.NomenclatureReconcileRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reconciliation did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		method     string
+		path       string
+		wantStatus int
+		wantBody   []string
+	}{
+		{"list", s.handleNomenclatureReconciliations, http.MethodGet, "/api/nomenclature/reconciliation", http.StatusOK,
+			[]string{`"total":1`, `"status":"completed"`, `"backfilled":2`}},
+		{"run", s.handleNomenclatureReconciliationRoutes, http.MethodGet, "/api/nomenclature/reconciliation/1", http.StatusOK,
+			[]string{`"catalog_missing":1`, `"nomenclature_missing":1`}},
+		{"issues by kind", s.handleNomenclatureReconciliationRoutes, http.MethodGet, "/api/nomenclature/reconciliation/1/issues?kind=nomenclature_missing", http.StatusOK,
+			[]string{`"total":1`, `"reference":"ref-2"`, `"resolution":"backfilled"`}},
+		{"invalid kind", s.handleNomenclatureReconciliationRoutes, http.MethodGet, "/api/nomenclature/reconciliation/1/issues?kind=other", http.StatusBadRequest, nil},
+		{"unknown run", s.handleNomenclatureReconciliationRoutes, http.MethodGet, "/api/nomenclature/reconciliation/99", http.StatusNotFound, nil},
+		{"invalid mode", s.handleNomenclatureReconciliations, http.MethodPost, "/api/nomenclature/reconciliation", http.StatusBadRequest, nil},
+		{"unknown upload", s.handleNomenclatureReconciliations, http.MethodPost, "/api/nomenclature/reconciliation", http.StatusNotFound, nil},
+	}
+	bodies := map[string]string{"invalid mode": `{"mode": "delete"}`, "unknown upload": `{"upload_id": 42}`}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(bodies[tt.name])))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body = %s, want %q", rec.Body.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// Запись истории событий нормализации пакетами
+const (
+	normalizerEventsFlushInterval = time.Second
+	normalizerEventsFlushBatch    = 100
+)
+
+// normalizerEventLevel уровень события по тексту сообщения: нормализатор передает только строки
+func normalizerEventLevel(message string) string {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "ошибк") || strings.Contains(lower, "error") || strings.HasPrefix(message, "✗"):
+		return database.NormalizationEventError
+	case strings.Contains(lower, "предупреждение") || strings.Contains(lower, "warning") || strings.HasPrefix(message, "⚠"):
+		return database.NormalizationEventWarn
+	}
+	return database.NormalizationEventInfo
+}
+
+// normalizerHistoryEnabled проверяет, ведется ли история событий нормализации
+func (s *Server) normalizerHistoryEnabled() bool {
+	return s.serviceDB != nil && s.currentConfig() != nil && s.currentConfig().NormalizerEventsHistoryLimit > 0
+}
+
+// runNormalizerEventsPump читает события нормализации этого экземпляра, сразу передает их SSE потоку
+// и другим экземплярам, а в историю записывает пакетами. События других экземпляров в историю
+// не записываются: каждый экземпляр сохраняет свои события в общую service.db
+func (s *Server) runNormalizerEventsPump() {
+	ticker := time.NewTicker(normalizerEventsFlushInterval)
+	defer ticker.Stop()
+
+	var pending []database.NormalizationEvent
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		if err := s.serviceDB.AddNormalizationEvents(pending, s.currentConfig().NormalizerEventsHistoryLimit); err != nil {
+			log.Printf("Ошибка записи истории событий нормализации: %v", err)
+		}
+		pending = nil
+	}
+	defer flush()
+
+	for {
+		select {
+		case event := <-s.normalizerEvents:
+			if s.normalizerHistoryEnabled() {
+				pending = append(pending, database.NormalizationEvent{
+					SessionID:  int(s.normalizerSessionID.Load()),
+					InstanceID: s.instanceID,
+					Level:      normalizerEventLevel(event),
+					Message:    event,
+					CreatedAt:  time.Now(),
+				})
+				if len(pending) >= normalizerEventsFlushBatch {
+					flush()
+				}
+			}
+			if s.clusterRelayEnabled() {
+				if err := s.serviceDB.PublishClusterEvent(s.instanceID, database.ClusterChannelNormalizer, event); err != nil {
+					log.Printf("Ошибка публикации события нормализации: %v", err)
+				}
+			}
+			s.deliverNormalizerEvent(event)
+		case <-ticker.C:
+			flush()
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// handleNormalizationEventsHistory история событий нормализации и классификации, последние первыми.
+// session_id - фоновая задача запуска нормализации (см. /api/jobs); живые события - SSE /api/normalize/events
+// GET /api/normalization/events/history?session_id=12&level=ERROR&q=КПВЭД&since=2025-01-01&until=2025-01-31&limit=100&offset=0
+func (s *Server) handleNormalizationEventsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := database.NormalizationEventFilter{
+		Level: strings.ToUpper(query.Get("level")),
+		Query: query.Get("q"),
+		Limit: 100,
+	}
+	if v := query.Get("session_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			s.writeJSONError(w, "Invalid session_id", http.StatusBadRequest)
+			return
+		}
+		filter.SessionID = id
+	}
+	switch filter.Level {
+	case "", database.NormalizationEventInfo, database.NormalizationEventWarn, database.NormalizationEventError:
+	default:
+		s.writeJSONError(w, fmt.Sprintf("Invalid level: %s (expected INFO, WARN or ERROR)", filter.Level), http.StatusBadRequest)
+		return
+	}
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := parseDateParam(value, name == "until")
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Invalid %s: %s (expected RFC3339 or YYYY-MM-DD)", name, value), http.StatusBadRequest)
+			return
+		}
+		*target = t
+	}
+	if v, err := strconv.Atoi(query.Get("limit")); err == nil && v > 0 && v <= 1000 {
+		filter.Limit = v
+	}
+	if v, err := strconv.Atoi(query.Get("offset")); err == nil && v > 0 {
+		filter.Offset = v
+	}
+
+	events, total, err := s.serviceDB.GetNormalizationEvents(filter)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"events": events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	}, http.StatusOK)
+}
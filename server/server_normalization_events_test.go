@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestNormalizerEventLevel(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Начало нормализации данных...", database.NormalizationEventInfo},
+		{"Ошибка КПВЭД: timeout", database.NormalizationEventError},
+		{"✗ Не удалось сохранить группу", database.NormalizationEventError},
+		{"⚠ Предупреждение: неверное имя модели", database.NormalizationEventWarn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := normalizerEventLevel(tt.message); got != tt.want {
+				t.Errorf("normalizerEventLevel() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizationEventsHistory(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service DB: %v", err)
+	}
+	defer serviceDB.Close()
+
+	s := &Server{
+		logChan:                make(chan LogEntry, 100),
+		serviceDB:              serviceDB,
+		config:                 &Config{NormalizerEventsHistoryLimit: 100},
+		normalizerEvents:       make(chan string, 10),
+		normalizerStreamEvents: make(chan string, 10),
+		shutdownChan:           make(chan struct{}),
+	}
+	s.normalizerSessionID.Store(7)
+	done := make(chan struct{})
+	go func() {
+		s.runNormalizerEventsPump()
+		close(done)
+	}()
+
+	s.normalizerEvents <- "Начало нормализации данных..."
+	s.normalizerEvents <- "Ошибка КПВЭД: timeout"
+	// SSE получает события сразу, история записывается пакетом
+	for _, want := range []string{"Начало нормализации данных...", "Ошибка КПВЭД: timeout"} {
+		select {
+		case got := <-s.normalizerStream():
+			if got != want {
+				t.Errorf("stream event = %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("stream event %q not delivered", want)
+		}
+	}
+	close(s.shutdownChan)
+	<-done
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   []string
+	}{
+		{"all", "/api/normalization/events/history", http.StatusOK, []string{`"total":2`, `"session_id":7`, `"level":"ERROR"`}},
+		{"level filter", "/api/normalization/events/history?level=info", http.StatusOK, []string{`"total":1`, "Начало нормализации"}},
+		{"other session", "/api/normalization/events/history?session_id=8", http.StatusOK, []string{`"total":0`, `"events":[]`}},
+		{"invalid level", "/api/normalization/events/history?level=debug", http.StatusBadRequest, nil},
+		{"invalid since", "/api/normalization/events/history?since=yesterday", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleNormalizationEventsHistory(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, body = %s, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("body = %s, want %q", rec.Body.String(), want)
+				}
+			}
+		})
+	}
+}
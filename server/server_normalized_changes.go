@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+	"httpserver/server/middleware"
+)
+
+// normalizedChangesJanitorInterval периодичность очистки журнала изменений нормализованных данных
+const normalizedChangesJanitorInterval = time.Hour
+
+// NormalizedChangesResponse страница журнала изменений нормализованных данных
+type NormalizedChangesResponse struct {
+	Changes    []*database.NormalizedChange `json:"changes"`
+	NextCursor string                       `json:"next_cursor"` // Курсор для следующего запроса
+	HasMore    bool                         `json:"has_more"`
+	Head       string                       `json:"head"` // Курсор последнего изменения журнала
+}
+
+// parseChangesCursor разбирает курсор журнала изменений; пустой курсор - с начала журнала
+func parseChangesCursor(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	seq, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seq < 0 {
+		return 0, fmt.Errorf("invalid cursor: %q", value)
+	}
+	return seq, nil
+}
+
+// handleNormalizedChanges возвращает изменения нормализованных записей после курсора в порядке фиксации.
+// Потребитель сохраняет next_cursor и передает его в следующем запросе; для начала синхронизации после
+// полной выгрузки используется head. Курсор старше очищенной части журнала - 410 cursor_expired:
+// потребитель заново получает полные данные
+// GET /api/normalized/changes?cursor=...&limit=100
+func (s *Server) handleNormalizedChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cursor, err := parseChangesCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	head, err := s.db.GetNormalizedChangesHead()
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get normalized changes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	changes, hasMore, err := s.db.GetNormalizedChanges(cursor, limit)
+	if errors.Is(err, database.ErrChangesCursorExpired) {
+		middleware.WriteJSONErrorCode(w, err.Error(), "cursor_expired", http.StatusGone)
+		return
+	}
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get normalized changes: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	next := cursor
+	if len(changes) > 0 {
+		next = changes[len(changes)-1].Seq
+	}
+	if next > head {
+		head = next
+	}
+	s.writeJSONResponse(w, NormalizedChangesResponse{
+		Changes:    changes,
+		NextCursor: strconv.FormatInt(next, 10),
+		HasMore:    hasMore,
+		Head:       strconv.FormatInt(head, 10),
+	}, http.StatusOK)
+}
+
+// runNormalizedChangesJanitor периодически удаляет изменения журнала старше NORMALIZED_CHANGES_RETENTION_DAYS
+func (s *Server) runNormalizedChangesJanitor() {
+	ticker := time.NewTicker(normalizedChangesJanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			days := s.currentConfig().NormalizedChangesRetentionDays
+			if days <= 0 || s.db == nil {
+				continue
+			}
+			pruned, err := s.db.PruneNormalizedChanges(now.AddDate(0, 0, -days))
+			if err != nil {
+				log.Printf("Ошибка очистки журнала изменений нормализованных данных: %v", err)
+			} else if pruned > 0 {
+				log.Printf("Удалено изменений журнала нормализованных данных старше %d дн.: %d", days, pruned)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestHandleNormalizedChanges(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO normalized_data (id, code, normalized_name, category) VALUES (1, 'c1', 'болт', 'крепеж'), (2, 'c2', 'гайка', 'крепеж'), (3, 'c3', 'шайба', 'крепеж')`); err != nil {
+		t.Fatalf("insert error = %v", err)
+	}
+	s := &Server{db: db}
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantChanges int
+		wantNext    string
+		wantMore    bool
+	}{
+		{"from start", "?limit=2", http.StatusOK, 2, "2", true},
+		{"next page", "?cursor=2&limit=2", http.StatusOK, 1, "3", false},
+		{"up to date", "?cursor=3", http.StatusOK, 0, "3", false},
+		{"invalid cursor", "?cursor=abc", http.StatusBadRequest, 0, "", false},
+		{"negative cursor", "?cursor=-1", http.StatusBadRequest, 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleNormalizedChanges(rec, httptest.NewRequest(http.MethodGet, "/api/normalized/changes"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp NormalizedChangesResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode error = %v", err)
+			}
+			if len(resp.Changes) != tt.wantChanges || resp.NextCursor != tt.wantNext || resp.HasMore != tt.wantMore || resp.Head != "3" {
+				t.Errorf("response = %d changes, next %s, more %v, head %s", len(resp.Changes), resp.NextCursor, resp.HasMore, resp.Head)
+			}
+		})
+	}
+
+	t.Run("expired cursor", func(t *testing.T) {
+		if _, err := db.PruneNormalizedChanges(time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("PruneNormalizedChanges() error = %v", err)
+		}
+		rec := httptest.NewRecorder()
+		s.handleNormalizedChanges(rec, httptest.NewRequest(http.MethodGet, "/api/normalized/changes?cursor=1", nil))
+		if rec.Code != http.StatusGone {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusGone, rec.Body.String())
+		}
+	})
+}
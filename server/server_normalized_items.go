@@ -0,0 +1,238 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// NormalizedItemUpdateRequest запрос ручного изменения одной записи
+type NormalizedItemUpdateRequest struct {
+	database.NormalizedItemEdit
+	Editor string `json:"editor,omitempty"`
+}
+
+// NormalizedItemsBulkUpdateRequest запрос пакетного ручного изменения записей
+type NormalizedItemsBulkUpdateRequest struct {
+	Items  []database.NormalizedItemEdit `json:"items"`
+	Editor string                        `json:"editor,omitempty"`
+}
+
+// handleNormalizedItemRoutes маршрутизирует запросы к отдельным нормализованным записям
+// GET/PUT /api/normalized/items/{id}
+// GET /api/normalized/items/{id}?as_of=TIMESTAMP - состояние записи на момент времени
+// GET /api/normalized/items/{id}/lineage
+// PUT /api/normalized/items/bulk
+func (s *Server) handleNormalizedItemRoutes(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/normalized/items/"), "/")
+	parts := strings.Split(path, "/")
+
+	if len(parts) == 1 && parts[0] == "bulk" {
+		s.handleNormalizedItemsBulkUpdate(w, r)
+		return
+	}
+
+	itemID, err := strconv.Atoi(parts[0])
+	if err != nil || itemID <= 0 {
+		s.writeJSONError(w, "Invalid normalized item ID", http.StatusBadRequest)
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("as_of") != "" {
+				s.handleGetNormalizedItemAsOf(w, r, itemID)
+				return
+			}
+			s.handleGetNormalizedItem(w, itemID)
+		case http.MethodPut:
+			s.handleUpdateNormalizedItem(w, r, itemID)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "lineage" {
+		s.handleNormalizedItemLineage(w, r, itemID)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// handleNormalizedItemLineage возвращает происхождение нормализованной записи:
+// исходные элементы и выгрузки, сессии нормализации, примененные правила, вызовы AI и объединения
+func (s *Server) handleNormalizedItemLineage(w http.ResponseWriter, r *http.Request, itemID int) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	lineage, err := s.db.GetNormalizedItemLineage(itemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeJSONError(w, fmt.Sprintf("Normalized item %d not found", itemID), http.StatusNotFound)
+			return
+		}
+		s.writeJSONError(w, fmt.Sprintf("Failed to get lineage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, lineage, http.StatusOK)
+}
+
+// handleGetNormalizedItem возвращает нормализованную запись с текущей версией для последующего изменения
+func (s *Server) handleGetNormalizedItem(w http.ResponseWriter, itemID int) {
+	item, err := s.db.GetNormalizedItemByID(itemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.writeJSONError(w, fmt.Sprintf("Normalized item %d not found", itemID), http.StatusNotFound)
+			return
+		}
+		s.writeJSONError(w, fmt.Sprintf("Failed to get normalized item: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, item, http.StatusOK)
+}
+
+// handleUpdateNormalizedItem вручную изменяет normalized_name/category/kpved_code записи.
+// Клиент передает прочитанную версию; если запись изменилась после чтения, возвращается 409.
+func (s *Server) handleUpdateNormalizedItem(w http.ResponseWriter, r *http.Request, itemID int) {
+	var req NormalizedItemUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.ID != 0 && req.ID != itemID {
+		s.writeJSONError(w, "Item ID in body does not match URL", http.StatusBadRequest)
+		return
+	}
+	req.ID = itemID
+
+	items, ok := s.applyNormalizedItemEdits(w, []database.NormalizedItemEdit{req.NormalizedItemEdit}, requestActor(r, req.Editor, ""))
+	if !ok {
+		return
+	}
+
+	s.writeJSONResponse(w, items[0], http.StatusOK)
+}
+
+// handleNormalizedItemsBulkUpdate применяет изменения к нескольким записям в одной транзакции:
+// при ошибке проверки или конфликте версий не сохраняется ни одно изменение
+func (s *Server) handleNormalizedItemsBulkUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req NormalizedItemsBulkUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) == 0 {
+		s.writeJSONError(w, "items must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(req.Items) > database.MaxNormalizedItemsBulkEdit {
+		s.writeJSONError(w, fmt.Sprintf("Too many items: %d (max %d)", len(req.Items), database.MaxNormalizedItemsBulkEdit), http.StatusBadRequest)
+		return
+	}
+
+	items, ok := s.applyNormalizedItemEdits(w, req.Items, requestActor(r, req.Editor, ""))
+	if !ok {
+		return
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"updated": len(items),
+		"items":   items,
+	}, http.StatusOK)
+}
+
+// applyNormalizedItemEdits проверяет изменения, сохраняет их и пишет ответ об ошибке.
+// Возвращает false, если ответ уже записан.
+func (s *Server) applyNormalizedItemEdits(w http.ResponseWriter, edits []database.NormalizedItemEdit, editor string) ([]*database.NormalizedItem, bool) {
+	for i := range edits {
+		if err := edits[i].Validate(); err != nil {
+			s.writeNormalizedItemEditError(w, err)
+			return nil, false
+		}
+		if err := s.resolveKpvedEdit(&edits[i]); err != nil {
+			s.writeNormalizedItemEditError(w, err)
+			return nil, false
+		}
+	}
+
+	editor = strings.TrimSpace(editor)
+	if editor == "" {
+		editor = database.ManualEditModel
+	}
+
+	items, err := s.db.UpdateNormalizedItems(edits, editor)
+	if err != nil {
+		s.writeNormalizedItemEditError(w, err)
+		return nil, false
+	}
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Manually updated %d normalized items (editor: %s)", len(items), editor),
+		Endpoint:  "/api/normalized/items",
+	})
+
+	return items, true
+}
+
+// resolveKpvedEdit проверяет код КПВЭД по классификатору и подставляет его наименование
+func (s *Server) resolveKpvedEdit(edit *database.NormalizedItemEdit) error {
+	if edit.KpvedCode == nil || *edit.KpvedCode == "" || s.serviceDB == nil {
+		return nil
+	}
+
+	var name string
+	err := s.serviceDB.QueryRow("SELECT name FROM kpved_classifier WHERE code = ?", *edit.KpvedCode).Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &database.NormalizedItemFieldError{ItemID: edit.ID, Field: "kpved_code", Message: "unknown KPVED code"}
+		}
+		return fmt.Errorf("failed to check KPVED code: %w", err)
+	}
+	if edit.KpvedName == nil || *edit.KpvedName == "" {
+		edit.KpvedName = &name
+	}
+	return nil
+}
+
+// writeNormalizedItemEditError записывает ответ для ошибки ручного изменения записи
+func (s *Server) writeNormalizedItemEditError(w http.ResponseWriter, err error) {
+	var fieldErr *database.NormalizedItemFieldError
+	var conflictErr *database.NormalizedItemConflictError
+	switch {
+	case errors.As(err, &fieldErr):
+		s.writeJSONResponse(w, map[string]interface{}{
+			"error": fieldErr.Error(),
+			"field": fieldErr,
+		}, http.StatusBadRequest)
+	case errors.As(err, &conflictErr):
+		s.writeJSONResponse(w, map[string]interface{}{
+			"error":     "Normalized items were modified by another user, reload and retry",
+			"conflicts": conflictErr.Conflicts,
+		}, http.StatusConflict)
+	case errors.Is(err, sql.ErrNoRows):
+		s.writeJSONError(w, err.Error(), http.StatusNotFound)
+	default:
+		s.writeJSONError(w, fmt.Sprintf("Failed to update normalized items: %v", err), http.StatusInternalServerError)
+	}
+}
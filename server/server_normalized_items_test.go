@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestNormalizedItemUpdate(t *testing.T) {
+	db := newReclassifyTestDB(t)
+	defer db.Close()
+	s := &Server{db: db}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleNormalizedItemRoutes(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/api/normalized/items/1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var item database.NormalizedItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &item); err != nil {
+		t.Fatalf("Failed to decode item: %v", err)
+	}
+	if item.Version != 1 {
+		t.Fatalf("initial version = %d, want 1", item.Version)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"empty name", "/api/normalized/items/1", `{"version": 1, "normalized_name": "  "}`, http.StatusBadRequest},
+		{"bad kpved code", "/api/normalized/items/1", `{"version": 1, "kpved_code": "25-94"}`, http.StatusBadRequest},
+		{"missing version", "/api/normalized/items/1", `{"category": "метизы"}`, http.StatusBadRequest},
+		{"id mismatch", "/api/normalized/items/1", `{"id": 2, "version": 1, "category": "метизы"}`, http.StatusBadRequest},
+		{"not found", "/api/normalized/items/99", `{"version": 1, "category": "метизы"}`, http.StatusNotFound},
+		{"updated", "/api/normalized/items/1", `{"version": 1, "normalized_name": " болт м8х30 ", "kpved_code": "25.94.11", "kpved_name": "Болты"}`, http.StatusOK},
+		{"stale version", "/api/normalized/items/1", `{"version": 1, "category": "метизы"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(http.MethodPut, tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	updated, err := db.GetNormalizedItemByID(1)
+	if err != nil {
+		t.Fatalf("GetNormalizedItemByID() error = %v", err)
+	}
+	if updated.Version != 2 || updated.NormalizedName != "болт м8х30" || updated.KpvedCode != "25.94.11" ||
+		updated.KpvedConfidence != 1.0 || updated.Category != "крепеж" || updated.UpdatedAt == nil {
+		t.Errorf("unexpected item after update: %+v", updated)
+	}
+
+	lineage, err := db.GetNormalizedItemLineage(1)
+	if err != nil {
+		t.Fatalf("GetNormalizedItemLineage() error = %v", err)
+	}
+	if len(lineage.Events) != 1 || lineage.Events[0].EventType != database.LineageEventManualEdit ||
+		!strings.Contains(lineage.Events[0].Details, `"old":"болт м8"`) {
+		t.Errorf("unexpected lineage events: %+v", lineage.Events)
+	}
+}
+
+func TestNormalizedItemsBulkUpdate(t *testing.T) {
+	db := newReclassifyTestDB(t)
+	defer db.Close()
+	s := &Server{db: db}
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/normalized/items/bulk", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleNormalizedItemRoutes(rec, req)
+		return rec
+	}
+
+	rec := do(`{"editor": "analyst", "items": [
+		{"id": 1, "version": 1, "category": "метизы"},
+		{"id": 2, "version": 1, "category": "метизы"},
+		{"id": 5, "version": 1, "kpved_code": "27.32"}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("bulk status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	// Конфликт в одной записи отменяет весь пакет
+	rec = do(`{"items": [
+		{"id": 3, "version": 1, "category": "метизы"},
+		{"id": 1, "version": 1, "category": "крепеж"}
+	]}`)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("conflict status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var conflict struct {
+		Conflicts []database.NormalizedItemConflict `json:"conflicts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &conflict); err != nil {
+		t.Fatalf("Failed to decode conflict: %v", err)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].ItemID != 1 || conflict.Conflicts[0].CurrentVersion != 2 {
+		t.Errorf("unexpected conflicts: %+v", conflict.Conflicts)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM normalized_data WHERE category = 'метизы'`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count items: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("items in new category = %d, want 2", count)
+	}
+
+	if rec := do(`{"items": [{"id": 3, "version": 1, "category": "a"}, {"id": 3, "version": 1, "category": "b"}]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("duplicate ids status = %d, want 400", rec.Code)
+	}
+	if rec := do(`{"items": []}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty items status = %d, want 400", rec.Code)
+	}
+}
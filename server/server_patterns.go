@@ -17,7 +17,8 @@ func (s *Server) handlePatternDetect(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var request struct {
-		Name string `json:"name"`
+		Name      string `json:"name"`
+		ProjectID int    `json:"project_id,omitempty"` // Словари сокращений и защищенных токенов проекта
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -32,6 +33,7 @@ func (s *Server) handlePatternDetect(w http.ResponseWriter, r *http.Request) {
 
 	// Создаем детектор паттернов
 	detector := normalization.NewPatternDetector()
+	detector.SetDictionaries(s.projectDictionaries(request.ProjectID))
 
 	// Обнаруживаем паттерны
 	matches := detector.DetectPatterns(request.Name)
@@ -63,6 +65,7 @@ func (s *Server) handlePatternSuggest(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		Name string `json:"name"`
 		UseAI bool  `json:"use_ai,omitempty"`
+		ProjectID int `json:"project_id,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -77,6 +80,7 @@ func (s *Server) handlePatternSuggest(w http.ResponseWriter, r *http.Request) {
 
 	// Создаем детектор паттернов
 	detector := normalization.NewPatternDetector()
+	detector.SetDictionaries(s.projectDictionaries(request.ProjectID))
 
 	// Обнаруживаем паттерны
 	matches := detector.DetectPatterns(request.Name)
@@ -132,6 +136,7 @@ func (s *Server) handlePatternTestBatch(w http.ResponseWriter, r *http.Request)
 		UseAI  bool `json:"use_ai,omitempty"`
 		Table  string `json:"table,omitempty"`
 		Column string `json:"column,omitempty"`
+		ProjectID int `json:"project_id,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -158,6 +163,7 @@ func (s *Server) handlePatternTestBatch(w http.ResponseWriter, r *http.Request)
 
 	// Создаем детектор паттернов
 	detector := normalization.NewPatternDetector()
+	detector.SetDictionaries(s.projectDictionaries(request.ProjectID))
 
 	// Инициализируем AI интегратор если нужно
 	var aiIntegrator *normalization.PatternAIIntegrator
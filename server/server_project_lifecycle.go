@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+)
+
+// projectLifecycleRequest тело запросов архивации, восстановления и клонирования проекта
+type projectLifecycleRequest struct {
+	Actor          string `json:"actor"`
+	TargetClientID int    `json:"target_client_id"` // Клиент нового проекта, по умолчанию клиент исходного
+	Name           string `json:"name"`             // Наименование нового проекта
+}
+
+// isProjectLifecycleAction проверяет, является ли сегмент маршрута действием жизненного цикла проекта
+func isProjectLifecycleAction(action string) bool {
+	return action == "archive" || action == "restore" || action == "clone"
+}
+
+// projectWriteAllowed проверяет, разрешено ли изменение проекта по маршруту /api/clients/{id}/projects/{projectId}/...
+// (subpath - сегменты после ID проекта). Архивный проект доступен только на чтение, восстановление,
+// клонирование как шаблона и удаление
+func projectWriteAllowed(method string, subpath []string) bool {
+	if method == http.MethodGet || method == http.MethodHead {
+		return true
+	}
+	if len(subpath) == 0 {
+		return method == http.MethodDelete
+	}
+	return len(subpath) == 1 && (subpath[0] == "restore" || subpath[0] == "clone")
+}
+
+// rejectArchivedProjectWrite отклоняет изменение архивного проекта с 409; возвращает true, если запрос отклонен
+func (s *Server) rejectArchivedProjectWrite(w http.ResponseWriter, r *http.Request, projectID int, subpath []string) bool {
+	if s.serviceDB == nil || projectWriteAllowed(r.Method, subpath) {
+		return false
+	}
+	archived, err := s.serviceDB.IsProjectArchived(projectID)
+	if err != nil || !archived {
+		// Отсутствующий проект обрабатывает основной обработчик маршрута
+		return false
+	}
+	s.writeAPIError(w, "Project is archived, restore it before making changes", database.ErrProjectArchived)
+	return true
+}
+
+// handleProjectLifecycle архивация, восстановление и клонирование проекта
+// POST /api/clients/{id}/projects/{projectId}/archive {"actor": "admin"}
+// POST /api/clients/{id}/projects/{projectId}/restore {"actor": "admin"}
+// POST /api/clients/{id}/projects/{projectId}/clone {"target_client_id": 2, "name": "Новый проект", "actor": "admin"}
+func (s *Server) handleProjectLifecycle(w http.ResponseWriter, r *http.Request, clientID, projectID int, action string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req projectLifecycleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Actor = requestActor(r, req.Actor, r.RemoteAddr)
+
+	project, err := s.serviceDB.GetClientProject(projectID)
+	if err != nil {
+		s.writeJSONError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if project.ClientID != clientID {
+		s.writeJSONError(w, "Project does not belong to this client", http.StatusBadRequest)
+		return
+	}
+
+	switch action {
+	case "archive":
+		s.changeProjectArchiveState(w, req.Actor, project, true)
+	case "restore":
+		s.changeProjectArchiveState(w, req.Actor, project, false)
+	case "clone":
+		s.cloneProject(w, req, project)
+	}
+}
+
+// changeProjectArchiveState переводит проект в архив или возвращает из него с записью в журнал аудита
+func (s *Server) changeProjectArchiveState(w http.ResponseWriter, actor string, project *database.ClientProject, archive bool) {
+	action, change := database.AuditActionProjectRestore, s.serviceDB.RestoreClientProject
+	if archive {
+		action, change = database.AuditActionProjectArchive, s.serviceDB.ArchiveClientProject
+	}
+	details := map[string]interface{}{"client_id": project.ClientID, "name": project.Name}
+	if err := change(project.ID); err != nil {
+		details["error"] = err.Error()
+		s.recordProjectLifecycle(action, actor, project.ID, "rejected", details)
+		s.writeAPIError(w, "Failed to change project status", err)
+		return
+	}
+	s.recordProjectLifecycle(action, actor, project.ID, "success", details)
+
+	updated, err := s.serviceDB.GetClientProject(project.ID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to get project", err)
+		return
+	}
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Project %d status changed to %s by %s", project.ID, updated.Status, actor),
+		Endpoint:  "/api/clients/projects",
+	})
+	s.writeJSONResponse(w, updated, http.StatusOK)
+}
+
+// cloneProject создает проект по конфигурации исходного: словари нормализации, атрибуты промптов AI
+// и настройку сходства в сервисной БД, классификаторы, стратегии свертки и политика порогов уверенности
+// в основной БД. Основная БД изменяется до фиксации транзакции сервисной БД; если фиксация не удалась,
+// скопированное удаляется
+func (s *Server) cloneProject(w http.ResponseWriter, req projectLifecycleRequest, source *database.ClientProject) {
+	if req.TargetClientID == 0 {
+		req.TargetClientID = source.ClientID
+	}
+	if req.Name == "" {
+		req.Name = source.Name + " (копия)"
+	}
+
+	s.dbMutex.RLock()
+	db := s.db
+	s.dbMutex.RUnlock()
+
+	clonedProjectID := 0
+	summary, err := s.serviceDB.CloneClientProject(source.ID, req.TargetClientID, req.Name, func(summary *database.ProjectCloneSummary) error {
+		if db == nil {
+			return nil
+		}
+		if err := db.CloneProjectClassification(summary, source.ClientID); err != nil {
+			return err
+		}
+		clonedProjectID = summary.Project.ID
+		return nil
+	})
+	details := map[string]interface{}{"source_project_id": source.ID, "target_client_id": req.TargetClientID, "name": req.Name}
+	if err != nil {
+		if clonedProjectID != 0 {
+			if cleanupErr := db.DeleteProjectClassification(clonedProjectID); cleanupErr != nil {
+				log.Printf("Ошибка отмены клонирования классификаторов проекта %d: %v", clonedProjectID, cleanupErr)
+			}
+		}
+		details["error"] = err.Error()
+		s.recordProjectLifecycle(database.AuditActionProjectClone, req.Actor, source.ID, "failed", details)
+		s.writeAPIError(w, "Failed to clone project", err)
+		return
+	}
+
+	details["project_id"] = summary.Project.ID
+	details["dictionary_entries"] = summary.DictionaryEntries
+	details["classifiers"] = summary.Classifiers
+	details["strategies"] = summary.Strategies
+	details["prompt_attributes"] = summary.PromptAttributes
+	details["similarity_config"] = summary.SimilarityConfig
+	s.recordProjectLifecycle(database.AuditActionProjectClone, req.Actor, source.ID, "success", details)
+	s.writeJSONResponse(w, summary, http.StatusCreated)
+}
+
+// recordProjectLifecycle записывает действие жизненного цикла проекта в журнал аудита
+func (s *Server) recordProjectLifecycle(action, actor string, projectID int, status string, details map[string]interface{}) {
+	err := s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action:  action,
+		Actor:   actor,
+		Target:  "project:" + strconv.Itoa(projectID),
+		Status:  status,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Ошибка записи действия с проектом в журнал аудита: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestProjectLifecycleRoutes(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	client, _ := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	target, _ := serviceDB.CreateClient("ООО Лютик", "", "", "", "", "", "test")
+	project, _ := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	serviceDB.CreateNormalizationDictionaryEntry(&database.NormalizationDictionaryEntry{ProjectID: project.ID, Kind: database.DictionaryKindStopWord, Term: "прочее"})
+
+	s := &Server{logChan: make(chan LogEntry, 100), db: db, serviceDB: serviceDB}
+	projectPath := fmt.Sprintf("/api/clients/%d/projects/%d", client.ID, project.ID)
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"archive", http.MethodPost, projectPath + "/archive", `{"actor": "admin"}`, http.StatusOK, `"status":"archived"`},
+		{"archive twice", http.MethodPost, projectPath + "/archive", "", http.StatusConflict, "project_archived"},
+		{"listing hides archived", http.MethodGet, fmt.Sprintf("/api/clients/%d/projects", client.ID), "", http.StatusOK, "[]"},
+		{"listing with archived", http.MethodGet, fmt.Sprintf("/api/clients/%d/projects?include_archived=true", client.ID), "", http.StatusOK, `"status":"archived"`},
+		{"read archived project", http.MethodGet, projectPath + "/dictionaries", "", http.StatusOK, "прочее"},
+		{"write to archived project", http.MethodPost, projectPath + "/dictionaries", `{"kind": "stop_word", "term": "разное"}`, http.StatusConflict, "project_archived"},
+		{"update archived project", http.MethodPut, projectPath, `{"name": "Новое"}`, http.StatusConflict, "project_archived"},
+		{"clone archived template", http.MethodPost, projectPath + "/clone", fmt.Sprintf(`{"target_client_id": %d, "name": "Номенклатура Лютик"}`, target.ID), http.StatusCreated, `"dictionary_entries":1`},
+		{"clone to unknown client", http.MethodPost, projectPath + "/clone", `{"target_client_id": 999}`, http.StatusNotFound, "client_not_found"},
+		{"restore", http.MethodPost, projectPath + "/restore", "", http.StatusOK, `"status":"active"`},
+		{"restore active", http.MethodPost, projectPath + "/restore", "", http.StatusConflict, "project_not_archived"},
+		{"write after restore", http.MethodPost, projectPath + "/dictionaries", `{"kind": "stop_word", "term": "разное"}`, http.StatusCreated, "разное"},
+		{"wrong client", http.MethodPost, fmt.Sprintf("/api/clients/%d/projects/%d/archive", target.ID, project.ID), "", http.StatusBadRequest, ""},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleClientRoutes(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d, body = %s, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	projects, _ := serviceDB.GetClientProjects(target.ID)
+	if len(projects) != 1 || projects[0].Name != "Номенклатура Лютик" {
+		t.Fatalf("target projects = %+v, want cloned project", projects)
+	}
+	var audit []string
+	for _, action := range []string{database.AuditActionProjectArchive, database.AuditActionProjectClone, database.AuditActionProjectRestore} {
+		events, err := serviceDB.GetAuditEvents(action, 10)
+		if err != nil {
+			t.Fatalf("GetAuditEvents() error = %v", err)
+		}
+		for _, event := range events {
+			audit = append(audit, event.Action+":"+event.Status)
+		}
+	}
+	data, _ := json.Marshal(audit)
+	want := `["project_archive:success","project_clone:failed","project_clone:success","project_restore:rejected","project_restore:success"]`
+	if string(data) != want {
+		t.Errorf("audit = %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"httpserver/database"
+	"httpserver/provisioning"
+)
+
+// maxProvisionFileSize максимальный размер файла провижининга
+const maxProvisionFileSize = 1 << 20
+
+// handleAdminProvision применяет декларативный файл провижининга (YAML или JSON в теле запроса).
+// Без apply=true возвращает только план изменений
+// POST /api/admin/provision[?apply=true]
+func (s *Server) handleAdminProvision(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProvisionFileSize))
+	if err != nil {
+		s.writeJSONError(w, "Failed to read provisioning file", http.StatusBadRequest)
+		return
+	}
+	spec, err := provisioning.ParseSpec(data)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actor := requestActor(r, "", r.RemoteAddr)
+	provisioner := provisioning.NewProvisioner(s.serviceDB, s.db, actor)
+	apply := r.URL.Query().Get("apply") == "true"
+	var plan *provisioning.Plan
+	if apply {
+		plan, err = provisioner.Apply(spec)
+	} else {
+		plan, err = provisioner.Plan(spec)
+	}
+	if err != nil {
+		if apply {
+			s.recordProvision(actor, "failed", plan, err)
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"error": err.Error(),
+			"plan":  plan,
+		}, http.StatusBadRequest)
+		return
+	}
+
+	if apply {
+		s.recordProvision(actor, "success", plan, nil)
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message: fmt.Sprintf("Провижининг применен (%s): создано %d, изменено %d, без изменений %d",
+				actor, plan.Create, plan.Update, plan.Unchanged),
+			Endpoint: r.URL.Path,
+		})
+	}
+	s.writeJSONResponse(w, plan, http.StatusOK)
+}
+
+// recordProvision записывает применение файла провижининга в журнал аудита (без токенов API ключей)
+func (s *Server) recordProvision(actor, status string, plan *provisioning.Plan, applyErr error) {
+	details := map[string]interface{}{}
+	if plan != nil {
+		var changed []*provisioning.Change
+		for _, change := range plan.Changes {
+			if change.Action != provisioning.ActionUnchanged {
+				changed = append(changed, change)
+			}
+		}
+		details["changes"] = changed
+	}
+	if applyErr != nil {
+		details["error"] = applyErr.Error()
+	}
+	err := s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action:  database.AuditActionProvision,
+		Actor:   actor,
+		Target:  "provisioning",
+		Status:  status,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Ошибка записи провижининга в журнал аудита: %v", err)
+	}
+}
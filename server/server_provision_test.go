@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/provisioning"
+)
+
+func TestAdminProvision(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	s := &Server{serviceDB: serviceDB, logChan: make(chan LogEntry, 10)}
+
+	spec := "clients:\n  - name: Acme\n    projects:\n      - name: P\n        type: normalization\n"
+	tests := []struct {
+		name       string
+		query      string
+		body       string
+		wantStatus int
+		wantCreate int
+	}{
+		{"invalid file", "", "clients:\n  - name: Acme\n    colour: red\n", http.StatusBadRequest, 0},
+		{"classifiers without main database", "", spec + "        classifiers: [X]\n", http.StatusBadRequest, 0},
+		{"plan", "", spec, http.StatusOK, 2},
+		{"apply", "?apply=true", spec, http.StatusOK, 2},
+		{"plan after apply", "", spec, http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/admin/provision"+tt.query, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			s.handleAdminProvision(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var plan provisioning.Plan
+			if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if plan.Create != tt.wantCreate {
+				t.Errorf("create = %d, want %d", plan.Create, tt.wantCreate)
+			}
+		})
+	}
+
+	events, err := serviceDB.GetAuditEvents(database.AuditActionProvision, 10)
+	if err != nil || len(events) != 1 {
+		t.Errorf("provision audit events = %d, err = %v", len(events), err)
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -114,6 +115,10 @@ func (s *Server) handleQualityViolations(w http.ResponseWriter, r *http.Request)
 		filters["category"] = category
 	}
 
+	if status := r.URL.Query().Get("status"); status != "" {
+		filters["status"] = status
+	}
+
 	// Pagination
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	if limit <= 0 {
@@ -680,8 +685,19 @@ func (s *Server) handleQualityAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	job, err := s.startBackgroundJob(r.Context(), jobKindQualityAnalysis, reqBody.Table)
+	if err != nil {
+		db.Close()
+		s.qualityAnalysisMutex.Lock()
+		s.qualityAnalysisRunning = false
+		s.qualityAnalysisStatus.Error = err.Error()
+		s.qualityAnalysisMutex.Unlock()
+		s.writeAPIError(w, "Failed to start quality analysis", err)
+		return
+	}
+
 	// Запускаем анализ в фоновой горутине
-	go s.runQualityAnalysis(db, reqBody.Table, codeColumn, nameColumn)
+	go s.runQualityAnalysis(job, db, reqBody.Table, codeColumn, nameColumn)
 
 	s.writeJSONResponse(w, map[string]interface{}{
 		"success": true,
@@ -690,18 +706,39 @@ func (s *Server) handleQualityAnalyze(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
-// runQualityAnalysis выполняет анализ качества в фоновом режиме
-func (s *Server) runQualityAnalysis(db *database.DB, tableName, codeColumn, nameColumn string) {
+// runQualityAnalysis выполняет анализ качества в фоновом режиме. При остановке сервера
+// анализ прерывается между этапами
+func (s *Server) runQualityAnalysis(job *backgroundJob, db *database.DB, tableName, codeColumn, nameColumn string) {
 	defer db.Close()
+	defer func() {
+		s.qualityAnalysisMutex.Lock()
+		status := s.qualityAnalysisStatus
+		s.qualityAnalysisMutex.Unlock()
+		job.setCheckpoint(map[string]interface{}{
+			"table":      tableName,
+			"step":       status.CurrentStep,
+			"duplicates": status.DuplicatesFound,
+			"violations": status.ViolationsFound,
+		})
+		var err error
+		if status.Error != "" {
+			err = errors.New(status.Error)
+		}
+		s.finishBackgroundJob(job, err)
+	}()
 	defer func() {
 		s.qualityAnalysisMutex.Lock()
 		s.qualityAnalysisRunning = false
+		if err := job.panicError(); err != nil {
+			s.qualityAnalysisStatus.Error = err.Error()
+		}
 		if s.qualityAnalysisStatus.Error == "" {
 			s.qualityAnalysisStatus.CurrentStep = "completed"
 			s.qualityAnalysisStatus.Progress = 100
 		}
 		s.qualityAnalysisMutex.Unlock()
 	}()
+	defer s.recoverWorker(jobKindQualityAnalysis, job)
 
 	analyzer := quality.NewTableAnalyzer(db)
 	batchSize := 1000
@@ -735,6 +772,10 @@ func (s *Server) runQualityAnalysis(db *database.DB, tableName, codeColumn, name
 	s.qualityAnalysisStatus.DuplicatesFound = duplicatesCount
 	s.qualityAnalysisMutex.Unlock()
 
+	if s.qualityAnalysisInterrupted(job) {
+		return
+	}
+
 	// 2. Анализ нарушений
 	s.qualityAnalysisMutex.Lock()
 	s.qualityAnalysisStatus.CurrentStep = "violations"
@@ -766,6 +807,10 @@ func (s *Server) runQualityAnalysis(db *database.DB, tableName, codeColumn, name
 	s.qualityAnalysisStatus.ViolationsFound = violationsCount
 	s.qualityAnalysisMutex.Unlock()
 
+	if s.qualityAnalysisInterrupted(job) {
+		return
+	}
+
 	// 3. Анализ предложений
 	s.qualityAnalysisMutex.Lock()
 	s.qualityAnalysisStatus.CurrentStep = "suggestions"
@@ -810,4 +855,15 @@ func (s *Server) handleQualityAnalyzeStatus(w http.ResponseWriter, r *http.Reque
 	s.qualityAnalysisMutex.RUnlock()
 
 	s.writeJSONResponse(w, status, http.StatusOK)
-}
\ No newline at end of file
+}
+
+// qualityAnalysisInterrupted отмечает анализ прерванным, если сервер останавливается
+func (s *Server) qualityAnalysisInterrupted(job *backgroundJob) bool {
+	if job.ctx.Err() == nil {
+		return false
+	}
+	s.qualityAnalysisMutex.Lock()
+	s.qualityAnalysisStatus.Error = "Analysis interrupted by server shutdown"
+	s.qualityAnalysisMutex.Unlock()
+	return true
+}
@@ -0,0 +1,396 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"httpserver/database"
+
+	"github.com/google/uuid"
+)
+
+// Действия массовой обработки нарушений качества
+const (
+	qualityBulkAcknowledge = "acknowledge"
+	qualityBulkDismiss     = "dismiss"
+	qualityBulkAutoFix     = "auto_fix"
+)
+
+// Итоги обработки отдельного нарушения
+const (
+	qualityBulkOutcomeAcknowledged = "acknowledged"
+	qualityBulkOutcomeDismissed    = "dismissed"
+	qualityBulkOutcomeFixed        = "fixed"
+	qualityBulkOutcomeSkipped      = "skipped" // Нет предложения с исправлением или поле не редактируется
+	qualityBulkOutcomeFailed       = "failed"
+)
+
+// Статусы задачи массовой обработки
+const (
+	qualityBulkJobRunning     = "running"
+	qualityBulkJobCompleted   = "completed"
+	qualityBulkJobFailed      = "failed"
+	qualityBulkJobInterrupted = "interrupted"
+)
+
+const (
+	// maxQualityBulkItems максимальное число нарушений, обрабатываемых одной задачей
+	maxQualityBulkItems = 50000
+	// qualityBulkBatchSize число нарушений в одной транзакции смены статуса
+	qualityBulkBatchSize = 500
+)
+
+// QualityBulkRequest запрос массовой обработки нарушений, отобранных фильтром
+type QualityBulkRequest struct {
+	Action     string                          `json:"action"` // acknowledge, dismiss, auto_fix
+	Filter     database.QualityViolationFilter `json:"filter"`
+	ResolvedBy string                          `json:"resolved_by"`
+	Limit      int                             `json:"limit,omitempty"` // 0 = maxQualityBulkItems
+}
+
+// QualityBulkItemResult итог обработки одного нарушения
+type QualityBulkItemResult struct {
+	ViolationID      int    `json:"violation_id"`
+	NormalizedItemID int    `json:"normalized_item_id"`
+	Outcome          string `json:"outcome"`
+	Message          string `json:"message,omitempty"`
+	SuggestionID     int    `json:"suggestion_id,omitempty"`
+}
+
+// QualityBulkJob фоновая задача массовой обработки нарушений качества
+type QualityBulkJob struct {
+	mu         sync.RWMutex
+	ID         string
+	Action     string
+	Filter     database.QualityViolationFilter
+	ResolvedBy string
+	Status     string
+	Error      string
+	CreatedAt  time.Time
+	FinishedAt *time.Time
+	Total      int
+	Summary    map[string]int
+	Results    []QualityBulkItemResult
+}
+
+// QualityBulkJobView DTO задачи для ответа API
+type QualityBulkJobView struct {
+	ID         string                          `json:"id"`
+	Action     string                          `json:"action"`
+	Filter     database.QualityViolationFilter `json:"filter"`
+	ResolvedBy string                          `json:"resolved_by,omitempty"`
+	Status     string                          `json:"status"`
+	Error      string                          `json:"error,omitempty"`
+	CreatedAt  time.Time                       `json:"created_at"`
+	FinishedAt *time.Time                      `json:"finished_at,omitempty"`
+	Total      int                             `json:"total"`
+	Processed  int                             `json:"processed"`
+	Summary    map[string]int                  `json:"summary"`
+	Results    []QualityBulkItemResult         `json:"results,omitempty"`
+}
+
+func newQualityBulkJob(req QualityBulkRequest) *QualityBulkJob {
+	return &QualityBulkJob{
+		ID:         uuid.New().String(),
+		Action:     req.Action,
+		Filter:     req.Filter,
+		ResolvedBy: req.ResolvedBy,
+		Status:     qualityBulkJobRunning,
+		CreatedAt:  time.Now(),
+		Summary:    make(map[string]int),
+	}
+}
+
+// snapshot возвращает копию состояния задачи; результаты по нарушениям включаются по withResults
+func (job *QualityBulkJob) snapshot(withResults bool) QualityBulkJobView {
+	job.mu.RLock()
+	defer job.mu.RUnlock()
+
+	view := QualityBulkJobView{
+		ID:         job.ID,
+		Action:     job.Action,
+		Filter:     job.Filter,
+		ResolvedBy: job.ResolvedBy,
+		Status:     job.Status,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		Total:      job.Total,
+		Processed:  len(job.Results),
+		Summary:    make(map[string]int, len(job.Summary)),
+	}
+	for outcome, count := range job.Summary {
+		view.Summary[outcome] = count
+	}
+	if job.FinishedAt != nil {
+		finished := *job.FinishedAt
+		view.FinishedAt = &finished
+	}
+	if withResults {
+		view.Results = append([]QualityBulkItemResult(nil), job.Results...)
+	}
+	return view
+}
+
+func (job *QualityBulkJob) setTotal(total int) {
+	job.mu.Lock()
+	job.Total = total
+	job.mu.Unlock()
+}
+
+func (job *QualityBulkJob) addResults(results ...QualityBulkItemResult) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	for _, result := range results {
+		job.Results = append(job.Results, result)
+		job.Summary[result.Outcome]++
+	}
+}
+
+func (job *QualityBulkJob) finish(status string, err error) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// finishIfRunning завершает задачу ошибкой, если она не была завершена (обработчик упал паникой)
+func (job *QualityBulkJob) finishIfRunning(err error) {
+	job.mu.RLock()
+	running := job.Status == qualityBulkJobRunning
+	job.mu.RUnlock()
+	if running {
+		job.finish(qualityBulkJobFailed, err)
+	}
+}
+
+// handleQualityViolationsBulk запускает массовую обработку нарушений, отобранных фильтром:
+// acknowledge - принять в работу, dismiss - отклонить, auto_fix - применить предложение с исправлением
+// POST /api/quality/violations/bulk
+func (s *Server) handleQualityViolationsBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req QualityBulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	switch req.Action {
+	case qualityBulkAcknowledge, qualityBulkDismiss, qualityBulkAutoFix:
+	default:
+		s.writeJSONError(w, fmt.Sprintf("Unknown action %q: expected acknowledge, dismiss or auto_fix", req.Action), http.StatusBadRequest)
+		return
+	}
+	switch req.Filter.Status {
+	case "", database.QualityViolationOpen, database.QualityViolationAcknowledged, database.QualityViolationDismissed, database.QualityViolationResolved:
+	default:
+		s.writeJSONError(w, fmt.Sprintf("Unknown violation status %q", req.Filter.Status), http.StatusBadRequest)
+		return
+	}
+	req.ResolvedBy = strings.TrimSpace(req.ResolvedBy)
+	if req.ResolvedBy == "" {
+		s.writeJSONError(w, "resolved_by is required", http.StatusBadRequest)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > maxQualityBulkItems {
+		req.Limit = maxQualityBulkItems
+	}
+
+	violations, err := s.normalizedDB.FindQualityViolations(req.Filter, req.Limit)
+	if err != nil {
+		log.Printf("Error finding violations for bulk action: %v", err)
+		s.writeJSONError(w, fmt.Sprintf("Failed to find violations: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	job := newQualityBulkJob(req)
+	job.setTotal(len(violations))
+	bgJob, err := s.startBackgroundJob(r.Context(), jobKindQualityBulk, job.ID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to start bulk action", err)
+		return
+	}
+
+	s.qualityBulkJobsMutex.Lock()
+	s.qualityBulkJobs[job.ID] = job
+	s.qualityBulkJobsMutex.Unlock()
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Quality bulk job %s created: %s for %d violations by %s", job.ID, job.Action, len(violations), job.ResolvedBy),
+		Endpoint:  "/api/quality/violations/bulk",
+	})
+
+	go func() {
+		var runErr error
+		defer func() { s.finishBackgroundJob(bgJob, runErr) }()
+		defer job.finishIfRunning(errors.New("bulk action stopped unexpectedly"))
+		defer s.recoverWorker(jobKindQualityBulk, bgJob)
+		runErr = s.runQualityBulkJob(bgJob, job, violations)
+	}()
+
+	s.writeJSONResponse(w, job.snapshot(false), http.StatusAccepted)
+}
+
+// runQualityBulkJob обрабатывает нарушения задачи. Смена статуса выполняется транзакциями по
+// qualityBulkBatchSize нарушений, исправления применяются по одному: каждое меняет запись
+// normalized_data с проверкой версии и записью в происхождение
+func (s *Server) runQualityBulkJob(bgJob *backgroundJob, job *QualityBulkJob, violations []database.QualityViolation) error {
+	for start := 0; start < len(violations); start += qualityBulkBatchSize {
+		if err := bgJob.ctx.Err(); err != nil {
+			job.finish(qualityBulkJobInterrupted, err)
+			return err
+		}
+		batch := violations[start:min(start+qualityBulkBatchSize, len(violations))]
+
+		var err error
+		switch job.Action {
+		case qualityBulkAcknowledge:
+			err = s.setQualityBulkStatus(job, batch, database.QualityViolationAcknowledged, qualityBulkOutcomeAcknowledged)
+		case qualityBulkDismiss:
+			err = s.setQualityBulkStatus(job, batch, database.QualityViolationDismissed, qualityBulkOutcomeDismissed)
+		case qualityBulkAutoFix:
+			for _, violation := range batch {
+				job.addResults(s.autoFixViolation(violation, job.ResolvedBy))
+			}
+		}
+		if err != nil {
+			job.finish(qualityBulkJobFailed, err)
+			return err
+		}
+		bgJob.setCheckpoint(map[string]interface{}{"job_id": job.ID, "processed": start + len(batch), "total": len(violations)})
+	}
+
+	job.finish(qualityBulkJobCompleted, nil)
+	return nil
+}
+
+// setQualityBulkStatus меняет статус пакета нарушений одной транзакцией
+func (s *Server) setQualityBulkStatus(job *QualityBulkJob, batch []database.QualityViolation, status, outcome string) error {
+	ids := make([]int, len(batch))
+	for i, violation := range batch {
+		ids[i] = violation.ID
+	}
+	if _, err := s.normalizedDB.SetQualityViolationsStatus(ids, status, job.ResolvedBy); err != nil {
+		return err
+	}
+	results := make([]QualityBulkItemResult, len(batch))
+	for i, violation := range batch {
+		results[i] = QualityBulkItemResult{ViolationID: violation.ID, NormalizedItemID: violation.NormalizedItemID, Outcome: outcome}
+	}
+	job.addResults(results...)
+	return nil
+}
+
+// autoFixViolation применяет к записи нарушения предложение с исправленным значением поля.
+// Нарушения без такого предложения и нарушения нередактируемых полей пропускаются
+func (s *Server) autoFixViolation(violation database.QualityViolation, resolvedBy string) QualityBulkItemResult {
+	result := QualityBulkItemResult{ViolationID: violation.ID, NormalizedItemID: violation.NormalizedItemID, Outcome: qualityBulkOutcomeFailed}
+
+	suggestion, err := s.normalizedDB.FindFixSuggestion(violation.NormalizedItemID, violation.Field)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if suggestion == nil {
+		result.Outcome = qualityBulkOutcomeSkipped
+		result.Message = "no fix suggestion"
+		return result
+	}
+	result.SuggestionID = suggestion.ID
+
+	item, err := s.normalizedDB.GetNormalizedItemByID(violation.NormalizedItemID)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	edit := database.NormalizedItemEdit{ID: item.ID, Version: item.Version, Comment: fmt.Sprintf("quality suggestion %d", suggestion.ID)}
+	value := suggestion.SuggestedValue
+	switch violation.Field {
+	case "normalized_name":
+		edit.NormalizedName = &value
+	case "category":
+		edit.Category = &value
+	case "kpved_code":
+		edit.KpvedCode = &value
+	default:
+		result.Outcome = qualityBulkOutcomeSkipped
+		result.Message = fmt.Sprintf("field %q is not editable", violation.Field)
+		return result
+	}
+	if err := edit.Validate(); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if _, err := s.normalizedDB.UpdateNormalizedItems([]database.NormalizedItemEdit{edit}, resolvedBy); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	// Запись уже исправлена: ошибки отметки предложения и нарушения не отменяют исправление
+	if err := s.normalizedDB.ApplySuggestion(suggestion.ID); err != nil {
+		log.Printf("Error marking suggestion %d applied: %v", suggestion.ID, err)
+	}
+	if _, err := s.normalizedDB.SetQualityViolationsStatus([]int{violation.ID}, database.QualityViolationResolved, resolvedBy); err != nil {
+		log.Printf("Error resolving violation %d: %v", violation.ID, err)
+	}
+	result.Outcome = qualityBulkOutcomeFixed
+	return result
+}
+
+// handleQualityBulkJobs список задач массовой обработки нарушений, новые первыми
+// GET /api/quality/bulk-jobs
+func (s *Server) handleQualityBulkJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.qualityBulkJobsMutex.RLock()
+	jobs := make([]QualityBulkJobView, 0, len(s.qualityBulkJobs))
+	for _, job := range s.qualityBulkJobs {
+		jobs = append(jobs, job.snapshot(false))
+	}
+	s.qualityBulkJobsMutex.RUnlock()
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt.After(jobs[k].CreatedAt) })
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"jobs":  jobs,
+		"total": len(jobs),
+	}, http.StatusOK)
+}
+
+// handleQualityBulkJobDetail статус задачи массовой обработки с итогом по каждому нарушению;
+// results=false возвращает только сводку
+// GET /api/quality/bulk-jobs/{id}
+func (s *Server) handleQualityBulkJobDetail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/quality/bulk-jobs/"), "/")
+	s.qualityBulkJobsMutex.RLock()
+	job := s.qualityBulkJobs[id]
+	s.qualityBulkJobsMutex.RUnlock()
+	if job == nil {
+		s.writeJSONError(w, "Quality bulk job not found", http.StatusNotFound)
+		return
+	}
+
+	s.writeJSONResponse(w, job.snapshot(r.URL.Query().Get("results") != "false"), http.StatusOK)
+}
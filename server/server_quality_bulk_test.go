@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+// waitQualityBulkJob ожидает завершения задачи массовой обработки
+func waitQualityBulkJob(t *testing.T, s *Server, id string) QualityBulkJobView {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.qualityBulkJobsMutex.RLock()
+		job := s.qualityBulkJobs[id]
+		s.qualityBulkJobsMutex.RUnlock()
+		if job != nil {
+			if view := job.snapshot(true); view.Status != qualityBulkJobRunning {
+				return view
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("quality bulk job %s did not finish", id)
+	return QualityBulkJobView{}
+}
+
+func TestHandleQualityViolationsBulk(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO normalized_data (id, code, normalized_name, category) VALUES (1, 'c1', 'болт', 'крепеж'), (2, 'c2', 'гайка', 'крепеж')`); err != nil {
+		t.Fatalf("insert error = %v", err)
+	}
+	for _, v := range []database.QualityViolation{
+		{NormalizedItemID: 1, RuleName: "name_format", Category: "format", Severity: "warning", Field: "normalized_name"},
+		{NormalizedItemID: 2, RuleName: "name_format", Category: "format", Severity: "warning", Field: "normalized_name"},
+		{NormalizedItemID: 2, RuleName: "require_kpved_code", Category: "completeness", Severity: "error", Field: "kpved_code"},
+	} {
+		v.DetectedAt = time.Now()
+		if err := db.SaveQualityViolation(&v); err != nil {
+			t.Fatalf("SaveQualityViolation() error = %v", err)
+		}
+	}
+	suggestion := database.QualitySuggestion{NormalizedItemID: 1, SuggestionType: "correct_format", Priority: "low", Field: "normalized_name", SuggestedValue: "болт м10", Confidence: 0.8, CreatedAt: time.Now()}
+	if err := db.SaveQualitySuggestion(&suggestion); err != nil {
+		t.Fatalf("SaveQualitySuggestion() error = %v", err)
+	}
+
+	s := &Server{normalizedDB: db, config: &Config{}, logChan: make(chan LogEntry, 100), qualityBulkJobs: make(map[string]*QualityBulkJob)}
+	post := func(body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		rec := httptest.NewRecorder()
+		s.handleQualityViolationsBulk(rec, httptest.NewRequest(http.MethodPost, "/api/quality/violations/bulk", bytes.NewReader(payload)))
+		return rec
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name string
+			body QualityBulkRequest
+		}{
+			{"unknown action", QualityBulkRequest{Action: "delete", ResolvedBy: "аналитик"}},
+			{"unknown status", QualityBulkRequest{Action: qualityBulkDismiss, ResolvedBy: "аналитик", Filter: database.QualityViolationFilter{Status: "closed"}}},
+			{"missing resolved_by", QualityBulkRequest{Action: qualityBulkDismiss, ResolvedBy: " "}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if rec := post(tt.body); rec.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+				}
+			})
+		}
+	})
+
+	tests := []struct {
+		name        string
+		body        QualityBulkRequest
+		wantTotal   int
+		wantSummary map[string]int
+	}{
+		{
+			name:        "auto fix",
+			body:        QualityBulkRequest{Action: qualityBulkAutoFix, ResolvedBy: "аналитик", Filter: database.QualityViolationFilter{RuleName: "name_format"}},
+			wantTotal:   2,
+			wantSummary: map[string]int{qualityBulkOutcomeFixed: 1, qualityBulkOutcomeSkipped: 1},
+		},
+		{
+			name:        "dismiss remaining open",
+			body:        QualityBulkRequest{Action: qualityBulkDismiss, ResolvedBy: "аналитик"},
+			wantTotal:   2,
+			wantSummary: map[string]int{qualityBulkOutcomeDismissed: 2},
+		},
+		{
+			name:        "acknowledge dismissed",
+			body:        QualityBulkRequest{Action: qualityBulkAcknowledge, ResolvedBy: "аналитик", Filter: database.QualityViolationFilter{Status: database.QualityViolationDismissed, Severity: "error"}},
+			wantTotal:   1,
+			wantSummary: map[string]int{qualityBulkOutcomeAcknowledged: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(tt.body)
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var created QualityBulkJobView
+			if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			view := waitQualityBulkJob(t, s, created.ID)
+			if view.Status != qualityBulkJobCompleted || view.Total != tt.wantTotal || view.Processed != tt.wantTotal {
+				t.Fatalf("job = %+v, want completed with %d violations", view, tt.wantTotal)
+			}
+			for outcome, count := range tt.wantSummary {
+				if view.Summary[outcome] != count {
+					t.Errorf("summary[%s] = %d, want %d", outcome, view.Summary[outcome], count)
+				}
+			}
+		})
+	}
+
+	item, err := db.GetNormalizedItemByID(1)
+	if err != nil {
+		t.Fatalf("GetNormalizedItemByID() error = %v", err)
+	}
+	if item.NormalizedName != "болт м10" {
+		t.Errorf("normalized_name = %q, want fixed value", item.NormalizedName)
+	}
+	resolved, err := db.FindQualityViolations(database.QualityViolationFilter{Status: database.QualityViolationResolved}, 10)
+	if err != nil || len(resolved) != 1 || resolved[0].NormalizedItemID != 1 {
+		t.Errorf("resolved violations = %+v, %v", resolved, err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleQualityBulkJobs(rec, httptest.NewRequest(http.MethodGet, "/api/quality/bulk-jobs", nil))
+	var list struct {
+		Jobs  []QualityBulkJobView `json:"jobs"`
+		Total int                  `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || list.Total != len(tests) {
+		t.Fatalf("jobs list = %+v, %v", list, err)
+	}
+	if list.Jobs[0].Action != qualityBulkAcknowledge || list.Jobs[0].Results != nil {
+		t.Errorf("jobs list should start with the newest job without results: %+v", list.Jobs[0])
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleQualityBulkJobDetail(rec, httptest.NewRequest(http.MethodGet, "/api/quality/bulk-jobs/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown job status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+	"httpserver/quality"
+)
+
+// handleDatabaseGates пороги качества базы данных перед выгрузкой в 1С
+// GET /api/databases/{id}/gates - состояние порогов (для базы без порогов - по порогам по умолчанию)
+// PUT /api/databases/{id}/gates {"min_classification_coverage": 0.95, "max_duplicate_rate": 0.02, "require_empty_review_queue": true}
+// DELETE /api/databases/{id}/gates - выгрузка в 1С перестает проверять пороги
+func (s *Server) handleDatabaseGates(w http.ResponseWriter, r *http.Request, databaseID int) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req database.QualityGates
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.DatabaseID = databaseID
+		if err := s.serviceDB.SetQualityGates(&req); err != nil {
+			s.writeAPIError(w, "Failed to set quality gates", err)
+			return
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Пороги качества базы %d обновлены", databaseID),
+			Endpoint:  r.URL.Path,
+		})
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteQualityGates(databaseID); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to delete quality gates: %v", err), http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, status, err := s.evaluateDatabaseGates(databaseID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), status)
+		return
+	}
+	s.writeJSONResponse(w, report, http.StatusOK)
+}
+
+// evaluateDatabaseGates проверяет пороги качества по файлу базы данных проекта
+func (s *Server) evaluateDatabaseGates(databaseID int) (*quality.GateReport, int, error) {
+	stored, err := s.serviceDB.GetQualityGates(databaseID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	gates := database.DefaultQualityGates(databaseID)
+	if stored != nil {
+		gates = *stored
+	}
+
+	dbInfo, err := s.serviceDB.GetProjectDatabase(databaseID)
+	if err != nil || dbInfo == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("database %d not found", databaseID)
+	}
+	projectDB, err := database.NewDB(dbInfo.FilePath)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to open database %s: %w", dbInfo.Name, err)
+	}
+	defer projectDB.Close()
+
+	report, err := quality.EvaluateDatabaseGates(projectDB, gates)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to evaluate quality gates: %w", err)
+	}
+	report.Configured = stored != nil
+	return report, http.StatusOK, nil
+}
+
+// checkExportGates проверяет пороги качества базы выгрузки перед выгрузкой в 1С. Проверяются только
+// настроенные пороги; при невыполненных порогах выгрузка запускается лишь с override_gates, что
+// записывается в журнал аудита. Возвращает false, если ответ уже записан
+func (s *Server) checkExportGates(w http.ResponseWriter, r *http.Request, upload *database.Upload, exportType string, payload ExportRequest) bool {
+	if s.serviceDB == nil || upload.DatabaseID == nil {
+		return true
+	}
+	databaseID := *upload.DatabaseID
+	stored, err := s.serviceDB.GetQualityGates(databaseID)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get quality gates: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	if stored == nil {
+		return true
+	}
+
+	report, status, err := s.evaluateDatabaseGates(databaseID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), status)
+		return false
+	}
+	if report.Passed {
+		return true
+	}
+
+	failed := report.Failed()
+	if !payload.OverrideGates {
+		s.log(LogEntry{
+			Timestamp:  time.Now(),
+			Level:      "WARN",
+			Message:    fmt.Sprintf("Export of upload %s refused: quality gates %v of database %d failed", upload.UploadUUID, failed, databaseID),
+			UploadUUID: upload.UploadUUID,
+			Endpoint:   "/api/uploads/{uuid}/export",
+		})
+		s.writeJSONResponse(w, map[string]interface{}{
+			"error":     "Quality gates failed, fix the data or start export with override_gates",
+			"code":      "quality_gates_failed",
+			"gates":     report,
+			"timestamp": time.Now().Format(time.RFC3339),
+		}, http.StatusConflict)
+		return false
+	}
+
+	actor := requestActor(r, payload.Actor, r.RemoteAddr)
+	err = s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action: database.AuditActionGatesOverride,
+		Actor:  actor,
+		Target: "database:" + strconv.Itoa(databaseID),
+		Status: "success",
+		Details: map[string]interface{}{
+			"upload_uuid":  upload.UploadUUID,
+			"export_type":  exportType,
+			"target_url":   payload.TargetURL,
+			"failed_gates": failed,
+		},
+	})
+	if err != nil {
+		log.Printf("Ошибка записи обхода порогов качества в журнал аудита: %v", err)
+	}
+	s.log(LogEntry{
+		Timestamp:  time.Now(),
+		Level:      "WARN",
+		Message:    fmt.Sprintf("Export of upload %s started by %s despite failed quality gates %v", upload.UploadUUID, actor, failed),
+		UploadUUID: upload.UploadUUID,
+		Endpoint:   "/api/uploads/{uuid}/export",
+	})
+	return true
+}
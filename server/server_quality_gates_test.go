@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestDatabaseQualityGates(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+
+	projectPath := filepath.Join(t.TempDir(), "project.db")
+	projectDB, err := database.NewDB(projectPath)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+	projectDB.Close()
+
+	client, _ := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	project, _ := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	dbInfo, err := serviceDB.CreateProjectDatabase(project.ID, "Основная", projectPath, "", 0)
+	if err != nil {
+		t.Fatalf("CreateProjectDatabase() error = %v", err)
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), serviceDB: serviceDB, exportJobs: make(map[string]*ExportJob)}
+	gatesPath := fmt.Sprintf("/api/databases/%d/gates", dbInfo.ID)
+	upload := &database.Upload{UploadUUID: "uuid-gates", DatabaseID: &dbInfo.ID}
+	export := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleUploadExport(rec, httptest.NewRequest(http.MethodPost, "/api/uploads/uuid-gates/export", strings.NewReader(body)), upload)
+		return rec
+	}
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"defaults not enforced", http.MethodGet, gatesPath, "", http.StatusOK, `"configured":false`},
+		{"invalid threshold", http.MethodPut, gatesPath, `{"min_classification_coverage": 1.5}`, http.StatusBadRequest, "invalid_quality_gate"},
+		// Без нормализованных данных порог покрытия классификацией не выполнен
+		{"configure", http.MethodPut, gatesPath, `{"min_classification_coverage": 0.9}`, http.StatusOK, `"passed":false`},
+		{"unknown database", http.MethodGet, "/api/databases/999/gates", "", http.StatusNotFound, ""},
+		{"nested path", http.MethodGet, gatesPath + "/extra", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleDatabaseRoutes(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d, body = %s, want %d with %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	if rec := export(`{"target_url": "http://1c.local"}`); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "quality_gates_failed") {
+		t.Fatalf("export status = %d, body = %s, want 409 quality_gates_failed", rec.Code, rec.Body.String())
+	}
+	if len(s.getAllExportJobs()) != 0 {
+		t.Error("export job was created despite failed quality gates")
+	}
+
+	rec := httptest.NewRecorder()
+	ok := s.checkExportGates(rec, httptest.NewRequest(http.MethodPost, "/", nil), upload, ExportTypeProtocol, ExportRequest{OverrideGates: true, Actor: "admin"})
+	if !ok {
+		t.Fatalf("checkExportGates(override) = false, body = %s", rec.Body.String())
+	}
+	events, _ := serviceDB.GetAuditEvents(database.AuditActionGatesOverride, 10)
+	if len(events) != 1 || events[0].Actor != "admin" || events[0].Target != fmt.Sprintf("database:%d", dbInfo.ID) {
+		t.Errorf("audit events = %+v, want one override by admin", events)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleDatabaseRoutes(rec, httptest.NewRequest(http.MethodDelete, gatesPath, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"configured":false`) {
+		t.Fatalf("delete status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !s.checkExportGates(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil), upload, ExportTypeProtocol, ExportRequest{}) {
+		t.Error("checkExportGates() = false after gates were deleted")
+	}
+}
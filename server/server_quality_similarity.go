@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"httpserver/quality"
+)
+
+// handleQualitySimilarity объясняет сходство двух наименований по составляющим оценки нечеткого сопоставителя
+// GET /api/quality/similarity?a=...&b=...&threshold=0.85
+func (s *Server) handleQualitySimilarity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	a, b := query.Get("a"), query.Get("b")
+	if a == "" || b == "" {
+		s.writeJSONError(w, "Parameters a and b are required", http.StatusBadRequest)
+		return
+	}
+
+	threshold := 0.0
+	if value := query.Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			s.writeJSONError(w, "Invalid threshold parameter", http.StatusBadRequest)
+			return
+		}
+		threshold = parsed
+	}
+
+	s.writeJSONResponse(w, quality.ExplainSimilarity(a, b, threshold), http.StatusOK)
+}
@@ -34,6 +34,8 @@ type ReclassificationRequest struct {
 	ClassifierID int    `json:"classifier_id"`
 	StrategyID   string `json:"strategy_id"`
 	Limit        int    `json:"limit,omitempty"` // 0 = без лимита
+	// Классифицировать и записи, входные данные которых не изменились с прошлой классификации
+	Force bool `json:"force,omitempty"`
 }
 
 var (
@@ -85,8 +87,17 @@ func (s *Server) handleReclassificationStart(w http.ResponseWriter, r *http.Requ
 		req.StrategyID = "top_priority"
 	}
 
+	job, err := s.startBackgroundJob(r.Context(), jobKindReclassification, req.StrategyID)
+	if err != nil {
+		reclassificationMutex.Lock()
+		reclassificationRunning = false
+		reclassificationMutex.Unlock()
+		s.writeAPIError(w, "Failed to start reclassification", err)
+		return
+	}
+
 	// Запускаем переклассификацию в отдельной горутине
-	go s.runReclassification(req)
+	go s.runReclassification(job, req)
 
 	s.writeJSONResponse(w, map[string]interface{}{
 		"success": true,
@@ -94,6 +105,7 @@ func (s *Server) handleReclassificationStart(w http.ResponseWriter, r *http.Requ
 		"classifier_id": req.ClassifierID,
 		"strategy_id": req.StrategyID,
 		"limit": req.Limit,
+		"force": req.Force,
 	}, http.StatusOK)
 }
 
@@ -183,7 +195,8 @@ func (s *Server) handleReclassificationStop(w http.ResponseWriter, r *http.Reque
 }
 
 // runReclassification выполняет переклассификацию
-func (s *Server) runReclassification(req ReclassificationRequest) {
+func (s *Server) runReclassification(job *backgroundJob, req ReclassificationRequest) {
+	defer s.finishBackgroundJob(job, nil)
 	defer func() {
 		reclassificationMutex.Lock()
 		reclassificationRunning = false
@@ -195,6 +208,7 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 
 		s.sendReclassificationEvent("✅ Переклассификация завершена")
 	}()
+	defer s.recoverWorker(jobKindReclassification, job)
 
 	startTime := time.Now()
 
@@ -219,6 +233,9 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 	if req.Limit > 0 {
 		s.sendReclassificationEvent(fmt.Sprintf("🔢 Лимит: %d записей", req.Limit))
 	}
+	if req.Force {
+		s.sendReclassificationEvent("♻️  Принудительный режим: записи с неизмененными данными классифицируются повторно")
+	}
 
 	// Получаем классификатор (из основной БД, где хранятся классификаторы)
 	classifier, err := s.db.GetCategoryClassifier(req.ClassifierID)
@@ -229,10 +246,10 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 
 	s.sendReclassificationEvent(fmt.Sprintf("✅ Классификатор загружен: %s (глубина: %d)", classifier.Name, classifier.MaxDepth))
 
-	// Парсим дерево классификатора
-	var classifierTree classification.CategoryNode
-	if err := json.Unmarshal([]byte(classifier.TreeStructure), &classifierTree); err != nil {
-		s.sendReclassificationEvent(fmt.Sprintf("❌ Ошибка парсинга дерева классификатора: %v", err))
+	// Загружаем дерево классификатора (узлы хранятся строками, JSON разбирается только для старых классификаторов)
+	classifierTree, err := classification.LoadClassifierTree(s.db, classifier)
+	if err != nil {
+		s.sendReclassificationEvent(fmt.Sprintf("❌ Ошибка загрузки дерева классификатора: %v", err))
 		return
 	}
 
@@ -263,7 +280,7 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 	model := s.getModelFromConfig()
 
 	aiClassifier := classification.NewAIClassifier(apiKey, model)
-	aiClassifier.SetClassifierTree(&classifierTree)
+	aiClassifier.SetClassifierTree(classifierTree)
 
 	// Создаем менеджер стратегий
 	strategyManager := classification.NewStrategyManager()
@@ -273,7 +290,7 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 	s.sendReclassificationEvent("📥 Загрузка нормализованных записей...")
 
 	query := `
-		SELECT id, source_name, normalized_name, code, category
+		SELECT id, source_name, normalized_name, code, category, COALESCE(classification_hash, '')
 		FROM normalized_data
 		WHERE source_name IS NOT NULL AND source_name != ''
 		ORDER BY id
@@ -295,12 +312,13 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 		NormalizedName string
 		Code          string
 		OldCategory   string
+		Hash          string // Хеш входных данных прошлой классификации
 	}
 
 	var items []Item
 	for rows.Next() {
 		var item Item
-		if err := rows.Scan(&item.ID, &item.SourceName, &item.NormalizedName, &item.Code, &item.OldCategory); err != nil {
+		if err := rows.Scan(&item.ID, &item.SourceName, &item.NormalizedName, &item.Code, &item.OldCategory, &item.Hash); err != nil {
 			log.Printf("Ошибка сканирования: %v", err)
 			continue
 		}
@@ -338,6 +356,15 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 			s.sendReclassificationEvent("⚠ Процесс остановлен пользователем")
 			break
 		}
+		if job.ctx.Err() != nil {
+			s.sendReclassificationEvent(fmt.Sprintf("⚠ Процесс остановлен при завершении работы сервера на записи %d из %d", i, totalItems))
+			break
+		}
+		job.setCheckpoint(map[string]interface{}{
+			"processed":    i,
+			"total":        totalItems,
+			"last_item_id": item.ID,
+		})
 
 		// Классифицируем с помощью AI и КПВЭД
 		aiRequest := classification.AIClassificationRequest{
@@ -346,6 +373,18 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 			MaxLevels:   classifier.MaxDepth,
 		}
 
+		// Входные данные не изменились с прошлой классификации - повторный запрос к AI не нужен
+		contentHash := aiClassifier.ContentHash(aiRequest, req.StrategyID)
+		if !req.Force && item.Hash == contentHash {
+			skippedCount++
+			reclassificationStatusMutex.Lock()
+			reclassificationStatus.Processed = i + 1
+			reclassificationStatus.Skipped = skippedCount
+			reclassificationStatus.Progress = float64(i+1) / float64(totalItems) * 100
+			reclassificationStatusMutex.Unlock()
+			continue
+		}
+
 		aiResponse, err := aiClassifier.ClassifyWithAI(aiRequest)
 		if err != nil {
 			// Детальная информация об ошибке
@@ -402,7 +441,8 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 			SET category = ?,
 			    kpved_code = ?,
 			    kpved_name = ?,
-			    kpved_confidence = ?
+			    kpved_confidence = ?,
+			    classification_hash = ?
 			WHERE id = ?
 		`
 
@@ -413,7 +453,7 @@ func (s *Server) runReclassification(req ReclassificationRequest) {
 		}
 
 		// Обновляем запись в основной БД (1c_data.db), где реально хранятся данные
-		_, err = s.db.Exec(updateQuery, newCategory, kpvedCode, kpvedName, aiResponse.Confidence, item.ID)
+		_, err = s.db.Exec(updateQuery, newCategory, kpvedCode, kpvedName, aiResponse.Confidence, contentHash, item.ID)
 		if err != nil {
 			errorMsg := fmt.Sprintf("❌ Ошибка обновления для '%s' (ID: %d): %v", item.SourceName, item.ID, err)
 			log.Printf("%s", errorMsg)
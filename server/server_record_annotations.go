@@ -0,0 +1,261 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// RecordCommentRequest запрос на добавление или изменение комментария
+type RecordCommentRequest struct {
+	TargetType string `json:"target_type"`
+	TargetKey  string `json:"target_key"`
+	Author     string `json:"author"`
+	Body       string `json:"body"`
+}
+
+// RecordTagRequest запрос на отметку записи тегом
+type RecordTagRequest struct {
+	TargetType string `json:"target_type"`
+	TargetKey  string `json:"target_key"`
+	Tag        string `json:"tag"`
+	CreatedBy  string `json:"created_by"`
+}
+
+// annotationFilter разбирает фильтр комментариев и тегов из параметров запроса
+func annotationFilter(r *http.Request) (database.RecordAnnotationFilter, error) {
+	query := r.URL.Query()
+	filter := database.RecordAnnotationFilter{
+		TargetType: query.Get("target_type"),
+		TargetKey:  query.Get("target_key"),
+		Tag:        query.Get("tag"),
+	}
+	if filter.TargetType != "" && !database.IsValidRecordTarget(filter.TargetType) {
+		return filter, fmt.Errorf("target_type must be %s or %s", database.RecordTargetItem, database.RecordTargetGroup)
+	}
+	return filter, nil
+}
+
+// handleProjectComments управляет комментариями к нормализованным записям и группам дубликатов проекта
+// GET/POST /api/clients/{id}/projects/{projectId}/comments[?target_type=&target_key=]
+// GET/PUT/DELETE /api/clients/{id}/projects/{projectId}/comments/{commentId}
+func (s *Server) handleProjectComments(w http.ResponseWriter, r *http.Request, clientID, projectID, commentID int) {
+	if !s.checkClientProject(w, clientID, projectID) {
+		return
+	}
+
+	if commentID == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			filter, err := annotationFilter(r)
+			if err != nil {
+				s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			comments, err := s.serviceDB.GetRecordComments(projectID, filter)
+			if err != nil {
+				s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			s.writeJSONResponse(w, map[string]interface{}{
+				"project_id": projectID,
+				"comments":   comments,
+				"total":      len(comments),
+			}, http.StatusOK)
+		case http.MethodPost:
+			var req RecordCommentRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			comment := &database.RecordComment{
+				ProjectID:  projectID,
+				TargetType: req.TargetType,
+				TargetKey:  req.TargetKey,
+				Author:     requestActor(r, req.Author, ""),
+				Body:       req.Body,
+			}
+			if err := s.serviceDB.CreateRecordComment(comment); err != nil {
+				s.writeRecordAnnotationError(w, err)
+				return
+			}
+			s.log(LogEntry{
+				Timestamp: time.Now(),
+				Level:     "INFO",
+				Message:   fmt.Sprintf("Комментарий проекта %d: добавлен к %s %s", projectID, comment.TargetType, comment.TargetKey),
+				Endpoint:  r.URL.Path,
+			})
+			s.writeJSONResponse(w, comment, http.StatusCreated)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	comment, err := s.serviceDB.GetRecordComment(projectID, commentID)
+	if err != nil {
+		s.writeJSONError(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSONResponse(w, comment, http.StatusOK)
+	case http.MethodPut:
+		var req RecordCommentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		comment.Body = req.Body
+		if err := s.serviceDB.UpdateRecordComment(comment); err != nil {
+			s.writeRecordAnnotationError(w, err)
+			return
+		}
+		s.writeJSONResponse(w, comment, http.StatusOK)
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteRecordComment(projectID, commentID); err != nil {
+			s.writeRecordAnnotationError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProjectTags управляет тегами нормализованных записей и групп дубликатов проекта
+// GET/POST /api/clients/{id}/projects/{projectId}/tags[?target_type=&target_key=&tag=]
+// GET /api/clients/{id}/projects/{projectId}/tags/counts
+// DELETE /api/clients/{id}/projects/{projectId}/tags/{tagId}
+func (s *Server) handleProjectTags(w http.ResponseWriter, r *http.Request, clientID, projectID int, action string) {
+	if !s.checkClientProject(w, clientID, projectID) {
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		filter, err := annotationFilter(r)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tags, err := s.serviceDB.GetRecordTags(projectID, filter)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"project_id": projectID,
+			"tags":       tags,
+			"total":      len(tags),
+		}, http.StatusOK)
+	case action == "" && r.Method == http.MethodPost:
+		var req RecordTagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		tag := &database.RecordTag{
+			ProjectID:  projectID,
+			TargetType: req.TargetType,
+			TargetKey:  req.TargetKey,
+			Tag:        req.Tag,
+			CreatedBy:  requestActor(r, req.CreatedBy, ""),
+		}
+		if err := s.serviceDB.AddRecordTag(tag); err != nil {
+			s.writeRecordAnnotationError(w, err)
+			return
+		}
+		s.writeJSONResponse(w, tag, http.StatusCreated)
+	case action == "counts" && r.Method == http.MethodGet:
+		counts, err := s.serviceDB.GetRecordTagCounts(projectID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"project_id": projectID,
+			"counts":     counts,
+		}, http.StatusOK)
+	case action != "" && action != "counts" && r.Method == http.MethodDelete:
+		tagID, err := strconv.Atoi(action)
+		if err != nil {
+			s.writeJSONError(w, "Invalid tag ID", http.StatusBadRequest)
+			return
+		}
+		if err := s.serviceDB.DeleteRecordTag(projectID, tagID); err != nil {
+			s.writeRecordAnnotationError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeRecordAnnotationError возвращает ошибку сохранения комментария или тега с подходящим статусом
+func (s *Server) writeRecordAnnotationError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		s.writeJSONError(w, err.Error(), http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "failed"):
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+	default:
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// taggedRecordsCondition возвращает условие на normalized_data для записей проекта с тегом:
+// отмеченные записи и записи отмеченных групп. Пустой tag - без ограничения
+func (s *Server) taggedRecordsCondition(projectID int, tag string) (string, []interface{}, error) {
+	if strings.TrimSpace(tag) == "" {
+		return "1=1", nil, nil
+	}
+	if projectID <= 0 {
+		return "", nil, fmt.Errorf("project_id is required to filter by tag")
+	}
+	if s.serviceDB == nil {
+		return "", nil, fmt.Errorf("service database is not available")
+	}
+	tagged, err := s.serviceDB.GetTaggedRecords(projectID, tag)
+	if err != nil {
+		return "", nil, err
+	}
+	if tagged.Empty() {
+		return "1=0", nil, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	if len(tagged.ItemIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("id IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(tagged.ItemIDs)), ",")))
+		for _, id := range tagged.ItemIDs {
+			args = append(args, id)
+		}
+	}
+	if len(tagged.GroupRefs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("normalized_reference IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(tagged.GroupRefs)), ",")))
+		for _, ref := range tagged.GroupRefs {
+			args = append(args, ref)
+		}
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+}
+
+// projectTagCounts возвращает число отмеченных записей по тегам проекта; nil при ошибке
+func (s *Server) projectTagCounts(projectID int) map[string]int {
+	if s.serviceDB == nil || projectID <= 0 {
+		return nil
+	}
+	counts, err := s.serviceDB.GetRecordTagCounts(projectID)
+	if err != nil {
+		return nil
+	}
+	return counts
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestProjectTagsFilterNormalizationGroups(t *testing.T) {
+	db := newReclassifyTestDB(t)
+	defer db.Close()
+	if _, err := db.Exec(`UPDATE normalized_data SET normalized_reference = 'ref-' || code`); err != nil {
+		t.Fatalf("Failed to set references: %v", err)
+	}
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	project, err := serviceDB.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("CreateClientProject() error = %v", err)
+	}
+	s := &Server{db: db, serviceDB: serviceDB, logChan: make(chan LogEntry, 10)}
+
+	tags := func(method, action, body string, clientID int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/clients/tags", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleProjectTags(rec, req, clientID, project.ID, action)
+		return rec
+	}
+
+	statuses := []struct {
+		name       string
+		body       string
+		clientID   int
+		wantStatus int
+	}{
+		{"other client", `{"target_type": "item", "target_key": "4", "tag": "ask client"}`, client.ID + 1, http.StatusBadRequest},
+		{"unknown target type", `{"target_type": "upload", "target_key": "4", "tag": "ask client"}`, client.ID, http.StatusBadRequest},
+		{"tag item", `{"target_type": "item", "target_key": "4", "tag": "Ask client"}`, client.ID, http.StatusCreated},
+		{"tag another item", `{"target_type": "item", "target_key": "3", "tag": "verify with warehouse"}`, client.ID, http.StatusCreated},
+	}
+	for _, tt := range statuses {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := tags(http.MethodPost, "", tt.body, tt.clientID); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	rec := tags(http.MethodGet, "counts", "", client.ID)
+	var counts struct {
+		Counts map[string]int `json:"counts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &counts); err != nil || counts.Counts["ask client"] != 1 {
+		t.Errorf("tag counts = %s", rec.Body.String())
+	}
+
+	groups := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/normalization/groups?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleNormalizationGroups(rec, req)
+		return rec
+	}
+	if rec := groups("tag=ask+client"); rec.Code != http.StatusBadRequest {
+		t.Errorf("tag filter without project: status = %d, want 400", rec.Code)
+	}
+
+	rec = groups(fmt.Sprintf("project_id=%d&tag=%s", project.ID, url.QueryEscape("ask client")))
+	var result struct {
+		Groups []struct {
+			NormalizedName string `json:"normalized_name"`
+		} `json:"groups"`
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response: %v, body = %s", err, rec.Body.String())
+	}
+	if result.Total != 1 || len(result.Groups) != 1 || result.Groups[0].NormalizedName != "молоко 3.2%" {
+		t.Errorf("groups with tag = %+v", result)
+	}
+
+	rec = groups(fmt.Sprintf("project_id=%d&tag=unknown", project.ID))
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || result.Total != 0 {
+		t.Errorf("groups with unused tag = %s", rec.Body.String())
+	}
+}
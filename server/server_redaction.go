@@ -0,0 +1,259 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+// requestRedaction возвращает профиль скрытия колонок пользователя запроса: назначенный ему лично,
+// иначе его роли. Запросы без сессии получают профиль REDACTION_DEFAULT_PROFILE
+func (s *Server) requestRedaction(r *http.Request) (*database.RedactionProfile, error) {
+	if s.serviceDB == nil {
+		return nil, nil
+	}
+	session := sessionFromContext(r.Context())
+	if session == nil {
+		config := s.currentConfig()
+		if config == nil || config.RedactionDefaultProfile == "" {
+			return nil, nil
+		}
+		return s.serviceDB.GetRedactionProfileByName(config.RedactionDefaultProfile)
+	}
+	return s.serviceDB.ResolveRedactionProfile(session.User.Username, session.User.Role)
+}
+
+// recordRedactionUsage записывает в журнал аудита выдачу данных с профилем скрытия; channel - эндпоинт
+// или тип задачи экспорта, target - выгрузка или задача
+func (s *Server) recordRedactionUsage(actor string, profile *database.RedactionProfile, channel, target string) {
+	if profile == nil || s.serviceDB == nil {
+		return
+	}
+	err := s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action: database.AuditActionRedactionApplied,
+		Actor:  actor,
+		Target: target,
+		Status: "success",
+		Details: map[string]interface{}{
+			"profile": profile.Name,
+			"channel": channel,
+			"rules":   len(profile.Rules),
+		},
+	})
+	if err != nil {
+		log.Printf("Ошибка записи применения профиля скрытия в журнал аудита: %v", err)
+	}
+}
+
+// recordRedactionChange записывает в журнал аудита изменение профиля скрытия или его назначения
+func (s *Server) recordRedactionChange(r *http.Request, target string, details map[string]interface{}) {
+	err := s.serviceDB.RecordAuditEvent(&database.AuditEvent{
+		Action:  database.AuditActionRedactionProfile,
+		Actor:   requestActor(r, "", "api"),
+		Target:  target,
+		Status:  "success",
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("Ошибка записи изменения профиля скрытия в журнал аудита: %v", err)
+	}
+}
+
+// redactionProfileRequest запрос создания или изменения профиля скрытия
+type redactionProfileRequest struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Rules       []database.RedactionRule `json:"rules"`
+}
+
+// handleRedactionProfiles список профилей скрытия колонок или создание профиля
+// GET /api/redaction/profiles
+// POST /api/redaction/profiles {"name": "contractor", "description": "...", "rules": [{"field": "attributes.ЦенаПоставщика", "action": "hide"}, {"field": "code", "action": "mask"}]}
+func (s *Server) handleRedactionProfiles(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		profiles, err := s.serviceDB.ListRedactionProfiles()
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if profiles == nil {
+			profiles = []*database.RedactionProfile{}
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"profiles": profiles,
+			"total":    len(profiles),
+		}, http.StatusOK)
+	case http.MethodPost:
+		var req redactionProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		profile := &database.RedactionProfile{Name: req.Name, Description: req.Description, Rules: req.Rules}
+		if err := s.serviceDB.CreateRedactionProfile(profile); err != nil {
+			s.writeAPIError(w, err.Error(), err)
+			return
+		}
+		s.recordRedactionChange(r, "redaction_profile:"+profile.Name, map[string]interface{}{"operation": "create", "rules": profile.Rules})
+		s.writeJSONResponse(w, profile, http.StatusCreated)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRedactionProfileRoutes профиль скрытия, его изменение и удаление
+// GET /api/redaction/profiles/{id}
+// PUT /api/redaction/profiles/{id} {"description": "...", "rules": [...]}
+// DELETE /api/redaction/profiles/{id}
+func (s *Server) handleRedactionProfileRoutes(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/redaction/profiles/"), "/"))
+	if err != nil || id <= 0 {
+		s.writeJSONError(w, "Invalid profile ID", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		profile, err := s.serviceDB.GetRedactionProfile(id)
+		if err != nil {
+			s.writeAPIError(w, err.Error(), err)
+			return
+		}
+		s.writeJSONResponse(w, profile, http.StatusOK)
+	case http.MethodPut:
+		var req redactionProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		profile, err := s.serviceDB.UpdateRedactionProfile(id, req.Description, req.Rules)
+		if err != nil {
+			s.writeAPIError(w, err.Error(), err)
+			return
+		}
+		s.recordRedactionChange(r, "redaction_profile:"+profile.Name, map[string]interface{}{"operation": "update", "rules": profile.Rules})
+		s.writeJSONResponse(w, profile, http.StatusOK)
+	case http.MethodDelete:
+		profile, err := s.serviceDB.GetRedactionProfile(id)
+		if err != nil {
+			s.writeAPIError(w, err.Error(), err)
+			return
+		}
+		if err := s.serviceDB.DeleteRedactionProfile(id); err != nil {
+			s.writeAPIError(w, err.Error(), err)
+			return
+		}
+		s.recordRedactionChange(r, "redaction_profile:"+profile.Name, map[string]interface{}{"operation": "delete"})
+		s.writeJSONResponse(w, map[string]interface{}{"id": id, "deleted": true}, http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRedactionAssignments назначения профилей скрытия ролям и пользователям.
+// Профиль пользователя важнее профиля его роли; пустой profile снимает назначение
+// GET /api/redaction/assignments
+// PUT /api/redaction/assignments {"subject_type": "role", "subject": "viewer", "profile": "contractor"}
+func (s *Server) handleRedactionAssignments(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		assignments, err := s.serviceDB.ListRedactionAssignments()
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if assignments == nil {
+			assignments = []*database.RedactionAssignment{}
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"assignments": assignments,
+			"total":       len(assignments),
+		}, http.StatusOK)
+	case http.MethodPut:
+		var req struct {
+			SubjectType string `json:"subject_type"`
+			Subject     string `json:"subject"`
+			Profile     string `json:"profile"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		profileID := 0
+		if strings.TrimSpace(req.Profile) != "" {
+			profile, err := s.serviceDB.GetRedactionProfileByName(req.Profile)
+			if err != nil {
+				s.writeAPIError(w, err.Error(), err)
+				return
+			}
+			profileID = profile.ID
+		}
+		if err := s.serviceDB.AssignRedactionProfile(req.SubjectType, req.Subject, profileID); err != nil {
+			s.writeAPIError(w, err.Error(), err)
+			return
+		}
+		s.recordRedactionChange(r, req.SubjectType+":"+strings.TrimSpace(req.Subject),
+			map[string]interface{}{"operation": "assign", "profile": req.Profile})
+		s.writeJSONResponse(w, map[string]interface{}{
+			"subject_type": req.SubjectType,
+			"subject":      strings.TrimSpace(req.Subject),
+			"profile":      req.Profile,
+		}, http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// exportRedaction профиль скрытия задачи экспорта: профиль создателя задачи, объединенный
+// с профилем получателя из запроса. Профиль создателя нельзя ослабить выбором другого профиля
+func (s *Server) exportRedaction(r *http.Request, profileName string) (*database.RedactionProfile, error) {
+	requester, err := s.requestRedaction(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(profileName) == "" {
+		return requester, nil
+	}
+	if s.serviceDB == nil {
+		return nil, apperrors.Validation("redaction_unavailable", "redaction profiles are not available")
+	}
+	recipient, err := s.serviceDB.GetRedactionProfileByName(profileName)
+	if err != nil {
+		return nil, err
+	}
+	return database.MergeRedactionProfiles(requester, recipient), nil
+}
+
+// importRedaction профиль скрытия запроса обработки 1С: профиль пользователя запроса, объединенный
+// с профилем задачи pull. Применение профиля задачи записано в аудит при ее создании
+func (s *Server) importRedaction(r *http.Request, pullJob *ExportJob, target string) (*database.RedactionProfile, error) {
+	requester, err := s.requestRedaction(r)
+	if err != nil {
+		return nil, err
+	}
+	s.recordRedactionUsage(requestActor(r, "", "api"), requester, r.URL.Path, target)
+	if pullJob == nil {
+		return requester, nil
+	}
+	return database.MergeRedactionProfiles(requester, pullJob.redaction), nil
+}
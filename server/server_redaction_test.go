@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestRedactionProfileHandlers(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+	s := &Server{logChan: make(chan LogEntry, 100), serviceDB: serviceDB, config: &Config{}}
+
+	steps := []struct {
+		name       string
+		handler    http.HandlerFunc
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"create", s.handleRedactionProfiles, http.MethodPost, "/api/redaction/profiles",
+			`{"name": "contractor", "rules": [{"field": "attributes.ЦенаПоставщика", "action": "hide"}]}`, http.StatusCreated},
+		{"invalid rule", s.handleRedactionProfiles, http.MethodPost, "/api/redaction/profiles",
+			`{"name": "other", "rules": [{"field": "price", "action": "hide"}]}`, http.StatusBadRequest},
+		{"update", s.handleRedactionProfileRoutes, http.MethodPut, "/api/redaction/profiles/1",
+			`{"rules": [{"field": "attributes.ЦенаПоставщика", "action": "hide"}, {"field": "code", "action": "mask"}]}`, http.StatusOK},
+		{"unknown profile", s.handleRedactionProfileRoutes, http.MethodGet, "/api/redaction/profiles/9", "", http.StatusNotFound},
+		{"assign", s.handleRedactionAssignments, http.MethodPut, "/api/redaction/assignments",
+			`{"subject_type": "role", "subject": "viewer", "profile": "contractor"}`, http.StatusOK},
+		{"assign unknown profile", s.handleRedactionAssignments, http.MethodPut, "/api/redaction/assignments",
+			`{"subject_type": "role", "subject": "viewer", "profile": "missing"}`, http.StatusNotFound},
+		{"list assignments", s.handleRedactionAssignments, http.MethodGet, "/api/redaction/assignments", "", http.StatusOK},
+	}
+	for _, tt := range steps {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	events, _ := serviceDB.GetAuditEvents(database.AuditActionRedactionProfile, 10)
+	if len(events) != 3 {
+		t.Errorf("audit events = %d, want 3", len(events))
+	}
+}
+
+func TestUploadDataRedaction(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+
+	upload, _ := db.CreateUpload("uuid-redaction", "8.3", "УправлениеТорговлей")
+	catalog, _ := db.AddCatalog(upload.ID, "Номенклатура", "Номенклатура")
+	db.AddCatalogItem(catalog.ID, "ref-1", "001", "Болт М10", "<Артикул>B-10</Артикул><ЦенаПоставщика>42</ЦенаПоставщика>", "")
+	db.AddConstant(upload.ID, "КлючAPI", "Ключ", "Строка", "secret-key")
+
+	profile := &database.RedactionProfile{Name: "contractor", Rules: []database.RedactionRule{
+		{Field: "attributes.ЦенаПоставщика", Action: database.RedactionHide},
+		{Field: "constants.КлючAPI", Action: database.RedactionMask},
+	}}
+	if err := serviceDB.CreateRedactionProfile(profile); err != nil {
+		t.Fatalf("CreateRedactionProfile() error = %v", err)
+	}
+	if err := serviceDB.AssignRedactionProfile(database.RedactionSubjectRole, database.RoleViewer, profile.ID); err != nil {
+		t.Fatalf("AssignRedactionProfile() error = %v", err)
+	}
+	s := &Server{logChan: make(chan LogEntry, 100), db: db, serviceDB: serviceDB, config: &Config{}}
+
+	withRole := func(path, role string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if role == "" {
+			return r
+		}
+		session := &database.UserSession{User: &database.User{Username: "u-" + role, Role: role}}
+		return r.WithContext(context.WithValue(r.Context(), sessionKey{}, session))
+	}
+	tests := []struct {
+		name           string
+		role           string
+		defaultProfile string
+		stream         bool
+		wantHidden     bool
+	}{
+		{"viewer data", database.RoleViewer, "", false, true},
+		{"viewer stream", database.RoleViewer, "", true, true},
+		{"operator data", database.RoleOperator, "", false, false},
+		{"anonymous stream", "", "", true, false},
+		{"anonymous with default profile", "", "contractor", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.config.RedactionDefaultProfile = tt.defaultProfile
+			rec := httptest.NewRecorder()
+			if tt.stream {
+				s.streamUploadDataEvents(rec, withRole("/api/uploads/uuid-redaction/stream", tt.role), db, upload, "all", nil)
+			} else {
+				s.writeUploadData(rec, withRole("/api/uploads/uuid-redaction/data", tt.role), db, upload, "Upload data", "/api/uploads/{uuid}/data")
+			}
+			body := rec.Body.String()
+			if !strings.Contains(body, "B-10") {
+				t.Fatalf("response lacks visible attributes: %s", body)
+			}
+			hidden := !strings.Contains(body, "ЦенаПоставщика") && !strings.Contains(body, "secret-key") && strings.Contains(body, "***")
+			if hidden != tt.wantHidden {
+				t.Errorf("hidden = %v, want %v: %s", hidden, tt.wantHidden, body)
+			}
+		})
+	}
+
+	events, _ := serviceDB.GetAuditEvents(database.AuditActionRedactionApplied, 10)
+	if len(events) != 3 || events[0].Details["profile"] != "contractor" {
+		t.Errorf("usage audit events = %+v, want 3", events)
+	}
+}
+
+// newCatalogRedactionServer сервер с элементом справочника в двух выгрузках (цена изменилась) и профилем
+// скрытия цены и маскирования наименования для запросов без сессии
+func newCatalogRedactionServer(t *testing.T) *Server {
+	t.Helper()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	t.Cleanup(func() { serviceDB.Close() })
+	db, err := database.NewUnifiedDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewUnifiedDBWithConfig() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tableName, err := database.GetOrCreateCatalogTable(db.GetDB(), "Номенклатура")
+	if err != nil {
+		t.Fatalf("GetOrCreateCatalogTable() error = %v", err)
+	}
+	for i, price := range []string{"42", "50"} {
+		upload, err := db.CreateUpload([]string{"u1", "u2"}[i], "8.3", "УправлениеТорговлей")
+		if err != nil {
+			t.Fatalf("CreateUpload() error = %v", err)
+		}
+		attributes := "<Артикул>B-10</Артикул><ЦенаПоставщика>" + price + "</ЦенаПоставщика>"
+		if err := db.AddCatalogItemToTable(tableName, upload.ID, "ref-1", "0001", "Болт М10", attributes, ""); err != nil {
+			t.Fatalf("AddCatalogItemToTable() error = %v", err)
+		}
+	}
+
+	profile := &database.RedactionProfile{Name: "public", Rules: []database.RedactionRule{
+		{Field: "attributes.ЦенаПоставщика", Action: database.RedactionHide},
+		{Field: "name", Action: database.RedactionMask},
+	}}
+	if err := serviceDB.CreateRedactionProfile(profile); err != nil {
+		t.Fatalf("CreateRedactionProfile() error = %v", err)
+	}
+	return &Server{logChan: make(chan LogEntry, 100), unifiedCatalogsDB: db, serviceDB: serviceDB,
+		config: &Config{RedactionDefaultProfile: "public"}}
+}
+
+func TestCatalogItemsSearchRedaction(t *testing.T) {
+	s := newCatalogRedactionServer(t)
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"search by visible attribute", "?catalog=Номенклатура&attribute=Артикул&value=B-10", http.StatusOK},
+		{"search by hidden attribute", "?catalog=Номенклатура&attribute=ЦенаПоставщика&value=50", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleCatalogItemsSearch(rec, httptest.NewRequest(http.MethodGet, "/api/catalogs/items/search"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			body := rec.Body.String()
+			if tt.wantStatus == http.StatusOK && (!strings.Contains(body, "B-10") || strings.Contains(body, "ЦенаПоставщика") ||
+				strings.Contains(body, "Болт") || !strings.Contains(body, `"name":"***"`)) {
+				t.Errorf("body = %s, want price hidden and name masked", body)
+			}
+		})
+	}
+}
+
+func TestCatalogItemHistoryRedaction(t *testing.T) {
+	s := newCatalogRedactionServer(t)
+	rec := httptest.NewRecorder()
+	s.handleCatalogItemHistory(rec, httptest.NewRequest(http.MethodGet, "/api/catalogs/items/history?reference=ref-1", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `"total":2`) {
+		t.Fatalf("status = %d, body = %s", rec.Code, body)
+	}
+	if !strings.Contains(body, "B-10") || strings.Contains(body, "ЦенаПоставщика") || strings.Contains(body, "Болт") {
+		t.Errorf("body = %s, want price hidden in versions and changes, name masked", body)
+	}
+}
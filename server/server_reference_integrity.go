@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"httpserver/database"
+	"httpserver/quality"
+)
+
+// handleUploadReferenceIntegrity анализирует ссылочную целостность реквизитов справочников выгрузки.
+// GET /api/uploads/{uuid}/integrity - отчет с оценкой по справочникам;
+// POST - то же, с записью битых ссылок в проблемы качества и метрики reference_integrity
+func (s *Server) handleUploadReferenceIntegrity(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	source := uploadDB
+	if r.Method == http.MethodGet {
+		source = uploadDB.ReadOnly()
+	}
+	report, err := quality.AnalyzeReferenceIntegrity(r.Context(), source, upload.ID)
+	if err != nil {
+		s.writeAPIError(w, "Failed to analyze reference integrity", err)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		databaseID := 0
+		if upload.DatabaseID != nil {
+			databaseID = *upload.DatabaseID
+		}
+		if err := s.qualityAnalyzerFor(uploadDB).RecordReferenceIntegrity(report, databaseID); err != nil {
+			s.writeAPIError(w, "Failed to record reference integrity issues", err)
+			return
+		}
+		s.logCtx(r.Context(), LogEntry{
+			Timestamp:  time.Now(),
+			Level:      "INFO",
+			Message:    fmt.Sprintf("Reference integrity of upload %s: %d references, %d dangling, score %.2f", upload.UploadUUID, report.References, report.Dangling, report.Score),
+			UploadUUID: upload.UploadUUID,
+			Endpoint:   "/api/uploads/{uuid}/integrity",
+		})
+	}
+
+	s.writeJSONResponse(w, report, http.StatusOK)
+}
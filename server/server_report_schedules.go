@@ -0,0 +1,611 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/i18n"
+	"httpserver/reports"
+)
+
+// reportWebhookTimeout таймаут отправки webhook о сформированных отчетах
+const reportWebhookTimeout = 30 * time.Second
+
+// ReportRunWebhookPayload уведомление webhook о запуске отчетов по расписанию
+type ReportRunWebhookPayload struct {
+	Event        string                  `json:"event"`
+	ScheduleID   int                     `json:"schedule_id"`
+	ScheduleName string                  `json:"schedule_name"`
+	ProjectID    int                     `json:"project_id"`
+	RunID        int                     `json:"run_id"`
+	Status       string                  `json:"status"`
+	Error        string                  `json:"error,omitempty"`
+	Artifacts    []ReportRunArtifactLink `json:"artifacts"`
+}
+
+// ReportRunArtifactLink отчет запуска со ссылкой для скачивания
+type ReportRunArtifactLink struct {
+	Template     string `json:"template"`
+	Database     string `json:"database"`
+	DownloadURL  string `json:"download_url,omitempty"`
+	PresignedURL string `json:"presigned_url,omitempty"` // Прямая ссылка на объект в хранилище артефактов
+	Error        string `json:"error,omitempty"`
+}
+
+// runReportSchedulerLoop периодически запускает расписания отчетов, время которых наступило
+func (s *Server) runReportSchedulerLoop() {
+	if s.serviceDB == nil || s.currentConfig().ReportSchedulerInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.currentConfig().ReportSchedulerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.runDueReportSchedules(time.Now())
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// runDueReportSchedules выполняет все расписания, время запуска которых наступило
+func (s *Server) runDueReportSchedules(now time.Time) {
+	schedules, err := s.serviceDB.GetDueReportSchedules(now)
+	if err != nil {
+		log.Printf("Ошибка получения расписаний отчетов: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		if _, err := s.executeReportSchedule(schedule); err != nil {
+			log.Printf("Ошибка запуска отчетов по расписанию %d: %v", schedule.ID, err)
+		}
+	}
+}
+
+// nextReportRun вычисляет следующий запуск расписания (nil для выключенного расписания)
+func nextReportRun(schedule *database.ReportSchedule, after time.Time) (*time.Time, error) {
+	cron, err := reports.ParseCron(schedule.Cron)
+	if err != nil {
+		return nil, err
+	}
+	if !schedule.Enabled {
+		return nil, nil
+	}
+	next := cron.Next(after)
+	if next.IsZero() {
+		return nil, nil
+	}
+	return &next, nil
+}
+
+// executeReportSchedule формирует отчеты расписания по базам проекта, сохраняет их и рассылает
+func (s *Server) executeReportSchedule(schedule *database.ReportSchedule) (*database.ReportRun, error) {
+	startedAt := time.Now()
+	nextRunAt, err := nextReportRun(schedule, startedAt)
+	if err != nil {
+		return nil, err
+	}
+	// Следующий запуск фиксируется до формирования отчетов, чтобы долгий запуск не повторялся
+	if err := s.serviceDB.MarkReportScheduleRun(schedule.ID, startedAt, nextRunAt); err != nil {
+		return nil, err
+	}
+
+	run, err := s.serviceDB.CreateReportRun(schedule.ID, schedule.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	errorsList := s.generateScheduledReports(schedule, run)
+	s.archiveReportArtifacts(run)
+	run.Status = database.ReportRunStatusSuccess
+	if len(errorsList) > 0 {
+		run.Status = database.ReportRunStatusFailed
+		run.Error = strings.Join(errorsList, "; ")
+	}
+	run.DeliveryStatus = s.deliverReportRun(schedule, run)
+
+	if err := s.serviceDB.FinishReportRun(run); err != nil {
+		return nil, err
+	}
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Report schedule %d (%s) finished: %s, %d reports, delivery: %s", schedule.ID, schedule.Name, run.Status, len(run.Artifacts), run.DeliveryStatus),
+		Endpoint:  "/api/reports/schedules",
+	})
+	return run, nil
+}
+
+// generateScheduledReports формирует отчеты по всем базам расписания и возвращает ошибки
+func (s *Server) generateScheduledReports(schedule *database.ReportSchedule, run *database.ReportRun) []string {
+	var databases []*database.ProjectDatabase
+	if schedule.DatabaseID > 0 {
+		dbInfo, err := s.serviceDB.GetProjectDatabase(schedule.DatabaseID)
+		if err != nil {
+			return []string{err.Error()}
+		}
+		if dbInfo == nil {
+			return []string{fmt.Sprintf("database %d not found", schedule.DatabaseID)}
+		}
+		databases = append(databases, dbInfo)
+	} else {
+		var err error
+		if databases, err = s.serviceDB.GetProjectDatabases(schedule.ProjectID, true); err != nil {
+			return []string{err.Error()}
+		}
+		if len(databases) == 0 {
+			return []string{fmt.Sprintf("project %d has no active databases", schedule.ProjectID)}
+		}
+	}
+
+	runDir := filepath.Join(s.currentConfig().ReportArtifactsDir, fmt.Sprintf("run_%d", run.ID))
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return []string{fmt.Sprintf("failed to create artifacts directory: %v", err)}
+	}
+
+	branding := reports.Branding{}
+	secondLanguage := ""
+	if project, err := s.serviceDB.GetClientProject(schedule.ProjectID); err == nil && project != nil {
+		if client, err := s.serviceDB.GetClient(project.ClientID); err == nil && client != nil {
+			branding.ClientName = client.Name
+		}
+		secondLanguage = s.clientReportSecondLanguage(project.ClientID)
+	}
+	lang := s.scheduleLanguage(schedule)
+
+	var errorsList []string
+	for _, dbInfo := range databases {
+		projectDB, err := database.NewDB(dbInfo.FilePath)
+		if err != nil {
+			errorsList = append(errorsList, fmt.Sprintf("%s: %v", dbInfo.Name, err))
+			continue
+		}
+
+		stats := reports.NewProvider(projectDB.GetDB(), s.serviceDB.GetDB()).WithSecondLanguage(secondLanguage)
+		for _, name := range schedule.Templates {
+			artifact := database.ReportArtifact{Template: name, DatabaseID: dbInfo.ID, Database: dbInfo.Name}
+			artifact.Path = filepath.Join(runDir, fmt.Sprintf("%s_%d.html", name, dbInfo.ID))
+			if err := s.renderReportArtifact(name, artifact.Path, &reports.ReportContext{
+				Database: filepath.Base(dbInfo.FilePath),
+				Lang:     lang,
+				Branding: branding,
+				Stats:    stats,
+			}); err != nil {
+				artifact.Path = ""
+				artifact.Error = err.Error()
+				errorsList = append(errorsList, fmt.Sprintf("%s/%s: %v", dbInfo.Name, name, err))
+			} else if info, err := os.Stat(artifact.Path); err == nil {
+				artifact.Size = info.Size()
+			}
+			run.Artifacts = append(run.Artifacts, artifact)
+		}
+		projectDB.Close()
+	}
+	return errorsList
+}
+
+// renderReportArtifact формирует отчет по шаблону в файл
+func (s *Server) renderReportArtifact(name, path string, ctx *reports.ReportContext) error {
+	tpl, err := s.reportEngine.Lookup(name)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer file.Close()
+
+	if err := s.reportEngine.Render(file, tpl, ctx); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// deliverReportRun рассылает сформированные отчеты по почте и через webhook, возвращает итог рассылки
+func (s *Server) deliverReportRun(schedule *database.ReportSchedule, run *database.ReportRun) string {
+	var statuses []string
+
+	if len(schedule.Recipients) > 0 {
+		if err := s.emailReportRun(schedule, run); err != nil {
+			statuses = append(statuses, fmt.Sprintf("email: failed: %v", err))
+		} else {
+			statuses = append(statuses, "email: sent")
+		}
+	}
+
+	if schedule.WebhookURL != "" {
+		payload := ReportRunWebhookPayload{
+			Event:        "report_run",
+			ScheduleID:   schedule.ID,
+			ScheduleName: schedule.Name,
+			ProjectID:    schedule.ProjectID,
+			RunID:        run.ID,
+			Status:       run.Status,
+			Error:        run.Error,
+			Artifacts:    s.reportRunArtifactLinks(run),
+		}
+		client := &http.Client{Timeout: reportWebhookTimeout}
+		if err := reports.PostWebhook(client, schedule.WebhookURL, payload); err != nil {
+			statuses = append(statuses, fmt.Sprintf("webhook: failed: %v", err))
+		} else {
+			statuses = append(statuses, "webhook: sent")
+		}
+	}
+
+	return strings.Join(statuses, "; ")
+}
+
+// emailReportRun отправляет отчеты запуска получателям расписания вложениями
+func (s *Server) emailReportRun(schedule *database.ReportSchedule, run *database.ReportRun) error {
+	lang := s.scheduleLanguage(schedule)
+	var attachments []reports.Attachment
+	var body strings.Builder
+	body.WriteString(i18n.T(lang, "report.email.body", schedule.Name, run.StartedAt.Format("2006-01-02 15:04")))
+	body.WriteString("\n\n")
+	for _, artifact := range run.Artifacts {
+		if artifact.Error != "" {
+			fmt.Fprintf(&body, "- %s (%s): %s: %s\n", artifact.Template, artifact.Database, i18n.T(lang, "report.email.error"), artifact.Error)
+			continue
+		}
+		data, err := s.readReportArtifact(artifact)
+		if err != nil {
+			return fmt.Errorf("failed to read report %s: %w", artifact.Path, err)
+		}
+		attachments = append(attachments, reports.Attachment{
+			Filename:    filepath.Base(artifact.Path),
+			ContentType: "text/html; charset=utf-8",
+			Data:        data,
+		})
+		fmt.Fprintf(&body, "- %s (%s)\n", artifact.Template, artifact.Database)
+	}
+
+	config := reports.MailConfig{
+		Host:     s.currentConfig().SMTPHost,
+		Port:     s.currentConfig().SMTPPort,
+		Username: s.currentConfig().SMTPUsername,
+		Password: s.currentConfig().SMTPPassword,
+		From:     s.currentConfig().SMTPFrom,
+	}
+	subject := i18n.T(lang, "report.email.subject", schedule.Name)
+	return reports.SendReportEmail(config, schedule.Recipients, subject, body.String(), attachments)
+}
+
+// scheduleLanguage возвращает язык отчетов расписания: язык клиента проекта или язык по умолчанию
+func (s *Server) scheduleLanguage(schedule *database.ReportSchedule) string {
+	if project, err := s.serviceDB.GetClientProject(schedule.ProjectID); err == nil && project != nil {
+		if lang := s.clientLanguage(project.ClientID); lang != "" {
+			return lang
+		}
+	}
+	return i18n.Default
+}
+
+// archiveReportArtifacts переносит сформированные отчеты в хранилище артефактов.
+// Если хранилище недоступно, отчет остается в локальном каталоге запуска.
+func (s *Server) archiveReportArtifacts(run *database.ReportRun) {
+	for i := range run.Artifacts {
+		artifact := &run.Artifacts[i]
+		if artifact.Error != "" || artifact.Path == "" {
+			continue
+		}
+		key := fmt.Sprintf("reports/run_%d/%s", run.ID, filepath.Base(artifact.Path))
+		if _, err := s.storeArtifactFile(artifact.Path, key, "text/html; charset=utf-8"); err != nil {
+			log.Printf("Warning: Failed to archive report %s: %v", artifact.Path, err)
+			continue
+		}
+		artifact.StorageKey = key
+	}
+}
+
+// readReportArtifact читает отчет из хранилища артефактов или из локального файла
+func (s *Server) readReportArtifact(artifact database.ReportArtifact) ([]byte, error) {
+	if artifact.StorageKey == "" {
+		return os.ReadFile(artifact.Path)
+	}
+	store := s.artifactStorage()
+	if store == nil {
+		return nil, fmt.Errorf("artifact storage is not available")
+	}
+	reader, err := store.Get(context.Background(), artifact.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// reportRunArtifactLinks возвращает отчеты запуска со ссылками для скачивания
+func (s *Server) reportRunArtifactLinks(run *database.ReportRun) []ReportRunArtifactLink {
+	links := make([]ReportRunArtifactLink, 0, len(run.Artifacts))
+	for i, artifact := range run.Artifacts {
+		link := ReportRunArtifactLink{Template: artifact.Template, Database: artifact.Database, Error: artifact.Error}
+		if artifact.Error == "" {
+			link.DownloadURL = fmt.Sprintf("/api/reports/runs/%d/artifacts/%d", run.ID, i)
+			link.PresignedURL = s.presignArtifact(artifact.StorageKey)
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// validateReportSchedule проверяет расписание и вычисляет следующий запуск
+func (s *Server) validateReportSchedule(schedule *database.ReportSchedule) error {
+	schedule.Name = strings.TrimSpace(schedule.Name)
+	if schedule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if schedule.ProjectID <= 0 {
+		return fmt.Errorf("project_id is required")
+	}
+	if len(schedule.Templates) == 0 {
+		return fmt.Errorf("at least one template is required")
+	}
+	for _, name := range schedule.Templates {
+		if _, err := s.reportEngine.Lookup(name); err != nil {
+			return fmt.Errorf("template %s: %v", name, err)
+		}
+	}
+	for _, recipient := range schedule.Recipients {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return fmt.Errorf("invalid recipient %s", recipient)
+		}
+	}
+	if schedule.WebhookURL != "" {
+		parsed, err := url.Parse(schedule.WebhookURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("webhook_url must be an http(s) URL")
+		}
+	}
+
+	nextRunAt, err := nextReportRun(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = nextRunAt
+	return nil
+}
+
+// handleReportSchedules список расписаний и создание нового расписания
+// GET/POST /api/reports/schedules
+func (s *Server) handleReportSchedules(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		projectID := 0
+		if projectIDStr := r.URL.Query().Get("project_id"); projectIDStr != "" {
+			var err error
+			if projectID, err = strconv.Atoi(projectIDStr); err != nil {
+				s.writeJSONError(w, "Invalid project_id", http.StatusBadRequest)
+				return
+			}
+		}
+		schedules, err := s.serviceDB.GetReportSchedules(projectID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"schedules": schedules,
+			"total":     len(schedules),
+		}, http.StatusOK)
+	case http.MethodPost:
+		schedule := database.ReportSchedule{Enabled: true}
+		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := s.validateReportSchedule(&schedule); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		created, err := s.serviceDB.CreateReportSchedule(&schedule)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, created, http.StatusCreated)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleReportScheduleRoutes работа с отдельным расписанием и ручной запуск
+// GET/PUT/DELETE /api/reports/schedules/{id}, POST /api/reports/schedules/{id}/run
+func (s *Server) handleReportScheduleRoutes(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/reports/schedules/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) > 2 || (len(parts) == 2 && parts[1] != "run") {
+		http.NotFound(w, r)
+		return
+	}
+
+	schedule, err := s.serviceDB.GetReportSchedule(id)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if schedule == nil {
+		s.writeJSONError(w, "Report schedule not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		run, err := s.executeReportSchedule(schedule)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, run, http.StatusOK)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSONResponse(w, schedule, http.StatusOK)
+	case http.MethodPut:
+		updated := *schedule
+		if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		updated.ID = schedule.ID
+		if err := s.validateReportSchedule(&updated); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.serviceDB.UpdateReportSchedule(&updated); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result, err := s.serviceDB.GetReportSchedule(id)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, result, http.StatusOK)
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteReportSchedule(id); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{"deleted": id}, http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleReportRuns история запусков отчетов по расписанию
+// GET /api/reports/runs?schedule_id=N&project_id=N&limit=N
+func (s *Server) handleReportRuns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter := database.ReportRunFilter{Limit: 50}
+	query := r.URL.Query()
+	for param, target := range map[string]*int{
+		"schedule_id": &filter.ScheduleID,
+		"project_id":  &filter.ProjectID,
+		"limit":       &filter.Limit,
+	} {
+		if value := query.Get(param); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				s.writeJSONError(w, fmt.Sprintf("Invalid %s", param), http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+	if filter.Limit > 500 {
+		filter.Limit = 500
+	}
+
+	runs, err := s.serviceDB.GetReportRuns(filter)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"runs":  runs,
+		"total": len(runs),
+	}, http.StatusOK)
+}
+
+// handleReportRunRoutes отдельный запуск и скачивание сформированного отчета
+// GET /api/reports/runs/{id}, GET /api/reports/runs/{id}/artifacts/{index}
+func (s *Server) handleReportRunRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/reports/runs/"), "/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil || (len(parts) != 1 && (len(parts) != 3 || parts[1] != "artifacts")) {
+		http.NotFound(w, r)
+		return
+	}
+
+	run, err := s.serviceDB.GetReportRun(id)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		s.writeJSONError(w, "Report run not found", http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 1 {
+		s.writeJSONResponse(w, map[string]interface{}{
+			"run":       run,
+			"artifacts": s.reportRunArtifactLinks(run),
+		}, http.StatusOK)
+		return
+	}
+
+	index, err := strconv.Atoi(parts[2])
+	if err != nil || index < 0 || index >= len(run.Artifacts) || run.Artifacts[index].Path == "" {
+		s.writeJSONError(w, "Report artifact not found", http.StatusNotFound)
+		return
+	}
+	artifact := run.Artifacts[index]
+	if artifact.StorageKey != "" {
+		if store := s.artifactStorage(); store != nil {
+			s.serveStorageObject(w, r, store, artifact.StorageKey)
+			return
+		}
+	}
+	if _, err := os.Stat(artifact.Path); err != nil {
+		s.writeJSONError(w, "Report file is no longer available", http.StatusGone)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filepath.Base(artifact.Path)))
+	http.ServeFile(w, r, artifact.Path)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/reports"
+	"httpserver/storage"
+)
+
+func TestReportSchedules(t *testing.T) {
+	dir := t.TempDir()
+	projectDBPath := filepath.Join(dir, "project.db")
+	projectDB, err := database.NewDB(projectDBPath)
+	if err != nil {
+		t.Fatalf("Failed to create project database: %v", err)
+	}
+	if _, err := projectDB.Exec(`
+		INSERT INTO normalized_data (code, source_name, normalized_name, category, kpved_code, kpved_confidence, merged_count)
+		VALUES ('1', 'Болт М8', 'болт м8', 'крепеж', '25.94.11', 0.95, 1)
+	`); err != nil {
+		t.Fatalf("Failed to seed normalized_data: %v", err)
+	}
+	projectDB.Close()
+
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	project, err := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	if err != nil {
+		t.Fatalf("CreateClientProject() error = %v", err)
+	}
+	if _, err := serviceDB.CreateProjectDatabase(project.ID, "Основная", projectDBPath, "", 0); err != nil {
+		t.Fatalf("CreateProjectDatabase() error = %v", err)
+	}
+
+	var payload ReportRunWebhookPayload
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	s := &Server{
+		serviceDB:    serviceDB,
+		config:       &Config{ReportArtifactsDir: filepath.Join(dir, "artifacts"), Storage: storage.Config{LocalDir: filepath.Join(dir, "storage")}},
+		logChan:      make(chan LogEntry, 10),
+		reportEngine: reports.NewEngine("", serviceDB),
+	}
+
+	invalid := []struct {
+		name string
+		body string
+	}{
+		{"bad cron", `{"name": "s", "project_id": 1, "cron": "* * *", "templates": ["quality"]}`},
+		{"unknown template", `{"name": "s", "project_id": 1, "cron": "@daily", "templates": ["missing"]}`},
+		{"bad recipient", `{"name": "s", "project_id": 1, "cron": "@daily", "templates": ["quality"], "recipients": ["not-an-email"]}`},
+		{"bad webhook", `{"name": "s", "project_id": 1, "cron": "@daily", "templates": ["quality"], "webhook_url": "ftp://host"}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleReportSchedules(rec, httptest.NewRequest(http.MethodPost, "/api/reports/schedules", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400, body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleReportSchedules(rec, httptest.NewRequest(http.MethodPost, "/api/reports/schedules", strings.NewReader(`{
+		"name": "Еженедельный",
+		"project_id": `+strconv.Itoa(project.ID)+`,
+		"cron": "0 9 * * 1",
+		"templates": ["quality", "normalization"],
+		"webhook_url": "`+webhook.URL+`"
+	}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var schedule database.ReportSchedule
+	json.Unmarshal(rec.Body.Bytes(), &schedule)
+	if !schedule.Enabled || schedule.NextRunAt == nil || schedule.NextRunAt.Weekday() != 1 {
+		t.Errorf("unexpected schedule: %+v", schedule)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleReportScheduleRoutes(rec, httptest.NewRequest(http.MethodPost, "/api/reports/schedules/"+strconv.Itoa(schedule.ID)+"/run", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("run status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var run database.ReportRun
+	json.Unmarshal(rec.Body.Bytes(), &run)
+	if run.Status != database.ReportRunStatusSuccess || len(run.Artifacts) != 2 || run.DeliveryStatus != "webhook: sent" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if payload.RunID != run.ID || len(payload.Artifacts) != 2 || payload.Artifacts[0].DownloadURL == "" || payload.Artifacts[0].PresignedURL == "" {
+		t.Errorf("unexpected webhook payload: %+v", payload)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleReportRuns(rec, httptest.NewRequest(http.MethodGet, "/api/reports/runs?schedule_id="+strconv.Itoa(schedule.ID), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"total":1`) {
+		t.Errorf("runs status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleReportRunRoutes(rec, httptest.NewRequest(http.MethodGet, payload.Artifacts[0].DownloadURL, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "ООО Ромашка") {
+		t.Errorf("artifact status = %d, body = %.200s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleStorageObject(rec, httptest.NewRequest(http.MethodGet, payload.Artifacts[0].PresignedURL, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "ООО Ромашка") {
+		t.Errorf("presigned artifact status = %d, body = %.200s", rec.Code, rec.Body.String())
+	}
+}
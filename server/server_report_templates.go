@@ -0,0 +1,294 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/reports"
+)
+
+// handleReportTemplates список шаблонов отчетов и сохранение нового шаблона в сервисной БД
+// GET/POST /api/reports/templates
+func (s *Server) handleReportTemplates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		templates, err := s.reportEngine.Templates()
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get report templates: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"templates": templates,
+			"total":     len(templates),
+		}, http.StatusOK)
+	case http.MethodPost:
+		if s.serviceDB == nil {
+			s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		var tpl database.ReportTemplate
+		if err := json.NewDecoder(r.Body).Decode(&tpl); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := validateReportTemplate(&tpl); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		existing, err := s.serviceDB.GetReportTemplate(tpl.Name)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			s.writeJSONError(w, fmt.Sprintf("Report template %s already exists", tpl.Name), http.StatusConflict)
+			return
+		}
+
+		created, err := s.serviceDB.CreateReportTemplate(&tpl)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Report template %s saved", created.Name),
+			Endpoint:  "/api/reports/templates",
+		})
+		s.writeJSONResponse(w, created, http.StatusCreated)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleReportTemplate работа с отдельным шаблоном; изменять и удалять можно только шаблоны из сервисной БД
+// GET/PUT/DELETE /api/reports/templates/{name}
+func (s *Server) handleReportTemplate(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/reports/templates/"), "/")
+	if err := reports.ValidateTemplateName(name); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		tpl, err := s.reportEngine.Lookup(name)
+		if err != nil {
+			s.writeReportTemplateError(w, err)
+			return
+		}
+		response := map[string]interface{}{
+			"template": tpl.TemplateInfo,
+			"branding": tpl.Branding,
+		}
+		if tpl.Source == reports.TemplateSourceDatabase {
+			stored, err := s.serviceDB.GetReportTemplate(name)
+			if err == nil && stored != nil {
+				response["content"] = stored.Content
+			}
+		}
+		s.writeJSONResponse(w, response, http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	existing, err := s.serviceDB.GetReportTemplate(name)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		s.writeJSONError(w, fmt.Sprintf("Report template %s is not stored in service database", name), http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := s.serviceDB.DeleteReportTemplate(name); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{"deleted": name}, http.StatusOK)
+		return
+	}
+
+	var tpl database.ReportTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tpl); err != nil {
+		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	tpl.Name = name
+	if err := validateReportTemplate(&tpl); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.serviceDB.UpdateReportTemplate(&tpl); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := s.serviceDB.GetReportTemplate(name)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, updated, http.StatusOK)
+}
+
+// validateReportTemplate проверяет имя и синтаксис сохраняемого шаблона
+func validateReportTemplate(tpl *database.ReportTemplate) error {
+	tpl.Name = strings.TrimSpace(tpl.Name)
+	if err := reports.ValidateTemplateName(tpl.Name); err != nil {
+		return err
+	}
+	if strings.TrimSpace(tpl.Content) == "" {
+		return fmt.Errorf("content is required")
+	}
+	if _, err := reports.ParseTemplate(tpl.Name, tpl.Content); err != nil {
+		return err
+	}
+	return nil
+}
+
+// writeReportTemplateError преобразует ошибку поиска шаблона в HTTP ответ
+func (s *Server) writeReportTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, reports.ErrTemplateNotFound):
+		s.writeJSONError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, reports.ErrInvalidTemplateName):
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+	default:
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleRenderReport строит отчет по зарегистрированному шаблону для базы проекта или выгрузки
+// GET /api/reports/render?template=name&database_id=N|upload_uuid=UUID&client_id=N
+func (s *Server) handleRenderReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	name := query.Get("template")
+	if name == "" {
+		name = "normalization"
+	}
+	tpl, err := s.reportEngine.Lookup(name)
+	if err != nil {
+		s.writeReportTemplateError(w, err)
+		return
+	}
+
+	var classifier *sql.DB
+	if s.serviceDB != nil {
+		classifier = s.serviceDB.GetDB()
+	}
+
+	ctx := &reports.ReportContext{Title: query.Get("title"), Lang: requestLanguage(r)}
+	switch {
+	case query.Get("database_id") != "":
+		databaseID, err := strconv.Atoi(query.Get("database_id"))
+		if err != nil || databaseID <= 0 {
+			s.writeJSONError(w, "Invalid database_id", http.StatusBadRequest)
+			return
+		}
+		if s.serviceDB == nil {
+			s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+			return
+		}
+		dbInfo, err := s.serviceDB.GetProjectDatabase(databaseID)
+		if err != nil {
+			s.writeAPIError(w, err.Error(), err)
+			return
+		}
+		if dbInfo == nil {
+			s.writeJSONError(w, "Database not found", http.StatusNotFound)
+			return
+		}
+		projectDB, err := database.NewDB(dbInfo.FilePath)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to open database: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer projectDB.Close()
+		ctx.Database = filepath.Base(dbInfo.FilePath)
+		ctx.Stats = reports.NewProvider(projectDB.GetDB(), classifier)
+	case query.Get("upload_uuid") != "":
+		uploadUUID := query.Get("upload_uuid")
+		uploadDB := s.db
+		upload, err := uploadDB.GetUploadByUUID(uploadUUID)
+		if err != nil {
+			if uploadDB, err = s.getUploadDatabase(uploadUUID); err == nil {
+				upload, err = uploadDB.GetUploadByUUID(uploadUUID)
+			}
+		}
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Upload %s not found", uploadUUID), http.StatusNotFound)
+			return
+		}
+		ctx.Upload = upload.UploadUUID
+		ctx.Stats = reports.NewProvider(uploadDB.GetDB(), classifier).ForUpload(upload.ID)
+		if upload.ClientID != nil && query.Get("client_id") == "" {
+			query.Set("client_id", strconv.Itoa(*upload.ClientID))
+		}
+	default:
+		s.dbMutex.RLock()
+		ctx.Database = filepath.Base(s.currentDBPath)
+		s.dbMutex.RUnlock()
+		ctx.Stats = reports.NewProvider(s.db.GetDB(), classifier)
+	}
+
+	// Брендирование клиента: явный client_id, клиент выгрузки или клиент, для которого оформлен шаблон
+	clientID := tpl.ClientID
+	if clientIDStr := query.Get("client_id"); clientIDStr != "" {
+		if clientID, err = strconv.Atoi(clientIDStr); err != nil {
+			s.writeJSONError(w, "Invalid client_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if clientID > 0 && s.serviceDB != nil {
+		if client, err := s.serviceDB.GetClient(clientID); err == nil && client != nil {
+			ctx.Branding.ClientName = client.Name
+		}
+	}
+	secondLanguage, err := s.reportSecondLanguage(r, clientID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx.Stats = ctx.Stats.WithSecondLanguage(secondLanguage)
+
+	var buf bytes.Buffer
+	if err := s.reportEngine.Render(&buf, tpl, ctx); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if query.Get("download") == "true" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s.html", tpl.Name, time.Now().Format("20060102_150405")))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
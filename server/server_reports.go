@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/reports"
+)
+
+// handleKpvedReport возвращает отчет о классификации, сгруппированный по разделам и классам КПВЭД
+// GET /api/reports/kpved?format=json|html|xlsx&examples=N
+func (s *Server) handleKpvedReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "html" && format != "xlsx" {
+		s.writeJSONError(w, "format must be json, html or xlsx", http.StatusBadRequest)
+		return
+	}
+
+	s.dbMutex.RLock()
+	options := reports.KpvedReportOptions{Database: filepath.Base(s.currentDBPath)}
+	s.dbMutex.RUnlock()
+	if examplesStr := r.URL.Query().Get("examples"); examplesStr != "" {
+		examples, err := strconv.Atoi(examplesStr)
+		if err != nil || examples < 0 || examples > 100 {
+			s.writeJSONError(w, "examples must be between 0 and 100", http.StatusBadRequest)
+			return
+		}
+		options.ExamplesPerClass = examples
+		if examples == 0 {
+			options.ExamplesPerClass = -1
+		}
+	}
+
+	clientID, _ := strconv.Atoi(r.URL.Query().Get("client_id"))
+	secondLanguage, err := s.reportSecondLanguage(r, clientID)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	options.SecondLanguage = secondLanguage
+
+	var classifier *sql.DB
+	if s.serviceDB != nil {
+		classifier = s.serviceDB.GetDB()
+	}
+	report, err := reports.BuildKpvedReport(s.db.GetDB(), classifier, options)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to build KPVED report: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("kpved_report_%s", time.Now().Format("20060102_150405"))
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		s.writeJSONResponse(w, report, http.StatusOK)
+		return
+	case "html":
+		if err := reports.RenderKpvedReportHTML(&buf, report, requestLanguage(r)); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	case "xlsx":
+		if err := reports.RenderKpvedReportXLSX(&buf, report, requestLanguage(r)); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to render XLSX: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// inspectionContentTypes типы содержимого форматов вывода инспекций
+var inspectionContentTypes = map[string]string{
+	reports.FormatText: "text/plain; charset=utf-8",
+	reports.FormatCSV:  "text/csv; charset=utf-8",
+	reports.FormatTSV:  "text/tab-separated-values; charset=utf-8",
+	reports.FormatJSON: "application/json",
+}
+
+// handleInspectionReport возвращает набор данных инспекции, общий с утилитами show_*
+// GET /api/reports/inspection/{dataset}?format=json|csv|tsv|text&limit=N&upload_id=N
+func (s *Server) handleInspectionReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dataset := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/reports/inspection"), "/")
+	if !reports.IsInspectionDataset(dataset) {
+		s.writeJSONError(w, fmt.Sprintf("dataset must be one of: %s", strings.Join(reports.InspectionDatasets, ", ")), http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	format := reports.FormatJSON
+	if query.Get("format") != "" {
+		var err error
+		if format, err = reports.ParseOutputFormat(query.Get("format")); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		value, err := strconv.Atoi(limitStr)
+		if err != nil || value <= 0 || value > 10000 {
+			s.writeJSONError(w, "limit must be between 1 and 10000", http.StatusBadRequest)
+			return
+		}
+		limit = value
+	}
+
+	provider := reports.NewProvider(s.db.GetDB(), nil)
+	if uploadIDStr := query.Get("upload_id"); uploadIDStr != "" {
+		uploadID, err := strconv.Atoi(uploadIDStr)
+		if err != nil || uploadID <= 0 {
+			s.writeJSONError(w, "Invalid upload_id", http.StatusBadRequest)
+			return
+		}
+		provider = provider.ForUpload(uploadID)
+	}
+	table, err := provider.InspectionTable(dataset, limit)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to build inspection: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := reports.WriteTable(&buf, format, table); err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", inspectionContentTypes[format])
+	if format == reports.FormatCSV || format == reports.FormatTSV {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s.%s", dataset, time.Now().Format("20060102_150405"), format))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
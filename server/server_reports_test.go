@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestInspectionReport(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`
+		INSERT INTO normalized_data (code, source_name, normalized_name, category, kpved_code, kpved_name, merged_count) VALUES
+			('1', 'Болт М8', 'болт м8', 'крепеж', '25.94.11', 'Болты', 1),
+			('2', 'Гайка М8', 'гайка м8', 'крепеж', '25.94.11', 'Болты', 1)
+	`); err != nil {
+		t.Fatalf("Failed to seed normalized_data: %v", err)
+	}
+	s := &Server{db: db, logChan: make(chan LogEntry, 10)}
+
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{"csv", "/api/reports/inspection/kpved-codes?format=csv", http.StatusOK, "text/csv", "kpved_code,kpved_name,count\n25.94.11,Болты,2\n"},
+		{"tsv", "/api/reports/inspection/categories?format=tsv", http.StatusOK, "text/tab-separated-values", "category\tcount\tpercent\nкрепеж\t2\t100\n"},
+		{"json by default", "/api/reports/inspection/normalized?limit=1", http.StatusOK, "application/json", `"source_name": "Болт М8"`},
+		{"unknown dataset", "/api/reports/inspection/unknown", http.StatusNotFound, "", ""},
+		{"unknown format", "/api/reports/inspection/summary?format=xml", http.StatusBadRequest, "", ""},
+		{"invalid limit", "/api/reports/inspection/normalized?limit=0", http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleInspectionReport(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantStatus || !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.wantContentType) ||
+				!strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("status = %d, content type = %s, body = %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+// ReviewTaskRequest запрос на создание задачи проверки
+type ReviewTaskRequest struct {
+	TargetType string `json:"target_type"`
+	TargetKey  string `json:"target_key"`
+	Title      string `json:"title"`
+	Assignee   string `json:"assignee"`
+	Note       string `json:"note"`
+	CreatedBy  string `json:"created_by"`
+}
+
+// ReviewTaskUpdateRequest запрос на изменение задачи проверки; отсутствующие поля не меняются
+type ReviewTaskUpdateRequest struct {
+	Assignee *string `json:"assignee"`
+	State    *string `json:"state"`
+	Note     *string `json:"note"`
+}
+
+// reviewTaskFilter разбирает фильтр задач проверки из параметров запроса; state - список через запятую
+func reviewTaskFilter(r *http.Request) (database.ReviewTaskFilter, error) {
+	query := r.URL.Query()
+	filter := database.ReviewTaskFilter{
+		Assignee:   strings.TrimSpace(query.Get("assignee")),
+		TargetType: query.Get("target_type"),
+	}
+	if filter.TargetType != "" && filter.TargetType != database.RecordTargetClassification && !database.IsValidRecordTarget(filter.TargetType) {
+		return filter, fmt.Errorf("target_type must be %s, %s or %s",
+			database.RecordTargetItem, database.RecordTargetGroup, database.RecordTargetClassification)
+	}
+	if raw := query.Get("state"); raw != "" {
+		for _, state := range strings.Split(raw, ",") {
+			state = strings.TrimSpace(state)
+			if !database.IsValidReviewState(state) {
+				return filter, fmt.Errorf("invalid review state %q", state)
+			}
+			filter.States = append(filter.States, state)
+		}
+	}
+	return filter, nil
+}
+
+// handleProjectReviewTasks управляет задачами проверки записей, групп дубликатов и классификаций проекта
+// GET/POST /api/clients/{id}/projects/{projectId}/review-tasks[?assignee=&state=&target_type=]
+// GET /api/clients/{id}/projects/{projectId}/review-tasks/stats
+// GET/PATCH/DELETE /api/clients/{id}/projects/{projectId}/review-tasks/{taskId}
+func (s *Server) handleProjectReviewTasks(w http.ResponseWriter, r *http.Request, clientID, projectID int, action string) {
+	if !s.checkClientProject(w, clientID, projectID) {
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		filter, err := reviewTaskFilter(r)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.ProjectID = projectID
+		tasks, err := s.serviceDB.GetReviewTasks(filter)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"project_id": projectID,
+			"tasks":      tasks,
+			"total":      len(tasks),
+		}, http.StatusOK)
+		return
+	case action == "" && r.Method == http.MethodPost:
+		var req ReviewTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		task := &database.ReviewTask{
+			ProjectID:  projectID,
+			TargetType: req.TargetType,
+			TargetKey:  req.TargetKey,
+			Title:      req.Title,
+			Assignee:   req.Assignee,
+			Note:       req.Note,
+			CreatedBy:  requestActor(r, req.CreatedBy, ""),
+		}
+		if err := s.serviceDB.CreateReviewTask(task); err != nil {
+			if strings.Contains(err.Error(), "UNIQUE") {
+				s.writeJSONError(w, "Review task for this record already exists", http.StatusConflict)
+				return
+			}
+			s.writeRecordAnnotationError(w, err)
+			return
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message: fmt.Sprintf("Задача проверки проекта %d: создана для %s %s, исполнитель %q",
+				projectID, task.TargetType, task.TargetKey, task.Assignee),
+			Endpoint: r.URL.Path,
+		})
+		s.writeJSONResponse(w, task, http.StatusCreated)
+		return
+	case action == "stats" && r.Method == http.MethodGet:
+		stats, err := s.serviceDB.GetReviewUserStats(projectID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"project_id": projectID,
+			"users":      stats,
+		}, http.StatusOK)
+		return
+	case action == "" || action == "stats":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	taskID, err := strconv.Atoi(action)
+	if err != nil {
+		s.writeJSONError(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+	task, err := s.serviceDB.GetReviewTask(projectID, taskID)
+	if err != nil {
+		s.writeRecordAnnotationError(w, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSONResponse(w, task, http.StatusOK)
+	case http.MethodPatch:
+		var req ReviewTaskUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		previousState := task.State
+		if req.Assignee != nil {
+			task.Assignee = *req.Assignee
+		}
+		if req.State != nil {
+			task.State = *req.State
+		}
+		if req.Note != nil {
+			task.Note = *req.Note
+		}
+		if err := s.serviceDB.UpdateReviewTask(task); err != nil {
+			s.writeRecordAnnotationError(w, err)
+			return
+		}
+		if task.State != previousState {
+			s.log(LogEntry{
+				Timestamp: time.Now(),
+				Level:     "INFO",
+				Message: fmt.Sprintf("Задача проверки %d проекта %d: %s -> %s (%s)",
+					task.ID, projectID, previousState, task.State, requestActor(r, "", "unknown")),
+				Endpoint: r.URL.Path,
+			})
+		}
+		s.writeJSONResponse(w, task, http.StatusOK)
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteReviewTask(projectID, taskID); err != nil {
+			s.writeRecordAnnotationError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMyReviewTasks возвращает очередь задач текущего пользователя во всех проектах.
+// Пользователь берется из сессии, без сессии - из параметра assignee. По умолчанию только незавершенные задачи
+// GET /api/review-tasks/my[?assignee=&project_id=&state=&target_type=]
+func (s *Server) handleMyReviewTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	filter, err := reviewTaskFilter(r)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Assignee = requestActor(r, filter.Assignee, "")
+	if filter.Assignee == "" {
+		s.writeJSONError(w, "assignee is required without a user session", http.StatusBadRequest)
+		return
+	}
+	if raw := r.URL.Query().Get("project_id"); raw != "" {
+		if filter.ProjectID, err = strconv.Atoi(raw); err != nil || filter.ProjectID <= 0 {
+			s.writeJSONError(w, "Invalid project_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if len(filter.States) == 0 {
+		filter.States = []string{database.ReviewStateNew, database.ReviewStateInProgress, database.ReviewStateEscalated}
+	}
+
+	tasks, err := s.serviceDB.GetReviewTasks(filter)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"assignee": filter.Assignee,
+		"tasks":    tasks,
+		"total":    len(tasks),
+	}, http.StatusOK)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestProjectReviewTasksQueue(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	project, err := serviceDB.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("CreateClientProject() error = %v", err)
+	}
+	s := &Server{serviceDB: serviceDB, logChan: make(chan LogEntry, 10)}
+
+	tasks := func(method, action, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/clients/review-tasks", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleProjectReviewTasks(rec, req, client.ID, project.ID, action)
+		return rec
+	}
+
+	statuses := []struct {
+		name       string
+		method     string
+		action     string
+		body       string
+		wantStatus int
+	}{
+		{"assign item", http.MethodPost, "", `{"target_type": "item", "target_key": "4", "assignee": "anna"}`, http.StatusCreated},
+		{"assign classification", http.MethodPost, "", `{"target_type": "classification", "target_key": "4", "assignee": "anna"}`, http.StatusCreated},
+		{"duplicate task", http.MethodPost, "", `{"target_type": "item", "target_key": "4", "assignee": "oleg"}`, http.StatusConflict},
+		{"unknown target", http.MethodPost, "", `{"target_type": "upload", "target_key": "4"}`, http.StatusBadRequest},
+		{"unknown task", http.MethodGet, "999", "", http.StatusNotFound},
+		{"list tasks", http.MethodGet, "", "", http.StatusOK},
+	}
+	for _, tt := range statuses {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := tasks(tt.method, tt.action, tt.body); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	created, err := serviceDB.GetReviewTasks(database.ReviewTaskFilter{ProjectID: project.ID, TargetType: database.RecordTargetItem})
+	if err != nil || len(created) != 1 {
+		t.Fatalf("GetReviewTasks() = %v, %v", created, err)
+	}
+	taskID := fmt.Sprint(created[0].ID)
+	if rec := tasks(http.MethodPatch, taskID, `{"state": "done"}`); rec.Code != http.StatusOK {
+		t.Fatalf("finish task: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := tasks(http.MethodPatch, taskID, `{"state": "escalated"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("escalate done task: status = %d, want 400", rec.Code)
+	}
+
+	queue := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/review-tasks/my?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleMyReviewTasks(rec, req)
+		return rec
+	}
+	if rec := queue(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("queue without assignee: status = %d, want 400", rec.Code)
+	}
+	var result struct {
+		Tasks []database.ReviewTask `json:"tasks"`
+		Total int                   `json:"total"`
+	}
+	rec := queue("assignee=anna")
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response: %v, body = %s", err, rec.Body.String())
+	}
+	if result.Total != 1 || result.Tasks[0].TargetType != database.RecordTargetClassification {
+		t.Errorf("open queue of anna = %+v", result)
+	}
+
+	rec = tasks(http.MethodGet, "stats", "")
+	var stats struct {
+		Users []database.ReviewUserStats `json:"users"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil || len(stats.Users) != 1 || stats.Users[0].Done != 1 || stats.Users[0].Total != 2 {
+		t.Errorf("review stats = %s", rec.Body.String())
+	}
+}
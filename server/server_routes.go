@@ -0,0 +1,151 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RouteSmokeCheck подсказка для smoke-проверки маршрута (используется http_checker)
+type RouteSmokeCheck struct {
+	Method         string `json:"method"`
+	ExpectedStatus int    `json:"expected_status"`
+	Category       string `json:"category"`
+}
+
+// RouteInfo описание зарегистрированного маршрута
+type RouteInfo struct {
+	Path  string           `json:"path"`
+	Group string           `json:"group"`
+	Smoke *RouteSmokeCheck `json:"smoke,omitempty"`
+}
+
+// smokeCheckRoutes маршруты, которые безопасно вызывать без параметров после деплоя.
+// Сюда попадают только идемпотентные GET эндпоинты без побочных эффектов и без SSE.
+var smokeCheckRoutes = map[string]RouteSmokeCheck{
+	"/health":                        {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "critical"},
+	"/api/v1/health":                 {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "critical"},
+	"/stats":                         {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "critical"},
+	"/api/uploads":                   {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/normalized/uploads":        {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/normalization/status":      {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/normalization/config":      {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/quality/stats":             {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/kpved/stats":               {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/kpved/workers/status":      {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/clients":                   {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/databases/list":            {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/database/info":             {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/workers/config":            {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/workers/providers":         {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/monitoring/metrics":        {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "monitoring"},
+	"/api/monitoring/cache":          {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "monitoring"},
+	"/api/monitoring/ai":             {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "monitoring"},
+	"/api/monitoring/ai/budget":      {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "monitoring"},
+	"/api/classification/strategies": {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/routes":                    {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+	"/api/config":                    {Method: http.MethodGet, ExpectedStatus: http.StatusOK, Category: "api"},
+}
+
+// routeRecorder обертка над http.ServeMux, запоминающая зарегистрированные маршруты
+type routeRecorder struct {
+	*http.ServeMux
+	mu       sync.RWMutex
+	patterns []string
+}
+
+func newRouteRecorder() *routeRecorder {
+	return &routeRecorder{ServeMux: http.NewServeMux()}
+}
+
+// Handle регистрирует обработчик и запоминает маршрут
+func (rr *routeRecorder) Handle(pattern string, handler http.Handler) {
+	rr.record(pattern)
+	rr.ServeMux.Handle(pattern, handler)
+}
+
+// HandleFunc регистрирует функцию-обработчик и запоминает маршрут
+func (rr *routeRecorder) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	rr.record(pattern)
+	rr.ServeMux.HandleFunc(pattern, handler)
+}
+
+func (rr *routeRecorder) record(pattern string) {
+	rr.mu.Lock()
+	rr.patterns = append(rr.patterns, pattern)
+	rr.mu.Unlock()
+}
+
+// Routes возвращает описание всех зарегистрированных маршрутов, отсортированных по пути
+func (rr *routeRecorder) Routes() []RouteInfo {
+	rr.mu.RLock()
+	patterns := make([]string, len(rr.patterns))
+	copy(patterns, rr.patterns)
+	rr.mu.RUnlock()
+
+	sort.Strings(patterns)
+
+	routes := make([]RouteInfo, 0, len(patterns))
+	for _, pattern := range patterns {
+		info := RouteInfo{
+			Path:  pattern,
+			Group: routeGroup(pattern),
+		}
+		if smoke, ok := smokeCheckRoutes[pattern]; ok {
+			check := smoke
+			info.Smoke = &check
+		}
+		routes = append(routes, info)
+	}
+	return routes
+}
+
+// routeGroup определяет группу маршрута по первым сегментам пути
+// Например: /api/kpved/stats -> kpved, /handshake -> 1c
+func routeGroup(pattern string) string {
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	if len(parts) == 0 || parts[0] == "" {
+		return "static"
+	}
+	switch parts[0] {
+	case "api":
+		if len(parts) > 2 && parts[1] == "v1" {
+			return "v1/" + parts[2]
+		}
+		if len(parts) > 1 {
+			return parts[1]
+		}
+		return "api"
+	case "static":
+		return "static"
+	default:
+		return "1c"
+	}
+}
+
+// handleRoutes возвращает список зарегистрированных маршрутов с подсказками для smoke-проверок
+func (s *Server) handleRoutes(w http.ResponseWriter, r *http.Request, recorder *routeRecorder) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	routes := recorder.Routes()
+
+	// Фильтр только маршрутов, пригодных для smoke-проверки
+	if r.URL.Query().Get("smoke") == "true" {
+		filtered := make([]RouteInfo, 0, len(smokeCheckRoutes))
+		for _, route := range routes {
+			if route.Smoke != nil {
+				filtered = append(filtered, route)
+			}
+		}
+		routes = filtered
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"routes": routes,
+		"total":  len(routes),
+	}, http.StatusOK)
+}
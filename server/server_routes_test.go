@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRouteGroup(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"/handshake", "1c"},
+		{"/catalog/items", "1c"},
+		{"/api/kpved/stats", "kpved"},
+		{"/api/v1/upload/handshake", "v1/upload"},
+		{"/static/", "static"},
+		{"/", "static"},
+	}
+
+	for _, tc := range tests {
+		if got := routeGroup(tc.pattern); got != tc.want {
+			t.Errorf("routeGroup(%q) = %q, want %q", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestRouteRecorderRoutes(t *testing.T) {
+	recorder := newRouteRecorder()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	recorder.HandleFunc("/health", noop)
+	recorder.HandleFunc("/api/kpved/reclassify", noop)
+	recorder.Handle("/static/", http.HandlerFunc(noop))
+
+	routes := recorder.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("expected 3 routes, got %d", len(routes))
+	}
+	if routes[0].Path != "/api/kpved/reclassify" || routes[0].Smoke != nil {
+		t.Fatalf("unexpected first route: %+v", routes[0])
+	}
+	if routes[1].Path != "/health" || routes[1].Smoke == nil || routes[1].Smoke.Category != "critical" {
+		t.Fatalf("expected /health with critical smoke check, got %+v", routes[1])
+	}
+}
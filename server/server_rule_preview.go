@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"httpserver/normalization"
+)
+
+// Размер страницы предварительного просмотра правила
+const (
+	defaultRulePreviewLimit = 50
+	maxRulePreviewLimit     = 500
+)
+
+// rulePreviewRequest запрос предварительного просмотра правила нормализации
+type rulePreviewRequest struct {
+	Rule     normalization.CandidateRule `json:"rule"`
+	Category string                      `json:"category"`
+	Offset   int                         `json:"offset"`
+	Limit    int                         `json:"limit"`
+}
+
+// handleRulePreview применяет правило-кандидат ко всем группам наименований без изменения данных:
+// сколько записей изменится, примеры до/после и наименования, которые станут одинаковыми.
+// Образцы и коллизии постраничные: offset и limit применяются к обоим спискам
+// POST /api/normalization/rules/preview {"rule": {"type": "regex", "pattern": "\\s+шт\\.?$", "replacement": ""}, "category": "...", "offset": 0, "limit": 50}
+func (s *Server) handleRulePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rulePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	apply, err := req.Rule.Compile()
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultRulePreviewLimit
+	}
+	if req.Limit > maxRulePreviewLimit {
+		req.Limit = maxRulePreviewLimit
+	}
+
+	groups, err := s.db.GetNormalizedNameGroups(req.Category)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	preview := normalization.PreviewRule(groups, apply)
+	samplesFrom, samplesTo := rulePreviewPage(len(preview.Samples), req.Offset, req.Limit)
+	collisionsFrom, collisionsTo := rulePreviewPage(len(preview.Collisions), req.Offset, req.Limit)
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"rule":             req.Rule,
+		"category":         req.Category,
+		"total_groups":     preview.TotalGroups,
+		"total_items":      preview.TotalItems,
+		"matched_groups":   preview.MatchedGroups,
+		"matched_items":    preview.MatchedItems,
+		"samples":          preview.Samples[samplesFrom:samplesTo],
+		"samples_total":    len(preview.Samples),
+		"collisions":       preview.Collisions[collisionsFrom:collisionsTo],
+		"collisions_total": len(preview.Collisions),
+		"offset":           req.Offset,
+		"limit":            req.Limit,
+	}, http.StatusOK)
+}
+
+// rulePreviewPage границы страницы списка длины total
+func rulePreviewPage(total, offset, limit int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestHandleRulePreview(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewDBWithConfig() error = %v", err)
+	}
+	defer db.Close()
+	for i, name := range []string{"болт м10", "болт  м10 шт", "гайка м10 шт", "гайка м10 шт", "шайба"} {
+		if _, err := db.GetDB().Exec(`INSERT INTO normalized_data (source_reference, source_name, code, normalized_name, normalized_reference, category)
+			VALUES ('', ?, ?, ?, '', 'крепеж')`, name, fmt.Sprintf("%05d", i), name); err != nil {
+			t.Fatalf("insert error = %v", err)
+		}
+	}
+	s := &Server{logChan: make(chan LogEntry, 100), db: db, config: &Config{}}
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"invalid body", http.MethodPost, "{", http.StatusBadRequest},
+		{"invalid regex", http.MethodPost, `{"rule": {"type": "regex", "pattern": "("}}`, http.StatusBadRequest},
+		{"preview", http.MethodPost, `{"rule": {"type": "stop_word", "term": "шт"}, "limit": 1}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleRulePreview(rec, httptest.NewRequest(tt.method, "/api/normalization/rules/preview", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				MatchedGroups   int               `json:"matched_groups"`
+				MatchedItems    int               `json:"matched_items"`
+				Samples         []json.RawMessage `json:"samples"`
+				SamplesTotal    int               `json:"samples_total"`
+				CollisionsTotal int               `json:"collisions_total"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode error = %v", err)
+			}
+			if resp.MatchedGroups != 2 || resp.MatchedItems != 3 {
+				t.Errorf("matched = %d/%d, want 2/3", resp.MatchedGroups, resp.MatchedItems)
+			}
+			if len(resp.Samples) != 1 || resp.SamplesTotal != 2 || resp.CollisionsTotal != 1 {
+				t.Errorf("samples = %d of %d, collisions = %d", len(resp.Samples), resp.SamplesTotal, resp.CollisionsTotal)
+			}
+		})
+	}
+
+	var count int
+	if err := db.GetDB().QueryRow(`SELECT COUNT(*) FROM normalized_data WHERE normalized_name LIKE '%шт'`).Scan(&count); err != nil || count != 3 {
+		t.Errorf("preview modified data: count = %d, err = %v", count, err)
+	}
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+// sandboxUploadsJanitorInterval периодичность удаления просроченных тестовых выгрузок
+const sandboxUploadsJanitorInterval = time.Minute
+
+// sandboxUpload тестовая выгрузка во временной БД в памяти
+type sandboxUpload struct {
+	db        *database.DB
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// SandboxUploadInfo состояние тестовой выгрузки
+type SandboxUploadInfo struct {
+	UploadUUID    string                             `json:"upload_uuid"`
+	Status        string                             `json:"status"`
+	ConfigName    string                             `json:"config_name"`
+	CreatedAt     time.Time                          `json:"created_at"`
+	ExpiresAt     time.Time                          `json:"expires_at"`
+	Counts        database.UploadCounters            `json:"counts"`
+	Discrepancies []database.CompletenessDiscrepancy `json:"discrepancies,omitempty"`
+}
+
+// sandboxUploadRegistry тестовые выгрузки (ключ - upload_uuid). У каждой выгрузки своя единая БД в памяти
+// с той же схемой, что и рабочая, которая закрывается по истечении срока жизни. Нулевое значение готово к использованию
+type sandboxUploadRegistry struct {
+	mu      sync.Mutex
+	uploads map[string]*sandboxUpload
+}
+
+// create открывает временную БД для выгрузки; maxUploads ограничивает число одновременных выгрузок (0 - без ограничения)
+func (r *sandboxUploadRegistry) create(uploadUUID string, ttl time.Duration, maxUploads int) (*sandboxUpload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.uploads == nil {
+		r.uploads = make(map[string]*sandboxUpload)
+	}
+	if _, exists := r.uploads[uploadUUID]; exists {
+		return nil, apperrors.Conflict("sandbox_exists", fmt.Sprintf("sandbox upload %s already exists", uploadUUID))
+	}
+	if maxUploads > 0 && len(r.uploads) >= maxUploads {
+		return nil, apperrors.Unavailable("sandbox_limit", fmt.Sprintf("sandbox upload limit reached (%d)", maxUploads))
+	}
+
+	db, err := database.NewUnifiedDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox database: %w", err)
+	}
+	now := time.Now()
+	sandbox := &sandboxUpload{db: db, createdAt: now, expiresAt: now.Add(ttl)}
+	r.uploads[uploadUUID] = sandbox
+	return sandbox, nil
+}
+
+// get возвращает непросроченную тестовую выгрузку
+func (r *sandboxUploadRegistry) get(uploadUUID string) (*sandboxUpload, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sandbox, ok := r.uploads[uploadUUID]
+	if !ok || !time.Now().Before(sandbox.expiresAt) {
+		return nil, false
+	}
+	return sandbox, true
+}
+
+// remove удаляет тестовую выгрузку и закрывает ее БД
+func (r *sandboxUploadRegistry) remove(uploadUUID string) bool {
+	r.mu.Lock()
+	sandbox, ok := r.uploads[uploadUUID]
+	delete(r.uploads, uploadUUID)
+	r.mu.Unlock()
+	if ok {
+		sandbox.db.Close()
+	}
+	return ok
+}
+
+// expire удаляет выгрузки, срок жизни которых истек к моменту now; возвращает число удаленных
+func (r *sandboxUploadRegistry) expire(now time.Time) int {
+	r.mu.Lock()
+	var expired []*sandboxUpload
+	for uploadUUID, sandbox := range r.uploads {
+		if !now.Before(sandbox.expiresAt) {
+			expired = append(expired, sandbox)
+			delete(r.uploads, uploadUUID)
+		}
+	}
+	r.mu.Unlock()
+	for _, sandbox := range expired {
+		sandbox.db.Close()
+	}
+	return len(expired)
+}
+
+// uuids возвращает UUID действующих тестовых выгрузок по порядку создания
+func (r *sandboxUploadRegistry) uuids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	result := make([]string, 0, len(r.uploads))
+	for uploadUUID, sandbox := range r.uploads {
+		if now.Before(sandbox.expiresAt) {
+			result = append(result, uploadUUID)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := r.uploads[result[i]], r.uploads[result[j]]
+		if !a.createdAt.Equal(b.createdAt) {
+			return a.createdAt.Before(b.createdAt)
+		}
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// closeAll закрывает БД всех тестовых выгрузок при остановке сервера
+func (r *sandboxUploadRegistry) closeAll() {
+	r.mu.Lock()
+	uploads := r.uploads
+	r.uploads = nil
+	r.mu.Unlock()
+	for _, sandbox := range uploads {
+		sandbox.db.Close()
+	}
+}
+
+// info собирает состояние тестовой выгрузки: принятые количества и расхождения сверки полноты
+func (sandbox *sandboxUpload) info(uploadUUID string) (*SandboxUploadInfo, error) {
+	upload, err := sandbox.db.GetUploadByUUID(uploadUUID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := sandbox.db.ComputeUploadCounters(upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	discrepancies, err := sandbox.db.CheckUploadCompleteness(upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SandboxUploadInfo{
+		UploadUUID:    uploadUUID,
+		Status:        upload.Status,
+		ConfigName:    upload.ConfigName,
+		CreatedAt:     sandbox.createdAt,
+		ExpiresAt:     sandbox.expiresAt,
+		Counts:        *counts,
+		Discrepancies: discrepancies,
+	}, nil
+}
+
+// sandboxUploadTTL время жизни тестовой выгрузки
+func (s *Server) sandboxUploadTTL() time.Duration {
+	if s.currentConfig() == nil || s.currentConfig().SandboxUploadTTL <= 0 {
+		return time.Hour
+	}
+	return s.currentConfig().SandboxUploadTTL
+}
+
+// createSandboxUpload создает тестовую выгрузку во временной БД. Базы данных проекта не определяются
+// и индекс выгрузок service.db не пополняется, чтобы тестовые данные не попали в рабочие отчеты
+func (s *Server) createSandboxUpload(uploadUUID string, req *HandshakeRequest, protocolVersion int) (*sandboxUpload, *database.Upload, error) {
+	maxUploads := 0
+	if s.currentConfig() != nil {
+		maxUploads = s.currentConfig().SandboxMaxUploads
+	}
+	sandbox, err := s.sandboxUploads.create(uploadUUID, s.sandboxUploadTTL(), maxUploads)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	iterationNumber := req.IterationNumber
+	if iterationNumber <= 0 {
+		iterationNumber = 1
+	}
+	upload, err := sandbox.db.CreateUploadWithDatabase(
+		uploadUUID, req.Version1C, req.ConfigName, nil,
+		req.ComputerName, req.UserName, req.ConfigVersion,
+		iterationNumber, req.IterationLabel, req.ProgrammerName, req.UploadPurpose, nil,
+	)
+	if err == nil {
+		err = sandbox.db.SetUploadProtocolVersion(upload.ID, protocolVersion)
+	}
+	if err != nil {
+		s.sandboxUploads.remove(uploadUUID)
+		return nil, nil, err
+	}
+	return sandbox, upload, nil
+}
+
+// runSandboxUploadsJanitor периодически удаляет тестовые выгрузки с истекшим сроком жизни
+func (s *Server) runSandboxUploadsJanitor() {
+	ticker := time.NewTicker(sandboxUploadsJanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if expired := s.sandboxUploads.expire(now); expired > 0 {
+				log.Printf("Удалено тестовых выгрузок по истечении срока: %d", expired)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// handleSandboxUploads список действующих тестовых выгрузок
+// GET /api/sandbox/uploads
+func (s *Server) handleSandboxUploads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	uploads := []*SandboxUploadInfo{}
+	for _, uploadUUID := range s.sandboxUploads.uuids() {
+		sandbox, ok := s.sandboxUploads.get(uploadUUID)
+		if !ok {
+			continue
+		}
+		info, err := sandbox.info(uploadUUID)
+		if err != nil {
+			// Выгрузка могла быть удалена, пока собирался список
+			continue
+		}
+		uploads = append(uploads, info)
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"uploads": uploads,
+		"total":   len(uploads),
+		"ttl":     s.sandboxUploadTTL().String(),
+	}, http.StatusOK)
+}
+
+// handleSandboxUploadRoutes состояние тестовой выгрузки или ее досрочное удаление
+// GET /api/sandbox/uploads/{uuid}
+// DELETE /api/sandbox/uploads/{uuid}
+func (s *Server) handleSandboxUploadRoutes(w http.ResponseWriter, r *http.Request) {
+	uploadUUID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/sandbox/uploads/"), "/")
+	if uploadUUID == "" || strings.Contains(uploadUUID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		sandbox, ok := s.sandboxUploads.get(uploadUUID)
+		if !ok {
+			s.writeJSONError(w, fmt.Sprintf("Sandbox upload %s not found", uploadUUID), http.StatusNotFound)
+			return
+		}
+		info, err := sandbox.info(uploadUUID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), apperrors.HTTPStatus(err))
+			return
+		}
+		s.writeJSONResponse(w, info, http.StatusOK)
+	case http.MethodDelete:
+		if !s.sandboxUploads.remove(uploadUUID) {
+			s.writeJSONError(w, fmt.Sprintf("Sandbox upload %s not found", uploadUUID), http.StatusNotFound)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"upload_uuid": uploadUUID,
+			"deleted":     true,
+		}, http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestSandboxUpload(t *testing.T) {
+	unifiedPath := filepath.Join(t.TempDir(), "unified.db")
+	db, err := database.NewUnifiedDBWithConfig(unifiedPath, database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	s := &Server{db: db, unifiedCatalogsDB: db, config: &Config{UnifiedCatalogsDBPath: unifiedPath, SandboxUploadTTL: time.Hour}, logChan: make(chan LogEntry, 100)}
+	defer s.sandboxUploads.closeAll()
+
+	post := func(handler http.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s status = %d, body = %s", path, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	rec := post(s.handleHandshake, "/handshake", "<handshake><version_1c>8.3</version_1c><config_name>УТ</config_name><protocol_version>3</protocol_version><sandbox>true</sandbox></handshake>")
+	var handshake HandshakeResponse
+	if err := xml.Unmarshal(rec.Body.Bytes(), &handshake); err != nil || !handshake.Success || !handshake.Sandbox || handshake.SandboxExpiresAt == "" {
+		t.Fatalf("handshake body = %s, want sandbox upload", rec.Body.String())
+	}
+	uploadUUID := handshake.UploadUUID
+
+	post(s.handleConstant, "/constant", "<constant><upload_uuid>"+uploadUUID+"</upload_uuid><name>Организация</name><type>Строка</type><value>ООО Ромашка</value></constant>")
+	post(s.handleCatalogItems, "/catalog/items", "<catalog_items><upload_uuid>"+uploadUUID+"</upload_uuid><catalog_name>Товары</catalog_name><items>"+
+		"<item><reference>ref-1</reference><code>1</code><name>Дрель</name></item>"+
+		"<item><reference>ref-2</reference><code>2</code><name>Шуруповерт</name></item></items></catalog_items>")
+
+	rec = post(s.handleComplete, "/complete", "<complete><upload_uuid>"+uploadUUID+"</upload_uuid></complete>")
+	var complete CompleteResponse
+	if err := xml.Unmarshal(rec.Body.Bytes(), &complete); err != nil || !complete.Success {
+		t.Fatalf("complete body = %s", rec.Body.String())
+	}
+	if complete.SandboxCounts == nil || *complete.SandboxCounts != (SandboxCounts{Constants: 1, Catalogs: 1, Items: 2}) {
+		t.Errorf("sandbox counts = %+v, want 1 constant, 1 catalog, 2 items", complete.SandboxCounts)
+	}
+
+	// Рабочая БД не содержит ни выгрузки, ни ее данных
+	if _, err := db.GetUploadByUUID(uploadUUID); err == nil {
+		t.Errorf("sandbox upload %s was written to the unified database", uploadUUID)
+	}
+	var uploads int
+	if err := db.QueryRow("SELECT COUNT(*) FROM uploads").Scan(&uploads); err != nil || uploads != 0 {
+		t.Errorf("unified database uploads = %d, err = %v, want 0", uploads, err)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleSandboxUploadRoutes(rec, httptest.NewRequest(http.MethodGet, "/api/sandbox/uploads/"+uploadUUID, nil))
+	var info SandboxUploadInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("sandbox info status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if info.Status != "completed" || info.Counts.Items != 2 {
+		t.Errorf("sandbox info = %+v, want completed upload with 2 items", info)
+	}
+
+	// По истечении срока жизни пакеты тестовой выгрузки отклоняются
+	if expired := s.sandboxUploads.expire(time.Now().Add(2 * time.Hour)); expired != 1 {
+		t.Fatalf("expire() = %d, want 1", expired)
+	}
+	rec = httptest.NewRecorder()
+	s.handleConstant(rec, httptest.NewRequest(http.MethodPost, "/constant", strings.NewReader(
+		"<constant><upload_uuid>"+uploadUUID+"</upload_uuid><name>Валюта</name><type>Строка</type><value>KZT</value></constant>")))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("constant after expiry status = %d, want 404", rec.Code)
+	}
+}
+
+func TestSandboxUploadRegistry(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxUploads int
+		uuids      []string
+		wantErrAt  int // -1 - без ошибок
+	}{
+		{"within limit", 2, []string{"a", "b"}, -1},
+		{"limit reached", 1, []string{"a", "b"}, 1},
+		{"duplicate uuid", 0, []string{"a", "a"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var registry sandboxUploadRegistry
+			defer registry.closeAll()
+			for i, uploadUUID := range tt.uuids {
+				_, err := registry.create(uploadUUID, time.Hour, tt.maxUploads)
+				if (err != nil) != (i == tt.wantErrAt) {
+					t.Fatalf("create(%q) error = %v, want error %v", uploadUUID, err, i == tt.wantErrAt)
+				}
+			}
+			if !registry.remove("a") || registry.remove("a") {
+				t.Errorf("remove() should delete the sandbox only once")
+			}
+		})
+	}
+}
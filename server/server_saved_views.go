@@ -0,0 +1,425 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+)
+
+const (
+	defaultSavedViewPageSize = 50
+	maxSavedViewPageSize     = 500
+)
+
+// NormalizedViewFilter фильтры сохраненного представления: область выборки как у
+// выборочной переклассификации плюс категория, решение политики порогов и статус проверки
+type NormalizedViewFilter struct {
+	ReclassifyScopeFilter
+	Category           string `json:"category,omitempty"`            // Точное совпадение категории
+	ConfidenceDecision string `json:"confidence_decision,omitempty"` // accept/review/reject
+	ValidationStatus   string `json:"validation_status,omitempty"`
+	Tag                string `json:"tag,omitempty"` // Тег проекта представления на записи или ее группе
+}
+
+// normalizedViewColumns колонки normalized_data, доступные для выбора и сортировки
+var normalizedViewColumns = map[string]string{
+	"id":                   "id",
+	"code":                 "code",
+	"source_name":          "source_name",
+	"source_reference":     "source_reference",
+	"normalized_name":      "normalized_name",
+	"normalized_reference": "normalized_reference",
+	"category":             "category",
+	"merged_count":         "merged_count",
+	"ai_confidence":        "ai_confidence",
+	"processing_level":     "processing_level",
+	"kpved_code":           "kpved_code",
+	"kpved_name":           "kpved_name",
+	"kpved_confidence":     "kpved_confidence",
+	"kpved_model":          "kpved_model",
+	"confidence_decision":  "confidence_decision",
+	"quality_score":        "quality_score",
+	"validation_status":    "validation_status",
+	"version":              "version",
+	"created_at":           "created_at",
+	"updated_at":           "updated_at",
+}
+
+// defaultNormalizedViewColumns колонки представления, если они не выбраны
+var defaultNormalizedViewColumns = []string{
+	"id", "code", "normalized_name", "category", "kpved_code", "kpved_name", "kpved_confidence", "merged_count", "created_at",
+}
+
+// decodeNormalizedViewFilter разбирает фильтры представления
+func decodeNormalizedViewFilter(raw json.RawMessage) (NormalizedViewFilter, error) {
+	var filter NormalizedViewFilter
+	if len(raw) == 0 || string(raw) == "null" {
+		return filter, nil
+	}
+	if err := json.Unmarshal(raw, &filter); err != nil {
+		return filter, fmt.Errorf("invalid filters: %w", err)
+	}
+	return filter, nil
+}
+
+// buildNormalizedViewWhere строит условие WHERE по фильтрам представления
+func buildNormalizedViewWhere(filter NormalizedViewFilter) (string, []interface{}, error) {
+	where, args, err := buildReclassifyScopeWhere(filter.ReclassifyScopeFilter)
+	if err != nil {
+		return "", nil, err
+	}
+
+	conditions := []string{where}
+	if category := strings.TrimSpace(filter.Category); category != "" {
+		conditions = append(conditions, "category = ?")
+		args = append(args, category)
+	}
+	switch filter.ConfidenceDecision {
+	case "":
+	case database.ConfidenceDecisionAccept, database.ConfidenceDecisionReview, database.ConfidenceDecisionReject:
+		conditions = append(conditions, "confidence_decision = ?")
+		args = append(args, filter.ConfidenceDecision)
+	default:
+		return "", nil, fmt.Errorf("invalid confidence_decision: %s", filter.ConfidenceDecision)
+	}
+	if status := strings.TrimSpace(filter.ValidationStatus); status != "" {
+		conditions = append(conditions, "validation_status = ?")
+		args = append(args, status)
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// buildNormalizedViewOrder строит ORDER BY по сортировке представления
+func buildNormalizedViewOrder(sort []database.SavedViewSort) (string, error) {
+	if len(sort) == 0 {
+		return "id", nil
+	}
+
+	parts := make([]string, 0, len(sort)+1)
+	for _, field := range sort {
+		column, ok := normalizedViewColumns[field.Field]
+		if !ok {
+			return "", fmt.Errorf("unknown sort field: %s", field.Field)
+		}
+		if field.Desc {
+			column += " DESC"
+		}
+		parts = append(parts, column)
+	}
+	// Стабильный порядок страниц при одинаковых значениях
+	parts = append(parts, "id")
+	return strings.Join(parts, ", "), nil
+}
+
+// normalizedViewSelectColumns возвращает выбранные колонки представления
+func normalizedViewSelectColumns(columns []string) ([]string, error) {
+	if len(columns) == 0 {
+		return defaultNormalizedViewColumns, nil
+	}
+	for _, column := range columns {
+		if _, ok := normalizedViewColumns[column]; !ok {
+			return nil, fmt.Errorf("unknown column: %s", column)
+		}
+	}
+	return columns, nil
+}
+
+// validateSavedView проверяет имя, фильтры, сортировку и колонки представления
+func validateSavedView(view *database.SavedView) error {
+	view.Name = strings.TrimSpace(view.Name)
+	view.Owner = strings.TrimSpace(view.Owner)
+	if view.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if view.ProjectID < 0 {
+		return fmt.Errorf("project_id must not be negative")
+	}
+
+	filter, err := decodeNormalizedViewFilter(view.Filters)
+	if err != nil {
+		return err
+	}
+	if _, _, err := buildNormalizedViewWhere(filter); err != nil {
+		return err
+	}
+	if strings.TrimSpace(filter.Tag) != "" && view.ProjectID == 0 {
+		return fmt.Errorf("tag filter requires project_id")
+	}
+	if _, err := buildNormalizedViewOrder(view.Sort); err != nil {
+		return err
+	}
+	if _, err := normalizedViewSelectColumns(view.Columns); err != nil {
+		return err
+	}
+	return nil
+}
+
+// queryNormalizedView выполняет представление на normalized_data и возвращает страницу записей
+// с выбранными колонками и общее число записей. tagged - условие фильтра по тегу (см. taggedRecordsCondition)
+func queryNormalizedView(db *sql.DB, view *database.SavedView, tagged string, taggedArgs []interface{}, limit, offset int) ([]map[string]interface{}, int, error) {
+	filter, err := decodeNormalizedViewFilter(view.Filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	where, args, err := buildNormalizedViewWhere(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if tagged != "" && tagged != "1=1" {
+		where += " AND " + tagged
+		args = append(args, taggedArgs...)
+	}
+	order, err := buildNormalizedViewOrder(view.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	columns, err := normalizedViewSelectColumns(view.Columns)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM normalized_data WHERE %s", where), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count view items: %w", err)
+	}
+
+	expressions := make([]string, len(columns))
+	for i, column := range columns {
+		expressions[i] = normalizedViewColumns[column]
+	}
+	query := fmt.Sprintf("SELECT %s FROM normalized_data WHERE %s ORDER BY %s LIMIT ? OFFSET ?",
+		strings.Join(expressions, ", "), where, order)
+	rows, err := db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query view items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan view item: %w", err)
+		}
+
+		item := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if data, ok := values[i].([]byte); ok {
+				item[column] = string(data)
+			} else {
+				item[column] = values[i]
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, total, rows.Err()
+}
+
+// handleNormalizedViews обрабатывает список и создание сохраненных представлений
+// GET /api/normalized/views?owner=&project_id=
+// POST /api/normalized/views
+func (s *Server) handleNormalizedViews(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		filter := database.SavedViewFilter{Owner: strings.TrimSpace(r.URL.Query().Get("owner"))}
+		if projectStr := r.URL.Query().Get("project_id"); projectStr != "" {
+			projectID, err := strconv.Atoi(projectStr)
+			if err != nil || projectID < 0 {
+				s.writeJSONError(w, "Invalid project_id", http.StatusBadRequest)
+				return
+			}
+			filter.ProjectID = projectID
+		}
+
+		views, err := s.serviceDB.GetSavedViews(filter)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get saved views: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"views": views,
+			"total": len(views),
+		}, http.StatusOK)
+
+	case http.MethodPost:
+		var view database.SavedView
+		if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := validateSavedView(&view); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		created, err := s.serviceDB.CreateSavedView(&view)
+		if err != nil {
+			s.writeSavedViewSaveError(w, err)
+			return
+		}
+
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Saved view created: %s (id=%d, owner=%s, project=%d)", created.Name, created.ID, created.Owner, created.ProjectID),
+			Endpoint:  "/api/normalized/views",
+		})
+		s.writeJSONResponse(w, created, http.StatusCreated)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleNormalizedViewRoutes обрабатывает операции с сохраненным представлением
+// GET/PUT/DELETE /api/normalized/views/{id}
+// GET /api/normalized/views/{id}/items?page=&limit=
+func (s *Server) handleNormalizedViewRoutes(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusInternalServerError)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/normalized/views/"), "/")
+	parts := strings.Split(path, "/")
+
+	viewID, err := strconv.Atoi(parts[0])
+	if err != nil || viewID <= 0 {
+		s.writeJSONError(w, "Invalid view ID", http.StatusBadRequest)
+		return
+	}
+	if len(parts) > 2 || (len(parts) == 2 && parts[1] != "items") {
+		http.NotFound(w, r)
+		return
+	}
+
+	view, err := s.serviceDB.GetSavedView(viewID)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get saved view: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if view == nil {
+		s.writeJSONError(w, fmt.Sprintf("Saved view %d not found", viewID), http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		s.handleNormalizedViewItems(w, r, view)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSONResponse(w, view, http.StatusOK)
+
+	case http.MethodPut:
+		var update database.SavedView
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		update.ID = view.ID
+		if err := validateSavedView(&update); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.serviceDB.UpdateSavedView(&update); err != nil {
+			s.writeSavedViewSaveError(w, err)
+			return
+		}
+
+		updated, err := s.serviceDB.GetSavedView(view.ID)
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to get saved view: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, updated, http.StatusOK)
+
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteSavedView(view.ID); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to delete saved view: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"message": "Saved view deleted",
+			"id":      view.ID,
+		}, http.StatusOK)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleNormalizedViewItems применяет представление на стороне сервера и возвращает страницу записей
+func (s *Server) handleNormalizedViewItems(w http.ResponseWriter, r *http.Request, view *database.SavedView) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page := 1
+	limit := defaultSavedViewPageSize
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+		if limit > maxSavedViewPageSize {
+			limit = maxSavedViewPageSize
+		}
+	}
+
+	filter, err := decodeNormalizedViewFilter(view.Filters)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to apply saved view: %v", err), http.StatusBadRequest)
+		return
+	}
+	tagged, taggedArgs, err := s.taggedRecordsCondition(view.ProjectID, filter.Tag)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to apply saved view: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	items, total, err := queryNormalizedView(s.db.GetDB(), view, tagged, taggedArgs, limit, (page-1)*limit)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to apply saved view: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	columns, _ := normalizedViewSelectColumns(view.Columns)
+	s.writeJSONResponse(w, map[string]interface{}{
+		"view":       view,
+		"columns":    columns,
+		"items":      items,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": (total + limit - 1) / limit,
+	}, http.StatusOK)
+}
+
+// writeSavedViewSaveError записывает ответ для ошибки сохранения представления
+func (s *Server) writeSavedViewSaveError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		s.writeJSONError(w, "Saved view with this name already exists for the owner and project", http.StatusConflict)
+		return
+	}
+	s.writeJSONError(w, fmt.Sprintf("Failed to save view: %v", err), http.StatusInternalServerError)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestNormalizedSavedViews(t *testing.T) {
+	db := newReclassifyTestDB(t)
+	defer db.Close()
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	s := &Server{db: db, serviceDB: serviceDB}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		if path == "/api/normalized/views" || strings.HasPrefix(path, "/api/normalized/views?") {
+			s.handleNormalizedViews(rec, req)
+		} else {
+			s.handleNormalizedViewRoutes(rec, req)
+		}
+		return rec
+	}
+
+	invalid := []struct {
+		name string
+		body string
+	}{
+		{"missing name", `{"owner": "ivanov"}`},
+		{"unknown column", `{"name": "v", "columns": ["password"]}`},
+		{"unknown sort field", `{"name": "v", "sort": [{"field": "kpved_code; DROP TABLE x"}]}`},
+		{"bad decision", `{"name": "v", "filters": {"confidence_decision": "maybe"}}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(http.MethodPost, "/api/normalized/views", tt.body); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400, body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	rec := do(http.MethodPost, "/api/normalized/views", `{
+		"name": "Низкая уверенность 25.x",
+		"owner": "ivanov",
+		"project_id": 3,
+		"filters": {"kpved_code_prefix": "25", "max_confidence": 0.5},
+		"sort": [{"field": "merged_count", "desc": true}],
+		"columns": ["id", "normalized_name", "kpved_confidence", "merged_count"]
+	}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var view database.SavedView
+	if err := json.Unmarshal(rec.Body.Bytes(), &view); err != nil {
+		t.Fatalf("Failed to decode view: %v", err)
+	}
+
+	if rec := do(http.MethodPost, "/api/normalized/views", `{"name": "Низкая уверенность 25.x", "owner": "ivanov", "project_id": 3}`); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate name status = %d, want 409", rec.Code)
+	}
+	if _, err := serviceDB.CreateSavedView(&database.SavedView{Name: "Чужое", Owner: "petrov", ProjectID: 3}); err != nil {
+		t.Fatalf("CreateSavedView() error = %v", err)
+	}
+
+	rec = do(http.MethodGet, "/api/normalized/views?owner=ivanov&project_id=3", "")
+	var list struct {
+		Views []database.SavedView `json:"views"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(list.Views) != 1 || list.Views[0].ID != view.ID {
+		t.Errorf("unexpected views for owner: %+v", list.Views)
+	}
+
+	rec = do(http.MethodGet, "/api/normalized/views/"+strconv.Itoa(view.ID)+"/items", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("items status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var result struct {
+		Items []map[string]interface{} `json:"items"`
+		Total int                      `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode items: %v", err)
+	}
+	if result.Total != 2 || len(result.Items) != 2 {
+		t.Fatalf("unexpected items: %+v", result)
+	}
+	if result.Items[0]["merged_count"] != float64(3) || len(result.Items[0]) != 4 {
+		t.Errorf("unexpected first item: %+v", result.Items[0])
+	}
+
+	if rec := do(http.MethodDelete, "/api/normalized/views/"+strconv.Itoa(view.ID), ""); rec.Code != http.StatusOK {
+		t.Errorf("delete status = %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/normalized/views/"+strconv.Itoa(view.ID), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want 404", rec.Code)
+	}
+}
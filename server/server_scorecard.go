@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"httpserver/database"
+	"httpserver/quality"
+)
+
+// handleDatabaseRoutes обрабатывает маршруты конкретной базы данных проекта
+// GET /api/databases/{id}/scorecard
+// GET /api/databases/{id}/scorecard/history
+// GET /api/databases/{id}/constants/{name}/history
+// GET /api/databases/{id}/constants/changes
+// GET/PUT/DELETE /api/databases/{id}/gates
+func (s *Server) handleDatabaseRoutes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/databases/"), "/"), "/")
+	if len(parts) < 2 || (parts[1] != "scorecard" && parts[1] != "constants" && parts[1] != "gates") {
+		s.writeJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	databaseID, err := strconv.Atoi(parts[0])
+	if err != nil || databaseID <= 0 {
+		s.writeJSONError(w, "Invalid database ID", http.StatusBadRequest)
+		return
+	}
+	if parts[1] == "gates" {
+		if len(parts) != 2 {
+			s.writeJSONError(w, "Not found", http.StatusNotFound)
+			return
+		}
+		s.handleDatabaseGates(w, r, databaseID)
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if parts[1] == "constants" {
+		s.handleDatabaseConstants(w, r, databaseID, parts[2:])
+		return
+	}
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch {
+	case len(parts) == 2:
+		s.handleDatabaseScorecard(w, r, databaseID)
+	case len(parts) == 3 && parts[2] == "history":
+		s.handleDatabaseScorecardHistory(w, r, databaseID)
+	default:
+		s.writeJSONError(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// handleDatabaseScorecard возвращает последнюю карту качества базы данных.
+// Карта рассчитывается заново, если ее еще нет или передан refresh=true
+func (s *Server) handleDatabaseScorecard(w http.ResponseWriter, r *http.Request, databaseID int) {
+	history, err := s.serviceDB.GetQualityScorecards(databaseID, 2)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get scorecards: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if len(history) == 0 || r.URL.Query().Get("refresh") == "true" {
+		scorecard, status, err := s.refreshDatabaseScorecard(databaseID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), status)
+			return
+		}
+		history = append([]*database.QualityScorecard{scorecard}, history...)
+	}
+
+	response := map[string]interface{}{"scorecard": history[0]}
+	if len(history) > 1 {
+		// Изменение итоговой оценки относительно предыдущего расчета
+		response["previous"] = history[1]
+		response["score_delta"] = history[0].Score - history[1].Score
+	}
+	s.writeJSONResponse(w, response, http.StatusOK)
+}
+
+// handleDatabaseScorecardHistory возвращает историю карт качества базы данных
+func (s *Server) handleDatabaseScorecardHistory(w http.ResponseWriter, r *http.Request, databaseID int) {
+	limit := 30
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			s.writeJSONError(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	history, err := s.serviceDB.GetQualityScorecards(databaseID, limit)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get scorecards: %v", err), http.StatusInternalServerError)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"database_id": databaseID,
+		"history":     history,
+		"total":       len(history),
+	}, http.StatusOK)
+}
+
+// refreshDatabaseScorecard рассчитывает карту качества по файлу базы данных и сохраняет ее в историю
+func (s *Server) refreshDatabaseScorecard(databaseID int) (*database.QualityScorecard, int, error) {
+	dbInfo, err := s.serviceDB.GetProjectDatabase(databaseID)
+	if err != nil || dbInfo == nil {
+		return nil, http.StatusNotFound, fmt.Errorf("database %d not found", databaseID)
+	}
+
+	projectDB, err := database.NewDB(dbInfo.FilePath)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to open database %s: %w", dbInfo.Name, err)
+	}
+	defer projectDB.Close()
+
+	scorecard, err := quality.BuildScorecard(projectDB, databaseID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to build scorecard: %w", err)
+	}
+	if err := s.serviceDB.SaveQualityScorecard(scorecard); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	log.Printf("Карта качества базы %d (%s): %.1f, оценка %s", databaseID, dbInfo.Name, scorecard.Score, scorecard.Grade)
+	return scorecard, http.StatusOK, nil
+}
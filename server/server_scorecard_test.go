@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestDatabaseScorecardRoutes(t *testing.T) {
+	dir := t.TempDir()
+	projectDBPath := filepath.Join(dir, "project.db")
+	projectDB, err := database.NewDB(projectDBPath)
+	if err != nil {
+		t.Fatalf("Failed to create project database: %v", err)
+	}
+	if _, err := projectDB.Exec(`
+		INSERT INTO nomenclature_items (upload_id, nomenclature_reference, nomenclature_code, nomenclature_name)
+		VALUES (1, 'r1', '1', 'Болт М8'), (1, 'r2', '2', 'Гайка М8')
+	`); err != nil {
+		t.Fatalf("Failed to seed nomenclature: %v", err)
+	}
+	if _, err := projectDB.Exec(`
+		INSERT INTO normalized_data (code, source_name, normalized_name, category, kpved_code, merged_count)
+		VALUES ('1', 'Болт М8', 'болт м8', 'крепеж', '25.94.11', 1), ('2', 'Гайка М8', 'гайка м8', 'крепеж', '', 1)
+	`); err != nil {
+		t.Fatalf("Failed to seed normalized_data: %v", err)
+	}
+	projectDB.Close()
+
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("ООО Ромашка", "", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	project, err := serviceDB.CreateClientProject(client.ID, "Номенклатура", "nomenclature", "", "1C", 0.9)
+	if err != nil {
+		t.Fatalf("CreateClientProject() error = %v", err)
+	}
+	dbInfo, err := serviceDB.CreateProjectDatabase(project.ID, "Основная", projectDBPath, "", 0)
+	if err != nil {
+		t.Fatalf("CreateProjectDatabase() error = %v", err)
+	}
+
+	s := &Server{serviceDB: serviceDB, logChan: make(chan LogEntry, 10)}
+	base := "/api/databases/" + strconv.Itoa(dbInfo.ID) + "/scorecard"
+
+	statuses := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"unknown database", http.MethodGet, "/api/databases/999/scorecard", http.StatusNotFound},
+		{"invalid id", http.MethodGet, "/api/databases/abc/scorecard", http.StatusBadRequest},
+		{"unknown route", http.MethodGet, "/api/databases/1/other", http.StatusNotFound},
+		{"wrong method", http.MethodPost, base, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range statuses {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleDatabaseRoutes(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	// Первый запрос рассчитывает карту, повторный с refresh добавляет запись в историю
+	for _, path := range []string{base, base + "?refresh=true"} {
+		rec := httptest.NewRecorder()
+		s.handleDatabaseRoutes(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", path, rec.Code, rec.Body.String())
+		}
+		var response struct {
+			Scorecard database.QualityScorecard `json:"scorecard"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("decode scorecard: %v", err)
+		}
+		// Классификация покрыта наполовину: 100*0.75 + 50*0.25 = 87.5
+		if response.Scorecard.Score != 87.5 || response.Scorecard.Grade != "B" || response.Scorecard.ItemsAnalyzed != 2 {
+			t.Errorf("GET %s scorecard = %+v, want 87.5 B over 2 items", path, response.Scorecard)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleDatabaseRoutes(rec, httptest.NewRequest(http.MethodGet, base+"/history", nil))
+	var history struct {
+		Total int `json:"total"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &history)
+	if rec.Code != http.StatusOK || history.Total != 2 {
+		t.Errorf("GET history = %d %s, want 2 scorecards", rec.Code, rec.Body.String())
+	}
+}
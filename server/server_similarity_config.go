@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/quality"
+)
+
+// Ограничения подтвержденных пар: сохраняемых одним запросом и оцениваемых калибровкой
+const (
+	maxSimilarityPairsPerRequest  = 10000
+	maxSimilarityCalibrationPairs = 50000
+)
+
+// similarityPairsRequest подтвержденные пары дубликатов и разных позиций
+type similarityPairsRequest struct {
+	Pairs     []database.DuplicatePairLabel `json:"pairs"`
+	LabeledBy string                        `json:"labeled_by"`
+}
+
+// similarityCalibrateRequest запрос калибровки: настройка для проверки до сохранения
+// и пары, оцениваемые вместе с сохраненными
+type similarityCalibrateRequest struct {
+	Config *database.SimilarityConfig    `json:"config,omitempty"`
+	Pairs  []database.DuplicatePairLabel `json:"pairs,omitempty"`
+}
+
+// projectSimilarityConfig возвращает настройку сходства проекта; без проекта, без настройки
+// или при ошибке - настройку по умолчанию
+func (s *Server) projectSimilarityConfig(projectID int) database.SimilarityConfig {
+	config := quality.DefaultSimilarityConfig()
+	if projectID <= 0 || s.serviceDB == nil {
+		return config
+	}
+	saved, err := s.serviceDB.GetProjectSimilarityConfig(projectID)
+	if err != nil {
+		log.Printf("Не удалось загрузить настройку сходства проекта %d: %v", projectID, err)
+		return config
+	}
+	if saved == nil {
+		config.ProjectID = projectID
+		return config
+	}
+	return *saved
+}
+
+// uploadQualityAnalyzer возвращает анализатор качества выгрузки с настройкой сходства ее проекта
+func (s *Server) uploadQualityAnalyzer(uploadDB *database.DB, upload *database.Upload) *quality.QualityAnalyzer {
+	analyzer := s.qualityAnalyzerFor(uploadDB)
+	if upload.ProjectID == nil {
+		return analyzer
+	}
+	return analyzer.WithSimilarityConfig(s.projectSimilarityConfig(*upload.ProjectID))
+}
+
+// handleProjectSimilarity управляет метриками сходства нечеткого сопоставителя проекта,
+// подтвержденными парами дубликатов и калибровкой метрик на них
+// GET/PUT/DELETE /api/clients/{id}/projects/{projectId}/similarity
+// GET/POST /api/clients/{id}/projects/{projectId}/similarity/pairs
+// DELETE /api/clients/{id}/projects/{projectId}/similarity/pairs/{pairId}
+// POST /api/clients/{id}/projects/{projectId}/similarity/calibrate
+func (s *Server) handleProjectSimilarity(w http.ResponseWriter, r *http.Request, clientID, projectID int, parts []string) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+	project, err := s.serviceDB.GetClientProject(projectID)
+	if err != nil {
+		s.writeJSONError(w, "Project not found", http.StatusNotFound)
+		return
+	}
+	if project.ClientID != clientID {
+		s.writeJSONError(w, "Project does not belong to this client", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 0:
+		s.handleProjectSimilarityConfig(w, r, projectID)
+	case parts[0] == "pairs" && len(parts) == 1:
+		s.handleProjectSimilarityPairs(w, r, projectID)
+	case parts[0] == "pairs" && len(parts) == 2:
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		pairID, err := strconv.Atoi(parts[1])
+		if err != nil {
+			s.writeJSONError(w, "Invalid pair ID", http.StatusBadRequest)
+			return
+		}
+		if err := s.serviceDB.DeleteDuplicatePairLabel(projectID, pairID); err != nil {
+			s.writeDictionaryError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case parts[0] == "calibrate" && len(parts) == 1:
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.calibrateProjectSimilarity(w, r, projectID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleProjectSimilarityConfig возвращает, сохраняет или сбрасывает настройку сходства проекта
+func (s *Server) handleProjectSimilarityConfig(w http.ResponseWriter, r *http.Request, projectID int) {
+	switch r.Method {
+	case http.MethodGet:
+		saved, err := s.serviceDB.GetProjectSimilarityConfig(projectID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		config := s.projectSimilarityConfig(projectID)
+		s.writeJSONResponse(w, map[string]interface{}{
+			"project_id": projectID,
+			"config":     config,
+			"is_default": saved == nil,
+			"metrics":    quality.SimilarityMetricNames(),
+		}, http.StatusOK)
+	case http.MethodPut:
+		var config database.SimilarityConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		config.ProjectID = projectID
+		config.UpdatedAt = nil
+		if err := quality.ValidateSimilarityConfig(config); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.serviceDB.SaveProjectSimilarityConfig(config); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("Проект %d: метрики сходства %s, порог %.3f", projectID, formatSimilarityMetrics(config.Metrics), config.Threshold),
+			Endpoint:  r.URL.Path,
+		})
+		s.writeJSONResponse(w, s.projectSimilarityConfig(projectID), http.StatusOK)
+	case http.MethodDelete:
+		if err := s.serviceDB.DeleteProjectSimilarityConfig(projectID); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, s.projectSimilarityConfig(projectID), http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleProjectSimilarityPairs возвращает или добавляет подтвержденные пары проекта
+func (s *Server) handleProjectSimilarityPairs(w http.ResponseWriter, r *http.Request, projectID int) {
+	switch r.Method {
+	case http.MethodGet:
+		limit := 100
+		if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= maxSimilarityCalibrationPairs {
+			limit = v
+		}
+		pairs, err := s.serviceDB.GetDuplicatePairLabels(projectID, limit)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"project_id": projectID,
+			"pairs":      pairs,
+			"total":      len(pairs),
+		}, http.StatusOK)
+	case http.MethodPost:
+		var req similarityPairsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.Pairs) == 0 {
+			s.writeJSONError(w, "pairs are required", http.StatusBadRequest)
+			return
+		}
+		if len(req.Pairs) > maxSimilarityPairsPerRequest {
+			s.writeJSONError(w, fmt.Sprintf("Too many pairs: %d (max %d)", len(req.Pairs), maxSimilarityPairsPerRequest), http.StatusBadRequest)
+			return
+		}
+		saved, err := s.serviceDB.SaveDuplicatePairLabels(projectID, req.Pairs, strings.TrimSpace(req.LabeledBy))
+		if err != nil {
+			s.writeDictionaryError(w, err)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"project_id": projectID,
+			"saved":      saved,
+		}, http.StatusCreated)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// calibrateProjectSimilarity оценивает метрики сходства на подтвержденных парах проекта
+// и парах из запроса: точность и полноту при текущем пороге и порог с наибольшей F1
+func (s *Server) calibrateProjectSimilarity(w http.ResponseWriter, r *http.Request, projectID int) {
+	var req similarityCalibrateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	config := s.projectSimilarityConfig(projectID)
+	if req.Config != nil {
+		config = *req.Config
+		config.ProjectID = projectID
+	}
+
+	pairs, err := s.serviceDB.GetDuplicatePairLabels(projectID, maxSimilarityCalibrationPairs)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pairs = append(pairs, req.Pairs...)
+	if len(pairs) == 0 {
+		s.writeJSONError(w, "Project has no labeled pairs", http.StatusBadRequest)
+		return
+	}
+
+	calibration, err := quality.CalibrateSimilarity(pairs, config)
+	if err != nil {
+		s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"project_id":  projectID,
+		"config":      config,
+		"calibration": calibration,
+	}, http.StatusOK)
+}
+
+// formatSimilarityMetrics описывает метрики настройки для журнала: "levenshtein=1, jaro_winkler=0.5"
+func formatSimilarityMetrics(metrics []database.SimilarityMetricWeight) string {
+	parts := make([]string, len(metrics))
+	for i, metric := range metrics {
+		parts[i] = fmt.Sprintf("%s=%g", metric.Metric, metric.Weight)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/quality"
+)
+
+func TestHandleProjectSimilarity(t *testing.T) {
+	serviceDB, err := database.NewServiceDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("NewServiceDBWithConfig() error = %v", err)
+	}
+	defer serviceDB.Close()
+	client, err := serviceDB.CreateClient("Client", "Client LLC", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	project, err := serviceDB.CreateClientProject(client.ID, "Project", "normalization", "", "1C", 0.8)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	s := &Server{logChan: make(chan LogEntry, 100), serviceDB: serviceDB}
+	call := func(method string, parts []string, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleProjectSimilarity(rec, httptest.NewRequest(method, "/api/clients/1/projects/1/similarity", strings.NewReader(body)), client.ID, project.ID, parts)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		parts      []string
+		body       string
+		wantStatus int
+	}{
+		{"default config", http.MethodGet, nil, "", http.StatusOK},
+		{"unknown metric", http.MethodPut, nil, `{"threshold":0.8,"metrics":[{"metric":"soundex","weight":1}]}`, http.StatusBadRequest},
+		{"save config", http.MethodPut, nil, `{"threshold":0.9,"metrics":[{"metric":"token_set_ratio","weight":2},{"metric":"levenshtein","weight":1}]}`, http.StatusOK},
+		{"empty pairs", http.MethodPost, []string{"pairs"}, `{"pairs":[]}`, http.StatusBadRequest},
+		{"label pairs", http.MethodPost, []string{"pairs"}, `{"labeled_by":"аналитик","pairs":[
+			{"name_a":"Болт М10 оцинкованный","name_b":"оцинкованный болт М10","is_duplicate":true},
+			{"name_a":"Болт М10","name_b":"Гайка М10","is_duplicate":false}]}`, http.StatusCreated},
+		{"calibrate", http.MethodPost, []string{"calibrate"}, "", http.StatusOK},
+		{"calibrate with get", http.MethodGet, []string{"calibrate"}, "", http.StatusMethodNotAllowed},
+		{"unknown action", http.MethodGet, []string{"weights"}, "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := call(tt.method, tt.parts, tt.body); rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	config := s.projectSimilarityConfig(project.ID)
+	if config.Threshold != 0.9 || len(config.Metrics) != 2 || config.Metrics[0].Metric != quality.MetricTokenSetRatio {
+		t.Errorf("projectSimilarityConfig() = %+v, want saved config", config)
+	}
+
+	rec := call(http.MethodPost, []string{"calibrate"}, `{"config":{"threshold":0.85,"metrics":[{"metric":"levenshtein","weight":1}]}}`)
+	var resp struct {
+		Config      database.SimilarityConfig     `json:"config"`
+		Calibration quality.SimilarityCalibration `json:"calibration"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v: %s", err, rec.Body.String())
+	}
+	if resp.Config.Threshold != 0.85 || resp.Calibration.Pairs != 2 || resp.Calibration.Duplicates != 1 {
+		t.Errorf("calibration with config override = %+v", resp)
+	}
+
+	if rec := call(http.MethodDelete, nil, ""); rec.Code != http.StatusOK {
+		t.Fatalf("reset status = %d", rec.Code)
+	}
+	if config := s.projectSimilarityConfig(project.ID); config.Threshold != quality.DefaultFuzzyThreshold || config.Metrics[0].Metric != quality.MetricLevenshtein {
+		t.Errorf("projectSimilarityConfig() after reset = %+v, want default", config)
+	}
+}
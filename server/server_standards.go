@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"httpserver/normalization"
+)
+
+// Ограничения отчета по стандартам
+const (
+	standardsDefaultItemsPerGroup = 20
+	standardsMaxItemsPerGroup     = 500
+	standardsDefaultInvalidLimit  = 100
+	standardsMaxInvalidLimit      = 1000
+)
+
+// handleStandardsReport возвращает нормализованные элементы, сгруппированные по обозначениям стандартов,
+// и обозначения с ошибкой формата
+// GET /api/normalization/standards?type=ГОСТ&items_per_group=20&invalid_limit=100
+func (s *Server) handleStandardsReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	itemsPerGroup, err := boundedIntParam(query.Get("items_per_group"), standardsDefaultItemsPerGroup, standardsMaxItemsPerGroup)
+	if err != nil {
+		s.writeJSONError(w, "Invalid items_per_group parameter", http.StatusBadRequest)
+		return
+	}
+	invalidLimit, err := boundedIntParam(query.Get("invalid_limit"), standardsDefaultInvalidLimit, standardsMaxInvalidLimit)
+	if err != nil {
+		s.writeJSONError(w, "Invalid invalid_limit parameter", http.StatusBadRequest)
+		return
+	}
+
+	groups, err := s.db.GetStandardGroups(query.Get("type"), itemsPerGroup)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get standard groups: %v", err), http.StatusInternalServerError)
+		return
+	}
+	invalid, err := s.db.GetInvalidStandardReferences(invalidLimit)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get invalid standard references: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	byType := make(map[string]int)
+	for _, group := range groups {
+		byType[group.Type] += group.ItemCount
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"groups":          groups,
+		"total_standards": len(groups),
+		"items_by_type":   byType,
+		"invalid":         invalid,
+	}, http.StatusOK)
+}
+
+// handleStandardsExtract извлекает и проверяет обозначения стандартов в наименовании
+// POST /api/normalization/standards/extract {"name": "Болт М8 ГОСТ 7798 - 70"}
+func (s *Server) handleStandardsExtract(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Name == "" {
+		s.writeJSONError(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	standards := normalization.ExtractStandards(request.Name)
+	if standards == nil {
+		standards = []normalization.StandardReference{}
+	}
+	s.writeJSONResponse(w, map[string]interface{}{
+		"name":                   request.Name,
+		"standards":              standards,
+		"standard_references":    normalization.StandardReferencesValue(standards),
+		"name_without_standards": normalization.RemoveStandards(request.Name),
+	}, http.StatusOK)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"httpserver/database"
+	"httpserver/storage"
+)
+
+// BackupInfo резервная копия базы в хранилище артефактов
+type BackupInfo struct {
+	storage.ObjectInfo
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// artifactStorage возвращает хранилище артефактов развертывания.
+// При ошибке настройки S3 используется локальный каталог, чтобы отчеты и копии не терялись.
+func (s *Server) artifactStorage() storage.Storage {
+	s.storageOnce.Do(func() {
+		if s.storage != nil {
+			return
+		}
+		var config storage.Config
+		if s.currentConfig() != nil {
+			config = s.currentConfig().Storage
+		}
+		if config.LocalDir == "" {
+			config.LocalDir = filepath.Join(os.TempDir(), "httpserver_storage")
+		}
+		store, err := storage.New(config)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize %s storage: %v, falling back to local directory", config.Backend, err)
+			config.Backend = storage.BackendLocal
+			if store, err = storage.New(config); err != nil {
+				log.Printf("Warning: Failed to initialize local storage: %v", err)
+				return
+			}
+		}
+		s.storage = store
+	})
+	return s.storage
+}
+
+// presignTTL возвращает срок действия ссылок на скачивание
+func (s *Server) presignTTL() time.Duration {
+	if s.currentConfig() == nil || s.currentConfig().StoragePresignTTL <= 0 {
+		return storage.DefaultPresignTTL
+	}
+	return s.currentConfig().StoragePresignTTL
+}
+
+// presignArtifact возвращает ссылку на скачивание объекта или пустую строку при ошибке
+func (s *Server) presignArtifact(key string) string {
+	store := s.artifactStorage()
+	if store == nil || key == "" {
+		return ""
+	}
+	link, err := store.PresignGet(context.Background(), key, s.presignTTL())
+	if err != nil {
+		log.Printf("Warning: Failed to presign %s: %v", key, err)
+		return ""
+	}
+	return link
+}
+
+// storeArtifactFile переносит локальный файл в хранилище артефактов и удаляет его с диска
+func (s *Server) storeArtifactFile(localPath, key, contentType string) (int64, error) {
+	store := s.artifactStorage()
+	if store == nil {
+		return 0, errors.New("artifact storage is not available")
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+	err = store.Put(context.Background(), key, file, info.Size(), contentType)
+	file.Close()
+	if err != nil {
+		return 0, err
+	}
+	os.Remove(localPath)
+	return info.Size(), nil
+}
+
+// handleStorageObject отдает объект локального хранилища по подписанной ссылке
+// GET /api/storage/objects/{key}?expires=...&signature=...
+func (s *Server) handleStorageObject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	local, ok := s.artifactStorage().(*storage.LocalStorage)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/api/storage/objects/")
+	if err := local.VerifyPresigned(key, r.URL.Query().Get("expires"), r.URL.Query().Get("signature")); err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Invalid download link: %v", err), http.StatusForbidden)
+		return
+	}
+	s.serveStorageObject(w, r, local, key)
+}
+
+// serveStorageObject передает объект хранилища клиенту как вложение
+func (s *Server) serveStorageObject(w http.ResponseWriter, r *http.Request, store storage.Storage, key string) {
+	info, err := store.Stat(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		s.writeJSONError(w, "Object not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to read object: %v", err), http.StatusInternalServerError)
+		return
+	}
+	reader, err := store.Get(r.Context(), key)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to read object: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", path.Base(info.Key)))
+	if info.Size > 0 {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size))
+	}
+	io.Copy(w, reader)
+}
+
+// handleBackups список и создание резервных копий баз в хранилище артефактов
+// GET /api/backups, POST /api/backups {"target": "main|normalized|service|unified"} или {"database_id": 1}
+func (s *Server) handleBackups(w http.ResponseWriter, r *http.Request) {
+	store := s.artifactStorage()
+	if store == nil {
+		s.writeJSONError(w, "Artifact storage is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		objects, err := store.List(r.Context(), "backups/")
+		if err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to list backups: %v", err), http.StatusInternalServerError)
+			return
+		}
+		backups := make([]BackupInfo, 0, len(objects))
+		for _, object := range objects {
+			backups = append(backups, BackupInfo{ObjectInfo: object, DownloadURL: s.presignArtifact(object.Key)})
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"backups": backups,
+			"total":   len(backups),
+			"storage": store.Backend(),
+		}, http.StatusOK)
+	case http.MethodPost:
+		var req struct {
+			Target     string `json:"target"`
+			DatabaseID int    `json:"database_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			s.writeJSONError(w, fmt.Sprintf("Invalid payload: %v", err), http.StatusBadRequest)
+			return
+		}
+		backup, err := s.createBackup(r.Context(), req.Target, req.DatabaseID)
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.writeJSONResponse(w, backup, http.StatusCreated)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// createBackup снимает согласованную копию SQLite базы (VACUUM INTO) и сохраняет ее в хранилище
+func (s *Server) createBackup(ctx context.Context, target string, databaseID int) (*BackupInfo, error) {
+	var conn *sql.DB
+	name := target
+	switch {
+	case databaseID > 0:
+		if s.serviceDB == nil {
+			return nil, errors.New("service database is not available")
+		}
+		dbInfo, err := s.serviceDB.GetProjectDatabase(databaseID)
+		if err != nil {
+			return nil, err
+		}
+		if dbInfo == nil {
+			return nil, fmt.Errorf("database %d not found", databaseID)
+		}
+		projectDB, err := database.NewDB(dbInfo.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database %d: %w", databaseID, err)
+		}
+		defer projectDB.Close()
+		conn = projectDB.GetDB()
+		name = fmt.Sprintf("database_%d", databaseID)
+	case target == "main" && s.db != nil:
+		conn = s.db.GetDB()
+	case target == "normalized" && s.normalizedDB != nil:
+		conn = s.normalizedDB.GetDB()
+	case target == "service" && s.serviceDB != nil:
+		conn = s.serviceDB.GetDB()
+	case target == "unified" && s.unifiedCatalogsDB != nil:
+		conn = s.unifiedCatalogsDB.GetDB()
+	default:
+		return nil, fmt.Errorf("unknown backup target: %q", target)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	snapshot := filepath.Join(tmpDir, name+".db")
+	if _, err := conn.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	key := fmt.Sprintf("backups/%s/%s.db", name, time.Now().Format("20060102_150405"))
+	size, err := s.storeArtifactFile(snapshot, key, "application/vnd.sqlite3")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store backup: %w", err)
+	}
+
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fmt.Sprintf("Backup of %s stored as %s (%d bytes)", name, key, size),
+		Endpoint:  "/api/backups",
+	})
+	return &BackupInfo{
+		ObjectInfo:  storage.ObjectInfo{Key: key, Size: size, ContentType: "application/vnd.sqlite3", LastModified: time.Now()},
+		DownloadURL: s.presignArtifact(key),
+	}, nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpserver/database"
+	"httpserver/storage"
+)
+
+func TestBackups(t *testing.T) {
+	dir := t.TempDir()
+	serviceDB, err := database.NewServiceDB(filepath.Join(dir, "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+
+	s := &Server{
+		serviceDB: serviceDB,
+		config:    &Config{Storage: storage.Config{LocalDir: filepath.Join(dir, "storage"), LocalSigningKey: "secret"}},
+		logChan:   make(chan LogEntry, 10),
+	}
+
+	invalid := []struct {
+		name string
+		body string
+	}{
+		{"unknown target", `{"target": "missing"}`},
+		{"unknown database", `{"database_id": 42}`},
+		{"bad payload", `{`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleBackups(rec, httptest.NewRequest(http.MethodPost, "/api/backups", strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400, body = %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleBackups(rec, httptest.NewRequest(http.MethodPost, "/api/backups", strings.NewReader(`{"target": "service"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var backup BackupInfo
+	json.Unmarshal(rec.Body.Bytes(), &backup)
+	if !strings.HasPrefix(backup.Key, "backups/service/") || backup.Size == 0 || backup.DownloadURL == "" {
+		t.Fatalf("unexpected backup: %+v", backup)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleBackups(rec, httptest.NewRequest(http.MethodGet, "/api/backups", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"total":1`) {
+		t.Errorf("list status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	downloads := []struct {
+		name string
+		url  string
+		want int
+	}{
+		{"presigned", backup.DownloadURL, http.StatusOK},
+		{"tampered", strings.Replace(backup.DownloadURL, "signature=", "signature=0", 1), http.StatusForbidden},
+		{"unsigned", "/api/storage/objects/" + backup.Key, http.StatusForbidden},
+	}
+	for _, tt := range downloads {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleStorageObject(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %.200s", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.want == http.StatusOK && !strings.HasPrefix(rec.Body.String(), "SQLite format 3") {
+				t.Errorf("backup is not a SQLite database: %.20q", rec.Body.String())
+			}
+		})
+	}
+}
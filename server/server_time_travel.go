@@ -0,0 +1,131 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+)
+
+// UploadNormalizedAsOfResponse состояние нормализованных записей выгрузки на момент времени
+type UploadNormalizedAsOfResponse struct {
+	UploadUUID string                          `json:"upload_uuid"`
+	AsOf       time.Time                       `json:"as_of"`
+	Items      []*database.NormalizedItemState `json:"items"`
+	Total      int                             `json:"total"`
+	Page       int                             `json:"page"`
+	Limit      int                             `json:"limit"`
+}
+
+// errAsOfBeyondRetention запрошенный момент старше глубины запросов на момент времени
+var errAsOfBeyondRetention = errors.New("as_of is beyond time travel retention")
+
+// parseAsOf разбирает параметр as_of (RFC3339 или YYYY-MM-DD - состояние на конец дня) и проверяет,
+// что момент не старше TIME_TRAVEL_RETENTION_DAYS. Без параметра возвращается текущий момент
+func (s *Server) parseAsOf(r *http.Request) (time.Time, error) {
+	now := time.Now()
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		return now, nil
+	}
+	asOf, err := parseDateParam(value, true)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as_of: expected RFC3339 or YYYY-MM-DD, got %q", value)
+	}
+	if s.currentConfig() != nil && s.currentConfig().TimeTravelRetentionDays > 0 {
+		earliest := now.AddDate(0, 0, -s.currentConfig().TimeTravelRetentionDays)
+		if asOf.Before(earliest) {
+			return time.Time{}, fmt.Errorf("%w: earliest supported is %s (%d days)", errAsOfBeyondRetention,
+				earliest.UTC().Format(time.RFC3339), s.currentConfig().TimeTravelRetentionDays)
+		}
+	}
+	return asOf, nil
+}
+
+// writeAsOfError отвечает ошибкой разбора as_of
+func (s *Server) writeAsOfError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAsOfBeyondRetention) {
+		s.writeJSONError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+}
+
+// handleGetNormalizedItemAsOf возвращает состояние нормализованной записи на момент as_of,
+// восстановленное по ее происхождению
+// GET /api/normalized/items/{id}?as_of=TIMESTAMP
+func (s *Server) handleGetNormalizedItemAsOf(w http.ResponseWriter, r *http.Request, itemID int) {
+	asOf, err := s.parseAsOf(r)
+	if err != nil {
+		s.writeAsOfError(w, err)
+		return
+	}
+
+	state, err := s.db.GetNormalizedItemAsOf(itemID, asOf)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			s.writeJSONError(w, fmt.Sprintf("Normalized item %d not found", itemID), http.StatusNotFound)
+		case errors.Is(err, database.ErrNormalizedItemNotCreatedAsOf):
+			s.writeJSONError(w, err.Error(), http.StatusNotFound)
+		default:
+			s.writeJSONError(w, fmt.Sprintf("Failed to get normalized item: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.writeJSONResponse(w, state, http.StatusOK)
+}
+
+// handleUploadNormalizedAsOf возвращает нормализованные записи исходных элементов выгрузки в состоянии
+// на момент as_of. Нормализованные данные хранятся в основной БД, поэтому выгрузка ищется в ней
+// GET /api/uploads/{uuid}/normalized?as_of=TIMESTAMP&page=1&limit=100
+func (s *Server) handleUploadNormalizedAsOf(w http.ResponseWriter, r *http.Request, upload *database.Upload) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	asOf, err := s.parseAsOf(r)
+	if err != nil {
+		s.writeAsOfError(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	mainUpload, err := s.db.GetUploadByUUID(upload.UploadUUID)
+	if err != nil {
+		s.writeJSONError(w, "Upload has no normalized data in the main database", http.StatusNotFound)
+		return
+	}
+
+	items, total, err := s.db.GetUploadNormalizedItemsAsOf(mainUpload.ID, asOf, (page-1)*limit, limit)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get normalized items: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, UploadNormalizedAsOfResponse{
+		UploadUUID: upload.UploadUUID,
+		AsOf:       asOf,
+		Items:      items,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+	}, http.StatusOK)
+}
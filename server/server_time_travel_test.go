@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	s := &Server{config: &Config{TimeTravelRetentionDays: 30}}
+	recent := time.Now().AddDate(0, 0, -10).UTC()
+	tests := []struct {
+		name    string
+		asOf    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"timestamp", recent.Format(time.RFC3339), recent.Truncate(time.Second), false},
+		{"date means end of day", recent.Format("2006-01-02"), time.Date(recent.Year(), recent.Month(), recent.Day(), 23, 59, 59, 0, time.UTC), false},
+		{"beyond retention", time.Now().AddDate(0, 0, -31).Format(time.RFC3339), time.Time{}, true},
+		{"invalid", "last month", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.parseAsOf(httptest.NewRequest("GET", "/api/normalized/items/1?as_of="+url.QueryEscape(tt.asOf), nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAsOf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("parseAsOf() = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	// Без ограничения глубины принимается любой момент
+	unlimited := &Server{config: &Config{}}
+	if _, err := unlimited.parseAsOf(httptest.NewRequest("GET", "/?as_of=2001-01-01", nil)); err != nil {
+		t.Errorf("parseAsOf() without retention error = %v", err)
+	}
+}
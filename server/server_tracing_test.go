@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"httpserver/tracing"
+)
+
+func TestBackgroundJobContinuesRequestTrace(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	tracer, err := tracing.New(tracing.Config{Endpoint: collector.URL, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("tracing.New() error = %v", err)
+	}
+	tracing.SetGlobal(tracer)
+	defer func() {
+		tracing.SetGlobal(nil)
+		tracer.Shutdown(context.Background())
+	}()
+
+	s := newCrashTestServer(t)
+	requestCtx, cancel := context.WithCancel(context.Background())
+	requestCtx, request := tracing.Start(requestCtx, "POST /api/normalize/start")
+	traceID := request.SpanContext().TraceID.String()
+
+	job, err := s.startBackgroundJob(requestCtx, jobKindNormalization, "catalog_items")
+	if err != nil {
+		t.Fatalf("startBackgroundJob() error = %v", err)
+	}
+	// Завершение запроса не останавливает задачу
+	cancel()
+	request.End()
+
+	tests := []struct {
+		name string
+		got  string
+	}{
+		{"job trace id", job.TraceID},
+		{"job context", tracing.TraceIDFromContext(job.ctx)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != traceID {
+				t.Errorf("trace id = %q, want %q", tt.got, traceID)
+			}
+		})
+	}
+	if job.ctx.Err() != nil {
+		t.Error("job context must not be cancelled with request")
+	}
+
+	s.logCtx(job.ctx, LogEntry{Level: "INFO", Message: "normalization started"})
+	if entry := <-s.logChan; entry.TraceID != traceID {
+		t.Errorf("log trace id = %q, want %q", entry.TraceID, traceID)
+	}
+	s.finishBackgroundJob(job, nil)
+}
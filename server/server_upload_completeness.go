@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+
+	"httpserver/client"
+	"httpserver/database"
+)
+
+// Режимы сверки полноты выгрузки при завершении
+const (
+	UploadCompletenessOff    = "off"    // Не проверять
+	UploadCompletenessWarn   = "warn"   // Завершать со статусом completed_with_warnings
+	UploadCompletenessStrict = "strict" // Отклонять завершение до устранения расхождений
+)
+
+// uploadCompletenessMode возвращает режим сверки полноты выгрузки
+func (s *Server) uploadCompletenessMode() string {
+	if s.currentConfig() == nil || s.currentConfig().UploadCompletenessMode == "" {
+		return UploadCompletenessWarn
+	}
+	return s.currentConfig().UploadCompletenessMode
+}
+
+// saveExpectedCounts сохраняет объявленные в метаданных количества констант и элементов справочников
+func saveExpectedCounts(uploadDB *database.DB, uploadID int, req *MetadataRequest) error {
+	catalogItems := make(map[string]int)
+	for _, catalog := range req.Catalogs {
+		if catalog.ExpectedItems != nil {
+			catalogItems[catalog.Name] = *catalog.ExpectedItems
+		}
+	}
+	if req.ExpectedConstants == nil && len(catalogItems) == 0 {
+		return nil
+	}
+	return uploadDB.SetUploadExpectedCounts(uploadID, req.ExpectedConstants, catalogItems)
+}
+
+// toProtocolDiscrepancies переводит расхождения сверки в формат ответа протокола
+func toProtocolDiscrepancies(discrepancies []database.CompletenessDiscrepancy) []client.CompletenessDiscrepancy {
+	result := make([]client.CompletenessDiscrepancy, len(discrepancies))
+	for i, d := range discrepancies {
+		result[i] = client.CompletenessDiscrepancy{
+			Kind:           d.Kind,
+			Catalog:        d.Catalog,
+			Expected:       d.Expected,
+			Actual:         d.Actual,
+			MissingBatches: d.MissingBatches,
+			Message:        d.Message,
+		}
+	}
+	return result
+}
+
+// writeUploadIncomplete отвечает 409 на завершение выгрузки с расхождениями в режиме strict.
+// Выгрузка остается незавершенной: 1С может дослать недостающие данные и повторить /complete
+func (s *Server) writeUploadIncomplete(w http.ResponseWriter, discrepancies []database.CompletenessDiscrepancy) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusConflict)
+
+	response := ErrorResponse{
+		Success:       false,
+		Error:         fmt.Sprintf("upload is incomplete: %d discrepancies", len(discrepancies)),
+		Code:          "upload_incomplete",
+		Message:       "Upload completeness check failed, resend missing data and complete again",
+		Timestamp:     time.Now().Format(time.RFC3339),
+		Discrepancies: toProtocolDiscrepancies(discrepancies),
+	}
+
+	xmlData, _ := xml.MarshalIndent(response, "", "  ")
+	w.Write([]byte(xml.Header))
+	w.Write(xmlData)
+}
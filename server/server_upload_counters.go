@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"httpserver/database"
+)
+
+// handleRecountUploadCounters пересчитывает счетчики выгрузки по фактическим данным
+// POST /api/uploads/{uuid}/recount
+func (s *Server) handleRecountUploadCounters(w http.ResponseWriter, r *http.Request, uploadDB *database.DB, upload *database.Upload) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	recount, err := uploadDB.RecountUploadCounters(upload.ID)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to recount upload counters: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if recount.Drifted {
+		s.logCountersDrift(recount)
+	}
+	s.writeJSONResponse(w, recount, http.StatusOK)
+}
+
+// handleRecountAllUploadCounters пересчитывает счетчики всех известных выгрузок и возвращает исправленные
+// POST /api/uploads/recount
+func (s *Server) handleRecountAllUploadCounters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fixed := s.recountAllUploadCounters()
+	s.writeJSONResponse(w, map[string]interface{}{
+		"fixed": fixed,
+		"total": len(fixed),
+	}, http.StatusOK)
+}
+
+// recountAllUploadCounters сверяет счетчики выгрузок во всех известных БД и исправляет расхождения
+func (s *Server) recountAllUploadCounters() []*database.UploadCountersRecount {
+	seen := make(map[*database.DB]bool)
+	sources := []*database.DB{s.db, s.unifiedCatalogsDB}
+
+	sources = append(sources, s.uploadDBs.databases()...)
+
+	fixed := []*database.UploadCountersRecount{}
+	for _, sourceDB := range sources {
+		if sourceDB == nil || seen[sourceDB] {
+			continue
+		}
+		seen[sourceDB] = true
+
+		drifted, err := sourceDB.RecountAllUploadCounters()
+		if err != nil {
+			log.Printf("Ошибка сверки счетчиков выгрузок: %v", err)
+		}
+		for _, recount := range drifted {
+			s.logCountersDrift(recount)
+		}
+		fixed = append(fixed, drifted...)
+	}
+	return fixed
+}
+
+// logCountersDrift записывает в журнал исправленное расхождение счетчиков
+func (s *Server) logCountersDrift(recount *database.UploadCountersRecount) {
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "WARNING",
+		Message: fmt.Sprintf("Upload counters drift fixed: constants %d -> %d, catalogs %d -> %d, items %d -> %d",
+			recount.Stored.Constants, recount.Computed.Constants,
+			recount.Stored.Catalogs, recount.Computed.Catalogs,
+			recount.Stored.Items, recount.Computed.Items),
+		UploadUUID: recount.UploadUUID,
+		Endpoint:   "/api/uploads/recount",
+	})
+}
+
+// runUploadCountersRecountLoop периодически сверяет счетчики выгрузок с фактическими данными
+func (s *Server) runUploadCountersRecountLoop() {
+	if s.currentConfig().UploadRecountInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.currentConfig().UploadRecountInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.recountAllUploadCounters()
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"httpserver/database"
+)
+
+// StopAcceptingUploads запрещает новые выгрузки: рукопожатие отвечает 503, начатые выгрузки продолжаются
+func (s *Server) StopAcceptingUploads() {
+	if s.uploadsClosed.CompareAndSwap(false, true) {
+		s.log(LogEntry{
+			Timestamp: time.Now(),
+			Level:     "INFO",
+			Message:   "Новые выгрузки не принимаются, сервер остановится после завершения текущих",
+		})
+	}
+}
+
+// WaitCurrentUploads запрещает новые выгрузки и ждет завершения выгрузок, начатых до вызова.
+// Выгрузка считается завершенной, когда ее статус перестает быть in_progress; прерванная
+// клиентом выгрузка так и остается незавершенной, поэтому ожидание ограничивается ctx
+func (s *Server) WaitCurrentUploads(ctx context.Context, poll time.Duration) error {
+	s.StopAcceptingUploads()
+	db := s.unifiedCatalogsDB
+	if db == nil {
+		db = s.db
+	}
+	if db == nil {
+		return nil
+	}
+
+	current, err := db.GetInProgressUploadUUIDs()
+	if err != nil {
+		return err
+	}
+	for {
+		current, err = remainingUploads(db, current)
+		if err != nil {
+			return err
+		}
+		if len(current) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("uploads still in progress: %d: %w", len(current), ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+// remainingUploads возвращает выгрузки из uuids, которые еще не завершены
+func remainingUploads(db *database.DB, uuids []string) ([]string, error) {
+	if len(uuids) == 0 {
+		return nil, nil
+	}
+	inProgress, err := db.GetInProgressUploadUUIDs()
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[string]bool, len(inProgress))
+	for _, uuid := range inProgress {
+		active[uuid] = true
+	}
+	var remaining []string
+	for _, uuid := range uuids {
+		if active[uuid] {
+			remaining = append(remaining, uuid)
+		}
+	}
+	return remaining, nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestWaitCurrentUploads(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	s := &Server{logChan: make(chan LogEntry, 100), unifiedCatalogsDB: db, config: &Config{}}
+
+	current, _ := db.CreateUploadWithDatabase("current", "8.3", "УТ", nil, "", "", "", 1, "", "", "", nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.WaitCurrentUploads(context.Background(), 10*time.Millisecond)
+	}()
+
+	// Новая выгрузка не принимается, уже начатая продолжается
+	time.Sleep(30 * time.Millisecond)
+	rec := httptest.NewRecorder()
+	s.handleHandshake(rec, httptest.NewRequest(http.MethodPost, "/handshake", strings.NewReader("<handshake/>")))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "uploads_closed") {
+		t.Errorf("handshake status = %d, body = %s, want 503 uploads_closed", rec.Code, rec.Body.String())
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("WaitCurrentUploads() returned %v before upload completed", err)
+	default:
+	}
+
+	db.CompleteUpload(current.ID)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WaitCurrentUploads() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitCurrentUploads() did not return after upload completed")
+	}
+
+	// Незавершенная выгрузка ограничивает ожидание контекстом
+	db.CreateUploadWithDatabase("stale", "8.3", "УТ", nil, "", "", "", 1, "", "", "", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := s.WaitCurrentUploads(ctx, 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitCurrentUploads() error = %v, want deadline exceeded", err)
+	}
+}
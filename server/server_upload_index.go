@@ -0,0 +1,207 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"httpserver/apperrors"
+	"httpserver/database"
+)
+
+// UploadIndexBackfillStatus состояние заполнения индекса расположения выгрузок
+type UploadIndexBackfillStatus struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Databases  int        `json:"databases"` // Просмотрено файлов БД
+	Skipped    int        `json:"skipped"`   // Файлов БД, отсутствующих на диске
+	Indexed    int        `json:"indexed"`   // Добавлено выгрузок в индекс
+	Errors     []string   `json:"errors,omitempty"`
+}
+
+// uploadIndexBackfill состояние фонового заполнения индекса. Нулевое значение готово к использованию
+type uploadIndexBackfill struct {
+	mu     sync.Mutex
+	status UploadIndexBackfillStatus
+}
+
+// start отмечает начало заполнения; false, если заполнение уже выполняется
+func (b *uploadIndexBackfill) start() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.status.Running {
+		return false
+	}
+	now := time.Now()
+	b.status = UploadIndexBackfillStatus{Running: true, StartedAt: &now}
+	return true
+}
+
+// finish сохраняет итоги заполнения
+func (b *uploadIndexBackfill) finish(result UploadIndexBackfillStatus) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	result.Running = false
+	result.StartedAt = b.status.StartedAt
+	result.FinishedAt = &now
+	b.status = result
+}
+
+// snapshot возвращает копию текущего состояния
+func (b *uploadIndexBackfill) snapshot() UploadIndexBackfillStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := b.status
+	status.Errors = append([]string(nil), b.status.Errors...)
+	return status
+}
+
+// unifiedCatalogsDBPath возвращает путь к единой БД справочников
+func (s *Server) unifiedCatalogsDBPath() string {
+	if s.currentConfig() == nil {
+		return ""
+	}
+	return s.currentConfig().UnifiedCatalogsDBPath
+}
+
+// registerUploadLocation записывает файл БД выгрузки в индекс service.db
+func (s *Server) registerUploadLocation(uploadUUID, filePath string, projectDatabaseID *int) {
+	if s.serviceDB == nil || filePath == "" {
+		return
+	}
+	if err := s.serviceDB.RegisterUploadLocation(uploadUUID, filePath, projectDatabaseID); err != nil {
+		log.Printf("Не удалось добавить выгрузку %s в индекс: %v", uploadUUID, err)
+	}
+}
+
+// openIndexedUploadDB открывает БД по записи индекса и добавляет ее в кэш выгрузок.
+// Запись с отсутствующим файлом удаляется из индекса
+func (s *Server) openIndexedUploadDB(location *database.UploadLocation) (*database.DB, error) {
+	if s.unifiedCatalogsDB != nil && samePath(location.FilePath, s.unifiedCatalogsDBPath()) {
+		s.uploadDBs.putShared(location.UploadUUID, s.unifiedCatalogsDB)
+		return s.unifiedCatalogsDB, nil
+	}
+
+	if _, statErr := os.Stat(location.FilePath); statErr != nil {
+		err := fmt.Errorf("database file %s for upload %s is not available: %w", location.FilePath, location.UploadUUID, statErr)
+		if !os.IsNotExist(statErr) {
+			return nil, err
+		}
+		if err := s.serviceDB.DeleteUploadLocation(location.UploadUUID); err != nil {
+			log.Printf("Не удалось удалить выгрузку %s из индекса: %v", location.UploadUUID, err)
+		}
+		return nil, apperrors.Wrap(apperrors.KindNotFound, "upload_not_found", err)
+	}
+
+	uploadDB, err := database.NewDBWithConfig(location.FilePath, s.databaseConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database for upload %s: %w", location.UploadUUID, err)
+	}
+	return s.uploadDBs.put(location.UploadUUID, location.FilePath, uploadDB), nil
+}
+
+// runUploadIndexBackfill заполняет индекс при первом запуске после его появления
+func (s *Server) runUploadIndexBackfill() {
+	if s.serviceDB == nil {
+		return
+	}
+	count, err := s.serviceDB.CountUploadLocations()
+	if err != nil {
+		log.Printf("Ошибка проверки индекса выгрузок: %v", err)
+		return
+	}
+	if count > 0 || !s.uploadIndexBackfill.start() {
+		return
+	}
+	s.backfillUploadIndex()
+}
+
+// backfillUploadIndex добавляет в индекс выгрузки единой БД и всех БД проектов.
+// Вызывается после успешного uploadIndexBackfill.start
+func (s *Server) backfillUploadIndex() UploadIndexBackfillStatus {
+	var result UploadIndexBackfillStatus
+	index := func(db *database.DB, path string, projectDatabaseID *int) {
+		result.Databases++
+		uuids, err := db.GetUploadUUIDs()
+		if err == nil {
+			var added int
+			added, err = s.serviceDB.IndexUploadLocations(path, projectDatabaseID, uuids)
+			result.Indexed += added
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+		}
+	}
+
+	unifiedPath := s.unifiedCatalogsDBPath()
+	if s.unifiedCatalogsDB != nil && unifiedPath != "" {
+		index(s.unifiedCatalogsDB, unifiedPath, nil)
+	}
+
+	databases, err := s.serviceDB.GetAllProjectDatabases()
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	for _, dbInfo := range databases {
+		if samePath(dbInfo.FilePath, unifiedPath) {
+			continue
+		}
+		if _, err := os.Stat(dbInfo.FilePath); err != nil {
+			result.Skipped++
+			continue
+		}
+		projectDB, err := database.NewDB(dbInfo.FilePath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", dbInfo.FilePath, err))
+			continue
+		}
+		projectDatabaseID := dbInfo.ID
+		index(projectDB, dbInfo.FilePath, &projectDatabaseID)
+		projectDB.Close()
+	}
+
+	s.uploadIndexBackfill.finish(result)
+	log.Printf("Индекс выгрузок заполнен: БД %d, пропущено %d, добавлено выгрузок %d, ошибок %d",
+		result.Databases, result.Skipped, result.Indexed, len(result.Errors))
+	return result
+}
+
+// handleUploadIndex возвращает состояние индекса расположения выгрузок и запускает его перестроение
+// GET  /api/uploads/index
+// POST /api/uploads/index - повторный просмотр всех БД проектов в фоне
+func (s *Server) handleUploadIndex(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		count, err := s.serviceDB.CountUploadLocations()
+		if err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.writeJSONResponse(w, map[string]interface{}{
+			"indexed_uploads": count,
+			"backfill":        s.uploadIndexBackfill.snapshot(),
+		}, http.StatusOK)
+	case http.MethodPost:
+		if !s.uploadIndexBackfill.start() {
+			s.writeJSONError(w, "Upload index backfill is already running", http.StatusConflict)
+			return
+		}
+		go s.runIsolated("upload_index_backfill", nil, nil, false, func() { s.backfillUploadIndex() })
+		s.writeJSONResponse(w, map[string]interface{}{
+			"status":  "started",
+			"message": "Upload index backfill started",
+		}, http.StatusAccepted)
+	default:
+		s.writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"httpserver/database"
+)
+
+func TestGetUploadDatabaseUsesIndex(t *testing.T) {
+	dir := t.TempDir()
+	serviceDB, err := database.NewServiceDB(filepath.Join(dir, "service.db"))
+	if err != nil {
+		t.Fatalf("Failed to create service database: %v", err)
+	}
+	defer serviceDB.Close()
+
+	unifiedPath := filepath.Join(dir, "unified.db")
+	unifiedDB, err := database.NewDB(unifiedPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer unifiedDB.Close()
+	if _, err := unifiedDB.CreateUpload("unified-upload", "8.3", "УТ"); err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	client, err := serviceDB.CreateClient("Клиент", "", "", "", "", "", "test")
+	if err != nil {
+		t.Fatalf("CreateClient() error = %v", err)
+	}
+	project, err := serviceDB.CreateClientProject(client.ID, "Проект", "nomenclature", "", "", 0.8)
+	if err != nil {
+		t.Fatalf("CreateClientProject() error = %v", err)
+	}
+	projectPath := filepath.Join(dir, "project.db")
+	projectDB, err := database.NewDB(projectPath)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if _, err := projectDB.CreateUpload("project-upload", "8.3", "БП"); err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	projectDB.Close()
+	if _, err := serviceDB.CreateProjectDatabase(project.ID, "База", projectPath, "", 0); err != nil {
+		t.Fatalf("CreateProjectDatabase() error = %v", err)
+	}
+	if _, err := serviceDB.CreateProjectDatabase(project.ID, "Удаленная", filepath.Join(dir, "missing.db"), "", 0); err != nil {
+		t.Fatalf("CreateProjectDatabase() error = %v", err)
+	}
+
+	s := &Server{
+		serviceDB:         serviceDB,
+		unifiedCatalogsDB: unifiedDB,
+		config:            &Config{UnifiedCatalogsDBPath: unifiedPath},
+		logChan:           make(chan LogEntry, 10),
+	}
+	defer s.uploadDBs.closeAll()
+
+	// Без индекса выгрузка проекта не ищется перебором БД
+	if _, err := s.getUploadDatabase("project-upload"); err == nil {
+		t.Error("getUploadDatabase() must not scan project databases")
+	}
+
+	s.runUploadIndexBackfill()
+	status := s.uploadIndexBackfill.snapshot()
+	if status.Running || status.Databases != 2 || status.Skipped != 1 || status.Indexed != 2 || len(status.Errors) != 0 {
+		t.Errorf("backfill status = %+v", status)
+	}
+
+	tests := []struct {
+		uuid     string
+		wantPath string
+	}{
+		{"unified-upload", unifiedPath},
+		{"project-upload", projectPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uuid, func(t *testing.T) {
+			db, err := s.getUploadDatabase(tt.uuid)
+			if err != nil {
+				t.Fatalf("getUploadDatabase() error = %v", err)
+			}
+			if upload, err := db.GetUploadByUUID(tt.uuid); err != nil || upload == nil {
+				t.Errorf("GetUploadByUUID() = %v, %v", upload, err)
+			}
+			location, err := serviceDB.GetUploadLocation(tt.uuid)
+			if err != nil || location == nil || location.FilePath != tt.wantPath {
+				t.Errorf("GetUploadLocation() = %+v, %v", location, err)
+			}
+		})
+	}
+
+	// Запись с удаленным файлом удаляется из индекса
+	if err := serviceDB.RegisterUploadLocation("lost-upload", filepath.Join(dir, "lost.db"), nil); err != nil {
+		t.Fatalf("RegisterUploadLocation() error = %v", err)
+	}
+	if _, err := s.getUploadDatabase("lost-upload"); err == nil {
+		t.Error("getUploadDatabase() must fail for missing file")
+	}
+	if location, _ := serviceDB.GetUploadLocation("lost-upload"); location != nil {
+		t.Errorf("stale location was not removed: %+v", location)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleUploadIndex(rec, httptest.NewRequest(http.MethodGet, "/api/uploads/index", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
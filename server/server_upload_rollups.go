@@ -0,0 +1,137 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"httpserver/database"
+)
+
+// uploadRollupRecentWindow окно, за которое сводки пересчитываются периодически
+// (более старые дни не меняются и остаются в таблице сводок)
+const uploadRollupRecentWindow = 48 * time.Hour
+
+// refreshAllUploadRollups пересчитывает сводки по всем известным БД выгрузок, начиная с дня since.
+// Сводка сохраняется с заменой, поэтому since выравнивается на начало дня UTC (иначе первый день
+// перезаписывается неполными счетчиками), а сводки разных БД с одним ключом предварительно суммируются
+func (s *Server) refreshAllUploadRollups(since time.Time) error {
+	if s.serviceDB == nil {
+		return nil
+	}
+	since = since.UTC().Truncate(24 * time.Hour)
+
+	seen := make(map[*database.DB]bool)
+	sources := []*database.DB{s.db, s.unifiedCatalogsDB}
+
+	sources = append(sources, s.uploadDBs.databases()...)
+
+	var computed [][]database.UploadDailyRollup
+	for _, sourceDB := range sources {
+		if sourceDB == nil || seen[sourceDB] {
+			continue
+		}
+		seen[sourceDB] = true
+
+		rollups, err := sourceDB.ComputeUploadDailyRollups(since)
+		if err != nil {
+			// Без одной из БД суммы были бы занижены, поэтому сводки не сохраняются
+			return fmt.Errorf("failed to compute upload rollups: %w", err)
+		}
+		computed = append(computed, rollups)
+	}
+
+	return s.serviceDB.SaveUploadDailyRollups(database.MergeUploadDailyRollups(computed...))
+}
+
+// runUploadRollupsLoop при старте пересчитывает все сводки, затем периодически обновляет последние дни
+func (s *Server) runUploadRollupsLoop() {
+	if err := s.refreshAllUploadRollups(time.Time{}); err != nil {
+		log.Printf("Ошибка пересчета сводок по выгрузкам: %v", err)
+	}
+
+	if s.currentConfig().UploadRollupInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.currentConfig().UploadRollupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.refreshAllUploadRollups(time.Now().Add(-uploadRollupRecentWindow)); err != nil {
+				log.Printf("Ошибка пересчета сводок по выгрузкам: %v", err)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// handleUploadRollupSeries возвращает временной ряд дневных сводок по выгрузкам для графиков
+// GET /api/databases/analytics?client_id=&project_id=&from=&to=&recompute=true
+func (s *Server) handleUploadRollupSeries(w http.ResponseWriter, r *http.Request) {
+	if s.serviceDB == nil {
+		s.writeJSONError(w, "Service database not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	var filter database.UploadRollupFilter
+
+	if value := query.Get("client_id"); value != "" {
+		clientID, err := strconv.Atoi(value)
+		if err != nil {
+			s.writeJSONError(w, "Invalid client_id", http.StatusBadRequest)
+			return
+		}
+		filter.ClientID = &clientID
+	}
+	if value := query.Get("project_id"); value != "" {
+		projectID, err := strconv.Atoi(value)
+		if err != nil {
+			s.writeJSONError(w, "Invalid project_id", http.StatusBadRequest)
+			return
+		}
+		filter.ProjectID = &projectID
+	}
+
+	if value := query.Get("from"); value != "" {
+		from, err := parseDateParam(value, false)
+		if err != nil {
+			s.writeJSONError(w, "Invalid from date, expected YYYY-MM-DD or RFC3339", http.StatusBadRequest)
+			return
+		}
+		filter.From = &from
+	}
+	if value := query.Get("to"); value != "" {
+		to, err := parseDateParam(value, true)
+		if err != nil {
+			s.writeJSONError(w, "Invalid to date, expected YYYY-MM-DD or RFC3339", http.StatusBadRequest)
+			return
+		}
+		filter.To = &to
+	}
+
+	// Принудительный пересчет последних дней перед выдачей ряда
+	if query.Get("recompute") == "true" {
+		if err := s.refreshAllUploadRollups(time.Now().Add(-uploadRollupRecentWindow)); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Failed to recompute upload rollups: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	points, err := s.serviceDB.GetUploadRollupSeries(filter)
+	if err != nil {
+		s.writeJSONError(w, fmt.Sprintf("Failed to get upload rollups: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSONResponse(w, map[string]interface{}{
+		"series": points,
+		"total":  len(points),
+	}, http.StatusOK)
+}
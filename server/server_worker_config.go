@@ -10,6 +10,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"httpserver/normalization"
 )
 
 // handleGetWorkerConfig возвращает текущую конфигурацию воркеров и моделей
@@ -95,6 +97,21 @@ func (s *Server) handleUpdateWorkerConfig(w http.ResponseWriter, r *http.Request
 		err = s.workerConfigManager.SetGlobalMaxWorkers(maxWorkers)
 		response = map[string]interface{}{"message": "Global max workers updated successfully"}
 
+	case "set_model_chain":
+		var chain struct {
+			Task  string                         `json:"task"`
+			Steps []normalization.ModelChainStep `json:"steps"`
+		}
+		if err = mapToStruct(req.Data, &chain); err != nil {
+			s.writeJSONError(w, fmt.Sprintf("Invalid model chain: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err = s.workerConfigManager.SetModelChain(chain.Task, chain.Steps); err != nil {
+			s.writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response = map[string]interface{}{"message": "Model chain updated successfully"}
+
 	default:
 		s.writeJSONError(w, "Unknown action", http.StatusBadRequest)
 		return
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"httpserver/database"
+	"httpserver/nomenclature"
+	"httpserver/normalization"
+)
+
+// registerAIClient добавляет AI клиент запущенной задачи в список получателей изменений конфигурации.
+// Возвращает функцию для удаления клиента после завершения задачи.
+func (s *Server) registerAIClient(client *nomenclature.AIClient, owner string) func() {
+	if client == nil {
+		return func() {}
+	}
+
+	// Владелец клиента - его задача в общем бюджете AI запросов
+	client.SetJob(owner)
+	s.aiClientsMutex.Lock()
+	s.aiClients[client] = owner
+	s.aiClientsMutex.Unlock()
+
+	return func() {
+		s.aiClientsMutex.Lock()
+		delete(s.aiClients, client)
+		s.aiClientsMutex.Unlock()
+	}
+}
+
+// liveAIClients возвращает AI клиенты запущенных задач и долгоживущих компонентов сервера
+func (s *Server) liveAIClients() map[*nomenclature.AIClient]string {
+	clients := make(map[*nomenclature.AIClient]string)
+
+	s.aiClientsMutex.Lock()
+	for client, owner := range s.aiClients {
+		clients[client] = owner
+	}
+	s.aiClientsMutex.Unlock()
+
+	if client := s.normalizer.AIClient(); client != nil {
+		clients[client] = "normalizer"
+	}
+
+	s.kpvedClassifierMutex.RLock()
+	if client := s.hierarchicalClassifier.AIClient(); client != nil {
+		clients[client] = "kpved_classifier"
+	}
+	s.kpvedClassifierMutex.RUnlock()
+
+	s.processorMutex.RLock()
+	if client := s.nomenclatureProcessor.AIClient(); client != nil {
+		clients[client] = "nomenclature_processor"
+	}
+	s.processorMutex.RUnlock()
+
+	return clients
+}
+
+// applyWorkerConfigChange переключает модель и лимит запросов у работающих AI клиентов.
+// Модель меняется со следующего запроса (между элементами пакета), текущие запросы не прерываются.
+// Число воркеров запущенных задач не меняется - новый максимум действует для новых задач.
+func (s *Server) applyWorkerConfigChange(change WorkerConfigChange) {
+	type affectedClient struct {
+		Owner         string `json:"owner"`
+		PreviousModel string `json:"previous_model"`
+	}
+
+	affected := []affectedClient{}
+	for client, owner := range s.liveAIClients() {
+		affected = append(affected, affectedClient{Owner: owner, PreviousModel: client.Model()})
+		client.SetModel(change.Model)
+		client.SetRateLimit(change.RateLimit)
+	}
+	sort.Slice(affected, func(i, j int) bool { return affected[i].Owner < affected[j].Owner })
+
+	// Цепочка моделей нормализации применяется к следующим элементам
+	if s.normalizer != nil && s.workerConfigManager != nil {
+		s.normalizer.SetModelChain(s.workerConfigManager.GetModelChain(normalization.ModelChainTaskNormalization))
+	}
+
+	message := fmt.Sprintf("Worker config changed (%s): provider=%s, model=%s, rate_limit=%d/min, max_workers=%d, applied to %d running clients",
+		change.Action, change.Provider, change.Model, change.RateLimit, change.MaxWorkers, len(affected))
+	s.log(LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   message,
+		Endpoint:  "/api/workers/config/update",
+	})
+
+	select {
+	case s.normalizerEvents <- fmt.Sprintf("⚙ Конфигурация воркеров изменена: модель %s, лимит %d запросов/мин", change.Model, change.RateLimit):
+	default:
+	}
+
+	s.publishMonitoringEvent(map[string]interface{}{
+		"type":      "worker_config_changed",
+		"timestamp": change.Timestamp.Format(time.RFC3339),
+		"change":    change,
+		"affected":  affected,
+	})
+}
+
+// subscribeMonitoringEvents подписывает SSE соединение мониторинга на события сервера
+func (s *Server) subscribeMonitoringEvents() (<-chan []byte, func()) {
+	ch := make(chan []byte, 16)
+
+	s.monitoringSubscribersMutex.Lock()
+	s.monitoringSubscribers[ch] = struct{}{}
+	s.monitoringSubscribersMutex.Unlock()
+
+	return ch, func() {
+		s.monitoringSubscribersMutex.Lock()
+		delete(s.monitoringSubscribers, ch)
+		s.monitoringSubscribersMutex.Unlock()
+	}
+}
+
+// publishMonitoringEvent рассылает событие подписчикам мониторинга этого и, при ретрансляции, остальных экземпляров
+func (s *Server) publishMonitoringEvent(event map[string]interface{}) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Ошибка сериализации события мониторинга: %v", err)
+		return
+	}
+	if s.clusterRelayEnabled() {
+		if err := s.serviceDB.PublishClusterEvent(s.instanceID, database.ClusterChannelMonitoring, string(data)); err != nil {
+			log.Printf("Ошибка публикации события мониторинга: %v", err)
+		}
+	}
+	s.broadcastMonitoringData(data)
+}
+
+// broadcastMonitoringData рассылает событие локальным подписчикам мониторинга (медленные подписчики пропускают событие)
+func (s *Server) broadcastMonitoringData(data []byte) {
+	s.monitoringSubscribersMutex.Lock()
+	defer s.monitoringSubscribersMutex.Unlock()
+
+	for ch := range s.monitoringSubscribers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
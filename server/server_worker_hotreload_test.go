@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"httpserver/nomenclature"
+	"httpserver/normalization"
+)
+
+func TestWorkerConfigHotReload(t *testing.T) {
+	manager := NewWorkerConfigManager(nil)
+	s := &Server{
+		workerConfigManager:   manager,
+		aiClients:             make(map[*nomenclature.AIClient]string),
+		monitoringSubscribers: make(map[chan []byte]struct{}),
+	}
+	manager.Subscribe(s.applyWorkerConfigChange)
+
+	client := nomenclature.NewAIClient("test-key", "GLM-4.5")
+	unregister := s.registerAIClient(client, "kpved_reclassify_hierarchical")
+	events, unsubscribe := s.subscribeMonitoringEvents()
+	defer unsubscribe()
+
+	// Переключение на более дешевую модель применяется к запущенному клиенту
+	if err := manager.SetDefaultModel("arliai", "GLM-4.5-Air"); err != nil {
+		t.Fatalf("SetDefaultModel() error = %v", err)
+	}
+	if got := client.Model(); got != "GLM-4.5-Air" {
+		t.Errorf("client model = %s, want GLM-4.5-Air", got)
+	}
+	if got := client.RateLimit(); got != 120 {
+		t.Errorf("client rate limit = %.0f/min, want 120", got)
+	}
+
+	select {
+	case data := <-events:
+		var event struct {
+			Type     string             `json:"type"`
+			Change   WorkerConfigChange `json:"change"`
+			Affected []struct {
+				Owner         string `json:"owner"`
+				PreviousModel string `json:"previous_model"`
+			} `json:"affected"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if event.Type != "worker_config_changed" || event.Change.Action != "set_default_model" || event.Change.Model != "GLM-4.5-Air" {
+			t.Errorf("unexpected event: %+v", event)
+		}
+		if len(event.Affected) != 1 || event.Affected[0].PreviousModel != "GLM-4.5" {
+			t.Errorf("unexpected affected clients: %+v", event.Affected)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("monitoring event was not published")
+	}
+
+	// Лимит запросов провайдера меняется на лету
+	provider, err := manager.GetActiveProvider()
+	if err != nil {
+		t.Fatalf("GetActiveProvider() error = %v", err)
+	}
+	updated := *provider
+	updated.APIKey = ""
+	updated.RateLimit = 30
+	if err := manager.UpdateProvider("arliai", &updated); err != nil {
+		t.Fatalf("UpdateProvider() error = %v", err)
+	}
+	if got := client.RateLimit(); got != 30 {
+		t.Errorf("client rate limit = %.0f/min, want 30", got)
+	}
+
+	// После завершения задачи клиент больше не получает изменений
+	unregister()
+	if err := manager.SetDefaultModel("arliai", "GLM-4.5"); err != nil {
+		t.Fatalf("SetDefaultModel() error = %v", err)
+	}
+	if got := client.Model(); got != "GLM-4.5-Air" {
+		t.Errorf("unregistered client model changed to %s", got)
+	}
+}
+
+func TestWorkerConfigModelChain(t *testing.T) {
+	manager := NewWorkerConfigManager(nil)
+
+	var changes []WorkerConfigChange
+	manager.Subscribe(func(change WorkerConfigChange) {
+		changes = append(changes, change)
+	})
+
+	if chain := manager.GetModelChain(normalization.ModelChainTaskClassification); chain != nil {
+		t.Fatalf("expected no chain by default, got %+v", chain)
+	}
+
+	steps := []normalization.ModelChainStep{
+		{Model: "GLM-4.5-Air", MinConfidence: 0.8},
+		{Model: "GLM-4.5", MinConfidence: 0},
+	}
+	if err := manager.SetModelChain(normalization.ModelChainTaskClassification, steps); err != nil {
+		t.Fatalf("SetModelChain() error = %v", err)
+	}
+	if chain := manager.GetModelChain(normalization.ModelChainTaskClassification); len(chain) != 2 || chain[1].Model != "GLM-4.5" {
+		t.Errorf("unexpected chain: %+v", chain)
+	}
+	if len(changes) != 1 || changes[0].Action != "set_model_chain" {
+		t.Errorf("unexpected change notifications: %+v", changes)
+	}
+
+	if err := manager.SetModelChain("unknown", steps); err == nil {
+		t.Error("expected error for unknown task")
+	}
+	if err := manager.SetModelChain(normalization.ModelChainTaskNormalization, []normalization.ModelChainStep{{Model: ""}}); err == nil {
+		t.Error("expected error for empty model")
+	}
+
+	// Пустая цепочка удаляет настройку
+	if err := manager.SetModelChain(normalization.ModelChainTaskClassification, nil); err != nil {
+		t.Fatalf("SetModelChain(nil) error = %v", err)
+	}
+	if chain := manager.GetModelChain(normalization.ModelChainTaskClassification); chain != nil {
+		t.Errorf("expected chain to be removed, got %+v", chain)
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"time"
+
+	"httpserver/database"
+)
+
+// streamFlushEvery количество записей, после которого потоковый ответ сбрасывается клиенту
+const streamFlushEvery = 100
+
+// streamBufferSize размер буфера потокового ответа
+const streamBufferSize = 32 * 1024
+
+// innerXML содержимое элемента, которое уже является XML и вставляется как есть
+type innerXML struct {
+	XML string `xml:",innerxml"`
+}
+
+// constantDataXML константа в ответах /data и /stream
+type constantDataXML struct {
+	XMLName    xml.Name `xml:"constant"`
+	ID         int      `xml:"id"`
+	UploadID   int      `xml:"upload_id"`
+	Name       string   `xml:"name"`
+	Synonym    string   `xml:"synonym"`
+	Type       string   `xml:"type"`
+	Value      string   `xml:"value"`
+	ValueKind  string   `xml:"value_kind"`
+	TypedValue string   `xml:"typed_value"`
+	CreatedAt  string   `xml:"created_at"`
+}
+
+// catalogItemDataXML элемент справочника в ответах /data и /stream;
+// attributes_xml и table_parts_xml уже содержат XML и вставляются как есть
+type catalogItemDataXML struct {
+	XMLName     xml.Name `xml:"catalog_item"`
+	ID          int      `xml:"id"`
+	CatalogID   int      `xml:"catalog_id"`
+	CatalogName string   `xml:"catalog_name"`
+	Reference   string   `xml:"reference"`
+	Code        string   `xml:"code"`
+	Name        string   `xml:"name"`
+	Attributes  innerXML `xml:"attributes_xml"`
+	TableParts  innerXML `xml:"table_parts_xml"`
+	CreatedAt   string   `xml:"created_at"`
+}
+
+// dataItemXML элемент данных выгрузки с той же разметкой, что и DataItem, но содержимое
+// кодируется xml.Encoder напрямую в ответ, без промежуточной XML строки
+type dataItemXML struct {
+	XMLName     xml.Name            `xml:"item"`
+	Type        string              `xml:"type,attr"`
+	ID          int                 `xml:"id,attr"`
+	CreatedAt   time.Time           `xml:"created_at,attr"`
+	Constant    *constantDataXML    `xml:"constant,omitempty"`
+	CatalogItem *catalogItemDataXML `xml:"catalog_item,omitempty"`
+}
+
+// newConstantDataItem формирует элемент данных для константы; значение скрывается профилем redaction
+func newConstantDataItem(source *database.Constant, redaction *database.RedactionProfile) dataItemXML {
+	constant := *source
+	redaction.RedactConstant(&constant)
+	return dataItemXML{
+		Type:      "constant",
+		ID:        constant.ID,
+		CreatedAt: constant.CreatedAt,
+		Constant: &constantDataXML{
+			ID:         constant.ID,
+			UploadID:   constant.UploadID,
+			Name:       constant.Name,
+			Synonym:    constant.Synonym,
+			Type:       constant.Type,
+			Value:      constant.Value,
+			ValueKind:  constant.ValueKind,
+			TypedValue: database.FormatConstantTypedValue(constant.TypedValue),
+			CreatedAt:  constant.CreatedAt.Format(time.RFC3339),
+		},
+	}
+}
+
+// newCatalogDataItem формирует элемент данных для элемента справочника; зашифрованные
+// реквизиты раскрываются только для запросов с правом расшифровки, затем поля скрываются профилем redaction
+func (s *Server) newCatalogDataItem(r *http.Request, source *database.CatalogItem, redaction *database.RedactionProfile) dataItemXML {
+	item := *source
+	item.Attributes = s.revealValue(r, source.Attributes)
+	item.TableParts = s.revealValue(r, source.TableParts)
+	redaction.RedactCatalogItem(&item)
+	return dataItemXML{
+		Type:      "catalog_item",
+		ID:        item.ID,
+		CreatedAt: item.CreatedAt,
+		CatalogItem: &catalogItemDataXML{
+			ID:          item.ID,
+			CatalogID:   item.CatalogID,
+			CatalogName: item.CatalogName,
+			Reference:   item.Reference,
+			Code:        item.Code,
+			Name:        item.Name,
+			Attributes:  innerXML{XML: item.Attributes},
+			TableParts:  innerXML{XML: item.TableParts},
+			CreatedAt:   item.CreatedAt.Format(time.RFC3339),
+		},
+	}
+}
+
+// xmlStreamWriter кодирует XML записи напрямую в ответ через буфер фиксированного размера и
+// сбрасывает его клиенту каждые streamFlushEvery записей. Запись в медленного клиента блокирует
+// кодирование следующих записей, поэтому в памяти не накапливается больше одного буфера
+type xmlStreamWriter struct {
+	buf     *bufio.Writer
+	enc     *xml.Encoder
+	flusher http.Flusher
+	pending int
+}
+
+// newXMLStreamWriter создает потоковый кодировщик поверх w; если w реализует http.Flusher,
+// данные отправляются клиенту при каждом сбросе
+func newXMLStreamWriter(w io.Writer) *xmlStreamWriter {
+	buf := bufio.NewWriterSize(w, streamBufferSize)
+	sw := &xmlStreamWriter{buf: buf, enc: xml.NewEncoder(buf)}
+	sw.flusher, _ = w.(http.Flusher)
+	return sw
+}
+
+// flush отправляет клиенту все закодированные данные
+func (sw *xmlStreamWriter) flush() error {
+	if err := sw.enc.Flush(); err != nil {
+		return err
+	}
+	if err := sw.buf.Flush(); err != nil {
+		return err
+	}
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+	sw.pending = 0
+	return nil
+}
+
+// written учитывает записанную запись и сбрасывает буфер каждые streamFlushEvery записей
+func (sw *xmlStreamWriter) written() error {
+	sw.pending++
+	if sw.pending < streamFlushEvery {
+		return nil
+	}
+	return sw.flush()
+}
+
+// beginDataResponse пишет заголовок data_response и поля перед списком элементов.
+// Элементы затем добавляются writeDataItem, ответ закрывается endDataResponse
+func (sw *xmlStreamWriter) beginDataResponse(header DataResponse) error {
+	sw.enc.Indent("", "  ")
+	if _, err := sw.buf.WriteString(xml.Header); err != nil {
+		return err
+	}
+	if err := sw.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "data_response"}}); err != nil {
+		return err
+	}
+	fields := []struct {
+		name  string
+		value interface{}
+	}{
+		{"upload_uuid", header.UploadUUID},
+		{"type", header.Type},
+		{"page", header.Page},
+		{"limit", header.Limit},
+		{"total", header.Total},
+	}
+	for _, field := range fields {
+		if err := sw.enc.EncodeElement(field.value, xml.StartElement{Name: xml.Name{Local: field.name}}); err != nil {
+			return err
+		}
+	}
+	return sw.enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "items"}})
+}
+
+// writeDataItem добавляет элемент в список items ответа data_response
+func (sw *xmlStreamWriter) writeDataItem(item dataItemXML) error {
+	if err := sw.enc.Encode(item); err != nil {
+		return err
+	}
+	return sw.written()
+}
+
+// endDataResponse закрывает ответ data_response и отправляет остаток клиенту
+func (sw *xmlStreamWriter) endDataResponse() error {
+	if err := sw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "items"}}); err != nil {
+		return err
+	}
+	if err := sw.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "data_response"}}); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+// writeEvent отправляет элемент отдельным SSE событием
+func (sw *xmlStreamWriter) writeEvent(item dataItemXML) error {
+	if _, err := sw.buf.WriteString("data: "); err != nil {
+		return err
+	}
+	if err := sw.enc.Encode(item); err != nil {
+		return err
+	}
+	if _, err := sw.buf.WriteString("\n\n"); err != nil {
+		return err
+	}
+	return sw.written()
+}
+
+// writeEventData отправляет SSE событие с готовыми данными и сразу сбрасывает его клиенту
+func (sw *xmlStreamWriter) writeEventData(data string) error {
+	if err := sw.enc.Flush(); err != nil {
+		return err
+	}
+	if _, err := sw.buf.WriteString("data: " + data + "\n\n"); err != nil {
+		return err
+	}
+	return sw.flush()
+}
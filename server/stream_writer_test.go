@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+// countingFlusher принимает потоковый ответ, не сохраняя его, и считает байты и сбросы
+type countingFlusher struct {
+	bytes   int64
+	flushes int
+}
+
+func (c *countingFlusher) Write(p []byte) (int, error) {
+	c.bytes += int64(len(p))
+	return len(p), nil
+}
+
+func (c *countingFlusher) Flush() {
+	c.flushes++
+}
+
+func TestHandleGetUploadDataStreaming(t *testing.T) {
+	db, err := database.NewDBWithConfig(":memory:", database.DBConfig{MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create DB: %v", err)
+	}
+	defer db.Close()
+	upload, err := db.CreateUpload("uuid-data", "8.3", "УправлениеТорговлей")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		if err := db.AddConstant(upload.ID, fmt.Sprintf("Константа%d", i), "A & <B>", "Строка", "v"); err != nil {
+			t.Fatalf("AddConstant() error = %v", err)
+		}
+	}
+	catalog, err := db.AddCatalog(upload.ID, "Номенклатура", "Номенклатура")
+	if err != nil {
+		t.Fatalf("AddCatalog() error = %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := db.AddCatalogItem(catalog.ID, fmt.Sprintf("ref-%d", i), fmt.Sprintf("%03d", i), "Болт \"М10\"", "<Вес>1</Вес>", ""); err != nil {
+			t.Fatalf("AddCatalogItem() error = %v", err)
+		}
+	}
+
+	s := &Server{db: db, config: &Config{}, logChan: make(chan LogEntry, 100)}
+	s.uploadDBs.putShared(upload.UploadUUID, db)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTotal int
+		wantTypes []string
+	}{
+		{"constants page", "?type=all&limit=2", 8, []string{"constant", "constant"}},
+		{"page across constants and items", "?type=all&limit=2&page=2", 8, []string{"constant", "catalog_item"}},
+		{"items after constants", "?type=all&limit=2&page=3", 8, []string{"catalog_item", "catalog_item"}},
+		{"beyond last page", "?type=all&limit=5&page=3", 8, nil},
+		{"catalogs only", "?type=catalogs&limit=10", 5, []string{"catalog_item", "catalog_item", "catalog_item", "catalog_item", "catalog_item"}},
+		{"constants only", "?type=constants&limit=2&page=2", 3, []string{"constant"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleGetUploadData(rec, httptest.NewRequest(http.MethodGet, "/api/uploads/uuid-data/data"+tt.query, nil), upload)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp DataResponse
+			if err := xml.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("xml.Unmarshal() error = %v: %s", err, rec.Body.String())
+			}
+			if resp.Total != tt.wantTotal || len(resp.Items) != len(tt.wantTypes) {
+				t.Fatalf("total = %d, items = %d, want %d and %d", resp.Total, len(resp.Items), tt.wantTotal, len(tt.wantTypes))
+			}
+			for i, item := range resp.Items {
+				if item.Type != tt.wantTypes[i] {
+					t.Errorf("item %d type = %s, want %s", i, item.Type, tt.wantTypes[i])
+				}
+				switch item.Type {
+				case "constant":
+					var constant constantDataXML
+					if err := xml.Unmarshal([]byte(item.Data), &constant); err != nil || constant.Synonym != "A & <B>" {
+						t.Errorf("constant = %+v, %v", constant, err)
+					}
+				case "catalog_item":
+					if !strings.Contains(item.Data, "<attributes_xml><Вес>1</Вес></attributes_xml>") {
+						t.Errorf("catalog item attributes are not embedded as XML: %s", item.Data)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestXMLStreamWriterBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping 1M row export in short mode")
+	}
+	const rows = 1000000
+	// Буфер потока и служебные аллокации кодировщика; весь ответ на 1M строк занимает сотни мегабайт
+	const memoryBudget = 16 << 20
+
+	s := &Server{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := func(i int) dataItemXML {
+		if i%2 == 0 {
+			return newConstantDataItem(&database.Constant{ID: i, UploadID: 1, Name: "Константа", Value: "A & <B>", CreatedAt: createdAt}, nil)
+		}
+		return s.newCatalogDataItem(r, &database.CatalogItem{
+			ID: i, CatalogID: 1, CatalogName: "Номенклатура", Reference: "ref", Code: "001",
+			Name: "Болт М10", Attributes: "<Вес>1</Вес>", CreatedAt: createdAt,
+		}, nil)
+	}
+
+	tests := []struct {
+		name  string
+		write func(sw *xmlStreamWriter, i int) error
+		begin func(sw *xmlStreamWriter) error
+		end   func(sw *xmlStreamWriter) error
+	}{
+		{
+			name:  "data response",
+			begin: func(sw *xmlStreamWriter) error { return sw.beginDataResponse(DataResponse{Type: "all", Total: rows}) },
+			write: func(sw *xmlStreamWriter, i int) error { return sw.writeDataItem(item(i)) },
+			end:   func(sw *xmlStreamWriter) error { return sw.endDataResponse() },
+		},
+		{
+			name:  "sse events",
+			begin: func(sw *xmlStreamWriter) error { return nil },
+			write: func(sw *xmlStreamWriter, i int) error { return sw.writeEvent(item(i)) },
+			end:   func(sw *xmlStreamWriter) error { return sw.writeEventData(`{"type":"complete"}`) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			base := stats.HeapAlloc
+			peak := base
+
+			out := &countingFlusher{}
+			sw := newXMLStreamWriter(out)
+			if err := tt.begin(sw); err != nil {
+				t.Fatalf("begin error = %v", err)
+			}
+			for i := 0; i < rows; i++ {
+				if err := tt.write(sw, i); err != nil {
+					t.Fatalf("write %d error = %v", i, err)
+				}
+				if i%100000 == 0 {
+					runtime.ReadMemStats(&stats)
+					peak = max(peak, stats.HeapAlloc)
+				}
+			}
+			if err := tt.end(sw); err != nil {
+				t.Fatalf("end error = %v", err)
+			}
+
+			if growth := peak - base; growth > memoryBudget {
+				t.Errorf("heap grew by %d MB while streaming %d rows, budget %d MB", growth>>20, rows, memoryBudget>>20)
+			}
+			if out.flushes < rows/streamFlushEvery {
+				t.Errorf("flushes = %d, want at least %d", out.flushes, rows/streamFlushEvery)
+			}
+			if out.bytes < int64(rows)*100 {
+				t.Errorf("streamed %d bytes for %d rows", out.bytes, rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,434 @@
+package server
+
+import (
+	"container/list"
+	"log"
+	"sync"
+	"time"
+
+	"httpserver/database"
+)
+
+// uploadDBHandle открытая БД выгрузок и UUID выгрузок, которые к ней обращаются
+type uploadDBHandle struct {
+	db       *database.DB
+	path     string
+	owned    bool // БД открыта кэшем и закрывается при вытеснении; общие БД сервера не закрываются
+	lastUsed time.Time
+	uuids    map[string]bool
+	elem     *list.Element
+
+	refs       int  // Захваты долгими операциями (потоковая отдача, экспорт, пересчет)
+	closing    bool // БД вытеснена и закрывается, когда истечет задержка и будут отпущены все захваты
+	graceEnded bool
+}
+
+// UploadDBCacheStats метрики кэша БД выгрузок
+type UploadDBCacheStats struct {
+	Entries    int   `json:"entries"`     // Выгрузок в кэше
+	OpenDBs    int   `json:"open_dbs"`    // Открытых кэшем файлов БД, включая ожидающие закрытия
+	Closing    int   `json:"closing"`     // Вытесненных БД, ожидающих закрытия (задержка или захват)
+	MaxOpen    int   `json:"max_open"`    // Ограничение открытых файлов (0 - без ограничения)
+	Evicted    int   `json:"evicted"`     // Выгрузок, которые будут открыты заново при обращении
+	Hits       int64 `json:"hits"`        // Обращений к открытой БД
+	Misses     int64 `json:"misses"`      // Обращений к неизвестной выгрузке
+	Reopens    int64 `json:"reopens"`     // Повторных открытий вытесненных БД
+	Evictions  int64 `json:"evictions"`   // Вытеснений по ограничению числа открытых БД
+	IdleClosed int64 `json:"idle_closed"` // Закрытий по простою
+}
+
+// uploadDBCache кэш БД выгрузок (ключ - upload_uuid) с вытеснением давно не используемых БД (LRU),
+// ограничением числа открытых файлов и закрытием по простою. Нулевое значение готово к использованию
+type uploadDBCache struct {
+	mu          sync.Mutex
+	maxOpen     int
+	idleTimeout time.Duration
+	// Время, в течение которого вытесненная БД остается открытой для коротких запросов,
+	// получивших ссылку на БД без захвата (см. retain); 0 - закрывается сразу
+	closeGrace time.Duration
+	open       func(path string) (*database.DB, error)
+
+	byUUID  map[string]*uploadDBHandle
+	byPath  map[string]*uploadDBHandle
+	evicted map[string]string // upload_uuid -> путь вытесненной БД для повторного открытия
+	lru     list.List         // Открытые кэшем БД, в начале - недавно использованные
+	closing map[*database.DB]*uploadDBHandle
+	// Захваты общих БД сервера; переходят к закрываемой БД при выводе общей БД из работы (retire)
+	sharedRefs map[*database.DB]int
+	stats      UploadDBCacheStats
+}
+
+// configure задает ограничение открытых БД, таймаут простоя, задержку закрытия вытесненных БД и функцию открытия БД
+func (c *uploadDBCache) configure(maxOpen int, idleTimeout, closeGrace time.Duration, open func(path string) (*database.DB, error)) {
+	c.mu.Lock()
+	c.maxOpen = maxOpen
+	c.idleTimeout = idleTimeout
+	c.closeGrace = closeGrace
+	c.open = open
+	c.mu.Unlock()
+}
+
+// init создает карты при первом обращении
+func (c *uploadDBCache) init() {
+	if c.byUUID == nil {
+		c.byUUID = make(map[string]*uploadDBHandle)
+		c.byPath = make(map[string]*uploadDBHandle)
+		c.evicted = make(map[string]string)
+		c.closing = make(map[*database.DB]*uploadDBHandle)
+		c.sharedRefs = make(map[*database.DB]int)
+	}
+}
+
+// get возвращает БД выгрузки; вытесненная ранее БД открывается заново
+func (c *uploadDBCache) get(uploadUUID string) (*database.DB, bool) {
+	c.mu.Lock()
+	c.init()
+	if handle, ok := c.byUUID[uploadUUID]; ok {
+		c.touch(handle)
+		c.stats.Hits++
+		c.mu.Unlock()
+		return handle.db, true
+	}
+	path, wasEvicted := c.evicted[uploadUUID]
+	open := c.open
+	if !wasEvicted {
+		c.stats.Misses++
+	}
+	c.mu.Unlock()
+
+	if !wasEvicted {
+		return nil, false
+	}
+	if open == nil {
+		open = database.NewDB
+	}
+	db, err := open(path)
+	if err != nil {
+		log.Printf("Не удалось повторно открыть БД выгрузки %s (%s): %v", uploadUUID, path, err)
+		return nil, false
+	}
+
+	c.mu.Lock()
+	c.stats.Reopens++
+	c.mu.Unlock()
+	return c.put(uploadUUID, path, db), true
+}
+
+// put добавляет БД, открытую для выгрузки, и возвращает используемый экземпляр.
+// Если файл уже открыт кэшем, переданная БД закрывается и используется открытая
+func (c *uploadDBCache) put(uploadUUID, path string, db *database.DB) *database.DB {
+	c.mu.Lock()
+	c.init()
+	handle, exists := c.byPath[path]
+	var duplicate *database.DB
+	if exists && handle.db != db {
+		duplicate = db
+	} else if !exists {
+		handle = &uploadDBHandle{db: db, path: path, owned: true, uuids: make(map[string]bool)}
+		handle.elem = c.lru.PushFront(handle)
+		c.byPath[path] = handle
+	}
+	c.attach(uploadUUID, handle)
+	toClose := c.evictOverLimit()
+	c.mu.Unlock()
+
+	if duplicate != nil {
+		duplicate.Close()
+	}
+	c.closeHandles(toClose)
+	return handle.db
+}
+
+// putShared связывает выгрузку с общей БД сервера, которая не закрывается кэшем
+func (c *uploadDBCache) putShared(uploadUUID string, db *database.DB) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	c.attach(uploadUUID, &uploadDBHandle{db: db, uuids: make(map[string]bool)})
+}
+
+// attach связывает выгрузку с БД, отвязывая ее от предыдущей
+func (c *uploadDBCache) attach(uploadUUID string, handle *uploadDBHandle) {
+	if previous, ok := c.byUUID[uploadUUID]; ok && previous != handle {
+		delete(previous.uuids, uploadUUID)
+	}
+	handle.uuids[uploadUUID] = true
+	c.byUUID[uploadUUID] = handle
+	delete(c.evicted, uploadUUID)
+	c.touch(handle)
+}
+
+// touch отмечает использование БД
+func (c *uploadDBCache) touch(handle *uploadDBHandle) {
+	handle.lastUsed = time.Now()
+	if handle.elem != nil {
+		c.lru.MoveToFront(handle.elem)
+	}
+}
+
+// evictOverLimit вытесняет давно не используемые БД сверх ограничения и возвращает их для закрытия
+func (c *uploadDBCache) evictOverLimit() []*uploadDBHandle {
+	var victims []*uploadDBHandle
+	for c.maxOpen > 0 && c.lru.Len() > c.maxOpen {
+		handle := c.lru.Back().Value.(*uploadDBHandle)
+		c.remove(handle, true)
+		c.stats.Evictions++
+		victims = append(victims, handle)
+	}
+	return victims
+}
+
+// remove удаляет БД из кэша; при remember выгрузки запоминаются для повторного открытия
+func (c *uploadDBCache) remove(handle *uploadDBHandle, remember bool) {
+	for uploadUUID := range handle.uuids {
+		delete(c.byUUID, uploadUUID)
+		if remember && handle.owned {
+			c.evicted[uploadUUID] = handle.path
+		}
+	}
+	if handle.elem != nil {
+		c.lru.Remove(handle.elem)
+		handle.elem = nil
+		delete(c.byPath, handle.path)
+	}
+}
+
+// evictIdle удаляет из кэша БД, не использовавшиеся дольше таймаута простоя, и закрывает открытые кэшем
+func (c *uploadDBCache) evictIdle(now time.Time) int {
+	c.mu.Lock()
+	c.init()
+	if c.idleTimeout <= 0 {
+		c.mu.Unlock()
+		return 0
+	}
+	seen := make(map[*uploadDBHandle]bool)
+	var victims []*uploadDBHandle
+	for _, handle := range c.byUUID {
+		if seen[handle] || handle.refs > 0 || now.Sub(handle.lastUsed) < c.idleTimeout {
+			continue
+		}
+		seen[handle] = true
+		c.remove(handle, true)
+		if handle.owned {
+			c.stats.IdleClosed++
+			victims = append(victims, handle)
+		}
+	}
+	c.mu.Unlock()
+
+	c.closeHandles(victims)
+	return len(victims)
+}
+
+// retire удаляет из кэша все выгрузки общей БД, выведенной сервером из работы (переключение БД), и закрывает ее
+// как вытесненную: после задержки для коротких запросов, освобождения всех захватов и закрытия inUse
+// (nil - не ожидается). Возвращает число удаленных выгрузок
+func (c *uploadDBCache) retire(db *database.DB, inUse <-chan struct{}) int {
+	c.mu.Lock()
+	c.init()
+	removed := 0
+	for uploadUUID, handle := range c.byUUID {
+		if handle.db == db {
+			delete(c.byUUID, uploadUUID)
+			delete(handle.uuids, uploadUUID)
+			removed++
+		}
+	}
+	handle := &uploadDBHandle{db: db, owned: true, lastUsed: time.Now(), refs: c.sharedRefs[db]}
+	delete(c.sharedRefs, db)
+	if inUse != nil {
+		handle.refs++
+	}
+	c.mu.Unlock()
+
+	c.closeHandles([]*uploadDBHandle{handle})
+	if inUse != nil {
+		go func() {
+			<-inUse
+			c.release(handle)
+		}()
+	}
+	return removed
+}
+
+// databases возвращает различные БД в кэше
+func (c *uploadDBCache) databases() []*database.DB {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seen := make(map[*database.DB]bool)
+	var dbs []*database.DB
+	for _, handle := range c.byUUID {
+		if !seen[handle.db] {
+			seen[handle.db] = true
+			dbs = append(dbs, handle.db)
+		}
+	}
+	return dbs
+}
+
+// Stats возвращает метрики кэша
+func (c *uploadDBCache) Stats() UploadDBCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.byUUID)
+	stats.Closing = len(c.closing)
+	stats.OpenDBs = c.lru.Len() + stats.Closing
+	stats.MaxOpen = c.maxOpen
+	stats.Evicted = len(c.evicted)
+	return stats
+}
+
+// closeAll закрывает все открытые кэшем БД (при остановке сервера)
+func (c *uploadDBCache) closeAll() {
+	c.mu.Lock()
+	c.init()
+	var handles []*uploadDBHandle
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		handles = append(handles, e.Value.(*uploadDBHandle))
+	}
+	for _, handle := range handles {
+		c.remove(handle, false)
+	}
+	for db, handle := range c.closing {
+		delete(c.closing, db)
+		handles = append(handles, handle)
+	}
+	c.mu.Unlock()
+
+	for _, handle := range handles {
+		handle.db.Close()
+	}
+}
+
+// retain захватывает БД, полученную из кэша, на время долгой операции: вытесненная или выведенная из работы БД
+// не закрывается, пока захват не отпущен. Для БД вне кэша возвращается пустая функция освобождения
+func (c *uploadDBCache) retain(db *database.DB) (release func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.init()
+	var handle *uploadDBHandle
+	for _, h := range c.byPath {
+		if h.db == db {
+			handle = h
+			break
+		}
+	}
+	if handle == nil {
+		handle = c.closing[db]
+	}
+	var once sync.Once
+	if handle == nil {
+		if !c.isShared(db) {
+			return func() {}
+		}
+		c.sharedRefs[db]++
+		return func() {
+			once.Do(func() { c.releaseShared(db) })
+		}
+	}
+
+	handle.refs++
+	return func() {
+		once.Do(func() { c.release(handle) })
+	}
+}
+
+// isShared проверяет, связана ли с db выгрузка как с общей БД сервера
+func (c *uploadDBCache) isShared(db *database.DB) bool {
+	for _, handle := range c.byUUID {
+		if handle.db == db && !handle.owned {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseShared отпускает захват общей БД; если БД уже выведена из работы, захват отпускается у закрываемой БД
+func (c *uploadDBCache) releaseShared(db *database.DB) {
+	c.mu.Lock()
+	if handle, retired := c.closing[db]; retired {
+		c.mu.Unlock()
+		c.release(handle)
+		return
+	}
+	if c.sharedRefs[db]--; c.sharedRefs[db] <= 0 {
+		delete(c.sharedRefs, db)
+	}
+	c.mu.Unlock()
+}
+
+// release отпускает захват БД и закрывает вытесненную БД после последнего захвата
+func (c *uploadDBCache) release(handle *uploadDBHandle) {
+	c.mu.Lock()
+	handle.refs--
+	handle.lastUsed = time.Now()
+	closeNow := handle.refs == 0 && handle.closing && handle.graceEnded
+	if closeNow {
+		delete(c.closing, handle.db)
+	}
+	c.mu.Unlock()
+
+	if closeNow {
+		handle.db.Close()
+	}
+}
+
+// closeHandles закрывает вытесненные БД. Недавно использованные закрываются с задержкой, чтобы короткие
+// запросы, уже получившие ссылку на БД, успели завершиться; захваченные - после освобождения последнего захвата
+func (c *uploadDBCache) closeHandles(handles []*uploadDBHandle) {
+	for _, handle := range handles {
+		if !handle.owned {
+			continue
+		}
+		c.mu.Lock()
+		handle.closing = true
+		c.closing[handle.db] = handle
+		grace := c.closeGrace
+		c.mu.Unlock()
+
+		if idle := time.Since(handle.lastUsed); idle < grace {
+			handle := handle
+			time.AfterFunc(grace-idle, func() { c.endGrace(handle) })
+			continue
+		}
+		c.endGrace(handle)
+	}
+}
+
+// endGrace закрывает вытесненную БД по истечении задержки, если она не захвачена
+func (c *uploadDBCache) endGrace(handle *uploadDBHandle) {
+	c.mu.Lock()
+	handle.graceEnded = true
+	_, pending := c.closing[handle.db]
+	closeNow := pending && handle.refs == 0
+	if closeNow {
+		delete(c.closing, handle.db)
+	}
+	c.mu.Unlock()
+
+	if closeNow {
+		handle.db.Close()
+	}
+}
+
+// runUploadDBCacheJanitor периодически закрывает БД выгрузок, не использовавшиеся дольше таймаута простоя
+func (s *Server) runUploadDBCacheJanitor() {
+	if s.currentConfig().UploadDBIdleTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.currentConfig().UploadDBIdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if closed := s.uploadDBs.evictIdle(now); closed > 0 {
+				log.Printf("Закрыто БД выгрузок по простою: %d", closed)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
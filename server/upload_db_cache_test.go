@@ -0,0 +1,145 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"httpserver/database"
+)
+
+func TestUploadDBCache(t *testing.T) {
+	dir := t.TempDir()
+	var opened int
+	var cache uploadDBCache
+	cache.configure(2, time.Minute, 0, func(path string) (*database.DB, error) {
+		opened++
+		return database.NewDB(path)
+	})
+	defer cache.closeAll()
+
+	paths := make([]string, 3)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("upload%d.db", i))
+		db, err := database.NewDB(paths[i])
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		cache.put(fmt.Sprintf("uuid-%d", i), paths[i], db)
+	}
+
+	stats := cache.Stats()
+	if stats.OpenDBs != 2 || stats.Entries != 2 || stats.Evictions != 1 || stats.Evicted != 1 {
+		t.Fatalf("stats after limit = %+v, want 2 open and uuid-0 evicted", stats)
+	}
+
+	// Вытесненная выгрузка открывается заново и вытесняет наименее используемую
+	db, ok := cache.get("uuid-0")
+	if !ok || db == nil || opened != 1 {
+		t.Fatalf("get(evicted) = %v, %v, opened %d", db, ok, opened)
+	}
+	if _, err := db.GetStats(); err != nil {
+		t.Errorf("reopened database is not usable: %v", err)
+	}
+	if _, ok := cache.get("unknown"); ok {
+		t.Error("get(unknown) must miss")
+	}
+	stats = cache.Stats()
+	if stats.Reopens != 1 || stats.OpenDBs != 2 || stats.Misses != 1 {
+		t.Errorf("stats after reopen = %+v", stats)
+	}
+
+	// Повторное открытие того же файла для другой выгрузки использует открытое подключение
+	duplicate, err := database.NewDB(paths[0])
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if got := cache.put("uuid-0-second", paths[0], duplicate); got != db {
+		t.Error("put() must reuse open database for the same file")
+	}
+
+	shared, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer shared.Close()
+	cache.putShared("shared", shared)
+
+	if closed := cache.evictIdle(time.Now()); closed != 0 {
+		t.Errorf("evictIdle() closed %d recently used databases", closed)
+	}
+	if closed := cache.evictIdle(time.Now().Add(2 * time.Minute)); closed != 2 {
+		t.Errorf("evictIdle() closed %d databases, want 2", closed)
+	}
+	stats = cache.Stats()
+	if stats.Entries != 0 || stats.OpenDBs != 0 || stats.IdleClosed != 2 {
+		t.Errorf("stats after idle eviction = %+v", stats)
+	}
+	if _, err := shared.GetStats(); err != nil {
+		t.Errorf("shared database must stay open: %v", err)
+	}
+	if _, ok := cache.get("shared"); ok {
+		t.Error("idle shared entry must be dropped from cache")
+	}
+}
+
+func TestUploadDBCacheRetain(t *testing.T) {
+	dir := t.TempDir()
+	var cache uploadDBCache
+	cache.configure(1, time.Minute, time.Minute, nil)
+	defer cache.closeAll()
+
+	open := func(name string) (string, *database.DB) {
+		path := filepath.Join(dir, name)
+		db, err := database.NewDB(path)
+		if err != nil {
+			t.Fatalf("Failed to create database: %v", err)
+		}
+		return path, db
+	}
+	path, streamed := open("streamed.db")
+	cache.put("uuid-streamed", path, streamed)
+	release := cache.retain(streamed)
+
+	// Вытеснение захваченной БД не закрывает ее даже по истечении задержки
+	path, other := open("other.db")
+	cache.put("uuid-other", path, other)
+	cache.mu.Lock()
+	handle := cache.closing[streamed]
+	cache.mu.Unlock()
+	if handle == nil {
+		t.Fatal("evicted database must wait for release")
+	}
+	if stats := cache.Stats(); stats.OpenDBs != 2 || stats.Closing != 1 {
+		t.Errorf("stats during close grace = %+v, want 2 open and 1 closing", stats)
+	}
+	cache.endGrace(handle)
+	if _, err := streamed.GetStats(); err != nil {
+		t.Fatalf("retained database was closed: %v", err)
+	}
+
+	release()
+	release() // Повторное освобождение не уменьшает счетчик
+	if _, err := streamed.GetStats(); err == nil {
+		t.Error("evicted database must be closed after the last release")
+	}
+	if handle.refs != 0 {
+		t.Errorf("refs = %d, want 0", handle.refs)
+	}
+	if stats := cache.Stats(); stats.OpenDBs != 1 || stats.Closing != 0 {
+		t.Errorf("stats after close = %+v, want 1 open", stats)
+	}
+
+	// Общие БД сервера не захватываются
+	shared, err := database.NewDB(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer shared.Close()
+	cache.putShared("shared", shared)
+	cache.retain(shared)()
+	if _, err := shared.GetStats(); err != nil {
+		t.Errorf("shared database must stay open: %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"httpserver/database"
 	"httpserver/nomenclature"
+	"httpserver/normalization"
 )
 
 // ProviderConfig конфигурация провайдера AI
@@ -48,6 +49,18 @@ type WorkerConfigManager struct {
 	globalMaxWorkers  int
 	configFilePath    string
 	serviceDB         *database.ServiceDB // Добавить это поле
+	listeners         []func(WorkerConfigChange) // Подписчики на изменения конфигурации
+	modelChains       map[string][]normalization.ModelChainStep // Цепочки моделей по задачам (classification, normalization)
+}
+
+// WorkerConfigChange итоговая конфигурация после изменения, рассылается подписчикам
+type WorkerConfigChange struct {
+	Action     string    `json:"action"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	RateLimit  int       `json:"rate_limit"`  // Запросов в минуту
+	MaxWorkers int       `json:"max_workers"` // Глобальный максимум воркеров
+	Timestamp  time.Time `json:"timestamp"`
 }
 
 // NewWorkerConfigManager создает новый менеджер конфигурации
@@ -59,6 +72,7 @@ func NewWorkerConfigManager(serviceDB *database.ServiceDB) *WorkerConfigManager
 		globalMaxWorkers: 2,
 		configFilePath:   "worker_config.json",
 		serviceDB:        serviceDB, // Добавить это
+		modelChains:      make(map[string][]normalization.ModelChainStep),
 	}
 
 	// Инициализация дефолтной конфигурации
@@ -148,101 +162,197 @@ func (wcm *WorkerConfigManager) GetConfig() map[string]interface{} {
 		"default_provider": wcm.defaultProvider,
 		"default_model":    wcm.defaultModel,
 		"global_max_workers": wcm.globalMaxWorkers,
+		"model_chains":     wcm.modelChains,
 	}
 }
 
-// UpdateProvider обновляет конфигурацию провайдера
-func (wcm *WorkerConfigManager) UpdateProvider(name string, config *ProviderConfig) error {
+// GetModelChain возвращает цепочку моделей задачи (nil - используется активная модель)
+func (wcm *WorkerConfigManager) GetModelChain(task string) []normalization.ModelChainStep {
+	wcm.mu.RLock()
+	defer wcm.mu.RUnlock()
+
+	steps := wcm.modelChains[task]
+	if len(steps) == 0 {
+		return nil
+	}
+	return append([]normalization.ModelChainStep{}, steps...)
+}
+
+// SetModelChain устанавливает цепочку моделей задачи (пустая цепочка удаляет настройку)
+func (wcm *WorkerConfigManager) SetModelChain(task string, steps []normalization.ModelChainStep) error {
+	if task != normalization.ModelChainTaskClassification && task != normalization.ModelChainTaskNormalization {
+		return fmt.Errorf("unknown task %s (expected %s or %s)", task,
+			normalization.ModelChainTaskClassification, normalization.ModelChainTaskNormalization)
+	}
+	if err := normalization.ValidateModelChain(steps); err != nil {
+		return err
+	}
+
+	return wcm.applyChange("set_model_chain", func() error {
+		if len(steps) == 0 {
+			delete(wcm.modelChains, task)
+		} else {
+			wcm.modelChains[task] = append([]normalization.ModelChainStep{}, steps...)
+		}
+		return wcm.saveConfig()
+	})
+}
+
+// Subscribe регистрирует обработчик изменений конфигурации.
+// Обработчик вызывается после сохранения изменения, вне блокировки менеджера.
+func (wcm *WorkerConfigManager) Subscribe(listener func(WorkerConfigChange)) {
 	wcm.mu.Lock()
 	defer wcm.mu.Unlock()
 
-	if config == nil {
-		return fmt.Errorf("config cannot be nil")
+	wcm.listeners = append(wcm.listeners, listener)
+}
+
+// applyChange выполняет изменение под блокировкой и уведомляет подписчиков об итоговой конфигурации
+func (wcm *WorkerConfigManager) applyChange(action string, change func() error) error {
+	wcm.mu.Lock()
+	err := change()
+	listeners := append([]func(WorkerConfigChange){}, wcm.listeners...)
+	wcm.mu.Unlock()
+
+	if err != nil {
+		return err
 	}
 
-	// Сохраняем старый API ключ, если он не указан в новом конфиге
-	if existing, ok := wcm.providers[name]; ok && config.APIKey == "" {
-		config.APIKey = existing.APIKey
+	if len(listeners) > 0 {
+		event := wcm.currentChange(action)
+		for _, listener := range listeners {
+			listener(event)
+		}
 	}
+	return nil
+}
+
+// currentChange собирает действующие провайдера, модель и лимиты
+func (wcm *WorkerConfigManager) currentChange(action string) WorkerConfigChange {
+	change := WorkerConfigChange{
+		Action:    action,
+		Timestamp: time.Now(),
+	}
+
+	if provider, err := wcm.GetActiveProvider(); err == nil {
+		change.Provider = provider.Name
+		change.RateLimit = provider.RateLimit
+		if model, err := wcm.GetActiveModel(provider.Name); err == nil {
+			change.Model = model.Name
+		}
+	}
+
+	wcm.mu.RLock()
+	if change.Model == "" {
+		change.Model = wcm.defaultModel
+	}
+	change.MaxWorkers = wcm.globalMaxWorkers
+	wcm.mu.RUnlock()
 
-	config.Name = name
-	wcm.providers[name] = config
+	return change
+}
+
+// UpdateProvider обновляет конфигурацию провайдера
+func (wcm *WorkerConfigManager) UpdateProvider(name string, config *ProviderConfig) error {
+	return wcm.applyChange("update_provider", func() error {
+		if config == nil {
+			return fmt.Errorf("config cannot be nil")
+		}
 
-	// Сохраняем конфигурацию
-	return wcm.saveConfig()
+		// Сохраняем старый API ключ, если он не указан в новом конфиге
+		if existing, ok := wcm.providers[name]; ok && config.APIKey == "" {
+			config.APIKey = existing.APIKey
+		}
+
+		config.Name = name
+		wcm.providers[name] = config
+
+		// Сохраняем конфигурацию
+		return wcm.saveConfig()
+	})
 }
 
 // UpdateModel обновляет конфигурацию модели
 func (wcm *WorkerConfigManager) UpdateModel(providerName, modelName string, modelConfig *ModelConfig) error {
-	wcm.mu.Lock()
-	defer wcm.mu.Unlock()
+	return wcm.applyChange("update_model", func() error {
+		provider, ok := wcm.providers[providerName]
+		if !ok {
+			return fmt.Errorf("provider %s not found", providerName)
+		}
 
-	provider, ok := wcm.providers[providerName]
-	if !ok {
-		return fmt.Errorf("provider %s not found", providerName)
-	}
+		// Ищем модель и обновляем или добавляем
+		found := false
+		for i, model := range provider.Models {
+			if model.Name == modelName {
+				modelConfig.Name = modelName
+				modelConfig.Provider = providerName
+				provider.Models[i] = *modelConfig
+				found = true
+				break
+			}
+		}
 
-	// Ищем модель и обновляем или добавляем
-	found := false
-	for i, model := range provider.Models {
-		if model.Name == modelName {
+		if !found {
 			modelConfig.Name = modelName
 			modelConfig.Provider = providerName
-			provider.Models[i] = *modelConfig
-			found = true
-			break
+			provider.Models = append(provider.Models, *modelConfig)
 		}
-	}
 
-	if !found {
-		modelConfig.Name = modelName
-		modelConfig.Provider = providerName
-		provider.Models = append(provider.Models, *modelConfig)
-	}
-
-	// Сохраняем конфигурацию
-	return wcm.saveConfig()
+		// Сохраняем конфигурацию
+		return wcm.saveConfig()
+	})
 }
 
 // SetDefaultProvider устанавливает провайдера по умолчанию
 func (wcm *WorkerConfigManager) SetDefaultProvider(name string) error {
-	wcm.mu.Lock()
-	defer wcm.mu.Unlock()
-
-	if _, ok := wcm.providers[name]; !ok {
-		return fmt.Errorf("provider %s not found", name)
-	}
+	return wcm.applyChange("set_default_provider", func() error {
+		if _, ok := wcm.providers[name]; !ok {
+			return fmt.Errorf("provider %s not found", name)
+		}
 
-	wcm.defaultProvider = name
-	return wcm.saveConfig()
+		wcm.defaultProvider = name
+		return wcm.saveConfig()
+	})
 }
 
 // SetDefaultModel устанавливает модель по умолчанию
 func (wcm *WorkerConfigManager) SetDefaultModel(providerName, modelName string) error {
-	wcm.mu.Lock()
-	defer wcm.mu.Unlock()
+	return wcm.applyChange("set_default_model", func() error {
+		provider, ok := wcm.providers[providerName]
+		if !ok {
+			return fmt.Errorf("provider %s not found", providerName)
+		}
 
-	provider, ok := wcm.providers[providerName]
-	if !ok {
-		return fmt.Errorf("provider %s not found", providerName)
-	}
+		// Проверяем, что модель существует
+		found := false
+		for _, model := range provider.Models {
+			if model.Name == modelName {
+				found = true
+				break
+			}
+		}
 
-	// Проверяем, что модель существует
-	found := false
-	for _, model := range provider.Models {
-		if model.Name == modelName {
-			found = true
-			break
+		if !found {
+			return fmt.Errorf("model %s not found in provider %s", modelName, providerName)
 		}
-	}
 
-	if !found {
-		return fmt.Errorf("model %s not found in provider %s", modelName, providerName)
-	}
+		wcm.defaultProvider = providerName
+		wcm.defaultModel = modelName
+		return wcm.saveConfig()
+	})
+}
+
+// SetProviderAPIKey заменяет API ключ провайдера
+func (wcm *WorkerConfigManager) SetProviderAPIKey(providerName, apiKey string) error {
+	return wcm.applyChange("set_api_key", func() error {
+		provider, ok := wcm.providers[providerName]
+		if !ok {
+			return fmt.Errorf("provider %s not found", providerName)
+		}
 
-	wcm.defaultProvider = providerName
-	wcm.defaultModel = modelName
-	return wcm.saveConfig()
+		provider.APIKey = apiKey
+		return wcm.saveConfig()
+	})
 }
 
 // SetGlobalMaxWorkers устанавливает глобальный максимум воркеров
@@ -251,11 +361,10 @@ func (wcm *WorkerConfigManager) SetGlobalMaxWorkers(maxWorkers int) error {
 		return fmt.Errorf("max workers must be between 1 and 100")
 	}
 
-	wcm.mu.Lock()
-	defer wcm.mu.Unlock()
-
-	wcm.globalMaxWorkers = maxWorkers
-	return wcm.saveConfig()
+	return wcm.applyChange("set_max_workers", func() error {
+		wcm.globalMaxWorkers = maxWorkers
+		return wcm.saveConfig()
+	})
 }
 
 // GetActiveProvider возвращает активный провайдер (с наивысшим приоритетом)
@@ -301,6 +410,10 @@ func (wcm *WorkerConfigManager) GetActiveModel(providerName string) (*ModelConfi
 		if !model.Enabled {
 			continue
 		}
+		// Явно выбранная модель по умолчанию важнее приоритетов (переключение модели во время работы)
+		if providerName == wcm.defaultProvider && model.Name == wcm.defaultModel {
+			return model, nil
+		}
 		if model.Priority < lowestPriority {
 			lowestPriority = model.Priority
 			activeModel = model
@@ -493,14 +606,38 @@ func (wcm *WorkerConfigManager) loadConfig() {
 		wcm.globalMaxWorkers = int(globalMaxWorkers)
 	}
 
+	// Восстанавливаем цепочки моделей
+	if chainsData, ok := configData["model_chains"].(map[string]interface{}); ok {
+		for task, stepsData := range chainsData {
+			stepsList, ok := stepsData.([]interface{})
+			if !ok {
+				continue
+			}
+			var steps []normalization.ModelChainStep
+			for _, stepData := range stepsList {
+				if stepMap, ok := stepData.(map[string]interface{}); ok {
+					steps = append(steps, normalization.ModelChainStep{
+						Model:         getString(stepMap, "model"),
+						MinConfidence: getFloat64(stepMap, "min_confidence"),
+					})
+				}
+			}
+			if err := normalization.ValidateModelChain(steps); err != nil {
+				log.Printf("Invalid model chain for %s: %v, skipping", task, err)
+				continue
+			}
+			if len(steps) > 0 {
+				wcm.modelChains[task] = steps
+			}
+		}
+	}
+
 	log.Printf("Config loaded from service database")
 }
 
 // saveConfig сохраняет конфигурацию в сервисную БД
+// Вызывается под блокировкой wcm.mu (из applyChange)
 func (wcm *WorkerConfigManager) saveConfig() error {
-	wcm.mu.RLock()
-	defer wcm.mu.RUnlock()
-
 	// Если ServiceDB не доступна, только логируем
 	if wcm.serviceDB == nil {
 		log.Printf("Config updated in memory (ServiceDB not available)")
@@ -530,6 +667,7 @@ func (wcm *WorkerConfigManager) saveConfig() error {
 		"default_provider":   wcm.defaultProvider,
 		"default_model":      wcm.defaultModel,
 		"global_max_workers": wcm.globalMaxWorkers,
+		"model_chains":       wcm.modelChains,
 	}
 
 	configJSON, err := json.Marshal(configData)
@@ -0,0 +1,95 @@
+// Package storage хранилище крупных артефактов (отчеты, резервные копии, архивы):
+// локальный каталог или S3-совместимое объектное хранилище (AWS S3, MinIO)
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound объект отсутствует в хранилище
+var ErrNotFound = errors.New("object not found")
+
+// Backend-ы хранилища
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// DefaultPresignTTL срок действия ссылки на скачивание по умолчанию
+const DefaultPresignTTL = time.Hour
+
+// ObjectInfo сведения об объекте
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Storage объектное хранилище артефактов
+type Storage interface {
+	// Backend возвращает тип хранилища (local, s3)
+	Backend() string
+	// Put сохраняет объект; size может быть -1, если размер неизвестен
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get открывает объект на чтение
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat возвращает сведения об объекте или ErrNotFound
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Delete удаляет объект; отсутствие объекта ошибкой не считается
+	Delete(ctx context.Context, key string) error
+	// List возвращает объекты с ключами, начинающимися с prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// PresignGet возвращает ссылку на скачивание объекта, действующую ttl
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Config настройки хранилища развертывания
+type Config struct {
+	Backend string // local (по умолчанию) или s3
+
+	// Локальный каталог
+	LocalDir        string
+	LocalURLPrefix  string // Префикс ссылок на скачивание, которые обслуживает сервер
+	LocalSigningKey string // Ключ подписи ссылок на скачивание
+
+	// S3 / MinIO
+	S3Endpoint  string // Например https://s3.eu-central-1.amazonaws.com или http://minio:9000
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3PathStyle bool // Адрес вида endpoint/bucket/key (MinIO) вместо bucket.endpoint/key
+	S3Prefix    string
+}
+
+// New создает хранилище по конфигурации
+func New(config Config) (Storage, error) {
+	switch strings.ToLower(strings.TrimSpace(config.Backend)) {
+	case "", BackendLocal:
+		return NewLocalStorage(config.LocalDir, config.LocalURLPrefix, config.LocalSigningKey)
+	case BackendS3:
+		return NewS3Storage(config)
+	default:
+		return nil, fmt.Errorf("unsupported storage backend: %s", config.Backend)
+	}
+}
+
+// CleanKey нормализует ключ объекта и запрещает выход за пределы хранилища
+func CleanKey(key string) (string, error) {
+	key = strings.TrimSpace(strings.ReplaceAll(key, "\\", "/"))
+	if key == "" {
+		return "", errors.New("object key is required")
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", fmt.Errorf("invalid object key: %s", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader заголовок W3C Trace Context
+const TraceparentHeader = "traceparent"
+
+// TraceIDHeader заголовок ответа с идентификатором трассировки для поиска запроса в коллекторе
+const TraceIDHeader = "X-Trace-ID"
+
+// ParseTraceparent разбирает заголовок traceparent версии 00: 00-<trace-id>-<span-id>-<flags>
+func ParseTraceparent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if parts[0] == "ff" {
+		return SpanContext{}, fmt.Errorf("unsupported traceparent version %q", parts[0])
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid trace id: %w", err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid parent id: %w", err)
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, fmt.Errorf("invalid trace flags: %w", err)
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("traceparent contains zero ids")
+	}
+	sc.Sampled = flags[0]&0x01 == 0x01
+	return sc, nil
+}
+
+// FormatTraceparent формирует заголовок traceparent
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// Inject добавляет в заголовки исходящего запроса контекст трассировки ctx
+func Inject(ctx context.Context, header http.Header) {
+	if sc, ok := parentSpanContext(ctx); ok && sc.IsValid() {
+		header.Set(TraceparentHeader, FormatTraceparent(sc))
+	}
+}
+
+// Middleware создает серверный спан для каждого HTTP запроса, продолжая трассировку из traceparent.
+// route возвращает шаблон маршрута для имени спана; nil - используется путь запроса
+func Middleware(next http.Handler, route func(*http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Global() == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		if sc, err := ParseTraceparent(r.Header.Get(TraceparentHeader)); err == nil {
+			ctx = ContextWithRemoteSpanContext(ctx, sc)
+		}
+		name := r.URL.Path
+		if route != nil {
+			if pattern := route(r); pattern != "" {
+				name = pattern
+			}
+		}
+		ctx, span := StartKind(ctx, r.Method+" "+name, SpanKindServer,
+			Attr("http.method", r.Method),
+			Attr("http.target", r.URL.Path),
+			Attr("http.route", name),
+		)
+		defer span.End()
+		w.Header().Set(TraceIDHeader, span.SpanContext().TraceID.String())
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(Attr("http.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("HTTP %d", recorder.status))
+		}
+	})
+}
+
+// statusRecorder запоминает код ответа
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush поддерживает потоковые ответы (SSE) через обертку
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// ParseHeaders разбирает список заголовков в формате OTEL_EXPORTER_OTLP_HEADERS: key1=value1,key2=value2
+func ParseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" {
+			headers[key] = strings.TrimSpace(val)
+		}
+	}
+	return headers
+}
@@ -0,0 +1,350 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Значения по умолчанию пакетного экспорта (совпадают с переменными OTEL_BSP_* спецификации)
+const (
+	DefaultBatchSize     = 512
+	DefaultQueueSize     = 2048
+	DefaultFlushInterval = 5 * time.Second
+	DefaultExportTimeout = 10 * time.Second
+)
+
+// Config настройки трассировки
+type Config struct {
+	Endpoint      string            // OTLP/HTTP коллектор, например http://localhost:4318; пустое значение отключает трассировку
+	ServiceName   string            // Атрибут ресурса service.name
+	Headers       map[string]string // Дополнительные заголовки запросов к коллектору (авторизация)
+	SampleRatio   float64           // Доля корневых трассировок, отправляемых в коллектор (0..1)
+	BatchSize     int               // Максимум спанов в одном запросе к коллектору
+	QueueSize     int               // Очередь завершенных спанов; при переполнении спаны отбрасываются
+	FlushInterval time.Duration     // Период отправки неполного пакета
+	ExportTimeout time.Duration     // Ограничение времени запроса к коллектору
+}
+
+// Stats счетчики экспорта спанов
+type Stats struct {
+	Exported int64 `json:"exported"`
+	Dropped  int64 `json:"dropped"` // Отброшены из-за переполнения очереди
+	Failed   int64 `json:"failed"`  // Не приняты коллектором
+}
+
+// Tracer создает спаны и отправляет завершенные спаны в коллектор пакетами
+type Tracer struct {
+	config   Config
+	endpoint string
+	client   *http.Client
+
+	queue    chan *Span
+	flushReq chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	exported atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+}
+
+// New создает трассировщик и запускает фоновую отправку спанов
+func New(config Config) (*Tracer, error) {
+	endpoint, err := tracesEndpoint(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio must be between 0 and 1")
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "httpserver"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.ExportTimeout <= 0 {
+		config.ExportTimeout = DefaultExportTimeout
+	}
+
+	t := &Tracer{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: config.ExportTimeout},
+		queue:    make(chan *Span, config.QueueSize),
+		flushReq: make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t, nil
+}
+
+// tracesEndpoint возвращает адрес приема спанов: к базовому адресу коллектора добавляется /v1/traces
+func tracesEndpoint(endpoint string) (string, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		return "", fmt.Errorf("OTLP endpoint is required")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: expected http(s)://host:port", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, "/v1/traces") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/traces"
+	}
+	return u.String(), nil
+}
+
+// Stats возвращает счетчики экспорта
+func (t *Tracer) Stats() Stats {
+	return Stats{Exported: t.exported.Load(), Dropped: t.dropped.Load(), Failed: t.failed.Load()}
+}
+
+// enqueue ставит завершенный спан в очередь экспорта без блокировки
+func (t *Tracer) enqueue(span *Span) {
+	select {
+	case <-t.stop:
+		t.dropped.Add(1)
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// Flush отправляет накопленные спаны и ждет завершения отправки
+func (t *Tracer) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flushReq <- ack:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown отправляет оставшиеся спаны и останавливает экспорт
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run собирает спаны в пакеты и отправляет их по заполнению, по таймеру, по запросу Flush и при остановке
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, t.config.BatchSize)
+	send := func() {
+		if len(batch) > 0 {
+			t.export(batch)
+			batch = batch[:0]
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-t.queue:
+				if batch = append(batch, span); len(batch) >= t.config.BatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			if batch = append(batch, span); len(batch) >= t.config.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-t.flushReq:
+			drain()
+			close(ack)
+		case <-t.stop:
+			drain()
+			return
+		}
+	}
+}
+
+// export отправляет пакет спанов в коллектор
+func (t *Tracer) export(spans []*Span) {
+	body, err := json.Marshal(t.encode(spans))
+	if err != nil {
+		t.failed.Add(int64(len(spans)))
+		log.Printf("Ошибка кодирования спанов OTLP: %v", err)
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		t.failed.Add(int64(len(spans)))
+		log.Printf("Ошибка создания запроса OTLP: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.failed.Add(int64(len(spans)))
+		log.Printf("Ошибка отправки спанов в коллектор %s: %v", t.endpoint, err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		t.failed.Add(int64(len(spans)))
+		log.Printf("Коллектор %s отклонил спаны: статус %d", t.endpoint, resp.StatusCode)
+		return
+	}
+	t.exported.Add(int64(len(spans)))
+}
+
+// Структуры JSON кодирования OTLP (ExportTraceServiceRequest)
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 0 - не задан, 2 - ошибка
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// encode формирует тело запроса OTLP для пакета спанов
+func (t *Tracer) encode(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		item := otlpSpan{
+			TraceID:           span.spanContext.TraceID.String(),
+			SpanID:            span.spanContext.SpanID.String(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.attributes),
+		}
+		if span.errMessage != "" {
+			item.Status = otlpStatus{Code: 2, Message: span.errMessage}
+		}
+		span.mu.Unlock()
+		if span.parentID.IsValid() {
+			item.ParentSpanID = span.parentID.String()
+		}
+		encoded = append(encoded, item)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes([]Attribute{Attr("service.name", t.config.ServiceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "httpserver/tracing"}, Spans: encoded}},
+	}}}
+}
+
+// encodeAttributes преобразует атрибуты в OTLP; неизвестные типы передаются строкой
+func encodeAttributes(attrs []Attribute) []otlpKeyValue {
+	encoded := make([]otlpKeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		var value otlpValue
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			value.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		encoded = append(encoded, otlpKeyValue{Key: attr.Key, Value: value})
+	}
+	return encoded
+}
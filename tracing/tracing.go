@@ -0,0 +1,275 @@
+// Package tracing трассировка запросов в модели OpenTelemetry: спаны с контекстом W3C Trace Context
+// и пакетный экспорт в коллектор по OTLP/HTTP (JSON). Пока трассировщик не установлен через SetGlobal,
+// все функции пакета ничего не делают, а методы nil *Span безопасны
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceID идентификатор трассировки
+type TraceID [16]byte
+
+// String возвращает идентификатор в шестнадцатеричном виде
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid сообщает, что идентификатор не нулевой
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// SpanID идентификатор спана
+type SpanID [8]byte
+
+// String возвращает идентификатор в шестнадцатеричном виде
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid сообщает, что идентификатор не нулевой
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext идентификация спана, передаваемая между сервисами
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool // Трассировка экспортируется
+}
+
+// IsValid сообщает, что контекст содержит идентификаторы трассировки и спана
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// SpanKind вид спана в терминах OTLP
+type SpanKind int
+
+// Виды спанов
+const (
+	SpanKindInternal SpanKind = 1 // Внутренняя операция (этапы нормализации)
+	SpanKindServer   SpanKind = 2 // Обработка входящего запроса
+	SpanKindClient   SpanKind = 3 // Исходящий запрос (БД, AI провайдер)
+)
+
+// Attribute атрибут спана
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr создает атрибут спана
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span операция в рамках трассировки. Методы nil спана ничего не делают
+type Span struct {
+	tracer      *Tracer
+	name        string
+	kind        SpanKind
+	spanContext SpanContext
+	parentID    SpanID
+	start       time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	errMessage string
+	ended      bool
+}
+
+// SpanContext возвращает идентификацию спана
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.spanContext
+}
+
+// SetName заменяет имя спана (например, маршрутом, известным только после диспетчеризации)
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttributes добавляет атрибуты спана
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attributes = append(s.attributes, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError отмечает спан как завершившийся ошибкой; nil игнорируется
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End завершает спан и передает его на экспорт. Повторные вызовы игнорируются
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.spanContext.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan возвращает контекст с текущим спаном
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext возвращает текущий спан контекста или nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext возвращает контекст с родительским спаном другого сервиса
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// parentSpanContext возвращает контекст родительского спана: локального или удаленного
+func parentSpanContext(ctx context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.spanContext, true
+	}
+	sc, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return sc, ok
+}
+
+// Detach возвращает новый фоновый контекст, продолжающий трассировку ctx, но не отменяемый вместе с ним.
+// Используется для фоновых задач, запускаемых из HTTP запроса
+func Detach(ctx context.Context) context.Context {
+	if sc, ok := parentSpanContext(ctx); ok {
+		return ContextWithRemoteSpanContext(context.Background(), sc)
+	}
+	return context.Background()
+}
+
+// TraceIDFromContext возвращает идентификатор трассировки контекста или пустую строку
+func TraceIDFromContext(ctx context.Context) string {
+	if sc, ok := parentSpanContext(ctx); ok {
+		return sc.TraceID.String()
+	}
+	return ""
+}
+
+// global трассировщик, используемый функциями пакета
+var global atomic.Pointer[Tracer]
+
+// SetGlobal устанавливает трассировщик; nil отключает трассировку
+func SetGlobal(t *Tracer) {
+	global.Store(t)
+}
+
+// Global возвращает установленный трассировщик или nil
+func Global() *Tracer {
+	return global.Load()
+}
+
+// Start начинает спан - дочерний для спана ctx или корневой. Без трассировщика возвращает ctx и nil
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	return StartKind(ctx, name, SpanKindInternal, attrs...)
+}
+
+// StartKind начинает спан заданного вида
+func StartKind(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	t := Global()
+	if t == nil {
+		return ctx, nil
+	}
+	span := t.newSpan(ctx, name, kind, attrs)
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartChild начинает спан только внутри существующей трассировки, чтобы частые операции
+// (запросы к БД) не порождали отдельных корневых трассировок
+func StartChild(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	if _, ok := parentSpanContext(ctx); !ok {
+		return ctx, nil
+	}
+	return StartKind(ctx, name, kind, attrs...)
+}
+
+// newSpan создает спан, наследуя трассировку и решение о сэмплировании родителя
+func (t *Tracer) newSpan(ctx context.Context, name string, kind SpanKind, attrs []Attribute) *Span {
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: append([]Attribute(nil), attrs...),
+	}
+	if parent, ok := parentSpanContext(ctx); ok {
+		span.spanContext.TraceID = parent.TraceID
+		span.spanContext.Sampled = parent.Sampled
+		span.parentID = parent.SpanID
+	} else {
+		span.spanContext.TraceID = newTraceID()
+		span.spanContext.Sampled = t.sample(span.spanContext.TraceID)
+	}
+	span.spanContext.SpanID = newSpanID()
+	return span
+}
+
+// sample принимает решение о сэмплировании корневой трассировки по ее идентификатору
+func (t *Tracer) sample(id TraceID) bool {
+	switch {
+	case t.config.SampleRatio >= 1:
+		return true
+	case t.config.SampleRatio <= 0:
+		return false
+	}
+	// Младшие 8 байт идентификатора случайны, поэтому их доля от максимума равномерно распределена
+	return float64(binary.BigEndian.Uint64(id[8:])>>11)/float64(uint64(1)<<53) < t.config.SampleRatio
+}
+
+// newTraceID создает случайный идентификатор трассировки (crypto/rand не возвращает ошибок)
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// newSpanID создает случайный идентификатор спана
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
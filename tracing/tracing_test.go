@@ -0,0 +1,225 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		sampled bool
+		wantErr bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, false},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, true},
+		{"bad version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, true},
+		{"bad hex", "00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01", false, true},
+		{"short", "00-4bf92f35-00f067aa0ba902b7-01", false, true},
+		{"empty", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTraceparent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if sc.Sampled != tt.sampled {
+				t.Errorf("Sampled = %v, want %v", sc.Sampled, tt.sampled)
+			}
+			if got := FormatTraceparent(sc); got != tt.value {
+				t.Errorf("FormatTraceparent() = %q, want %q", got, tt.value)
+			}
+		})
+	}
+}
+
+// collector принимает запросы OTLP/HTTP и запоминает спаны
+type collector struct {
+	mu     sync.Mutex
+	spans  []otlpSpan
+	header http.Header
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if r.URL.Path != "/v1/traces" || json.NewDecoder(r.Body).Decode(&req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.header = r.Header.Clone()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+	c.mu.Unlock()
+}
+
+func (c *collector) byName() map[string]otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spans := make(map[string]otlpSpan)
+	for _, span := range c.spans {
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func newTestTracer(t *testing.T, ratio float64) (*Tracer, *collector) {
+	t.Helper()
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+
+	tracer, err := New(Config{Endpoint: srv.URL, SampleRatio: ratio, Headers: map[string]string{"Authorization": "Bearer token"}, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	SetGlobal(tracer)
+	t.Cleanup(func() {
+		SetGlobal(nil)
+		tracer.Shutdown(context.Background())
+	})
+	return tracer, c
+}
+
+func TestTracerExport(t *testing.T) {
+	tracer, c := newTestTracer(t, 1)
+
+	ctx, root := Start(context.Background(), "normalization", Attr("items", 10))
+	_, child := StartChild(ctx, "db.query", SpanKindClient, Attr("db.system", "sqlite"))
+	child.RecordError(errors.New("database is locked"))
+	child.End()
+	root.End()
+	root.End() // Повторное завершение не дублирует спан
+
+	if _, orphan := StartChild(context.Background(), "db.query", SpanKindClient); orphan != nil {
+		t.Error("StartChild() without parent must not create span")
+	}
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	spans := c.byName()
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+
+	parent, query := spans["normalization"], spans["db.query"]
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"same trace", parent.TraceID == query.TraceID && parent.TraceID == root.SpanContext().TraceID.String()},
+		{"parent link", query.ParentSpanID == parent.SpanID && parent.ParentSpanID == ""},
+		{"kind", query.Kind == SpanKindClient && parent.Kind == SpanKindInternal},
+		{"error status", query.Status.Code == 2 && query.Status.Message == "database is locked" && parent.Status.Code == 0},
+		{"int attribute", len(parent.Attributes) == 1 && parent.Attributes[0].Value.IntValue != nil && *parent.Attributes[0].Value.IntValue == "10"},
+		{"headers", c.header.Get("Authorization") == "Bearer token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.ok {
+				t.Errorf("parent = %+v, query = %+v", parent, query)
+			}
+		})
+	}
+	if stats := tracer.Stats(); stats.Exported != 2 || stats.Failed != 0 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
+
+func TestTracerSampling(t *testing.T) {
+	tracer, c := newTestTracer(t, 0)
+
+	_, dropped := Start(context.Background(), "unsampled")
+	dropped.End()
+
+	// Решение вызывающего сервиса о сэмплировании имеет приоритет
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, kept := Start(ContextWithRemoteSpanContext(context.Background(), remote), "sampled by parent")
+	kept.End()
+
+	tracer.Flush(context.Background())
+	spans := c.byName()
+	if _, ok := spans["unsampled"]; ok {
+		t.Error("root span with ratio 0 must not be exported")
+	}
+	if span, ok := spans["sampled by parent"]; !ok || span.TraceID != remote.TraceID.String() || span.ParentSpanID != remote.SpanID.String() {
+		t.Errorf("spans = %+v, want child of remote parent", spans)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tracer, c := newTestTracer(t, 1)
+
+	var traceID string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID = TraceIDFromContext(r.Context())
+		// Фоновая задача продолжает трассировку, но не отменяется вместе с запросом
+		_, job := Start(Detach(r.Context()), "job")
+		job.End()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), func(*http.Request) string { return "/api/uploads/" })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/uploads/abc", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || rec.Header().Get(TraceIDHeader) != traceID {
+		t.Errorf("trace id = %q, header = %q", traceID, rec.Header().Get(TraceIDHeader))
+	}
+
+	tracer.Flush(context.Background())
+	spans := c.byName()
+	server, ok := spans["GET /api/uploads/"]
+	if !ok || server.Kind != SpanKindServer || server.Status.Code != 2 {
+		t.Fatalf("spans = %+v, want failed server span", spans)
+	}
+	if job := spans["job"]; job.ParentSpanID != server.SpanID {
+		t.Errorf("job parent = %q, want %q", job.ParentSpanID, server.SpanID)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		endpoint string
+		wantErr  bool
+	}{
+		{"base url", Config{Endpoint: "http://collector:4318"}, "http://collector:4318/v1/traces", false},
+		{"full url", Config{Endpoint: "https://collector/otlp/v1/traces"}, "https://collector/otlp/v1/traces", false},
+		{"empty", Config{}, "", true},
+		{"bad scheme", Config{Endpoint: "grpc://collector:4317"}, "", true},
+		{"bad ratio", Config{Endpoint: "http://collector:4318", SampleRatio: 2}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, err := New(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				defer tracer.Shutdown(context.Background())
+				if tracer.endpoint != tt.endpoint {
+					t.Errorf("endpoint = %q, want %q", tracer.endpoint, tt.endpoint)
+				}
+			}
+		})
+	}
+}